@@ -11,6 +11,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envflag"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
@@ -61,7 +62,19 @@ func main() {
 	startTime := time.Now()
 	storage.SetDedupInterval(*minScrapeInterval)
 	storage.SetDataFlushInterval(*inmemoryDataFlushInterval)
+	auditlog.Init()
 	vmstorage.Init(promql.ResetRollupResultCacheIfNeeded)
+	if *rewriteRelabelConfig != "" {
+		err := rewriteSeries()
+		vmstorage.Stop()
+		fs.MustStopDirRemover()
+		auditlog.MustStop()
+		if err != nil {
+			logger.Fatalf("cannot rewrite series: %s", err)
+		}
+		logger.Infof("successfully rewritten series; exiting with 0 status code")
+		return
+	}
 	vmselect.Init()
 	vminsert.Init()
 	startSelfScraper()
@@ -86,6 +99,7 @@ func main() {
 	vmselect.Stop()
 
 	fs.MustStopDirRemover()
+	auditlog.MustStop()
 
 	logger.Infof("the VictoriaMetrics has been stopped in %.3f seconds", time.Since(startTime).Seconds())
 }
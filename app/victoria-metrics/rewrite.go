@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var (
+	rewriteRelabelConfig = flag.String("rewrite.relabelConfig", "", "Optional path to file with relabeling rules to apply to all the series stored at -storageDataPath. "+
+		"VictoriaMetrics rewrites the series matching -rewrite.match and then exits without accepting incoming requests. "+
+		"See https://docs.victoriametrics.com/#rewriting-series")
+	rewriteMatch = flagutil.NewArrayString("rewrite.match", "Series selector for series to rewrite with -rewrite.relabelConfig. "+
+		`All the series are rewritten if the flag isn't set. For example, -rewrite.match='{job="foo"}'`)
+	rewriteDryRun = flag.Bool("rewrite.dryRun", false, "Whether to only report the number of series, which would be rewritten or dropped by -rewrite.relabelConfig, "+
+		"without modifying the data")
+)
+
+// rewriteSeries rewrites series at -storageDataPath according to -rewrite.* command-line flags.
+func rewriteSeries() error {
+	pcs, err := promrelabel.LoadRelabelConfigs(*rewriteRelabelConfig)
+	if err != nil {
+		return fmt.Errorf("cannot load -rewrite.relabelConfig: %w", err)
+	}
+	tfss, err := getRewriteTagFilterss()
+	if err != nil {
+		return err
+	}
+	logger.Infof("rewriting series matching %s with -rewrite.relabelConfig=%q, -rewrite.dryRun=%v", tfss, *rewriteRelabelConfig, *rewriteDryRun)
+	startTime := time.Now()
+	vmstorage.WG.Add(1)
+	rs, err := vmstorage.Storage.RewriteSeries(nil, tfss, newSeriesRewriter(pcs), *rewriteDryRun)
+	vmstorage.WG.Done()
+	if err != nil {
+		return err
+	}
+	logger.Infof("series matched: %d, held: %d, rewritten: %d, dropped: %d; samples rewritten: %d; duration: %.3f seconds",
+		rs.SeriesMatched, rs.SeriesHeld, rs.SeriesRewritten, rs.SeriesDropped, rs.SamplesRewritten, time.Since(startTime).Seconds())
+	return nil
+}
+
+func getRewriteTagFilterss() ([]*storage.TagFilters, error) {
+	selectors := *rewriteMatch
+	if len(selectors) == 0 {
+		selectors = []string{`{__name__!=""}`}
+	}
+	tfss := make([]*storage.TagFilters, 0, len(selectors))
+	for _, selector := range selectors {
+		tagFilters, err := searchutils.ParseMetricSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse -rewrite.match=%q: %w", selector, err)
+		}
+		tfs := storage.NewTagFilters()
+		for i := range tagFilters {
+			tf := &tagFilters[i]
+			if err := tfs.Add(tf.Key, tf.Value, tf.IsNegative, tf.IsRegexp); err != nil {
+				return nil, fmt.Errorf("cannot parse tag filter %s from -rewrite.match=%q: %w", tf, selector, err)
+			}
+		}
+		tfss = append(tfss, tfs)
+	}
+	return tfss, nil
+}
+
+// newSeriesRewriter returns storage.SeriesRewriter, which applies pcs to series.
+func newSeriesRewriter(pcs *promrelabel.ParsedConfigs) storage.SeriesRewriter {
+	var labels []prompbmarshal.Label
+	var mnNew storage.MetricName
+	return func(mn *storage.MetricName) *storage.MetricName {
+		labels = append(labels[:0], prompbmarshal.Label{
+			Name:  "__name__",
+			Value: string(mn.MetricGroup),
+		})
+		for _, tag := range mn.Tags {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  string(tag.Key),
+				Value: string(tag.Value),
+			})
+		}
+		labels = pcs.Apply(labels, 0)
+		labels = promrelabel.FinalizeLabels(labels[:0], labels)
+		if len(labels) == 0 {
+			return nil
+		}
+		mnNew.Reset()
+		for _, label := range labels {
+			if label.Name == "__name__" {
+				mnNew.MetricGroup = append(mnNew.MetricGroup, label.Value...)
+				continue
+			}
+			mnNew.AddTag(label.Name, label.Value)
+		}
+		return &mnNew
+	}
+}
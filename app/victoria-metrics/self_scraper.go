@@ -82,6 +82,11 @@ func selfScraper(scrapeInterval time.Duration) {
 			}
 			mr := &mrs[len(mrs)-1]
 			mr.MetricNameRaw = storage.MarshalMetricNameRaw(mr.MetricNameRaw[:0], labels)
+			if len(mr.MetricNameRaw) == 0 {
+				// The metric is rejected because of label limits.
+				mrs = mrs[:len(mrs)-1]
+				continue
+			}
 			mr.Timestamp = currentTimestamp
 			mr.Value = r.Value
 		}
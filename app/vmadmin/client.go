@@ -0,0 +1,98 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// client sends requests to VictoriaMetrics admin endpoints.
+type client struct {
+	addr        string
+	user        string
+	password    string
+	bearerToken string
+	authKey     string
+	hc          *http.Client
+}
+
+func newClient(c *cli.Context) (*client, error) {
+	addr := strings.TrimSuffix(c.String(globalAddr), "/")
+	if addr == "" {
+		return nil, fmt.Errorf("--%s cannot be empty", globalAddr)
+	}
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	if c.Bool(globalInsecureSkipVerify) {
+		tr.TLSClientConfig = &tls.Config{
+			InsecureSkipVerify: true,
+		}
+	}
+	return &client{
+		addr:        addr,
+		user:        c.String(globalUser),
+		password:    c.String(globalPassword),
+		bearerToken: c.String(globalBearerToken),
+		authKey:     c.String(globalAuthKey),
+		hc: &http.Client{
+			Timeout:   c.Duration(globalTimeout),
+			Transport: tr,
+		},
+	}, nil
+}
+
+// get sends GET request to the given path with the given args and returns the response body.
+func (cl *client) get(path string, args url.Values) ([]byte, error) {
+	return cl.do(http.MethodGet, path, args)
+}
+
+// post sends POST request to the given path with the given args and returns the response body.
+func (cl *client) post(path string, args url.Values) ([]byte, error) {
+	return cl.do(http.MethodPost, path, args)
+}
+
+func (cl *client) do(method, path string, args url.Values) ([]byte, error) {
+	if args == nil {
+		args = url.Values{}
+	}
+	if cl.authKey != "" {
+		args.Set("authKey", cl.authKey)
+	}
+	var body io.Reader
+	reqURL := cl.addr + path
+	if method == http.MethodPost {
+		body = strings.NewReader(args.Encode())
+	} else if len(args) > 0 {
+		reqURL += "?" + args.Encode()
+	}
+	req, err := http.NewRequest(method, reqURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request to %q: %w", cl.addr+path, err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if cl.user != "" || cl.password != "" {
+		req.SetBasicAuth(cl.user, cl.password)
+	}
+	if cl.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+cl.bearerToken)
+	}
+	resp, err := cl.hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot send request to %q: %w", cl.addr+path, err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %q: %w", cl.addr+path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response code %d from %q; response body: %q", resp.StatusCode, cl.addr+path, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
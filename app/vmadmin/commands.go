@@ -0,0 +1,517 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// withClient returns cli action, which calls f with the client and the output configured via global flags.
+func withClient(f func(c *cli.Context, cl *client, o *output) error) cli.ActionFunc {
+	return func(c *cli.Context) error {
+		cl, err := newClient(c)
+		if err != nil {
+			return err
+		}
+		o, err := newOutput(os.Stdout, c.String(globalOutput))
+		if err != nil {
+			return err
+		}
+		return f(c, cl, o)
+	}
+}
+
+// statusResponse is the common part of JSON responses returned by VictoriaMetrics admin endpoints.
+type statusResponse struct {
+	Status string `json:"status"`
+	Msg    string `json:"msg"`
+	Error  string `json:"error"`
+}
+
+// unmarshalResponse unmarshals data into dst and returns an error if data contains error status.
+func unmarshalResponse(data []byte, dst interface{}) error {
+	var sr statusResponse
+	if err := json.Unmarshal(data, &sr); err != nil {
+		return fmt.Errorf("cannot parse response %q: %w", data, err)
+	}
+	if sr.Status == "error" {
+		msg := sr.Msg
+		if msg == "" {
+			msg = sr.Error
+		}
+		return fmt.Errorf("error response: %s", msg)
+	}
+	if dst == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("cannot parse response %q: %w", data, err)
+	}
+	return nil
+}
+
+func snapshotCreate(c *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	for _, name := range []string{"start", "end", "lookback"} {
+		if v := c.String(name); v != "" {
+			args.Set(name, v)
+		}
+	}
+	data, err := cl.post("/snapshot/create", args)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Snapshot string `json:"snapshot"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	return o.writeTable([]string{"SNAPSHOT"}, [][]string{{resp.Snapshot}})
+}
+
+func snapshotList(_ *cli.Context, cl *client, o *output) error {
+	data, err := cl.get("/snapshot/list", nil)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Snapshots []string `json:"snapshots"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	rows := make([][]string, 0, len(resp.Snapshots))
+	for _, s := range resp.Snapshots {
+		rows = append(rows, []string{s})
+	}
+	return o.writeTable([]string{"SNAPSHOT"}, rows)
+}
+
+func snapshotDelete(c *cli.Context, cl *client, o *output) error {
+	name := c.Args().First()
+	if name == "" {
+		return fmt.Errorf("missing snapshot name; usage: vmadmin snapshot delete <name>")
+	}
+	args := url.Values{}
+	args.Set("snapshot", name)
+	data, err := cl.post("/snapshot/delete", args)
+	if err != nil {
+		return err
+	}
+	if err := unmarshalResponse(data, nil); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	return o.writeTable([]string{"DELETED"}, [][]string{{name}})
+}
+
+func snapshotDeleteAll(_ *cli.Context, cl *client, o *output) error {
+	data, err := cl.post("/snapshot/delete_all", nil)
+	if err != nil {
+		return err
+	}
+	if err := unmarshalResponse(data, nil); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	return o.writeTable([]string{"STATUS"}, [][]string{{"all snapshots have been deleted"}})
+}
+
+// forceMergeJob is the forced merge job status returned by /internal/force_merge/status.
+type forceMergeJob struct {
+	JobID            uint64  `json:"job_id"`
+	Scope            string  `json:"scope"`
+	State            string  `json:"state"`
+	Error            string  `json:"error"`
+	StartTime        string  `json:"start_time"`
+	FinishTime       string  `json:"finish_time"`
+	CurrentPartition string  `json:"current_partition"`
+	PartitionsTotal  int     `json:"partitions_total"`
+	PartitionsMerged int     `json:"partitions_merged"`
+	BytesTotal       uint64  `json:"bytes_total"`
+	BytesMerged      uint64  `json:"bytes_merged"`
+	ETASeconds       float64 `json:"eta_seconds"`
+}
+
+func forceMerge(c *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	for _, name := range []string{"partition_prefix", "min_partition", "max_partition"} {
+		if v := c.String(strings.ReplaceAll(name, "_", "-")); v != "" {
+			args.Set(name, v)
+		}
+	}
+	data, err := cl.post("/internal/force_merge", args)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		JobID uint64 `json:"job_id"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	return o.writeTable([]string{"JOB_ID"}, [][]string{{strconv.FormatUint(resp.JobID, 10)}})
+}
+
+func forceMergeStatus(c *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	if id := c.Args().First(); id != "" {
+		args.Set("job_id", id)
+	}
+	data, err := cl.get("/internal/force_merge/status", args)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Job  *forceMergeJob  `json:"job"`
+		Jobs []forceMergeJob `json:"jobs"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	jobs := resp.Jobs
+	if resp.Job != nil {
+		jobs = []forceMergeJob{*resp.Job}
+	}
+	rows := make([][]string, 0, len(jobs))
+	for _, j := range jobs {
+		rows = append(rows, []string{
+			strconv.FormatUint(j.JobID, 10),
+			j.State,
+			fmt.Sprintf("%d/%d", j.PartitionsMerged, j.PartitionsTotal),
+			formatPercent(j.BytesMerged, j.BytesTotal),
+			fmt.Sprintf("%.0fs", j.ETASeconds),
+			j.StartTime,
+			j.FinishTime,
+			j.Error,
+		})
+	}
+	return o.writeTable([]string{"JOB_ID", "STATE", "PARTITIONS", "PROGRESS", "ETA", "STARTED", "FINISHED", "ERROR"}, rows)
+}
+
+func formatPercent(n, total uint64) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(n)/float64(total))
+}
+
+func storageMode(c *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	if mode := c.Args().First(); mode != "" {
+		args.Set("set", mode)
+	}
+	return setStorageMode(cl, o, args)
+}
+
+func drain(_ *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	args.Set("set", "drain")
+	return setStorageMode(cl, o, args)
+}
+
+func setStorageMode(cl *client, o *output, args url.Values) error {
+	var data []byte
+	var err error
+	if len(args) > 0 {
+		data, err = cl.post("/internal/mode", args)
+	} else {
+		data, err = cl.get("/internal/mode", nil)
+	}
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Mode         string `json:"mode"`
+		ActiveMerges int    `json:"activeMerges"`
+		PendingRows  uint64 `json:"pendingRows"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	return o.writeTable([]string{"MODE", "ACTIVE_MERGES", "PENDING_ROWS"}, [][]string{{
+		resp.Mode, strconv.Itoa(resp.ActiveMerges), strconv.FormatUint(resp.PendingRows, 10),
+	}})
+}
+
+// tsdbStatusEntry is an entry returned by /api/v1/status/tsdb.
+type tsdbStatusEntry struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+func tsdbStatus(c *cli.Context, cl *client, o *output) error {
+	args := url.Values{}
+	if n := c.Int("top-n"); n > 0 {
+		args.Set("topN", strconv.Itoa(n))
+	}
+	if v := c.String("date"); v != "" {
+		args.Set("date", v)
+	}
+	if v := c.String("focus-label"); v != "" {
+		args.Set("focusLabel", v)
+	}
+	for _, m := range c.StringSlice("match") {
+		args.Add("match[]", m)
+	}
+	data, err := cl.get("/api/v1/status/tsdb", args)
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data struct {
+			TotalSeries                  uint64            `json:"totalSeries"`
+			TotalLabelValuePairs         uint64            `json:"totalLabelValuePairs"`
+			SeriesCountByMetricName      []tsdbStatusEntry `json:"seriesCountByMetricName"`
+			SeriesCountByLabelName       []tsdbStatusEntry `json:"seriesCountByLabelName"`
+			SeriesCountByFocusLabelValue []tsdbStatusEntry `json:"seriesCountByFocusLabelValue"`
+			SeriesCountByLabelValuePair  []tsdbStatusEntry `json:"seriesCountByLabelValuePair"`
+			LabelValueCountByLabelName   []tsdbStatusEntry `json:"labelValueCountByLabelName"`
+		} `json:"data"`
+	}
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	d := &resp.Data
+	rows := [][]string{
+		{"totalSeries", "", strconv.FormatUint(d.TotalSeries, 10)},
+		{"totalLabelValuePairs", "", strconv.FormatUint(d.TotalLabelValuePairs, 10)},
+	}
+	rows = appendTSDBStatusRows(rows, "seriesCountByMetricName", d.SeriesCountByMetricName)
+	rows = appendTSDBStatusRows(rows, "seriesCountByLabelName", d.SeriesCountByLabelName)
+	rows = appendTSDBStatusRows(rows, "seriesCountByFocusLabelValue", d.SeriesCountByFocusLabelValue)
+	rows = appendTSDBStatusRows(rows, "seriesCountByLabelValuePair", d.SeriesCountByLabelValuePair)
+	rows = appendTSDBStatusRows(rows, "labelValueCountByLabelName", d.LabelValueCountByLabelName)
+	return o.writeTable([]string{"STAT", "NAME", "VALUE"}, rows)
+}
+
+func appendTSDBStatusRows(dst [][]string, stat string, entries []tsdbStatusEntry) [][]string {
+	for _, e := range entries {
+		dst = append(dst, []string{stat, e.Name, strconv.FormatUint(e.Value, 10)})
+	}
+	return dst
+}
+
+func deleteSeries(c *cli.Context, cl *client, o *output) error {
+	matches := c.StringSlice("match")
+	if len(matches) == 0 {
+		return fmt.Errorf("at least a single --match must be set")
+	}
+	args := url.Values{}
+	for _, m := range matches {
+		args.Add("match[]", m)
+	}
+	if _, err := cl.post("/api/v1/admin/tsdb/delete_series", args); err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON([]byte(`{"status":"ok"}`))
+	}
+	rows := make([][]string, 0, len(matches))
+	for _, m := range matches {
+		rows = append(rows, []string{m})
+	}
+	return o.writeTable([]string{"DELETED_MATCH"}, rows)
+}
+
+// activeQuery is a query returned by /api/v1/status/active_queries.
+type activeQuery struct {
+	Duration   string `json:"duration"`
+	ID         string `json:"id"`
+	RemoteAddr string `json:"remoteAddr"`
+	Query      string `json:"query"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Step       string `json:"step"`
+}
+
+func activeQueries(_ *cli.Context, cl *client, o *output) error {
+	data, err := cl.get("/api/v1/status/active_queries", nil)
+	if err != nil {
+		return err
+	}
+	aqs, err := parseActiveQueries(string(data))
+	if err != nil {
+		return err
+	}
+	if o.isJSON() {
+		data, err := json.Marshal(aqs)
+		if err != nil {
+			return fmt.Errorf("cannot marshal active queries: %w", err)
+		}
+		return o.writeJSON(data)
+	}
+	rows := make([][]string, 0, len(aqs))
+	for _, aq := range aqs {
+		rows = append(rows, []string{aq.Duration, aq.ID, aq.RemoteAddr, aq.Start, aq.End, aq.Step, aq.Query})
+	}
+	return o.writeTable([]string{"DURATION", "ID", "REMOTE_ADDR", "START", "END", "STEP", "QUERY"}, rows)
+}
+
+// parseActiveQueries parses the response from /api/v1/status/active_queries.
+//
+// Every line in s has the following format:
+//
+//	duration: 0.123s, id=000000000000000A, remote_addr="1.2.3.4:5678", query="up", start=1, end=2, step=3
+func parseActiveQueries(s string) ([]activeQuery, error) {
+	aqs := []activeQuery{}
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		n := strings.Index(line, ", query=")
+		m := strings.LastIndex(line, ", start=")
+		if n < 0 || m < n {
+			return nil, fmt.Errorf("cannot parse active query line %q", line)
+		}
+		var aq activeQuery
+		for _, kv := range strings.Split(line[:n], ", ") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch {
+			case strings.HasPrefix(k, "duration: "):
+				aq.Duration = strings.TrimPrefix(k, "duration: ")
+			case k == "id":
+				aq.ID = v
+			case k == "remote_addr":
+				aq.RemoteAddr = v
+			}
+		}
+		q, err := strconv.Unquote(line[n+len(", query=") : m])
+		if err != nil {
+			return nil, fmt.Errorf("cannot unquote query in active query line %q: %w", line, err)
+		}
+		aq.Query = q
+		for _, kv := range strings.Split(line[m+len(", "):], ", ") {
+			k, v, _ := strings.Cut(kv, "=")
+			switch k {
+			case "start":
+				aq.Start = v
+			case "end":
+				aq.End = v
+			case "step":
+				aq.Step = v
+			}
+		}
+		aqs = append(aqs, aq)
+	}
+	return aqs, nil
+}
+
+// topQueriesResponse is the response returned by /api/v1/status/top_queries.
+type topQueriesResponse struct {
+	TopByCount []struct {
+		Tenant           string `json:"tenant"`
+		Query            string `json:"query"`
+		TimeRangeSeconds int64  `json:"timeRangeSeconds"`
+		Count            int    `json:"count"`
+	} `json:"topByCount"`
+	TopByAvgDuration []struct {
+		Tenant             string  `json:"tenant"`
+		Query              string  `json:"query"`
+		TimeRangeSeconds   int64   `json:"timeRangeSeconds"`
+		AvgDurationSeconds float64 `json:"avgDurationSeconds"`
+		Count              int     `json:"count"`
+	} `json:"topByAvgDuration"`
+	TopBySumDuration []struct {
+		Tenant             string  `json:"tenant"`
+		Query              string  `json:"query"`
+		TimeRangeSeconds   int64   `json:"timeRangeSeconds"`
+		SumDurationSeconds float64 `json:"sumDurationSeconds"`
+		Count              int     `json:"count"`
+	} `json:"topBySumDuration"`
+	TopTenantsBySumDuration []struct {
+		Tenant             string  `json:"tenant"`
+		SumDurationSeconds float64 `json:"sumDurationSeconds"`
+		Count              int     `json:"count"`
+	} `json:"topTenantsBySumDuration"`
+}
+
+func getTopQueries(c *cli.Context, cl *client) ([]byte, *topQueriesResponse, error) {
+	args := url.Values{}
+	if n := c.Int("top-n"); n > 0 {
+		args.Set("topN", strconv.Itoa(n))
+	}
+	if d := c.Duration("max-lifetime"); d > 0 {
+		args.Set("maxLifetime", strconv.FormatFloat(d.Seconds(), 'f', -1, 64))
+	}
+	data, err := cl.get("/api/v1/status/top_queries", args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp topQueriesResponse
+	if err := unmarshalResponse(data, &resp); err != nil {
+		return nil, nil, err
+	}
+	return data, &resp, nil
+}
+
+func topQueries(c *cli.Context, cl *client, o *output) error {
+	data, resp, err := getTopQueries(c, cl)
+	if err != nil {
+		return err
+	}
+	if o.isJSON() {
+		return o.writeJSON(data)
+	}
+	var rows [][]string
+	for _, q := range resp.TopByCount {
+		rows = append(rows, []string{"count", q.Tenant, strconv.Itoa(q.Count), "", strconv.FormatInt(q.TimeRangeSeconds, 10), q.Query})
+	}
+	for _, q := range resp.TopByAvgDuration {
+		rows = append(rows, []string{"avgDuration", q.Tenant, strconv.Itoa(q.Count), fmt.Sprintf("%.3fs", q.AvgDurationSeconds),
+			strconv.FormatInt(q.TimeRangeSeconds, 10), q.Query})
+	}
+	for _, q := range resp.TopBySumDuration {
+		rows = append(rows, []string{"sumDuration", q.Tenant, strconv.Itoa(q.Count), fmt.Sprintf("%.3fs", q.SumDurationSeconds),
+			strconv.FormatInt(q.TimeRangeSeconds, 10), q.Query})
+	}
+	return o.writeTable([]string{"TOP_BY", "TENANT", "COUNT", "DURATION", "TIME_RANGE_SECONDS", "QUERY"}, rows)
+}
+
+func tenantStats(c *cli.Context, cl *client, o *output) error {
+	_, resp, err := getTopQueries(c, cl)
+	if err != nil {
+		return err
+	}
+	if o.isJSON() {
+		data, err := json.Marshal(resp.TopTenantsBySumDuration)
+		if err != nil {
+			return fmt.Errorf("cannot marshal tenant stats: %w", err)
+		}
+		return o.writeJSON(data)
+	}
+	rows := make([][]string, 0, len(resp.TopTenantsBySumDuration))
+	for _, t := range resp.TopTenantsBySumDuration {
+		rows = append(rows, []string{t.Tenant, strconv.Itoa(t.Count), fmt.Sprintf("%.3fs", t.SumDurationSeconds)})
+	}
+	return o.writeTable([]string{"TENANT", "QUERIES", "SUM_DURATION"}, rows)
+}
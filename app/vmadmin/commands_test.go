@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseActiveQueries(t *testing.T) {
+	f := func(s string, resultExpected []activeQuery) {
+		t.Helper()
+		result, err := parseActiveQueries(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result\ngot\n%+v\nwant\n%+v", result, resultExpected)
+		}
+	}
+	f("", []activeQuery{})
+	f("\tduration: 1.234s, id=000000000000000A, remote_addr=\"1.2.3.4:5678\", query=\"sum(rate(foo{bar=\\\"a, b\\\"}[5m]))\", start=1000, end=2000, step=15000\n"+
+		"\tduration: 0.010s, id=000000000000000B, remote_addr=\"5.6.7.8:1234\", query=\"up\", start=0, end=1, step=1\n", []activeQuery{
+		{
+			Duration:   "1.234s",
+			ID:         "000000000000000A",
+			RemoteAddr: `"1.2.3.4:5678"`,
+			Query:      `sum(rate(foo{bar="a, b"}[5m]))`,
+			Start:      "1000",
+			End:        "2000",
+			Step:       "15000",
+		},
+		{
+			Duration:   "0.010s",
+			ID:         "000000000000000B",
+			RemoteAddr: `"5.6.7.8:1234"`,
+			Query:      "up",
+			Start:      "0",
+			End:        "1",
+			Step:       "1",
+		},
+	})
+
+	if _, err := parseActiveQueries("foobar\n"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid line")
+	}
+}
+
+func TestUnmarshalResponse(t *testing.T) {
+	var resp struct {
+		JobID uint64 `json:"job_id"`
+	}
+	if err := unmarshalResponse([]byte(`{"status":"ok","job_id":42}`), &resp); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resp.JobID != 42 {
+		t.Fatalf("unexpected job_id; got %d; want 42", resp.JobID)
+	}
+
+	f := func(data string) {
+		t.Helper()
+		if err := unmarshalResponse([]byte(data), nil); err == nil {
+			t.Fatalf("expecting non-nil error for %q", data)
+		}
+	}
+	f(`foobar`)
+	f(`{"status":"error","msg":"cannot find snapshot"}`)
+	f(`{"status":"error","errorType":"422","error":"cannot parse query"}`)
+}
+
+func TestOutput(t *testing.T) {
+	var bb bytes.Buffer
+	o, err := newOutput(&bb, outputTable)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := o.writeTable([]string{"MODE", "PENDING_ROWS"}, [][]string{{"read-write", "123"}, {"drain", "0"}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := "MODE        PENDING_ROWS\n" +
+		"read-write  123\n" +
+		"drain       0\n"
+	if bb.String() != resultExpected {
+		t.Fatalf("unexpected table output\ngot\n%q\nwant\n%q", bb.String(), resultExpected)
+	}
+
+	bb.Reset()
+	o, err = newOutput(&bb, outputJSON)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := o.writeJSON([]byte(`{"status":"ok","snapshots":[]}`)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected = "{\n  \"status\": \"ok\",\n  \"snapshots\": []\n}\n"
+	if bb.String() != resultExpected {
+		t.Fatalf("unexpected JSON output\ngot\n%q\nwant\n%q", bb.String(), resultExpected)
+	}
+
+	if _, err := newOutput(&bb, "yaml"); err == nil {
+		t.Fatalf("expecting non-nil error for unsupported output format")
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("authKey") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("invalid authKey"))
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		if !ok || user != "foo" || password != "bar" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status":"ok","mode":` + `"` + r.FormValue("set") + `"}`))
+	}))
+	defer srv.Close()
+
+	cl := &client{
+		addr:     srv.URL,
+		user:     "foo",
+		password: "bar",
+		authKey:  "secret",
+		hc: &http.Client{
+			Timeout: time.Second,
+		},
+	}
+	data, err := cl.get("/internal/mode", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != `{"status":"ok","mode":""}` {
+		t.Fatalf("unexpected response: %q", data)
+	}
+
+	// POST args must be sent in request body
+	args := url.Values{
+		"set": {"drain"},
+	}
+	data, err = cl.post("/internal/mode", args)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != `{"status":"ok","mode":"drain"}` {
+		t.Fatalf("unexpected response: %q", data)
+	}
+
+	// non-2xx responses must result in error
+	cl.authKey = "invalid"
+	if _, err := cl.get("/internal/mode", nil); err == nil {
+		t.Fatalf("expecting non-nil error for invalid authKey")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	globalAddr               = "addr"
+	globalUser               = "user"
+	globalPassword           = "password"
+	globalBearerToken        = "bearer-token"
+	globalAuthKey            = "auth-key"
+	globalOutput             = "output"
+	globalTimeout            = "timeout"
+	globalInsecureSkipVerify = "insecure-skip-verify"
+)
+
+var globalFlags = []cli.Flag{
+	&cli.StringFlag{
+		Name:    globalAddr,
+		Value:   "http://localhost:8428",
+		Usage:   "VictoriaMetrics address to send admin requests to",
+		EnvVars: []string{"VM_ADDR"},
+	},
+	&cli.StringFlag{
+		Name:    globalUser,
+		Usage:   "VictoriaMetrics username for basic auth",
+		EnvVars: []string{"VM_USERNAME"},
+	},
+	&cli.StringFlag{
+		Name:    globalPassword,
+		Usage:   "VictoriaMetrics password for basic auth",
+		EnvVars: []string{"VM_PASSWORD"},
+	},
+	&cli.StringFlag{
+		Name:    globalBearerToken,
+		Usage:   "Optional bearer token to send in Authorization header",
+		EnvVars: []string{"VM_BEARER_TOKEN"},
+	},
+	&cli.StringFlag{
+		Name: globalAuthKey,
+		Usage: "Optional authKey query arg to send with every request. \n" +
+			"It must match -snapshotAuthKey, -forceMergeAuthKey, -deleteAuthKey or -maintenanceAuthKey at VictoriaMetrics depending on the command.",
+		EnvVars: []string{"VM_AUTH_KEY"},
+	},
+	&cli.StringFlag{
+		Name:  globalOutput,
+		Value: "table",
+		Usage: "Output format. Supported values: table, json",
+	},
+	&cli.DurationFlag{
+		Name:  globalTimeout,
+		Value: time.Minute,
+		Usage: "Timeout for every request to VictoriaMetrics",
+	},
+	&cli.BoolFlag{
+		Name:  globalInsecureSkipVerify,
+		Usage: "Whether to skip TLS certificate verification when connecting to --addr",
+	},
+}
+
+func mergeFlags(flags ...[]cli.Flag) []cli.Flag {
+	var result []cli.Flag
+	for _, f := range flags {
+		result = append(result, f...)
+	}
+	return result
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "vmadmin",
+		Usage: "VictoriaMetrics command-line tool for admin APIs",
+		// Disable `-version` flag to avoid conflict with lib/buildinfo flags
+		// see https://github.com/urfave/cli/issues/1560
+		Version:     buildinfo.Version,
+		HideVersion: true,
+		Commands: []*cli.Command{
+			{
+				Name:  "snapshot",
+				Usage: "Manage snapshots. See https://docs.victoriametrics.com/#how-to-work-with-snapshots",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create",
+						Usage: "Create new snapshot",
+						Flags: mergeFlags(globalFlags, []cli.Flag{
+							&cli.StringFlag{
+								Name:  "start",
+								Usage: "Optional start of the time range for partial snapshot in unix seconds or RFC3339 format",
+							},
+							&cli.StringFlag{
+								Name:  "end",
+								Usage: "Optional end of the time range for partial snapshot in unix seconds or RFC3339 format",
+							},
+							&cli.StringFlag{
+								Name:  "lookback",
+								Usage: "Optional lookback duration for partial snapshot such as 7d. It cannot be set together with --start",
+							},
+						}),
+						Action: withClient(snapshotCreate),
+					},
+					{
+						Name:   "list",
+						Usage:  "List existing snapshots",
+						Flags:  globalFlags,
+						Action: withClient(snapshotList),
+					},
+					{
+						Name:      "delete",
+						Usage:     "Delete the given snapshot",
+						ArgsUsage: "<name>",
+						Flags:     globalFlags,
+						Action:    withClient(snapshotDelete),
+					},
+					{
+						Name:   "delete-all",
+						Usage:  "Delete all the snapshots",
+						Flags:  globalFlags,
+						Action: withClient(snapshotDeleteAll),
+					},
+				},
+			},
+			{
+				Name:  "force-merge",
+				Usage: "Start forced merge. See https://docs.victoriametrics.com/#forced-merge",
+				Flags: mergeFlags(globalFlags, []cli.Flag{
+					&cli.StringFlag{
+						Name:  "partition-prefix",
+						Usage: "Optional prefix of partitions to merge such as 2023_",
+					},
+					&cli.StringFlag{
+						Name:  "min-partition",
+						Usage: "Optional minimum partition to merge such as 2023_01",
+					},
+					&cli.StringFlag{
+						Name:  "max-partition",
+						Usage: "Optional maximum partition to merge such as 2023_06",
+					},
+				}),
+				Action: withClient(forceMerge),
+				Subcommands: []*cli.Command{
+					{
+						Name:      "status",
+						Usage:     "Show the status for the given forced merge job or for all the recent jobs",
+						ArgsUsage: "[job_id]",
+						Flags:     globalFlags,
+						Action:    withClient(forceMergeStatus),
+					},
+				},
+			},
+			{
+				Name:   "tsdb-status",
+				Usage:  "Show cardinality stats. See https://docs.victoriametrics.com/#tsdb-stats",
+				Flags:  mergeFlags(globalFlags, tsdbStatusFlags),
+				Action: withClient(tsdbStatus),
+			},
+			{
+				Name:  "delete-series",
+				Usage: "Delete time series matching the given series selectors. See https://docs.victoriametrics.com/#how-to-delete-time-series",
+				Flags: mergeFlags(globalFlags, []cli.Flag{
+					&cli.StringSliceFlag{
+						Name:     "match",
+						Usage:    "Series selector for time series to delete. May be set multiple times",
+						Required: true,
+					},
+				}),
+				Action: withClient(deleteSeries),
+			},
+			{
+				Name:   "active-queries",
+				Usage:  "Show currently executed queries",
+				Flags:  globalFlags,
+				Action: withClient(activeQueries),
+			},
+			{
+				Name:   "top-queries",
+				Usage:  "Show the most frequently executed and the slowest queries. See https://docs.victoriametrics.com/#prometheus-querying-api-enhancements",
+				Flags:  mergeFlags(globalFlags, topQueriesFlags),
+				Action: withClient(topQueries),
+			},
+			{
+				Name:   "tenant-stats",
+				Usage:  "Show query stats per tenant ordered by the summary duration of their queries",
+				Flags:  mergeFlags(globalFlags, topQueriesFlags),
+				Action: withClient(tenantStats),
+			},
+			{
+				Name:      "mode",
+				Usage:     "Show or change storage mode. See https://docs.victoriametrics.com/#maintenance-modes",
+				ArgsUsage: "[read-write|read-only|drain]",
+				Flags:     globalFlags,
+				Action:    withClient(storageMode),
+			},
+			{
+				Name:   "drain",
+				Usage:  "Switch storage to drain mode before maintenance. Run `vmadmin mode read-write` for returning it back",
+				Flags:  globalFlags,
+				Action: withClient(drain),
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+var tsdbStatusFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "top-n",
+		Value: 10,
+		Usage: "The number of top entries to return per each stat",
+	},
+	&cli.StringFlag{
+		Name:  "date",
+		Usage: "Optional date in YYYY-MM-DD format to return stats for. Stats for today are returned by default",
+	},
+	&cli.StringFlag{
+		Name:  "focus-label",
+		Usage: "Optional label name to return seriesCountByFocusLabelValue stats for",
+	},
+	&cli.StringSliceFlag{
+		Name:  "match",
+		Usage: "Optional series selector to limit the stats to. May be set multiple times",
+	},
+}
+
+var topQueriesFlags = []cli.Flag{
+	&cli.IntFlag{
+		Name:  "top-n",
+		Value: 20,
+		Usage: "The number of top entries to return",
+	},
+	&cli.DurationFlag{
+		Name:  "max-lifetime",
+		Usage: "Optional max lifetime of queries to take into account. VictoriaMetrics uses 10m by default",
+	},
+}
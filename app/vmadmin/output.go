@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// output writes command results in the format set via --output flag.
+type output struct {
+	w      io.Writer
+	format string
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case outputTable, outputJSON:
+		return &output{
+			w:      w,
+			format: format,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported --%s=%q; supported values: %s, %s", globalOutput, format, outputTable, outputJSON)
+	}
+}
+
+// isJSON returns true if the results must be written as JSON.
+func (o *output) isJSON() bool {
+	return o.format == outputJSON
+}
+
+// writeJSON writes indented data to o.
+func (o *output) writeJSON(data []byte) error {
+	var bb bytes.Buffer
+	if err := json.Indent(&bb, data, "", "  "); err != nil {
+		return fmt.Errorf("cannot parse JSON response %q: %w", data, err)
+	}
+	bb.WriteByte('\n')
+	_, err := o.w.Write(bb.Bytes())
+	return err
+}
+
+// writeTable writes rows with the given header as aligned table to o.
+func (o *output) writeTable(header []string, rows [][]string) error {
+	tw := tabwriter.NewWriter(o.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,184 @@
+package convertconfig
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Convert converts Telegraf config or Datadog check config at path into vmagent config for -promscrape.config.
+//
+// Files with .yaml or .yml extension are treated as Datadog check configs, while the rest of files are treated as Telegraf configs.
+// Inputs, which cannot be converted into scrape configs, are listed in comments at the top of the returned config
+// together with the vmagent command-line flags needed for accepting the data from these inputs.
+//
+// See https://docs.victoriametrics.com/vmagent.html#converting-telegraf-and-datadog-configs
+func Convert(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	var cfg *config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		cfg, err = convertDatadogCheck(data)
+	default:
+		cfg, err = convertTelegrafConfig(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q: %w", path, err)
+	}
+	return cfg.marshal(path)
+}
+
+type config struct {
+	Global        *globalConfig   `yaml:"global,omitempty"`
+	ScrapeConfigs []*scrapeConfig `yaml:"scrape_configs,omitempty"`
+
+	// notes contain messages about inputs, which couldn't be converted into scrape configs.
+	notes []string
+}
+
+type globalConfig struct {
+	ScrapeInterval string            `yaml:"scrape_interval,omitempty"`
+	ExternalLabels map[string]string `yaml:"external_labels,omitempty"`
+}
+
+type scrapeConfig struct {
+	JobName              string               `yaml:"job_name"`
+	ScrapeInterval       string               `yaml:"scrape_interval,omitempty"`
+	ScrapeTimeout        string               `yaml:"scrape_timeout,omitempty"`
+	BasicAuth            *basicAuthConfig     `yaml:"basic_auth,omitempty"`
+	BearerToken          string               `yaml:"bearer_token,omitempty"`
+	BearerTokenFile      string               `yaml:"bearer_token_file,omitempty"`
+	TLSConfig            *tlsConfig           `yaml:"tls_config,omitempty"`
+	Headers              []string             `yaml:"headers,omitempty"`
+	StaticConfigs        []staticConfig       `yaml:"static_configs,omitempty"`
+	KubernetesSDConfigs  []kubernetesSDConfig `yaml:"kubernetes_sd_configs,omitempty"`
+	RelabelConfigs       []relabelConfig      `yaml:"relabel_configs,omitempty"`
+	MetricRelabelConfigs []relabelConfig      `yaml:"metric_relabel_configs,omitempty"`
+}
+
+type basicAuthConfig struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+}
+
+type tlsConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+}
+
+type staticConfig struct {
+	Targets []string          `yaml:"targets"`
+	Labels  map[string]string `yaml:"labels,omitempty"`
+}
+
+type kubernetesSDConfig struct {
+	Role       string               `yaml:"role"`
+	Namespaces *kubernetesNamespace `yaml:"namespaces,omitempty"`
+	Selectors  []kubernetesSelector `yaml:"selectors,omitempty"`
+}
+
+type kubernetesNamespace struct {
+	Names []string `yaml:"names"`
+}
+
+type kubernetesSelector struct {
+	Role  string `yaml:"role"`
+	Label string `yaml:"label,omitempty"`
+	Field string `yaml:"field,omitempty"`
+}
+
+type relabelConfig struct {
+	Action       string   `yaml:"action,omitempty"`
+	SourceLabels []string `yaml:"source_labels,flow,omitempty"`
+	Regex        string   `yaml:"regex,omitempty"`
+	TargetLabel  string   `yaml:"target_label,omitempty"`
+	Replacement  string   `yaml:"replacement,omitempty"`
+}
+
+func (cfg *config) addNote(format string, args ...interface{}) {
+	cfg.notes = append(cfg.notes, fmt.Sprintf(format, args...))
+}
+
+func (cfg *config) marshal(srcPath string) ([]byte, error) {
+	var bb bytes.Buffer
+	fmt.Fprintf(&bb, "# This config has been generated by `vmagent -convertConfig=%s`.\n", srcPath)
+	fmt.Fprintf(&bb, "# Pass it to vmagent via -promscrape.config command-line flag.\n")
+	if len(cfg.notes) > 0 {
+		fmt.Fprintf(&bb, "#\n")
+		fmt.Fprintf(&bb, "# The following inputs need manual attention:\n")
+		for _, note := range cfg.notes {
+			fmt.Fprintf(&bb, "# - %s\n", note)
+		}
+	}
+	if len(cfg.ScrapeConfigs) == 0 {
+		fmt.Fprintf(&bb, "#\n")
+		fmt.Fprintf(&bb, "# There are no inputs, which can be converted into scrape configs.\n")
+		return bb.Bytes(), nil
+	}
+	bb.WriteString("\n")
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal config: %w", err)
+	}
+	bb.Write(data)
+	return bb.Bytes(), nil
+}
+
+// newJobName returns unique job name with the given prefix across cfg.ScrapeConfigs.
+func (cfg *config) newJobName(prefix string) string {
+	jobName := prefix
+	for n := 2; cfg.hasJobName(jobName); n++ {
+		jobName = fmt.Sprintf("%s_%d", prefix, n)
+	}
+	return jobName
+}
+
+func (cfg *config) hasJobName(jobName string) bool {
+	for _, sc := range cfg.ScrapeConfigs {
+		if sc.JobName == jobName {
+			return true
+		}
+	}
+	return false
+}
+
+// globsToRegex converts Telegraf globs such as `http_*` into regex matching any of the globs.
+func globsToRegex(globs []string) string {
+	a := make([]string, 0, len(globs))
+	for _, glob := range globs {
+		var b strings.Builder
+		for _, c := range glob {
+			switch c {
+			case '*':
+				b.WriteString(".*")
+			case '?':
+				b.WriteString(".")
+			default:
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		}
+		a = append(a, b.String())
+	}
+	return strings.Join(a, "|")
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,165 @@
+package convertconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertSuccess(t *testing.T) {
+	f := func(filename, data, resultExpected string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), filename)
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("cannot write test config: %s", err)
+		}
+		result, err := Convert(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		header := "# This config has been generated by `vmagent -convertConfig=" + path + "`.\n" +
+			"# Pass it to vmagent via -promscrape.config command-line flag.\n"
+		if string(result) != header+resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, header+resultExpected)
+		}
+	}
+
+	// Telegraf config
+	f("telegraf.conf", `
+[global_tags]
+  dc = "us-east-1"
+
+[agent]
+  interval = "10s"
+
+[[inputs.prometheus]]
+  urls = ["http://localhost:9100/metrics"]
+  interval = "30s"
+  response_timeout = "5s"
+  bearer_token_string = "secret"
+  insecure_skip_verify = true
+  namepass = ["node_cpu_*"]
+  fieldpass = ["x"]
+  [inputs.prometheus.tags]
+    team = "infra"
+
+[[inputs.cpu]]
+
+[[inputs.socket_listener]]
+  service_address = "tcp://:2003"
+  data_format = "graphite"
+`, `#
+# The following inputs need manual attention:
+# - inputs.cpu: the input cannot be converted; replace it with the corresponding Prometheus exporter and add a scrape config for it; see https://prometheus.io/docs/instrumenting/exporters/
+# - inputs.prometheus: "fieldpass" option is ignored for job_name "telegraf_prometheus"
+# - inputs.socket_listener: pass `+"`-graphiteListenAddr=:2003`"+` command-line flag to vmagent
+
+global:
+  scrape_interval: 10s
+  external_labels:
+    dc: us-east-1
+scrape_configs:
+- job_name: telegraf_prometheus
+  scrape_interval: 30s
+  scrape_timeout: 5s
+  bearer_token: secret
+  tls_config:
+    insecure_skip_verify: true
+  static_configs:
+  - targets:
+    - http://localhost:9100/metrics
+    labels:
+      team: infra
+  metric_relabel_configs:
+  - action: keep_metrics
+    regex: node_cpu_.*
+`)
+
+	// Telegraf config without convertible inputs
+	f("telegraf.conf", `
+[[inputs.mem]]
+
+[[inputs.statsd]]
+  service_address = ":9125"
+`, `#
+# The following inputs need manual attention:
+# - inputs.mem: the input cannot be converted; replace it with the corresponding Prometheus exporter and add a scrape config for it; see https://prometheus.io/docs/instrumenting/exporters/
+# - inputs.statsd: pass `+"`-statsdListenAddr=:9125`"+` command-line flag to vmagent; see https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-clients
+#
+# There are no inputs, which can be converted into scrape configs.
+`)
+
+	// Datadog openmetrics check
+	f("conf.yaml", `
+init_config:
+instances:
+  - openmetrics_endpoint: http://localhost:8080/metrics
+    namespace: myapp
+    min_collection_interval: 30
+    tags: ["env:prod"]
+    metrics:
+      - http_requests_.*
+      - process_cpu_seconds_total: cpu_seconds
+  - prometheus_url: http://localhost:8081/metrics
+    namespace: myapp
+    metrics: [".*"]
+`, `
+scrape_configs:
+- job_name: myapp
+  scrape_interval: 30s
+  static_configs:
+  - targets:
+    - http://localhost:8080/metrics
+    labels:
+      env: prod
+  metric_relabel_configs:
+  - action: keep_metrics
+    regex: http_requests_.*|process_cpu_seconds_total
+  - source_labels: [__name__]
+    regex: process_cpu_seconds_total
+    target_label: __name__
+    replacement: cpu_seconds
+  - source_labels: [__name__]
+    regex: (.+)
+    target_label: __name__
+    replacement: myapp_$1
+- job_name: myapp_2
+  static_configs:
+  - targets:
+    - http://localhost:8081/metrics
+  metric_relabel_configs:
+  - source_labels: [__name__]
+    regex: (.+)
+    target_label: __name__
+    replacement: myapp_$1
+`)
+}
+
+func TestConvertFailure(t *testing.T) {
+	f := func(filename, data string) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), filename)
+		if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("cannot write test config: %s", err)
+		}
+		if _, err := Convert(path); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Invalid TOML
+	f("telegraf.conf", `[[inputs.prometheus]`)
+
+	// Invalid type for input
+	f("telegraf.conf", `inputs = 1`)
+
+	// Missing instances in Datadog check
+	f("conf.yaml", `init_config:`)
+
+	// Unsupported Datadog check
+	f("conf.yaml", `
+instances:
+  - host: localhost
+    port: 5432
+`)
+}
@@ -0,0 +1,171 @@
+package convertconfig
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// datadogCheck is a Datadog Agent check config from conf.d directory.
+//
+// Only openmetrics and prometheus checks can be converted into scrape configs.
+// See https://docs.datadoghq.com/integrations/openmetrics/
+type datadogCheck struct {
+	InitConfig interface{}       `yaml:"init_config"`
+	Instances  []datadogInstance `yaml:"instances"`
+}
+
+type datadogInstance struct {
+	OpenMetricsEndpoint   string            `yaml:"openmetrics_endpoint"`
+	PrometheusURL         string            `yaml:"prometheus_url"`
+	Namespace             string            `yaml:"namespace"`
+	Metrics               []interface{}     `yaml:"metrics"`
+	ExcludeMetrics        []string          `yaml:"exclude_metrics"`
+	Tags                  []string          `yaml:"tags"`
+	MinCollectionInterval float64           `yaml:"min_collection_interval"`
+	Timeout               float64           `yaml:"timeout"`
+	Username              string            `yaml:"username"`
+	Password              string            `yaml:"password"`
+	Headers               map[string]string `yaml:"headers"`
+	TLSVerify             *bool             `yaml:"tls_verify"`
+	TLSCACert             string            `yaml:"tls_ca_cert"`
+	TLSCert               string            `yaml:"tls_cert"`
+	TLSPrivateKey         string            `yaml:"tls_private_key"`
+}
+
+// convertDatadogCheck converts Datadog openmetrics or prometheus check config into vmagent config.
+func convertDatadogCheck(data []byte) (*config, error) {
+	var dc datadogCheck
+	if err := yaml.Unmarshal(data, &dc); err != nil {
+		return nil, fmt.Errorf("cannot parse Datadog check config: %w", err)
+	}
+	if len(dc.Instances) == 0 {
+		return nil, fmt.Errorf("missing `instances` section in Datadog check config")
+	}
+	cfg := &config{}
+	for i := range dc.Instances {
+		if err := convertDatadogInstance(cfg, &dc.Instances[i]); err != nil {
+			return nil, fmt.Errorf("cannot convert instance #%d: %w", i+1, err)
+		}
+	}
+	return cfg, nil
+}
+
+func convertDatadogInstance(cfg *config, di *datadogInstance) error {
+	target := di.OpenMetricsEndpoint
+	if target == "" {
+		target = di.PrometheusURL
+	}
+	if target == "" {
+		return fmt.Errorf("only openmetrics and prometheus checks are supported; " +
+			"they must contain either `openmetrics_endpoint` or `prometheus_url`")
+	}
+	jobName := di.Namespace
+	if jobName == "" {
+		jobName = "datadog_openmetrics"
+	}
+	sc := &scrapeConfig{
+		JobName: cfg.newJobName(jobName),
+	}
+	if di.MinCollectionInterval > 0 {
+		sc.ScrapeInterval = fmt.Sprintf("%gs", di.MinCollectionInterval)
+	}
+	if di.Timeout > 0 {
+		sc.ScrapeTimeout = fmt.Sprintf("%gs", di.Timeout)
+	}
+	labels := make(map[string]string)
+	for _, tag := range di.Tags {
+		n := strings.IndexByte(tag, ':')
+		if n < 0 {
+			cfg.addNote("job_name %q: tag %q without value is ignored", sc.JobName, tag)
+			continue
+		}
+		labels[tag[:n]] = tag[n+1:]
+	}
+	if len(labels) == 0 {
+		labels = nil
+	}
+	sc.StaticConfigs = []staticConfig{{
+		Targets: []string{target},
+		Labels:  labels,
+	}}
+	if di.Username != "" {
+		sc.BasicAuth = &basicAuthConfig{
+			Username: di.Username,
+			Password: di.Password,
+		}
+	}
+	tc := tlsConfig{
+		CAFile:   di.TLSCACert,
+		CertFile: di.TLSCert,
+		KeyFile:  di.TLSPrivateKey,
+	}
+	if di.TLSVerify != nil && !*di.TLSVerify {
+		tc.InsecureSkipVerify = true
+	}
+	if tc != (tlsConfig{}) {
+		sc.TLSConfig = &tc
+	}
+	for _, name := range sortedStringKeys(di.Headers) {
+		sc.Headers = append(sc.Headers, name+": "+di.Headers[name])
+	}
+
+	// Datadog matches metric names against regular expressions from `metrics` list
+	// and optionally renames them according to `metric_name: new_name` entries.
+	var keepRegexs []string
+	var renames []relabelConfig
+	for _, m := range di.Metrics {
+		switch t := m.(type) {
+		case string:
+			keepRegexs = append(keepRegexs, t)
+		case map[interface{}]interface{}:
+			entries := make(map[string]interface{}, len(t))
+			for k, v := range t {
+				entries[fmt.Sprintf("%v", k)] = v
+			}
+			for _, name := range sortedKeys(entries) {
+				v := entries[name]
+				keepRegexs = append(keepRegexs, regexp.QuoteMeta(name))
+				newName, ok := v.(string)
+				if !ok {
+					cfg.addNote("job_name %q: options for metric %q are ignored", sc.JobName, name)
+					continue
+				}
+				renames = append(renames, relabelConfig{
+					SourceLabels: []string{"__name__"},
+					Regex:        regexp.QuoteMeta(name),
+					TargetLabel:  "__name__",
+					Replacement:  newName,
+				})
+			}
+		default:
+			return fmt.Errorf("unsupported entry in `metrics` list: %v", m)
+		}
+	}
+	if len(keepRegexs) > 0 && !(len(keepRegexs) == 1 && (keepRegexs[0] == ".*" || keepRegexs[0] == ".+")) {
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			Action: "keep_metrics",
+			Regex:  strings.Join(keepRegexs, "|"),
+		})
+	}
+	if len(di.ExcludeMetrics) > 0 {
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			Action: "drop_metrics",
+			Regex:  strings.Join(di.ExcludeMetrics, "|"),
+		})
+	}
+	sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, renames...)
+	if di.Namespace != "" {
+		// Datadog prefixes metric names with `<namespace>.`, while Prometheus naming uses `_` as a delimiter.
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			SourceLabels: []string{"__name__"},
+			Regex:        "(.+)",
+			TargetLabel:  "__name__",
+			Replacement:  di.Namespace + "_$1",
+		})
+	}
+	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, sc)
+	return nil
+}
@@ -0,0 +1,279 @@
+package convertconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// convertTelegrafConfig converts Telegraf config into vmagent config.
+//
+// See https://github.com/influxdata/telegraf/blob/master/docs/CONFIGURATION.md
+func convertTelegrafConfig(data string) (*config, error) {
+	root, err := parseTOML(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	var global globalConfig
+	if agent, ok := root["agent"].(tomlTable); ok {
+		global.ScrapeInterval = getString(agent, "interval")
+	}
+	if tags, ok := root["global_tags"].(tomlTable); ok {
+		global.ExternalLabels = getStringMap(tags)
+	}
+	if global.ScrapeInterval != "" || len(global.ExternalLabels) > 0 {
+		cfg.Global = &global
+	}
+
+	inputs, ok := root["inputs"].(tomlTable)
+	if !ok && root["inputs"] != nil {
+		return nil, fmt.Errorf("unexpected type for inputs: %T; want table", root["inputs"])
+	}
+	for _, name := range sortedKeys(inputs) {
+		var plugins []tomlTable
+		switch t := inputs[name].(type) {
+		case []tomlTable:
+			plugins = t
+		case tomlTable:
+			plugins = []tomlTable{t}
+		default:
+			return nil, fmt.Errorf("unexpected type for inputs.%s: %T; want table", name, t)
+		}
+		for _, plugin := range plugins {
+			if err := convertTelegrafInput(cfg, name, plugin); err != nil {
+				return nil, fmt.Errorf("cannot convert inputs.%s: %w", name, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+func convertTelegrafInput(cfg *config, name string, t tomlTable) error {
+	switch name {
+	case "prometheus":
+		return convertTelegrafPrometheus(cfg, t)
+	case "influxdb_listener", "influxdb_v2_listener":
+		cfg.addNote("inputs.%s: send InfluxDB line protocol data to `http://<vmagent>:8429/write` "+
+			"instead of %q; see https://docs.victoriametrics.com/vmagent.html#features", name, getString(t, "service_address"))
+	case "http_listener_v2":
+		if format := getString(t, "data_format"); format != "influx" {
+			cfg.addNote("inputs.%s: data_format=%q isn't supported by vmagent", name, format)
+			return nil
+		}
+		cfg.addNote("inputs.%s: send InfluxDB line protocol data to `http://<vmagent>:8429/write` "+
+			"instead of %q; see https://docs.victoriametrics.com/vmagent.html#features", name, getString(t, "service_address"))
+	case "socket_listener":
+		addr := getString(t, "service_address")
+		if n := strings.Index(addr, "://"); n >= 0 {
+			addr = addr[n+len("://"):]
+		}
+		switch format := getString(t, "data_format"); format {
+		case "graphite":
+			cfg.addNote("inputs.%s: pass `-graphiteListenAddr=%s` command-line flag to vmagent", name, addr)
+		case "influx", "":
+			cfg.addNote("inputs.%s: pass `-influxListenAddr=%s` command-line flag to vmagent", name, addr)
+		default:
+			cfg.addNote("inputs.%s: data_format=%q isn't supported by vmagent", name, format)
+		}
+	case "statsd":
+		addr := getString(t, "service_address")
+		if addr == "" {
+			addr = ":8125"
+		}
+		cfg.addNote("inputs.%s: pass `-statsdListenAddr=%s` command-line flag to vmagent; "+
+			"see https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-clients", name, addr)
+	default:
+		cfg.addNote("inputs.%s: the input cannot be converted; replace it with the corresponding Prometheus exporter "+
+			"and add a scrape config for it; see https://prometheus.io/docs/instrumenting/exporters/", name)
+	}
+	return nil
+}
+
+// convertTelegrafPrometheus converts Telegraf prometheus input into scrape config.
+//
+// See https://github.com/influxdata/telegraf/tree/master/plugins/inputs/prometheus
+func convertTelegrafPrometheus(cfg *config, t tomlTable) error {
+	sc := &scrapeConfig{
+		JobName:        cfg.newJobName("telegraf_prometheus"),
+		ScrapeInterval: getString(t, "interval"),
+		ScrapeTimeout:  getString(t, "response_timeout"),
+	}
+	if s := getString(t, "timeout"); s != "" && sc.ScrapeTimeout == "" {
+		sc.ScrapeTimeout = s
+	}
+	var labels map[string]string
+	if tags, ok := t["tags"].(tomlTable); ok {
+		labels = getStringMap(tags)
+	}
+	if urls := getStrings(t, "urls"); len(urls) > 0 {
+		sc.StaticConfigs = append(sc.StaticConfigs, staticConfig{
+			Targets: urls,
+			Labels:  labels,
+		})
+	}
+	if getBool(t, "monitor_kubernetes_pods") {
+		sdc := kubernetesSDConfig{
+			Role: "pod",
+		}
+		if ns := getString(t, "monitor_kubernetes_pods_namespace"); ns != "" {
+			sdc.Namespaces = &kubernetesNamespace{
+				Names: []string{ns},
+			}
+		}
+		labelSelector := getString(t, "kubernetes_label_selector")
+		fieldSelector := getString(t, "kubernetes_field_selector")
+		if labelSelector != "" || fieldSelector != "" {
+			sdc.Selectors = append(sdc.Selectors, kubernetesSelector{
+				Role:  "pod",
+				Label: labelSelector,
+				Field: fieldSelector,
+			})
+		}
+		sc.KubernetesSDConfigs = append(sc.KubernetesSDConfigs, sdc)
+		sc.RelabelConfigs = append(sc.RelabelConfigs, kubernetesPodsRelabelConfigs...)
+		for _, name := range sortedStringKeys(labels) {
+			sc.RelabelConfigs = append(sc.RelabelConfigs, relabelConfig{
+				TargetLabel: name,
+				Replacement: labels[name],
+			})
+		}
+	}
+	if len(sc.StaticConfigs) == 0 && len(sc.KubernetesSDConfigs) == 0 {
+		cfg.addNote("inputs.prometheus: neither urls nor monitor_kubernetes_pods are set, so the input is skipped")
+		return nil
+	}
+
+	if token := getString(t, "bearer_token_string"); token != "" {
+		sc.BearerToken = token
+	}
+	sc.BearerTokenFile = getString(t, "bearer_token")
+	if username := getString(t, "username"); username != "" {
+		sc.BasicAuth = &basicAuthConfig{
+			Username: username,
+			Password: getString(t, "password"),
+		}
+	}
+	tc := tlsConfig{
+		CAFile:             getString(t, "tls_ca"),
+		CertFile:           getString(t, "tls_cert"),
+		KeyFile:            getString(t, "tls_key"),
+		ServerName:         getString(t, "tls_server_name"),
+		InsecureSkipVerify: getBool(t, "insecure_skip_verify"),
+	}
+	if tc != (tlsConfig{}) {
+		sc.TLSConfig = &tc
+	}
+	if headers, ok := t["http_headers"].(tomlTable); ok {
+		m := getStringMap(headers)
+		for _, name := range sortedStringKeys(m) {
+			sc.Headers = append(sc.Headers, name+": "+m[name])
+		}
+	}
+	if globs := getStrings(t, "namepass"); len(globs) > 0 {
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			Action: "keep_metrics",
+			Regex:  globsToRegex(globs),
+		})
+	}
+	if globs := getStrings(t, "namedrop"); len(globs) > 0 {
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			Action: "drop_metrics",
+			Regex:  globsToRegex(globs),
+		})
+	}
+	if prefix := getString(t, "name_prefix"); prefix != "" {
+		sc.MetricRelabelConfigs = append(sc.MetricRelabelConfigs, relabelConfig{
+			SourceLabels: []string{"__name__"},
+			Regex:        "(.+)",
+			TargetLabel:  "__name__",
+			Replacement:  prefix + "$1",
+		})
+	}
+	for _, key := range []string{"fieldpass", "fielddrop", "tagpass", "tagdrop", "url_tag"} {
+		if _, ok := t[key]; ok {
+			cfg.addNote("inputs.prometheus: %q option is ignored for job_name %q", key, sc.JobName)
+		}
+	}
+	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, sc)
+	return nil
+}
+
+// kubernetesPodsRelabelConfigs select pods with `prometheus.io/scrape: "true"` annotation in the same way as Telegraf does.
+var kubernetesPodsRelabelConfigs = []relabelConfig{
+	{
+		Action:       "keep",
+		SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_scrape"},
+		Regex:        "true",
+	},
+	{
+		SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_path"},
+		Regex:        "(.+)",
+		TargetLabel:  "__metrics_path__",
+	},
+	{
+		SourceLabels: []string{"__meta_kubernetes_pod_annotation_prometheus_io_scheme"},
+		Regex:        "(https?)",
+		TargetLabel:  "__scheme__",
+	},
+	{
+		SourceLabels: []string{"__address__", "__meta_kubernetes_pod_annotation_prometheus_io_port"},
+		Regex:        `([^:]+)(?::\d+)?;(\d+)`,
+		TargetLabel:  "__address__",
+		Replacement:  "$1:$2",
+	},
+	{
+		SourceLabels: []string{"__meta_kubernetes_namespace"},
+		TargetLabel:  "namespace",
+	},
+	{
+		SourceLabels: []string{"__meta_kubernetes_pod_name"},
+		TargetLabel:  "pod_name",
+	},
+}
+
+func getString(t tomlTable, key string) string {
+	switch v := t[key].(type) {
+	case string:
+		return v
+	case int64, float64, bool:
+		return fmt.Sprintf("%v", v)
+	default:
+		return ""
+	}
+}
+
+func getBool(t tomlTable, key string) bool {
+	v, _ := t[key].(bool)
+	return v
+}
+
+func getStrings(t tomlTable, key string) []string {
+	a, _ := t[key].([]interface{})
+	var ss []string
+	for _, v := range a {
+		if s, ok := v.(string); ok {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+func getStringMap(t tomlTable) map[string]string {
+	m := make(map[string]string, len(t))
+	for k := range t {
+		if s := getString(t, k); s != "" {
+			m[k] = s
+		}
+	}
+	return m
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
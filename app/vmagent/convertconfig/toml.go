@@ -0,0 +1,392 @@
+package convertconfig
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tomlTable is a table parsed from TOML.
+//
+// Values may have the following types: string, int64, float64, bool, []interface{}, tomlTable and []tomlTable.
+type tomlTable map[string]interface{}
+
+// parseTOML parses TOML data.
+//
+// It supports the subset of TOML used in Telegraf configs: tables, arrays of tables, dotted keys,
+// strings, numbers, booleans, arrays and inline tables. Dates are returned as strings.
+func parseTOML(data string) (tomlTable, error) {
+	p := &tomlParser{
+		s:    data,
+		line: 1,
+	}
+	root := tomlTable{}
+	current := root
+	for {
+		p.skipSpacesAndComments(true)
+		if p.pos >= len(p.s) {
+			return root, nil
+		}
+		var err error
+		if p.s[p.pos] == '[' {
+			current, err = p.parseTableHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", p.line, err)
+		}
+		p.skipSpacesAndComments(false)
+		if p.pos < len(p.s) && p.s[p.pos] != '\n' && p.s[p.pos] != '\r' {
+			return nil, fmt.Errorf("line %d: unexpected trailing data: %q", p.line, p.lineTail())
+		}
+	}
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) lineTail() string {
+	s := p.s[p.pos:]
+	if n := strings.IndexByte(s, '\n'); n >= 0 {
+		s = s[:n]
+	}
+	return s
+}
+
+// skipSpacesAndComments skips spaces and comments. Newlines are skipped only if skipNewlines is set.
+func (p *tomlParser) skipSpacesAndComments(skipNewlines bool) {
+	for p.pos < len(p.s) {
+		switch c := p.s[p.pos]; c {
+		case ' ', '\t':
+			p.pos++
+		case '\r', '\n':
+			if !skipNewlines {
+				return
+			}
+			if c == '\n' {
+				p.line++
+			}
+			p.pos++
+		case '#':
+			for p.pos < len(p.s) && p.s[p.pos] != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) parseTableHeader(root tomlTable) (tomlTable, error) {
+	isArray := strings.HasPrefix(p.s[p.pos:], "[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+	keys, err := p.parseKeys()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	p.skipSpacesAndComments(false)
+	if !strings.HasPrefix(p.s[p.pos:], closing) {
+		return nil, fmt.Errorf("missing %q at the end of table header", closing)
+	}
+	p.pos += len(closing)
+
+	t, err := getSubtable(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	key := keys[len(keys)-1]
+	if !isArray {
+		return getSubtable(t, keys[len(keys)-1:])
+	}
+	var a []tomlTable
+	if v, ok := t[key]; ok {
+		if a, ok = v.([]tomlTable); !ok {
+			return nil, fmt.Errorf("cannot define array of tables %q, since it is already defined as %T", strings.Join(keys, "."), v)
+		}
+	}
+	nt := tomlTable{}
+	t[key] = append(a, nt)
+	return nt, nil
+}
+
+// getSubtable returns the subtable of t at the given keys, creating missing tables.
+//
+// The last table is used if the key refers to an array of tables.
+func getSubtable(t tomlTable, keys []string) (tomlTable, error) {
+	for _, key := range keys {
+		v, ok := t[key]
+		if !ok {
+			nt := tomlTable{}
+			t[key] = nt
+			t = nt
+			continue
+		}
+		switch x := v.(type) {
+		case tomlTable:
+			t = x
+		case []tomlTable:
+			t = x[len(x)-1]
+		default:
+			return nil, fmt.Errorf("cannot define table %q, since it is already defined as %T", key, v)
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) parseKeyValue(t tomlTable) error {
+	keys, err := p.parseKeys()
+	if err != nil {
+		return err
+	}
+	p.skipSpacesAndComments(false)
+	if p.pos >= len(p.s) || p.s[p.pos] != '=' {
+		return fmt.Errorf("missing '=' after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpacesAndComments(false)
+	v, err := p.parseValue()
+	if err != nil {
+		return fmt.Errorf("cannot parse value for key %q: %w", strings.Join(keys, "."), err)
+	}
+	t, err = getSubtable(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	key := keys[len(keys)-1]
+	if _, ok := t[key]; ok {
+		return fmt.Errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	t[key] = v
+	return nil
+}
+
+func (p *tomlParser) parseKeys() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpacesAndComments(false)
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipSpacesAndComments(false)
+		if p.pos >= len(p.s) || p.s[p.pos] != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) parseKey() (string, error) {
+	if p.pos >= len(p.s) {
+		return "", fmt.Errorf("missing key")
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.parseString()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && isBareKeyChar(p.s[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		return "", fmt.Errorf("unexpected char at the start of key: %q", p.lineTail())
+	}
+	return p.s[start:p.pos], nil
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.pos >= len(p.s) {
+		return nil, fmt.Errorf("missing value")
+	}
+	switch p.s[p.pos] {
+	case '"', '\'':
+		return p.parseString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	}
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" \t\r\n,]}#", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	s := p.s[start:p.pos]
+	switch s {
+	case "":
+		return nil, fmt.Errorf("missing value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	sNum := strings.ReplaceAll(s, "_", "")
+	if n, err := strconv.ParseInt(sNum, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(sNum, 64); err == nil {
+		return f, nil
+	}
+	if s[0] >= '0' && s[0] <= '9' {
+		// Dates and times are returned as strings.
+		return s, nil
+	}
+	return nil, fmt.Errorf("unsupported value: %q", s)
+}
+
+func (p *tomlParser) parseString() (string, error) {
+	quote := p.s[p.pos]
+	multiLine := strings.Repeat(string(quote), 3)
+	if strings.HasPrefix(p.s[p.pos:], multiLine) {
+		p.pos += 3
+		// A newline immediately following the opening delimiter is trimmed.
+		if strings.HasPrefix(p.s[p.pos:], "\r\n") {
+			p.pos += 2
+			p.line++
+		} else if strings.HasPrefix(p.s[p.pos:], "\n") {
+			p.pos++
+			p.line++
+		}
+		n := strings.Index(p.s[p.pos:], multiLine)
+		if n < 0 {
+			return "", fmt.Errorf("missing closing %s", multiLine)
+		}
+		s := p.s[p.pos : p.pos+n]
+		p.line += strings.Count(s, "\n")
+		p.pos += n + 3
+		if quote == '\'' {
+			return s, nil
+		}
+		return unescapeTOMLString(s)
+	}
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != quote && p.s[p.pos] != '\n' {
+		if quote == '"' && p.s[p.pos] == '\\' {
+			p.pos++
+		}
+		p.pos++
+	}
+	if p.pos >= len(p.s) || p.s[p.pos] != quote {
+		return "", fmt.Errorf("missing closing %c", quote)
+	}
+	s := p.s[start:p.pos]
+	p.pos++
+	if quote == '\'' {
+		return s, nil
+	}
+	return unescapeTOMLString(s)
+}
+
+func unescapeTOMLString(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		if i >= len(s) {
+			return "", fmt.Errorf("unexpected backslash at the end of string %q", s)
+		}
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"':
+			b.WriteByte('"')
+		case '\\':
+			b.WriteByte('\\')
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("too short unicode escape sequence in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape sequence in %q: %w", s, err)
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("unsupported escape sequence \\%c in %q", s[i], s)
+		}
+	}
+	return b.String(), nil
+}
+
+func (p *tomlParser) parseArray() ([]interface{}, error) {
+	p.pos++
+	a := []interface{}{}
+	for {
+		p.skipSpacesAndComments(true)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("missing ']' at the end of array")
+		}
+		if p.s[p.pos] == ']' {
+			p.pos++
+			return a, nil
+		}
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		a = append(a, v)
+		p.skipSpacesAndComments(true)
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (tomlTable, error) {
+	p.pos++
+	t := tomlTable{}
+	for {
+		p.skipSpacesAndComments(false)
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("missing '}' at the end of inline table")
+		}
+		if p.s[p.pos] == '}' {
+			p.pos++
+			return t, nil
+		}
+		if err := p.parseKeyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpacesAndComments(false)
+		if p.pos < len(p.s) && p.s[p.pos] == ',' {
+			p.pos++
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package convertconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTOMLSuccess(t *testing.T) {
+	f := func(data string, resultExpected tomlTable) {
+		t.Helper()
+		result, err := parseTOML(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%#v\nwant\n%#v", result, resultExpected)
+		}
+	}
+	f(``, tomlTable{})
+	f(`# comment only`, tomlTable{})
+	f(`
+a = "foo\tbar" # comment
+"quoted key" = 'C:\path'
+b.c = 1_000
+d = -1.5
+e = true
+f = 1979-05-27T07:32:00Z
+`, tomlTable{
+		"a":          "foo\tbar",
+		"quoted key": `C:\path`,
+		"b": tomlTable{
+			"c": int64(1000),
+		},
+		"d": -1.5,
+		"e": true,
+		"f": "1979-05-27T07:32:00Z",
+	})
+	f(`
+arr = [
+  "a", # comment
+  "b",
+]
+nested = [[1, 2], []]
+inline = {x = "y", z = [1]}
+multi = """
+line1
+line2"""
+`, tomlTable{
+		"arr":    []interface{}{"a", "b"},
+		"nested": []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{}},
+		"inline": tomlTable{
+			"x": "y",
+			"z": []interface{}{int64(1)},
+		},
+		"multi": "line1\nline2",
+	})
+	f(`
+[agent]
+  interval = "10s"
+
+[[inputs.prometheus]]
+  urls = ["http://foo"]
+  [inputs.prometheus.tags]
+    team = "a"
+
+[[inputs.prometheus]]
+  urls = ["http://bar"]
+`, tomlTable{
+		"agent": tomlTable{
+			"interval": "10s",
+		},
+		"inputs": tomlTable{
+			"prometheus": []tomlTable{
+				{
+					"urls": []interface{}{"http://foo"},
+					"tags": tomlTable{
+						"team": "a",
+					},
+				},
+				{
+					"urls": []interface{}{"http://bar"},
+				},
+			},
+		},
+	})
+}
+
+func TestParseTOMLFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		result, err := parseTOML(data)
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if result != nil {
+			t.Fatalf("expecting nil result; got %#v", result)
+		}
+	}
+	f(`a`)
+	f(`a = `)
+	f(`a = "foo`)
+	f(`a = [1, 2`)
+	f(`a = 1 b = 2`)
+	f(`a = 1
+a = 2`)
+	f(`[foo`)
+	f(`a = 1
+[a]`)
+	f(`a = foo`)
+	f(`a = "\q"`)
+}
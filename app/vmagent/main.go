@@ -7,21 +7,26 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/convertconfig"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/csvimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/datadog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/influx"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/jsonimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/native"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/opentsdb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/opentsdbhttp"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/prometheusimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/promremotewrite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/statsd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/vmimport"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -33,10 +38,14 @@ import (
 	influxserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/influx"
 	opentsdbserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/opentsdb"
 	opentsdbhttpserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/opentsdbhttp"
+	statsdserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	jsonimportstream "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/jsonmapping/stream"
+	vmimportstream "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/vmimport/stream"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/pushmetrics"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -65,15 +74,26 @@ var (
 		"See also -opentsdbHTTPListenAddr.useProxyProtocol")
 	opentsdbHTTPUseProxyProtocol = flag.Bool("opentsdbHTTPListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted "+
 		"at -opentsdbHTTPListenAddr . See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
+	statsdListenAddr = flag.String("statsdListenAddr", "", "TCP and UDP address to listen for StatsD metrics. Usually :8125 must be set. Doesn't work if empty. "+
+		"The received metrics are aggregated over -statsd.flushInterval and converted into Prometheus-style series according to -statsd.mappingConfig. "+
+		"See also -statsdListenAddr.useProxyProtocol")
+	statsdUseProxyProtocol = flag.Bool("statsdListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -statsdListenAddr . "+
+		"See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
 	configAuthKey = flag.String("configAuthKey", "", "Authorization key for accessing /config page. It must be passed via authKey query arg")
 	dryRun        = flag.Bool("dryRun", false, "Whether to check only config files without running vmagent. The following files are checked: "+
-		"-promscrape.config, -remoteWrite.relabelConfig, -remoteWrite.urlRelabelConfig . "+
+		"-promscrape.config, -remoteWrite.relabelConfig, -remoteWrite.urlRelabelConfig . The parsed relabeling rules are printed to stdout. "+
 		"Unknown config entries aren't allowed in -promscrape.config by default. This can be changed by passing -promscrape.config.strictParse=false command-line flag")
+	convertConfig = flag.String("convertConfig", "", "Optional path to Telegraf config or to Datadog openmetrics check config to convert into -promscrape.config. "+
+		"The converted config is printed to stdout and then vmagent exits. Files with .yaml or .yml extension are treated as Datadog check configs. "+
+		"See https://docs.victoriametrics.com/vmagent.html#converting-telegraf-and-datadog-configs")
+	scrapeConfigsAuthKey = flag.String("scrapeConfigsAuthKey", "", "Authorization key for /api/v1/scrape_configs API. It must be passed via authKey query arg. "+
+		"See https://docs.victoriametrics.com/vmagent.html#dynamic-scrape-configs")
 )
 
 var (
 	influxServer       *influxserver.Server
 	graphiteServer     *graphiteserver.Server
+	statsdServer       *statsdserver.Server
 	opentsdbServer     *opentsdbserver.Server
 	opentsdbhttpServer *opentsdbhttpserver.Server
 )
@@ -94,6 +114,16 @@ func main() {
 	logger.Init()
 	pushmetrics.Init()
 
+	if *convertConfig != "" {
+		data, err := convertconfig.Convert(*convertConfig)
+		if err != nil {
+			logger.Fatalf("cannot convert -convertConfig=%q: %s", *convertConfig, err)
+		}
+		if _, err := os.Stdout.Write(data); err != nil {
+			logger.Fatalf("cannot write the converted config to stdout: %s", err)
+		}
+		return
+	}
 	if promscrape.IsDryRun() {
 		if err := promscrape.CheckConfig(); err != nil {
 			logger.Fatalf("error when checking -promscrape.config: %s", err)
@@ -102,7 +132,7 @@ func main() {
 		return
 	}
 	if *dryRun {
-		if err := remotewrite.CheckRelabelConfigs(); err != nil {
+		if err := remotewrite.WriteRelabelConfigs(os.Stdout); err != nil {
 			logger.Fatalf("error when checking relabel configs: %s", err)
 		}
 		if err := promscrape.CheckConfig(); err != nil {
@@ -114,16 +144,22 @@ func main() {
 
 	logger.Infof("starting vmagent at %q...", *httpListenAddr)
 	startTime := time.Now()
+	auditlog.Init()
 	remotewrite.Init()
 	common.StartUnmarshalWorkers()
+	jsonimportstream.Init()
 	if len(*influxListenAddr) > 0 {
 		influxServer = influxserver.MustStart(*influxListenAddr, *influxUseProxyProtocol, func(r io.Reader) error {
-			return influx.InsertHandlerForReader(r, false)
+			return influx.InsertHandlerForReader(r)
 		})
 	}
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer = graphiteserver.MustStart(*graphiteListenAddr, *graphiteUseProxyProtocol, graphite.InsertHandler)
 	}
+	if len(*statsdListenAddr) > 0 {
+		statsd.Init()
+		statsdServer = statsdserver.MustStart(*statsdListenAddr, *statsdUseProxyProtocol, statsd.InsertHandler)
+	}
 	if len(*opentsdbListenAddr) > 0 {
 		httpInsertHandler := getOpenTSDBHTTPInsertHandler()
 		opentsdbServer = opentsdbserver.MustStart(*opentsdbListenAddr, *opentsdbUseProxyProtocol, opentsdb.InsertHandler, httpInsertHandler)
@@ -160,6 +196,10 @@ func main() {
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer.MustStop()
 	}
+	if len(*statsdListenAddr) > 0 {
+		statsdServer.MustStop()
+		statsd.Stop()
+	}
 	if len(*opentsdbListenAddr) > 0 {
 		opentsdbServer.MustStop()
 	}
@@ -168,6 +208,7 @@ func main() {
 	}
 	common.StopUnmarshalWorkers()
 	remotewrite.Stop()
+	auditlog.MustStop()
 
 	logger.Infof("successfully stopped vmagent in %.3f seconds", time.Since(startTime).Seconds())
 }
@@ -176,8 +217,10 @@ func getOpenTSDBHTTPInsertHandler() func(req *http.Request) error {
 	if !remotewrite.MultitenancyEnabled() {
 		return func(req *http.Request) error {
 			path := strings.Replace(req.URL.Path, "//", "/", -1)
-			if path != "/api/put" {
-				return fmt.Errorf("unsupported path requested: %q; expecting '/api/put'", path)
+			switch path {
+			case "/api/put", "/api/rollup", "/api/histogram":
+			default:
+				return fmt.Errorf("unsupported path requested: %q; expecting '/api/put', '/api/rollup' or '/api/histogram'", path)
 			}
 			return opentsdbhttp.InsertHandler(nil, req)
 		}
@@ -200,8 +243,10 @@ func getAuthTokenFromPath(path string) (*auth.Token, error) {
 	if p.Prefix != "insert" {
 		return nil, fmt.Errorf(`unsupported multitenant prefix: %q; expected "insert"`, p.Prefix)
 	}
-	if p.Suffix != "opentsdb/api/put" {
-		return nil, fmt.Errorf("unsupported path requested: %q; expecting 'opentsdb/api/put'", p.Suffix)
+	switch p.Suffix {
+	case "opentsdb/api/put", "opentsdb/api/rollup", "opentsdb/api/histogram":
+	default:
+		return nil, fmt.Errorf("unsupported path requested: %q; expecting 'opentsdb/api/put', 'opentsdb/api/rollup' or 'opentsdb/api/histogram'", p.Suffix)
 	}
 	return auth.NewToken(p.AuthToken)
 }
@@ -220,6 +265,7 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 			{"service-discovery", "labels before and after relabeling for discovered targets"},
 			{"metric-relabel-debug", "debug metric relabeling"},
 			{"api/v1/targets", "advanced information about discovered targets in JSON format"},
+			{"api/v1/metadata", "metric metadata collected from targets with metadata_only option"},
 			{"config", "-promscrape.config contents"},
 			{"metrics", "available service metrics"},
 			{"flags", "command-line flags"},
@@ -259,16 +305,18 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
+		w.Header().Set(labelsintern.SupportHeader, "1")
 		w.WriteHeader(http.StatusNoContent)
 		return true
 	case "/prometheus/api/v1/import", "/api/v1/import":
 		vmimportRequests.Inc()
-		if err := vmimport.InsertHandler(nil, r); err != nil {
+		res, err := vmimport.InsertHandler(nil, r)
+		if err != nil {
 			vmimportErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
-		w.WriteHeader(http.StatusNoContent)
+		vmimportstream.WriteResponse(w, res)
 		return true
 	case "/prometheus/api/v1/import/csv", "/api/v1/import/csv":
 		csvimportRequests.Inc()
@@ -288,6 +336,15 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
+	case "/prometheus/api/v1/import/json", "/api/v1/import/json":
+		jsonimportRequests.Inc()
+		if err := jsonimport.InsertHandler(nil, r); err != nil {
+			jsonimportErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
 	case "/influx/write", "/influx/api/v2/write", "/write", "/api/v2/write":
 		influxWriteRequests.Inc()
 		if err := influx.InsertHandlerForHTTP(nil, r); err != nil {
@@ -358,6 +415,16 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		state := r.FormValue("state")
 		promscrape.WriteAPIV1Targets(w, state)
 		return true
+	case "/prometheus/api/v1/metadata", "/api/v1/metadata":
+		promscrapeAPIV1MetadataRequests.Inc()
+		limit, err := getMetadataLimit(r)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		promscrape.WriteMetadata(w, r.FormValue("metric"), limit)
+		return true
 	case "/prometheus/target_response", "/target_response":
 		promscrapeTargetResponseRequests.Inc()
 		if err := promscrape.WriteTargetResponse(w, r); err != nil {
@@ -385,11 +452,36 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		promscrape.WriteConfigData(&bb)
 		fmt.Fprintf(w, `{"status":"success","data":{"yaml":%q}}`, bb.B)
 		return true
+	case "/prometheus/api/v1/status/config_version", "/api/v1/status/config_version":
+		promscrapeStatusConfigVersionRequests.Inc()
+		w.Header().Set("Content-Type", "application/json")
+		promscrape.WriteConfigVersion(w)
+		return true
 	case "/prometheus/-/reload", "/-/reload":
 		promscrapeConfigReloadRequests.Inc()
+		if r.FormValue("config_hash") != "" {
+			// Reload -promscrape.config synchronously and apply it only if it has the expected hash.
+			promscrape.ReloadConfig(w, r)
+			return true
+		}
+		auditlog.Log(auditlog.NewEvent(r, "config_reload_request"), nil)
 		procutil.SelfSIGHUP()
 		w.WriteHeader(http.StatusOK)
 		return true
+	case "/prometheus/api/v1/scrape_configs", "/api/v1/scrape_configs":
+		promscrapeScrapeConfigsRequests.Inc()
+		if !httpserver.CheckAuthFlag(w, r, *scrapeConfigsAuthKey, "scrapeConfigsAuthKey") {
+			return true
+		}
+		if err := promscrape.ScrapeConfigsHandler(w, r, ""); err != nil {
+			promscrapeScrapeConfigsErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/prometheus/-/rollback", "/-/rollback":
+		promscrapeConfigRollbackRequests.Inc()
+		promscrape.RollbackConfig(w, r)
+		return true
 	case "/ready":
 		if rdy := atomic.LoadInt32(&promscrape.PendingScrapeConfigs); rdy > 0 {
 			errMsg := fmt.Sprintf("waiting for scrapes to init, left: %d", rdy)
@@ -405,6 +497,17 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 			staticServer.ServeHTTP(w, r)
 			return true
 		}
+		if jobName, ok := getScrapeConfigsJobName(path); ok {
+			promscrapeScrapeConfigsRequests.Inc()
+			if !httpserver.CheckAuthFlag(w, r, *scrapeConfigsAuthKey, "scrapeConfigsAuthKey") {
+				return true
+			}
+			if err := promscrape.ScrapeConfigsHandler(w, r, jobName); err != nil {
+				promscrapeScrapeConfigsErrors.Inc()
+				httpserver.Errorf(w, r, "%s", err)
+			}
+			return true
+		}
 		if remotewrite.MultitenancyEnabled() {
 			return processMultitenantRequest(w, r, path)
 		}
@@ -412,6 +515,16 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 	}
 }
 
+// getScrapeConfigsJobName returns job name from /api/v1/scrape_configs/<job_name> path.
+func getScrapeConfigsJobName(path string) (string, bool) {
+	path = strings.TrimPrefix(path, "/prometheus")
+	jobName := strings.TrimPrefix(path, "/api/v1/scrape_configs/")
+	if jobName == path || jobName == "" {
+		return "", false
+	}
+	return jobName, true
+}
+
 func processMultitenantRequest(w http.ResponseWriter, r *http.Request, path string) bool {
 	p, err := httpserver.ParsePath(path)
 	if err != nil {
@@ -450,16 +563,18 @@ func processMultitenantRequest(w http.ResponseWriter, r *http.Request, path stri
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
+		w.Header().Set(labelsintern.SupportHeader, "1")
 		w.WriteHeader(http.StatusNoContent)
 		return true
 	case "prometheus/api/v1/import":
 		vmimportRequests.Inc()
-		if err := vmimport.InsertHandler(at, r); err != nil {
+		res, err := vmimport.InsertHandler(at, r)
+		if err != nil {
 			vmimportErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
-		w.WriteHeader(http.StatusNoContent)
+		vmimportstream.WriteResponse(w, res)
 		return true
 	case "prometheus/api/v1/import/csv":
 		csvimportRequests.Inc()
@@ -479,6 +594,15 @@ func processMultitenantRequest(w http.ResponseWriter, r *http.Request, path stri
 		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
+	case "prometheus/api/v1/import/json":
+		jsonimportRequests.Inc()
+		if err := jsonimport.InsertHandler(at, r); err != nil {
+			jsonimportErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
 	case "influx/write", "influx/api/v2/write":
 		influxWriteRequests.Inc()
 		if err := influx.InsertHandlerForHTTP(at, r); err != nil {
@@ -548,6 +672,9 @@ var (
 	nativeimportRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 	nativeimportErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 
+	jsonimportRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/import/json", protocol="jsonimport"}`)
+	jsonimportErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/import/json", protocol="jsonimport"}`)
+
 	influxWriteRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/influx/write", protocol="influx"}`)
 	influxWriteErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/influx/write", protocol="influx"}`)
 
@@ -567,15 +694,20 @@ var (
 	promscrapeMetricRelabelDebugRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/metric-relabel-debug"}`)
 	promscrapeTargetRelabelDebugRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/target-relabel-debug"}`)
 
-	promscrapeAPIV1TargetsRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/targets"}`)
+	promscrapeAPIV1TargetsRequests  = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/targets"}`)
+	promscrapeAPIV1MetadataRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/metadata"}`)
 
 	promscrapeTargetResponseRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/target_response"}`)
 	promscrapeTargetResponseErrors   = metrics.NewCounter(`vmagent_http_request_errors_total{path="/target_response"}`)
 
-	promscrapeConfigRequests       = metrics.NewCounter(`vmagent_http_requests_total{path="/config"}`)
-	promscrapeStatusConfigRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/status/config"}`)
+	promscrapeConfigRequests              = metrics.NewCounter(`vmagent_http_requests_total{path="/config"}`)
+	promscrapeStatusConfigRequests        = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/status/config"}`)
+	promscrapeStatusConfigVersionRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/status/config_version"}`)
 
-	promscrapeConfigReloadRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/-/reload"}`)
+	promscrapeConfigReloadRequests   = metrics.NewCounter(`vmagent_http_requests_total{path="/-/reload"}`)
+	promscrapeScrapeConfigsRequests  = metrics.NewCounter(`vmagent_http_requests_total{path="/api/v1/scrape_configs"}`)
+	promscrapeScrapeConfigsErrors    = metrics.NewCounter(`vmagent_http_request_errors_total{path="/api/v1/scrape_configs"}`)
+	promscrapeConfigRollbackRequests = metrics.NewCounter(`vmagent_http_requests_total{path="/-/rollback"}`)
 )
 
 func usage() {
@@ -586,3 +718,16 @@ See the docs at https://docs.victoriametrics.com/vmagent.html .
 `
 	flagutil.Usage(s)
 }
+
+// getMetadataLimit returns the value of `limit` query arg for /api/v1/metadata.
+func getMetadataLimit(r *http.Request) (int, error) {
+	s := r.FormValue("limit")
+	if s == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse limit=%q: %w", s, err)
+	}
+	return limit, nil
+}
@@ -18,7 +18,7 @@ var (
 	rowsPerInsert = metrics.NewHistogram(`vmagent_rows_per_insert{type="opentsdbhttp"}`)
 )
 
-// InsertHandler processes HTTP OpenTSDB put requests.
+// InsertHandler processes HTTP OpenTSDB put, rollup and histogram requests.
 // See http://opentsdb.net/docs/build/html/api_http/put.html
 func InsertHandler(at *auth.Token, req *http.Request) error {
 	extraLabels, err := parserCommon.GetExtraLabels(req)
@@ -2,6 +2,7 @@ package prometheusimport
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
@@ -31,11 +32,12 @@ func InsertHandler(at *auth.Token, req *http.Request) error {
 	if err != nil {
 		return err
 	}
-	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
-	return stream.Parse(req.Body, defaultTimestamp, isGzipped, func(rows []parser.Row) error {
+	ce := req.Header.Get("Content-Encoding")
+	return stream.Parse(req.Body, defaultTimestamp, ce, func(rows []parser.Row) error {
 		return insertRows(at, rows, extraLabels)
 	}, func(s string) {
 		httpserver.LogError(req, s)
+		remotewrite.PushDeadLetterInvalidLine("prometheus", s, time.Now().UnixMilli())
 	})
 }
 
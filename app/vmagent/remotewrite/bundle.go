@@ -0,0 +1,275 @@
+package remotewrite
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/gzip"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	bundlePath = flag.String("remoteWrite.bundlePath", "", "Optional path to directory for writing the collected data to gzip-compressed files in VictoriaMetrics native format. "+
+		"The files may be transferred to another site and imported with vmctl bundle mode. -remoteWrite.url may be omitted if this flag is set. "+
+		"See https://docs.victoriametrics.com/vmagent.html#offline-bundles")
+	bundleMaxFileSize = flagutil.NewBytes("remoteWrite.bundleMaxFileSize", 100*1024*1024, "The maximum size of a single file at -remoteWrite.bundlePath. "+
+		"The file is closed and a new file is started when the size is exceeded. See also -remoteWrite.bundleMaxFileAge")
+	bundleMaxFileAge = flag.Duration("remoteWrite.bundleMaxFileAge", time.Hour, "The maximum duration for writing data to a single file at -remoteWrite.bundlePath. "+
+		"The file is closed and a new file is started after the given duration. See also -remoteWrite.bundleMaxFileSize")
+)
+
+const (
+	// bundleFileSuffix is the suffix for complete bundle files, which are ready for transfer.
+	bundleFileSuffix = ".native.gz"
+
+	// bundleTmpFileSuffix is the suffix for the bundle file, which is being written.
+	bundleTmpFileSuffix = bundleFileSuffix + ".tmp"
+)
+
+// bundleWriter writes time series to rotated gzip-compressed files in VictoriaMetrics native format.
+//
+// Every file can be imported via /api/v1/import/native with `Content-Encoding: gzip` request header.
+type bundleWriter struct {
+	dir string
+
+	mu sync.Mutex
+
+	// f is the currently written file. It is nil if no data has been written since the last rotation.
+	f        *os.File
+	zw       *gzip.Writer
+	path     string
+	size     uint64
+	openedAt time.Time
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+
+	filesCreated *metrics.Counter
+	bytesWritten *metrics.Counter
+	rowsWritten  *metrics.Counter
+}
+
+// mustOpenBundleWriter opens bundleWriter for the given dir, which is set via the given flagName.
+func mustOpenBundleWriter(flagName, dir string) *bundleWriter {
+	if err := fs.MkdirAllIfNotExist(dir); err != nil {
+		logger.Fatalf("cannot create -%s=%q: %s", flagName, dir, err)
+	}
+	removeIncompleteBundleFiles(flagName, dir)
+	bw := &bundleWriter{
+		dir:    dir,
+		stopCh: make(chan struct{}),
+
+		filesCreated: metrics.NewCounter(fmt.Sprintf(`vmagent_remotewrite_bundle_files_created_total{path=%q}`, dir)),
+		bytesWritten: metrics.NewCounter(fmt.Sprintf(`vmagent_remotewrite_bundle_bytes_written_total{path=%q}`, dir)),
+		rowsWritten:  metrics.NewCounter(fmt.Sprintf(`vmagent_remotewrite_bundle_rows_written_total{path=%q}`, dir)),
+	}
+	bw.wg.Add(1)
+	go func() {
+		defer bw.wg.Done()
+		bw.rotateWorker()
+	}()
+	return bw
+}
+
+// removeIncompleteBundleFiles removes files left after unclean shutdown, since they contain truncated gzip stream.
+func removeIncompleteBundleFiles(flagName, dir string) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		logger.Fatalf("cannot read -%s=%q: %s", flagName, dir, err)
+	}
+	for _, de := range des {
+		if !strings.HasSuffix(de.Name(), bundleTmpFileSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, de.Name())
+		logger.Warnf("removing incomplete bundle file %q left after unclean shutdown", path)
+		fs.MustRemoveAll(path)
+	}
+}
+
+func (bw *bundleWriter) rotateWorker() {
+	if *bundleMaxFileAge <= 0 {
+		// Files are rotated only by size.
+		return
+	}
+	d := *bundleMaxFileAge / 10
+	if d > time.Second {
+		d = time.Second
+	}
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-bw.stopCh:
+			return
+		case <-ticker.C:
+		}
+		bw.mu.Lock()
+		if bw.f != nil && time.Since(bw.openedAt) >= *bundleMaxFileAge {
+			bw.closeFileLocked()
+		}
+		bw.mu.Unlock()
+	}
+}
+
+// MustStop stops bw and finalizes the currently written file.
+func (bw *bundleWriter) MustStop() {
+	close(bw.stopCh)
+	bw.wg.Wait()
+
+	bw.mu.Lock()
+	if bw.f != nil {
+		bw.closeFileLocked()
+	}
+	bw.mu.Unlock()
+}
+
+// Push writes tss to the current bundle file.
+func (bw *bundleWriter) Push(tss []prompbmarshal.TimeSeries) {
+	bb := bundleBufPool.Get()
+	bb.B = marshalNativeBlocks(bb.B[:0], tss)
+	rows := getRowsCount(tss)
+
+	bw.mu.Lock()
+	if bw.f == nil {
+		bw.openFileLocked()
+	}
+	if _, err := bw.zw.Write(bb.B); err != nil {
+		logger.Panicf("FATAL: cannot write %d bytes to %q: %s", len(bb.B), bw.path, err)
+	}
+	bw.size += uint64(len(bb.B))
+	if bw.size >= uint64(bundleMaxFileSize.N) {
+		bw.closeFileLocked()
+	}
+	bw.mu.Unlock()
+
+	bw.bytesWritten.Add(len(bb.B))
+	bw.rowsWritten.Add(rows)
+	bundleBufPool.Put(bb)
+}
+
+var bundleBufPool bytesutil.ByteBufferPool
+
+func (bw *bundleWriter) openFileLocked() {
+	// Use the current time in file names, so files are imported in the order they were created.
+	name := fmt.Sprintf("%016X%s", uint64(time.Now().UnixNano()), bundleTmpFileSuffix)
+	path := filepath.Join(bw.dir, name)
+	f, err := os.Create(path)
+	if err != nil {
+		logger.Panicf("FATAL: cannot create bundle file: %s", err)
+	}
+	zw, err := gzip.NewWriterLevel(f, gzip.DefaultCompression)
+	if err != nil {
+		logger.Panicf("BUG: cannot create gzip writer: %s", err)
+	}
+	// Write the time range covering all the possible timestamps, so the importer doesn't drop any samples.
+	var trBuf []byte
+	trBuf = encoding.MarshalInt64(trBuf, math.MinInt64)
+	trBuf = encoding.MarshalInt64(trBuf, math.MaxInt64)
+	if _, err := zw.Write(trBuf); err != nil {
+		logger.Panicf("FATAL: cannot write time range to %q: %s", path, err)
+	}
+	bw.f = f
+	bw.zw = zw
+	bw.path = path
+	bw.size = uint64(len(trBuf))
+	bw.openedAt = time.Now()
+	bw.filesCreated.Inc()
+}
+
+func (bw *bundleWriter) closeFileLocked() {
+	if err := bw.zw.Close(); err != nil {
+		logger.Panicf("FATAL: cannot flush gzip stream to %q: %s", bw.path, err)
+	}
+	if err := bw.f.Sync(); err != nil {
+		logger.Panicf("FATAL: cannot sync %q: %s", bw.path, err)
+	}
+	fs.MustClose(bw.f)
+
+	// Rename the file only after it is complete, so it cannot be transferred while being written.
+	dstPath := strings.TrimSuffix(bw.path, ".tmp")
+	if err := os.Rename(bw.path, dstPath); err != nil {
+		logger.Panicf("FATAL: cannot rename %q to %q: %s", bw.path, dstPath, err)
+	}
+	fs.MustSyncPath(bw.dir)
+	logger.Infof("finished writing bundle file %q with size %d bytes before compression", dstPath, bw.size)
+
+	bw.f = nil
+	bw.zw = nil
+	bw.path = ""
+	bw.size = 0
+}
+
+// marshalNativeBlocks appends tss in the format accepted by /api/v1/import/native to dst and returns the result.
+//
+// The time range header isn't appended.
+func marshalNativeBlocks(dst []byte, tss []prompbmarshal.TimeSeries) []byte {
+	var mn storage.MetricName
+	var b storage.Block
+	var tsid storage.TSID
+	var timestamps []int64
+	var floatValues []float64
+	var values []int64
+	var metricNameBuf []byte
+	var blockBuf []byte
+	for i := range tss {
+		ts := &tss[i]
+		if len(ts.Samples) == 0 {
+			continue
+		}
+		mn.Reset()
+		for _, label := range ts.Labels {
+			if label.Name == "__name__" {
+				mn.MetricGroup = append(mn.MetricGroup[:0], label.Value...)
+				continue
+			}
+			mn.AddTag(label.Name, label.Value)
+		}
+		metricNameBuf = mn.Marshal(metricNameBuf[:0])
+
+		samples := ts.Samples
+		for len(samples) > 0 {
+			// Limit the number of samples per block, since the importer rejects too big blocks.
+			n := len(samples)
+			if n > maxSamplesPerNativeBlock {
+				n = maxSamplesPerNativeBlock
+			}
+			timestamps = timestamps[:0]
+			floatValues = floatValues[:0]
+			for _, s := range samples[:n] {
+				timestamps = append(timestamps, s.Timestamp)
+				floatValues = append(floatValues, s.Value)
+			}
+			samples = samples[n:]
+
+			var scale int16
+			values, scale = decimal.AppendFloatToDecimal(values[:0], floatValues)
+			b.Init(&tsid, timestamps, values, scale, 64)
+
+			blockBuf = b.MarshalPortable(blockBuf[:0])
+
+			dst = encoding.MarshalUint32(dst, uint32(len(metricNameBuf)))
+			dst = append(dst, metricNameBuf...)
+			dst = encoding.MarshalUint32(dst, uint32(len(blockBuf)))
+			dst = append(dst, blockBuf...)
+		}
+	}
+	return dst
+}
+
+const maxSamplesPerNativeBlock = 8 * 1024
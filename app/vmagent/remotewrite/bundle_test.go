@@ -0,0 +1,95 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/native/stream"
+)
+
+func TestBundleWriter(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	dir := t.TempDir()
+
+	// Leftover of unclean shutdown must be removed.
+	tmpPath := filepath.Join(dir, "0000000000000001"+bundleTmpFileSuffix)
+	if err := os.WriteFile(tmpPath, []byte("truncated"), 0600); err != nil {
+		t.Fatalf("cannot create %q: %s", tmpPath, err)
+	}
+
+	tss := []prompbmarshal.TimeSeries{
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+				{Name: "job", Value: "bar"},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Value: 1.5, Timestamp: 1000},
+				{Value: 2, Timestamp: 2000},
+			},
+		},
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "baz"},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Value: -3, Timestamp: 1000},
+			},
+		},
+	}
+	bw := mustOpenBundleWriter("remoteWrite.bundlePath", dir)
+	bw.Push(tss)
+	bw.Push(tss[1:])
+	bw.MustStop()
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read %q: %s", dir, err)
+	}
+	if len(des) != 1 {
+		t.Fatalf("unexpected number of files in %q; got %d; want 1", dir, len(des))
+	}
+	name := des[0].Name()
+	if !strings.HasSuffix(name, bundleFileSuffix) {
+		t.Fatalf("unexpected file name %q; it must end with %q", name, bundleFileSuffix)
+	}
+
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("cannot open bundle file: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+	var mu sync.Mutex
+	var rows []string
+	err = stream.Parse(f, "gzip", func(block *stream.Block) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range block.Timestamps {
+			rows = append(rows, fmt.Sprintf("%s %g %d", block.MetricName.String(), block.Values[i], block.Timestamps[i]))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot parse bundle file: %s", err)
+	}
+	sort.Strings(rows)
+	rowsExpected := []string{
+		"baz{} -3 1000",
+		"baz{} -3 1000",
+		`foo{job="bar"} 1.5 1000`,
+		`foo{job="bar"} 2 2000`,
+	}
+	if !reflect.DeepEqual(rows, rowsExpected) {
+		t.Fatalf("unexpected rows;\ngot\n%q\nwant\n%q", rows, rowsExpected)
+	}
+}
@@ -6,15 +6,21 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cpupool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/persistentqueue"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -64,6 +70,12 @@ var (
 	awsService     = flagutil.NewArrayString("remoteWrite.aws.service", "Optional AWS Service to use for the corresponding -remoteWrite.url if -remoteWrite.aws.useSigv4 is set. "+
 		"Defaults to \"aps\"")
 	awsSecretKey = flagutil.NewArrayString("remoteWrite.aws.secretKey", "Optional AWS SecretKey to use for the corresponding -remoteWrite.url if -remoteWrite.aws.useSigv4 is set")
+
+	disableLabelsIntern = flagutil.NewArrayBool("remoteWrite.disableLabelsIntern", "Whether to disable interning of label sets across requests to the corresponding -remoteWrite.url. "+
+		"By default label sets are interned if -remoteWrite.useVMProto is set and the remote storage supports it. "+
+		"See https://docs.victoriametrics.com/vmagent.html#labels-interning")
+	labelsInternCacheSize = flagutil.NewBytes("remoteWrite.labelsInternCacheSize", 64*1024*1024, "The maximum size of interned label sets cached for every -remoteWrite.url. "+
+		"A new interning session is started when the cache becomes full. See https://docs.victoriametrics.com/vmagent.html#labels-interning")
 )
 
 type client struct {
@@ -73,7 +85,16 @@ type client struct {
 	fq              *persistentqueue.FastQueue
 	hc              *http.Client
 
-	sendBlock func(block []byte) bool
+	// Whether to intern label sets across requests if the remote storage supports it.
+	useLabelsIntern bool
+
+	// The maximum size of interned label sets per each worker.
+	labelsInternMaxSize int
+
+	// zstd compression level for blocks re-encoded with interned label sets.
+	zstdLevel int
+
+	sendBlock func(block []byte, lic *labelsInternCtx) bool
 	authCfg   *promauth.Config
 	awsCfg    *awsapi.Config
 
@@ -89,6 +110,12 @@ type client struct {
 	retriesCount    *metrics.Counter
 	sendDuration    *metrics.FloatCounter
 
+	labelsInternBlocksSent *metrics.Counter
+	labelsInternResets     *metrics.Counter
+	labelsInternFallbacks  *metrics.Counter
+
+	backpressureResponses *metrics.Counter
+
 	wg     sync.WaitGroup
 	stopCh chan struct{}
 }
@@ -134,7 +161,10 @@ func newHTTPClient(argIdx int, remoteWriteURL, sanitizedURL string, fq *persiste
 			Transport: tr,
 			Timeout:   sendTimeout.GetOptionalArgOrDefault(argIdx, time.Minute),
 		},
-		stopCh: make(chan struct{}),
+		useLabelsIntern:     isVMRemoteWrite && !disableLabelsIntern.GetOptionalArg(argIdx),
+		labelsInternMaxSize: labelsInternCacheSize.IntN() / concurrency,
+		zstdLevel:           compressionLevel.GetOptionalArgOrDefault(argIdx, 0),
+		stopCh:              make(chan struct{}),
 	}
 	c.sendBlock = c.sendBlockHTTP
 	return c
@@ -158,14 +188,22 @@ func (c *client) init(argIdx, concurrency int, sanitizedURL string) {
 	c.packetsDropped = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_packets_dropped_total{url=%q}`, c.sanitizedURL))
 	c.retriesCount = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_retries_count_total{url=%q}`, c.sanitizedURL))
 	c.sendDuration = metrics.GetOrCreateFloatCounter(fmt.Sprintf(`vmagent_remotewrite_send_duration_seconds_total{url=%q}`, c.sanitizedURL))
+	c.labelsInternBlocksSent = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_labels_intern_blocks_sent_total{url=%q}`, c.sanitizedURL))
+	c.labelsInternResets = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_labels_intern_resets_total{url=%q}`, c.sanitizedURL))
+	c.labelsInternFallbacks = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_labels_intern_fallbacks_total{url=%q}`, c.sanitizedURL))
+	c.backpressureResponses = metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_backpressure_responses_total{url=%q}`, c.sanitizedURL))
 	metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_queues{url=%q}`, c.sanitizedURL), func() float64 {
 		return float64(*queues)
 	})
 	for i := 0; i < concurrency; i++ {
+		var p *cpupool.Pool
+		if rwCPUPools.Len() > 0 {
+			p = rwCPUPools.Get(uint64(i))
+		}
 		c.wg.Add(1)
 		go func() {
 			defer c.wg.Done()
-			c.runWorker()
+			c.runWorker(p)
 		}()
 	}
 	logger.Infof("initialized client for -remoteWrite.url=%q", c.sanitizedURL)
@@ -252,9 +290,17 @@ func getAWSAPIConfig(argIdx int) (*awsapi.Config, error) {
 	return cfg, nil
 }
 
-func (c *client) runWorker() {
+// runWorker sends blocks from c.fq to remote storage.
+//
+// Blocks are sent from goroutines pinned to CPUs of p if p isn't nil.
+func (c *client) runWorker(p *cpupool.Pool) {
 	var ok bool
 	var block []byte
+	var lic *labelsInternCtx
+	if c.useLabelsIntern {
+		// Every worker sends requests sequentially, so it holds its own interning session.
+		lic = newLabelsInternCtx(c.labelsInternMaxSize, c.zstdLevel)
+	}
 	ch := make(chan bool, 1)
 	for {
 		block, ok = c.fq.MustReadBlock(block[:0])
@@ -262,8 +308,11 @@ func (c *client) runWorker() {
 			return
 		}
 		go func() {
+			if p != nil {
+				defer p.Pin()()
+			}
 			startTime := time.Now()
-			ch <- c.sendBlock(block)
+			ch <- c.sendBlock(block, lic)
 			c.sendDuration.Add(time.Since(startTime).Seconds())
 		}()
 		select {
@@ -295,21 +344,27 @@ func (c *client) runWorker() {
 
 // sendBlockHTTP sends the given block to c.remoteWriteURL.
 //
+// The block is sent with interned label sets if lic isn't nil and the remote storage supports labels interning.
+//
 // The function returns false only if c.stopCh is closed.
 // Otherwise it tries sending the block to remote storage indefinitely.
-func (c *client) sendBlockHTTP(block []byte) bool {
-	c.rl.register(len(block), c.stopCh)
+func (c *client) sendBlockHTTP(block []byte, lic *labelsInternCtx) bool {
+	body, sessionID := lic.getRequestBody(block)
+	c.rl.register(len(body), c.stopCh)
 	retryDuration := time.Second
 	retriesCount := 0
-	c.bytesSent.Add(len(block))
+	c.bytesSent.Add(len(body))
 	c.blocksSent.Inc()
+	if sessionID != "" {
+		c.labelsInternBlocksSent.Inc()
+	}
 	sigv4Hash := ""
 	if c.awsCfg != nil {
-		sigv4Hash = awsapi.HashHex(block)
+		sigv4Hash = awsapi.HashHex(body)
 	}
 
 again:
-	req, err := http.NewRequest("POST", c.remoteWriteURL, bytes.NewBuffer(block))
+	req, err := http.NewRequest("POST", c.remoteWriteURL, bytes.NewBuffer(body))
 	if err != nil {
 		logger.Panicf("BUG: unexpected error from http.NewRequest(%q): %s", c.sanitizedURL, err)
 	}
@@ -320,6 +375,9 @@ again:
 	if c.isVMRemoteWrite {
 		h.Set("Content-Encoding", "zstd")
 		h.Set("X-VictoriaMetrics-Remote-Write-Version", "1")
+		if sessionID != "" {
+			h.Set(labelsintern.SessionHeader, sessionID)
+		}
 	} else {
 		h.Set("Content-Encoding", "snappy")
 		h.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
@@ -340,7 +398,7 @@ again:
 			retryDuration = time.Minute
 		}
 		logger.Warnf("couldn't send a block with size %d bytes to %q: %s; re-sending the block in %.3f seconds",
-			len(block), c.sanitizedURL, err, retryDuration.Seconds())
+			len(body), c.sanitizedURL, err, retryDuration.Seconds())
 		t := timerpool.Get(retryDuration)
 		select {
 		case <-c.stopCh:
@@ -356,9 +414,46 @@ again:
 	if statusCode/100 == 2 {
 		_ = resp.Body.Close()
 		c.requestsOKCount.Inc()
+		if lic != nil {
+			if sessionID != "" {
+				lic.enc.Commit()
+			}
+			lic.isSupported = resp.Header.Get(labelsintern.SupportHeader) == "1"
+		}
 		return true
 	}
 	metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_requests_total{url=%q, status_code="%d"}`, c.sanitizedURL, statusCode)).Inc()
+	if sessionID != "" && statusCode == http.StatusPreconditionFailed {
+		// The remote storage lost the interning session, for example, because of restart.
+		// Start a new session and immediately re-send the block with label sets in full.
+		_ = resp.Body.Close()
+		c.labelsInternResets.Inc()
+		lic.enc.Reset()
+		body, sessionID = lic.getRequestBody(block)
+		if c.awsCfg != nil {
+			sigv4Hash = awsapi.HashHex(body)
+		}
+		goto again
+	}
+	if sessionID != "" && (statusCode == 409 || statusCode == 400) {
+		// The remote storage may not support labels interning, for example, if it is located behind load balancer
+		// together with older versions of remote storage. Re-send the block without labels interning,
+		// so it isn't dropped because of unsupported request format.
+		respBody, err := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			respBody = []byte(err.Error())
+		}
+		logger.Warnf("the block with interned label sets was rejected by %q with status code %d; response body: %s; "+
+			"re-sending the block without labels interning", c.sanitizedURL, statusCode, respBody)
+		c.labelsInternFallbacks.Inc()
+		lic.isSupported = false
+		body, sessionID = block, ""
+		if c.awsCfg != nil {
+			sigv4Hash = awsapi.HashHex(body)
+		}
+		goto again
+	}
 	if statusCode == 409 || statusCode == 400 {
 		body, err := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
@@ -384,15 +479,23 @@ again:
 	if retryDuration > time.Minute {
 		retryDuration = time.Minute
 	}
-	body, err := io.ReadAll(resp.Body)
+	sleepDuration := retryDuration
+	if d := getRetryAfterDuration(resp.Header, time.Now()); d > 0 && (statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable) {
+		// Respect the delay requested by the remote storage, e.g. when it applies backpressure.
+		sleepDuration = d
+	}
+	if resp.Header.Get(httpserver.BackpressureHeader) != "" {
+		c.backpressureResponses.Inc()
+	}
+	respBody, err := io.ReadAll(resp.Body)
 	_ = resp.Body.Close()
 	if err != nil {
 		logger.Errorf("cannot read response body from %q during retry #%d: %s", c.sanitizedURL, retriesCount, err)
 	} else {
 		logger.Errorf("unexpected status code received after sending a block with size %d bytes to %q during retry #%d: %d; response body=%q; "+
-			"re-sending the block in %.3f seconds", len(block), c.sanitizedURL, retriesCount, statusCode, body, retryDuration.Seconds())
+			"re-sending the block in %.3f seconds", len(block), c.sanitizedURL, retriesCount, statusCode, respBody, sleepDuration.Seconds())
 	}
-	t := timerpool.Get(retryDuration)
+	t := timerpool.Get(sleepDuration)
 	select {
 	case <-c.stopCh:
 		timerpool.Put(t)
@@ -404,8 +507,83 @@ again:
 	goto again
 }
 
+// maxRetryAfterDuration is the maximum delay before re-sending the block, which can be requested by remote storage via Retry-After header.
+const maxRetryAfterDuration = 5 * time.Minute
+
+// getRetryAfterDuration returns the delay requested via Retry-After header in h.
+//
+// Zero is returned if the header is missing or invalid.
+// See https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/Retry-After
+func getRetryAfterDuration(h http.Header, now time.Time) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	var d time.Duration
+	if n, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(n) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = t.Sub(now)
+	}
+	if d <= 0 {
+		return 0
+	}
+	if d > maxRetryAfterDuration {
+		d = maxRetryAfterDuration
+	}
+	return d
+}
+
 var remoteWriteRejectedLogger = logger.WithThrottler("remoteWriteRejected", 5*time.Second)
 
+// labelsInternCtx holds the state for sending blocks with label sets interned across requests.
+//
+// See lib/labelsintern for details.
+type labelsInternCtx struct {
+	enc *labelsintern.Encoder
+
+	// zstdLevel is the compression level for re-encoded blocks.
+	zstdLevel int
+
+	// isSupported is set to true when the remote storage responds with labelsintern.SupportHeader.
+	isSupported bool
+
+	wr     prompb.WriteRequest
+	buf    []byte
+	encBuf []byte
+	zb     []byte
+}
+
+func newLabelsInternCtx(maxSize, zstdLevel int) *labelsInternCtx {
+	return &labelsInternCtx{
+		enc:       labelsintern.NewEncoder(maxSize),
+		zstdLevel: zstdLevel,
+	}
+}
+
+// getRequestBody returns request body and interning session id for the given block.
+//
+// The block is returned as is with empty session id if the remote storage doesn't support labels interning.
+func (lic *labelsInternCtx) getRequestBody(block []byte) ([]byte, string) {
+	if lic == nil || !lic.isSupported {
+		return block, ""
+	}
+	var err error
+	lic.buf, err = zstd.Decompress(lic.buf[:0], block)
+	if err != nil {
+		logger.Errorf("cannot decompress block with size %d bytes for sending with interned labels; sending it as is: %s", len(block), err)
+		return block, ""
+	}
+	lic.wr.Reset()
+	if err := lic.wr.Unmarshal(lic.buf); err != nil {
+		logger.Errorf("cannot unmarshal block with size %d bytes for sending with interned labels; sending it as is: %s", len(block), err)
+		return block, ""
+	}
+	lic.encBuf = lic.enc.Encode(lic.encBuf[:0], lic.wr.Timeseries)
+	lic.zb = zstd.CompressLevel(lic.zb[:0], lic.encBuf, lic.zstdLevel)
+	return lic.zb, lic.enc.SessionID()
+}
+
 type rateLimiter struct {
 	perSecondLimit int64
 
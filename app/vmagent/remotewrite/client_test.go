@@ -0,0 +1,205 @@
+package remotewrite
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/promremotewrite/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+func TestClientSendBlockLabelsIntern(t *testing.T) {
+	var mu sync.Mutex
+	supportsIntern := true
+	rejectIntern := false
+	var seriesReceived []string
+	var sessionIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		callback := func(tss []prompb.TimeSeries) error {
+			for _, ts := range tss {
+				seriesReceived = append(seriesReceived, fmt.Sprintf("%s=%s", ts.Labels[0].Name, ts.Labels[0].Value))
+			}
+			return nil
+		}
+		sessionID := r.Header.Get(labelsintern.SessionHeader)
+		sessionIDs = append(sessionIDs, sessionID)
+		var err error
+		switch {
+		case sessionID != "" && rejectIntern:
+			err = fmt.Errorf("cannot parse request with interned labels")
+		case sessionID != "":
+			err = stream.ParseInterned(r.Body, sessionID, callback)
+		default:
+			err = stream.Parse(r.Body, true, callback)
+		}
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+		if supportsIntern {
+			w.Header().Set(labelsintern.SupportHeader, "1")
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := newHTTPClient(0, srv.URL, "test", nil, 1, true)
+	initTestClientMetrics(c)
+	lic := newLabelsInternCtx(1024*1024, 0)
+
+	block := newTestBlock("foo", "bar")
+	f := func(sessionIDExpected bool, seriesExpected []string) {
+		t.Helper()
+		if !c.sendBlockHTTP(block, lic) {
+			t.Fatalf("cannot send block")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if sessionID := sessionIDs[len(sessionIDs)-1]; (sessionID != "") != sessionIDExpected {
+			t.Fatalf("unexpected session id for the last request: %q", sessionID)
+		}
+		if fmt.Sprintf("%q", seriesReceived) != fmt.Sprintf("%q", seriesExpected) {
+			t.Fatalf("unexpected series received; got %q; want %q", seriesReceived, seriesExpected)
+		}
+		seriesReceived = seriesReceived[:0]
+	}
+
+	// The first block must be sent without interning, since the remote storage support isn't known yet.
+	f(false, []string{"__name__=foo", "__name__=bar"})
+
+	// The remote storage advertised interning support, so the next blocks must be sent with interned labels.
+	f(true, []string{"__name__=foo", "__name__=bar"})
+	f(true, []string{"__name__=foo", "__name__=bar"})
+	if n := c.labelsInternBlocksSent.Get(); n != 2 {
+		t.Fatalf("unexpected number of blocks sent with interned labels; got %d; want 2", n)
+	}
+
+	// The client must fall back to sending blocks without interning if the remote storage rejects them.
+	mu.Lock()
+	rejectIntern = true
+	supportsIntern = false
+	mu.Unlock()
+	f(false, []string{"__name__=foo", "__name__=bar"})
+	if n := c.labelsInternFallbacks.Get(); n != 1 {
+		t.Fatalf("unexpected number of fallbacks; got %d; want 1", n)
+	}
+	f(false, []string{"__name__=foo", "__name__=bar"})
+}
+
+func TestClientSendBlockLabelsInternUnknownRef(t *testing.T) {
+	var mu sync.Mutex
+	var sessionIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sessionID := r.Header.Get(labelsintern.SessionHeader)
+		sessionIDs = append(sessionIDs, sessionID)
+		callback := func(tss []prompb.TimeSeries) error {
+			return nil
+		}
+		var err error
+		if sessionID != "" {
+			// Emulate the remote storage restart by using distinct session id on the receiver side.
+			err = stream.ParseInterned(r.Body, fmt.Sprintf("%s-%d", sessionID, len(sessionIDs)), callback)
+		} else {
+			err = stream.Parse(r.Body, true, callback)
+		}
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+		w.Header().Set(labelsintern.SupportHeader, "1")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := newHTTPClient(0, srv.URL, "test", nil, 1, true)
+	initTestClientMetrics(c)
+	lic := newLabelsInternCtx(1024*1024, 0)
+	block := newTestBlock("foo")
+	for i := 0; i < 3; i++ {
+		if !c.sendBlockHTTP(block, lic) {
+			t.Fatalf("cannot send block")
+		}
+	}
+	// The first block is sent without interning, the second block defines the label set,
+	// while the third block refers the label set unknown to the receiver and must be re-sent in the new session.
+	if len(sessionIDs) != 4 {
+		t.Fatalf("unexpected number of requests; got %d; want 4", len(sessionIDs))
+	}
+	if sessionIDs[2] == sessionIDs[3] {
+		t.Fatalf("the block must be re-sent in the new session")
+	}
+	if n := c.labelsInternResets.Get(); n != 1 {
+		t.Fatalf("unexpected number of session resets; got %d; want 1", n)
+	}
+}
+
+func initTestClientMetrics(c *client) {
+	c.bytesSent = &metrics.Counter{}
+	c.blocksSent = &metrics.Counter{}
+	c.requestDuration = &metrics.Histogram{}
+	c.requestsOKCount = &metrics.Counter{}
+	c.errorsCount = &metrics.Counter{}
+	c.packetsDropped = &metrics.Counter{}
+	c.retriesCount = &metrics.Counter{}
+	c.sendDuration = &metrics.FloatCounter{}
+	c.labelsInternBlocksSent = &metrics.Counter{}
+	c.labelsInternResets = &metrics.Counter{}
+	c.labelsInternFallbacks = &metrics.Counter{}
+}
+
+func newTestBlock(metricNames ...string) []byte {
+	var wr prompbmarshal.WriteRequest
+	for _, metricName := range metricNames {
+		wr.Timeseries = append(wr.Timeseries, prompbmarshal.TimeSeries{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: metricName},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Timestamp: 1670000000000, Value: 1},
+			},
+		})
+	}
+	data := prompbmarshal.MarshalWriteRequest(nil, &wr)
+	return zstd.CompressLevel(nil, data, 0)
+}
+
+func TestGetRetryAfterDuration(t *testing.T) {
+	now := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	f := func(retryAfter string, resultExpected time.Duration) {
+		t.Helper()
+		h := make(http.Header)
+		if retryAfter != "" {
+			h.Set("Retry-After", retryAfter)
+		}
+		result := getRetryAfterDuration(h, now)
+		if result != resultExpected {
+			t.Fatalf("unexpected duration for Retry-After=%q; got %s; want %s", retryAfter, result, resultExpected)
+		}
+	}
+	// missing or invalid header
+	f("", 0)
+	f("foo", 0)
+	f("-5", 0)
+	f("0", 0)
+
+	// seconds
+	f("10", 10*time.Second)
+	f("3600", maxRetryAfterDuration)
+
+	// http date
+	f(now.Add(30*time.Second).Format(http.TimeFormat), 30*time.Second)
+	f(now.Add(-30*time.Second).Format(http.TimeFormat), 0)
+}
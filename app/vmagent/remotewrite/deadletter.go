@@ -0,0 +1,158 @@
+package remotewrite
+
+import (
+	"flag"
+	"math"
+	"net/url"
+	"path/filepath"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	deadLetterPath = flag.String("remoteWrite.deadLetterPath", "", "Optional path to directory for writing samples rejected by vmagent to gzip-compressed files in VictoriaMetrics native format. "+
+		"Files are rotated according to -remoteWrite.bundleMaxFileSize and -remoteWrite.bundleMaxFileAge. "+
+		"See https://docs.victoriametrics.com/vmagent.html#dead-letter-queue")
+	deadLetterURL = flag.String("remoteWrite.deadLetterURL", "", "Optional remote storage URL for sending samples rejected by vmagent. It must support Prometheus remote_write protocol. "+
+		"See https://docs.victoriametrics.com/vmagent.html#dead-letter-queue")
+	deadLetterSampleRatio = flag.Float64("remoteWrite.deadLetterSampleRatio", 1, "The ratio of rejected series in the range (0..1] to write to -remoteWrite.deadLetterPath and -remoteWrite.deadLetterURL. "+
+		"The same series are selected on every rejection, so their samples are written without gaps")
+)
+
+// deadLetterReasonLabel is the label added to series written to dead-letter sinks.
+//
+// Its value contains the reason for the series rejection.
+const deadLetterReasonLabel = "vmagent_dead_letter_reason"
+
+var (
+	// deadLetterBundleWriter writes rejected series to -remoteWrite.deadLetterPath if it is set.
+	deadLetterBundleWriter *bundleWriter
+
+	// deadLetterRWCtx sends rejected series to -remoteWrite.deadLetterURL if it is set.
+	deadLetterRWCtx *remoteWriteCtx
+)
+
+func initDeadLetter() {
+	if *deadLetterSampleRatio <= 0 || *deadLetterSampleRatio > 1 {
+		logger.Fatalf("-remoteWrite.deadLetterSampleRatio must be in the range (0..1]; got %v", *deadLetterSampleRatio)
+	}
+	if *deadLetterPath != "" {
+		if *bundlePath != "" && filepath.Clean(*deadLetterPath) == filepath.Clean(*bundlePath) {
+			logger.Fatalf("-remoteWrite.deadLetterPath must differ from -remoteWrite.bundlePath=%q", *bundlePath)
+		}
+		deadLetterBundleWriter = mustOpenBundleWriter("remoteWrite.deadLetterPath", *deadLetterPath)
+	}
+	if *deadLetterURL != "" {
+		u, err := url.Parse(*deadLetterURL)
+		if err != nil {
+			logger.Fatalf("invalid -remoteWrite.deadLetterURL: %s", err)
+		}
+		sanitizedURL := "dead-letter:secret-url"
+		if *showRemoteWriteURL {
+			sanitizedURL = "dead-letter:" + u.String()
+		}
+		// Use the index after the last -remoteWrite.url, so the per-url options are applied to the dead-letter url
+		// only if they are set to a single value for all the urls.
+		deadLetterRWCtx = newRemoteWriteCtx(len(*remoteWriteURLs), nil, u, 2*(*queues), sanitizedURL)
+	}
+}
+
+func stopDeadLetter() {
+	if bw := deadLetterBundleWriter; bw != nil {
+		bw.MustStop()
+		deadLetterBundleWriter = nil
+	}
+	if rwctx := deadLetterRWCtx; rwctx != nil {
+		rwctx.MustStop()
+		deadLetterRWCtx = nil
+	}
+}
+
+func isDeadLetterEnabled() bool {
+	return deadLetterBundleWriter != nil || deadLetterRWCtx != nil
+}
+
+// pushDeadLetter writes a copy of tss with the given reason to the configured dead-letter sinks.
+//
+// Only -remoteWrite.deadLetterSampleRatio share of series is written.
+func pushDeadLetter(reason string, tss []prompbmarshal.TimeSeries) {
+	if !isDeadLetterEnabled() {
+		return
+	}
+	var dst []prompbmarshal.TimeSeries
+	for i := range tss {
+		ts := &tss[i]
+		if !isDeadLetterSampled(getLabelsHash(ts.Labels)) {
+			continue
+		}
+		labels := make([]prompbmarshal.Label, 0, len(ts.Labels)+1)
+		for _, label := range ts.Labels {
+			if label.Name == deadLetterReasonLabel {
+				continue
+			}
+			labels = append(labels, label)
+		}
+		labels = append(labels, prompbmarshal.Label{
+			Name:  deadLetterReasonLabel,
+			Value: reason,
+		})
+		dst = append(dst, prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: append([]prompbmarshal.Sample{}, ts.Samples...),
+		})
+	}
+	if len(dst) == 0 {
+		return
+	}
+	if bw := deadLetterBundleWriter; bw != nil {
+		bw.Push(dst)
+	}
+	if rwctx := deadLetterRWCtx; rwctx != nil {
+		// Relabeling and stream aggregation aren't applied to rejected series.
+		rwctx.pushInternal(dst)
+	}
+	deadLetterRowsWritten.Add(getRowsCount(dst))
+}
+
+// PushDeadLetterInvalidLine writes the given parse error for the line in the given ingestion protocol to the configured dead-letter sinks.
+//
+// The error is written as `vmagent_dead_letter_invalid_line{vmagent_dead_letter_reason="parse_error",type="<protocol>",error="<errMsg>"} 1`.
+func PushDeadLetterInvalidLine(protocol, errMsg string, timestamp int64) {
+	if !isDeadLetterEnabled() {
+		return
+	}
+	tss := []prompbmarshal.TimeSeries{{
+		Labels: []prompbmarshal.Label{
+			{
+				Name:  "__name__",
+				Value: "vmagent_dead_letter_invalid_line",
+			},
+			{
+				Name:  "type",
+				Value: protocol,
+			},
+			{
+				Name:  "error",
+				Value: errMsg,
+			},
+		},
+		Samples: []prompbmarshal.Sample{{
+			Value:     1,
+			Timestamp: timestamp,
+		}},
+	}}
+	pushDeadLetter("parse_error", tss)
+}
+
+// isDeadLetterSampled returns true if the series with the given labels hash h must be written to dead-letter sinks.
+func isDeadLetterSampled(h uint64) bool {
+	ratio := *deadLetterSampleRatio
+	if ratio >= 1 {
+		return true
+	}
+	return float64(h) < ratio*math.MaxUint64
+}
+
+var deadLetterRowsWritten = metrics.NewCounter(`vmagent_remotewrite_dead_letter_rows_written_total`)
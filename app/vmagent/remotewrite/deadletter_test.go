@@ -0,0 +1,104 @@
+package remotewrite
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/native/stream"
+)
+
+func TestPushDeadLetter(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	dir := t.TempDir()
+	deadLetterBundleWriter = mustOpenBundleWriter("remoteWrite.deadLetterPath", dir)
+	tss := []prompbmarshal.TimeSeries{
+		{
+			Labels: []prompbmarshal.Label{
+				{Name: "__name__", Value: "foo"},
+				{Name: deadLetterReasonLabel, Value: "must be overwritten"},
+			},
+			Samples: []prompbmarshal.Sample{
+				{Value: 1, Timestamp: 1000},
+			},
+		},
+	}
+	pushDeadLetter("hourly_series_limit", tss)
+	PushDeadLetterInvalidLine("prometheus", "cannot parse", 2000)
+	stopDeadLetter()
+
+	// The original series must remain unchanged.
+	if n := len(tss[0].Labels); n != 2 || tss[0].Labels[1].Value != "must be overwritten" {
+		t.Fatalf("unexpected modification of the original labels: %v", tss[0].Labels)
+	}
+
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("cannot read %q: %s", dir, err)
+	}
+	if len(des) != 1 {
+		t.Fatalf("unexpected number of files in %q; got %d; want 1", dir, len(des))
+	}
+	f, err := os.Open(filepath.Join(dir, des[0].Name()))
+	if err != nil {
+		t.Fatalf("cannot open dead-letter file: %s", err)
+	}
+	defer func() { _ = f.Close() }()
+	var mu sync.Mutex
+	var rows []string
+	err = stream.Parse(f, "gzip", func(block *stream.Block) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := range block.Timestamps {
+			rows = append(rows, fmt.Sprintf("%s %g %d", block.MetricName.String(), block.Values[i], block.Timestamps[i]))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("cannot parse dead-letter file: %s", err)
+	}
+	sort.Strings(rows)
+	rowsExpected := []string{
+		`foo{vmagent_dead_letter_reason="hourly_series_limit"} 1 1000`,
+		`vmagent_dead_letter_invalid_line{type="prometheus",error="cannot parse",vmagent_dead_letter_reason="parse_error"} 1 2000`,
+	}
+	if !reflect.DeepEqual(rows, rowsExpected) {
+		t.Fatalf("unexpected rows;\ngot\n%q\nwant\n%q", rows, rowsExpected)
+	}
+}
+
+func TestIsDeadLetterSampled(t *testing.T) {
+	f := func(ratio float64, minExpected, maxExpected int) {
+		t.Helper()
+		origRatio := *deadLetterSampleRatio
+		*deadLetterSampleRatio = ratio
+		defer func() {
+			*deadLetterSampleRatio = origRatio
+		}()
+		n := 0
+		for i := 0; i < 10000; i++ {
+			labels := []prompbmarshal.Label{{Name: "__name__", Value: fmt.Sprintf("metric_%d", i)}}
+			h := getLabelsHash(labels)
+			if isDeadLetterSampled(h) {
+				n++
+			}
+			if isDeadLetterSampled(h) != isDeadLetterSampled(getLabelsHash(labels)) {
+				t.Fatalf("sampling must be deterministic for the same series")
+			}
+		}
+		if n < minExpected || n > maxExpected {
+			t.Fatalf("unexpected number of sampled series for ratio=%v; got %d; want [%d..%d]", ratio, n, minExpected, maxExpected)
+		}
+	}
+	f(1, 10000, 10000)
+	f(0.5, 4500, 5500)
+	f(0.01, 50, 150)
+}
@@ -2,6 +2,7 @@ package remotewrite
 
 import (
 	"flag"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -20,10 +21,18 @@ import (
 )
 
 var (
-	flushInterval = flag.Duration("remoteWrite.flushInterval", time.Second, "Interval for flushing the data to remote storage. "+
-		"This option takes effect only when less than 10K data points per second are pushed to -remoteWrite.url")
-	maxUnpackedBlockSize = flagutil.NewBytes("remoteWrite.maxBlockSize", 8*1024*1024, "The maximum block size to send to remote storage. Bigger blocks may improve performance at the cost of the increased memory usage. See also -remoteWrite.maxRowsPerBlock")
-	maxRowsPerBlock      = flag.Int("remoteWrite.maxRowsPerBlock", 10000, "The maximum number of samples to send in each block to remote storage. Higher number may improve performance at the cost of the increased memory usage. See also -remoteWrite.maxBlockSize")
+	flushInterval = flagutil.NewArrayDuration("remoteWrite.flushInterval", "Interval for flushing the data to the corresponding -remoteWrite.url. "+
+		"This option takes effect only when less than 10K data points per second are pushed to -remoteWrite.url. By default 1s is used")
+	maxUnpackedBlockSize = flagutil.NewArrayBytes("remoteWrite.maxBlockSize", "The maximum block size to send to the corresponding -remoteWrite.url. "+
+		"Bigger blocks may improve performance at the cost of the increased memory usage. By default 8MB is used. See also -remoteWrite.maxRowsPerBlock")
+	compressionCodec = flagutil.NewArrayString("remoteWrite.compressionCodec", "Compression codec to use for sending data to the corresponding -remoteWrite.url. "+
+		"Supported values: snappy, zstd. The zstd codec is supported only by VictoriaMetrics remote storage and it enables VictoriaMetrics remote write protocol. "+
+		"By default zstd is used if -remoteWrite.useVMProto is set, otherwise snappy is used. "+
+		"See https://docs.victoriametrics.com/vmagent.html#compression-tuning")
+	compressionLevel = flagutil.NewArrayInt("remoteWrite.compressionLevel", "Compression level for zstd codec at the corresponding -remoteWrite.url. "+
+		"Higher levels reduce network bandwidth usage at the cost of higher CPU usage. Zero means the default level. "+
+		"This option is ignored for snappy codec. See https://docs.victoriametrics.com/vmagent.html#compression-tuning")
+	maxRowsPerBlock = flag.Int("remoteWrite.maxRowsPerBlock", 10000, "The maximum number of samples to send in each block to remote storage. Higher number may improve performance at the cost of the increased memory usage. See also -remoteWrite.maxBlockSize")
 )
 
 type pendingSeries struct {
@@ -34,17 +43,60 @@ type pendingSeries struct {
 	periodicFlusherWG sync.WaitGroup
 }
 
-func newPendingSeries(pushBlock func(block []byte), isVMRemoteWrite bool, significantFigures, roundDigits int) *pendingSeries {
+// writeRequestEncoding holds settings for encoding write requests into blocks for a particular -remoteWrite.url.
+type writeRequestEncoding struct {
+	// Whether to encode write requests with VictoriaMetrics remote write protocol, e.g. to compress them with zstd instead of snappy.
+	isVMRemoteWrite bool
+
+	// zstd compression level if isVMRemoteWrite is set.
+	zstdLevel int
+
+	// The maximum size of the unpacked block.
+	maxUnpackedBlockSize int
+}
+
+// getWriteRequestEncoding returns writeRequestEncoding for -remoteWrite.url with the given argIdx.
+func getWriteRequestEncoding(argIdx int) (*writeRequestEncoding, error) {
+	isVMRemoteWrite, err := isZstdCodec(compressionCodec.GetOptionalArg(argIdx), useVMProto.GetOptionalArg(argIdx))
+	if err != nil {
+		return nil, err
+	}
+	enc := &writeRequestEncoding{
+		isVMRemoteWrite:      isVMRemoteWrite,
+		zstdLevel:            compressionLevel.GetOptionalArgOrDefault(argIdx, 0),
+		maxUnpackedBlockSize: int(maxUnpackedBlockSize.GetOptionalArgOrDefault(argIdx, 8*1024*1024)),
+	}
+	return enc, nil
+}
+
+// isZstdCodec returns true if the given -remoteWrite.compressionCodec value results in zstd compression.
+func isZstdCodec(codec string, isVMProto bool) (bool, error) {
+	switch codec {
+	case "":
+		return isVMProto, nil
+	case "zstd":
+		return true, nil
+	case "snappy":
+		if isVMProto {
+			return false, fmt.Errorf("-remoteWrite.compressionCodec=snappy cannot be used together with -remoteWrite.useVMProto, since VictoriaMetrics remote write protocol uses zstd")
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported -remoteWrite.compressionCodec=%q; supported values: snappy, zstd", codec)
+	}
+}
+
+func newPendingSeries(pushBlock func(block []byte), enc *writeRequestEncoding, flushInterval time.Duration, significantFigures, roundDigits int) *pendingSeries {
 	var ps pendingSeries
 	ps.wr.pushBlock = pushBlock
-	ps.wr.isVMRemoteWrite = isVMRemoteWrite
+	ps.wr.enc = enc
 	ps.wr.significantFigures = significantFigures
 	ps.wr.roundDigits = roundDigits
 	ps.stopCh = make(chan struct{})
 	ps.periodicFlusherWG.Add(1)
 	go func() {
 		defer ps.periodicFlusherWG.Done()
-		ps.periodicFlusher()
+		ps.periodicFlusher(flushInterval)
 	}()
 	return &ps
 }
@@ -60,12 +112,12 @@ func (ps *pendingSeries) Push(tss []prompbmarshal.TimeSeries) {
 	ps.mu.Unlock()
 }
 
-func (ps *pendingSeries) periodicFlusher() {
+func (ps *pendingSeries) periodicFlusher(flushInterval time.Duration) {
 	flushSeconds := int64(flushInterval.Seconds())
 	if flushSeconds <= 0 {
 		flushSeconds = 1
 	}
-	ticker := time.NewTicker(*flushInterval)
+	ticker := time.NewTicker(flushInterval)
 	defer ticker.Stop()
 	mustStop := false
 	for !mustStop {
@@ -90,8 +142,8 @@ type writeRequest struct {
 	// pushBlock is called when whe write request is ready to be sent.
 	pushBlock func(block []byte)
 
-	// Whether to encode the write request with VictoriaMetrics remote write protocol.
-	isVMRemoteWrite bool
+	// enc contains settings for encoding the write request into a block.
+	enc *writeRequestEncoding
 
 	// How many significant figures must be left before sending the writeRequest to pushBlock.
 	significantFigures int
@@ -109,7 +161,7 @@ type writeRequest struct {
 }
 
 func (wr *writeRequest) reset() {
-	// Do not reset lastFlushTime, pushBlock, isVMRemoteWrite, significantFigures and roundDigits, since they are re-used.
+	// Do not reset lastFlushTime, pushBlock, enc, significantFigures and roundDigits, since they are re-used.
 
 	wr.wr.Timeseries = nil
 
@@ -131,7 +183,7 @@ func (wr *writeRequest) flush() {
 	wr.wr.Timeseries = wr.tss
 	wr.adjustSampleValues()
 	atomic.StoreUint64(&wr.lastFlushTime, fasttime.UnixTimestamp())
-	pushWriteRequest(&wr.wr, wr.pushBlock, wr.isVMRemoteWrite)
+	pushWriteRequest(&wr.wr, wr.pushBlock, wr.enc)
 	wr.reset()
 }
 
@@ -193,17 +245,17 @@ func (wr *writeRequest) copyTimeSeries(dst, src *prompbmarshal.TimeSeries) {
 	wr.buf = buf
 }
 
-func pushWriteRequest(wr *prompbmarshal.WriteRequest, pushBlock func(block []byte), isVMRemoteWrite bool) {
+func pushWriteRequest(wr *prompbmarshal.WriteRequest, pushBlock func(block []byte), enc *writeRequestEncoding) {
 	if len(wr.Timeseries) == 0 {
 		// Nothing to push
 		return
 	}
 	bb := writeRequestBufPool.Get()
 	bb.B = prompbmarshal.MarshalWriteRequest(bb.B[:0], wr)
-	if len(bb.B) <= maxUnpackedBlockSize.IntN() {
+	if len(bb.B) <= enc.maxUnpackedBlockSize {
 		zb := snappyBufPool.Get()
-		if isVMRemoteWrite {
-			zb.B = zstd.CompressLevel(zb.B[:0], bb.B, 0)
+		if enc.isVMRemoteWrite {
+			zb.B = zstd.CompressLevel(zb.B[:0], bb.B, enc.zstdLevel)
 		} else {
 			zb.B = snappy.Encode(zb.B[:cap(zb.B)], bb.B)
 		}
@@ -225,23 +277,23 @@ func pushWriteRequest(wr *prompbmarshal.WriteRequest, pushBlock func(block []byt
 		// A single time series left. Recursively split its samples into smaller parts if possible.
 		samples := wr.Timeseries[0].Samples
 		if len(samples) == 1 {
-			logger.Warnf("dropping a sample for metric with too long labels exceeding -remoteWrite.maxBlockSize=%d bytes", maxUnpackedBlockSize.N)
+			logger.Warnf("dropping a sample for metric with too long labels exceeding -remoteWrite.maxBlockSize=%d bytes", enc.maxUnpackedBlockSize)
 			return
 		}
 		n := len(samples) / 2
 		wr.Timeseries[0].Samples = samples[:n]
-		pushWriteRequest(wr, pushBlock, isVMRemoteWrite)
+		pushWriteRequest(wr, pushBlock, enc)
 		wr.Timeseries[0].Samples = samples[n:]
-		pushWriteRequest(wr, pushBlock, isVMRemoteWrite)
+		pushWriteRequest(wr, pushBlock, enc)
 		wr.Timeseries[0].Samples = samples
 		return
 	}
 	timeseries := wr.Timeseries
 	n := len(timeseries) / 2
 	wr.Timeseries = timeseries[:n]
-	pushWriteRequest(wr, pushBlock, isVMRemoteWrite)
+	pushWriteRequest(wr, pushBlock, enc)
 	wr.Timeseries = timeseries[n:]
-	pushWriteRequest(wr, pushBlock, isVMRemoteWrite)
+	pushWriteRequest(wr, pushBlock, enc)
 	wr.Timeseries = timeseries
 }
 
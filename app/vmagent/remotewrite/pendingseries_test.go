@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
@@ -31,7 +32,11 @@ func testPushWriteRequest(t *testing.T, rowsCount, expectedBlockLenProm, expecte
 			}
 			pushBlockLen = len(block)
 		}
-		pushWriteRequest(wr, pushBlock, isVMRemoteWrite)
+		enc := &writeRequestEncoding{
+			isVMRemoteWrite:      isVMRemoteWrite,
+			maxUnpackedBlockSize: 8 * 1024 * 1024,
+		}
+		pushWriteRequest(wr, pushBlock, enc)
 		if pushBlockLen != expectedBlockLen {
 			t.Fatalf("unexpected block len for rowsCount=%d, isVMRemoteWrite=%v; got %d bytes; expecting %d bytes",
 				rowsCount, isVMRemoteWrite, pushBlockLen, expectedBlockLen)
@@ -45,6 +50,90 @@ func testPushWriteRequest(t *testing.T, rowsCount, expectedBlockLenProm, expecte
 	f(true, expectedBlockLenVM)
 }
 
+func TestPushWriteRequestEncoding(t *testing.T) {
+	f := func(enc *writeRequestEncoding, expectedBlocks int) []byte {
+		t.Helper()
+		wr := newTestWriteRequest(1000, 20)
+		var blocks [][]byte
+		pushBlock := func(block []byte) {
+			blocks = append(blocks, append([]byte{}, block...))
+		}
+		pushWriteRequest(wr, pushBlock, enc)
+		if len(blocks) != expectedBlocks {
+			t.Fatalf("unexpected number of blocks; got %d; want %d", len(blocks), expectedBlocks)
+		}
+		return blocks[0]
+	}
+
+	// All the zstd compression levels must result in the same unpacked block
+	var dataExpected []byte
+	for _, level := range []int{0, 1, 3, 9, 19} {
+		block := f(&writeRequestEncoding{
+			isVMRemoteWrite:      true,
+			zstdLevel:            level,
+			maxUnpackedBlockSize: 8 * 1024 * 1024,
+		}, 1)
+		data, err := zstd.Decompress(nil, block)
+		if err != nil {
+			t.Fatalf("cannot decompress block for zstd level %d: %s", level, err)
+		}
+		if dataExpected == nil {
+			dataExpected = data
+		}
+		if string(data) != string(dataExpected) {
+			t.Fatalf("unexpected unpacked block for zstd level %d", level)
+		}
+	}
+
+	// zstd level must be ignored for snappy
+	blockSnappy := f(&writeRequestEncoding{
+		maxUnpackedBlockSize: 8 * 1024 * 1024,
+	}, 1)
+	blockSnappyLevel := f(&writeRequestEncoding{
+		zstdLevel:            19,
+		maxUnpackedBlockSize: 8 * 1024 * 1024,
+	}, 1)
+	if len(blockSnappy) != len(blockSnappyLevel) {
+		t.Fatalf("unexpected block size for snappy with zstd level; got %d bytes; want %d bytes", len(blockSnappyLevel), len(blockSnappy))
+	}
+
+	// Small maxUnpackedBlockSize must result in multiple blocks
+	f(&writeRequestEncoding{
+		isVMRemoteWrite:      true,
+		maxUnpackedBlockSize: 64 * 1024,
+	}, 16)
+}
+
+func TestIsZstdCodec(t *testing.T) {
+	f := func(codec string, isVMProto, resultExpected bool) {
+		t.Helper()
+		result, err := isZstdCodec(codec, isVMProto)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected result for codec=%q, isVMProto=%v; got %v; want %v", codec, isVMProto, result, resultExpected)
+		}
+	}
+	f("", false, false)
+	f("", true, true)
+	f("snappy", false, false)
+	f("zstd", false, true)
+	f("zstd", true, true)
+}
+
+func TestIsZstdCodecFailure(t *testing.T) {
+	f := func(codec string, isVMProto bool) {
+		t.Helper()
+		if _, err := isZstdCodec(codec, isVMProto); err == nil {
+			t.Fatalf("expecting non-nil error for codec=%q, isVMProto=%v", codec, isVMProto)
+		}
+	}
+	f("snappy", true)
+	f("gzip", false)
+	f("ZSTD", false)
+}
+
 func newTestWriteRequest(seriesCount, labelsCount int) *prompbmarshal.WriteRequest {
 	var wr prompbmarshal.WriteRequest
 	for i := 0; i < seriesCount; i++ {
@@ -3,13 +3,16 @@ package remotewrite
 import (
 	"flag"
 	"fmt"
+	"io"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
@@ -23,6 +26,15 @@ var (
 		"See also -remoteWrite.relabelConfig. The path can point either to local file or to http url. "+
 		"See https://docs.victoriametrics.com/vmagent.html#relabeling")
 
+	relabelDebugGlobal = flag.Bool("remoteWrite.relabelDebug", false, "Whether to log sampled label sets before and after applying -remoteWrite.relabelConfig and -remoteWrite.label. "+
+		"This may be useful for verifying relabeling rules on production data. See also -remoteWrite.urlRelabelDebug and -remoteWrite.relabelDebugSampleEvery. "+
+		"See https://docs.victoriametrics.com/vmagent.html#relabel-debug")
+	relabelDebug = flagutil.NewArrayBool("remoteWrite.urlRelabelDebug", "Whether to log sampled label sets before and after applying -remoteWrite.urlRelabelConfig "+
+		"for the corresponding -remoteWrite.url. See also -remoteWrite.relabelDebug and -remoteWrite.relabelDebugSampleEvery. "+
+		"See https://docs.victoriametrics.com/vmagent.html#relabel-debug")
+	relabelDebugSampleEvery = flag.Int("remoteWrite.relabelDebugSampleEvery", 1000, "Log every N-th series passed through relabeling "+
+		"if -remoteWrite.relabelDebug or -remoteWrite.urlRelabelDebug is set")
+
 	usePromCompatibleNaming = flag.Bool("usePromCompatibleNaming", false, "Whether to replace characters unsupported by Prometheus with underscores "+
 		"in the ingested metric names and label names. For example, foo.bar{a.b='c'} is transformed into foo_bar{a_b='c'} during data ingestion if this flag is set. "+
 		"See https://prometheus.io/docs/concepts/data_model/#metric-names-and-labels")
@@ -30,10 +42,22 @@ var (
 
 var labelsGlobal []prompbmarshal.Label
 
-// CheckRelabelConfigs checks -remoteWrite.relabelConfig and -remoteWrite.urlRelabelConfig.
-func CheckRelabelConfigs() error {
-	_, err := loadRelabelConfigs()
-	return err
+// WriteRelabelConfigs checks -remoteWrite.relabelConfig and -remoteWrite.urlRelabelConfig and writes the parsed relabeling rules to w.
+func WriteRelabelConfigs(w io.Writer) error {
+	rcs, err := loadRelabelConfigs()
+	if err != nil {
+		return err
+	}
+	if *relabelConfigPathGlobal != "" {
+		fmt.Fprintf(w, "# -remoteWrite.relabelConfig=%q\n%s", *relabelConfigPathGlobal, rcs.global)
+	}
+	for i, pcs := range rcs.perURL {
+		if pcs == nil {
+			continue
+		}
+		fmt.Fprintf(w, "# -remoteWrite.urlRelabelConfig=%q for -remoteWrite.url #%d\n%s", (*relabelConfigPaths)[i], i+1, pcs)
+	}
+	return nil
 }
 
 func loadRelabelConfigs() (*relabelConfigs, error) {
@@ -87,7 +111,10 @@ func initLabelsGlobal() {
 	}
 }
 
-func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, extraLabels []prompbmarshal.Label, pcs *promrelabel.ParsedConfigs) []prompbmarshal.TimeSeries {
+// applyRelabeling applies extraLabels and pcs to tss and returns the result.
+//
+// Sampled label sets before and after the relabeling are logged via rd if it isn't nil.
+func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, extraLabels []prompbmarshal.Label, pcs *promrelabel.ParsedConfigs, rd *relabelDebugger) []prompbmarshal.TimeSeries {
 	if len(extraLabels) == 0 && pcs.Len() == 0 && !*usePromCompatibleNaming {
 		// Nothing to change.
 		return tss
@@ -98,6 +125,9 @@ func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, extraLab
 		ts := &tss[i]
 		labelsLen := len(labels)
 		labels = append(labels, ts.Labels...)
+		if rd != nil {
+			rctx.labelsBefore = append(rctx.labelsBefore[:0], ts.Labels...)
+		}
 		// extraLabels must be added before applying relabeling according to https://prometheus.io/docs/prometheus/latest/configuration/configuration/#remote_write
 		for j := range extraLabels {
 			extraLabel := &extraLabels[j]
@@ -122,6 +152,9 @@ func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, extraLab
 		}
 		labels = pcs.Apply(labels, labelsLen)
 		labels = promrelabel.FinalizeLabels(labels[:labelsLen], labels[labelsLen:])
+		if rd != nil {
+			rd.observe(rctx.labelsBefore, labels[labelsLen:])
+		}
 		if len(labels) == labelsLen {
 			// Drop the current time series, since relabeling removed all the labels.
 			continue
@@ -138,11 +171,16 @@ func (rctx *relabelCtx) applyRelabeling(tss []prompbmarshal.TimeSeries, extraLab
 type relabelCtx struct {
 	// pool for labels, which are used during the relabeling.
 	labels []prompbmarshal.Label
+
+	// labelsBefore holds a copy of labels for the currently relabeled series if relabel debug is enabled.
+	labelsBefore []prompbmarshal.Label
 }
 
 func (rctx *relabelCtx) reset() {
 	promrelabel.CleanLabels(rctx.labels)
 	rctx.labels = rctx.labels[:0]
+	promrelabel.CleanLabels(rctx.labelsBefore)
+	rctx.labelsBefore = rctx.labelsBefore[:0]
 }
 
 var relabelCtxPool = &sync.Pool{
@@ -157,5 +195,58 @@ func getRelabelCtx() *relabelCtx {
 
 func putRelabelCtx(rctx *relabelCtx) {
 	rctx.labels = rctx.labels[:0]
+	rctx.labelsBefore = rctx.labelsBefore[:0]
 	relabelCtxPool.Put(rctx)
 }
+
+// relabelDebugger logs sampled label sets before and after relabeling.
+//
+// See https://docs.victoriametrics.com/vmagent.html#relabel-debug
+type relabelDebugger struct {
+	// name is the name of the relabeling stage, which is used in logs.
+	name string
+
+	seriesObserved uint64
+
+	seriesChanged *metrics.Counter
+}
+
+func newRelabelDebugger(name, seriesChangedMetric string) *relabelDebugger {
+	return &relabelDebugger{
+		name:          name,
+		seriesChanged: metrics.GetOrCreateCounter(seriesChangedMetric),
+	}
+}
+
+// observe registers labelsAfter obtained after relabeling labelsBefore.
+//
+// Empty labelsAfter means the series is dropped.
+func (rd *relabelDebugger) observe(labelsBefore, labelsAfter []prompbmarshal.Label) {
+	if !labelsEqual(labelsBefore, labelsAfter) {
+		rd.seriesChanged.Inc()
+	}
+	n := atomic.AddUint64(&rd.seriesObserved, 1)
+	sampleEvery := uint64(*relabelDebugSampleEvery)
+	if sampleEvery > 1 && n%sampleEvery != 1 {
+		return
+	}
+	after := "<dropped>"
+	if len(labelsAfter) > 0 {
+		after = promrelabel.LabelsToString(labelsAfter)
+	}
+	logger.Infof("relabel debug for %s: %s => %s", rd.name, promrelabel.LabelsToString(labelsBefore), after)
+}
+
+// labelsEqual returns true if a and b contain the same labels in any order.
+func labelsEqual(a, b []prompbmarshal.Label) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		label := promrelabel.GetLabelByName(b, a[i].Name)
+		if label == nil || label.Value != a[i].Value {
+			return false
+		}
+	}
+	return true
+}
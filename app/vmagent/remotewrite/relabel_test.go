@@ -2,6 +2,7 @@ package remotewrite
 
 import (
 	"reflect"
+	"sync/atomic"
 	"testing"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
@@ -13,7 +14,7 @@ func TestApplyRelabeling(t *testing.T) {
 	f := func(extraLabels []prompbmarshal.Label, pcs *promrelabel.ParsedConfigs, sTss, sExpTss string) {
 		rctx := &relabelCtx{}
 		tss, expTss := parseSeries(sTss), parseSeries(sExpTss)
-		gotTss := rctx.applyRelabeling(tss, extraLabels, pcs)
+		gotTss := rctx.applyRelabeling(tss, extraLabels, pcs, nil)
 		if !reflect.DeepEqual(gotTss, expTss) {
 			t.Fatalf("expected to have: \n%v;\ngot: \n%v", expTss, gotTss)
 		}
@@ -40,6 +41,59 @@ func TestApplyRelabeling(t *testing.T) {
 	*usePromCompatibleNaming = oldVal
 }
 
+func TestApplyRelabelingDebug(t *testing.T) {
+	pcs, err := promrelabel.ParseRelabelConfigsData([]byte(`
+- target_label: "foo"
+  replacement: "aaa"
+- action: drop
+  source_labels: [env]
+  regex: "dev"
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	rd := newRelabelDebugger("test", `vmagent_remotewrite_relabel_debug_series_changed_total{url="TestApplyRelabelingDebug"}`)
+	f := func(sTss, sExpTss string, seriesChangedExpected uint64) {
+		t.Helper()
+		rctx := &relabelCtx{}
+		tss := parseSeries(sTss)
+		expTss := []prompbmarshal.TimeSeries{}
+		if sExpTss != "" {
+			expTss = parseSeries(sExpTss)
+		}
+		gotTss := rctx.applyRelabeling(tss, nil, pcs, rd)
+		if !reflect.DeepEqual(gotTss, expTss) {
+			t.Fatalf("expected to have: \n%v;\ngot: \n%v", expTss, gotTss)
+		}
+		if n := rd.seriesChanged.Get(); n != seriesChangedExpected {
+			t.Fatalf("unexpected number of changed series; got %d; want %d", n, seriesChangedExpected)
+		}
+	}
+
+	f(`up{foo="aaa"}`, `up{foo="aaa"}`, 0)
+	f(`up{foo="bar"}`, `up{foo="aaa"}`, 1)
+	f(`up{env="dev"}`, ``, 2)
+	if n := atomic.LoadUint64(&rd.seriesObserved); n != 3 {
+		t.Fatalf("unexpected number of observed series; got %d; want 3", n)
+	}
+}
+
+func TestLabelsEqual(t *testing.T) {
+	f := func(a, b string, resultExpected bool) {
+		t.Helper()
+		la := promutils.MustNewLabelsFromString(a).GetLabels()
+		lb := promutils.MustNewLabelsFromString(b).GetLabels()
+		if result := labelsEqual(la, lb); result != resultExpected {
+			t.Fatalf("unexpected result for labelsEqual(%s, %s); got %v; want %v", a, b, result, resultExpected)
+		}
+	}
+	f(`up`, `up`, true)
+	f(`up{a="b",c="d"}`, `up{c="d",a="b"}`, true)
+	f(`up{a="b"}`, `up{a="c"}`, false)
+	f(`up{a="b"}`, `up{a="b",c="d"}`, false)
+	f(`up`, `down`, false)
+}
+
 func parseSeries(data string) []prompbmarshal.TimeSeries {
 	var tss []prompbmarshal.TimeSeries
 	tss = append(tss, prompbmarshal.TimeSeries{
@@ -5,14 +5,18 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/actions"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bloomfilter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cpupool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -41,6 +45,11 @@ var (
 		"See https://docs.victoriametrics.com/vmagent.html#victoriametrics-remote-write-protocol")
 	tmpDataPath = flag.String("remoteWrite.tmpDataPath", "vmagent-remotewrite-data", "Path to directory where temporary data for remote write component is stored. "+
 		"See also -remoteWrite.maxDiskUsagePerURL")
+	tmpDataRemotePath = flag.String("remoteWrite.tmpDataRemotePath", "", "Optional path at object storage for storing the buffered data for -remoteWrite.url "+
+		"instead of -remoteWrite.tmpDataPath. For example, s3://bucket/prefix, gs://bucket/prefix or azblob://container/prefix. "+
+		"Every vmagent instance must use a unique path. See https://docs.victoriametrics.com/vmagent.html#object-storage-buffer")
+	tmpDataRemoteChunkSize = flagutil.NewBytes("remoteWrite.tmpDataRemoteChunkSize", 16*1024*1024, "The size of chunks, which are uploaded to -remoteWrite.tmpDataRemotePath. "+
+		"Bigger chunks reduce the number of requests to object storage, while smaller chunks reduce the amount of data lost on unclean shutdown")
 	queues = flag.Int("remoteWrite.queues", cgroup.AvailableCPUs()*2, "The number of concurrent queues to each -remoteWrite.url. Set more queues if default number of queues "+
 		"isn't enough for sending high volume of collected data to remote storage. Default value is 2 * numberOfAvailableCPUs")
 	showRemoteWriteURL = flag.Bool("remoteWrite.showURL", false, "Whether to show -remoteWrite.url in the exported metrics. "+
@@ -73,6 +82,9 @@ var (
 		"See https://docs.victoriametrics.com/stream-aggregation.html")
 	streamAggrDedupInterval = flagutil.NewArrayDuration("remoteWrite.streamAggr.dedupInterval", "Input samples are de-duplicated with this interval before being aggregated. "+
 		"Only the last sample per each time series per each interval is aggregated if the interval is greater than zero")
+	cpuPools = flag.String("remoteWrite.cpuPools", "", "Optional CPU sets for pinning workers, which send data to -remoteWrite.url. "+
+		"Set it to 'numa' for a CPU set per NUMA node or to semicolon-delimited list of CPU sets such as '0-23,48-71;24-47,72-95'. "+
+		"Workers are evenly spread among the CPU sets. This flag is supported only on Linux. See https://docs.victoriametrics.com/vmagent.html#cpu-pinning")
 )
 
 var (
@@ -85,6 +97,12 @@ var (
 
 	// Data without tenant id is written to defaultAuthToken if -remoteWrite.multitenantURL is specified.
 	defaultAuthToken = &auth.Token{}
+
+	// bundleWriterDefault writes data to -remoteWrite.bundlePath if it is set.
+	bundleWriterDefault *bundleWriter
+
+	// rwCPUPools contains optional CPU sets for remote write workers. See -remoteWrite.cpuPools.
+	rwCPUPools *cpupool.Pools
 )
 
 // MultitenancyEnabled returns true if -remoteWrite.multitenantURL is specified.
@@ -95,6 +113,9 @@ func MultitenancyEnabled() bool {
 // Contains the current relabelConfigs.
 var allRelabelConfigs atomic.Value
 
+// globalRelabelDebugger is non-nil if -remoteWrite.relabelDebug is set.
+var globalRelabelDebugger *relabelDebugger
+
 // maxQueues limits the maximum value for `-remoteWrite.queues`. There is no sense in setting too high value,
 // since it may lead to high memory usage due to big number of buffers.
 var maxQueues = cgroup.AvailableCPUs() * 16
@@ -113,8 +134,8 @@ func InitSecretFlags() {
 //
 // Stop must be called for graceful shutdown.
 func Init() {
-	if len(*remoteWriteURLs) == 0 && len(*remoteWriteMultitenantURLs) == 0 {
-		logger.Fatalf("at least one `-remoteWrite.url` or `-remoteWrite.multitenantURL` command-line flag must be set")
+	if len(*remoteWriteURLs) == 0 && len(*remoteWriteMultitenantURLs) == 0 && *bundlePath == "" {
+		logger.Fatalf("at least one `-remoteWrite.url`, `-remoteWrite.multitenantURL` or `-remoteWrite.bundlePath` command-line flag must be set")
 	}
 	if len(*remoteWriteURLs) > 0 && len(*remoteWriteMultitenantURLs) > 0 {
 		logger.Fatalf("cannot set both `-remoteWrite.url` and `-remoteWrite.multitenantURL` command-line flags")
@@ -143,7 +164,15 @@ func Init() {
 	if *queues <= 0 {
 		*queues = 1
 	}
+	ps, err := cpupool.NewPools("remotewrite", *cpuPools)
+	if err != nil {
+		logger.Fatalf("cannot initialize -remoteWrite.cpuPools=%q: %s", *cpuPools, err)
+	}
+	rwCPUPools = ps
 	initLabelsGlobal()
+	if *relabelDebugGlobal {
+		globalRelabelDebugger = newRelabelDebugger("-remoteWrite.relabelConfig", `vmagent_remotewrite_global_relabel_debug_series_changed_total`)
+	}
 
 	// Register SIGHUP handler for config reload before loadRelabelConfigs.
 	// This guarantees that the config will be re-read if the signal arrives just after loadRelabelConfig.
@@ -162,6 +191,10 @@ func Init() {
 	if len(*remoteWriteURLs) > 0 {
 		rwctxsDefault = newRemoteWriteCtxs(nil, *remoteWriteURLs)
 	}
+	if *bundlePath != "" {
+		bundleWriterDefault = mustOpenBundleWriter("remoteWrite.bundlePath", *bundlePath)
+	}
+	initDeadLetter()
 
 	// Start config reloader.
 	configReloaderWG.Add(1)
@@ -186,6 +219,9 @@ func Init() {
 			allRelabelConfigs.Store(rcs)
 			configSuccess.Set(1)
 			configTimestamp.Set(fasttime.UnixTimestamp())
+			ae := auditlog.NewEvent(nil, "config_applied")
+			ae.Target = "-remoteWrite.relabelConfig,-remoteWrite.urlRelabelConfig"
+			auditlog.Log(ae, nil)
 			logger.Infof("Successfully reloaded relabel configs")
 		}
 	}()
@@ -229,6 +265,7 @@ func newRemoteWriteCtxs(at *auth.Token, urls []string) []*remoteWriteCtx {
 			sanitizedURL = fmt.Sprintf("%d:%s", i+1, remoteWriteURL)
 		}
 		rwctxs[i] = newRemoteWriteCtx(i, at, remoteWriteURL, maxInmemoryBlocks, sanitizedURL)
+		rwctxs[i].initStreamAggr()
 	}
 	return rwctxs
 }
@@ -256,12 +293,20 @@ func Stop() {
 	}
 	rwctxsMap = nil
 
+	if bw := bundleWriterDefault; bw != nil {
+		bw.MustStop()
+		bundleWriterDefault = nil
+	}
+	stopDeadLetter()
+
 	if sl := hourlySeriesLimiter; sl != nil {
 		sl.MustStop()
 	}
 	if sl := dailySeriesLimiter; sl != nil {
 		sl.MustStop()
 	}
+	rwCPUPools.MustStop()
+	rwCPUPools = nil
 }
 
 // Push sends wr to remote storage systems set via `-remoteWrite.url`.
@@ -329,13 +374,16 @@ func Push(at *auth.Token, wr *prompbmarshal.WriteRequest) {
 		}
 		if rctx != nil {
 			rowsCountBeforeRelabel := getRowsCount(tssBlock)
-			tssBlock = rctx.applyRelabeling(tssBlock, labelsGlobal, pcsGlobal)
+			tssBlock = rctx.applyRelabeling(tssBlock, labelsGlobal, pcsGlobal, globalRelabelDebugger)
 			rowsCountAfterRelabel := getRowsCount(tssBlock)
 			rowsDroppedByGlobalRelabel.Add(rowsCountBeforeRelabel - rowsCountAfterRelabel)
 		}
 		sortLabelsIfNeeded(tssBlock)
 		tssBlock = limitSeriesCardinality(tssBlock)
 		pushBlockToRemoteStorages(rwctxs, tssBlock)
+		if bw := bundleWriterDefault; bw != nil && len(tssBlock) > 0 {
+			bw.Push(tssBlock)
+		}
 		if rctx != nil {
 			rctx.reset()
 		}
@@ -383,11 +431,13 @@ func limitSeriesCardinality(tss []prompbmarshal.TimeSeries) []prompbmarshal.Time
 		if hourlySeriesLimiter != nil && !hourlySeriesLimiter.Add(h) {
 			hourlySeriesLimitRowsDropped.Add(len(tss[i].Samples))
 			logSkippedSeries(labels, "-remoteWrite.maxHourlySeries", hourlySeriesLimiter.MaxItems())
+			pushDeadLetter("hourly_series_limit", tss[i:i+1])
 			continue
 		}
 		if dailySeriesLimiter != nil && !dailySeriesLimiter.Add(h) {
 			dailySeriesLimitRowsDropped.Add(len(tss[i].Samples))
 			logSkippedSeries(labels, "-remoteWrite.maxDailySeries", dailySeriesLimiter.MaxItems())
+			pushDeadLetter("daily_series_limit", tss[i:i+1])
 			continue
 		}
 		dst = append(dst, tss[i])
@@ -463,6 +513,9 @@ type remoteWriteCtx struct {
 
 	rowsPushedAfterRelabel *metrics.Counter
 	rowsDroppedByRelabel   *metrics.Counter
+
+	// relabelDebugger is non-nil if -remoteWrite.urlRelabelDebug is set for the given -remoteWrite.url.
+	relabelDebugger *relabelDebugger
 }
 
 func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxInmemoryBlocks int, sanitizedURL string) *remoteWriteCtx {
@@ -473,18 +526,31 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 	h := xxhash.Sum64([]byte(pqURL.String()))
 	queuePath := fmt.Sprintf("%s/persistent-queue/%d_%016X", *tmpDataPath, argIdx+1, h)
 	maxPendingBytes := maxPendingBytesPerURL.GetOptionalArgOrDefault(argIdx, 0)
-	fq := persistentqueue.MustOpenFastQueue(queuePath, sanitizedURL, maxInmemoryBlocks, maxPendingBytes)
+	var fq *persistentqueue.FastQueue
+	if *tmpDataRemotePath != "" {
+		queuePath = fmt.Sprintf("%s/%d_%016X", strings.TrimSuffix(*tmpDataRemotePath, "/"), argIdx+1, h)
+		fs, err := actions.NewRemoteFS(queuePath)
+		if err != nil {
+			logger.Fatalf("cannot open -remoteWrite.tmpDataRemotePath=%q: %s", *tmpDataRemotePath, err)
+		}
+		fq = persistentqueue.MustOpenFastQueueAtRemoteFS(fs, queuePath, maxInmemoryBlocks, tmpDataRemoteChunkSize.N, maxPendingBytes)
+	} else {
+		fq = persistentqueue.MustOpenFastQueue(queuePath, sanitizedURL, maxInmemoryBlocks, maxPendingBytes)
+	}
 	_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_pending_data_bytes{path=%q, url=%q}`, queuePath, sanitizedURL), func() float64 {
 		return float64(fq.GetPendingBytes())
 	})
 	_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmagent_remotewrite_pending_inmemory_blocks{path=%q, url=%q}`, queuePath, sanitizedURL), func() float64 {
 		return float64(fq.GetInmemoryQueueLen())
 	})
-	isVMRemoteWrite := useVMProto.GetOptionalArg(argIdx)
+	enc, err := getWriteRequestEncoding(argIdx)
+	if err != nil {
+		logger.Fatalf("invalid compression settings for -remoteWrite.url=%q: %s", sanitizedURL, err)
+	}
 	var c *client
 	switch remoteWriteURL.Scheme {
 	case "http", "https":
-		c = newHTTPClient(argIdx, remoteWriteURL.String(), sanitizedURL, fq, *queues, isVMRemoteWrite)
+		c = newHTTPClient(argIdx, remoteWriteURL.String(), sanitizedURL, fq, *queues, enc.isVMRemoteWrite)
 	default:
 		logger.Fatalf("unsupported scheme: %s for remoteWriteURL: %s, want `http`, `https`", remoteWriteURL.Scheme, sanitizedURL)
 	}
@@ -493,6 +559,7 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 	// Initialize pss
 	sf := significantFigures.GetOptionalArgOrDefault(argIdx, 0)
 	rd := roundDigits.GetOptionalArgOrDefault(argIdx, 100)
+	fi := flushInterval.GetOptionalArgOrDefault(argIdx, time.Second)
 	pssLen := *queues
 	if n := cgroup.AvailableCPUs(); pssLen > n {
 		// There is no sense in running more than availableCPUs concurrent pendingSeries,
@@ -501,7 +568,7 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 	}
 	pss := make([]*pendingSeries, pssLen)
 	for i := range pss {
-		pss[i] = newPendingSeries(fq.MustWriteBlock, isVMRemoteWrite, sf, rd)
+		pss[i] = newPendingSeries(fq.MustWriteBlock, enc, fi, sf, rd)
 	}
 
 	rwctx := &remoteWriteCtx{
@@ -513,20 +580,27 @@ func newRemoteWriteCtx(argIdx int, at *auth.Token, remoteWriteURL *url.URL, maxI
 		rowsPushedAfterRelabel: metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_rows_pushed_after_relabel_total{path=%q, url=%q}`, queuePath, sanitizedURL)),
 		rowsDroppedByRelabel:   metrics.GetOrCreateCounter(fmt.Sprintf(`vmagent_remotewrite_relabel_metrics_dropped_total{path=%q, url=%q}`, queuePath, sanitizedURL)),
 	}
+	if relabelDebug.GetOptionalArg(argIdx) {
+		rwctx.relabelDebugger = newRelabelDebugger(fmt.Sprintf("-remoteWrite.url=%q", sanitizedURL),
+			fmt.Sprintf(`vmagent_remotewrite_relabel_debug_series_changed_total{path=%q, url=%q}`, queuePath, sanitizedURL))
+	}
+	return rwctx
+}
 
-	// Initialize sas
+// initStreamAggr initializes stream aggregation for rwctx according to -remoteWrite.streamAggr.* flags at rwctx.idx.
+func (rwctx *remoteWriteCtx) initStreamAggr() {
+	argIdx := rwctx.idx
 	sasFile := streamAggrConfig.GetOptionalArg(argIdx)
-	if sasFile != "" {
-		dedupInterval := streamAggrDedupInterval.GetOptionalArgOrDefault(argIdx, 0)
-		sas, err := streamaggr.LoadFromFile(sasFile, rwctx.pushInternal, dedupInterval)
-		if err != nil {
-			logger.Fatalf("cannot initialize stream aggregators from -remoteWrite.streamAggrFile=%q: %s", sasFile, err)
-		}
-		rwctx.sas = sas
-		rwctx.streamAggrKeepInput = streamAggrKeepInput.GetOptionalArg(argIdx)
+	if sasFile == "" {
+		return
 	}
-
-	return rwctx
+	dedupInterval := streamAggrDedupInterval.GetOptionalArgOrDefault(argIdx, 0)
+	sas, err := streamaggr.LoadFromFile(sasFile, rwctx.pushInternal, dedupInterval)
+	if err != nil {
+		logger.Fatalf("cannot initialize stream aggregators from -remoteWrite.streamAggrFile=%q: %s", sasFile, err)
+	}
+	rwctx.sas = sas
+	rwctx.streamAggrKeepInput = streamAggrKeepInput.GetOptionalArg(argIdx)
 }
 
 func (rwctx *remoteWriteCtx) MustStop() {
@@ -545,6 +619,7 @@ func (rwctx *remoteWriteCtx) MustStop() {
 
 	rwctx.rowsPushedAfterRelabel = nil
 	rwctx.rowsDroppedByRelabel = nil
+	rwctx.relabelDebugger = nil
 }
 
 func (rwctx *remoteWriteCtx) Push(tss []prompbmarshal.TimeSeries) {
@@ -562,7 +637,7 @@ func (rwctx *remoteWriteCtx) Push(tss []prompbmarshal.TimeSeries) {
 		v = tssRelabelPool.Get().(*[]prompbmarshal.TimeSeries)
 		tss = append(*v, tss...)
 		rowsCountBeforeRelabel := getRowsCount(tss)
-		tss = rctx.applyRelabeling(tss, nil, pcs)
+		tss = rctx.applyRelabeling(tss, nil, pcs, rwctx.relabelDebugger)
 		rowsCountAfterRelabel := getRowsCount(tss)
 		rwctx.rowsDroppedByRelabel.Add(rowsCountBeforeRelabel - rowsCountAfterRelabel)
 	}
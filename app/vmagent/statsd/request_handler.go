@@ -0,0 +1,48 @@
+package statsd
+
+import (
+	"io"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmagent/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted  = metrics.NewCounter(`vmagent_rows_inserted_total{type="statsd"}`)
+	rowsPerInsert = metrics.NewHistogram(`vmagent_rows_per_insert{type="statsd"}`)
+)
+
+var aggregator *parser.Aggregator
+
+// Init must be called before InsertHandler.
+func Init() {
+	aggregator = parser.MustStartAggregator(pushSeries)
+}
+
+// Stop flushes the aggregated StatsD metrics and stops the aggregator.
+func Stop() {
+	aggregator.MustStop()
+}
+
+// InsertHandler processes StatsD lines.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+func InsertHandler(r io.Reader) error {
+	return stream.Parse(r, insertRows)
+}
+
+func insertRows(rows []parser.Row) error {
+	aggregator.Add(rows)
+	rowsInserted.Add(len(rows))
+	rowsPerInsert.Update(float64(len(rows)))
+	return nil
+}
+
+func pushSeries(tss []prompbmarshal.TimeSeries) {
+	remotewrite.Push(nil, &prompbmarshal.WriteRequest{
+		Timeseries: tss,
+	})
+}
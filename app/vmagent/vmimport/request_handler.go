@@ -25,13 +25,15 @@ var (
 // InsertHandler processes `/api/v1/import` request.
 //
 // See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/6
-func InsertHandler(at *auth.Token, req *http.Request) error {
+//
+// The returned result contains errors for invalid lines, which were skipped.
+func InsertHandler(at *auth.Token, req *http.Request) (*stream.Result, error) {
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
-	return stream.Parse(req.Body, isGzipped, func(rows []parser.Row) error {
+	ce := req.Header.Get("Content-Encoding")
+	return stream.Parse(req.Body, ce, stream.IsStrictMode(req), func(rows []parser.Row) error {
 		return insertRows(at, rows, extraLabels)
 	})
 }
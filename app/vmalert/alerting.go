@@ -58,7 +58,7 @@ func newAlertingRule(qb datasource.QuerierBuilder, group *Group, cfg config.Rule
 		Type:         group.Type,
 		RuleID:       cfg.ID,
 		Name:         cfg.Alert,
-		Expr:         cfg.Expr,
+		Expr:         cfg.QueryExpr(),
 		For:          cfg.For.Duration(),
 		Labels:       cfg.Labels,
 		Annotations:  cfg.Annotations,
@@ -72,6 +72,7 @@ func newAlertingRule(qb datasource.QuerierBuilder, group *Group, cfg config.Rule
 			QueryParams:        group.Params,
 			Headers:            group.Headers,
 			Debug:              cfg.Debug,
+			Quorum:             cfg.Quorum,
 		}),
 		alerts:  make(map[uint64]*notifier.Alert),
 		metrics: &alertingRuleMetrics{},
@@ -238,6 +239,12 @@ func (ar *AlertingRule) ExecRange(ctx context.Context, start, end time.Time) ([]
 	if err != nil {
 		return nil, err
 	}
+	return ar.seriesToAlertTimeSeries(series)
+}
+
+// seriesToAlertTimeSeries converts series returned by range query for ar.Expr
+// into ALERT and ALERT_FOR_STATE time series.
+func (ar *AlertingRule) seriesToAlertTimeSeries(series []datasource.Metric) ([]prompbmarshal.TimeSeries, error) {
 	var result []prompbmarshal.TimeSeries
 	qFn := func(query string) ([]datasource.Metric, error) {
 		return nil, fmt.Errorf("`query` template isn't supported in replay mode")
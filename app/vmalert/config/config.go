@@ -11,6 +11,7 @@ import (
 	"gopkg.in/yaml.v2"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/configreload"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
@@ -26,6 +27,9 @@ type Group struct {
 	Limit       int                 `yaml:"limit,omitempty"`
 	Rules       []Rule              `yaml:"rules"`
 	Concurrency int                 `yaml:"concurrency"`
+	// EvalOffset is an optional offset of the group evaluations within the interval.
+	// If set, the group is evaluated at interval*N+EvalOffset timestamps.
+	EvalOffset *promutils.Duration `yaml:"eval_offset,omitempty"`
 	// Labels is a set of label value pairs, that will be added to every rule.
 	// It has priority over the external labels.
 	Labels map[string]string `yaml:"labels"`
@@ -67,6 +71,15 @@ func (g *Group) Validate(validateTplFn ValidateTplFn, validateExpressions bool)
 	if g.Name == "" {
 		return fmt.Errorf("group name must be set")
 	}
+	if g.EvalOffset != nil {
+		offset := g.EvalOffset.Duration()
+		if offset < 0 {
+			return fmt.Errorf("eval_offset cannot be negative for group %q; got %s", g.Name, offset)
+		}
+		if g.Interval != nil && offset >= g.Interval.Duration() {
+			return fmt.Errorf("eval_offset=%s must be smaller than interval=%s for group %q", offset, g.Interval.Duration(), g.Name)
+		}
+	}
 
 	uniqueRules := map[uint64]struct{}{}
 	for _, r := range g.Rules {
@@ -85,10 +98,13 @@ func (g *Group) Validate(validateTplFn ValidateTplFn, validateExpressions bool)
 			// its needed only for tests.
 			// because correct types must be inherited after unmarshalling.
 			exprValidator := g.Type.ValidateExpr
-			if err := exprValidator(r.Expr); err != nil {
+			if err := exprValidator(r.QueryExpr()); err != nil {
 				return fmt.Errorf("invalid expression for rule %q.%q: %w", g.Name, ruleName, err)
 			}
 		}
+		if r.ThresholdFrom != nil && g.Type.String() != "prometheus" {
+			return fmt.Errorf("invalid rule %q.%q: `threshold_from` is supported only for prometheus datasource type", g.Name, ruleName)
+		}
 		if validateTplFn != nil {
 			if err := validateTplFn(r.Annotations); err != nil {
 				return fmt.Errorf("invalid annotations for rule %q.%q: %w", g.Name, ruleName, err)
@@ -115,6 +131,12 @@ type Rule struct {
 	// UpdateEntriesLimit defines max number of rule's state updates stored in memory.
 	// Overrides `-rule.updateEntriesLimit`.
 	UpdateEntriesLimit *int `yaml:"update_entries_limit,omitempty"`
+	// Quorum defines the number of datasources, which must return identical results for the rule expression.
+	// It is used only if multiple `-datasource.url` are configured.
+	Quorum int `yaml:"quorum,omitempty"`
+	// ThresholdFrom defines per-series thresholds for alerting rule, which are compared with Expr results.
+	// See https://docs.victoriametrics.com/vmalert.html#dynamic-thresholds
+	ThresholdFrom *ThresholdFrom `yaml:"threshold_from,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]interface{} `yaml:",inline"`
@@ -168,7 +190,7 @@ func (r *Rule) String() string {
 // unique hash that supposed to define Rule uniqueness
 func HashRule(r Rule) uint64 {
 	h := fnv.New64a()
-	h.Write([]byte(r.Expr))
+	h.Write([]byte(r.QueryExpr()))
 	if r.Record != "" {
 		h.Write([]byte("recording"))
 		h.Write([]byte(r.Record))
@@ -193,18 +215,67 @@ func (r *Rule) Validate() error {
 	if r.Expr == "" {
 		return fmt.Errorf("expression can't be empty")
 	}
+	if r.Quorum < 0 {
+		return fmt.Errorf("quorum can't be negative; got %d", r.Quorum)
+	}
+	if r.ThresholdFrom != nil {
+		if r.Alert == "" {
+			return fmt.Errorf("`threshold_from` can be set only for alerting rules")
+		}
+		if err := r.ThresholdFrom.Validate(); err != nil {
+			return fmt.Errorf("invalid `threshold_from`: %w", err)
+		}
+	}
 	return checkOverflow(r.XXX, "rule")
 }
 
+// QueryExpr returns the expression, which must be executed for the rule.
+//
+// It differs from Expr if ThresholdFrom is set.
+func (r *Rule) QueryExpr() string {
+	if r.ThresholdFrom == nil {
+		return r.Expr
+	}
+	return r.ThresholdFrom.buildExpr(r.Expr)
+}
+
 // ValidateTplFn must validate the given annotations
 type ValidateTplFn func(annotations map[string]string) error
 
 // Parse parses rule configs from given file patterns
 func Parse(pathPatterns []string, validateTplFn ValidateTplFn, validateExpressions bool) ([]Group, error) {
+	groups, _, err := ParseWithHash(pathPatterns, validateTplFn, validateExpressions)
+	return groups, err
+}
+
+// ParseWithHash parses rule configs from given file patterns
+// and returns the hash of rule files contents concatenated in the order of file names.
+func ParseWithHash(pathPatterns []string, validateTplFn ValidateTplFn, validateExpressions bool) ([]Group, string, error) {
 	files, err := readFromFS(pathPatterns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from the config: %s", err)
+		return nil, "", fmt.Errorf("failed to read from the config: %s", err)
+	}
+	groups, err := parseFiles(files, pathPatterns, validateTplFn, validateExpressions)
+	if err != nil {
+		return nil, "", err
+	}
+	return groups, getFilesHash(files), nil
+}
+
+func getFilesHash(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	data := make([][]byte, len(names))
+	for i, name := range names {
+		data[i] = files[name]
+	}
+	return configreload.Hash(data...)
+}
+
+func parseFiles(files map[string][]byte, pathPatterns []string, validateTplFn ValidateTplFn, validateExpressions bool) ([]Group, error) {
 	errGroup := new(utils.ErrGroup)
 	var groups []Group
 	for file, data := range files {
@@ -231,6 +302,9 @@ func Parse(pathPatterns []string, validateTplFn ValidateTplFn, validateExpressio
 	if err := errGroup.Err(); err != nil {
 		return nil, err
 	}
+	if err := checkDependencies(groups); err != nil {
+		return nil, err
+	}
 	if len(groups) < 1 {
 		logger.Warnf("no groups found in %s", strings.Join(pathPatterns, ";"))
 	}
@@ -64,6 +64,14 @@ func TestParseBad(t *testing.T) {
 			[]string{"testdata/dir/rules6-bad.rules"},
 			"missing ':' in header",
 		},
+		{
+			[]string{"testdata/dir/rules7-bad.rules"},
+			"cyclic dependency",
+		},
+		{
+			[]string{"testdata/dir/rules8-bad.rules"},
+			"cyclic dependency",
+		},
 	}
 	for _, tc := range testCases {
 		_, err := Parse(tc.path, notifier.ValidateTemplates, true)
@@ -87,6 +95,9 @@ func TestRule_Validate(t *testing.T) {
 	if err := (&Rule{Alert: "alert", Expr: "test>0"}).Validate(); err != nil {
 		t.Errorf("expected valid rule; got %s", err)
 	}
+	if err := (&Rule{Alert: "alert", Expr: "test>0", Quorum: -1}).Validate(); err == nil {
+		t.Errorf("expected negative quorum error")
+	}
 }
 
 func TestGroup_Validate(t *testing.T) {
@@ -101,6 +112,26 @@ func TestGroup_Validate(t *testing.T) {
 			group:  &Group{},
 			expErr: "group name must be set",
 		},
+		{
+			group: &Group{Name: "test",
+				Interval:   promutils.NewDuration(time.Minute),
+				EvalOffset: promutils.NewDuration(30 * time.Second),
+			},
+			expErr: "",
+		},
+		{
+			group: &Group{Name: "test",
+				Interval:   promutils.NewDuration(time.Minute),
+				EvalOffset: promutils.NewDuration(time.Minute),
+			},
+			expErr: "eval_offset=1m0s must be smaller than interval=1m0s",
+		},
+		{
+			group: &Group{Name: "test",
+				EvalOffset: promutils.NewDuration(-time.Second),
+			},
+			expErr: "eval_offset cannot be negative",
+		},
 		{
 			group: &Group{Name: "test",
 				Rules: []Rule{
@@ -289,6 +320,60 @@ func TestGroup_Validate(t *testing.T) {
 			},
 			expErr: "invalid rule",
 		},
+		{
+			group: &Group{
+				Name: "threshold_from for recording rule",
+				Rules: []Rule{
+					{
+						Record:        "record",
+						Expr:          "up",
+						ThresholdFrom: &ThresholdFrom{Query: "up_threshold"},
+					},
+				},
+			},
+			expErr: "`threshold_from` can be set only for alerting rules",
+		},
+		{
+			group: &Group{
+				Name: "threshold_from with bad operator",
+				Rules: []Rule{
+					{
+						Alert:         "alert",
+						Expr:          "up",
+						ThresholdFrom: &ThresholdFrom{Query: "up_threshold", Operator: "=~"},
+					},
+				},
+			},
+			expErr: "unsupported operator",
+		},
+		{
+			group: &Group{
+				Name: "threshold_from for graphite",
+				Type: NewGraphiteType(),
+				Rules: []Rule{
+					{
+						Alert:         "alert",
+						Expr:          "sumSeries(time('foo.bar',10))",
+						ThresholdFrom: &ThresholdFrom{Query: "sumSeries(time('foo.baz',10))"},
+					},
+				},
+			},
+			expErr: "supported only for prometheus datasource type",
+		},
+		{
+			group: &Group{
+				Name: "threshold_from with bad query",
+				Rules: []Rule{
+					{
+						Alert:         "alert",
+						Expr:          "up",
+						ThresholdFrom: &ThresholdFrom{Query: "up_threshold{"},
+					},
+				},
+			},
+			validateExpressions: true,
+			expErr:              "invalid expression",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -366,6 +451,11 @@ func TestHashRule(t *testing.T) {
 			Rule{Record: "record", Expr: "up == 2"},
 			false,
 		},
+		{
+			Rule{Alert: "alert", Expr: "up", ThresholdFrom: &ThresholdFrom{Query: "up_threshold"}},
+			Rule{Alert: "alert", Expr: "up", ThresholdFrom: &ThresholdFrom{Query: "up_threshold", Operator: "<"}},
+			false,
+		},
 		{
 			Rule{Alert: "alert", Expr: "up == 1", Labels: map[string]string{
 				"foo": "bar",
@@ -486,6 +576,24 @@ rules:
 `)
 	})
 
+	t.Run("`eval_offset` change", func(t *testing.T) {
+		f(t, `
+name: TestGroup
+interval: 1m
+eval_offset: 10s
+rules:
+  - alert: ExampleAlertWithFor
+    expr: sum by(job) (up == 1)
+`, `
+name: TestGroup
+interval: 1m
+eval_offset: 20s
+rules:
+  - alert: ExampleAlertWithFor
+    expr: sum by(job) (up == 1)
+`)
+	})
+
 	t.Run("`params` change", func(t *testing.T) {
 		f(t, `
 name: TestGroup
@@ -602,3 +710,59 @@ rules:
 `, url.Values{"nocache": {"1"}, "denyPartialResponse": {"true"}})
 	})
 }
+
+func TestRule_QueryExpr(t *testing.T) {
+	f := func(data, exprExpected string) {
+		t.Helper()
+		var r Rule
+		if err := yaml.Unmarshal([]byte(data), &r); err != nil {
+			t.Fatalf("cannot unmarshal rule: %s", err)
+		}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("unexpected validation error: %s", err)
+		}
+		expr := r.QueryExpr()
+		if expr != exprExpected {
+			t.Fatalf("unexpected expr\ngot\n%s\nwant\n%s", expr, exprExpected)
+		}
+		tp := NewPrometheusType()
+		if err := tp.ValidateExpr(expr); err != nil {
+			t.Fatalf("invalid expr %q: %s", expr, err)
+		}
+	}
+
+	f(`
+alert: foo
+expr: disk_used_percent
+`, "disk_used_percent")
+	f(`
+alert: foo
+expr: disk_used_percent
+threshold_from:
+  query: disk_used_threshold
+`, "(disk_used_percent) > (disk_used_threshold)")
+	f(`
+alert: foo
+expr: rate(errors_total[5m])
+threshold_from:
+  query: max(errors_threshold) by (job)
+  on: [job]
+  operator: ">="
+`, "(rate(errors_total[5m])) >= on(job) group_left() (max(errors_threshold) by (job))")
+	f(`
+alert: foo
+expr: disk_free_bytes
+threshold_from:
+  query: disk_free_threshold
+  on: [instance, mountpoint]
+  operator: "<"
+  default: 1e9
+`, "(disk_free_bytes) < on(instance,mountpoint) group_left() (disk_free_threshold or on(instance,mountpoint) (max by(instance,mountpoint) (disk_free_bytes) * 0 + 1e+09))")
+	f(`
+alert: foo
+expr: disk_used_percent
+threshold_from:
+  query: disk_used_threshold
+  default: 90
+`, "(disk_used_percent) > (disk_used_threshold or ((disk_used_percent) * 0 + 90))")
+}
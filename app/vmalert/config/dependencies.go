@@ -0,0 +1,187 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// DependencyLayers splits the given rules into layers, so rules from every layer
+// depend only on recording rules from the previous layers.
+//
+// The rule depends on the recording rule if the rule expression selects
+// the metric name produced by the recording rule. Dependencies are detected
+// for prometheus datasource type only, since graphite expressions cannot refer
+// to recording rules results by metric name.
+//
+// Every layer contains indexes of rules in the original order.
+// An error is returned if rules have cyclic dependencies.
+func DependencyLayers(t Type, rules []Rule) ([][]int, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	deps := make([][]int, len(rules))
+	if t.String() == "prometheus" {
+		recordings := make(map[string][]int)
+		for i, r := range rules {
+			if r.Record != "" {
+				recordings[r.Record] = append(recordings[r.Record], i)
+			}
+		}
+		for i, r := range rules {
+			for _, name := range getSelectedMetricNames(r.Expr) {
+				for _, j := range recordings[name] {
+					// Self-references are allowed, since they refer to the previous rule results.
+					if j != i {
+						deps[i] = append(deps[i], j)
+					}
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(rules))
+	levels := make([]int, len(rules))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%s has cyclic dependency on itself via other recording rules", rules[i].String())
+		}
+		states[i] = visiting
+		level := 0
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+			if levels[j]+1 > level {
+				level = levels[j] + 1
+			}
+		}
+		levels[i] = level
+		states[i] = visited
+		return nil
+	}
+	maxLevel := 0
+	for i := range rules {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+		if levels[i] > maxLevel {
+			maxLevel = levels[i]
+		}
+	}
+	layers := make([][]int, maxLevel+1)
+	for i, level := range levels {
+		layers[level] = append(layers[level], i)
+	}
+	return layers, nil
+}
+
+// getSelectedMetricNames returns metric names selected by the given MetricsQL expr.
+//
+// Invalid expressions and selectors without exact metric name are ignored.
+func getSelectedMetricNames(expr string) []string {
+	e, err := metricsql.Parse(expr)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok {
+			return
+		}
+		for _, lf := range me.LabelFilters {
+			if lf.Label == "__name__" && !lf.IsRegexp && !lf.IsNegative {
+				names = append(names, lf.Value)
+			}
+		}
+	})
+	return names
+}
+
+// GroupDependencies returns indexes of groups every group from groups depends on.
+//
+// The group depends on another group if its rules select metric names
+// produced by recording rules from another group. Such groups are evaluated
+// after the groups they depend on. An error is returned if groups have cyclic dependencies.
+func GroupDependencies(groups []Group) ([][]int, error) {
+	recordings := make(map[string][]int)
+	for i, g := range groups {
+		if g.Type.String() != "prometheus" {
+			continue
+		}
+		for _, r := range g.Rules {
+			if r.Record != "" {
+				recordings[r.Record] = append(recordings[r.Record], i)
+			}
+		}
+	}
+	deps := make([][]int, len(groups))
+	for i, g := range groups {
+		if g.Type.String() != "prometheus" {
+			continue
+		}
+		seen := make(map[int]bool)
+		for _, r := range g.Rules {
+			for _, name := range getSelectedMetricNames(r.Expr) {
+				for _, j := range recordings[name] {
+					if j == i || seen[j] {
+						continue
+					}
+					seen[j] = true
+					deps[i] = append(deps[i], j)
+				}
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(groups))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("group %q in file %q has cyclic dependency on itself via recording rules from other groups", groups[i].Name, groups[i].File)
+		}
+		states[i] = visiting
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		states[i] = visited
+		return nil
+	}
+	for i := range groups {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return deps, nil
+}
+
+// checkDependencies returns an error if rules within groups or groups have cyclic dependencies.
+func checkDependencies(groups []Group) error {
+	for _, g := range groups {
+		if _, err := DependencyLayers(g.Type, g.Rules); err != nil {
+			return fmt.Errorf("invalid group %q in file %q: %w", g.Name, g.File, err)
+		}
+	}
+	_, err := GroupDependencies(groups)
+	return err
+}
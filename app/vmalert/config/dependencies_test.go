@@ -0,0 +1,115 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencyLayers(t *testing.T) {
+	f := func(typ Type, rules []Rule, layersExpected [][]int) {
+		t.Helper()
+		layers, err := DependencyLayers(typ, rules)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(layers, layersExpected) {
+			t.Fatalf("unexpected layers; got %v; want %v", layers, layersExpected)
+		}
+	}
+
+	f(NewPrometheusType(), nil, nil)
+
+	// no dependencies
+	f(NewPrometheusType(), []Rule{
+		{Record: "foo", Expr: "sum(bar)"},
+		{Alert: "baz", Expr: "qux > 0"},
+	}, [][]int{{0, 1}})
+
+	// self-reference
+	f(NewPrometheusType(), []Rule{
+		{Record: "foo", Expr: "foo + 1"},
+	}, [][]int{{0}})
+
+	// chain of dependencies in reverse order
+	f(NewPrometheusType(), []Rule{
+		{Alert: "high", Expr: `job:rate:sum{job="x"} > 10`},
+		{Record: "job:rate:sum", Expr: "sum(job:rate) by (job)"},
+		{Record: "job:rate", Expr: "rate(http_requests_total[5m])"},
+		{Alert: "down", Expr: "up == 0"},
+	}, [][]int{{2, 3}, {1}, {0}})
+
+	// regexp and negative filters on metric name are ignored
+	f(NewPrometheusType(), []Rule{
+		{Alert: "a", Expr: `{__name__=~"foo"} > 0`},
+		{Record: "foo", Expr: "bar"},
+		{Alert: "b", Expr: `{__name__!="foo"} > 0`},
+	}, [][]int{{0, 1, 2}})
+
+	// dependencies aren't detected for graphite
+	f(NewGraphiteType(), []Rule{
+		{Alert: "a", Expr: "foo"},
+		{Record: "foo", Expr: "bar"},
+	}, [][]int{{0, 1}})
+}
+
+func TestDependencyLayersCycle(t *testing.T) {
+	rules := []Rule{
+		{Record: "foo", Expr: "bar + 1"},
+		{Record: "bar", Expr: "baz + 1"},
+		{Record: "baz", Expr: "foo + 1"},
+	}
+	if _, err := DependencyLayers(NewPrometheusType(), rules); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestGroupDependencies(t *testing.T) {
+	f := func(groups []Group, depsExpected [][]int) {
+		t.Helper()
+		deps, err := GroupDependencies(groups)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(deps, depsExpected) {
+			t.Fatalf("unexpected dependencies; got %v; want %v", deps, depsExpected)
+		}
+	}
+
+	f(nil, [][]int{})
+
+	f([]Group{
+		{Name: "a", Type: NewPrometheusType(), Rules: []Rule{
+			{Alert: "high", Expr: "job:rate:sum > 10"},
+			{Alert: "low", Expr: "job:rate:max < 1"},
+		}},
+		{Name: "b", Type: NewPrometheusType(), Rules: []Rule{
+			{Record: "job:rate:sum", Expr: "sum(job:rate)"},
+			{Record: "job:rate:max", Expr: "max(job:rate)"},
+		}},
+		{Name: "c", Type: NewPrometheusType(), Rules: []Rule{
+			{Record: "job:rate", Expr: "rate(http_requests_total[5m])"},
+			{Alert: "down", Expr: "up == 0"},
+		}},
+		// dependencies aren't detected for graphite
+		{Name: "d", Type: NewGraphiteType(), Rules: []Rule{
+			{Alert: "graphite", Expr: "job:rate"},
+		}},
+	}, [][]int{{1}, {2}, nil, nil})
+}
+
+func TestGroupDependenciesCycle(t *testing.T) {
+	groups := []Group{
+		{Name: "a", Type: NewPrometheusType(), Rules: []Rule{
+			{Record: "foo", Expr: "sum(baz)"},
+		}},
+		{Name: "b", Type: NewPrometheusType(), Rules: []Rule{
+			{Record: "bar", Expr: "sum(foo)"},
+		}},
+		{Name: "c", Type: NewPrometheusType(), Rules: []Rule{
+			{Record: "baz", Expr: "sum(bar)"},
+		}},
+	}
+	if _, err := GroupDependencies(groups); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ThresholdFrom defines per-series thresholds for alerting rule, which are obtained from the given Query.
+//
+// See https://docs.victoriametrics.com/vmalert.html#dynamic-thresholds
+type ThresholdFrom struct {
+	// Query is the expression, which returns thresholds.
+	Query string `yaml:"query"`
+
+	// On is the list of labels for matching series returned by the rule expression with series returned by Query.
+	// Series are matched by all the labels if On is empty.
+	On []string `yaml:"on,omitempty"`
+
+	// Operator is the comparison operator for the rule expression and the threshold. By default `>` is used.
+	Operator string `yaml:"operator,omitempty"`
+
+	// Default is the threshold for series without the matching series returned by Query.
+	// Such series never trigger the alert if Default isn't set.
+	Default *float64 `yaml:"default,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]interface{} `yaml:",inline"`
+}
+
+var (
+	thresholdOperators = []string{">", ">=", "<", "<=", "==", "!="}
+	labelNameRegexp    = regexp.MustCompile("^[a-zA-Z_][a-zA-Z0-9_]*$")
+)
+
+// Validate validates tf.
+func (tf *ThresholdFrom) Validate() error {
+	if tf.Query == "" {
+		return fmt.Errorf("query can't be empty")
+	}
+	if tf.Operator != "" && !hasString(thresholdOperators, tf.Operator) {
+		return fmt.Errorf("unsupported operator %q; supported operators: %s", tf.Operator, strings.Join(thresholdOperators, ", "))
+	}
+	for _, label := range tf.On {
+		if !labelNameRegexp.MatchString(label) {
+			return fmt.Errorf("invalid label name %q in `on` list", label)
+		}
+	}
+	if tf.Default != nil && math.IsNaN(*tf.Default) {
+		return fmt.Errorf("default threshold can't be NaN")
+	}
+	return checkOverflow(tf.XXX, "threshold_from")
+}
+
+// buildExpr returns the expression, which compares the result of expr with thresholds from tf.
+//
+// The returned expression returns series from expr, which match the threshold.
+func (tf *ThresholdFrom) buildExpr(expr string) string {
+	op := tf.Operator
+	if op == "" {
+		op = ">"
+	}
+	// Thresholds are matched by all the labels if `on` list is empty.
+	matching := ""
+	if len(tf.On) > 0 {
+		matching = fmt.Sprintf(" on(%s) group_left()", strings.Join(tf.On, ","))
+	}
+	thresholds := fmt.Sprintf("(%s)", tf.Query)
+	if tf.Default != nil {
+		defaultValue := strconv.FormatFloat(*tf.Default, 'g', -1, 64)
+		if len(tf.On) > 0 {
+			// Generate a single default threshold per each group of series from expr,
+			// so the right side of the comparison contains unique series per each `on` labels set.
+			by := strings.Join(tf.On, ",")
+			thresholds = fmt.Sprintf("(%s or on(%s) (max by(%s) (%s) * 0 + %s))", tf.Query, by, by, expr, defaultValue)
+		} else {
+			thresholds = fmt.Sprintf("(%s or ((%s) * 0 + %s))", tf.Query, expr, defaultValue)
+		}
+	}
+	return fmt.Sprintf("(%s) %s%s %s", expr, op, matching, thresholds)
+}
+
+func hasString(a []string, s string) bool {
+	for _, x := range a {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -33,6 +33,9 @@ type QuerierParams struct {
 	QueryParams        url.Values
 	Headers            map[string]string
 	Debug              bool
+	// Quorum is the number of datasources, which must return identical results.
+	// It is used only if multiple datasources are configured.
+	Quorum int
 }
 
 // Metric is the basic entity which should be return by datasource
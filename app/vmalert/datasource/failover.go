@@ -0,0 +1,260 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// failoverBuilder builds queriers, which fail over between multiple datasources.
+type failoverBuilder struct {
+	vms []*VMStorage
+	eps []*endpoint
+}
+
+// endpoint holds health state for a single datasource.
+//
+// It is shared among all the queriers built by failoverBuilder.
+type endpoint struct {
+	name    string
+	backoff time.Duration
+
+	// unhealthyUntil is unix timestamp in seconds until the endpoint is skipped.
+	unhealthyUntil uint64
+
+	requests *utils.Counter
+	errors   *utils.Counter
+}
+
+func newFailoverBuilder(vms []*VMStorage, backoff time.Duration) *failoverBuilder {
+	fb := &failoverBuilder{
+		vms: vms,
+	}
+	for i, vm := range vms {
+		name := fmt.Sprintf("%d:secret-url", i+1)
+		if *showDatasourceURL {
+			name = vm.datasourceURL
+		}
+		ep := &endpoint{
+			name:     name,
+			backoff:  backoff,
+			requests: utils.GetOrCreateCounter(fmt.Sprintf(`vmalert_datasource_requests_total{url=%q}`, name)),
+			errors:   utils.GetOrCreateCounter(fmt.Sprintf(`vmalert_datasource_errors_total{url=%q}`, name)),
+		}
+		utils.GetOrCreateGauge(fmt.Sprintf(`vmalert_datasource_up{url=%q}`, name), func() float64 {
+			if ep.isHealthy() {
+				return 1
+			}
+			return 0
+		})
+		fb.eps = append(fb.eps, ep)
+	}
+	return fb
+}
+
+func (ep *endpoint) isHealthy() bool {
+	return fasttime.UnixTimestamp() >= atomic.LoadUint64(&ep.unhealthyUntil)
+}
+
+func (ep *endpoint) registerResult(err error) {
+	ep.requests.Inc()
+	if err == nil {
+		atomic.StoreUint64(&ep.unhealthyUntil, 0)
+		return
+	}
+	ep.errors.Inc()
+	if !isEndpointError(err) {
+		return
+	}
+	if ep.isHealthy() {
+		logger.Warnf("skipping -datasource.url=%q for %s because of error: %s", ep.name, ep.backoff, err)
+	}
+	atomic.StoreUint64(&ep.unhealthyUntil, fasttime.UnixTimestamp()+uint64(ep.backoff.Seconds()))
+}
+
+// isEndpointError returns true if err is caused by datasource unavailability.
+//
+// Such errors may be fixed by sending the query to another datasource.
+func isEndpointError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *statusCodeError
+	if errors.As(err, &se) {
+		// Errors with 4xx status codes are caused by invalid requests, so they are returned by all the datasources.
+		return se.statusCode >= 500 || se.statusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// BuildWithParams implements QuerierBuilder interface.
+func (fb *failoverBuilder) BuildWithParams(params QuerierParams) Querier {
+	fq := &failoverQuerier{
+		eps:    fb.eps,
+		quorum: params.Quorum,
+	}
+	for _, vm := range fb.vms {
+		fq.qs = append(fq.qs, vm.BuildWithParams(params))
+	}
+	return fq
+}
+
+// failoverQuerier sends queries to the first healthy datasource.
+//
+// If quorum is bigger than 1, then queries are sent to all the datasources
+// and the result is returned only if at least quorum datasources return identical results.
+type failoverQuerier struct {
+	qs     []Querier
+	eps    []*endpoint
+	quorum int
+}
+
+var quorumFailures = utils.GetOrCreateCounter(`vmalert_datasource_quorum_failures_total`)
+
+type queryFunc func(q Querier) ([]Metric, *http.Request, error)
+
+// Query implements Querier interface.
+func (fq *failoverQuerier) Query(ctx context.Context, query string, ts time.Time) ([]Metric, *http.Request, error) {
+	return fq.do(func(q Querier) ([]Metric, *http.Request, error) {
+		return q.Query(ctx, query, ts)
+	})
+}
+
+// QueryRange implements Querier interface.
+func (fq *failoverQuerier) QueryRange(ctx context.Context, query string, from, to time.Time) ([]Metric, error) {
+	result, _, err := fq.do(func(q Querier) ([]Metric, *http.Request, error) {
+		result, err := q.QueryRange(ctx, query, from, to)
+		return result, nil, err
+	})
+	return result, err
+}
+
+func (fq *failoverQuerier) do(f queryFunc) ([]Metric, *http.Request, error) {
+	if fq.quorum > 1 {
+		return fq.doQuorum(f)
+	}
+	return fq.doFailover(f)
+}
+
+// doFailover sends the query to healthy datasources in the configured order until the first successful response.
+//
+// Unhealthy datasources are queried only if all the healthy datasources fail.
+func (fq *failoverQuerier) doFailover(f queryFunc) ([]Metric, *http.Request, error) {
+	idxs := make([]int, 0, len(fq.qs))
+	for i, ep := range fq.eps {
+		if ep.isHealthy() {
+			idxs = append(idxs, i)
+		}
+	}
+	for i, ep := range fq.eps {
+		if !ep.isHealthy() {
+			idxs = append(idxs, i)
+		}
+	}
+	var req *http.Request
+	var err error
+	for _, idx := range idxs {
+		var result []Metric
+		result, req, err = f(fq.qs[idx])
+		fq.eps[idx].registerResult(err)
+		if err == nil {
+			return result, req, nil
+		}
+		if !isEndpointError(err) {
+			return nil, req, err
+		}
+	}
+	return nil, req, fmt.Errorf("all the %d datasources failed; last error: %w", len(fq.qs), err)
+}
+
+// doQuorum sends the query to all the datasources and returns the result
+// if at least fq.quorum datasources return identical results.
+func (fq *failoverQuerier) doQuorum(f queryFunc) ([]Metric, *http.Request, error) {
+	if fq.quorum > len(fq.qs) {
+		return nil, nil, fmt.Errorf("quorum=%d exceeds the number of configured datasources: %d", fq.quorum, len(fq.qs))
+	}
+	type response struct {
+		result []Metric
+		req    *http.Request
+		err    error
+	}
+	resps := make([]response, len(fq.qs))
+	var wg sync.WaitGroup
+	for i := range fq.qs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			result, req, err := f(fq.qs[i])
+			fq.eps[i].registerResult(err)
+			resps[i] = response{
+				result: result,
+				req:    req,
+				err:    err,
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Group identical results.
+	groups := make(map[string][]int)
+	var errs []string
+	for i, resp := range resps {
+		if resp.err != nil {
+			errs = append(errs, resp.err.Error())
+			continue
+		}
+		k := metricsKey(resp.result)
+		groups[k] = append(groups[k], i)
+	}
+	var best []int
+	for _, idxs := range groups {
+		if len(idxs) > len(best) || (len(idxs) == len(best) && idxs[0] < best[0]) {
+			best = idxs
+		}
+	}
+	if len(best) >= fq.quorum {
+		resp := resps[best[0]]
+		return resp.result, resp.req, nil
+	}
+	quorumFailures.Inc()
+	err := fmt.Errorf("quorum=%d isn't reached: %d out of %d datasources returned identical results", fq.quorum, len(best), len(fq.qs))
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w; errors: %s", err, strings.Join(errs, "; "))
+	}
+	var req *http.Request
+	if len(best) > 0 {
+		req = resps[best[0]].req
+	}
+	return nil, req, err
+}
+
+// metricsKey returns a key, which is identical for identical sets of metrics regardless of their order.
+func metricsKey(ms []Metric) string {
+	keys := make([]string, len(ms))
+	for i, m := range ms {
+		labels := make([]string, len(m.Labels))
+		for j, l := range m.Labels {
+			labels[j] = fmt.Sprintf("%q=%q", l.Name, l.Value)
+		}
+		sort.Strings(labels)
+		var sb strings.Builder
+		sb.WriteString(strings.Join(labels, ","))
+		for j, ts := range m.Timestamps {
+			// Compare string representations of values, since NaN != NaN.
+			fmt.Fprintf(&sb, " %d:%v", ts, m.Values[j])
+		}
+		keys[i] = sb.String()
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\n")
+}
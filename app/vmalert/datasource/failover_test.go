@@ -0,0 +1,114 @@
+package datasource
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFailoverServer(t *testing.T, statusCode int, value string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if statusCode != http.StatusOK {
+			w.WriteHeader(statusCode)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"vm_rows"},"value":[1583786142,"` + value + `"]}]}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestFailoverBuilder(srvs ...*httptest.Server) *failoverBuilder {
+	var vms []*VMStorage
+	for _, srv := range srvs {
+		vms = append(vms, NewVMStorage(srv.URL, nil, time.Minute, 0, false, srv.Client()))
+	}
+	return newFailoverBuilder(vms, time.Minute)
+}
+
+func TestFailoverQuerier(t *testing.T) {
+	f := func(fb *failoverBuilder, quorum int, valueExpected float64, errExpected string) {
+		t.Helper()
+		q := fb.BuildWithParams(QuerierParams{DataSourceType: string(datasourcePrometheus), Quorum: quorum})
+		m, _, err := q.Query(ctx, query, time.Now())
+		if errExpected != "" {
+			if err == nil {
+				t.Fatalf("expecting non-nil error containing %q", errExpected)
+			}
+			if !strings.Contains(err.Error(), errExpected) {
+				t.Fatalf("unexpected error; got %q; want %q", err, errExpected)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(m) != 1 || len(m[0].Values) != 1 {
+			t.Fatalf("unexpected result: %+v", m)
+		}
+		if got := m[0].Values[0]; got != valueExpected {
+			t.Fatalf("unexpected value; got %v; want %v", got, valueExpected)
+		}
+	}
+
+	ok1 := newTestFailoverServer(t, http.StatusOK, "1")
+	ok2 := newTestFailoverServer(t, http.StatusOK, "2")
+	unavailable := newTestFailoverServer(t, http.StatusServiceUnavailable, "")
+	badRequest := newTestFailoverServer(t, http.StatusBadRequest, "")
+	down := httptest.NewServer(nil)
+	down.Close()
+
+	// The first healthy datasource is used
+	f(newTestFailoverBuilder(ok1, ok2), 0, 1, "")
+
+	// Fail over to the next datasource on unavailability
+	f(newTestFailoverBuilder(unavailable, ok2), 0, 2, "")
+	f(newTestFailoverBuilder(down, ok2), 0, 2, "")
+
+	// Do not fail over on invalid requests
+	f(newTestFailoverBuilder(badRequest, ok2), 0, 0, "unexpected response code 400")
+
+	// All the datasources are unavailable
+	f(newTestFailoverBuilder(unavailable, down), 0, 0, "all the 2 datasources failed")
+
+	// Quorum is reached
+	f(newTestFailoverBuilder(ok1, ok2, ok1), 2, 1, "")
+	f(newTestFailoverBuilder(unavailable, ok2, ok2), 2, 2, "")
+
+	// Quorum isn't reached
+	f(newTestFailoverBuilder(ok1, ok2, ok1), 3, 0, "quorum=3 isn't reached: 2 out of 3")
+	f(newTestFailoverBuilder(ok1, unavailable), 2, 0, "quorum=2 isn't reached: 1 out of 2")
+
+	// Quorum exceeds the number of datasources
+	f(newTestFailoverBuilder(ok1, ok1), 3, 0, "quorum=3 exceeds the number of configured datasources: 2")
+}
+
+func TestFailoverQuerierSkipUnhealthy(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+	ok := newTestFailoverServer(t, http.StatusOK, "1")
+
+	fb := newTestFailoverBuilder(srv, ok)
+	q := fb.BuildWithParams(QuerierParams{DataSourceType: string(datasourcePrometheus)})
+	for i := 0; i < 3; i++ {
+		if _, _, err := q.Query(ctx, query, time.Now()); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("unhealthy datasource must be skipped; got %d requests; want 1", requests)
+	}
+	if fb.eps[0].isHealthy() {
+		t.Fatalf("the first datasource must be marked as unhealthy")
+	}
+	if !fb.eps[1].isHealthy() {
+		t.Fatalf("the second datasource must be healthy")
+	}
+}
@@ -13,8 +13,11 @@ import (
 )
 
 var (
-	addr = flag.String("datasource.url", "", "Datasource compatible with Prometheus HTTP API. It can be single node VictoriaMetrics or vmselect URL. Required parameter. "+
-		"E.g. http://127.0.0.1:8428 . See also -remoteRead.disablePathAppend and -datasource.showURL")
+	addrs = flagutil.NewArrayString("datasource.url", "Datasource compatible with Prometheus HTTP API. It can be single node VictoriaMetrics or vmselect URL. Required parameter. "+
+		"E.g. http://127.0.0.1:8428 . Multiple urls can be set for failover; see https://docs.victoriametrics.com/vmalert.html#datasource-failover . "+
+		"See also -remoteRead.disablePathAppend and -datasource.showURL")
+	failoverBackoff = flag.Duration("datasource.failoverBackoff", 30*time.Second, "How long to skip -datasource.url after a failed request if multiple -datasource.url are set. "+
+		"Requests are sent to the skipped url only if all the other urls are unavailable. See https://docs.victoriametrics.com/vmalert.html#datasource-failover")
 	appendTypePrefix  = flag.Bool("datasource.appendTypePrefix", false, "Whether to add type prefix to -datasource.url based on the query type. Set to true if sending different query types to the vmselect URL.")
 	showDatasourceURL = flag.Bool("datasource.showURL", false, "Whether to show -datasource.url in the exported metrics. "+
 		"It is hidden by default, since it can contain sensitive info such as auth key")
@@ -70,19 +73,17 @@ type Param struct {
 // Init creates a Querier from provided flag values.
 // Provided extraParams will be added as GET params for
 // each request.
+//
+// If multiple -datasource.url are set, then the returned QuerierBuilder
+// fails over between them. See https://docs.victoriametrics.com/vmalert.html#datasource-failover
 func Init(extraParams url.Values) (QuerierBuilder, error) {
-	if *addr == "" {
+	if len(*addrs) == 0 {
 		return nil, fmt.Errorf("datasource.url is empty")
 	}
-
-	tr, err := utils.Transport(*addr, *tlsCertFile, *tlsKeyFile, *tlsCAFile, *tlsServerName, *tlsInsecureSkipVerify)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create transport: %w", err)
-	}
-	tr.DisableKeepAlives = *disableKeepAlive
-	tr.MaxIdleConnsPerHost = *maxIdleConnections
-	if tr.MaxIdleConns != 0 && tr.MaxIdleConns < tr.MaxIdleConnsPerHost {
-		tr.MaxIdleConns = tr.MaxIdleConnsPerHost
+	for _, addr := range *addrs {
+		if addr == "" {
+			return nil, fmt.Errorf("datasource.url cannot contain empty values")
+		}
 	}
 
 	if extraParams == nil {
@@ -101,14 +102,30 @@ func Init(extraParams url.Values) (QuerierBuilder, error) {
 		return nil, fmt.Errorf("failed to configure auth: %w", err)
 	}
 
-	return &VMStorage{
-		c:                &http.Client{Transport: tr},
-		authCfg:          authCfg,
-		datasourceURL:    strings.TrimSuffix(*addr, "/"),
-		appendTypePrefix: *appendTypePrefix,
-		lookBack:         *lookBack,
-		queryStep:        *queryStep,
-		dataSourceType:   datasourcePrometheus,
-		extraParams:      extraParams,
-	}, nil
+	vms := make([]*VMStorage, 0, len(*addrs))
+	for _, addr := range *addrs {
+		tr, err := utils.Transport(addr, *tlsCertFile, *tlsKeyFile, *tlsCAFile, *tlsServerName, *tlsInsecureSkipVerify)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create transport: %w", err)
+		}
+		tr.DisableKeepAlives = *disableKeepAlive
+		tr.MaxIdleConnsPerHost = *maxIdleConnections
+		if tr.MaxIdleConns != 0 && tr.MaxIdleConns < tr.MaxIdleConnsPerHost {
+			tr.MaxIdleConns = tr.MaxIdleConnsPerHost
+		}
+		vms = append(vms, &VMStorage{
+			c:                &http.Client{Transport: tr},
+			authCfg:          authCfg,
+			datasourceURL:    strings.TrimSuffix(addr, "/"),
+			appendTypePrefix: *appendTypePrefix,
+			lookBack:         *lookBack,
+			queryStep:        *queryStep,
+			dataSourceType:   datasourcePrometheus,
+			extraParams:      extraParams,
+		})
+	}
+	if len(vms) == 1 {
+		return vms[0], nil
+	}
+	return newFailoverBuilder(vms, *failoverBackoff), nil
 }
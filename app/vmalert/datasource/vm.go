@@ -168,11 +168,25 @@ func (s *VMStorage) do(ctx context.Context, req *http.Request) (*http.Response,
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
-		return nil, fmt.Errorf("unexpected response code %d for %s. Response body %s", resp.StatusCode, req.URL.Redacted(), body)
+		return nil, &statusCodeError{
+			statusCode: resp.StatusCode,
+			msg:        fmt.Sprintf("unexpected response code %d for %s. Response body %s", resp.StatusCode, req.URL.Redacted(), body),
+		}
 	}
 	return resp, nil
 }
 
+// statusCodeError is returned when the datasource responds with unexpected status code.
+type statusCodeError struct {
+	statusCode int
+	msg        string
+}
+
+// Error implements error interface
+func (e *statusCodeError) Error() string {
+	return e.msg
+}
+
 func (s *VMStorage) newRequestPOST() (*http.Request, error) {
 	req, err := http.NewRequest("POST", s.datasourceURL, nil)
 	if err != nil {
@@ -32,9 +32,29 @@ type Group struct {
 	Interval       time.Duration
 	Limit          int
 	Concurrency    int
+	EvalOffset     *time.Duration
 	Checksum       string
 	LastEvaluation time.Time
 
+	// evalLayers contains Rules split into layers according to their dependencies.
+	// Layers are evaluated sequentially, so rules are evaluated after
+	// the recording rules they depend on.
+	evalLayers [][]Rule
+
+	// dependencies contains groups with recording rules used by rules from the group.
+	// The group evaluation waits for the evaluation of these groups.
+	dependencies []*Group
+	// hasDependants is set if recording rules from the group are used by other groups.
+	// Results of such groups are flushed to remote storage after every evaluation.
+	hasDependants bool
+
+	// evalMu protects lastEvalTS and evalDoneCh
+	evalMu sync.Mutex
+	// lastEvalTS is the timestamp of the last completed evaluation
+	lastEvalTS time.Time
+	// evalDoneCh is closed when the next evaluation is completed
+	evalDoneCh chan struct{}
+
 	Labels  map[string]string
 	Params  url.Values
 	Headers map[string]string
@@ -102,6 +122,7 @@ func newGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval ti
 
 		doneCh:     make(chan struct{}),
 		finishedCh: make(chan struct{}),
+		evalDoneCh: make(chan struct{}),
 		updateCh:   make(chan *Group),
 	}
 	if g.Interval == 0 {
@@ -110,6 +131,14 @@ func newGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval ti
 	if g.Concurrency < 1 {
 		g.Concurrency = 1
 	}
+	if cfg.EvalOffset != nil {
+		offset := cfg.EvalOffset.Duration()
+		if offset >= g.Interval {
+			logger.Warnf("group %q: eval_offset=%s exceeds interval=%s; using eval_offset=%s", g.Name, offset, g.Interval, offset%g.Interval)
+			offset %= g.Interval
+		}
+		g.EvalOffset = &offset
+	}
 	for _, h := range cfg.Headers {
 		g.Headers[h.Key] = h.Value
 	}
@@ -133,9 +162,37 @@ func newGroup(cfg config.Group, qb datasource.QuerierBuilder, defaultInterval ti
 		rules[i] = g.newRule(qb, r)
 	}
 	g.Rules = rules
+	g.evalLayers = g.getEvalLayers()
 	return g
 }
 
+// getEvalLayers splits g.Rules into layers for evaluation according to rules dependencies.
+//
+// All the rules are put into a single layer if they have cyclic dependencies.
+func (g *Group) getEvalLayers() [][]Rule {
+	cfgRules := make([]config.Rule, len(g.Rules))
+	for i, r := range g.Rules {
+		switch rule := r.(type) {
+		case *RecordingRule:
+			cfgRules[i] = config.Rule{Record: rule.Name, Expr: rule.Expr}
+		case *AlertingRule:
+			cfgRules[i] = config.Rule{Alert: rule.Name, Expr: rule.Expr}
+		}
+	}
+	layers, err := config.DependencyLayers(g.Type, cfgRules)
+	if err != nil {
+		logger.Warnf("group %q: %s; rules will be evaluated without taking into account their dependencies", g.Name, err)
+		return [][]Rule{g.Rules}
+	}
+	evalLayers := make([][]Rule, len(layers))
+	for i, layer := range layers {
+		for _, idx := range layer {
+			evalLayers[i] = append(evalLayers[i], g.Rules[idx])
+		}
+	}
+	return evalLayers
+}
+
 func (g *Group) newRule(qb datasource.QuerierBuilder, rule config.Rule) Rule {
 	if rule.Alert != "" {
 		return newAlertingRule(qb, g, rule)
@@ -230,6 +287,7 @@ func (g *Group) updateWith(newGroup *Group) error {
 	g.Limit = newGroup.Limit
 	g.Checksum = newGroup.Checksum
 	g.Rules = newRules
+	g.evalLayers = g.getEvalLayers()
 	return nil
 }
 
@@ -251,6 +309,56 @@ func (g *Group) close() {
 
 var skipRandSleepOnGroupStart bool
 
+// delayBeforeStart returns the delay before the first evaluation of the group with the given key.
+//
+// If offset is set, then the group is evaluated at interval*N+offset timestamps.
+// Otherwise evaluations for distinct groups are spread over the interval according to key
+// in order to reduce load on the datasource.
+func delayBeforeStart(ts time.Time, key uint64, interval time.Duration, offset *time.Duration) time.Duration {
+	var randSleep uint64
+	if offset != nil {
+		randSleep = uint64(*offset)
+	} else {
+		randSleep = uint64(float64(interval) * (float64(key) / (1 << 64)))
+	}
+	sleepOffset := uint64(ts.UnixNano()) % uint64(interval)
+	if randSleep < sleepOffset {
+		randSleep += uint64(interval)
+	}
+	randSleep -= sleepOffset
+	return time.Duration(randSleep)
+}
+
+// sleepBeforeStart sleeps for the delay returned by delayBeforeStart.
+//
+// It returns false if ctx is cancelled or the group is stopped during the sleep.
+func (g *Group) sleepBeforeStart(ctx context.Context) bool {
+	if skipRandSleepOnGroupStart {
+		return true
+	}
+	id := g.ID()
+	g.mu.RLock()
+	d := delayBeforeStart(time.Now(), id, g.Interval, g.EvalOffset)
+	g.mu.RUnlock()
+	sleepTimer := time.NewTimer(d)
+	defer sleepTimer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-g.doneCh:
+		return false
+	case <-sleepTimer.C:
+		return true
+	}
+}
+
+func equalDurations(a, b *time.Duration) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 func (g *Group) start(ctx context.Context, nts func() []notifier.Notifier, rw *remotewrite.Client, rr datasource.QuerierBuilder) {
 	defer func() { close(g.finishedCh) }()
 
@@ -274,15 +382,25 @@ func (g *Group) start(ctx context.Context, nts func() []notifier.Notifier, rw *r
 			return
 		}
 
+		g.waitForDependencies(ctx, ts)
+
 		resolveDuration := getResolveDuration(g.Interval, *resendDelay, *maxResolveDuration)
-		errs := e.execConcurrently(ctx, g.Rules, ts, g.Concurrency, resolveDuration, g.Limit)
-		for err := range errs {
-			if err != nil {
-				logger.Errorf("group %q: %s", g.Name, err)
+		for i, rules := range g.evalLayers {
+			errs := e.execConcurrently(ctx, rules, ts, g.Concurrency, resolveDuration, g.Limit)
+			for err := range errs {
+				if err != nil {
+					logger.Errorf("group %q: %s", g.Name, err)
+				}
+			}
+			// Recording rules results are buffered by remote write client.
+			// Flush them, so the rules from the next layer could query fresh results.
+			if i < len(g.evalLayers)-1 || g.getHasDependants() {
+				e.flushRemoteWrite(ctx, g.Name)
 			}
 		}
 		g.metrics.iterationDuration.UpdateDuration(start)
 		g.LastEvaluation = start
+		g.markEvaluated(ts)
 	}
 
 	eval(evalTS)
@@ -319,12 +437,25 @@ func (g *Group) start(ctx context.Context, nts func() []notifier.Notifier, rw *r
 			// ensure that staleness is tracked or existing rules only
 			e.purgeStaleSeries(g.Rules)
 
-			if g.Interval != ng.Interval {
+			realign := false
+			if g.Interval != ng.Interval || !equalDurations(g.EvalOffset, ng.EvalOffset) {
 				g.Interval = ng.Interval
+				g.EvalOffset = ng.EvalOffset
 				t.Stop()
 				t = time.NewTicker(g.Interval)
+				realign = g.EvalOffset != nil
 			}
 			g.mu.Unlock()
+			if realign {
+				// Wait until the next interval*N+eval_offset timestamp, so the group evaluations are aligned to the new eval_offset.
+				t.Stop()
+				if !g.sleepBeforeStart(ctx) {
+					return
+				}
+				t = time.NewTicker(g.Interval)
+				evalTS = time.Now()
+				eval(evalTS)
+			}
 			logger.Infof("group %q re-started; interval=%v; concurrency=%d", g.Name, g.Interval, g.Concurrency)
 		case <-t.C:
 			missed := (time.Since(evalTS) / g.Interval) - 1
@@ -338,6 +469,75 @@ func (g *Group) start(ctx context.Context, nts func() []notifier.Notifier, rw *r
 	}
 }
 
+// setDependencies sets groups g depends on and whether other groups depend on g.
+func (g *Group) setDependencies(dependencies []*Group, hasDependants bool) {
+	g.mu.Lock()
+	g.dependencies = dependencies
+	g.hasDependants = hasDependants
+	g.mu.Unlock()
+}
+
+func (g *Group) getHasDependants() bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.hasDependants
+}
+
+// waitForDependencies waits until the groups g depends on complete their evaluation
+// scheduled at or before ts, so rules from g could query fresh results of their recording rules.
+//
+// It waits for up to g.Interval, so stuck dependencies do not stop g evaluation.
+func (g *Group) waitForDependencies(ctx context.Context, ts time.Time) {
+	g.mu.RLock()
+	dependencies := g.dependencies
+	interval := g.Interval
+	g.mu.RUnlock()
+	if len(dependencies) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+	for _, dg := range dependencies {
+		if err := dg.waitForEvaluation(ctx, ts); err != nil {
+			logger.Warnf("group %q: cannot wait for evaluation of group %q it depends on: %s", g.Name, dg.Name, err)
+		}
+	}
+}
+
+// waitForEvaluation waits until g completes the evaluation scheduled at or before ts.
+func (g *Group) waitForEvaluation(ctx context.Context, ts time.Time) error {
+	g.mu.RLock()
+	interval := g.Interval
+	g.mu.RUnlock()
+	for {
+		g.evalMu.Lock()
+		ok := g.lastEvalTS.After(ts.Add(-interval))
+		evalDoneCh := g.evalDoneCh
+		g.evalMu.Unlock()
+		if ok {
+			return nil
+		}
+		select {
+		case <-evalDoneCh:
+		case <-g.doneCh:
+			return fmt.Errorf("group has been stopped")
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// markEvaluated notifies groups waiting for g evaluation that the evaluation at ts is completed.
+func (g *Group) markEvaluated(ts time.Time) {
+	g.evalMu.Lock()
+	g.lastEvalTS = ts
+	if g.evalDoneCh != nil {
+		close(g.evalDoneCh)
+	}
+	g.evalDoneCh = make(chan struct{})
+	g.evalMu.Unlock()
+}
+
 // getResolveDuration returns the duration after which firing alert
 // can be considered as resolved.
 func getResolveDuration(groupInterval, delta, maxDuration time.Duration) time.Duration {
@@ -363,6 +563,16 @@ type executor struct {
 	previouslySentSeriesToRW map[uint64]map[string][]prompbmarshal.Label
 }
 
+// flushRemoteWrite sends buffered recording rules results to remote storage.
+func (e *executor) flushRemoteWrite(ctx context.Context, groupName string) {
+	if e.rw == nil {
+		return
+	}
+	if err := e.rw.Flush(ctx); err != nil {
+		logger.Errorf("group %q: cannot flush recording rules results to remote storage: %s", groupName, err)
+	}
+}
+
 func (e *executor) execConcurrently(ctx context.Context, rules []Rule, ts time.Time, concurrency int, resolveDuration time.Duration, limit int) chan error {
 	res := make(chan error, len(rules))
 	if concurrency == 1 {
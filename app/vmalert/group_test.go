@@ -269,6 +269,36 @@ func TestResolveDuration(t *testing.T) {
 	}
 }
 
+func TestDelayBeforeStart(t *testing.T) {
+	offset := func(d time.Duration) *time.Duration {
+		return &d
+	}
+	ts := time.Unix(1000, 0)
+	testCases := []struct {
+		ts       time.Time
+		key      uint64
+		interval time.Duration
+		offset   *time.Duration
+		expected time.Duration
+	}{
+		// eval_offset after the current position within the interval
+		{ts, 0, time.Minute, offset(50 * time.Second), 10 * time.Second},
+		// eval_offset before the current position within the interval
+		{ts, 0, time.Minute, offset(30 * time.Second), 50 * time.Second},
+		// eval_offset matching the current position within the interval
+		{ts, 0, time.Minute, offset(40 * time.Second), 0},
+		// groups without eval_offset are spread over the interval according to key
+		{ts, 0, time.Minute, nil, 20 * time.Second},
+		{ts, 1 << 63, time.Minute, nil, 50 * time.Second},
+	}
+	for _, tc := range testCases {
+		got := delayBeforeStart(tc.ts, tc.key, tc.interval, tc.offset)
+		if got != tc.expected {
+			t.Fatalf("unexpected delay for key=%d, interval=%s, offset=%v; got %s; want %s", tc.key, tc.interval, tc.offset, got, tc.expected)
+		}
+	}
+}
+
 func TestGetStaleSeries(t *testing.T) {
 	ts := time.Now()
 	e := &executor{
@@ -474,3 +504,91 @@ func TestFaultyRW(t *testing.T) {
 		t.Fatalf("expected to get an error from faulty RW client, got nil instead")
 	}
 }
+
+func TestGroupStartDependentRules(t *testing.T) {
+	fq := &fakeQuerierWithRegistry{}
+	fq.set("bar", metricWithValueAndLabels(t, 42, "__name__", "bar"))
+	rs := newFakeRemoteStorage(t, fq)
+	defer rs.Close()
+
+	// flush interval is big enough, so recording rules results
+	// could be visible to dependent rules only via explicit flush
+	rw, err := remotewrite.NewClient(context.Background(), remotewrite.Config{
+		Addr:          rs.URL,
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer func() { _ = rw.Close() }()
+
+	// getMetric waits for g evaluation, flushes its results and returns the value of the given metric
+	getMetric := func(t *testing.T, g *Group, name string) float64 {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := g.waitForEvaluation(ctx, time.Now()); err != nil {
+			t.Fatalf("cannot wait for group %q evaluation: %s", g.Name, err)
+		}
+		if err := rw.Flush(ctx); err != nil {
+			t.Fatalf("cannot flush remote write: %s", err)
+		}
+		ms, _, _ := fq.Query(ctx, name, time.Now())
+		if len(ms) == 0 {
+			t.Fatalf("missing results for %q", name)
+		}
+		return ms[0].Values[0]
+	}
+
+	t.Run("same group", func(t *testing.T) {
+		fq.set("foo")
+		fq.set("baz")
+		// rules are defined in the reverse order of their dependencies
+		g := newGroup(config.Group{
+			Name:     "dependent",
+			Interval: promutils.NewDuration(time.Hour),
+			Rules: []config.Rule{
+				{ID: 1, Record: "baz", Expr: "foo"},
+				{ID: 2, Record: "foo", Expr: "bar"},
+			},
+		}, fq, time.Hour, nil)
+		go g.start(context.Background(), nil, rw, nil)
+		defer g.close()
+
+		if v := getMetric(t, g, "baz"); v != 42 {
+			t.Fatalf("dependent rule must see fresh results of the recording rule; got %v; want %v", v, 42)
+		}
+	})
+
+	t.Run("distinct groups", func(t *testing.T) {
+		fq.set("foo")
+		fq.set("baz")
+		ga := newGroup(config.Group{
+			Name:     "a",
+			Interval: promutils.NewDuration(time.Hour),
+			Rules: []config.Rule{
+				{ID: 1, Record: "foo", Expr: "bar"},
+			},
+		}, fq, time.Hour, nil)
+		gb := newGroup(config.Group{
+			Name:     "b",
+			Interval: promutils.NewDuration(time.Hour),
+			Rules: []config.Rule{
+				{ID: 2, Record: "baz", Expr: "foo"},
+			},
+		}, fq, time.Hour, nil)
+		ga.setDependencies(nil, true)
+		gb.setDependencies([]*Group{ga}, false)
+
+		// start the dependent group before the group it depends on
+		go gb.start(context.Background(), nil, rw, nil)
+		defer gb.close()
+		time.Sleep(100 * time.Millisecond)
+		go ga.start(context.Background(), nil, rw, nil)
+		defer ga.close()
+
+		if v := getMetric(t, gb, "baz"); v != 42 {
+			t.Fatalf("dependent group must see fresh results of the recording rule; got %v; want %v", v, 42)
+		}
+	})
+}
@@ -3,15 +3,20 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"sort"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
+
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
@@ -104,6 +109,51 @@ func (fqr *fakeQuerierWithRegistry) Query(_ context.Context, expr string, _ time
 	return cp, req, nil
 }
 
+// fakeRemoteStorage accepts remote write requests and makes the received samples
+// available for querying via fakeQuerierWithRegistry by metric name.
+type fakeRemoteStorage struct {
+	*httptest.Server
+	fq *fakeQuerierWithRegistry
+}
+
+func newFakeRemoteStorage(t *testing.T, fq *fakeQuerierWithRegistry) *fakeRemoteStorage {
+	rs := &fakeRemoteStorage{fq: fq}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+			return
+		}
+		b, err := snappy.Decode(nil, data)
+		if err != nil {
+			t.Errorf("cannot decode request body: %s", err)
+			return
+		}
+		wr := &prompb.WriteRequest{}
+		if err := wr.Unmarshal(b); err != nil {
+			t.Errorf("cannot unmarshal request body: %s", err)
+			return
+		}
+		for _, ts := range wr.Timeseries {
+			var m datasource.Metric
+			var name string
+			for _, l := range ts.Labels {
+				if string(l.Name) == "__name__" {
+					name = string(l.Value)
+				}
+				m.AddLabel(string(l.Name), string(l.Value))
+			}
+			for _, s := range ts.Samples {
+				m.Values = append(m.Values, s.Value)
+				m.Timestamps = append(m.Timestamps, s.Timestamp)
+			}
+			fq.set(name, m)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	return rs
+}
+
 type fakeNotifier struct {
 	sync.Mutex
 	alerts []notifier.Alert
@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
@@ -15,13 +16,17 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/remoteread"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/report"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/templates"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/configreload"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envflag"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/pushmetrics"
 	"github.com/VictoriaMetrics/metrics"
@@ -159,13 +164,14 @@ func main() {
 		return
 	}
 
+	auditlog.Init()
 	ctx, cancel := context.WithCancel(context.Background())
 	manager, err := newManager(ctx)
 	if err != nil {
 		logger.Fatalf("failed to init: %s", err)
 	}
 	logger.Infof("reading rules configuration file from %q", strings.Join(*rulePath, ";"))
-	groupsCfg, err := config.Parse(*rulePath, validateTplFn, *validateExpressions)
+	groupsCfg, groupsHash, err := config.ParseWithHash(*rulePath, validateTplFn, *validateExpressions)
 	if err != nil {
 		logger.Fatalf("cannot parse configuration file: %s", err)
 	}
@@ -178,9 +184,18 @@ func main() {
 	if err := manager.start(ctx, groupsCfg); err != nil {
 		logger.Fatalf("failed to start: %s", err)
 	}
+	configTracker.SetApplied(groupsHash)
 
 	go configReload(ctx, manager, groupsCfg, sighupCh)
 
+	reports, err := report.Init(manager.querierBuilder)
+	if err != nil {
+		logger.Fatalf("failed to init reports: %s", err)
+	}
+	if reports != nil {
+		reports.Start(ctx)
+	}
+
 	rh := &requestHandler{m: manager}
 	go httpserver.Serve(*httpListenAddr, *useProxyProtocol, rh.handler)
 
@@ -191,9 +206,15 @@ func main() {
 	}
 	cancel()
 	manager.close()
+	if reports != nil {
+		reports.Close()
+	}
+	auditlog.MustStop()
 }
 
 var (
+	configTracker = configreload.NewTracker("vmalert", "-rule")
+
 	configReloads      = metrics.NewCounter(`vmalert_config_last_reload_total`)
 	configReloadErrors = metrics.NewCounter(`vmalert_config_last_reload_errors_total`)
 	configSuccess      = metrics.NewCounter(`vmalert_config_last_reload_successful`)
@@ -252,8 +273,11 @@ func getExternalURL(externalURL, httpListenAddr string, isSecure bool) (*url.URL
 		return nil, err
 	}
 	port := ""
-	if ipport := strings.Split(httpListenAddr, ":"); len(ipport) > 1 {
-		port = ":" + ipport[1]
+	// Use the port from the first address if -httpListenAddr contains multiple addresses.
+	if addrs := netutil.ParseListenAddrs(httpListenAddr); len(addrs) > 0 {
+		if _, p, err := net.SplitHostPort(addrs[0]); err == nil {
+			port = ":" + p
+		}
 	}
 	schema := "http://"
 	if isSecure {
@@ -318,7 +342,16 @@ func configReload(ctx context.Context, m *manager, groupsCfg []config.Group, sig
 	// init reload metrics with positive values to improve alerting conditions
 	configSuccess.Set(1)
 	configTimestamp.Set(fasttime.UnixTimestamp())
+
+	// fileHash contains the hash of rule files loaded the last time.
+	// It may differ from the hash of groupsCfg after the rollback to the previous rules.
+	fileHash := configTracker.Current().Hash
+
+	// prevGroupsCfg contains the previously applied rules for rollback.
+	var prevGroupsCfg []config.Group
 	for {
+		var req *configreload.Request
+		isCheck := false
 		select {
 		case <-ctx.Done():
 			return
@@ -330,46 +363,92 @@ func configReload(ctx context.Context, m *manager, groupsCfg []config.Group, sig
 			logger.Infof("SIGHUP received. Going to reload rules %q %s...", *rulePath, tmplMsg)
 			configReloads.Inc()
 		case <-configCheckCh:
+			isCheck = true
+		case req = <-configTracker.Requests():
+			if req.IsRollback {
+				if prevGroupsCfg == nil {
+					req.Done(configreload.ErrNoPreviousVersion)
+					continue
+				}
+				if err := m.update(ctx, prevGroupsCfg, false); err != nil {
+					configReloadErrors.Inc()
+					configSuccess.Set(0)
+					logger.Errorf("error while rolling back rules: %s", err)
+					req.Done(fmt.Errorf("error while rolling back rules: %w", err))
+					continue
+				}
+				groupsCfg = prevGroupsCfg
+				prevGroupsCfg = nil
+				configTracker.SetRolledBack()
+				configSuccess.Set(1)
+				configTimestamp.Set(fasttime.UnixTimestamp())
+				logger.Infof("Rules rolled back to the previous version")
+				req.Done(nil)
+				continue
+			}
+			logger.Infof("API reload request received. Going to reload rules %q...", *rulePath)
+			configReloads.Inc()
 		}
-		if err := notifier.Reload(); err != nil {
-			configReloadErrors.Inc()
-			configSuccess.Set(0)
-			logger.Errorf("failed to reload notifier config: %s", err)
-			continue
+		done := func(err error) {
+			if req != nil {
+				req.Done(err)
+			}
 		}
 		err := templates.Load(*ruleTemplatesPath, false)
 		if err != nil {
 			configReloadErrors.Inc()
 			configSuccess.Set(0)
 			logger.Errorf("failed to load new templates: %s", err)
+			done(fmt.Errorf("failed to load new templates: %w", err))
 			continue
 		}
-		newGroupsCfg, err := config.Parse(*rulePath, validateTplFn, *validateExpressions)
+		newGroupsCfg, newHash, err := config.ParseWithHash(*rulePath, validateTplFn, *validateExpressions)
 		if err != nil {
 			configReloadErrors.Inc()
 			configSuccess.Set(0)
 			logger.Errorf("cannot parse configuration file: %s", err)
+			done(fmt.Errorf("cannot parse configuration file: %w", err))
+			continue
+		}
+		if req != nil && req.ExpectedHash != "" && req.ExpectedHash != newHash {
+			// Do not apply any changes, including notifier config, if rules do not match the expected hash.
+			req.Done(configreload.NewHashMismatchError(req.ExpectedHash, newHash))
+			continue
+		}
+		if err := notifier.Reload(); err != nil {
+			configReloadErrors.Inc()
+			configSuccess.Set(0)
+			logger.Errorf("failed to reload notifier config: %s", err)
+			done(fmt.Errorf("failed to reload notifier config: %w", err))
 			continue
 		}
-		if configsEqual(newGroupsCfg, groupsCfg) {
+		// Periodic checks do not re-apply rules after the rollback until rule files are changed.
+		rulesUnchanged := isCheck && newHash == fileHash
+		fileHash = newHash
+		if rulesUnchanged || configsEqual(newGroupsCfg, groupsCfg) {
 			templates.Reload()
 			// set success to 1 since previous reload
 			// could have been unsuccessful
 			configSuccess.Set(1)
 			// config didn't change - skip it
+			done(nil)
 			continue
 		}
 		if err := m.update(ctx, newGroupsCfg, false); err != nil {
 			configReloadErrors.Inc()
 			configSuccess.Set(0)
 			logger.Errorf("error while reloading rules: %s", err)
+			done(fmt.Errorf("error while reloading rules: %w", err))
 			continue
 		}
 		templates.Reload()
+		prevGroupsCfg = groupsCfg
 		groupsCfg = newGroupsCfg
+		configTracker.SetApplied(newHash)
 		configSuccess.Set(1)
 		configTimestamp.Set(fasttime.UnixTimestamp())
 		logger.Infof("Rules reloaded successfully from %q", *rulePath)
+		done(nil)
 	}
 }
 
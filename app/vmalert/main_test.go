@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/remotewrite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/configreload"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 )
 
@@ -31,6 +35,13 @@ func TestGetExternalURL(t *testing.T) {
 	if u.String() != expURL {
 		t.Errorf("unexpected url want %s, got %s", expURL, u.String())
 	}
+	u, err = getExternalURL("", "[::]:4242,0.0.0.0:4343", true)
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if u.String() != expURL {
+		t.Errorf("unexpected url want %s, got %s", expURL, u.String())
+	}
 }
 
 func TestGetAlertURLGenerator(t *testing.T) {
@@ -151,6 +162,114 @@ groups:
 	<-syncCh
 }
 
+func TestConfigReloadAPI(t *testing.T) {
+	originalRulePath := *rulePath
+	originalRulesCheckInterval := *rulesCheckInterval
+	defer func() {
+		*rulePath = originalRulePath
+		*rulesCheckInterval = originalRulesCheckInterval
+	}()
+
+	const (
+		rules1 = `
+groups:
+  - name: group-1
+    rules:
+      - record: job:up:sum
+        expr: sum by(job) (up)
+`
+		rules2 = `
+groups:
+  - name: group-1
+    rules:
+      - record: job:up:sum
+        expr: sum by(job) (up)
+  - name: group-2
+    rules:
+      - record: job:up:count
+        expr: count by(job) (up)
+`
+	)
+
+	rulesFile, err := os.CreateTemp("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(rulesFile.Name()) }()
+	writeToFile(t, rulesFile.Name(), rules1)
+	*rulePath = []string{rulesFile.Name()}
+	*rulesCheckInterval = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &manager{
+		querierBuilder: &fakeQuerier{},
+		groups:         make(map[uint64]*Group),
+		labels:         map[string]string{},
+		notifiers:      func() []notifier.Notifier { return []notifier.Notifier{&fakeNotifier{}} },
+		rw:             &remotewrite.Client{},
+	}
+	groupsCfg, hash, err := config.ParseWithHash(*rulePath, notifier.ValidateTemplates, true)
+	if err != nil {
+		t.Fatalf("cannot parse rules: %s", err)
+	}
+	if err := m.update(ctx, groupsCfg, false); err != nil {
+		t.Fatalf("cannot start rules: %s", err)
+	}
+	configTracker.SetApplied(hash)
+
+	syncCh := make(chan struct{})
+	go func() {
+		configReload(ctx, m, groupsCfg, make(chan os.Signal))
+		close(syncCh)
+	}()
+
+	rh := &requestHandler{m: m}
+	f := func(method, path string, statusCodeExpected, groupsExpected int) {
+		t.Helper()
+		r := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		rh.handler(w, r)
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code for %s %s; got %d; want %d; response: %s", method, path, w.Code, statusCodeExpected, w.Body.String())
+		}
+		m.groupsMu.RLock()
+		groupsLen := len(m.groups)
+		m.groupsMu.RUnlock()
+		if groupsLen != groupsExpected {
+			t.Fatalf("unexpected number of groups after %s %s; got %d; want %d", method, path, groupsLen, groupsExpected)
+		}
+	}
+
+	writeToFile(t, rulesFile.Name(), rules2)
+	hash2 := configreload.Hash([]byte(rules2))
+	version := configTracker.Current().Version
+
+	// Rules mustn't be applied if they do not match the expected hash
+	f("GET", "/-/reload?config_hash="+hash, http.StatusPreconditionFailed, 1)
+	if v := configTracker.Current(); v.Version != version || v.Hash != hash {
+		t.Fatalf("unexpected config version after failed reload: %+v", v)
+	}
+
+	// Rules must be applied if they match the expected hash
+	f("GET", "/-/reload?config_hash="+hash2, http.StatusOK, 2)
+	if v := configTracker.Current(); v.Version != version+1 || v.Hash != hash2 {
+		t.Fatalf("unexpected config version after reload: %+v", v)
+	}
+
+	// Rollback must apply the previous rules
+	f("GET", "/-/rollback", http.StatusBadRequest, 2)
+	f("POST", "/-/rollback", http.StatusOK, 1)
+	if v := configTracker.Current(); v.Version != version+2 || v.Hash != hash {
+		t.Fatalf("unexpected config version after rollback: %+v", v)
+	}
+
+	// There is no previous version after the rollback
+	f("POST", "/-/rollback", http.StatusConflict, 1)
+
+	cancel()
+	<-syncCh
+}
+
 func writeToFile(t *testing.T, file, b string) {
 	t.Helper()
 	err := os.WriteFile(file, []byte(b), 0644)
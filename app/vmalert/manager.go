@@ -33,19 +33,28 @@ type manager struct {
 
 // RuleAPI generates APIRule object from alert by its ID(hash)
 func (m *manager) RuleAPI(gID, rID uint64) (APIRule, error) {
+	rule, err := m.ruleByID(gID, rID)
+	if err != nil {
+		return APIRule{}, err
+	}
+	return rule.ToAPI(), nil
+}
+
+// ruleByID returns the rule with the given ID(hash) from the group with gID
+func (m *manager) ruleByID(gID, rID uint64) (Rule, error) {
 	m.groupsMu.RLock()
 	defer m.groupsMu.RUnlock()
 
 	g, ok := m.groups[gID]
 	if !ok {
-		return APIRule{}, fmt.Errorf("can't find group with id %d", gID)
+		return nil, fmt.Errorf("can't find group with id %d", gID)
 	}
 	for _, rule := range g.Rules {
 		if rule.ID() == rID {
-			return rule.ToAPI(), nil
+			return rule, nil
 		}
 	}
-	return APIRule{}, fmt.Errorf("can't find rule with id %d in group %q", rID, g.Name)
+	return nil, fmt.Errorf("can't find rule with id %d in group %q", rID, g.Name)
 }
 
 // AlertAPI generates APIAlert object from alert by its ID(hash)
@@ -88,23 +97,10 @@ func (m *manager) startGroup(ctx context.Context, g *Group, restore bool) error
 	id := g.ID()
 	go func() {
 		// Spread group rules evaluation over time in order to reduce load on VictoriaMetrics.
-		if !skipRandSleepOnGroupStart {
-			randSleep := uint64(float64(g.Interval) * (float64(g.ID()) / (1 << 64)))
-			sleepOffset := uint64(time.Now().UnixNano()) % uint64(g.Interval)
-			if randSleep < sleepOffset {
-				randSleep += uint64(g.Interval)
-			}
-			randSleep -= sleepOffset
-			sleepTimer := time.NewTimer(time.Duration(randSleep))
-			select {
-			case <-ctx.Done():
-				sleepTimer.Stop()
-				return
-			case <-g.doneCh:
-				sleepTimer.Stop()
-				return
-			case <-sleepTimer.C:
-			}
+		if !g.sleepBeforeStart(ctx) {
+			close(g.finishedCh)
+			m.wg.Done()
+			return
 		}
 		if restore {
 			g.start(ctx, m.notifiers, m.rw, m.rr)
@@ -119,9 +115,15 @@ func (m *manager) startGroup(ctx context.Context, g *Group, restore bool) error
 }
 
 func (m *manager) update(ctx context.Context, groupsCfg []config.Group, restore bool) error {
+	groupDeps, err := config.GroupDependencies(groupsCfg)
+	if err != nil {
+		return err
+	}
+
 	var rrPresent, arPresent bool
 	groupsRegistry := make(map[uint64]*Group)
-	for _, cfg := range groupsCfg {
+	groupIDs := make([]uint64, len(groupsCfg))
+	for i, cfg := range groupsCfg {
 		for _, r := range cfg.Rules {
 			if rrPresent && arPresent {
 				continue
@@ -135,6 +137,7 @@ func (m *manager) update(ctx context.Context, groupsCfg []config.Group, restore
 		}
 		ng := newGroup(cfg, m.querierBuilder, *evaluationInterval, m.labels)
 		groupsRegistry[ng.ID()] = ng
+		groupIDs[i] = ng.ID()
 	}
 
 	if rrPresent && m.rw == nil {
@@ -171,6 +174,7 @@ func (m *manager) update(ctx context.Context, groupsCfg []config.Group, restore
 			return err
 		}
 	}
+	m.setGroupDependencies(groupIDs, groupDeps)
 	m.groupsMu.Unlock()
 
 	if len(toUpdate) > 0 {
@@ -187,6 +191,32 @@ func (m *manager) update(ctx context.Context, groupsCfg []config.Group, restore
 	return nil
 }
 
+// setGroupDependencies links running groups with the groups they depend on.
+//
+// groupIDs contains group IDs, while groupDeps contains indexes of groupIDs
+// every group depends on. m.groupsMu must be locked by the caller.
+func (m *manager) setGroupDependencies(groupIDs []uint64, groupDeps [][]int) {
+	hasDependants := make(map[uint64]bool)
+	for _, deps := range groupDeps {
+		for _, j := range deps {
+			hasDependants[groupIDs[j]] = true
+		}
+	}
+	for i, id := range groupIDs {
+		g, ok := m.groups[id]
+		if !ok {
+			continue
+		}
+		var dependencies []*Group
+		for _, j := range groupDeps[i] {
+			if dg, ok := m.groups[groupIDs[j]]; ok {
+				dependencies = append(dependencies, dg)
+			}
+		}
+		g.setDependencies(dependencies, hasDependants[id])
+	}
+}
+
 func (g *Group) toAPI() APIGroup {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
@@ -201,6 +231,7 @@ func (g *Group) toAPI() APIGroup {
 		Interval:       g.Interval.Seconds(),
 		LastEvaluation: g.LastEvaluation,
 		Concurrency:    g.Concurrency,
+		EvalOffset:     durationSeconds(g.EvalOffset),
 		Params:         urlValuesToStrings(g.Params),
 		Headers:        headersToStrings(g.Headers),
 		Labels:         g.Labels,
@@ -211,6 +242,13 @@ func (g *Group) toAPI() APIGroup {
 	return ag
 }
 
+func durationSeconds(d *time.Duration) float64 {
+	if d == nil {
+		return 0
+	}
+	return d.Seconds()
+}
+
 func urlValuesToStrings(values url.Values) []string {
 	if len(values) < 1 {
 		return nil
@@ -3,15 +3,19 @@ package notifier
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 )
 
 // AlertManager represents integration provider with Prometheus alert manager
@@ -26,38 +30,215 @@ type AlertManager struct {
 	// stores already parsed RelabelConfigs object
 	relabelConfigs *promrelabel.ParsedConfigs
 
+	opts sendOptions
+
+	// q holds alerts waiting for delivery to addr.
+	// It is nil if opts.isQueued() returns false.
+	q *sendQueue
+
+	// rl limits the number of requests per second to addr
+	rl *rateLimiter
+
+	// stopCh and cancel are used for stopping the queue worker
+	stopCh chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
 	metrics *metrics
 }
 
 type metrics struct {
-	alertsSent       *utils.Counter
-	alertsSendErrors *utils.Counter
+	alertsSent        *utils.Counter
+	alertsSendErrors  *utils.Counter
+	alertsSendRetries *utils.Counter
 }
 
 func newMetrics(addr string) *metrics {
 	return &metrics{
-		alertsSent:       utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
-		alertsSendErrors: utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
+		alertsSent:        utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_sent_total{addr=%q}", addr)),
+		alertsSendErrors:  utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_send_errors_total{addr=%q}", addr)),
+		alertsSendRetries: utils.GetOrCreateCounter(fmt.Sprintf("vmalert_alerts_send_retries_total{addr=%q}", addr)),
 	}
 }
 
 // Close is a destructor method for AlertManager
 func (am *AlertManager) Close() {
+	if am.q != nil {
+		close(am.stopCh)
+		am.cancel()
+		am.wg.Wait()
+		if alerts := am.q.pop(0); len(alerts) > 0 {
+			am.metrics.alertsSendErrors.Add(len(alerts))
+			deadLetters.add(am.addr, alerts, fmt.Errorf("notifier %q has been stopped before sending the alerts", am.addr))
+		}
+	}
 	am.metrics.alertsSent.Unregister()
 	am.metrics.alertsSendErrors.Unregister()
+	am.metrics.alertsSendRetries.Unregister()
 }
 
 // Addr returns address where alerts are sent.
-func (am AlertManager) Addr() string { return am.addr }
+func (am *AlertManager) Addr() string { return am.addr }
 
-// Send an alert or resolve message
+// Send an alert or resolve message.
+//
+// If retries, batching or rate limiting are enabled, then alerts are put into
+// the per-notifier queue and are delivered in background, so the caller isn't blocked.
+// Alerts, which couldn't be delivered, are put into the dead-letter queue.
 func (am *AlertManager) Send(ctx context.Context, alerts []Alert) error {
-	am.metrics.alertsSent.Add(len(alerts))
-	err := am.send(ctx, alerts)
-	if err != nil {
+	if am.q == nil {
+		am.metrics.alertsSent.Add(len(alerts))
+		if err := am.send(ctx, alerts); err != nil {
+			am.metrics.alertsSendErrors.Add(len(alerts))
+			deadLetters.add(am.addr, alerts, err)
+			return err
+		}
+		return nil
+	}
+	if err := am.q.push(alerts); err != nil {
 		am.metrics.alertsSendErrors.Add(len(alerts))
+		deadLetters.add(am.addr, alerts, err)
+		return err
+	}
+	return nil
+}
+
+// runQueueWorker sends alerts from am.q in batches of up to opts.maxBatchSize alerts
+// until am.stopCh is closed.
+func (am *AlertManager) runQueueWorker(ctx context.Context) {
+	defer am.wg.Done()
+	for {
+		select {
+		case <-am.stopCh:
+			return
+		case <-am.q.notifyCh:
+		}
+		for {
+			// Alerts from multiple rules, which were queued while the previous batch
+			// has been sent, are merged into a single request.
+			batch := am.q.pop(am.opts.maxBatchSize)
+			if len(batch) == 0 {
+				break
+			}
+			am.metrics.alertsSent.Add(len(batch))
+			if err := am.sendWithRetries(ctx, batch); err != nil {
+				am.metrics.alertsSendErrors.Add(len(batch))
+				deadLetters.add(am.addr, batch, err)
+				logger.Errorf("cannot send %d alerts to %q: %s", len(batch), am.addr, err)
+			}
+		}
+	}
+}
+
+// sendWithRetries sends alerts to am.addr and retries temporary errors
+// with exponential backoff starting from opts.retryMinInterval.
+func (am *AlertManager) sendWithRetries(ctx context.Context, alerts []Alert) error {
+	retryInterval := am.opts.retryMinInterval
+	for retries := 0; ; retries++ {
+		if err := am.rl.wait(ctx); err != nil {
+			return err
+		}
+		err := am.send(ctx, alerts)
+		if err == nil || retries >= am.opts.maxRetries || !isRetriableError(err) {
+			return err
+		}
+		am.metrics.alertsSendRetries.Add(len(alerts))
+		logger.Warnf("cannot send %d alerts to %q: %s; retrying in %.3f seconds", len(alerts), am.addr, err, retryInterval.Seconds())
+		t := timerpool.Get(retryInterval)
+		select {
+		case <-ctx.Done():
+			timerpool.Put(t)
+			return err
+		case <-t.C:
+			timerpool.Put(t)
+		}
+		retryInterval *= 2
+		if retryInterval > am.opts.retryMaxInterval {
+			retryInterval = am.opts.retryMaxInterval
+		}
+	}
+}
+
+// sendQueue holds alerts waiting for delivery.
+type sendQueue struct {
+	maxSize int
+
+	// notifyCh is notified when new alerts are pushed to the queue
+	notifyCh chan struct{}
+
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func newSendQueue(maxSize int) *sendQueue {
+	return &sendQueue{
+		maxSize:  maxSize,
+		notifyCh: make(chan struct{}, 1),
+	}
+}
+
+// push adds alerts to q. It returns an error if q has no space for alerts.
+func (q *sendQueue) push(alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	q.mu.Lock()
+	if len(q.alerts)+len(alerts) > q.maxSize {
+		n := len(q.alerts)
+		q.mu.Unlock()
+		return fmt.Errorf("cannot queue %d alerts, since the queue already contains %d alerts out of %d allowed; "+
+			"the notifier is too slow or unavailable", len(alerts), n, q.maxSize)
 	}
-	return err
+	q.alerts = append(q.alerts, alerts...)
+	q.mu.Unlock()
+
+	select {
+	case q.notifyCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop removes up to maxAlerts alerts from q and returns them.
+//
+// All the alerts are returned if maxAlerts <= 0.
+func (q *sendQueue) pop(maxAlerts int) []Alert {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := len(q.alerts)
+	if maxAlerts > 0 && n > maxAlerts {
+		n = maxAlerts
+	}
+	if n == 0 {
+		return nil
+	}
+	alerts := append([]Alert{}, q.alerts[:n]...)
+	q.alerts = append(q.alerts[:0], q.alerts[n:]...)
+	return alerts
+}
+
+// statusCodeError is returned when the notifier responds with unexpected status code.
+type statusCodeError struct {
+	statusCode int
+	addr       string
+	body       []byte
+}
+
+func (e *statusCodeError) Error() string {
+	return fmt.Sprintf("invalid SC %d from %q; response body: %s", e.statusCode, e.addr, e.body)
+}
+
+// isRetriableError returns true if the request failed with err may succeed on retry.
+func isRetriableError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var sce *statusCodeError
+	if errors.As(err, &sce) {
+		return sce.statusCode == http.StatusTooManyRequests || sce.statusCode >= 500
+	}
+	// Network errors and timeouts.
+	return true
 }
 
 func (am *AlertManager) send(ctx context.Context, alerts []Alert) error {
@@ -93,7 +274,11 @@ func (am *AlertManager) send(ctx context.Context, alerts []Alert) error {
 		if err != nil {
 			return fmt.Errorf("failed to read response from %q: %w", am.addr, err)
 		}
-		return fmt.Errorf("invalid SC %d from %q; response body: %s", resp.StatusCode, am.addr, string(body))
+		return &statusCodeError{
+			statusCode: resp.StatusCode,
+			addr:       am.addr,
+			body:       body,
+		}
 	}
 	return nil
 }
@@ -104,7 +289,7 @@ type AlertURLGenerator func(Alert) string
 const alertManagerPath = "/api/v2/alerts"
 
 // NewAlertManager is a constructor for AlertManager
-func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg promauth.HTTPClientConfig,
+func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg promauth.HTTPClientConfig, sendCfg SendConfig,
 	relabelCfg *promrelabel.ParsedConfigs, timeout time.Duration) (*AlertManager, error) {
 	tls := &promauth.TLSConfig{}
 	if authCfg.TLSConfig != nil {
@@ -132,13 +317,75 @@ func NewAlertManager(alertManagerURL string, fn AlertURLGenerator, authCfg proma
 		return nil, fmt.Errorf("failed to configure auth: %w", err)
 	}
 
-	return &AlertManager{
+	am := &AlertManager{
 		addr:           alertManagerURL,
 		argFunc:        fn,
 		authCfg:        aCfg,
 		relabelConfigs: relabelCfg,
 		client:         &http.Client{Transport: tr},
 		timeout:        timeout,
+		opts:           sendCfg.options(),
 		metrics:        newMetrics(alertManagerURL),
-	}, nil
+	}
+	if am.opts.isQueued() {
+		am.q = newSendQueue(am.opts.queueSize)
+		am.rl = newRateLimiter(am.opts.rateLimit)
+		am.stopCh = make(chan struct{})
+		ctx, cancel := context.WithCancel(context.Background())
+		am.cancel = cancel
+		am.wg.Add(1)
+		go am.runQueueWorker(ctx)
+	}
+	return am, nil
+}
+
+// rateLimiter limits the number of requests per second.
+type rateLimiter struct {
+	// interval is the minimum interval between requests
+	interval time.Duration
+
+	// mu protects next from concurrent access.
+	mu sync.Mutex
+
+	// next is the time when the next request is allowed.
+	next time.Time
+}
+
+func newRateLimiter(perSecondLimit int) *rateLimiter {
+	if perSecondLimit <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		interval: time.Second / time.Duration(perSecondLimit),
+	}
+}
+
+// wait blocks until the next request is allowed or ctx is cancelled.
+func (rl *rateLimiter) wait(ctx context.Context) error {
+	if rl == nil {
+		return nil
+	}
+
+	// Reserve the time slot for the request under the lock and wait for it without the lock,
+	// so concurrent callers aren't blocked by each other.
+	rl.mu.Lock()
+	now := time.Now()
+	if rl.next.Before(now) {
+		rl.next = now
+	}
+	d := rl.next.Sub(now)
+	rl.next = rl.next.Add(rl.interval)
+	rl.mu.Unlock()
+
+	if d <= 0 {
+		return nil
+	}
+	t := timerpool.Get(d)
+	defer timerpool.Put(t)
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("cannot wait for the rate limit: %w", ctx.Err())
+	case <-t.C:
+		return nil
+	}
 }
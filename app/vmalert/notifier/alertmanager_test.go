@@ -6,15 +6,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 )
 
 func TestAlertManager_Addr(t *testing.T) {
 	const addr = "http://localhost"
-	am, err := NewAlertManager(addr, nil, promauth.HTTPClientConfig{}, nil, 0)
+	am, err := NewAlertManager(addr, nil, promauth.HTTPClientConfig{}, SendConfig{}, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
 	}
@@ -86,7 +88,7 @@ func TestAlertManager_Send(t *testing.T) {
 	}
 	am, err := NewAlertManager(srv.URL+alertManagerPath, func(alert Alert) string {
 		return strconv.FormatUint(alert.GroupID, 10) + "/" + strconv.FormatUint(alert.ID, 10)
-	}, aCfg, nil, 0)
+	}, aCfg, SendConfig{}, nil, 0)
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
 	}
@@ -109,3 +111,225 @@ func TestAlertManager_Send(t *testing.T) {
 		t.Errorf("expected 2 calls(count from zero) to server got %d", c)
 	}
 }
+
+func newInt(n int) *int { return &n }
+
+func TestAlertManager_SendRetriesAndBatching(t *testing.T) {
+	var mu sync.Mutex
+	var calls, failures int
+	doneCh := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc(alertManagerPath, func(w http.ResponseWriter, r *http.Request) {
+		var a []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("cannot unmarshal alerts: %s", err)
+		}
+		if len(a) > 2 {
+			t.Errorf("expected at most %d alerts in request; got %d", 2, len(a))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		// fail every first attempt with a retriable error
+		if calls%2 == 1 {
+			failures++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if calls == 4 {
+			close(doneCh)
+		}
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	sc := SendConfig{
+		MaxRetries:       newInt(2),
+		MaxBatchSize:     newInt(2),
+		RetryMinInterval: promutils.NewDuration(time.Millisecond),
+	}
+	am, err := NewAlertManager(srv.URL+alertManagerPath, func(alert Alert) string { return "" }, promauth.HTTPClientConfig{}, sc, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer am.Close()
+	if err := am.Send(context.Background(), []Alert{{}, {}, {}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	select {
+	case <-doneCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout while waiting for alerts delivery")
+	}
+	// 2 batches, each failed once and retried once
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 4 || failures != 2 {
+		t.Fatalf("expected 4 calls with 2 failures; got %d calls with %d failures", calls, failures)
+	}
+}
+
+func TestAlertManager_SendNonBlocking(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(alertManagerPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	sc := SendConfig{
+		MaxRetries:       newInt(10),
+		RetryMinInterval: promutils.NewDuration(time.Hour),
+		QueueSize:        newInt(2),
+	}
+	am, err := NewAlertManager(srv.URL+alertManagerPath, func(alert Alert) string { return "" }, promauth.HTTPClientConfig{}, sc, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	deadLetters.takeAll()
+
+	// Send mustn't wait for retries
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := am.Send(context.Background(), []Alert{{Name: "foo"}}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("Send has been blocked for %s", d)
+	}
+	// the queue is full, since the first alert is being retried and the second alert is queued
+	for i := 0; i < 10; i++ {
+		if err := am.Send(context.Background(), []Alert{{Name: "bar"}, {Name: "baz"}}); err != nil {
+			break
+		}
+		if i == 9 {
+			t.Fatalf("expected queue overflow error")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Close must interrupt retries and put all the undelivered alerts into the dead-letter queue
+	am.Close()
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("Close has been blocked for %s", d)
+	}
+	n := 0
+	for _, dl := range GetDeadLetters() {
+		n += len(dl.Alerts)
+	}
+	if n < 4 {
+		t.Fatalf("expected at least 4 alerts in dead-letter queue; got %d", n)
+	}
+	deadLetters.takeAll()
+}
+
+func TestAlertManager_SendBatchesAcrossCalls(t *testing.T) {
+	releaseCh := make(chan struct{})
+	requestsCh := make(chan int, 10)
+	mux := http.NewServeMux()
+	mux.HandleFunc(alertManagerPath, func(w http.ResponseWriter, r *http.Request) {
+		var a []json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+			t.Errorf("cannot unmarshal alerts: %s", err)
+		}
+		requestsCh <- len(a)
+		<-releaseCh
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	sc := SendConfig{
+		MaxBatchSize: newInt(10),
+	}
+	am, err := NewAlertManager(srv.URL+alertManagerPath, func(alert Alert) string { return "" }, promauth.HTTPClientConfig{}, sc, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer am.Close()
+
+	waitRequest := func() int {
+		t.Helper()
+		select {
+		case n := <-requestsCh:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout while waiting for request")
+		}
+		return 0
+	}
+
+	if err := am.Send(context.Background(), []Alert{{Name: "foo"}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n := waitRequest(); n != 1 {
+		t.Fatalf("expected 1 alert in the first request; got %d", n)
+	}
+	// alerts from multiple rules must be merged while the first request is in progress
+	for i := 0; i < 3; i++ {
+		if err := am.Send(context.Background(), []Alert{{Name: "bar"}}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	close(releaseCh)
+	if n := waitRequest(); n != 3 {
+		t.Fatalf("expected 3 alerts in the second request; got %d", n)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	rl := newRateLimiter(1)
+	if err := rl.wait(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the second call waits for the next second
+	waitCh := make(chan error)
+	go func() {
+		waitCh <- rl.wait(context.Background())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// a caller with cancelled context mustn't be blocked by the waiting caller
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if err := rl.wait(ctx); err == nil {
+		t.Fatalf("expected non-nil error for cancelled context")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("wait with cancelled context has been blocked for %s", d)
+	}
+
+	if err := <-waitCh; err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d := time.Since(start); d < 500*time.Millisecond {
+		t.Fatalf("expected the second request to be delayed; it has been delayed for %s", d)
+	}
+}
+
+func TestAlertManager_SendDeadLetters(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(alertManagerPath, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	am, err := NewAlertManager(srv.URL+alertManagerPath, func(alert Alert) string { return "" }, promauth.HTTPClientConfig{}, SendConfig{}, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	deadLetters.takeAll()
+	if err := am.Send(context.Background(), []Alert{{Name: "foo"}}); err == nil {
+		t.Fatalf("expected non-nil error")
+	}
+	dls := GetDeadLetters()
+	if len(dls) != 1 {
+		t.Fatalf("expected 1 dead letter; got %d", len(dls))
+	}
+	if dls[0].Addr != am.Addr() || len(dls[0].Alerts) != 1 || dls[0].Alerts[0].Name != "foo" {
+		t.Fatalf("unexpected dead letter: %+v", dls[0])
+	}
+	deadLetters.takeAll()
+}
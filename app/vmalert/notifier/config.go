@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/consul"
@@ -44,6 +45,8 @@ type Config struct {
 	AlertRelabelConfigs []promrelabel.RelabelConfig `yaml:"alert_relabel_configs,omitempty"`
 	// The timeout used when sending alerts.
 	Timeout *promutils.Duration `yaml:"timeout,omitempty"`
+	// SendConfig contains alerts delivery settings for Notifier clients
+	SendConfig SendConfig `yaml:",inline"`
 
 	// Checksum stores the hash of yaml definition for the config.
 	// May be used to detect any changes to the config file.
@@ -69,6 +72,90 @@ type StaticConfig struct {
 	Targets []string `yaml:"targets"`
 	// HTTPClientConfig contains HTTP configuration for the Targets
 	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
+	// SendConfig contains alerts delivery settings for the Targets
+	SendConfig SendConfig `yaml:",inline"`
+}
+
+// SendConfig contains settings for alerts delivery to notifiers.
+//
+// Missing settings are populated from the corresponding -notifier.* command-line flags.
+type SendConfig struct {
+	// MaxRetries is the maximum number of retries for failed requests
+	MaxRetries *int `yaml:"max_retries,omitempty"`
+	// RetryMinInterval is the minimum delay between retries
+	RetryMinInterval *promutils.Duration `yaml:"retry_min_interval,omitempty"`
+	// RetryMaxInterval is the maximum delay between retries
+	RetryMaxInterval *promutils.Duration `yaml:"retry_max_interval,omitempty"`
+	// MaxBatchSize is the maximum number of alerts to send in a single request
+	MaxBatchSize *int `yaml:"max_batch_size,omitempty"`
+	// RateLimit is the maximum number of requests per second
+	RateLimit *int `yaml:"rate_limit,omitempty"`
+	// QueueSize is the maximum number of alerts waiting for delivery
+	QueueSize *int `yaml:"queue_size,omitempty"`
+}
+
+func (sc *SendConfig) validate() error {
+	for name, v := range map[string]*int{
+		"max_retries":    sc.MaxRetries,
+		"max_batch_size": sc.MaxBatchSize,
+		"rate_limit":     sc.RateLimit,
+		"queue_size":     sc.QueueSize,
+	} {
+		if v != nil && *v < 0 {
+			return fmt.Errorf("%s cannot be negative; got %d", name, *v)
+		}
+	}
+	if sc.RetryMinInterval.Duration() < 0 || sc.RetryMaxInterval.Duration() < 0 {
+		return fmt.Errorf("retry_min_interval and retry_max_interval cannot be negative")
+	}
+	return nil
+}
+
+// sendOptions contains resolved SendConfig settings.
+type sendOptions struct {
+	maxRetries       int
+	retryMinInterval time.Duration
+	retryMaxInterval time.Duration
+	maxBatchSize     int
+	rateLimit        int
+	queueSize        int
+}
+
+// options returns sc settings with missing values populated from command-line flags.
+func (sc SendConfig) options() sendOptions {
+	opts := sendOptions{
+		maxRetries:       *maxRetries,
+		retryMinInterval: *retryMinInterval,
+		retryMaxInterval: *retryMaxInterval,
+		maxBatchSize:     *maxBatchSize,
+		rateLimit:        *rateLimit,
+		queueSize:        *queueSize,
+	}
+	if sc.MaxRetries != nil {
+		opts.maxRetries = *sc.MaxRetries
+	}
+	if sc.RetryMinInterval != nil {
+		opts.retryMinInterval = sc.RetryMinInterval.Duration()
+	}
+	if sc.RetryMaxInterval != nil {
+		opts.retryMaxInterval = sc.RetryMaxInterval.Duration()
+	}
+	if sc.MaxBatchSize != nil {
+		opts.maxBatchSize = *sc.MaxBatchSize
+	}
+	if sc.RateLimit != nil {
+		opts.rateLimit = *sc.RateLimit
+	}
+	if sc.QueueSize != nil {
+		opts.queueSize = *sc.QueueSize
+	}
+	return opts
+}
+
+// isQueued returns true if alerts must be delivered via the per-notifier queue,
+// so rule evaluation isn't blocked by retries, batching or rate limiting.
+func (opts *sendOptions) isQueued() bool {
+	return opts.maxRetries > 0 || opts.maxBatchSize > 0 || opts.rateLimit > 0
 }
 
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
@@ -83,6 +170,14 @@ func (cfg *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	if cfg.Timeout.Duration() == 0 {
 		cfg.Timeout = promutils.NewDuration(time.Second * 10)
 	}
+	if err := cfg.SendConfig.validate(); err != nil {
+		return err
+	}
+	for i := range cfg.StaticConfigs {
+		if err := cfg.StaticConfigs[i].SendConfig.validate(); err != nil {
+			return fmt.Errorf("invalid static_configs: %w", err)
+		}
+	}
 	rCfg, err := promrelabel.ParseRelabelConfigs(cfg.RelabelConfigs)
 	if err != nil {
 		return fmt.Errorf("failed to parse relabeling config: %w", err)
@@ -109,6 +204,10 @@ func parseConfig(path string) (*Config, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error reading config file: %w", err)
 	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
 	var cfg *Config
 	err = yaml.Unmarshal(data, &cfg)
 	if err != nil {
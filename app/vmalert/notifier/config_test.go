@@ -28,5 +28,26 @@ func TestConfigParseBad(t *testing.T) {
 	}
 
 	f("testdata/unknownFields.bad.yaml", "unknown field")
+	f("testdata/sendConfig.bad.yaml", "max_retries cannot be negative")
+	f("testdata/env.bad.yaml", "cannot expand environment vars")
 	f("non-existing-file", "error reading")
 }
+
+func TestConfigSendConfig(t *testing.T) {
+	cfg, err := parseConfig("testdata/static.good.yaml")
+	checkErr(t, err)
+	if len(cfg.StaticConfigs) != 2 {
+		t.Fatalf("expected 2 static configs; got %d", len(cfg.StaticConfigs))
+	}
+
+	// the first static config inherits settings from the top level
+	opts := mergeSendConfigs(cfg.SendConfig, cfg.StaticConfigs[0].SendConfig).options()
+	if opts.maxRetries != 3 || opts.rateLimit != 10 || opts.maxBatchSize != *maxBatchSize {
+		t.Fatalf("unexpected send options for the first static config: %+v", opts)
+	}
+	// the second static config overrides the top level settings
+	opts = mergeSendConfigs(cfg.SendConfig, cfg.StaticConfigs[1].SendConfig).options()
+	if opts.maxRetries != 3 || opts.rateLimit != 5 || opts.maxBatchSize != 100 {
+		t.Fatalf("unexpected send options for the second static config: %+v", opts)
+	}
+}
@@ -144,7 +144,7 @@ func targetsFromLabels(labelsFn getLabels, cfg *Config, genFn AlertURLGenerator)
 		}
 		duplicates[u] = struct{}{}
 
-		am, err := NewAlertManager(u, genFn, cfg.HTTPClientConfig, cfg.parsedAlertRelabelConfigs, cfg.Timeout.Duration())
+		am, err := NewAlertManager(u, genFn, cfg.HTTPClientConfig, cfg.SendConfig, cfg.parsedAlertRelabelConfigs, cfg.Timeout.Duration())
 		if err != nil {
 			errors = append(errors, err)
 			continue
@@ -164,12 +164,13 @@ func (cw *configWatcher) start() error {
 		var targets []Target
 		for _, cfg := range cw.cfg.StaticConfigs {
 			httpCfg := mergeHTTPClientConfigs(cw.cfg.HTTPClientConfig, cfg.HTTPClientConfig)
+			sendCfg := mergeSendConfigs(cw.cfg.SendConfig, cfg.SendConfig)
 			for _, target := range cfg.Targets {
 				address, labels, err := parseLabels(target, nil, cw.cfg)
 				if err != nil {
 					return fmt.Errorf("failed to parse labels for target %q: %s", target, err)
 				}
-				notifier, err := NewAlertManager(address, cw.genFn, httpCfg, cw.cfg.parsedAlertRelabelConfigs, cw.cfg.Timeout.Duration())
+				notifier, err := NewAlertManager(address, cw.genFn, httpCfg, sendCfg, cw.cfg.parsedAlertRelabelConfigs, cw.cfg.Timeout.Duration())
 				if err != nil {
 					return fmt.Errorf("failed to init alertmanager for addr %q: %s", address, err)
 				}
@@ -282,3 +283,27 @@ func mergeHTTPClientConfigs(parent, child promauth.HTTPClientConfig) promauth.HT
 	}
 	return child
 }
+
+// mergeSendConfigs merges fields between child and parent params
+// by populating child from parent params if they're missing.
+func mergeSendConfigs(parent, child SendConfig) SendConfig {
+	if child.MaxRetries == nil {
+		child.MaxRetries = parent.MaxRetries
+	}
+	if child.RetryMinInterval == nil {
+		child.RetryMinInterval = parent.RetryMinInterval
+	}
+	if child.RetryMaxInterval == nil {
+		child.RetryMaxInterval = parent.RetryMaxInterval
+	}
+	if child.MaxBatchSize == nil {
+		child.MaxBatchSize = parent.MaxBatchSize
+	}
+	if child.RateLimit == nil {
+		child.RateLimit = parent.RateLimit
+	}
+	if child.QueueSize == nil {
+		child.QueueSize = parent.QueueSize
+	}
+	return child
+}
@@ -0,0 +1,140 @@
+package notifier
+
+import (
+	"context"
+	"flag"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+)
+
+var deadLetterQueueSize = flag.Int("notifier.deadLetterQueueSize", 1000, "The maximum number of undeliverable notifications to keep in memory "+
+	"for inspection and re-sending via /api/v1/notifiers/dead_letters API. The oldest notifications are dropped when the limit is reached. "+
+	"Set to 0 for disabling the dead-letter queue")
+
+// DeadLetter is a notification, which couldn't be delivered to the notifier.
+type DeadLetter struct {
+	// ID is the unique identifier of the DeadLetter
+	ID uint64 `json:"id"`
+	// Addr is the notifier address the notification was sent to
+	Addr string `json:"addr"`
+	// Error is the last error returned by the notifier
+	Error string `json:"error"`
+	// Time is the moment when the notification has been put into the dead-letter queue
+	Time time.Time `json:"time"`
+	// Alerts contains undelivered alerts
+	Alerts []DeadLetterAlert `json:"alerts"`
+
+	alerts []Alert
+}
+
+// DeadLetterAlert is a short representation of Alert for DeadLetter
+type DeadLetterAlert struct {
+	Name   string            `json:"name"`
+	State  string            `json:"state"`
+	Labels map[string]string `json:"labels"`
+}
+
+// deadLetterQueue holds the most recent notifications, which couldn't be delivered.
+type deadLetterQueue struct {
+	mu     sync.Mutex
+	nextID uint64
+	items  []*DeadLetter
+}
+
+var deadLetters = &deadLetterQueue{}
+
+var (
+	deadLettersTotal   = utils.GetOrCreateCounter(`vmalert_notifier_dead_letters_total`)
+	deadLettersDropped = utils.GetOrCreateCounter(`vmalert_notifier_dead_letters_dropped_total`)
+	_                  = utils.GetOrCreateGauge(`vmalert_notifier_dead_letters`, func() float64 {
+		deadLetters.mu.Lock()
+		n := len(deadLetters.items)
+		deadLetters.mu.Unlock()
+		return float64(n)
+	})
+)
+
+func (q *deadLetterQueue) add(addr string, alerts []Alert, err error) {
+	maxSize := *deadLetterQueueSize
+	if maxSize <= 0 {
+		return
+	}
+	dl := &DeadLetter{
+		Addr:   addr,
+		Error:  err.Error(),
+		Time:   time.Now(),
+		alerts: append([]Alert{}, alerts...),
+	}
+	for _, a := range alerts {
+		dl.Alerts = append(dl.Alerts, DeadLetterAlert{
+			Name:   a.Name,
+			State:  a.State.String(),
+			Labels: a.Labels,
+		})
+	}
+	deadLettersTotal.Inc()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.nextID++
+	dl.ID = q.nextID
+	q.items = append(q.items, dl)
+	if n := len(q.items) - maxSize; n > 0 {
+		deadLettersDropped.Add(n)
+		q.items = append(q.items[:0], q.items[n:]...)
+	}
+}
+
+// takeAll removes all the items from q and returns them.
+func (q *deadLetterQueue) takeAll() []*DeadLetter {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	items := q.items
+	q.items = nil
+	return items
+}
+
+// GetDeadLetters returns notifications, which couldn't be delivered to notifiers.
+func GetDeadLetters() []DeadLetter {
+	deadLetters.mu.Lock()
+	defer deadLetters.mu.Unlock()
+
+	result := make([]DeadLetter, 0, len(deadLetters.items))
+	for _, dl := range deadLetters.items {
+		result = append(result, *dl)
+	}
+	return result
+}
+
+// ResendDeadLetters re-sends all the notifications from the dead-letter queue
+// to the notifiers they were originally sent to.
+//
+// Notifications, which fail again, are put back into the dead-letter queue by Notifier.Send.
+// Notifications for notifiers, which no longer exist, are dropped.
+// It returns the number of successfully re-sent and failed notifications.
+func ResendDeadLetters(ctx context.Context) (sent, failed int) {
+	notifiers := make(map[string]Notifier)
+	for _, ts := range GetTargets() {
+		for _, t := range ts {
+			notifiers[t.Addr()] = t.Notifier
+		}
+	}
+	for _, dl := range deadLetters.takeAll() {
+		nt, ok := notifiers[dl.Addr]
+		if !ok {
+			deadLettersDropped.Inc()
+			failed++
+			continue
+		}
+		if err := nt.Send(ctx, dl.alerts); err != nil {
+			failed++
+			continue
+		}
+		sent++
+	}
+	return sent, failed
+}
@@ -14,9 +14,24 @@ import (
 )
 
 var (
-	configPath                    = flag.String("notifier.config", "", "Path to configuration file for notifiers")
+	configPath                    = flag.String("notifier.config", "", "Path to configuration file for notifiers. The file may contain %{ENV_VAR} placeholders, which are substituted by the corresponding env vars")
 	suppressDuplicateTargetErrors = flag.Bool("notifier.suppressDuplicateTargetErrors", false, "Whether to suppress 'duplicate target' errors during discovery")
 
+	maxRetries = flag.Int("notifier.maxRetries", 0, "The maximum number of retries for failed requests to notifiers. "+
+		"Requests are retried only on network errors, timeouts, 429 and 5xx responses. "+
+		"The delay between retries starts from -notifier.retryMinInterval and is doubled on every attempt up to -notifier.retryMaxInterval. "+
+		"By default failed requests aren't retried")
+	retryMinInterval = flag.Duration("notifier.retryMinInterval", time.Second, "The minimum delay between retries for failed requests to notifiers. See also -notifier.maxRetries")
+	retryMaxInterval = flag.Duration("notifier.retryMaxInterval", 30*time.Second, "The maximum delay between retries for failed requests to notifiers. See also -notifier.maxRetries")
+	maxBatchSize     = flag.Int("notifier.maxBatchSize", 0, "The maximum number of alerts to send to a notifier in a single request. "+
+		"Alerts from multiple rules are merged into a single request while waiting in the per-notifier queue. "+
+		"By default all the queued alerts are sent in a single request")
+	rateLimit = flag.Int("notifier.rateLimit", 0, "Optional limit on the number of requests per second to every notifier. "+
+		"Requests exceeding the limit are delayed in the per-notifier queue. By default the number of requests isn't limited")
+	queueSize = flag.Int("notifier.queueSize", 10000, "The maximum number of alerts waiting for delivery in the per-notifier queue. "+
+		"The queue is used if -notifier.maxRetries, -notifier.maxBatchSize or -notifier.rateLimit is set, so rule evaluation isn't blocked by alerts delivery. "+
+		"Alerts exceeding the limit are put into the dead-letter queue")
+
 	addrs = flagutil.NewArrayString("notifier.url", "Prometheus Alertmanager URL, e.g. http://127.0.0.1:9093. "+
 		"List all Alertmanager URLs if it runs in the cluster mode to ensure high availability.")
 
@@ -147,7 +162,7 @@ func notifiersFromFlags(gen AlertURLGenerator) ([]Notifier, error) {
 		}
 
 		addr = strings.TrimSuffix(addr, "/")
-		am, err := NewAlertManager(addr+alertManagerPath, gen, authCfg, nil, time.Second*10)
+		am, err := NewAlertManager(addr+alertManagerPath, gen, authCfg, SendConfig{}, nil, time.Second*10)
 		if err != nil {
 			return nil, err
 		}
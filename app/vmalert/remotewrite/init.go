@@ -8,6 +8,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 )
 
 var (
@@ -33,6 +34,11 @@ var (
 	concurrency   = flag.Int("remoteWrite.concurrency", 1, "Defines number of writers for concurrent writing into remote querier")
 	flushInterval = flag.Duration("remoteWrite.flushInterval", 5*time.Second, "Defines interval of flushes to remote write endpoint")
 
+	haReplica = flag.String("remoteWrite.haReplica", "", "Optional name of vmalert replica in HA pair of vmalert instances. "+
+		"If set, then -remoteWrite.haReplicaLabel label with this value is added to all the time series sent to -remoteWrite.url, "+
+		"so the receiver can store results from a single replica. See https://docs.victoriametrics.com/vmalert.html#ha-vmalert")
+	haReplicaLabel = flag.String("remoteWrite.haReplicaLabel", "vmalert_replica", "Label name for -remoteWrite.haReplica")
+
 	tlsInsecureSkipVerify = flag.Bool("remoteWrite.tlsInsecureSkipVerify", false, "Whether to skip tls verification when connecting to -remoteWrite.url")
 	tlsCertFile           = flag.String("remoteWrite.tlsCertFile", "", "Optional path to client-side TLS certificate file to use when connecting to -remoteWrite.url")
 	tlsKeyFile            = flag.String("remoteWrite.tlsKeyFile", "", "Optional path to client-side TLS certificate key to use when connecting to -remoteWrite.url")
@@ -76,8 +82,17 @@ func Init(ctx context.Context) (*Client, error) {
 		return nil, fmt.Errorf("failed to configure auth: %w", err)
 	}
 
+	var extraLabels []prompbmarshal.Label
+	if *haReplica != "" {
+		extraLabels = append(extraLabels, prompbmarshal.Label{
+			Name:  *haReplicaLabel,
+			Value: *haReplica,
+		})
+	}
+
 	return NewClient(ctx, Config{
 		Addr:          *addr,
+		ExtraLabels:   extraLabels,
 		AuthCfg:       authCfg,
 		Concurrency:   *concurrency,
 		MaxQueueSize:  *maxQueueSize,
@@ -35,6 +35,10 @@ type Client struct {
 	flushInterval time.Duration
 	maxBatchSize  int
 	maxQueueSize  int
+	extraLabels   []prompbmarshal.Label
+
+	// flushChs contains per-worker channels for Flush requests
+	flushChs []chan *sync.WaitGroup
 
 	wg     sync.WaitGroup
 	doneCh chan struct{}
@@ -60,6 +64,8 @@ type Config struct {
 	FlushInterval time.Duration
 	// Transport will be used by the underlying http.Client
 	Transport *http.Transport
+	// ExtraLabels are added to all the time series pushed via the client
+	ExtraLabels []prompbmarshal.Label
 }
 
 const (
@@ -102,12 +108,15 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 		flushInterval: cfg.FlushInterval,
 		maxBatchSize:  cfg.MaxBatchSize,
 		maxQueueSize:  cfg.MaxQueueSize,
+		extraLabels:   cfg.ExtraLabels,
 		doneCh:        make(chan struct{}),
 		input:         make(chan prompbmarshal.TimeSeries, cfg.MaxQueueSize),
 	}
 
 	for i := 0; i < cc; i++ {
-		c.run(ctx)
+		flushCh := make(chan *sync.WaitGroup)
+		c.flushChs = append(c.flushChs, flushCh)
+		c.run(ctx, flushCh)
 	}
 	return c, nil
 }
@@ -115,6 +124,12 @@ func NewClient(ctx context.Context, cfg Config) (*Client, error) {
 // Push adds timeseries into queue for writing into remote storage.
 // Push returns and error if client is stopped or if queue is full.
 func (c *Client) Push(s prompbmarshal.TimeSeries) error {
+	if len(c.extraLabels) > 0 {
+		// Copy labels in order to avoid modification of the labels owned by the caller.
+		labels := make([]prompbmarshal.Label, 0, len(s.Labels)+len(c.extraLabels))
+		labels = append(labels, s.Labels...)
+		s.Labels = append(labels, c.extraLabels...)
+	}
 	select {
 	case <-c.doneCh:
 		return fmt.Errorf("client is closed")
@@ -127,6 +142,26 @@ func (c *Client) Push(s prompbmarshal.TimeSeries) error {
 	}
 }
 
+// Flush sends all the time series pushed before the call to remote storage
+// and waits until they are sent.
+func (c *Client) Flush(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, flushCh := range c.flushChs {
+		wg.Add(1)
+		select {
+		case flushCh <- &wg:
+		case <-c.doneCh:
+			wg.Done()
+			return fmt.Errorf("client is closed")
+		case <-ctx.Done():
+			wg.Done()
+			return ctx.Err()
+		}
+	}
+	wg.Wait()
+	return nil
+}
+
 // Close stops the client and waits for all goroutines
 // to exit.
 func (c *Client) Close() error {
@@ -139,7 +174,7 @@ func (c *Client) Close() error {
 	return nil
 }
 
-func (c *Client) run(ctx context.Context) {
+func (c *Client) run(ctx context.Context, flushCh <-chan *sync.WaitGroup) {
 	ticker := time.NewTicker(c.flushInterval)
 	wr := &prompbmarshal.WriteRequest{}
 	shutdown := func() {
@@ -164,6 +199,26 @@ func (c *Client) run(ctx context.Context) {
 				return
 			case <-ticker.C:
 				c.flush(ctx, wr)
+			case wg := <-flushCh:
+				// Time series pushed before the Flush call are either in wr
+				// or still in the input queue, so drain the queue before flushing.
+			drain:
+				for {
+					select {
+					case ts, ok := <-c.input:
+						if !ok {
+							break drain
+						}
+						wr.Timeseries = append(wr.Timeseries, ts)
+						if len(wr.Timeseries) >= c.maxBatchSize {
+							c.flush(ctx, wr)
+						}
+					default:
+						break drain
+					}
+				}
+				c.flush(ctx, wr)
+				wg.Done()
 			case ts, ok := <-c.input:
 				if !ok {
 					continue
@@ -54,6 +54,70 @@ func TestClient_Push(t *testing.T) {
 	}
 }
 
+func TestClient_Flush(t *testing.T) {
+	testSrv := newRWServer()
+	cfg := Config{
+		Addr:          testSrv.URL,
+		MaxBatchSize:  100,
+		FlushInterval: time.Hour,
+	}
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create client: %s", err)
+	}
+	const rowsN = 250
+	for i := 0; i < rowsN; i++ {
+		s := prompbmarshal.TimeSeries{
+			Samples: []prompbmarshal.Sample{{
+				Value:     float64(i),
+				Timestamp: time.Now().Unix(),
+			}},
+		}
+		if err := client.Push(s); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := client.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got := testSrv.accepted(); got != rowsN {
+		t.Fatalf("expected to have %d series after flush; got %d", rowsN, got)
+	}
+	if err := client.Close(); err != nil {
+		t.Fatalf("failed to close client: %s", err)
+	}
+	if err := client.Flush(context.Background()); err == nil {
+		t.Fatalf("expected error on flushing closed client")
+	}
+}
+
+func TestClient_PushExtraLabels(t *testing.T) {
+	c := &Client{
+		input:  make(chan prompbmarshal.TimeSeries, 1),
+		doneCh: make(chan struct{}),
+		extraLabels: []prompbmarshal.Label{{
+			Name:  "vmalert_replica",
+			Value: "a",
+		}},
+	}
+	labels := make([]prompbmarshal.Label, 1, 2)
+	labels[0] = prompbmarshal.Label{
+		Name:  "__name__",
+		Value: "foo",
+	}
+	if err := c.Push(prompbmarshal.TimeSeries{Labels: labels}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s := <-c.input
+	if len(s.Labels) != 2 || s.Labels[0] != labels[0] || s.Labels[1] != c.extraLabels[0] {
+		t.Fatalf("unexpected labels: %v", s.Labels)
+	}
+	// labels owned by the caller must remain unchanged
+	if extra := labels[:2][1]; extra.Name != "" {
+		t.Fatalf("unexpected modification of the caller's labels: %v", labels[:2])
+	}
+}
+
 func newRWServer() *rwServer {
 	rw := &rwServer{}
 	rw.Server = httptest.NewServer(http.HandlerFunc(rw.handler))
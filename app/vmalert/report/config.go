@@ -0,0 +1,125 @@
+package report
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/metricsql"
+	"gopkg.in/yaml.v2"
+)
+
+// Config contains scheduled reports loaded from -report.config
+type Config struct {
+	Reports []Report `yaml:"reports"`
+}
+
+// Report is a query, which is executed on schedule with the result sent to webhook.
+type Report struct {
+	// Name is the unique name of the report.
+	Name string `yaml:"name"`
+	// Expr is MetricsQL or PromQL query to execute.
+	Expr string `yaml:"expr"`
+	// Schedule is cron schedule for the report such as `0 9 * * 1`.
+	Schedule string `yaml:"schedule"`
+	// Timezone is the timezone for Schedule. UTC is used by default.
+	Timezone string `yaml:"timezone,omitempty"`
+	// Range is the optional time range for the query ending at the report time.
+	// Instant query is executed if Range isn't set.
+	Range *promutils.Duration `yaml:"range,omitempty"`
+	// Step is the step for the query if Range is set.
+	Step *promutils.Duration `yaml:"step,omitempty"`
+	// Format is the format for the query result sent to URL. Supported values: json, csv.
+	Format string `yaml:"format,omitempty"`
+	// URL is the webhook url, which receives the query result via POST request.
+	URL string `yaml:"url"`
+	// HTTPClientConfig contains headers, auth and TLS settings for sending requests to URL.
+	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
+
+	schedule *cronSchedule
+	location *time.Location
+}
+
+func parseConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+	return parseConfigData(data)
+}
+
+func parseConfigData(data []byte) (*Config, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var cfg Config
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(cfg.Reports))
+	for i := range cfg.Reports {
+		r := &cfg.Reports[i]
+		if err := r.init(); err != nil {
+			return nil, fmt.Errorf("invalid report %q: %w", r.Name, err)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate report name %q", r.Name)
+		}
+		names[r.Name] = true
+	}
+	return &cfg, nil
+}
+
+func (r *Report) init() error {
+	if r.Name == "" {
+		return fmt.Errorf("`name` cannot be empty")
+	}
+	if r.Expr == "" {
+		return fmt.Errorf("`expr` cannot be empty")
+	}
+	if _, err := metricsql.Parse(r.Expr); err != nil {
+		return fmt.Errorf("cannot parse `expr`: %w", err)
+	}
+	schedule, err := parseCronSchedule(r.Schedule)
+	if err != nil {
+		return fmt.Errorf("cannot parse `schedule`: %w", err)
+	}
+	r.schedule = schedule
+	r.location = time.UTC
+	if r.Timezone != "" {
+		loc, err := time.LoadLocation(r.Timezone)
+		if err != nil {
+			return fmt.Errorf("cannot load `timezone`: %w", err)
+		}
+		r.location = loc
+	}
+	if r.Range.Duration() < 0 {
+		return fmt.Errorf("`range` cannot be negative")
+	}
+	if r.Step != nil && r.Range == nil {
+		return fmt.Errorf("`step` can be set only together with `range`")
+	}
+	if r.Step.Duration() < 0 {
+		return fmt.Errorf("`step` cannot be negative")
+	}
+	switch r.Format {
+	case "":
+		r.Format = "json"
+	case "json", "csv":
+	default:
+		return fmt.Errorf("unsupported `format` %q; supported values: json, csv", r.Format)
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return fmt.Errorf("cannot parse `url`: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("`url` must start with http:// or https://; got %q", r.URL)
+	}
+	return nil
+}
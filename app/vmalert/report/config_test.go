@@ -0,0 +1,134 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseConfigDataSuccess(t *testing.T) {
+	cfg, err := parseConfigData([]byte(`
+reports:
+- name: weekly-capacity
+  expr: sum(vm_data_size_bytes) by (instance)
+  schedule: "0 9 * * 1"
+  timezone: Europe/Berlin
+  range: 7d
+  step: 1d
+  format: csv
+  url: https://example.com/hook
+  headers:
+  - "X-Report: capacity"
+  basic_auth:
+    username: foo
+    password: bar
+- name: daily
+  expr: up
+  schedule: "@daily"
+  url: http://example.com/hook
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(cfg.Reports) != 2 {
+		t.Fatalf("unexpected number of reports; got %d; want 2", len(cfg.Reports))
+	}
+	r := &cfg.Reports[0]
+	if r.Format != "csv" || r.Range.Duration() != 7*24*time.Hour || r.Step.Duration() != 24*time.Hour {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+	if r.location.String() != "Europe/Berlin" {
+		t.Fatalf("unexpected location; got %q; want %q", r.location, "Europe/Berlin")
+	}
+	r = &cfg.Reports[1]
+	if r.Format != "json" || r.location != time.UTC {
+		t.Fatalf("unexpected report: %+v", r)
+	}
+}
+
+func TestParseConfigDataFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseConfigData([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error for\n%s", data)
+		}
+	}
+	// unknown field
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  url: http://example.com
+  foo: bar
+`)
+	// missing name
+	f(`
+reports:
+- expr: up
+  schedule: "@daily"
+  url: http://example.com
+`)
+	// invalid expr
+	f(`
+reports:
+- name: foo
+  expr: up{
+  schedule: "@daily"
+  url: http://example.com
+`)
+	// invalid schedule
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "0 0 * *"
+  url: http://example.com
+`)
+	// invalid timezone
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  timezone: Foo/Bar
+  url: http://example.com
+`)
+	// step without range
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  step: 1h
+  url: http://example.com
+`)
+	// unsupported format
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  format: xml
+  url: http://example.com
+`)
+	// invalid url
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  url: example.com
+`)
+	// duplicate name
+	f(`
+reports:
+- name: foo
+  expr: up
+  schedule: "@daily"
+  url: http://example.com
+- name: foo
+  expr: up
+  schedule: "@hourly"
+  url: http://example.com
+`)
+}
@@ -0,0 +1,158 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed schedule in the standard cron format with 5 fields:
+// minute, hour, day of month, month and day of week.
+//
+// See https://man7.org/linux/man-pages/man5/crontab.5.html
+type cronSchedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	// anyDayOfMonth and anyDayOfWeek are set if the corresponding fields equal to `*`.
+	// They are needed for matching days according to cron rules - see cronSchedule.matchDay.
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCronSchedule parses cron schedule from s.
+func parseCronSchedule(s string) (*cronSchedule, error) {
+	s = strings.TrimSpace(s)
+	if macro, ok := cronMacros[s]; ok {
+		s = macro
+	}
+	fields := strings.Fields(s)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron schedule must contain 5 fields: minute, hour, day of month, month and day of week; got %d fields in %q", len(fields), s)
+	}
+	var cs cronSchedule
+	var err error
+	if cs.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("cannot parse minute in %q: %w", s, err)
+	}
+	if cs.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("cannot parse hour in %q: %w", s, err)
+	}
+	if cs.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("cannot parse day of month in %q: %w", s, err)
+	}
+	if cs.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("cannot parse month in %q: %w", s, err)
+	}
+	// Both 0 and 7 mean Sunday.
+	if cs.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("cannot parse day of week in %q: %w", s, err)
+	}
+	if cs.daysOfWeek&(1<<7) != 0 {
+		cs.daysOfWeek |= 1
+	}
+	cs.anyDayOfMonth = fields[2] == "*"
+	cs.anyDayOfWeek = fields[4] == "*"
+	return &cs, nil
+}
+
+// parseCronField parses cron field in the form `*`, `*/step`, `n`, `n-m`, `n-m/step` or a comma-separated list of these values.
+//
+// It returns a bitmask with bits set for the matching values.
+func parseCronField(s string, minValue, maxValue int) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if n := strings.IndexByte(part, '/'); n >= 0 {
+			v, err := strconv.Atoi(part[n+1:])
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			step = v
+			part = part[:n]
+		}
+		start, end := minValue, maxValue
+		if part != "*" {
+			bounds := strings.Split(part, "-")
+			if len(bounds) > 2 {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+			v, err := strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("cannot parse %q: %w", part, err)
+			}
+			start, end = v, v
+			if len(bounds) == 2 {
+				v, err := strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("cannot parse %q: %w", part, err)
+				}
+				end = v
+			} else if step > 1 {
+				// `n/step` means `n-max/step`
+				end = maxValue
+			}
+		}
+		if start < minValue || end > maxValue || start > end {
+			return 0, fmt.Errorf("%q is out of the allowed range [%d...%d]", part, minValue, maxValue)
+		}
+		for v := start; v <= end; v += step {
+			mask |= 1 << uint(v)
+		}
+	}
+	return mask, nil
+}
+
+// next returns the next time after t matching cs.
+//
+// Zero time is returned if there is no matching time in the next 5 years, e.g. for `0 0 30 2 *` schedule.
+func (cs *cronSchedule) next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	deadline := t.AddDate(5, 0, 0)
+	for t.Before(deadline) {
+		if cs.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !cs.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if cs.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay returns true if the day for t matches cs.
+//
+// If both day of month and day of week are restricted, then the day matches if any of them matches.
+func (cs *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := cs.daysOfMonth&(1<<uint(t.Day())) != 0
+	dowMatch := cs.daysOfWeek&(1<<uint(t.Weekday())) != 0
+	if !cs.anyDayOfMonth && !cs.anyDayOfWeek {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
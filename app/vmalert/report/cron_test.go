@@ -0,0 +1,66 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronScheduleFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := parseCronSchedule(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	f("")
+	f("* * * *")
+	f("* * * * * *")
+	f("60 * * * *")
+	f("* 24 * * *")
+	f("* * 0 * *")
+	f("* * * 13 *")
+	f("* * * * 8")
+	f("*/0 * * * *")
+	f("5-3 * * * *")
+	f("a * * * *")
+	f("@every")
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	f := func(schedule, tStr, nextExpected string) {
+		t.Helper()
+		cs, err := parseCronSchedule(schedule)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", schedule, err)
+		}
+		ts, err := time.Parse(time.RFC3339, tStr)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", tStr, err)
+		}
+		next := cs.next(ts)
+		nextStr := ""
+		if !next.IsZero() {
+			nextStr = next.Format(time.RFC3339)
+		}
+		if nextStr != nextExpected {
+			t.Fatalf("unexpected next time for %q after %s; got %q; want %q", schedule, tStr, nextStr, nextExpected)
+		}
+	}
+	f("* * * * *", "2023-05-10T12:34:56Z", "2023-05-10T12:35:00Z")
+	f("*/15 * * * *", "2023-05-10T12:34:56Z", "2023-05-10T12:45:00Z")
+	f("0 * * * *", "2023-05-10T12:00:00Z", "2023-05-10T13:00:00Z")
+	f("@hourly", "2023-05-10T23:30:00Z", "2023-05-11T00:00:00Z")
+	f("@daily", "2023-05-31T12:00:00Z", "2023-06-01T00:00:00Z")
+	f("30 9 * * 1-5", "2023-05-12T10:00:00Z", "2023-05-15T09:30:00Z")
+	// Both 0 and 7 mean Sunday
+	f("0 9 * * 7", "2023-05-10T10:00:00Z", "2023-05-14T09:00:00Z")
+	f("@weekly", "2023-05-10T10:00:00Z", "2023-05-14T00:00:00Z")
+	f("0 0 1 */3 *", "2023-05-10T10:00:00Z", "2023-07-01T00:00:00Z")
+	f("@yearly", "2023-05-10T10:00:00Z", "2024-01-01T00:00:00Z")
+	f("0 0 29 2 *", "2023-03-01T00:00:00Z", "2024-02-29T00:00:00Z")
+	// Day of month or day of week matches if both are restricted
+	f("0 0 13 * 5", "2023-05-10T10:00:00Z", "2023-05-12T00:00:00Z")
+	f("0 12 1,15 * *", "2023-05-10T10:00:00Z", "2023-05-15T12:00:00Z")
+	// Impossible schedule
+	f("0 0 30 2 *", "2023-05-10T10:00:00Z", "")
+}
@@ -0,0 +1,299 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/utils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
+)
+
+var (
+	configPath = flag.String("report.config", "", "Optional path to file with scheduled reports. Every report executes the query on cron schedule "+
+		"and sends the result in JSON or CSV format to webhook url. See https://docs.victoriametrics.com/vmalert.html#scheduled-reports")
+	sendTimeout = flag.Duration("report.sendTimeout", 30*time.Second, "Timeout for executing the report query and for sending the result to webhook url")
+)
+
+// Manager runs scheduled reports.
+type Manager struct {
+	reports []*reporter
+
+	wg sync.WaitGroup
+}
+
+// Init parses -report.config and returns Manager for the reports there.
+//
+// nil is returned if -report.config isn't set.
+func Init(qb datasource.QuerierBuilder) (*Manager, error) {
+	if *configPath == "" {
+		return nil, nil
+	}
+	cfg, err := parseConfig(*configPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -report.config=%q: %w", *configPath, err)
+	}
+	m := &Manager{}
+	for i := range cfg.Reports {
+		r, err := newReporter(&cfg.Reports[i], qb)
+		if err != nil {
+			return nil, fmt.Errorf("cannot init report %q: %w", cfg.Reports[i].Name, err)
+		}
+		m.reports = append(m.reports, r)
+	}
+	logger.Infof("loaded %d scheduled reports from -report.config=%q", len(m.reports), *configPath)
+	return m, nil
+}
+
+// Start starts running reports on their schedules until ctx is cancelled.
+func (m *Manager) Start(ctx context.Context) {
+	for _, r := range m.reports {
+		m.wg.Add(1)
+		go func(r *reporter) {
+			defer m.wg.Done()
+			r.run(ctx)
+		}(r)
+	}
+}
+
+// Close waits until the running reports are stopped.
+//
+// The ctx passed to Start must be cancelled before calling Close.
+func (m *Manager) Close() {
+	m.wg.Wait()
+}
+
+type reporter struct {
+	r       *Report
+	q       datasource.Querier
+	client  *http.Client
+	authCfg *promauth.Config
+
+	runs                 *utils.Counter
+	errors               *utils.Counter
+	lastSuccessTimestamp *utils.Gauge
+
+	// lastSuccess is unix timestamp in seconds for the last successfully sent report.
+	lastSuccess int64
+	mu          sync.Mutex
+}
+
+func newReporter(r *Report, qb datasource.QuerierBuilder) (*reporter, error) {
+	hc := &r.HTTPClientConfig
+	tls := &promauth.TLSConfig{}
+	if hc.TLSConfig != nil {
+		tls = hc.TLSConfig
+	}
+	tr, err := utils.Transport(r.URL, tls.CertFile, tls.KeyFile, tls.CAFile, tls.ServerName, tls.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %w", err)
+	}
+	ba := new(promauth.BasicAuthConfig)
+	if hc.BasicAuth != nil {
+		ba = hc.BasicAuth
+	}
+	authCfg, err := utils.AuthConfig(
+		utils.WithBasicAuth(ba.Username, ba.Password.String(), ba.PasswordFile),
+		utils.WithBearer(hc.BearerToken.String(), hc.BearerTokenFile),
+		utils.WithHeaders(strings.Join(hc.Headers, "^^")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure auth: %w", err)
+	}
+	rp := &reporter{
+		r: r,
+		q: qb.BuildWithParams(datasource.QuerierParams{
+			EvaluationInterval: r.Step.Duration(),
+			// prevent queries from caching, since reports are executed rarely
+			QueryParams: url.Values{"nocache": {"1"}},
+		}),
+		client:  &http.Client{Transport: tr},
+		authCfg: authCfg,
+		runs:    utils.GetOrCreateCounter(fmt.Sprintf(`vmalert_report_runs_total{report=%q}`, r.Name)),
+		errors:  utils.GetOrCreateCounter(fmt.Sprintf(`vmalert_report_errors_total{report=%q}`, r.Name)),
+	}
+	rp.lastSuccessTimestamp = utils.GetOrCreateGauge(fmt.Sprintf(`vmalert_report_last_success_timestamp_seconds{report=%q}`, r.Name), func() float64 {
+		rp.mu.Lock()
+		defer rp.mu.Unlock()
+		return float64(rp.lastSuccess)
+	})
+	return rp, nil
+}
+
+func (rp *reporter) run(ctx context.Context) {
+	defer func() {
+		rp.runs.Unregister()
+		rp.errors.Unregister()
+		rp.lastSuccessTimestamp.Unregister()
+	}()
+	for {
+		now := time.Now().In(rp.r.location)
+		next := rp.r.schedule.next(now)
+		if next.IsZero() {
+			logger.Errorf("report %q: cannot find the next time matching schedule %q; stopping the report", rp.r.Name, rp.r.Schedule)
+			return
+		}
+		t := time.NewTimer(next.Sub(now))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		rp.runs.Inc()
+		if err := rp.exec(ctx, next); err != nil {
+			rp.errors.Inc()
+			logger.Errorf("report %q: %s", rp.r.Name, err)
+			continue
+		}
+		rp.mu.Lock()
+		rp.lastSuccess = time.Now().Unix()
+		rp.mu.Unlock()
+	}
+}
+
+// exec executes the report query at ts and sends the result to the report url.
+func (rp *reporter) exec(ctx context.Context, ts time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, *sendTimeout)
+	defer cancel()
+
+	r := rp.r
+	var result []datasource.Metric
+	var err error
+	if r.Range != nil {
+		result, err = rp.q.QueryRange(ctx, r.Expr, ts.Add(-r.Range.Duration()), ts)
+	} else {
+		result, _, err = rp.q.Query(ctx, r.Expr, ts)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot execute query %q: %w", r.Expr, err)
+	}
+
+	var bb bytes.Buffer
+	contentType := "application/json"
+	if r.Format == "csv" {
+		contentType = "text/csv"
+		err = writeCSV(&bb, result)
+	} else {
+		err = writeJSON(&bb, r, ts, result)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot marshal query result: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, &bb)
+	if err != nil {
+		return fmt.Errorf("cannot create request to %q: %w", r.URL, err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if rp.authCfg != nil {
+		rp.authCfg.SetHeaders(req, true)
+	}
+	resp, err := rp.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send report to %q: %w", r.URL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code %d from %q; response body: %q", resp.StatusCode, r.URL, body)
+	}
+	return nil
+}
+
+type jsonReport struct {
+	Report    string       `json:"report"`
+	Expr      string       `json:"expr"`
+	Timestamp int64        `json:"timestamp"`
+	Result    []jsonSeries `json:"result"`
+	Range     string       `json:"range,omitempty"`
+}
+
+type jsonSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+// writeJSON writes result for the report r executed at ts to w in JSON.
+//
+// Values have the same format as in Prometheus querying API - `[unix_timestamp_seconds, "value"]`.
+func writeJSON(w io.Writer, r *Report, ts time.Time, result []datasource.Metric) error {
+	jr := &jsonReport{
+		Report:    r.Name,
+		Expr:      r.Expr,
+		Timestamp: ts.Unix(),
+		Result:    make([]jsonSeries, 0, len(result)),
+	}
+	if r.Range != nil {
+		jr.Range = r.Range.Duration().String()
+	}
+	for _, m := range result {
+		js := jsonSeries{
+			Metric: make(map[string]string, len(m.Labels)),
+			Values: make([][2]interface{}, 0, len(m.Values)),
+		}
+		for _, l := range m.Labels {
+			js.Metric[l.Name] = l.Value
+		}
+		for i, v := range m.Values {
+			js.Values = append(js.Values, [2]interface{}{m.Timestamps[i], formatValue(v)})
+		}
+		jr.Result = append(jr.Result, js)
+	}
+	return json.NewEncoder(w).Encode(jr)
+}
+
+// writeCSV writes result to w in CSV.
+//
+// The first line contains column names: sorted label names followed by `timestamp` and `value`.
+func writeCSV(w io.Writer, result []datasource.Metric) error {
+	labelNamesMap := make(map[string]bool)
+	for _, m := range result {
+		for _, l := range m.Labels {
+			labelNamesMap[l.Name] = true
+		}
+	}
+	labelNames := make([]string, 0, len(labelNamesMap))
+	for name := range labelNamesMap {
+		labelNames = append(labelNames, name)
+	}
+	sort.Strings(labelNames)
+
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, labelNames...), "timestamp", "value")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for _, m := range result {
+		for i, name := range labelNames {
+			row[i] = m.Label(name)
+		}
+		for i, v := range m.Values {
+			row[len(labelNames)] = strconv.FormatInt(m.Timestamps[i], 10)
+			row[len(labelNames)+1] = formatValue(v)
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatValue formats v in the same way as Prometheus querying API does, e.g. `+Inf` and `NaN` for special values.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+)
+
+type fakeQuerier struct {
+	metrics []datasource.Metric
+}
+
+func (fq *fakeQuerier) BuildWithParams(_ datasource.QuerierParams) datasource.Querier {
+	return fq
+}
+
+func (fq *fakeQuerier) Query(_ context.Context, _ string, _ time.Time) ([]datasource.Metric, *http.Request, error) {
+	return fq.metrics, nil, nil
+}
+
+func (fq *fakeQuerier) QueryRange(_ context.Context, _ string, _, _ time.Time) ([]datasource.Metric, error) {
+	return fq.metrics, nil
+}
+
+func TestWriteJSON(t *testing.T) {
+	r := &Report{
+		Name: "foo",
+		Expr: "up",
+	}
+	result := []datasource.Metric{
+		{
+			Labels:     []datasource.Label{{Name: "job", Value: "vm"}},
+			Timestamps: []int64{1000, 2000},
+			Values:     []float64{1, math.Inf(1)},
+		},
+	}
+	var bb bytes.Buffer
+	if err := writeJSON(&bb, r, time.Unix(2000, 0), result); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := `{"report":"foo","expr":"up","timestamp":2000,"result":[{"metric":{"job":"vm"},"values":[[1000,"1"],[2000,"+Inf"]]}]}` + "\n"
+	if bb.String() != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", bb.String(), resultExpected)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	result := []datasource.Metric{
+		{
+			Labels:     []datasource.Label{{Name: "job", Value: "vm"}, {Name: "instance", Value: "foo,bar"}},
+			Timestamps: []int64{1000, 2000},
+			Values:     []float64{1, 2.5},
+		},
+		{
+			Labels:     []datasource.Label{{Name: "job", Value: "node"}},
+			Timestamps: []int64{1000},
+			Values:     []float64{math.NaN()},
+		},
+	}
+	var bb bytes.Buffer
+	if err := writeCSV(&bb, result); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := "instance,job,timestamp,value\n" +
+		"\"foo,bar\",vm,1000,1\n" +
+		"\"foo,bar\",vm,2000,2.5\n" +
+		",node,1000,NaN\n"
+	if bb.String() != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", bb.String(), resultExpected)
+	}
+}
+
+func TestReporterExec(t *testing.T) {
+	var body []byte
+	var contentType, reportHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		reportHeader = r.Header.Get("X-Report")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	cfg, err := parseConfigData([]byte(`
+reports:
+- name: ok
+  expr: up
+  schedule: "@daily"
+  format: csv
+  url: ` + srv.URL + `/hook
+  headers:
+  - "X-Report: capacity"
+- name: fail
+  expr: up
+  schedule: "@daily"
+  url: ` + srv.URL + `/fail
+`))
+	if err != nil {
+		t.Fatalf("cannot parse config: %s", err)
+	}
+	fq := &fakeQuerier{
+		metrics: []datasource.Metric{
+			{
+				Labels:     []datasource.Label{{Name: "job", Value: "vm"}},
+				Timestamps: []int64{1000},
+				Values:     []float64{1},
+			},
+		},
+	}
+
+	rp, err := newReporter(&cfg.Reports[0], fq)
+	if err != nil {
+		t.Fatalf("cannot create reporter: %s", err)
+	}
+	if err := rp.exec(context.Background(), time.Unix(1000, 0)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if contentType != "text/csv" {
+		t.Fatalf("unexpected Content-Type; got %q; want %q", contentType, "text/csv")
+	}
+	if reportHeader != "capacity" {
+		t.Fatalf("unexpected X-Report header; got %q; want %q", reportHeader, "capacity")
+	}
+	if string(body) != "job,timestamp,value\nvm,1000,1\n" {
+		t.Fatalf("unexpected body: %q", body)
+	}
+
+	rp, err = newReporter(&cfg.Reports[1], fq)
+	if err != nil {
+		t.Fatalf("cannot create reporter: %s", err)
+	}
+	if err := rp.exec(context.Background(), time.Unix(1000, 0)); err == nil {
+		t.Fatalf("expecting non-nil error for failed webhook")
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/config"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+var (
+	rulePreviewMaxRange = flag.Duration("rule.previewMaxRange", 7*24*time.Hour, "The maximum time range, which can be requested "+
+		"via /api/v1/rules/test for previewing alerting rules. See https://docs.victoriametrics.com/vmalert.html#rule-preview")
+	rulePreviewDefaultRange = flag.Duration("rule.previewDefaultRange", 24*time.Hour, "The time range used by /api/v1/rules/test "+
+		"if start query arg is missing. See https://docs.victoriametrics.com/vmalert.html#rule-preview")
+)
+
+// maxRulePreviewBodySize is the maximum size of the rule definition accepted by /api/v1/rules/test
+const maxRulePreviewBodySize = 1024 * 1024
+
+type rulePreviewResponse struct {
+	Status string          `json:"status"`
+	Data   *APIRulePreview `json:"data"`
+}
+
+// previewRule evaluates the alerting rule from r body on the time range from r query args
+// and returns JSON-encoded APIRulePreview.
+func (rh *requestHandler) previewRule(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRulePreviewBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read rule definition: %w", err)
+	}
+	if len(body) > maxRulePreviewBodySize {
+		return nil, fmt.Errorf("rule definition exceeds %d bytes", maxRulePreviewBodySize)
+	}
+	var cfg config.Rule
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("cannot parse rule definition: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rule definition: %w", err)
+	}
+	if cfg.Alert == "" {
+		return nil, fmt.Errorf("only alerting rules can be previewed; got recording rule %q", cfg.Record)
+	}
+	dsType := config.NewRawType(r.FormValue("type"))
+	if cfg.ThresholdFrom != nil && dsType.String() != "prometheus" {
+		return nil, fmt.Errorf("`threshold_from` is supported only for prometheus datasource type")
+	}
+	if err := dsType.ValidateExpr(cfg.QueryExpr()); err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+
+	end, err := parsePreviewTime(r.FormValue("end"), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `end` arg: %w", err)
+	}
+	start, err := parsePreviewTime(r.FormValue("start"), end.Add(-*rulePreviewDefaultRange))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `start` arg: %w", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("`end`=%s must be bigger than `start`=%s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	if d := end.Sub(start); d > *rulePreviewMaxRange {
+		return nil, fmt.Errorf("the requested time range %s exceeds -rule.previewMaxRange=%s", d, *rulePreviewMaxRange)
+	}
+	step := *evaluationInterval
+	if s := r.FormValue("step"); s != "" {
+		step, err = promutils.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `step` arg: %w", err)
+		}
+		if step <= 0 {
+			return nil, fmt.Errorf("`step` must be positive; got %s", s)
+		}
+	}
+
+	ar := newPreviewAlertingRule(rh.m.querierBuilder, cfg, dsType, step)
+	series, err := queryRangeByChunks(r.Context(), ar.q, ar.Expr, start, end, step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query %q: %w", ar.Expr, err)
+	}
+	tss, err := ar.seriesToAlertTimeSeries(series)
+	if err != nil {
+		return nil, err
+	}
+	resp := rulePreviewResponse{
+		Status: "success",
+		Data:   newAPIRulePreview(ar, tss, start, end, step),
+	}
+	b, err := json.Marshal(resp)
+	if err != nil {
+		return nil, &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf(`error encoding rule preview: %w`, err),
+			StatusCode: http.StatusInternalServerError,
+		}
+	}
+	return b, nil
+}
+
+// newPreviewAlertingRule creates AlertingRule for the given cfg, which is used only for ExecRange-like evaluation.
+//
+// Unlike newAlertingRule, it doesn't register rule metrics, since the rule isn't installed.
+func newPreviewAlertingRule(qb datasource.QuerierBuilder, cfg config.Rule, dsType config.Type, step time.Duration) *AlertingRule {
+	return &AlertingRule{
+		Type:         dsType,
+		RuleID:       cfg.ID,
+		Name:         cfg.Alert,
+		Expr:         cfg.Expr,
+		For:          cfg.For.Duration(),
+		Labels:       cfg.Labels,
+		Annotations:  cfg.Annotations,
+		EvalInterval: step,
+		q: qb.BuildWithParams(datasource.QuerierParams{
+			DataSourceType:     dsType.String(),
+			EvaluationInterval: step,
+		}),
+		alerts:  make(map[uint64]*notifier.Alert),
+		metrics: &alertingRuleMetrics{},
+		state:   newRuleState(1),
+	}
+}
+
+// queryRangeByChunks executes range query for expr on [start...end] time range.
+//
+// The time range is split into chunks with up to -replay.maxDatapointsPerQuery points per chunk.
+// Series from all the chunks are merged by labels.
+func queryRangeByChunks(ctx context.Context, q datasource.Querier, expr string, start, end time.Time, step time.Duration) ([]datasource.Metric, error) {
+	ri := rangeIterator{start: start, end: end, step: step * time.Duration(*replayMaxDatapoints)}
+	var result []datasource.Metric
+	idx := make(map[uint64]int)
+	for ri.next() {
+		series, err := q.QueryRange(ctx, expr, ri.s, ri.e)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range series {
+			ls := make(map[string]string, len(s.Labels))
+			for _, l := range s.Labels {
+				ls[l.Name] = l.Value
+			}
+			h := hash(ls)
+			i, ok := idx[h]
+			if !ok {
+				idx[h] = len(result)
+				result = append(result, s)
+				continue
+			}
+			m := &result[i]
+			for j, ts := range s.Timestamps {
+				if n := len(m.Timestamps); n > 0 && ts <= m.Timestamps[n-1] {
+					// Skip duplicate points at chunk boundaries.
+					continue
+				}
+				m.Timestamps = append(m.Timestamps, ts)
+				m.Values = append(m.Values, s.Values[j])
+			}
+		}
+	}
+	return result, nil
+}
+
+// newAPIRulePreview builds APIRulePreview from ALERTS time series generated by ar on [start...end] time range.
+func newAPIRulePreview(ar *AlertingRule, tss []prompbmarshal.TimeSeries, start, end time.Time, step time.Duration) *APIRulePreview {
+	type alertSamples struct {
+		labels     map[string]string
+		timestamps []int64
+	}
+	alerts := make(map[uint64]*alertSamples)
+	for _, ts := range tss {
+		ls := make(map[string]string, len(ts.Labels))
+		isAlert, isFiring := false, false
+		for _, l := range ts.Labels {
+			switch l.Name {
+			case "__name__":
+				isAlert = l.Value == alertMetricName
+			case alertStateLabel:
+				isFiring = l.Value == notifier.StateFiring.String()
+			default:
+				ls[l.Name] = l.Value
+			}
+		}
+		if !isAlert || !isFiring {
+			continue
+		}
+		h := hash(ls)
+		as, ok := alerts[h]
+		if !ok {
+			as = &alertSamples{labels: ls}
+			alerts[h] = as
+		}
+		for _, s := range ts.Samples {
+			as.timestamps = append(as.timestamps, s.Timestamp)
+		}
+	}
+
+	arp := &APIRulePreview{
+		Name:       ar.Name,
+		Expression: ar.Expr,
+		For:        ar.For.Seconds(),
+		Start:      start,
+		End:        end,
+		Step:       step.Seconds(),
+		Alerts:     make([]APIAlertPreview, 0, len(alerts)),
+	}
+	stepMsecs := step.Milliseconds()
+	for _, as := range alerts {
+		sort.Slice(as.timestamps, func(i, j int) bool {
+			return as.timestamps[i] < as.timestamps[j]
+		})
+		ap := APIAlertPreview{
+			Labels: as.labels,
+		}
+		var intervalStart, prevTimestamp int64
+		addInterval := func() {
+			ap.Firings = append(ap.Firings, APIFiringInterval{
+				Start: time.UnixMilli(intervalStart).UTC(),
+				End:   time.UnixMilli(prevTimestamp).UTC(),
+			})
+			ap.FiringDuration += float64(prevTimestamp-intervalStart+stepMsecs) / 1e3
+		}
+		for i, ts := range as.timestamps {
+			if i == 0 {
+				intervalStart = ts
+			} else if ts-prevTimestamp > stepMsecs {
+				// The gap between firing samples exceeds step, so the alert was resolved in between.
+				addInterval()
+				intervalStart = ts
+			}
+			prevTimestamp = ts
+		}
+		addInterval()
+		ap.FiringsCount = len(ap.Firings)
+		arp.FiringsCount += ap.FiringsCount
+		arp.Alerts = append(arp.Alerts, ap)
+	}
+	sort.Slice(arp.Alerts, func(i, j int) bool {
+		return arp.Alerts[i].Firings[0].Start.Before(arp.Alerts[j].Firings[0].Start)
+	})
+	return arp
+}
+
+// parsePreviewTime parses s as unix timestamp in seconds or as RFC3339 time.
+//
+// It returns defaultValue if s is empty.
+func parsePreviewTime(s string, defaultValue time.Time) (time.Time, error) {
+	if s == "" {
+		return defaultValue, nil
+	}
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1e3)), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as unix timestamp or RFC3339 time", s)
+	}
+	return t, nil
+}
+
+// evalRule evaluates the loaded rule with gID and rID from r query args at the current time.
+//
+// The evaluation doesn't change the rule state, so it can be used for checking
+// which alerts or time series the rule would produce right now.
+func (rh *requestHandler) evalRule(r *http.Request) (*APIRuleEval, error) {
+	groupID, err := strconv.ParseUint(r.FormValue(paramGroupID), 10, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q param: %s", paramGroupID, err)
+	}
+	ruleID, err := strconv.ParseUint(r.FormValue(paramRuleID), 10, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q param: %s", paramRuleID, err)
+	}
+	rule, err := rh.m.ruleByID(groupID, ruleID)
+	if err != nil {
+		return nil, errResponse(err, http.StatusNotFound)
+	}
+	return evalRuleAt(r.Context(), rule, time.Now()), nil
+}
+
+// evalRuleAt executes rule query at ts and returns alerts or time series,
+// which would be produced by the rule.
+//
+// Unlike Rule.Exec, it doesn't modify the rule state.
+func evalRuleAt(ctx context.Context, rule Rule, ts time.Time) *APIRuleEval {
+	re := &APIRuleEval{
+		Rule:        rule.ToAPI(),
+		EvaluatedAt: ts,
+		Series:      make([]APISeriesEval, 0),
+	}
+	var q datasource.Querier
+	var expr string
+	switch rr := rule.(type) {
+	case *AlertingRule:
+		q, expr = rr.q, rr.Expr
+	case *RecordingRule:
+		q, expr = rr.q, rr.Expr
+	default:
+		re.Error = fmt.Sprintf("unsupported rule type %T", rule)
+		return re
+	}
+
+	start := time.Now()
+	qMetrics, _, err := q.Query(ctx, expr, ts)
+	re.Duration = time.Since(start).Seconds()
+	if err != nil {
+		re.Error = fmt.Sprintf("failed to execute query %q: %s", expr, err)
+		return re
+	}
+	for _, m := range qMetrics {
+		se := APISeriesEval{
+			Value: m.Values[0],
+		}
+		switch rr := rule.(type) {
+		case *AlertingRule:
+			qFn := func(query string) ([]datasource.Metric, error) {
+				res, _, err := rr.q.Query(ctx, query, ts)
+				return res, err
+			}
+			ls, err := rr.toLabels(m, qFn)
+			if err != nil {
+				re.Error = fmt.Sprintf("failed to expand labels: %s", err)
+				return re
+			}
+			se.Labels = ls.processed
+			se.State = rr.stateAfterEval(hash(ls.processed), ts).String()
+		case *RecordingRule:
+			tss := rr.toTimeSeries(m)
+			se.Labels = make(map[string]string, len(tss.Labels))
+			for _, l := range tss.Labels {
+				se.Labels[l.Name] = l.Value
+			}
+		}
+		re.Series = append(re.Series, se)
+	}
+	sort.Slice(re.Series, func(i, j int) bool {
+		return labelsString(re.Series[i].Labels) < labelsString(re.Series[j].Labels)
+	})
+	return re
+}
+
+// stateAfterEval returns the state the alert with hash h
+// would have after the evaluation at ts.
+func (ar *AlertingRule) stateAfterEval(h uint64, ts time.Time) notifier.AlertState {
+	if ar.For == 0 {
+		return notifier.StateFiring
+	}
+	ar.alertsMu.RLock()
+	defer ar.alertsMu.RUnlock()
+	a, ok := ar.alerts[h]
+	if !ok || a.State == notifier.StateInactive {
+		return notifier.StatePending
+	}
+	if a.State == notifier.StateFiring || ts.Sub(a.ActiveAt) >= ar.For {
+		return notifier.StateFiring
+	}
+	return notifier.StatePending
+}
+
+// labelsString returns string representation of labels sorted by name.
+func labelsString(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, "%s=%q", k, labels[k])
+	}
+	return b.String()
+}
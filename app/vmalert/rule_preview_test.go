@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/datasource"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
+)
+
+func TestRulePreview(t *testing.T) {
+	fq := &fakeQuerier{}
+	m := &manager{querierBuilder: fq, groups: make(map[uint64]*Group)}
+	rh := &requestHandler{m: m}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { rh.handler(w, r) }))
+	defer ts.Close()
+
+	f := func(rule, args string, statusCodeExpected int) *APIRulePreview {
+		t.Helper()
+		resp, err := http.Post(ts.URL+"/api/v1/rules/test?"+args, "application/x-yaml", strings.NewReader(rule))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", resp.StatusCode, statusCodeExpected)
+		}
+		if statusCodeExpected != http.StatusOK {
+			return nil
+		}
+		var rpr rulePreviewResponse
+		if err := json.NewDecoder(resp.Body).Decode(&rpr); err != nil {
+			t.Fatalf("cannot decode response: %s", err)
+		}
+		if rpr.Status != "success" {
+			t.Fatalf("unexpected status; got %q; want %q", rpr.Status, "success")
+		}
+		return rpr.Data
+	}
+
+	const rule = `
+alert: HighLatency
+expr: latency > 1
+labels:
+  severity: page
+`
+	// bad rule definitions
+	f(`foo: bar`, "", http.StatusBadRequest)
+	f(`{record: foo, expr: up}`, "", http.StatusBadRequest)
+	f(`{alert: foo, expr: "up{"}`, "", http.StatusBadRequest)
+
+	// bad time range
+	f(rule, "start=200&end=100", http.StatusBadRequest)
+	f(rule, "start=foo", http.StatusBadRequest)
+	f(rule, "start=0&end=1e9", http.StatusBadRequest)
+	f(rule, "start=0&end=100&step=-1s", http.StatusBadRequest)
+
+	// the alert fires twice with a gap between firings
+	fq.add(datasource.Metric{
+		Labels:     []datasource.Label{{Name: "instance", Value: "foo"}},
+		Timestamps: []int64{1000, 1060, 1120, 1300, 1360},
+		Values:     []float64{2, 3, 4, 2, 2},
+	})
+	arp := f(rule, "start=1000&end=1400&step=1m", http.StatusOK)
+	if arp.Name != "HighLatency" {
+		t.Fatalf("unexpected name; got %q; want %q", arp.Name, "HighLatency")
+	}
+	if arp.FiringsCount != 2 {
+		t.Fatalf("unexpected firings count; got %d; want %d", arp.FiringsCount, 2)
+	}
+	if len(arp.Alerts) != 1 {
+		t.Fatalf("unexpected number of alerts; got %d; want %d", len(arp.Alerts), 1)
+	}
+	a := arp.Alerts[0]
+	if a.Labels["instance"] != "foo" || a.Labels["severity"] != "page" || a.Labels[alertNameLabel] != "HighLatency" {
+		t.Fatalf("unexpected alert labels: %v", a.Labels)
+	}
+	firingsExpected := []APIFiringInterval{
+		{Start: time.Unix(1000, 0).UTC(), End: time.Unix(1120, 0).UTC()},
+		{Start: time.Unix(1300, 0).UTC(), End: time.Unix(1360, 0).UTC()},
+	}
+	if len(a.Firings) != len(firingsExpected) {
+		t.Fatalf("unexpected firings; got %v; want %v", a.Firings, firingsExpected)
+	}
+	for i := range firingsExpected {
+		if !a.Firings[i].Start.Equal(firingsExpected[i].Start) || !a.Firings[i].End.Equal(firingsExpected[i].End) {
+			t.Fatalf("unexpected firing #%d; got %v; want %v", i, a.Firings[i], firingsExpected[i])
+		}
+	}
+	if a.FiringDuration != 300 {
+		t.Fatalf("unexpected firing duration; got %v; want %v", a.FiringDuration, 300)
+	}
+
+	// the alert with `for` doesn't fire if the condition isn't met long enough
+	arp = f(rule+"for: 2m\n", "start=1000&end=1400&step=1m", http.StatusOK)
+	if arp.FiringsCount != 1 {
+		t.Fatalf("unexpected firings count; got %d; want %d", arp.FiringsCount, 1)
+	}
+	if len(arp.Alerts[0].Firings) != 1 || !arp.Alerts[0].Firings[0].Start.Equal(time.Unix(1120, 0)) {
+		t.Fatalf("unexpected firings: %v", arp.Alerts[0].Firings)
+	}
+
+	// only POST is supported
+	resp, err := http.Get(ts.URL + "/api/v1/rules/test")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code for GET request; got %d; want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestParsePreviewTime(t *testing.T) {
+	defaultValue := time.Unix(123, 0)
+	f := func(s string, resultExpected time.Time) {
+		t.Helper()
+		result, err := parsePreviewTime(s, defaultValue)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !result.Equal(resultExpected) {
+			t.Fatalf("unexpected result; got %s; want %s", result, resultExpected)
+		}
+	}
+	f("", defaultValue)
+	f("1000", time.Unix(1000, 0))
+	f("1000.5", time.UnixMilli(1000500))
+	f("2022-10-01T10:00:00Z", time.Date(2022, 10, 1, 10, 0, 0, 0, time.UTC))
+
+	if _, err := parsePreviewTime("foo", defaultValue); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestEvalRuleAt(t *testing.T) {
+	fq := &fakeQuerier{}
+	now := time.Now()
+	ar := &AlertingRule{
+		Name:   "HighLatency",
+		Expr:   "latency > 1",
+		For:    time.Minute,
+		Labels: map[string]string{"severity": "page"},
+		q:      fq,
+		alerts: make(map[uint64]*notifier.Alert),
+		state:  newRuleState(10),
+	}
+	fq.add(metricWithValueAndLabels(t, 2, "__name__", "latency", "instance", "foo"))
+	fq.add(metricWithValueAndLabels(t, 3, "__name__", "latency", "instance", "bar"))
+
+	// the alert for instance=foo is active for longer than `for`
+	fooLabels := map[string]string{"instance": "foo", "severity": "page", alertNameLabel: "HighLatency"}
+	ar.alerts[hash(fooLabels)] = &notifier.Alert{State: notifier.StatePending, ActiveAt: now.Add(-2 * time.Minute)}
+
+	re := evalRuleAt(context.Background(), ar, now)
+	if re.Error != "" {
+		t.Fatalf("unexpected error: %s", re.Error)
+	}
+	if len(re.Series) != 2 {
+		t.Fatalf("unexpected number of series; got %d; want 2", len(re.Series))
+	}
+	f := func(se APISeriesEval, instance, stateExpected string, valueExpected float64) {
+		t.Helper()
+		if se.Labels["instance"] != instance || se.Labels["severity"] != "page" {
+			t.Fatalf("unexpected labels %v", se.Labels)
+		}
+		if se.State != stateExpected {
+			t.Fatalf("unexpected state for %q; got %q; want %q", instance, se.State, stateExpected)
+		}
+		if se.Value != valueExpected {
+			t.Fatalf("unexpected value for %q; got %v; want %v", instance, se.Value, valueExpected)
+		}
+	}
+	f(re.Series[0], "bar", "pending", 3)
+	f(re.Series[1], "foo", "firing", 2)
+
+	// the evaluation mustn't change the rule state
+	if len(ar.alerts) != 1 {
+		t.Fatalf("unexpected number of alerts after the evaluation; got %d; want 1", len(ar.alerts))
+	}
+	if updates := ar.state.getAll(); len(updates) != 0 {
+		t.Fatalf("unexpected number of state updates after the evaluation; got %d; want 0", len(updates))
+	}
+
+	// recording rule
+	rr := &RecordingRule{
+		Name:   "job:latency",
+		Expr:   "latency",
+		Labels: map[string]string{"source": "test"},
+		q:      fq,
+		state:  newRuleState(10),
+	}
+	re = evalRuleAt(context.Background(), rr, now)
+	if re.Error != "" {
+		t.Fatalf("unexpected error: %s", re.Error)
+	}
+	if len(re.Series) != 2 {
+		t.Fatalf("unexpected number of series; got %d; want 2", len(re.Series))
+	}
+	if ls := re.Series[0].Labels; ls["__name__"] != "job:latency" || ls["source"] != "test" || re.Series[0].State != "" {
+		t.Fatalf("unexpected series %v", re.Series[0])
+	}
+
+	// query error
+	fq.setErr(fmt.Errorf("connection refused"))
+	re = evalRuleAt(context.Background(), ar, now)
+	if !strings.Contains(re.Error, "connection refused") {
+		t.Fatalf("expecting query error; got %q", re.Error)
+	}
+}
@@ -289,6 +289,33 @@ func templateFuncs() textTpl.FuncMap {
 			return re.ReplaceAllString(text, repl)
 		},
 
+		// regexReplace is the same as reReplaceAll, but it returns an error
+		// instead of panic if the pattern is invalid regular expression.
+		"regexReplace": func(pattern, repl, text string) (string, error) {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return "", fmt.Errorf("cannot parse regexp %q: %w", pattern, err)
+			}
+			return re.ReplaceAllString(text, repl), nil
+		},
+
+		// join concatenates the elements of a to create a single string.
+		// The separator string sep is placed between elements in the resulting string.
+		// alias for https://golang.org/pkg/strings/#Join
+		"join": func(sep string, a []string) string {
+			return strings.Join(a, sep)
+		},
+
+		// stringSlice returns the given args as a slice of strings.
+		// It can be used in conjunction with join.
+		"stringSlice": func(s ...string) []string {
+			return s
+		},
+
+		// trimSpace returns s with all leading and trailing white space removed.
+		// alias for https://golang.org/pkg/strings/#TrimSpace
+		"trimSpace": strings.TrimSpace,
+
 		// parseDuration parses a duration string such as "1h" into the number of seconds it represents
 		"parseDuration": func(s string) (float64, error) {
 			d, err := promutils.ParseDuration(s)
@@ -353,6 +380,27 @@ func templateFuncs() textTpl.FuncMap {
 			return formatutil.HumanizeBytes(v), nil
 		},
 
+		// humanizeBytes converts given number of bytes to a human readable format
+		// with IEC units such as KiB, MiB, GiB, etc.
+		"humanizeBytes": func(i interface{}) (string, error) {
+			v, err := toFloat64(i)
+			if err != nil {
+				return "", err
+			}
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Sprintf("%.4g", v), nil
+			}
+			unit := "B"
+			for _, u := range []string{"KiB", "MiB", "GiB", "TiB", "PiB", "EiB", "ZiB", "YiB"} {
+				if math.Abs(v) < 1024 {
+					break
+				}
+				unit = u
+				v /= 1024
+			}
+			return fmt.Sprintf("%.4g%s", v, unit), nil
+		},
+
 		// humanizeDuration converts given seconds to a human-readable duration
 		"humanizeDuration": func(i interface{}) (string, error) {
 			v, err := toFloat64(i)
@@ -31,6 +31,7 @@ func TestTemplateFuncs(t *testing.T) {
 	f("stripPort", "foo:1234", "foo")
 	f("stripDomain", "foo.bar.baz", "foo")
 	f("stripDomain", "foo.bar:123", "foo:123")
+	f("trimSpace", " foo\n", "foo")
 
 	// check "match" func
 	matchFunc := funcs["match"].(func(pattern, s string) (bool, error))
@@ -52,6 +53,26 @@ func TestTemplateFuncs(t *testing.T) {
 		t.Fatalf("unexpected mismatch")
 	}
 
+	// check "regexReplace" func
+	regexReplaceFunc := funcs["regexReplace"].(func(pattern, repl, text string) (string, error))
+	if _, err := regexReplaceFunc("invalid[regexp", "", "abc"); err == nil {
+		t.Fatalf("expecting non-nil error on invalid regexp")
+	}
+	s, err := regexReplaceFunc("(.+):[0-9]+", "$1", "foo:1234")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if s != "foo" {
+		t.Fatalf("unexpected result for regexReplace; got %q; want %q", s, "foo")
+	}
+
+	// check "join" and "stringSlice" funcs
+	joinFunc := funcs["join"].(func(sep string, a []string) string)
+	stringSliceFunc := funcs["stringSlice"].(func(s ...string) []string)
+	if s := joinFunc(", ", stringSliceFunc("foo", "bar")); s != "foo, bar" {
+		t.Fatalf("unexpected result for join; got %q; want %q", s, "foo, bar")
+	}
+
 	formatting := func(funcName string, p interface{}, resultExpected string) {
 		t.Helper()
 		v := funcs[funcName]
@@ -76,6 +97,13 @@ func TestTemplateFuncs(t *testing.T) {
 	formatting("humanize1024", float64(146521335255970361638912), "124.1Zi")
 	formatting("humanize1024", float64(150037847302113650318245888), "124.1Yi")
 	formatting("humanize1024", float64(153638755637364377925883789312), "1.271e+05Yi")
+
+	formatting("humanizeBytes", float64(0), "0B")
+	formatting("humanizeBytes", float64(512), "512B")
+	formatting("humanizeBytes", math.NaN(), "NaN")
+	formatting("humanizeBytes", float64(1536), "1.5KiB")
+	formatting("humanizeBytes", float64(130137088), "124.1MiB")
+	formatting("humanizeBytes", float64(-133260378112), "-124.1GiB")
 }
 
 func mkTemplate(current, replacement interface{}) textTemplate {
@@ -26,14 +26,20 @@ func Transport(URL, certFile, keyFile, CAFile, serverName string, insecureSkipVe
 
 // TLSConfig creates tls.Config object from provided arguments
 func TLSConfig(certFile, keyFile, CAFile, serverName string, insecureSkipVerify bool) (*tls.Config, error) {
-	var certs []tls.Certificate
+	var getClientCert func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
 	if certFile != "" {
-		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
-		if err != nil {
-			return nil, fmt.Errorf("cannot load TLS certificate from `cert_file`=%q, `key_file`=%q: %w", certFile, keyFile, err)
+		getClientCert = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			// Re-read TLS certificate from disk, so rotated certificates are applied without restart.
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load TLS certificate from `cert_file`=%q, `key_file`=%q: %w", certFile, keyFile, err)
+			}
+			return &cert, nil
+		}
+		// Check whether the configured TLS certificate can be loaded.
+		if _, err := getClientCert(nil); err != nil {
+			return nil, err
 		}
-
-		certs = []tls.Certificate{cert}
 	}
 
 	var rootCAs *x509.CertPool
@@ -50,9 +56,9 @@ func TLSConfig(certFile, keyFile, CAFile, serverName string, insecureSkipVerify
 	}
 
 	return &tls.Config{
-		Certificates:       certs,
-		InsecureSkipVerify: insecureSkipVerify,
-		RootCAs:            rootCAs,
-		ServerName:         serverName,
+		GetClientCertificate: getClientCert,
+		InsecureSkipVerify:   insecureSkipVerify,
+		RootCAs:              rootCAs,
+		ServerName:           serverName,
 	}, nil
 }
@@ -11,6 +11,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/notifier"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmalert/tpl"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
@@ -23,6 +24,8 @@ var (
 		{"api/v1/rules", "list all loaded groups and rules"},
 		{"api/v1/alerts", "list all active alerts"},
 		{fmt.Sprintf("api/v1/alert?%s=<int>&%s=<int>", paramGroupID, paramAlertID), "get alert status by group and alert ID"},
+		{"api/v1/notifiers/dead_letters", "list notifications, which couldn't be delivered to notifiers"},
+		{"api/v1/status/config_version", "show the applied rules version"},
 	}
 	systemLinks = [][2]string{
 		{"/flags", "command-line flags"},
@@ -82,6 +85,14 @@ func (rh *requestHandler) handler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		WriteRuleDetails(w, r, rule)
 		return true
+	case "/vmalert/rule_eval":
+		re, err := rh.evalRule(r)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		WriteRuleEval(w, r, re)
+		return true
 	case "/vmalert/groups":
 		WriteListGroups(w, r, rh.groups())
 		return true
@@ -131,11 +142,57 @@ func (rh *requestHandler) handler(w http.ResponseWriter, r *http.Request) bool {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(data)
 		return true
+	case "/vmalert/api/v1/rules/test", "/api/v1/rules/test":
+		if r.Method != "POST" {
+			httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+			return true
+		}
+		data, err := rh.previewRule(r)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return true
+	case "/vmalert/api/v1/notifiers/dead_letters", "/api/v1/notifiers/dead_letters":
+		data, err := json.Marshal(notifier.GetDeadLetters())
+		if err != nil {
+			httpserver.Errorf(w, r, "failed to marshal dead letters: %s", err)
+			return true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+		return true
+	case "/vmalert/api/v1/notifiers/dead_letters/resend", "/api/v1/notifiers/dead_letters/resend":
+		if r.Method != "POST" {
+			httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+			return true
+		}
+		sent, failed := notifier.ResendDeadLetters(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"sent":%d,"failed":%d}`, sent, failed)
+		return true
+	case "/vmalert/api/v1/status/config_version", "/api/v1/status/config_version":
+		w.Header().Set("Content-Type", "application/json")
+		configTracker.WriteVersion(w)
+		return true
 	case "/-/reload":
+		if r.FormValue("config_hash") != "" {
+			// Reload the config synchronously and apply it only if rules have the expected hash.
+			logger.Infof("api config reload with config_hash was called")
+			configTracker.HandleReload(w, r)
+			return true
+		}
 		logger.Infof("api config reload was called, sending sighup")
+		auditlog.Log(auditlog.NewEvent(r, "config_reload_request"), nil)
 		procutil.SelfSIGHUP()
 		w.WriteHeader(http.StatusOK)
 		return true
+	case "/-/rollback":
+		logger.Infof("api config rollback was called")
+		configTracker.HandleRollback(w, r)
+		return true
 
 	default:
 		// Support of deprecated links:
@@ -314,274 +314,285 @@ func StreamListGroups(qw422016 *qt422016.Writer, r *http.Request, groups []APIGr
                 <table class="table table-striped table-hover table-sm">
                     <thead>
                         <tr>
-                            <th scope="col" style="width: 60%">Rule</th>
-                            <th scope="col" style="width: 20%" class="text-center" title="How many samples were produced by the rule">Samples</th>
-                            <th scope="col" style="width: 20%" class="text-center" title="How many seconds ago rule was executed">Updated</th>
+                            <th scope="col" style="width: 55%">Rule</th>
+                            <th scope="col" style="width: 15%" class="text-center" title="How many samples were produced by the rule">Samples</th>
+                            <th scope="col" style="width: 15%" class="text-center" title="How many seconds the last evaluation took">Duration</th>
+                            <th scope="col" style="width: 15%" class="text-center" title="How many seconds ago rule was executed">Updated</th>
                         </tr>
                     </thead>
                     <tbody>
                     `)
-//line app/vmalert/web.qtpl:84
+//line app/vmalert/web.qtpl:85
 			for _, r := range g.Rules {
-//line app/vmalert/web.qtpl:84
+//line app/vmalert/web.qtpl:85
 				qw422016.N().S(`
                         <tr`)
-//line app/vmalert/web.qtpl:85
+//line app/vmalert/web.qtpl:86
 				if r.LastError != "" {
-//line app/vmalert/web.qtpl:85
+//line app/vmalert/web.qtpl:86
 					qw422016.N().S(` class="alert-danger"`)
-//line app/vmalert/web.qtpl:85
+//line app/vmalert/web.qtpl:86
 				}
-//line app/vmalert/web.qtpl:85
+//line app/vmalert/web.qtpl:86
 				qw422016.N().S(`>
                             <td>
                                 <div class="row">
                                     <div class="col-12 mb-2">
                                         `)
-//line app/vmalert/web.qtpl:89
+//line app/vmalert/web.qtpl:90
 				if r.Type == "alerting" {
-//line app/vmalert/web.qtpl:89
+//line app/vmalert/web.qtpl:90
 					qw422016.N().S(`
                                         <b>alert:</b> `)
-//line app/vmalert/web.qtpl:90
+//line app/vmalert/web.qtpl:91
 					qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:90
+//line app/vmalert/web.qtpl:91
 					qw422016.N().S(` (for: `)
-//line app/vmalert/web.qtpl:90
+//line app/vmalert/web.qtpl:91
 					qw422016.E().V(r.Duration)
-//line app/vmalert/web.qtpl:90
+//line app/vmalert/web.qtpl:91
 					qw422016.N().S(` seconds)
                                         `)
-//line app/vmalert/web.qtpl:91
+//line app/vmalert/web.qtpl:92
 				} else {
-//line app/vmalert/web.qtpl:91
+//line app/vmalert/web.qtpl:92
 					qw422016.N().S(`
                                         <b>record:</b> `)
-//line app/vmalert/web.qtpl:92
+//line app/vmalert/web.qtpl:93
 					qw422016.E().S(r.Name)
-//line app/vmalert/web.qtpl:92
+//line app/vmalert/web.qtpl:93
 					qw422016.N().S(`
                                         `)
-//line app/vmalert/web.qtpl:93
+//line app/vmalert/web.qtpl:94
 				}
-//line app/vmalert/web.qtpl:93
+//line app/vmalert/web.qtpl:94
 				qw422016.N().S(`
                                         | <span><a target="_blank" href="`)
-//line app/vmalert/web.qtpl:94
+//line app/vmalert/web.qtpl:95
 				qw422016.E().S(prefix + r.WebLink())
-//line app/vmalert/web.qtpl:94
+//line app/vmalert/web.qtpl:95
 				qw422016.N().S(`">Details</a></span>
+                                        | <span><a target="_blank" href="`)
+//line app/vmalert/web.qtpl:96
+				qw422016.E().S(prefix + r.EvalLink())
+//line app/vmalert/web.qtpl:96
+				qw422016.N().S(`">Test now</a></span>
                                     </div>
                                     <div class="col-12">
                                         <code><pre>`)
-//line app/vmalert/web.qtpl:97
+//line app/vmalert/web.qtpl:99
 				qw422016.E().S(r.Query)
-//line app/vmalert/web.qtpl:97
+//line app/vmalert/web.qtpl:99
 				qw422016.N().S(`</pre></code>
                                     </div>
                                     <div class="col-12 mb-2">
                                         `)
-//line app/vmalert/web.qtpl:100
+//line app/vmalert/web.qtpl:102
 				if len(r.Labels) > 0 {
-//line app/vmalert/web.qtpl:100
+//line app/vmalert/web.qtpl:102
 					qw422016.N().S(` <b>Labels:</b>`)
-//line app/vmalert/web.qtpl:100
+//line app/vmalert/web.qtpl:102
 				}
-//line app/vmalert/web.qtpl:100
+//line app/vmalert/web.qtpl:102
 				qw422016.N().S(`
                                         `)
-//line app/vmalert/web.qtpl:101
+//line app/vmalert/web.qtpl:103
 				for k, v := range r.Labels {
-//line app/vmalert/web.qtpl:101
+//line app/vmalert/web.qtpl:103
 					qw422016.N().S(`
                                                 <span class="ms-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:102
+//line app/vmalert/web.qtpl:104
 					qw422016.E().S(k)
-//line app/vmalert/web.qtpl:102
+//line app/vmalert/web.qtpl:104
 					qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:102
+//line app/vmalert/web.qtpl:104
 					qw422016.E().S(v)
-//line app/vmalert/web.qtpl:102
+//line app/vmalert/web.qtpl:104
 					qw422016.N().S(`</span>
                                         `)
-//line app/vmalert/web.qtpl:103
+//line app/vmalert/web.qtpl:105
 				}
-//line app/vmalert/web.qtpl:103
+//line app/vmalert/web.qtpl:105
 				qw422016.N().S(`
                                     </div>
                                     `)
-//line app/vmalert/web.qtpl:105
+//line app/vmalert/web.qtpl:107
 				if r.LastError != "" {
-//line app/vmalert/web.qtpl:105
+//line app/vmalert/web.qtpl:107
 					qw422016.N().S(`
                                     <div class="col-12">
                                         <b>Error:</b>
                                         <div class="error-cell">
                                         `)
-//line app/vmalert/web.qtpl:109
+//line app/vmalert/web.qtpl:111
 					qw422016.E().S(r.LastError)
-//line app/vmalert/web.qtpl:109
+//line app/vmalert/web.qtpl:111
 					qw422016.N().S(`
                                         </div>
                                     </div>
                                     `)
-//line app/vmalert/web.qtpl:112
+//line app/vmalert/web.qtpl:114
 				}
-//line app/vmalert/web.qtpl:112
+//line app/vmalert/web.qtpl:114
 				qw422016.N().S(`
                                 </div>
                             </td>
                             <td class="text-center">`)
-//line app/vmalert/web.qtpl:115
+//line app/vmalert/web.qtpl:117
 				qw422016.N().D(r.LastSamples)
-//line app/vmalert/web.qtpl:115
+//line app/vmalert/web.qtpl:117
 				qw422016.N().S(`</td>
                             <td class="text-center">`)
-//line app/vmalert/web.qtpl:116
+//line app/vmalert/web.qtpl:118
+				qw422016.N().FPrec(r.EvaluationTime, 3)
+//line app/vmalert/web.qtpl:118
+				qw422016.N().S(`s</td>
+                            <td class="text-center">`)
+//line app/vmalert/web.qtpl:119
 				qw422016.N().FPrec(time.Since(r.LastEvaluation).Seconds(), 3)
-//line app/vmalert/web.qtpl:116
+//line app/vmalert/web.qtpl:119
 				qw422016.N().S(`s ago</td>
                         </tr>
                     `)
-//line app/vmalert/web.qtpl:118
+//line app/vmalert/web.qtpl:121
 			}
-//line app/vmalert/web.qtpl:118
+//line app/vmalert/web.qtpl:121
 			qw422016.N().S(`
                  </tbody>
                 </table>
             </div>
         `)
-//line app/vmalert/web.qtpl:122
+//line app/vmalert/web.qtpl:125
 		}
-//line app/vmalert/web.qtpl:122
+//line app/vmalert/web.qtpl:125
 		qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:124
+//line app/vmalert/web.qtpl:127
 	} else {
-//line app/vmalert/web.qtpl:124
+//line app/vmalert/web.qtpl:127
 		qw422016.N().S(`
         <div>
             <p>No groups...</p>
         </div>
     `)
-//line app/vmalert/web.qtpl:128
+//line app/vmalert/web.qtpl:131
 	}
-//line app/vmalert/web.qtpl:128
+//line app/vmalert/web.qtpl:131
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:130
+//line app/vmalert/web.qtpl:133
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:130
+//line app/vmalert/web.qtpl:133
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 }
 
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 func WriteListGroups(qq422016 qtio422016.Writer, r *http.Request, groups []APIGroup) {
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	StreamListGroups(qw422016, r, groups)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 }
 
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 func ListGroups(r *http.Request, groups []APIGroup) string {
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	WriteListGroups(qb422016, r, groups)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 	return qs422016
-//line app/vmalert/web.qtpl:132
+//line app/vmalert/web.qtpl:135
 }
 
-//line app/vmalert/web.qtpl:135
+//line app/vmalert/web.qtpl:138
 func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []GroupAlerts) {
-//line app/vmalert/web.qtpl:135
+//line app/vmalert/web.qtpl:138
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:136
+//line app/vmalert/web.qtpl:139
 	prefix := utils.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:136
+//line app/vmalert/web.qtpl:139
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:137
+//line app/vmalert/web.qtpl:140
 	tpl.StreamHeader(qw422016, r, navItems, "Alerts")
-//line app/vmalert/web.qtpl:137
+//line app/vmalert/web.qtpl:140
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:138
+//line app/vmalert/web.qtpl:141
 	if len(groupAlerts) > 0 {
-//line app/vmalert/web.qtpl:138
+//line app/vmalert/web.qtpl:141
 		qw422016.N().S(`
          <a class="btn btn-primary" role="button" onclick="collapseAll()">Collapse All</a>
          <a class="btn btn-primary" role="button" onclick="expandAll()">Expand All</a>
          `)
-//line app/vmalert/web.qtpl:141
+//line app/vmalert/web.qtpl:144
 		for _, ga := range groupAlerts {
-//line app/vmalert/web.qtpl:141
+//line app/vmalert/web.qtpl:144
 			qw422016.N().S(`
             `)
-//line app/vmalert/web.qtpl:142
+//line app/vmalert/web.qtpl:145
 			g := ga.Group
 
-//line app/vmalert/web.qtpl:142
+//line app/vmalert/web.qtpl:145
 			qw422016.N().S(`
             <div class="group-heading alert-danger" data-bs-target="rules-`)
-//line app/vmalert/web.qtpl:143
+//line app/vmalert/web.qtpl:146
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:143
+//line app/vmalert/web.qtpl:146
 			qw422016.N().S(`">
                 <span class="anchor" id="group-`)
-//line app/vmalert/web.qtpl:144
+//line app/vmalert/web.qtpl:147
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:144
+//line app/vmalert/web.qtpl:147
 			qw422016.N().S(`"></span>
                 <a href="#group-`)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			qw422016.E().S(g.Name)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			if g.Type != "prometheus" {
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 				qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 				qw422016.E().S(g.Type)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 				qw422016.N().S(`)`)
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			}
-//line app/vmalert/web.qtpl:145
+//line app/vmalert/web.qtpl:148
 			qw422016.N().S(`</a>
                 <span class="badge bg-danger" title="Number of active alerts">`)
-//line app/vmalert/web.qtpl:146
+//line app/vmalert/web.qtpl:149
 			qw422016.N().D(len(ga.Alerts))
-//line app/vmalert/web.qtpl:146
+//line app/vmalert/web.qtpl:149
 			qw422016.N().S(`</span>
                 <br>
                 <p class="fs-6 fw-lighter">`)
-//line app/vmalert/web.qtpl:148
+//line app/vmalert/web.qtpl:151
 			qw422016.E().S(g.File)
-//line app/vmalert/web.qtpl:148
+//line app/vmalert/web.qtpl:151
 			qw422016.N().S(`</p>
             </div>
             `)
-//line app/vmalert/web.qtpl:151
+//line app/vmalert/web.qtpl:154
 			var keys []string
 			alertsByRule := make(map[string][]*APIAlert)
 			for _, alert := range ga.Alerts {
@@ -592,20 +603,20 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
 			}
 			sort.Strings(keys)
 
-//line app/vmalert/web.qtpl:160
+//line app/vmalert/web.qtpl:163
 			qw422016.N().S(`
             <div class="collapse" id="rules-`)
-//line app/vmalert/web.qtpl:161
+//line app/vmalert/web.qtpl:164
 			qw422016.E().S(g.ID)
-//line app/vmalert/web.qtpl:161
+//line app/vmalert/web.qtpl:164
 			qw422016.N().S(`">
                 `)
-//line app/vmalert/web.qtpl:162
+//line app/vmalert/web.qtpl:165
 			for _, ruleID := range keys {
-//line app/vmalert/web.qtpl:162
+//line app/vmalert/web.qtpl:165
 				qw422016.N().S(`
                     `)
-//line app/vmalert/web.qtpl:164
+//line app/vmalert/web.qtpl:167
 				defaultAR := alertsByRule[ruleID][0]
 				var labelKeys []string
 				for k := range defaultAR.Labels {
@@ -613,28 +624,28 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
 				}
 				sort.Strings(labelKeys)
 
-//line app/vmalert/web.qtpl:170
+//line app/vmalert/web.qtpl:173
 				qw422016.N().S(`
                     <br>
                     <b>alert:</b> `)
-//line app/vmalert/web.qtpl:172
+//line app/vmalert/web.qtpl:175
 				qw422016.E().S(defaultAR.Name)
-//line app/vmalert/web.qtpl:172
+//line app/vmalert/web.qtpl:175
 				qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:172
+//line app/vmalert/web.qtpl:175
 				qw422016.N().D(len(alertsByRule[ruleID]))
-//line app/vmalert/web.qtpl:172
+//line app/vmalert/web.qtpl:175
 				qw422016.N().S(`)
                      | <span><a target="_blank" href="`)
-//line app/vmalert/web.qtpl:173
+//line app/vmalert/web.qtpl:176
 				qw422016.E().S(defaultAR.SourceLink)
-//line app/vmalert/web.qtpl:173
+//line app/vmalert/web.qtpl:176
 				qw422016.N().S(`">Source</a></span>
                     <br>
                     <b>expr:</b><code><pre>`)
-//line app/vmalert/web.qtpl:175
+//line app/vmalert/web.qtpl:178
 				qw422016.E().S(defaultAR.Expression)
-//line app/vmalert/web.qtpl:175
+//line app/vmalert/web.qtpl:178
 				qw422016.N().S(`</pre></code>
                     <table class="table table-striped table-hover table-sm">
                         <thead>
@@ -648,204 +659,204 @@ func StreamListAlerts(qw422016 *qt422016.Writer, r *http.Request, groupAlerts []
                         </thead>
                         <tbody>
                         `)
-//line app/vmalert/web.qtpl:187
+//line app/vmalert/web.qtpl:190
 				for _, ar := range alertsByRule[ruleID] {
-//line app/vmalert/web.qtpl:187
+//line app/vmalert/web.qtpl:190
 					qw422016.N().S(`
                             <tr>
                                 <td>
                                     `)
-//line app/vmalert/web.qtpl:190
+//line app/vmalert/web.qtpl:193
 					for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:190
+//line app/vmalert/web.qtpl:193
 						qw422016.N().S(`
                                         <span class="ms-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:191
+//line app/vmalert/web.qtpl:194
 						qw422016.E().S(k)
-//line app/vmalert/web.qtpl:191
+//line app/vmalert/web.qtpl:194
 						qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:191
+//line app/vmalert/web.qtpl:194
 						qw422016.E().S(ar.Labels[k])
-//line app/vmalert/web.qtpl:191
+//line app/vmalert/web.qtpl:194
 						qw422016.N().S(`</span>
                                     `)
-//line app/vmalert/web.qtpl:192
+//line app/vmalert/web.qtpl:195
 					}
-//line app/vmalert/web.qtpl:192
+//line app/vmalert/web.qtpl:195
 					qw422016.N().S(`
                                 </td>
                                 <td>`)
-//line app/vmalert/web.qtpl:194
+//line app/vmalert/web.qtpl:197
 					streambadgeState(qw422016, ar.State)
-//line app/vmalert/web.qtpl:194
+//line app/vmalert/web.qtpl:197
 					qw422016.N().S(`</td>
                                 <td>
                                     `)
-//line app/vmalert/web.qtpl:196
+//line app/vmalert/web.qtpl:199
 					qw422016.E().S(ar.ActiveAt.Format("2006-01-02T15:04:05Z07:00"))
-//line app/vmalert/web.qtpl:196
+//line app/vmalert/web.qtpl:199
 					qw422016.N().S(`
                                     `)
-//line app/vmalert/web.qtpl:197
+//line app/vmalert/web.qtpl:200
 					if ar.Restored {
-//line app/vmalert/web.qtpl:197
+//line app/vmalert/web.qtpl:200
 						streambadgeRestored(qw422016)
-//line app/vmalert/web.qtpl:197
+//line app/vmalert/web.qtpl:200
 					}
-//line app/vmalert/web.qtpl:197
+//line app/vmalert/web.qtpl:200
 					qw422016.N().S(`
                                 </td>
                                 <td>`)
-//line app/vmalert/web.qtpl:199
+//line app/vmalert/web.qtpl:202
 					qw422016.E().S(ar.Value)
-//line app/vmalert/web.qtpl:199
+//line app/vmalert/web.qtpl:202
 					qw422016.N().S(`</td>
                                 <td>
                                     <a href="`)
-//line app/vmalert/web.qtpl:201
+//line app/vmalert/web.qtpl:204
 					qw422016.E().S(prefix + ar.WebLink())
-//line app/vmalert/web.qtpl:201
+//line app/vmalert/web.qtpl:204
 					qw422016.N().S(`">Details</a>
                                 </td>
                             </tr>
                         `)
-//line app/vmalert/web.qtpl:204
+//line app/vmalert/web.qtpl:207
 				}
-//line app/vmalert/web.qtpl:204
+//line app/vmalert/web.qtpl:207
 				qw422016.N().S(`
                      </tbody>
                     </table>
                 `)
-//line app/vmalert/web.qtpl:207
+//line app/vmalert/web.qtpl:210
 			}
-//line app/vmalert/web.qtpl:207
+//line app/vmalert/web.qtpl:210
 			qw422016.N().S(`
             </div>
             <br>
         `)
-//line app/vmalert/web.qtpl:210
+//line app/vmalert/web.qtpl:213
 		}
-//line app/vmalert/web.qtpl:210
+//line app/vmalert/web.qtpl:213
 		qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:212
+//line app/vmalert/web.qtpl:215
 	} else {
-//line app/vmalert/web.qtpl:212
+//line app/vmalert/web.qtpl:215
 		qw422016.N().S(`
         <div>
             <p>No active alerts...</p>
         </div>
     `)
-//line app/vmalert/web.qtpl:216
+//line app/vmalert/web.qtpl:219
 	}
-//line app/vmalert/web.qtpl:216
+//line app/vmalert/web.qtpl:219
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:218
+//line app/vmalert/web.qtpl:221
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:218
+//line app/vmalert/web.qtpl:221
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 }
 
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 func WriteListAlerts(qq422016 qtio422016.Writer, r *http.Request, groupAlerts []GroupAlerts) {
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	StreamListAlerts(qw422016, r, groupAlerts)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 }
 
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 func ListAlerts(r *http.Request, groupAlerts []GroupAlerts) string {
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	WriteListAlerts(qb422016, r, groupAlerts)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 	return qs422016
-//line app/vmalert/web.qtpl:220
+//line app/vmalert/web.qtpl:223
 }
 
-//line app/vmalert/web.qtpl:222
+//line app/vmalert/web.qtpl:225
 func StreamListTargets(qw422016 *qt422016.Writer, r *http.Request, targets map[notifier.TargetType][]notifier.Target) {
-//line app/vmalert/web.qtpl:222
+//line app/vmalert/web.qtpl:225
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:223
+//line app/vmalert/web.qtpl:226
 	tpl.StreamHeader(qw422016, r, navItems, "Notifiers")
-//line app/vmalert/web.qtpl:223
+//line app/vmalert/web.qtpl:226
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:224
+//line app/vmalert/web.qtpl:227
 	if len(targets) > 0 {
-//line app/vmalert/web.qtpl:224
+//line app/vmalert/web.qtpl:227
 		qw422016.N().S(`
          <a class="btn btn-primary" role="button" onclick="collapseAll()">Collapse All</a>
          <a class="btn btn-primary" role="button" onclick="expandAll()">Expand All</a>
 
          `)
-//line app/vmalert/web.qtpl:229
+//line app/vmalert/web.qtpl:232
 		var keys []string
 		for key := range targets {
 			keys = append(keys, string(key))
 		}
 		sort.Strings(keys)
 
-//line app/vmalert/web.qtpl:234
+//line app/vmalert/web.qtpl:237
 		qw422016.N().S(`
 
          `)
-//line app/vmalert/web.qtpl:236
+//line app/vmalert/web.qtpl:239
 		for i := range keys {
-//line app/vmalert/web.qtpl:236
+//line app/vmalert/web.qtpl:239
 			qw422016.N().S(`
            `)
-//line app/vmalert/web.qtpl:237
+//line app/vmalert/web.qtpl:240
 			typeK, ns := keys[i], targets[notifier.TargetType(keys[i])]
 			count := len(ns)
 
-//line app/vmalert/web.qtpl:239
+//line app/vmalert/web.qtpl:242
 			qw422016.N().S(`
            <div class="group-heading data-bs-target="rules-`)
-//line app/vmalert/web.qtpl:240
+//line app/vmalert/web.qtpl:243
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:240
+//line app/vmalert/web.qtpl:243
 			qw422016.N().S(`">
              <span class="anchor" id="notifiers-`)
-//line app/vmalert/web.qtpl:241
+//line app/vmalert/web.qtpl:244
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:241
+//line app/vmalert/web.qtpl:244
 			qw422016.N().S(`"></span>
              <a href="#notifiers-`)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.N().S(` (`)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.N().D(count)
-//line app/vmalert/web.qtpl:242
+//line app/vmalert/web.qtpl:245
 			qw422016.N().S(`)</a>
          </div>
          <div class="collapse show" id="notifiers-`)
-//line app/vmalert/web.qtpl:244
+//line app/vmalert/web.qtpl:247
 			qw422016.E().S(typeK)
-//line app/vmalert/web.qtpl:244
+//line app/vmalert/web.qtpl:247
 			qw422016.N().S(`">
              <table class="table table-striped table-hover table-sm">
                  <thead>
@@ -856,119 +867,119 @@ func StreamListTargets(qw422016 *qt422016.Writer, r *http.Request, targets map[n
                  </thead>
                  <tbody>
                  `)
-//line app/vmalert/web.qtpl:253
+//line app/vmalert/web.qtpl:256
 			for _, n := range ns {
-//line app/vmalert/web.qtpl:253
+//line app/vmalert/web.qtpl:256
 				qw422016.N().S(`
                      <tr>
                          <td>
                               `)
-//line app/vmalert/web.qtpl:256
+//line app/vmalert/web.qtpl:259
 				for _, l := range n.Labels.GetLabels() {
-//line app/vmalert/web.qtpl:256
+//line app/vmalert/web.qtpl:259
 					qw422016.N().S(`
                                       <span class="ms-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:257
+//line app/vmalert/web.qtpl:260
 					qw422016.E().S(l.Name)
-//line app/vmalert/web.qtpl:257
+//line app/vmalert/web.qtpl:260
 					qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:257
+//line app/vmalert/web.qtpl:260
 					qw422016.E().S(l.Value)
-//line app/vmalert/web.qtpl:257
+//line app/vmalert/web.qtpl:260
 					qw422016.N().S(`</span>
                               `)
-//line app/vmalert/web.qtpl:258
+//line app/vmalert/web.qtpl:261
 				}
-//line app/vmalert/web.qtpl:258
+//line app/vmalert/web.qtpl:261
 				qw422016.N().S(`
                           </td>
                          <td>`)
-//line app/vmalert/web.qtpl:260
+//line app/vmalert/web.qtpl:263
 				qw422016.E().S(n.Notifier.Addr())
-//line app/vmalert/web.qtpl:260
+//line app/vmalert/web.qtpl:263
 				qw422016.N().S(`</td>
                      </tr>
                  `)
-//line app/vmalert/web.qtpl:262
+//line app/vmalert/web.qtpl:265
 			}
-//line app/vmalert/web.qtpl:262
+//line app/vmalert/web.qtpl:265
 			qw422016.N().S(`
               </tbody>
              </table>
          </div>
      `)
-//line app/vmalert/web.qtpl:266
+//line app/vmalert/web.qtpl:269
 		}
-//line app/vmalert/web.qtpl:266
+//line app/vmalert/web.qtpl:269
 		qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:268
+//line app/vmalert/web.qtpl:271
 	} else {
-//line app/vmalert/web.qtpl:268
+//line app/vmalert/web.qtpl:271
 		qw422016.N().S(`
         <div>
             <p>No targets...</p>
         </div>
     `)
-//line app/vmalert/web.qtpl:272
+//line app/vmalert/web.qtpl:275
 	}
-//line app/vmalert/web.qtpl:272
+//line app/vmalert/web.qtpl:275
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:274
+//line app/vmalert/web.qtpl:277
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:274
+//line app/vmalert/web.qtpl:277
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 }
 
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 func WriteListTargets(qq422016 qtio422016.Writer, r *http.Request, targets map[notifier.TargetType][]notifier.Target) {
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	StreamListTargets(qw422016, r, targets)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 }
 
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 func ListTargets(r *http.Request, targets map[notifier.TargetType][]notifier.Target) string {
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	WriteListTargets(qb422016, r, targets)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 	return qs422016
-//line app/vmalert/web.qtpl:276
+//line app/vmalert/web.qtpl:279
 }
 
-//line app/vmalert/web.qtpl:278
+//line app/vmalert/web.qtpl:281
 func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
-//line app/vmalert/web.qtpl:278
+//line app/vmalert/web.qtpl:281
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:279
+//line app/vmalert/web.qtpl:282
 	prefix := utils.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:279
+//line app/vmalert/web.qtpl:282
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:280
+//line app/vmalert/web.qtpl:283
 	tpl.StreamHeader(qw422016, r, navItems, "")
-//line app/vmalert/web.qtpl:280
+//line app/vmalert/web.qtpl:283
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:282
+//line app/vmalert/web.qtpl:285
 	var labelKeys []string
 	for k := range alert.Labels {
 		labelKeys = append(labelKeys, k)
@@ -981,28 +992,28 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
 	}
 	sort.Strings(annotationKeys)
 
-//line app/vmalert/web.qtpl:293
+//line app/vmalert/web.qtpl:296
 	qw422016.N().S(`
     <div class="display-6 pb-3 mb-3">Alert: `)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	qw422016.E().S(alert.Name)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	qw422016.N().S(`<span class="ms-2 badge `)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	if alert.State == "firing" {
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 		qw422016.N().S(`bg-danger`)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	} else {
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 		qw422016.N().S(` bg-warning text-dark`)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	}
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	qw422016.E().S(alert.State)
-//line app/vmalert/web.qtpl:294
+//line app/vmalert/web.qtpl:297
 	qw422016.N().S(`</span></div>
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1011,9 +1022,9 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:301
+//line app/vmalert/web.qtpl:304
 	qw422016.E().S(alert.ActiveAt.Format("2006-01-02T15:04:05Z07:00"))
-//line app/vmalert/web.qtpl:301
+//line app/vmalert/web.qtpl:304
 	qw422016.N().S(`
         </div>
       </div>
@@ -1025,9 +1036,9 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
           <code><pre>`)
-//line app/vmalert/web.qtpl:311
+//line app/vmalert/web.qtpl:314
 	qw422016.E().S(alert.Expression)
-//line app/vmalert/web.qtpl:311
+//line app/vmalert/web.qtpl:314
 	qw422016.N().S(`</pre></code>
         </div>
       </div>
@@ -1039,23 +1050,23 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:321
+//line app/vmalert/web.qtpl:324
 	for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:321
+//line app/vmalert/web.qtpl:324
 		qw422016.N().S(`
                 <span class="m-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:322
+//line app/vmalert/web.qtpl:325
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:322
+//line app/vmalert/web.qtpl:325
 		qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:322
+//line app/vmalert/web.qtpl:325
 		qw422016.E().S(alert.Labels[k])
-//line app/vmalert/web.qtpl:322
+//line app/vmalert/web.qtpl:325
 		qw422016.N().S(`</span>
           `)
-//line app/vmalert/web.qtpl:323
+//line app/vmalert/web.qtpl:326
 	}
-//line app/vmalert/web.qtpl:323
+//line app/vmalert/web.qtpl:326
 	qw422016.N().S(`
         </div>
       </div>
@@ -1067,24 +1078,24 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:333
+//line app/vmalert/web.qtpl:336
 	for _, k := range annotationKeys {
-//line app/vmalert/web.qtpl:333
+//line app/vmalert/web.qtpl:336
 		qw422016.N().S(`
                 <b>`)
-//line app/vmalert/web.qtpl:334
+//line app/vmalert/web.qtpl:337
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:334
+//line app/vmalert/web.qtpl:337
 		qw422016.N().S(`:</b><br>
                 <p>`)
-//line app/vmalert/web.qtpl:335
+//line app/vmalert/web.qtpl:338
 		qw422016.E().S(alert.Annotations[k])
-//line app/vmalert/web.qtpl:335
+//line app/vmalert/web.qtpl:338
 		qw422016.N().S(`</p>
           `)
-//line app/vmalert/web.qtpl:336
+//line app/vmalert/web.qtpl:339
 	}
-//line app/vmalert/web.qtpl:336
+//line app/vmalert/web.qtpl:339
 	qw422016.N().S(`
         </div>
       </div>
@@ -1096,17 +1107,17 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.E().S(prefix)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.N().S(`groups#group-`)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.E().S(alert.GroupID)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.E().S(alert.GroupID)
-//line app/vmalert/web.qtpl:346
+//line app/vmalert/web.qtpl:349
 	qw422016.N().S(`</a>
         </div>
       </div>
@@ -1118,66 +1129,66 @@ func StreamAlert(qw422016 *qt422016.Writer, r *http.Request, alert *APIAlert) {
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:356
+//line app/vmalert/web.qtpl:359
 	qw422016.E().S(alert.SourceLink)
-//line app/vmalert/web.qtpl:356
+//line app/vmalert/web.qtpl:359
 	qw422016.N().S(`">Link</a>
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:360
+//line app/vmalert/web.qtpl:363
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:360
+//line app/vmalert/web.qtpl:363
 	qw422016.N().S(`
 
 `)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 }
 
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 func WriteAlert(qq422016 qtio422016.Writer, r *http.Request, alert *APIAlert) {
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	StreamAlert(qw422016, r, alert)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 }
 
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 func Alert(r *http.Request, alert *APIAlert) string {
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	WriteAlert(qb422016, r, alert)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 	return qs422016
-//line app/vmalert/web.qtpl:362
+//line app/vmalert/web.qtpl:365
 }
 
-//line app/vmalert/web.qtpl:365
+//line app/vmalert/web.qtpl:368
 func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule) {
-//line app/vmalert/web.qtpl:365
+//line app/vmalert/web.qtpl:368
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:366
+//line app/vmalert/web.qtpl:369
 	prefix := utils.Prefix(r.URL.Path)
 
-//line app/vmalert/web.qtpl:366
+//line app/vmalert/web.qtpl:369
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:367
+//line app/vmalert/web.qtpl:370
 	tpl.StreamHeader(qw422016, r, navItems, "")
-//line app/vmalert/web.qtpl:367
+//line app/vmalert/web.qtpl:370
 	qw422016.N().S(`
     `)
-//line app/vmalert/web.qtpl:369
+//line app/vmalert/web.qtpl:372
 	var labelKeys []string
 	for k := range rule.Labels {
 		labelKeys = append(labelKeys, k)
@@ -1190,29 +1201,34 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
 	}
 	sort.Strings(annotationKeys)
 
-//line app/vmalert/web.qtpl:380
+//line app/vmalert/web.qtpl:383
 	qw422016.N().S(`
     <div class="display-6 pb-3 mb-3">Rule: `)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	qw422016.E().S(rule.Name)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	qw422016.N().S(`<span class="ms-2 badge `)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	if rule.Health != "ok" {
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 		qw422016.N().S(`bg-danger`)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	} else {
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 		qw422016.N().S(` bg-warning text-dark`)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	}
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	qw422016.E().S(rule.Health)
-//line app/vmalert/web.qtpl:381
+//line app/vmalert/web.qtpl:384
 	qw422016.N().S(`</span></div>
+    <a class="btn btn-primary mb-3" role="button" href="`)
+//line app/vmalert/web.qtpl:385
+	qw422016.E().S(prefix + rule.EvalLink())
+//line app/vmalert/web.qtpl:385
+	qw422016.N().S(`" title="Execute the rule expression against the datasource and show the result without changing the rule state">Test now</a>
     <div class="container border-bottom p-2">
       <div class="row">
         <div class="col-2">
@@ -1220,17 +1236,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
           <code><pre>`)
-//line app/vmalert/web.qtpl:388
+//line app/vmalert/web.qtpl:392
 	qw422016.E().S(rule.Query)
-//line app/vmalert/web.qtpl:388
+//line app/vmalert/web.qtpl:392
 	qw422016.N().S(`</pre></code>
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:392
+//line app/vmalert/web.qtpl:396
 	if rule.Type == "alerting" {
-//line app/vmalert/web.qtpl:392
+//line app/vmalert/web.qtpl:396
 		qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1239,17 +1255,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
          `)
-//line app/vmalert/web.qtpl:399
+//line app/vmalert/web.qtpl:403
 		qw422016.E().V(rule.Duration)
-//line app/vmalert/web.qtpl:399
+//line app/vmalert/web.qtpl:403
 		qw422016.N().S(` seconds
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:403
+//line app/vmalert/web.qtpl:407
 	}
-//line app/vmalert/web.qtpl:403
+//line app/vmalert/web.qtpl:407
 	qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1258,31 +1274,31 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:410
+//line app/vmalert/web.qtpl:414
 	for _, k := range labelKeys {
-//line app/vmalert/web.qtpl:410
+//line app/vmalert/web.qtpl:414
 		qw422016.N().S(`
                 <span class="m-1 badge bg-primary">`)
-//line app/vmalert/web.qtpl:411
+//line app/vmalert/web.qtpl:415
 		qw422016.E().S(k)
-//line app/vmalert/web.qtpl:411
+//line app/vmalert/web.qtpl:415
 		qw422016.N().S(`=`)
-//line app/vmalert/web.qtpl:411
+//line app/vmalert/web.qtpl:415
 		qw422016.E().S(rule.Labels[k])
-//line app/vmalert/web.qtpl:411
+//line app/vmalert/web.qtpl:415
 		qw422016.N().S(`</span>
           `)
-//line app/vmalert/web.qtpl:412
+//line app/vmalert/web.qtpl:416
 	}
-//line app/vmalert/web.qtpl:412
+//line app/vmalert/web.qtpl:416
 	qw422016.N().S(`
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:416
+//line app/vmalert/web.qtpl:420
 	if rule.Type == "alerting" {
-//line app/vmalert/web.qtpl:416
+//line app/vmalert/web.qtpl:420
 		qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1291,24 +1307,24 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
           `)
-//line app/vmalert/web.qtpl:423
+//line app/vmalert/web.qtpl:427
 		for _, k := range annotationKeys {
-//line app/vmalert/web.qtpl:423
+//line app/vmalert/web.qtpl:427
 			qw422016.N().S(`
                 <b>`)
-//line app/vmalert/web.qtpl:424
+//line app/vmalert/web.qtpl:428
 			qw422016.E().S(k)
-//line app/vmalert/web.qtpl:424
+//line app/vmalert/web.qtpl:428
 			qw422016.N().S(`:</b><br>
                 <p>`)
-//line app/vmalert/web.qtpl:425
+//line app/vmalert/web.qtpl:429
 			qw422016.E().S(rule.Annotations[k])
-//line app/vmalert/web.qtpl:425
+//line app/vmalert/web.qtpl:429
 			qw422016.N().S(`</p>
           `)
-//line app/vmalert/web.qtpl:426
+//line app/vmalert/web.qtpl:430
 		}
-//line app/vmalert/web.qtpl:426
+//line app/vmalert/web.qtpl:430
 		qw422016.N().S(`
         </div>
       </div>
@@ -1320,17 +1336,17 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
            `)
-//line app/vmalert/web.qtpl:436
+//line app/vmalert/web.qtpl:440
 		qw422016.E().V(rule.Debug)
-//line app/vmalert/web.qtpl:436
+//line app/vmalert/web.qtpl:440
 		qw422016.N().S(`
         </div>
       </div>
     </div>
     `)
-//line app/vmalert/web.qtpl:440
+//line app/vmalert/web.qtpl:444
 	}
-//line app/vmalert/web.qtpl:440
+//line app/vmalert/web.qtpl:444
 	qw422016.N().S(`
     <div class="container border-bottom p-2">
       <div class="row">
@@ -1339,32 +1355,56 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
         </div>
         <div class="col">
            <a target="_blank" href="`)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.E().S(prefix)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.N().S(`groups#group-`)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.E().S(rule.GroupID)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.E().S(rule.GroupID)
-//line app/vmalert/web.qtpl:447
+//line app/vmalert/web.qtpl:451
 	qw422016.N().S(`</a>
         </div>
       </div>
     </div>
 
+    `)
+//line app/vmalert/web.qtpl:457
+	var failedUpdates int
+	for _, u := range rule.Updates {
+		if u.err != nil {
+			failedUpdates++
+		}
+	}
+
+//line app/vmalert/web.qtpl:463
+	qw422016.N().S(`
     <br>
     <div class="display-6 pb-3">Last `)
-//line app/vmalert/web.qtpl:453
+//line app/vmalert/web.qtpl:465
 	qw422016.N().D(len(rule.Updates))
-//line app/vmalert/web.qtpl:453
+//line app/vmalert/web.qtpl:465
 	qw422016.N().S(`/`)
-//line app/vmalert/web.qtpl:453
+//line app/vmalert/web.qtpl:465
 	qw422016.N().D(rule.MaxUpdates)
-//line app/vmalert/web.qtpl:453
-	qw422016.N().S(` updates</span>:</div>
+//line app/vmalert/web.qtpl:465
+	qw422016.N().S(` updates
+        `)
+//line app/vmalert/web.qtpl:466
+	if failedUpdates > 0 {
+//line app/vmalert/web.qtpl:466
+		qw422016.N().S(`<span class="ms-2 badge bg-danger" title="Number of updates with errors">`)
+//line app/vmalert/web.qtpl:466
+		qw422016.N().D(failedUpdates)
+//line app/vmalert/web.qtpl:466
+		qw422016.N().S(` failed</span>`)
+//line app/vmalert/web.qtpl:466
+	}
+//line app/vmalert/web.qtpl:466
+	qw422016.N().S(`:</div>
         <table class="table table-striped table-hover table-sm">
             <thead>
                 <tr>
@@ -1378,201 +1418,511 @@ func StreamRuleDetails(qw422016 *qt422016.Writer, r *http.Request, rule APIRule)
             <tbody>
 
      `)
-//line app/vmalert/web.qtpl:466
+//line app/vmalert/web.qtpl:479
 	for _, u := range rule.Updates {
-//line app/vmalert/web.qtpl:466
+//line app/vmalert/web.qtpl:479
 		qw422016.N().S(`
              <tr`)
-//line app/vmalert/web.qtpl:467
+//line app/vmalert/web.qtpl:480
 		if u.err != nil {
-//line app/vmalert/web.qtpl:467
+//line app/vmalert/web.qtpl:480
 			qw422016.N().S(` class="alert-danger"`)
-//line app/vmalert/web.qtpl:467
+//line app/vmalert/web.qtpl:480
 		}
-//line app/vmalert/web.qtpl:467
+//line app/vmalert/web.qtpl:480
 		qw422016.N().S(`>
                  <td>
                     <span class="badge bg-primary rounded-pill me-3" title="Updated at">`)
-//line app/vmalert/web.qtpl:469
+//line app/vmalert/web.qtpl:482
 		qw422016.E().S(u.time.Format(time.RFC3339))
-//line app/vmalert/web.qtpl:469
+//line app/vmalert/web.qtpl:482
 		qw422016.N().S(`</span>
                  </td>
                  <td class="text-center" wi>`)
-//line app/vmalert/web.qtpl:471
+//line app/vmalert/web.qtpl:484
 		qw422016.N().D(u.samples)
-//line app/vmalert/web.qtpl:471
+//line app/vmalert/web.qtpl:484
 		qw422016.N().S(`</td>
                  <td class="text-center">`)
-//line app/vmalert/web.qtpl:472
+//line app/vmalert/web.qtpl:485
 		qw422016.N().FPrec(u.duration.Seconds(), 3)
-//line app/vmalert/web.qtpl:472
+//line app/vmalert/web.qtpl:485
 		qw422016.N().S(`s</td>
                  <td class="text-center">`)
-//line app/vmalert/web.qtpl:473
+//line app/vmalert/web.qtpl:486
 		qw422016.E().S(u.at.Format(time.RFC3339))
-//line app/vmalert/web.qtpl:473
+//line app/vmalert/web.qtpl:486
 		qw422016.N().S(`</td>
                  <td>
                     <textarea class="curl-area" rows="1" onclick="this.focus();this.select()">`)
-//line app/vmalert/web.qtpl:475
+//line app/vmalert/web.qtpl:488
 		qw422016.E().S(u.curl)
-//line app/vmalert/web.qtpl:475
+//line app/vmalert/web.qtpl:488
 		qw422016.N().S(`</textarea>
                 </td>
              </tr>
           </li>
           `)
-//line app/vmalert/web.qtpl:479
+//line app/vmalert/web.qtpl:492
 		if u.err != nil {
-//line app/vmalert/web.qtpl:479
+//line app/vmalert/web.qtpl:492
 			qw422016.N().S(`
              <tr`)
-//line app/vmalert/web.qtpl:480
+//line app/vmalert/web.qtpl:493
 			if u.err != nil {
-//line app/vmalert/web.qtpl:480
+//line app/vmalert/web.qtpl:493
 				qw422016.N().S(` class="alert-danger"`)
-//line app/vmalert/web.qtpl:480
+//line app/vmalert/web.qtpl:493
 			}
-//line app/vmalert/web.qtpl:480
+//line app/vmalert/web.qtpl:493
 			qw422016.N().S(`>
                <td colspan="5">
                    <span class="alert-danger">`)
-//line app/vmalert/web.qtpl:482
+//line app/vmalert/web.qtpl:495
 			qw422016.E().V(u.err)
-//line app/vmalert/web.qtpl:482
+//line app/vmalert/web.qtpl:495
 			qw422016.N().S(`</span>
                </td>
              </tr>
           `)
-//line app/vmalert/web.qtpl:485
+//line app/vmalert/web.qtpl:498
 		}
-//line app/vmalert/web.qtpl:485
+//line app/vmalert/web.qtpl:498
 		qw422016.N().S(`
      `)
-//line app/vmalert/web.qtpl:486
+//line app/vmalert/web.qtpl:499
 	}
-//line app/vmalert/web.qtpl:486
+//line app/vmalert/web.qtpl:499
 	qw422016.N().S(`
 
     `)
-//line app/vmalert/web.qtpl:488
+//line app/vmalert/web.qtpl:501
 	tpl.StreamFooter(qw422016, r)
-//line app/vmalert/web.qtpl:488
+//line app/vmalert/web.qtpl:501
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 }
 
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 func WriteRuleDetails(qq422016 qtio422016.Writer, r *http.Request, rule APIRule) {
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	StreamRuleDetails(qw422016, r, rule)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 }
 
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 func RuleDetails(r *http.Request, rule APIRule) string {
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	WriteRuleDetails(qb422016, r, rule)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 	return qs422016
-//line app/vmalert/web.qtpl:489
+//line app/vmalert/web.qtpl:502
 }
 
-//line app/vmalert/web.qtpl:493
+//line app/vmalert/web.qtpl:506
+func StreamRuleEval(qw422016 *qt422016.Writer, r *http.Request, re *APIRuleEval) {
+//line app/vmalert/web.qtpl:506
+	qw422016.N().S(`
+    `)
+//line app/vmalert/web.qtpl:507
+	prefix := utils.Prefix(r.URL.Path)
+
+//line app/vmalert/web.qtpl:507
+	qw422016.N().S(`
+    `)
+//line app/vmalert/web.qtpl:508
+	tpl.StreamHeader(qw422016, r, navItems, "")
+//line app/vmalert/web.qtpl:508
+	qw422016.N().S(`
+    `)
+//line app/vmalert/web.qtpl:509
+	rule := re.Rule
+
+//line app/vmalert/web.qtpl:509
+	qw422016.N().S(`
+    <div class="display-6 pb-3 mb-3">Test: `)
+//line app/vmalert/web.qtpl:510
+	qw422016.E().S(rule.Name)
+//line app/vmalert/web.qtpl:510
+	qw422016.N().S(`<span class="ms-2 badge `)
+//line app/vmalert/web.qtpl:510
+	if re.Error != "" {
+//line app/vmalert/web.qtpl:510
+		qw422016.N().S(`bg-danger`)
+//line app/vmalert/web.qtpl:510
+	} else {
+//line app/vmalert/web.qtpl:510
+		qw422016.N().S(` bg-success`)
+//line app/vmalert/web.qtpl:510
+	}
+//line app/vmalert/web.qtpl:510
+	qw422016.N().S(`">`)
+//line app/vmalert/web.qtpl:510
+	if re.Error != "" {
+//line app/vmalert/web.qtpl:510
+		qw422016.N().S(`err`)
+//line app/vmalert/web.qtpl:510
+	} else {
+//line app/vmalert/web.qtpl:510
+		qw422016.N().S(`ok`)
+//line app/vmalert/web.qtpl:510
+	}
+//line app/vmalert/web.qtpl:510
+	qw422016.N().S(`</span></div>
+    <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          Expr
+        </div>
+        <div class="col">
+          <code><pre>`)
+//line app/vmalert/web.qtpl:517
+	qw422016.E().S(rule.Query)
+//line app/vmalert/web.qtpl:517
+	qw422016.N().S(`</pre></code>
+        </div>
+      </div>
+    </div>
+    `)
+//line app/vmalert/web.qtpl:521
+	if rule.Type == "alerting" {
+//line app/vmalert/web.qtpl:521
+		qw422016.N().S(`
+    <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          For
+        </div>
+        <div class="col">
+         `)
+//line app/vmalert/web.qtpl:528
+		qw422016.E().V(rule.Duration)
+//line app/vmalert/web.qtpl:528
+		qw422016.N().S(` seconds
+        </div>
+      </div>
+    </div>
+    `)
+//line app/vmalert/web.qtpl:532
+	}
+//line app/vmalert/web.qtpl:532
+	qw422016.N().S(`
+    <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          Evaluated at
+        </div>
+        <div class="col">
+          `)
+//line app/vmalert/web.qtpl:539
+	qw422016.E().S(re.EvaluatedAt.Format(time.RFC3339))
+//line app/vmalert/web.qtpl:539
+	qw422016.N().S(` (took `)
+//line app/vmalert/web.qtpl:539
+	qw422016.N().FPrec(re.Duration, 3)
+//line app/vmalert/web.qtpl:539
+	qw422016.N().S(`s)
+        </div>
+      </div>
+    </div>
+    <div class="container border-bottom p-2">
+      <div class="row">
+        <div class="col-2">
+          Rule
+        </div>
+        <div class="col">
+           <a href="`)
+//line app/vmalert/web.qtpl:549
+	qw422016.E().S(prefix + rule.WebLink())
+//line app/vmalert/web.qtpl:549
+	qw422016.N().S(`">Details</a>
+           | <a href="`)
+//line app/vmalert/web.qtpl:550
+	qw422016.E().S(prefix + rule.EvalLink())
+//line app/vmalert/web.qtpl:550
+	qw422016.N().S(`">Test again</a>
+        </div>
+      </div>
+    </div>
+    `)
+//line app/vmalert/web.qtpl:554
+	if re.Error != "" {
+//line app/vmalert/web.qtpl:554
+		qw422016.N().S(`
+    <div class="container p-2 alert-danger">
+        <b>Error:</b>
+        <div class="error-cell">
+        `)
+//line app/vmalert/web.qtpl:558
+		qw422016.E().S(re.Error)
+//line app/vmalert/web.qtpl:558
+		qw422016.N().S(`
+        </div>
+    </div>
+    `)
+//line app/vmalert/web.qtpl:561
+	}
+//line app/vmalert/web.qtpl:561
+	qw422016.N().S(`
+
+    <br>
+    `)
+//line app/vmalert/web.qtpl:564
+	if rule.Type == "alerting" {
+//line app/vmalert/web.qtpl:564
+		qw422016.N().S(`
+    <div class="display-6 pb-3">Would-be alerts (`)
+//line app/vmalert/web.qtpl:565
+		qw422016.N().D(len(re.Series))
+//line app/vmalert/web.qtpl:565
+		qw422016.N().S(`):</div>
+    `)
+//line app/vmalert/web.qtpl:566
+	} else {
+//line app/vmalert/web.qtpl:566
+		qw422016.N().S(`
+    <div class="display-6 pb-3">Would-be series (`)
+//line app/vmalert/web.qtpl:567
+		qw422016.N().D(len(re.Series))
+//line app/vmalert/web.qtpl:567
+		qw422016.N().S(`):</div>
+    `)
+//line app/vmalert/web.qtpl:568
+	}
+//line app/vmalert/web.qtpl:568
+	qw422016.N().S(`
+    `)
+//line app/vmalert/web.qtpl:569
+	if len(re.Series) > 0 {
+//line app/vmalert/web.qtpl:569
+		qw422016.N().S(`
+        <table class="table table-striped table-hover table-sm">
+            <thead>
+                <tr>
+                    <th scope="col">Labels</th>
+                    `)
+//line app/vmalert/web.qtpl:574
+		if rule.Type == "alerting" {
+//line app/vmalert/web.qtpl:574
+			qw422016.N().S(`
+                    <th scope="col" class="text-center" title="The state alert would have after the evaluation">State</th>
+                    `)
+//line app/vmalert/web.qtpl:576
+		}
+//line app/vmalert/web.qtpl:576
+		qw422016.N().S(`
+                    <th scope="col" class="text-center">Value</th>
+                </tr>
+            </thead>
+            <tbody>
+            `)
+//line app/vmalert/web.qtpl:581
+		for _, se := range re.Series {
+//line app/vmalert/web.qtpl:581
+			qw422016.N().S(`
+                `)
+//line app/vmalert/web.qtpl:583
+			var labelKeys []string
+			for k := range se.Labels {
+				labelKeys = append(labelKeys, k)
+			}
+			sort.Strings(labelKeys)
+
+//line app/vmalert/web.qtpl:588
+			qw422016.N().S(`
+                <tr>
+                    <td>
+                        `)
+//line app/vmalert/web.qtpl:591
+			for _, k := range labelKeys {
+//line app/vmalert/web.qtpl:591
+				qw422016.N().S(`
+                            <span class="ms-1 badge bg-primary">`)
+//line app/vmalert/web.qtpl:592
+				qw422016.E().S(k)
+//line app/vmalert/web.qtpl:592
+				qw422016.N().S(`=`)
+//line app/vmalert/web.qtpl:592
+				qw422016.E().S(se.Labels[k])
+//line app/vmalert/web.qtpl:592
+				qw422016.N().S(`</span>
+                        `)
+//line app/vmalert/web.qtpl:593
+			}
+//line app/vmalert/web.qtpl:593
+			qw422016.N().S(`
+                    </td>
+                    `)
+//line app/vmalert/web.qtpl:595
+			if rule.Type == "alerting" {
+//line app/vmalert/web.qtpl:595
+				qw422016.N().S(`
+                    <td class="text-center">`)
+//line app/vmalert/web.qtpl:596
+				streambadgeState(qw422016, se.State)
+//line app/vmalert/web.qtpl:596
+				qw422016.N().S(`</td>
+                    `)
+//line app/vmalert/web.qtpl:597
+			}
+//line app/vmalert/web.qtpl:597
+			qw422016.N().S(`
+                    <td class="text-center">`)
+//line app/vmalert/web.qtpl:598
+			qw422016.E().V(se.Value)
+//line app/vmalert/web.qtpl:598
+			qw422016.N().S(`</td>
+                </tr>
+            `)
+//line app/vmalert/web.qtpl:600
+		}
+//line app/vmalert/web.qtpl:600
+		qw422016.N().S(`
+            </tbody>
+        </table>
+    `)
+//line app/vmalert/web.qtpl:603
+	} else {
+//line app/vmalert/web.qtpl:603
+		qw422016.N().S(`
+        <div>
+            <p>No results...</p>
+        </div>
+    `)
+//line app/vmalert/web.qtpl:607
+	}
+//line app/vmalert/web.qtpl:607
+	qw422016.N().S(`
+
+    `)
+//line app/vmalert/web.qtpl:609
+	tpl.StreamFooter(qw422016, r)
+//line app/vmalert/web.qtpl:609
+	qw422016.N().S(`
+`)
+//line app/vmalert/web.qtpl:610
+}
+
+//line app/vmalert/web.qtpl:610
+func WriteRuleEval(qq422016 qtio422016.Writer, r *http.Request, re *APIRuleEval) {
+//line app/vmalert/web.qtpl:610
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmalert/web.qtpl:610
+	StreamRuleEval(qw422016, r, re)
+//line app/vmalert/web.qtpl:610
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmalert/web.qtpl:610
+}
+
+//line app/vmalert/web.qtpl:610
+func RuleEval(r *http.Request, re *APIRuleEval) string {
+//line app/vmalert/web.qtpl:610
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmalert/web.qtpl:610
+	WriteRuleEval(qb422016, r, re)
+//line app/vmalert/web.qtpl:610
+	qs422016 := string(qb422016.B)
+//line app/vmalert/web.qtpl:610
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmalert/web.qtpl:610
+	return qs422016
+//line app/vmalert/web.qtpl:610
+}
+
+//line app/vmalert/web.qtpl:612
 func streambadgeState(qw422016 *qt422016.Writer, state string) {
-//line app/vmalert/web.qtpl:493
+//line app/vmalert/web.qtpl:612
 	qw422016.N().S(`
 `)
-//line app/vmalert/web.qtpl:495
+//line app/vmalert/web.qtpl:614
 	badgeClass := "bg-warning text-dark"
 	if state == "firing" {
 		badgeClass = "bg-danger"
 	}
 
-//line app/vmalert/web.qtpl:499
+//line app/vmalert/web.qtpl:618
 	qw422016.N().S(`
 <span class="badge `)
-//line app/vmalert/web.qtpl:500
+//line app/vmalert/web.qtpl:619
 	qw422016.E().S(badgeClass)
-//line app/vmalert/web.qtpl:500
+//line app/vmalert/web.qtpl:619
 	qw422016.N().S(`">`)
-//line app/vmalert/web.qtpl:500
+//line app/vmalert/web.qtpl:619
 	qw422016.E().S(state)
-//line app/vmalert/web.qtpl:500
+//line app/vmalert/web.qtpl:619
 	qw422016.N().S(`</span>
 `)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 }
 
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 func writebadgeState(qq422016 qtio422016.Writer, state string) {
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	streambadgeState(qw422016, state)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 }
 
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 func badgeState(state string) string {
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	writebadgeState(qb422016, state)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 	return qs422016
-//line app/vmalert/web.qtpl:501
+//line app/vmalert/web.qtpl:620
 }
 
-//line app/vmalert/web.qtpl:503
+//line app/vmalert/web.qtpl:622
 func streambadgeRestored(qw422016 *qt422016.Writer) {
-//line app/vmalert/web.qtpl:503
+//line app/vmalert/web.qtpl:622
 	qw422016.N().S(`
 <span class="badge bg-warning text-dark" title="Alert state was restored after the service restart from remote storage">restored</span>
 `)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 }
 
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 func writebadgeRestored(qq422016 qtio422016.Writer) {
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	streambadgeRestored(qw422016)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 }
 
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 func badgeRestored() string {
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	writebadgeRestored(qb422016)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	qs422016 := string(qb422016.B)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 	return qs422016
-//line app/vmalert/web.qtpl:505
+//line app/vmalert/web.qtpl:624
 }
@@ -68,6 +68,8 @@ type APIGroup struct {
 	File string `json:"file"`
 	// Concurrency shows how many rules may be evaluated simultaneously
 	Concurrency int `json:"concurrency"`
+	// EvalOffset is the Group's evaluation offset within the interval in float seconds
+	EvalOffset float64 `json:"eval_offset,omitempty"`
 	// Params contains HTTP URL parameters added to each Rule's request
 	Params []string `json:"params,omitempty"`
 	// Headers contains HTTP headers added to each Rule's request
@@ -134,3 +136,76 @@ func (ar APIRule) WebLink() string {
 	return fmt.Sprintf("rule?%s=%s&%s=%s",
 		paramGroupID, ar.GroupID, paramRuleID, ar.ID)
 }
+
+// EvalLink returns a link for instant evaluation of the rule which can be used in UI.
+func (ar APIRule) EvalLink() string {
+	return fmt.Sprintf("rule_eval?%s=%s&%s=%s",
+		paramGroupID, ar.GroupID, paramRuleID, ar.ID)
+}
+
+// APIRulePreview represents the result of alerting rule evaluation
+// over the given time range via /api/v1/rules/test
+type APIRulePreview struct {
+	// Name is the alert name of the previewed rule
+	Name string `json:"name"`
+	// Expression contains the PromQL/MetricsQL expression of the previewed rule
+	Expression string `json:"expression"`
+	// For is the rule's `for` duration in float seconds
+	For float64 `json:"for"`
+	// Start and End define the evaluated time range
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	// Step is the evaluation interval in float seconds
+	Step float64 `json:"step"`
+	// FiringsCount is the total number of times alerts would have fired
+	FiringsCount int `json:"firings_count"`
+	// Alerts contains alerts, which would have fired on the evaluated time range
+	Alerts []APIAlertPreview `json:"alerts"`
+}
+
+// APIAlertPreview represents a single alert from APIRulePreview
+type APIAlertPreview struct {
+	// Labels contains alert labels
+	Labels map[string]string `json:"labels"`
+	// FiringsCount is the number of times the alert would have fired
+	FiringsCount int `json:"firings_count"`
+	// FiringDuration is the total duration in float seconds the alert would have been firing
+	FiringDuration float64 `json:"firing_duration"`
+	// Firings contains time intervals when the alert would have been firing
+	Firings []APIFiringInterval `json:"firings"`
+}
+
+// APIFiringInterval represents the time interval when the alert would have been firing
+type APIFiringInterval struct {
+	// Start is the timestamp of the first evaluation with firing state
+	Start time.Time `json:"start"`
+	// End is the timestamp of the last evaluation with firing state
+	End time.Time `json:"end"`
+}
+
+// APIRuleEval represents the result of instant evaluation
+// of the loaded rule via /vmalert/rule_eval
+type APIRuleEval struct {
+	// Rule is the evaluated rule
+	Rule APIRule `json:"rule"`
+	// EvaluatedAt is the timestamp the rule was evaluated at
+	EvaluatedAt time.Time `json:"evaluated_at"`
+	// Duration is the time taken by the query in float seconds
+	Duration float64 `json:"duration"`
+	// Error contains the error faced during the evaluation
+	Error string `json:"error,omitempty"`
+	// Series contains alerts or time series, which would have been
+	// produced by the rule at EvaluatedAt
+	Series []APISeriesEval `json:"series"`
+}
+
+// APISeriesEval represents a single alert or time series from APIRuleEval
+type APISeriesEval struct {
+	// Labels contains the resulting labels
+	Labels map[string]string `json:"labels"`
+	// Value is the value returned by the rule's query
+	Value float64 `json:"value"`
+	// State is the state the alert would have after the evaluation.
+	// It is empty for recording rules
+	State string `json:"state,omitempty"`
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	accessLogOutput = flag.String("accessLog.output", "", "Where to write access logs for proxied requests. Possible values: stdout or path to file. "+
+		"Access logs are disabled by default. See https://docs.victoriametrics.com/vmauth.html#access-logs")
+	accessLogFields = flagutil.NewArrayString("accessLog.fields", "Comma-separated list of fields to write to access logs. "+
+		"Supported fields: ts, user, tenant, remote_addr, method, path, backend, status, duration, bytes. All the fields are written by default. "+
+		"See https://docs.victoriametrics.com/vmauth.html#access-logs")
+	accessLogSampleRate = flag.Float64("accessLog.sampleRate", 1, "The share of successful requests to write to access logs in the range (0..1]. "+
+		"Requests with 4xx and 5xx response status codes are always logged. See https://docs.victoriametrics.com/vmauth.html#access-logs")
+	accessLogErrorsOnly = flag.Bool("accessLog.errorsOnly", false, "Whether to write only requests with 4xx and 5xx response status codes to access logs. "+
+		"See https://docs.victoriametrics.com/vmauth.html#access-logs")
+	accessLogMaxFileSize = flagutil.NewBytes("accessLog.maxFileSize", 100*1024*1024, "The maximum size of -accessLog.output file before it is rotated")
+	accessLogMaxFiles    = flag.Int("accessLog.maxFiles", 5, "The maximum number of rotated -accessLog.output files to keep")
+)
+
+// accessLogAllFields contains all the supported access log fields in the order they are written.
+var accessLogAllFields = []string{"ts", "user", "tenant", "remote_addr", "method", "path", "backend", "status", "duration", "bytes"}
+
+var (
+	accessLogLock   sync.Mutex
+	accessLogWriter io.Writer
+	accessLogFile   *rotatingFile
+
+	// accessLogFieldsEnabled contains the fields, which must be written to access logs.
+	accessLogFieldsEnabled map[string]bool
+
+	accessLogWriteErrors = metrics.NewCounter(`vmauth_access_log_write_errors_total`)
+	accessLogEntries     = metrics.NewCounter(`vmauth_access_log_entries_total`)
+)
+
+func initAccessLog() {
+	if *accessLogOutput == "" {
+		return
+	}
+	if *accessLogSampleRate <= 0 || *accessLogSampleRate > 1 {
+		logger.Fatalf("-accessLog.sampleRate must be in the range (0..1]; got %v", *accessLogSampleRate)
+	}
+	fields, err := parseAccessLogFields(*accessLogFields)
+	if err != nil {
+		logger.Fatalf("cannot parse -accessLog.fields: %s", err)
+	}
+
+	accessLogLock.Lock()
+	defer accessLogLock.Unlock()
+
+	accessLogFieldsEnabled = fields
+	if *accessLogOutput == "stdout" {
+		accessLogWriter = os.Stdout
+		return
+	}
+	rf, err := openRotatingFile(*accessLogOutput, accessLogMaxFileSize.N, *accessLogMaxFiles)
+	if err != nil {
+		logger.Fatalf("cannot open -accessLog.output=%q: %s", *accessLogOutput, err)
+	}
+	accessLogFile = rf
+	accessLogWriter = rf
+}
+
+func stopAccessLog() {
+	accessLogLock.Lock()
+	defer accessLogLock.Unlock()
+
+	if accessLogFile != nil {
+		if err := accessLogFile.Close(); err != nil {
+			logger.Errorf("cannot close -accessLog.output=%q: %s", *accessLogOutput, err)
+		}
+		accessLogFile = nil
+	}
+	accessLogWriter = nil
+}
+
+func isAccessLogEnabled() bool {
+	accessLogLock.Lock()
+	defer accessLogLock.Unlock()
+	return accessLogWriter != nil
+}
+
+// parseAccessLogFields returns the set of fields from a.
+//
+// All the supported fields are returned if a is empty.
+func parseAccessLogFields(a []string) (map[string]bool, error) {
+	m := make(map[string]bool)
+	if len(a) == 0 {
+		a = accessLogAllFields
+	}
+	for _, field := range a {
+		field = strings.TrimSpace(field)
+		if !isSupportedAccessLogField(field) {
+			return nil, fmt.Errorf("unsupported field %q; supported fields: %s", field, strings.Join(accessLogAllFields, ", "))
+		}
+		m[field] = true
+	}
+	return m, nil
+}
+
+func isSupportedAccessLogField(field string) bool {
+	for _, f := range accessLogAllFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldWriteAccessLog returns true if the request with the given statusCode must be written to access logs.
+//
+// Requests with 4xx and 5xx status codes are always written. Other requests are written with the given sampleRate
+// unless errorsOnly is set.
+func shouldWriteAccessLog(statusCode int, sampleRate float64, errorsOnly bool, rnd float64) bool {
+	if statusCode >= 400 {
+		return true
+	}
+	if errorsOnly {
+		return false
+	}
+	return rnd < sampleRate
+}
+
+// accessLogResponseWriter tracks response status code, response size and backend for the proxied request.
+type accessLogResponseWriter struct {
+	http.ResponseWriter
+
+	statusCode int
+	bytes      int64
+	backend    string
+}
+
+func (w *accessLogResponseWriter) WriteHeader(statusCode int) {
+	if w.statusCode == 0 {
+		w.statusCode = statusCode
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *accessLogResponseWriter) Write(p []byte) (int, error) {
+	if w.statusCode == 0 {
+		w.statusCode = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher, so streaming responses are sent to the client without delays.
+func (w *accessLogResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// setAccessLogBackend registers targetURL as the backend for the request written to w.
+func setAccessLogBackend(w http.ResponseWriter, targetURL *url.URL) {
+	if alw, ok := w.(*accessLogResponseWriter); ok {
+		alw.backend = targetURL.Redacted()
+	}
+}
+
+// accessLogEntry is a single access log entry.
+type accessLogEntry struct {
+	Timestamp  string  `json:"ts,omitempty"`
+	User       string  `json:"user,omitempty"`
+	Tenant     string  `json:"tenant,omitempty"`
+	RemoteAddr string  `json:"remote_addr,omitempty"`
+	Method     string  `json:"method,omitempty"`
+	Path       string  `json:"path,omitempty"`
+	Backend    string  `json:"backend,omitempty"`
+	Status     int     `json:"status,omitempty"`
+	Duration   float64 `json:"duration,omitempty"`
+	Bytes      int64   `json:"bytes,omitempty"`
+}
+
+func newAccessLogEntry(r *http.Request, ui *UserInfo, w *accessLogResponseWriter, startTime, currentTime time.Time, fields map[string]bool) *accessLogEntry {
+	var e accessLogEntry
+	if fields["ts"] {
+		e.Timestamp = startTime.UTC().Format(time.RFC3339Nano)
+	}
+	if fields["user"] {
+		e.User = ui.name()
+		if t := ui.apiToken; t != nil {
+			e.User = t.ID
+		}
+	}
+	if fields["tenant"] && ui.apiToken != nil {
+		e.Tenant = ui.apiToken.Tenant
+	}
+	if fields["remote_addr"] {
+		e.RemoteAddr = httpserver.GetQuotedRemoteAddr(r)
+	}
+	if fields["method"] {
+		e.Method = r.Method
+	}
+	if fields["path"] {
+		e.Path = r.URL.Path
+	}
+	if fields["backend"] {
+		e.Backend = w.backend
+	}
+	if fields["status"] {
+		e.Status = w.statusCode
+		if e.Status == 0 {
+			e.Status = http.StatusOK
+		}
+	}
+	if fields["duration"] {
+		e.Duration = currentTime.Sub(startTime).Seconds()
+	}
+	if fields["bytes"] {
+		e.Bytes = w.bytes
+	}
+	return &e
+}
+
+// processRequestWithAccessLog calls processRequest and writes access log entry for the request
+// according to -accessLog.* command-line flags.
+func processRequestWithAccessLog(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
+	if !isAccessLogEnabled() {
+		processRequest(w, r, ui)
+		return
+	}
+	startTime := time.Now()
+	alw := &accessLogResponseWriter{
+		ResponseWriter: w,
+	}
+	processRequest(alw, r, ui)
+
+	statusCode := alw.statusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	if !shouldWriteAccessLog(statusCode, *accessLogSampleRate, *accessLogErrorsOnly, rand.Float64()) {
+		return
+	}
+
+	accessLogLock.Lock()
+	defer accessLogLock.Unlock()
+
+	if accessLogWriter == nil {
+		return
+	}
+	e := newAccessLogEntry(r, ui, alw, startTime, time.Now(), accessLogFieldsEnabled)
+	data, err := json.Marshal(e)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal access log entry: %s", err)
+	}
+	data = append(data, '\n')
+	if _, err := accessLogWriter.Write(data); err != nil {
+		accessLogWriteErrors.Inc()
+		return
+	}
+	accessLogEntries.Inc()
+}
+
+// rotatingFile is a file, which is rotated when its size exceeds maxSize.
+//
+// Rotated files are renamed to path.1, path.2, ..., path.maxFiles. The oldest file is removed.
+type rotatingFile struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, maxFiles int) (*rotatingFile, error) {
+	rf := &rotatingFile{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot stat %q: %w", rf.path, err)
+	}
+	rf.f = f
+	rf.size = fi.Size()
+	return nil
+}
+
+// Write writes p to rf and rotates rf if its size exceeds rf.maxSize.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			logger.Errorf("cannot rotate %q: %s", rf.path, err)
+		}
+	}
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) rotate() error {
+	if err := rf.f.Close(); err != nil {
+		return fmt.Errorf("cannot close %q: %w", rf.path, err)
+	}
+	rf.f = nil
+	if rf.maxFiles <= 0 {
+		if err := os.Remove(rf.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return rf.open()
+	}
+	for i := rf.maxFiles - 1; i > 0; i-- {
+		src := fmt.Sprintf("%s.%d", rf.path, i)
+		dst := fmt.Sprintf("%s.%d", rf.path, i+1)
+		if err := os.Rename(src, dst); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil {
+		return err
+	}
+	return rf.open()
+}
+
+// Close closes rf.
+func (rf *rotatingFile) Close() error {
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseAccessLogFields(t *testing.T) {
+	f := func(a []string, expected []string) {
+		t.Helper()
+		fields, err := parseAccessLogFields(a)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(fields) != len(expected) {
+			t.Fatalf("unexpected number of fields; got %d; want %d", len(fields), len(expected))
+		}
+		for _, field := range expected {
+			if !fields[field] {
+				t.Fatalf("missing field %q", field)
+			}
+		}
+	}
+	f(nil, accessLogAllFields)
+	f([]string{"user", " status"}, []string{"user", "status"})
+
+	if _, err := parseAccessLogFields([]string{"user", "foo"}); err == nil {
+		t.Fatalf("expecting non-nil error for unsupported field")
+	}
+}
+
+func TestShouldWriteAccessLog(t *testing.T) {
+	f := func(statusCode int, sampleRate float64, errorsOnly bool, rnd float64, expected bool) {
+		t.Helper()
+		result := shouldWriteAccessLog(statusCode, sampleRate, errorsOnly, rnd)
+		if result != expected {
+			t.Fatalf("unexpected result for statusCode=%d, sampleRate=%v, errorsOnly=%v, rnd=%v; got %v; want %v",
+				statusCode, sampleRate, errorsOnly, rnd, result, expected)
+		}
+	}
+	f(200, 1, false, 0.99, true)
+	f(200, 0.1, false, 0.05, true)
+	f(200, 0.1, false, 0.5, false)
+	f(200, 1, true, 0, false)
+
+	// errors are always logged
+	f(404, 0.1, false, 0.5, true)
+	f(503, 0.1, true, 0.5, true)
+}
+
+func TestNewAccessLogEntry(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "http://vmauth:8427/api/v1/query?query=up", nil)
+	startTime := time.Unix(1700000000, 0)
+	w := &accessLogResponseWriter{
+		ResponseWriter: httptest.NewRecorder(),
+	}
+	w.backend = "http://vmselect:8481/select/0/prometheus/api/v1/query"
+	if _, err := w.Write([]byte("foobar")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ui := &UserInfo{
+		Username: "foo",
+		apiToken: &APIToken{
+			ID:     "token-id",
+			Tenant: "42",
+		},
+	}
+	fields, err := parseAccessLogFields(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	e := newAccessLogEntry(r, ui, w, startTime, startTime.Add(1500*time.Millisecond), fields)
+	expected := accessLogEntry{
+		Timestamp:  "2023-11-14T22:13:20Z",
+		User:       "token-id",
+		Tenant:     "42",
+		RemoteAddr: `"192.0.2.1:1234"`,
+		Method:     "GET",
+		Path:       "/api/v1/query",
+		Backend:    "http://vmselect:8481/select/0/prometheus/api/v1/query",
+		Status:     200,
+		Duration:   1.5,
+		Bytes:      6,
+	}
+	if *e != expected {
+		t.Fatalf("unexpected entry\ngot\n%+v\nwant\n%+v", *e, expected)
+	}
+
+	// only the selected fields must be set
+	fields, err = parseAccessLogFields([]string{"user", "status"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ui.apiToken = nil
+	e = newAccessLogEntry(r, ui, w, startTime, startTime, fields)
+	expected = accessLogEntry{
+		User:   "foo",
+		Status: 200,
+	}
+	if *e != expected {
+		t.Fatalf("unexpected entry\ngot\n%+v\nwant\n%+v", *e, expected)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("cannot open rotating file: %s", err)
+	}
+	for _, s := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+		if _, err := rf.Write([]byte(s)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	if err := rf.Close(); err != nil {
+		t.Fatalf("cannot close rotating file: %s", err)
+	}
+
+	f := func(path, expected string) {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("cannot read %q: %s", path, err)
+		}
+		if string(data) != expected {
+			t.Fatalf("unexpected contents of %q; got %q; want %q", path, data, expected)
+		}
+	}
+	f(path, "dddddd\n")
+	f(path+".1", "cccccc\n")
+	f(path+".2", "bbbbbb\n")
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Fatalf("expecting the oldest rotated file to be removed; got %v", err)
+	}
+}
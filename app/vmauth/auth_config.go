@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"flag"
 	"fmt"
+	"net/http"
 	"net/url"
 	"os"
 	"regexp"
@@ -12,6 +13,7 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
@@ -29,23 +31,36 @@ var (
 // AuthConfig represents auth config.
 type AuthConfig struct {
 	Users []UserInfo `yaml:"users,omitempty"`
+
+	// Tokens contains tenant-scoped API tokens issued via -issueToken.
+	//
+	// See https://docs.victoriametrics.com/vmauth.html#api-tokens
+	Tokens *TokensConfig `yaml:"tokens,omitempty"`
 }
 
 // UserInfo is user information read from authConfigPath
 type UserInfo struct {
-	Name                  string     `yaml:"name,omitempty"`
-	BearerToken           string     `yaml:"bearer_token,omitempty"`
-	Username              string     `yaml:"username,omitempty"`
-	Password              string     `yaml:"password,omitempty"`
-	URLPrefix             *URLPrefix `yaml:"url_prefix,omitempty"`
-	URLMaps               []URLMap   `yaml:"url_map,omitempty"`
-	Headers               []Header   `yaml:"headers,omitempty"`
-	MaxConcurrentRequests int        `yaml:"max_concurrent_requests,omitempty"`
+	Name                  string      `yaml:"name,omitempty"`
+	BearerToken           string      `yaml:"bearer_token,omitempty"`
+	Username              string      `yaml:"username,omitempty"`
+	Password              string      `yaml:"password,omitempty"`
+	URLPrefix             *URLPrefix  `yaml:"url_prefix,omitempty"`
+	URLMaps               []URLMap    `yaml:"url_map,omitempty"`
+	HeadersConf           HeadersConf `yaml:",inline"`
+	MaxConcurrentRequests int         `yaml:"max_concurrent_requests,omitempty"`
+
+	// Retry contains optional retry and hedging policy for idempotent requests proxied to url_prefix.
+	//
+	// It is also used for `url_map` entries without their own `retry` section.
+	Retry *RetryConfig `yaml:"retry,omitempty"`
 
 	concurrencyLimitCh      chan struct{}
 	concurrencyLimitReached *metrics.Counter
 
 	requests *metrics.Counter
+
+	// apiToken is set if the user is created from API token at `tokens` section.
+	apiToken *APIToken
 }
 
 func (ui *UserInfo) beginConcurrencyLimit() error {
@@ -70,38 +85,180 @@ func (ui *UserInfo) getMaxConcurrentRequests() int {
 	return mcr
 }
 
-// Header is `Name: Value` http header, which must be added to the proxied request.
+// HeadersConf represents config for request and response headers.
+type HeadersConf struct {
+	RequestHeaders  []Header `yaml:"headers,omitempty"`
+	ResponseHeaders []Header `yaml:"response_headers,omitempty"`
+}
+
+// Supported actions for Header.
+const (
+	headerActionSet    = "set"
+	headerActionAdd    = "add"
+	headerActionRemove = "remove"
+)
+
+// Header is http header manipulation rule, which must be applied to the proxied request or response.
+//
+// It may be set either as `Name: Value` string, which sets the header to the given value,
+// or as `{name: Name, value: Value, action: set|add|remove}` object.
+// Empty Action is equivalent to "set".
 type Header struct {
-	Name  string
-	Value string
+	Name   string
+	Value  string
+	Action string
 }
 
 // UnmarshalYAML unmarshals h from f.
 func (h *Header) UnmarshalYAML(f func(interface{}) error) error {
 	var s string
-	if err := f(&s); err != nil {
-		return err
+	if err := f(&s); err == nil {
+		n := strings.IndexByte(s, ':')
+		if n < 0 {
+			return fmt.Errorf("missing speparator char ':' between Name and Value in the header %q; expected format - 'Name: Value'", s)
+		}
+		h.Name = strings.TrimSpace(s[:n])
+		h.Value = strings.TrimSpace(s[n+1:])
+		return nil
 	}
-	n := strings.IndexByte(s, ':')
-	if n < 0 {
-		return fmt.Errorf("missing speparator char ':' between Name and Value in the header %q; expected format - 'Name: Value'", s)
+	var hm struct {
+		Name   string `yaml:"name"`
+		Value  string `yaml:"value,omitempty"`
+		Action string `yaml:"action,omitempty"`
+	}
+	if err := f(&hm); err != nil {
+		return fmt.Errorf("cannot unmarshal header; expected either 'Name: Value' string or {name, value, action} object: %w", err)
+	}
+	h.Name = strings.TrimSpace(hm.Name)
+	h.Value = hm.Value
+	h.Action = hm.Action
+	if h.Name == "" {
+		return fmt.Errorf("missing header name")
+	}
+	switch h.Action {
+	case "", headerActionSet, headerActionAdd:
+	case headerActionRemove:
+		if h.Value != "" {
+			return fmt.Errorf("unexpected value %q for header %q with action %q", h.Value, h.Name, h.Action)
+		}
+	default:
+		return fmt.Errorf("unsupported action %q for header %q; supported actions: %q, %q, %q",
+			h.Action, h.Name, headerActionSet, headerActionAdd, headerActionRemove)
 	}
-	h.Name = strings.TrimSpace(s[:n])
-	h.Value = strings.TrimSpace(s[n+1:])
 	return nil
 }
 
 // MarshalYAML marshals h to yaml.
 func (h *Header) MarshalYAML() (interface{}, error) {
-	s := fmt.Sprintf("%s: %s", h.Name, h.Value)
-	return s, nil
+	if h.Action == "" || h.Action == headerActionSet {
+		s := fmt.Sprintf("%s: %s", h.Name, h.Value)
+		return s, nil
+	}
+	hm := map[string]string{
+		"name":   h.Name,
+		"action": h.Action,
+	}
+	if h.Value != "" {
+		hm["value"] = h.Value
+	}
+	return hm, nil
+}
+
+// applyHeaders applies the given headers manipulation rules to dst.
+func applyHeaders(dst http.Header, headers []Header) {
+	for _, h := range headers {
+		switch h.Action {
+		case headerActionAdd:
+			dst.Add(h.Name, h.Value)
+		case headerActionRemove:
+			dst.Del(h.Name)
+		default:
+			dst.Set(h.Name, h.Value)
+		}
+	}
 }
 
 // URLMap is a mapping from source paths to target urls.
 type URLMap struct {
-	SrcPaths  []*SrcPath `yaml:"src_paths,omitempty"`
-	URLPrefix *URLPrefix `yaml:"url_prefix,omitempty"`
-	Headers   []Header   `yaml:"headers,omitempty"`
+	SrcPaths    []*SrcPath  `yaml:"src_paths,omitempty"`
+	URLPrefix   *URLPrefix  `yaml:"url_prefix,omitempty"`
+	HeadersConf HeadersConf `yaml:",inline"`
+
+	// LegacyAPI is the name of the shim for translating legacy read API requests into Prometheus querying API requests.
+	LegacyAPI string `yaml:"legacy_api,omitempty"`
+
+	// Rewrite contains optional rules for rewriting request path and query args before proxying the request to url_prefix.
+	Rewrite *RewriteConfig `yaml:"rewrite,omitempty"`
+
+	// Retry contains optional retry and hedging policy for idempotent requests proxied to url_prefix.
+	Retry *RetryConfig `yaml:"retry,omitempty"`
+
+	legacyAPIShim legacyAPIShim
+}
+
+// RewriteConfig contains rules for rewriting request urls at `url_map`.
+//
+// See https://docs.victoriametrics.com/vmauth.html#url-rewriting
+type RewriteConfig struct {
+	// Path is an optional regexp for the request path. It must match the whole path.
+	Path string `yaml:"path,omitempty"`
+
+	// PathReplacement is the replacement for the path matching Path. It may refer capture groups from Path via $N.
+	PathReplacement string `yaml:"path_replacement,omitempty"`
+
+	// DropQueryArgs contains query arg names to remove from the request.
+	DropQueryArgs []string `yaml:"drop_query_args,omitempty"`
+
+	// AddQueryArgs contains `name=value` query args to add to the request.
+	AddQueryArgs []string `yaml:"add_query_args,omitempty"`
+
+	pathRe    *regexp.Regexp
+	addedArgs url.Values
+}
+
+func (rc *RewriteConfig) init() error {
+	if rc.Path == "" && rc.PathReplacement != "" {
+		return fmt.Errorf("missing `path` for `path_replacement: %q` in `rewrite`", rc.PathReplacement)
+	}
+	if rc.Path != "" {
+		re, err := regexp.Compile("^(?:" + rc.Path + ")$")
+		if err != nil {
+			return fmt.Errorf("cannot build regexp from `path: %q` in `rewrite`: %w", rc.Path, err)
+		}
+		rc.pathRe = re
+	}
+	rc.addedArgs = make(url.Values, len(rc.AddQueryArgs))
+	for _, s := range rc.AddQueryArgs {
+		n := strings.IndexByte(s, '=')
+		if n <= 0 {
+			return fmt.Errorf("cannot parse `add_query_args` entry %q in `rewrite`; it must have the form `name=value`", s)
+		}
+		rc.addedArgs.Add(s[:n], s[n+1:])
+	}
+	return nil
+}
+
+// apply returns a copy of u rewritten according to rc.
+func (rc *RewriteConfig) apply(u *url.URL) *url.URL {
+	uCopy := *u
+	if rc.pathRe != nil && rc.pathRe.MatchString(uCopy.Path) {
+		uCopy.Path = rc.pathRe.ReplaceAllString(uCopy.Path, rc.PathReplacement)
+		uCopy.RawPath = ""
+	}
+	if len(rc.DropQueryArgs) == 0 && len(rc.addedArgs) == 0 {
+		return &uCopy
+	}
+	args := uCopy.Query()
+	for _, name := range rc.DropQueryArgs {
+		args.Del(name)
+	}
+	for name, values := range rc.addedArgs {
+		for _, v := range values {
+			args.Add(name, v)
+		}
+	}
+	uCopy.RawQuery = args.Encode()
+	return &uCopy
 }
 
 // SrcPath represents an src path
@@ -114,6 +271,9 @@ type SrcPath struct {
 type URLPrefix struct {
 	n   uint32
 	bus []*backendURL
+
+	// retry is the retry policy for requests proxied to bus. It is nil if retries and hedging are disabled.
+	retry *RetryConfig
 }
 
 type backendURL struct {
@@ -317,6 +477,9 @@ func authConfigReloader(sighupCh <-chan os.Signal) {
 				continue
 			}
 			authConfig.Store(m)
+			ae := auditlog.NewEvent(nil, "config_applied")
+			ae.Target = "-auth.config"
+			auditlog.Log(ae, nil)
 			logger.Infof("Successfully reloaded -auth.config=%q", *authConfigPath)
 		}
 	}
@@ -350,7 +513,7 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 		return nil, fmt.Errorf("cannot unmarshal AuthConfig data: %w", err)
 	}
 	uis := ac.Users
-	if len(uis) == 0 {
+	if len(uis) == 0 && ac.Tokens == nil {
 		return nil, fmt.Errorf("`users` section cannot be empty in AuthConfig")
 	}
 	byAuthToken := make(map[string]*UserInfo, len(uis))
@@ -369,12 +532,19 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 		if byAuthToken[at2] != nil {
 			return nil, fmt.Errorf("duplicate auth token found for bearer_token=%q, username=%q: %q", ui.BearerToken, ui.Username, at2)
 		}
+		if ui.Retry != nil {
+			if err := ui.Retry.init(); err != nil {
+				return nil, fmt.Errorf("cannot parse `retry` section: %w", err)
+			}
+		}
 		if ui.URLPrefix != nil {
 			if err := ui.URLPrefix.sanitize(); err != nil {
 				return nil, err
 			}
+			ui.URLPrefix.retry = ui.Retry
 		}
-		for _, e := range ui.URLMaps {
+		for j := range ui.URLMaps {
+			e := &ui.URLMaps[j]
 			if len(e.SrcPaths) == 0 {
 				return nil, fmt.Errorf("missing `src_paths` in `url_map`")
 			}
@@ -384,6 +554,31 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 			if err := e.URLPrefix.sanitize(); err != nil {
 				return nil, err
 			}
+			if e.LegacyAPI != "" {
+				shim, err := getLegacyAPIShim(e.LegacyAPI)
+				if err != nil {
+					return nil, err
+				}
+				e.legacyAPIShim = shim
+			}
+			if e.Rewrite != nil {
+				if e.LegacyAPI != "" {
+					return nil, fmt.Errorf("`rewrite` cannot be used together with `legacy_api` in `url_map`")
+				}
+				if err := e.Rewrite.init(); err != nil {
+					return nil, err
+				}
+			}
+			e.URLPrefix.retry = ui.Retry
+			if e.Retry != nil {
+				if e.LegacyAPI != "" {
+					return nil, fmt.Errorf("`retry` cannot be used together with `legacy_api` in `url_map`")
+				}
+				if err := e.Retry.init(); err != nil {
+					return nil, fmt.Errorf("cannot parse `retry` section in `url_map`: %w", err)
+				}
+				e.URLPrefix.retry = e.Retry
+			}
 		}
 		if len(ui.URLMaps) == 0 && ui.URLPrefix == nil {
 			return nil, fmt.Errorf("missing `url_prefix`")
@@ -398,21 +593,30 @@ func parseAuthConfig(data []byte) (map[string]*UserInfo, error) {
 		if ui.Username != "" {
 			ui.requests = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_total{username=%q}`, name))
 		}
-		mcr := ui.getMaxConcurrentRequests()
-		ui.concurrencyLimitCh = make(chan struct{}, mcr)
-		ui.concurrencyLimitReached = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_concurrent_requests_limit_reached_total{username=%q}`, name))
-		_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmauth_user_concurrent_requests_capacity{username=%q}`, name), func() float64 {
-			return float64(cap(ui.concurrencyLimitCh))
-		})
-		_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmauth_user_concurrent_requests_current{username=%q}`, name), func() float64 {
-			return float64(len(ui.concurrencyLimitCh))
-		})
+		ui.initConcurrencyLimit(name)
 		byAuthToken[at1] = ui
 		byAuthToken[at2] = ui
 	}
+	if ac.Tokens != nil {
+		if err := ac.Tokens.addUsers(byAuthToken); err != nil {
+			return nil, fmt.Errorf("cannot parse `tokens` section: %w", err)
+		}
+	}
 	return byAuthToken, nil
 }
 
+func (ui *UserInfo) initConcurrencyLimit(name string) {
+	mcr := ui.getMaxConcurrentRequests()
+	ui.concurrencyLimitCh = make(chan struct{}, mcr)
+	ui.concurrencyLimitReached = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_concurrent_requests_limit_reached_total{username=%q}`, name))
+	_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmauth_user_concurrent_requests_capacity{username=%q}`, name), func() float64 {
+		return float64(cap(ui.concurrencyLimitCh))
+	})
+	_ = metrics.GetOrCreateGauge(fmt.Sprintf(`vmauth_user_concurrent_requests_current{username=%q}`, name), func() float64 {
+		return float64(len(ui.concurrencyLimitCh))
+	})
+}
+
 func (ui *UserInfo) name() string {
 	if ui.Name != "" {
 		return ui.Name
@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"net/http"
 	"net/url"
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -196,6 +198,132 @@ users:
     url_prefix: http://foobar
     headers:
       aaa: bbb
+`)
+	// Invalid header action
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  headers:
+  - name: X-Foo
+    value: bar
+    action: replace
+`)
+	// Missing header name
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  response_headers:
+  - value: bar
+    action: add
+`)
+	// Non-empty value for the removed header
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  headers:
+  - name: Authorization
+    value: bar
+    action: remove
+`)
+	// Unsupported legacy_api in url_map
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/render']
+    url_prefix: http://foobar
+    legacy_api: influx
+`)
+	// Invalid path regexp in rewrite
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/foo']
+    url_prefix: http://foobar
+    rewrite:
+      path: '[foo'
+      path_replacement: /bar
+`)
+	// Missing path for path_replacement in rewrite
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/foo']
+    url_prefix: http://foobar
+    rewrite:
+      path_replacement: /bar
+`)
+	// Invalid add_query_args in rewrite
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/foo']
+    url_prefix: http://foobar
+    rewrite:
+      add_query_args: ['foo']
+`)
+	// rewrite together with legacy_api
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/render']
+    url_prefix: http://foobar
+    legacy_api: graphite
+    rewrite:
+      drop_query_args: ['foo']
+`)
+	// Negative max_retries
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  retry:
+    max_retries: -1
+`)
+	// Non-5xx status code in retry_status_codes
+	f(`
+users:
+- username: a
+  url_prefix: http://foobar
+  retry:
+    max_retries: 2
+    retry_status_codes: [404]
+`)
+	// Invalid budget_ratio
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/foo']
+    url_prefix: http://foobar
+    retry:
+      budget_ratio: 1.5
+`)
+	// Non-positive hedge_after
+	f(`
+users:
+- username: a
+  url_prefix: [http://foo, http://bar]
+  retry:
+    hedge_after: 0s
+`)
+	// retry together with legacy_api
+	f(`
+users:
+- username: a
+  url_map:
+  - src_paths: ['/render']
+    url_prefix: http://foobar
+    legacy_api: graphite
+    retry:
+      max_retries: 1
 `)
 }
 
@@ -291,14 +419,16 @@ users:
 						"http://vminsert1/insert/0/prometheus",
 						"http://vminsert2/insert/0/prometheus",
 					}),
-					Headers: []Header{
-						{
-							Name:  "foo",
-							Value: "bar",
-						},
-						{
-							Name:  "xxx",
-							Value: "y",
+					HeadersConf: HeadersConf{
+						RequestHeaders: []Header{
+							{
+								Name:  "foo",
+								Value: "bar",
+							},
+							{
+								Name:  "xxx",
+								Value: "y",
+							},
 						},
 					},
 				},
@@ -317,14 +447,16 @@ users:
 						"http://vminsert1/insert/0/prometheus",
 						"http://vminsert2/insert/0/prometheus",
 					}),
-					Headers: []Header{
-						{
-							Name:  "foo",
-							Value: "bar",
-						},
-						{
-							Name:  "xxx",
-							Value: "y",
+					HeadersConf: HeadersConf{
+						RequestHeaders: []Header{
+							{
+								Name:  "foo",
+								Value: "bar",
+							},
+							{
+								Name:  "xxx",
+								Value: "y",
+							},
 						},
 					},
 				},
@@ -353,6 +485,110 @@ users:
 		},
 	})
 
+	// Request and response headers manipulation rules
+	f(`
+users:
+- username: foo
+  url_prefix: http://foo
+  headers:
+  - "X-Scope-OrgID: abc"
+  - name: Authorization
+    action: remove
+  response_headers:
+  - name: X-Served-By
+    value: vmauth
+    action: add
+  url_map:
+  - src_paths: ["/api/v1/write"]
+    url_prefix: http://bar
+    response_headers:
+    - name: Server
+      action: remove
+`, map[string]*UserInfo{
+		getAuthToken("", "foo", ""): {
+			Username:  "foo",
+			URLPrefix: mustParseURL("http://foo"),
+			HeadersConf: HeadersConf{
+				RequestHeaders: []Header{
+					{
+						Name:  "X-Scope-OrgID",
+						Value: "abc",
+					},
+					{
+						Name:   "Authorization",
+						Action: "remove",
+					},
+				},
+				ResponseHeaders: []Header{
+					{
+						Name:   "X-Served-By",
+						Value:  "vmauth",
+						Action: "add",
+					},
+				},
+			},
+			URLMaps: []URLMap{
+				{
+					SrcPaths:  getSrcPaths([]string{"/api/v1/write"}),
+					URLPrefix: mustParseURL("http://bar"),
+					HeadersConf: HeadersConf{
+						ResponseHeaders: []Header{
+							{
+								Name:   "Server",
+								Action: "remove",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestApplyHeaders(t *testing.T) {
+	f := func(headers []Header, expectedHeader http.Header) {
+		t.Helper()
+		h := http.Header{
+			"Authorization": []string{"Bearer foo"},
+			"X-Foo":         []string{"bar"},
+		}
+		applyHeaders(h, headers)
+		if !reflect.DeepEqual(h, expectedHeader) {
+			t.Fatalf("unexpected headers; got %q; want %q", h, expectedHeader)
+		}
+	}
+	f(nil, http.Header{
+		"Authorization": []string{"Bearer foo"},
+		"X-Foo":         []string{"bar"},
+	})
+	f([]Header{
+		{
+			Name:  "x-foo",
+			Value: "baz",
+		},
+		{
+			Name:   "X-Scope-OrgID",
+			Value:  "abc",
+			Action: "set",
+		},
+	}, http.Header{
+		"Authorization": []string{"Bearer foo"},
+		"X-Foo":         []string{"baz"},
+		"X-Scope-Orgid": []string{"abc"},
+	})
+	f([]Header{
+		{
+			Name:   "X-Foo",
+			Value:  "baz",
+			Action: "add",
+		},
+		{
+			Name:   "authorization",
+			Action: "remove",
+		},
+	}, http.Header{
+		"X-Foo": []string{"bar", "baz"},
+	})
 }
 
 func getSrcPaths(paths []string) []*SrcPath {
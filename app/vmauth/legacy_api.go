@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// legacyAPIShim translates requests to legacy read API into Prometheus querying API requests and reshapes the responses.
+//
+// Shims cover only basic legacy API requests, which can be expressed via /api/v1/query_range at vmselect.
+type legacyAPIShim interface {
+	// parseRequest parses the legacy API request r into range queries.
+	parseRequest(r *http.Request, currentTime time.Time) (*legacyAPIRequest, error)
+
+	// writeResponse writes results for lr queries to w in legacy API format.
+	//
+	// results[i] contains the series returned for lr.queries[i].
+	writeResponse(w io.Writer, lr *legacyAPIRequest, results [][]promSeries) error
+}
+
+// legacyAPIShims contains the supported values for `legacy_api` option at `url_map`.
+var legacyAPIShims = map[string]legacyAPIShim{
+	"graphite": graphiteShim{},
+	"opentsdb": opentsdbShim{},
+}
+
+func getLegacyAPIShim(name string) (legacyAPIShim, error) {
+	shim, ok := legacyAPIShims[name]
+	if !ok {
+		names := make([]string, 0, len(legacyAPIShims))
+		for name := range legacyAPIShims {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unsupported `legacy_api`: %q; supported values: %q", name, names)
+	}
+	return shim, nil
+}
+
+// legacyAPIRequest is a legacy API request translated into range queries.
+type legacyAPIRequest struct {
+	queries []legacyAPIQuery
+
+	// msResolution is set if the response must contain timestamps in milliseconds.
+	msResolution bool
+}
+
+// legacyAPIQuery is a range query for /api/v1/query_range.
+type legacyAPIQuery struct {
+	// name is the metric name or target from the legacy API request.
+	name string
+
+	// query is MetricsQL query for the name.
+	query string
+
+	// start, end and step are in seconds.
+	start int64
+	end   int64
+	step  int64
+}
+
+// legacyAPIMaxPoints is the maximum number of points per series for queries without explicit step.
+const legacyAPIMaxPoints = 10000
+
+// getLegacyAPIDefaultStep returns step in seconds for queries on [start ... end] time range without explicit step.
+func getLegacyAPIDefaultStep(start, end int64) int64 {
+	step := int64(60)
+	if n := (end - start) / legacyAPIMaxPoints; n > step {
+		step = n
+	}
+	return step
+}
+
+// processLegacyAPIRequest translates r with the given shim into range queries, sends them to up and writes the reshaped response to w.
+func processLegacyAPIRequest(w http.ResponseWriter, r *http.Request, up *URLPrefix, hc HeadersConf, shim legacyAPIShim) {
+	legacyAPIRequests.Inc()
+	lr, err := shim.parseRequest(r, time.Now())
+	if err != nil {
+		legacyAPIErrors.Inc()
+		err = &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("cannot parse legacy API request: %w", err),
+			StatusCode: http.StatusBadRequest,
+		}
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	results := make([][]promSeries, len(lr.queries))
+	for i := range lr.queries {
+		series, err := fetchLegacyAPIQuery(r, up, hc, &lr.queries[i])
+		if err != nil {
+			legacyAPIErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+		results[i] = series
+	}
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	applyHeaders(h, hc.ResponseHeaders)
+	bw := bufio.NewWriter(w)
+	if err := shim.writeResponse(bw, lr, results); err != nil {
+		logger.Warnf("remoteAddr: %s; requestURI: %s; cannot write legacy API response: %s",
+			httpserver.GetQuotedRemoteAddr(r), httpserver.GetRequestURI(r), err)
+		return
+	}
+	_ = bw.Flush()
+}
+
+// fetchLegacyAPIQuery executes q at /api/v1/query_range of up backends and returns the resulting series.
+func fetchLegacyAPIQuery(r *http.Request, up *URLPrefix, hc HeadersConf, q *legacyAPIQuery) ([]promSeries, error) {
+	args := url.Values{
+		"query": {q.query},
+		"start": {strconv.FormatInt(q.start, 10)},
+		"end":   {strconv.FormatInt(q.end, 10)},
+		"step":  {strconv.FormatInt(q.step, 10)},
+	}
+	u := &url.URL{
+		Path:     "/api/v1/query_range",
+		RawQuery: args.Encode(),
+	}
+	transportOnce.Do(transportInit)
+	maxAttempts := up.getBackendsCount()
+	for i := 0; i < maxAttempts; i++ {
+		bu := up.getLeastLoadedBackendURL()
+		targetURL := mergeURLs(bu.url, u)
+		req := sanitizeRequestHeaders(r.Context(), r)
+		req.Method = "GET"
+		req.URL = targetURL
+		req.Body = http.NoBody
+		req.ContentLength = 0
+		req.Header.Del("Content-Type")
+		req.Header.Del("Content-Length")
+		req.Header.Del("Content-Encoding")
+		// The response is decoded by vmauth, so it must be uncompressed.
+		req.Header.Del("Accept-Encoding")
+		applyHeaders(req.Header, hc.RequestHeaders)
+		res, err := transport.RoundTrip(req)
+		if err != nil {
+			bu.put()
+			bu.setBroken()
+			logger.Warnf("remoteAddr: %s; requestURI: %s; error when proxying the legacy API request to %q: %s",
+				httpserver.GetQuotedRemoteAddr(r), httpserver.GetRequestURI(r), targetURL, err)
+			continue
+		}
+		series, err := readPromResponse(res)
+		bu.put()
+		if err != nil {
+			return nil, fmt.Errorf("cannot obtain response for query %q from %q: %w", q.query, targetURL, err)
+		}
+		return series, nil
+	}
+	return nil, &httpserver.ErrorWithStatusCode{
+		Err:        fmt.Errorf("all the backends are unavailable"),
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
+func readPromResponse(res *http.Response) ([]promSeries, error) {
+	defer func() {
+		_ = res.Body.Close()
+	}()
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("unexpected status code %d; response body: %q", res.StatusCode, body),
+			StatusCode: res.StatusCode,
+		}
+	}
+	var pr promResponse
+	if err := json.NewDecoder(res.Body).Decode(&pr); err != nil {
+		return nil, fmt.Errorf("cannot parse response: %w", err)
+	}
+	if pr.Status != "success" {
+		return nil, fmt.Errorf("unexpected response status %q; error: %q", pr.Status, pr.Error)
+	}
+	if pr.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("unexpected resultType %q; want %q", pr.Data.ResultType, "matrix")
+	}
+	return pr.Data.Result, nil
+}
+
+// promResponse is a response from /api/v1/query_range.
+type promResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string       `json:"resultType"`
+		Result     []promSeries `json:"result"`
+	} `json:"data"`
+}
+
+// promSeries is a single series from /api/v1/query_range response.
+type promSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values []promPoint       `json:"values"`
+}
+
+// promPoint is a point in [timestamp_seconds, "value"] format.
+type promPoint struct {
+	// timestamp is in milliseconds.
+	timestamp int64
+	value     float64
+}
+
+// UnmarshalJSON unmarshals p from [timestamp_seconds, "value"] array.
+func (p *promPoint) UnmarshalJSON(data []byte) error {
+	var a [2]json.RawMessage
+	if err := json.Unmarshal(data, &a); err != nil {
+		return err
+	}
+	var ts float64
+	if err := json.Unmarshal(a[0], &ts); err != nil {
+		return fmt.Errorf("cannot parse timestamp: %w", err)
+	}
+	var s string
+	if err := json.Unmarshal(a[1], &s); err != nil {
+		return fmt.Errorf("cannot parse value: %w", err)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("cannot parse value %q: %w", s, err)
+	}
+	p.timestamp = int64(ts*1000 + 0.5)
+	p.value = v
+	return nil
+}
+
+// appendJSONFloat appends JSON representation of v to dst.
+//
+// JSON doesn't support NaN and Inf, so they are marshaled as null.
+func appendJSONFloat(dst []byte, v float64) []byte {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return append(dst, "null"...)
+	}
+	return strconv.AppendFloat(dst, v, 'g', -1, 64)
+}
+
+// appendJSONString appends JSON string for s to dst.
+func appendJSONString(dst []byte, s string) []byte {
+	b, err := json.Marshal(s)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal string %q to JSON: %s", s, err)
+	}
+	return append(dst, b...)
+}
+
+// appendJSONTags appends JSON object for labels from m except of __name__ to dst.
+func appendJSONTags(dst []byte, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	dst = append(dst, '{')
+	for i, k := range keys {
+		if i > 0 {
+			dst = append(dst, ',')
+		}
+		dst = appendJSONString(dst, k)
+		dst = append(dst, ':')
+		dst = appendJSONString(dst, m[k])
+	}
+	return append(dst, '}')
+}
+
+// parseUnixTimestamp parses s as unix timestamp in seconds or milliseconds and returns it in seconds.
+func parseUnixTimestamp(s string) (int64, bool) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	if len(s) > 10 {
+		// Timestamp in milliseconds.
+		n /= 1000
+	}
+	return n, true
+}
+
+var (
+	legacyAPIRequests = metrics.NewCounter(`vmauth_legacy_api_requests_total`)
+	legacyAPIErrors   = metrics.NewCounter(`vmauth_legacy_api_request_errors_total`)
+)
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// graphiteShim translates Graphite /render requests for plain targets into /api/v1/query_range requests.
+//
+// Graphite functions aren't supported by the shim. Use Graphite Render API at vmselect for them.
+// See https://docs.victoriametrics.com/#graphite-render-api-usage
+type graphiteShim struct{}
+
+func (graphiteShim) parseRequest(r *http.Request, currentTime time.Time) (*legacyAPIRequest, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	if format := r.FormValue("format"); format != "" && format != "json" {
+		return nil, fmt.Errorf("unsupported `format=%s`; only `format=json` is supported", format)
+	}
+	fromStr := r.FormValue("from")
+	if fromStr == "" {
+		fromStr = "-24h"
+	}
+	from, err := parseGraphiteTime(fromStr, currentTime)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `from`: %w", err)
+	}
+	untilStr := r.FormValue("until")
+	if untilStr == "" {
+		untilStr = "now"
+	}
+	until, err := parseGraphiteTime(untilStr, currentTime)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `until`: %w", err)
+	}
+	if until < from {
+		return nil, fmt.Errorf("`until`=%d cannot be smaller than `from`=%d", until, from)
+	}
+	targets := r.Form["target"]
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("missing `target` query arg")
+	}
+	var lr legacyAPIRequest
+	for _, target := range targets {
+		if target == "" {
+			return nil, fmt.Errorf("`target` cannot be empty")
+		}
+		if strings.ContainsAny(target, "()") {
+			return nil, fmt.Errorf("unsupported `target=%s`; Graphite functions aren't supported", target)
+		}
+		lr.queries = append(lr.queries, legacyAPIQuery{
+			name:  target,
+			query: "{__graphite__=" + strconv.Quote(target) + "}",
+			start: from,
+			end:   until,
+			step:  getLegacyAPIDefaultStep(from, until),
+		})
+	}
+	return &lr, nil
+}
+
+var graphiteRelativeTimeRe = regexp.MustCompile(`^-(\d+)([a-z]+)$`)
+
+var graphiteTimeLayouts = []string{
+	"15:04_20060102",
+	"20060102",
+}
+
+// parseGraphiteTime parses Graphite time in absolute or relative format and returns unix timestamp in seconds.
+//
+// See https://graphite.readthedocs.io/en/latest/render_api.html#from-until
+func parseGraphiteTime(s string, currentTime time.Time) (int64, error) {
+	if s == "now" {
+		return currentTime.Unix(), nil
+	}
+	if a := graphiteRelativeTimeRe.FindStringSubmatch(s); a != nil {
+		n, err := strconv.ParseInt(a[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		unit := graphiteUnitSeconds(a[2])
+		if unit == 0 {
+			return 0, fmt.Errorf("unsupported time unit %q in %q", a[2], s)
+		}
+		return currentTime.Unix() - n*unit, nil
+	}
+	for _, layout := range graphiteTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	if ts, ok := parseUnixTimestamp(s); ok {
+		return ts, nil
+	}
+	return 0, fmt.Errorf("unsupported time format %q; see https://graphite.readthedocs.io/en/latest/render_api.html#from-until", s)
+}
+
+func graphiteUnitSeconds(unit string) int64 {
+	switch unit {
+	case "s", "sec", "secs", "second", "seconds":
+		return 1
+	case "min", "mins", "minute", "minutes":
+		return 60
+	case "h", "hour", "hours":
+		return 3600
+	case "d", "day", "days":
+		return 24 * 3600
+	case "w", "week", "weeks":
+		return 7 * 24 * 3600
+	case "mon", "month", "months":
+		return 30 * 24 * 3600
+	case "y", "year", "years":
+		return 365 * 24 * 3600
+	default:
+		return 0
+	}
+}
+
+func (graphiteShim) writeResponse(w io.Writer, lr *legacyAPIRequest, results [][]promSeries) error {
+	b := []byte{'['}
+	isFirst := true
+	for i, series := range results {
+		q := &lr.queries[i]
+		for j := range series {
+			s := &series[j]
+			name := s.Metric["__name__"]
+			if name == "" {
+				name = q.name
+			}
+			if !isFirst {
+				b = append(b, ',')
+			}
+			isFirst = false
+			b = append(b, `{"target":`...)
+			b = appendJSONString(b, name)
+			b = append(b, `,"tags":{"name":`...)
+			b = appendJSONString(b, name)
+			b = append(b, `},"datapoints":[`...)
+			for k, p := range s.Values {
+				if k > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, '[')
+				b = appendJSONFloat(b, p.value)
+				b = append(b, ',')
+				b = strconv.AppendInt(b, p.timestamp/1000, 10)
+				b = append(b, ']')
+			}
+			b = append(b, "]}"...)
+			if len(b) > 64*1024 {
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+				b = b[:0]
+			}
+		}
+	}
+	b = append(b, ']')
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestGraphiteShimParseRequestSuccess(t *testing.T) {
+	currentTime := time.Unix(1670000000, 0)
+	f := func(args string, queryExpected string, startExpected, endExpected, stepExpected int64) {
+		t.Helper()
+		r := &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: "/render", RawQuery: args},
+		}
+		lr, err := graphiteShim{}.parseRequest(r, currentTime)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(lr.queries) != 1 {
+			t.Fatalf("unexpected number of queries; got %d; want 1", len(lr.queries))
+		}
+		q := &lr.queries[0]
+		if q.query != queryExpected {
+			t.Fatalf("unexpected query;\ngot\n%s\nwant\n%s", q.query, queryExpected)
+		}
+		if q.start != startExpected || q.end != endExpected || q.step != stepExpected {
+			t.Fatalf("unexpected start, end, step; got %d, %d, %d; want %d, %d, %d", q.start, q.end, q.step, startExpected, endExpected, stepExpected)
+		}
+	}
+
+	// Default time range
+	f("target=foo.bar", `{__graphite__="foo.bar"}`, 1669913600, 1670000000, 60)
+
+	// Relative time range
+	f("target=foo.*.baz&from=-2hours&until=-1h&format=json", `{__graphite__="foo.*.baz"}`, 1669992800, 1669996400, 60)
+	f("target=foo.{a,b}&from=-3mon", `{__graphite__="foo.{a,b}"}`, 1670000000-90*24*3600, 1670000000, 90*24*3600/legacyAPIMaxPoints)
+
+	// Absolute time range
+	f("target=foo&from=1669990000&until=now", `{__graphite__="foo"}`, 1669990000, 1670000000, 60)
+	f("target=foo&from=20221201&until=12:30_20221201", `{__graphite__="foo"}`, 1669852800, 1669897800, 60)
+}
+
+func TestGraphiteShimParseRequestFailure(t *testing.T) {
+	currentTime := time.Unix(1670000000, 0)
+	f := func(args string) {
+		t.Helper()
+		r := &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: "/render", RawQuery: args},
+		}
+		if _, err := (graphiteShim{}).parseRequest(r, currentTime); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// Missing target
+	f("")
+	f("target=")
+
+	// Graphite functions
+	f("target=sumSeries(foo.*)")
+
+	// Unsupported format
+	f("target=foo&format=csv")
+
+	// Invalid time range
+	f("target=foo&from=yesterday")
+	f("target=foo&from=-1fortnight")
+	f("target=foo&until=bar")
+	f("target=foo&from=-1h&until=-2h")
+}
+
+func TestGraphiteShimWriteResponse(t *testing.T) {
+	lr := &legacyAPIRequest{
+		queries: []legacyAPIQuery{
+			{name: "foo.*"},
+		},
+	}
+	results := [][]promSeries{
+		{
+			{
+				Metric: map[string]string{"__name__": "foo.bar"},
+				Values: []promPoint{{timestamp: 1670000000000, value: 1.5}, {timestamp: 1670000060000, value: math.NaN()}},
+			},
+			{
+				Metric: map[string]string{},
+				Values: []promPoint{{timestamp: 1670000000000, value: 3}},
+			},
+		},
+	}
+	var bb bytes.Buffer
+	if err := (graphiteShim{}).writeResponse(&bb, lr, results); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	resultExpected := `[{"target":"foo.bar","tags":{"name":"foo.bar"},"datapoints":[[1.5,1670000000],[null,1670000060]]},` +
+		`{"target":"foo.*","tags":{"name":"foo.*"},"datapoints":[[3,1670000000]]}]`
+	if bb.String() != resultExpected {
+		t.Fatalf("unexpected response;\ngot\n%s\nwant\n%s", bb.String(), resultExpected)
+	}
+}
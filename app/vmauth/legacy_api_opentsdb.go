@@ -0,0 +1,401 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// opentsdbShim translates OpenTSDB /api/query requests with `m` query args into /api/v1/query_range requests.
+//
+// See http://opentsdb.net/docs/build/html/api_http/query/index.html
+type opentsdbShim struct{}
+
+// opentsdbAggregators maps OpenTSDB aggregators to MetricsQL aggregate functions.
+var opentsdbAggregators = map[string]string{
+	"sum":    "sum",
+	"zimsum": "sum",
+	"min":    "min",
+	"mimmin": "min",
+	"max":    "max",
+	"mimmax": "max",
+	"avg":    "avg",
+	"count":  "count",
+	"dev":    "stddev",
+	"none":   "",
+}
+
+// opentsdbDownsamplers maps OpenTSDB downsampling functions to MetricsQL rollup functions.
+var opentsdbDownsamplers = map[string]string{
+	"sum":    "sum_over_time",
+	"zimsum": "sum_over_time",
+	"min":    "min_over_time",
+	"mimmin": "min_over_time",
+	"max":    "max_over_time",
+	"mimmax": "max_over_time",
+	"avg":    "avg_over_time",
+	"count":  "count_over_time",
+	"dev":    "stddev_over_time",
+	"first":  "first_over_time",
+	"last":   "last_over_time",
+	"median": "median_over_time",
+}
+
+func (opentsdbShim) parseRequest(r *http.Request, currentTime time.Time) (*legacyAPIRequest, error) {
+	if r.Method != "GET" {
+		return nil, fmt.Errorf("unsupported method %q; only GET requests with `m` query args are supported", r.Method)
+	}
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	startStr := r.FormValue("start")
+	if startStr == "" {
+		return nil, fmt.Errorf("missing `start` query arg")
+	}
+	start, err := parseOpenTSDBTime(startStr, currentTime)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `start`: %w", err)
+	}
+	end := currentTime.Unix()
+	if endStr := r.FormValue("end"); endStr != "" {
+		end, err = parseOpenTSDBTime(endStr, currentTime)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `end`: %w", err)
+		}
+	}
+	if end < start {
+		return nil, fmt.Errorf("`end`=%d cannot be smaller than `start`=%d", end, start)
+	}
+	ms := r.Form["m"]
+	if len(ms) == 0 {
+		return nil, fmt.Errorf("missing `m` query arg")
+	}
+	lr := &legacyAPIRequest{
+		msResolution: r.FormValue("ms") == "true" || r.FormValue("msResolution") == "true",
+	}
+	for _, m := range ms {
+		q, err := parseOpenTSDBMetricQuery(m, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `m=%s`: %w", m, err)
+		}
+		lr.queries = append(lr.queries, *q)
+	}
+	return lr, nil
+}
+
+// parseOpenTSDBMetricQuery parses OpenTSDB metric query in the format
+// `aggregator:[downsample:][rate[{counter...}]:]metric[{groupByFilters}][{filters}]`.
+func parseOpenTSDBMetricQuery(m string, start, end int64) (*legacyAPIQuery, error) {
+	parts, err := splitOpenTSDBMetricQuery(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("missing aggregator")
+	}
+	aggr, ok := opentsdbAggregators[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported aggregator %q", parts[0])
+	}
+	metric, groupBy, filters, err := parseOpenTSDBMetricWithFilters(parts[len(parts)-1])
+	if err != nil {
+		return nil, err
+	}
+	step := getLegacyAPIDefaultStep(start, end)
+	query := "{" + strings.Join(filters, ",") + "}"
+	isRate := false
+	downsampler := ""
+	for _, part := range parts[1 : len(parts)-1] {
+		if part == "rate" || strings.HasPrefix(part, "rate{") {
+			isRate = true
+			continue
+		}
+		interval, fn, err := parseOpenTSDBDownsample(part)
+		if err != nil {
+			return nil, err
+		}
+		step = interval
+		downsampler = fn
+	}
+	if isRate {
+		query = "rate(" + query + ")"
+	}
+	if downsampler != "" {
+		if isRate {
+			query = fmt.Sprintf("%s((%s)[%ds])", downsampler, query, step)
+		} else {
+			query = fmt.Sprintf("%s(%s[%ds])", downsampler, query, step)
+		}
+	}
+	if aggr != "" {
+		query = fmt.Sprintf("%s(%s) by (%s)", aggr, query, strings.Join(groupBy, ","))
+	}
+	return &legacyAPIQuery{
+		name:  metric,
+		query: query,
+		start: start,
+		end:   end,
+		step:  step,
+	}, nil
+}
+
+// splitOpenTSDBMetricQuery splits m by ':' chars outside curly braces.
+func splitOpenTSDBMetricQuery(m string) ([]string, error) {
+	var parts []string
+	depth := 0
+	n := 0
+	for i := 0; i < len(m); i++ {
+		switch m[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected '}' at position %d", i)
+			}
+		case ':':
+			if depth == 0 {
+				parts = append(parts, m[n:i])
+				n = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("missing '}'")
+	}
+	return append(parts, m[n:]), nil
+}
+
+var opentsdbDownsampleRe = regexp.MustCompile(`^(\d+)(ms|s|m|h|d|w|n|y)-([a-z]+)(?:-[a-z]+)?$`)
+
+// parseOpenTSDBDownsample parses OpenTSDB downsample spec such as `1m-avg` or `1h-sum-zero`.
+//
+// The fill policy is ignored.
+func parseOpenTSDBDownsample(s string) (int64, string, error) {
+	a := opentsdbDownsampleRe.FindStringSubmatch(s)
+	if a == nil {
+		return 0, "", fmt.Errorf("unsupported downsample spec %q; expecting `<interval>-<function>`", s)
+	}
+	n, err := strconv.ParseInt(a[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("cannot parse downsample interval in %q: %w", s, err)
+	}
+	d := n * opentsdbUnitSeconds(a[2])
+	if d <= 0 {
+		return 0, "", fmt.Errorf("downsample interval must be at least 1s; got %q", s)
+	}
+	fn, ok := opentsdbDownsamplers[a[3]]
+	if !ok {
+		return 0, "", fmt.Errorf("unsupported downsample function %q in %q", a[3], s)
+	}
+	return d, fn, nil
+}
+
+// parseOpenTSDBMetricWithFilters parses `metric{groupByFilters}{filters}` into metric name,
+// group by tags and MetricsQL label filters.
+func parseOpenTSDBMetricWithFilters(s string) (string, []string, []string, error) {
+	n := strings.IndexByte(s, '{')
+	if n < 0 {
+		n = len(s)
+	}
+	metric := s[:n]
+	if metric == "" {
+		return "", nil, nil, fmt.Errorf("missing metric name")
+	}
+	filters := []string{"__name__=" + strconv.Quote(metric)}
+	var groupBy []string
+	tail := s[n:]
+	for i := 0; tail != ""; i++ {
+		if i >= 2 || tail[0] != '{' {
+			return "", nil, nil, fmt.Errorf("unexpected trailing data after metric name: %q", s[n:])
+		}
+		m := strings.IndexByte(tail, '}')
+		if m < 0 {
+			return "", nil, nil, fmt.Errorf("missing '}' in %q", tail)
+		}
+		tagFilters := tail[1:m]
+		tail = tail[m+1:]
+		if tagFilters == "" {
+			continue
+		}
+		for _, tf := range strings.Split(tagFilters, ",") {
+			k := strings.IndexByte(tf, '=')
+			if k <= 0 {
+				return "", nil, nil, fmt.Errorf("missing '=' in tag filter %q", tf)
+			}
+			tag := strings.TrimSpace(tf[:k])
+			filter, err := getOpenTSDBTagFilter(tag, strings.TrimSpace(tf[k+1:]))
+			if err != nil {
+				return "", nil, nil, err
+			}
+			filters = append(filters, filter)
+			if i == 0 {
+				// Tags from the first curly braces are used for grouping.
+				groupBy = append(groupBy, tag)
+			}
+		}
+	}
+	sort.Strings(groupBy)
+	return metric, groupBy, filters, nil
+}
+
+// getOpenTSDBTagFilter returns MetricsQL label filter for the given OpenTSDB tag filter.
+func getOpenTSDBTagFilter(tag, value string) (string, error) {
+	fn := ""
+	arg := value
+	if n := strings.IndexByte(value, '('); n > 0 && strings.HasSuffix(value, ")") {
+		fn = value[:n]
+		arg = value[n+1 : len(value)-1]
+	}
+	switch fn {
+	case "":
+		if arg == "*" {
+			return tag + `=~".+"`, nil
+		}
+		if strings.Contains(arg, "|") {
+			return tag + "=~" + strconv.Quote(quoteOpenTSDBLiterals(arg)), nil
+		}
+		if strings.Contains(arg, "*") {
+			return tag + "=~" + strconv.Quote(getOpenTSDBWildcardRegexp(arg)), nil
+		}
+		return tag + "=" + strconv.Quote(arg), nil
+	case "literal_or":
+		return tag + "=~" + strconv.Quote(quoteOpenTSDBLiterals(arg)), nil
+	case "not_literal_or":
+		return tag + "!~" + strconv.Quote(quoteOpenTSDBLiterals(arg)), nil
+	case "iliteral_or":
+		return tag + "=~" + strconv.Quote("(?i)"+quoteOpenTSDBLiterals(arg)), nil
+	case "wildcard":
+		return tag + "=~" + strconv.Quote(getOpenTSDBWildcardRegexp(arg)), nil
+	case "iwildcard":
+		return tag + "=~" + strconv.Quote("(?i)"+getOpenTSDBWildcardRegexp(arg)), nil
+	case "regexp":
+		if _, err := regexp.Compile(arg); err != nil {
+			return "", fmt.Errorf("invalid regexp in tag filter %s=%s: %w", tag, value, err)
+		}
+		return tag + "=~" + strconv.Quote(arg), nil
+	default:
+		return "", fmt.Errorf("unsupported tag filter function %q in %s=%s", fn, tag, value)
+	}
+}
+
+func quoteOpenTSDBLiterals(s string) string {
+	a := strings.Split(s, "|")
+	for i := range a {
+		a[i] = regexp.QuoteMeta(a[i])
+	}
+	return strings.Join(a, "|")
+}
+
+func getOpenTSDBWildcardRegexp(s string) string {
+	a := strings.Split(s, "*")
+	for i := range a {
+		a[i] = regexp.QuoteMeta(a[i])
+	}
+	return strings.Join(a, ".*")
+}
+
+func opentsdbUnitSeconds(unit string) int64 {
+	switch unit {
+	case "s":
+		return 1
+	case "m":
+		return 60
+	case "h":
+		return 3600
+	case "d":
+		return 24 * 3600
+	case "w":
+		return 7 * 24 * 3600
+	case "n":
+		return 30 * 24 * 3600
+	case "y":
+		return 365 * 24 * 3600
+	default:
+		// Milliseconds are rounded down to zero seconds.
+		return 0
+	}
+}
+
+var opentsdbRelativeTimeRe = regexp.MustCompile(`^(\d+)(ms|s|m|h|d|w|n|y)-ago$`)
+
+var opentsdbTimeLayouts = []string{
+	"2006/01/02-15:04:05",
+	"2006/01/02 15:04:05",
+	"2006/01/02-15:04",
+	"2006/01/02 15:04",
+	"2006/01/02",
+}
+
+// parseOpenTSDBTime parses OpenTSDB time in absolute or relative format and returns unix timestamp in seconds.
+//
+// See http://opentsdb.net/docs/build/html/user_guide/query/dates.html
+func parseOpenTSDBTime(s string, currentTime time.Time) (int64, error) {
+	if ts, ok := parseUnixTimestamp(s); ok {
+		return ts, nil
+	}
+	if a := opentsdbRelativeTimeRe.FindStringSubmatch(s); a != nil {
+		n, err := strconv.ParseInt(a[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if a[2] == "ms" {
+			return currentTime.Add(-time.Duration(n) * time.Millisecond).Unix(), nil
+		}
+		return currentTime.Unix() - n*opentsdbUnitSeconds(a[2]), nil
+	}
+	for _, layout := range opentsdbTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Unix(), nil
+		}
+	}
+	return 0, fmt.Errorf("unsupported time format %q; see http://opentsdb.net/docs/build/html/user_guide/query/dates.html", s)
+}
+
+func (opentsdbShim) writeResponse(w io.Writer, lr *legacyAPIRequest, results [][]promSeries) error {
+	b := []byte{'['}
+	isFirst := true
+	for i, series := range results {
+		q := &lr.queries[i]
+		for j := range series {
+			s := &series[j]
+			if !isFirst {
+				b = append(b, ',')
+			}
+			isFirst = false
+			b = append(b, `{"metric":`...)
+			b = appendJSONString(b, q.name)
+			b = append(b, `,"tags":`...)
+			b = appendJSONTags(b, s.Metric)
+			b = append(b, `,"aggregateTags":[],"dps":{`...)
+			for k, p := range s.Values {
+				if k > 0 {
+					b = append(b, ',')
+				}
+				b = append(b, '"')
+				if lr.msResolution {
+					b = strconv.AppendInt(b, p.timestamp, 10)
+				} else {
+					b = strconv.AppendInt(b, p.timestamp/1000, 10)
+				}
+				b = append(b, `":`...)
+				b = appendJSONFloat(b, p.value)
+			}
+			b = append(b, "}}"...)
+			if len(b) > 64*1024 {
+				if _, err := w.Write(b); err != nil {
+					return err
+				}
+				b = b[:0]
+			}
+		}
+	}
+	b = append(b, ']')
+	_, err := w.Write(b)
+	return err
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestOpenTSDBShimParseRequestSuccess(t *testing.T) {
+	currentTime := time.Unix(1670000000, 0)
+	f := func(args string, queryExpected string, startExpected, endExpected, stepExpected int64) {
+		t.Helper()
+		r := &http.Request{
+			Method: "GET",
+			URL:    &url.URL{Path: "/api/query", RawQuery: args},
+		}
+		lr, err := opentsdbShim{}.parseRequest(r, currentTime)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(lr.queries) != 1 {
+			t.Fatalf("unexpected number of queries; got %d; want 1", len(lr.queries))
+		}
+		q := &lr.queries[0]
+		if q.query != queryExpected {
+			t.Fatalf("unexpected query;\ngot\n%s\nwant\n%s", q.query, queryExpected)
+		}
+		if q.start != startExpected || q.end != endExpected || q.step != stepExpected {
+			t.Fatalf("unexpected start, end, step; got %d, %d, %d; want %d, %d, %d", q.start, q.end, q.step, startExpected, endExpected, stepExpected)
+		}
+	}
+
+	// Plain aggregation
+	f("start=1h-ago&m=sum:sys.cpu.user", `sum({__name__="sys.cpu.user"}) by ()`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=none:sys.cpu.user", `{__name__="sys.cpu.user"}`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=zimsum:sys.cpu.user", `sum({__name__="sys.cpu.user"}) by ()`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=dev:sys.cpu.user", `stddev({__name__="sys.cpu.user"}) by ()`, 1669996400, 1670000000, 60)
+
+	// Absolute start and end
+	f("start=1669990000&end=1669999000000&m=max:foo", `max({__name__="foo"}) by ()`, 1669990000, 1669999000, 60)
+	f("start=2022/12/01-00:00:00&end=2022/12/02&m=min:foo", `min({__name__="foo"}) by ()`, 1669852800, 1669939200, 60)
+
+	// Default step for wide time ranges
+	f("start=2y-ago&m=avg:foo", `avg({__name__="foo"}) by ()`, 1670000000-2*365*24*3600, 1670000000, 2*365*24*3600/legacyAPIMaxPoints)
+
+	// Group by and filters
+	f("start=1h-ago&m=sum:foo{host=a,dc=*}", `sum({__name__="foo",host="a",dc=~".+"}) by (dc,host)`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=sum:foo{host=a|b}{dc=literal_or(x.y|z)}", `sum({__name__="foo",host=~"a|b",dc=~"x\\.y|z"}) by (host)`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=sum:foo{}{dc=not_literal_or(x),host=wildcard(web*),env=regexp(prod.%2B)}",
+		`sum({__name__="foo",dc!~"x",host=~"web.*",env=~"prod.+"}) by ()`, 1669996400, 1670000000, 60)
+
+	// Rate and downsampling
+	f("start=1h-ago&m=sum:rate:foo", `sum(rate({__name__="foo"})) by ()`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=sum:rate{counter,,1000}:foo", `sum(rate({__name__="foo"})) by ()`, 1669996400, 1670000000, 60)
+	f("start=1h-ago&m=sum:5m-avg:foo{host=a}", `sum(avg_over_time({__name__="foo",host="a"}[300s])) by (host)`, 1669996400, 1670000000, 300)
+	f("start=1h-ago&m=max:1h-last-zero:rate:foo", `max(last_over_time((rate({__name__="foo"}))[3600s])) by ()`, 1669996400, 1670000000, 3600)
+}
+
+func TestOpenTSDBShimParseRequestFailure(t *testing.T) {
+	currentTime := time.Unix(1670000000, 0)
+	f := func(method, args string) {
+		t.Helper()
+		r := &http.Request{
+			Method: method,
+			URL:    &url.URL{Path: "/api/query", RawQuery: args},
+		}
+		if _, err := (opentsdbShim{}).parseRequest(r, currentTime); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// JSON body requests aren't supported
+	f("POST", "")
+
+	// Missing or invalid start
+	f("GET", "m=sum:foo")
+	f("GET", "start=foo&m=sum:foo")
+
+	// Invalid end
+	f("GET", "start=1h-ago&end=bar&m=sum:foo")
+	f("GET", "start=1h-ago&end=2h-ago&m=sum:foo")
+
+	// Missing or invalid m
+	f("GET", "start=1h-ago")
+	f("GET", "start=1h-ago&m=foo")
+	f("GET", "start=1h-ago&m=p99:foo")
+	f("GET", "start=1h-ago&m=sum:")
+	f("GET", "start=1h-ago&m=sum:1m-p99:foo")
+	f("GET", "start=1h-ago&m=sum:1x-avg:foo")
+	f("GET", "start=1h-ago&m=sum:foo{host=a")
+	f("GET", "start=1h-ago&m=sum:foo}")
+	f("GET", "start=1h-ago&m=sum:foo{host}")
+	f("GET", "start=1h-ago&m=sum:foo{}{}{}")
+	f("GET", "start=1h-ago&m=sum:foo{host=regexp(a[)}")
+	f("GET", "start=1h-ago&m=sum:foo{host=unknown(a)}")
+}
+
+func TestOpenTSDBShimWriteResponse(t *testing.T) {
+	f := func(msResolution bool, resultExpected string) {
+		t.Helper()
+		lr := &legacyAPIRequest{
+			queries: []legacyAPIQuery{
+				{name: "foo"},
+				{name: "bar"},
+			},
+			msResolution: msResolution,
+		}
+		results := [][]promSeries{
+			{
+				{
+					Metric: map[string]string{"host": "a", "dc": "x"},
+					Values: []promPoint{{timestamp: 1670000000000, value: 1.5}, {timestamp: 1670000060000, value: 2}},
+				},
+				{
+					Metric: map[string]string{"__name__": "foo"},
+				},
+			},
+			nil,
+		}
+		var bb bytes.Buffer
+		if err := (opentsdbShim{}).writeResponse(&bb, lr, results); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if bb.String() != resultExpected {
+			t.Fatalf("unexpected response;\ngot\n%s\nwant\n%s", bb.String(), resultExpected)
+		}
+	}
+	f(false, `[{"metric":"foo","tags":{"dc":"x","host":"a"},"aggregateTags":[],"dps":{"1670000000":1.5,"1670000060":2}},`+
+		`{"metric":"foo","tags":{},"aggregateTags":[],"dps":{}}]`)
+	f(true, `[{"metric":"foo","tags":{"dc":"x","host":"a"},"aggregateTags":[],"dps":{"1670000000000":1.5,"1670000060000":2}},`+
+		`{"metric":"foo","tags":{},"aggregateTags":[],"dps":{}}]`)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProcessLegacyAPIRequest(t *testing.T) {
+	var queryReceived string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/select/0/prometheus/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		queryReceived = r.FormValue("query")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[` +
+			`{"metric":{"host":"a"},"values":[[1670000000,"1"],[1670000060.5,"NaN"]]}]}}`))
+	}))
+	defer backend.Close()
+
+	f := func(shim legacyAPIShim, requestURI string, statusCodeExpected int, queryExpected, responseExpected string) {
+		t.Helper()
+		queryReceived = ""
+		up := mustParseURL(backend.URL + "/select/0/prometheus")
+		if err := up.sanitize(); err != nil {
+			t.Fatalf("cannot sanitize url_prefix: %s", err)
+		}
+		r := httptest.NewRequest("GET", requestURI, nil)
+		w := httptest.NewRecorder()
+		processLegacyAPIRequest(w, r, up, HeadersConf{}, shim)
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d; response: %s", w.Code, statusCodeExpected, w.Body.String())
+		}
+		if queryReceived != queryExpected {
+			t.Fatalf("unexpected query received by the backend; got %q; want %q", queryReceived, queryExpected)
+		}
+		if responseExpected != "" && w.Body.String() != responseExpected {
+			t.Fatalf("unexpected response;\ngot\n%s\nwant\n%s", w.Body.String(), responseExpected)
+		}
+	}
+
+	f(opentsdbShim{}, "/api/query?start=1669990000&end=1670000100&m=sum:foo{host=*}", http.StatusOK, `sum({__name__="foo",host=~".+"}) by (host)`,
+		`[{"metric":"foo","tags":{"host":"a"},"aggregateTags":[],"dps":{"1670000000":1,"1670000060":null}}]`)
+	f(graphiteShim{}, "/render?target=foo.*&from=1669990000&until=1670000100", http.StatusOK, `{__graphite__="foo.*"}`,
+		`[{"target":"foo.*","tags":{"name":"foo.*"},"datapoints":[[1,1670000000],[null,1670000060]]}]`)
+
+	// Invalid requests mustn't be proxied to the backend
+	f(opentsdbShim{}, "/api/query?m=sum:foo", http.StatusBadRequest, "", "")
+	f(graphiteShim{}, "/render?target=sumSeries(foo.*)", http.StatusBadRequest, "", "")
+}
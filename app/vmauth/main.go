@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envflag"
@@ -49,10 +51,18 @@ func main() {
 	envflag.Parse()
 	buildinfo.Init()
 	logger.Init()
+	if *issueToken {
+		if err := issueAPIToken(os.Stdout, time.Now()); err != nil {
+			logger.Fatalf("cannot issue API token: %s", err)
+		}
+		return
+	}
 	pushmetrics.Init()
 
 	logger.Infof("starting vmauth at %q...", *httpListenAddr)
 	startTime := time.Now()
+	auditlog.Init()
+	initAccessLog()
 	initAuthConfig()
 	go httpserver.Serve(*httpListenAddr, *useProxyProtocol, requestHandler)
 	logger.Infof("started vmauth in %.3f seconds", time.Since(startTime).Seconds())
@@ -67,6 +77,8 @@ func main() {
 	}
 	logger.Infof("successfully shut down the webservice in %.3f seconds", time.Since(startTime).Seconds())
 	stopAuthConfig()
+	stopAccessLog()
+	auditlog.MustStop()
 	logger.Infof("successfully stopped vmauth in %.3f seconds", time.Since(startTime).Seconds())
 }
 
@@ -77,6 +89,7 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		configReloadRequests.Inc()
+		auditlog.Log(auditlog.NewEvent(r, "config_reload_request"), nil)
 		procutil.SelfSIGHUP()
 		w.WriteHeader(http.StatusOK)
 		return true
@@ -95,6 +108,9 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 
 	ac := authConfig.Load().(map[string]*UserInfo)
 	ui := ac[authToken]
+	if ui == nil {
+		ui = getUserByAPIToken(ac, authToken)
+	}
 	if ui == nil {
 		invalidAuthTokenRequests.Inc()
 		err := fmt.Errorf("cannot find the provided auth token %q in config", authToken)
@@ -110,6 +126,15 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	}
 	ui.requests.Inc()
+	if t := ui.apiToken; t != nil {
+		path := normalizeURL(r.URL).Path
+		statusCode, err := t.checkRequest(path, time.Now())
+		logAPITokenUsage(r, t, path, err)
+		if err != nil {
+			http.Error(w, err.Error(), statusCode)
+			return true
+		}
+	}
 
 	// Limit the concurrency of requests to backends
 	concurrencyLimitOnce.Do(concurrencyLimitInit)
@@ -126,7 +151,7 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 		handleConcurrencyLimitError(w, r, err)
 		return true
 	}
-	processRequest(w, r, ui)
+	processRequestWithAccessLog(w, r, ui)
 	ui.endConcurrencyLimit()
 	<-concurrencyLimitCh
 	return true
@@ -134,16 +159,27 @@ func requestHandler(w http.ResponseWriter, r *http.Request) bool {
 
 func processRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 	u := normalizeURL(r.URL)
-	up, headers, err := ui.getURLPrefixAndHeaders(u)
+	up, hc, rc, shim, err := ui.getURLPrefixAndHeaders(u)
 	if err != nil {
 		httpserver.Errorf(w, r, "cannot determine targetURL: %s", err)
 		return
 	}
+	if shim != nil {
+		processLegacyAPIRequest(w, r, up, hc, shim)
+		return
+	}
+	if rc != nil {
+		u = rc.apply(u)
+	}
+	if up.retry != nil && isIdempotentRequest(r) {
+		processRequestWithRetries(w, r, ui, up, u, hc)
+		return
+	}
 	maxAttempts := up.getBackendsCount()
 	for i := 0; i < maxAttempts; i++ {
 		bu := up.getLeastLoadedBackendURL()
 		targetURL := mergeURLs(bu.url, u)
-		ok := tryProcessingRequest(w, r, targetURL, headers)
+		ok := tryProcessingRequest(w, r, targetURL, hc)
 		bu.put()
 		if ok {
 			return
@@ -157,15 +193,9 @@ func processRequest(w http.ResponseWriter, r *http.Request, ui *UserInfo) {
 	httpserver.Errorf(w, r, "%s", err)
 }
 
-func tryProcessingRequest(w http.ResponseWriter, r *http.Request, targetURL *url.URL, headers []Header) bool {
-	// This code has been copied from net/http/httputil/reverseproxy.go
-	req := sanitizeRequestHeaders(r)
-	req.URL = targetURL
-	for _, h := range headers {
-		req.Header.Set(h.Name, h.Value)
-	}
-	transportOnce.Do(transportInit)
-	res, err := transport.RoundTrip(req)
+func tryProcessingRequest(w http.ResponseWriter, r *http.Request, targetURL *url.URL, hc HeadersConf) bool {
+	setAccessLogBackend(w, targetURL)
+	res, err := roundTrip(r.Context(), r, targetURL, hc)
 	if err != nil {
 		remoteAddr := httpserver.GetQuotedRemoteAddr(r)
 		requestURI := httpserver.GetRequestURI(r)
@@ -182,22 +212,42 @@ func tryProcessingRequest(w http.ResponseWriter, r *http.Request, targetURL *url
 		logger.Warnf("remoteAddr: %s; requestURI: %s; error when proxying the request to %q: %s", remoteAddr, requestURI, targetURL, err)
 		return false
 	}
+	writeResponse(w, r, res, targetURL, hc)
+	_ = res.Body.Close()
+	return true
+}
+
+// roundTrip sends r to targetURL and returns the response from the backend.
+//
+// The request is canceled when ctx is canceled.
+func roundTrip(ctx context.Context, r *http.Request, targetURL *url.URL, hc HeadersConf) (*http.Response, error) {
+	// This code has been copied from net/http/httputil/reverseproxy.go
+	req := sanitizeRequestHeaders(ctx, r)
+	req.URL = targetURL
+	applyHeaders(req.Header, hc.RequestHeaders)
+	transportOnce.Do(transportInit)
+	return transport.RoundTrip(req)
+}
+
+// writeResponse copies res obtained from targetURL to w.
+//
+// The caller is responsible for closing res.Body.
+func writeResponse(w http.ResponseWriter, r *http.Request, res *http.Response, targetURL *url.URL, hc HeadersConf) {
+	setAccessLogBackend(w, targetURL)
 	removeHopHeaders(res.Header)
+	applyHeaders(res.Header, hc.ResponseHeaders)
 	copyHeader(w.Header(), res.Header)
 	w.WriteHeader(res.StatusCode)
 
 	copyBuf := copyBufPool.Get()
 	copyBuf.B = bytesutil.ResizeNoCopyNoOverallocate(copyBuf.B, 16*1024)
-	_, err = io.CopyBuffer(w, res.Body, copyBuf.B)
+	_, err := io.CopyBuffer(w, res.Body, copyBuf.B)
 	copyBufPool.Put(copyBuf)
-	_ = res.Body.Close()
 	if err != nil && !netutil.IsTrivialNetworkError(err) {
 		remoteAddr := httpserver.GetQuotedRemoteAddr(r)
 		requestURI := httpserver.GetRequestURI(r)
 		logger.Warnf("remoteAddr: %s; requestURI: %s; error when proxying response body from %s: %s", remoteAddr, requestURI, targetURL, err)
-		return true
 	}
-	return true
 }
 
 var copyBufPool bytesutil.ByteBufferPool
@@ -210,9 +260,9 @@ func copyHeader(dst, src http.Header) {
 	}
 }
 
-func sanitizeRequestHeaders(r *http.Request) *http.Request {
+func sanitizeRequestHeaders(ctx context.Context, r *http.Request) *http.Request {
 	// This code has been copied from net/http/httputil/reverseproxy.go
-	req := r.Clone(r.Context())
+	req := r.Clone(ctx)
 	removeHopHeaders(req.Header)
 	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
 		// If we aren't the first proxy retain prior
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// RetryConfig contains retry and hedging policy for idempotent requests proxied to `url_prefix` backends.
+//
+// See https://docs.victoriametrics.com/vmauth.html#retries-and-hedging
+type RetryConfig struct {
+	// MaxRetries is the maximum number of retries for requests failed with connection errors or with RetryStatusCodes.
+	MaxRetries int `yaml:"max_retries,omitempty"`
+
+	// RetryStatusCodes contains backend response status codes, which must be retried.
+	//
+	// By default 502, 503 and 504 status codes are retried.
+	RetryStatusCodes []int `yaml:"retry_status_codes,omitempty"`
+
+	// HedgeAfter is the duration after which a hedged request is sent to another backend if the first backend didn't respond yet.
+	//
+	// The response from the fastest backend is returned to the client, while the slower request is canceled.
+	HedgeAfter *promutils.Duration `yaml:"hedge_after,omitempty"`
+
+	// BudgetRatio is the maximum ratio of retried and hedged requests to the overall number of requests per 10 seconds.
+	//
+	// By default 0.1 is used, e.g. up to 10% of additional requests are allowed.
+	BudgetRatio float64 `yaml:"budget_ratio,omitempty"`
+
+	retryStatusCodes map[int]bool
+	budget           retryBudget
+}
+
+// defaultRetryStatusCodes contains status codes, which are returned by proxies and backends on temporary unavailability.
+var defaultRetryStatusCodes = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+
+func (rc *RetryConfig) init() error {
+	if rc.MaxRetries < 0 {
+		return fmt.Errorf("`max_retries` cannot be negative; got %d", rc.MaxRetries)
+	}
+	if rc.HedgeAfter != nil && rc.HedgeAfter.Duration() <= 0 {
+		return fmt.Errorf("`hedge_after` must be positive; got %s", rc.HedgeAfter.Duration())
+	}
+	if rc.BudgetRatio < 0 || rc.BudgetRatio > 1 {
+		return fmt.Errorf("`budget_ratio` must be in the range [0..1]; got %v", rc.BudgetRatio)
+	}
+	if rc.BudgetRatio == 0 {
+		rc.BudgetRatio = 0.1
+	}
+	codes := rc.RetryStatusCodes
+	if len(codes) == 0 {
+		codes = defaultRetryStatusCodes
+	}
+	rc.retryStatusCodes = make(map[int]bool, len(codes))
+	for _, code := range codes {
+		if code < 500 || code > 599 {
+			return fmt.Errorf("`retry_status_codes` may contain only 5xx status codes; got %d", code)
+		}
+		rc.retryStatusCodes[code] = true
+	}
+	return nil
+}
+
+func (rc *RetryConfig) hedgeAfter() time.Duration {
+	if rc.HedgeAfter == nil {
+		return 0
+	}
+	return rc.HedgeAfter.Duration()
+}
+
+// retryBudget limits the number of retried and hedged requests relative to the number of requests during the last 10 seconds.
+type retryBudget struct {
+	mu          sync.Mutex
+	windowStart uint64
+	requests    int
+	extra       int
+}
+
+const retryBudgetWindowSeconds = 10
+
+func (rb *retryBudget) resetIfNeededLocked() {
+	ct := fasttime.UnixTimestamp()
+	if ct-rb.windowStart >= retryBudgetWindowSeconds {
+		rb.windowStart = ct
+		rb.requests = 0
+		rb.extra = 0
+	}
+}
+
+func (rb *retryBudget) registerRequest() {
+	rb.mu.Lock()
+	rb.resetIfNeededLocked()
+	rb.requests++
+	rb.mu.Unlock()
+}
+
+// tryAcquire returns true if an additional request fits the budget with the given ratio.
+func (rb *retryBudget) tryAcquire(ratio float64) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	rb.resetIfNeededLocked()
+	limit := int(ratio * float64(rb.requests))
+	if limit < 1 {
+		// Always allow a single additional request, so rarely accessed routes could be retried.
+		limit = 1
+	}
+	if rb.extra >= limit {
+		return false
+	}
+	rb.extra++
+	return true
+}
+
+func (rc *RetryConfig) tryAcquireBudget() bool {
+	if rc.budget.tryAcquire(rc.BudgetRatio) {
+		return true
+	}
+	retryBudgetExhausted.Inc()
+	return false
+}
+
+// isIdempotentRequest returns true if r can be safely sent multiple times to backends.
+//
+// Only requests without body are considered idempotent, since the request body is streamed to the backend.
+func isIdempotentRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// processRequestWithRetries proxies r to backends from up according to up.retry policy.
+func processRequestWithRetries(w http.ResponseWriter, r *http.Request, ui *UserInfo, up *URLPrefix, u *url.URL, hc HeadersConf) {
+	rc := up.retry
+	rc.budget.registerRequest()
+	var lastErr error
+	for attempt := 0; attempt <= rc.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if !rc.tryAcquireBudget() {
+				break
+			}
+			retriedRequests.Inc()
+		}
+		rr := roundTripHedged(r, up, u, hc, rc)
+		if rr.err != nil {
+			lastErr = rr.err
+			remoteAddr := httpserver.GetQuotedRemoteAddr(r)
+			requestURI := httpserver.GetRequestURI(r)
+			logger.Warnf("remoteAddr: %s; requestURI: %s; error when proxying the request to %q: %s", remoteAddr, requestURI, rr.targetURL, rr.err)
+			continue
+		}
+		if rc.retryStatusCodes[rr.res.StatusCode] && attempt < rc.MaxRetries {
+			lastErr = fmt.Errorf("unexpected response status code %d from %q", rr.res.StatusCode, rr.targetURL)
+			rr.discard()
+			continue
+		}
+		writeResponse(w, r, rr.res, rr.targetURL, hc)
+		rr.done()
+		return
+	}
+	err := &httpserver.ErrorWithStatusCode{
+		Err:        fmt.Errorf("all the attempts to proxy the request for the user %q have failed; last error: %w", ui.name(), lastErr),
+		StatusCode: http.StatusServiceUnavailable,
+	}
+	httpserver.Errorf(w, r, "%s", err)
+}
+
+// roundTripResult is the result of a single request to backend.
+type roundTripResult struct {
+	res       *http.Response
+	err       error
+	bu        *backendURL
+	targetURL *url.URL
+	cancel    context.CancelFunc
+}
+
+// done must be called after the response body is read.
+func (rr *roundTripResult) done() {
+	if rr.res != nil {
+		_ = rr.res.Body.Close()
+	}
+	rr.cancel()
+	rr.bu.put()
+}
+
+// discard drops the response without reading it.
+func (rr *roundTripResult) discard() {
+	if rr.res != nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(rr.res.Body, 64*1024))
+	}
+	rr.done()
+}
+
+func (rr *roundTripResult) isSuccess(rc *RetryConfig) bool {
+	return rr.err == nil && !rc.retryStatusCodes[rr.res.StatusCode]
+}
+
+// roundTripHedged sends r to the least loaded backend from up.
+//
+// If the backend doesn't respond during rc.HedgeAfter, then r is sent to another least loaded backend,
+// and the first successful response is returned. The caller must call done() on the returned result.
+func roundTripHedged(r *http.Request, up *URLPrefix, u *url.URL, hc HeadersConf, rc *RetryConfig) *roundTripResult {
+	resultCh := make(chan *roundTripResult, 2)
+	var started []*roundTripResult
+	startRoundTrip := func(bu *backendURL) {
+		ctx, cancel := context.WithCancel(r.Context())
+		rr := &roundTripResult{
+			bu:        bu,
+			targetURL: mergeURLs(bu.url, u),
+			cancel:    cancel,
+		}
+		started = append(started, rr)
+		go func() {
+			rr.res, rr.err = roundTrip(ctx, r, rr.targetURL, hc)
+			if rr.err != nil && ctx.Err() == nil {
+				// Do not mark the backend as broken if the request has been canceled by the faster hedged request or by the client.
+				bu.setBroken()
+			}
+			resultCh <- rr
+		}()
+	}
+	bu := up.getLeastLoadedBackendURL()
+	startRoundTrip(bu)
+	pending := 1
+
+	hedgeAfter := rc.hedgeAfter()
+	if hedgeAfter > 0 && up.getBackendsCount() > 1 {
+		t := timerpool.Get(hedgeAfter)
+		select {
+		case rr := <-resultCh:
+			timerpool.Put(t)
+			return rr
+		case <-t.C:
+			timerpool.Put(t)
+			buHedged := up.getLeastLoadedBackendURL()
+			if buHedged != bu && rc.tryAcquireBudget() {
+				hedgedRequests.Inc()
+				startRoundTrip(buHedged)
+				pending++
+			} else {
+				buHedged.put()
+			}
+		}
+	}
+
+	var rr *roundTripResult
+	for pending > 0 {
+		rr = <-resultCh
+		pending--
+		if rr.isSuccess(rc) || pending == 0 {
+			break
+		}
+		// Wait for the other request, since this one has failed.
+		rr.discard()
+	}
+	if pending > 0 {
+		// Cancel the slower request and release its resources in background.
+		for _, rrSlow := range started {
+			if rrSlow != rr {
+				rrSlow.cancel()
+			}
+		}
+		go func() {
+			rrSlow := <-resultCh
+			rrSlow.discard()
+		}()
+	}
+	return rr
+}
+
+var (
+	retriedRequests      = metrics.NewCounter(`vmauth_retried_requests_total`)
+	hedgedRequests       = metrics.NewCounter(`vmauth_hedged_requests_total`)
+	retryBudgetExhausted = metrics.NewCounter(`vmauth_retry_budget_exhausted_total`)
+)
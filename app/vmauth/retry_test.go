@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+)
+
+func TestRetryBudget(t *testing.T) {
+	var rb retryBudget
+
+	// A single additional request must be allowed even without requests
+	if !rb.tryAcquire(0.1) {
+		t.Fatalf("expecting the first additional request to be allowed")
+	}
+	if rb.tryAcquire(0.1) {
+		t.Fatalf("expecting the second additional request to be denied")
+	}
+
+	for i := 0; i < 30; i++ {
+		rb.registerRequest()
+	}
+	// 30 requests * 0.1 = 3 additional requests, one of them is already used
+	for i := 0; i < 2; i++ {
+		if !rb.tryAcquire(0.1) {
+			t.Fatalf("expecting additional request #%d to be allowed", i)
+		}
+	}
+	if rb.tryAcquire(0.1) {
+		t.Fatalf("expecting additional request to be denied after the budget is exhausted")
+	}
+}
+
+func TestProcessRequestWithRetries(t *testing.T) {
+	var failedRequests, okRequests atomic.Int64
+	failingBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failedRequests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failingBackend.Close()
+	slowBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+		w.WriteHeader(http.StatusGatewayTimeout)
+	}))
+	defer slowBackend.Close()
+	okBackend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		okRequests.Add(1)
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer okBackend.Close()
+
+	f := func(backends []string, rc *RetryConfig, statusCodeExpected int, responseExpected string) {
+		t.Helper()
+		if err := rc.init(); err != nil {
+			t.Fatalf("cannot init retry config: %s", err)
+		}
+		up := mustParseURLs(backends)
+		if err := up.sanitize(); err != nil {
+			t.Fatalf("cannot sanitize url_prefix: %s", err)
+		}
+		up.retry = rc
+		ui := &UserInfo{
+			Username:  "foo",
+			URLPrefix: up,
+		}
+		r := httptest.NewRequest("GET", "/api/v1/query?query=up", nil)
+		w := httptest.NewRecorder()
+		processRequestWithRetries(w, r, ui, up, normalizeURL(r.URL), HeadersConf{})
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d; response: %s", w.Code, statusCodeExpected, w.Body.String())
+		}
+		if responseExpected != "" && w.Body.String() != responseExpected {
+			t.Fatalf("unexpected response; got %q; want %q", w.Body.String(), responseExpected)
+		}
+	}
+
+	// The failed request is retried at another backend.
+	// Note that the second backend is selected first for the newly created url_prefix.
+	failedRequestsPrev := failedRequests.Load()
+	f([]string{okBackend.URL, failingBackend.URL}, &RetryConfig{
+		MaxRetries: 1,
+	}, http.StatusOK, "ok")
+	if n := failedRequests.Load() - failedRequestsPrev; n != 1 {
+		t.Fatalf("unexpected number of failed requests; got %d; want 1", n)
+	}
+
+	// The last response is returned to the client if all the retries fail
+	f([]string{failingBackend.URL}, &RetryConfig{
+		MaxRetries: 1,
+	}, http.StatusServiceUnavailable, "")
+
+	// The slow request is hedged to another backend
+	okRequestsPrev := okRequests.Load()
+	f([]string{okBackend.URL, slowBackend.URL}, &RetryConfig{
+		HedgeAfter: promutils.NewDuration(50 * time.Millisecond),
+	}, http.StatusOK, "ok")
+	if n := okRequests.Load() - okRequestsPrev; n != 1 {
+		t.Fatalf("unexpected number of hedged requests; got %d; want 1", n)
+	}
+}
@@ -30,19 +30,20 @@ func mergeURLs(uiURL, requestURI *url.URL) *url.URL {
 	return &targetURL
 }
 
-func (ui *UserInfo) getURLPrefixAndHeaders(u *url.URL) (*URLPrefix, []Header, error) {
+// getURLPrefixAndHeaders returns url prefix, headers, optional rewrite rules and optional legacy API shim for u.
+func (ui *UserInfo) getURLPrefixAndHeaders(u *url.URL) (*URLPrefix, HeadersConf, *RewriteConfig, legacyAPIShim, error) {
 	for _, e := range ui.URLMaps {
 		for _, sp := range e.SrcPaths {
 			if sp.match(u.Path) {
-				return e.URLPrefix, e.Headers, nil
+				return e.URLPrefix, e.HeadersConf, e.Rewrite, e.legacyAPIShim, nil
 			}
 		}
 	}
 	if ui.URLPrefix != nil {
-		return ui.URLPrefix, ui.Headers, nil
+		return ui.URLPrefix, ui.HeadersConf, nil, nil, nil
 	}
 	missingRouteRequests.Inc()
-	return nil, nil, fmt.Errorf("missing route for %q", u.String())
+	return nil, HeadersConf{}, nil, nil, fmt.Errorf("missing route for %q", u.String())
 }
 
 func normalizeURL(uOrig *url.URL) *url.URL {
@@ -14,17 +14,20 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 			t.Fatalf("cannot parse %q: %s", requestURI, err)
 		}
 		u = normalizeURL(u)
-		up, headers, err := ui.getURLPrefixAndHeaders(u)
+		up, hc, rc, _, err := ui.getURLPrefixAndHeaders(u)
 		if err != nil {
 			t.Fatalf("unexpected error: %s", err)
 		}
+		if rc != nil {
+			u = rc.apply(u)
+		}
 		bu := up.getLeastLoadedBackendURL()
 		target := mergeURLs(bu.url, u)
 		bu.put()
 		if target.String() != expectedTarget {
 			t.Fatalf("unexpected target; got %q; want %q", target, expectedTarget)
 		}
-		headersStr := fmt.Sprintf("%q", headers)
+		headersStr := fmt.Sprintf("%q", hc.RequestHeaders)
 		if headersStr != expectedHeaders {
 			t.Fatalf("unexpected headers; got %s; want %s", headersStr, expectedHeaders)
 		}
@@ -35,11 +38,13 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 	}, "", "http://foo.bar/.", "[]")
 	f(&UserInfo{
 		URLPrefix: mustParseURL("http://foo.bar"),
-		Headers: []Header{{
-			Name:  "bb",
-			Value: "aaa",
-		}},
-	}, "/", "http://foo.bar", `[{"bb" "aaa"}]`)
+		HeadersConf: HeadersConf{
+			RequestHeaders: []Header{{
+				Name:  "bb",
+				Value: "aaa",
+			}},
+		},
+	}, "/", "http://foo.bar", `[{"bb" "aaa" ""}]`)
 	f(&UserInfo{
 		URLPrefix: mustParseURL("http://foo.bar/federate"),
 	}, "/", "http://foo.bar/federate", "[]")
@@ -62,14 +67,16 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 			{
 				SrcPaths:  getSrcPaths([]string{"/api/v1/query"}),
 				URLPrefix: mustParseURL("http://vmselect/0/prometheus"),
-				Headers: []Header{
-					{
-						Name:  "xx",
-						Value: "aa",
-					},
-					{
-						Name:  "yy",
-						Value: "asdf",
+				HeadersConf: HeadersConf{
+					RequestHeaders: []Header{
+						{
+							Name:  "xx",
+							Value: "aa",
+						},
+						{
+							Name:  "yy",
+							Value: "asdf",
+						},
 					},
 				},
 			},
@@ -79,14 +86,16 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 			},
 		},
 		URLPrefix: mustParseURL("http://default-server"),
-		Headers: []Header{{
-			Name:  "bb",
-			Value: "aaa",
-		}},
+		HeadersConf: HeadersConf{
+			RequestHeaders: []Header{{
+				Name:  "bb",
+				Value: "aaa",
+			}},
+		},
 	}
-	f(ui, "/api/v1/query?query=up", "http://vmselect/0/prometheus/api/v1/query?query=up", `[{"xx" "aa"} {"yy" "asdf"}]`)
+	f(ui, "/api/v1/query?query=up", "http://vmselect/0/prometheus/api/v1/query?query=up", `[{"xx" "aa" ""} {"yy" "asdf" ""}]`)
 	f(ui, "/api/v1/write", "http://vminsert/0/prometheus/api/v1/write", "[]")
-	f(ui, "/api/v1/query_range", "http://default-server/api/v1/query_range", `[{"bb" "aaa"}]`)
+	f(ui, "/api/v1/query_range", "http://default-server/api/v1/query_range", `[{"bb" "aaa" ""}]`)
 
 	// Complex routing regexp paths in `url_map`
 	ui = &UserInfo{
@@ -114,6 +123,38 @@ func TestCreateTargetURLSuccess(t *testing.T) {
 		URLPrefix: mustParseURL("http://foo.bar?extra_label=team=mobile"),
 	}, "/api/v1/query?extra_label=team=dev", "http://foo.bar/api/v1/query?extra_label=team%3Dmobile", "[]")
 
+	// Rewriting path and query args with `rewrite` in `url_map`
+	ui = &UserInfo{
+		URLMaps: []URLMap{
+			{
+				SrcPaths:  getSrcPaths([]string{"/legacy/.+"}),
+				URLPrefix: mustParseURL("http://vmselect/0/prometheus"),
+				Rewrite: mustInitRewriteConfig(&RewriteConfig{
+					Path:            "/legacy/(.+)",
+					PathReplacement: "/api/v1/$1",
+					DropQueryArgs:   []string{"token", "extra_label"},
+					AddQueryArgs:    []string{"extra_label=tenant=foo"},
+				}),
+			},
+			{
+				SrcPaths:  getSrcPaths([]string{"/api/v1/write"}),
+				URLPrefix: mustParseURL("http://vminsert/0/prometheus"),
+				Rewrite: mustInitRewriteConfig(&RewriteConfig{
+					AddQueryArgs: []string{"extra_label=team=dev"},
+				}),
+			},
+		},
+	}
+	f(ui, "/legacy/query?query=up&token=secret", "http://vmselect/0/prometheus/api/v1/query?extra_label=tenant%3Dfoo&query=up", "[]")
+	f(ui, "/legacy/query_range?extra_label=tenant=bar", "http://vmselect/0/prometheus/api/v1/query_range?extra_label=tenant%3Dfoo", "[]")
+	f(ui, "/api/v1/write", "http://vminsert/0/prometheus/api/v1/write?extra_label=team%3Ddev", "[]")
+}
+
+func mustInitRewriteConfig(rc *RewriteConfig) *RewriteConfig {
+	if err := rc.init(); err != nil {
+		panic(fmt.Errorf("BUG: cannot init rewrite config: %w", err))
+	}
+	return rc
 }
 
 func TestCreateTargetURLFailure(t *testing.T) {
@@ -124,15 +165,15 @@ func TestCreateTargetURLFailure(t *testing.T) {
 			t.Fatalf("cannot parse %q: %s", requestURI, err)
 		}
 		u = normalizeURL(u)
-		up, headers, err := ui.getURLPrefixAndHeaders(u)
+		up, hc, _, _, err := ui.getURLPrefixAndHeaders(u)
 		if err == nil {
 			t.Fatalf("expecting non-nil error")
 		}
 		if up != nil {
 			t.Fatalf("unexpected non-empty up=%#v", up)
 		}
-		if headers != nil {
-			t.Fatalf("unexpected non-empty headers=%q", headers)
+		if hc.RequestHeaders != nil || hc.ResponseHeaders != nil {
+			t.Fatalf("unexpected non-empty headers=%q", hc)
 		}
 	}
 	f(&UserInfo{}, "/foo/bar")
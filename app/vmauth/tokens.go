@@ -0,0 +1,329 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/metrics"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	issueToken = flag.Bool("issueToken", false, "Whether to issue new API token, print it together with the entry for tokens section of -auth.config and exit. "+
+		"See also -issueToken.id, -issueToken.tenant, -issueToken.permissions and -issueToken.ttl. See https://docs.victoriametrics.com/vmauth.html#api-tokens")
+	issueTokenID          = flag.String("issueToken.id", "", "Unique id for the token issued via -issueToken. It is used for token revocation and it is logged on token usage")
+	issueTokenTenant      = flag.String("issueToken.tenant", "0", "Tenant in the form accountID or accountID:projectID for the token issued via -issueToken")
+	issueTokenPermissions = flag.String("issueToken.permissions", "read", "Comma-separated list of permissions for the token issued via -issueToken. Supported permissions: read, write, admin")
+	issueTokenTTL         = flag.Duration("issueToken.ttl", 0, "Lifetime for the token issued via -issueToken. The token never expires if zero")
+	logTokenUsage         = flag.Bool("logTokenUsage", false, "Whether to log requests authorized with API tokens to the audit log configured via -auditLog.* flags. "+
+		"See https://docs.victoriametrics.com/vmauth.html#api-tokens")
+)
+
+// TokensConfig contains tenant-scoped API tokens.
+type TokensConfig struct {
+	// URLPrefix contains url prefixes for requests authorized with API tokens per each permission.
+	//
+	// `{tenant}` placeholder in url prefixes is substituted with the tenant of the token.
+	URLPrefix TokensURLPrefix `yaml:"url_prefix"`
+
+	// Issued contains the issued tokens.
+	Issued []APIToken `yaml:"issued,omitempty"`
+
+	// Revoked contains ids for revoked tokens.
+	Revoked []string `yaml:"revoked,omitempty"`
+}
+
+// TokensURLPrefix contains url prefixes for requests authorized with API tokens.
+type TokensURLPrefix struct {
+	Read  string `yaml:"read,omitempty"`
+	Write string `yaml:"write,omitempty"`
+	Admin string `yaml:"admin,omitempty"`
+}
+
+// APIToken is an API token bound to a tenant and a set of permissions.
+type APIToken struct {
+	// ID is the unique id of the token.
+	ID string `yaml:"id"`
+
+	// Tenant is the tenant for the token in the form accountID or accountID:projectID.
+	Tenant string `yaml:"tenant"`
+
+	// Permissions contains permissions for the token: read, write and/or admin.
+	Permissions []string `yaml:"permissions"`
+
+	// Hash is sha256 hash for the token in the form `sha256:<hex>`. The token itself isn't stored anywhere.
+	Hash string `yaml:"hash"`
+
+	// ExpiresAt is the optional expiration time for the token in RFC3339 format.
+	ExpiresAt string `yaml:"expires_at,omitempty"`
+
+	permissions map[string]bool
+	expiresAt   time.Time
+	revoked     bool
+}
+
+const (
+	permissionRead  = "read"
+	permissionWrite = "write"
+	permissionAdmin = "admin"
+)
+
+var tenantRe = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// addUsers adds users for the issued tokens in tc to byAuthToken.
+func (tc *TokensConfig) addUsers(byAuthToken map[string]*UserInfo) error {
+	revoked := make(map[string]bool, len(tc.Revoked))
+	for _, id := range tc.Revoked {
+		revoked[id] = true
+	}
+	ids := make(map[string]bool, len(tc.Issued))
+	for i := range tc.Issued {
+		t := &tc.Issued[i]
+		if err := t.init(); err != nil {
+			return fmt.Errorf("invalid token %q: %w", t.ID, err)
+		}
+		if ids[t.ID] {
+			return fmt.Errorf("duplicate token id %q", t.ID)
+		}
+		ids[t.ID] = true
+		t.revoked = revoked[t.ID]
+
+		ui := &UserInfo{
+			Name:     t.ID,
+			apiToken: t,
+		}
+		for _, permission := range []string{permissionAdmin, permissionWrite, permissionRead} {
+			if !t.permissions[permission] {
+				continue
+			}
+			up, err := tc.URLPrefix.get(permission, t.Tenant)
+			if err != nil {
+				return fmt.Errorf("cannot initialize url_prefix for token %q: %w", t.ID, err)
+			}
+			if permission == permissionRead {
+				ui.URLPrefix = up
+				continue
+			}
+			ui.URLMaps = append(ui.URLMaps, URLMap{
+				SrcPaths:  []*SrcPath{getPermissionSrcPath(permission)},
+				URLPrefix: up,
+			})
+		}
+		ui.requests = metrics.GetOrCreateCounter(fmt.Sprintf(`vmauth_user_requests_total{username=%q}`, t.ID))
+		ui.initConcurrencyLimit(t.ID)
+
+		key := getAPITokenKey(t.Hash)
+		if byAuthToken[key] != nil {
+			return fmt.Errorf("duplicate hash for token %q", t.ID)
+		}
+		byAuthToken[key] = ui
+	}
+	return nil
+}
+
+func (up *TokensURLPrefix) get(permission, tenant string) (*URLPrefix, error) {
+	var s string
+	switch permission {
+	case permissionRead:
+		s = up.Read
+	case permissionWrite:
+		s = up.Write
+	case permissionAdmin:
+		s = up.Admin
+	}
+	if s == "" {
+		return nil, fmt.Errorf("missing `url_prefix.%s` for tokens with %q permission", permission, permission)
+	}
+	s = strings.ReplaceAll(s, "{tenant}", tenant)
+	pu, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse `url_prefix.%s`: %w", permission, err)
+	}
+	up1 := &URLPrefix{
+		bus: []*backendURL{{url: pu}},
+	}
+	if err := up1.sanitize(); err != nil {
+		return nil, err
+	}
+	return up1, nil
+}
+
+func (t *APIToken) init() error {
+	if t.ID == "" {
+		return fmt.Errorf("missing `id`")
+	}
+	if !tenantRe.MatchString(t.Tenant) {
+		return fmt.Errorf("`tenant` must be in the form accountID or accountID:projectID; got %q", t.Tenant)
+	}
+	permissions, err := parsePermissions(t.Permissions)
+	if err != nil {
+		return err
+	}
+	t.permissions = permissions
+	if !strings.HasPrefix(t.Hash, "sha256:") {
+		return fmt.Errorf("`hash` must start with `sha256:`; got %q", t.Hash)
+	}
+	if b, err := hex.DecodeString(t.Hash[len("sha256:"):]); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("`hash` must contain hex-encoded sha256 hash; got %q", t.Hash)
+	}
+	if t.ExpiresAt != "" {
+		expiresAt, err := time.Parse(time.RFC3339, t.ExpiresAt)
+		if err != nil {
+			return fmt.Errorf("cannot parse `expires_at`: %w", err)
+		}
+		t.expiresAt = expiresAt
+	}
+	return nil
+}
+
+func parsePermissions(a []string) (map[string]bool, error) {
+	if len(a) == 0 {
+		return nil, fmt.Errorf("`permissions` cannot be empty")
+	}
+	m := make(map[string]bool, len(a))
+	for _, permission := range a {
+		switch permission {
+		case permissionRead, permissionWrite, permissionAdmin:
+			m[permission] = true
+		default:
+			return nil, fmt.Errorf("unsupported permission %q; supported permissions: read, write, admin", permission)
+		}
+	}
+	return m, nil
+}
+
+// checkRequest verifies whether the request to the given path can be authorized with t.
+//
+// It returns http status code for the error if the request cannot be authorized.
+func (t *APIToken) checkRequest(path string, currentTime time.Time) (int, error) {
+	if t.revoked {
+		revokedTokenRequests.Inc()
+		return http.StatusUnauthorized, fmt.Errorf("the token %q is revoked", t.ID)
+	}
+	if !t.expiresAt.IsZero() && currentTime.After(t.expiresAt) {
+		expiredTokenRequests.Inc()
+		return http.StatusUnauthorized, fmt.Errorf("the token %q has been expired at %s", t.ID, t.ExpiresAt)
+	}
+	permission := getPathPermission(path)
+	if !t.permissions[permission] {
+		tokenPermissionDeniedRequests.Inc()
+		return http.StatusForbidden, fmt.Errorf("the token %q has no %q permission required for %q", t.ID, permission, path)
+	}
+	return 0, nil
+}
+
+var (
+	adminPathsRe = `(?:/prometheus)?/api/v1/admin/.*|/internal/.*|/snapshot/.*|/tags/delSeries|/-/reload`
+	writePathsRe = `(?:/prometheus)?/api/v1/(?:write|push|import(?:/.*)?)|/write|/api/v2/write|/influx/(?:api/v2/)?write|` +
+		`/datadog/.*|/opentelemetry/.*|/newrelic/.*|/api/put|/opentsdb/.*`
+
+	adminSrcPath = mustNewSrcPath(adminPathsRe)
+	writeSrcPath = mustNewSrcPath(writePathsRe)
+)
+
+func mustNewSrcPath(s string) *SrcPath {
+	return &SrcPath{
+		sOriginal: s,
+		re:        regexp.MustCompile("^(?:" + s + ")$"),
+	}
+}
+
+func getPermissionSrcPath(permission string) *SrcPath {
+	if permission == permissionAdmin {
+		return adminSrcPath
+	}
+	return writeSrcPath
+}
+
+// getPathPermission returns the permission required for the request to the given path.
+func getPathPermission(path string) string {
+	if adminSrcPath.re.MatchString(path) {
+		return permissionAdmin
+	}
+	if writeSrcPath.re.MatchString(path) {
+		return permissionWrite
+	}
+	return permissionRead
+}
+
+// getAPITokenKey returns the key for the token with the given hash in the map returned by parseAuthConfig.
+func getAPITokenKey(hash string) string {
+	return "APIToken " + hash
+}
+
+func getAPITokenHash(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+// getUserByAPIToken returns the user for API token from authToken.
+//
+// nil is returned if authToken doesn't contain known API token.
+func getUserByAPIToken(ac map[string]*UserInfo, authToken string) *UserInfo {
+	token := strings.TrimPrefix(authToken, "Bearer ")
+	if token == authToken {
+		return nil
+	}
+	return ac[getAPITokenKey(getAPITokenHash(token))]
+}
+
+func logAPITokenUsage(r *http.Request, t *APIToken, path string, statusErr error) {
+	if !*logTokenUsage {
+		return
+	}
+	ae := auditlog.NewEvent(r, "token_usage")
+	ae.User = t.ID
+	ae.Target = t.Tenant
+	ae.SetDetail("permission", getPathPermission(path))
+	auditlog.Log(ae, statusErr)
+}
+
+// issueAPIToken issues new API token according to -issueToken.* flags and writes it to w.
+func issueAPIToken(w io.Writer, currentTime time.Time) error {
+	if *issueTokenID == "" {
+		return fmt.Errorf("missing -issueToken.id")
+	}
+	t := &APIToken{
+		ID:          *issueTokenID,
+		Tenant:      *issueTokenTenant,
+		Permissions: strings.Split(*issueTokenPermissions, ","),
+	}
+	if *issueTokenTTL > 0 {
+		t.ExpiresAt = currentTime.Add(*issueTokenTTL).UTC().Format(time.RFC3339)
+	}
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Errorf("cannot generate random token: %w", err)
+	}
+	token := "vmauth_" + base64.RawURLEncoding.EncodeToString(b[:])
+	t.Hash = getAPITokenHash(token)
+	if err := t.init(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal([]*APIToken{t})
+	if err != nil {
+		return fmt.Errorf("cannot marshal token: %w", err)
+	}
+	fmt.Fprintf(w, "# The token below is shown only once, since it isn't stored anywhere. Pass it to the client in `Authorization: Bearer <token>` request header:\n")
+	fmt.Fprintf(w, "# %s\n", token)
+	fmt.Fprintf(w, "#\n# Add the following entry to `tokens.issued` section of -auth.config and reload vmauth:\n")
+	fmt.Fprintf(w, "%s", data)
+	return nil
+}
+
+var (
+	revokedTokenRequests          = metrics.NewCounter(`vmauth_http_request_errors_total{reason="revoked_token"}`)
+	expiredTokenRequests          = metrics.NewCounter(`vmauth_http_request_errors_total{reason="expired_token"}`)
+	tokenPermissionDeniedRequests = metrics.NewCounter(`vmauth_http_request_errors_total{reason="token_permission_denied"}`)
+)
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testTokenHash = "sha256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"
+
+func TestParseTokensConfigFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		_, err := parseAuthConfig([]byte(s))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Missing id
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - tenant: "1"
+    permissions: [read]
+    hash: ` + testTokenHash)
+
+	// Invalid tenant
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "foo"
+    permissions: [read]
+    hash: ` + testTokenHash)
+
+	// Missing permissions
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    hash: ` + testTokenHash)
+
+	// Unsupported permission
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [delete]
+    hash: ` + testTokenHash)
+
+	// Missing url_prefix for the granted permission
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read, write]
+    hash: ` + testTokenHash)
+
+	// Invalid hash
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: foobar`)
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: sha256:abcd`)
+
+	// Invalid expires_at
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: ` + testTokenHash + `
+    expires_at: tomorrow`)
+
+	// Duplicate id
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: ` + testTokenHash + `
+  - id: foo
+    tenant: "2"
+    permissions: [read]
+    hash: sha256:fcde2b2edba56bf408601fb721fe9b5c338d10ee429ea04fae5511b68fbf8fb9`)
+
+	// Duplicate hash
+	f(`
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: ` + testTokenHash + `
+  - id: bar
+    tenant: "2"
+    permissions: [read]
+    hash: ` + testTokenHash)
+
+	// Invalid url_prefix
+	f(`
+tokens:
+  url_prefix:
+    read: ftp://vmselect/select/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "1"
+    permissions: [read]
+    hash: ` + testTokenHash)
+}
+
+func TestParseTokensConfigSuccess(t *testing.T) {
+	ac, err := parseAuthConfig([]byte(`
+tokens:
+  url_prefix:
+    read: http://vmselect:8481/select/{tenant}/prometheus
+    write: http://vminsert:8480/insert/{tenant}/prometheus
+  issued:
+  - id: foo
+    tenant: "12:34"
+    permissions: [read, write]
+    hash: ` + testTokenHash + `
+    expires_at: "2030-01-02T03:04:05Z"
+  revoked: [bar]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// sha256("foo") equals to testTokenHash
+	ui := getUserByAPIToken(ac, "Bearer foo")
+	if ui == nil {
+		t.Fatalf("cannot find user for the token")
+	}
+	if getUserByAPIToken(ac, "Bearer bar") != nil {
+		t.Fatalf("unexpected user for unknown token")
+	}
+	if getUserByAPIToken(ac, "Basic foo") != nil {
+		t.Fatalf("unexpected user for basic auth")
+	}
+	if ui.name() != "foo" {
+		t.Fatalf("unexpected user name; got %q; want %q", ui.name(), "foo")
+	}
+	tk := ui.apiToken
+	if tk.Tenant != "12:34" {
+		t.Fatalf("unexpected tenant; got %q; want %q", tk.Tenant, "12:34")
+	}
+	if tk.revoked {
+		t.Fatalf("the token mustn't be revoked")
+	}
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	if !tk.expiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected expiresAt; got %s; want %s", tk.expiresAt, expiresAt)
+	}
+
+	f := func(path, targetURLExpected string) {
+		t.Helper()
+		u, err := url.Parse(path)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", path, err)
+		}
+		u = normalizeURL(u)
+		up, _, _, _, err := ui.getURLPrefixAndHeaders(u)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		bu := up.getLeastLoadedBackendURL()
+		targetURL := mergeURLs(bu.url, u).String()
+		bu.put()
+		if targetURL != targetURLExpected {
+			t.Fatalf("unexpected targetURL; got %q; want %q", targetURL, targetURLExpected)
+		}
+	}
+	f("/api/v1/query?query=up", "http://vmselect:8481/select/12:34/prometheus/api/v1/query?query=up")
+	f("/api/v1/write", "http://vminsert:8480/insert/12:34/prometheus/api/v1/write")
+	f("/api/v1/import/prometheus", "http://vminsert:8480/insert/12:34/prometheus/api/v1/import/prometheus")
+}
+
+func TestGetPathPermission(t *testing.T) {
+	f := func(path, permissionExpected string) {
+		t.Helper()
+		permission := getPathPermission(path)
+		if permission != permissionExpected {
+			t.Fatalf("unexpected permission for %q; got %q; want %q", path, permission, permissionExpected)
+		}
+	}
+	f("/api/v1/query", permissionRead)
+	f("/prometheus/api/v1/query_range", permissionRead)
+	f("/api/v1/export", permissionRead)
+	f("/api/v1/series", permissionRead)
+	f("/api/v1/write", permissionWrite)
+	f("/prometheus/api/v1/write", permissionWrite)
+	f("/api/v1/import", permissionWrite)
+	f("/api/v1/import/csv", permissionWrite)
+	f("/influx/write", permissionWrite)
+	f("/datadog/api/v1/series", permissionWrite)
+	f("/opentelemetry/api/v1/push", permissionWrite)
+	f("/api/v1/admin/tsdb/delete_series", permissionAdmin)
+	f("/prometheus/api/v1/admin/tsdb/snapshot", permissionAdmin)
+	f("/internal/force_merge", permissionAdmin)
+	f("/snapshot/create", permissionAdmin)
+	f("/tags/delSeries", permissionAdmin)
+}
+
+func TestAPITokenCheckRequest(t *testing.T) {
+	tk := &APIToken{
+		ID:          "foo",
+		Tenant:      "1",
+		Permissions: []string{"read"},
+		Hash:        testTokenHash,
+		ExpiresAt:   "2030-01-02T03:04:05Z",
+	}
+	if err := tk.init(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := func(path string, currentTime time.Time, statusCodeExpected int) {
+		t.Helper()
+		statusCode, err := tk.checkRequest(path, currentTime)
+		if statusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code for %q; got %d; want %d", path, statusCode, statusCodeExpected)
+		}
+		if (err != nil) != (statusCodeExpected != 0) {
+			t.Fatalf("unexpected error for %q: %v", path, err)
+		}
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	f("/api/v1/query", now, 0)
+	f("/api/v1/write", now, http.StatusForbidden)
+	f("/api/v1/admin/tsdb/delete_series", now, http.StatusForbidden)
+
+	// expired token
+	f("/api/v1/query", time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC), http.StatusUnauthorized)
+
+	// revoked token
+	tk.revoked = true
+	f("/api/v1/query", now, http.StatusUnauthorized)
+}
+
+func TestIssueAPIToken(t *testing.T) {
+	defer func(id, tenant, permissions string, ttl time.Duration) {
+		*issueTokenID = id
+		*issueTokenTenant = tenant
+		*issueTokenPermissions = permissions
+		*issueTokenTTL = ttl
+	}(*issueTokenID, *issueTokenTenant, *issueTokenPermissions, *issueTokenTTL)
+
+	// Missing id
+	var bb bytes.Buffer
+	if err := issueAPIToken(&bb, time.Now()); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+
+	*issueTokenID = "team-a-reader"
+	*issueTokenTenant = "42"
+	*issueTokenPermissions = "read,write"
+	*issueTokenTTL = time.Hour
+	currentTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	bb.Reset()
+	if err := issueAPIToken(&bb, currentTime); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result := bb.String()
+	token := regexp.MustCompile(`(?m)^# (vmauth_\S+)$`).FindStringSubmatch(result)
+	if token == nil {
+		t.Fatalf("cannot find the token in the output:\n%s", result)
+	}
+	if !strings.Contains(result, getAPITokenHash(token[1])) {
+		t.Fatalf("cannot find the token hash in the output:\n%s", result)
+	}
+
+	// The output must be usable in `tokens.issued` section as is.
+	cfg := `
+tokens:
+  url_prefix:
+    read: http://vmselect/select/{tenant}/prometheus
+    write: http://vminsert/insert/{tenant}/prometheus
+  issued:
+` + strings.ReplaceAll("  "+result, "\n", "\n  ")
+	ac, err := parseAuthConfig([]byte(cfg))
+	if err != nil {
+		t.Fatalf("cannot parse config:\n%s\nerror: %s", cfg, err)
+	}
+	ui := getUserByAPIToken(ac, "Bearer "+token[1])
+	if ui == nil {
+		t.Fatalf("cannot find user for the issued token")
+	}
+	tk := ui.apiToken
+	if tk.ID != "team-a-reader" || tk.Tenant != "42" {
+		t.Fatalf("unexpected token: %+v", tk)
+	}
+	if expiresAt := currentTime.Add(time.Hour); !tk.expiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected expiresAt; got %s; want %s", tk.expiresAt, expiresAt)
+	}
+}
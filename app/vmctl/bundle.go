@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cheggaaa/pb/v3"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+)
+
+const (
+	// bundleFileSuffix is the suffix of files written by vmagent to -remoteWrite.bundlePath.
+	bundleFileSuffix = ".native.gz"
+
+	// bundleImportedSuffix is appended to the names of successfully imported bundle files.
+	bundleImportedSuffix = ".imported"
+)
+
+type bundleProcessor struct {
+	// paths contains bundle files and directories with bundle files
+	paths []string
+	// dst performs import requests
+	dst *vmNativeClient
+	// importURL is the url for importing data in native format
+	importURL string
+	// disableProgressBar disables progress bar for imported files
+	disableProgressBar bool
+}
+
+func (bp *bundleProcessor) run(ctx context.Context, silent bool) error {
+	files, err := findBundleFiles(bp.paths)
+	if err != nil {
+		return err
+	}
+	if len(files) < 1 {
+		log.Println("Found no bundle files to import")
+		return nil
+	}
+	question := fmt.Sprintf("Found %d bundle files to import. Continue?", len(files))
+	if !silent && !prompt(question) {
+		return nil
+	}
+
+	var bar *pb.ProgressBar
+	if !bp.disableProgressBar {
+		bar = barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing bundle files"), len(files))
+		if err := barpool.Start(); err != nil {
+			return err
+		}
+		defer barpool.Stop()
+	}
+
+	// Import files sequentially in the order they were created.
+	for _, path := range files {
+		if err := bp.do(ctx, path); err != nil {
+			return fmt.Errorf("failed to import %q: %s", path, err)
+		}
+		if bar != nil {
+			bar.Increment()
+		}
+	}
+	log.Println("Import finished!")
+	return nil
+}
+
+func (bp *bundleProcessor) do(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", bp.importURL, f)
+	if err != nil {
+		return fmt.Errorf("cannot create import request to %q: %s", bp.dst.addr, err)
+	}
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := bp.dst.do(req, http.StatusNoContent)
+	if err != nil {
+		return fmt.Errorf("import request failed: %s", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("cannot close import response body: %s", err)
+	}
+
+	// Mark the file as imported, so it is skipped on the next run.
+	if err := os.Rename(path, path+bundleImportedSuffix); err != nil {
+		return fmt.Errorf("cannot mark file as imported: %s", err)
+	}
+	return nil
+}
+
+// findBundleFiles returns bundle files for the given paths.
+//
+// Directories are scanned for files with bundleFileSuffix. Files in every directory are sorted by name.
+func findBundleFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		des, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var dirFiles []string
+		for _, de := range des {
+			if de.IsDir() || !strings.HasSuffix(de.Name(), bundleFileSuffix) {
+				continue
+			}
+			dirFiles = append(dirFiles, filepath.Join(path, de.Name()))
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBundleProcessor(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"0000000000000002.native.gz":          "second",
+		"0000000000000001.native.gz":          "first",
+		"0000000000000000.native.gz.imported": "imported",
+		"0000000000000003.native.gz.tmp":      "incomplete",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatalf("cannot create file: %s", err)
+		}
+	}
+
+	var mu sync.Mutex
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+nativeImportAddr {
+			t.Errorf("unexpected path; got %q; want %q", r.URL.Path, "/"+nativeImportAddr)
+		}
+		if ce := r.Header.Get("Content-Encoding"); ce != "gzip" {
+			t.Errorf("unexpected Content-Encoding; got %q; want %q", ce, "gzip")
+		}
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(b))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	bp := bundleProcessor{
+		paths:              []string{dir},
+		dst:                &vmNativeClient{addr: srv.URL},
+		importURL:          srv.URL + "/" + nativeImportAddr,
+		disableProgressBar: true,
+	}
+	if err := bp.run(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	bodiesExpected := []string{"first", "second"}
+	if !reflect.DeepEqual(bodies, bodiesExpected) {
+		t.Fatalf("unexpected imported files; got %q; want %q", bodies, bodiesExpected)
+	}
+
+	// Imported files must be skipped on the next run.
+	bodies = nil
+	if err := bp.run(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(bodies) != 0 {
+		t.Fatalf("unexpected files imported on the second run: %q", bodies)
+	}
+}
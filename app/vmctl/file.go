@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/file"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+type fileProcessor struct {
+	// cl reads time series
+	// from file chunks
+	cl *file.Client
+	// im performs import requests
+	// for the time series read from chunks
+	im *vm.Importer
+	// cp tracks the imported chunks.
+	// It may be nil
+	cp *file.Checkpoint
+	// cc stands for concurrency
+	// and defines number of concurrently
+	// imported chunks
+	cc int
+	// batchSize is the maximum number of samples
+	// in a single import request
+	batchSize int
+	// maxRowErrors is the maximum number of rows
+	// with errors per chunk
+	maxRowErrors int
+	// reportPath is an optional path to the file
+	// for reporting rows with errors
+	reportPath string
+
+	reportMu sync.Mutex
+	report   *os.File
+}
+
+func (fp *fileProcessor) run(ctx context.Context, silent bool) error {
+	if fp.cc < 1 {
+		fp.cc = 1
+	}
+	if fp.batchSize < 1 {
+		fp.batchSize = 1e5
+	}
+	var chunks []file.Chunk
+	registered := make(map[string]bool)
+	for _, ch := range fp.cl.Chunks() {
+		if !registered[ch.Path] {
+			if err := fp.cp.Register(ch.Path, fp.cl.Size(ch.Path)); err != nil {
+				return err
+			}
+			registered[ch.Path] = true
+		}
+		if fp.cp.IsDone(ch) {
+			continue
+		}
+		chunks = append(chunks, ch)
+	}
+	if len(chunks) < 1 {
+		log.Println("Found no chunks to import")
+		return nil
+	}
+	question := fmt.Sprintf("Found %d chunks to import. Continue?", len(chunks))
+	if !silent && !prompt(question) {
+		return nil
+	}
+	if fp.reportPath != "" {
+		f, err := os.OpenFile(fp.reportPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("cannot open errors report: %s", err)
+		}
+		fp.report = f
+		defer func() { _ = f.Close() }()
+	}
+
+	bar := barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing chunks"), len(chunks))
+	if err := barpool.Start(); err != nil {
+		return err
+	}
+	defer barpool.Stop()
+
+	chunkCh := make(chan file.Chunk)
+	errCh := make(chan error, fp.cc)
+	fp.im.ResetStats()
+
+	var wg sync.WaitGroup
+	wg.Add(fp.cc)
+	for i := 0; i < fp.cc; i++ {
+		go func() {
+			defer wg.Done()
+			for ch := range chunkCh {
+				if err := fp.do(ctx, ch); err != nil {
+					errCh <- fmt.Errorf("failed to import %s: %s", ch, err)
+					return
+				}
+				bar.Increment()
+			}
+		}()
+	}
+	// any error breaks the import
+	for _, ch := range chunks {
+		select {
+		case err := <-errCh:
+			close(chunkCh)
+			return fmt.Errorf("import process failed: %s", err)
+		case chunkCh <- ch:
+		}
+	}
+
+	close(chunkCh)
+	wg.Wait()
+	fp.im.Close()
+	close(errCh)
+	for err := range errCh {
+		return fmt.Errorf("import process failed: %s", err)
+	}
+
+	log.Println("Import finished!")
+	log.Print(fp.im.Stats())
+	return nil
+}
+
+func (fp *fileProcessor) do(ctx context.Context, ch file.Chunk) error {
+	rowErrs, err := fp.cl.ReadChunk(ctx, ch, fp.batchSize, fp.maxRowErrors, fp.im.ImportBatch)
+	if reportErr := fp.reportRowErrors(ch, rowErrs); reportErr != nil {
+		return reportErr
+	}
+	if err != nil {
+		return err
+	}
+	return fp.cp.MarkDone(ch)
+}
+
+func (fp *fileProcessor) reportRowErrors(ch file.Chunk, rowErrs []*file.RowError) error {
+	if len(rowErrs) == 0 {
+		return nil
+	}
+	if fp.report == nil {
+		log.Printf("%s: skipped %d rows with errors; the first error: %s", ch, len(rowErrs), rowErrs[0])
+		return nil
+	}
+	log.Printf("%s: skipped %d rows with errors; see %q for details", ch, len(rowErrs), fp.reportPath)
+
+	fp.reportMu.Lock()
+	defer fp.reportMu.Unlock()
+	for _, re := range rowErrs {
+		if _, err := fmt.Fprintf(fp.report, "%s: %s\n", ch, re); err != nil {
+			return fmt.Errorf("cannot write errors report: %s", err)
+		}
+	}
+	return nil
+}
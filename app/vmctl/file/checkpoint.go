@@ -0,0 +1,137 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+// Checkpoint tracks the imported chunks, so the interrupted import could be resumed
+// without importing the already imported chunks again.
+//
+// All the methods are safe to call on nil Checkpoint. In this case the progress isn't tracked.
+type Checkpoint struct {
+	path string
+
+	mu    sync.Mutex
+	state checkpointState
+}
+
+type checkpointState struct {
+	ChunkSize int64                      `json:"chunkSize"`
+	Files     map[string]*checkpointFile `json:"files"`
+}
+
+type checkpointFile struct {
+	Size int64 `json:"size"`
+	Done []int `json:"done"`
+}
+
+// OpenCheckpoint opens checkpoint at the given path for the import with the given chunkSize.
+//
+// nil Checkpoint is returned if path is empty.
+func OpenCheckpoint(path string, chunkSize int64) (*Checkpoint, error) {
+	if path == "" {
+		return nil, nil
+	}
+	cp := &Checkpoint{
+		path: path,
+		state: checkpointState{
+			ChunkSize: chunkSize,
+			Files:     make(map[string]*checkpointFile),
+		},
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cp, nil
+		}
+		return nil, fmt.Errorf("cannot read checkpoint: %w", err)
+	}
+	var state checkpointState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("cannot parse checkpoint %q: %w", path, err)
+	}
+	if state.ChunkSize != chunkSize {
+		return nil, fmt.Errorf("checkpoint %q was created for chunk size %d, while the current chunk size is %d; "+
+			"use the same chunk size or remove the checkpoint in order to start the import from scratch", path, state.ChunkSize, chunkSize)
+	}
+	if state.Files != nil {
+		cp.state.Files = state.Files
+	}
+	return cp, nil
+}
+
+// Register registers the file at the given path with the given size in cp.
+//
+// An error is returned if the checkpoint contains the file with another size.
+func (cp *Checkpoint) Register(path string, size int64) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cf := cp.state.Files[path]
+	if cf == nil {
+		cp.state.Files[path] = &checkpointFile{
+			Size: size,
+		}
+		return nil
+	}
+	if cf.Size != size {
+		return fmt.Errorf("file %q has been changed since the checkpoint %q was created: size %d, while the checkpoint has %d; "+
+			"remove the checkpoint in order to start the import from scratch", path, cp.path, size, cf.Size)
+	}
+	return nil
+}
+
+// IsDone returns true if ch has been already imported.
+func (cp *Checkpoint) IsDone(ch Chunk) bool {
+	if cp == nil {
+		return false
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cf := cp.state.Files[ch.Path]
+	if cf == nil {
+		return false
+	}
+	n := sort.SearchInts(cf.Done, ch.Index)
+	return n < len(cf.Done) && cf.Done[n] == ch.Index
+}
+
+// MarkDone marks ch as imported and persists cp.
+func (cp *Checkpoint) MarkDone(ch Chunk) error {
+	if cp == nil {
+		return nil
+	}
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	cf := cp.state.Files[ch.Path]
+	if cf == nil {
+		return fmt.Errorf("BUG: file %q isn't registered in the checkpoint", ch.Path)
+	}
+	n := sort.SearchInts(cf.Done, ch.Index)
+	if n < len(cf.Done) && cf.Done[n] == ch.Index {
+		return nil
+	}
+	cf.Done = append(cf.Done, 0)
+	copy(cf.Done[n+1:], cf.Done[n:])
+	cf.Done[n] = ch.Index
+
+	data, err := json.Marshal(&cp.state)
+	if err != nil {
+		return fmt.Errorf("cannot marshal checkpoint: %w", err)
+	}
+	if err := fs.WriteFileAtomically(cp.path, data, true); err != nil {
+		return fmt.Errorf("cannot save checkpoint: %w", err)
+	}
+	return nil
+}
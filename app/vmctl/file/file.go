@@ -0,0 +1,396 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/valyala/fastjson/fastfloat"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+// Config contains a list of params needed for reading files.
+type Config struct {
+	// Paths to the imported files. Paths starting with `s3://` are read from S3.
+	Paths []string
+	// Schema describes how to map file columns to time series.
+	Schema *Schema
+	// ChunkSize is the size in bytes of file chunks, which are imported in parallel.
+	ChunkSize int64
+	// S3 contains params for reading files from S3.
+	S3 S3Config
+}
+
+// Chunk is a part of the imported file.
+//
+// Chunk contains all the lines, which start at [Start ... End) byte range of the file.
+type Chunk struct {
+	Path  string
+	Index int
+	Start int64
+	End   int64
+}
+
+// String returns human-readable representation of ch.
+func (ch Chunk) String() string {
+	return fmt.Sprintf("%s chunk #%d [%d..%d)", ch.Path, ch.Index, ch.Start, ch.End)
+}
+
+// RowError describes a row, which couldn't be parsed.
+type RowError struct {
+	// Offset is the offset of the row in the file.
+	Offset int64
+	// Err is the parse error.
+	Err error
+}
+
+// Error implements error interface.
+func (re *RowError) Error() string {
+	return fmt.Sprintf("cannot parse row at offset %d: %s", re.Offset, re.Err)
+}
+
+// Client reads time series from files according to Schema.
+type Client struct {
+	schema    *Schema
+	delimiter byte
+	chunkSize int64
+	files     map[string]*fileReader
+	paths     []string
+}
+
+type fileReader struct {
+	src  source
+	cols *columns
+}
+
+// NewClient creates new Client for the given cfg.
+//
+// Close must be called when the client is no longer needed.
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if len(cfg.Paths) == 0 {
+		return nil, fmt.Errorf("at least a single file path must be set")
+	}
+	if cfg.Schema == nil {
+		return nil, fmt.Errorf("schema must be set")
+	}
+	if cfg.ChunkSize <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive; got %d", cfg.ChunkSize)
+	}
+	c := &Client{
+		schema:    cfg.Schema,
+		delimiter: ',',
+		chunkSize: cfg.ChunkSize,
+		files:     make(map[string]*fileReader),
+	}
+	if cfg.Schema.Delimiter != "" {
+		c.delimiter = cfg.Schema.Delimiter[0]
+	}
+	for _, path := range cfg.Paths {
+		if _, ok := c.files[path]; ok {
+			c.Close()
+			return nil, fmt.Errorf("duplicate file path %q", path)
+		}
+		src, err := openSource(ctx, path, cfg.S3)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("cannot open %q: %w", path, err)
+		}
+		fr := &fileReader{
+			src: src,
+		}
+		c.files[path] = fr
+		c.paths = append(c.paths, path)
+
+		var header []string
+		if c.schema.Header {
+			header, err = c.readHeader(ctx, src)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("cannot read header from %q: %w", path, err)
+			}
+		}
+		fr.cols, err = c.schema.resolve(header)
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("cannot apply schema to %q: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+// Close closes all the files opened by c.
+func (c *Client) Close() {
+	for _, fr := range c.files {
+		_ = fr.src.Close()
+	}
+}
+
+// Size returns the size of the file at the given path.
+func (c *Client) Size(path string) int64 {
+	return c.files[path].src.Size()
+}
+
+// Chunks returns chunks for all the files in c.
+func (c *Client) Chunks() []Chunk {
+	var chunks []Chunk
+	for _, path := range c.paths {
+		size := c.Size(path)
+		for i := 0; int64(i)*c.chunkSize < size; i++ {
+			start := int64(i) * c.chunkSize
+			end := start + c.chunkSize
+			if end > size {
+				end = size
+			}
+			chunks = append(chunks, Chunk{
+				Path:  path,
+				Index: i,
+				Start: start,
+				End:   end,
+			})
+		}
+	}
+	return chunks
+}
+
+func (c *Client) readHeader(ctx context.Context, src source) ([]string, error) {
+	r, err := src.Open(ctx, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	line, err := readLine(bufio.NewReader(r), nil)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	s := strings.TrimRight(string(line), "\r\n")
+	if s == "" {
+		return nil, fmt.Errorf("missing header line")
+	}
+	return splitFields(nil, s, c.delimiter)
+}
+
+// ReadChunk reads time series from ch and passes them to callback in batches of up to batchSize samples.
+//
+// Rows, which cannot be parsed, are skipped and returned in RowError list.
+// The reading stops with an error if the number of such rows exceeds maxRowErrors. Zero maxRowErrors means no limit.
+func (c *Client) ReadChunk(ctx context.Context, ch Chunk, batchSize, maxRowErrors int, callback func(tss []*vm.TimeSeries) error) ([]*RowError, error) {
+	fr, ok := c.files[ch.Path]
+	if !ok {
+		return nil, fmt.Errorf("BUG: unknown file %q", ch.Path)
+	}
+	offset := ch.Start
+	if offset > 0 {
+		// Start reading from the previous byte in order to detect whether the chunk starts at the line beginning.
+		offset--
+	}
+	r, err := fr.src.Open(ctx, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = r.Close() }()
+	br := bufio.NewReaderSize(r, 64*1024)
+
+	var buf []byte
+	if ch.Start > 0 || c.schema.Header {
+		// Skip the partial line, which belongs to the previous chunk, or the header line.
+		buf, err = readLine(br, buf[:0])
+		offset += int64(len(buf))
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+
+	var rowErrs []*RowError
+	b := newBatch(fr.cols)
+	var fields []string
+	for offset < ch.End && err != io.EOF {
+		if err := ctx.Err(); err != nil {
+			return rowErrs, err
+		}
+		lineOffset := offset
+		buf, err = readLine(br, buf[:0])
+		if err != nil && err != io.EOF {
+			return rowErrs, err
+		}
+		offset += int64(len(buf))
+		line := strings.TrimRight(string(buf), "\r\n")
+		if line == "" {
+			continue
+		}
+		var rowErr error
+		fields, rowErr = splitFields(fields[:0], line, c.delimiter)
+		if rowErr == nil {
+			rowErr = b.addRow(fields)
+		}
+		if rowErr != nil {
+			rowErrs = append(rowErrs, &RowError{
+				Offset: lineOffset,
+				Err:    rowErr,
+			})
+			if maxRowErrors > 0 && len(rowErrs) > maxRowErrors {
+				return rowErrs, fmt.Errorf("too many rows with errors in %s; the last error: %w", ch, rowErrs[len(rowErrs)-1])
+			}
+			continue
+		}
+		if b.samples >= batchSize {
+			if err := callback(b.series()); err != nil {
+				return rowErrs, err
+			}
+			b.reset()
+		}
+	}
+	if b.samples > 0 {
+		if err := callback(b.series()); err != nil {
+			return rowErrs, err
+		}
+	}
+	return rowErrs, nil
+}
+
+// readLine appends the next line including trailing newline from br to dst.
+func readLine(br *bufio.Reader, dst []byte) ([]byte, error) {
+	for {
+		line, err := br.ReadSlice('\n')
+		dst = append(dst, line...)
+		if err != bufio.ErrBufferFull {
+			return dst, err
+		}
+	}
+}
+
+// splitFields appends fields from the given csv line to dst.
+func splitFields(dst []string, line string, delimiter byte) ([]string, error) {
+	for {
+		if strings.HasPrefix(line, `"`) {
+			field, tail, err := readQuotedField(line)
+			if err != nil {
+				return dst, err
+			}
+			dst = append(dst, field)
+			if tail == "" {
+				return dst, nil
+			}
+			if tail[0] != delimiter {
+				return dst, fmt.Errorf("missing delimiter after quoted field %q", field)
+			}
+			line = tail[1:]
+			continue
+		}
+		n := strings.IndexByte(line, delimiter)
+		if n < 0 {
+			return append(dst, line), nil
+		}
+		dst = append(dst, line[:n])
+		line = line[n+1:]
+	}
+}
+
+func readQuotedField(s string) (string, string, error) {
+	sOrig := s
+	s = s[1:]
+	var b []byte
+	for {
+		n := strings.IndexByte(s, '"')
+		if n < 0 {
+			return "", "", fmt.Errorf("missing closing quote for %q", sOrig)
+		}
+		if n+1 < len(s) && s[n+1] == '"' {
+			// Escaped quote
+			b = append(b, s[:n+1]...)
+			s = s[n+2:]
+			continue
+		}
+		if b == nil {
+			return s[:n], s[n+1:], nil
+		}
+		b = append(b, s[:n]...)
+		return string(b), s[n+1:], nil
+	}
+}
+
+// batch groups samples from rows into time series.
+type batch struct {
+	cols    *columns
+	m       map[string]*vm.TimeSeries
+	tss     []*vm.TimeSeries
+	samples int
+	key     []byte
+}
+
+func newBatch(cols *columns) *batch {
+	return &batch{
+		cols: cols,
+		m:    make(map[string]*vm.TimeSeries),
+	}
+}
+
+func (b *batch) reset() {
+	b.m = make(map[string]*vm.TimeSeries)
+	b.tss = nil
+	b.samples = 0
+}
+
+func (b *batch) series() []*vm.TimeSeries {
+	return b.tss
+}
+
+func (b *batch) addRow(fields []string) error {
+	cols := b.cols
+	if len(fields) <= cols.maxIdx {
+		return fmt.Errorf("too few columns; got %d; want at least %d", len(fields), cols.maxIdx+1)
+	}
+	timestamp, err := cols.parseTimestamp(fields[cols.timestampIdx])
+	if err != nil {
+		return err
+	}
+	// Parse all the values before adding them to the batch, so invalid rows are skipped entirely.
+	values := make([]float64, len(cols.metricIdxs))
+	for i, idx := range cols.metricIdxs {
+		s := fields[idx]
+		if s == "" {
+			continue
+		}
+		v, err := fastfloat.Parse(s)
+		if err != nil {
+			return fmt.Errorf("cannot parse value for metric %q: %w", cols.metricNames[i], err)
+		}
+		values[i] = v
+	}
+	for i, idx := range cols.metricIdxs {
+		if fields[idx] == "" {
+			// Missing value
+			continue
+		}
+		name := cols.metricNames[i]
+		key := append(b.key[:0], name...)
+		for _, labelIdx := range cols.labelIdxs {
+			key = append(key, 0)
+			key = append(key, fields[labelIdx]...)
+		}
+		b.key = key
+		ts := b.m[string(key)]
+		if ts == nil {
+			ts = &vm.TimeSeries{
+				Name: name,
+			}
+			for j, labelIdx := range cols.labelIdxs {
+				if fields[labelIdx] == "" {
+					continue
+				}
+				ts.LabelPairs = append(ts.LabelPairs, vm.LabelPair{
+					Name:  cols.labelNames[j],
+					Value: fields[labelIdx],
+				})
+			}
+			b.m[string(key)] = ts
+			b.tss = append(b.tss, ts)
+		}
+		ts.Timestamps = append(ts.Timestamps, timestamp)
+		ts.Values = append(ts.Values, values[i])
+		b.samples++
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+func TestParseSchemaSuccess(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseSchema([]byte(data)); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	f(`
+timestamp: {column: "1", format: unix_s}
+metrics: [{column: "2", name: foo}]
+`)
+	f(`
+format: csv
+delimiter: ";"
+header: true
+timestamp: {column: ts, format: "custom:2006-01-02"}
+labels: [{column: region}]
+metrics: [{column: revenue, name: kpi_revenue}, {column: cost}]
+`)
+}
+
+func TestParseSchemaFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseSchema([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// unknown field
+	f(`
+foo: bar
+timestamp: {column: "1", format: unix_s}
+metrics: [{column: "2"}]
+`)
+	// unsupported format
+	f(`
+format: parquet
+timestamp: {column: "1", format: unix_s}
+metrics: [{column: "2"}]
+`)
+	// bad delimiter
+	f(`
+delimiter: ";;"
+timestamp: {column: "1", format: unix_s}
+metrics: [{column: "2"}]
+`)
+	// missing timestamp column
+	f(`
+timestamp: {format: unix_s}
+metrics: [{column: "2"}]
+`)
+	// bad timestamp format
+	f(`
+timestamp: {column: "1", format: foo}
+metrics: [{column: "2"}]
+`)
+	// missing metrics
+	f(`
+timestamp: {column: "1", format: unix_s}
+`)
+	// missing label column
+	f(`
+timestamp: {column: "1", format: unix_s}
+labels: [{name: foo}]
+metrics: [{column: "2"}]
+`)
+}
+
+func TestSplitFields(t *testing.T) {
+	f := func(line string, resultExpected []string) {
+		t.Helper()
+		result, err := splitFields(nil, line, ',')
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for %q; got %q; want %q", line, result, resultExpected)
+		}
+	}
+	f("", []string{""})
+	f("a", []string{"a"})
+	f("a,,b", []string{"a", "", "b"})
+	f(`"a,b",c`, []string{"a,b", "c"})
+	f(`"a ""b""",""`, []string{`a "b"`, ""})
+
+	for _, line := range []string{`"a`, `"a"b`} {
+		if _, err := splitFields(nil, line, ','); err == nil {
+			t.Fatalf("expecting non-nil error for %q", line)
+		}
+	}
+}
+
+func TestClientReadChunks(t *testing.T) {
+	data := `ts,region,revenue,cost
+1,eu,10,1
+1,us,20,
+2,eu,11,2
+bad,us,21,2
+2,us,21,3
+
+3,eu,12,x
+3,us,22,4
+`
+	path := filepath.Join(t.TempDir(), "data.csv")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("cannot write file: %s", err)
+	}
+	schema, err := parseSchema([]byte(`
+header: true
+timestamp: {column: ts, format: unix_s}
+labels: [{column: region}]
+metrics: [{column: revenue}, {column: cost, name: total_cost}]
+`))
+	if err != nil {
+		t.Fatalf("cannot parse schema: %s", err)
+	}
+	resultExpected := []string{
+		`revenue{region="eu"} 1000 10`,
+		`revenue{region="eu"} 2000 11`,
+		`revenue{region="us"} 1000 20`,
+		`revenue{region="us"} 2000 21`,
+		`revenue{region="us"} 3000 22`,
+		`total_cost{region="eu"} 1000 1`,
+		`total_cost{region="eu"} 2000 2`,
+		`total_cost{region="us"} 2000 3`,
+		`total_cost{region="us"} 3000 4`,
+	}
+	rowErrOffsetsExpected := []int64{int64(strings.Index(data, "bad")), int64(strings.Index(data, "3,eu"))}
+
+	// The result mustn't depend on the chunk size and batch size
+	for _, chunkSize := range []int64{1, 3, 7, 16, 1024} {
+		for _, batchSize := range []int{1, 3, 1000} {
+			c, err := NewClient(context.Background(), Config{
+				Paths:     []string{path},
+				Schema:    schema,
+				ChunkSize: chunkSize,
+			})
+			if err != nil {
+				t.Fatalf("cannot create client: %s", err)
+			}
+			var result []string
+			var rowErrOffsets []int64
+			for _, ch := range c.Chunks() {
+				rowErrs, err := c.ReadChunk(context.Background(), ch, batchSize, 0, func(tss []*vm.TimeSeries) error {
+					samples := 0
+					for _, ts := range tss {
+						for i := range ts.Timestamps {
+							result = append(result, fmt.Sprintf("%s %d %g", ts, ts.Timestamps[i], ts.Values[i]))
+						}
+						samples += len(ts.Timestamps)
+					}
+					if samples > batchSize+1 {
+						return fmt.Errorf("too big batch; got %d samples; want up to %d samples", samples, batchSize+1)
+					}
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("unexpected error when reading %s: %s", ch, err)
+				}
+				for _, re := range rowErrs {
+					rowErrOffsets = append(rowErrOffsets, re.Offset)
+				}
+			}
+			c.Close()
+			sort.Strings(result)
+			if !reflect.DeepEqual(result, resultExpected) {
+				t.Fatalf("unexpected result for chunkSize=%d, batchSize=%d\ngot\n%s\nwant\n%s",
+					chunkSize, batchSize, strings.Join(result, "\n"), strings.Join(resultExpected, "\n"))
+			}
+			if !reflect.DeepEqual(rowErrOffsets, rowErrOffsetsExpected) {
+				t.Fatalf("unexpected row error offsets for chunkSize=%d; got %d; want %d", chunkSize, rowErrOffsets, rowErrOffsetsExpected)
+			}
+		}
+	}
+
+	// Too many rows with errors
+	c, err := NewClient(context.Background(), Config{
+		Paths:     []string{path},
+		Schema:    schema,
+		ChunkSize: 1024,
+	})
+	if err != nil {
+		t.Fatalf("cannot create client: %s", err)
+	}
+	defer c.Close()
+	chunks := c.Chunks()
+	if len(chunks) != 1 {
+		t.Fatalf("unexpected number of chunks; got %d; want 1", len(chunks))
+	}
+	_, err = c.ReadChunk(context.Background(), chunks[0], 10, 1, func(tss []*vm.TimeSeries) error { return nil })
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	ch := Chunk{Path: "data.csv", Index: 3}
+
+	cp, err := OpenCheckpoint(path, 100)
+	if err != nil {
+		t.Fatalf("cannot open checkpoint: %s", err)
+	}
+	if err := cp.Register(ch.Path, 1000); err != nil {
+		t.Fatalf("cannot register file: %s", err)
+	}
+	if cp.IsDone(ch) {
+		t.Fatalf("chunk mustn't be done")
+	}
+	for _, idx := range []int{3, 1, 5, 3} {
+		if err := cp.MarkDone(Chunk{Path: ch.Path, Index: idx}); err != nil {
+			t.Fatalf("cannot mark chunk as done: %s", err)
+		}
+	}
+
+	// Re-open the checkpoint
+	cp, err = OpenCheckpoint(path, 100)
+	if err != nil {
+		t.Fatalf("cannot open checkpoint: %s", err)
+	}
+	if err := cp.Register(ch.Path, 1000); err != nil {
+		t.Fatalf("cannot register file: %s", err)
+	}
+	for idx := 0; idx < 7; idx++ {
+		isDoneExpected := idx == 1 || idx == 3 || idx == 5
+		if isDone := cp.IsDone(Chunk{Path: ch.Path, Index: idx}); isDone != isDoneExpected {
+			t.Fatalf("unexpected IsDone for chunk #%d; got %v; want %v", idx, isDone, isDoneExpected)
+		}
+	}
+
+	// The file has been changed
+	if err := cp.Register(ch.Path, 1001); err == nil {
+		t.Fatalf("expecting non-nil error for changed file size")
+	}
+	// The chunk size has been changed
+	if _, err := OpenCheckpoint(path, 200); err == nil {
+		t.Fatalf("expecting non-nil error for changed chunk size")
+	}
+
+	// nil checkpoint
+	cp, err = OpenCheckpoint("", 100)
+	if err != nil {
+		t.Fatalf("cannot open checkpoint: %s", err)
+	}
+	if err := cp.Register(ch.Path, 1000); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := cp.MarkDone(ch); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cp.IsDone(ch) {
+		t.Fatalf("nil checkpoint mustn't track chunks")
+	}
+}
@@ -0,0 +1,193 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/csvimport"
+)
+
+// Schema describes how to map file columns to time series.
+//
+// Schema is loaded from YAML file via LoadSchema.
+type Schema struct {
+	// Format is the format of the imported files. Only `csv` is supported at the moment.
+	Format string `yaml:"format,omitempty"`
+	// Delimiter is the csv fields delimiter. By default `,` is used.
+	Delimiter string `yaml:"delimiter,omitempty"`
+	// Header must be set to true if the first line of every file contains column names.
+	Header bool `yaml:"header,omitempty"`
+	// Timestamp describes the column with sample timestamps.
+	Timestamp TimestampColumn `yaml:"timestamp"`
+	// Labels describes columns with label values.
+	Labels []LabelColumn `yaml:"labels,omitempty"`
+	// Metrics describes columns with metric values.
+	Metrics []MetricColumn `yaml:"metrics"`
+}
+
+// TimestampColumn describes the column with timestamps.
+type TimestampColumn struct {
+	// Column is either column name from the header or 1-based column position.
+	Column string `yaml:"column"`
+	// Format is timestamp format. See csvimport.ParseTimeFormat for supported formats.
+	Format string `yaml:"format"`
+}
+
+// LabelColumn describes the column with label values.
+type LabelColumn struct {
+	// Column is either column name from the header or 1-based column position.
+	Column string `yaml:"column"`
+	// Name is label name. By default it equals to Column.
+	Name string `yaml:"name,omitempty"`
+}
+
+// MetricColumn describes the column with metric values.
+type MetricColumn struct {
+	// Column is either column name from the header or 1-based column position.
+	Column string `yaml:"column"`
+	// Name is metric name. By default it equals to Column.
+	Name string `yaml:"name,omitempty"`
+}
+
+// LoadSchema loads schema from the given path.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read schema file: %w", err)
+	}
+	s, err := parseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse schema file %q: %w", path, err)
+	}
+	return s, nil
+}
+
+func parseSchema(data []byte) (*Schema, error) {
+	var s Schema
+	if err := yaml.UnmarshalStrict(data, &s); err != nil {
+		return nil, err
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *Schema) validate() error {
+	switch s.Format {
+	case "", "csv":
+	case "parquet":
+		return fmt.Errorf("parquet format isn't supported yet; convert the files to csv before the import")
+	default:
+		return fmt.Errorf("unsupported format %q; supported values: csv", s.Format)
+	}
+	if len(s.Delimiter) > 1 {
+		return fmt.Errorf("delimiter must contain a single char; got %q", s.Delimiter)
+	}
+	if s.Timestamp.Column == "" {
+		return fmt.Errorf("missing `timestamp.column`")
+	}
+	if _, err := csvimport.ParseTimeFormat(s.Timestamp.Format); err != nil {
+		return fmt.Errorf("cannot parse `timestamp.format`: %w", err)
+	}
+	if len(s.Metrics) == 0 {
+		return fmt.Errorf("at least a single entry must be set in `metrics`")
+	}
+	for i, lc := range s.Labels {
+		if lc.Column == "" {
+			return fmt.Errorf("missing `column` in `labels` entry #%d", i+1)
+		}
+	}
+	for i, mc := range s.Metrics {
+		if mc.Column == "" {
+			return fmt.Errorf("missing `column` in `metrics` entry #%d", i+1)
+		}
+	}
+	return nil
+}
+
+// columns contains resolved column positions for the Schema.
+type columns struct {
+	parseTimestamp func(s string) (int64, error)
+	timestampIdx   int
+
+	labelIdxs  []int
+	labelNames []string
+
+	metricIdxs  []int
+	metricNames []string
+
+	maxIdx int
+}
+
+// resolve resolves column positions for s using the given header.
+//
+// header may be empty if s.Header isn't set.
+func (s *Schema) resolve(header []string) (*columns, error) {
+	parseTimestamp, err := csvimport.ParseTimeFormat(s.Timestamp.Format)
+	if err != nil {
+		return nil, err
+	}
+	cs := &columns{
+		parseTimestamp: parseTimestamp,
+	}
+	idx, err := resolveColumn(s.Timestamp.Column, header)
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve `timestamp` column: %w", err)
+	}
+	cs.timestampIdx = idx
+	cs.maxIdx = idx
+	for _, lc := range s.Labels {
+		idx, err := resolveColumn(lc.Column, header)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve label column: %w", err)
+		}
+		name := lc.Name
+		if name == "" {
+			name = lc.Column
+		}
+		cs.labelIdxs = append(cs.labelIdxs, idx)
+		cs.labelNames = append(cs.labelNames, name)
+		if idx > cs.maxIdx {
+			cs.maxIdx = idx
+		}
+	}
+	for _, mc := range s.Metrics {
+		idx, err := resolveColumn(mc.Column, header)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve metric column: %w", err)
+		}
+		name := mc.Name
+		if name == "" {
+			name = mc.Column
+		}
+		cs.metricIdxs = append(cs.metricIdxs, idx)
+		cs.metricNames = append(cs.metricNames, name)
+		if idx > cs.maxIdx {
+			cs.maxIdx = idx
+		}
+	}
+	return cs, nil
+}
+
+func resolveColumn(column string, header []string) (int, error) {
+	for i, name := range header {
+		if name == column {
+			return i, nil
+		}
+	}
+	n, err := strconv.Atoi(column)
+	if err != nil {
+		if len(header) == 0 {
+			return 0, fmt.Errorf("column %q must be a number, since `header` isn't set in schema", column)
+		}
+		return 0, fmt.Errorf("cannot find column %q in the header %q", column, header)
+	}
+	if n < 1 {
+		return 0, fmt.Errorf("column position must be bigger than 0; got %d", n)
+	}
+	return n - 1, nil
+}
@@ -0,0 +1,152 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Config contains params for reading files from S3.
+type S3Config struct {
+	// CustomEndpoint is set for using S3-compatible endpoint such as MinIO etc.
+	CustomEndpoint string
+	// ForcePathStyle forces using path style for s3 when CustomEndpoint is set.
+	ForcePathStyle bool
+	// ProfileName is the name of S3 config profile to use.
+	ProfileName string
+}
+
+// source provides random access to the imported file.
+type source interface {
+	// Size returns the file size in bytes.
+	Size() int64
+	// Open returns a reader for the file contents starting from the given offset.
+	Open(ctx context.Context, offset int64) (io.ReadCloser, error)
+	// Close closes the source.
+	Close() error
+}
+
+func openSource(ctx context.Context, path string, s3Cfg S3Config) (source, error) {
+	if strings.HasPrefix(path, "s3://") {
+		return openS3Source(ctx, path, s3Cfg)
+	}
+	return openLocalSource(path)
+}
+
+type localSource struct {
+	f    *os.File
+	size int64
+}
+
+func openLocalSource(path string) (*localSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &localSource{
+		f:    f,
+		size: fi.Size(),
+	}, nil
+}
+
+func (ls *localSource) Size() int64 { return ls.size }
+
+func (ls *localSource) Open(_ context.Context, offset int64) (io.ReadCloser, error) {
+	return io.NopCloser(io.NewSectionReader(ls.f, offset, ls.size-offset)), nil
+}
+
+func (ls *localSource) Close() error { return ls.f.Close() }
+
+type s3Source struct {
+	s3     *s3.Client
+	bucket string
+	key    string
+	size   int64
+}
+
+func openS3Source(ctx context.Context, path string, cfg S3Config) (*s3Source, error) {
+	n := strings.IndexByte(path[len("s3://"):], '/')
+	if n <= 0 {
+		return nil, fmt.Errorf("path %q must have the form s3://bucket/key", path)
+	}
+	bucket := path[len("s3://") : len("s3://")+n]
+	key := path[len("s3://")+n+1:]
+	if key == "" {
+		return nil, fmt.Errorf("missing object key in path %q", path)
+	}
+
+	client, err := NewS3Client(ctx, bucket, cfg)
+	if err != nil {
+		return nil, err
+	}
+	out, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain size for %q: %w", path, err)
+	}
+	return &s3Source{
+		s3:     client,
+		bucket: bucket,
+		key:    key,
+		size:   out.ContentLength,
+	}, nil
+}
+
+// NewS3Client returns S3 client for accessing the given bucket with the given cfg.
+func NewS3Client(ctx context.Context, bucket string, cfg S3Config) (*s3.Client, error) {
+	awsCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithSharedConfigProfile(cfg.ProfileName),
+		config.WithDefaultRegion("us-east-1"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load S3 config: %w", err)
+	}
+	var outerErr error
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if len(cfg.CustomEndpoint) > 0 {
+			o.UsePathStyle = cfg.ForcePathStyle
+			o.EndpointResolver = s3.EndpointResolverFromURL(cfg.CustomEndpoint)
+			return
+		}
+		region, err := manager.GetBucketRegion(ctx, s3.NewFromConfig(awsCfg), bucket)
+		if err != nil {
+			outerErr = fmt.Errorf("cannot determine region for bucket %q: %w", bucket, err)
+			return
+		}
+		o.Region = region
+	})
+	if outerErr != nil {
+		return nil, outerErr
+	}
+	return client, nil
+}
+
+func (ss *s3Source) Size() int64 { return ss.size }
+
+func (ss *s3Source) Open(ctx context.Context, offset int64) (io.ReadCloser, error) {
+	out, err := ss.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(ss.bucket),
+		Key:    aws.String(ss.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-", offset)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot read s3://%s/%s at offset %d: %w", ss.bucket, ss.key, offset, err)
+	}
+	return out.Body, nil
+}
+
+func (ss *s3Source) Close() error { return nil }
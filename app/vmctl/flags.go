@@ -424,6 +424,9 @@ const (
 	remoteReadHTTPTimeout        = "remote-read-http-timeout"
 	remoteReadHeaders            = "remote-read-headers"
 	remoteReadInsecureSkipVerify = "remote-read-insecure-skip-verify"
+	remoteReadFollow             = "remote-read-follow"
+	remoteReadFollowInterval     = "remote-read-follow-interval"
+	remoteReadFollowLag          = "remote-read-follow-lag"
 )
 
 var (
@@ -499,6 +502,209 @@ var (
 			Usage: "Whether to skip TLS certificate verification when connecting to the remote read address",
 			Value: false,
 		},
+		&cli.BoolFlag{
+			Name: remoteReadFollow,
+			Usage: fmt.Sprintf("Whether to continue reading new data from the remote read address after the selected time range is imported. "+
+				"This allows switching writes to VictoriaMetrics without data gaps. Stop vmctl with Ctrl+C after the switch. "+
+				"Cannot be used together with --%s. See https://docs.victoriametrics.com/vmctl.html#live-migration", remoteReadFilterTimeEnd),
+			Value: false,
+		},
+		&cli.DurationFlag{
+			Name:  remoteReadFollowInterval,
+			Usage: fmt.Sprintf("The interval for reading new data from the remote read address if --%s is set", remoteReadFollow),
+			Value: 30 * time.Second,
+		},
+		&cli.DurationFlag{
+			Name: remoteReadFollowLag,
+			Usage: fmt.Sprintf("The delay for reading new data from the remote read address if --%s is set. "+
+				"It must cover the maximum delay for samples ingested into the remote read source", remoteReadFollow),
+			Value: time.Minute,
+		},
+	}
+)
+
+const (
+	fileSchema           = "file-schema"
+	filePath             = "file-path"
+	fileChunkSize        = "file-chunk-size"
+	fileConcurrency      = "file-concurrency"
+	fileCheckpoint       = "file-checkpoint"
+	fileErrorsReport     = "file-errors-report"
+	fileMaxRowErrors     = "file-max-row-errors-per-chunk"
+	fileS3Endpoint       = "file-s3-endpoint"
+	fileS3ForcePathStyle = "file-s3-force-path-style"
+	fileS3ConfigProfile  = "file-s3-config-profile"
+)
+
+var (
+	fileFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name:     fileSchema,
+			Usage:    "Path to YAML file with the schema, which maps file columns to timestamps, labels and metric values. See https://docs.victoriametrics.com/vmctl.html#migrating-data-from-files",
+			Required: true,
+		},
+		&cli.StringSliceFlag{
+			Name: filePath,
+			Usage: "Path to the file to import. Paths starting with 's3://' are read from S3, e.g. 's3://bucket/path/to/file.csv'. \n" +
+				"Flag can be set multiple times for importing multiple files",
+			Required: true,
+		},
+		&cli.Int64Flag{
+			Name:  fileChunkSize,
+			Usage: "The size in bytes of file chunks, which are read and imported in parallel",
+			Value: 64 * 1024 * 1024,
+		},
+		&cli.IntFlag{
+			Name:  fileConcurrency,
+			Usage: "Number of concurrently imported file chunks",
+			Value: 1,
+		},
+		&cli.StringFlag{
+			Name: fileCheckpoint,
+			Usage: "Optional path to the file for storing the list of imported chunks. \n" +
+				"If the import is interrupted, then the next run with the same checkpoint skips the already imported chunks",
+		},
+		&cli.StringFlag{
+			Name:  fileErrorsReport,
+			Usage: "Optional path to the file for storing rows, which couldn't be parsed. By default such rows are only counted in logs",
+		},
+		&cli.IntFlag{
+			Name:  fileMaxRowErrors,
+			Usage: "The maximum number of rows, which couldn't be parsed, per each chunk. The import stops when the limit is exceeded. Zero value disables the limit",
+			Value: 100,
+		},
+		&cli.StringFlag{
+			Name:  fileS3Endpoint,
+			Usage: "Custom S3 endpoint for use with S3-compatible storages (e.g. MinIO). S3 is used if not set",
+		},
+		&cli.BoolFlag{
+			Name:  fileS3ForcePathStyle,
+			Usage: "Prefixing endpoint with bucket name when set false, true by default.",
+			Value: true,
+		},
+		&cli.StringFlag{
+			Name:  fileS3ConfigProfile,
+			Usage: "Profile name for S3 configs. If no set, the value of the environment variable will be loaded (AWS_PROFILE or AWS_DEFAULT_PROFILE), or if both not set, DefaultSharedConfigProfile is used",
+		},
+	}
+)
+
+const (
+	thanosBucket              = "thanos-bucket"
+	thanosConcurrency         = "thanos-concurrency"
+	thanosResolution          = "thanos-resolution"
+	thanosAggrType            = "thanos-aggr-type"
+	thanosTenantLabel         = "thanos-tenant-label"
+	thanosTenantMap           = "thanos-tenant-map"
+	thanosDropExternalLabels  = "thanos-drop-external-labels"
+	thanosTmpDir              = "thanos-tmp-dir"
+	thanosFilterTimeStart     = "thanos-filter-time-start"
+	thanosFilterTimeEnd       = "thanos-filter-time-end"
+	thanosFilterLabel         = "thanos-filter-label"
+	thanosFilterLabelValue    = "thanos-filter-label-value"
+	thanosS3Endpoint          = "thanos-s3-endpoint"
+	thanosS3ForcePathStyle    = "thanos-s3-force-path-style"
+	thanosS3ConfigProfile     = "thanos-s3-config-profile"
+	thanosMaxRequestSizeBytes = "thanos-max-request-size-bytes"
+)
+
+var (
+	thanosFlags = []cli.Flag{
+		&cli.StringFlag{
+			Name: thanosBucket,
+			Usage: "Path to the directory with Thanos blocks or to the Thanos bucket in S3, e.g. 's3://bucket/optional/prefix'. \n" +
+				"Every block must be stored in a separate directory named by block ULID as Thanos does",
+			Required: true,
+		},
+		&cli.IntFlag{
+			Name:  thanosConcurrency,
+			Usage: "Number of concurrently imported blocks",
+			Value: 1,
+		},
+		&cli.StringSliceFlag{
+			Name: thanosResolution,
+			Usage: "Resolution of blocks to import. Supported values: 'raw', '5m' and '1h'. \n" +
+				"Flag can be set multiple times for importing blocks with multiple resolutions",
+			Value: cli.NewStringSlice("raw"),
+		},
+		&cli.StringSliceFlag{
+			Name: thanosAggrType,
+			Usage: "Aggregate to import from downsampled blocks. Supported values: 'count', 'sum', 'min', 'max', 'counter' and 'avg'. \n" +
+				"Series from downsampled blocks are imported with ':<resolution>_<aggr>' suffix in metric name, e.g. 'up:5m_avg'. \n" +
+				"Flag can be set multiple times for importing multiple aggregates",
+			Value: cli.NewStringSlice("avg"),
+		},
+		&cli.StringFlag{
+			Name: thanosTenantLabel,
+			Usage: "Optional name of the external label, which value is used as tenant for importing the block into the clustered version of VictoriaMetrics. \n" +
+				"The value must be in the form accountID[:projectID] unless mapped via --" + thanosTenantMap + ". \n" +
+				"Blocks without this label are imported into the tenant set via --" + vmAccountID,
+		},
+		&cli.StringSliceFlag{
+			Name: thanosTenantMap,
+			Usage: "Optional mapping of --" + thanosTenantLabel + " values to tenants in the form 'value=accountID[:projectID]', e.g. 'team-a=1:0'. \n" +
+				"Flag can be set multiple times. Blocks with unmapped label values aren't imported if the mapping is set",
+		},
+		&cli.BoolFlag{
+			Name:  thanosDropExternalLabels,
+			Usage: "Whether to drop external labels of blocks. By default external labels are added to every imported series",
+			Value: false,
+		},
+		&cli.StringFlag{
+			Name:  thanosTmpDir,
+			Usage: "Directory for temporary storing blocks fetched from S3. By default the OS temporary directory is used",
+		},
+		&cli.StringFlag{
+			Name:  thanosFilterTimeStart,
+			Usage: "The time filter in RFC3339 format to select timeseries with timestamp equal or higher than provided value. E.g. '2020-01-01T20:07:00Z'",
+		},
+		&cli.StringFlag{
+			Name:  thanosFilterTimeEnd,
+			Usage: "The time filter in RFC3339 format to select timeseries with timestamp equal or lower than provided value. E.g. '2020-01-01T20:07:00Z'",
+		},
+		&cli.StringFlag{
+			Name:  thanosFilterLabel,
+			Usage: "Prometheus label name to filter timeseries by. E.g. '__name__' will filter timeseries by name.",
+			Value: "__name__",
+		},
+		&cli.StringFlag{
+			Name:  thanosFilterLabelValue,
+			Usage: fmt.Sprintf("Prometheus regular expression to filter label from %q flag.", thanosFilterLabel),
+			Value: ".*",
+		},
+		&cli.StringFlag{
+			Name:  thanosS3Endpoint,
+			Usage: "Custom S3 endpoint for use with S3-compatible storages (e.g. MinIO). S3 is used if not set",
+		},
+		&cli.BoolFlag{
+			Name:  thanosS3ForcePathStyle,
+			Usage: "Prefixing endpoint with bucket name when set false, true by default.",
+			Value: true,
+		},
+		&cli.StringFlag{
+			Name:  thanosS3ConfigProfile,
+			Usage: "Profile name for S3 configs. If no set, the value of the environment variable will be loaded (AWS_PROFILE or AWS_DEFAULT_PROFILE), or if both not set, DefaultSharedConfigProfile is used",
+		},
+		&cli.Int64Flag{
+			Name:  thanosMaxRequestSizeBytes,
+			Usage: "The maximum size in bytes of a single import request in native format",
+			Value: 32 << 20,
+		},
+	}
+)
+
+const (
+	bundlePath = "bundle-path"
+)
+
+var (
+	bundleFlags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name: bundlePath,
+			Usage: "Path to the bundle file or to the directory with bundle files written by vmagent to -remoteWrite.bundlePath. \n" +
+				"Flag can be set multiple times for importing multiple files or directories",
+			Required: true,
+		},
 	}
 )
 
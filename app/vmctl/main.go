@@ -14,9 +14,11 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/remoteread"
 	"github.com/urfave/cli/v2"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/file"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/influx"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/opentsdb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/prometheus"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/thanos"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/buildinfo"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -150,7 +152,10 @@ func main() {
 							timeEnd:   c.Timestamp(remoteReadFilterTimeEnd),
 							chunk:     c.String(remoteReadStepInterval),
 						},
-						cc: c.Int(remoteReadConcurrency),
+						cc:             c.Int(remoteReadConcurrency),
+						follow:         c.Bool(remoteReadFollow),
+						followInterval: c.Duration(remoteReadFollowInterval),
+						followLag:      c.Duration(remoteReadFollowLag),
 					}
 					return rmp.run(ctx, c.Bool(globalSilent), c.Bool(globalVerbose))
 				},
@@ -189,6 +194,160 @@ func main() {
 					return pp.run(c.Bool(globalSilent), c.Bool(globalVerbose))
 				},
 			},
+			{
+				Name:  "thanos",
+				Usage: "Migrate time series from Thanos blocks stored in object storage bucket layout",
+				Flags: mergeFlags(globalFlags, thanosFlags, vmFlags),
+				Action: func(c *cli.Context) error {
+					fmt.Println("Thanos import mode")
+
+					var resolutions []time.Duration
+					for _, s := range c.StringSlice(thanosResolution) {
+						r, err := parseThanosResolution(s)
+						if err != nil {
+							return fmt.Errorf("invalid %s: %s", thanosResolution, err)
+						}
+						resolutions = append(resolutions, r)
+					}
+					var aggrTypes []thanos.AggrType
+					for _, s := range c.StringSlice(thanosAggrType) {
+						at, err := thanos.ParseAggrType(s)
+						if err != nil {
+							return fmt.Errorf("invalid %s: %s", thanosAggrType, err)
+						}
+						aggrTypes = append(aggrTypes, at)
+					}
+					tenantMap, err := parseThanosTenantMap(c.StringSlice(thanosTenantMap))
+					if err != nil {
+						return fmt.Errorf("invalid %s: %s", thanosTenantMap, err)
+					}
+					if len(tenantMap) > 0 && c.String(thanosTenantLabel) == "" {
+						return fmt.Errorf("flag %q must be set when %q is set", thanosTenantLabel, thanosTenantMap)
+					}
+
+					tCfg := thanos.Config{
+						Bucket: c.String(thanosBucket),
+						S3: file.S3Config{
+							CustomEndpoint: c.String(thanosS3Endpoint),
+							ForcePathStyle: c.Bool(thanosS3ForcePathStyle),
+							ProfileName:    c.String(thanosS3ConfigProfile),
+						},
+						TmpDir:      c.String(thanosTmpDir),
+						Resolutions: resolutions,
+						AggrTypes:   aggrTypes,
+						Filter: thanos.Filter{
+							TimeMin:    c.String(thanosFilterTimeStart),
+							TimeMax:    c.String(thanosFilterTimeEnd),
+							Label:      c.String(thanosFilterLabel),
+							LabelValue: c.String(thanosFilterLabelValue),
+						},
+					}
+					cl, err := thanos.NewClient(ctx, tCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create thanos client: %s", err)
+					}
+					tp := thanosProcessor{
+						cl: cl,
+						dst: &vmNativeClient{
+							addr:     strings.Trim(c.String(vmAddr), "/"),
+							user:     c.String(vmUser),
+							password: c.String(vmPassword),
+						},
+						defaultTenant:      c.String(vmAccountID),
+						tenantLabel:        c.String(thanosTenantLabel),
+						tenantMap:          tenantMap,
+						dropExternalLabels: c.Bool(thanosDropExternalLabels),
+						extraLabels:        c.StringSlice(vmExtraLabel),
+						maxRequestSize:     int(c.Int64(thanosMaxRequestSizeBytes)),
+						cc:                 c.Int(thanosConcurrency),
+						disableProgressBar: c.Bool(vmDisableProgressBar),
+					}
+					return tp.run(ctx, c.Bool(globalSilent))
+				},
+			},
+			{
+				Name:  "file",
+				Usage: "Import time series from CSV files",
+				Flags: mergeFlags(globalFlags, fileFlags, vmFlags),
+				Action: func(c *cli.Context) error {
+					fmt.Println("File import mode")
+
+					schema, err := file.LoadSchema(c.String(fileSchema))
+					if err != nil {
+						return fmt.Errorf("failed to load schema: %s", err)
+					}
+					fCfg := file.Config{
+						Paths:     c.StringSlice(filePath),
+						Schema:    schema,
+						ChunkSize: c.Int64(fileChunkSize),
+						S3: file.S3Config{
+							CustomEndpoint: c.String(fileS3Endpoint),
+							ForcePathStyle: c.Bool(fileS3ForcePathStyle),
+							ProfileName:    c.String(fileS3ConfigProfile),
+						},
+					}
+					fileClient, err := file.NewClient(ctx, fCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create file client: %s", err)
+					}
+					defer fileClient.Close()
+
+					cp, err := file.OpenCheckpoint(c.String(fileCheckpoint), fCfg.ChunkSize)
+					if err != nil {
+						return fmt.Errorf("failed to open checkpoint: %s", err)
+					}
+
+					vmCfg := initConfigVM(c)
+					// disable progress bars since file chunks
+					// are imported synchronously by file workers
+					vmCfg.DisableProgressBar = true
+					importer, err = vm.NewImporter(vmCfg)
+					if err != nil {
+						return fmt.Errorf("failed to create VM importer: %s", err)
+					}
+
+					fp := fileProcessor{
+						cl:           fileClient,
+						im:           importer,
+						cp:           cp,
+						cc:           c.Int(fileConcurrency),
+						batchSize:    vmCfg.BatchSize,
+						maxRowErrors: c.Int(fileMaxRowErrors),
+						reportPath:   c.String(fileErrorsReport),
+					}
+					return fp.run(ctx, c.Bool(globalSilent))
+				},
+			},
+			{
+				Name:  "bundle",
+				Usage: "Import bundle files written by vmagent in offline bundling mode",
+				Flags: mergeFlags(globalFlags, bundleFlags, vmFlags),
+				Action: func(c *cli.Context) error {
+					fmt.Println("Bundle import mode")
+
+					addr := strings.Trim(c.String(vmAddr), "/")
+					importURL := fmt.Sprintf("%s/%s", addr, nativeImportAddr)
+					if accountID := c.String(vmAccountID); accountID != "" {
+						// see https://docs.victoriametrics.com/Cluster-VictoriaMetrics.html#url-format
+						importURL = fmt.Sprintf("%s/insert/%s/prometheus/%s", addr, accountID, nativeImportAddr)
+					}
+					importURL, err := vm.AddExtraLabelsToImportPath(importURL, c.StringSlice(vmExtraLabel))
+					if err != nil {
+						return fmt.Errorf("failed to add labels to import path: %s", err)
+					}
+					bp := bundleProcessor{
+						paths: c.StringSlice(bundlePath),
+						dst: &vmNativeClient{
+							addr:     addr,
+							user:     c.String(vmUser),
+							password: c.String(vmPassword),
+						},
+						importURL:          importURL,
+						disableProgressBar: c.Bool(vmDisableProgressBar),
+					}
+					return bp.run(ctx, c.Bool(globalSilent))
+				},
+			},
 			{
 				Name:  "vm-native",
 				Usage: "Migrate time series between VictoriaMetrics installations via native binary format",
@@ -237,7 +396,10 @@ func main() {
 				Action: func(c *cli.Context) error {
 					common.StartUnmarshalWorkers()
 					blockPath := c.Args().First()
-					isBlockGzipped := c.Bool("gunzip")
+					contentEncoding := ""
+					if c.Bool("gunzip") {
+						contentEncoding = "gzip"
+					}
 					if len(blockPath) == 0 {
 						return cli.Exit("you must provide path for exported data block", 1)
 					}
@@ -247,7 +409,7 @@ func main() {
 						return cli.Exit(fmt.Errorf("cannot open exported block at path=%q err=%w", blockPath, err), 1)
 					}
 					var blocksCount uint64
-					if err := stream.Parse(f, isBlockGzipped, func(block *stream.Block) error {
+					if err := stream.Parse(f, contentEncoding, func(block *stream.Block) error {
 						atomic.AddUint64(&blocksCount, 1)
 						return nil
 					}); err != nil {
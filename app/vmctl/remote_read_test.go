@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
@@ -317,3 +318,53 @@ func TestSteamRemoteRead(t *testing.T) {
 		})
 	}
 }
+
+func TestDropSamplesFrom(t *testing.T) {
+	f := func(timestamps []int64, maxTimestamp int64, timestampsExpected []int64) {
+		t.Helper()
+		series := &vm.TimeSeries{
+			Name:       "foo",
+			Timestamps: append([]int64{}, timestamps...),
+			Values:     make([]float64, len(timestamps)),
+		}
+		for i, ts := range timestamps {
+			series.Values[i] = float64(ts)
+		}
+		dropSamplesFrom(series, maxTimestamp)
+		if !reflect.DeepEqual(series.Timestamps, timestampsExpected) {
+			t.Fatalf("unexpected timestamps; got %v; want %v", series.Timestamps, timestampsExpected)
+		}
+		for i, ts := range series.Timestamps {
+			if series.Values[i] != float64(ts) {
+				t.Fatalf("unexpected value for timestamp %d; got %v; want %v", ts, series.Values[i], float64(ts))
+			}
+		}
+	}
+	f([]int64{}, 10, []int64{})
+	f([]int64{1, 5, 9}, 10, []int64{1, 5, 9})
+	f([]int64{1, 5, 10}, 10, []int64{1, 5})
+	f([]int64{10, 11}, 10, []int64{})
+}
+
+func TestRemoteReadFollowInvalidConfig(t *testing.T) {
+	f := func(rrp remoteReadProcessor) {
+		t.Helper()
+		if err := rrp.run(context.Background(), true, false); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	end := time.Now()
+	// follow mode with time end
+	f(remoteReadProcessor{
+		filter:         remoteReadFilter{timeStart: &start, timeEnd: &end, chunk: stepper.StepHour},
+		follow:         true,
+		followInterval: time.Second,
+	})
+
+	// zero follow interval
+	f(remoteReadProcessor{
+		filter: remoteReadFilter{timeStart: &start, chunk: stepper.StepHour},
+		follow: true,
+	})
+}
@@ -21,6 +21,14 @@ type remoteReadProcessor struct {
 	src *remoteread.Client
 
 	cc int
+
+	// follow enables tailing new data from src after the selected time range is imported.
+	follow bool
+	// followInterval is the interval for reading new data from src in follow mode.
+	followInterval time.Duration
+	// followLag is the delay for reading new data from src in follow mode.
+	// It gives src time for receiving delayed samples.
+	followLag time.Duration
 }
 
 type remoteReadFilter struct {
@@ -30,6 +38,16 @@ type remoteReadFilter struct {
 }
 
 func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) error {
+	if rrp.follow {
+		if rrp.filter.timeEnd != nil {
+			return fmt.Errorf("--%s cannot be used together with --%s", remoteReadFollow, remoteReadFilterTimeEnd)
+		}
+		if rrp.followInterval <= 0 {
+			return fmt.Errorf("--%s must be positive; got %s", remoteReadFollowInterval, rrp.followInterval)
+		}
+		t := time.Now().Add(-rrp.followLag).In(rrp.filter.timeStart.Location())
+		rrp.filter.timeEnd = &t
+	}
 	rrp.dst.ResetStats()
 	if rrp.filter.timeEnd == nil {
 		t := time.Now().In(rrp.filter.timeStart.Location())
@@ -46,6 +64,9 @@ func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) e
 
 	question := fmt.Sprintf("Selected time range %q - %q will be split into %d ranges according to %q step. Continue?",
 		rrp.filter.timeStart.String(), rrp.filter.timeEnd.String(), len(ranges), rrp.filter.chunk)
+	if rrp.follow {
+		question += fmt.Sprintf(" New data will be read every %s with %s lag after that until interrupted.", rrp.followInterval, rrp.followLag)
+	}
 	if !silent && !prompt(question) {
 		return nil
 	}
@@ -66,7 +87,8 @@ func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) e
 	}()
 
 	rangeC := make(chan *remoteread.Filter)
-	errCh := make(chan error)
+	// Every worker sends at most one error, so workers never block on errCh.
+	errCh := make(chan error, rrp.cc)
 
 	var wg sync.WaitGroup
 	wg.Add(rrp.cc)
@@ -75,6 +97,10 @@ func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) e
 			defer wg.Done()
 			for r := range rangeC {
 				if err := rrp.do(ctx, r); err != nil {
+					if rrp.follow && ctx.Err() != nil {
+						// Follow mode is stopped by interruption.
+						return
+					}
 					errCh <- fmt.Errorf("request failed for: %s", err)
 					return
 				}
@@ -98,6 +124,12 @@ func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) e
 		}
 	}
 
+	if rrp.follow {
+		if err := rrp.followSource(ctx, rangeC, errCh, bar, *rrp.filter.timeEnd, verbose); err != nil {
+			return err
+		}
+	}
+
 	close(rangeC)
 	wg.Wait()
 	rrp.dst.Close()
@@ -115,8 +147,59 @@ func (rrp *remoteReadProcessor) run(ctx context.Context, silent, verbose bool) e
 	return nil
 }
 
+// followSource sends time ranges with new data starting from the given start to rangeC until ctx is cancelled.
+//
+// The sent time ranges are contiguous, so the data is imported without gaps.
+func (rrp *remoteReadProcessor) followSource(ctx context.Context, rangeC chan<- *remoteread.Filter, errCh <-chan error,
+	bar *pb.ProgressBar, start time.Time, verbose bool) error {
+	log.Printf("following new data starting from %q", start.String())
+	ticker := time.NewTicker(rrp.followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("stopped following new data at %q", start.String())
+			return nil
+		case infErr := <-errCh:
+			return fmt.Errorf("remote read error: %s", infErr)
+		case vmErr := <-rrp.dst.Errors():
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
+		case <-ticker.C:
+		}
+		end := time.Now().Add(-rrp.followLag)
+		if !end.After(start) {
+			continue
+		}
+		if bar != nil {
+			bar.AddTotal(1)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("stopped following new data at %q", start.String())
+			return nil
+		case infErr := <-errCh:
+			return fmt.Errorf("remote read error: %s", infErr)
+		case vmErr := <-rrp.dst.Errors():
+			return fmt.Errorf("import process failed: %s", wrapErr(vmErr, verbose))
+		case rangeC <- &remoteread.Filter{
+			StartTimestampMs: start.UnixMilli(),
+			EndTimestampMs:   end.UnixMilli(),
+		}:
+		}
+		start = end
+	}
+}
+
 func (rrp *remoteReadProcessor) do(ctx context.Context, filter *remoteread.Filter) error {
 	return rrp.src.Read(ctx, filter, func(series *vm.TimeSeries) error {
+		if rrp.follow {
+			// Adjacent time ranges share the boundary, since remote read returns samples on both ends of the time range.
+			// Drop samples at the end of the time range, since they are imported with the next time range.
+			dropSamplesFrom(series, filter.EndTimestampMs)
+			if len(series.Timestamps) == 0 {
+				return nil
+			}
+		}
 		if err := rrp.dst.Input(series); err != nil {
 			return fmt.Errorf(
 				"failed to read data for time range start: %d, end: %d, %s",
@@ -125,3 +208,18 @@ func (rrp *remoteReadProcessor) do(ctx context.Context, filter *remoteread.Filte
 		return nil
 	})
 }
+
+// dropSamplesFrom drops samples with timestamps bigger or equal to the given maxTimestamp from series.
+func dropSamplesFrom(series *vm.TimeSeries, maxTimestamp int64) {
+	n := 0
+	for i, ts := range series.Timestamps {
+		if ts >= maxTimestamp {
+			continue
+		}
+		series.Timestamps[n] = ts
+		series.Values[n] = series.Values[i]
+		n++
+	}
+	series.Timestamps = series.Timestamps[:n]
+	series.Values = series.Values[:n]
+}
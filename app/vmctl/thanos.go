@@ -0,0 +1,286 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/barpool"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/thanos"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/vm"
+)
+
+type thanosProcessor struct {
+	// cl reads blocks from Thanos bucket
+	cl *thanos.Client
+	// dst performs import requests
+	dst *vmNativeClient
+	// defaultTenant is the tenant for blocks without tenantLabel.
+	// Empty value means single-node VictoriaMetrics.
+	defaultTenant string
+	// tenantLabel is the name of the external label with the tenant
+	tenantLabel string
+	// tenantMap maps tenantLabel values to tenants.
+	// All the values are used as tenants as is if tenantMap is empty
+	tenantMap map[string]string
+	// dropExternalLabels disables adding external labels to series
+	dropExternalLabels bool
+	// extraLabels are added to all the imported series
+	extraLabels []string
+	// maxRequestSize is the maximum size of a single import request
+	maxRequestSize int
+	// cc stands for concurrency
+	// and defines number of concurrently
+	// imported blocks
+	cc int
+	// disableProgressBar disables progress bar for imported blocks
+	disableProgressBar bool
+
+	samples  uint64
+	series   uint64
+	requests uint64
+}
+
+func (tp *thanosProcessor) run(ctx context.Context, silent bool) error {
+	blocks, err := tp.cl.Explore(ctx)
+	if err != nil {
+		return fmt.Errorf("explore failed: %s", err)
+	}
+	var blocksToImport []*thanos.Block
+	for _, b := range blocks {
+		if _, ok := tp.blockTenant(b); !ok {
+			log.Printf("skipping block %s: no tenant mapping for external label %s=%q", b.ID, tp.tenantLabel, b.Meta.Thanos.Labels[tp.tenantLabel])
+			continue
+		}
+		blocksToImport = append(blocksToImport, b)
+	}
+	if len(blocksToImport) < 1 {
+		return fmt.Errorf("found no blocks to import")
+	}
+	question := fmt.Sprintf("Found %d blocks to import. Continue?", len(blocksToImport))
+	if !silent && !prompt(question) {
+		return nil
+	}
+	if tp.cc < 1 {
+		tp.cc = 1
+	}
+
+	var bar *pb.ProgressBar
+	if !tp.disableProgressBar {
+		bar = barpool.AddWithTemplate(fmt.Sprintf(barTpl, "Processing blocks"), len(blocksToImport))
+		if err := barpool.Start(); err != nil {
+			return err
+		}
+		defer barpool.Stop()
+	}
+
+	startTime := time.Now()
+	blockCh := make(chan *thanos.Block)
+	errCh := make(chan error, tp.cc)
+
+	var wg sync.WaitGroup
+	wg.Add(tp.cc)
+	for i := 0; i < tp.cc; i++ {
+		go func() {
+			defer wg.Done()
+			for b := range blockCh {
+				if err := tp.do(ctx, b); err != nil {
+					errCh <- fmt.Errorf("failed to import block %s: %s", b.ID, err)
+					return
+				}
+				if bar != nil {
+					bar.Increment()
+				}
+			}
+		}()
+	}
+	// any error breaks the import
+	for _, b := range blocksToImport {
+		select {
+		case err := <-errCh:
+			close(blockCh)
+			return fmt.Errorf("import process failed: %s", err)
+		case blockCh <- b:
+		}
+	}
+
+	close(blockCh)
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		return fmt.Errorf("import process failed: %s", err)
+	}
+
+	log.Println("Import finished!")
+	log.Printf("Imported %d samples of %d series in %d requests; duration %s",
+		atomic.LoadUint64(&tp.samples), atomic.LoadUint64(&tp.series), atomic.LoadUint64(&tp.requests), time.Since(startTime))
+	return nil
+}
+
+func (tp *thanosProcessor) do(ctx context.Context, b *thanos.Block) error {
+	tenant, _ := tp.blockTenant(b)
+	importURL, err := tp.importURL(tenant)
+	if err != nil {
+		return err
+	}
+	var externalLabels []vm.LabelPair
+	if !tp.dropExternalLabels {
+		for name, value := range b.Meta.Thanos.Labels {
+			if name == tp.tenantLabel {
+				continue
+			}
+			externalLabels = append(externalLabels, vm.LabelPair{Name: name, Value: value})
+		}
+	}
+	nameSuffix := ""
+	if r := b.Meta.Resolution(); r > 0 {
+		nameSuffix = ":" + formatResolution(r) + "_"
+	}
+
+	header := vm.AppendNativeTimeRange(nil, b.Meta.MinTime, b.Meta.MaxTime)
+	buf := append([]byte{}, header...)
+	flush := func() error {
+		if len(buf) == len(header) {
+			return nil
+		}
+		if err := tp.importData(ctx, importURL, buf); err != nil {
+			return err
+		}
+		buf = buf[:len(header)]
+		return nil
+	}
+
+	var nm vm.NativeMarshaler
+	var ts vm.TimeSeries
+	err = tp.cl.Read(ctx, b, func(s *thanos.Series) error {
+		ts.Name = ""
+		ts.LabelPairs = ts.LabelPairs[:0]
+		s.Labels.Range(func(l labels.Label) {
+			if l.Name == "__name__" {
+				ts.Name = l.Value
+				return
+			}
+			ts.LabelPairs = append(ts.LabelPairs, vm.LabelPair{Name: l.Name, Value: l.Value})
+		})
+		if ts.Name == "" {
+			return fmt.Errorf("failed to find `__name__` label in labelset %s", s.Labels)
+		}
+		if nameSuffix != "" {
+			ts.Name += nameSuffix + s.Aggr.String()
+		}
+		for _, lp := range externalLabels {
+			// series labels take precedence over external labels
+			if !s.Labels.Has(lp.Name) {
+				ts.LabelPairs = append(ts.LabelPairs, lp)
+			}
+		}
+		ts.Timestamps = s.Timestamps
+		ts.Values = s.Values
+		buf = nm.AppendBlocks(buf, &ts)
+		atomic.AddUint64(&tp.samples, uint64(len(s.Timestamps)))
+		atomic.AddUint64(&tp.series, 1)
+		if len(buf) >= tp.maxRequestSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return flush()
+}
+
+func (tp *thanosProcessor) importData(ctx context.Context, importURL string, data []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", importURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot create import request to %q: %s", tp.dst.addr, err)
+	}
+	resp, err := tp.dst.do(req, http.StatusNoContent)
+	if err != nil {
+		return fmt.Errorf("import request failed: %s", err)
+	}
+	if err := resp.Body.Close(); err != nil {
+		return fmt.Errorf("cannot close import response body: %s", err)
+	}
+	atomic.AddUint64(&tp.requests, 1)
+	return nil
+}
+
+// blockTenant returns the tenant for importing the given block.
+//
+// false is returned if tenantMap has no mapping for the block.
+func (tp *thanosProcessor) blockTenant(b *thanos.Block) (string, bool) {
+	if tp.tenantLabel == "" {
+		return tp.defaultTenant, true
+	}
+	value, ok := b.Meta.Thanos.Labels[tp.tenantLabel]
+	if !ok {
+		return tp.defaultTenant, true
+	}
+	if len(tp.tenantMap) == 0 {
+		return value, true
+	}
+	tenant, ok := tp.tenantMap[value]
+	return tenant, ok
+}
+
+func (tp *thanosProcessor) importURL(tenant string) (string, error) {
+	// see https://docs.victoriametrics.com/#how-to-import-data-in-native-format
+	importURL := fmt.Sprintf("%s/%s", tp.dst.addr, nativeImportAddr)
+	if tenant != "" {
+		// see https://docs.victoriametrics.com/Cluster-VictoriaMetrics.html#url-format
+		importURL = fmt.Sprintf("%s/insert/%s/prometheus/%s", tp.dst.addr, tenant, nativeImportAddr)
+	}
+	importURL, err := vm.AddExtraLabelsToImportPath(importURL, tp.extraLabels)
+	if err != nil {
+		return "", fmt.Errorf("failed to add labels to import path: %s", err)
+	}
+	return importURL, nil
+}
+
+// parseThanosTenantMap parses `value=accountID[:projectID]` items.
+func parseThanosTenantMap(items []string) (map[string]string, error) {
+	m := make(map[string]string, len(items))
+	for _, item := range items {
+		n := strings.LastIndexByte(item, '=')
+		if n <= 0 || n == len(item)-1 {
+			return nil, fmt.Errorf("bad format for %q; it must be `value=accountID[:projectID]`", item)
+		}
+		m[item[:n]] = item[n+1:]
+	}
+	return m, nil
+}
+
+// parseThanosResolution parses resolution of Thanos blocks.
+func parseThanosResolution(s string) (time.Duration, error) {
+	switch s {
+	case "raw", "0", "0s":
+		return 0, nil
+	case "5m":
+		return 5 * time.Minute, nil
+	case "1h":
+		return time.Hour, nil
+	default:
+		return 0, fmt.Errorf("unsupported resolution %q; supported values: raw, 5m, 1h", s)
+	}
+}
+
+// formatResolution returns r in the form used by Thanos, e.g. 5m or 1h.
+func formatResolution(r time.Duration) string {
+	if r%time.Hour == 0 {
+		return fmt.Sprintf("%dh", r/time.Hour)
+	}
+	if r%time.Minute == 0 {
+		return fmt.Sprintf("%dm", r/time.Minute)
+	}
+	return r.String()
+}
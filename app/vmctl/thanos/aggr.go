@@ -0,0 +1,183 @@
+package thanos
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+// chunkEncAggr is the encoding of chunks in downsampled Thanos blocks.
+//
+// See https://github.com/thanos-io/thanos/blob/main/pkg/compact/downsample/aggr.go
+const chunkEncAggr = chunkenc.Encoding(0xff)
+
+// AggrType is the type of aggregate stored in downsampled Thanos blocks.
+type AggrType int
+
+// Aggregates in the order they are stored in downsampled chunks.
+const (
+	AggrCount AggrType = iota
+	AggrSum
+	AggrMin
+	AggrMax
+	AggrCounter
+
+	// AggrAvg isn't stored in downsampled chunks.
+	// It is calculated as AggrSum / AggrCount.
+	AggrAvg
+)
+
+var aggrTypeNames = []string{"count", "sum", "min", "max", "counter", "avg"}
+
+// String returns string representation of at.
+func (at AggrType) String() string {
+	if at < 0 || int(at) >= len(aggrTypeNames) {
+		return fmt.Sprintf("unknown(%d)", int(at))
+	}
+	return aggrTypeNames[at]
+}
+
+// ParseAggrType parses AggrType from s.
+func ParseAggrType(s string) (AggrType, error) {
+	for i, name := range aggrTypeNames {
+		if strings.EqualFold(s, name) {
+			return AggrType(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown aggregate %q; supported values: %s", s, strings.Join(aggrTypeNames, ", "))
+}
+
+// aggrChunk is a chunk from downsampled Thanos block.
+//
+// It consists of up to 5 sub-chunks - one per every stored aggregate.
+// Every sub-chunk is prefixed with uvarint length. Zero length means the aggregate is missing.
+// Sub-chunk starts with the encoding byte followed by chunk data.
+type aggrChunk []byte
+
+func (c aggrChunk) get(at AggrType) (chunkenc.Chunk, error) {
+	if at < AggrCount || at > AggrCounter {
+		return nil, fmt.Errorf("aggregate %s isn't stored in downsampled chunks", at)
+	}
+	b := c
+	var x []byte
+	for i := AggrCount; i <= at; i++ {
+		n, nSize := binary.Uvarint(b)
+		if nSize <= 0 || uint64(len(b)-nSize) < n {
+			return nil, fmt.Errorf("invalid size for aggregate %s", i)
+		}
+		x = b[nSize : nSize+int(n)]
+		b = b[nSize+int(n):]
+	}
+	if len(x) == 0 {
+		return nil, nil
+	}
+	return chunkenc.FromData(chunkenc.Encoding(x[0]), x[1:])
+}
+
+// appendSamples appends samples for the given aggregate from c to timestamps and values.
+func (c aggrChunk) appendSamples(timestamps []int64, values []float64, at AggrType) ([]int64, []float64, error) {
+	if at != AggrAvg {
+		chk, err := c.get(at)
+		if err != nil || chk == nil {
+			return timestamps, values, err
+		}
+		return appendChunkSamples(timestamps, values, chk)
+	}
+
+	countChk, err := c.get(AggrCount)
+	if err != nil {
+		return timestamps, values, err
+	}
+	sumChk, err := c.get(AggrSum)
+	if err != nil {
+		return timestamps, values, err
+	}
+	if countChk == nil || sumChk == nil {
+		return timestamps, values, nil
+	}
+	// count and sum samples are stored for the same timestamps
+	countIt := countChk.Iterator(nil)
+	sumIt := sumChk.Iterator(nil)
+	for countIt.Next() == chunkenc.ValFloat && sumIt.Next() == chunkenc.ValFloat {
+		tc, count := countIt.At()
+		ts, sum := sumIt.At()
+		if tc != ts {
+			return timestamps, values, fmt.Errorf("timestamps mismatch for count and sum aggregates: %d vs %d", tc, ts)
+		}
+		timestamps = append(timestamps, ts)
+		values = append(values, sum/count)
+	}
+	if err := countIt.Err(); err != nil {
+		return timestamps, values, err
+	}
+	return timestamps, values, sumIt.Err()
+}
+
+// appendChunkSamples appends float samples from chk to timestamps and values.
+//
+// Samples of other types are skipped.
+func appendChunkSamples(timestamps []int64, values []float64, chk chunkenc.Chunk) ([]int64, []float64, error) {
+	it := chk.Iterator(nil)
+	for {
+		typ := it.Next()
+		if typ == chunkenc.ValNone {
+			break
+		}
+		if typ != chunkenc.ValFloat {
+			// Skip unsupported values
+			continue
+		}
+		t, v := it.At()
+		timestamps = append(timestamps, t)
+		values = append(values, v)
+	}
+	return timestamps, values, it.Err()
+}
+
+// chunkPool is chunkenc.Pool, which supports chunks from downsampled Thanos blocks.
+type chunkPool struct {
+	chunkenc.Pool
+}
+
+func newChunkPool() chunkenc.Pool {
+	return &chunkPool{Pool: chunkenc.NewPool()}
+}
+
+// Get implements chunkenc.Pool interface.
+func (p *chunkPool) Get(e chunkenc.Encoding, b []byte) (chunkenc.Chunk, error) {
+	if e == chunkEncAggr {
+		return &rawAggrChunk{b: aggrChunk(b)}, nil
+	}
+	return p.Pool.Get(e, b)
+}
+
+// Put implements chunkenc.Pool interface.
+func (p *chunkPool) Put(c chunkenc.Chunk) error {
+	if c.Encoding() == chunkEncAggr {
+		return nil
+	}
+	return p.Pool.Put(c)
+}
+
+// rawAggrChunk wraps aggrChunk into chunkenc.Chunk interface,
+// so it could be returned from tsdb.ChunkReader.
+//
+// Samples must be read via aggrChunk.appendSamples.
+type rawAggrChunk struct {
+	b aggrChunk
+}
+
+func (c *rawAggrChunk) Bytes() []byte               { return c.b }
+func (c *rawAggrChunk) Encoding() chunkenc.Encoding { return chunkEncAggr }
+func (c *rawAggrChunk) NumSamples() int             { return 0 }
+func (c *rawAggrChunk) Compact()                    {}
+func (c *rawAggrChunk) Iterator(_ chunkenc.Iterator) chunkenc.Iterator {
+	return chunkenc.NewNopIterator()
+}
+func (c *rawAggrChunk) Appender() (chunkenc.Appender, error) {
+	return nil, fmt.Errorf("cannot append to downsampled chunk")
+}
+
+var _ chunkenc.Chunk = &rawAggrChunk{}
@@ -0,0 +1,85 @@
+package thanos
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+func newXORChunk(t *testing.T, timestamps []int64, values []float64) []byte {
+	t.Helper()
+	c := chunkenc.NewXORChunk()
+	app, err := c.Appender()
+	if err != nil {
+		t.Fatalf("cannot create appender: %s", err)
+	}
+	for i, ts := range timestamps {
+		app.Append(ts, values[i])
+	}
+	return append([]byte{byte(chunkenc.EncXOR)}, c.Bytes()...)
+}
+
+func TestAggrChunk(t *testing.T) {
+	timestamps := []int64{300e3, 600e3, 900e3}
+	// count, sum, min, max; counter is missing
+	subChunks := [][]byte{
+		newXORChunk(t, timestamps, []float64{2, 4, 5}),
+		newXORChunk(t, timestamps, []float64{10, 20, 40}),
+		newXORChunk(t, timestamps, []float64{1, 2, 3}),
+		newXORChunk(t, timestamps, []float64{9, 8, 7}),
+		nil,
+	}
+	var c aggrChunk
+	for _, sc := range subChunks {
+		c = binary.AppendUvarint(c, uint64(len(sc)))
+		c = append(c, sc...)
+	}
+
+	f := func(at AggrType, valuesExpected []float64) {
+		t.Helper()
+		gotTimestamps, gotValues, err := c.appendSamples(nil, nil, at)
+		if err != nil {
+			t.Fatalf("unexpected error for %s: %s", at, err)
+		}
+		if len(valuesExpected) == 0 {
+			if len(gotTimestamps) != 0 {
+				t.Fatalf("unexpected samples for %s: %v", at, gotValues)
+			}
+			return
+		}
+		if !reflect.DeepEqual(gotTimestamps, timestamps) {
+			t.Fatalf("unexpected timestamps for %s; got %v; want %v", at, gotTimestamps, timestamps)
+		}
+		if !reflect.DeepEqual(gotValues, valuesExpected) {
+			t.Fatalf("unexpected values for %s; got %v; want %v", at, gotValues, valuesExpected)
+		}
+	}
+	f(AggrCount, []float64{2, 4, 5})
+	f(AggrSum, []float64{10, 20, 40})
+	f(AggrMin, []float64{1, 2, 3})
+	f(AggrMax, []float64{9, 8, 7})
+	f(AggrCounter, nil)
+	f(AggrAvg, []float64{5, 5, 8})
+
+	// truncated chunk
+	if _, _, err := c[:len(c)/2].appendSamples(nil, nil, AggrMax); err == nil {
+		t.Fatalf("expecting non-nil error for truncated chunk")
+	}
+}
+
+func TestParseAggrType(t *testing.T) {
+	for i, name := range aggrTypeNames {
+		at, err := ParseAggrType(name)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", name, err)
+		}
+		if at != AggrType(i) || at.String() != name {
+			t.Fatalf("unexpected aggregate for %q: %s", name, at)
+		}
+	}
+	if _, err := ParseAggrType("foo"); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
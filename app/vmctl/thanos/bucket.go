@@ -0,0 +1,208 @@
+package thanos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/oklog/ulid"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/file"
+)
+
+// bucket provides access to blocks stored in Thanos bucket layout,
+// where every block is stored in a separate directory named by block ULID.
+type bucket interface {
+	// Blocks returns ULIDs of blocks in the bucket.
+	Blocks(ctx context.Context) ([]string, error)
+	// ReadFile returns the contents of the given file from the given block.
+	//
+	// os.ErrNotExist is returned if the file is missing.
+	ReadFile(ctx context.Context, id, name string) ([]byte, error)
+	// Fetch makes the given block available on the local filesystem.
+	//
+	// It returns the path to the block directory and the function
+	// for releasing the resources occupied by the fetched block.
+	Fetch(ctx context.Context, id string) (string, func(), error)
+	// String returns human-readable bucket location.
+	String() string
+}
+
+func openBucket(ctx context.Context, bucketPath, tmpDir string, s3Cfg file.S3Config) (bucket, error) {
+	if strings.HasPrefix(bucketPath, "s3://") {
+		return openS3Bucket(ctx, bucketPath, tmpDir, s3Cfg)
+	}
+	fi, err := os.Stat(bucketPath)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("%q must be a directory", bucketPath)
+	}
+	return &localBucket{dir: bucketPath}, nil
+}
+
+func isBlockID(name string) bool {
+	_, err := ulid.ParseStrict(name)
+	return err == nil
+}
+
+type localBucket struct {
+	dir string
+}
+
+func (lb *localBucket) Blocks(_ context.Context) ([]string, error) {
+	des, err := os.ReadDir(lb.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, de := range des {
+		if de.IsDir() && isBlockID(de.Name()) {
+			ids = append(ids, de.Name())
+		}
+	}
+	return ids, nil
+}
+
+func (lb *localBucket) ReadFile(_ context.Context, id, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(lb.dir, id, name))
+}
+
+func (lb *localBucket) Fetch(_ context.Context, id string) (string, func(), error) {
+	// Local blocks are read in place.
+	return filepath.Join(lb.dir, id), func() {}, nil
+}
+
+func (lb *localBucket) String() string { return lb.dir }
+
+type s3Bucket struct {
+	s3     *s3.Client
+	bucket string
+	prefix string
+	tmpDir string
+}
+
+func openS3Bucket(ctx context.Context, bucketPath, tmpDir string, cfg file.S3Config) (*s3Bucket, error) {
+	bucketPath = strings.TrimSuffix(bucketPath[len("s3://"):], "/")
+	if bucketPath == "" {
+		return nil, fmt.Errorf("missing bucket name; the path must have the form s3://bucket/optional/prefix")
+	}
+	bucket, prefix := bucketPath, ""
+	if n := strings.IndexByte(bucketPath, '/'); n > 0 {
+		bucket, prefix = bucketPath[:n], bucketPath[n+1:]+"/"
+	}
+	client, err := file.NewS3Client(ctx, bucket, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Bucket{
+		s3:     client,
+		bucket: bucket,
+		prefix: prefix,
+		tmpDir: tmpDir,
+	}, nil
+}
+
+func (sb *s3Bucket) Blocks(ctx context.Context) ([]string, error) {
+	var ids []string
+	p := s3.NewListObjectsV2Paginator(sb.s3, &s3.ListObjectsV2Input{
+		Bucket:    aws.String(sb.bucket),
+		Prefix:    aws.String(sb.prefix),
+		Delimiter: aws.String("/"),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list %s: %w", sb, err)
+		}
+		for _, cp := range page.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(aws.ToString(cp.Prefix), sb.prefix), "/")
+			if isBlockID(name) {
+				ids = append(ids, name)
+			}
+		}
+	}
+	return ids, nil
+}
+
+func (sb *s3Bucket) ReadFile(ctx context.Context, id, name string) ([]byte, error) {
+	key := sb.prefix + path.Join(id, name)
+	out, err := sb.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(sb.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var nsk interface{ ErrorCode() string }
+		if errors.As(err, &nsk) && nsk.ErrorCode() == "NoSuchKey" {
+			return nil, os.ErrNotExist
+		}
+		return nil, fmt.Errorf("cannot read s3://%s/%s: %w", sb.bucket, key, err)
+	}
+	defer func() { _ = out.Body.Close() }()
+	return io.ReadAll(out.Body)
+}
+
+func (sb *s3Bucket) Fetch(ctx context.Context, id string) (string, func(), error) {
+	dir, err := os.MkdirTemp(sb.tmpDir, "vmctl-thanos-"+id+"-")
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot create temporary directory for block %s: %w", id, err)
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	blockPrefix := sb.prefix + id + "/"
+	p := s3.NewListObjectsV2Paginator(sb.s3, &s3.ListObjectsV2Input{
+		Bucket: aws.String(sb.bucket),
+		Prefix: aws.String(blockPrefix),
+	})
+	for p.HasMorePages() {
+		page, err := p.NextPage(ctx)
+		if err != nil {
+			cleanup()
+			return "", nil, fmt.Errorf("cannot list files for block %s: %w", id, err)
+		}
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			dst := filepath.Join(dir, filepath.FromSlash(strings.TrimPrefix(key, blockPrefix)))
+			if err := sb.download(ctx, key, dst); err != nil {
+				cleanup()
+				return "", nil, err
+			}
+		}
+	}
+	return dir, cleanup, nil
+}
+
+func (sb *s3Bucket) download(ctx context.Context, key, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := sb.s3.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(sb.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("cannot read s3://%s/%s: %w", sb.bucket, key, err)
+	}
+	defer func() { _ = out.Body.Close() }()
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, out.Body); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot download s3://%s/%s to %q: %w", sb.bucket, key, dst, err)
+	}
+	return f.Close()
+}
+
+func (sb *s3Bucket) String() string {
+	return fmt.Sprintf("s3://%s/%s", sb.bucket, sb.prefix)
+}
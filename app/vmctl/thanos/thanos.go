@@ -0,0 +1,332 @@
+package thanos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/file"
+)
+
+// Config contains a list of params needed
+// for reading blocks from Thanos bucket
+type Config struct {
+	// Bucket is the path to the directory with Thanos blocks
+	// or s3://bucket/prefix url.
+	Bucket string
+	// S3 contains params for reading blocks from S3.
+	S3 file.S3Config
+	// TmpDir is the directory for storing blocks fetched from S3.
+	TmpDir string
+	// Resolutions contains resolutions of blocks to read.
+	// Zero resolution means raw blocks.
+	Resolutions []time.Duration
+	// AggrTypes contains aggregates to read from downsampled blocks.
+	AggrTypes []AggrType
+
+	Filter Filter
+}
+
+// Filter contains configuration for filtering
+// the timeseries
+type Filter struct {
+	TimeMin    string
+	TimeMax    string
+	Label      string
+	LabelValue string
+}
+
+// Meta is the contents of meta.json file of Thanos block.
+type Meta struct {
+	tsdb.BlockMeta
+
+	Thanos ThanosMeta `json:"thanos"`
+}
+
+// ThanosMeta contains Thanos-specific block metadata.
+type ThanosMeta struct {
+	// Labels contains external labels of the block.
+	Labels map[string]string `json:"labels"`
+	// Downsample contains downsampling params of the block.
+	Downsample struct {
+		// Resolution is the block resolution in milliseconds.
+		// Zero resolution means raw block.
+		Resolution int64 `json:"resolution"`
+	} `json:"downsample"`
+	// Source is the component, which created the block.
+	Source string `json:"source"`
+}
+
+// Resolution returns m resolution.
+func (m *Meta) Resolution() time.Duration {
+	return time.Duration(m.Thanos.Downsample.Resolution) * time.Millisecond
+}
+
+// Block is a block from Thanos bucket.
+type Block struct {
+	ID   string
+	Meta Meta
+}
+
+// Series is a time series read from Thanos block.
+type Series struct {
+	// Labels contains series labels without external labels.
+	Labels labels.Labels
+	// Aggr is the aggregate the samples were read for.
+	// It is set only for series from downsampled blocks.
+	Aggr       AggrType
+	Timestamps []int64
+	Values     []float64
+}
+
+// Client reads blocks from Thanos bucket.
+type Client struct {
+	bucket      bucket
+	resolutions map[time.Duration]bool
+	aggrTypes   []AggrType
+	filter      filter
+}
+
+type filter struct {
+	min, max   int64
+	label      string
+	labelValue string
+}
+
+func (f filter) inRange(min, max int64) bool {
+	fmin, fmax := f.min, f.max
+	if fmin == 0 {
+		fmin = min
+	}
+	if fmax == 0 {
+		fmax = max
+	}
+	return min <= fmax && fmin <= max
+}
+
+// NewClient creates and validates new Client
+// with given Config
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	b, err := openBucket(ctx, cfg.Bucket, cfg.TmpDir, cfg.S3)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %q: %s", cfg.Bucket, err)
+	}
+	min, max, err := parseTime(cfg.Filter.TimeMin, cfg.Filter.TimeMax)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse time in filter: %s", err)
+	}
+	c := &Client{
+		bucket:      b,
+		resolutions: make(map[time.Duration]bool),
+		aggrTypes:   cfg.AggrTypes,
+		filter: filter{
+			min:        min,
+			max:        max,
+			label:      cfg.Filter.Label,
+			labelValue: cfg.Filter.LabelValue,
+		},
+	}
+	for _, r := range cfg.Resolutions {
+		c.resolutions[r] = true
+	}
+	if len(c.resolutions) == 0 {
+		c.resolutions[0] = true
+	}
+	if len(c.aggrTypes) == 0 {
+		c.aggrTypes = []AggrType{AggrAvg}
+	}
+	return c, nil
+}
+
+// Explore fetches meta.json for all the blocks in the bucket
+// and returns blocks matching the configured resolutions and time range.
+// Blocks marked for deletion are skipped.
+func (c *Client) Explore(ctx context.Context) ([]*Block, error) {
+	ids, err := c.bucket.Blocks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch blocks from %s: %s", c.bucket, err)
+	}
+	s := &Stats{
+		Filtered: c.filter.min != 0 || c.filter.max != 0 || c.filter.label != "",
+		Blocks:   len(ids),
+	}
+	var blocks []*Block
+	for _, id := range ids {
+		if _, err := c.bucket.ReadFile(ctx, id, "deletion-mark.json"); err == nil {
+			s.SkippedBlocks++
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to check deletion mark for block %s: %s", id, err)
+		}
+		data, err := c.bucket.ReadFile(ctx, id, "meta.json")
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// The block is being uploaded or is partially deleted
+				log.Printf("skipping block %s without meta.json", id)
+				s.SkippedBlocks++
+				continue
+			}
+			return nil, fmt.Errorf("failed to read meta.json for block %s: %s", id, err)
+		}
+		b := &Block{ID: id}
+		if err := json.Unmarshal(data, &b.Meta); err != nil {
+			return nil, fmt.Errorf("failed to parse meta.json for block %s: %s", id, err)
+		}
+		meta := &b.Meta
+		if !c.resolutions[meta.Resolution()] || !c.filter.inRange(meta.MinTime, meta.MaxTime) {
+			s.SkippedBlocks++
+			continue
+		}
+		if s.MinTime == 0 || meta.MinTime < s.MinTime {
+			s.MinTime = meta.MinTime
+		}
+		if s.MaxTime == 0 || meta.MaxTime > s.MaxTime {
+			s.MaxTime = meta.MaxTime
+		}
+		s.Samples += meta.Stats.NumSamples
+		s.Series += meta.Stats.NumSeries
+		blocks = append(blocks, b)
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Meta.MinTime < blocks[j].Meta.MinTime
+	})
+	fmt.Println(s)
+	return blocks, nil
+}
+
+// Read reads series from the given block according to configured
+// time and label filters and calls f for every read series.
+//
+// Series from downsampled blocks are passed to f once per every configured aggregate.
+// f must not hold references to s after returning.
+func (c *Client) Read(ctx context.Context, b *Block, f func(s *Series) error) error {
+	dir, cleanup, err := c.bucket.Fetch(ctx, b.ID)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	pb, err := tsdb.OpenBlock(nil, dir, newChunkPool())
+	if err != nil {
+		return fmt.Errorf("failed to open block: %s", err)
+	}
+	defer func() { _ = pb.Close() }()
+
+	ir, err := pb.Index()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = ir.Close() }()
+	cr, err := pb.Chunks()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cr.Close() }()
+
+	minTime, maxTime := b.Meta.MinTime, b.Meta.MaxTime
+	if c.filter.min != 0 {
+		minTime = c.filter.min
+	}
+	if c.filter.max != 0 {
+		maxTime = c.filter.max
+	}
+	m, err := labels.NewMatcher(labels.MatchRegexp, c.filter.label, c.filter.labelValue)
+	if err != nil {
+		return fmt.Errorf("failed to create label filter: %s", err)
+	}
+	p, err := tsdb.PostingsForMatchers(ir, m)
+	if err != nil {
+		return err
+	}
+
+	aggrTypes := c.aggrTypes
+	if b.Meta.Resolution() == 0 {
+		aggrTypes = []AggrType{0}
+	}
+	var s Series
+	var builder labels.ScratchBuilder
+	var chks []chunks.Meta
+	for p.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := ir.Series(p.At(), &builder, &chks); err != nil {
+			return err
+		}
+		s.Labels = builder.Labels()
+		for _, at := range aggrTypes {
+			s.Aggr = at
+			s.Timestamps = s.Timestamps[:0]
+			s.Values = s.Values[:0]
+			for _, chk := range chks {
+				if chk.MaxTime < minTime || chk.MinTime > maxTime {
+					continue
+				}
+				chunk, err := cr.Chunk(chk)
+				if err != nil {
+					return fmt.Errorf("failed to read chunk for series %s: %s", s.Labels, err)
+				}
+				if ac, ok := chunk.(*rawAggrChunk); ok {
+					s.Timestamps, s.Values, err = ac.b.appendSamples(s.Timestamps, s.Values, at)
+				} else {
+					s.Timestamps, s.Values, err = appendChunkSamples(s.Timestamps, s.Values, chunk)
+				}
+				if err != nil {
+					return fmt.Errorf("failed to decode chunk for series %s: %s", s.Labels, err)
+				}
+			}
+			s.Timestamps, s.Values = filterSamples(s.Timestamps, s.Values, minTime, maxTime)
+			if len(s.Timestamps) == 0 {
+				continue
+			}
+			if err := f(&s); err != nil {
+				return err
+			}
+		}
+	}
+	return p.Err()
+}
+
+// filterSamples removes samples outside [minTime..maxTime] time range.
+func filterSamples(timestamps []int64, values []float64, minTime, maxTime int64) ([]int64, []float64) {
+	dstTimestamps, dstValues := timestamps[:0], values[:0]
+	for i, ts := range timestamps {
+		if ts < minTime || ts > maxTime {
+			continue
+		}
+		dstTimestamps = append(dstTimestamps, ts)
+		dstValues = append(dstValues, values[i])
+	}
+	return dstTimestamps, dstValues
+}
+
+func parseTime(start, end string) (int64, int64, error) {
+	var s, e int64
+	if start == "" && end == "" {
+		return 0, 0, nil
+	}
+	if start != "" {
+		v, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse %q: %s", start, err)
+		}
+		s = v.UnixNano() / int64(time.Millisecond)
+	}
+	if end != "" {
+		v, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to parse %q: %s", end, err)
+		}
+		e = v.UnixNano() / int64(time.Millisecond)
+	}
+	return s, e, nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmctl/thanos"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/native/stream"
+)
+
+type testSample struct {
+	t int64
+	v float64
+}
+
+func (s testSample) T() int64                      { return s.t }
+func (s testSample) V() float64                    { return s.v }
+func (s testSample) H() *histogram.Histogram       { return nil }
+func (s testSample) FH() *histogram.FloatHistogram { return nil }
+func (s testSample) Type() chunkenc.ValueType      { return chunkenc.ValFloat }
+
+// createThanosBlock creates raw block in dir with the given external labels.
+func createThanosBlock(t *testing.T, dir string, externalLabels map[string]string, series ...*storage.SeriesEntry) {
+	t.Helper()
+	var ss []storage.Series
+	for _, s := range series {
+		ss = append(ss, s)
+	}
+	blockDir, err := tsdb.CreateBlock(ss, dir, 0, log.NewNopLogger())
+	if err != nil {
+		t.Fatalf("cannot create block: %s", err)
+	}
+	metaPath := filepath.Join(blockDir, "meta.json")
+	data, err := os.ReadFile(metaPath)
+	if err != nil {
+		t.Fatalf("cannot read meta.json: %s", err)
+	}
+	var meta thanos.Meta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		t.Fatalf("cannot parse meta.json: %s", err)
+	}
+	meta.Thanos.Labels = externalLabels
+	meta.Thanos.Source = "sidecar"
+	data, err = json.Marshal(&meta)
+	if err != nil {
+		t.Fatalf("cannot marshal meta.json: %s", err)
+	}
+	if err := os.WriteFile(metaPath, data, 0600); err != nil {
+		t.Fatalf("cannot write meta.json: %s", err)
+	}
+}
+
+func TestThanosProcessor(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	bucketDir := t.TempDir()
+	createThanosBlock(t, bucketDir, map[string]string{"tenant": "team-a", "replica": "r1"},
+		storage.NewListSeries(labels.FromStrings("__name__", "foo", "job", "bar"), []tsdbutil.Sample{
+			testSample{t: 1000, v: 1},
+			testSample{t: 2000, v: 2},
+		}),
+		storage.NewListSeries(labels.FromStrings("__name__", "baz", "replica", "own"), []tsdbutil.Sample{
+			testSample{t: 1000, v: 3},
+		}),
+	)
+	createThanosBlock(t, bucketDir, map[string]string{"tenant": "unknown"},
+		storage.NewListSeries(labels.FromStrings("__name__", "skipped"), []tsdbutil.Sample{
+			testSample{t: 1000, v: 1},
+		}),
+	)
+
+	var mu sync.Mutex
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := stream.Parse(r.Body, r.Header.Get("Content-Encoding"), func(block *stream.Block) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for i, ts := range block.Timestamps {
+				got = append(got, fmt.Sprintf("%s %s %d %v", r.URL.Path, &block.MetricName, ts, block.Values[i]))
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	cl, err := thanos.NewClient(context.Background(), thanos.Config{
+		Bucket: bucketDir,
+		Filter: thanos.Filter{
+			Label:      "__name__",
+			LabelValue: ".*",
+		},
+	})
+	if err != nil {
+		t.Fatalf("cannot create thanos client: %s", err)
+	}
+	tp := &thanosProcessor{
+		cl:                 cl,
+		dst:                &vmNativeClient{addr: srv.URL},
+		tenantLabel:        "tenant",
+		tenantMap:          map[string]string{"team-a": "1:0"},
+		maxRequestSize:     1 << 20,
+		cc:                 2,
+		disableProgressBar: true,
+	}
+	if err := tp.run(context.Background(), true); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	sort.Strings(got)
+	expected := []string{
+		`/insert/1:0/prometheus/api/v1/import/native baz{replica="own"} 1000 3`,
+		`/insert/1:0/prometheus/api/v1/import/native foo{job="bar",replica="r1"} 1000 1`,
+		`/insert/1:0/prometheus/api/v1/import/native foo{job="bar",replica="r1"} 2000 2`,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected imported samples;\ngot\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestParseThanosTenantMap(t *testing.T) {
+	m, err := parseThanosTenantMap([]string{"team-a=1", "team=b=2:3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := map[string]string{"team-a": "1", "team=b": "2:3"}
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("unexpected map; got %v; want %v", m, expected)
+	}
+	for _, item := range []string{"team-a", "=1", "team-a="} {
+		if _, err := parseThanosTenantMap([]string{item}); err == nil {
+			t.Fatalf("expecting non-nil error for %q", item)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package vm
+
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// maxSamplesPerNativeBlock is the maximum number of samples in a single native block,
+// since the importer rejects too big blocks.
+const maxSamplesPerNativeBlock = 8 * 1024
+
+// AppendNativeTimeRange appends the time range header to dst and returns the result.
+//
+// The header must be written at the start of every request to /api/v1/import/native.
+// Samples outside [minTimestamp..maxTimestamp] time range are dropped by the importer.
+func AppendNativeTimeRange(dst []byte, minTimestamp, maxTimestamp int64) []byte {
+	dst = encoding.MarshalInt64(dst, minTimestamp)
+	return encoding.MarshalInt64(dst, maxTimestamp)
+}
+
+// NativeMarshaler marshals time series into the format accepted by /api/v1/import/native.
+//
+// NativeMarshaler cannot be used from concurrently running goroutines.
+type NativeMarshaler struct {
+	mn            storage.MetricName
+	b             storage.Block
+	tsid          storage.TSID
+	values        []int64
+	metricNameBuf []byte
+	blockBuf      []byte
+}
+
+// AppendBlocks appends ts to dst in native format and returns the result.
+//
+// The time range header isn't appended. See AppendNativeTimeRange.
+func (nm *NativeMarshaler) AppendBlocks(dst []byte, ts *TimeSeries) []byte {
+	if len(ts.Timestamps) == 0 {
+		return dst
+	}
+	nm.mn.Reset()
+	nm.mn.MetricGroup = append(nm.mn.MetricGroup[:0], ts.Name...)
+	for _, lp := range ts.LabelPairs {
+		nm.mn.AddTag(lp.Name, lp.Value)
+	}
+	nm.metricNameBuf = nm.mn.Marshal(nm.metricNameBuf[:0])
+
+	timestamps, values := ts.Timestamps, ts.Values
+	for len(timestamps) > 0 {
+		n := len(timestamps)
+		if n > maxSamplesPerNativeBlock {
+			n = maxSamplesPerNativeBlock
+		}
+		var scale int16
+		nm.values, scale = decimal.AppendFloatToDecimal(nm.values[:0], values[:n])
+		nm.b.Init(&nm.tsid, timestamps[:n], nm.values, scale, 64)
+		nm.blockBuf = nm.b.MarshalPortable(nm.blockBuf[:0])
+
+		dst = encoding.MarshalUint32(dst, uint32(len(nm.metricNameBuf)))
+		dst = append(dst, nm.metricNameBuf...)
+		dst = encoding.MarshalUint32(dst, uint32(len(nm.blockBuf)))
+		dst = append(dst, nm.blockBuf...)
+
+		timestamps, values = timestamps[n:], values[n:]
+	}
+	return dst
+}
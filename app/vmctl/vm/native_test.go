@@ -0,0 +1,68 @@
+package vm
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/native/stream"
+)
+
+func TestNativeMarshaler(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	var timestamps []int64
+	var values []float64
+	for i := 0; i < maxSamplesPerNativeBlock+10; i++ {
+		timestamps = append(timestamps, int64(i)*1000)
+		values = append(values, float64(i)/2)
+	}
+	ts := &TimeSeries{
+		Name: "foo",
+		LabelPairs: []LabelPair{
+			{Name: "job", Value: "bar"},
+		},
+		Timestamps: timestamps,
+		Values:     values,
+	}
+	var nm NativeMarshaler
+	data := AppendNativeTimeRange(nil, 0, timestamps[len(timestamps)-1])
+	data = nm.AppendBlocks(data, ts)
+	// empty series must be skipped
+	data = nm.AppendBlocks(data, &TimeSeries{Name: "empty"})
+
+	var mu sync.Mutex
+	var blocks int
+	got := make(map[int64]float64)
+	err := stream.Parse(bytes.NewReader(data), "", func(block *stream.Block) error {
+		mu.Lock()
+		defer mu.Unlock()
+		blocks++
+		if name := string(block.MetricName.MetricGroup); name != "foo" {
+			t.Errorf("unexpected metric name %q", name)
+		}
+		if v := block.MetricName.GetTagValue("job"); string(v) != "bar" {
+			t.Errorf("unexpected job label %q", v)
+		}
+		for i, ts := range block.Timestamps {
+			got[ts] = block.Values[i]
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if blocks != 2 {
+		t.Fatalf("unexpected number of blocks; got %d; want 2", blocks)
+	}
+	if len(got) != len(timestamps) {
+		t.Fatalf("unexpected number of samples; got %d; want %d", len(got), len(timestamps))
+	}
+	for i, ts := range timestamps {
+		if got[ts] != values[i] {
+			t.Fatalf("unexpected value at %d; got %v; want %v", ts, got[ts], values[i])
+		}
+	}
+}
@@ -72,6 +72,9 @@ type Importer struct {
 
 	rl *limiter.Limiter
 
+	significantFigures int
+	roundDigits        int
+
 	wg   sync.WaitGroup
 	once sync.Once
 
@@ -136,6 +139,9 @@ func NewImporter(cfg Config) (*Importer, error) {
 		close:      make(chan struct{}),
 		input:      make(chan *TimeSeries, cfg.Concurrency*4),
 		errors:     make(chan *ImportError, cfg.Concurrency),
+
+		significantFigures: cfg.SignificantFigures,
+		roundDigits:        cfg.RoundDigits,
 	}
 	if err := im.Ping(); err != nil {
 		return nil, fmt.Errorf("ping to %q failed: %s", addr, err)
@@ -264,6 +270,17 @@ func (im *Importer) startWorker(bar *pb.ProgressBar, batchSize, significantFigur
 	}
 }
 
+// ImportBatch synchronously imports tsBatch with retries on recoverable errors.
+//
+// Unlike Input, it returns only after tsBatch has been delivered to VictoriaMetrics,
+// so the caller may track the import progress.
+func (im *Importer) ImportBatch(tsBatch []*TimeSeries) error {
+	for i, ts := range tsBatch {
+		tsBatch[i] = roundTimeseriesValue(ts, im.significantFigures, im.roundDigits)
+	}
+	return im.flush(tsBatch)
+}
+
 const (
 	// TODO: make configurable
 	backoffRetries     = 5
@@ -392,7 +409,9 @@ func do(req *http.Request) error {
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	if resp.StatusCode != http.StatusNoContent {
+	// 200 OK is returned if some lines were skipped by VictoriaMetrics as invalid.
+	// Such lines were skipped before the response with the summary for them has been introduced, so ignore them.
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read response body for status code %d: %s", resp.StatusCode, err)
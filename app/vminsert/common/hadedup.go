@@ -0,0 +1,63 @@
+package common
+
+import (
+	"flag"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/hadedup"
+)
+
+var (
+	haDedupReplicaLabel = flag.String("haDedup.replicaLabel", "", "Optional label name, which identifies the replica in HA pair of vmalert or Prometheus instances, "+
+		"which send the same data via Prometheus remote write protocol. If set, then only samples from a single elected replica are stored, "+
+		"while this label is removed from the stored samples. See https://docs.victoriametrics.com/#ha-deduplication")
+	haDedupClusterLabel = flag.String("haDedup.clusterLabel", "", "Optional label name, which identifies the cluster of HA replicas. "+
+		"Replicas are elected independently per each cluster. By default all the samples with -haDedup.replicaLabel belong to a single cluster. "+
+		"See https://docs.victoriametrics.com/#ha-deduplication")
+	haDedupFailoverTimeout = flag.Duration("haDedup.failoverTimeout", 90*time.Second, "Another replica is elected if the elected replica doesn't send samples during this timeout. "+
+		"The timeout must exceed the interval between samples sent by replicas, e.g. vmalert evaluation interval. See -haDedup.replicaLabel")
+)
+
+var haDedupTracker *hadedup.Tracker
+
+// InitHADedup must be called after flag.Parse and before using the common package.
+func InitHADedup() {
+	if *haDedupReplicaLabel == "" {
+		// Nothing to initialize
+		return
+	}
+	haDedupTracker = hadedup.NewTracker(uint64(haDedupFailoverTimeout.Seconds()))
+}
+
+// HasHADedup returns true if -haDedup.replicaLabel is set.
+func HasHADedup() bool {
+	return haDedupTracker != nil
+}
+
+// ApplyHADedup removes -haDedup.replicaLabel from ctx.Labels.
+//
+// It returns false if ctx.Labels belong to the replica, which isn't elected, so the samples must be dropped.
+func (ctx *InsertCtx) ApplyHADedup() bool {
+	replicaIdx := -1
+	cluster := ""
+	for i, label := range ctx.Labels {
+		name := bytesutil.ToUnsafeString(label.Name)
+		if name == *haDedupReplicaLabel {
+			replicaIdx = i
+		} else if *haDedupClusterLabel != "" && name == *haDedupClusterLabel {
+			cluster = bytesutil.ToUnsafeString(label.Value)
+		}
+	}
+	if replicaIdx < 0 {
+		// Samples without replica label are always accepted.
+		return true
+	}
+	replica := bytesutil.ToUnsafeString(ctx.Labels[replicaIdx].Value)
+	if !haDedupTracker.Accept(cluster, replica, fasttime.UnixTimestamp()) {
+		return false
+	}
+	ctx.Labels = append(ctx.Labels[:replicaIdx], ctx.Labels[replicaIdx+1:]...)
+	return true
+}
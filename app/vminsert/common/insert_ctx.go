@@ -1,10 +1,12 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/shadow"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
@@ -49,26 +51,44 @@ func (ctx *InsertCtx) Reset(rowsLen int) {
 	ctx.skipStreamAggr = false
 }
 
-func (ctx *InsertCtx) marshalMetricNameRaw(prefix []byte, labels []prompb.Label) []byte {
+// marshalMetricNameRaw returns prefix followed by marshaled labels.
+//
+// false is returned if labels are rejected because of label limits.
+func (ctx *InsertCtx) marshalMetricNameRaw(prefix []byte, labels []prompb.Label) ([]byte, bool) {
 	start := len(ctx.metricNamesBuf)
 	ctx.metricNamesBuf = append(ctx.metricNamesBuf, prefix...)
+	n := len(ctx.metricNamesBuf)
 	ctx.metricNamesBuf = storage.MarshalMetricNameRaw(ctx.metricNamesBuf, labels)
+	if len(labels) > 0 && len(ctx.metricNamesBuf) == n {
+		ctx.metricNamesBuf = ctx.metricNamesBuf[:start]
+		return nil, false
+	}
 	metricNameRaw := ctx.metricNamesBuf[start:]
-	return metricNameRaw[:len(metricNameRaw):len(metricNameRaw)]
+	return metricNameRaw[:len(metricNameRaw):len(metricNameRaw)], true
 }
 
 // WriteDataPoint writes (timestamp, value) with the given prefix and labels into ctx buffer.
+//
+// The data point is skipped if labels are rejected because of label limits.
 func (ctx *InsertCtx) WriteDataPoint(prefix []byte, labels []prompb.Label, timestamp int64, value float64) error {
-	metricNameRaw := ctx.marshalMetricNameRaw(prefix, labels)
+	metricNameRaw, ok := ctx.marshalMetricNameRaw(prefix, labels)
+	if !ok {
+		return nil
+	}
 	return ctx.addRow(metricNameRaw, timestamp, value)
 }
 
 // WriteDataPointExt writes (timestamp, value) with the given metricNameRaw and labels into ctx buffer.
 //
 // It returns metricNameRaw for the given labels if len(metricNameRaw) == 0.
+// The data point is skipped if labels are rejected because of label limits.
 func (ctx *InsertCtx) WriteDataPointExt(metricNameRaw []byte, labels []prompb.Label, timestamp int64, value float64) ([]byte, error) {
 	if len(metricNameRaw) == 0 {
-		metricNameRaw = ctx.marshalMetricNameRaw(nil, labels)
+		var ok bool
+		metricNameRaw, ok = ctx.marshalMetricNameRaw(nil, labels)
+		if !ok {
+			return nil, nil
+		}
 	}
 	err := ctx.addRow(metricNameRaw, timestamp, value)
 	return metricNameRaw, err
@@ -137,6 +157,10 @@ func (ctx *InsertCtx) ApplyRelabeling() {
 
 // FlushBufs flushes buffered rows to the underlying storage.
 func (ctx *InsertCtx) FlushBufs() error {
+	if !ctx.skipStreamAggr {
+		// Mirror the ingested samples before the stream aggregation, so the shadow storage receives the original data.
+		shadow.Push(ctx.mrs)
+	}
 	if sa != nil && !ctx.skipStreamAggr {
 		ctx.streamAggrCtx.push(ctx.mrs)
 		if !*streamAggrKeepInput {
@@ -152,8 +176,17 @@ func (ctx *InsertCtx) FlushBufs() error {
 	if err == nil {
 		return nil
 	}
+	statusCode := http.StatusServiceUnavailable
+	var header http.Header
+	var esc *httpserver.ErrorWithStatusCode
+	if errors.As(err, &esc) {
+		// Preserve the status code and headers returned by the storage, e.g. for backpressure.
+		statusCode = esc.StatusCode
+		header = esc.Header
+	}
 	return &httpserver.ErrorWithStatusCode{
 		Err:        fmt.Errorf("cannot store metrics: %w", err),
-		StatusCode: http.StatusServiceUnavailable,
+		StatusCode: statusCode,
+		Header:     header,
 	}
 }
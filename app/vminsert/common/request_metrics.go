@@ -0,0 +1,95 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// RequestMetrics contains histograms for ingestion requests over a particular protocol.
+//
+// See https://docs.victoriametrics.com/#ingestion-metrics
+type RequestMetrics struct {
+	requestSize       *metrics.Histogram
+	samplesPerRequest *metrics.Histogram
+	requestDuration   *metrics.Histogram
+}
+
+// NewRequestMetrics returns RequestMetrics for the given protocol.
+//
+// It must be called only once per protocol.
+func NewRequestMetrics(protocol string) *RequestMetrics {
+	return &RequestMetrics{
+		requestSize:       metrics.NewHistogram(fmt.Sprintf(`vm_ingestion_request_size_bytes{protocol=%q}`, protocol)),
+		samplesPerRequest: metrics.NewHistogram(fmt.Sprintf(`vm_ingestion_samples_per_request{protocol=%q}`, protocol)),
+		requestDuration:   metrics.NewHistogram(fmt.Sprintf(`vm_ingestion_request_duration_seconds{protocol=%q}`, protocol)),
+	}
+}
+
+// TrackRequest starts tracking the request with the body r.
+//
+// The body must be read via the returned RequestTracker.
+// RequestTracker.Done must be called when the request is processed.
+func (rm *RequestMetrics) TrackRequest(r io.Reader) *RequestTracker {
+	return &RequestTracker{
+		rm:        rm,
+		r:         r,
+		startTime: time.Now(),
+	}
+}
+
+// TrackHTTPRequest starts tracking req.
+//
+// It replaces req.Body, so the size of the read request body is tracked.
+// RequestTracker.Done must be called when the request is processed.
+func (rm *RequestMetrics) TrackHTTPRequest(req *http.Request) *RequestTracker {
+	rt := rm.TrackRequest(req.Body)
+	req.Body = rt
+	return rt
+}
+
+// RequestTracker tracks the size, the number of samples and the duration of a single ingestion request.
+//
+// For streaming protocols such as Graphite plaintext the request is a single client connection.
+type RequestTracker struct {
+	rm        *RequestMetrics
+	r         io.Reader
+	startTime time.Time
+
+	bytesRead uint64
+	samples   uint64
+}
+
+// Read implements io.Reader.
+func (rt *RequestTracker) Read(p []byte) (int, error) {
+	n, err := rt.r.Read(p)
+	atomic.AddUint64(&rt.bytesRead, uint64(n))
+	return n, err
+}
+
+// Close implements io.Closer.
+func (rt *RequestTracker) Close() error {
+	if c, ok := rt.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// AddSamples registers n samples ingested from the tracked request.
+//
+// It is safe calling AddSamples from concurrently running goroutines.
+func (rt *RequestTracker) AddSamples(n int) {
+	atomic.AddUint64(&rt.samples, uint64(n))
+}
+
+// Done updates request metrics for the tracked request.
+func (rt *RequestTracker) Done() {
+	rm := rt.rm
+	rm.requestSize.Update(float64(atomic.LoadUint64(&rt.bytesRead)))
+	rm.samplesPerRequest.Update(float64(atomic.LoadUint64(&rt.samples)))
+	rm.requestDuration.UpdateDuration(rt.startTime)
+}
@@ -13,22 +13,26 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="csvimport"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="csvimport"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="csvimport"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="csvimport"}`)
+	requestMetrics = common.NewRequestMetrics("csvimport")
 )
 
 // InsertHandler processes /api/v1/import/csv requests.
 func InsertHandler(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
 	return stream.Parse(req, func(rows []parser.Row) error {
-		return insertRows(rows, extraLabels)
+		return insertRows(rows, extraLabels, rt)
 	})
 }
 
-func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
+func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
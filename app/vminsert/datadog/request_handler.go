@@ -13,25 +13,29 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="datadog"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="datadog"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="datadog"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="datadog"}`)
+	requestMetrics = common.NewRequestMetrics("datadog")
 )
 
 // InsertHandlerForHTTP processes remote write for DataDog POST /api/v1/series request.
 //
 // See https://docs.datadoghq.com/api/latest/metrics/#submit-metrics
 func InsertHandlerForHTTP(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
 	ce := req.Header.Get("Content-Encoding")
 	return stream.Parse(req.Body, ce, func(series []parser.Series) error {
-		return insertRows(series, extraLabels)
+		return insertRows(series, extraLabels, rt)
 	})
 }
 
-func insertRows(series []parser.Series, extraLabels []prompbmarshal.Label) error {
+func insertRows(series []parser.Series, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -84,6 +88,7 @@ func insertRows(series []parser.Series, extraLabels []prompbmarshal.Label) error
 		}
 	}
 	rowsInserted.Add(rowsTotal)
+	rt.AddSamples(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
 	return ctx.FlushBufs()
 }
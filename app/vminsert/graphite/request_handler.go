@@ -11,18 +11,24 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="graphite"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="graphite"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="graphite"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="graphite"}`)
+	requestMetrics = common.NewRequestMetrics("graphite")
 )
 
 // InsertHandler processes remote write for graphite plaintext protocol.
 //
 // See https://graphite.readthedocs.io/en/latest/feeding-carbon.html#the-plaintext-protocol
 func InsertHandler(r io.Reader) error {
-	return stream.Parse(r, insertRows)
+	rt := requestMetrics.TrackRequest(r)
+	defer rt.Done()
+
+	return stream.Parse(rt, func(rows []parser.Row) error {
+		return insertRows(rows, rt)
+	})
 }
 
-func insertRows(rows []parser.Row) error {
+func insertRows(rows []parser.Row, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
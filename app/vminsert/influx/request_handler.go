@@ -27,16 +27,20 @@ var (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="influx"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="influx"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="influx"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="influx"}`)
+	requestMetrics = common.NewRequestMetrics("influx")
 )
 
 // InsertHandlerForReader processes remote write for influx line protocol.
 //
 // See https://github.com/influxdata/telegraf/tree/master/plugins/inputs/socket_listener/
 func InsertHandlerForReader(r io.Reader) error {
-	return stream.Parse(r, false, "", "", func(db string, rows []parser.Row) error {
-		return insertRows(db, rows, nil)
+	rt := requestMetrics.TrackRequest(r)
+	defer rt.Done()
+
+	return stream.Parse(rt, "", "", "", func(db string, rows []parser.Row) error {
+		return insertRows(db, rows, nil, rt)
 	})
 }
 
@@ -44,21 +48,24 @@ func InsertHandlerForReader(r io.Reader) error {
 //
 // See https://github.com/influxdata/influxdb/blob/4cbdc197b8117fee648d62e2e5be75c6575352f0/tsdb/README.md
 func InsertHandlerForHTTP(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
-	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
+	ce := req.Header.Get("Content-Encoding")
 	q := req.URL.Query()
 	precision := q.Get("precision")
 	// Read db tag from https://docs.influxdata.com/influxdb/v1.7/tools/api/#write-http-endpoint
 	db := q.Get("db")
-	return stream.Parse(req.Body, isGzipped, precision, db, func(db string, rows []parser.Row) error {
-		return insertRows(db, rows, extraLabels)
+	return stream.Parse(req.Body, ce, precision, db, func(db string, rows []parser.Row) error {
+		return insertRows(db, rows, extraLabels, rt)
 	})
 }
 
-func insertRows(db string, rows []parser.Row, extraLabels []prompbmarshal.Label) error {
+func insertRows(db string, rows []parser.Row, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := getPushCtx()
 	defer putPushCtx(ctx)
 
@@ -122,6 +129,10 @@ func insertRows(db string, rows []parser.Row, extraLabels []prompbmarshal.Label)
 		} else {
 			ic.SortLabelsIfNeeded()
 			ctx.metricNameBuf = storage.MarshalMetricNameRaw(ctx.metricNameBuf[:0], ic.Labels)
+			if len(ctx.metricNameBuf) == 0 {
+				// The time series is rejected because of label limits.
+				continue
+			}
 			labelsLen := len(ic.Labels)
 			for j := range r.Fields {
 				f := &r.Fields[j]
@@ -142,6 +153,7 @@ func insertRows(db string, rows []parser.Row, extraLabels []prompbmarshal.Label)
 		}
 	}
 	rowsInserted.Add(rowsTotal)
+	rt.AddSamples(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
 	return ic.FlushBufs()
 }
@@ -0,0 +1,71 @@
+package jsonimport
+
+import (
+	"net/http"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/jsonmapping"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/jsonmapping/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="jsonimport"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="jsonimport"}`)
+	requestMetrics = common.NewRequestMetrics("jsonimport")
+)
+
+// InsertHandler processes /api/v1/import/json requests.
+//
+// See https://docs.victoriametrics.com/#how-to-import-arbitrary-json-documents
+func InsertHandler(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
+	extraLabels, err := parserCommon.GetExtraLabels(req)
+	if err != nil {
+		return err
+	}
+	ce := req.Header.Get("Content-Encoding")
+	return stream.Parse(req.Body, ce, func(rows []parser.Row) error {
+		return insertRows(rows, extraLabels, rt)
+	})
+}
+
+func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
+	ctx := common.GetInsertCtx()
+	defer common.PutInsertCtx(ctx)
+
+	ctx.Reset(len(rows))
+	hasRelabeling := relabel.HasRelabeling()
+	for i := range rows {
+		r := &rows[i]
+		ctx.Labels = ctx.Labels[:0]
+		ctx.AddLabel("", r.Metric)
+		for j := range r.Tags {
+			tag := &r.Tags[j]
+			ctx.AddLabel(tag.Key, tag.Value)
+		}
+		for j := range extraLabels {
+			label := &extraLabels[j]
+			ctx.AddLabel(label.Name, label.Value)
+		}
+		if hasRelabeling {
+			ctx.ApplyRelabeling()
+		}
+		if len(ctx.Labels) == 0 {
+			// Skip metric without labels.
+			continue
+		}
+		ctx.SortLabelsIfNeeded()
+		if err := ctx.WriteDataPoint(nil, ctx.Labels, r.Timestamp, r.Value); err != nil {
+			return err
+		}
+	}
+	rowsInserted.Add(len(rows))
+	rowsPerInsert.Update(float64(len(rows)))
+	return ctx.FlushBufs()
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/datadog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/influx"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/jsonimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/native"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/opentsdb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/opentsdbhttp"
@@ -21,19 +22,28 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/prompush"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/promremotewrite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/shadow"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/statsd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/vmimport"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/influxutils"
 	graphiteserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/graphite"
 	influxserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/influx"
+	multiprotoserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/multiproto"
 	opentsdbserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/opentsdb"
 	opentsdbhttpserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/opentsdbhttp"
+	statsdserver "github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	jsonimportstream "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/jsonmapping/stream"
+	vmimportstream "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/vmimport/stream"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metrics"
 )
@@ -58,16 +68,36 @@ var (
 		"See also -opentsdbHTTPListenAddr.useProxyProtocol")
 	opentsdbHTTPUseProxyProtocol = flag.Bool("opentsdbHTTPListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted "+
 		"at -opentsdbHTTPListenAddr . See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
+	statsdListenAddr = flag.String("statsdListenAddr", "", "TCP and UDP address to listen for StatsD metrics. Usually :8125 must be set. Doesn't work if empty. "+
+		"The received metrics are aggregated over -statsd.flushInterval and converted into Prometheus-style series according to -statsd.mappingConfig. "+
+		"See also -statsdListenAddr.useProxyProtocol")
+	statsdUseProxyProtocol = flag.Bool("statsdListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -statsdListenAddr . "+
+		"See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
+	ingestListenAddr = flag.String("ingestListenAddr", "", "TCP address to listen for InfluxDB line protocol, Graphite plaintext protocol, OpenTSDB telnet protocol "+
+		"and Prometheus remote write requests on a single port. The protocol is automatically detected for every incoming connection. Doesn't work if empty. "+
+		"See also -ingestListenAddr.useProxyProtocol")
+	ingestUseProxyProtocol = flag.Bool("ingestListenAddr.useProxyProtocol", false, "Whether to use proxy protocol for connections accepted at -ingestListenAddr . "+
+		"See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt")
 	configAuthKey          = flag.String("configAuthKey", "", "Authorization key for accessing /config page. It must be passed via authKey query arg")
 	maxLabelsPerTimeseries = flag.Int("maxLabelsPerTimeseries", 30, "The maximum number of labels accepted per time series. Superfluous labels are dropped. In this case the vm_metrics_with_dropped_labels_total metric at /metrics page is incremented")
-	maxLabelValueLen       = flag.Int("maxLabelValueLen", 16*1024, "The maximum length of label values in the accepted time series. Longer label values are truncated. In this case the vm_too_long_label_values_total metric at /metrics page is incremented")
+	maxLabelValueLen       = flag.Int("maxLabelValueLen", 16*1024, "The maximum length of label values in the accepted time series. Longer label values are handled according to -labelLimitsPolicy. "+
+		"In this case the vm_too_long_label_values_total metric at /metrics page is incremented")
+	maxLabelNameLen = flag.Int("maxLabelNameLen", 256, "The maximum length of label names in the accepted time series. Longer label names are handled according to -labelLimitsPolicy. "+
+		"In this case the vm_too_long_label_names_total metric at /metrics page is incremented")
+	labelLimitsPolicy = flag.String("labelLimitsPolicy", "truncate", "The policy for labels exceeding -maxLabelNameLen and -maxLabelValueLen limits. "+
+		"Supported values: truncate - truncate too long names and values; truncateWithHash - truncate too long names and values and replace their tails with the hash of the original, "+
+		"so distinct values remain distinct; dropLabel - drop labels with too long names or values; reject - reject time series exceeding -maxLabelNameLen, -maxLabelValueLen "+
+		"or -maxLabelsPerTimeseries limits. The number of actions is exposed via vm_label_limits_actions_total metric at /metrics page. "+
+		"See https://docs.victoriametrics.com/#label-limits")
 )
 
 var (
 	graphiteServer     *graphiteserver.Server
+	statsdServer       *statsdserver.Server
 	influxServer       *influxserver.Server
 	opentsdbServer     *opentsdbserver.Server
 	opentsdbhttpServer *opentsdbhttpserver.Server
+	multiprotoServer   *multiprotoserver.Server
 )
 
 //go:embed static
@@ -78,13 +108,24 @@ var staticServer = http.FileServer(http.FS(staticFiles))
 // Init initializes vminsert.
 func Init() {
 	relabel.Init()
+	shadow.Init()
 	vminsertCommon.InitStreamAggr()
+	vminsertCommon.InitHADedup()
 	storage.SetMaxLabelsPerTimeseries(*maxLabelsPerTimeseries)
 	storage.SetMaxLabelValueLen(*maxLabelValueLen)
+	storage.SetMaxLabelNameLen(*maxLabelNameLen)
+	if err := storage.SetLabelLimitsPolicy(*labelLimitsPolicy); err != nil {
+		logger.Fatalf("invalid -labelLimitsPolicy: %s", err)
+	}
 	common.StartUnmarshalWorkers()
+	jsonimportstream.Init()
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer = graphiteserver.MustStart(*graphiteListenAddr, *graphiteUseProxyProtocol, graphite.InsertHandler)
 	}
+	if len(*statsdListenAddr) > 0 {
+		statsd.Init()
+		statsdServer = statsdserver.MustStart(*statsdListenAddr, *statsdUseProxyProtocol, statsd.InsertHandler)
+	}
 	if len(*influxListenAddr) > 0 {
 		influxServer = influxserver.MustStart(*influxListenAddr, *influxUseProxyProtocol, influx.InsertHandlerForReader)
 	}
@@ -94,6 +135,14 @@ func Init() {
 	if len(*opentsdbHTTPListenAddr) > 0 {
 		opentsdbhttpServer = opentsdbhttpserver.MustStart(*opentsdbHTTPListenAddr, *opentsdbHTTPUseProxyProtocol, opentsdbhttp.InsertHandler)
 	}
+	if len(*ingestListenAddr) > 0 {
+		multiprotoServer = multiprotoserver.MustStart(*ingestListenAddr, *ingestUseProxyProtocol, multiprotoserver.Handlers{
+			Influx:   influx.InsertHandlerForReader,
+			Graphite: graphite.InsertHandler,
+			OpenTSDB: opentsdb.InsertHandler,
+			HTTP:     multiprotoHTTPInsertHandler,
+		})
+	}
 	promscrape.Init(func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
 		prompush.Push(wr)
 	})
@@ -105,6 +154,10 @@ func Stop() {
 	if len(*graphiteListenAddr) > 0 {
 		graphiteServer.MustStop()
 	}
+	if len(*statsdListenAddr) > 0 {
+		statsdServer.MustStop()
+		statsd.Stop()
+	}
 	if len(*influxListenAddr) > 0 {
 		influxServer.MustStop()
 	}
@@ -114,8 +167,23 @@ func Stop() {
 	if len(*opentsdbHTTPListenAddr) > 0 {
 		opentsdbhttpServer.MustStop()
 	}
+	if len(*ingestListenAddr) > 0 {
+		multiprotoServer.MustStop()
+	}
 	common.StopUnmarshalWorkers()
 	vminsertCommon.MustStopStreamAggr()
+	shadow.Stop()
+}
+
+// multiprotoHTTPInsertHandler processes HTTP requests accepted at -ingestListenAddr.
+func multiprotoHTTPInsertHandler(req *http.Request) error {
+	path := strings.Replace(req.URL.Path, "//", "/", -1)
+	switch path {
+	case "/prometheus/api/v1/write", "/api/v1/write":
+		return promremotewrite.InsertHandler(req)
+	default:
+		return fmt.Errorf("unsupported path requested at -ingestListenAddr: %q; expecting '/api/v1/write'", path)
+	}
 }
 
 // RequestHandler is a handler for Prometheus remote storage write API
@@ -163,16 +231,18 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
+		w.Header().Set(labelsintern.SupportHeader, "1")
 		w.WriteHeader(http.StatusNoContent)
 		return true
 	case "/prometheus/api/v1/import", "/api/v1/import":
 		vmimportRequests.Inc()
-		if err := vmimport.InsertHandler(r); err != nil {
+		res, err := vmimport.InsertHandler(r)
+		if err != nil {
 			vmimportErrors.Inc()
 			httpserver.Errorf(w, r, "%s", err)
 			return true
 		}
-		w.WriteHeader(http.StatusNoContent)
+		vmimportstream.WriteResponse(w, res)
 		return true
 	case "/prometheus/api/v1/import/csv", "/api/v1/import/csv":
 		csvimportRequests.Inc()
@@ -192,6 +262,15 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
+	case "/prometheus/api/v1/import/json", "/api/v1/import/json":
+		jsonimportRequests.Inc()
+		if err := jsonimport.InsertHandler(r); err != nil {
+			jsonimportErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return true
 	case "/influx/write", "/influx/api/v2/write", "/write", "/api/v2/write":
 		influxWriteRequests.Inc()
 		addInfluxResponseHeaders(w)
@@ -285,6 +364,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		return true
 	case "/prometheus/-/reload", "/-/reload":
 		promscrapeConfigReloadRequests.Inc()
+		auditlog.Log(auditlog.NewEvent(r, "config_reload_request"), nil)
 		procutil.SelfSIGHUP()
 		w.WriteHeader(http.StatusNoContent)
 		return true
@@ -328,6 +408,9 @@ var (
 	nativeimportRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 	nativeimportErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/import/native", protocol="nativeimport"}`)
 
+	jsonimportRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/import/json", protocol="jsonimport"}`)
+	jsonimportErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/import/json", protocol="jsonimport"}`)
+
 	influxWriteRequests = metrics.NewCounter(`vm_http_requests_total{path="/influx/write", protocol="influx"}`)
 	influxWriteErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/influx/write", protocol="influx"}`)
 
@@ -363,4 +446,16 @@ var (
 	_ = metrics.NewGauge(`vm_too_long_label_values_total`, func() float64 {
 		return float64(atomic.LoadUint64(&storage.TooLongLabelValues))
 	})
+	_ = metrics.NewGauge(`vm_label_limits_actions_total{action="truncate"}`, func() float64 {
+		return float64(atomic.LoadUint64(&storage.LabelLimitsTruncated))
+	})
+	_ = metrics.NewGauge(`vm_label_limits_actions_total{action="truncateWithHash"}`, func() float64 {
+		return float64(atomic.LoadUint64(&storage.LabelLimitsTruncatedWithHash))
+	})
+	_ = metrics.NewGauge(`vm_label_limits_actions_total{action="dropLabel"}`, func() float64 {
+		return float64(atomic.LoadUint64(&storage.LabelLimitsDroppedLabels))
+	})
+	_ = metrics.NewGauge(`vm_label_limits_actions_total{action="reject"}`, func() float64 {
+		return float64(atomic.LoadUint64(&storage.LabelLimitsRejectedSeries))
+	})
 )
@@ -16,23 +16,27 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="native"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="native"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="native"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="native"}`)
+	requestMetrics = common.NewRequestMetrics("native")
 )
 
 // InsertHandler processes `/api/v1/import/native` request.
 func InsertHandler(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
-	isGzip := req.Header.Get("Content-Encoding") == "gzip"
-	return stream.Parse(req.Body, isGzip, func(block *stream.Block) error {
-		return insertRows(block, extraLabels)
+	ce := req.Header.Get("Content-Encoding")
+	return stream.Parse(req.Body, ce, func(block *stream.Block) error {
+		return insertRows(block, extraLabels, rt)
 	})
 }
 
-func insertRows(block *stream.Block, extraLabels []prompbmarshal.Label) error {
+func insertRows(block *stream.Block, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := getPushCtx()
 	defer putPushCtx(ctx)
 
@@ -40,6 +44,7 @@ func insertRows(block *stream.Block, extraLabels []prompbmarshal.Label) error {
 	// since relabeling can prevent from inserting the rows.
 	rowsLen := len(block.Values)
 	rowsInserted.Add(rowsLen)
+	rt.AddSamples(rowsLen)
 	rowsPerInsert.Update(float64(rowsLen))
 
 	ic := &ctx.Common
@@ -65,6 +70,10 @@ func insertRows(block *stream.Block, extraLabels []prompbmarshal.Label) error {
 	}
 	ic.SortLabelsIfNeeded()
 	ctx.metricNameBuf = storage.MarshalMetricNameRaw(ctx.metricNameBuf[:0], ic.Labels)
+	if len(ctx.metricNameBuf) == 0 {
+		// The time series is rejected because of label limits.
+		return nil
+	}
 	values := block.Values
 	timestamps := block.Timestamps
 	if len(timestamps) != len(values) {
@@ -11,18 +11,24 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="opentsdb"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="opentsdb"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="opentsdb"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="opentsdb"}`)
+	requestMetrics = common.NewRequestMetrics("opentsdb")
 )
 
 // InsertHandler processes remote write for OpenTSDB put protocol.
 //
 // See http://opentsdb.net/docs/build/html/api_telnet/put.html
 func InsertHandler(r io.Reader) error {
-	return stream.Parse(r, insertRows)
+	rt := requestMetrics.TrackRequest(r)
+	defer rt.Done()
+
+	return stream.Parse(rt, func(rows []parser.Row) error {
+		return insertRows(rows, rt)
+	})
 }
 
-func insertRows(rows []parser.Row) error {
+func insertRows(rows []parser.Row, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -14,29 +14,35 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="opentsdbhttp"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="opentsdbhttp"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="opentsdbhttp"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="opentsdbhttp"}`)
+	requestMetrics = common.NewRequestMetrics("opentsdbhttp")
 )
 
-// InsertHandler processes HTTP OpenTSDB put requests.
+// InsertHandler processes HTTP OpenTSDB put, rollup and histogram requests.
 // See http://opentsdb.net/docs/build/html/api_http/put.html
 func InsertHandler(req *http.Request) error {
 	path := req.URL.Path
 	switch path {
-	case "/opentsdb/api/put", "/api/put":
+	case "/opentsdb/api/put", "/api/put",
+		"/opentsdb/api/rollup", "/api/rollup",
+		"/opentsdb/api/histogram", "/api/histogram":
+		rt := requestMetrics.TrackHTTPRequest(req)
+		defer rt.Done()
+
 		extraLabels, err := parserCommon.GetExtraLabels(req)
 		if err != nil {
 			return err
 		}
 		return stream.Parse(req, func(rows []parser.Row) error {
-			return insertRows(rows, extraLabels)
+			return insertRows(rows, extraLabels, rt)
 		})
 	default:
 		return fmt.Errorf("unexpected path requested on HTTP OpenTSDB server: %q", path)
 	}
 }
 
-func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
+func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
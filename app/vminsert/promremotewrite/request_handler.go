@@ -5,6 +5,7 @@ import (
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/relabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	parserCommon "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
@@ -13,23 +14,32 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="promremotewrite"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="promremotewrite"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="promremotewrite"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="promremotewrite"}`)
+	requestMetrics = common.NewRequestMetrics("promremotewrite")
 )
 
 // InsertHandler processes remote write for prometheus.
 func InsertHandler(req *http.Request) error {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
 		return err
 	}
+	if sessionID := req.Header.Get(labelsintern.SessionHeader); sessionID != "" {
+		return stream.ParseInterned(req.Body, sessionID, func(tss []prompb.TimeSeries) error {
+			return insertRows(tss, extraLabels, rt)
+		})
+	}
 	isVMRemoteWrite := req.Header.Get("Content-Encoding") == "zstd"
 	return stream.Parse(req.Body, isVMRemoteWrite, func(tss []prompb.TimeSeries) error {
-		return insertRows(tss, extraLabels)
+		return insertRows(tss, extraLabels, rt)
 	})
 }
 
-func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label) error {
+func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := common.GetInsertCtx()
 	defer common.PutInsertCtx(ctx)
 
@@ -40,6 +50,7 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 	ctx.Reset(rowsLen)
 	rowsTotal := 0
 	hasRelabeling := relabel.HasRelabeling()
+	hasHADedup := common.HasHADedup()
 	for i := range timeseries {
 		ts := &timeseries[i]
 		rowsTotal += len(ts.Samples)
@@ -52,6 +63,10 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 			label := &extraLabels[j]
 			ctx.AddLabel(label.Name, label.Value)
 		}
+		if hasHADedup && !ctx.ApplyHADedup() {
+			// Drop samples from the replica, which isn't elected.
+			continue
+		}
 		if hasRelabeling {
 			ctx.ApplyRelabeling()
 		}
@@ -72,6 +87,7 @@ func insertRows(timeseries []prompb.TimeSeries, extraLabels []prompbmarshal.Labe
 		}
 	}
 	rowsInserted.Add(rowsTotal)
+	rt.AddSamples(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
 	return ctx.FlushBufs()
 }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync/atomic"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -27,6 +28,8 @@ var (
 
 // Init must be called after flag.Parse and before using the relabel package.
 func Init() {
+	initSampling()
+
 	// Register SIGHUP handler for config re-read just before loadRelabelConfig call.
 	// This guarantees that the config will be re-read if the signal arrives during loadRelabelConfig call.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1240
@@ -57,6 +60,9 @@ func Init() {
 			pcsGlobal.Store(pcs)
 			configSuccess.Set(1)
 			configTimestamp.Set(fasttime.UnixTimestamp())
+			ae := auditlog.NewEvent(nil, "config_applied")
+			ae.Target = "-relabelConfig"
+			auditlog.Log(ae, nil)
 			logger.Infof("successfully reloaded -relabelConfig=%q", *relabelConfig)
 		}
 	}()
@@ -85,13 +91,17 @@ func loadRelabelConfig() (*promrelabel.ParsedConfigs, error) {
 // HasRelabeling returns true if there is global relabeling.
 func HasRelabeling() bool {
 	pcs := pcsGlobal.Load().(*promrelabel.ParsedConfigs)
-	return pcs.Len() > 0 || *usePromCompatibleNaming
+	srs := samplingRulesGlobal.Load().(*samplingRules)
+	return pcs.Len() > 0 || srs.Len() > 0 || *usePromCompatibleNaming
 }
 
 // Ctx holds relabeling context.
 type Ctx struct {
 	// tmpLabels is used during ApplyRelabeling call.
 	tmpLabels []prompbmarshal.Label
+
+	// hashBuf is used for calculating series hash during sampling.
+	hashBuf []byte
 }
 
 // Reset resets ctx.
@@ -105,7 +115,8 @@ func (ctx *Ctx) Reset() {
 // The returned labels are valid until the next call to ApplyRelabeling.
 func (ctx *Ctx) ApplyRelabeling(labels []prompb.Label) []prompb.Label {
 	pcs := pcsGlobal.Load().(*promrelabel.ParsedConfigs)
-	if pcs.Len() == 0 && !*usePromCompatibleNaming {
+	srs := samplingRulesGlobal.Load().(*samplingRules)
+	if pcs.Len() == 0 && srs.Len() == 0 && !*usePromCompatibleNaming {
 		// There are no relabeling rules.
 		return labels
 	}
@@ -144,6 +155,11 @@ func (ctx *Ctx) ApplyRelabeling(labels []prompb.Label) []prompb.Label {
 		}
 	}
 
+	if srs.Len() > 0 && len(tmpLabels) > 0 {
+		// Apply sampling to the relabeled series.
+		tmpLabels, ctx.hashBuf = srs.apply(tmpLabels, *samplingRatioLabel, ctx.hashBuf)
+	}
+
 	ctx.tmpLabels = tmpLabels
 
 	// Return back labels to the desired format.
@@ -0,0 +1,199 @@
+package relabel
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	samplingConfig = flag.String("ingestSampling.config", "", "Optional path to a file with rules for storing only a sample of the ingested series "+
+		"matching the given series selectors. The path can point either to local file or to http url. "+
+		"See https://docs.victoriametrics.com/#ingestion-sampling for details. The config is reloaded on SIGHUP signal")
+	samplingRatioLabel = flag.String("ingestSampling.ratioLabel", "sample_ratio", "The name of the label with the sampling ratio, which is added to series "+
+		"stored according to -ingestSampling.config. See https://docs.victoriametrics.com/#ingestion-sampling")
+)
+
+// SamplingRule is a rule for storing only a sample of the ingested series.
+type SamplingRule struct {
+	// Match is a series selector for the series to sample.
+	Match *promrelabel.IfExpression `yaml:"match"`
+
+	// Ratio is the ratio of the matching series in the range (0..1] to store.
+	Ratio float64 `yaml:"ratio"`
+}
+
+type samplingRule struct {
+	ie        *promrelabel.IfExpression
+	threshold uint64
+	ratioStr  string
+}
+
+type samplingRules struct {
+	rules []samplingRule
+}
+
+// Len returns the number of sampling rules in srs.
+func (srs *samplingRules) Len() int {
+	if srs == nil {
+		return 0
+	}
+	return len(srs.rules)
+}
+
+func initSampling() {
+	// Register SIGHUP handler for config re-read just before loadSamplingConfig call.
+	// This guarantees that the config will be re-read if the signal arrives during loadSamplingConfig call.
+	sighupCh := procutil.NewSighupChan()
+
+	srs, err := loadSamplingConfig()
+	if err != nil {
+		logger.Fatalf("cannot load -ingestSampling.config: %s", err)
+	}
+	samplingRulesGlobal.Store(srs)
+
+	if len(*samplingConfig) == 0 {
+		return
+	}
+	samplingConfigSuccess.Set(1)
+	samplingConfigTimestamp.Set(fasttime.UnixTimestamp())
+	go func() {
+		for range sighupCh {
+			samplingConfigReloads.Inc()
+			logger.Infof("received SIGHUP; reloading -ingestSampling.config=%q...", *samplingConfig)
+			srs, err := loadSamplingConfig()
+			if err != nil {
+				samplingConfigReloadErrors.Inc()
+				samplingConfigSuccess.Set(0)
+				logger.Errorf("cannot load the updated -ingestSampling.config: %s; preserving the previous config", err)
+				continue
+			}
+			samplingRulesGlobal.Store(srs)
+			samplingConfigSuccess.Set(1)
+			samplingConfigTimestamp.Set(fasttime.UnixTimestamp())
+			logger.Infof("successfully reloaded -ingestSampling.config=%q", *samplingConfig)
+		}
+	}()
+}
+
+var (
+	samplingConfigReloads      = metrics.NewCounter(`vm_ingest_sampling_config_reloads_total`)
+	samplingConfigReloadErrors = metrics.NewCounter(`vm_ingest_sampling_config_reloads_errors_total`)
+	samplingConfigSuccess      = metrics.NewCounter(`vm_ingest_sampling_config_last_reload_successful`)
+	samplingConfigTimestamp    = metrics.NewCounter(`vm_ingest_sampling_config_last_reload_success_timestamp_seconds`)
+)
+
+var samplingRulesGlobal atomic.Value
+
+func loadSamplingConfig() (*samplingRules, error) {
+	if len(*samplingConfig) == 0 {
+		return nil, nil
+	}
+	data, err := fs.ReadFileOrHTTP(*samplingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read -ingestSampling.config=%q: %w", *samplingConfig, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at -ingestSampling.config=%q: %w", *samplingConfig, err)
+	}
+	srs, err := parseSamplingConfigData(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -ingestSampling.config=%q: %w", *samplingConfig, err)
+	}
+	return srs, nil
+}
+
+func parseSamplingConfigData(data []byte) (*samplingRules, error) {
+	var cfgs []SamplingRule
+	if err := yaml.UnmarshalStrict(data, &cfgs); err != nil {
+		return nil, err
+	}
+	rules := make([]samplingRule, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Match == nil {
+			return nil, fmt.Errorf("missing `match` option in the rule #%d", i+1)
+		}
+		if cfg.Ratio <= 0 || cfg.Ratio > 1 {
+			return nil, fmt.Errorf("`ratio` must be in the range (0..1] in the rule #%d; got %v", i+1, cfg.Ratio)
+		}
+		threshold := uint64(math.MaxUint64)
+		if cfg.Ratio < 1 {
+			threshold = uint64(cfg.Ratio * math.MaxUint64)
+		}
+		rules = append(rules, samplingRule{
+			ie:        cfg.Match,
+			threshold: threshold,
+			ratioStr:  strconv.FormatFloat(cfg.Ratio, 'g', -1, 64),
+		})
+	}
+	return &samplingRules{
+		rules: rules,
+	}, nil
+}
+
+// apply applies the first matching sampling rule from srs to labels.
+//
+// It returns empty labels if the series for the given labels must be dropped.
+// Otherwise the label with the sampling ratio is added to labels.
+func (srs *samplingRules) apply(labels []prompbmarshal.Label, ratioLabel string, buf []byte) ([]prompbmarshal.Label, []byte) {
+	for i := range srs.rules {
+		sr := &srs.rules[i]
+		if !sr.ie.Match(labels) {
+			continue
+		}
+		var h uint64
+		h, buf = hashSeries(labels, ratioLabel, buf)
+		if h > sr.threshold {
+			samplingDroppedSamples.Inc()
+			return labels[:0], buf
+		}
+		return setLabelValue(labels, ratioLabel, sr.ratioStr), buf
+	}
+	return labels, buf
+}
+
+// hashSeries returns a hash for the given labels, which doesn't depend on the order of labels.
+//
+// The label with the ratioLabel name is ignored, so the hash remains the same for already sampled series.
+func hashSeries(labels []prompbmarshal.Label, ratioLabel string, buf []byte) (uint64, []byte) {
+	var h uint64
+	for _, label := range labels {
+		if label.Name == ratioLabel {
+			continue
+		}
+		buf = append(buf[:0], label.Name...)
+		buf = append(buf, '=')
+		buf = append(buf, label.Value...)
+		h += xxhash.Sum64(buf)
+	}
+	return h, buf
+}
+
+func setLabelValue(labels []prompbmarshal.Label, name, value string) []prompbmarshal.Label {
+	for i := range labels {
+		if labels[i].Name == name {
+			labels[i].Value = value
+			return labels
+		}
+	}
+	return append(labels, prompbmarshal.Label{
+		Name:  name,
+		Value: value,
+	})
+}
+
+var samplingDroppedSamples = metrics.NewCounter(`vm_ingest_sampling_dropped_samples_total`)
@@ -0,0 +1,138 @@
+package relabel
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestParseSamplingConfigDataFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseSamplingConfigData([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error for %q", data)
+		}
+	}
+
+	// Invalid yaml
+	f("foobar")
+
+	// Unknown option
+	f(`
+- match: '{__name__="foo"}'
+  ratio: 0.5
+  foo: bar
+`)
+
+	// Missing match
+	f(`
+- ratio: 0.5
+`)
+
+	// Invalid match
+	f(`
+- match: 'foo{'
+  ratio: 0.5
+`)
+
+	// Invalid ratio
+	f(`
+- match: '{__name__="foo"}'
+`)
+	f(`
+- match: '{__name__="foo"}'
+  ratio: 1.5
+`)
+	f(`
+- match: '{__name__="foo"}'
+  ratio: -0.1
+`)
+}
+
+func TestSamplingRulesApply(t *testing.T) {
+	srs, err := parseSamplingConfigData([]byte(`
+- match: '{__name__=~"debug_.+"}'
+  ratio: 0.1
+- match: '{job="all"}'
+  ratio: 1
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var buf []byte
+	apply := func(labels []prompbmarshal.Label) []prompbmarshal.Label {
+		var result []prompbmarshal.Label
+		result, buf = srs.apply(labels, "sample_ratio", buf)
+		return result
+	}
+
+	// Series not matching any rule must be left untouched.
+	labels := apply([]prompbmarshal.Label{
+		{Name: "__name__", Value: "foo"},
+		{Name: "job", Value: "bar"},
+	})
+	if s := labelsString(labels); s != `__name__=foo,job=bar` {
+		t.Fatalf("unexpected labels for non-matching series: %s", s)
+	}
+
+	// All the series must be stored with ratio=1.
+	labels = apply([]prompbmarshal.Label{
+		{Name: "__name__", Value: "foo"},
+		{Name: "job", Value: "all"},
+	})
+	if s := labelsString(labels); s != `__name__=foo,job=all,sample_ratio=1` {
+		t.Fatalf("unexpected labels for series with ratio=1: %s", s)
+	}
+
+	// Roughly 10% of debug series must be stored.
+	const seriesCount = 10000
+	stored := 0
+	for i := 0; i < seriesCount; i++ {
+		labels := apply([]prompbmarshal.Label{
+			{Name: "__name__", Value: "debug_requests_total"},
+			{Name: "instance", Value: fmt.Sprintf("host-%d", i)},
+		})
+		if len(labels) == 0 {
+			continue
+		}
+		stored++
+		if s := labelsString(labels); s != fmt.Sprintf(`__name__=debug_requests_total,instance=host-%d,sample_ratio=0.1`, i) {
+			t.Fatalf("unexpected labels for sampled series: %s", s)
+		}
+	}
+	if ratio := float64(stored) / seriesCount; math.Abs(ratio-0.1) > 0.02 {
+		t.Fatalf("unexpected ratio of stored series; got %.3f; want 0.1", ratio)
+	}
+
+	// The decision must be stable per series and must not depend on the order of labels
+	// and on the presence of the ratio label.
+	for i := 0; i < 100; i++ {
+		instance := fmt.Sprintf("host-%d", i)
+		stored1 := len(apply([]prompbmarshal.Label{
+			{Name: "__name__", Value: "debug_requests_total"},
+			{Name: "instance", Value: instance},
+		})) > 0
+		stored2 := len(apply([]prompbmarshal.Label{
+			{Name: "sample_ratio", Value: "0.1"},
+			{Name: "instance", Value: instance},
+			{Name: "__name__", Value: "debug_requests_total"},
+		})) > 0
+		if stored1 != stored2 {
+			t.Fatalf("unstable sampling decision for instance=%q", instance)
+		}
+	}
+}
+
+func labelsString(labels []prompbmarshal.Label) string {
+	s := ""
+	for i, label := range labels {
+		if i > 0 {
+			s += ","
+		}
+		s += label.Name + "=" + label.Value
+	}
+	return s
+}
@@ -0,0 +1,288 @@
+package shadow
+
+import (
+	"bytes"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+	"github.com/golang/snappy"
+)
+
+var (
+	shadowURL = flag.String("shadow.url", "", "Optional remote storage URL for mirroring the ingested samples via Prometheus remote_write protocol. "+
+		"This may be useful for testing new versions and configs under real load. "+
+		"Failures at the shadow storage do not affect the data ingestion. See https://docs.victoriametrics.com/#write-shadowing")
+	shadowSampleRatio = flag.Float64("shadow.sampleRatio", 1, "The ratio of series in the range (0..1] to mirror to -shadow.url. "+
+		"The same series are selected on every ingestion, so the mirrored series do not contain gaps")
+	shadowMatchSeries = flag.String("shadow.matchSeries", "", "Optional series selector for limiting the series mirrored to -shadow.url. "+
+		`For example, -shadow.matchSeries='{job="foo"}' mirrors only series with job="foo" label`)
+	shadowMaxPendingRequests = flag.Int("shadow.maxPendingRequests", 100, "The maximum number of requests waiting for sending to -shadow.url. "+
+		"Excess requests are dropped, so slow shadow storage do not slow down the data ingestion")
+	shadowConcurrency = flag.Int("shadow.concurrency", 2, "The number of concurrent requests to -shadow.url")
+	shadowSendTimeout = flag.Duration("shadow.sendTimeout", 30*time.Second, "Timeout for sending a single request to -shadow.url")
+)
+
+// Init initializes shadowing of the ingested samples to -shadow.url.
+//
+// It must be called after flag.Parse(). Stop must be called when shadowing is no longer needed.
+func Init() {
+	if *shadowURL == "" {
+		return
+	}
+	u, err := url.Parse(*shadowURL)
+	if err != nil {
+		logger.Fatalf("cannot parse -shadow.url: %s", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		logger.Fatalf("unsupported scheme for -shadow.url: %q; supported schemes: http, https", u.Scheme)
+	}
+	if *shadowSampleRatio <= 0 || *shadowSampleRatio > 1 {
+		logger.Fatalf("-shadow.sampleRatio must be in the range (0..1]; got %v", *shadowSampleRatio)
+	}
+	var ie *promrelabel.IfExpression
+	if *shadowMatchSeries != "" {
+		ie = &promrelabel.IfExpression{}
+		if err := ie.Parse(*shadowMatchSeries); err != nil {
+			logger.Fatalf("cannot parse -shadow.matchSeries=%q: %s", *shadowMatchSeries, err)
+		}
+	}
+	concurrency := *shadowConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	maxPendingRequests := *shadowMaxPendingRequests
+	if maxPendingRequests <= 0 {
+		maxPendingRequests = 1
+	}
+	s := newShadower(u.String(), *shadowSampleRatio, ie, maxPendingRequests)
+	s.start(concurrency, *shadowSendTimeout)
+	defaultShadower = s
+	logger.Infof("mirroring %.2f%% of the ingested series to -shadow.url", 100*s.sampleRatio)
+}
+
+// Stop stops shadowing.
+//
+// Requests, which weren't sent yet, are dropped.
+func Stop() {
+	if defaultShadower == nil {
+		return
+	}
+	defaultShadower.stop()
+	defaultShadower = nil
+}
+
+// Enabled returns true if shadowing is enabled via -shadow.url.
+func Enabled() bool {
+	return defaultShadower != nil
+}
+
+// Push mirrors mrs to -shadow.url.
+//
+// It never blocks, so it doesn't slow down the data ingestion.
+func Push(mrs []storage.MetricRow) {
+	if defaultShadower == nil {
+		return
+	}
+	defaultShadower.push(mrs)
+}
+
+var defaultShadower *shadower
+
+type shadower struct {
+	url         string
+	sampleRatio float64
+	ie          *promrelabel.IfExpression
+
+	requestsCh chan []byte
+	stopCh     chan struct{}
+	wg         sync.WaitGroup
+}
+
+func newShadower(url string, sampleRatio float64, ie *promrelabel.IfExpression, maxPendingRequests int) *shadower {
+	return &shadower{
+		url:         url,
+		sampleRatio: sampleRatio,
+		ie:          ie,
+		requestsCh:  make(chan []byte, maxPendingRequests),
+		stopCh:      make(chan struct{}),
+	}
+}
+
+func (s *shadower) start(concurrency int, sendTimeout time.Duration) {
+	c := &http.Client{
+		Timeout: sendTimeout,
+	}
+	for i := 0; i < concurrency; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.sendWorker(c)
+		}()
+	}
+}
+
+func (s *shadower) stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+func (s *shadower) sendWorker(c *http.Client) {
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case req := <-s.requestsCh:
+			if err := s.send(c, req); err != nil {
+				requestErrors.Inc()
+				logger.WithThrottler("shadow_send", 5*time.Second).Warnf("cannot send %d bytes to -shadow.url: %s; dropping them", len(req), err)
+				continue
+			}
+			bytesSent.Add(len(req))
+		}
+	}
+}
+
+func (s *shadower) send(c *http.Client, data []byte) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response code %d; response body: %q", resp.StatusCode, body)
+	}
+	return nil
+}
+
+func (s *shadower) push(mrs []storage.MetricRow) {
+	ctx := getPushCtx()
+	defer putPushCtx(ctx)
+
+	mn := &ctx.mn
+	buf := ctx.buf[:0]
+	rows := 0
+	for i := range mrs {
+		mr := &mrs[i]
+		if !s.isSampled(mr.MetricNameRaw) {
+			continue
+		}
+		if err := mn.UnmarshalRaw(mr.MetricNameRaw); err != nil {
+			logger.Panicf("BUG: cannot unmarshal recently marshaled MetricName: %s", err)
+		}
+		labels := append(ctx.labels[:0], prompbmarshal.Label{
+			Name:  "__name__",
+			Value: bytesutil.ToUnsafeString(mn.MetricGroup),
+		})
+		for _, tag := range mn.Tags {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  bytesutil.ToUnsafeString(tag.Key),
+				Value: bytesutil.ToUnsafeString(tag.Value),
+			})
+		}
+		ctx.labels = labels
+		if s.ie != nil && !s.ie.Match(labels) {
+			continue
+		}
+		ctx.samples[0] = prompbmarshal.Sample{
+			Value:     mr.Value,
+			Timestamp: mr.Timestamp,
+		}
+		// Marshal the series into WriteRequest.Timeseries field while labels refer to mn,
+		// since mn is re-used for the next row.
+		ts := prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: ctx.samples[:],
+		}
+		size := ts.Size()
+		buf = append(buf, 0xa)
+		buf = binary.AppendUvarint(buf, uint64(size))
+		n := len(buf)
+		buf = bytesutil.ResizeWithCopyMayOverallocate(buf, n+size)
+		if _, err := ts.MarshalToSizedBuffer(buf[n:]); err != nil {
+			logger.Panicf("BUG: cannot marshal TimeSeries: %s", err)
+		}
+		rows++
+	}
+	ctx.buf = buf
+	if rows == 0 {
+		return
+	}
+
+	req := snappy.Encode(nil, buf)
+	select {
+	case s.requestsCh <- req:
+		rowsPushed.Add(rows)
+	default:
+		// Drop the request instead of blocking the data ingestion when the shadow storage is slow.
+		rowsDropped.Add(rows)
+	}
+}
+
+// isSampled returns true if the series with the given metricNameRaw must be mirrored to -shadow.url.
+func (s *shadower) isSampled(metricNameRaw []byte) bool {
+	if s.sampleRatio >= 1 {
+		return true
+	}
+	h := xxhash.Sum64(metricNameRaw)
+	return float64(h) < s.sampleRatio*math.MaxUint64
+}
+
+type pushCtx struct {
+	labels  []prompbmarshal.Label
+	samples [1]prompbmarshal.Sample
+	mn      storage.MetricName
+
+	// buf contains marshaled WriteRequest.
+	buf []byte
+}
+
+func (ctx *pushCtx) reset() {
+	promrelabel.CleanLabels(ctx.labels)
+	ctx.labels = ctx.labels[:0]
+	ctx.mn.Reset()
+	ctx.buf = ctx.buf[:0]
+}
+
+func getPushCtx() *pushCtx {
+	v := pushCtxPool.Get()
+	if v == nil {
+		return &pushCtx{}
+	}
+	return v.(*pushCtx)
+}
+
+func putPushCtx(ctx *pushCtx) {
+	ctx.reset()
+	pushCtxPool.Put(ctx)
+}
+
+var pushCtxPool sync.Pool
+
+var (
+	rowsPushed    = metrics.NewCounter(`vm_shadow_rows_pushed_total`)
+	rowsDropped   = metrics.NewCounter(`vm_shadow_rows_dropped_total`)
+	bytesSent     = metrics.NewCounter(`vm_shadow_bytes_sent_total`)
+	requestErrors = metrics.NewCounter(`vm_shadow_request_errors_total`)
+)
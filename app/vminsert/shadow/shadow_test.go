@@ -0,0 +1,116 @@
+package shadow
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/golang/snappy"
+)
+
+func TestShadowerPush(t *testing.T) {
+	var mu sync.Mutex
+	var rows []string
+	rowsCh := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+			return
+		}
+		data, err = snappy.Decode(nil, data)
+		if err != nil {
+			t.Errorf("cannot decode request body: %s", err)
+			return
+		}
+		var wr prompb.WriteRequest
+		if err := wr.Unmarshal(data); err != nil {
+			t.Errorf("cannot unmarshal request body: %s", err)
+			return
+		}
+		mu.Lock()
+		for _, ts := range wr.Timeseries {
+			var s string
+			for _, label := range ts.Labels {
+				s += fmt.Sprintf("%s=%q,", label.Name, label.Value)
+			}
+			for _, sample := range ts.Samples {
+				rows = append(rows, fmt.Sprintf("{%s} %g %d", s, sample.Value, sample.Timestamp))
+			}
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+		rowsCh <- struct{}{}
+	}))
+	defer srv.Close()
+
+	var ie promrelabel.IfExpression
+	if err := ie.Parse(`{job!="skip"}`); err != nil {
+		t.Fatalf("cannot parse series selector: %s", err)
+	}
+	s := newShadower(srv.URL, 1, &ie, 10)
+	s.start(1, time.Second)
+	defer s.stop()
+
+	newRow := func(name, job string, value float64, timestamp int64) storage.MetricRow {
+		labels := []prompb.Label{
+			{Name: []byte("job"), Value: []byte(job)},
+			{Name: nil, Value: []byte(name)},
+		}
+		return storage.MetricRow{
+			MetricNameRaw: storage.MarshalMetricNameRaw(nil, labels),
+			Timestamp:     timestamp,
+			Value:         value,
+		}
+	}
+	mrs := []storage.MetricRow{
+		newRow("foo", "bar", 1, 1000),
+		newRow("foo", "skip", 2, 1000),
+		newRow("baz", "bar", 3.5, 2000),
+	}
+	s.push(mrs)
+	select {
+	case <-rowsCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout when waiting for the shadow request")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	sort.Strings(rows)
+	rowsExpected := []string{
+		`{__name__="baz",job="bar",} 3.5 2000`,
+		`{__name__="foo",job="bar",} 1 1000`,
+	}
+	if !reflect.DeepEqual(rows, rowsExpected) {
+		t.Fatalf("unexpected rows;\ngot\n%q\nwant\n%q", rows, rowsExpected)
+	}
+}
+
+func TestShadowerIsSampled(t *testing.T) {
+	f := func(ratio float64, minExpected, maxExpected int) {
+		t.Helper()
+		s := newShadower("", ratio, nil, 1)
+		n := 0
+		for i := 0; i < 10000; i++ {
+			if s.isSampled([]byte(fmt.Sprintf("metric_%d", i))) {
+				n++
+			}
+		}
+		if n < minExpected || n > maxExpected {
+			t.Fatalf("unexpected number of sampled series for ratio=%v; got %d; want [%d..%d]", ratio, n, minExpected, maxExpected)
+		}
+	}
+	f(1, 10000, 10000)
+	f(0.5, 4500, 5500)
+	f(0.01, 50, 150)
+}
@@ -0,0 +1,56 @@
+package statsd
+
+import (
+	"io"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vminsert/prompush"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd/stream"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="statsd"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="statsd"}`)
+	requestMetrics = common.NewRequestMetrics("statsd")
+)
+
+var aggregator *parser.Aggregator
+
+// Init must be called before InsertHandler.
+func Init() {
+	aggregator = parser.MustStartAggregator(pushSeries)
+}
+
+// Stop flushes the aggregated StatsD metrics and stops the aggregator.
+func Stop() {
+	aggregator.MustStop()
+}
+
+// InsertHandler processes StatsD lines.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+func InsertHandler(r io.Reader) error {
+	rt := requestMetrics.TrackRequest(r)
+	defer rt.Done()
+
+	return stream.Parse(rt, func(rows []parser.Row) error {
+		return insertRows(rows, rt)
+	})
+}
+
+func insertRows(rows []parser.Row, rt *common.RequestTracker) error {
+	aggregator.Add(rows)
+	rt.AddSamples(len(rows))
+	rowsInserted.Add(len(rows))
+	rowsPerInsert.Update(float64(len(rows)))
+	return nil
+}
+
+func pushSeries(tss []prompbmarshal.TimeSeries) {
+	prompush.Push(&prompbmarshal.WriteRequest{
+		Timeseries: tss,
+	})
+}
@@ -17,25 +17,31 @@ import (
 )
 
 var (
-	rowsInserted  = metrics.NewCounter(`vm_rows_inserted_total{type="vmimport"}`)
-	rowsPerInsert = metrics.NewHistogram(`vm_rows_per_insert{type="vmimport"}`)
+	rowsInserted   = metrics.NewCounter(`vm_rows_inserted_total{type="vmimport"}`)
+	rowsPerInsert  = metrics.NewHistogram(`vm_rows_per_insert{type="vmimport"}`)
+	requestMetrics = common.NewRequestMetrics("vmimport")
 )
 
 // InsertHandler processes `/api/v1/import` request.
 //
 // See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/6
-func InsertHandler(req *http.Request) error {
+//
+// The returned result contains errors for invalid lines, which were skipped.
+func InsertHandler(req *http.Request) (*stream.Result, error) {
+	rt := requestMetrics.TrackHTTPRequest(req)
+	defer rt.Done()
+
 	extraLabels, err := parserCommon.GetExtraLabels(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	isGzipped := req.Header.Get("Content-Encoding") == "gzip"
-	return stream.Parse(req.Body, isGzipped, func(rows []parser.Row) error {
-		return insertRows(rows, extraLabels)
+	ce := req.Header.Get("Content-Encoding")
+	return stream.Parse(req.Body, ce, stream.IsStrictMode(req), func(rows []parser.Row) error {
+		return insertRows(rows, extraLabels, rt)
 	})
 }
 
-func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
+func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label, rt *common.RequestTracker) error {
 	ctx := getPushCtx()
 	defer putPushCtx(ctx)
 
@@ -68,6 +74,10 @@ func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
 		}
 		ic.SortLabelsIfNeeded()
 		ctx.metricNameBuf = storage.MarshalMetricNameRaw(ctx.metricNameBuf[:0], ic.Labels)
+		if len(ctx.metricNameBuf) == 0 {
+			// The time series is rejected because of label limits.
+			continue
+		}
 		values := r.Values
 		timestamps := r.Timestamps
 		if len(timestamps) != len(values) {
@@ -81,6 +91,7 @@ func insertRows(rows []parser.Row, extraLabels []prompbmarshal.Label) error {
 		}
 	}
 	rowsInserted.Add(rowsTotal)
+	rt.AddSamples(rowsTotal)
 	rowsPerInsert.Update(float64(rowsTotal))
 	return ic.FlushBufs()
 }
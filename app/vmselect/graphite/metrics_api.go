@@ -222,24 +222,7 @@ func MetricsIndexHandler(startTime time.Time, w http.ResponseWriter, r *http.Req
 func metricsFind(tr storage.TimeRange, label, qHead, qTail string, delimiter byte, isExpand bool, deadline searchutils.Deadline) ([]string, error) {
 	n := strings.IndexAny(qTail, "*{[")
 	if n < 0 {
-		query := qHead + qTail
-		suffixes, err := netstorage.TagValueSuffixes(nil, tr, label, query, delimiter, *maxTagValueSuffixes, deadline)
-		if err != nil {
-			return nil, err
-		}
-		if len(suffixes) == 0 {
-			return nil, nil
-		}
-		if len(query) > 0 && query[len(query)-1] == delimiter {
-			return []string{query}, nil
-		}
-		results := make([]string, 0, len(suffixes))
-		for _, suffix := range suffixes {
-			if len(suffix) == 0 || len(suffix) == 1 && suffix[0] == delimiter {
-				results = append(results, query+suffix)
-			}
-		}
-		return results, nil
+		return metricsFindExact(tr, label, qHead+qTail, delimiter, deadline)
 	}
 	if n == len(qTail)-1 && strings.HasSuffix(qTail, "*") {
 		query := qHead + qTail[:len(qTail)-1]
@@ -257,16 +240,31 @@ func metricsFind(tr storage.TimeRange, label, qHead, qTail string, delimiter byt
 		return results, nil
 	}
 	qHead += qTail[:n]
-	paths, err := metricsFind(tr, label, qHead, "*", delimiter, isExpand, deadline)
-	if err != nil {
-		return nil, err
-	}
 	suffix := qTail[n:]
 	qTail = ""
 	if m := strings.IndexByte(suffix, delimiter); m >= 0 {
 		qTail = suffix[m+1:]
 		suffix = suffix[:m+1]
 	}
+	var paths []string
+	if variants, ok := expandQuerySegment(suffix, delimiter); ok {
+		// Fast path: the segment contains a small number of variants such as `{foo,bar}` or `[0-9]`.
+		// Search for every variant instead of searching for all the children of qHead,
+		// since the number of children may be much bigger than the number of variants.
+		for _, variant := range variants {
+			ps, err := metricsFindExact(tr, label, qHead+variant, delimiter, deadline)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, ps...)
+		}
+	} else {
+		ps, err := metricsFind(tr, label, qHead, "*", delimiter, isExpand, deadline)
+		if err != nil {
+			return nil, err
+		}
+		paths = ps
+	}
 	qPrefix := qHead + suffix
 	rePrefix, err := getRegexpForQuery(qPrefix, delimiter)
 	if err != nil {
@@ -296,6 +294,154 @@ func metricsFind(tr storage.TimeRange, label, qHead, qTail string, delimiter byt
 	return results, nil
 }
 
+// metricsFindExact returns paths for the given query without wildcards.
+func metricsFindExact(tr storage.TimeRange, label, query string, delimiter byte, deadline searchutils.Deadline) ([]string, error) {
+	suffixes, err := netstorage.TagValueSuffixes(nil, tr, label, query, delimiter, *maxTagValueSuffixes, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if len(suffixes) == 0 {
+		return nil, nil
+	}
+	if len(query) > 0 && query[len(query)-1] == delimiter {
+		return []string{query}, nil
+	}
+	results := make([]string, 0, len(suffixes))
+	for _, suffix := range suffixes {
+		if len(suffix) == 0 || len(suffix) == 1 && suffix[0] == delimiter {
+			results = append(results, query+suffix)
+		}
+	}
+	return results, nil
+}
+
+// maxQuerySegmentVariants is the maximum number of variants for query segment,
+// which may be searched individually instead of searching for all the children of the parent node.
+const maxQuerySegmentVariants = 32
+
+// expandQuerySegment expands query segment with `{a,b}` alternations and `[0-9]` character ranges into a list of literal variants.
+//
+// false is returned if the segment cannot be expanded, e.g. if it contains `*`
+// or if the number of variants exceeds maxQuerySegmentVariants.
+func expandQuerySegment(segment string, delimiter byte) ([]string, bool) {
+	variants, tail, ok := expandQuerySegmentInternal(segment, false)
+	if !ok || len(tail) > 0 {
+		return nil, false
+	}
+	m := make(map[string]struct{}, len(variants))
+	result := variants[:0]
+	for _, v := range variants {
+		if n := strings.IndexByte(v, delimiter); n >= 0 && n < len(v)-1 {
+			// The delimiter inside the variant changes the number of path segments. Do not expand such segments.
+			return nil, false
+		}
+		if _, ok := m[v]; ok {
+			continue
+		}
+		m[v] = struct{}{}
+		result = append(result, v)
+	}
+	return result, true
+}
+
+func expandQuerySegmentInternal(segment string, isSubquery bool) ([]string, string, bool) {
+	variants := []string{""}
+	for {
+		n := strings.IndexAny(segment, "*{[,}")
+		if n < 0 {
+			return appendSuffix(variants, segment), "", true
+		}
+		variants = appendSuffix(variants, segment[:n])
+		segment = segment[n:]
+		switch segment[0] {
+		case ',', '}':
+			if isSubquery {
+				return variants, segment, true
+			}
+			variants = appendSuffix(variants, segment[:1])
+			segment = segment[1:]
+		case '*':
+			return nil, "", false
+		case '{':
+			var opts []string
+			for {
+				vs, tail, ok := expandQuerySegmentInternal(segment[1:], true)
+				if !ok || len(tail) == 0 {
+					// Unclosed `{` is treated as a literal by getRegexpForQuery. Do not expand it for the sake of simplicity.
+					return nil, "", false
+				}
+				opts = append(opts, vs...)
+				segment = tail
+				if tail[0] == '}' {
+					segment = tail[1:]
+					break
+				}
+			}
+			if len(variants)*len(opts) > maxQuerySegmentVariants {
+				return nil, "", false
+			}
+			variants = crossJoin(variants, opts)
+		case '[':
+			m := strings.IndexByte(segment, ']')
+			if m < 0 {
+				return nil, "", false
+			}
+			chars, ok := expandCharClass(segment[1:m])
+			if !ok || len(variants)*len(chars) > maxQuerySegmentVariants {
+				return nil, "", false
+			}
+			variants = crossJoin(variants, chars)
+			segment = segment[m+1:]
+		}
+	}
+}
+
+// expandCharClass expands character class such as `a-cx` into the list of chars such as `a`, `b`, `c` and `x`.
+func expandCharClass(s string) ([]string, bool) {
+	if len(s) == 0 || s[0] == '^' || s[0] == '!' || strings.ContainsAny(s, `\[:`) {
+		return nil, false
+	}
+	var chars []string
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return nil, false
+		}
+		if i+2 < len(s) && s[i+1] == '-' {
+			start, end := s[i], s[i+2]
+			if end >= 0x80 || start > end || int(end-start) >= maxQuerySegmentVariants {
+				return nil, false
+			}
+			for c := start; c <= end; c++ {
+				chars = append(chars, string(c))
+			}
+			i += 2
+			continue
+		}
+		chars = append(chars, s[i:i+1])
+	}
+	return chars, true
+}
+
+func appendSuffix(variants []string, suffix string) []string {
+	if len(suffix) == 0 {
+		return variants
+	}
+	for i := range variants {
+		variants[i] += suffix
+	}
+	return variants
+}
+
+func crossJoin(prefixes, suffixes []string) []string {
+	result := make([]string, 0, len(prefixes)*len(suffixes))
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			result = append(result, prefix+suffix)
+		}
+	}
+	return result
+}
+
 var (
 	metricsFindDuration   = metrics.NewSummary(`vm_request_duration_seconds{path="/metrics/find"}`)
 	metricsExpandDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/metrics/expand"}`)
@@ -82,3 +82,35 @@ func TestAddAutomaticVariants(t *testing.T) {
 	f("foo.bar,baz,aa.bb,cc", ".", "foo.{bar,baz,aa}.{bb,cc}")
 	f("foo.b*r,b[a-xz]z,aa.bb,cc", ".", "foo.{b*r,b[a-xz]z,aa}.{bb,cc}")
 }
+
+func TestExpandQuerySegment(t *testing.T) {
+	f := func(segment string, delimiter byte, variantsExpected []string, okExpected bool) {
+		t.Helper()
+		variants, ok := expandQuerySegment(segment, delimiter)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok for segment=%q; got %v; want %v", segment, ok, okExpected)
+		}
+		if !reflect.DeepEqual(variants, variantsExpected) {
+			t.Fatalf("unexpected variants for segment=%q;\ngot\n%q\nwant\n%q", segment, variants, variantsExpected)
+		}
+	}
+	f("foo", '.', []string{"foo"}, true)
+	f("foo.", '.', []string{"foo."}, true)
+	f("{foo,bar}.", '.', []string{"foo.", "bar."}, true)
+	f("x{foo,bar,}y", '.', []string{"xfooy", "xbary", "xy"}, true)
+	f("{a,b{c,d}}", '.', []string{"a", "bc", "bd"}, true)
+	f("{a,a}", '.', []string{"a"}, true)
+	f("host[1-3]", '.', []string{"host1", "host2", "host3"}, true)
+	f("[ab][0-1]", '.', []string{"a0", "a1", "b0", "b1"}, true)
+	f("a,b}", '.', []string{"a,b}"}, true)
+
+	// segments, which cannot be expanded
+	f("*", '.', nil, false)
+	f("{foo,b*}", '.', nil, false)
+	f("{foo,bar", '.', nil, false)
+	f("foo[1-3", '.', nil, false)
+	f("[^a]", '.', nil, false)
+	f("[a-z][0-9]", '.', nil, false)
+	f("[0-9][0-9]", '.', nil, false)
+	f("{a.b,c}", '.', nil, false)
+}
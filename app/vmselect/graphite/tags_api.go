@@ -12,6 +12,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	graphiteparser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/graphite"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
@@ -52,6 +53,10 @@ func TagsDelSeriesHandler(startTime time.Time, w http.ResponseWriter, r *http.Re
 		tfss := joinTagFilterss(tfs, etfs)
 		sq := storage.NewSearchQuery(0, ct, tfss, 0)
 		n, err := netstorage.DeleteSeries(nil, sq, deadline)
+		ae := auditlog.NewEvent(r, "delete_series")
+		ae.Matches = []string{path}
+		ae.SetSeries(n)
+		auditlog.Log(ae, err)
 		if err != nil {
 			return fmt.Errorf("cannot delete series for %q: %w", sq, err)
 		}
@@ -127,6 +132,9 @@ func registerMetrics(startTime time.Time, w http.ResponseWriter, r *http.Request
 		// Put labels with the current timestamp to MetricRow
 		mr := &mrs[i]
 		mr.MetricNameRaw = storage.MarshalMetricNameRaw(mr.MetricNameRaw[:0], labels)
+		if len(mr.MetricNameRaw) == 0 {
+			return fmt.Errorf("path=%q is rejected because of label limits; see -labelLimitsPolicy", path)
+		}
 		mr.Timestamp = ct
 	}
 	if err := vmstorage.RegisterMetricNames(nil, mrs); err != nil {
@@ -8,32 +8,45 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/graphite"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/metricaliases"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/prometheus"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryprofiler"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fairqueue"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
 	deleteAuthKey         = flag.String("deleteAuthKey", "", "authKey for metrics' deletion via /api/v1/admin/tsdb/delete_series and /tags/delSeries")
+	holdsAuthKey          = flag.String("holdsAuthKey", "", "authKey for managing compliance holds via /api/v1/admin/holds* pages. See https://docs.victoriametrics.com/#compliance-holds")
+	retentionAuthKey      = flag.String("retentionAuthKey", "", "authKey for the retention report at /api/v1/admin/retention and for extending retention via /api/v1/admin/retention/extend. See https://docs.victoriametrics.com/#retention")
+	metricAliasesAuthKey  = flag.String("metricAliasesAuthKey", "", "authKey for managing metric aliases via /api/v1/admin/metric_aliases* pages. See https://docs.victoriametrics.com/#metric-aliases")
+	indexAuthKey          = flag.String("indexAuthKey", "", "authKey for exporting and importing series index via /api/v1/admin/tsdb/export_index and /api/v1/admin/tsdb/import_index pages. See https://docs.victoriametrics.com/#index-export-and-import")
 	maxConcurrentRequests = flag.Int("search.maxConcurrentRequests", getDefaultMaxConcurrentRequests(), "The maximum number of concurrent search requests. "+
 		"It shouldn't be high, since a single request can saturate all the CPU cores, while many concurrently executed requests may require high amounts of memory. "+
 		"See also -search.maxQueueDuration and -search.maxMemoryPerQuery")
 	maxQueueDuration = flag.Duration("search.maxQueueDuration", 10*time.Second, "The maximum time the request waits for execution when -search.maxConcurrentRequests "+
 		"limit is reached; see also -search.maxQueryDuration")
+	tenantWeights = flagutil.NewArrayString("search.tenantWeights", "Optional weights for tenants in the form tenant:weight. Under contention tenants get "+
+		"execution slots proportionally to their weights. Tenants without weights get weight 1. See -search.tenantHeader")
+	tenantStarvationTimeout = flag.Duration("search.tenantStarvationTimeout", 5*time.Second, "Queued requests waiting for longer than this duration "+
+		"are executed before other queued requests regardless of -search.tenantWeights. This prevents from starvation of tenants with low weights. "+
+		"Zero disables starvation protection")
 	resetCacheAuthKey    = flag.String("search.resetCacheAuthKey", "", "Optional authKey for resetting rollup cache via /internal/resetRollupResultCache call")
 	logSlowQueryDuration = flag.Duration("search.logSlowQueryDuration", 5*time.Second, "Log queries with execution time exceeding this value. Zero disables slow query logging")
 	vmalertProxyURL      = flag.String("vmalert.proxyURL", "", "Optional URL for proxying requests to vmalert. For example, if -vmalert.proxyURL=http://vmalert:8880 , then alerting API requests such as /api/v1/rules from Grafana will be proxied to http://vmalert:8880/api/v1/rules")
@@ -61,27 +74,60 @@ func Init() {
 	fs.RemoveDirContents(tmpDirPath)
 	netstorage.InitTmpBlocksDir(tmpDirPath)
 	promql.InitRollupResultCache(*vmstorage.DataPath + "/cache/rollupResult")
+	metricaliases.Init(promql.ResetRollupResultCache, *vmstorage.DataPath+"/metric_aliases.json")
 
-	concurrencyLimitCh = make(chan struct{}, *maxConcurrentRequests)
+	weights, err := parseTenantWeights(*tenantWeights)
+	if err != nil {
+		logger.Fatalf("cannot parse -search.tenantWeights: %s", err)
+	}
+	concurrencyLimiter = fairqueue.NewLimiter(*maxConcurrentRequests, weights, 1, *tenantStarvationTimeout)
+	prometheus.InitMaxLookbehindWindows()
+	queryprofiler.Init()
 	initVMAlertProxy()
 }
 
 // Stop stops vmselect
 func Stop() {
+	queryprofiler.Stop()
 	promql.StopRollupResultCache()
 }
 
-var concurrencyLimitCh chan struct{}
+func parseTenantWeights(a []string) (map[string]int, error) {
+	weights := make(map[string]int, len(a))
+	for _, s := range a {
+		if s == "" {
+			continue
+		}
+		n := strings.LastIndexByte(s, ':')
+		if n < 0 {
+			return nil, fmt.Errorf("missing ':' in %q; expecting tenant:weight", s)
+		}
+		weight, err := strconv.Atoi(s[n+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse weight in %q: %w", s, err)
+		}
+		if weight <= 0 {
+			return nil, fmt.Errorf("weight must be positive; got %d in %q", weight, s)
+		}
+		weights[s[:n]] = weight
+	}
+	return weights, nil
+}
+
+var concurrencyLimiter *fairqueue.Limiter
 
 var (
 	concurrencyLimitReached = metrics.NewCounter(`vm_concurrent_select_limit_reached_total`)
 	concurrencyLimitTimeout = metrics.NewCounter(`vm_concurrent_select_limit_timeout_total`)
 
 	_ = metrics.NewGauge(`vm_concurrent_select_capacity`, func() float64 {
-		return float64(cap(concurrencyLimitCh))
+		return float64(concurrencyLimiter.Capacity())
 	})
 	_ = metrics.NewGauge(`vm_concurrent_select_current`, func() float64 {
-		return float64(len(concurrencyLimitCh))
+		return float64(concurrencyLimiter.Inflight())
+	})
+	_ = metrics.NewGauge(`vm_concurrent_select_queued`, func() float64 {
+		return float64(concurrencyLimiter.Queued())
 	})
 )
 
@@ -98,24 +144,21 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	qt := querytracer.New(tracerEnabled, r.URL.Path)
 
 	// Limit the number of concurrent queries.
-	select {
-	case concurrencyLimitCh <- struct{}{}:
-		defer func() { <-concurrencyLimitCh }()
-	default:
+	if concurrencyLimiter.TryAcquire() {
+		defer concurrencyLimiter.Release()
+	} else {
 		// Sleep for a while until giving up. This should resolve short bursts in requests.
+		// Queued requests are executed according to per-tenant weights.
 		concurrencyLimitReached.Inc()
 		d := searchutils.GetMaxQueryDuration(r)
 		if d > *maxQueueDuration {
 			d = *maxQueueDuration
 		}
-		t := timerpool.Get(d)
-		select {
-		case concurrencyLimitCh <- struct{}{}:
-			timerpool.Put(t)
+		tenant := searchutils.GetTenant(r)
+		if concurrencyLimiter.Acquire(tenant, d) {
 			qt.Printf("wait in queue because -search.maxConcurrentRequests=%d concurrent requests are executed", *maxConcurrentRequests)
-			defer func() { <-concurrencyLimitCh }()
-		case <-t.C:
-			timerpool.Put(t)
+			defer concurrencyLimiter.Release()
+		} else {
 			concurrencyLimitTimeout.Inc()
 			err := &httpserver.ErrorWithStatusCode{
 				Err: fmt.Errorf("couldn't start executing the request in %.3f seconds, since -search.maxConcurrentRequests=%d concurrent requests "+
@@ -276,6 +319,33 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/series/iterate":
+		seriesIterateRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.SeriesIterateHandler(qt, startTime, w, r); err != nil {
+			seriesIterateErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/series/fingerprints":
+		seriesFingerprintsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.SeriesFingerprintsHandler(qt, startTime, w, r); err != nil {
+			seriesFingerprintsErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/series/diff":
+		seriesDiffRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.SeriesDiffHandler(qt, startTime, w, r); err != nil {
+			seriesDiffErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
 	case "/api/v1/series/count":
 		seriesCountRequests.Inc()
 		httpserver.EnableCORS(w, r)
@@ -303,6 +373,75 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/selector_stats":
+		selectorStatsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.SelectorStatsHandler(qt, startTime, w, r); err != nil {
+			selectorStatsErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/last":
+		lastRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.LastHandler(qt, startTime, w, r); err != nil {
+			lastErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/lint_query":
+		lintQueryRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.LintQueryHandler(qt, startTime, w, r); err != nil {
+			lintQueryErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/format_query":
+		formatQueryRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.FormatQueryHandler(startTime, w, r); err != nil {
+			formatQueryErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/api/v1/status/metric_names_stats":
+		statusMetricNamesStatsRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.MetricNamesStatsHandler(qt, startTime, w, r); err != nil {
+			statusMetricNamesStatsErrors.Inc()
+			sendPrometheusError(w, r, err)
+			return true
+		}
+		return true
+	case "/grafana", "/grafana/":
+		// This is needed for `Test connection` button in Grafana SimpleJSON datasource.
+		grafanaRootRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		fmt.Fprintf(w, "OK")
+		return true
+	case "/grafana/search":
+		grafanaSearchRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.GrafanaSearchHandler(qt, startTime, w, r); err != nil {
+			grafanaSearchErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
+	case "/grafana/query":
+		grafanaQueryRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.GrafanaQueryHandler(qt, startTime, w, r); err != nil {
+			grafanaQueryErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+			return true
+		}
+		return true
 	case "/api/v1/status/active_queries":
 		statusActiveQueriesRequests.Inc()
 		promql.WriteActiveQueries(w)
@@ -316,6 +455,15 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		return true
+	case "/api/v1/status/top_query_shapes":
+		topQueryShapesRequests.Inc()
+		httpserver.EnableCORS(w, r)
+		if err := prometheus.TopQueryShapesHandler(startTime, w, r); err != nil {
+			topQueryShapesErrors.Inc()
+			sendPrometheusError(w, r, fmt.Errorf("cannot query status endpoint: %w", err))
+			return true
+		}
+		return true
 	case "/api/v1/export":
 		exportRequests.Inc()
 		if err := prometheus.ExportHandler(startTime, w, r); err != nil {
@@ -469,10 +617,20 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		fmt.Fprint(w, `{"status":"success","data":{"alerts":[]}}`)
 		return true
 	case "/api/v1/metadata":
-		// Return dumb placeholder for https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+		// Return metric metadata collected from scrape targets with `metadata_only: true` option.
+		// See https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
 		metadataRequests.Inc()
+		limit := 0
+		if s := r.FormValue("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				httpserver.Errorf(w, r, "cannot parse limit=%q: %s", s, err)
+				return true
+			}
+			limit = n
+		}
 		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s", `{"status":"success","data":{}}`)
+		promscrape.WriteMetadata(w, r.FormValue("metric"), limit)
 		return true
 	case "/api/v1/status/buildinfo":
 		buildInfoRequests.Inc()
@@ -497,6 +655,82 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		w.WriteHeader(http.StatusNoContent)
 		return true
+	case "/api/v1/admin/tsdb/export_index", "/api/v1/admin/tsdb/import_index":
+		if !httpserver.CheckAuthFlag(w, r, *indexAuthKey, "indexAuthKey") {
+			return true
+		}
+		indexRequests.Inc()
+		var err error
+		if path == "/api/v1/admin/tsdb/export_index" {
+			err = prometheus.ExportIndexHandler(startTime, w, r)
+		} else {
+			err = prometheus.ImportIndexHandler(startTime, w, r)
+		}
+		if err != nil {
+			indexErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/api/v1/admin/holds", "/api/v1/admin/holds/create", "/api/v1/admin/holds/release", "/api/v1/admin/holds/audit":
+		if !httpserver.CheckAuthFlag(w, r, *holdsAuthKey, "holdsAuthKey") {
+			return true
+		}
+		holdsRequests.Inc()
+		var err error
+		switch path {
+		case "/api/v1/admin/holds":
+			err = prometheus.ListHoldsHandler(startTime, w, r)
+		case "/api/v1/admin/holds/create":
+			err = prometheus.CreateHoldHandler(startTime, w, r)
+		case "/api/v1/admin/holds/release":
+			err = prometheus.ReleaseHoldHandler(startTime, w, r)
+		default:
+			err = prometheus.HoldsAuditHandler(startTime, w, r)
+		}
+		if err != nil {
+			holdsErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/api/v1/admin/retention":
+		if !httpserver.CheckAuthFlag(w, r, *retentionAuthKey, "retentionAuthKey") {
+			return true
+		}
+		retentionReportRequests.Inc()
+		if err := prometheus.RetentionReportHandler(startTime, w, r); err != nil {
+			retentionReportErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/api/v1/admin/retention/extend":
+		if !httpserver.CheckAuthFlag(w, r, *retentionAuthKey, "retentionAuthKey") {
+			return true
+		}
+		extendRetentionRequests.Inc()
+		if err := prometheus.ExtendRetentionHandler(startTime, w, r); err != nil {
+			extendRetentionErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
+	case "/api/v1/admin/metric_aliases", "/api/v1/admin/metric_aliases/register", "/api/v1/admin/metric_aliases/unregister":
+		if !httpserver.CheckAuthFlag(w, r, *metricAliasesAuthKey, "metricAliasesAuthKey") {
+			return true
+		}
+		metricAliasesRequests.Inc()
+		var err error
+		switch path {
+		case "/api/v1/admin/metric_aliases":
+			err = prometheus.ListMetricAliasesHandler(startTime, w, r)
+		case "/api/v1/admin/metric_aliases/register":
+			err = prometheus.RegisterMetricAliasHandler(startTime, w, r)
+		default:
+			err = prometheus.UnregisterMetricAliasHandler(startTime, w, r)
+		}
+		if err != nil {
+			metricAliasesErrors.Inc()
+			httpserver.Errorf(w, r, "%s", err)
+		}
+		return true
 	default:
 		return false
 	}
@@ -548,6 +782,15 @@ var (
 	seriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series"}`)
 	seriesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series"}`)
 
+	seriesIterateRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series/iterate"}`)
+	seriesIterateErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series/iterate"}`)
+
+	seriesFingerprintsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series/fingerprints"}`)
+	seriesFingerprintsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series/fingerprints"}`)
+
+	seriesDiffRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series/diff"}`)
+	seriesDiffErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series/diff"}`)
+
 	seriesCountRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/series/count"}`)
 	seriesCountErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/series/count"}`)
 
@@ -557,14 +800,53 @@ var (
 	statusTSDBRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/tsdb"}`)
 	statusTSDBErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/tsdb"}`)
 
+	selectorStatsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/selector_stats"}`)
+	selectorStatsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/selector_stats"}`)
+
+	lastRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/last"}`)
+	lastErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/last"}`)
+
+	grafanaRootRequests   = metrics.NewCounter(`vm_http_requests_total{path="/grafana"}`)
+	grafanaSearchRequests = metrics.NewCounter(`vm_http_requests_total{path="/grafana/search"}`)
+	grafanaSearchErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/grafana/search"}`)
+	grafanaQueryRequests  = metrics.NewCounter(`vm_http_requests_total{path="/grafana/query"}`)
+	grafanaQueryErrors    = metrics.NewCounter(`vm_http_request_errors_total{path="/grafana/query"}`)
+
+	lintQueryRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/lint_query"}`)
+	lintQueryErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/lint_query"}`)
+
+	formatQueryRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/format_query"}`)
+	formatQueryErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/format_query"}`)
+
+	statusMetricNamesStatsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/metric_names_stats"}`)
+	statusMetricNamesStatsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/metric_names_stats"}`)
+
 	statusActiveQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/active_queries"}`)
 
 	topQueriesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/top_queries"}`)
 	topQueriesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/top_queries"}`)
 
+	topQueryShapesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/status/top_query_shapes"}`)
+	topQueryShapesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/status/top_query_shapes"}`)
+
 	deleteRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/tsdb/delete_series"}`)
 	deleteErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/tsdb/delete_series"}`)
 
+	indexRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/tsdb/index"}`)
+	indexErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/tsdb/index"}`)
+
+	holdsRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/holds"}`)
+	holdsErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/holds"}`)
+
+	retentionReportRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/retention"}`)
+	retentionReportErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/retention"}`)
+
+	extendRetentionRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/retention/extend"}`)
+	extendRetentionErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/retention/extend"}`)
+
+	metricAliasesRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/admin/metric_aliases"}`)
+	metricAliasesErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/admin/metric_aliases"}`)
+
 	exportRequests = metrics.NewCounter(`vm_http_requests_total{path="/api/v1/export"}`)
 	exportErrors   = metrics.NewCounter(`vm_http_request_errors_total{path="/api/v1/export"}`)
 
@@ -0,0 +1,301 @@
+package metricaliases
+
+import (
+	"flag"
+	"fmt"
+	"sync/atomic"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var metricAliasesFile = flag.String("search.metricAliasesFile", "", "Optional path to a file with metric aliases, which map old metric names and label names "+
+	"to new ones at query time. This allows querying renamed metrics by their old names. The path can point either to local file or to http url. "+
+	"See https://docs.victoriametrics.com/#metric-aliases for details. The file is reloaded on SIGHUP signal")
+
+// Init must be called after flag.Parse and before using the metricaliases package.
+//
+// resetCache is called after the aliases are changed, so cached query results are re-calculated with the updated aliases.
+//
+// registeredAliasesPath is the path to the file for persisting aliases registered via Register.
+// Registered aliases aren't persisted if registeredAliasesPath is empty.
+func Init(resetCache func(), registeredAliasesPath string) {
+	// Register SIGHUP handler for config re-read just before loadAliases call.
+	// This guarantees that the config will be re-read if the signal arrives during loadAliases call.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1240
+	sighupCh := procutil.NewSighupChan()
+
+	registered, err := loadRegisteredAliases(registeredAliasesPath)
+	if err != nil {
+		logger.Fatalf("cannot load registered metric aliases: %s", err)
+	}
+	aliases, err := loadAliases()
+	if err != nil {
+		logger.Fatalf("cannot load -search.metricAliasesFile: %s", err)
+	}
+	mu.Lock()
+	resetCacheFunc = resetCache
+	registeredPath = registeredAliasesPath
+	registeredAliases = registered
+	err = updateAliasesLocked(aliases)
+	mu.Unlock()
+	if err != nil {
+		logger.Fatalf("cannot apply -search.metricAliasesFile: %s", err)
+	}
+	configSuccess.Set(1)
+	configTimestamp.Set(fasttime.UnixTimestamp())
+
+	if len(*metricAliasesFile) == 0 {
+		return
+	}
+	go func() {
+		for range sighupCh {
+			configReloads.Inc()
+			logger.Infof("received SIGHUP; reloading -search.metricAliasesFile=%q...", *metricAliasesFile)
+			aliases, err := loadAliases()
+			if err == nil {
+				mu.Lock()
+				err = updateAliasesLocked(aliases)
+				mu.Unlock()
+			}
+			if err != nil {
+				configReloadErrors.Inc()
+				configSuccess.Set(0)
+				logger.Errorf("cannot load the updated -search.metricAliasesFile: %s; preserving the previous aliases", err)
+				continue
+			}
+			configSuccess.Set(1)
+			configTimestamp.Set(fasttime.UnixTimestamp())
+			resetCache()
+			logger.Infof("successfully reloaded -search.metricAliasesFile=%q", *metricAliasesFile)
+		}
+	}()
+}
+
+var (
+	configReloads      = metrics.NewCounter(`vm_metric_aliases_config_reloads_total`)
+	configReloadErrors = metrics.NewCounter(`vm_metric_aliases_config_reloads_errors_total`)
+	configSuccess      = metrics.NewCounter(`vm_metric_aliases_config_last_reload_successful`)
+	configTimestamp    = metrics.NewCounter(`vm_metric_aliases_config_last_reload_success_timestamp_seconds`)
+
+	expandedSelectors = metrics.NewCounter(`vm_metric_aliases_expanded_selectors_total`)
+)
+
+var asGlobal atomic.Value
+
+// loadAliases loads aliases from -search.metricAliasesFile.
+func loadAliases() ([]Alias, error) {
+	if len(*metricAliasesFile) == 0 {
+		return nil, nil
+	}
+	data, err := fs.ReadFileOrHTTP(*metricAliasesFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", *metricAliasesFile, err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars at %q: %w", *metricAliasesFile, err)
+	}
+	var aliases []Alias
+	if err := yaml.UnmarshalStrict(data, &aliases); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", *metricAliasesFile, err)
+	}
+	if _, err := newAliases(aliases); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", *metricAliasesFile, err)
+	}
+	return aliases, nil
+}
+
+// Get returns the currently loaded aliases.
+//
+// nil is returned if there are no aliases.
+func Get() *Aliases {
+	as, _ := asGlobal.Load().(*Aliases)
+	return as
+}
+
+// Alias is a single alias from -search.metricAliasesFile.
+type Alias struct {
+	// Metric is the old metric name, which is used in queries.
+	Metric string `yaml:"metric" json:"metric"`
+
+	// NewMetric is the new metric name, which is stored in the database.
+	//
+	// It equals to Metric if it isn't set. This allows renaming only labels.
+	NewMetric string `yaml:"new_metric,omitempty" json:"new_metric,omitempty"`
+
+	// Labels maps old label names used in queries to new label names stored in the database.
+	Labels map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// Aliases holds parsed aliases.
+type Aliases struct {
+	m map[string]*parsedAlias
+}
+
+type parsedAlias struct {
+	metric    string
+	newMetric string
+
+	// newLabels maps old label names to new label names.
+	newLabels map[string]string
+
+	// oldLabels maps new label names to old label names.
+	oldLabels map[string]string
+}
+
+// Parse parses aliases from YAML data.
+func Parse(data []byte) (*Aliases, error) {
+	var aliases []Alias
+	if err := yaml.UnmarshalStrict(data, &aliases); err != nil {
+		return nil, err
+	}
+	return newAliases(aliases)
+}
+
+func newAliases(aliases []Alias) (*Aliases, error) {
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	as := &Aliases{
+		m: make(map[string]*parsedAlias, len(aliases)),
+	}
+	for i := range aliases {
+		pa, err := parseAlias(&aliases[i])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse alias #%d: %w", i+1, err)
+		}
+		if as.m[pa.metric] != nil {
+			return nil, fmt.Errorf("duplicate alias for metric %q", pa.metric)
+		}
+		as.m[pa.metric] = pa
+	}
+	return as, nil
+}
+
+func parseAlias(a *Alias) (*parsedAlias, error) {
+	if a.Metric == "" {
+		return nil, fmt.Errorf("missing `metric`")
+	}
+	pa := &parsedAlias{
+		metric:    a.Metric,
+		newMetric: a.NewMetric,
+		newLabels: make(map[string]string, len(a.Labels)),
+		oldLabels: make(map[string]string, len(a.Labels)),
+	}
+	if pa.newMetric == "" {
+		pa.newMetric = pa.metric
+	}
+	for oldLabel, newLabel := range a.Labels {
+		if oldLabel == "" || newLabel == "" {
+			return nil, fmt.Errorf("label names in `labels` cannot be empty; got %q: %q", oldLabel, newLabel)
+		}
+		if oldLabel == "__name__" || newLabel == "__name__" {
+			return nil, fmt.Errorf("`labels` cannot contain __name__; use `new_metric` instead")
+		}
+		if oldLabel == newLabel {
+			continue
+		}
+		if prev, ok := pa.oldLabels[newLabel]; ok {
+			return nil, fmt.Errorf("labels %q and %q cannot be mapped to the same label %q", prev, oldLabel, newLabel)
+		}
+		pa.newLabels[oldLabel] = newLabel
+		pa.oldLabels[newLabel] = oldLabel
+	}
+	if pa.newMetric == pa.metric && len(pa.newLabels) == 0 {
+		return nil, fmt.Errorf("`new_metric` or `labels` must be set for metric %q", pa.metric)
+	}
+	return pa, nil
+}
+
+// ExpandTagFilterss appends tag filters for new metric names and label names to tfss
+// for each tag filters matching old metric name by exact match.
+//
+// It returns the resulting tag filters and the renamer for the fetched series.
+// The returned renamer is nil if tfss doesn't match any alias.
+func (as *Aliases) ExpandTagFilterss(tfss [][]storage.TagFilter) ([][]storage.TagFilter, *Renamer) {
+	if as == nil {
+		return tfss, nil
+	}
+	var r *Renamer
+	for _, tfs := range tfss {
+		pa := as.getAlias(tfs)
+		if pa == nil {
+			continue
+		}
+		tfsNew := make([]storage.TagFilter, len(tfs))
+		changed := pa.metric != pa.newMetric
+		for i, tf := range tfs {
+			if len(tf.Key) == 0 {
+				tf.Value = []byte(pa.newMetric)
+			} else if newLabel, ok := pa.newLabels[string(tf.Key)]; ok {
+				tf.Key = []byte(newLabel)
+				changed = true
+			}
+			tfsNew[i] = tf
+		}
+		if changed {
+			// Do not add tag filters, which match the same series as tfs.
+			tfss = append(tfss, tfsNew)
+		}
+		if r == nil {
+			r = &Renamer{
+				m: make(map[string]*parsedAlias),
+			}
+		}
+		r.m[pa.newMetric] = pa
+	}
+	if r != nil {
+		expandedSelectors.Inc()
+	}
+	return tfss, r
+}
+
+func (as *Aliases) getAlias(tfs []storage.TagFilter) *parsedAlias {
+	for _, tf := range tfs {
+		if len(tf.Key) == 0 && !tf.IsNegative && !tf.IsRegexp {
+			return as.m[string(tf.Value)]
+		}
+	}
+	return nil
+}
+
+// Renamer renames series fetched by tag filters returned from Aliases.ExpandTagFilterss.
+type Renamer struct {
+	// m maps new metric names to aliases.
+	m map[string]*parsedAlias
+}
+
+// RenameMetricName renames new metric name and label names in mn to old ones.
+//
+// It returns false if mn doesn't match any alias.
+func (r *Renamer) RenameMetricName(mn *storage.MetricName) bool {
+	pa := r.m[bytesutil.ToUnsafeString(mn.MetricGroup)]
+	if pa == nil {
+		return false
+	}
+	renamed := false
+	if pa.metric != pa.newMetric {
+		mn.MetricGroup = append(mn.MetricGroup[:0], pa.metric...)
+		renamed = true
+	}
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		oldLabel, ok := pa.oldLabels[bytesutil.ToUnsafeString(tag.Key)]
+		if !ok || mn.GetTagValue(oldLabel) != nil {
+			// Do not overwrite the existing label.
+			continue
+		}
+		tag.Key = append(tag.Key[:0], oldLabel...)
+		renamed = true
+	}
+	return renamed
+}
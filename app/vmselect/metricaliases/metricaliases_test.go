@@ -0,0 +1,311 @@
+package metricaliases
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+func TestParseFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		as, err := Parse([]byte(data))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if as != nil {
+			t.Fatalf("expecting nil aliases")
+		}
+	}
+	// invalid yaml
+	f(`foo`)
+	// unknown field
+	f(`
+- metric: foo
+  new_metric: bar
+  foo: bar
+`)
+	// missing metric
+	f(`
+- new_metric: bar
+`)
+	// missing new_metric and labels
+	f(`
+- metric: foo
+`)
+	f(`
+- metric: foo
+  new_metric: foo
+  labels:
+    a: a
+`)
+	// empty label
+	f(`
+- metric: foo
+  labels:
+    a: ""
+`)
+	// __name__ in labels
+	f(`
+- metric: foo
+  labels:
+    __name__: bar
+`)
+	// multiple labels mapped to the same label
+	f(`
+- metric: foo
+  labels:
+    a: c
+    b: c
+`)
+	// duplicate metric
+	f(`
+- metric: foo
+  new_metric: bar
+- metric: foo
+  new_metric: baz
+`)
+}
+
+func TestParseSuccess(t *testing.T) {
+	f := func(data string, aliasesExpected int) {
+		t.Helper()
+		as, err := Parse([]byte(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		n := 0
+		if as != nil {
+			n = len(as.m)
+		}
+		if n != aliasesExpected {
+			t.Fatalf("unexpected number of aliases; got %d; want %d", n, aliasesExpected)
+		}
+	}
+	f(``, 0)
+	f(`[]`, 0)
+	f(`
+- metric: foo
+  new_metric: bar
+- metric: node_cpu
+  labels:
+    cpu_mode: mode
+- metric: http_requests_total
+  new_metric: http_server_requests_total
+  labels:
+    code: status_code
+    path: handler
+`, 3)
+}
+
+func TestExpandTagFilterss(t *testing.T) {
+	as, err := Parse([]byte(`
+- metric: foo
+  new_metric: bar
+  labels:
+    a: b
+- metric: baz
+  labels:
+    x: y
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := func(tfssStr []string, resultExpected []string, renamerExpected bool) {
+		t.Helper()
+		var tfss [][]storage.TagFilter
+		for _, s := range tfssStr {
+			tfss = append(tfss, newTagFilters(s))
+		}
+		tfss, r := as.ExpandTagFilterss(tfss)
+		var result []string
+		for _, tfs := range tfss {
+			result = append(result, tagFiltersToString(tfs))
+		}
+		if strings.Join(result, "\n") != strings.Join(resultExpected, "\n") {
+			t.Fatalf("unexpected tag filters;\ngot\n%s\nwant\n%s", strings.Join(result, "\n"), strings.Join(resultExpected, "\n"))
+		}
+		if (r != nil) != renamerExpected {
+			t.Fatalf("unexpected renamer; got %v; want non-nil: %v", r, renamerExpected)
+		}
+	}
+	// no matching aliases
+	f([]string{`=qwe,a=c`}, []string{`=qwe,a=c`}, false)
+	// regexp and negative filters on metric name are ignored
+	f([]string{`=~foo`}, []string{`=~foo`}, false)
+	f([]string{`!=foo`}, []string{`!=foo`}, false)
+	// metric name and label renaming
+	f([]string{`=foo,a=c,c=d`}, []string{`=foo,a=c,c=d`, `=bar,b=c,c=d`}, true)
+	// label-only renaming
+	f([]string{`=baz,x!=1`}, []string{`=baz,x!=1`, `=baz,y!=1`}, true)
+	f([]string{`=baz,z=1`}, []string{`=baz,z=1`}, true)
+	// multiple tag filters
+	f([]string{`=foo`, `=abc`, `=baz,x=~a.+`}, []string{`=foo`, `=abc`, `=baz,x=~a.+`, `=bar`, `=baz,y=~a.+`}, true)
+
+	// nil aliases
+	var asNil *Aliases
+	tfss, r := asNil.ExpandTagFilterss([][]storage.TagFilter{newTagFilters(`=foo`)})
+	if len(tfss) != 1 || r != nil {
+		t.Fatalf("unexpected result for nil aliases; got %d tag filters; renamer=%v", len(tfss), r)
+	}
+}
+
+func TestRenameMetricName(t *testing.T) {
+	as, err := Parse([]byte(`
+- metric: foo
+  new_metric: bar
+  labels:
+    a: b
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	_, r := as.ExpandTagFilterss([][]storage.TagFilter{newTagFilters(`=foo`)})
+	// s must contain comma-separated metric name and `key=value` labels
+	f := func(s, resultExpected string, renamedExpected bool) {
+		t.Helper()
+		var mn storage.MetricName
+		items := strings.Split(s, ",")
+		mn.MetricGroup = []byte(items[0])
+		for _, item := range items[1:] {
+			n := strings.IndexByte(item, '=')
+			mn.AddTag(item[:n], item[n+1:])
+		}
+		renamed := r.RenameMetricName(&mn)
+		if renamed != renamedExpected {
+			t.Fatalf("unexpected renamed result; got %v; want %v", renamed, renamedExpected)
+		}
+		if result := mn.String(); result != resultExpected {
+			t.Fatalf("unexpected metric name; got %s; want %s", result, resultExpected)
+		}
+	}
+	f(`foo,a=x`, `foo{a="x"}`, false)
+	f(`qwe,b=x`, `qwe{b="x"}`, false)
+	f(`bar`, `foo{}`, true)
+	f(`bar,b=x,c=y`, `foo{a="x",c="y"}`, true)
+	// the existing label mustn't be overwritten
+	f(`bar,a=y,b=x`, `foo{a="y",b="x"}`, true)
+}
+
+// newTagFilters returns tag filters from comma-separated string with `key=value`, `key!=value`, `key=~value` or `key!~value` items.
+//
+// Empty key matches metric name.
+func newTagFilters(s string) []storage.TagFilter {
+	var tfs []storage.TagFilter
+	for _, item := range strings.Split(s, ",") {
+		var tf storage.TagFilter
+		n := strings.IndexAny(item, "!=")
+		tf.Key = []byte(item[:n])
+		op := item[n:]
+		switch {
+		case strings.HasPrefix(op, "!~"):
+			tf.IsNegative, tf.IsRegexp = true, true
+		case strings.HasPrefix(op, "!="):
+			tf.IsNegative = true
+		case strings.HasPrefix(op, "=~"):
+			tf.IsRegexp = true
+		}
+		if tf.IsNegative || tf.IsRegexp {
+			op = op[2:]
+		} else {
+			op = op[1:]
+		}
+		tf.Value = []byte(op)
+		tfs = append(tfs, tf)
+	}
+	return tfs
+}
+
+func tagFiltersToString(tfs []storage.TagFilter) string {
+	var a []string
+	for _, tf := range tfs {
+		op := "="
+		switch {
+		case tf.IsNegative && tf.IsRegexp:
+			op = "!~"
+		case tf.IsNegative:
+			op = "!="
+		case tf.IsRegexp:
+			op = "=~"
+		}
+		a = append(a, string(tf.Key)+op+string(tf.Value))
+	}
+	return strings.Join(a, ",")
+}
+
+func TestRegister(t *testing.T) {
+	path := t.TempDir() + "/metric_aliases.json"
+	resets := 0
+	mu.Lock()
+	fileAliases = []Alias{{Metric: "foo", NewMetric: "bar"}}
+	registeredAliases = nil
+	registeredPath = path
+	resetCacheFunc = func() { resets++ }
+	mu.Unlock()
+	defer func() {
+		mu.Lock()
+		fileAliases = nil
+		registeredAliases = nil
+		registeredPath = ""
+		resetCacheFunc = nil
+		asGlobal.Store((*Aliases)(nil))
+		mu.Unlock()
+	}()
+
+	// invalid alias
+	if err := Register(Alias{Metric: "baz"}); err == nil {
+		t.Fatalf("expecting non-nil error for alias without new_metric and labels")
+	}
+	// alias from the file cannot be overridden
+	if err := Register(Alias{Metric: "foo", NewMetric: "qwe"}); err == nil {
+		t.Fatalf("expecting non-nil error when overriding alias from the file")
+	}
+
+	if err := Register(Alias{Metric: "node_cpu_seconds_total", NewMetric: "node_cpu", Labels: map[string]string{"mode": "cpu_mode"}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// re-registering replaces the previous alias
+	if err := Register(Alias{Metric: "node_cpu_seconds_total", NewMetric: "node_cpu"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if resets != 2 {
+		t.Fatalf("unexpected number of cache resets; got %d; want 2", resets)
+	}
+	ais := List()
+	if len(ais) != 2 {
+		t.Fatalf("unexpected number of aliases; got %d; want 2", len(ais))
+	}
+	if ai := ais[1]; ai.Metric != "node_cpu_seconds_total" || ai.NewMetric != "node_cpu" || len(ai.Labels) != 0 || ai.Source != "api" {
+		t.Fatalf("unexpected registered alias %+v", ai)
+	}
+	if as := Get(); as == nil || as.m["node_cpu_seconds_total"] == nil || as.m["foo"] == nil {
+		t.Fatalf("registered alias must be active along with aliases from the file")
+	}
+
+	// registered aliases must be persisted
+	aliases, err := loadRegisteredAliases(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(aliases) != 1 || aliases[0].Metric != "node_cpu_seconds_total" {
+		t.Fatalf("unexpected persisted aliases %+v", aliases)
+	}
+
+	if err := Unregister("foo"); err == nil {
+		t.Fatalf("expecting non-nil error when unregistering alias from the file")
+	}
+	if err := Unregister("node_cpu_seconds_total"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if as := Get(); as.m["node_cpu_seconds_total"] != nil {
+		t.Fatalf("unregistered alias must be inactive")
+	}
+	aliases, err = loadRegisteredAliases(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(aliases) != 0 {
+		t.Fatalf("unexpected persisted aliases after unregistering %+v", aliases)
+	}
+}
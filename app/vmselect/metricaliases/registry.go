@@ -0,0 +1,160 @@
+package metricaliases
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+var (
+	// mu protects the variables below and serializes updates of asGlobal.
+	mu sync.Mutex
+
+	// fileAliases contains aliases loaded from -search.metricAliasesFile.
+	fileAliases []Alias
+
+	// registeredAliases contains aliases registered via Register.
+	registeredAliases []Alias
+
+	// registeredPath is the path for persisting registeredAliases.
+	registeredPath string
+
+	resetCacheFunc func()
+)
+
+// AliasInfo is an alias with its source.
+type AliasInfo struct {
+	Alias
+
+	// Source is the source of the alias: `file` for aliases from -search.metricAliasesFile
+	// and `api` for aliases registered via Register.
+	Source string `json:"source"`
+}
+
+// List returns all the active aliases sorted by metric name.
+func List() []AliasInfo {
+	mu.Lock()
+	defer mu.Unlock()
+
+	ais := make([]AliasInfo, 0, len(fileAliases)+len(registeredAliases))
+	for _, a := range fileAliases {
+		ais = append(ais, AliasInfo{Alias: a, Source: "file"})
+	}
+	for _, a := range registeredAliases {
+		ais = append(ais, AliasInfo{Alias: a, Source: "api"})
+	}
+	sort.Slice(ais, func(i, j int) bool {
+		return ais[i].Metric < ais[j].Metric
+	})
+	return ais
+}
+
+// Register registers a at runtime.
+//
+// The alias replaces the previously registered alias for the same metric.
+// It cannot override aliases from -search.metricAliasesFile.
+// Registered aliases are persisted across restarts.
+func Register(a Alias) error {
+	if _, err := parseAlias(&a); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, fa := range fileAliases {
+		if fa.Metric == a.Metric {
+			return fmt.Errorf("alias for metric %q is already defined at -search.metricAliasesFile", a.Metric)
+		}
+	}
+	registered := make([]Alias, 0, len(registeredAliases)+1)
+	for _, ra := range registeredAliases {
+		if ra.Metric != a.Metric {
+			registered = append(registered, ra)
+		}
+	}
+	registered = append(registered, a)
+	return updateRegisteredAliasesLocked(registered)
+}
+
+// Unregister removes the alias for the given metric registered via Register.
+func Unregister(metric string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registered := make([]Alias, 0, len(registeredAliases))
+	for _, ra := range registeredAliases {
+		if ra.Metric != metric {
+			registered = append(registered, ra)
+		}
+	}
+	if len(registered) == len(registeredAliases) {
+		return fmt.Errorf("cannot find registered alias for metric %q", metric)
+	}
+	return updateRegisteredAliasesLocked(registered)
+}
+
+func updateRegisteredAliasesLocked(registered []Alias) error {
+	as, err := newAliases(append(append([]Alias{}, fileAliases...), registered...))
+	if err != nil {
+		return err
+	}
+	if err := saveRegisteredAliases(registeredPath, registered); err != nil {
+		return err
+	}
+	registeredAliases = registered
+	asGlobal.Store(as)
+	if resetCacheFunc != nil {
+		resetCacheFunc()
+	}
+	return nil
+}
+
+// updateAliasesLocked replaces fileAliases with aliases.
+//
+// mu must be locked by the caller.
+func updateAliasesLocked(aliases []Alias) error {
+	as, err := newAliases(append(append([]Alias{}, aliases...), registeredAliases...))
+	if err != nil {
+		return fmt.Errorf("conflict with the aliases registered via API: %w", err)
+	}
+	fileAliases = aliases
+	asGlobal.Store(as)
+	return nil
+}
+
+func loadRegisteredAliases(path string) ([]Alias, error) {
+	if path == "" || !fs.IsPathExist(path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var aliases []Alias
+	if err := json.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	if _, err := newAliases(aliases); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return aliases, nil
+}
+
+func saveRegisteredAliases(path string, aliases []Alias) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(aliases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("BUG: cannot marshal aliases: %w", err)
+	}
+	if err := fs.WriteFileAtomically(path, data, true); err != nil {
+		return fmt.Errorf("cannot save registered aliases: %w", err)
+	}
+	return nil
+}
@@ -698,7 +698,15 @@ func (sb *sortBlock) reset() {
 func (sb *sortBlock) unpackFrom(tmpBlock *storage.Block, tbf *tmpBlocksFile, br blockRef, tr storage.TimeRange) error {
 	tmpBlock.Reset()
 	brReal := tbf.MustReadBlockRefAt(br.partRef, br.addr)
-	brReal.MustReadBlock(tmpBlock)
+	if err := brReal.ReadBlock(tmpBlock); err != nil {
+		if errors.Is(err, storage.ErrPartQuarantined) {
+			// Return partial results for series with blocks in quarantined parts.
+			sb.Timestamps = sb.Timestamps[:0]
+			sb.Values = sb.Values[:0]
+			return nil
+		}
+		return fmt.Errorf("cannot read block: %w", err)
+	}
 	if err := tmpBlock.UnmarshalData(); err != nil {
 		return fmt.Errorf("cannot unmarshal block: %w", err)
 	}
@@ -787,6 +795,48 @@ func DeleteSeries(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline sear
 	return vmstorage.DeleteSeries(qt, tfss)
 }
 
+// AddHold adds the given hold, which protects the matching series from deletion.
+func AddHold(qt *querytracer.Tracer, hold *storage.Hold) error {
+	qt = qt.NewChild("add hold: filters=%s", hold.FiltersString())
+	defer qt.Done()
+	return vmstorage.AddHold(hold)
+}
+
+// ReleaseHold releases the hold with the given id.
+func ReleaseHold(qt *querytracer.Tracer, id, author, reason string) error {
+	qt = qt.NewChild("release hold: id=%s", id)
+	defer qt.Done()
+	return vmstorage.ReleaseHold(id, author, reason)
+}
+
+// ListHolds returns active holds.
+func ListHolds(qt *querytracer.Tracer) []storage.Hold {
+	qt = qt.NewChild("list holds")
+	defer qt.Done()
+	return vmstorage.ListHolds()
+}
+
+// GetHoldsAuditLog returns audit records for actions with holds.
+func GetHoldsAuditLog(qt *querytracer.Tracer) ([]storage.HoldAuditRecord, error) {
+	qt = qt.NewChild("get holds audit log")
+	defer qt.Done()
+	return vmstorage.GetHoldsAuditLog()
+}
+
+// ExtendRetention extends the storage retention to retentionMsecs.
+func ExtendRetention(qt *querytracer.Tracer, retentionMsecs int64) error {
+	qt = qt.NewChild("extend retention: retentionMsecs=%d", retentionMsecs)
+	defer qt.Done()
+	return vmstorage.ExtendRetention(retentionMsecs)
+}
+
+// GetRetentionReport returns retention report for the storage.
+func GetRetentionReport(qt *querytracer.Tracer) *storage.RetentionReport {
+	qt = qt.NewChild("get retention report")
+	defer qt.Done()
+	return vmstorage.GetRetentionReport()
+}
+
 // LabelNames returns label names matching the given sq until the given deadline.
 func LabelNames(qt *querytracer.Tracer, sq *storage.SearchQuery, maxLabelNames int, deadline searchutils.Deadline) ([]string, error) {
 	qt = qt.NewChild("get labels: %s", sq)
@@ -956,6 +1006,165 @@ func TSDBStatus(qt *querytracer.Tracer, sq *storage.SearchQuery, focusLabel stri
 	return status, nil
 }
 
+// TSDBStatusFromSketches returns approximate tsdb status for the given date and tenant from cardinality sketches.
+func TSDBStatusFromSketches(qt *querytracer.Tracer, date uint64, tenant string, topN int) (*storage.TSDBStatus, error) {
+	qt = qt.NewChild("get tsdb stats from cardinality sketches: date=%d, tenant=%q, topN=%d", date, tenant, topN)
+	defer qt.Done()
+	status, err := vmstorage.GetTSDBStatusFromSketches(date, tenant, topN)
+	if err != nil {
+		return nil, fmt.Errorf("error during tsdb status request: %w", err)
+	}
+	return status, nil
+}
+
+// SelectorStats returns stats for series matching sq.
+//
+// The stats are obtained from the index without reading the data blocks.
+func SelectorStats(qt *querytracer.Tracer, sq *storage.SearchQuery, focusLabel string, topN int, deadline searchutils.Deadline) (*storage.SelectorStats, error) {
+	qt = qt.NewChild("get selector stats: %s, focusLabel=%q, topN=%d", sq, focusLabel, topN)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting the query processing: %s", deadline.String())
+	}
+	tr := sq.GetTimeRange()
+	if err := vmstorage.CheckTimeRange(tr); err != nil {
+		return nil, err
+	}
+	tfss, err := setupTfss(qt, tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := vmstorage.GetSelectorStats(qt, tfss, tr, focusLabel, topN, sq.MaxMetrics, deadline.Deadline())
+	if err != nil {
+		if errors.Is(err, storage.ErrDeadlineExceeded) {
+			return nil, fmt.Errorf("timeout exceeded during the query: %s", deadline.String())
+		}
+		return nil, fmt.Errorf("error during selector stats request: %w", err)
+	}
+	return ss, nil
+}
+
+// MetricNameStats contains storage stats for a single metric name.
+type MetricNameStats struct {
+	// MetricName is the metric name.
+	MetricName string
+
+	// SeriesCount is the number of time series with the given MetricName.
+	SeriesCount uint64
+
+	// SamplesCount is the number of samples for the given MetricName.
+	SamplesCount uint64
+
+	// SizeBytes is the approximate size in bytes occupied by samples for the given MetricName.
+	SizeBytes uint64
+}
+
+// MetricNamesStats returns storage stats per each metric name for time series matching sq.
+//
+// The stats are calculated from block headers without reading the blocks data,
+// so samples and sizes are counted for the whole blocks intersecting the time range from sq.
+// The returned stats are sorted by SizeBytes in descending order.
+func MetricNamesStats(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline searchutils.Deadline) ([]MetricNameStats, error) {
+	qt = qt.NewChild("get metric names stats: %s", sq)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, fmt.Errorf("timeout exceeded before starting the query processing: %s", deadline.String())
+	}
+	tr := sq.GetTimeRange()
+	if err := vmstorage.CheckTimeRange(tr); err != nil {
+		return nil, err
+	}
+	tfss, err := setupTfss(qt, tr, sq.TagFilterss, sq.MaxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	vmstorage.WG.Add(1)
+	defer vmstorage.WG.Done()
+
+	sr := getStorageSearch()
+	defer putStorageSearch(sr)
+	startTime := time.Now()
+	sr.Init(qt, vmstorage.Storage, tfss, tr, sq.MaxMetrics, deadline.Deadline())
+	indexSearchDuration.UpdateDuration(startTime)
+
+	var msa metricNamesStatsAggregator
+	blocksRead := 0
+	for sr.NextMetricBlock() {
+		blocksRead++
+		if deadline.Exceeded() {
+			return nil, fmt.Errorf("timeout exceeded while fetching data block #%d from storage: %s", blocksRead, deadline.String())
+		}
+		br := sr.MetricBlockRef.BlockRef
+		if err := msa.addBlock(sr.MetricBlockRef.MetricName, br.RowsCount(), br.SizeBytes()); err != nil {
+			return nil, fmt.Errorf("cannot process block #%d: %w", blocksRead, err)
+		}
+	}
+	if err := sr.Error(); err != nil {
+		if errors.Is(err, storage.ErrDeadlineExceeded) {
+			return nil, fmt.Errorf("timeout exceeded during the query: %s", deadline.String())
+		}
+		return nil, fmt.Errorf("search error after reading %d data blocks: %w", blocksRead, err)
+	}
+	result := msa.getResult()
+	qt.Printf("collected stats for %d metric names from %d blocks", len(result), blocksRead)
+	return result, nil
+}
+
+// metricNamesStatsAggregator aggregates per-block stats into per-metric name stats.
+type metricNamesStatsAggregator struct {
+	m              map[string]*MetricNameStats
+	mn             storage.MetricName
+	prevMetricName []byte
+	stats          *MetricNameStats
+}
+
+// addBlock adds stats for the block with the given rowsCount and sizeBytes for the given metricName to msa.
+//
+// Blocks for the same time series must be added one after another.
+func (msa *metricNamesStatsAggregator) addBlock(metricName []byte, rowsCount, sizeBytes int) error {
+	// Blocks for the same time series are added one after another,
+	// so it is enough to compare the current metric name with the previous one
+	// in order to detect a new time series.
+	if msa.stats == nil || string(metricName) != string(msa.prevMetricName) {
+		if err := msa.mn.Unmarshal(metricName); err != nil {
+			return fmt.Errorf("cannot unmarshal metricName: %w", err)
+		}
+		if msa.m == nil {
+			msa.m = make(map[string]*MetricNameStats)
+		}
+		stats := msa.m[string(msa.mn.MetricGroup)]
+		if stats == nil {
+			stats = &MetricNameStats{
+				MetricName: string(msa.mn.MetricGroup),
+			}
+			msa.m[stats.MetricName] = stats
+		}
+		stats.SeriesCount++
+		msa.stats = stats
+		msa.prevMetricName = append(msa.prevMetricName[:0], metricName...)
+	}
+	msa.stats.SamplesCount += uint64(rowsCount)
+	msa.stats.SizeBytes += uint64(sizeBytes)
+	return nil
+}
+
+// getResult returns the aggregated stats sorted by SizeBytes in descending order.
+func (msa *metricNamesStatsAggregator) getResult() []MetricNameStats {
+	result := make([]MetricNameStats, 0, len(msa.m))
+	for _, stats := range msa.m {
+		result = append(result, *stats)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := &result[i], &result[j]
+		if a.SizeBytes != b.SizeBytes {
+			return a.SizeBytes > b.SizeBytes
+		}
+		return a.MetricName < b.MetricName
+	})
+	return result
+}
+
 // SeriesCount returns the number of unique series.
 func SeriesCount(qt *querytracer.Tracer, deadline searchutils.Deadline) (uint64, error) {
 	qt = qt.NewChild("get series count")
@@ -1060,7 +1269,15 @@ func ExportBlocks(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline sear
 			return fmt.Errorf("cannot unmarshal metricName for block #%d: %w", blocksRead, err)
 		}
 		br := sr.MetricBlockRef.BlockRef
-		br.MustReadBlock(&xw.b)
+		if err := br.ReadBlock(&xw.b); err != nil {
+			if errors.Is(err, storage.ErrPartQuarantined) {
+				// Return partial results for series with blocks in quarantined parts.
+				xw.reset()
+				exportWorkPool.Put(xw)
+				continue
+			}
+			return fmt.Errorf("cannot read block #%d: %w", blocksRead, err)
+		}
 		samples += br.RowsCount()
 		workCh <- xw
 	}
@@ -1129,6 +1346,41 @@ func SearchMetricNames(qt *querytracer.Tracer, sq *storage.SearchQuery, deadline
 	return metricNames, nil
 }
 
+// IterateMetricNames returns up to limit metric names matching tagFilterss for series with metricID bigger than afterMetricID.
+//
+// Metric names are returned in the order they are stored in the index. The returned nextMetricID must be passed
+// as afterMetricID to the next call in order to continue the iteration. nextMetricID is 0 when the iteration is finished.
+func IterateMetricNames(qt *querytracer.Tracer, tagFilterss [][]storage.TagFilter, afterMetricID uint64, limit int, deadline searchutils.Deadline) ([]string, uint64, error) {
+	qt = qt.NewChild("iterate over metric names: afterMetricID=%d, limit=%d", afterMetricID, limit)
+	defer qt.Done()
+	if deadline.Exceeded() {
+		return nil, 0, fmt.Errorf("timeout exceeded before starting to iterate over metric names: %s", deadline.String())
+	}
+
+	// Setup search. The time range is used only for Graphite filters, since the iteration covers all the series in the index.
+	tr := storage.TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: time.Now().UnixNano() / 1e6,
+	}
+	tfss, err := setupTfss(qt, tr, tagFilterss, limit, deadline)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	metricNames, nextMetricID, err := vmstorage.IterateMetricNames(qt, tfss, afterMetricID, limit, deadline.Deadline())
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot iterate over metric names: %w", err)
+	}
+	return metricNames, nextMetricID, nil
+}
+
+// RegisterMetricNames registers metric names from mrs in the index without storing samples.
+func RegisterMetricNames(qt *querytracer.Tracer, mrs []storage.MetricRow) error {
+	qt = qt.NewChild("register %d metric names", len(mrs))
+	defer qt.Done()
+	return vmstorage.RegisterMetricNames(qt, mrs)
+}
+
 // ProcessSearchQuery performs sq until the given deadline.
 //
 // Results.RunParallel or Results.Cancel must be called on the returned Results.
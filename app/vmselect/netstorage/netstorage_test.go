@@ -3,6 +3,8 @@ package netstorage
 import (
 	"reflect"
 	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
 func TestMergeSortBlocks(t *testing.T) {
@@ -194,3 +196,79 @@ func TestMergeSortBlocks(t *testing.T) {
 		Values:     []float64{7, 24, 26},
 	})
 }
+
+func TestMetricNamesStatsAggregator(t *testing.T) {
+	type block struct {
+		metricGroup string
+		job         string
+		rowsCount   int
+		sizeBytes   int
+	}
+	f := func(blocks []block, resultExpected []MetricNameStats) {
+		t.Helper()
+		var msa metricNamesStatsAggregator
+		for _, b := range blocks {
+			var mn storage.MetricName
+			mn.MetricGroup = []byte(b.metricGroup)
+			mn.AddTag("job", b.job)
+			if err := msa.addBlock(mn.Marshal(nil), b.rowsCount, b.sizeBytes); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		}
+		result := msa.getResult()
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%+v\nwant\n%+v", result, resultExpected)
+		}
+	}
+
+	// No blocks
+	f(nil, []MetricNameStats{})
+
+	// Multiple blocks for a single series
+	f([]block{
+		{"foo", "a", 10, 100},
+		{"foo", "a", 20, 200},
+	}, []MetricNameStats{
+		{
+			MetricName:   "foo",
+			SeriesCount:  1,
+			SamplesCount: 30,
+			SizeBytes:    300,
+		},
+	})
+
+	// Multiple series per metric name; the result is sorted by size in descending order
+	f([]block{
+		{"foo", "a", 10, 100},
+		{"foo", "b", 5, 50},
+		{"bar", "a", 100, 1000},
+		{"bar", "a", 1, 10},
+		{"baz", "a", 3, 150},
+		{"aaa", "a", 2, 150},
+	}, []MetricNameStats{
+		{
+			MetricName:   "bar",
+			SeriesCount:  1,
+			SamplesCount: 101,
+			SizeBytes:    1010,
+		},
+		{
+			MetricName:   "aaa",
+			SeriesCount:  1,
+			SamplesCount: 2,
+			SizeBytes:    150,
+		},
+		{
+			MetricName:   "baz",
+			SeriesCount:  1,
+			SamplesCount: 3,
+			SizeBytes:    150,
+		},
+		{
+			MetricName:   "foo",
+			SeriesCount:  2,
+			SamplesCount: 15,
+			SizeBytes:    150,
+		},
+	})
+}
@@ -1,10 +1,10 @@
 // Code generated by qtc from "federate.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-//line app/vmselect/prometheus/federate.qtpl:1
+//line federate.qtpl:1
 package prometheus
 
-//line app/vmselect/prometheus/federate.qtpl:1
+//line federate.qtpl:1
 import (
 	"math"
 
@@ -13,78 +13,84 @@ import (
 
 // Federate writes rs in /federate format.// See https://prometheus.io/docs/prometheus/latest/federation/
 
-//line app/vmselect/prometheus/federate.qtpl:11
+//line federate.qtpl:11
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vmselect/prometheus/federate.qtpl:11
+//line federate.qtpl:11
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vmselect/prometheus/federate.qtpl:11
+//line federate.qtpl:11
 func StreamFederate(qw422016 *qt422016.Writer, rs *netstorage.Result) {
-//line app/vmselect/prometheus/federate.qtpl:13
+//line federate.qtpl:13
 	values := rs.Values
 	timestamps := rs.Timestamps
 
-//line app/vmselect/prometheus/federate.qtpl:16
+//line federate.qtpl:16
 	if len(timestamps) == 0 || len(values) == 0 {
-//line app/vmselect/prometheus/federate.qtpl:16
+//line federate.qtpl:16
 		return
-//line app/vmselect/prometheus/federate.qtpl:16
+//line federate.qtpl:16
 	}
-//line app/vmselect/prometheus/federate.qtpl:18
+//line federate.qtpl:17
+	if len(rs.MetricName.MetricGroup) == 0 {
+//line federate.qtpl:22
+		return
+//line federate.qtpl:23
+	}
+//line federate.qtpl:25
 	lastValue := values[len(values)-1]
 
-//line app/vmselect/prometheus/federate.qtpl:20
+//line federate.qtpl:27
 	if math.IsNaN(lastValue) {
-//line app/vmselect/prometheus/federate.qtpl:26
+//line federate.qtpl:33
 		return
-//line app/vmselect/prometheus/federate.qtpl:27
+//line federate.qtpl:34
 	}
-//line app/vmselect/prometheus/federate.qtpl:28
+//line federate.qtpl:35
 	streamprometheusMetricName(qw422016, &rs.MetricName)
-//line app/vmselect/prometheus/federate.qtpl:28
+//line federate.qtpl:35
 	qw422016.N().S(` `)
-//line app/vmselect/prometheus/federate.qtpl:29
+//line federate.qtpl:36
 	qw422016.N().F(lastValue)
-//line app/vmselect/prometheus/federate.qtpl:29
+//line federate.qtpl:36
 	qw422016.N().S(` `)
-//line app/vmselect/prometheus/federate.qtpl:30
+//line federate.qtpl:37
 	qw422016.N().DL(timestamps[len(timestamps)-1])
-//line app/vmselect/prometheus/federate.qtpl:30
+//line federate.qtpl:37
 	qw422016.N().S(`
 `)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 }
 
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 func WriteFederate(qq422016 qtio422016.Writer, rs *netstorage.Result) {
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	StreamFederate(qw422016, rs)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 }
 
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 func Federate(rs *netstorage.Result) string {
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	WriteFederate(qb422016, rs)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 	return qs422016
-//line app/vmselect/prometheus/federate.qtpl:31
+//line federate.qtpl:38
 }
@@ -0,0 +1,156 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// CreateHoldHandler processes /api/v1/admin/holds/create request.
+//
+// See https://docs.victoriametrics.com/#compliance-holds
+func CreateHoldHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer createHoldDuration.UpdateDuration(startTime)
+
+	matches := append([]string{}, r.Form["match[]"]...)
+	matches = append(matches, r.Form["match"]...)
+	if len(matches) == 0 {
+		return fmt.Errorf("missing `match[]` arg")
+	}
+	tagFilterss, err := getTagFilterssFromMatches(matches)
+	if err != nil {
+		return err
+	}
+	for _, tfs := range tagFilterss {
+		for _, tf := range tfs {
+			if string(tf.Key) == "__graphite__" {
+				return fmt.Errorf("Graphite filters aren't supported in holds")
+			}
+		}
+	}
+	start, err := searchutils.GetTime(r, "start", 0)
+	if err != nil {
+		return err
+	}
+	end, err := searchutils.GetTime(r, "end", startTime.UnixNano()/1e6)
+	if err != nil {
+		return err
+	}
+	hold := &storage.Hold{
+		Filters: tagFilterss,
+		TimeRange: storage.TimeRange{
+			MinTimestamp: start,
+			MaxTimestamp: end,
+		},
+		Reason: r.FormValue("reason"),
+		Author: r.FormValue("author"),
+	}
+	err = netstorage.AddHold(nil, hold)
+	ae := auditlog.NewEvent(r, "hold_create")
+	ae.Target = hold.ID
+	ae.Matches = matches
+	ae.SetDetail("reason", hold.Reason)
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot create hold: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":{"id":%q}}`, hold.ID)
+	return nil
+}
+
+var createHoldDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/holds/create"}`)
+
+// ReleaseHoldHandler processes /api/v1/admin/holds/release request.
+func ReleaseHoldHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer releaseHoldDuration.UpdateDuration(startTime)
+
+	id := r.FormValue("id")
+	if id == "" {
+		return fmt.Errorf("missing `id` arg")
+	}
+	err := netstorage.ReleaseHold(nil, id, r.FormValue("author"), r.FormValue("reason"))
+	ae := auditlog.NewEvent(r, "hold_release")
+	ae.Target = id
+	ae.SetDetail("reason", r.FormValue("reason"))
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot release hold: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success"}`)
+	return nil
+}
+
+var releaseHoldDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/holds/release"}`)
+
+type holdResponse struct {
+	ID        string   `json:"id"`
+	Match     []string `json:"match"`
+	Start     int64    `json:"start"`
+	End       int64    `json:"end"`
+	Reason    string   `json:"reason"`
+	Author    string   `json:"author,omitempty"`
+	CreatedAt int64    `json:"created_at"`
+}
+
+// ListHoldsHandler processes /api/v1/admin/holds request.
+func ListHoldsHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer listHoldsDuration.UpdateDuration(startTime)
+
+	hs := netstorage.ListHolds(nil)
+	data := make([]holdResponse, len(hs))
+	for i := range hs {
+		hold := &hs[i]
+		data[i] = holdResponse{
+			ID:        hold.ID,
+			Match:     hold.FiltersString(),
+			Start:     hold.TimeRange.MinTimestamp,
+			End:       hold.TimeRange.MaxTimestamp,
+			Reason:    hold.Reason,
+			Author:    hold.Author,
+			CreatedAt: hold.CreatedAt,
+		}
+	}
+	return writeJSONSuccess(w, data)
+}
+
+var listHoldsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/holds"}`)
+
+// HoldsAuditHandler processes /api/v1/admin/holds/audit request.
+func HoldsAuditHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer holdsAuditDuration.UpdateDuration(startTime)
+
+	ars, err := netstorage.GetHoldsAuditLog(nil)
+	if err != nil {
+		return fmt.Errorf("cannot read holds audit log: %w", err)
+	}
+	if ars == nil {
+		ars = []storage.HoldAuditRecord{}
+	}
+	return writeJSONSuccess(w, ars)
+}
+
+var holdsAuditDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/holds/audit"}`)
+
+func writeJSONSuccess(w http.ResponseWriter, data interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	resp := struct {
+		Status string      `json:"status"`
+		Data   interface{} `json:"data"`
+	}{
+		Status: "success",
+		Data:   data,
+	}
+	if err := json.NewEncoder(w).Encode(&resp); err != nil {
+		return fmt.Errorf("cannot send response to the client: %w", err)
+	}
+	return nil
+}
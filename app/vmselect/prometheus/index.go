@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// indexBatchSize is the number of metric names to process in a single batch during index export and import.
+const indexBatchSize = 10000
+
+// maxIndexMetricNameSize is the maximum size of a single metric name accepted by ImportIndexHandler.
+const maxIndexMetricNameSize = 1024 * 1024
+
+// ExportIndexHandler processes /api/v1/admin/tsdb/export_index request.
+//
+// It exports metric names for all the series matching the optional `match[]` args in the order they are stored in the index.
+// Every metric name is encoded as a 4-byte big-endian length followed by the marshaled metric name,
+// i.e. in the same way as metric names are encoded in /api/v1/export/native.
+//
+// See https://docs.victoriametrics.com/#index-export-and-import
+func ExportIndexHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer exportIndexDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParams(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	cp.deadline = searchutils.GetDeadlineForExport(r, startTime)
+
+	w.Header().Set("Content-Type", "VictoriaMetrics/index")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	var buf []byte
+	afterMetricID := uint64(0)
+	for {
+		metricNames, nextMetricID, err := netstorage.IterateMetricNames(nil, cp.filterss, afterMetricID, indexBatchSize, cp.deadline)
+		if err != nil {
+			return err
+		}
+		buf = buf[:0]
+		for _, metricName := range metricNames {
+			buf = encoding.MarshalUint32(buf, uint32(len(metricName)))
+			buf = append(buf, metricName...)
+		}
+		if _, err := bw.Write(buf); err != nil {
+			return fmt.Errorf("cannot send index to remote client: %w", err)
+		}
+		exportIndexSeries.Add(len(metricNames))
+		if nextMetricID == 0 {
+			break
+		}
+		afterMetricID = nextMetricID
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send index to remote client: %w", err)
+	}
+	return nil
+}
+
+var (
+	exportIndexDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/tsdb/export_index"}`)
+	exportIndexSeries   = metrics.NewCounter(`vm_index_exported_series_total`)
+)
+
+// ImportIndexHandler processes /api/v1/admin/tsdb/import_index request.
+//
+// It registers series from the request body in the format returned by ExportIndexHandler without storing samples for them,
+// so the index doesn't need to be re-built when samples for these series are ingested.
+//
+// See https://docs.victoriametrics.com/#index-export-and-import
+func ImportIndexHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer importIndexDuration.UpdateDuration(startTime)
+
+	if r.Method != http.MethodPost {
+		return fmt.Errorf("unsupported method %q; use POST", r.Method)
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		// The request body is already consumed by form parsing in this case.
+		return fmt.Errorf("unsupported Content-Type: %q; use `Content-Type: application/octet-stream`", r.Header.Get("Content-Type"))
+	}
+	ae := auditlog.NewEvent(r, "index_import")
+	n, err := importIndex(r.Body, startTime.UnixNano()/1e6)
+	ae.SetDetail("series", fmt.Sprintf("%d", n))
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot import index after registering %d series: %w", n, err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success","data":{"seriesImported":%d}}`, n)
+	return nil
+}
+
+var importIndexDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/tsdb/import_index"}`)
+
+// importIndex registers series read from r at the given timestamp and returns the number of registered series.
+func importIndex(r io.Reader, timestamp int64) (int, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var sizeBuf [4]byte
+	var buf []byte
+	var labels []prompb.Label
+	var mn storage.MetricName
+	mrs := make([]storage.MetricRow, 0, indexBatchSize)
+	n := 0
+	flush := func() error {
+		if len(mrs) == 0 {
+			return nil
+		}
+		if err := netstorage.RegisterMetricNames(nil, mrs); err != nil {
+			return err
+		}
+		n += len(mrs)
+		importIndexSeries.Add(len(mrs))
+		mrs = mrs[:0]
+		return nil
+	}
+	for {
+		if _, err := io.ReadFull(br, sizeBuf[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return n, fmt.Errorf("cannot read metric name size: %w", err)
+		}
+		size := encoding.UnmarshalUint32(sizeBuf[:])
+		if size > maxIndexMetricNameSize {
+			return n, fmt.Errorf("too big metric name size: %d bytes; it mustn't exceed %d bytes", size, maxIndexMetricNameSize)
+		}
+		buf = bytesutil.ResizeNoCopyMayOverallocate(buf, int(size))
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return n, fmt.Errorf("cannot read metric name with size %d bytes: %w", size, err)
+		}
+		if err := mn.Unmarshal(buf); err != nil {
+			return n, fmt.Errorf("cannot unmarshal metric name: %w", err)
+		}
+		labels = append(labels[:0], prompb.Label{
+			Name:  []byte("__name__"),
+			Value: mn.MetricGroup,
+		})
+		for _, tag := range mn.Tags {
+			labels = append(labels, prompb.Label{
+				Name:  tag.Key,
+				Value: tag.Value,
+			})
+		}
+		mrs = mrs[:len(mrs)+1]
+		mr := &mrs[len(mrs)-1]
+		mr.MetricNameRaw = storage.MarshalMetricNameRaw(mr.MetricNameRaw[:0], labels)
+		if len(mr.MetricNameRaw) == 0 {
+			// The series is rejected because of label limits.
+			mrs = mrs[:len(mrs)-1]
+			continue
+		}
+		mr.Timestamp = timestamp
+		if len(mrs) >= indexBatchSize {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+var importIndexSeries = metrics.NewCounter(`vm_index_imported_series_total`)
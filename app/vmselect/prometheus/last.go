@@ -0,0 +1,86 @@
+package prometheus
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/bufferedwriter"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// LastHandler processes /api/v1/last request.
+//
+// It returns the last raw sample per each series matching `match[]` on the (end-lookback ... end] time range.
+// The samples are read directly from the storage without rollup calculations, so the request is cheaper than /api/v1/query.
+//
+// See https://docs.victoriametrics.com/#last-sample-api
+func LastHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer lastDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParams(r, startTime, true)
+	if err != nil {
+		return err
+	}
+	lookbackDelta, err := getMaxLookback(r)
+	if err != nil {
+		return err
+	}
+	if lookbackDelta <= 0 {
+		lookbackDelta = defaultStep
+	}
+	cp.start = cp.end - lookbackDelta
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxFederateSeries, "-search.maxFederateSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
+	rss, err := netstorage.ProcessSearchQuery(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch data for %q: %w", sq, err)
+	}
+
+	var resultLock sync.Mutex
+	var result []netstorage.Result
+	err = rss.RunParallel(qt, func(rs *netstorage.Result, workerID uint) error {
+		if len(rs.Values) == 0 {
+			return nil
+		}
+		lastValue := rs.Values[len(rs.Values)-1]
+		if math.IsNaN(lastValue) {
+			// This is most likely a staleness marker, so the series is stale.
+			return nil
+		}
+		var lr netstorage.Result
+		lr.MetricName.CopyFrom(&rs.MetricName)
+		lr.Timestamps = []int64{rs.Timestamps[len(rs.Timestamps)-1]}
+		lr.Values = []float64{lastValue}
+		resultLock.Lock()
+		result = append(result, lr)
+		resultLock.Unlock()
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("cannot obtain last samples for %q: %w", sq, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("last samples for %s: series=%d", sq, len(result))
+	}
+	WriteQueryResponse(bw, result, nil, qt, qtDone)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot flush last samples response to remote client: %w", err)
+	}
+	return nil
+}
+
+var lastDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/last"}`)
@@ -0,0 +1,97 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// LintQueryHandler processes /api/v1/lint_query request.
+//
+// It reports unknown functions and suspicious constructs in the given MetricsQL `query`
+// and metric selectors from the query, which match no series on the given [start ... end] time range.
+//
+// See https://docs.victoriametrics.com/#metricsql-linter
+func LintQueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer lintQueryDuration.UpdateDuration(startTime)
+
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	ct := startTime.UnixNano() / 1e6
+	end, err := searchutils.GetTime(r, "end", ct)
+	if err != nil {
+		return err
+	}
+	start, err := searchutils.GetTime(r, "start", end-secsPerDay*1000)
+	if err != nil {
+		return err
+	}
+	deadline := searchutils.GetDeadlineForStatusRequest(r, startTime)
+
+	issues, mes := promql.LintQuery(query)
+	checked := make(map[string]bool, len(mes))
+	for _, me := range mes {
+		selector := string(me.AppendString(nil))
+		if checked[selector] {
+			continue
+		}
+		checked[selector] = true
+		tfss := [][]storage.TagFilter{searchutils.ToTagFilters(me.LabelFilters)}
+		sq := storage.NewSearchQuery(start, end, tfss, *maxTSDBStatusSeries)
+		names, err := netstorage.LabelValues(qt, "__name__", sq, 1, deadline)
+		if err != nil {
+			issues = append(issues, promql.LintIssue{
+				Severity: "warning",
+				Message:  fmt.Sprintf("cannot check the selector against the index: %s", err),
+				Expr:     selector,
+			})
+			continue
+		}
+		if len(names) == 0 {
+			issues = append(issues, promql.LintIssue{
+				Severity: "warning",
+				Message:  "the selector matches no series on the given time range",
+				Expr:     selector,
+			})
+		}
+	}
+	if issues == nil {
+		issues = []promql.LintIssue{}
+	}
+	return writeJSONSuccess(w, map[string]interface{}{
+		"query":  query,
+		"issues": issues,
+	})
+}
+
+var lintQueryDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/lint_query"}`)
+
+// FormatQueryHandler processes /api/v1/format_query request.
+//
+// It returns the normalized and pretty-printed MetricsQL `query` in the same format as Prometheus does.
+//
+// See https://docs.victoriametrics.com/#metricsql-formatter
+func FormatQueryHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer formatQueryDuration.UpdateDuration(startTime)
+
+	query := r.FormValue("query")
+	if len(query) == 0 {
+		return fmt.Errorf("missing `query` arg")
+	}
+	result, err := promql.FormatQuery(query)
+	if err != nil {
+		return fmt.Errorf("cannot parse query %q: %w", query, err)
+	}
+	return writeJSONSuccess(w, result)
+}
+
+var formatQueryDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/format_query"}`)
@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/metricaliases"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// ListMetricAliasesHandler processes /api/v1/admin/metric_aliases request.
+//
+// See https://docs.victoriametrics.com/#metric-aliases
+func ListMetricAliasesHandler(startTime time.Time, w http.ResponseWriter, _ *http.Request) error {
+	defer listMetricAliasesDuration.UpdateDuration(startTime)
+
+	return writeJSONSuccess(w, metricaliases.List())
+}
+
+var listMetricAliasesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/metric_aliases"}`)
+
+// RegisterMetricAliasHandler processes /api/v1/admin/metric_aliases/register request.
+func RegisterMetricAliasHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer registerMetricAliasDuration.UpdateDuration(startTime)
+
+	a := metricaliases.Alias{
+		Metric:    r.FormValue("metric"),
+		NewMetric: r.FormValue("new_metric"),
+	}
+	for _, s := range r.Form["label"] {
+		n := strings.IndexByte(s, ':')
+		if n < 0 {
+			return fmt.Errorf("cannot parse `label=%q` arg; it must have the form `old_label:new_label`", s)
+		}
+		if a.Labels == nil {
+			a.Labels = make(map[string]string)
+		}
+		a.Labels[s[:n]] = s[n+1:]
+	}
+	err := metricaliases.Register(a)
+	ae := auditlog.NewEvent(r, "metric_alias_register")
+	ae.Target = a.Metric
+	ae.SetDetail("new_metric", a.NewMetric)
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot register alias: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success"}`)
+	return nil
+}
+
+var registerMetricAliasDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/metric_aliases/register"}`)
+
+// UnregisterMetricAliasHandler processes /api/v1/admin/metric_aliases/unregister request.
+func UnregisterMetricAliasHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer unregisterMetricAliasDuration.UpdateDuration(startTime)
+
+	metric := r.FormValue("metric")
+	if metric == "" {
+		return fmt.Errorf("missing `metric` arg")
+	}
+	err := metricaliases.Unregister(metric)
+	ae := auditlog.NewEvent(r, "metric_alias_unregister")
+	ae.Target = metric
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot unregister alias: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success"}`)
+	return nil
+}
+
+var unregisterMetricAliasDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/metric_aliases/unregister"}`)
@@ -0,0 +1,116 @@
+// Code generated by qtc from "metric_names_stats_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:1
+package prometheus
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// MetricNamesStatsResponse generates response for /api/v1/status/metric_names_stats .
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:8
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:8
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:8
+func StreamMetricNamesStatsResponse(qw422016 *qt422016.Writer, stats []netstorage.MetricNameStats, qt *querytracer.Tracer) {
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:10
+	var totalSeries, totalSamples, totalSizeBytes uint64
+	for i := range stats {
+		totalSeries += stats[i].SeriesCount
+		totalSamples += stats[i].SamplesCount
+		totalSizeBytes += stats[i].SizeBytes
+	}
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:16
+	qw422016.N().S(`{"status":"success","data":{"totalSeries":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:20
+	qw422016.N().DUL(totalSeries)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:20
+	qw422016.N().S(`,"totalSamples":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:21
+	qw422016.N().DUL(totalSamples)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:21
+	qw422016.N().S(`,"totalSizeBytes":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:22
+	qw422016.N().DUL(totalSizeBytes)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:22
+	qw422016.N().S(`,"records":[`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:24
+	for i, s := range stats {
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:24
+		qw422016.N().S(`{"metricName":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:26
+		qw422016.N().Q(s.MetricName)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:26
+		qw422016.N().S(`,"seriesCount":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:27
+		qw422016.N().DUL(s.SeriesCount)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:27
+		qw422016.N().S(`,"samplesCount":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:28
+		qw422016.N().DUL(s.SamplesCount)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:28
+		qw422016.N().S(`,"sizeBytes":`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:29
+		qw422016.N().DUL(s.SizeBytes)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:29
+		qw422016.N().S(`}`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:31
+		if i+1 < len(stats) {
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:31
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:31
+		}
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:32
+	}
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:32
+	qw422016.N().S(`]}`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:35
+	qt.Done()
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:36
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:36
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+}
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+func WriteMetricNamesStatsResponse(qq422016 qtio422016.Writer, stats []netstorage.MetricNameStats, qt *querytracer.Tracer) {
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	StreamMetricNamesStatsResponse(qw422016, stats, qt)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+}
+
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+func MetricNamesStatsResponse(stats []netstorage.MetricNameStats, qt *querytracer.Tracer) string {
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	WriteMetricNamesStatsResponse(qb422016, stats, qt)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+	return qs422016
+//line app/vmselect/prometheus/metric_names_stats_response.qtpl:38
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"net/http"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,16 +16,21 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/bufferedwriter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryprofiler"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
 	"github.com/valyala/fastjson/fastfloat"
 )
 
@@ -42,17 +48,35 @@ var (
 		"See also '-search.setLookbackToStep' flag")
 	setLookbackToStep = flag.Bool("search.setLookbackToStep", false, "Whether to fix lookback interval to 'step' query arg value. "+
 		"If set to true, the query model becomes closer to InfluxDB data model. If set to true, then -search.maxLookback and -search.maxStalenessInterval are ignored")
+	maxExtraLookbehind = flag.Duration("search.maxExtraLookbehind", 24*time.Hour, "The maximum value for extra_lookbehind query arg at /api/v1/query_range. "+
+		"This arg allows fetching raw samples before the start of the selected time range, so sparse counters have no artificial gaps at the beginning of graphs")
+	maxLookbehindWindow = flag.Duration("search.maxLookbehindWindow", 0, "The maximum lookbehind window in square brackets for rollup functions and subqueries "+
+		"at /api/v1/query and /api/v1/query_range. Bigger windows such as rate(m[365d]) are capped to this value in order to prevent from scanning "+
+		"the whole retention per each series. It can be lowered on per-query basis via max_lookbehind_window arg. Zero value disables the limit. "+
+		"See https://docs.victoriametrics.com/#resource-usage-limits")
+	maxLookbehindWindowPerTenant = flagutil.NewArrayString("search.maxLookbehindWindowPerTenant", "Optional per-tenant overrides for -search.maxLookbehindWindow "+
+		"in the form tenant:duration. For example, -search.maxLookbehindWindowPerTenant=batch:30d. The tenant is obtained from -search.tenantHeader")
+	promqlCompat = flag.Bool("search.promqlCompat", false, "Whether to disable MetricsQL behavioral extensions at /api/v1/query and /api/v1/query_range, "+
+		"so query results are closer to Prometheus results. This may be useful for comparing results with Prometheus during migration. "+
+		"It can be overridden on per-query basis via promql_compat arg. See also -search.promqlCompatTenants and https://docs.victoriametrics.com/#promql-compatibility-mode")
+	promqlCompatTenants = flagutil.NewArrayString("search.promqlCompatTenants", "Optional list of tenants for which -search.promqlCompat mode is enabled. "+
+		"The tenant is obtained from -search.tenantHeader")
 	maxStepForPointsAdjustment = flag.Duration("search.maxStepForPointsAdjustment", time.Minute, "The maximum step when /api/v1/query_range handler adjusts "+
 		"points with timestamps closer than -search.latencyOffset to the current time. The adjustment is needed because such points may contain incomplete data")
 
-	maxUniqueTimeseries    = flag.Int("search.maxUniqueTimeseries", 300e3, "The maximum number of unique time series, which can be selected during /api/v1/query and /api/v1/query_range queries. This option allows limiting memory usage")
-	maxFederateSeries      = flag.Int("search.maxFederateSeries", 1e6, "The maximum number of time series, which can be returned from /federate. This option allows limiting memory usage")
-	maxExportSeries        = flag.Int("search.maxExportSeries", 10e6, "The maximum number of time series, which can be returned from /api/v1/export* APIs. This option allows limiting memory usage")
-	maxTSDBStatusSeries    = flag.Int("search.maxTSDBStatusSeries", 10e6, "The maximum number of time series, which can be processed during the call to /api/v1/status/tsdb. This option allows limiting memory usage")
-	maxSeriesLimit         = flag.Int("search.maxSeries", 30e3, "The maximum number of time series, which can be returned from /api/v1/series. This option allows limiting memory usage")
+	maxUniqueTimeseries = flag.Int("search.maxUniqueTimeseries", 300e3, "The maximum number of unique time series, which can be selected during /api/v1/query and /api/v1/query_range queries. This option allows limiting memory usage")
+	maxFederateSeries   = flag.Int("search.maxFederateSeries", 1e6, "The maximum number of time series, which can be returned from /federate. This option allows limiting memory usage")
+	maxExportSeries     = flag.Int("search.maxExportSeries", 10e6, "The maximum number of time series, which can be returned from /api/v1/export* APIs. This option allows limiting memory usage")
+	maxTSDBStatusSeries = flag.Int("search.maxTSDBStatusSeries", 10e6, "The maximum number of time series, which can be processed during the call to /api/v1/status/tsdb, /api/v1/status/metric_names_stats and /api/v1/selector_stats. This option allows limiting memory usage")
+	maxSeriesLimit      = flag.Int("search.maxSeries", 30e3, "The maximum number of time series, which can be returned from /api/v1/series. This option allows limiting memory usage")
+	maxLabelsAPISeries  = flag.Int("search.maxLabelsAPISeries", 0, "The maximum number of time series, which could be scanned when searching for the matching time series "+
+		"at /api/v1/labels and /api/v1/label/.../values. This option allows limiting memory usage and CPU usage. By default -search.maxUniqueTimeseries is used")
 	maxPointsPerTimeseries = flag.Int("search.maxPointsPerTimeseries", 30e3, "The maximum points per a single timeseries returned from /api/v1/query_range. "+
 		"This option doesn't limit the number of scanned raw samples in the database. The main purpose of this option is to limit the number of per-series points "+
 		"returned to graphing UI such as VMUI or Grafana. There is no sense in setting this limit to values bigger than the horizontal resolution of the graph")
+	adjustStepForMaxPoints = flag.Bool("search.adjustStepForMaxPoints", false, "Whether to increase the step for /api/v1/query_range requests, which would return "+
+		"more than -search.maxPointsPerTimeseries points per series, instead of returning an error. The response contains a warning about the adjusted step. "+
+		"See https://docs.victoriametrics.com/#prometheus-querying-api-enhancements")
 )
 
 // Default step used if not set.
@@ -85,13 +109,19 @@ func FederateHandler(startTime time.Time, w http.ResponseWriter, r *http.Request
 	if cp.IsDefaultTimeRange() {
 		cp.start = cp.end - lookbackDelta
 	}
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxFederateSeries)
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxFederateSeries, "-search.maxFederateSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	rss, err := netstorage.ProcessSearchQuery(nil, sq, cp.deadline)
 	if err != nil {
 		return fmt.Errorf("cannot fetch data for %q: %w", sq, err)
 	}
 
-	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// Explicitly set the version of Prometheus text exposition format, since some Prometheus versions
+	// refuse parsing responses with unknown format.
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
 	sw := newScalableWriter(bw)
@@ -127,7 +157,11 @@ func ExportCSVHandler(startTime time.Time, w http.ResponseWriter, r *http.Reques
 	fieldNames := strings.Split(format, ",")
 	reduceMemUsage := searchutils.GetBool(r, "reduce_mem_usage")
 
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxExportSeries, "-search.maxExportSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
@@ -204,7 +238,11 @@ func ExportNativeHandler(startTime time.Time, w http.ResponseWriter, r *http.Req
 		return err
 	}
 
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxExportSeries, "-search.maxExportSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	w.Header().Set("Content-Type", "VictoriaMetrics/native")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
@@ -263,7 +301,15 @@ func ExportHandler(startTime time.Time, w http.ResponseWriter, r *http.Request)
 	format := r.FormValue("format")
 	maxRowsPerLine := int(fastfloat.ParseInt64BestEffort(r.FormValue("max_rows_per_line")))
 	reduceMemUsage := searchutils.GetBool(r, "reduce_mem_usage")
-	if err := exportHandler(nil, w, cp, format, maxRowsPerLine, reduceMemUsage); err != nil {
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxExportSeries, "-search.maxExportSeries")
+	if err != nil {
+		return err
+	}
+	lf, err := searchutils.GetLabelsFilter(r)
+	if err != nil {
+		return err
+	}
+	if err := exportHandler(nil, w, cp, format, maxRowsPerLine, reduceMemUsage, maxSeries, lf); err != nil {
 		return fmt.Errorf("error when exporting data on the time range (start=%d, end=%d): %w", cp.start, cp.end, err)
 	}
 	return nil
@@ -271,7 +317,8 @@ func ExportHandler(startTime time.Time, w http.ResponseWriter, r *http.Request)
 
 var exportDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/export"}`)
 
-func exportHandler(qt *querytracer.Tracer, w http.ResponseWriter, cp *commonParams, format string, maxRowsPerLine int, reduceMemUsage bool) error {
+func exportHandler(qt *querytracer.Tracer, w http.ResponseWriter, cp *commonParams, format string, maxRowsPerLine int, reduceMemUsage bool, maxSeries int,
+	lf *searchutils.LabelsFilter) error {
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
 	sw := newScalableWriter(bw)
@@ -343,8 +390,21 @@ func exportHandler(qt *querytracer.Tracer, w http.ResponseWriter, cp *commonPara
 			return nil
 		}
 	}
+	if lf != nil {
+		writeLineFuncOrig := writeLineFunc
+		writeLineFunc = func(xb *exportBlock, workerID uint) error {
+			// Do not modify xb.mn, since it may be shared with the caller. Apply lf to a copy instead.
+			mnOrig := xb.mn
+			xb.mnFiltered.CopyFrom(mnOrig)
+			lf.Apply(&xb.mnFiltered)
+			xb.mn = &xb.mnFiltered
+			err := writeLineFuncOrig(xb, workerID)
+			xb.mn = mnOrig
+			return err
+		}
+	}
 
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxExportSeries)
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	w.Header().Set("Content-Type", contentType)
 
 	doneCh := make(chan error, 1)
@@ -416,10 +476,14 @@ type exportBlock struct {
 	mn         *storage.MetricName
 	timestamps []int64
 	values     []float64
+
+	// mnFiltered contains mn with labels removed by LabelsFilter.
+	mnFiltered storage.MetricName
 }
 
 func (xb *exportBlock) reset() {
 	xb.mn = nil
+	xb.mnFiltered.Reset()
 	xb.timestamps = xb.timestamps[:0]
 	xb.values = xb.values[:0]
 }
@@ -445,6 +509,11 @@ func DeleteHandler(startTime time.Time, r *http.Request) error {
 	}
 	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, 0)
 	deletedCount, err := netstorage.DeleteSeries(nil, sq, cp.deadline)
+	ae := auditlog.NewEvent(r, "delete_series")
+	ae.Matches = append([]string{}, r.Form["match[]"]...)
+	ae.Matches = append(ae.Matches, r.Form["match"]...)
+	ae.SetSeries(deletedCount)
+	auditlog.Log(ae, err)
 	if err != nil {
 		return fmt.Errorf("cannot delete time series: %w", err)
 	}
@@ -470,7 +539,11 @@ func LabelValuesHandler(qt *querytracer.Tracer, startTime time.Time, labelName s
 	if err != nil {
 		return err
 	}
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxUniqueTimeseries)
+	maxSeries, err := searchutils.GetMaxSeries(r, getMaxLabelsAPISeries(), "-search.maxLabelsAPISeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	labelValues, err := netstorage.LabelValues(qt, labelName, sq, limit, cp.deadline)
 	if err != nil {
 		return fmt.Errorf("cannot obtain values for label %q: %w", labelName, err)
@@ -486,6 +559,13 @@ func LabelValuesHandler(qt *querytracer.Tracer, startTime time.Time, labelName s
 	return nil
 }
 
+func getMaxLabelsAPISeries() int {
+	if *maxLabelsAPISeries > 0 {
+		return *maxLabelsAPISeries
+	}
+	return *maxUniqueTimeseries
+}
+
 var labelValuesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/label/{}/values"}`)
 
 const secsPerDay = 3600 * 24
@@ -533,10 +613,23 @@ func TSDBStatusHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 		}
 		topN = n
 	}
-	start := int64(date*secsPerDay) * 1000
-	end := int64((date+1)*secsPerDay)*1000 - 1
-	sq := storage.NewSearchQuery(start, end, cp.filterss, *maxTSDBStatusSeries)
-	status, err := netstorage.TSDBStatus(qt, sq, focusLabel, topN, cp.deadline)
+	var status *storage.TSDBStatus
+	if searchutils.GetBool(r, "sketch") {
+		// Obtain approximate stats from cardinality sketches maintained at ingestion time.
+		if len(cp.filterss) > 0 || focusLabel != "" {
+			return fmt.Errorf("`match[]`, `extra_label`, `extra_filters[]` and `focusLabel` args cannot be used together with `sketch=1`")
+		}
+		tenant := r.FormValue("tenant")
+		if tenant == "" {
+			tenant = searchutils.GetTenant(r)
+		}
+		status, err = netstorage.TSDBStatusFromSketches(qt, date, tenant, topN)
+	} else {
+		start := int64(date*secsPerDay) * 1000
+		end := int64((date+1)*secsPerDay)*1000 - 1
+		sq := storage.NewSearchQuery(start, end, cp.filterss, *maxTSDBStatusSeries)
+		status, err = netstorage.TSDBStatus(qt, sq, focusLabel, topN, cp.deadline)
+	}
 	if err != nil {
 		return fmt.Errorf("cannot obtain tsdb stats: %w", err)
 	}
@@ -553,6 +646,114 @@ func TSDBStatusHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 
 var tsdbStatusDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/tsdb"}`)
 
+// MetricNamesStatsHandler processes /api/v1/status/metric_names_stats request.
+//
+// It returns series count, samples count and the approximate on-disk size per each metric name
+// on the given [start ... end] time range. It can accept `match[]` filters in order to narrow down the search.
+func MetricNamesStatsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer metricNamesStatsDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParamsWithDefaultDuration(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	cp.deadline = searchutils.GetDeadlineForStatusRequest(r, startTime)
+	if len(cp.filterss) == 0 {
+		// Select all the time series if match[] arg is missing.
+		cp.filterss = [][]storage.TagFilter{{{
+			Value:    []byte(".+"),
+			IsRegexp: true,
+		}}}
+	}
+	limit := 0
+	limitStr := r.FormValue("limit")
+	if len(limitStr) > 0 {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `limit` arg %q: %w", limitStr, err)
+		}
+		if n < 0 {
+			return fmt.Errorf("`limit` arg cannot be negative; got %d", n)
+		}
+		limit = n
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxTSDBStatusSeries)
+	stats, err := netstorage.MetricNamesStats(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot obtain metric names stats: %w", err)
+	}
+	if limit > 0 && limit < len(stats) {
+		stats = stats[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteMetricNamesStatsResponse(bw, stats, qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send metric names stats response to remote client: %w", err)
+	}
+	return nil
+}
+
+var metricNamesStatsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/metric_names_stats"}`)
+
+// maxSelectorStatsDays is the maximum number of days, which can be requested at /api/v1/selector_stats
+const maxSelectorStatsDays = 31
+
+// SelectorStatsHandler processes /api/v1/selector_stats request.
+//
+// It returns the number of series matching the given `match[]` selector, per-label breakdown for these series
+// and the number of matching series per each day on the given [start ... end] time range.
+// The stats are obtained from the index only, so they can be used for estimating the cost of heavy queries before running them.
+func SelectorStatsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer selectorStatsDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParams(r, startTime, true)
+	if err != nil {
+		return err
+	}
+	cp.deadline = searchutils.GetDeadlineForStatusRequest(r, startTime)
+	if cp.start == 0 {
+		cp.start = cp.end - secsPerDay*1000
+	}
+	if days := cp.end/(secsPerDay*1000) - cp.start/(secsPerDay*1000) + 1; days > maxSelectorStatsDays {
+		return fmt.Errorf("the [start ... end] time range cannot exceed %d days; got %d days", maxSelectorStatsDays, days)
+	}
+	focusLabel := r.FormValue("focusLabel")
+	topN := 10
+	topNStr := r.FormValue("topN")
+	if len(topNStr) > 0 {
+		n, err := strconv.Atoi(topNStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `topN` arg %q: %w", topNStr, err)
+		}
+		if n <= 0 {
+			n = 1
+		}
+		if n > 1000 {
+			n = 1000
+		}
+		topN = n
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxTSDBStatusSeries)
+	ss, err := netstorage.SelectorStats(qt, sq, focusLabel, topN, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot obtain selector stats: %w", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	WriteSelectorStatsResponse(bw, ss, qt)
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send selector stats response to remote client: %w", err)
+	}
+	return nil
+}
+
+var selectorStatsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/selector_stats"}`)
+
 // LabelsHandler processes /api/v1/labels request.
 //
 // See https://prometheus.io/docs/prometheus/latest/querying/api/#getting-label-names
@@ -567,7 +768,11 @@ func LabelsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 	if err != nil {
 		return err
 	}
-	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, *maxUniqueTimeseries)
+	maxSeries, err := searchutils.GetMaxSeries(r, getMaxLabelsAPISeries(), "-search.maxLabelsAPISeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
 	labels, err := netstorage.LabelNames(qt, sq, limit, cp.deadline)
 	if err != nil {
 		return fmt.Errorf("cannot obtain labels: %w", err)
@@ -626,8 +831,12 @@ func SeriesHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 		return err
 	}
 
-	minLimit := *maxSeriesLimit
-	if limit > 0 && limit < *maxSeriesLimit {
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxSeriesLimit, "-search.maxSeries")
+	if err != nil {
+		return err
+	}
+	minLimit := maxSeries
+	if limit > 0 && limit < maxSeries {
 		minLimit = limit
 	}
 	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, minLimit)
@@ -653,6 +862,195 @@ func SeriesHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseW
 
 var seriesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series"}`)
 
+// SeriesIterateHandler processes /api/v1/series/iterate request.
+//
+// It returns up to `limit` series matching the optional `match[]` args in the order they are stored in the index.
+// The returned `nextCursor` must be passed in the `cursor` arg to the next request in order to continue the iteration.
+// The iteration is finished when an empty `nextCursor` is returned.
+func SeriesIterateHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer seriesIterateDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParams(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	limit, err := searchutils.GetInt(r, "limit")
+	if err != nil {
+		return err
+	}
+	if limit <= 0 {
+		limit = defaultSeriesIterateLimit
+	}
+	if limit > *maxSeriesLimit {
+		return fmt.Errorf("`limit` arg cannot exceed -search.maxSeries=%d; got %d", *maxSeriesLimit, limit)
+	}
+	afterMetricID, err := parseSeriesIterateCursor(r.FormValue("cursor"))
+	if err != nil {
+		return err
+	}
+	metricNames, nextMetricID, err := netstorage.IterateMetricNames(qt, cp.filterss, afterMetricID, limit, cp.deadline)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("cursor=%q, limit=%d", r.FormValue("cursor"), limit)
+	}
+	WriteSeriesIterateResponse(bw, metricNames, marshalSeriesIterateCursor(nextMetricID), qt, qtDone)
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return nil
+}
+
+const defaultSeriesIterateLimit = 1000
+
+// marshalSeriesIterateCursor returns an opaque cursor for continuing the iteration after the given metricID.
+//
+// An empty cursor is returned if metricID is 0, e.g. when the iteration is finished.
+func marshalSeriesIterateCursor(metricID uint64) string {
+	if metricID == 0 {
+		return ""
+	}
+	return strconv.FormatUint(metricID, 16)
+}
+
+func parseSeriesIterateCursor(cursor string) (uint64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	metricID, err := strconv.ParseUint(cursor, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse `cursor` arg %q; it must be obtained from `nextCursor` field of the previous response", cursor)
+	}
+	return metricID, nil
+}
+
+var seriesIterateDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series/iterate"}`)
+
+// SeriesFingerprintsHandler processes /api/v1/series/fingerprints request.
+//
+// It returns sorted fingerprints for series matching `match[]` args on the given time range.
+// Fingerprints can be compared between requests for detecting series churn without downloading label sets.
+func SeriesFingerprintsHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer seriesFingerprintsDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParamsWithDefaultDuration(r, startTime, true)
+	if err != nil {
+		return err
+	}
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxSeriesLimit, "-search.maxSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
+	metricNames, err := netstorage.SearchMetricNames(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch time series for %q: %w", sq, err)
+	}
+	fingerprints := make([]string, len(metricNames))
+	for i, metricName := range metricNames {
+		fingerprints[i] = getSeriesFingerprint(metricName)
+	}
+	sort.Strings(fingerprints)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("start=%d, end=%d", cp.start, cp.end)
+	}
+	WriteSeriesFingerprintsResponse(bw, fingerprints, qt, qtDone)
+	return bw.Flush()
+}
+
+var seriesFingerprintsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series/fingerprints"}`)
+
+// SeriesDiffHandler processes /api/v1/series/diff request.
+//
+// It returns fingerprints for series matching `match[]` args, which appeared on the [start ... end] time range
+// and which disappeared from it comparing to the [base_start ... base_end] time range.
+// By default the base time range is the previous time range with the same duration.
+func SeriesDiffHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer seriesDiffDuration.UpdateDuration(startTime)
+
+	cp, err := getCommonParamsWithDefaultDuration(r, startTime, true)
+	if err != nil {
+		return err
+	}
+	baseEnd, err := searchutils.GetTime(r, "base_end", cp.start)
+	if err != nil {
+		return err
+	}
+	baseStart, err := searchutils.GetTime(r, "base_start", baseEnd-(cp.end-cp.start))
+	if err != nil {
+		return err
+	}
+	if baseEnd < baseStart {
+		return fmt.Errorf("`base_end`=%d cannot be smaller than `base_start`=%d", baseEnd, baseStart)
+	}
+	withLabels := searchutils.GetBool(r, "with_labels")
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxSeriesLimit, "-search.maxSeries")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
+	metricNames, err := netstorage.SearchMetricNames(qt, sq, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch time series for %q: %w", sq, err)
+	}
+	sqBase := storage.NewSearchQuery(baseStart, baseEnd, cp.filterss, maxSeries)
+	baseMetricNames, err := netstorage.SearchMetricNames(qt, sqBase, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot fetch time series for %q: %w", sqBase, err)
+	}
+	appeared, disappeared := diffMetricNames(metricNames, baseMetricNames)
+
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	qtDone := func() {
+		qt.Donef("start=%d, end=%d, base_start=%d, base_end=%d", cp.start, cp.end, baseStart, baseEnd)
+	}
+	WriteSeriesDiffResponse(bw, appeared, disappeared, withLabels, qt, qtDone)
+	return bw.Flush()
+}
+
+var seriesDiffDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/series/diff"}`)
+
+// getSeriesFingerprint returns fingerprint for the given metricName obtained from netstorage.SearchMetricNames.
+//
+// The fingerprint doesn't depend on the order of labels in the ingested series, since metricName contains sorted labels.
+func getSeriesFingerprint(metricName string) string {
+	return fmt.Sprintf("%016x", xxhash.Sum64String(metricName))
+}
+
+// diffMetricNames returns metricNames missing in baseMetricNames and baseMetricNames missing in metricNames.
+//
+// Both metricNames and baseMetricNames must be sorted.
+func diffMetricNames(metricNames, baseMetricNames []string) ([]string, []string) {
+	var appeared, disappeared []string
+	i, j := 0, 0
+	for i < len(metricNames) && j < len(baseMetricNames) {
+		switch {
+		case metricNames[i] < baseMetricNames[j]:
+			appeared = append(appeared, metricNames[i])
+			i++
+		case metricNames[i] > baseMetricNames[j]:
+			disappeared = append(disappeared, baseMetricNames[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	appeared = append(appeared, metricNames[i:]...)
+	disappeared = append(disappeared, baseMetricNames[j:]...)
+	return appeared, disappeared
+}
+
 // QueryHandler processes /api/v1/query request.
 //
 // See https://prometheus.io/docs/prometheus/latest/querying/api/#instant-queries
@@ -670,10 +1068,18 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	if err != nil {
 		return err
 	}
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxUniqueTimeseries, "-search.maxUniqueTimeseries")
+	if err != nil {
+		return err
+	}
 	lookbackDelta, err := getMaxLookback(r)
 	if err != nil {
 		return err
 	}
+	maxWindow, err := getMaxLookbehindWindow(r)
+	if err != nil {
+		return err
+	}
 	step, err := searchutils.GetDuration(r, "step", lookbackDelta)
 	if err != nil {
 		return err
@@ -689,8 +1095,12 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	if err != nil {
 		return err
 	}
+	lf, err := searchutils.GetLabelsFilter(r)
+	if err != nil {
+		return err
+	}
 	if childQuery, windowExpr, offsetExpr := promql.IsMetricSelectorWithRollup(query); childQuery != "" {
-		window := windowExpr.Duration(step)
+		window := promql.CapLookbehindWindow(qt, windowExpr.Duration(step), step, maxWindow)
 		offset := offsetExpr.Duration(step)
 		start -= offset
 		end := start
@@ -713,7 +1123,11 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 			end:      end,
 			filterss: filterss,
 		}
-		if err := exportHandler(qt, w, cp, "promapi", 0, false); err != nil {
+		exportMaxSeries, err := searchutils.GetMaxSeries(r, *maxExportSeries, "-search.maxExportSeries")
+		if err != nil {
+			return err
+		}
+		if err := exportHandler(qt, w, cp, "promapi", 0, false, exportMaxSeries, lf); err != nil {
 			return fmt.Errorf("error when exporting data for query=%q on the time range (start=%d, end=%d): %w", childQuery, start, end, err)
 		}
 		return nil
@@ -723,7 +1137,7 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 		if newStep > 0 {
 			step = newStep
 		}
-		window := windowExpr.Duration(step)
+		window := promql.CapLookbehindWindow(qt, windowExpr.Duration(step), step, maxWindow)
 		offset := offsetExpr.Duration(step)
 		start -= offset
 		end := start
@@ -752,13 +1166,16 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 		End:                 start,
 		Step:                step,
 		MaxPointsPerSeries:  *maxPointsPerTimeseries,
-		MaxSeries:           *maxUniqueTimeseries,
+		MaxSeries:           maxSeries,
 		QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
 		Deadline:            deadline,
 		MayCache:            mayCache,
 		LookbackDelta:       lookbackDelta,
+		MaxLookbehindWindow: maxWindow,
 		RoundDigits:         getRoundDigits(r),
 		EnforcedTagFilterss: etfs,
+		Tenant:              searchutils.GetTenant(r),
+		PromQLCompat:        getPromQLCompat(r),
 	}
 	result, err := promql.Exec(qt, &ec, query, true)
 	if err != nil {
@@ -776,6 +1193,10 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 			r.Timestamps = timestamps
 		}
 	}
+	result, err = applyLabelsFilter(result, lf)
+	if err != nil {
+		return fmt.Errorf("error when executing query=%q for (time=%d, step=%d): %w", query, start, step, err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	bw := bufferedwriter.Get(w)
@@ -783,7 +1204,8 @@ func QueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWr
 	qtDone := func() {
 		qt.Donef("query=%s, time=%d: series=%d", query, start, len(result))
 	}
-	WriteQueryResponse(bw, result, qt, qtDone)
+	warnings := appendQuarantineWarning(nil)
+	WriteQueryResponse(bw, result, warnings, qt, qtDone)
 	if err := bw.Flush(); err != nil {
 		return fmt.Errorf("cannot flush query response to remote client: %w", err)
 	}
@@ -833,6 +1255,22 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	if err != nil {
 		return err
 	}
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxUniqueTimeseries, "-search.maxUniqueTimeseries")
+	if err != nil {
+		return err
+	}
+	extraLookbehind, err := searchutils.GetDuration(r, "extra_lookbehind", 0)
+	if err != nil {
+		return err
+	}
+	maxWindow, err := getMaxLookbehindWindow(r)
+	if err != nil {
+		return err
+	}
+	lf, err := searchutils.GetLabelsFilter(r)
+	if err != nil {
+		return err
+	}
 
 	// Validate input args.
 	if len(query) > maxQueryLen.IntN() {
@@ -841,8 +1279,24 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	if start > end {
 		end = start + defaultStep
 	}
-	if err := promql.ValidateMaxPointsPerSeries(start, end, step, *maxPointsPerTimeseries); err != nil {
-		return fmt.Errorf("%w; (see -search.maxPointsPerTimeseries command-line flag)", err)
+	if d := maxExtraLookbehind.Milliseconds(); extraLookbehind > d {
+		return fmt.Errorf("too big extra_lookbehind=%dms; mustn't exceed `-search.maxExtraLookbehind=%s`", extraLookbehind, maxExtraLookbehind)
+	}
+	maxPoints, mustAdjustStep, err := getMaxPointsPerSeries(r)
+	if err != nil {
+		return err
+	}
+	var warnings []string
+	if mustAdjustStep {
+		if newStep := promql.AdjustStepForMaxPoints(start, end, step, maxPoints); newStep != step {
+			warnings = append(warnings, fmt.Sprintf("step=%dms has been increased to %dms in order to return up to %d points per series", step, newStep, maxPoints))
+			qt.Printf("increase step from %dms to %dms in order to return up to %d points per series", step, newStep, maxPoints)
+			stepAdjustments.Inc()
+			step = newStep
+		}
+	}
+	if err := promql.ValidateMaxPointsPerSeries(start, end, step, maxPoints); err != nil {
+		return fmt.Errorf("%w; (see -search.maxPointsPerTimeseries command-line flag and max_points_per_series query arg)", err)
 	}
 	if mayCache {
 		start, end = promql.AdjustStartEnd(start, end, step)
@@ -853,15 +1307,19 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 		End:                 end,
 		Step:                step,
 		MaxPointsPerSeries:  *maxPointsPerTimeseries,
-		MaxSeries:           *maxUniqueTimeseries,
+		MaxSeries:           maxSeries,
 		QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
 		Deadline:            deadline,
 		MayCache:            mayCache,
 		LookbackDelta:       lookbackDelta,
+		ExtraLookbehind:     extraLookbehind,
+		MaxLookbehindWindow: maxWindow,
 		RoundDigits:         getRoundDigits(r),
 		EnforcedTagFilterss: etfs,
+		Tenant:              searchutils.GetTenant(r),
+		PromQLCompat:        getPromQLCompat(r),
 	}
-	result, err := promql.Exec(qt, &ec, query, false)
+	result, err := promql.ExecRange(qt, &ec, query)
 	if err != nil {
 		return err
 	}
@@ -879,19 +1337,49 @@ func queryRangeHandler(qt *querytracer.Tracer, startTime time.Time, w http.Respo
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/153
 	result = removeEmptyValuesAndTimeseries(result)
 
+	result, err = applyLabelsFilter(result, lf)
+	if err != nil {
+		return err
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	bw := bufferedwriter.Get(w)
 	defer bufferedwriter.Put(bw)
 	qtDone := func() {
 		qt.Donef("start=%d, end=%d, step=%d, query=%q: series=%d", start, end, step, query, len(result))
 	}
-	WriteQueryRangeResponse(bw, result, qt, qtDone)
+	warnings = appendQuarantineWarning(warnings)
+	WriteQueryRangeResponse(bw, result, warnings, qt, qtDone)
 	if err := bw.Flush(); err != nil {
 		return fmt.Errorf("cannot send query range response to remote client: %w", err)
 	}
 	return nil
 }
 
+var stepAdjustments = metrics.NewCounter(`vm_query_range_step_adjustments_total`)
+
+// getMaxPointsPerSeries returns the maximum number of points per series for /api/v1/query_range request r.
+//
+// It also returns whether the step must be increased for queries exceeding the returned limit instead of returning an error.
+func getMaxPointsPerSeries(r *http.Request) (int, bool, error) {
+	maxPoints := *maxPointsPerTimeseries
+	n, err := searchutils.GetInt(r, "max_points_per_series")
+	if err != nil {
+		return 0, false, err
+	}
+	if n < 0 {
+		return 0, false, fmt.Errorf("`max_points_per_series` arg cannot be negative; got %d", n)
+	}
+	if n == 0 {
+		return maxPoints, *adjustStepForMaxPoints, nil
+	}
+	// The client explicitly asks for the limited number of points, so the step is always adjusted.
+	if n < maxPoints {
+		maxPoints = n
+	}
+	return maxPoints, true, nil
+}
+
 func removeEmptyValuesAndTimeseries(tss []netstorage.Result) []netstorage.Result {
 	dst := tss[:0]
 	for i := range tss {
@@ -939,6 +1427,28 @@ var nan = math.NaN()
 
 // adjustLastPoints substitutes the last point values on the time range (start..end]
 // with the previous point values, since these points may contain incomplete values.
+// applyLabelsFilter removes labels from tss according to lf.
+//
+// An error is returned if multiple series have identical labels after that,
+// since such series cannot be distinguished by the client.
+func applyLabelsFilter(tss []netstorage.Result, lf *searchutils.LabelsFilter) ([]netstorage.Result, error) {
+	if lf == nil {
+		return tss, nil
+	}
+	m := make(map[string]struct{}, len(tss))
+	for i := range tss {
+		mn := &tss[i].MetricName
+		lf.Apply(mn)
+		k := mn.String()
+		if _, ok := m[k]; ok {
+			return nil, fmt.Errorf("duplicate output timeseries after applying drop_labels or keep_labels query args: %s; "+
+				"consider aggregating the series with `sum without (...)` in the query", k)
+		}
+		m[k] = struct{}{}
+	}
+	return tss, nil
+}
+
 func adjustLastPoints(tss []netstorage.Result, start, end int64) []netstorage.Result {
 	for i := range tss {
 		ts := &tss[i]
@@ -987,6 +1497,80 @@ func getMaxLookback(r *http.Request) (int64, error) {
 	return d, nil
 }
 
+// InitMaxLookbehindWindows initializes per-tenant lookbehind window limits from -search.maxLookbehindWindowPerTenant.
+//
+// It must be called after flag.Parse().
+func InitMaxLookbehindWindows() {
+	m, err := parseMaxLookbehindWindows(*maxLookbehindWindowPerTenant)
+	if err != nil {
+		logger.Fatalf("cannot parse -search.maxLookbehindWindowPerTenant: %s", err)
+	}
+	maxLookbehindWindows = m
+}
+
+var maxLookbehindWindows map[string]int64
+
+func parseMaxLookbehindWindows(a []string) (map[string]int64, error) {
+	m := make(map[string]int64, len(a))
+	for _, s := range a {
+		if s == "" {
+			continue
+		}
+		n := strings.LastIndexByte(s, ':')
+		if n < 0 {
+			return nil, fmt.Errorf("missing ':' in %q; expecting tenant:duration", s)
+		}
+		d, err := promutils.ParseDuration(s[n+1:])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse duration in %q: %w", s, err)
+		}
+		if d < 0 {
+			return nil, fmt.Errorf("duration cannot be negative; got %s in %q", d, s)
+		}
+		m[s[:n]] = d.Milliseconds()
+	}
+	return m, nil
+}
+
+// getMaxLookbehindWindow returns the maximum lookbehind window in milliseconds for the given request r.
+//
+// The limit is obtained from -search.maxLookbehindWindowPerTenant for the tenant of r and falls back to -search.maxLookbehindWindow.
+// The max_lookbehind_window query arg may only lower the limit. Zero means 'no limit'.
+func getMaxLookbehindWindow(r *http.Request) (int64, error) {
+	d := maxLookbehindWindow.Milliseconds()
+	if td, ok := maxLookbehindWindows[searchutils.GetTenant(r)]; ok {
+		d = td
+	}
+	n, err := searchutils.GetDuration(r, "max_lookbehind_window", 0)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("`max_lookbehind_window` arg cannot be negative; got %dms", n)
+	}
+	if n > 0 && (d == 0 || n < d) {
+		d = n
+	}
+	return d, nil
+}
+
+// getPromQLCompat returns whether the query for the given request r must be executed in PromQL compatibility mode.
+//
+// The promql_compat query arg has priority over -search.promqlCompatTenants and -search.promqlCompat.
+func getPromQLCompat(r *http.Request) bool {
+	if r.FormValue("promql_compat") != "" {
+		return searchutils.GetBool(r, "promql_compat")
+	}
+	if tenant := searchutils.GetTenant(r); tenant != "" {
+		for _, t := range *promqlCompatTenants {
+			if t == tenant {
+				return true
+			}
+		}
+	}
+	return *promqlCompat
+}
+
 func getTagFilterssFromMatches(matches []string) ([][]storage.TagFilter, error) {
 	tagFilterss := make([][]storage.TagFilter, 0, len(matches))
 	for _, match := range matches {
@@ -1049,6 +1633,40 @@ func QueryStatsHandler(startTime time.Time, w http.ResponseWriter, r *http.Reque
 
 var queryStatsDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/top_queries"}`)
 
+// TopQueryShapesHandler returns query shapes with the highest CPU usage according to the query profiler.
+//
+// See https://docs.victoriametrics.com/#query-profiler
+func TopQueryShapesHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer topQueryShapesDuration.UpdateDuration(startTime)
+
+	topN := 20
+	topNStr := r.FormValue("topN")
+	if len(topNStr) > 0 {
+		n, err := strconv.Atoi(topNStr)
+		if err != nil {
+			return fmt.Errorf("cannot parse `topN` arg %q: %w", topNStr, err)
+		}
+		topN = n
+	}
+	maxAgeMsecs, err := searchutils.GetDuration(r, "maxAge", 3600*1000)
+	if err != nil {
+		return fmt.Errorf("cannot parse `maxAge` arg: %w", err)
+	}
+	maxAge := time.Duration(maxAgeMsecs) * time.Millisecond
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufferedwriter.Get(w)
+	defer bufferedwriter.Put(bw)
+	if err := queryprofiler.WriteJSONTopQueryShapes(bw, topN, maxAge); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("cannot send top query shapes response to client: %w", err)
+	}
+	return nil
+}
+
+var topQueryShapesDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/status/top_query_shapes"}`)
+
 // commonParams contains common parameters for all /api/v1/* handlers
 //
 // timeout, start, end, match[], extra_label, extra_filters[]
@@ -1183,3 +1801,15 @@ func (sw *scalableWriter) flush() error {
 	})
 	return sw.bw.Flush()
 }
+
+// appendQuarantineWarning appends a warning about data parts quarantined because of read errors to warnings if such parts exist.
+//
+// See https://docs.victoriametrics.com/#storage-read-errors
+func appendQuarantineWarning(warnings []string) []string {
+	n := storage.GetQuarantinedPartsCount()
+	if n == 0 {
+		return warnings
+	}
+	return append(warnings, fmt.Sprintf("%d data parts are quarantined because of read errors, so the response may miss some data; "+
+		"see https://docs.victoriametrics.com/#storage-read-errors", n))
+}
@@ -1,12 +1,17 @@
 package prometheus
 
 import (
+	"flag"
 	"math"
 	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 )
 
 func TestRemoveEmptyValuesAndTimeseries(t *testing.T) {
@@ -48,6 +53,49 @@ func TestRemoveEmptyValuesAndTimeseries(t *testing.T) {
 	})
 }
 
+func TestFederate(t *testing.T) {
+	f := func(rs *netstorage.Result, expectedResult string) {
+		t.Helper()
+		result := Federate(rs)
+		if result != expectedResult {
+			t.Fatalf("unexpected result; got\n%s\nwant\n%s", result, expectedResult)
+		}
+	}
+
+	f(&netstorage.Result{}, "")
+
+	f(&netstorage.Result{
+		MetricName: storage.MetricName{
+			MetricGroup: []byte("foo"),
+			Tags: []storage.Tag{
+				{Key: []byte("job"), Value: []byte("bar")},
+			},
+		},
+		Timestamps: []int64{100, 200},
+		Values:     []float64{1, 2.5},
+	}, `foo{job="bar"} 2.5 200`+"\n")
+
+	// Staleness marker
+	f(&netstorage.Result{
+		MetricName: storage.MetricName{
+			MetricGroup: []byte("foo"),
+		},
+		Timestamps: []int64{100, 200},
+		Values:     []float64{1, decimal.StaleNaN},
+	}, "")
+
+	// Series without metric name
+	f(&netstorage.Result{
+		MetricName: storage.MetricName{
+			Tags: []storage.Tag{
+				{Key: []byte("job"), Value: []byte("bar")},
+			},
+		},
+		Timestamps: []int64{100},
+		Values:     []float64{1},
+	}, "")
+}
+
 func TestAdjustLastPoints(t *testing.T) {
 	f := func(tss []netstorage.Result, start, end int64, tssExpected []netstorage.Result) {
 		t.Helper()
@@ -229,3 +277,267 @@ func TestGetLatencyOffsetMillisecondsFailure(t *testing.T) {
 	}
 	f("http://localhost?latency_offset=foobar")
 }
+
+func TestGetMaxPointsPerSeries(t *testing.T) {
+	f := func(url string, maxPointsExpected int, mustAdjustStepExpected bool) {
+		t.Helper()
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		maxPoints, mustAdjustStep, err := getMaxPointsPerSeries(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if maxPoints != maxPointsExpected || mustAdjustStep != mustAdjustStepExpected {
+			t.Fatalf("unexpected result; got (%d, %v); want (%d, %v)", maxPoints, mustAdjustStep, maxPointsExpected, mustAdjustStepExpected)
+		}
+	}
+	f("http://localhost", *maxPointsPerTimeseries, false)
+	f("http://localhost?max_points_per_series=1000", 1000, true)
+	// max_points_per_series cannot exceed -search.maxPointsPerTimeseries
+	f("http://localhost?max_points_per_series=1000000000", *maxPointsPerTimeseries, true)
+
+	*adjustStepForMaxPoints = true
+	defer func() {
+		*adjustStepForMaxPoints = false
+	}()
+	f("http://localhost", *maxPointsPerTimeseries, true)
+
+	for _, url := range []string{"http://localhost?max_points_per_series=foo", "http://localhost?max_points_per_series=-1"} {
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		if _, _, err := getMaxPointsPerSeries(r); err == nil {
+			t.Fatalf("expecting non-nil error for %q", url)
+		}
+	}
+}
+
+func TestApplyLabelsFilter(t *testing.T) {
+	newResults := func() []netstorage.Result {
+		var tss []netstorage.Result
+		for _, pod := range []string{"api-7d9f8b6c5d-x2x9z", "api-7d9f8b6c5d-k4p2q"} {
+			var rs netstorage.Result
+			rs.MetricName.MetricGroup = []byte("up")
+			rs.MetricName.AddTag("job", "api")
+			rs.MetricName.AddTag("pod", pod)
+			tss = append(tss, rs)
+		}
+		return tss
+	}
+	getLabelsFilter := func(url string) *searchutils.LabelsFilter {
+		t.Helper()
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("cannot parse form for %q: %s", url, err)
+		}
+		lf, err := searchutils.GetLabelsFilter(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		return lf
+	}
+	f := func(url string, resultExpected []string) {
+		t.Helper()
+		tss, err := applyLabelsFilter(newResults(), getLabelsFilter(url))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var result []string
+		for i := range tss {
+			result = append(result, tss[i].MetricName.String())
+		}
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for %q\ngot\n%q\nwant\n%q", url, result, resultExpected)
+		}
+	}
+	f("http://localhost", []string{`up{job="api",pod="api-7d9f8b6c5d-x2x9z"}`, `up{job="api",pod="api-7d9f8b6c5d-k4p2q"}`})
+	f("http://localhost?drop_labels=job", []string{`up{pod="api-7d9f8b6c5d-x2x9z"}`, `up{pod="api-7d9f8b6c5d-k4p2q"}`})
+	f("http://localhost?keep_labels=pod", []string{`up{pod="api-7d9f8b6c5d-x2x9z"}`, `up{pod="api-7d9f8b6c5d-k4p2q"}`})
+
+	// Duplicate series after dropping labels
+	for _, url := range []string{"http://localhost?drop_labels=pod", "http://localhost?keep_labels=job"} {
+		if _, err := applyLabelsFilter(newResults(), getLabelsFilter(url)); err == nil {
+			t.Fatalf("expecting non-nil error for %q", url)
+		}
+	}
+}
+
+func TestDiffMetricNames(t *testing.T) {
+	f := func(metricNames, baseMetricNames, appearedExpected, disappearedExpected []string) {
+		t.Helper()
+		appeared, disappeared := diffMetricNames(metricNames, baseMetricNames)
+		if !reflect.DeepEqual(appeared, appearedExpected) {
+			t.Fatalf("unexpected appeared series; got %q; want %q", appeared, appearedExpected)
+		}
+		if !reflect.DeepEqual(disappeared, disappearedExpected) {
+			t.Fatalf("unexpected disappeared series; got %q; want %q", disappeared, disappearedExpected)
+		}
+	}
+	f(nil, nil, nil, nil)
+	f([]string{"a", "b"}, []string{"a", "b"}, nil, nil)
+	f([]string{"a", "b"}, nil, []string{"a", "b"}, nil)
+	f(nil, []string{"a", "b"}, nil, []string{"a", "b"})
+	f([]string{"a", "c", "d"}, []string{"b", "c", "e"}, []string{"a", "d"}, []string{"b", "e"})
+}
+
+func TestSeriesDiffResponse(t *testing.T) {
+	var mn storage.MetricName
+	mn.MetricGroup = []byte("up")
+	mn.AddTag("job", "api")
+	metricName := string(mn.Marshal(nil))
+	fp := getSeriesFingerprint(metricName)
+	if len(fp) != 16 {
+		t.Fatalf("unexpected fingerprint length; got %d; want 16", len(fp))
+	}
+	if fp != getSeriesFingerprint(metricName) {
+		t.Fatalf("fingerprint must be stable")
+	}
+
+	f := func(withLabels bool, resultExpected string) {
+		t.Helper()
+		qtDone := func() {}
+		result := SeriesDiffResponse([]string{metricName}, nil, withLabels, nil, qtDone)
+		if result != resultExpected {
+			t.Fatalf("unexpected response\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+	f(false, `{"status":"success","data":{"appeared":["`+fp+`"],"disappeared":[]}}`)
+	f(true, `{"status":"success","data":{"appeared":[{"fingerprint":"`+fp+`","labels":{"__name__":"up","job":"api"}}],"disappeared":[]}}`)
+}
+
+func TestGetMaxLookbehindWindow(t *testing.T) {
+	f := func(url string, maxWindowExpected int64) {
+		t.Helper()
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		maxWindow, err := getMaxLookbehindWindow(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if maxWindow != maxWindowExpected {
+			t.Fatalf("unexpected max lookbehind window for %q; got %d; want %d", url, maxWindow, maxWindowExpected)
+		}
+	}
+	f("http://localhost", 0)
+	f("http://localhost?max_lookbehind_window=1h", 3600*1000)
+
+	*maxLookbehindWindow = 24 * time.Hour
+	defer func() {
+		*maxLookbehindWindow = 0
+	}()
+	f("http://localhost", 24*3600*1000)
+	f("http://localhost?max_lookbehind_window=1h", 3600*1000)
+	// max_lookbehind_window cannot exceed -search.maxLookbehindWindow
+	f("http://localhost?max_lookbehind_window=30d", 24*3600*1000)
+
+	for _, url := range []string{"http://localhost?max_lookbehind_window=foo", "http://localhost?max_lookbehind_window=-1h"} {
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		if _, err := getMaxLookbehindWindow(r); err == nil {
+			t.Fatalf("expecting non-nil error for %q", url)
+		}
+	}
+}
+
+func TestGetPromQLCompat(t *testing.T) {
+	f := func(url, tenant string, resultExpected bool) {
+		t.Helper()
+		r, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest(%q): %s", url, err)
+		}
+		if tenant != "" {
+			r.Header.Set("X-Tenant", tenant)
+		}
+		result := getPromQLCompat(r)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q, tenant=%q; got %v; want %v", url, tenant, result, resultExpected)
+		}
+	}
+	f("http://localhost", "", false)
+	f("http://localhost?promql_compat=1", "", true)
+	f("http://localhost?promql_compat=0", "", false)
+
+	if err := flag.Set("search.tenantHeader", "X-Tenant"); err != nil {
+		t.Fatalf("cannot set -search.tenantHeader: %s", err)
+	}
+	*promqlCompatTenants = []string{"migration"}
+	defer func() {
+		_ = flag.Set("search.tenantHeader", "")
+		*promqlCompatTenants = nil
+	}()
+	f("http://localhost", "migration", true)
+	f("http://localhost", "other", false)
+	f("http://localhost?promql_compat=0", "migration", false)
+
+	*promqlCompat = true
+	defer func() {
+		*promqlCompat = false
+	}()
+	f("http://localhost", "other", true)
+	f("http://localhost?promql_compat=false", "", false)
+}
+
+func TestParseMaxLookbehindWindows(t *testing.T) {
+	f := func(a []string, mExpected map[string]int64) {
+		t.Helper()
+		m, err := parseMaxLookbehindWindows(a)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(m, mExpected) {
+			t.Fatalf("unexpected result; got %v; want %v", m, mExpected)
+		}
+	}
+	f(nil, map[string]int64{})
+	f([]string{"foo:1h", "a:b:30d", ":5m"}, map[string]int64{
+		"foo": 3600 * 1000,
+		"a:b": 30 * 24 * 3600 * 1000,
+		"":    5 * 60 * 1000,
+	})
+
+	fError := func(a []string) {
+		t.Helper()
+		if _, err := parseMaxLookbehindWindows(a); err == nil {
+			t.Fatalf("expecting non-nil error for %q", a)
+		}
+	}
+	fError([]string{"foo"})
+	fError([]string{"foo:bar"})
+	fError([]string{"foo:-1h"})
+}
+
+func TestSeriesIterateCursor(t *testing.T) {
+	f := func(metricID uint64) {
+		t.Helper()
+		cursor := marshalSeriesIterateCursor(metricID)
+		result, err := parseSeriesIterateCursor(cursor)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing cursor %q: %s", cursor, err)
+		}
+		if result != metricID {
+			t.Fatalf("unexpected metricID for cursor %q; got %d; want %d", cursor, result, metricID)
+		}
+	}
+	f(0)
+	f(1)
+	f(1234567890)
+	f(1<<64 - 1)
+
+	if cursor := marshalSeriesIterateCursor(0); cursor != "" {
+		t.Fatalf("expecting empty cursor for finished iteration; got %q", cursor)
+	}
+	if _, err := parseSeriesIterateCursor("foobar"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid cursor")
+	}
+}
@@ -1,10 +1,10 @@
 // Code generated by qtc from "query_range_response.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-//line app/vmselect/prometheus/query_range_response.qtpl:1
+//line query_range_response.qtpl:1
 package prometheus
 
-//line app/vmselect/prometheus/query_range_response.qtpl:1
+//line query_range_response.qtpl:1
 import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
@@ -12,128 +12,148 @@ import (
 
 // QueryRangeResponse generates response for /api/v1/query_range.See https://prometheus.io/docs/prometheus/latest/querying/api/#range-queries
 
-//line app/vmselect/prometheus/query_range_response.qtpl:9
+//line query_range_response.qtpl:9
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line app/vmselect/prometheus/query_range_response.qtpl:9
+//line query_range_response.qtpl:9
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line app/vmselect/prometheus/query_range_response.qtpl:9
-func StreamQueryRangeResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) {
-//line app/vmselect/prometheus/query_range_response.qtpl:9
+//line query_range_response.qtpl:9
+func StreamQueryRangeResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) {
+//line query_range_response.qtpl:9
 	qw422016.N().S(`{`)
-//line app/vmselect/prometheus/query_range_response.qtpl:12
+//line query_range_response.qtpl:12
 	seriesCount := len(rs)
 	pointsCount := 0
 
-//line app/vmselect/prometheus/query_range_response.qtpl:14
+//line query_range_response.qtpl:14
 	qw422016.N().S(`"status":"success","data":{"resultType":"matrix","result":[`)
-//line app/vmselect/prometheus/query_range_response.qtpl:19
+//line query_range_response.qtpl:19
 	if len(rs) > 0 {
-//line app/vmselect/prometheus/query_range_response.qtpl:20
+//line query_range_response.qtpl:20
 		streamqueryRangeLine(qw422016, &rs[0])
-//line app/vmselect/prometheus/query_range_response.qtpl:21
+//line query_range_response.qtpl:21
 		pointsCount += len(rs[0].Values)
 
-//line app/vmselect/prometheus/query_range_response.qtpl:22
+//line query_range_response.qtpl:22
 		rs = rs[1:]
 
-//line app/vmselect/prometheus/query_range_response.qtpl:23
+//line query_range_response.qtpl:23
 		for i := range rs {
-//line app/vmselect/prometheus/query_range_response.qtpl:23
+//line query_range_response.qtpl:23
 			qw422016.N().S(`,`)
-//line app/vmselect/prometheus/query_range_response.qtpl:24
+//line query_range_response.qtpl:24
 			streamqueryRangeLine(qw422016, &rs[i])
-//line app/vmselect/prometheus/query_range_response.qtpl:25
+//line query_range_response.qtpl:25
 			pointsCount += len(rs[i].Values)
 
-//line app/vmselect/prometheus/query_range_response.qtpl:26
+//line query_range_response.qtpl:26
 		}
-//line app/vmselect/prometheus/query_range_response.qtpl:27
+//line query_range_response.qtpl:27
 	}
-//line app/vmselect/prometheus/query_range_response.qtpl:27
+//line query_range_response.qtpl:27
 	qw422016.N().S(`]}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:31
+//line query_range_response.qtpl:30
+	if len(warnings) > 0 {
+//line query_range_response.qtpl:30
+		qw422016.N().S(`,"warnings":[`)
+//line query_range_response.qtpl:32
+		for i, w := range warnings {
+//line query_range_response.qtpl:33
+			qw422016.N().Q(w)
+//line query_range_response.qtpl:34
+			if i+1 < len(warnings) {
+//line query_range_response.qtpl:34
+				qw422016.N().S(`,`)
+//line query_range_response.qtpl:34
+			}
+//line query_range_response.qtpl:35
+		}
+//line query_range_response.qtpl:35
+		qw422016.N().S(`]`)
+//line query_range_response.qtpl:37
+	}
+//line query_range_response.qtpl:39
 	qt.Printf("generate /api/v1/query_range response for series=%d, points=%d", seriesCount, pointsCount)
 	qtDone()
 
-//line app/vmselect/prometheus/query_range_response.qtpl:34
+//line query_range_response.qtpl:42
 	streamdumpQueryTrace(qw422016, qt)
-//line app/vmselect/prometheus/query_range_response.qtpl:34
+//line query_range_response.qtpl:42
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:36
-func WriteQueryRangeResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) {
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
+func WriteQueryRangeResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) {
+//line query_range_response.qtpl:44
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
-	StreamQueryRangeResponse(qw422016, rs, qt, qtDone)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
+	StreamQueryRangeResponse(qw422016, rs, warnings, qt, qtDone)
+//line query_range_response.qtpl:44
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:36
-func QueryRangeResponse(rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) string {
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
+func QueryRangeResponse(rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) string {
+//line query_range_response.qtpl:44
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/query_range_response.qtpl:36
-	WriteQueryRangeResponse(qb422016, rs, qt, qtDone)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
+	WriteQueryRangeResponse(qb422016, rs, warnings, qt, qtDone)
+//line query_range_response.qtpl:44
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
 	return qs422016
-//line app/vmselect/prometheus/query_range_response.qtpl:36
+//line query_range_response.qtpl:44
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:38
+//line query_range_response.qtpl:46
 func streamqueryRangeLine(qw422016 *qt422016.Writer, r *netstorage.Result) {
-//line app/vmselect/prometheus/query_range_response.qtpl:38
+//line query_range_response.qtpl:46
 	qw422016.N().S(`{"metric":`)
-//line app/vmselect/prometheus/query_range_response.qtpl:40
+//line query_range_response.qtpl:48
 	streammetricNameObject(qw422016, &r.MetricName)
-//line app/vmselect/prometheus/query_range_response.qtpl:40
+//line query_range_response.qtpl:48
 	qw422016.N().S(`,"values":`)
-//line app/vmselect/prometheus/query_range_response.qtpl:41
+//line query_range_response.qtpl:49
 	streamvaluesWithTimestamps(qw422016, r.Values, r.Timestamps)
-//line app/vmselect/prometheus/query_range_response.qtpl:41
+//line query_range_response.qtpl:49
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 func writequeryRangeLine(qq422016 qtio422016.Writer, r *netstorage.Result) {
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	streamqueryRangeLine(qw422016, r)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 }
 
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 func queryRangeLine(r *netstorage.Result) string {
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	writequeryRangeLine(qb422016, r)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 	return qs422016
-//line app/vmselect/prometheus/query_range_response.qtpl:43
+//line query_range_response.qtpl:51
 }
@@ -26,7 +26,7 @@ var (
 )
 
 //line app/vmselect/prometheus/query_response.qtpl:9
-func StreamQueryResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) {
+func StreamQueryResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) {
 //line app/vmselect/prometheus/query_response.qtpl:9
 	qw422016.N().S(`{`)
 //line app/vmselect/prometheus/query_response.qtpl:11
@@ -70,39 +70,59 @@ func StreamQueryResponse(qw422016 *qt422016.Writer, rs []netstorage.Result, qt *
 	}
 //line app/vmselect/prometheus/query_response.qtpl:29
 	qw422016.N().S(`]}`)
-//line app/vmselect/prometheus/query_response.qtpl:33
+//line app/vmselect/prometheus/query_response.qtpl:32
+	if len(warnings) > 0 {
+//line app/vmselect/prometheus/query_response.qtpl:32
+		qw422016.N().S(`,"warnings":[`)
+//line app/vmselect/prometheus/query_response.qtpl:34
+		for i, w := range warnings {
+//line app/vmselect/prometheus/query_response.qtpl:35
+			qw422016.N().Q(w)
+//line app/vmselect/prometheus/query_response.qtpl:36
+			if i+1 < len(warnings) {
+//line app/vmselect/prometheus/query_response.qtpl:36
+				qw422016.N().S(`,`)
+//line app/vmselect/prometheus/query_response.qtpl:36
+			}
+//line app/vmselect/prometheus/query_response.qtpl:37
+		}
+//line app/vmselect/prometheus/query_response.qtpl:37
+		qw422016.N().S(`]`)
+//line app/vmselect/prometheus/query_response.qtpl:39
+	}
+//line app/vmselect/prometheus/query_response.qtpl:41
 	qt.Printf("generate /api/v1/query response for series=%d", seriesCount)
 	qtDone()
 
-//line app/vmselect/prometheus/query_response.qtpl:36
+//line app/vmselect/prometheus/query_response.qtpl:44
 	streamdumpQueryTrace(qw422016, qt)
-//line app/vmselect/prometheus/query_response.qtpl:36
+//line app/vmselect/prometheus/query_response.qtpl:44
 	qw422016.N().S(`}`)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
 }
 
-//line app/vmselect/prometheus/query_response.qtpl:38
-func WriteQueryResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) {
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
+func WriteQueryResponse(qq422016 qtio422016.Writer, rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/query_response.qtpl:46
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line app/vmselect/prometheus/query_response.qtpl:38
-	StreamQueryResponse(qw422016, rs, qt, qtDone)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
+	StreamQueryResponse(qw422016, rs, warnings, qt, qtDone)
+//line app/vmselect/prometheus/query_response.qtpl:46
 	qt422016.ReleaseWriter(qw422016)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
 }
 
-//line app/vmselect/prometheus/query_response.qtpl:38
-func QueryResponse(rs []netstorage.Result, qt *querytracer.Tracer, qtDone func()) string {
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
+func QueryResponse(rs []netstorage.Result, warnings []string, qt *querytracer.Tracer, qtDone func()) string {
+//line app/vmselect/prometheus/query_response.qtpl:46
 	qb422016 := qt422016.AcquireByteBuffer()
-//line app/vmselect/prometheus/query_response.qtpl:38
-	WriteQueryResponse(qb422016, rs, qt, qtDone)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
+	WriteQueryResponse(qb422016, rs, warnings, qt, qtDone)
+//line app/vmselect/prometheus/query_response.qtpl:46
 	qs422016 := string(qb422016.B)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
 	qt422016.ReleaseByteBuffer(qb422016)
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
 	return qs422016
-//line app/vmselect/prometheus/query_response.qtpl:38
+//line app/vmselect/prometheus/query_response.qtpl:46
 }
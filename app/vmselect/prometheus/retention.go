@@ -0,0 +1,91 @@
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+type retentionReportResponse struct {
+	RetentionMsecs     int64                        `json:"retention_msecs"`
+	BaseRetentionMsecs int64                        `json:"base_retention_msecs"`
+	Extended           bool                         `json:"extended"`
+	NextRetentionCycle string                       `json:"next_retention_cycle"`
+	Partitions         []partitionRetentionResponse `json:"partitions"`
+}
+
+type partitionRetentionResponse struct {
+	Name               string `json:"name"`
+	MinTime            string `json:"min_time"`
+	MaxTime            string `json:"max_time"`
+	DeleteAt           string `json:"delete_at"`
+	Held               bool   `json:"held"`
+	DeletedAtNextCycle bool   `json:"deleted_at_next_cycle"`
+}
+
+// RetentionReportHandler processes /api/v1/admin/retention request.
+//
+// See https://docs.victoriametrics.com/#retention
+func RetentionReportHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer retentionReportDuration.UpdateDuration(startTime)
+
+	rr := netstorage.GetRetentionReport(nil)
+	data := retentionReportResponse{
+		RetentionMsecs:     rr.RetentionMsecs,
+		BaseRetentionMsecs: rr.BaseRetentionMsecs,
+		Extended:           rr.RetentionMsecs > rr.BaseRetentionMsecs,
+		NextRetentionCycle: formatRetentionTime(rr.NextRetentionCycle),
+		Partitions:         make([]partitionRetentionResponse, len(rr.Partitions)),
+	}
+	for i := range rr.Partitions {
+		pr := &rr.Partitions[i]
+		data.Partitions[i] = partitionRetentionResponse{
+			Name:               pr.Name,
+			MinTime:            formatRetentionTime(pr.MinTimestamp),
+			MaxTime:            formatRetentionTime(pr.MaxTimestamp),
+			DeleteAt:           formatRetentionTime(pr.DeleteAt),
+			Held:               pr.Held,
+			DeletedAtNextCycle: pr.DeletedAtNextCycle,
+		}
+	}
+	return writeJSONSuccess(w, data)
+}
+
+var retentionReportDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/retention"}`)
+
+// ExtendRetentionHandler processes /api/v1/admin/retention/extend request.
+//
+// See https://docs.victoriametrics.com/#retention
+func ExtendRetentionHandler(startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer extendRetentionDuration.UpdateDuration(startTime)
+
+	retentionPeriod := r.FormValue("retentionPeriod")
+	if retentionPeriod == "" {
+		return fmt.Errorf("missing `retentionPeriod` arg")
+	}
+	var d flagutil.Duration
+	if err := d.Set(retentionPeriod); err != nil {
+		return fmt.Errorf("cannot parse `retentionPeriod`: %w", err)
+	}
+	err := netstorage.ExtendRetention(nil, d.Msecs)
+	ae := auditlog.NewEvent(r, "retention_extend")
+	ae.SetDetail("retentionPeriod", retentionPeriod)
+	auditlog.Log(ae, err)
+	if err != nil {
+		return fmt.Errorf("cannot extend retention: %w", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"success"}`)
+	return nil
+}
+
+var extendRetentionDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/api/v1/admin/retention/extend"}`)
+
+func formatRetentionTime(timestamp int64) string {
+	return time.Unix(0, timestamp*1e6).UTC().Format(time.RFC3339)
+}
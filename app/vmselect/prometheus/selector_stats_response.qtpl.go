@@ -0,0 +1,114 @@
+// Code generated by qtc from "selector_stats_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line selector_stats_response.qtpl:1
+package prometheus
+
+//line selector_stats_response.qtpl:1
+import (
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// SelectorStatsResponse generates response for /api/v1/selector_stats .
+
+//line selector_stats_response.qtpl:10
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line selector_stats_response.qtpl:10
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line selector_stats_response.qtpl:10
+func StreamSelectorStatsResponse(qw422016 *qt422016.Writer, ss *storage.SelectorStats, qt *querytracer.Tracer) {
+//line selector_stats_response.qtpl:10
+	qw422016.N().S(`{"status":"success","data":{"totalSeries":`)
+//line selector_stats_response.qtpl:14
+	qw422016.N().DUL(ss.TotalSeries)
+//line selector_stats_response.qtpl:14
+	qw422016.N().S(`,"seriesCountByDate":[`)
+//line selector_stats_response.qtpl:16
+	for i, e := range ss.SeriesCountByDate {
+//line selector_stats_response.qtpl:16
+		qw422016.N().S(`{"date":`)
+//line selector_stats_response.qtpl:18
+		qw422016.N().Q(time.Unix(int64(e.Date)*secsPerDay, 0).UTC().Format("2006-01-02"))
+//line selector_stats_response.qtpl:18
+		qw422016.N().S(`,"value":`)
+//line selector_stats_response.qtpl:19
+		qw422016.N().DUL(e.Count)
+//line selector_stats_response.qtpl:19
+		qw422016.N().S(`}`)
+//line selector_stats_response.qtpl:21
+		if i+1 < len(ss.SeriesCountByDate) {
+//line selector_stats_response.qtpl:21
+			qw422016.N().S(`,`)
+//line selector_stats_response.qtpl:21
+		}
+//line selector_stats_response.qtpl:22
+	}
+//line selector_stats_response.qtpl:22
+	qw422016.N().S(`],"seriesCountByMetricName":`)
+//line selector_stats_response.qtpl:24
+	streamtsdbStatusEntries(qw422016, ss.Status.SeriesCountByMetricName)
+//line selector_stats_response.qtpl:24
+	qw422016.N().S(`,"seriesCountByLabelName":`)
+//line selector_stats_response.qtpl:25
+	streamtsdbStatusEntries(qw422016, ss.Status.SeriesCountByLabelName)
+//line selector_stats_response.qtpl:25
+	qw422016.N().S(`,"seriesCountByFocusLabelValue":`)
+//line selector_stats_response.qtpl:26
+	streamtsdbStatusEntries(qw422016, ss.Status.SeriesCountByFocusLabelValue)
+//line selector_stats_response.qtpl:26
+	qw422016.N().S(`,"seriesCountByLabelValuePair":`)
+//line selector_stats_response.qtpl:27
+	streamtsdbStatusEntries(qw422016, ss.Status.SeriesCountByLabelValuePair)
+//line selector_stats_response.qtpl:27
+	qw422016.N().S(`,"labelValueCountByLabelName":`)
+//line selector_stats_response.qtpl:28
+	streamtsdbStatusEntries(qw422016, ss.Status.LabelValueCountByLabelName)
+//line selector_stats_response.qtpl:28
+	qw422016.N().S(`}`)
+//line selector_stats_response.qtpl:30
+	qt.Done()
+
+//line selector_stats_response.qtpl:31
+	streamdumpQueryTrace(qw422016, qt)
+//line selector_stats_response.qtpl:31
+	qw422016.N().S(`}`)
+//line selector_stats_response.qtpl:33
+}
+
+//line selector_stats_response.qtpl:33
+func WriteSelectorStatsResponse(qq422016 qtio422016.Writer, ss *storage.SelectorStats, qt *querytracer.Tracer) {
+//line selector_stats_response.qtpl:33
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line selector_stats_response.qtpl:33
+	StreamSelectorStatsResponse(qw422016, ss, qt)
+//line selector_stats_response.qtpl:33
+	qt422016.ReleaseWriter(qw422016)
+//line selector_stats_response.qtpl:33
+}
+
+//line selector_stats_response.qtpl:33
+func SelectorStatsResponse(ss *storage.SelectorStats, qt *querytracer.Tracer) string {
+//line selector_stats_response.qtpl:33
+	qb422016 := qt422016.AcquireByteBuffer()
+//line selector_stats_response.qtpl:33
+	WriteSelectorStatsResponse(qb422016, ss, qt)
+//line selector_stats_response.qtpl:33
+	qs422016 := string(qb422016.B)
+//line selector_stats_response.qtpl:33
+	qt422016.ReleaseByteBuffer(qb422016)
+//line selector_stats_response.qtpl:33
+	return qs422016
+//line selector_stats_response.qtpl:33
+}
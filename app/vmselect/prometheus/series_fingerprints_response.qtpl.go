@@ -0,0 +1,209 @@
+// Code generated by qtc from "series_fingerprints_response.qtpl". DO NOT EDIT.
+// See https://github.com/valyala/quicktemplate for details.
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:1
+package prometheus
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:1
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// SeriesFingerprintsResponse generates response for /api/v1/series/fingerprints.
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:8
+import (
+	qtio422016 "io"
+
+	qt422016 "github.com/valyala/quicktemplate"
+)
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:8
+var (
+	_ = qtio422016.Copy
+	_ = qt422016.AcquireByteBuffer
+)
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:8
+func StreamSeriesFingerprintsResponse(qw422016 *qt422016.Writer, fingerprints []string, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:8
+	qw422016.N().S(`{"status":"success","data":[`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:12
+	for i, fp := range fingerprints {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:13
+		qw422016.N().Q(fp)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:14
+		if i+1 < len(fingerprints) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:14
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:14
+		}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:15
+	}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:15
+	qw422016.N().S(`]`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:18
+	qt.Printf("generate response: series=%d", len(fingerprints))
+	qtDone()
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:21
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:21
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+func WriteSeriesFingerprintsResponse(qq422016 qtio422016.Writer, fingerprints []string, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	StreamSeriesFingerprintsResponse(qw422016, fingerprints, qt, qtDone)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+func SeriesFingerprintsResponse(fingerprints []string, qt *querytracer.Tracer, qtDone func()) string {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	WriteSeriesFingerprintsResponse(qb422016, fingerprints, qt, qtDone)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+	return qs422016
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:23
+}
+
+// SeriesDiffResponse generates response for /api/v1/series/diff.
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:26
+func StreamSeriesDiffResponse(qw422016 *qt422016.Writer, appeared, disappeared []string, withLabels bool, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:26
+	qw422016.N().S(`{"status":"success","data":{"appeared":`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:30
+	streamseriesDiffEntries(qw422016, appeared, withLabels)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:30
+	qw422016.N().S(`,"disappeared":`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:31
+	streamseriesDiffEntries(qw422016, disappeared, withLabels)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:31
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:34
+	qt.Printf("generate response: appeared=%d, disappeared=%d", len(appeared), len(disappeared))
+	qtDone()
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:37
+	streamdumpQueryTrace(qw422016, qt)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:37
+	qw422016.N().S(`}`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+func WriteSeriesDiffResponse(qq422016 qtio422016.Writer, appeared, disappeared []string, withLabels bool, qt *querytracer.Tracer, qtDone func()) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	StreamSeriesDiffResponse(qw422016, appeared, disappeared, withLabels, qt, qtDone)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+func SeriesDiffResponse(appeared, disappeared []string, withLabels bool, qt *querytracer.Tracer, qtDone func()) string {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	WriteSeriesDiffResponse(qb422016, appeared, disappeared, withLabels, qt, qtDone)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+	return qs422016
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:39
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:41
+func streamseriesDiffEntries(qw422016 *qt422016.Writer, metricNames []string, withLabels bool) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:41
+	qw422016.N().S(`[`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:43
+	var mn storage.MetricName
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:44
+	for i, metricName := range metricNames {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:45
+		if withLabels {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:45
+			qw422016.N().S(`{"fingerprint":`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:47
+			qw422016.N().Q(getSeriesFingerprint(metricName))
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:47
+			qw422016.N().S(`,"labels":`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:49
+			err := mn.UnmarshalString(metricName)
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:50
+			if err != nil {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:51
+				qw422016.N().Q(err.Error())
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:52
+			} else {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:53
+				streammetricNameObject(qw422016, &mn)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:54
+			}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:54
+			qw422016.N().S(`}`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:56
+		} else {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:57
+			qw422016.N().Q(getSeriesFingerprint(metricName))
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:58
+		}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:59
+		if i+1 < len(metricNames) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:59
+			qw422016.N().S(`,`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:59
+		}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:60
+	}
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:60
+	qw422016.N().S(`]`)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+func writeseriesDiffEntries(qq422016 qtio422016.Writer, metricNames []string, withLabels bool) {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	qw422016 := qt422016.AcquireWriter(qq422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	streamseriesDiffEntries(qw422016, metricNames, withLabels)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	qt422016.ReleaseWriter(qw422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+}
+
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+func seriesDiffEntries(metricNames []string, withLabels bool) string {
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	qb422016 := qt422016.AcquireByteBuffer()
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	writeseriesDiffEntries(qb422016, metricNames, withLabels)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	qs422016 := string(qb422016.B)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	qt422016.ReleaseByteBuffer(qb422016)
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+	return qs422016
+//line app/vmselect/prometheus/series_fingerprints_response.qtpl:62
+}
@@ -0,0 +1,362 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/promql"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// maxGrafanaRequestSize is the maximum size of request body for /grafana/* handlers.
+const maxGrafanaRequestSize = 1024 * 1024
+
+type grafanaTimeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type grafanaSearchRequest struct {
+	Target string            `json:"target"`
+	Range  *grafanaTimeRange `json:"range"`
+}
+
+type grafanaQueryRequest struct {
+	Range         grafanaTimeRange `json:"range"`
+	IntervalMs    int64            `json:"intervalMs"`
+	MaxDataPoints int64            `json:"maxDataPoints"`
+	Targets       []grafanaTarget  `json:"targets"`
+}
+
+type grafanaTarget struct {
+	Target string `json:"target"`
+	RefID  string `json:"refId"`
+	Type   string `json:"type"`
+	Hide   bool   `json:"hide"`
+}
+
+type grafanaTimeseries struct {
+	Target     string       `json:"target"`
+	Datapoints [][2]float64 `json:"datapoints"`
+}
+
+type grafanaTable struct {
+	Type    string               `json:"type"`
+	RefID   string               `json:"refId,omitempty"`
+	Columns []grafanaTableColumn `json:"columns"`
+	Rows    [][]interface{}      `json:"rows"`
+}
+
+type grafanaTableColumn struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
+
+func readGrafanaRequest(r *http.Request, dst interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxGrafanaRequestSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	}
+	if len(data) > maxGrafanaRequestSize {
+		return fmt.Errorf("too big request body; mustn't exceed %d bytes", maxGrafanaRequestSize)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, dst); err != nil {
+		return fmt.Errorf("cannot parse request body: %w", err)
+	}
+	return nil
+}
+
+// GrafanaSearchHandler processes /grafana/search request from Grafana SimpleJSON datasource.
+//
+// The following targets are supported:
+//
+//   - empty target or metric name prefix - returns metric names
+//   - label_values(label) - returns values for the given label
+//   - label_values(series_selector, label) - returns values for the given label on series matching the given series_selector
+//
+// See https://docs.victoriametrics.com/#grafana-simplejson-api
+func GrafanaSearchHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer grafanaSearchDuration.UpdateDuration(startTime)
+
+	var req grafanaSearchRequest
+	if err := readGrafanaRequest(r, &req); err != nil {
+		return err
+	}
+	cp, err := getCommonParamsWithDefaultDuration(r, startTime, false)
+	if err != nil {
+		return err
+	}
+	if req.Range != nil && !req.Range.From.IsZero() && !req.Range.To.IsZero() {
+		cp.start = req.Range.From.UnixNano() / 1e6
+		cp.end = req.Range.To.UnixNano() / 1e6
+	}
+	labelName, selector, prefix, err := parseGrafanaSearchTarget(req.Target)
+	if err != nil {
+		return err
+	}
+	if selector != "" {
+		tfss, err := getTagFilterssFromMatches([]string{selector})
+		if err != nil {
+			return err
+		}
+		cp.filterss = searchutils.JoinTagFilterss(tfss, cp.filterss)
+	}
+	maxSeries, err := searchutils.GetMaxSeries(r, getMaxLabelsAPISeries(), "-search.maxLabelsAPISeries")
+	if err != nil {
+		return err
+	}
+	limit, err := searchutils.GetInt(r, "limit")
+	if err != nil {
+		return err
+	}
+	sq := storage.NewSearchQuery(cp.start, cp.end, cp.filterss, maxSeries)
+	labelValues, err := netstorage.LabelValues(qt, labelName, sq, limit, cp.deadline)
+	if err != nil {
+		return fmt.Errorf("cannot obtain values for label %q: %w", labelName, err)
+	}
+	result := make([]string, 0, len(labelValues))
+	for _, v := range labelValues {
+		if strings.HasPrefix(v, prefix) {
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return writeGrafanaResponse(w, result)
+}
+
+var grafanaSearchDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/grafana/search"}`)
+
+// parseGrafanaSearchTarget parses target from /grafana/search request.
+//
+// It returns the label name to search values for, optional series selector and optional prefix for the returned values.
+func parseGrafanaSearchTarget(target string) (string, string, string, error) {
+	target = strings.TrimSpace(target)
+	if !strings.HasPrefix(target, "label_values(") {
+		return "__name__", "", target, nil
+	}
+	if !strings.HasSuffix(target, ")") {
+		return "", "", "", fmt.Errorf("missing closing parenthesis in %q", target)
+	}
+	args := target[len("label_values(") : len(target)-1]
+	selector := ""
+	labelName := args
+	if n := strings.LastIndexByte(args, ','); n >= 0 {
+		selector = strings.TrimSpace(args[:n])
+		labelName = args[n+1:]
+	}
+	labelName = strings.TrimSpace(labelName)
+	if labelName == "" {
+		return "", "", "", fmt.Errorf("missing label name in %q", target)
+	}
+	return labelName, selector, "", nil
+}
+
+// GrafanaQueryHandler processes /grafana/query request from Grafana SimpleJSON datasource.
+//
+// Targets with `table` type are evaluated as instant queries at the end of the requested time range,
+// while the rest of targets are evaluated as range queries.
+//
+// See https://docs.victoriametrics.com/#grafana-simplejson-api
+func GrafanaQueryHandler(qt *querytracer.Tracer, startTime time.Time, w http.ResponseWriter, r *http.Request) error {
+	defer grafanaQueryDuration.UpdateDuration(startTime)
+
+	var req grafanaQueryRequest
+	if err := readGrafanaRequest(r, &req); err != nil {
+		return err
+	}
+	ct := startTime.UnixNano() / 1e6
+	end := ct
+	if !req.Range.To.IsZero() {
+		end = req.Range.To.UnixNano() / 1e6
+	}
+	start := end - defaultStep
+	if !req.Range.From.IsZero() {
+		start = req.Range.From.UnixNano() / 1e6
+	}
+	if start > end {
+		return fmt.Errorf("range.from=%s cannot exceed range.to=%s", req.Range.From.Format(time.RFC3339), req.Range.To.Format(time.RFC3339))
+	}
+	step := req.IntervalMs
+	if step <= 0 && req.MaxDataPoints > 0 {
+		step = (end - start) / req.MaxDataPoints
+	}
+	if step < 1000 {
+		step = 1000
+	}
+
+	result := make([]interface{}, 0, len(req.Targets))
+	for _, t := range req.Targets {
+		if t.Hide || strings.TrimSpace(t.Target) == "" {
+			continue
+		}
+		if t.Type == "table" {
+			// Use the default step for instant queries in the same way as /api/v1/query does,
+			// since the step is used as a lookbehind window for rollup functions without explicit window.
+			rs, err := execGrafanaQuery(qt, startTime, r, t.Target, end, end, defaultStep)
+			if err != nil {
+				return err
+			}
+			result = append(result, newGrafanaTable(t.RefID, rs))
+			continue
+		}
+		rs, err := execGrafanaQuery(qt, startTime, r, t.Target, start, end, step)
+		if err != nil {
+			return err
+		}
+		for i := range rs {
+			result = append(result, newGrafanaTimeseries(&rs[i]))
+		}
+	}
+	return writeGrafanaResponse(w, result)
+}
+
+var grafanaQueryDuration = metrics.NewSummary(`vm_request_duration_seconds{path="/grafana/query"}`)
+
+func execGrafanaQuery(qt *querytracer.Tracer, startTime time.Time, r *http.Request, query string, start, end, step int64) ([]netstorage.Result, error) {
+	if len(query) > maxQueryLen.IntN() {
+		return nil, fmt.Errorf("too long query; got %d bytes; mustn't exceed `-search.maxQueryLen=%d` bytes", len(query), maxQueryLen.N)
+	}
+	lookbackDelta, err := getMaxLookback(r)
+	if err != nil {
+		return nil, err
+	}
+	maxSeries, err := searchutils.GetMaxSeries(r, *maxUniqueTimeseries, "-search.maxUniqueTimeseries")
+	if err != nil {
+		return nil, err
+	}
+	etfs, err := searchutils.GetExtraTagFilters(r)
+	if err != nil {
+		return nil, err
+	}
+	isInstant := start == end
+	if !isInstant {
+		step = promql.AdjustStepForMaxPoints(start, end, step, *maxPointsPerTimeseries)
+		start, end = promql.AdjustStartEnd(start, end, step)
+	}
+	ec := promql.EvalConfig{
+		Start:               start,
+		End:                 end,
+		Step:                step,
+		MaxPointsPerSeries:  *maxPointsPerTimeseries,
+		MaxSeries:           maxSeries,
+		QuotedRemoteAddr:    httpserver.GetQuotedRemoteAddr(r),
+		Deadline:            searchutils.GetDeadlineForQuery(r, startTime),
+		MayCache:            true,
+		LookbackDelta:       lookbackDelta,
+		RoundDigits:         getRoundDigits(r),
+		EnforcedTagFilterss: etfs,
+		Tenant:              searchutils.GetTenant(r),
+	}
+	if isInstant {
+		result, err := promql.Exec(qt, &ec, query, true)
+		if err != nil {
+			return nil, fmt.Errorf("error when executing query=%q at time=%d: %w", query, start, err)
+		}
+		return result, nil
+	}
+	result, err := promql.ExecRange(qt, &ec, query)
+	if err != nil {
+		return nil, fmt.Errorf("error when executing query=%q on the time range (start=%d, end=%d, step=%d): %w", query, start, end, step, err)
+	}
+	return result, nil
+}
+
+func newGrafanaTimeseries(rs *netstorage.Result) *grafanaTimeseries {
+	datapoints := make([][2]float64, 0, len(rs.Values))
+	for i, v := range rs.Values {
+		if math.IsNaN(v) {
+			continue
+		}
+		datapoints = append(datapoints, [2]float64{v, float64(rs.Timestamps[i])})
+	}
+	return &grafanaTimeseries{
+		Target:     rs.MetricName.String(),
+		Datapoints: datapoints,
+	}
+}
+
+// newGrafanaTable returns a table with `Time` column, columns for every label seen in rss and `Value` column.
+func newGrafanaTable(refID string, rss []netstorage.Result) *grafanaTable {
+	labelNamesMap := make(map[string]struct{})
+	hasMetricName := false
+	for i := range rss {
+		mn := &rss[i].MetricName
+		if len(mn.MetricGroup) > 0 {
+			hasMetricName = true
+		}
+		for _, tag := range mn.Tags {
+			labelNamesMap[string(tag.Key)] = struct{}{}
+		}
+	}
+	labelNames := make([]string, 0, len(labelNamesMap))
+	for labelName := range labelNamesMap {
+		labelNames = append(labelNames, labelName)
+	}
+	sort.Strings(labelNames)
+	if hasMetricName {
+		labelNames = append([]string{"__name__"}, labelNames...)
+	}
+
+	columns := make([]grafanaTableColumn, 0, len(labelNames)+2)
+	columns = append(columns, grafanaTableColumn{
+		Text: "Time",
+		Type: "time",
+	})
+	for _, labelName := range labelNames {
+		columns = append(columns, grafanaTableColumn{
+			Text: labelName,
+			Type: "string",
+		})
+	}
+	columns = append(columns, grafanaTableColumn{
+		Text: "Value",
+		Type: "number",
+	})
+
+	rows := make([][]interface{}, 0, len(rss))
+	for i := range rss {
+		rs := &rss[i]
+		if len(rs.Values) == 0 || math.IsNaN(rs.Values[len(rs.Values)-1]) {
+			continue
+		}
+		row := make([]interface{}, 0, len(columns))
+		row = append(row, rs.Timestamps[len(rs.Timestamps)-1])
+		for _, labelName := range labelNames {
+			if labelName == "__name__" {
+				row = append(row, string(rs.MetricName.MetricGroup))
+				continue
+			}
+			row = append(row, string(rs.MetricName.GetTagValue(labelName)))
+		}
+		row = append(row, rs.Values[len(rs.Values)-1])
+		rows = append(rows, row)
+	}
+	return &grafanaTable{
+		Type:    "table",
+		RefID:   refID,
+		Columns: columns,
+		Rows:    rows,
+	}
+}
+
+func writeGrafanaResponse(w http.ResponseWriter, data interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		return fmt.Errorf("cannot send response to the client: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+)
+
+func TestParseGrafanaSearchTargetSuccess(t *testing.T) {
+	f := func(target, labelNameExpected, selectorExpected, prefixExpected string) {
+		t.Helper()
+		labelName, selector, prefix, err := parseGrafanaSearchTarget(target)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if labelName != labelNameExpected {
+			t.Fatalf("unexpected label name; got %q; want %q", labelName, labelNameExpected)
+		}
+		if selector != selectorExpected {
+			t.Fatalf("unexpected selector; got %q; want %q", selector, selectorExpected)
+		}
+		if prefix != prefixExpected {
+			t.Fatalf("unexpected prefix; got %q; want %q", prefix, prefixExpected)
+		}
+	}
+	f("", "__name__", "", "")
+	f(" node_", "__name__", "", "node_")
+	f("label_values(job)", "job", "", "")
+	f("label_values( up{job=~\"a,b\"} , instance )", "instance", `up{job=~"a,b"}`, "")
+}
+
+func TestParseGrafanaSearchTargetFailure(t *testing.T) {
+	f := func(target string) {
+		t.Helper()
+		if _, _, _, err := parseGrafanaSearchTarget(target); err == nil {
+			t.Fatalf("expecting non-nil error for %q", target)
+		}
+	}
+	f("label_values(job")
+	f("label_values()")
+	f("label_values(up, )")
+}
+
+func TestNewGrafanaTable(t *testing.T) {
+	var rs1 netstorage.Result
+	rs1.MetricName.MetricGroup = []byte("up")
+	rs1.MetricName.AddTag("job", "foo")
+	rs1.Timestamps = []int64{1000, 2000}
+	rs1.Values = []float64{0, 1}
+
+	var rs2 netstorage.Result
+	rs2.MetricName.MetricGroup = []byte("up")
+	rs2.MetricName.AddTag("instance", "bar")
+	rs2.Timestamps = []int64{2000}
+	rs2.Values = []float64{2}
+
+	// Series with NaN value must be skipped.
+	var rs3 netstorage.Result
+	rs3.MetricName.MetricGroup = []byte("up")
+	rs3.Timestamps = []int64{2000}
+	rs3.Values = []float64{math.NaN()}
+
+	table := newGrafanaTable("A", []netstorage.Result{rs1, rs2, rs3})
+	data, err := json.Marshal(table)
+	if err != nil {
+		t.Fatalf("cannot marshal table: %s", err)
+	}
+	resultExpected := `{"type":"table","refId":"A","columns":[{"text":"Time","type":"time"},{"text":"__name__","type":"string"},` +
+		`{"text":"instance","type":"string"},{"text":"job","type":"string"},{"text":"Value","type":"number"}],` +
+		`"rows":[[2000,"up","","foo",1],[2000,"up","bar","",2]]}`
+	if string(data) != resultExpected {
+		t.Fatalf("unexpected table;\ngot\n%s\nwant\n%s", data, resultExpected)
+	}
+}
@@ -856,22 +856,122 @@ func quantiles(qs, phis []float64, originValues []float64) []float64 {
 // quantile calculates the given phi from originValues without modifying originValues
 func quantile(phi float64, originValues []float64) float64 {
 	a := getFloat64s()
-	a.prepareForQuantileFloat64(originValues)
-	q := quantileSorted(phi, a.A)
+	a.A = appendNonNaNs(a.A[:0], originValues)
+	q := quantileUnsorted(phi, a.A)
 	putFloat64s(a)
 	return q
 }
 
-// prepareForQuantileFloat64 copies items from src to a but removes NaNs and sorts items in a.
-func (a *float64s) prepareForQuantileFloat64(src []float64) {
-	dst := a.A[:0]
+func appendNonNaNs(dst, src []float64) []float64 {
 	for _, v := range src {
 		if math.IsNaN(v) {
 			continue
 		}
 		dst = append(dst, v)
 	}
-	a.A = dst
+	return dst
+}
+
+// quantileUnsorted calculates the given quantile over an unsorted list of values.
+//
+// It returns the same result as quantileSorted, but it doesn't sort values.
+// Instead, it selects only the values needed for the quantile calculation in O(n) time on average.
+// values are partially reordered during the calculation.
+//
+// It is expected that values won't contain NaN items.
+func quantileUnsorted(phi float64, values []float64) float64 {
+	if len(values) == 0 || math.IsNaN(phi) {
+		return nan
+	}
+	if phi < 0 {
+		return math.Inf(-1)
+	}
+	if phi > 1 {
+		return math.Inf(+1)
+	}
+	n := float64(len(values))
+	rank := phi * (n - 1)
+
+	lowerIndex := int(math.Max(0, math.Floor(rank)))
+	upperIndex := int(math.Min(n-1, float64(lowerIndex)+1))
+
+	selectNth(values, lowerIndex)
+	lowerValue := values[lowerIndex]
+	upperValue := lowerValue
+	if upperIndex > lowerIndex {
+		// values after lowerIndex cannot be smaller than lowerValue after selectNth,
+		// so the value at upperIndex in sorted order is the minimum among them.
+		upperValue = values[upperIndex]
+		for _, v := range values[upperIndex+1:] {
+			if v < upperValue {
+				upperValue = v
+			}
+		}
+	}
+
+	weight := rank - math.Floor(rank)
+	return lowerValue*(1-weight) + upperValue*weight
+}
+
+// selectNth reorders a, so a[k] contains the value, which would be at k position if a is sorted.
+//
+// All the values before k don't exceed a[k], while all the values after k aren't smaller than a[k].
+//
+// It is expected that a doesn't contain NaN items.
+func selectNth(a []float64, k int) {
+	lo, hi := 0, len(a)-1
+	for hi-lo > 16 {
+		// Use median of three as a pivot in order to avoid quadratic complexity on already sorted values.
+		mid := lo + (hi-lo)/2
+		if a[mid] < a[lo] {
+			a[mid], a[lo] = a[lo], a[mid]
+		}
+		if a[hi] < a[lo] {
+			a[hi], a[lo] = a[lo], a[hi]
+		}
+		if a[hi] < a[mid] {
+			a[hi], a[mid] = a[mid], a[hi]
+		}
+		pivot := a[mid]
+		i, j := lo, hi
+		for i <= j {
+			for a[i] < pivot {
+				i++
+			}
+			for pivot < a[j] {
+				j--
+			}
+			if i <= j {
+				a[i], a[j] = a[j], a[i]
+				i++
+				j--
+			}
+		}
+		switch {
+		case k <= j:
+			hi = j
+		case k >= i:
+			lo = i
+		default:
+			// a[j+1:i] contains only pivot values, so a[k] is already at its place.
+			return
+		}
+	}
+	// Fast path: sort the remaining small range with insertion sort.
+	for i := lo + 1; i <= hi; i++ {
+		v := a[i]
+		j := i
+		for j > lo && v < a[j-1] {
+			a[j] = a[j-1]
+			j--
+		}
+		a[j] = v
+	}
+}
+
+// prepareForQuantileFloat64 copies items from src to a but removes NaNs and sorts items in a.
+func (a *float64s) prepareForQuantileFloat64(src []float64) {
+	a.A = appendNonNaNs(a.A[:0], src)
 	// Use sort.Sort instead of sort.Float64s in order to avoid a memory allocation
 	sort.Sort(a)
 }
@@ -2,6 +2,8 @@ package promql
 
 import (
 	"math"
+	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -34,3 +36,45 @@ func TestModeNoNaNs(t *testing.T) {
 	f(1, []float64{2, 3, 3, 4, 4}, 3)
 	f(1, []float64{4, 3, 2, 3, 4}, 3)
 }
+
+func TestQuantileUnsorted(t *testing.T) {
+	f := func(phi float64, values []float64) {
+		t.Helper()
+		a := append([]float64{}, values...)
+		sort.Float64s(a)
+		qExpected := quantileSorted(phi, a)
+		q := quantileUnsorted(phi, append([]float64{}, values...))
+		if math.IsNaN(qExpected) {
+			if !math.IsNaN(q) {
+				t.Fatalf("unexpected quantile for phi=%v over %v; got %v; want %v", phi, values, q, qExpected)
+			}
+			return
+		}
+		if q != qExpected {
+			t.Fatalf("unexpected quantile for phi=%v over %v; got %v; want %v", phi, values, q, qExpected)
+		}
+	}
+	f(0.5, nil)
+	f(nan, []float64{1, 2, 3})
+	f(-1, []float64{1, 2, 3})
+	f(2, []float64{1, 2, 3})
+	f(0.5, []float64{1})
+	f(0.5, []float64{3, 1, 2})
+	f(0.9, []float64{3, 1, 2, 4})
+	f(0.3, []float64{5, 5, 5, 1, 5, 5})
+	f(1, []float64{math.Inf(1), 1, 2})
+	f(0.5, []float64{math.Inf(-1), 1, math.Inf(1)})
+
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{17, 100, 1000} {
+		values := make([]float64, n)
+		for i := range values {
+			values[i] = math.Round(r.Float64() * 100)
+		}
+		for _, phi := range []float64{0, 0.01, 0.25, 0.5, 0.75, 0.99, 1} {
+			f(phi, values)
+		}
+		sort.Float64s(values)
+		f(0.5, values)
+	}
+}
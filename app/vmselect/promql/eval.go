@@ -10,7 +10,9 @@ import (
 	"sync"
 	"sync/atomic"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/metricaliases"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
@@ -53,6 +55,23 @@ func ValidateMaxPointsPerSeries(start, end, step int64, maxPoints int) error {
 	return nil
 }
 
+// AdjustStepForMaxPoints returns the smallest multiple of step, which results in up to maxPoints points
+// on the given [start ... end] time range.
+//
+// The returned step is a multiple of the original step, so the points remain aligned to the original step.
+func AdjustStepForMaxPoints(start, end, step int64, maxPoints int) int64 {
+	if step <= 0 || maxPoints <= 0 {
+		return step
+	}
+	points := (end-start)/step + 1
+	if points <= int64(maxPoints) {
+		return step
+	}
+	minStep := (end-start)/int64(maxPoints) + 1
+	n := (minStep + step - 1) / step
+	return n * step
+}
+
 // AdjustStartEnd adjusts start and end values, so response caching may be enabled.
 //
 // See EvalConfig.mayCache for details.
@@ -117,12 +136,39 @@ type EvalConfig struct {
 	// LookbackDelta is analog to `-query.lookback-delta` from Prometheus.
 	LookbackDelta int64
 
+	// ExtraLookbehind is an additional duration in milliseconds to fetch raw samples before Start.
+	//
+	// This allows sparse counters to have the previous sample for the first points on the selected time range.
+	ExtraLookbehind int64
+
+	// MaxLookbehindWindow is the maximum lookbehind window in milliseconds for rollup functions and subqueries.
+	//
+	// Bigger windows are capped to MaxLookbehindWindow. Zero means 'no limit'.
+	MaxLookbehindWindow int64
+
 	// How many decimal digits after the point to leave in response.
 	RoundDigits int
 
 	// EnforcedTagFilterss may contain additional label filters to use in the query.
 	EnforcedTagFilterss [][]storage.TagFilter
 
+	// Tenant is the tenant name for the query. See -search.tenantHeader.
+	Tenant string
+
+	// PromQLCompat disables MetricsQL behavioral extensions, so the query results are closer to Prometheus results.
+	//
+	// See -search.promqlCompat.
+	PromQLCompat bool
+
+	// QueryStats collects execution stats for the query. It may be nil.
+	QueryStats *querystats.QueryStats
+
+	// queryStart and queryEnd contain the time range for the original query.
+	//
+	// They are used for resolving start() and end() in `@` modifiers inside subqueries.
+	queryStart int64
+	queryEnd   int64
+
 	timestamps     []int64
 	timestampsOnce sync.Once
 }
@@ -138,13 +184,47 @@ func copyEvalConfig(src *EvalConfig) *EvalConfig {
 	ec.Deadline = src.Deadline
 	ec.MayCache = src.MayCache
 	ec.LookbackDelta = src.LookbackDelta
+	ec.ExtraLookbehind = src.ExtraLookbehind
+	ec.MaxLookbehindWindow = src.MaxLookbehindWindow
 	ec.RoundDigits = src.RoundDigits
 	ec.EnforcedTagFilterss = src.EnforcedTagFilterss
+	ec.Tenant = src.Tenant
+	ec.PromQLCompat = src.PromQLCompat
+	ec.QueryStats = src.QueryStats
+	ec.queryStart = src.queryStart
+	ec.queryEnd = src.queryEnd
 
 	// do not copy src.timestamps - they must be generated again.
 	return &ec
 }
 
+// capLookbehindWindow returns window limited by ec.MaxLookbehindWindow.
+func (ec *EvalConfig) capLookbehindWindow(qt *querytracer.Tracer, window int64) int64 {
+	return CapLookbehindWindow(qt, window, ec.Step, ec.MaxLookbehindWindow)
+}
+
+// CapLookbehindWindow returns window limited by maxWindow. Zero maxWindow means 'no limit'.
+//
+// Zero window means the implicit window, which equals to step, e.g. for `rate(m)` or `m`.
+// Zero is returned if the implicit window doesn't exceed maxWindow.
+func CapLookbehindWindow(qt *querytracer.Tracer, window, step, maxWindow int64) int64 {
+	if maxWindow <= 0 {
+		return window
+	}
+	if window == 0 {
+		if step <= maxWindow {
+			return 0
+		}
+	} else if window <= maxWindow {
+		return window
+	}
+	lookbehindWindowsCapped.Inc()
+	qt.Printf("cap lookbehind window from %dms to %dms", window, maxWindow)
+	return maxWindow
+}
+
+var lookbehindWindowsCapped = metrics.NewCounter(`vm_promql_lookbehind_windows_capped_total`)
+
 func (ec *EvalConfig) validate() {
 	if ec.Start > ec.End {
 		logger.Panicf("BUG: start cannot exceed end; got %d vs %d", ec.Start, ec.End)
@@ -154,6 +234,25 @@ func (ec *EvalConfig) validate() {
 	}
 }
 
+// getAtEvalConfig returns EvalConfig for evaluating `@` modifier.
+//
+// start() and end() in `@` modifier are resolved to the start and the end of the original query
+// even inside subqueries in the same way as Prometheus does.
+func (ec *EvalConfig) getAtEvalConfig() *EvalConfig {
+	if ec.queryEnd == 0 || (ec.Start == ec.queryStart && ec.End == ec.queryEnd) {
+		return ec
+	}
+	ecAt := copyEvalConfig(ec)
+	ecAt.Start = ec.queryStart
+	ecAt.End = ec.queryEnd
+	// Only the first value is used from `@` modifier, so there is no need in evaluating it on the original step.
+	ecAt.Step = ecAt.End - ecAt.Start
+	if ecAt.Step <= 0 {
+		ecAt.Step = ec.Step
+	}
+	return ecAt
+}
+
 func (ec *EvalConfig) mayCache() bool {
 	if *disableCache {
 		return false
@@ -161,6 +260,11 @@ func (ec *EvalConfig) mayCache() bool {
 	if !ec.MayCache {
 		return false
 	}
+	if ec.PromQLCompat {
+		// Results in PromQL compatibility mode differ from results for the same query in MetricsQL mode,
+		// so they cannot be shared via rollup result cache.
+		return false
+	}
 	if ec.Start%ec.Step != 0 {
 		return false
 	}
@@ -624,7 +728,7 @@ func tryGetArgRollupFuncWithMetricExpr(ae *metricsql.AggrFuncExpr) (*metricsql.F
 	if nrf == nil {
 		return nil, nil
 	}
-	rollupArgIdx := metricsql.GetRollupArgIdx(fe)
+	rollupArgIdx := getRollupArgIdx(fe)
 	if rollupArgIdx >= len(fe.Args) {
 		// Incorrect number of args for rollup func.
 		return nil, nil
@@ -694,7 +798,7 @@ func evalExprsInParallel(qt *querytracer.Tracer, ec *EvalConfig, es []metricsql.
 
 func evalRollupFuncArgs(qt *querytracer.Tracer, ec *EvalConfig, fe *metricsql.FuncExpr) ([]interface{}, *metricsql.RollupExpr, error) {
 	var re *metricsql.RollupExpr
-	rollupArgIdx := metricsql.GetRollupArgIdx(fe)
+	rollupArgIdx := getRollupArgIdx(fe)
 	if len(fe.Args) <= rollupArgIdx {
 		return nil, nil, fmt.Errorf("expecting at least %d args to %q; got %d args; expr: %q", rollupArgIdx+1, fe.Name, len(fe.Args), fe.AppendString(nil))
 	}
@@ -750,7 +854,7 @@ func evalRollupFunc(qt *querytracer.Tracer, ec *EvalConfig, funcName string, rf
 	if re.At == nil {
 		return evalRollupFuncWithoutAt(qt, ec, funcName, rf, expr, re, iafc)
 	}
-	tssAt, err := evalExpr(qt, ec, re.At)
+	tssAt, err := evalExpr(qt, ec.getAtEvalConfig(), re.At)
 	if err != nil {
 		return nil, &UserReadableError{
 			Err: fmt.Errorf("cannot evaluate `@` modifier: %w", err),
@@ -869,7 +973,7 @@ func evalRollupFuncWithSubquery(qt *querytracer.Tracer, ec *EvalConfig, funcName
 	if step == 0 {
 		step = ec.Step
 	}
-	window := re.Window.Duration(ec.Step)
+	window := ec.capLookbehindWindow(qt, re.Window.Duration(ec.Step))
 
 	ecSQ := copyEvalConfig(ec)
 	ecSQ.Start -= window + maxSilenceInterval + step
@@ -889,14 +993,14 @@ func evalRollupFuncWithSubquery(qt *querytracer.Tracer, ec *EvalConfig, funcName
 		return nil, nil
 	}
 	sharedTimestamps := getTimestamps(ec.Start, ec.End, ec.Step, ec.MaxPointsPerSeries)
-	preFunc, rcs, err := getRollupConfigs(funcName, rf, expr, ec.Start, ec.End, ec.Step, ec.MaxPointsPerSeries, window, ec.LookbackDelta, sharedTimestamps)
+	preFunc, rcs, err := getRollupConfigs(funcName, rf, expr, ec.Start, ec.End, ec.Step, ec.MaxPointsPerSeries, window, ec.LookbackDelta, ec.PromQLCompat, sharedTimestamps)
 	if err != nil {
 		return nil, err
 	}
 	tss := make([]*timeseries, 0, len(tssSQ)*len(rcs))
 	var tssLock sync.Mutex
 	var samplesScannedTotal uint64
-	keepMetricNames := getKeepMetricNames(expr)
+	keepMetricNames := getKeepMetricNames(expr) || rollupFuncKeepsMetricName(funcName, ec.PromQLCompat)
 	doParallel(tssSQ, func(tsSQ *timeseries, values []float64, timestamps []int64) ([]float64, []int64) {
 		values, timestamps = removeNanValues(values[:0], timestamps[:0], tsSQ.Values, tsSQ.Timestamps)
 		preFunc(values, timestamps)
@@ -910,7 +1014,7 @@ func evalRollupFuncWithSubquery(qt *querytracer.Tracer, ec *EvalConfig, funcName
 				continue
 			}
 			var ts timeseries
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, &ts, &tsSQ.MetricName, values, timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(keepMetricNames, rc, &ts, &tsSQ.MetricName, values, timestamps, sharedTimestamps)
 			atomic.AddUint64(&samplesScannedTotal, samplesScanned)
 			tssLock.Lock()
 			tss = append(tss, &ts)
@@ -1000,7 +1104,7 @@ var (
 func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcName string, rf rollupFunc,
 	expr metricsql.Expr, me *metricsql.MetricExpr, iafc *incrementalAggrFuncContext, windowExpr *metricsql.DurationExpr) ([]*timeseries, error) {
 	var rollupMemorySize int64
-	window := windowExpr.Duration(ec.Step)
+	window := ec.capLookbehindWindow(qt, windowExpr.Duration(ec.Step))
 	if qt.Enabled() {
 		qt = qt.NewChild("rollup %s(): timeRange=%s, step=%d, window=%d", funcName, ec.timeRangeString(), ec.Step, window)
 		defer func() {
@@ -1023,11 +1127,12 @@ func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcNa
 	} else {
 		rollupResultCacheMiss.Inc()
 	}
+	quarantineEvents := storage.GetPartQuarantineEvents()
 
 	// Obtain rollup configs before fetching data from db,
 	// so type errors can be caught earlier.
 	sharedTimestamps := getTimestamps(start, ec.End, ec.Step, ec.MaxPointsPerSeries)
-	preFunc, rcs, err := getRollupConfigs(funcName, rf, expr, start, ec.End, ec.Step, ec.MaxPointsPerSeries, window, ec.LookbackDelta, sharedTimestamps)
+	preFunc, rcs, err := getRollupConfigs(funcName, rf, expr, start, ec.End, ec.Step, ec.MaxPointsPerSeries, window, ec.LookbackDelta, ec.PromQLCompat, sharedTimestamps)
 	if err != nil {
 		return nil, err
 	}
@@ -1035,7 +1140,8 @@ func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcNa
 	// Fetch the remaining part of the result.
 	tfs := searchutils.ToTagFilters(me.LabelFilters)
 	tfss := searchutils.JoinTagFilterss([][]storage.TagFilter{tfs}, ec.EnforcedTagFilterss)
-	minTimestamp := start - maxSilenceInterval
+	tfss, renamer := metricaliases.Get().ExpandTagFilterss(tfss)
+	minTimestamp := start - maxSilenceInterval - ec.ExtraLookbehind
 	if window > ec.Step {
 		minTimestamp -= window
 	} else {
@@ -1049,6 +1155,7 @@ func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcNa
 		}
 	}
 	rssLen := rss.Len()
+	ec.QueryStats.AddSeriesFetched(rssLen)
 	if rssLen == 0 {
 		rss.Cancel()
 		tss := mergeTimeseries(tssCached, nil, start, ec)
@@ -1102,14 +1209,27 @@ func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcNa
 		}
 	}
 	defer rml.Put(uint64(rollupMemorySize))
+	ec.QueryStats.AddMemory(rollupMemorySize)
+	defer ec.QueryStats.AddMemory(-rollupMemorySize)
 
 	// Evaluate rollup
-	keepMetricNames := getKeepMetricNames(expr)
+	keepMetricNames := getKeepMetricNames(expr) || rollupFuncKeepsMetricName(funcName, ec.PromQLCompat)
 	var tss []*timeseries
-	if iafc != nil {
-		tss, err = evalRollupWithIncrementalAggregate(qt, funcName, keepMetricNames, iafc, rss, rcs, preFunc, sharedTimestamps)
+	if iafc != nil && renamer == nil {
+		tss, err = evalRollupWithIncrementalAggregate(qt, ec.QueryStats, funcName, keepMetricNames, iafc, rss, rcs, preFunc, sharedTimestamps)
 	} else {
-		tss, err = evalRollupNoIncrementalAggregate(qt, funcName, keepMetricNames, rss, rcs, preFunc, sharedTimestamps)
+		tss, err = evalRollupNoIncrementalAggregate(qt, ec.QueryStats, funcName, keepMetricNames, rss, rcs, preFunc, sharedTimestamps, renamer)
+		if err == nil && renamer != nil {
+			// Series for old and new metric names may have identical names after the renaming,
+			// so they must be merged before the aggregation.
+			tss = mergeAliasedTimeseries(tss)
+			if iafc != nil {
+				for _, ts := range tss {
+					iafc.updateTimeseries(ts, 0)
+				}
+				tss = iafc.finalizeTimeseries()
+			}
+		}
 	}
 	if err != nil {
 		return nil, &UserReadableError{
@@ -1117,6 +1237,12 @@ func evalRollupFuncWithMetricExpr(qt *querytracer.Tracer, ec *EvalConfig, funcNa
 		}
 	}
 	tss = mergeTimeseries(tssCached, tss, start, ec)
+	if storage.GetQuarantinedPartsCount() > 0 || storage.GetPartQuarantineEvents() != quarantineEvents {
+		// Do not cache results, which may miss data from parts quarantined because of read errors.
+		// Otherwise the incomplete results would be served from the cache after the parts are returned back to searches.
+		qt.Printf("do not store series to cache, since some parts are quarantined because of read errors")
+		return tss, nil
+	}
 	rollupResultCacheV.Put(qt, ec, expr, window, tss)
 	return tss, nil
 }
@@ -1133,7 +1259,7 @@ func getRollupMemoryLimiter() *memoryLimiter {
 	return &rollupMemoryLimiter
 }
 
-func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string, keepMetricNames bool,
+func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, qs *querystats.QueryStats, funcName string, keepMetricNames bool,
 	iafc *incrementalAggrFuncContext, rss *netstorage.Results, rcs []*rollupConfig,
 	preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64) ([]*timeseries, error) {
 	qt = qt.NewChild("rollup %s() with incremental aggregation %s() over %d series; rollupConfigs=%s", funcName, iafc.ae.Name, rss.Len(), rcs)
@@ -1154,7 +1280,7 @@ func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string,
 				continue
 			}
 			ts.Reset()
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(keepMetricNames, rc, ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
 			atomic.AddUint64(&samplesScannedTotal, samplesScanned)
 			iafc.updateTimeseries(ts, workerID)
 
@@ -1169,18 +1295,22 @@ func evalRollupWithIncrementalAggregate(qt *querytracer.Tracer, funcName string,
 	}
 	tss := iafc.finalizeTimeseries()
 	rowsScannedPerQuery.Update(float64(samplesScannedTotal))
+	qs.AddSamplesScanned(samplesScannedTotal)
 	qt.Printf("series after aggregation with %s(): %d; samplesScanned=%d", iafc.ae.Name, len(tss), samplesScannedTotal)
 	return tss, nil
 }
 
-func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, keepMetricNames bool, rss *netstorage.Results, rcs []*rollupConfig,
-	preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64) ([]*timeseries, error) {
+func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, qs *querystats.QueryStats, funcName string, keepMetricNames bool, rss *netstorage.Results, rcs []*rollupConfig,
+	preFunc func(values []float64, timestamps []int64), sharedTimestamps []int64, renamer *metricaliases.Renamer) ([]*timeseries, error) {
 	qt = qt.NewChild("rollup %s() over %d series; rollupConfigs=%s", funcName, rss.Len(), rcs)
 	defer qt.Done()
 	tss := make([]*timeseries, 0, rss.Len()*len(rcs))
 	var tssLock sync.Mutex
 	var samplesScannedTotal uint64
 	err := rss.RunParallel(qt, func(rs *netstorage.Result, workerID uint) error {
+		if renamer != nil {
+			renamer.RenameMetricName(&rs.MetricName)
+		}
 		rs.Values, rs.Timestamps = dropStaleNaNs(funcName, rs.Values, rs.Timestamps)
 		preFunc(rs.Values, rs.Timestamps)
 		for _, rc := range rcs {
@@ -1193,7 +1323,7 @@ func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, k
 				continue
 			}
 			var ts timeseries
-			samplesScanned := doRollupForTimeseries(funcName, keepMetricNames, rc, &ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
+			samplesScanned := doRollupForTimeseries(keepMetricNames, rc, &ts, &rs.MetricName, rs.Values, rs.Timestamps, sharedTimestamps)
 			atomic.AddUint64(&samplesScannedTotal, samplesScanned)
 			tssLock.Lock()
 			tss = append(tss, &ts)
@@ -1205,17 +1335,82 @@ func evalRollupNoIncrementalAggregate(qt *querytracer.Tracer, funcName string, k
 		return nil, err
 	}
 	rowsScannedPerQuery.Update(float64(samplesScannedTotal))
+	qs.AddSamplesScanned(samplesScannedTotal)
 	qt.Printf("samplesScanned=%d", samplesScannedTotal)
 	return tss, nil
 }
 
-func doRollupForTimeseries(funcName string, keepMetricNames bool, rc *rollupConfig, tsDst *timeseries, mnSrc *storage.MetricName,
+// mergeAliasedTimeseries merges time series with identical names in tss.
+//
+// Such time series may appear when series for old and new metric names are renamed via metric aliases.
+// Gaps in the series are filled with values from other series with the same name.
+// Values from the series with the most recent data take precedence on overlapping points.
+func mergeAliasedTimeseries(tss []*timeseries) []*timeseries {
+	m := make(map[string][]*timeseries, len(tss))
+	var keys []string
+	bb := bbPool.Get()
+	for _, ts := range tss {
+		bb.B = marshalMetricNameSorted(bb.B[:0], &ts.MetricName)
+		k := string(bb.B)
+		if _, ok := m[k]; !ok {
+			keys = append(keys, k)
+		}
+		m[k] = append(m[k], ts)
+	}
+	bbPool.Put(bb)
+	if len(keys) == len(tss) {
+		return tss
+	}
+	rvs := make([]*timeseries, 0, len(keys))
+	for _, k := range keys {
+		tssLocal := m[k]
+		if len(tssLocal) > 1 {
+			sort.SliceStable(tssLocal, func(i, j int) bool {
+				a, b := tssLocal[i].Values, tssLocal[j].Values
+				if na, nb := getLastNonNaNIdx(a), getLastNonNaNIdx(b); na != nb {
+					return na > nb
+				}
+				return getFirstNonNaNIdx(a) > getFirstNonNaNIdx(b)
+			})
+			dst := tssLocal[0]
+			for _, ts := range tssLocal[1:] {
+				for i, v := range dst.Values {
+					if math.IsNaN(v) {
+						dst.Values[i] = ts.Values[i]
+					}
+				}
+			}
+		}
+		rvs = append(rvs, tssLocal[0])
+	}
+	return rvs
+}
+
+func getFirstNonNaNIdx(values []float64) int {
+	for i, v := range values {
+		if !math.IsNaN(v) {
+			return i
+		}
+	}
+	return -1
+}
+
+func getLastNonNaNIdx(values []float64) int {
+	for i := len(values) - 1; i >= 0; i-- {
+		if !math.IsNaN(values[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func doRollupForTimeseries(keepMetricNames bool, rc *rollupConfig, tsDst *timeseries, mnSrc *storage.MetricName,
 	valuesSrc []float64, timestampsSrc []int64, sharedTimestamps []int64) uint64 {
 	tsDst.MetricName.CopyFrom(mnSrc)
 	if len(rc.TagValue) > 0 {
 		tsDst.MetricName.AddTag("rollup", rc.TagValue)
 	}
-	if !keepMetricNames && !rollupFuncsKeepMetricName[funcName] {
+	if !keepMetricNames {
 		tsDst.MetricName.ResetMetricGroup()
 	}
 	var samplesScanned uint64
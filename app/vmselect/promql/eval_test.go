@@ -76,3 +76,91 @@ func TestValidateMaxPointsPerSeriesSuccess(t *testing.T) {
 	f(1659962171908, 1659966077742, 5000, 800)
 	f(1659962150000, 1659966070000, 10000, 393)
 }
+
+func TestAdjustStepForMaxPoints(t *testing.T) {
+	f := func(start, end, step int64, maxPoints int, stepExpected int64) {
+		t.Helper()
+		newStep := AdjustStepForMaxPoints(start, end, step, maxPoints)
+		if newStep != stepExpected {
+			t.Fatalf("unexpected step for start=%d, end=%d, step=%d, maxPoints=%d; got %d; want %d", start, end, step, maxPoints, newStep, stepExpected)
+		}
+		if err := ValidateMaxPointsPerSeries(start, end, newStep, maxPoints); err != nil {
+			t.Fatalf("unexpected error for the adjusted step: %s", err)
+		}
+	}
+	// the number of points doesn't exceed maxPoints
+	f(0, 100, 1, 101, 1)
+	f(1659962171908, 1659966077742, 5000, 800, 5000)
+	// the step must be increased
+	f(0, 100, 1, 100, 2)
+	f(0, 100, 1, 1, 101)
+	f(0, 1000, 10, 11, 100)
+	f(0, 1000, 10, 10, 110)
+	f(1659962171908, 1659966077742, 5000, 700, 10000)
+	// 30 days with 1s step
+	f(0, 30*24*3600*1000, 1000, 30000, 87000)
+}
+
+func TestCapLookbehindWindow(t *testing.T) {
+	f := func(window, step, maxWindow, windowExpected int64) {
+		t.Helper()
+		w := CapLookbehindWindow(nil, window, step, maxWindow)
+		if w != windowExpected {
+			t.Fatalf("unexpected window for window=%d, step=%d, maxWindow=%d; got %d; want %d", window, step, maxWindow, w, windowExpected)
+		}
+	}
+	// no limit
+	f(365*24*3600*1000, 1000, 0, 365*24*3600*1000)
+	f(0, 24*3600*1000, 0, 0)
+	// explicit window
+	f(3600*1000, 1000, 24*3600*1000, 3600*1000)
+	f(365*24*3600*1000, 1000, 24*3600*1000, 24*3600*1000)
+	// implicit window equals to step
+	f(0, 3600*1000, 24*3600*1000, 0)
+	f(0, 7*24*3600*1000, 24*3600*1000, 24*3600*1000)
+}
+
+func TestMergeAliasedTimeseries(t *testing.T) {
+	f := func(tss []*timeseries, valuesExpected [][]float64) {
+		t.Helper()
+		tss = mergeAliasedTimeseries(tss)
+		if len(tss) != len(valuesExpected) {
+			t.Fatalf("unexpected number of series; got %d; want %d", len(tss), len(valuesExpected))
+		}
+		for i, ts := range tss {
+			if err := compareValues(ts.Values, valuesExpected[i]); err != nil {
+				t.Fatalf("unexpected values for series #%d: %s", i, err)
+			}
+		}
+	}
+	newTimeseries := func(name string, values ...float64) *timeseries {
+		ts := &timeseries{
+			Values:     values,
+			Timestamps: make([]int64, len(values)),
+		}
+		ts.MetricName.MetricGroup = []byte(name)
+		return ts
+	}
+	// no duplicates
+	f([]*timeseries{
+		newTimeseries("foo", 1, 2),
+		newTimeseries("bar", 3, 4),
+	}, [][]float64{{1, 2}, {3, 4}})
+
+	// old series ends before the new series starts
+	f([]*timeseries{
+		newTimeseries("foo", 1, 2, nan, nan),
+		newTimeseries("bar", 5, 6),
+		newTimeseries("foo", nan, nan, 3, 4),
+	}, [][]float64{{1, 2, 3, 4}, {5, 6}})
+
+	// overlapping series - the series with the most recent data takes precedence
+	f([]*timeseries{
+		newTimeseries("foo", nan, 10, 20, 30),
+		newTimeseries("foo", 1, 2, 3, nan),
+	}, [][]float64{{1, 10, 20, 30}})
+	f([]*timeseries{
+		newTimeseries("foo", 1, 2, 3, 4),
+		newTimeseries("foo", nan, 10, 20, 30),
+	}, [][]float64{{1, 10, 20, 30}})
+}
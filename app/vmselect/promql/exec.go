@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryprofiler"
 	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
@@ -47,17 +48,33 @@ func (ure *UserReadableError) Error() string {
 
 // Exec executes q for the given ec.
 func Exec(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly bool) ([]netstorage.Result, error) {
+	defer queryprofiler.StartQuery(q)()
 	if querystats.Enabled() {
 		startTime := time.Now()
-		defer querystats.RegisterQuery(q, ec.End-ec.Start, startTime)
+		if ec.QueryStats == nil {
+			ec.QueryStats = &querystats.QueryStats{}
+		}
+		defer querystats.RegisterQuery(ec.Tenant, q, ec.End-ec.Start, startTime, ec.QueryStats)
 	}
+	return exec(qt, ec, q, isFirstPointOnly)
+}
 
+func exec(qt *querytracer.Tracer, ec *EvalConfig, q string, isFirstPointOnly bool) ([]netstorage.Result, error) {
 	ec.validate()
+	ec.queryStart = ec.Start
+	ec.queryEnd = ec.End
 
 	e, err := parsePromQLWithCache(q)
 	if err != nil {
 		return nil, err
 	}
+	if ec.PromQLCompat {
+		e, err = adjustExprForPromQLCompat(e)
+		if err != nil {
+			return nil, err
+		}
+		qt.Printf("evaluate the query in PromQL compatibility mode")
+	}
 
 	qid := activeQueriesV.Add(ec, q)
 	rv, err := evalExpr(qt, ec, e)
@@ -252,6 +269,9 @@ func parsePromQLWithCache(q string) (metricsql.Expr, error) {
 	pcv := parseCacheV.Get(q)
 	if pcv == nil {
 		e, err := metricsql.Parse(q)
+		if err == nil {
+			e, err = expandSLOFuncs(e)
+		}
 		if err == nil {
 			e = metricsql.Optimize(e)
 			e = adjustCmpOps(e)
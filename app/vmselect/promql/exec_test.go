@@ -2,6 +2,7 @@ package promql
 
 import (
 	"math"
+	"reflect"
 	"testing"
 	"time"
 
@@ -1154,6 +1155,41 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{r}
 		f(q, resultExpected)
 	})
+	t.Run("time() @ end() offset -10m", func(t *testing.T) {
+		t.Parallel()
+		q := `time() @ end() offset -10m`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{2600, 2600, 2600, 2600, 2600, 2600},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
+	t.Run("max_over_time((time() @ end())[300s:100s])", func(t *testing.T) {
+		t.Parallel()
+		// end() must refer to the end of the query instead of the end of the subquery.
+		q := `max_over_time((time() @ end())[300s:100s])`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{2000, 2000, 2000, 2000, 2000, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
+	t.Run("min_over_time((time() @ start())[300s:100s])", func(t *testing.T) {
+		t.Parallel()
+		// start() must refer to the start of the query instead of the start of the subquery.
+		q := `min_over_time((time() @ start())[300s:100s])`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1000, 1000, 1000, 1000, 1000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
 	t.Run("rand()", func(t *testing.T) {
 		t.Parallel()
 		q := `round(rand()/2)`
@@ -3547,6 +3583,43 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{}
 		f(q, resultExpected)
 	})
+	t.Run(`slo_burn_rate()`, func(t *testing.T) {
+		t.Parallel()
+		q := `slo_burn_rate(label_set(0.25, "job", "foo"), 0.5, 1h)`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+			Timestamps: timestampsExpected,
+		}
+		r.MetricName.Tags = []storage.Tag{{
+			Key:   []byte("job"),
+			Value: []byte("foo"),
+		}}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
+	t.Run(`error_budget_remaining()`, func(t *testing.T) {
+		t.Parallel()
+		q := `error_budget_remaining(0.125, 0.75, 1h)`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0.5, 0.5, 0.5, 0.5, 0.5, 0.5},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
+	t.Run(`slo_burn_rate_alert()`, func(t *testing.T) {
+		t.Parallel()
+		q := `slo_burn_rate_alert(time()/1e4, 0.5, 0.25, 10m, 1m)`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{nan, nan, nan, 0.28, 0.32, 0.36},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
 	t.Run(`histogram_share(scalar)`, func(t *testing.T) {
 		t.Parallel()
 		q := `histogram_share(123, time())`
@@ -5535,6 +5608,170 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{r1, r2, r3}
 		f(q, resultExpected)
 	})
+	t.Run(`count_values_over_time`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort_by_label(count_values_over_time("x", round(label_set(time()/500, "foo", "bar"))[200s:100s]), "x")`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{2, 2, nan, nan, nan, nan},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("foo"),
+				Value: []byte("bar"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("2"),
+			},
+		}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{nan, nan, 2, 2, 1, nan},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("foo"),
+				Value: []byte("bar"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("3"),
+			},
+		}
+		r3 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{nan, nan, nan, nan, 1, 2},
+			Timestamps: timestampsExpected,
+		}
+		r3.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("foo"),
+				Value: []byte("bar"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("4"),
+			},
+		}
+		resultExpected := []netstorage.Result{r1, r2, r3}
+		f(q, resultExpected)
+	})
+	t.Run(`distribution_over_time`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort_by_label(distribution_over_time(label_set(time()/100, "foo", "bar")[400s:100s], 5, 25, 2), "vmrange")`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0, 0, 0, 2, 4, 4},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("foo"),
+				Value: []byte("bar"),
+			},
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("15...25"),
+			},
+		}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{4, 4, 4, 2, 0, 0},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("foo"),
+				Value: []byte("bar"),
+			},
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("5...15"),
+			},
+		}
+		resultExpected := []netstorage.Result{r1, r2}
+		f(q, resultExpected)
+	})
+	t.Run(`distribution_over_time(out_of_range)`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort_by_label(distribution_over_time(union(label_set(-5, "x", "a"), label_set(123, "x", "b"))[200s:100s], 0, 100, 1), "x", "vmrange")`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{2, 2, 2, 2, 2, 2},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("-Inf...0"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("a"),
+			},
+		}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0, 0, 0, 0, 0, 0},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("0...100"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("a"),
+			},
+		}
+		r3 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0, 0, 0, 0, 0, 0},
+			Timestamps: timestampsExpected,
+		}
+		r3.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("0...100"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("b"),
+			},
+		}
+		r4 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{2, 2, 2, 2, 2, 2},
+			Timestamps: timestampsExpected,
+		}
+		r4.MetricName.Tags = []storage.Tag{
+			{
+				Key:   []byte("vmrange"),
+				Value: []byte("100...+Inf"),
+			},
+			{
+				Key:   []byte("x"),
+				Value: []byte("b"),
+			},
+		}
+		resultExpected := []netstorage.Result{r1, r2, r3, r4}
+		f(q, resultExpected)
+	})
+	t.Run(`histogram_quantile(distribution_over_time)`, func(t *testing.T) {
+		t.Parallel()
+		q := `histogram_quantile(0.5, distribution_over_time(time()[200s:100s], 800, 2000, 4))`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{950, 1250, 1400, 1550, 1850, 1850},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
 	t.Run(`sum(histogram_over_time)`, func(t *testing.T) {
 		t.Parallel()
 		q := `sum(histogram_over_time(alias(label_set(rand(0)*1.3+1.1, "foo", "bar"), "xxx")[200s:5s]))`
@@ -6229,6 +6466,94 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{r1}
 		f(q, resultExpected)
 	})
+	t.Run(`interpolate(max_gap)`, func(t *testing.T) {
+		t.Parallel()
+		q := `interpolate(time() < 1300 default time() > 1700, 600)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, 1400, 1600, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`interpolate(max_gap_exceeded)`, func(t *testing.T) {
+		t.Parallel()
+		q := `interpolate(time() < 1300 default time() > 1700, 5m)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, nan, nan, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`keep_last_value(max_gap)`, func(t *testing.T) {
+		t.Parallel()
+		q := `keep_last_value(time() < 1300 default time() > 1700, 10m)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, 1200, 1200, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`keep_last_value(max_gap_exceeded)`, func(t *testing.T) {
+		t.Parallel()
+		q := `keep_last_value(time() < 1300 default time() > 1700, 400)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, nan, nan, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`keep_next_value(max_gap)`, func(t *testing.T) {
+		t.Parallel()
+		q := `keep_next_value(time() < 1300 default time() > 1700, 600)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, 1800, 1800, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`fill_value()`, func(t *testing.T) {
+		t.Parallel()
+		q := `fill_value(time() < 1300 default time() > 1700, 0)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, 0, 0, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`fill_value(max_gap)`, func(t *testing.T) {
+		t.Parallel()
+		q := `fill_value(time() > 1500, 5, 400)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{nan, nan, nan, 1600, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
+	t.Run(`fill_value(time)`, func(t *testing.T) {
+		t.Parallel()
+		q := `fill_value(time() < 1300 default time() > 1700, -time(), 600)`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, -1400, -1600, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		resultExpected := []netstorage.Result{r1}
+		f(q, resultExpected)
+	})
 	t.Run(`distinct_over_time([500s])`, func(t *testing.T) {
 		t.Parallel()
 		q := `distinct_over_time((time() < 1700)[500s])`
@@ -7511,6 +7836,46 @@ func TestExecSuccess(t *testing.T) {
 		resultExpected := []netstorage.Result{r1, r2, r3, r4}
 		f(q, resultExpected)
 	})
+	t.Run(`rollup_candlestick(single-value)`, func(t *testing.T) {
+		t.Parallel()
+		q := `rollup_candlestick(alias(round(rand(0),0.01),"foobar")[:10s], "close")`
+		r := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{0.1, 0.04, 0.49, 0.46, 0.57, 0.92},
+			Timestamps: timestampsExpected,
+		}
+		r.MetricName.MetricGroup = []byte("foobar")
+		r.MetricName.Tags = []storage.Tag{{
+			Key:   []byte("rollup"),
+			Value: []byte("close"),
+		}}
+		resultExpected := []netstorage.Result{r}
+		f(q, resultExpected)
+	})
+	t.Run(`max(rollup_candlestick(multi-value)) by (rollup)`, func(t *testing.T) {
+		t.Parallel()
+		q := `sort(max(rollup_candlestick(time()[:10s], ("low", "high"))) by (rollup))`
+		r1 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1000, 1200, 1400, 1600, 1800, 2000},
+			Timestamps: timestampsExpected,
+		}
+		r1.MetricName.Tags = []storage.Tag{{
+			Key:   []byte("rollup"),
+			Value: []byte("low"),
+		}}
+		r2 := netstorage.Result{
+			MetricName: metricNameExpected,
+			Values:     []float64{1190, 1390, 1590, 1790, 1990, 2190},
+			Timestamps: timestampsExpected,
+		}
+		r2.MetricName.Tags = []storage.Tag{{
+			Key:   []byte("rollup"),
+			Value: []byte("high"),
+		}}
+		resultExpected := []netstorage.Result{r1, r2}
+		f(q, resultExpected)
+	})
 	t.Run(`rollup_increase()`, func(t *testing.T) {
 		t.Parallel()
 		q := `sort(rollup_increase(time()))`
@@ -8337,6 +8702,91 @@ func TestExecSuccess(t *testing.T) {
 	})
 }
 
+func TestExecMaxLookbehindWindow(t *testing.T) {
+	f := func(q string, maxWindow int64, valuesExpected []float64) {
+		t.Helper()
+		ec := &EvalConfig{
+			Start:               1000e3,
+			End:                 2000e3,
+			Step:                200e3,
+			MaxPointsPerSeries:  1e4,
+			MaxSeries:           1000,
+			Deadline:            searchutils.NewDeadline(time.Now(), time.Minute, ""),
+			RoundDigits:         100,
+			MaxLookbehindWindow: maxWindow,
+		}
+		result, err := Exec(nil, ec, q, false)
+		if err != nil {
+			t.Fatalf("unexpected error when executing %q: %s", q, err)
+		}
+		if len(result) != 1 {
+			t.Fatalf("unexpected number of series returned from %q; got %d; want 1", q, len(result))
+		}
+		if !reflect.DeepEqual(result[0].Values, valuesExpected) {
+			t.Fatalf("unexpected values for %q with maxWindow=%d; got %v; want %v", q, maxWindow, result[0].Values, valuesExpected)
+		}
+	}
+	f(`count_over_time(time()[600s:100s])`, 0, []float64{6, 6, 6, 6, 6, 6})
+	f(`count_over_time(time()[600s:100s])`, 300e3, []float64{3, 3, 3, 3, 3, 3})
+	f(`count_over_time(time()[600s:100s])`, 1000e3, []float64{6, 6, 6, 6, 6, 6})
+}
+
+func TestExecTimeRangeIndependence(t *testing.T) {
+	f := func(q string) {
+		t.Helper()
+		execQuery := func(start, end int64) map[string]map[int64]float64 {
+			t.Helper()
+			ec := &EvalConfig{
+				Start:              start,
+				End:                end,
+				Step:               200e3,
+				MaxPointsPerSeries: 1e4,
+				MaxSeries:          1000,
+				Deadline:           searchutils.NewDeadline(time.Now(), time.Minute, ""),
+				RoundDigits:        100,
+			}
+			result, err := Exec(nil, ec, q, false)
+			if err != nil {
+				t.Fatalf("unexpected error when executing %q: %s", q, err)
+			}
+			m := make(map[string]map[int64]float64)
+			for _, r := range result {
+				points := make(map[int64]float64)
+				for i, timestamp := range r.Timestamps {
+					if !math.IsNaN(r.Values[i]) {
+						points[timestamp] = r.Values[i]
+					}
+				}
+				m[r.MetricName.String()] = points
+			}
+			return m
+		}
+		// Shift the time range and verify that the results for the common points remain the same.
+		m1 := execQuery(1000e3, 2000e3)
+		m2 := execQuery(1400e3, 2400e3)
+		keys := make(map[string]struct{})
+		for k := range m1 {
+			keys[k] = struct{}{}
+		}
+		for k := range m2 {
+			keys[k] = struct{}{}
+		}
+		for k := range keys {
+			for timestamp := int64(1400e3); timestamp <= 2000e3; timestamp += 200e3 {
+				v1, ok1 := m1[k][timestamp]
+				v2, ok2 := m2[k][timestamp]
+				if ok1 != ok2 || v1 != v2 {
+					t.Fatalf("unexpected value for %s at %d after shifting the time range for %q; got %v (exists=%v); want %v (exists=%v)",
+						k, timestamp, q, v2, ok2, v1, ok1)
+				}
+			}
+		}
+	}
+	f(`distribution_over_time(label_set(time()/100, "foo", "bar")[400s:100s], 5, 25, 2)`)
+	f(`distribution_over_time(label_set(time()/100, "foo", "bar")[400s:100s], 10, 20, 4)`)
+	f(`histogram_over_time(label_set(time()/100, "foo", "bar")[400s:100s])`)
+}
+
 func TestExecError(t *testing.T) {
 	f := func(q string) {
 		t.Helper()
@@ -8383,6 +8833,10 @@ func TestExecError(t *testing.T) {
 	f(`range_quantile()`)
 	f(`range_quantile(1, 2, 3)`)
 	f(`range_median()`)
+	f(`count_values_over_time()`)
+	f(`distribution_over_time(time()[1m], 4)`)
+	f(`distribution_over_time(time()[1m], 0, 10, 0)`)
+	f(`distribution_over_time(time()[1m], 10, 0, 4)`)
 	f(`abs()`)
 	f(`abs(1,2)`)
 	f(`absent(1, 2)`)
@@ -8472,6 +8926,12 @@ func TestExecError(t *testing.T) {
 	f(`keep_last_value()`)
 	f(`keep_next_value()`)
 	f(`interpolate()`)
+	f(`interpolate(1, 2, 3)`)
+	f(`interpolate(1, -5)`)
+	f(`keep_last_value(1, 0)`)
+	f(`fill_value()`)
+	f(`fill_value(1)`)
+	f(`fill_value(1, 2, 3, 4)`)
 	f(`distinct_over_time()`)
 	f(`distinct()`)
 	f(`alias()`)
@@ -8485,6 +8945,11 @@ func TestExecError(t *testing.T) {
 	f(`sum(aggr_over_time())`)
 	f(`sum(aggr_over_time(foo))`)
 	f(`count(aggr_over_time("foo", bar, 1))`)
+	f(`rollup_candlestick()`)
+	f(`rollup_candlestick(time(), "foo")`)
+	f(`rollup_candlestick(time(), 1)`)
+	f(`rollup_candlestick(time(), ())`)
+	f(`rollup_candlestick(time(), "open", 1)`)
 	f(`hoeffding_bound_lower()`)
 	f(`hoeffding_bound_lower(1)`)
 	f(`hoeffding_bound_lower(0.99, foo, 1)`)
@@ -8604,6 +9069,12 @@ func TestExecError(t *testing.T) {
 	f(`sum(foo) by (1)`)
 	f(`count(foo) without ("bar")`)
 
+	// Invalid SLO functions
+	f(`slo_burn_rate(1, 0.99)`)
+	f(`slo_burn_rate(1, 2, 1h)`)
+	f(`error_budget_remaining(1, 0.99, "1h")`)
+	f(`slo_burn_rate_alert(1, 0.99, 14.4, 1h)`)
+
 	// With expressions
 	f(`ttf()`)
 	f(`ttf(1, 2)`)
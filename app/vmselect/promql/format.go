@@ -0,0 +1,137 @@
+package promql
+
+import (
+	"strconv"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// maxFormattedLineLen is the maximum length of a line in the query formatted by FormatQuery.
+//
+// Longer expressions are split into multiple lines.
+const maxFormattedLineLen = 100
+
+// formatIndent is the indentation used for nested expressions by FormatQuery.
+const formatIndent = "  "
+
+// FormatQuery returns normalized and pretty-printed MetricsQL query q.
+//
+// Expressions exceeding maxFormattedLineLen are split into multiple indented lines
+// in the same way as Prometheus does. MetricsQL extensions such as `keep_metric_names`
+// and `limit N` are preserved, while WITH templates are expanded.
+func FormatQuery(q string) (string, error) {
+	e, err := metricsql.Parse(q)
+	if err != nil {
+		return "", err
+	}
+	return string(appendFormattedExpr(nil, e, 0)), nil
+}
+
+// appendFormattedExpr appends formatted e to dst, assuming the current line is already indented at the given level.
+func appendFormattedExpr(dst []byte, e metricsql.Expr, level int) []byte {
+	s := e.AppendString(nil)
+	if level*len(formatIndent)+len(s) <= maxFormattedLineLen {
+		return append(dst, s...)
+	}
+	switch t := e.(type) {
+	case *metricsql.BinaryOpExpr:
+		dst = appendFormattedBinaryOpArg(dst, t.Left, level)
+		dst = appendFormatNewline(dst, level)
+		dst = append(dst, t.Op...)
+		if t.Bool {
+			dst = append(dst, " bool"...)
+		}
+		if t.GroupModifier.Op != "" {
+			dst = append(dst, ' ')
+			dst = t.GroupModifier.AppendString(dst)
+		}
+		if t.JoinModifier.Op != "" {
+			dst = append(dst, ' ')
+			dst = t.JoinModifier.AppendString(dst)
+		}
+		dst = appendFormatNewline(dst, level)
+		dst = appendFormattedBinaryOpArg(dst, t.Right, level)
+		return dst
+	case *metricsql.FuncExpr:
+		dst = append(dst, t.Name...)
+		dst = appendFormattedArgs(dst, t.Args, level)
+		if t.KeepMetricNames {
+			dst = append(dst, " keep_metric_names"...)
+		}
+		return dst
+	case *metricsql.AggrFuncExpr:
+		dst = append(dst, t.Name...)
+		dst = appendFormattedArgs(dst, t.Args, level)
+		if t.Modifier.Op != "" {
+			dst = append(dst, ' ')
+			dst = t.Modifier.AppendString(dst)
+		}
+		if t.Limit > 0 {
+			dst = append(dst, " limit "...)
+			dst = strconv.AppendInt(dst, int64(t.Limit), 10)
+		}
+		return dst
+	case *metricsql.RollupExpr:
+		// The suffix contains the window, step, offset and @ modifier, which are always printed on a single line.
+		n := len(t.Expr.AppendString(nil))
+		if rollupArgNeedsParens(t.Expr) {
+			n += 2
+			dst = appendFormattedParens(dst, t.Expr, level)
+		} else {
+			dst = appendFormattedExpr(dst, t.Expr, level)
+		}
+		return append(dst, s[n:]...)
+	default:
+		return append(dst, s...)
+	}
+}
+
+func appendFormattedBinaryOpArg(dst []byte, e metricsql.Expr, level int) []byte {
+	if _, ok := e.(*metricsql.BinaryOpExpr); ok {
+		return appendFormattedParens(dst, e, level)
+	}
+	return appendFormattedExpr(dst, e, level)
+}
+
+func appendFormattedParens(dst []byte, e metricsql.Expr, level int) []byte {
+	dst = append(dst, '(')
+	dst = appendFormatNewline(dst, level+1)
+	dst = appendFormattedExpr(dst, e, level+1)
+	dst = appendFormatNewline(dst, level)
+	return append(dst, ')')
+}
+
+func appendFormattedArgs(dst []byte, args []metricsql.Expr, level int) []byte {
+	dst = append(dst, '(')
+	for i, arg := range args {
+		dst = appendFormatNewline(dst, level+1)
+		dst = appendFormattedExpr(dst, arg, level+1)
+		if i+1 < len(args) {
+			dst = append(dst, ',')
+		}
+	}
+	dst = appendFormatNewline(dst, level)
+	return append(dst, ')')
+}
+
+func appendFormatNewline(dst []byte, level int) []byte {
+	dst = append(dst, '\n')
+	for i := 0; i < level; i++ {
+		dst = append(dst, formatIndent...)
+	}
+	return dst
+}
+
+// rollupArgNeedsParens returns true if e is wrapped into parens when used as an arg for metricsql.RollupExpr.
+//
+// It must be in sync with metricsql.RollupExpr.AppendString.
+func rollupArgNeedsParens(e metricsql.Expr) bool {
+	switch t := e.(type) {
+	case *metricsql.RollupExpr, *metricsql.BinaryOpExpr:
+		return true
+	case *metricsql.AggrFuncExpr:
+		return t.Modifier.Op != ""
+	default:
+		return false
+	}
+}
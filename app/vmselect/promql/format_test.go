@@ -0,0 +1,80 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestFormatQuerySuccess(t *testing.T) {
+	f := func(q, resultExpected string) {
+		t.Helper()
+		result, err := FormatQuery(q)
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", q, err)
+		}
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q;\ngot\n%s\nwant\n%s", q, result, resultExpected)
+		}
+		// The formatted query must be parsed into the same expression.
+		e1, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("cannot parse the original query %q: %s", q, err)
+		}
+		e2, err := metricsql.Parse(result)
+		if err != nil {
+			t.Fatalf("cannot parse the formatted query %q: %s", result, err)
+		}
+		if s1, s2 := e1.AppendString(nil), e2.AppendString(nil); string(s1) != string(s2) {
+			t.Fatalf("the formatted query differs from the original query;\ngot\n%s\nwant\n%s", s2, s1)
+		}
+	}
+
+	// Short queries are normalized into a single line
+	f(`sum(  rate(foo{bar="baz"}[5m]) ) BY (job)`, `sum(rate(foo{bar="baz"}[5m])) by (job)`)
+	f(`((foo)) + on(a) group_left(b) bar`, `foo + on (a) group_left (b) bar`)
+	f(`with (x = foo{a="b"}) x + x`, `foo{a="b"} + foo{a="b"}`)
+
+	// MetricsQL extensions are preserved
+	f(`topk_max(3, sum(rate(foo)) by (job) limit 10)`, `topk_max(3, sum(rate(foo)) by (job) limit 10)`)
+	f(`abs(foo) keep_metric_names`, `abs(foo) keep_metric_names`)
+
+	// Long queries are split into multiple lines
+	f(`sum(rate(http_requests_total{job="api-server",handler="/api/v1/query_range",code=~"5.."}[5m])) by (job, handler) / sum(rate(http_requests_total{job="api-server"}[5m])) by (job, handler)`,
+		`sum(
+  rate(http_requests_total{job="api-server", handler="/api/v1/query_range", code=~"5.."}[5m])
+) by (job, handler)
+/
+sum(rate(http_requests_total{job="api-server"}[5m])) by (job, handler)`)
+	f(`histogram_quantile(0.99, sum(rate(http_request_duration_seconds_bucket{job="api-server",handler="/api/v1/query"}[5m])) by (le)) > bool on (job) group_left vector(1)`,
+		`histogram_quantile(
+  0.99,
+  sum(
+    rate(http_request_duration_seconds_bucket{job="api-server", handler="/api/v1/query"}[5m])
+  ) by (le)
+)
+> bool on (job) group_left ()
+vector(1)`)
+	f(`max_over_time((rate(very_long_metric_name_for_testing_query_formatting{instance="localhost:8428"}[5m]) * 100)[1h:1m] offset 1d) keep_metric_names`,
+		`max_over_time(
+  (
+    rate(very_long_metric_name_for_testing_query_formatting{instance="localhost:8428"}[5m]) * 100
+  )[1h:1m] offset 1d
+) keep_metric_names`)
+}
+
+func TestFormatQueryFailure(t *testing.T) {
+	f := func(q string) {
+		t.Helper()
+		result, err := FormatQuery(q)
+		if err == nil {
+			t.Fatalf("expecting non-nil error for %q", q)
+		}
+		if result != "" {
+			t.Fatalf("expecting empty result for %q; got %q", q, result)
+		}
+	}
+	f(``)
+	f(`sum(foo`)
+	f(`foo{bar=}`)
+}
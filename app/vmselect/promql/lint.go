@@ -0,0 +1,154 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// LintIssue is an issue found in MetricsQL query by LintQuery.
+type LintIssue struct {
+	// Severity is either `error` for queries, which cannot be executed, or `warning` for suspicious constructs.
+	Severity string `json:"severity"`
+
+	// Message is human-readable description of the issue.
+	Message string `json:"message"`
+
+	// Expr is the part of the query with the issue.
+	Expr string `json:"expr,omitempty"`
+}
+
+// LintQuery performs static analysis of MetricsQL query q.
+//
+// It returns the found issues and metric selectors from q, which can be checked against the index by the caller.
+func LintQuery(q string) ([]LintIssue, []*metricsql.MetricExpr) {
+	e, err := metricsql.Parse(q)
+	if err == nil {
+		e, err = expandSLOFuncs(e)
+	}
+	if err != nil {
+		return []LintIssue{{
+			Severity: "error",
+			Message:  fmt.Sprintf("cannot parse query: %s", err),
+		}}, nil
+	}
+	var issues []LintIssue
+	addIssue := func(severity string, expr metricsql.Expr, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{
+			Severity: severity,
+			Message:  fmt.Sprintf(format, args...),
+			Expr:     string(expr.AppendString(nil)),
+		})
+	}
+	var mes []*metricsql.MetricExpr
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		switch t := expr.(type) {
+		case *metricsql.FuncExpr:
+			if getRollupFunc(t.Name) == nil && getTransformFunc(t.Name) == nil {
+				addIssue("error", t, "unknown function %q", t.Name)
+				return
+			}
+			lintRollupFunc(t, addIssue)
+		case *metricsql.AggrFuncExpr:
+			if getAggrFunc(t.Name) == nil {
+				addIssue("error", t, "unknown aggregate function %q", t.Name)
+			}
+		case *metricsql.MetricExpr:
+			if len(t.LabelFilters) == 0 {
+				return
+			}
+			if label, ok := getConflictingLabelFilters(t.LabelFilters); ok {
+				addIssue("warning", t, "the selector never matches series, since it contains conflicting filters on %q label", label)
+				return
+			}
+			mes = append(mes, t)
+		}
+	})
+	return issues, mes
+}
+
+// counterRollupFuncs contains rollup functions, which expect counters.
+var counterRollupFuncs = map[string]bool{
+	"increase":            true,
+	"increase_prometheus": true,
+	"increase_pure":       true,
+	"irate":               true,
+	"rate":                true,
+	"resets":              true,
+}
+
+// gaugeRollupFuncs contains rollup functions, which expect gauges.
+var gaugeRollupFuncs = map[string]bool{
+	"delta":          true,
+	"deriv":          true,
+	"idelta":         true,
+	"predict_linear": true,
+}
+
+func lintRollupFunc(fe *metricsql.FuncExpr, addIssue func(severity string, expr metricsql.Expr, format string, args ...interface{})) {
+	idx := getRollupArgIdx(fe)
+	if idx < 0 || idx >= len(fe.Args) {
+		return
+	}
+	arg := fe.Args[idx]
+	if re, ok := arg.(*metricsql.RollupExpr); ok {
+		if re.Window == nil && re.Step == nil {
+			addIssue("warning", fe, "missing lookbehind window in square brackets for %s(); the window is set automatically to the step between points on the graph, "+
+				"so the result depends on the graph resolution", fe.Name)
+		}
+		arg = re.Expr
+	} else if _, ok := arg.(*metricsql.MetricExpr); ok {
+		addIssue("warning", fe, "missing lookbehind window in square brackets for %s(); the window is set automatically to the step between points on the graph, "+
+			"so the result depends on the graph resolution", fe.Name)
+	}
+	me, ok := arg.(*metricsql.MetricExpr)
+	if !ok {
+		return
+	}
+	metricName := getMetricNameFromLabelFilters(me.LabelFilters)
+	if metricName == "" {
+		return
+	}
+	funcName := strings.ToLower(fe.Name)
+	isCounter := isCounterMetricName(metricName)
+	if counterRollupFuncs[funcName] && !isCounter {
+		addIssue("warning", fe, "%s() must be applied to counters, while %q looks like a gauge; counters usually have _total, _count, _sum or _bucket suffix", fe.Name, metricName)
+	}
+	if gaugeRollupFuncs[funcName] && isCounter {
+		addIssue("warning", fe, "%s() must be applied to gauges, while %q looks like a counter; use rate() or increase() for counters", fe.Name, metricName)
+	}
+}
+
+func isCounterMetricName(metricName string) bool {
+	for _, suffix := range []string{"_total", "_count", "_sum", "_bucket"} {
+		if strings.HasSuffix(metricName, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func getMetricNameFromLabelFilters(lfs []metricsql.LabelFilter) string {
+	for _, lf := range lfs {
+		if lf.Label == "__name__" && !lf.IsNegative && !lf.IsRegexp {
+			return lf.Value
+		}
+	}
+	return ""
+}
+
+// getConflictingLabelFilters returns the label with multiple equality filters with distinct values in lfs.
+func getConflictingLabelFilters(lfs []metricsql.LabelFilter) (string, bool) {
+	m := make(map[string]string, len(lfs))
+	for _, lf := range lfs {
+		if lf.IsNegative || lf.IsRegexp {
+			continue
+		}
+		if v, ok := m[lf.Label]; ok && v != lf.Value {
+			return lf.Label, true
+		}
+		m[lf.Label] = lf.Value
+	}
+	return "", false
+}
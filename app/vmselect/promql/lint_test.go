@@ -0,0 +1,72 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLintQuery(t *testing.T) {
+	f := func(q string, issuesExpected []LintIssue, selectorsExpected []string) {
+		t.Helper()
+		issues, mes := LintQuery(q)
+		if !reflect.DeepEqual(issues, issuesExpected) {
+			t.Fatalf("unexpected issues for %q;\ngot\n%+v\nwant\n%+v", q, issues, issuesExpected)
+		}
+		var selectors []string
+		for _, me := range mes {
+			selectors = append(selectors, string(me.AppendString(nil)))
+		}
+		if !reflect.DeepEqual(selectors, selectorsExpected) {
+			t.Fatalf("unexpected selectors for %q;\ngot\n%q\nwant\n%q", q, selectors, selectorsExpected)
+		}
+	}
+
+	// Valid queries
+	f(`sum(rate(http_requests_total[5m])) by (job)`, nil, []string{`http_requests_total`})
+	f(`deriv(node_memory_free_bytes[1h]) / 2`, nil, []string{`node_memory_free_bytes`})
+	f(`1 + 2`, nil, nil)
+
+	// Lookbehind window is set by SLO functions
+	f(`slo_burn_rate(sum(rate(errors_total)) / sum(rate(requests_total)), 0.999, 1h)`, nil, []string{`errors_total`, `requests_total`})
+
+	// Parse error
+	f(`sum(foo`, []LintIssue{{
+		Severity: "error",
+		Message:  `cannot parse query: argList: unexpected token ""; want ",", ")"; unparsed data: ""`,
+	}}, nil)
+
+	// Unknown function
+	f(`foobar(baz)`, []LintIssue{{
+		Severity: "error",
+		Message:  `unknown function "foobar"`,
+		Expr:     `foobar(baz)`,
+	}}, []string{`baz`})
+
+	// Missing lookbehind window
+	f(`increase(errors_total)`, []LintIssue{{
+		Severity: "warning",
+		Message:  "missing lookbehind window in square brackets for increase(); the window is set automatically to the step between points on the graph, so the result depends on the graph resolution",
+		Expr:     `increase(errors_total)`,
+	}}, []string{`errors_total`})
+
+	// rate over gauge
+	f(`rate(node_memory_free_bytes[5m])`, []LintIssue{{
+		Severity: "warning",
+		Message:  `rate() must be applied to counters, while "node_memory_free_bytes" looks like a gauge; counters usually have _total, _count, _sum or _bucket suffix`,
+		Expr:     `rate(node_memory_free_bytes[5m])`,
+	}}, []string{`node_memory_free_bytes`})
+
+	// deriv over counter
+	f(`deriv(http_requests_total[5m])`, []LintIssue{{
+		Severity: "warning",
+		Message:  `deriv() must be applied to gauges, while "http_requests_total" looks like a counter; use rate() or increase() for counters`,
+		Expr:     `deriv(http_requests_total[5m])`,
+	}}, []string{`http_requests_total`})
+
+	// Conflicting label filters
+	f(`foo{job="a",job="b"}`, []LintIssue{{
+		Severity: "warning",
+		Message:  `the selector never matches series, since it contains conflicting filters on "job" label`,
+		Expr:     `foo{job="a", job="b"}`,
+	}}, nil)
+}
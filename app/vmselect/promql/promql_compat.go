@@ -0,0 +1,59 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// promqlCompatDefaultLookbackDelta is the default lookback delta in milliseconds for instant vector selectors in PromQL compatibility mode.
+//
+// It is equivalent to the default value for -query.lookback-delta in Prometheus.
+const promqlCompatDefaultLookbackDelta = 5 * 60 * 1000
+
+// promqlCompatRollupFuncs maps MetricsQL rollup functions to functions with Prometheus-compatible behavior.
+//
+// The MetricsQL functions take into account the last raw sample before the lookbehind window,
+// while Prometheus functions take into account only raw samples on the lookbehind window.
+var promqlCompatRollupFuncs = map[string]string{
+	"changes":  "changes_prometheus",
+	"delta":    "delta_prometheus",
+	"increase": "increase_prometheus",
+}
+
+// promqlCompatRollupFuncsKeepMetricName contains rollup functions, which don't drop metric name in Prometheus.
+var promqlCompatRollupFuncsKeepMetricName = map[string]bool{
+	"default_rollup": true,
+	"last_over_time": true,
+}
+
+// adjustExprForPromQLCompat returns a copy of e with MetricsQL rollup functions substituted with Prometheus-compatible functions.
+//
+// e isn't modified, since it may be shared via parse cache.
+func adjustExprForPromQLCompat(e metricsql.Expr) (metricsql.Expr, error) {
+	q := string(e.AppendString(nil))
+	eCopy, err := metricsql.Parse(q)
+	if err != nil {
+		return nil, fmt.Errorf("BUG: cannot parse %q: %w", q, err)
+	}
+	metricsql.VisitAll(eCopy, func(expr metricsql.Expr) {
+		fe, ok := expr.(*metricsql.FuncExpr)
+		if !ok {
+			return
+		}
+		if name, ok := promqlCompatRollupFuncs[strings.ToLower(fe.Name)]; ok {
+			fe.Name = name
+		}
+	})
+	return eCopy, nil
+}
+
+// rollupFuncKeepsMetricName returns true if the given rollup function doesn't drop metric name.
+func rollupFuncKeepsMetricName(funcName string, promqlCompat bool) bool {
+	funcName = strings.ToLower(funcName)
+	if promqlCompat {
+		return promqlCompatRollupFuncsKeepMetricName[funcName]
+	}
+	return rollupFuncsKeepMetricName[funcName]
+}
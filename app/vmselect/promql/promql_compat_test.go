@@ -0,0 +1,55 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestAdjustExprForPromQLCompat(t *testing.T) {
+	f := func(q, resultExpected string) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", q, err)
+		}
+		qOrig := string(e.AppendString(nil))
+		eCompat, err := adjustExprForPromQLCompat(e)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result := string(eCompat.AppendString(nil))
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q;\ngot\n%s\nwant\n%s", q, result, resultExpected)
+		}
+		// The original expression mustn't be modified, since it may be shared via parse cache.
+		if s := string(e.AppendString(nil)); s != qOrig {
+			t.Fatalf("the original expression has been modified;\ngot\n%s\nwant\n%s", s, qOrig)
+		}
+	}
+	f(`foo`, `foo`)
+	f(`rate(foo[5m])`, `rate(foo[5m])`)
+	f(`increase(foo[5m])`, `increase_prometheus(foo[5m])`)
+	f(`sum(INCREASE(foo[1h])) by (job)`, `sum(increase_prometheus(foo[1h])) by (job)`)
+	f(`delta(foo[5m]) + changes(bar[5m])`, `delta_prometheus(foo[5m]) + changes_prometheus(bar[5m])`)
+	f(`max_over_time(increase(foo[5m])[1h:1m])`, `max_over_time(increase_prometheus(foo[5m])[1h:1m])`)
+}
+
+func TestRollupFuncKeepsMetricName(t *testing.T) {
+	f := func(funcName string, promqlCompat, resultExpected bool) {
+		t.Helper()
+		result := rollupFuncKeepsMetricName(funcName, promqlCompat)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %s(), promqlCompat=%v; got %v; want %v", funcName, promqlCompat, result, resultExpected)
+		}
+	}
+	f("default_rollup", false, true)
+	f("default_rollup", true, true)
+	f("last_over_time", false, true)
+	f("LAST_OVER_TIME", true, true)
+	f("max_over_time", false, true)
+	f("max_over_time", true, false)
+	f("first_over_time", true, false)
+	f("rate", false, false)
+	f("rate", true, false)
+}
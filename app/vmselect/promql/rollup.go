@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"math"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -30,6 +31,7 @@ var rollupFuncs = map[string]newRollupFunc{
 	"count_le_over_time":      newRollupCountLE,
 	"count_ne_over_time":      newRollupCountNE,
 	"count_over_time":         newRollupFuncOneArg(rollupCount),
+	"count_values_over_time":  newRollupCountValues,
 	"decreases_over_time":     newRollupFuncOneArg(rollupDecreases),
 	"default_rollup":          newRollupFuncOneArg(rollupDefault), // default rollup func
 	"delta":                   newRollupFuncOneArg(rollupDelta),
@@ -38,6 +40,7 @@ var rollupFuncs = map[string]newRollupFunc{
 	"deriv_fast":              newRollupFuncOneArg(rollupDerivFast),
 	"descent_over_time":       newRollupFuncOneArg(rollupDescentOverTime),
 	"distinct_over_time":      newRollupFuncOneArg(rollupDistinct),
+	"distribution_over_time":  newRollupDistribution,
 	"duration_over_time":      newRollupDurationOverTime,
 	"first_over_time":         newRollupFuncOneArg(rollupFirst),
 	"geomean_over_time":       newRollupFuncOneArg(rollupGeomean),
@@ -69,7 +72,7 @@ var rollupFuncs = map[string]newRollupFunc{
 	"rate_over_sum":           newRollupFuncOneArg(rollupRateOverSum),
 	"resets":                  newRollupFuncOneArg(rollupResets),
 	"rollup":                  newRollupFuncOneArg(rollupFake),
-	"rollup_candlestick":      newRollupFuncOneArg(rollupFake),
+	"rollup_candlestick":      newRollupCandlestick,
 	"rollup_delta":            newRollupFuncOneArg(rollupFake),
 	"rollup_deriv":            newRollupFuncOneArg(rollupFake),
 	"rollup_increase":         newRollupFuncOneArg(rollupFake), // + rollupFuncsRemoveCounterResets
@@ -282,8 +285,69 @@ func getRollupAggrFuncNames(expr metricsql.Expr) ([]string, error) {
 	return aggrFuncNames, nil
 }
 
+// rollupCandlestickFuncs contains values, which can be returned by rollup_candlestick.
+var rollupCandlestickFuncs = map[string]rollupFunc{
+	"open":  rollupOpen,
+	"close": rollupClose,
+	"low":   rollupLow,
+	"high":  rollupHigh,
+}
+
+// getRollupCandlestickValues returns the list of values to return from rollup_candlestick expr.
+//
+// All the values are returned if the optional second arg isn't passed to rollup_candlestick.
+// Otherwise only the values enumerated in the second arg are returned, e.g. `rollup_candlestick(m[d], ("open", "close"))`.
+func getRollupCandlestickValues(expr metricsql.Expr) ([]string, error) {
+	afe, ok := expr.(*metricsql.AggrFuncExpr)
+	if ok {
+		// This is for incremental aggregate function case:
+		//
+		//     sum(rollup_candlestick(...))
+		//
+		// See aggr_incremental.go for details.
+		expr = afe.Args[0]
+	}
+	fe, ok := expr.(*metricsql.FuncExpr)
+	if !ok {
+		logger.Panicf("BUG: unexpected expression; want metricsql.FuncExpr; got %T; value: %s", expr, expr.AppendString(nil))
+	}
+	if fe.Name != "rollup_candlestick" {
+		logger.Panicf("BUG: unexpected function name: %q; want `rollup_candlestick`", fe.Name)
+	}
+	if len(fe.Args) < 2 {
+		return []string{"open", "close", "low", "high"}, nil
+	}
+	arg := fe.Args[1]
+	var tagValues []string
+	if se, ok := arg.(*metricsql.StringExpr); ok {
+		tagValues = append(tagValues, se.S)
+	} else {
+		fe, ok := arg.(*metricsql.FuncExpr)
+		if !ok || fe.Name != "" {
+			return nil, fmt.Errorf("%s cannot be passed to rollup_candlestick(); expecting quoted value name or a list of quoted value names",
+				arg.AppendString(nil))
+		}
+		for _, e := range fe.Args {
+			se, ok := e.(*metricsql.StringExpr)
+			if !ok {
+				return nil, fmt.Errorf("%s cannot be passed here; expecting quoted value name", e.AppendString(nil))
+			}
+			tagValues = append(tagValues, se.S)
+		}
+	}
+	if len(tagValues) == 0 {
+		return nil, fmt.Errorf("rollup_candlestick() must contain at least a single value name")
+	}
+	for _, s := range tagValues {
+		if rollupCandlestickFuncs[s] == nil {
+			return nil, fmt.Errorf("%q cannot be used in `rollup_candlestick` function; expecting one of `open`, `close`, `low` or `high`", s)
+		}
+	}
+	return tagValues, nil
+}
+
 func getRollupConfigs(funcName string, rf rollupFunc, expr metricsql.Expr, start, end, step int64, maxPointsPerSeries int,
-	window, lookbackDelta int64, sharedTimestamps []int64) (
+	window, lookbackDelta int64, promqlCompat bool, sharedTimestamps []int64) (
 	func(values []float64, timestamps []int64), []*rollupConfig, error) {
 	preFunc := func(values []float64, timestamps []int64) {}
 	funcName = strings.ToLower(funcName)
@@ -304,8 +368,9 @@ func getRollupConfigs(funcName string, rf rollupFunc, expr metricsql.Expr, start
 
 			MaxPointsPerSeries: maxPointsPerSeries,
 
-			MayAdjustWindow:       rollupFuncsCanAdjustWindow[funcName],
+			MayAdjustWindow:       rollupFuncsCanAdjustWindow[funcName] && !promqlCompat,
 			LookbackDelta:         lookbackDelta,
+			PromQLCompat:          promqlCompat,
 			Timestamps:            sharedTimestamps,
 			isDefaultRollup:       funcName == "default_rollup",
 			samplesScannedPerCall: samplesScannedPerCall,
@@ -336,10 +401,13 @@ func getRollupConfigs(funcName string, rf rollupFunc, expr metricsql.Expr, start
 		}
 		rcs = appendRollupConfigs(rcs)
 	case "rollup_candlestick":
-		rcs = append(rcs, newRollupConfig(rollupOpen, "open"))
-		rcs = append(rcs, newRollupConfig(rollupClose, "close"))
-		rcs = append(rcs, newRollupConfig(rollupLow, "low"))
-		rcs = append(rcs, newRollupConfig(rollupHigh, "high"))
+		tagValues, err := getRollupCandlestickValues(expr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid args to %s: %w", expr.AppendString(nil), err)
+		}
+		for _, tagValue := range tagValues {
+			rcs = append(rcs, newRollupConfig(rollupCandlestickFuncs[tagValue], tagValue))
+		}
 	case "rollup_scrape_interval":
 		preFuncPrev := preFunc
 		preFunc = func(values []float64, timestamps []int64) {
@@ -384,6 +452,20 @@ func getRollupFunc(funcName string) newRollupFunc {
 	return rollupFuncs[funcName]
 }
 
+// getRollupArgIdx returns the index of the arg for fe, which must contain the rollup expression.
+//
+// It extends metricsql.GetRollupArgIdx with rollup functions, which aren't known to metricsql yet.
+// -1 is returned if fe isn't a rollup function.
+func getRollupArgIdx(fe *metricsql.FuncExpr) int {
+	switch strings.ToLower(fe.Name) {
+	case "count_values_over_time":
+		return 1
+	case "distribution_over_time":
+		return 0
+	}
+	return metricsql.GetRollupArgIdx(fe)
+}
+
 type rollupFuncArg struct {
 	// The value preceding values if it fits staleness interval.
 	prevValue float64
@@ -456,6 +538,9 @@ type rollupConfig struct {
 	// LoookbackDelta is the analog to `-query.lookback-delta` from Prometheus world.
 	LookbackDelta int64
 
+	// Whether the rollup is evaluated in PromQL compatibility mode. See EvalConfig.PromQLCompat.
+	PromQLCompat bool
+
 	// Whether default_rollup is used.
 	isDefaultRollup bool
 
@@ -492,7 +577,7 @@ type timeseriesMap struct {
 func newTimeseriesMap(funcName string, keepMetricNames bool, sharedTimestamps []int64, mnSrc *storage.MetricName) *timeseriesMap {
 	funcName = strings.ToLower(funcName)
 	switch funcName {
-	case "histogram_over_time", "quantiles_over_time":
+	case "count_values_over_time", "distribution_over_time", "histogram_over_time", "quantiles_over_time":
 	default:
 		return nil
 	}
@@ -503,7 +588,7 @@ func newTimeseriesMap(funcName string, keepMetricNames bool, sharedTimestamps []
 	}
 	var origin timeseries
 	origin.MetricName.CopyFrom(mnSrc)
-	if !keepMetricNames && !rollupFuncsKeepMetricName[funcName] {
+	if !keepMetricNames {
 		origin.MetricName.ResetMetricGroup()
 	}
 	origin.Timestamps = sharedTimestamps
@@ -602,6 +687,14 @@ func (rc *rollupConfig) doInternal(dstValues []float64, tsm *timeseriesMap, valu
 			// according to https://github.com/VictoriaMetrics/VictoriaMetrics/issues/784
 			window = rc.LookbackDelta
 		}
+		if rc.isDefaultRollup && rc.PromQLCompat {
+			// Prometheus selects the last raw sample on the lookback delta window for instant vector selectors
+			// independently of the step and the interval between raw samples.
+			window = rc.LookbackDelta
+			if window <= 0 {
+				window = promqlCompatDefaultLookbackDelta
+			}
+		}
 	}
 	rfa := getRollupFuncArg()
 	rfa.idx = 0
@@ -766,8 +859,21 @@ func removeCounterResets(values []float64) {
 	if len(values) == 0 {
 		return
 	}
-	var correction float64
+	// Fast path: most counters have no resets, so search for the first reset
+	// without writing to values. This loop is cheaper than the loop below.
 	prevValue := values[0]
+	n := 0
+	for n < len(values) && values[n] >= prevValue {
+		prevValue = values[n]
+		n++
+	}
+	if n == len(values) {
+		return
+	}
+
+	// Slow path: correct values starting from the first counter reset.
+	var correction float64
+	values = values[n:]
 	for i, v := range values {
 		d := v - prevValue
 		if d < 0 {
@@ -836,6 +942,15 @@ func newRollupFuncOneArg(rf rollupFunc) newRollupFunc {
 	}
 }
 
+func newRollupCandlestick(args []interface{}) (rollupFunc, error) {
+	if len(args) != 1 {
+		if err := expectRollupArgsNum(args, 2); err != nil {
+			return nil, err
+		}
+	}
+	return rollupFake, nil
+}
+
 func newRollupFuncTwoArgs(rf rollupFunc) newRollupFunc {
 	return func(args []interface{}) (rollupFunc, error) {
 		if err := expectRollupArgsNum(args, 2); err != nil {
@@ -1251,6 +1366,160 @@ func rollupHistogram(rfa *rollupFuncArg) float64 {
 	return nan
 }
 
+func newRollupCountValues(args []interface{}) (rollupFunc, error) {
+	if err := expectRollupArgsNum(args, 2); err != nil {
+		return nil, err
+	}
+	tssLabel, ok := args[0].([]*timeseries)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for label arg: %T; want string", args[0])
+	}
+	labelName, err := getString(tssLabel, 0)
+	if err != nil {
+		return nil, err
+	}
+	rf := func(rfa *rollupFuncArg) float64 {
+		// There is no need in handling NaNs here, since they must be cleaned up
+		// before calling rollup funcs.
+		idx := rfa.idx
+		tsm := rfa.tsm
+		for _, v := range rfa.values {
+			labelValue := strconv.FormatFloat(v, 'f', -1, 64)
+			ts := tsm.GetOrCreateTimeseries(labelName, labelValue)
+			count := ts.Values[idx]
+			if math.IsNaN(count) {
+				count = 0
+			}
+			ts.Values[idx] = count + 1
+		}
+		return nan
+	}
+	return rf, nil
+}
+
+// maxDistributionBuckets is the maximum number of buckets, which can be passed to distribution_over_time.
+const maxDistributionBuckets = 1000
+
+func newRollupDistribution(args []interface{}) (rollupFunc, error) {
+	if err := expectRollupArgsNum(args, 4); err != nil {
+		return nil, err
+	}
+	minValue, err := getDistributionArg(args[1], 1, "min")
+	if err != nil {
+		return nil, err
+	}
+	maxValue, err := getDistributionArg(args[2], 2, "max")
+	if err != nil {
+		return nil, err
+	}
+	if minValue >= maxValue {
+		return nil, fmt.Errorf("min must be smaller than max; got min=%g, max=%g", minValue, maxValue)
+	}
+	bucketsArg, err := getDistributionArg(args[3], 3, "buckets")
+	if err != nil {
+		return nil, err
+	}
+	bucketsCount := int(bucketsArg)
+	if bucketsCount < 1 || bucketsCount > maxDistributionBuckets {
+		return nil, fmt.Errorf("the number of buckets must be in the range [1...%d]; got %g", maxDistributionBuckets, bucketsArg)
+	}
+	db := newDistributionBuckets(minValue, maxValue, bucketsCount)
+	rf := func(rfa *rollupFuncArg) float64 {
+		// There is no need in handling NaNs here, since they must be cleaned up
+		// before calling rollup funcs.
+		values := rfa.values
+		if len(values) == 0 {
+			return nan
+		}
+		tsm := rfa.tsm
+		idx := rfa.idx
+		for _, vmrange := range db.vmranges {
+			ts := tsm.GetOrCreateTimeseries("vmrange", vmrange)
+			ts.Values[idx] = 0
+		}
+		for _, v := range values {
+			ts := tsm.GetOrCreateTimeseries("vmrange", db.getVMRange(v))
+			count := ts.Values[idx]
+			if math.IsNaN(count) {
+				count = 0
+			}
+			ts.Values[idx] = count + 1
+		}
+		return nan
+	}
+	return rf, nil
+}
+
+func getDistributionArg(arg interface{}, argNum int, argName string) (float64, error) {
+	a, err := getScalar(arg, argNum)
+	if err != nil {
+		return 0, err
+	}
+	if len(a) == 0 || math.IsNaN(a[0]) {
+		return 0, fmt.Errorf("%s arg must be a number", argName)
+	}
+	return a[0], nil
+}
+
+// distributionBuckets contains buckets of equal width for distribution_over_time.
+//
+// The buckets depend only on the function args, so the results don't depend on the selected time range.
+// This allows caching and splitting queries with distribution_over_time.
+type distributionBuckets struct {
+	vmranges    []string
+	lowerBound  float64
+	upperBound  float64
+	bucketWidth float64
+
+	// underflowVMRange is used for values smaller than lowerBound.
+	underflowVMRange string
+
+	// overflowVMRange is used for values bigger than upperBound.
+	overflowVMRange string
+}
+
+// newDistributionBuckets returns bucketsCount buckets of equal width between minValue and maxValue.
+func newDistributionBuckets(minValue, maxValue float64, bucketsCount int) *distributionBuckets {
+	vmranges := make([]string, bucketsCount)
+	start := formatDistributionBound(minValue)
+	for i := range vmranges {
+		upperBound := maxValue
+		if i+1 < bucketsCount {
+			upperBound = minValue + (maxValue-minValue)*float64(i+1)/float64(bucketsCount)
+		}
+		end := formatDistributionBound(upperBound)
+		vmranges[i] = start + "..." + end
+		start = end
+	}
+	return &distributionBuckets{
+		vmranges:         vmranges,
+		lowerBound:       minValue,
+		upperBound:       maxValue,
+		bucketWidth:      (maxValue - minValue) / float64(bucketsCount),
+		underflowVMRange: "-Inf..." + formatDistributionBound(minValue),
+		overflowVMRange:  formatDistributionBound(maxValue) + "...+Inf",
+	}
+}
+
+func (db *distributionBuckets) getVMRange(v float64) string {
+	if v < db.lowerBound {
+		return db.underflowVMRange
+	}
+	if v > db.upperBound {
+		return db.overflowVMRange
+	}
+	n := int((v - db.lowerBound) / db.bucketWidth)
+	if n >= len(db.vmranges) {
+		// v equals to upperBound.
+		n = len(db.vmranges) - 1
+	}
+	return db.vmranges[n]
+}
+
+func formatDistributionBound(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
 func rollupAvg(rfa *rollupFuncArg) float64 {
 	// Do not use `Rapid calculation methods` at https://en.wikipedia.org/wiki/Standard_deviation,
 	// since it is slower and has no significant benefits in precision.
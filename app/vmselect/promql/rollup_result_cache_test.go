@@ -288,6 +288,27 @@ func TestRollupResultCache(t *testing.T) {
 		testTimeseriesEqual(t, tss, tssExpected)
 	})
 
+	// Results for distinct extra_lookbehind mustn't be mixed
+	t.Run("extra-lookbehind", func(t *testing.T) {
+		ResetRollupResultCache()
+		tss := []*timeseries{
+			{
+				Timestamps: []int64{1000, 1200},
+				Values:     []float64{1, 2},
+			},
+		}
+		rollupResultCacheV.Put(nil, ec, fe, window, tss)
+		ecExtra := copyEvalConfig(ec)
+		ecExtra.ExtraLookbehind = 3600e3
+		tss, newStart := rollupResultCacheV.Get(nil, ecExtra, fe, window)
+		if newStart != ec.Start {
+			t.Fatalf("unexpected newStart; got %d; want %d", newStart, ec.Start)
+		}
+		if len(tss) != 0 {
+			t.Fatalf("got %d timeseries, while expecting zero", len(tss))
+		}
+	})
+
 }
 
 func TestMergeTimeseries(t *testing.T) {
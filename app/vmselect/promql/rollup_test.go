@@ -107,6 +107,19 @@ func TestRemoveCounterResets(t *testing.T) {
 	valuesExpected = []float64{100, 100, 125, 125, 145, 195}
 	timestampsExpected = []int64{0, 1, 2, 3, 4, 5}
 	testRowsEqual(t, values, timestampsExpected, valuesExpected, timestampsExpected)
+
+	// verify counter without resets
+	values = []float64{1, 2, 2, 3, 10}
+	removeCounterResets(values)
+	valuesExpected = []float64{1, 2, 2, 3, 10}
+	timestampsExpected = []int64{0, 1, 2, 3, 4}
+	testRowsEqual(t, values, timestampsExpected, valuesExpected, timestampsExpected)
+
+	// verify counter reset at the last value
+	values = []float64{1, 2, 3, 4, 1}
+	removeCounterResets(values)
+	valuesExpected = []float64{1, 2, 3, 4, 5}
+	testRowsEqual(t, values, timestampsExpected, valuesExpected, timestampsExpected)
 }
 
 func TestDeltaValues(t *testing.T) {
@@ -555,6 +568,8 @@ func TestRollupNewRollupFuncError(t *testing.T) {
 	f("predict_linear", nil)
 	f("quantile_over_time", nil)
 	f("quantiles_over_time", nil)
+	f("count_values_over_time", nil)
+	f("distribution_over_time", nil)
 
 	// Invalid arg type
 	scalarTs := []*timeseries{{
@@ -569,6 +584,29 @@ func TestRollupNewRollupFuncError(t *testing.T) {
 	f("predict_linear", []interface{}{me, 123})
 	f("quantile_over_time", []interface{}{123, 123})
 	f("quantiles_over_time", []interface{}{123, 123})
+	f("count_values_over_time", []interface{}{123, me})
+	f("distribution_over_time", []interface{}{me, me, me, me})
+
+	// Invalid number of buckets
+	zeroTs := []*timeseries{{
+		Values:     []float64{0},
+		Timestamps: []int64{123},
+	}}
+	f("distribution_over_time", []interface{}{me, zeroTs, scalarTs, zeroTs})
+	tooBigTs := []*timeseries{{
+		Values:     []float64{maxDistributionBuckets + 1},
+		Timestamps: []int64{123},
+	}}
+	f("distribution_over_time", []interface{}{me, zeroTs, scalarTs, tooBigTs})
+
+	// Invalid min and max
+	f("distribution_over_time", []interface{}{me, scalarTs, scalarTs, scalarTs})
+	f("distribution_over_time", []interface{}{me, scalarTs, zeroTs, scalarTs})
+	nanTs := []*timeseries{{
+		Values:     []float64{nan},
+		Timestamps: []int64{123},
+	}}
+	f("distribution_over_time", []interface{}{me, nanTs, scalarTs, scalarTs})
 }
 
 func TestRollupNoWindowNoPoints(t *testing.T) {
@@ -610,6 +648,33 @@ func TestRollupNoWindowNoPoints(t *testing.T) {
 	})
 }
 
+func TestRollupDefaultPromQLCompat(t *testing.T) {
+	f := func(lookbackDelta int64, valuesExpected []float64) {
+		t.Helper()
+		rc := rollupConfig{
+			Func:               rollupDefault,
+			Start:              0,
+			End:                200,
+			Step:               40,
+			Window:             0,
+			MaxPointsPerSeries: 1e4,
+			LookbackDelta:      lookbackDelta,
+			PromQLCompat:       true,
+			isDefaultRollup:    true,
+		}
+		rc.Timestamps = rc.getTimestamps()
+		values, _ := rc.Do(nil, testValues, testTimestamps)
+		timestampsExpected := []int64{0, 40, 80, 120, 160, 200}
+		testRowsEqual(t, values, rc.Timestamps, valuesExpected, timestampsExpected)
+	}
+
+	// The last sample on the lookback delta window must be selected
+	f(30, []float64{nan, 21, 12, 34, nan, nan})
+
+	// The default lookback delta must be used
+	f(0, []float64{nan, 21, 12, 34, 34, 34})
+}
+
 func TestRollupWindowNoPoints(t *testing.T) {
 	t.Run("beforeStart", func(t *testing.T) {
 		rc := rollupConfig{
@@ -1455,3 +1520,26 @@ func TestRollupDelta(t *testing.T) {
 	f(1, nan, nan, nil, 0)
 	f(100, nan, nan, nil, 0)
 }
+
+func TestGetRollupArgIdx(t *testing.T) {
+	f := func(q string, idxExpected int) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", q, err)
+		}
+		fe, ok := e.(*metricsql.FuncExpr)
+		if !ok {
+			t.Fatalf("expecting function expression for %q; got %T", q, e)
+		}
+		idx := getRollupArgIdx(fe)
+		if idx != idxExpected {
+			t.Fatalf("unexpected rollup arg index for %q; got %d; want %d", q, idx, idxExpected)
+		}
+	}
+	f("rate(foo[5m])", 0)
+	f("quantile_over_time(0.5, foo[5m])", 1)
+	f(`count_values_over_time("value", foo[5m])`, 1)
+	f("distribution_over_time(foo[5m])", 0)
+	f("abs(foo)", -1)
+}
@@ -24,6 +24,82 @@ func BenchmarkRollupAvg(b *testing.B) {
 	})
 }
 
+func BenchmarkRollupQuantile(b *testing.B) {
+	phis := []*timeseries{{
+		Values: []float64{0.9},
+	}}
+	rf, err := newRollupQuantile([]interface{}{phis, nil})
+	if err != nil {
+		b.Fatalf("unexpected error: %s", err)
+	}
+	rfa := &rollupFuncArg{
+		values: benchValues,
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchValues)))
+	b.RunParallel(func(pb *testing.PB) {
+		var vSum float64
+		for pb.Next() {
+			vSum += rf(rfa)
+		}
+		SinkLock.Lock()
+		Sink += vSum
+		SinkLock.Unlock()
+	})
+}
+
+func BenchmarkRemoveCounterResets(b *testing.B) {
+	counterValues := make([]float64, len(benchValues))
+	var v float64
+	for i, d := range benchValues {
+		v += d
+		counterValues[i] = v
+	}
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(counterValues)))
+	b.RunParallel(func(pb *testing.PB) {
+		values := make([]float64, len(counterValues))
+		for pb.Next() {
+			copy(values, counterValues)
+			removeCounterResets(values)
+		}
+	})
+}
+
+func BenchmarkRollupRate(b *testing.B) {
+	counterValues := make([]float64, len(benchValues))
+	timestamps := make([]int64, len(benchValues))
+	var v float64
+	for i, d := range benchValues {
+		v += d
+		counterValues[i] = v
+		timestamps[i] = int64(i) * 10e3
+	}
+	rc := rollupConfig{
+		Func:               rollupDerivFast,
+		Start:              timestamps[0],
+		End:                timestamps[len(timestamps)-1],
+		Step:               60e3,
+		Window:             300e3,
+		MaxPointsPerSeries: 1e4,
+	}
+	rc.Timestamps = rc.getTimestamps()
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(counterValues)))
+	b.RunParallel(func(pb *testing.PB) {
+		values := make([]float64, len(counterValues))
+		var dstValues []float64
+		for pb.Next() {
+			copy(values, counterValues)
+			removeCounterResets(values)
+			dstValues, _ = rc.Do(dstValues[:0], values, timestamps)
+		}
+	})
+}
+
 var (
 	// Sink is a global sink for benchmarks.
 	// It guarantees the compiler doesn't remove the code in benchmarks,
@@ -0,0 +1,185 @@
+package promql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+// sloFuncs contains helper functions for SLO math.
+//
+// These functions are expanded into ordinary MetricsQL expressions by expandSLOFuncs before the evaluation.
+var sloFuncs = map[string]bool{
+	"error_budget_remaining": true,
+	"slo_burn_rate":          true,
+	"slo_burn_rate_alert":    true,
+}
+
+func isSLOFunc(funcName string) bool {
+	return sloFuncs[strings.ToLower(funcName)]
+}
+
+// expandSLOFuncs expands SLO helper functions in e into ordinary MetricsQL expressions.
+//
+// See https://docs.victoriametrics.com/MetricsQL.html#slo_burn_rate
+func expandSLOFuncs(e metricsql.Expr) (metricsql.Expr, error) {
+	var firstErr error
+	expand := func(arg metricsql.Expr) metricsql.Expr {
+		if firstErr != nil {
+			return arg
+		}
+		fe, ok := arg.(*metricsql.FuncExpr)
+		if !ok || !isSLOFunc(fe.Name) {
+			return arg
+		}
+		eExpanded, err := expandSLOFunc(fe)
+		if err != nil {
+			firstErr = err
+			return arg
+		}
+		return eExpanded
+	}
+	// VisitAll visits children before their parents, so SLO functions are expanded starting from the innermost ones.
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		switch t := expr.(type) {
+		case *metricsql.FuncExpr:
+			for i, arg := range t.Args {
+				t.Args[i] = expand(arg)
+			}
+		case *metricsql.AggrFuncExpr:
+			for i, arg := range t.Args {
+				t.Args[i] = expand(arg)
+			}
+		case *metricsql.BinaryOpExpr:
+			t.Left = expand(t.Left)
+			t.Right = expand(t.Right)
+		case *metricsql.RollupExpr:
+			t.Expr = expand(t.Expr)
+		}
+	})
+	e = expand(e)
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return e, nil
+}
+
+func expandSLOFunc(fe *metricsql.FuncExpr) (metricsql.Expr, error) {
+	funcName := strings.ToLower(fe.Name)
+	args := fe.Args
+	var s string
+	switch funcName {
+	case "slo_burn_rate", "error_budget_remaining":
+		if len(args) != 3 {
+			return nil, fmt.Errorf(`%s() expects 3 args: sli, objective and window; got %d args`, funcName, len(args))
+		}
+		burnRate, err := getSLOBurnRateString(funcName, args[0], args[1], args[2])
+		if err != nil {
+			return nil, err
+		}
+		s = burnRate
+		if funcName == "error_budget_remaining" {
+			s = "1 - (" + burnRate + ")"
+		}
+	case "slo_burn_rate_alert":
+		if len(args) < 5 || (len(args)-2)%3 != 0 {
+			return nil, fmt.Errorf(`%s() expects sli, objective and one or more (factor, long_window, short_window) triplets; got %d args`, funcName, len(args))
+		}
+		sli := args[0]
+		objective := args[1]
+		var conditions []string
+		for i := 2; i < len(args); i += 3 {
+			factor := string(args[i].AppendString(nil))
+			burnRateLong, err := getSLOBurnRateString(funcName, sli, objective, args[i+1])
+			if err != nil {
+				return nil, err
+			}
+			burnRateShort, err := getSLOBurnRateString(funcName, sli, objective, args[i+2])
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, fmt.Sprintf("((%s) > (%s) and (%s) > (%s))", burnRateLong, factor, burnRateShort, factor))
+		}
+		s = strings.Join(conditions, " or ")
+	default:
+		return nil, fmt.Errorf("BUG: unexpected SLO function %q", fe.Name)
+	}
+	e, err := metricsql.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the expansion for %s(): %w; expansion: %s", funcName, err, s)
+	}
+	return e, nil
+}
+
+// getSLOBurnRateString returns string representation for the expression, which calculates the error budget burn rate
+// for the given sli error ratio, objective and window.
+func getSLOBurnRateString(funcName string, sli, objective, window metricsql.Expr) (string, error) {
+	de, ok := window.(*metricsql.DurationExpr)
+	if !ok {
+		return "", fmt.Errorf(`%s(): window must be a duration such as 1h; got %s`, funcName, window.AppendString(nil))
+	}
+	errorBudget := fmt.Sprintf("(1 - (%s))", objective.AppendString(nil))
+	if ne, ok := objective.(*metricsql.NumberExpr); ok {
+		if ne.N <= 0 || ne.N >= 1 {
+			return "", fmt.Errorf(`%s(): objective must be in the range (0...1); got %g`, funcName, ne.N)
+		}
+		// Round the error budget in order to avoid floating-point noise such as 1-0.999=0.0010000000000000009
+		errorBudget = strconv.FormatFloat(1-ne.N, 'g', 12, 64)
+	}
+	errorRatio, err := getSLIOnWindow(sli, de)
+	if err != nil {
+		return "", fmt.Errorf("%s(): %w", funcName, err)
+	}
+	return fmt.Sprintf("(%s) / %s", errorRatio, errorBudget), nil
+}
+
+// getSLIOnWindow returns string representation for the sli error ratio calculated over the given window.
+//
+// The window is set for all the rollup functions without explicitly set lookbehind window in square brackets.
+// For example, `sum(rate(errors_total)) / sum(rate(requests_total))` is converted
+// to `sum(rate(errors_total[window])) / sum(rate(requests_total[window]))`.
+// If sli doesn't contain such rollup functions, then it is treated as an error ratio gauge,
+// which is averaged over the window with avg_over_time((sli)[window:]).
+//
+// An error is returned if sli contains rollup functions with and without explicitly set lookbehind window,
+// since such sli would be calculated over distinct windows.
+func getSLIOnWindow(sli metricsql.Expr, window *metricsql.DurationExpr) (string, error) {
+	sli = metricsql.Clone(sli)
+	windowSet := false
+	hasExplicitWindow := false
+	metricsql.VisitAll(sli, func(expr metricsql.Expr) {
+		fe, ok := expr.(*metricsql.FuncExpr)
+		if !ok || getRollupFunc(fe.Name) == nil {
+			return
+		}
+		idx := getRollupArgIdx(fe)
+		if idx < 0 || idx >= len(fe.Args) {
+			return
+		}
+		switch t := fe.Args[idx].(type) {
+		case *metricsql.MetricExpr:
+			fe.Args[idx] = &metricsql.RollupExpr{
+				Expr:   t,
+				Window: window,
+			}
+			windowSet = true
+		case *metricsql.RollupExpr:
+			if t.Window == nil {
+				t.Window = window
+				windowSet = true
+			} else {
+				hasExplicitWindow = true
+			}
+		}
+	})
+	if windowSet && hasExplicitWindow {
+		return "", fmt.Errorf("sli mustn't mix rollup functions with and without explicitly set lookbehind window in square brackets; "+
+			"either remove all the lookbehind windows in order to calculate sli over the given window or set them all; got %s", sli.AppendString(nil))
+	}
+	if !windowSet {
+		return fmt.Sprintf("avg_over_time((%s)[%s:])", sli.AppendString(nil), window.AppendString(nil)), nil
+	}
+	return string(sli.AppendString(nil)), nil
+}
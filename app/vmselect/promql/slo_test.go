@@ -0,0 +1,59 @@
+package promql
+
+import (
+	"testing"
+
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestExpandSLOFuncsSuccess(t *testing.T) {
+	f := func(q, resultExpected string) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", q, err)
+		}
+		e, err = expandSLOFuncs(e)
+		if err != nil {
+			t.Fatalf("unexpected error when expanding %q: %s", q, err)
+		}
+		result := string(e.AppendString(nil))
+		if result != resultExpected {
+			t.Fatalf("unexpected expansion for %q;\ngot\n%s\nwant\n%s", q, result, resultExpected)
+		}
+	}
+	f(`x`, `x`)
+	f(`slo_burn_rate(sum(rate(errors_total)) / sum(rate(requests_total)), 0.999, 1h)`, `(sum(rate(errors_total[1h])) / sum(rate(requests_total[1h]))) / 0.001`)
+	f(`error_budget_remaining(rate(errors_total) / rate(requests_total), 0.99, 30d)`, `1 - ((rate(errors_total[30d]) / rate(requests_total[30d])) / 0.01)`)
+	f(`error_budget_remaining(rate(errors_total[5m]) / rate(requests_total[5m]), 0.99, 30d)`, `1 - (avg_over_time((rate(errors_total[5m]) / rate(requests_total[5m]))[30d:]) / 0.01)`)
+	f(`error_budget_remaining(error_ratio, 0.99, 30d)`, `1 - (avg_over_time(error_ratio[30d:]) / 0.01)`)
+	f(`slo_burn_rate_alert(sum(increase(errors_total)) / sum(increase(requests_total)), 0.999, 14.4, 1h, 5m, 6, 6h, 30m)`, `((((sum(increase(errors_total[1h])) / sum(increase(requests_total[1h]))) / 0.001) > 14.4) and (((sum(increase(errors_total[5m])) / sum(increase(requests_total[5m]))) / 0.001) > 14.4)) or ((((sum(increase(errors_total[6h])) / sum(increase(requests_total[6h]))) / 0.001) > 6) and (((sum(increase(errors_total[30m])) / sum(increase(requests_total[30m]))) / 0.001) > 6))`)
+	f(`sum(slo_burn_rate(error_ratio, 0.9, 1h)) by (job)`, `sum(avg_over_time(error_ratio[1h:]) / 0.1) by (job)`)
+	f(`max_over_time(slo_burn_rate(error_ratio, 0.9, 1h)[1d:])`, `max_over_time((avg_over_time(error_ratio[1h:]) / 0.1)[1d:])`)
+}
+
+func TestExpandSLOFuncsFailure(t *testing.T) {
+	f := func(q string) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", q, err)
+		}
+		if _, err := expandSLOFuncs(e); err == nil {
+			t.Fatalf("expecting non-nil error when expanding %q", q)
+		}
+	}
+	f(`slo_burn_rate()`)
+	f(`slo_burn_rate(error_ratio, 0.99)`)
+	f(`error_budget_remaining(error_ratio, 0.99, 1h, 2)`)
+	f(`slo_burn_rate(error_ratio, 0.99, foo)`)
+	f(`slo_burn_rate(error_ratio, 1.5, 1h)`)
+	f(`slo_burn_rate(error_ratio, 0, 1h)`)
+	f(`slo_burn_rate_alert(error_ratio, 0.99, 14.4, 1h)`)
+	f(`slo_burn_rate_alert(error_ratio, 0.99, 14.4, 1h, 5m, 6)`)
+	f(`sum(slo_burn_rate(error_ratio, 0.99, "1h"))`)
+
+	// Rollup functions with and without explicitly set lookbehind window
+	f(`error_budget_remaining(rate(errors_total[5m]) / rate(requests_total), 0.99, 30d)`)
+	f(`slo_burn_rate_alert(sum(increase(errors_total)) / sum(increase(requests_total[1h])), 0.999, 14.4, 1h, 5m)`)
+}
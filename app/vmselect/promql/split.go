@@ -0,0 +1,390 @@
+package promql
+
+import (
+	"flag"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/netstorage"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/queryprofiler"
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/querystats"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+var (
+	splitQueryInterval = flag.Duration("search.splitQueryInterval", 0, "Split /api/v1/query_range requests with time range exceeding the given interval "+
+		"into subranges aligned to the given interval and execute them in parallel. For example, -search.splitQueryInterval=24h splits queries into per-day subranges. "+
+		"This may reduce latency for queries over long time ranges such as 30 days. Splitting is disabled if zero. "+
+		"See also -search.maxSplitQueryConcurrency and https://docs.victoriametrics.com/#query-splitting")
+	maxSplitQueryConcurrency = flag.Int("search.maxSplitQueryConcurrency", 4, "The maximum number of subranges, which can be executed concurrently "+
+		"for a single /api/v1/query_range request when -search.splitQueryInterval is set")
+)
+
+var (
+	splitQueries   = metrics.NewCounter(`vm_split_queries_total`)
+	splitSubranges = metrics.NewCounter(`vm_split_query_subranges_total`)
+)
+
+// ExecRange executes q on the [ec.Start ... ec.End] time range with ec.Step.
+//
+// The time range is split into subranges aligned to -search.splitQueryInterval, which are executed in parallel
+// with up to -search.maxSplitQueryConcurrency concurrency. Results for subranges are merged afterwards.
+// Queries, which results depend on the whole time range, are executed without splitting.
+func ExecRange(qt *querytracer.Tracer, ec *EvalConfig, q string) ([]netstorage.Result, error) {
+	defer queryprofiler.StartQuery(q)()
+	return execRange(qt, ec, q, splitQueryInterval.Milliseconds())
+}
+
+func execRange(qt *querytracer.Tracer, ec *EvalConfig, q string, interval int64) ([]netstorage.Result, error) {
+	subranges := getSplitSubranges(ec.Start, ec.End, ec.Step, interval)
+	if len(subranges) < 2 {
+		return Exec(qt, ec, q, false)
+	}
+	e, err := parsePromQLWithCache(q)
+	if err != nil {
+		return nil, err
+	}
+	if !maySplitQuery(e) {
+		qt.Printf("do not split the query by time, since its results depend on the whole time range")
+		return Exec(qt, ec, q, false)
+	}
+
+	if querystats.Enabled() {
+		startTime := time.Now()
+		if ec.QueryStats == nil {
+			ec.QueryStats = &querystats.QueryStats{}
+		}
+		defer querystats.RegisterQuery(ec.Tenant, q, ec.End-ec.Start, startTime, ec.QueryStats)
+	}
+	splitQueries.Inc()
+	splitSubranges.Add(len(subranges))
+	qt.Printf("split the query into %d subranges by %dms interval", len(subranges), interval)
+
+	results := make([][]netstorage.Result, len(subranges))
+	errs := make([]error, len(subranges))
+	concurrency := *maxSplitQueryConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	concurrencyCh := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, sr := range subranges {
+		ecSub := copyEvalConfig(ec)
+		ecSub.Start = sr.start
+		ecSub.End = sr.end
+		qtChild := qt.NewChild("subrange %d: start=%d, end=%d", i, sr.start, sr.end)
+		wg.Add(1)
+		go func(i int) {
+			concurrencyCh <- struct{}{}
+			defer func() {
+				<-concurrencyCh
+				qtChild.Done()
+				wg.Done()
+			}()
+			results[i], errs[i] = exec(qtChild, ecSub, q, false)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	result := mergeSplitResults(results, subranges, ec.Step, getTimestamps(ec.Start, ec.End, ec.Step, ec.MaxPointsPerSeries))
+	qt.Printf("merge results for %d subranges into %d series", len(subranges), len(result))
+	return result, nil
+}
+
+type timeRange struct {
+	start int64
+	end   int64
+}
+
+// getSplitSubranges splits [start ... end] time range with the given step into subranges aligned to interval.
+//
+// Every subrange contains points located on the original step grid, so the merged results are identical to the results for the original time range.
+func getSplitSubranges(start, end, step, interval int64) []timeRange {
+	if interval <= 0 || step <= 0 || step >= interval || end-start <= interval {
+		return nil
+	}
+	var subranges []timeRange
+	for start <= end {
+		boundary := (start/interval + 1) * interval
+		// The last point before the boundary on the step grid.
+		subrangeEnd := start + ((boundary-1-start)/step)*step
+		if subrangeEnd > end {
+			subrangeEnd = end
+		}
+		subranges = append(subranges, timeRange{
+			start: start,
+			end:   subrangeEnd,
+		})
+		start = subrangeEnd + step
+	}
+	return subranges
+}
+
+// maySplitQuery returns true if e consists only of functions and operations, which are known to be safe for splitting by time.
+//
+// Results for such expressions at every point depend only on the data around this point. Expressions, which depend on the set
+// of series existing on the whole time range such as limitk(), any() or `or`, cannot be split, since their results for subranges
+// may differ from the results for the whole time range.
+func maySplitQuery(e metricsql.Expr) bool {
+	if !maySortResults(e, nil) {
+		return false
+	}
+	ok := true
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		switch t := expr.(type) {
+		case *metricsql.FuncExpr:
+			name := strings.ToLower(t.Name)
+			if !splitSafeTransformFuncs[name] && !splitSafeRollupFuncs[name] {
+				ok = false
+			}
+		case *metricsql.AggrFuncExpr:
+			if !splitSafeAggrFuncs[strings.ToLower(t.Name)] {
+				ok = false
+			}
+		case *metricsql.BinaryOpExpr:
+			if !splitSafeBinaryOps[strings.ToLower(t.Op)] {
+				ok = false
+			}
+		case *metricsql.RollupExpr:
+			// metricsql.VisitAll doesn't visit `@` modifiers such as `foo @ end()`.
+			if t.At != nil && !maySplitQuery(t.At) {
+				ok = false
+			}
+		}
+	})
+	return ok
+}
+
+// splitSafeRollupFuncs contains rollup functions, which calculate every output point only from raw samples
+// on the lookbehind window for this point.
+//
+// Rollup functions, which results depend on the selected time range, mustn't be added here.
+var splitSafeRollupFuncs = map[string]bool{
+	"absent_over_time":        true,
+	"aggr_over_time":          true,
+	"ascent_over_time":        true,
+	"avg_over_time":           true,
+	"changes":                 true,
+	"changes_prometheus":      true,
+	"count_eq_over_time":      true,
+	"count_gt_over_time":      true,
+	"count_le_over_time":      true,
+	"count_ne_over_time":      true,
+	"count_over_time":         true,
+	"count_values_over_time":  true,
+	"decreases_over_time":     true,
+	"default_rollup":          true,
+	"delta":                   true,
+	"delta_prometheus":        true,
+	"deriv":                   true,
+	"deriv_fast":              true,
+	"descent_over_time":       true,
+	"distinct_over_time":      true,
+	"distribution_over_time":  true,
+	"duration_over_time":      true,
+	"first_over_time":         true,
+	"geomean_over_time":       true,
+	"histogram_over_time":     true,
+	"hoeffding_bound_lower":   true,
+	"hoeffding_bound_upper":   true,
+	"holt_winters":            true,
+	"idelta":                  true,
+	"ideriv":                  true,
+	"increase":                true,
+	"increase_prometheus":     true,
+	"increase_pure":           true,
+	"increases_over_time":     true,
+	"integrate":               true,
+	"irate":                   true,
+	"lag":                     true,
+	"last_over_time":          true,
+	"lifetime":                true,
+	"mad_over_time":           true,
+	"max_over_time":           true,
+	"min_over_time":           true,
+	"mode_over_time":          true,
+	"predict_linear":          true,
+	"present_over_time":       true,
+	"quantile_over_time":      true,
+	"quantiles_over_time":     true,
+	"range_over_time":         true,
+	"rate":                    true,
+	"rate_over_sum":           true,
+	"resets":                  true,
+	"rollup":                  true,
+	"rollup_candlestick":      true,
+	"rollup_delta":            true,
+	"rollup_deriv":            true,
+	"rollup_increase":         true,
+	"rollup_rate":             true,
+	"rollup_scrape_interval":  true,
+	"scrape_interval":         true,
+	"share_gt_over_time":      true,
+	"share_le_over_time":      true,
+	"stale_samples_over_time": true,
+	"stddev_over_time":        true,
+	"stdvar_over_time":        true,
+	"sum_over_time":           true,
+	"sum2_over_time":          true,
+	"tfirst_over_time":        true,
+	"timestamp":               true,
+	"timestamp_with_name":     true,
+	"tlast_change_over_time":  true,
+	"tlast_over_time":         true,
+	"tmax_over_time":          true,
+	"tmin_over_time":          true,
+	"zscore_over_time":        true,
+}
+
+// splitSafeTransformFuncs contains transform functions, which calculate every output point independently of other points.
+//
+// histogram_* functions aren't safe, since they skip `vmrange` buckets with zero values on the whole time range,
+// so their results for subranges may differ from the results for the whole time range.
+var splitSafeTransformFuncs = map[string]bool{
+	"":                true, // empty func is a synonym to union
+	"abs":             true,
+	"acos":            true,
+	"acosh":           true,
+	"asin":            true,
+	"asinh":           true,
+	"atan":            true,
+	"atanh":           true,
+	"bitmap_and":      true,
+	"bitmap_or":       true,
+	"bitmap_xor":      true,
+	"ceil":            true,
+	"clamp":           true,
+	"clamp_max":       true,
+	"clamp_min":       true,
+	"cos":             true,
+	"cosh":            true,
+	"day_of_month":    true,
+	"day_of_week":     true,
+	"day_of_year":     true,
+	"days_in_month":   true,
+	"deg":             true,
+	"exp":             true,
+	"floor":           true,
+	"hour":            true,
+	"label_copy":      true,
+	"label_del":       true,
+	"label_join":      true,
+	"label_keep":      true,
+	"label_lowercase": true,
+	"label_map":       true,
+	"label_match":     true,
+	"label_mismatch":  true,
+	"label_move":      true,
+	"label_replace":   true,
+	"label_set":       true,
+	"label_transform": true,
+	"label_uppercase": true,
+	"label_value":     true,
+	"ln":              true,
+	"log10":           true,
+	"log2":            true,
+	"minute":          true,
+	"month":           true,
+	"now":             true,
+	"pi":              true,
+	"rad":             true,
+	"round":           true,
+	"sgn":             true,
+	"sin":             true,
+	"sinh":            true,
+	"sqrt":            true,
+	"step":            true,
+	"tan":             true,
+	"tanh":            true,
+	"time":            true,
+	"union":           true,
+	"vector":          true,
+	"year":            true,
+}
+
+// splitSafeAggrFuncs contains aggregate functions, which aggregate series independently at every point.
+var splitSafeAggrFuncs = map[string]bool{
+	"avg":          true,
+	"count":        true,
+	"count_values": true,
+	"distinct":     true,
+	"geomean":      true,
+	"group":        true,
+	"histogram":    true,
+	"mad":          true,
+	"max":          true,
+	"median":       true,
+	"min":          true,
+	"mode":         true,
+	"quantile":     true,
+	"quantiles":    true,
+	"share":        true,
+	"stddev":       true,
+	"stdvar":       true,
+	"sum":          true,
+	"sum2":         true,
+	"zscore":       true,
+}
+
+// splitSafeBinaryOps contains binary operations, which are calculated independently at every point.
+//
+// Operations such as `or`, `and`, `unless` and `default` aren't safe, since their results depend on the series existing on the whole time range.
+var splitSafeBinaryOps = map[string]bool{
+	"+":     true,
+	"-":     true,
+	"*":     true,
+	"/":     true,
+	"%":     true,
+	"^":     true,
+	"atan2": true,
+	"==":    true,
+	"!=":    true,
+	">":     true,
+	"<":     true,
+	">=":    true,
+	"<=":    true,
+}
+
+// mergeSplitResults merges results for the given subranges into series with the given timestamps.
+func mergeSplitResults(results [][]netstorage.Result, subranges []timeRange, step int64, timestamps []int64) []netstorage.Result {
+	m := make(map[string]int)
+	var merged []netstorage.Result
+	bb := bbPool.Get()
+	offset := 0
+	for i, rss := range results {
+		for j := range rss {
+			rs := &rss[j]
+			bb.B = marshalMetricNameSorted(bb.B[:0], &rs.MetricName)
+			idx, ok := m[string(bb.B)]
+			if !ok {
+				idx = len(merged)
+				m[string(bb.B)] = idx
+				values := make([]float64, len(timestamps))
+				for k := range values {
+					values[k] = nan
+				}
+				merged = append(merged, netstorage.Result{
+					Values:     values,
+					Timestamps: timestamps,
+				})
+				merged[idx].MetricName.MoveFrom(&rs.MetricName)
+			}
+			copy(merged[idx].Values[offset:], rs.Values)
+		}
+		sr := subranges[i]
+		offset += int(1 + (sr.end-sr.start)/step)
+	}
+	bbPool.Put(bb)
+	sort.Slice(merged, func(i, j int) bool {
+		return metricNameLess(&merged[i].MetricName, &merged[j].MetricName)
+	})
+	return merged
+}
@@ -0,0 +1,156 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/app/vmselect/searchutils"
+	"github.com/VictoriaMetrics/metricsql"
+)
+
+func TestGetSplitSubranges(t *testing.T) {
+	f := func(start, end, step, interval int64, subrangesExpected []timeRange) {
+		t.Helper()
+		subranges := getSplitSubranges(start, end, step, interval)
+		if !reflect.DeepEqual(subranges, subrangesExpected) {
+			t.Fatalf("unexpected subranges;\ngot\n%v\nwant\n%v", subranges, subrangesExpected)
+		}
+	}
+
+	// Splitting is disabled
+	f(0, 1000, 10, 0, nil)
+
+	// The time range doesn't exceed the interval
+	f(0, 100, 10, 100, nil)
+
+	// The step exceeds the interval
+	f(0, 1000, 200, 100, nil)
+
+	// Aligned time range
+	f(0, 250, 10, 100, []timeRange{
+		{start: 0, end: 90},
+		{start: 100, end: 190},
+		{start: 200, end: 250},
+	})
+
+	// Unaligned time range with the step, which isn't a divisor of the interval
+	f(55, 310, 30, 100, []timeRange{
+		{start: 55, end: 85},
+		{start: 115, end: 175},
+		{start: 205, end: 295},
+	})
+}
+
+func TestMaySplitQuery(t *testing.T) {
+	f := func(q string, resultExpected bool) {
+		t.Helper()
+		e, err := metricsql.Parse(q)
+		if err != nil {
+			t.Fatalf("cannot parse %q: %s", q, err)
+		}
+		result := maySplitQuery(e)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for maySplitQuery(%q); got %v; want %v", q, result, resultExpected)
+		}
+	}
+	f(`foo`, true)
+	f(`sum(rate(foo[5m])) by (job)`, true)
+	f(`sum(increase(foo[1h])) by (le)`, true)
+	f(`topk(3, foo)`, false)
+	f(`sort_desc(foo)`, false)
+	f(`sum(sort(foo))`, false)
+	f(`range_max(foo)`, false)
+	f(`running_sum(foo)`, false)
+	f(`topk_avg(3, foo)`, false)
+	f(`foo + keep_last_value(bar)`, false)
+	f(`foo @ end()`, false)
+
+	// Functions, which depend on series existing on the whole time range, cannot be split.
+	f(`limitk(3, foo)`, false)
+	f(`any(foo) by (job)`, false)
+	f(`limit_offset(1, 1, foo)`, false)
+	f(`drop_common_labels(foo)`, false)
+	f(`scalar(foo)`, false)
+	f(`absent(foo)`, false)
+	f(`foo or bar`, false)
+	f(`foo default 0`, false)
+	f(`foo unless bar`, false)
+
+	// Functions, which aren't known to be safe for splitting, cannot be split.
+	f(`buckets_limit(10, foo)`, false)
+	f(`rand()`, false)
+
+	// Functions calculating every point independently can be split.
+	f(`abs(foo) * 2 > bar`, true)
+	f(`label_replace(max(foo) by (job), "x", "$1", "job", "(.+)")`, true)
+	f(`quantile(0.5, max_over_time(foo[1h]))`, true)
+	f(`distribution_over_time(foo[1h], 0, 100, 10)`, true)
+	f(`count_values_over_time("x", foo[1h])`, true)
+
+	// histogram_* functions depend on vmrange buckets with zero values on the whole time range, so they cannot be split.
+	f(`histogram_quantile(0.9, sum(increase(foo[1h])) by (le))`, false)
+	f(`histogram_share(10, distribution_over_time(foo[1h], 0, 100, 10))`, false)
+
+	// Unknown functions cannot be split.
+	f(`unknown_over_time(foo[1h])`, false)
+}
+
+func TestSplitSafeRollupFuncs(t *testing.T) {
+	for name := range splitSafeRollupFuncs {
+		if getRollupFunc(name) == nil {
+			t.Fatalf("unknown rollup function %q in splitSafeRollupFuncs", name)
+		}
+	}
+}
+
+func TestExecRangeSplit(t *testing.T) {
+	f := func(q string) {
+		t.Helper()
+		newEvalConfig := func() *EvalConfig {
+			return &EvalConfig{
+				Start:              1000e3,
+				End:                2000e3,
+				Step:               30e3,
+				MaxPointsPerSeries: 1e4,
+				MaxSeries:          1000,
+				Deadline:           searchutils.NewDeadline(time.Now(), time.Minute, ""),
+				RoundDigits:        100,
+			}
+		}
+		resultExpected, err := Exec(nil, newEvalConfig(), q, false)
+		if err != nil {
+			t.Fatalf("unexpected error when executing %q: %s", q, err)
+		}
+		result, err := execRange(nil, newEvalConfig(), q, 200e3)
+		if err != nil {
+			t.Fatalf("unexpected error when executing %q with splitting: %s", q, err)
+		}
+		testResultsEqual(t, result, resultExpected)
+	}
+	f(`time()`)
+	f(`label_set(time() > 1500, "foo", "bar")`)
+	f(`union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b"))`)
+	f(`running_sum(time())`)
+
+	// Split-safe functions over series, which exist only on a part of the time range.
+	f(`abs(label_set(time() > 1500, "foo", "bar") - 2000)`)
+	f(`sum(union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b")))`)
+	f(`max(union(label_set(time() < 1300, "x", "a"), label_set(time()*2 > 2500, "x", "b"))) by (x)`)
+	f(`count(union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b"), label_set(time(), "x", "c")))`)
+	f(`quantile(0.5, union(label_set(time() < 1300, "x", "a"), label_set(time(), "x", "c")))`)
+	f(`label_replace(label_set(time() > 1500, "foo", "bar"), "baz", "$1", "foo", "(.+)")`)
+	f(`clamp_max(label_set(time() < 1700, "foo", "bar"), 1400) + 1`)
+	f(`label_set(time() > 1500, "foo", "bar") >= 1700`)
+	f(`max_over_time((time() > 1500)[100s:30s])`)
+	f(`rate(label_set(time() < 1600, "foo", "bar")[2m:30s])`)
+	f(`distribution_over_time(label_set(time()/100, "foo", "bar")[400s:100s], 5, 25, 2)`)
+	f(`distribution_over_time(label_set(time()/100, "foo", "bar")[400s:100s], 10, 20, 3)`)
+	f(`histogram_quantile(0.5, distribution_over_time(time()[200s:30s], 800, 2000, 4))`)
+
+	// Functions, which depend on series existing on the whole time range, must return the same results as without splitting.
+	f(`limitk(1, union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b")))`)
+	f(`any(union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b")))`)
+	f(`limit_offset(1, 1, union(label_set(time() < 1300, "x", "a"), label_set(time() > 1700, "x", "b"), label_set(time(), "x", "c")))`)
+	f(`label_set(time() < 1300, "x", "a") or label_set(time(), "x", "b")`)
+}
@@ -46,6 +46,7 @@ var transformFuncs = map[string]transformFunc{
 	"drop_common_labels":         transformDropCommonLabels,
 	"end":                        newTransformFuncZeroArgs(transformEnd),
 	"exp":                        newTransformFuncOneArg(transformExp),
+	"fill_value":                 transformFillValue,
 	"floor":                      newTransformFuncOneArg(transformFloor),
 	"histogram_avg":              transformHistogramAvg,
 	"histogram_quantile":         transformHistogramQuantile,
@@ -138,6 +139,7 @@ var transformFuncsKeepMetricName = map[string]bool{
 	"clamp":                   true,
 	"clamp_max":               true,
 	"clamp_min":               true,
+	"fill_value":              true,
 	"floor":                   true,
 	"interpolate":             true,
 	"keep_last_value":         true,
@@ -1117,22 +1119,30 @@ func skipTrailingNaNs(values []float64) []float64 {
 
 func transformKeepLastValue(tfa *transformFuncArg) ([]*timeseries, error) {
 	args := tfa.args
-	if err := expectTransformArgsNum(args, 1); err != nil {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf(`unexpected number of args; got %d; want 1 or 2`, len(args))
+	}
+	maxGapPoints, err := getMaxGapPoints(tfa, 1)
+	if err != nil {
 		return nil, err
 	}
 	rvs := args[0]
 	for _, ts := range rvs {
 		values := ts.Values
-		if len(values) == 0 {
-			continue
-		}
-		lastValue := values[0]
-		for i, v := range values {
-			if !math.IsNaN(v) {
-				lastValue = v
+		lastValue := nan
+		for i := 0; i < len(values); {
+			if !math.IsNaN(values[i]) {
+				lastValue = values[i]
+				i++
 				continue
 			}
-			values[i] = lastValue
+			j := getNextNonNaNIndex(values, i)
+			if j-i <= maxGapPoints {
+				for k := i; k < j; k++ {
+					values[k] = lastValue
+				}
+			}
+			i = j
 		}
 	}
 	return rvs, nil
@@ -1140,23 +1150,32 @@ func transformKeepLastValue(tfa *transformFuncArg) ([]*timeseries, error) {
 
 func transformKeepNextValue(tfa *transformFuncArg) ([]*timeseries, error) {
 	args := tfa.args
-	if err := expectTransformArgsNum(args, 1); err != nil {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf(`unexpected number of args; got %d; want 1 or 2`, len(args))
+	}
+	maxGapPoints, err := getMaxGapPoints(tfa, 1)
+	if err != nil {
 		return nil, err
 	}
 	rvs := args[0]
 	for _, ts := range rvs {
 		values := ts.Values
-		if len(values) == 0 {
-			continue
-		}
-		nextValue := values[len(values)-1]
-		for i := len(values) - 1; i >= 0; i-- {
-			v := values[i]
-			if !math.IsNaN(v) {
-				nextValue = v
+		for i := 0; i < len(values); {
+			if !math.IsNaN(values[i]) {
+				i++
 				continue
 			}
-			values[i] = nextValue
+			j := getNextNonNaNIndex(values, i)
+			if j-i <= maxGapPoints {
+				nextValue := nan
+				if j < len(values) {
+					nextValue = values[j]
+				}
+				for k := i; k < j; k++ {
+					values[k] = nextValue
+				}
+			}
+			i = j
 		}
 	}
 	return rvs, nil
@@ -1164,7 +1183,11 @@ func transformKeepNextValue(tfa *transformFuncArg) ([]*timeseries, error) {
 
 func transformInterpolate(tfa *transformFuncArg) ([]*timeseries, error) {
 	args := tfa.args
-	if err := expectTransformArgsNum(args, 1); err != nil {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf(`unexpected number of args; got %d; want 1 or 2`, len(args))
+	}
+	maxGapPoints, err := getMaxGapPoints(tfa, 1)
+	if err != nil {
 		return nil, err
 	}
 	rvs := args[0]
@@ -1182,12 +1205,11 @@ func transformInterpolate(tfa *transformFuncArg) ([]*timeseries, error) {
 			if i > 0 {
 				prevValue = values[i-1]
 			}
-			j := i + 1
-			for j < len(values) {
-				if !math.IsNaN(values[j]) {
-					break
-				}
-				j++
+			j := getNextNonNaNIndex(values, i)
+			if j-i > maxGapPoints {
+				// The gap is too big to be filled.
+				i = j
+				continue
 			}
 			if j >= len(values) {
 				nextValue = prevValue
@@ -1208,6 +1230,73 @@ func transformInterpolate(tfa *transformFuncArg) ([]*timeseries, error) {
 	return rvs, nil
 }
 
+func transformFillValue(tfa *transformFuncArg) ([]*timeseries, error) {
+	args := tfa.args
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf(`unexpected number of args; got %d; want 2 or 3`, len(args))
+	}
+	fillValues, err := getScalar(args[1], 1)
+	if err != nil {
+		return nil, err
+	}
+	maxGapPoints, err := getMaxGapPoints(tfa, 2)
+	if err != nil {
+		return nil, err
+	}
+	rvs := args[0]
+	for _, ts := range rvs {
+		values := ts.Values
+		for i := 0; i < len(values); {
+			if !math.IsNaN(values[i]) {
+				i++
+				continue
+			}
+			j := getNextNonNaNIndex(values, i)
+			if j-i <= maxGapPoints {
+				for k := i; k < j; k++ {
+					values[k] = fillValues[k]
+				}
+			}
+			i = j
+		}
+	}
+	return rvs, nil
+}
+
+// getNextNonNaNIndex returns the index of the next non-NaN value in values starting from i.
+//
+// len(values) is returned if there are no non-NaN values starting from i.
+func getNextNonNaNIndex(values []float64, i int) int {
+	for i < len(values) && math.IsNaN(values[i]) {
+		i++
+	}
+	return i
+}
+
+// getMaxGapPoints returns the maximum number of consecutive missing points, which can be filled,
+// according to the optional max_gap arg in seconds at tfa.args[argIdx].
+//
+// The gap with n missing points spans (n+1)*step between the surrounding points.
+func getMaxGapPoints(tfa *transformFuncArg, argIdx int) (int, error) {
+	args := tfa.args
+	if len(args) <= argIdx {
+		return math.MaxInt32, nil
+	}
+	maxGaps, err := getScalar(args[argIdx], argIdx)
+	if err != nil {
+		return 0, err
+	}
+	if len(maxGaps) == 0 || math.IsNaN(maxGaps[0]) {
+		return math.MaxInt32, nil
+	}
+	maxGap := maxGaps[0]
+	if maxGap <= 0 {
+		return 0, fmt.Errorf("max_gap must be positive; got %g", maxGap)
+	}
+	maxGapMsecs := int64(maxGap * 1000)
+	return int(maxGapMsecs/tfa.ec.Step) - 1, nil
+}
+
 func newTransformFuncRunning(rf func(a, b float64, idx int) float64) transformFunc {
 	return func(tfa *transformFuncArg) ([]*timeseries, error) {
 		args := tfa.args
@@ -0,0 +1,271 @@
+package queryprofiler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// cpuProfileStats contains CPU time per query fingerprint obtained from a single CPU profile.
+type cpuProfileStats struct {
+	// totalNanos is the total CPU time in nanoseconds registered in the profile.
+	totalNanos int64
+
+	// nanosByFingerprint contains CPU time in nanoseconds per query fingerprint.
+	nanosByFingerprint map[string]int64
+}
+
+// parseCPUProfile parses gzipped CPU profile in pprof format generated by runtime/pprof.
+//
+// CPU time is attributed to query fingerprints via fingerprintLabel pprof label.
+// See https://github.com/google/pprof/blob/main/proto/profile.proto for the profile format.
+func parseCPUProfile(data []byte) (*cpuProfileStats, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot open gzipped profile: %w", err)
+	}
+	src, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress profile: %w", err)
+	}
+
+	var sampleTypes []int64
+	var samples []profileSample
+	var strs []string
+	for len(src) > 0 {
+		var f protoField
+		f, src, err = nextProtoField(src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read profile field: %w", err)
+		}
+		switch f.num {
+		case 1:
+			// sample_type
+			if f.wireType != 2 {
+				return nil, fmt.Errorf("unexpected wire type for sample_type: %d", f.wireType)
+			}
+			typeIdx, err := parseValueType(f.data)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse sample_type: %w", err)
+			}
+			sampleTypes = append(sampleTypes, typeIdx)
+		case 2:
+			// sample
+			if f.wireType != 2 {
+				return nil, fmt.Errorf("unexpected wire type for sample: %d", f.wireType)
+			}
+			s, err := parseSample(f.data)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse sample: %w", err)
+			}
+			samples = append(samples, s)
+		case 6:
+			// string_table
+			if f.wireType != 2 {
+				return nil, fmt.Errorf("unexpected wire type for string_table: %d", f.wireType)
+			}
+			strs = append(strs, string(f.data))
+		}
+	}
+	getString := func(idx int64) (string, error) {
+		if idx < 0 || idx >= int64(len(strs)) {
+			return "", fmt.Errorf("string index %d is out of string table with %d items", idx, len(strs))
+		}
+		return strs[idx], nil
+	}
+
+	cpuIdx := -1
+	for i, typeIdx := range sampleTypes {
+		s, err := getString(typeIdx)
+		if err != nil {
+			return nil, err
+		}
+		if s == "cpu" {
+			cpuIdx = i
+			break
+		}
+	}
+	if cpuIdx < 0 {
+		return nil, fmt.Errorf("missing cpu sample type in the profile")
+	}
+
+	stats := &cpuProfileStats{
+		nanosByFingerprint: make(map[string]int64),
+	}
+	for _, s := range samples {
+		if cpuIdx >= len(s.values) {
+			return nil, fmt.Errorf("missing cpu value in the sample; it has only %d values", len(s.values))
+		}
+		nanos := s.values[cpuIdx]
+		stats.totalNanos += nanos
+		for _, l := range s.labels {
+			key, err := getString(l.key)
+			if err != nil {
+				return nil, err
+			}
+			if key != fingerprintLabel {
+				continue
+			}
+			fp, err := getString(l.value)
+			if err != nil {
+				return nil, err
+			}
+			stats.nanosByFingerprint[fp] += nanos
+		}
+	}
+	return stats, nil
+}
+
+type profileSample struct {
+	values []int64
+	labels []profileLabel
+}
+
+// profileLabel contains string table indexes for label key and value.
+type profileLabel struct {
+	key   int64
+	value int64
+}
+
+func parseSample(src []byte) (profileSample, error) {
+	var s profileSample
+	for len(src) > 0 {
+		var f protoField
+		var err error
+		f, src, err = nextProtoField(src)
+		if err != nil {
+			return s, err
+		}
+		switch f.num {
+		case 2:
+			// value
+			s.values, err = appendVarints(s.values, f)
+			if err != nil {
+				return s, fmt.Errorf("cannot read value: %w", err)
+			}
+		case 3:
+			// label
+			if f.wireType != 2 {
+				return s, fmt.Errorf("unexpected wire type for label: %d", f.wireType)
+			}
+			l, err := parseLabel(f.data)
+			if err != nil {
+				return s, fmt.Errorf("cannot parse label: %w", err)
+			}
+			s.labels = append(s.labels, l)
+		}
+	}
+	return s, nil
+}
+
+func parseLabel(src []byte) (profileLabel, error) {
+	var l profileLabel
+	for len(src) > 0 {
+		var f protoField
+		var err error
+		f, src, err = nextProtoField(src)
+		if err != nil {
+			return l, err
+		}
+		switch f.num {
+		case 1:
+			l.key = int64(f.varint)
+		case 2:
+			l.value = int64(f.varint)
+		}
+	}
+	return l, nil
+}
+
+func parseValueType(src []byte) (int64, error) {
+	var typeIdx int64
+	for len(src) > 0 {
+		var f protoField
+		var err error
+		f, src, err = nextProtoField(src)
+		if err != nil {
+			return 0, err
+		}
+		if f.num == 1 {
+			typeIdx = int64(f.varint)
+		}
+	}
+	return typeIdx, nil
+}
+
+// protoField is a single protobuf field.
+type protoField struct {
+	num      uint64
+	wireType uint64
+
+	// varint contains the value for varint wire type.
+	varint uint64
+
+	// data contains the value for length-delimited wire type.
+	data []byte
+}
+
+// nextProtoField reads the next protobuf field from src and returns the tail of src.
+func nextProtoField(src []byte) (protoField, []byte, error) {
+	var f protoField
+	key, n := binary.Uvarint(src)
+	if n <= 0 {
+		return f, src, fmt.Errorf("cannot read field key")
+	}
+	src = src[n:]
+	f.num = key >> 3
+	f.wireType = key & 7
+	switch f.wireType {
+	case 0:
+		v, n := binary.Uvarint(src)
+		if n <= 0 {
+			return f, src, fmt.Errorf("cannot read varint value for field #%d", f.num)
+		}
+		f.varint = v
+		src = src[n:]
+	case 1:
+		if len(src) < 8 {
+			return f, src, fmt.Errorf("cannot read fixed64 value for field #%d", f.num)
+		}
+		src = src[8:]
+	case 2:
+		size, n := binary.Uvarint(src)
+		if n <= 0 || size > uint64(len(src)-n) {
+			return f, src, fmt.Errorf("cannot read length-delimited value for field #%d", f.num)
+		}
+		src = src[n:]
+		f.data = src[:size]
+		src = src[size:]
+	case 5:
+		if len(src) < 4 {
+			return f, src, fmt.Errorf("cannot read fixed32 value for field #%d", f.num)
+		}
+		src = src[4:]
+	default:
+		return f, src, fmt.Errorf("unsupported wire type %d for field #%d", f.wireType, f.num)
+	}
+	return f, src, nil
+}
+
+// appendVarints appends varint values from f to dst. Both packed and unpacked encodings are supported.
+func appendVarints(dst []int64, f protoField) ([]int64, error) {
+	switch f.wireType {
+	case 0:
+		return append(dst, int64(f.varint)), nil
+	case 2:
+		src := f.data
+		for len(src) > 0 {
+			v, n := binary.Uvarint(src)
+			if n <= 0 {
+				return dst, fmt.Errorf("cannot read packed varint")
+			}
+			dst = append(dst, int64(v))
+			src = src[n:]
+		}
+		return dst, nil
+	default:
+		return dst, fmt.Errorf("unexpected wire type for varint: %d", f.wireType)
+	}
+}
@@ -0,0 +1,320 @@
+package queryprofiler
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/VictoriaMetrics/metricsql"
+	"github.com/cespare/xxhash/v2"
+)
+
+var (
+	profileInterval = flag.Duration("search.queryProfiler.interval", 0, "Interval between CPU profile collections for attributing CPU usage to query shapes "+
+		"at /api/v1/status/top_query_shapes. Zero value disables the query profiler. See https://docs.victoriametrics.com/#query-profiler")
+	profileDuration = flag.Duration("search.queryProfiler.duration", 10*time.Second, "The duration of every CPU profile collection for the query profiler. "+
+		"It must be smaller than -search.queryProfiler.interval. See https://docs.victoriametrics.com/#query-profiler")
+	maxAge = flag.Duration("search.queryProfiler.maxAge", time.Hour, "The maximum age of CPU profiles to keep for /api/v1/status/top_query_shapes. "+
+		"See https://docs.victoriametrics.com/#query-profiler")
+)
+
+// fingerprintLabel is pprof label for attributing CPU samples to query fingerprints.
+const fingerprintLabel = "vm_query_fingerprint"
+
+// maxTrackedQueries is the maximum number of unique queries to cache fingerprints for.
+const maxTrackedQueries = 10000
+
+var p *profiler
+
+// Init starts the query profiler if -search.queryProfiler.interval is set.
+func Init() {
+	if *profileInterval <= 0 {
+		return
+	}
+	if *profileDuration <= 0 || *profileDuration >= *profileInterval {
+		logger.Fatalf("-search.queryProfiler.duration=%s must be in the range (0 ... -search.queryProfiler.interval=%s)", *profileDuration, *profileInterval)
+	}
+	p = newProfiler()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.run(*profileInterval, *profileDuration)
+	}()
+	logger.Infof("started query profiler with -search.queryProfiler.interval=%s, -search.queryProfiler.duration=%s", *profileInterval, *profileDuration)
+}
+
+// Stop stops the query profiler.
+func Stop() {
+	if p == nil {
+		return
+	}
+	close(p.stopCh)
+	p.wg.Wait()
+	p = nil
+}
+
+// Enabled returns true if the query profiler is enabled.
+func Enabled() bool {
+	return p != nil
+}
+
+// StartQuery attributes CPU usage in the current goroutine and its child goroutines to the fingerprint of the given query.
+//
+// The returned function must be called when the query is finished in the same goroutine.
+func StartQuery(query string) func() {
+	if p == nil {
+		return func() {}
+	}
+	fp := p.registerQuery(query)
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels(fingerprintLabel, fp))
+	pprof.SetGoroutineLabels(ctx)
+	return func() {
+		pprof.SetGoroutineLabels(context.Background())
+	}
+}
+
+// WriteJSONTopQueryShapes writes topN query shapes with the highest CPU usage during the last maxAge to w.
+func WriteJSONTopQueryShapes(w io.Writer, topN int, maxAge time.Duration) error {
+	if p == nil {
+		return fmt.Errorf("query profiler is disabled; set -search.queryProfiler.interval command-line flag for enabling it")
+	}
+	p.writeJSONTopQueryShapes(w, topN, maxAge)
+	return nil
+}
+
+type profiler struct {
+	mu sync.Mutex
+
+	// profiles contains stats for the collected CPU profiles ordered by collection time.
+	profiles []*collectedProfile
+
+	// fingerprints contains query fingerprints per query hash.
+	fingerprints map[uint64]string
+
+	// shapes contains query shapes per query fingerprint.
+	shapes map[string]*queryShape
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type collectedProfile struct {
+	timestamp time.Time
+	duration  time.Duration
+	stats     *cpuProfileStats
+}
+
+type queryShape struct {
+	shape    string
+	lastSeen time.Time
+}
+
+func newProfiler() *profiler {
+	return &profiler{
+		fingerprints: make(map[uint64]string),
+		shapes:       make(map[string]*queryShape),
+		stopCh:       make(chan struct{}),
+	}
+}
+
+func (p *profiler) registerQuery(query string) string {
+	h := xxhash.Sum64String(query)
+	now := time.Now()
+
+	p.mu.Lock()
+	if fp, ok := p.fingerprints[h]; ok {
+		// The query shape may be already deleted as outdated. Then it must be registered again below.
+		if qs := p.shapes[fp]; qs != nil {
+			qs.lastSeen = now
+			p.mu.Unlock()
+			return fp
+		}
+	}
+	p.mu.Unlock()
+
+	// Obtain query shape outside the lock, since it may be slow.
+	shape := getQueryShape(query)
+	fp := fmt.Sprintf("%016x", xxhash.Sum64String(shape))
+
+	p.mu.Lock()
+	if len(p.fingerprints) >= maxTrackedQueries {
+		p.fingerprints = make(map[uint64]string)
+	}
+	p.fingerprints[h] = fp
+	if qs := p.shapes[fp]; qs != nil {
+		qs.lastSeen = now
+	} else {
+		p.shapes[fp] = &queryShape{
+			shape:    shape,
+			lastSeen: now,
+		}
+	}
+	p.mu.Unlock()
+	return fp
+}
+
+// getQueryShape returns query with label filter values replaced by placeholders.
+//
+// This allows grouping queries, which differ only by label filter values.
+func getQueryShape(query string) string {
+	e, err := metricsql.Parse(query)
+	if err != nil {
+		return query
+	}
+	metricsql.VisitAll(e, func(expr metricsql.Expr) {
+		me, ok := expr.(*metricsql.MetricExpr)
+		if !ok {
+			return
+		}
+		for i := range me.LabelFilters {
+			lf := &me.LabelFilters[i]
+			if lf.Label != "__name__" {
+				lf.Value = "?"
+			}
+		}
+	})
+	return string(e.AppendString(nil))
+}
+
+func (p *profiler) run(interval, duration time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case <-ticker.C:
+			p.collectProfile(duration)
+		}
+	}
+}
+
+func (p *profiler) collectProfile(duration time.Duration) {
+	var bb bytes.Buffer
+	if err := pprof.StartCPUProfile(&bb); err != nil {
+		// This may be the case when CPU profile is collected via /debug/pprof/profile.
+		profileErrors.Inc()
+		logger.Warnf("cannot start CPU profile for query profiler: %s", err)
+		return
+	}
+	startTime := time.Now()
+	t := time.NewTimer(duration)
+	select {
+	case <-p.stopCh:
+		t.Stop()
+	case <-t.C:
+	}
+	pprof.StopCPUProfile()
+	d := time.Since(startTime)
+
+	stats, err := parseCPUProfile(bb.Bytes())
+	if err != nil {
+		profileErrors.Inc()
+		logger.Errorf("cannot parse CPU profile for query profiler: %s", err)
+		return
+	}
+	profilesCollected.Inc()
+	p.addProfile(startTime, d, stats)
+}
+
+func (p *profiler) addProfile(timestamp time.Time, duration time.Duration, stats *cpuProfileStats) {
+	deadline := time.Now().Add(-*maxAge)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.profiles = append(p.profiles, &collectedProfile{
+		timestamp: timestamp,
+		duration:  duration,
+		stats:     stats,
+	})
+
+	// Drop outdated profiles and query shapes.
+	n := 0
+	for n < len(p.profiles) && p.profiles[n].timestamp.Before(deadline) {
+		n++
+	}
+	p.profiles = append(p.profiles[:0], p.profiles[n:]...)
+	for fp, qs := range p.shapes {
+		if qs.lastSeen.Before(deadline) {
+			delete(p.shapes, fp)
+		}
+	}
+}
+
+type queryShapeStat struct {
+	fingerprint string
+	shape       string
+	nanos       int64
+}
+
+func (p *profiler) writeJSONTopQueryShapes(w io.Writer, topN int, maxAge time.Duration) {
+	deadline := time.Now().Add(-maxAge)
+
+	p.mu.Lock()
+	var profilesCount int
+	var profiledDuration time.Duration
+	var totalNanos int64
+	m := make(map[string]int64)
+	for _, cp := range p.profiles {
+		if cp.timestamp.Before(deadline) {
+			continue
+		}
+		profilesCount++
+		profiledDuration += cp.duration
+		totalNanos += cp.stats.totalNanos
+		for fp, nanos := range cp.stats.nanosByFingerprint {
+			m[fp] += nanos
+		}
+	}
+	a := make([]queryShapeStat, 0, len(m))
+	for fp, nanos := range m {
+		var shape string
+		if qs := p.shapes[fp]; qs != nil {
+			shape = qs.shape
+		}
+		a = append(a, queryShapeStat{
+			fingerprint: fp,
+			shape:       shape,
+			nanos:       nanos,
+		})
+	}
+	p.mu.Unlock()
+
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].nanos != a[j].nanos {
+			return a[i].nanos > a[j].nanos
+		}
+		return a[i].fingerprint < a[j].fingerprint
+	})
+	if len(a) > topN {
+		a = a[:topN]
+	}
+
+	fmt.Fprintf(w, `{"topN":"%d","maxAge":%q,"profilesCount":%d,"profiledSeconds":%.3f,"totalCPUSeconds":%.3f,"topByCPU":[`,
+		topN, maxAge, profilesCount, profiledDuration.Seconds(), float64(totalNanos)/1e9)
+	for i, r := range a {
+		share := float64(0)
+		if totalNanos > 0 {
+			share = float64(r.nanos) / float64(totalNanos)
+		}
+		fmt.Fprintf(w, `{"fingerprint":%q,"query":%q,"cpuSeconds":%.3f,"cpuShare":%.4f}`, r.fingerprint, r.shape, float64(r.nanos)/1e9, share)
+		if i+1 < len(a) {
+			fmt.Fprintf(w, `,`)
+		}
+	}
+	fmt.Fprintf(w, `]}`)
+}
+
+var (
+	profilesCollected = metrics.NewCounter(`vm_query_profiler_profiles_total`)
+	profileErrors     = metrics.NewCounter(`vm_query_profiler_errors_total`)
+)
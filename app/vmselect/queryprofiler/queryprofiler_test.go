@@ -0,0 +1,128 @@
+package queryprofiler
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetQueryShape(t *testing.T) {
+	f := func(query, shapeExpected string) {
+		t.Helper()
+		shape := getQueryShape(query)
+		if shape != shapeExpected {
+			t.Fatalf("unexpected shape for %q;\ngot\n%s\nwant\n%s", query, shape, shapeExpected)
+		}
+	}
+	f(`foo`, `foo`)
+	f(`foo{job="api"}`, `foo{job="?"}`)
+	f(`sum(rate(http_requests_total{job=~"api|web",status!="200"}[5m])) by (job)`, `sum(rate(http_requests_total{job=~"?", status!="?"}[5m])) by (job)`)
+	f(`{__name__="foo",instance="host:8080"} / 2`, `foo{instance="?"} / 2`)
+
+	// Invalid query must be returned as is
+	f(`foo{`, `foo{`)
+}
+
+func TestParseCPUProfile(t *testing.T) {
+	var bb bytes.Buffer
+	if err := pprof.StartCPUProfile(&bb); err != nil {
+		t.Skipf("cannot start CPU profile: %s", err)
+	}
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels(fingerprintLabel, "foobar"))
+	pprof.Do(ctx, pprof.Labels(), func(_ context.Context) {
+		deadline := time.Now().Add(500 * time.Millisecond)
+		n := 0
+		for time.Now().Before(deadline) {
+			n++
+		}
+		if n == 0 {
+			panic("BUG: unexpected zero iterations")
+		}
+	})
+	pprof.StopCPUProfile()
+
+	stats, err := parseCPUProfile(bb.Bytes())
+	if err != nil {
+		t.Fatalf("cannot parse CPU profile: %s", err)
+	}
+	if stats.totalNanos <= 0 {
+		t.Fatalf("expecting positive total CPU time; got %d", stats.totalNanos)
+	}
+	nanos := stats.nanosByFingerprint["foobar"]
+	if nanos <= 0 {
+		t.Fatalf("expecting positive CPU time for the fingerprint; got %d", nanos)
+	}
+	if nanos > stats.totalNanos {
+		t.Fatalf("CPU time for the fingerprint cannot exceed the total CPU time; got %d vs %d", nanos, stats.totalNanos)
+	}
+}
+
+func TestParseCPUProfileFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := parseCPUProfile([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	f("")
+	f("foobar")
+}
+
+func TestProfilerTopQueryShapes(t *testing.T) {
+	p := newProfiler()
+	fpFoo := p.registerQuery(`foo{job="a"}`)
+	fpBar := p.registerQuery(`rate(bar[5m])`)
+	if fp := p.registerQuery(`foo{job="b"}`); fp != fpFoo {
+		t.Fatalf("queries with the same shape must have the same fingerprint; got %q; want %q", fp, fpFoo)
+	}
+	if fpFoo == fpBar {
+		t.Fatalf("queries with distinct shapes must have distinct fingerprints")
+	}
+
+	now := time.Now()
+	p.addProfile(now.Add(-time.Minute), 10*time.Second, &cpuProfileStats{
+		totalNanos: 4e9,
+		nanosByFingerprint: map[string]int64{
+			fpFoo: 1e9,
+			fpBar: 2e9,
+		},
+	})
+	p.addProfile(now, 10*time.Second, &cpuProfileStats{
+		totalNanos: 4e9,
+		nanosByFingerprint: map[string]int64{
+			fpFoo: 3e9,
+		},
+	})
+
+	f := func(topN int, maxAge time.Duration, resultExpected string) {
+		t.Helper()
+		var bb bytes.Buffer
+		p.writeJSONTopQueryShapes(&bb, topN, maxAge)
+		result := bb.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+	f(10, time.Hour, `{"topN":"10","maxAge":"1h0m0s","profilesCount":2,"profiledSeconds":20.000,"totalCPUSeconds":8.000,"topByCPU":[`+
+		`{"fingerprint":"`+fpFoo+`","query":"foo{job=\"?\"}","cpuSeconds":4.000,"cpuShare":0.5000},`+
+		`{"fingerprint":"`+fpBar+`","query":"rate(bar[5m])","cpuSeconds":2.000,"cpuShare":0.2500}]}`)
+	f(1, time.Hour, `{"topN":"1","maxAge":"1h0m0s","profilesCount":2,"profiledSeconds":20.000,"totalCPUSeconds":8.000,"topByCPU":[`+
+		`{"fingerprint":"`+fpFoo+`","query":"foo{job=\"?\"}","cpuSeconds":4.000,"cpuShare":0.5000}]}`)
+	f(10, 30*time.Second, `{"topN":"10","maxAge":"30s","profilesCount":1,"profiledSeconds":10.000,"totalCPUSeconds":4.000,"topByCPU":[`+
+		`{"fingerprint":"`+fpFoo+`","query":"foo{job=\"?\"}","cpuSeconds":3.000,"cpuShare":0.7500}]}`)
+}
+
+func TestStartQueryDisabled(t *testing.T) {
+	if Enabled() {
+		t.Fatalf("query profiler must be disabled by default")
+	}
+	StartQuery("foo")()
+	var bb bytes.Buffer
+	err := WriteJSONTopQueryShapes(&bb, 10, time.Hour)
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Fatalf("expecting error for disabled query profiler; got %v", err)
+	}
+}
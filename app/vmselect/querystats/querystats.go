@@ -4,17 +4,25 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math/rand"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
 )
 
 var (
 	lastQueriesCount = flag.Int("search.queryStats.lastQueriesCount", 20000, "Query stats for /api/v1/status/top_queries is tracked on this number of last queries. "+
 		"Zero value disables query stats tracking")
-	minQueryDuration = flag.Duration("search.queryStats.minQueryDuration", time.Millisecond, "The minimum duration for queries to track in query stats at /api/v1/status/top_queries. Queries with lower duration are ignored in query stats")
+	minQueryDuration        = flag.Duration("search.queryStats.minQueryDuration", time.Millisecond, "The minimum duration for queries to track in query stats at /api/v1/status/top_queries. Queries with lower duration are ignored in query stats")
+	slowQueryLogMinDuration = flag.Duration("search.slowQueryLog.minDuration", 0, "Queries with execution duration exceeding this value are logged in JSON format "+
+		"together with tenant, the number of fetched series, the number of scanned samples and the peak memory usage. Zero value disables the slow query log. "+
+		"See also -search.slowQueryLog.sampleRate and https://docs.victoriametrics.com/#slow-query-log")
+	slowQueryLogSampleRate = flag.Float64("search.slowQueryLog.sampleRate", 1, "The share of slow queries to log in the range (0..1]. "+
+		"For example, -search.slowQueryLog.sampleRate=0.1 logs every 10th slow query on average. See -search.slowQueryLog.minDuration")
 )
 
 var (
@@ -22,19 +30,106 @@ var (
 	initOnce  sync.Once
 )
 
-// Enabled returns true of query stats tracking is enabled.
+// Enabled returns true of query stats tracking or slow query log is enabled.
 func Enabled() bool {
-	return *lastQueriesCount > 0
+	return *lastQueriesCount > 0 || *slowQueryLogMinDuration > 0
 }
 
-// RegisterQuery registers the query on the given timeRangeMsecs, which has been started at startTime.
+// QueryStats contains execution stats for a single query.
 //
+// QueryStats methods may be called concurrently. They are no-op for nil QueryStats.
+type QueryStats struct {
+	seriesFetched  uint64
+	samplesScanned uint64
+	memoryBytes    int64
+	peakMemory     int64
+}
+
+// AddSeriesFetched adds n to the number of series fetched from the storage.
+func (qs *QueryStats) AddSeriesFetched(n int) {
+	if qs == nil {
+		return
+	}
+	atomic.AddUint64(&qs.seriesFetched, uint64(n))
+}
+
+// AddSamplesScanned adds n to the number of scanned raw samples.
+func (qs *QueryStats) AddSamplesScanned(n uint64) {
+	if qs == nil {
+		return
+	}
+	atomic.AddUint64(&qs.samplesScanned, n)
+}
+
+// AddMemory adds n to the memory currently allocated for the query and updates the peak memory usage.
+//
+// AddMemory must be called with negative n when the memory is released.
+func (qs *QueryStats) AddMemory(n int64) {
+	if qs == nil {
+		return
+	}
+	memoryBytes := atomic.AddInt64(&qs.memoryBytes, n)
+	for {
+		peakMemory := atomic.LoadInt64(&qs.peakMemory)
+		if memoryBytes <= peakMemory || atomic.CompareAndSwapInt64(&qs.peakMemory, peakMemory, memoryBytes) {
+			return
+		}
+	}
+}
+
+// SeriesFetched returns the number of series fetched from the storage.
+func (qs *QueryStats) SeriesFetched() uint64 {
+	return atomic.LoadUint64(&qs.seriesFetched)
+}
+
+// SamplesScanned returns the number of scanned raw samples.
+func (qs *QueryStats) SamplesScanned() uint64 {
+	return atomic.LoadUint64(&qs.samplesScanned)
+}
+
+// PeakMemory returns the peak memory usage in bytes.
+func (qs *QueryStats) PeakMemory() int64 {
+	return atomic.LoadInt64(&qs.peakMemory)
+}
+
+// RegisterQuery registers the query for the given tenant on the given timeRangeMsecs, which has been started at startTime.
+//
+// The query is logged to the slow query log if its duration exceeds -search.slowQueryLog.minDuration.
 // RegisterQuery must be called when the query is finished.
-func RegisterQuery(query string, timeRangeMsecs int64, startTime time.Time) {
+func RegisterQuery(tenant, query string, timeRangeMsecs int64, startTime time.Time, qs *QueryStats) {
+	duration := time.Since(startTime)
+	if *slowQueryLogMinDuration > 0 && duration > *slowQueryLogMinDuration {
+		logSlowQuery(tenant, query, timeRangeMsecs, duration, qs)
+	}
+	if *lastQueriesCount <= 0 {
+		return
+	}
 	initOnce.Do(initQueryStats)
-	qsTracker.registerQuery(query, timeRangeMsecs, startTime)
+	qsTracker.registerQuery(tenant, query, timeRangeMsecs, startTime)
+}
+
+func logSlowQuery(tenant, query string, timeRangeMsecs int64, duration time.Duration, qs *QueryStats) {
+	slowQueries.Inc()
+	if rate := *slowQueryLogSampleRate; rate < 1 && rand.Float64() >= rate {
+		return
+	}
+	slowQueriesLogged.Inc()
+	if qs == nil {
+		qs = &QueryStats{}
+	}
+	logger.Warnf("slow query: %s", marshalSlowQuery(tenant, query, timeRangeMsecs, duration, qs))
+}
+
+func marshalSlowQuery(tenant, query string, timeRangeMsecs int64, duration time.Duration, qs *QueryStats) string {
+	return fmt.Sprintf(`{"tenant":%q,"query":%q,"timeRangeSeconds":%d,"durationSeconds":%.3f,"seriesFetched":%d,"samplesScanned":%d,"peakMemoryBytes":%d}`,
+		tenant, query, timeRangeMsecs/1000, duration.Seconds(), qs.SeriesFetched(), qs.SamplesScanned(), qs.PeakMemory())
 }
 
+var (
+	slowQueries       = metrics.NewCounter(`vm_slow_query_log_queries_total`)
+	slowQueriesLogged = metrics.NewCounter(`vm_slow_query_log_entries_total`)
+)
+
 // WriteJSONQueryStats writes query stats to given writer in json format.
 func WriteJSONQueryStats(w io.Writer, topN int, maxLifetime time.Duration) {
 	initOnce.Do(initQueryStats)
@@ -49,6 +144,7 @@ type queryStatsTracker struct {
 }
 
 type queryStatRecord struct {
+	tenant        string
 	query         string
 	timeRangeSecs int64
 	registerTime  time.Time
@@ -56,6 +152,7 @@ type queryStatRecord struct {
 }
 
 type queryStatKey struct {
+	tenant        string
 	query         string
 	timeRangeSecs int64
 }
@@ -80,7 +177,7 @@ func (qst *queryStatsTracker) writeJSONQueryStats(w io.Writer, topN int, maxLife
 	fmt.Fprintf(w, `"topByCount":[`)
 	topByCount := qst.getTopByCount(topN, maxLifetime)
 	for i, r := range topByCount {
-		fmt.Fprintf(w, `{"query":%q,"timeRangeSeconds":%d,"count":%d}`, r.query, r.timeRangeSecs, r.count)
+		fmt.Fprintf(w, `{"tenant":%q,"query":%q,"timeRangeSeconds":%d,"count":%d}`, r.tenant, r.query, r.timeRangeSecs, r.count)
 		if i+1 < len(topByCount) {
 			fmt.Fprintf(w, `,`)
 		}
@@ -88,7 +185,7 @@ func (qst *queryStatsTracker) writeJSONQueryStats(w io.Writer, topN int, maxLife
 	fmt.Fprintf(w, `],"topByAvgDuration":[`)
 	topByAvgDuration := qst.getTopByAvgDuration(topN, maxLifetime)
 	for i, r := range topByAvgDuration {
-		fmt.Fprintf(w, `{"query":%q,"timeRangeSeconds":%d,"avgDurationSeconds":%.3f,"count":%d}`, r.query, r.timeRangeSecs, r.duration.Seconds(), r.count)
+		fmt.Fprintf(w, `{"tenant":%q,"query":%q,"timeRangeSeconds":%d,"avgDurationSeconds":%.3f,"count":%d}`, r.tenant, r.query, r.timeRangeSecs, r.duration.Seconds(), r.count)
 		if i+1 < len(topByAvgDuration) {
 			fmt.Fprintf(w, `,`)
 		}
@@ -96,15 +193,23 @@ func (qst *queryStatsTracker) writeJSONQueryStats(w io.Writer, topN int, maxLife
 	fmt.Fprintf(w, `],"topBySumDuration":[`)
 	topBySumDuration := qst.getTopBySumDuration(topN, maxLifetime)
 	for i, r := range topBySumDuration {
-		fmt.Fprintf(w, `{"query":%q,"timeRangeSeconds":%d,"sumDurationSeconds":%.3f,"count":%d}`, r.query, r.timeRangeSecs, r.duration.Seconds(), r.count)
+		fmt.Fprintf(w, `{"tenant":%q,"query":%q,"timeRangeSeconds":%d,"sumDurationSeconds":%.3f,"count":%d}`, r.tenant, r.query, r.timeRangeSecs, r.duration.Seconds(), r.count)
 		if i+1 < len(topBySumDuration) {
 			fmt.Fprintf(w, `,`)
 		}
 	}
+	fmt.Fprintf(w, `],"topTenantsBySumDuration":[`)
+	topTenants := qst.getTopTenantsBySumDuration(topN, maxLifetime)
+	for i, r := range topTenants {
+		fmt.Fprintf(w, `{"tenant":%q,"sumDurationSeconds":%.3f,"count":%d}`, r.tenant, r.duration.Seconds(), r.count)
+		if i+1 < len(topTenants) {
+			fmt.Fprintf(w, `,`)
+		}
+	}
 	fmt.Fprintf(w, `]}`)
 }
 
-func (qst *queryStatsTracker) registerQuery(query string, timeRangeMsecs int64, startTime time.Time) {
+func (qst *queryStatsTracker) registerQuery(tenant, query string, timeRangeMsecs int64, startTime time.Time) {
 	registerTime := time.Now()
 	duration := registerTime.Sub(startTime)
 	if duration < *minQueryDuration {
@@ -121,6 +226,7 @@ func (qst *queryStatsTracker) registerQuery(query string, timeRangeMsecs int64,
 	}
 	qst.nextIdx = idx + 1
 	r := &a[idx]
+	r.tenant = tenant
 	r.query = query
 	r.timeRangeSecs = timeRangeMsecs / 1000
 	r.registerTime = registerTime
@@ -136,6 +242,7 @@ func (r *queryStatRecord) matches(currentTime time.Time, maxLifetime time.Durati
 
 func (r *queryStatRecord) key() queryStatKey {
 	return queryStatKey{
+		tenant:        r.tenant,
 		query:         r.query,
 		timeRangeSecs: r.timeRangeSecs,
 	}
@@ -156,6 +263,7 @@ func (qst *queryStatsTracker) getTopByCount(topN int, maxLifetime time.Duration)
 	var a []queryStatByCount
 	for k, count := range m {
 		a = append(a, queryStatByCount{
+			tenant:        k.tenant,
 			query:         k.query,
 			timeRangeSecs: k.timeRangeSecs,
 			count:         count,
@@ -171,6 +279,7 @@ func (qst *queryStatsTracker) getTopByCount(topN int, maxLifetime time.Duration)
 }
 
 type queryStatByCount struct {
+	tenant        string
 	query         string
 	timeRangeSecs int64
 	count         int
@@ -198,6 +307,7 @@ func (qst *queryStatsTracker) getTopByAvgDuration(topN int, maxLifetime time.Dur
 	var a []queryStatByDuration
 	for k, ks := range m {
 		a = append(a, queryStatByDuration{
+			tenant:        k.tenant,
 			query:         k.query,
 			timeRangeSecs: k.timeRangeSecs,
 			duration:      ks.sum / time.Duration(ks.count),
@@ -214,6 +324,7 @@ func (qst *queryStatsTracker) getTopByAvgDuration(topN int, maxLifetime time.Dur
 }
 
 type queryStatByDuration struct {
+	tenant        string
 	query         string
 	timeRangeSecs int64
 	duration      time.Duration
@@ -242,6 +353,7 @@ func (qst *queryStatsTracker) getTopBySumDuration(topN int, maxLifetime time.Dur
 	var a []queryStatByDuration
 	for k, kd := range m {
 		a = append(a, queryStatByDuration{
+			tenant:        k.tenant,
 			query:         k.query,
 			timeRangeSecs: k.timeRangeSecs,
 			duration:      kd.sum,
@@ -256,3 +368,38 @@ func (qst *queryStatsTracker) getTopBySumDuration(topN int, maxLifetime time.Dur
 	}
 	return a
 }
+
+func (qst *queryStatsTracker) getTopTenantsBySumDuration(topN int, maxLifetime time.Duration) []queryStatByDuration {
+	currentTime := time.Now()
+	qst.mu.Lock()
+	type countDuration struct {
+		count int
+		sum   time.Duration
+	}
+	m := make(map[string]countDuration)
+	for _, r := range qst.a {
+		if r.matches(currentTime, maxLifetime) {
+			kd := m[r.tenant]
+			kd.count++
+			kd.sum += r.duration
+			m[r.tenant] = kd
+		}
+	}
+	qst.mu.Unlock()
+
+	var a []queryStatByDuration
+	for tenant, kd := range m {
+		a = append(a, queryStatByDuration{
+			tenant:   tenant,
+			duration: kd.sum,
+			count:    kd.count,
+		})
+	}
+	sort.Slice(a, func(i, j int) bool {
+		return a[i].duration > a[j].duration
+	})
+	if len(a) > topN {
+		a = a[:topN]
+	}
+	return a
+}
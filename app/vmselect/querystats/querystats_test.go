@@ -0,0 +1,80 @@
+package querystats
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestQueryStatsMemory(t *testing.T) {
+	var qs QueryStats
+	qs.AddMemory(100)
+	qs.AddMemory(200)
+	qs.AddMemory(-200)
+	qs.AddMemory(50)
+	if n := qs.PeakMemory(); n != 300 {
+		t.Fatalf("unexpected peak memory; got %d; want %d", n, 300)
+	}
+	qs.AddMemory(-150)
+	if n := qs.PeakMemory(); n != 300 {
+		t.Fatalf("unexpected peak memory after release; got %d; want %d", n, 300)
+	}
+
+	// Methods must be no-op for nil QueryStats.
+	var qsNil *QueryStats
+	qsNil.AddMemory(100)
+	qsNil.AddSeriesFetched(10)
+	qsNil.AddSamplesScanned(20)
+}
+
+func TestMarshalSlowQuery(t *testing.T) {
+	var qs QueryStats
+	qs.AddSeriesFetched(3)
+	qs.AddSeriesFetched(2)
+	qs.AddSamplesScanned(1000)
+	qs.AddMemory(4096)
+	s := marshalSlowQuery("team-a", `sum(rate(foo{bar="baz"}[5m]))`, 3600e3, 1500*time.Millisecond, &qs)
+	sExpected := `{"tenant":"team-a","query":"sum(rate(foo{bar=\"baz\"}[5m]))","timeRangeSeconds":3600,"durationSeconds":1.500,` +
+		`"seriesFetched":5,"samplesScanned":1000,"peakMemoryBytes":4096}`
+	if s != sExpected {
+		t.Fatalf("unexpected slow query log entry;\ngot\n%s\nwant\n%s", s, sExpected)
+	}
+}
+
+func TestQueryStatsTrackerPerTenant(t *testing.T) {
+	qst := &queryStatsTracker{
+		a: make([]queryStatRecord, 10),
+	}
+	startTime := time.Now().Add(-time.Second)
+	qst.registerQuery("a", "foo", 60e3, startTime)
+	qst.registerQuery("a", "foo", 60e3, startTime)
+	qst.registerQuery("b", "foo", 60e3, startTime)
+	qst.registerQuery("b", "bar", 60e3, startTime.Add(-time.Second))
+
+	topByCount := qst.getTopByCount(10, time.Minute)
+	if len(topByCount) != 3 {
+		t.Fatalf("unexpected number of entries in topByCount; got %d; want 3", len(topByCount))
+	}
+	if r := topByCount[0]; r.tenant != "a" || r.query != "foo" || r.count != 2 {
+		t.Fatalf("unexpected top entry by count: %+v", r)
+	}
+
+	topTenants := qst.getTopTenantsBySumDuration(10, time.Minute)
+	if len(topTenants) != 2 {
+		t.Fatalf("unexpected number of tenants; got %d; want 2", len(topTenants))
+	}
+	if r := topTenants[0]; r.tenant != "b" || r.count != 2 {
+		t.Fatalf("unexpected top tenant by sum duration: %+v", r)
+	}
+
+	topTenants = qst.getTopTenantsBySumDuration(1, time.Minute)
+	if len(topTenants) != 1 {
+		t.Fatalf("unexpected number of tenants for topN=1; got %d; want 1", len(topTenants))
+	}
+
+	var bb bytes.Buffer
+	qst.writeJSONQueryStats(&bb, 1, time.Minute)
+	if !bytes.Contains(bb.Bytes(), []byte(`"topTenantsBySumDuration":[{"tenant":"b",`)) {
+		t.Fatalf("missing per-tenant stats in %s", bb.String())
+	}
+}
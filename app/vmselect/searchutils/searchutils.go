@@ -1,6 +1,7 @@
 package searchutils
 
 import (
+	"crypto/subtle"
 	"flag"
 	"fmt"
 	"math"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
 	"github.com/VictoriaMetrics/metricsql"
@@ -19,8 +21,24 @@ var (
 	maxExportDuration        = flag.Duration("search.maxExportDuration", time.Hour*24*30, "The maximum duration for /api/v1/export call")
 	maxQueryDuration         = flag.Duration("search.maxQueryDuration", time.Second*30, "The maximum duration for query execution")
 	maxStatusRequestDuration = flag.Duration("search.maxStatusRequestDuration", time.Minute*5, "The maximum duration for /api/v1/status/* requests")
+	limitsOverrideAuthKey    = flag.String("search.limitsOverrideAuthKey", "", "Optional authKey, which allows raising -search.max*Series limits "+
+		"on a per-request basis via max_series query arg. The authKey must be passed via authKey query arg. "+
+		"Lower max_series values are always accepted without authKey")
+	tenantHeader = flag.String("search.tenantHeader", "", "Optional HTTP request header with tenant name. When set, requests queued because of -search.maxConcurrentRequests "+
+		"limit are executed according to per-tenant weights from -search.tenantWeights, while queries are limited according to -search.maxLookbehindWindowPerTenant. "+
+		"Requests without the header belong to the tenant with empty name. See https://docs.victoriametrics.com/#query-scheduling-weights")
 )
 
+// GetTenant returns tenant name for r from the header set via -search.tenantHeader.
+//
+// Empty string is returned if -search.tenantHeader isn't set or r doesn't contain the header.
+func GetTenant(r *http.Request) string {
+	if *tenantHeader == "" {
+		return ""
+	}
+	return r.Header.Get(*tenantHeader)
+}
+
 func roundToSeconds(ms int64) int64 {
 	return ms - ms%1000
 }
@@ -193,6 +211,32 @@ func GetMaxQueryDuration(r *http.Request) time.Duration {
 	return d
 }
 
+// GetMaxSeries returns the maximum number of time series, which can be selected by the request r.
+//
+// The defaultLimit can be lowered via `max_series` query arg. It can be raised via `max_series` query arg
+// only if the request contains `authKey` query arg matching -search.limitsOverrideAuthKey.
+func GetMaxSeries(r *http.Request, defaultLimit int, flagHint string) (int, error) {
+	n, err := GetInt(r, "max_series")
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 || n == defaultLimit {
+		return defaultLimit, nil
+	}
+	if n < defaultLimit {
+		return n, nil
+	}
+	authKey := r.FormValue("authKey")
+	if len(*limitsOverrideAuthKey) == 0 || subtle.ConstantTimeCompare([]byte(authKey), []byte(*limitsOverrideAuthKey)) != 1 {
+		return 0, &httpserver.ErrorWithStatusCode{
+			Err: fmt.Errorf("max_series=%d exceeds %s=%d; pass authKey matching -search.limitsOverrideAuthKey in order to raise the limit",
+				n, flagHint, defaultLimit),
+			StatusCode: http.StatusForbidden,
+		}
+	}
+	return n, nil
+}
+
 // GetDeadlineForQuery returns deadline for the given query r.
 func GetDeadlineForQuery(r *http.Request, startTime time.Time) Deadline {
 	dMax := maxQueryDuration.Milliseconds()
@@ -322,6 +366,59 @@ func GetExtraTagFilters(r *http.Request) ([][]storage.TagFilter, error) {
 	return etfs, nil
 }
 
+// LabelsFilter removes labels from the returned series according to `drop_labels` and `keep_labels` query args.
+type LabelsFilter struct {
+	dropLabels []string
+	keepLabels []string
+}
+
+// GetLabelsFilter returns LabelsFilter for `drop_labels` and `keep_labels` query args from r.
+//
+// Every query arg may contain comma-separated list of label names and may be passed multiple times.
+// Metric name is always kept for `keep_labels`. It may be dropped by passing `__name__` to `drop_labels`.
+//
+// nil is returned if both query args are missing.
+func GetLabelsFilter(r *http.Request) (*LabelsFilter, error) {
+	dropLabels := getLabelNames(r, "drop_labels")
+	keepLabels := getLabelNames(r, "keep_labels")
+	if len(dropLabels) == 0 && len(keepLabels) == 0 {
+		return nil, nil
+	}
+	if len(dropLabels) > 0 && len(keepLabels) > 0 {
+		return nil, fmt.Errorf("`drop_labels` and `keep_labels` query args cannot be set simultaneously")
+	}
+	if len(keepLabels) > 0 {
+		keepLabels = append(keepLabels, "__name__")
+	}
+	lf := &LabelsFilter{
+		dropLabels: dropLabels,
+		keepLabels: keepLabels,
+	}
+	return lf, nil
+}
+
+func getLabelNames(r *http.Request, argKey string) []string {
+	var labelNames []string
+	for _, arg := range r.Form[argKey] {
+		for _, labelName := range strings.Split(arg, ",") {
+			labelName = strings.TrimSpace(labelName)
+			if labelName != "" {
+				labelNames = append(labelNames, labelName)
+			}
+		}
+	}
+	return labelNames
+}
+
+// Apply removes labels from mn according to lf.
+func (lf *LabelsFilter) Apply(mn *storage.MetricName) {
+	if len(lf.keepLabels) > 0 {
+		mn.RemoveTagsOn(lf.keepLabels)
+		return
+	}
+	mn.RemoveTagsIgnoring(lf.dropLabels)
+}
+
 // JoinTagFilterss adds etfs to every src filter and returns the result.
 func JoinTagFilterss(src, etfs [][]storage.TagFilter) [][]storage.TagFilter {
 	if len(src) == 0 {
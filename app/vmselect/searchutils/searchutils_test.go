@@ -223,6 +223,54 @@ func TestGetExtraTagFilters(t *testing.T) {
 	)
 }
 
+func TestGetLabelsFilter(t *testing.T) {
+	f := func(qs, resultExpected string) {
+		t.Helper()
+		q, err := url.ParseQuery(qs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		r := &http.Request{
+			Form: q,
+		}
+		lf, err := GetLabelsFilter(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		var mn storage.MetricName
+		mn.MetricGroup = []byte("http_requests_total")
+		mn.AddTag("job", "api")
+		mn.AddTag("instance", "host1:80")
+		mn.AddTag("pod", "api-7d9f8b6c5d-x2x9z")
+		if lf != nil {
+			lf.Apply(&mn)
+		}
+		result := mn.String()
+		if result != resultExpected {
+			t.Fatalf("unexpected result for %q; got %s; want %s", qs, result, resultExpected)
+		}
+	}
+	f("", `http_requests_total{job="api",instance="host1:80",pod="api-7d9f8b6c5d-x2x9z"}`)
+	f("drop_labels=pod", `http_requests_total{job="api",instance="host1:80"}`)
+	f("drop_labels=pod,instance", `http_requests_total{job="api"}`)
+	f("drop_labels=pod&drop_labels=__name__", `{job="api",instance="host1:80"}`)
+	f("drop_labels=missing", `http_requests_total{job="api",instance="host1:80",pod="api-7d9f8b6c5d-x2x9z"}`)
+	f("keep_labels=job", `http_requests_total{job="api"}`)
+	f("keep_labels=job&keep_labels=instance", `http_requests_total{job="api",instance="host1:80"}`)
+	f("keep_labels=missing", `http_requests_total{}`)
+
+	// Both drop_labels and keep_labels
+	r := &http.Request{
+		Form: url.Values{
+			"drop_labels": {"pod"},
+			"keep_labels": {"job"},
+		},
+	}
+	if _, err := GetLabelsFilter(r); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+}
+
 func TestParseMetricSelectorSuccess(t *testing.T) {
 	f := func(s string) {
 		t.Helper()
@@ -389,3 +437,49 @@ func tagFiltersToString(tfs []storage.TagFilter) string {
 	b = append(b, '}')
 	return string(b)
 }
+
+func TestGetMaxSeries(t *testing.T) {
+	defer func(s string) {
+		*limitsOverrideAuthKey = s
+	}(*limitsOverrideAuthKey)
+
+	f := func(authKey, query string, maxSeriesExpected int, errExpected bool) {
+		t.Helper()
+		*limitsOverrideAuthKey = authKey
+		r, err := http.NewRequest("GET", "http://foo.bar/baz?"+query, nil)
+		if err != nil {
+			t.Fatalf("unexpected error in NewRequest: %s", err)
+		}
+		maxSeries, err := GetMaxSeries(r, 100, "-search.maxFoo")
+		if errExpected {
+			if err == nil {
+				t.Fatalf("expecting non-nil error for %q", query)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %s", query, err)
+		}
+		if maxSeries != maxSeriesExpected {
+			t.Fatalf("unexpected maxSeries for %q; got %d; want %d", query, maxSeries, maxSeriesExpected)
+		}
+	}
+
+	// default limit
+	f("", "", 100, false)
+	f("secret", "max_series=0", 100, false)
+
+	// lower limit is always accepted
+	f("", "max_series=10", 10, false)
+	f("secret", "max_series=10", 10, false)
+
+	// higher limit requires valid authKey
+	f("", "max_series=1000", 0, true)
+	f("", "max_series=1000&authKey=", 0, true)
+	f("secret", "max_series=1000", 0, true)
+	f("secret", "max_series=1000&authKey=foo", 0, true)
+	f("secret", "max_series=1000&authKey=secret", 1000, false)
+
+	// invalid value
+	f("", "max_series=foo", 0, true)
+}
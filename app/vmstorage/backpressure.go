@@ -0,0 +1,159 @@
+package vmstorage
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	backpressureMaxParts = flag.Int("storage.backpressure.maxParts", 0, "The maximum number of in-memory and small parts waiting for merge, "+
+		"after which the storage rejects new writes with 429 Too Many Requests status code until merges catch up. "+
+		"Backpressure on merge backlog is disabled if it is set to 0. See https://docs.victoriametrics.com/#write-backpressure")
+	backpressureMinFreeDiskSpaceBytes = flagutil.NewBytes("storage.backpressure.minFreeDiskSpaceBytes", 0, "The minimum free disk space at -storageDataPath, "+
+		"after which the storage rejects new writes with 429 Too Many Requests status code. It must be bigger than -storage.minFreeDiskSpaceBytes. "+
+		"Backpressure on free disk space is disabled if it is set to 0. See https://docs.victoriametrics.com/#write-backpressure")
+	backpressureRetryAfter = flag.Duration("storage.backpressure.retryAfter", 10*time.Second, "The value for Retry-After response header "+
+		"sent to clients when writes are rejected because of backpressure. See https://docs.victoriametrics.com/#write-backpressure")
+)
+
+const (
+	backpressureReasonMergeBacklog = "merge_backlog"
+	backpressureReasonLowDiskSpace = "low_disk_space"
+)
+
+// getWritePressure returns write pressure for the given partsCount and freeDiskSpace.
+//
+// The pressure is the maximum ratio of partsCount to maxParts and of minFreeDiskSpace to freeDiskSpace.
+// Zero maxParts and minFreeDiskSpace disable the corresponding check.
+// Non-empty reason is returned if the pressure reaches 1, i.e. new writes must be rejected.
+func getWritePressure(partsCount, maxParts, freeDiskSpace, minFreeDiskSpace uint64) (float64, string) {
+	pressure := float64(0)
+	reason := ""
+	if maxParts > 0 {
+		pressure = float64(partsCount) / float64(maxParts)
+		if pressure >= 1 {
+			reason = backpressureReasonMergeBacklog
+		}
+	}
+	if minFreeDiskSpace > 0 {
+		p := float64(minFreeDiskSpace) / float64(freeDiskSpace)
+		if freeDiskSpace == 0 {
+			p = float64(minFreeDiskSpace)
+		}
+		if p > pressure {
+			pressure = p
+		}
+		if p >= 1 && reason == "" {
+			reason = backpressureReasonLowDiskSpace
+		}
+	}
+	return pressure, reason
+}
+
+var (
+	// writePressure contains math.Float64bits for the current write pressure.
+	writePressure uint64
+
+	// backpressureReason contains the current backpressure reason. It is empty if writes are accepted.
+	backpressureReason atomic.Value
+
+	backpressureStopCh chan struct{}
+	backpressureWG     sync.WaitGroup
+
+	backpressureRejectedWrites = metrics.NewCounter(`vm_storage_backpressure_rejected_writes_total`)
+)
+
+func isBackpressureEnabled() bool {
+	return *backpressureMaxParts > 0 || backpressureMinFreeDiskSpaceBytes.N > 0
+}
+
+func initBackpressure(strg *storage.Storage) {
+	backpressureReason.Store("")
+	backpressureStopCh = make(chan struct{})
+	if !isBackpressureEnabled() {
+		return
+	}
+	if backpressureMinFreeDiskSpaceBytes.N > 0 && backpressureMinFreeDiskSpaceBytes.N <= minFreeDiskSpaceBytes.N {
+		logger.Fatalf("-storage.backpressure.minFreeDiskSpaceBytes=%d must be bigger than -storage.minFreeDiskSpaceBytes=%d",
+			backpressureMinFreeDiskSpaceBytes.N, minFreeDiskSpaceBytes.N)
+	}
+	updateBackpressure(strg)
+	backpressureWG.Add(1)
+	go func() {
+		defer backpressureWG.Done()
+		t := time.NewTicker(time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-backpressureStopCh:
+				return
+			case <-t.C:
+			}
+			updateBackpressure(strg)
+		}
+	}()
+}
+
+func stopBackpressure() {
+	close(backpressureStopCh)
+	backpressureWG.Wait()
+}
+
+func updateBackpressure(strg *storage.Storage) {
+	var m storage.Metrics
+	strg.UpdateMetrics(&m)
+	tm := &m.TableMetrics
+	partsCount := tm.InmemoryPartsCount + tm.SmallPartsCount
+	freeDiskSpace := fs.MustGetFreeSpace(*DataPath)
+	pressure, reason := getWritePressure(partsCount, uint64(*backpressureMaxParts), freeDiskSpace, uint64(backpressureMinFreeDiskSpaceBytes.N))
+	atomic.StoreUint64(&writePressure, math.Float64bits(pressure))
+
+	prevReason, _ := backpressureReason.Swap(reason).(string)
+	if reason == prevReason {
+		return
+	}
+	if reason != "" {
+		logger.Warnf("rejecting new writes because of %s; parts waiting for merge: %d, -storage.backpressure.maxParts=%d; free disk space: %d bytes, "+
+			"-storage.backpressure.minFreeDiskSpaceBytes=%d", reason, partsCount, *backpressureMaxParts, freeDiskSpace, backpressureMinFreeDiskSpaceBytes.N)
+	} else {
+		logger.Infof("accepting new writes again, since %s has been resolved", prevReason)
+	}
+}
+
+// getBackpressureReason returns non-empty reason if new writes must be rejected because of backpressure.
+func getBackpressureReason() string {
+	reason, _ := backpressureReason.Load().(string)
+	return reason
+}
+
+func getWritePressureValue() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&writePressure))
+}
+
+func newBackpressureError(reason string) error {
+	retryAfter := int(backpressureRetryAfter.Seconds())
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	h := make(http.Header)
+	h.Set("Retry-After", strconv.Itoa(retryAfter))
+	h.Set(httpserver.BackpressureHeader, reason)
+	return &httpserver.ErrorWithStatusCode{
+		Err:        fmt.Errorf("the storage rejects new writes because of %s; retry in %d seconds; see https://docs.victoriametrics.com/#write-backpressure", reason, retryAfter),
+		StatusCode: http.StatusTooManyRequests,
+		Header:     h,
+	}
+}
@@ -0,0 +1,36 @@
+package vmstorage
+
+import (
+	"testing"
+)
+
+func TestGetWritePressure(t *testing.T) {
+	f := func(partsCount, maxParts, freeDiskSpace, minFreeDiskSpace uint64, pressureExpected float64, reasonExpected string) {
+		t.Helper()
+		pressure, reason := getWritePressure(partsCount, maxParts, freeDiskSpace, minFreeDiskSpace)
+		if pressure != pressureExpected {
+			t.Fatalf("unexpected pressure; got %v; want %v", pressure, pressureExpected)
+		}
+		if reason != reasonExpected {
+			t.Fatalf("unexpected reason; got %q; want %q", reason, reasonExpected)
+		}
+	}
+
+	// backpressure is disabled
+	f(1000, 0, 0, 0, 0, "")
+
+	// merge backlog
+	f(50, 100, 0, 0, 0.5, "")
+	f(100, 100, 0, 0, 1, backpressureReasonMergeBacklog)
+	f(200, 100, 0, 0, 2, backpressureReasonMergeBacklog)
+
+	// low disk space
+	f(0, 0, 2000, 1000, 0.5, "")
+	f(0, 0, 1000, 1000, 1, backpressureReasonLowDiskSpace)
+	f(0, 0, 500, 1000, 2, backpressureReasonLowDiskSpace)
+	f(0, 0, 0, 1000, 1000, backpressureReasonLowDiskSpace)
+
+	// both checks
+	f(50, 100, 500, 1000, 2, backpressureReasonLowDiskSpace)
+	f(400, 100, 500, 1000, 4, backpressureReasonMergeBacklog)
+}
@@ -0,0 +1,102 @@
+package vmstorage
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// handleForceMerge processes /internal/force_merge requests.
+//
+// It starts forced merge in background for partitions matching `partition_prefix`, `min_partition` and `max_partition` query args.
+// The id of the started job is returned in JSON. The job progress can be tracked via /internal/force_merge/status.
+func handleForceMerge(w http.ResponseWriter, r *http.Request) {
+	scope := &storage.ForceMergeScope{
+		PartitionPrefix: r.FormValue("partition_prefix"),
+		MinPartition:    r.FormValue("min_partition"),
+		MaxPartition:    r.FormValue("max_partition"),
+	}
+	ae := auditlog.NewEvent(r, "force_merge")
+	ae.Target = forceMergeScopeString(scope)
+	if r.FormValue("tenant") != "" {
+		err := fmt.Errorf("`tenant` query arg isn't supported by single-node VictoriaMetrics, since its partitions aren't split by tenants")
+		auditlog.Log(ae, err)
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	fmj, err := Storage.StartForceMerge(scope)
+	auditlog.Log(ae, err)
+	if err != nil {
+		httpserver.Errorf(w, r, "cannot start forced merge: %s", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"ok","job_id":%d}`, fmj.ID)
+}
+
+// handleForceMergeStatus processes /internal/force_merge/status requests.
+//
+// It returns the status for the job with the given `job_id` query arg or the statuses for all the recent jobs.
+func handleForceMergeStatus(w http.ResponseWriter, r *http.Request) {
+	if s := r.FormValue("job_id"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot parse job_id=%q: %s", s, err)
+			return
+		}
+		fmj := Storage.GetForceMergeJob(id)
+		if fmj == nil {
+			httpserver.Errorf(w, r, "cannot find forced merge job with job_id=%d", id)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"ok","job":%s}`, forceMergeJobStatusJSON(fmj.Status()))
+		return
+	}
+	jobs := Storage.GetForceMergeJobs()
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"ok","jobs":[`)
+	for i, fmj := range jobs {
+		if i > 0 {
+			fmt.Fprintf(w, `,`)
+		}
+		fmt.Fprintf(w, `%s`, forceMergeJobStatusJSON(fmj.Status()))
+	}
+	fmt.Fprintf(w, `]}`)
+}
+
+func forceMergeJobStatusJSON(st *storage.ForceMergeJobStatus) string {
+	finishTime := ""
+	if !st.FinishTime.IsZero() {
+		finishTime = st.FinishTime.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprintf(`{"job_id":%d,"scope":%q,"state":%q,"error":%q,"start_time":%q,"finish_time":%q,"current_partition":%q,`+
+		`"partitions_total":%d,"partitions_merged":%d,"parts_total":%d,"parts_merged":%d,`+
+		`"bytes_total":%d,"bytes_merged":%d,"bytes_remaining":%d,"eta_seconds":%.3f}`,
+		st.ID, forceMergeScopeString(&st.Scope), st.State, st.Error, st.StartTime.UTC().Format(time.RFC3339), finishTime, st.CurrentPartition,
+		st.PartitionsTotal, st.PartitionsMerged, st.PartsTotal, st.PartsMerged,
+		st.BytesTotal, st.BytesMerged, st.BytesRemaining, st.ETA.Seconds())
+}
+
+func forceMergeScopeString(scope *storage.ForceMergeScope) string {
+	return fmt.Sprintf("partition_prefix=%q, min_partition=%q, max_partition=%q", scope.PartitionPrefix, scope.MinPartition, scope.MaxPartition)
+}
+
+var _ = metrics.NewGauge("vm_active_force_merges", func() float64 {
+	if Storage == nil {
+		return 0
+	}
+	n := 0
+	for _, fmj := range Storage.GetForceMergeJobs() {
+		if fmj.Status().State == "running" {
+			n++
+		}
+	}
+	return float64(n)
+})
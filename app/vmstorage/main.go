@@ -9,6 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/actions"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
@@ -37,6 +40,9 @@ var (
 	finalMergeDelay = flag.Duration("finalMergeDelay", 0, "The delay before starting final merge for per-month partition after no new data is ingested into it. "+
 		"Final merge may require additional disk IO and CPU resources. Final merge may increase query speed and reduce disk space usage in some cases. "+
 		"Zero value disables final merge")
+	snapshotMergeThrottleDelay = flag.Duration("snapshotMergeThrottleDelay", time.Hour, "Background merges are limited to small parts while snapshots older than the given duration exist. "+
+		"This prevents from excess disk space usage by merged parts, which are held by snapshots during long-running backups. Zero value disables merge throttling. "+
+		"See https://docs.victoriametrics.com/#how-to-work-with-snapshots")
 	bigMergeConcurrency     = flag.Int("bigMergeConcurrency", 0, "The maximum number of CPU cores to use for big merges. Default value is used if set to 0")
 	smallMergeConcurrency   = flag.Int("smallMergeConcurrency", 0, "The maximum number of CPU cores to use for small merges. Default value is used if set to 0")
 	retentionTimezoneOffset = flag.Duration("retentionTimezoneOffset", 0, "The offset for performing indexdb rotation. "+
@@ -54,6 +60,70 @@ var (
 	maxDailySeries = flag.Int("storage.maxDailySeries", 0, "The maximum number of unique series can be added to the storage during the last 24 hours. "+
 		"Excess series are logged and dropped. This can be useful for limiting series churn rate. See https://docs.victoriametrics.com/#cardinality-limiter . "+
 		"See also -storage.maxHourlySeries")
+	maxIndexedLabelValueLen = flag.Int("storage.maxIndexedLabelValueLen", 0, "The maximum length of label values stored in indexdb. "+
+		"Longer label values are stored in indexdb as a prefix plus a hash of the full value, while the full value is stored once in the label value dictionary. "+
+		"This reduces indexdb size for long repetitive label values such as URLs. The minimum allowed value is 32. Zero disables the limit. "+
+		"See https://docs.victoriametrics.com/#long-label-values")
+
+	enableWAL = flag.Bool("storage.wal", false, "Whether to write the ingested samples to write-ahead log at -storageDataPath before storing them in memory. "+
+		"This reduces the amount of data lost on unclean shutdown at the cost of additional disk IO. The log is automatically replayed on startup. "+
+		"See https://docs.victoriametrics.com/#write-ahead-log")
+	walSyncPolicy = flag.String("storage.walSyncPolicy", "interval", "Policy for syncing write-ahead log to disk if -storage.wal is set. "+
+		"Supported values: 'always' - sync after every write, 'interval' - sync every -storage.walSyncInterval, 'none' - rely on the OS for syncing. "+
+		"See https://docs.victoriametrics.com/#write-ahead-log")
+	walSyncInterval       = flag.Duration("storage.walSyncInterval", time.Second, "The interval for syncing write-ahead log to disk if -storage.walSyncPolicy=interval")
+	walCheckpointInterval = flag.Duration("storage.walCheckpointInterval", time.Minute, "The interval for flushing the ingested data to disk and removing the obsolete "+
+		"write-ahead log segments if -storage.wal is set. Lower values reduce the write-ahead log size and the time needed for replaying it on startup")
+
+	backfillStagingInterval = flag.Duration("storage.backfillStagingInterval", 0, "Optional interval for accumulating samples older than the current per-month partition "+
+		"in staging area before adding them to the corresponding historical partitions in big batches. This reduces the number of small parts and merges "+
+		"in historical partitions during continuous backfilling. Staged samples are invisible to queries for up to the given interval until they are added to partitions. "+
+		"Staged samples are lost on unclean shutdown if -storage.wal isn't set. The staging area is disabled by default. See https://docs.victoriametrics.com/#backfill-staging")
+	backfillStagingMaxRows = flag.Int("storage.backfillStagingMaxRows", 1e6, "The maximum number of samples in staging area if -storage.backfillStagingInterval is set. "+
+		"The staged samples are added to historical partitions when the limit is reached. Each staged sample occupies around 50 bytes of RAM. See also -storage.backfillStagingMaxMemory")
+	backfillStagingMaxMemory = flagutil.NewBytes("storage.backfillStagingMaxMemory", 0, "The maximum memory, which may be occupied by samples in staging area "+
+		"if -storage.backfillStagingInterval is set. The staged samples are added to historical partitions when the limit is reached. "+
+		"By default the limit is set to 5% of the memory allowed via -memory.allowedPercent or -memory.allowedBytes. See also -storage.backfillStagingMaxRows")
+
+	readYourWrites = flag.Bool("search.readYourWrites", false, "Whether to make all the samples ingested before the query visible to the query. "+
+		"By default recently ingested samples may become visible to queries with up to a second delay. "+
+		"This increases CPU usage under high query rate. See https://docs.victoriametrics.com/#read-your-writes")
+	readYourWritesMaxStaleness = flag.Duration("search.readYourWritesMaxStaleness", 100*time.Millisecond, "The maximum age of ingested samples, "+
+		"which may be invisible to queries if -search.readYourWrites is set. Non-zero value reduces the overhead of -search.readYourWrites under high query rate, "+
+		"since concurrent queries share a single conversion of the buffered samples. Zero value makes visible all the samples ingested before the query. See https://docs.victoriametrics.com/#read-your-writes")
+
+	tieringDst = flag.String("storage.tieringDst", "", "Optional remote storage for offloading per-month partitions older than -storage.tieringOffloadAfter. "+
+		"Offloaded partitions are fetched back to local cache when queries touch their time ranges. "+
+		"Example: gs://<bucket>/<path/to/dir>, s3://<bucket>/<path/to/dir>, azblob://<container>/<path/to/dir> or fs:///path/to/local/dir. "+
+		"See https://docs.victoriametrics.com/#storage-tiering")
+	tieringOffloadAfter = flagutil.NewDuration("storage.tieringOffloadAfter", "90d", "Per-month partitions with data older than the given duration "+
+		"are offloaded to -storage.tieringDst. See https://docs.victoriametrics.com/#storage-tiering")
+	tieringCacheSize = flagutil.NewBytes("storage.tieringCacheSize", 10e9, "The maximum size of local cache at -storageDataPath "+
+		"for partitions fetched from -storage.tieringDst. See https://docs.victoriametrics.com/#storage-tiering")
+
+	quarantineOnReadError = flag.Bool("storage.quarantineOnReadError", false, "Whether to quarantine data parts with read errors instead of crashing. "+
+		"Quarantined parts are excluded from queries and merges, so queries may return partial results, until the data can be read again. "+
+		"This may help surviving transient errors of network-attached storage such as iSCSI or NFS. "+
+		"Only reads performed by queries are covered - read errors during background merges still result in a crash. See https://docs.victoriametrics.com/#storage-read-errors")
+	quarantineRetryInterval = flag.Duration("storage.quarantineRetryInterval", time.Minute, "The interval for retrying reads from parts quarantined because of read errors. "+
+		"See -storage.quarantineOnReadError")
+
+	extraDataPaths = flagutil.NewArrayString("storage.extraDataPath", "Optional extra paths for storing per-month partitions in addition to -storageDataPath. "+
+		"Every path is usually located at a distinct disk (aka JBOD). New partitions are placed at the path with the most free disk space, "+
+		"while paths with write errors or with less than -storage.minFreeDiskSpaceBytes of free space are excluded from placement. "+
+		"Partitions at unavailable paths are skipped on startup, so only their data becomes unavailable. "+
+		"See https://docs.victoriametrics.com/#multiple-data-paths")
+
+	cardinalitySketches = flag.Bool("storage.cardinalitySketches", false, "Whether to maintain approximate cardinality stats for the ingested series. "+
+		"The stats are returned instantly by /api/v1/status/tsdb?sketch=1 without scanning indexdb. "+
+		"See https://docs.victoriametrics.com/#cardinality-sketches")
+	cardinalitySketchesTenantLabel = flag.String("storage.cardinalitySketchesTenantLabel", "", "Optional label for maintaining separate cardinality sketches per each label value. "+
+		"Label values must match tenant names passed via -search.tenantHeader. See https://docs.victoriametrics.com/#cardinality-sketches")
+
+	compositeIndexLabels = flagutil.NewArrayString("storage.compositeIndexLabels", "Optional label names delimited by '+' such as 'job+namespace', "+
+		"which must be indexed together in per-day index. This speeds up queries with exact filters on all the given labels such as {job=\"foo\",namespace=\"bar\"} "+
+		"when every label alone matches big number of series. The index is used for queries starting from the day after the next day since it was enabled. "+
+		"See https://docs.victoriametrics.com/#composite-index-labels")
 
 	minFreeDiskSpaceBytes = flagutil.NewBytes("storage.minFreeDiskSpaceBytes", 10e6, "The minimum free disk space at -storageDataPath after which the storage stops accepting new data")
 
@@ -72,7 +142,8 @@ func CheckTimeRange(tr storage.TimeRange) error {
 	if !*denyQueriesOutsideRetention {
 		return nil
 	}
-	minAllowedTimestamp := int64(fasttime.UnixTimestamp()*1000) - retentionPeriod.Msecs
+	// Use the current retention, since it may be extended via /api/v1/admin/retention/extend.
+	minAllowedTimestamp := int64(fasttime.UnixTimestamp()*1000) - Storage.RetentionMsecs()
 	if tr.MinTimestamp > minAllowedTimestamp {
 		return nil
 	}
@@ -90,15 +161,53 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 
 	resetResponseCacheIfNeeded = resetCacheIfNeeded
 	storage.SetLogNewSeries(*logNewSeries)
+	storage.SetMaxIndexedLabelValueLen(*maxIndexedLabelValueLen)
 	storage.SetFinalMergeDelay(*finalMergeDelay)
+	storage.SetSnapshotMergeThrottleDelay(*snapshotMergeThrottleDelay)
 	storage.SetBigMergeWorkersCount(*bigMergeConcurrency)
 	storage.SetMergeWorkersCount(*smallMergeConcurrency)
 	storage.SetRetentionTimezoneOffset(*retentionTimezoneOffset)
 	storage.SetFreeDiskSpaceLimit(minFreeDiskSpaceBytes.N)
+	storage.SetExtraDataPaths(*extraDataPaths)
+	storage.SetCardinalitySketches(*cardinalitySketches, *cardinalitySketchesTenantLabel)
+	if err := storage.SetCompositeLabelsIndexes(*compositeIndexLabels); err != nil {
+		logger.Fatalf("invalid -storage.compositeIndexLabels: %s", err)
+	}
 	storage.SetTSIDCacheSize(cacheSizeStorageTSID.IntN())
 	storage.SetTagFiltersCacheSize(cacheSizeIndexDBTagFilters.IntN())
 	mergeset.SetIndexBlocksCacheSize(cacheSizeIndexDBIndexBlocks.IntN())
 	mergeset.SetDataBlocksCacheSize(cacheSizeIndexDBDataBlocks.IntN())
+	storage.SetReadYourWrites(*readYourWrites, *readYourWritesMaxStaleness)
+	if *quarantineOnReadError {
+		if *quarantineRetryInterval <= 0 {
+			logger.Fatalf("-storage.quarantineRetryInterval must be positive; got %s", *quarantineRetryInterval)
+		}
+		storage.SetReadErrorQuarantine(true, *quarantineRetryInterval)
+	}
+	if *enableWAL {
+		if err := storage.SetWAL(*walSyncPolicy, *walSyncInterval, *walCheckpointInterval); err != nil {
+			logger.Fatalf("invalid write-ahead log config: %s", err)
+		}
+	}
+
+	if *backfillStagingInterval > 0 {
+		if *backfillStagingMaxRows <= 0 {
+			logger.Fatalf("-storage.backfillStagingMaxRows must be positive; got %d", *backfillStagingMaxRows)
+		}
+		storage.SetBackfillStaging(*backfillStagingInterval, *backfillStagingMaxRows, backfillStagingMaxMemory.IntN())
+	}
+
+	if *tieringDst != "" {
+		remoteFS, err := actions.NewRemoteFS(*tieringDst)
+		if err != nil {
+			logger.Fatalf("cannot initialize remote storage for -storage.tieringDst=%q: %s", *tieringDst, err)
+		}
+		offloadAfter := time.Duration(tieringOffloadAfter.Msecs) * time.Millisecond
+		if err := storage.SetTiering(remoteFS, offloadAfter, tieringCacheSize.N); err != nil {
+			logger.Fatalf("invalid tiering config: %s", err)
+		}
+		tieringRemoteFS = remoteFS
+	}
 
 	if retentionPeriod.Msecs < 24*3600*1000 {
 		logger.Fatalf("-retentionPeriod cannot be smaller than a day; got %s", retentionPeriod)
@@ -112,6 +221,7 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	}
 	Storage = strg
 	initStaleSnapshotsRemover(strg)
+	initBackpressure(strg)
 
 	var m storage.Metrics
 	strg.UpdateMetrics(&m)
@@ -123,8 +233,12 @@ func Init(resetCacheIfNeeded func(mrs []storage.MetricRow)) {
 	logger.Infof("successfully opened storage %q in %.3f seconds; partsCount: %d; blocksCount: %d; rowsCount: %d; sizeBytes: %d",
 		*DataPath, time.Since(startTime).Seconds(), partsCount, blocksCount, rowsCount, sizeBytes)
 	registerStorageMetrics(Storage)
+	httpserver.SetHealthCheck(healthCheck)
 }
 
+// tieringRemoteFS is the remote storage for offloaded partitions. It is set if -storage.tieringDst is set.
+var tieringRemoteFS common.RemoteFS
+
 // Storage is a storage.
 //
 // Every storage call must be wrapped into WG.Add(1) ... WG.Done()
@@ -143,8 +257,8 @@ var resetResponseCacheIfNeeded func(mrs []storage.MetricRow)
 //
 // The caller should limit the number of concurrent calls to AddRows() in order to limit memory usage.
 func AddRows(mrs []storage.MetricRow) error {
-	if Storage.IsReadOnly() {
-		return errReadOnly
+	if err := checkWritable(); err != nil {
+		return err
 	}
 	resetResponseCacheIfNeeded(mrs)
 	WG.Add(1)
@@ -157,6 +271,9 @@ var errReadOnly = errors.New("the storage is in read-only mode; check -storage.m
 
 // RegisterMetricNames registers all the metrics from mrs in the storage.
 func RegisterMetricNames(qt *querytracer.Tracer, mrs []storage.MetricRow) error {
+	if err := checkWritable(); err != nil {
+		return err
+	}
 	WG.Add(1)
 	err := Storage.RegisterMetricNames(qt, mrs)
 	WG.Done()
@@ -173,6 +290,54 @@ func DeleteSeries(qt *querytracer.Tracer, tfss []*storage.TagFilters) (int, erro
 	return n, err
 }
 
+// AddHold adds the given hold to the storage.
+func AddHold(hold *storage.Hold) error {
+	WG.Add(1)
+	err := Storage.AddHold(hold)
+	WG.Done()
+	return err
+}
+
+// ReleaseHold releases the hold with the given id.
+func ReleaseHold(id, author, reason string) error {
+	WG.Add(1)
+	err := Storage.ReleaseHold(id, author, reason)
+	WG.Done()
+	return err
+}
+
+// ListHolds returns active holds.
+func ListHolds() []storage.Hold {
+	WG.Add(1)
+	hs := Storage.ListHolds()
+	WG.Done()
+	return hs
+}
+
+// GetHoldsAuditLog returns audit records for actions with holds.
+func GetHoldsAuditLog() ([]storage.HoldAuditRecord, error) {
+	WG.Add(1)
+	ars, err := Storage.GetHoldsAuditLog()
+	WG.Done()
+	return ars, err
+}
+
+// ExtendRetention extends the storage retention to retentionMsecs without restart.
+func ExtendRetention(retentionMsecs int64) error {
+	WG.Add(1)
+	err := Storage.ExtendRetention(retentionMsecs)
+	WG.Done()
+	return err
+}
+
+// GetRetentionReport returns retention report for the storage.
+func GetRetentionReport() *storage.RetentionReport {
+	WG.Add(1)
+	rr := Storage.GetRetentionReport()
+	WG.Done()
+	return rr
+}
+
 // SearchMetricNames returns metric names for the given tfss on the given tr.
 func SearchMetricNames(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxMetrics int, deadline uint64) ([]string, error) {
 	WG.Add(1)
@@ -181,6 +346,14 @@ func SearchMetricNames(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr st
 	return metricNames, err
 }
 
+// IterateMetricNames returns up to limit metric names for the given tfss for series with metricID bigger than afterMetricID.
+func IterateMetricNames(qt *querytracer.Tracer, tfss []*storage.TagFilters, afterMetricID uint64, limit int, deadline uint64) ([]string, uint64, error) {
+	WG.Add(1)
+	metricNames, nextMetricID, err := Storage.IterateMetricNames(qt, tfss, afterMetricID, limit, deadline)
+	WG.Done()
+	return metricNames, nextMetricID, err
+}
+
 // SearchLabelNamesWithFiltersOnTimeRange searches for tag keys matching the given tfss on tr.
 func SearchLabelNamesWithFiltersOnTimeRange(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, maxTagKeys, maxMetrics int, deadline uint64) ([]string, error) {
 	WG.Add(1)
@@ -224,6 +397,22 @@ func GetTSDBStatus(qt *querytracer.Tracer, tfss []*storage.TagFilters, date uint
 	return status, err
 }
 
+// GetTSDBStatusFromSketches returns approximate TSDB status for the given date and tenant from cardinality sketches.
+func GetTSDBStatusFromSketches(date uint64, tenant string, topN int) (*storage.TSDBStatus, error) {
+	WG.Add(1)
+	status, err := Storage.GetTSDBStatusFromSketches(date, tenant, topN)
+	WG.Done()
+	return status, err
+}
+
+// GetSelectorStats returns stats for series matching tfss on the given tr.
+func GetSelectorStats(qt *querytracer.Tracer, tfss []*storage.TagFilters, tr storage.TimeRange, focusLabel string, topN, maxMetrics int, deadline uint64) (*storage.SelectorStats, error) {
+	WG.Add(1)
+	ss, err := Storage.GetSelectorStats(qt, tfss, tr, focusLabel, topN, maxMetrics, deadline)
+	WG.Done()
+	return ss, err
+}
+
 // GetSeriesCount returns the number of time series in the storage.
 func GetSeriesCount(deadline uint64) (uint64, error) {
 	WG.Add(1)
@@ -238,7 +427,11 @@ func Stop() {
 	startTime := time.Now()
 	WG.WaitAndBlock()
 	stopStaleSnapshotsRemover()
+	stopBackpressure()
 	Storage.MustClose()
+	if tieringRemoteFS != nil {
+		tieringRemoteFS.MustStop()
+	}
 	logger.Infof("successfully closed the storage in %.3f seconds", time.Since(startTime).Seconds())
 
 	logger.Infof("the storage has been stopped")
@@ -251,18 +444,14 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		if !httpserver.CheckAuthFlag(w, r, *forceMergeAuthKey, "forceMergeAuthKey") {
 			return true
 		}
-		// Run force merge in background
-		partitionNamePrefix := r.FormValue("partition_prefix")
-		go func() {
-			activeForceMerges.Inc()
-			defer activeForceMerges.Dec()
-			logger.Infof("forced merge for partition_prefix=%q has been started", partitionNamePrefix)
-			startTime := time.Now()
-			if err := Storage.ForceMergePartitions(partitionNamePrefix); err != nil {
-				logger.Errorf("error in forced merge for partition_prefix=%q: %s", partitionNamePrefix, err)
-			}
-			logger.Infof("forced merge for partition_prefix=%q has been successfully finished in %.3f seconds", partitionNamePrefix, time.Since(startTime).Seconds())
-		}()
+		handleForceMerge(w, r)
+		return true
+	}
+	if path == "/internal/force_merge/status" {
+		if !httpserver.CheckAuthFlag(w, r, *forceMergeAuthKey, "forceMergeAuthKey") {
+			return true
+		}
+		handleForceMergeStatus(w, r)
 		return true
 	}
 	if path == "/internal/force_flush" {
@@ -273,6 +462,20 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		Storage.DebugFlush()
 		return true
 	}
+	if path == "/internal/mode" {
+		if !httpserver.CheckAuthFlag(w, r, *maintenanceAuthKey, "maintenanceAuthKey") {
+			return true
+		}
+		handleMode(w, r)
+		return true
+	}
+	if path == "/internal/upgrade/prepare" {
+		if !httpserver.CheckAuthFlag(w, r, *maintenanceAuthKey, "maintenanceAuthKey") {
+			return true
+		}
+		handleUpgradePrepare(w, r)
+		return true
+	}
 	prometheusCompatibleResponse := false
 	if path == "/api/v1/admin/tsdb/snapshot" {
 		// Handle Prometheus API - https://prometheus.io/docs/prometheus/latest/querying/api/#snapshot .
@@ -290,7 +493,24 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	switch path {
 	case "/create":
 		w.Header().Set("Content-Type", "application/json")
-		snapshotPath, err := Storage.CreateSnapshot()
+		tr, err := getSnapshotTimeRange(r, time.Now())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"status":"error","msg":%q}`, fmt.Errorf("cannot create snapshot: %w", err))
+			return true
+		}
+		var snapshotPath string
+		if tr != nil {
+			snapshotPath, err = Storage.CreatePartialSnapshot(*tr)
+		} else {
+			snapshotPath, err = Storage.CreateSnapshot()
+		}
+		ae := auditlog.NewEvent(r, "snapshot_create")
+		ae.Target = snapshotPath
+		if tr != nil {
+			ae.SetDetail("time_range", tr.String())
+		}
+		auditlog.Log(ae, err)
 		if err != nil {
 			err = fmt.Errorf("cannot create snapshot: %w", err)
 			jsonResponseError(w, err)
@@ -331,7 +551,9 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 		for _, snName := range snapshots {
 			if snName == snapshotName {
-				if err := Storage.DeleteSnapshot(snName); err != nil {
+				err := Storage.DeleteSnapshot(snName)
+				logSnapshotDelete(r, snName, err)
+				if err != nil {
 					err = fmt.Errorf("cannot delete snapshot %q: %w", snName, err)
 					jsonResponseError(w, err)
 					return true
@@ -342,6 +564,7 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 		}
 
 		err = fmt.Errorf("cannot find snapshot %q: %w", snapshotName, err)
+		logSnapshotDelete(r, snapshotName, err)
 		jsonResponseError(w, err)
 		return true
 	case "/delete_all":
@@ -353,7 +576,9 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 			return true
 		}
 		for _, snapshotName := range snapshots {
-			if err := Storage.DeleteSnapshot(snapshotName); err != nil {
+			err := Storage.DeleteSnapshot(snapshotName)
+			logSnapshotDelete(r, snapshotName, err)
+			if err != nil {
 				err = fmt.Errorf("cannot delete snapshot %q: %w", snapshotName, err)
 				jsonResponseError(w, err)
 				return true
@@ -366,6 +591,12 @@ func RequestHandler(w http.ResponseWriter, r *http.Request) bool {
 	}
 }
 
+func logSnapshotDelete(r *http.Request, snapshotName string, err error) {
+	ae := auditlog.NewEvent(r, "snapshot_delete")
+	ae.Target = snapshotName
+	auditlog.Log(ae, err)
+}
+
 func initStaleSnapshotsRemover(strg *storage.Storage) {
 	staleSnapshotsRemoverCh = make(chan struct{})
 	if snapshotsMaxAge.Msecs <= 0 {
@@ -401,8 +632,6 @@ var (
 	staleSnapshotsRemoverWG sync.WaitGroup
 )
 
-var activeForceMerges = metrics.NewCounter("vm_active_force_merges")
-
 func registerStorageMetrics(strg *storage.Storage) {
 	mCache := &storage.Metrics{}
 	var mCacheLock sync.Mutex
@@ -436,7 +665,22 @@ func registerStorageMetrics(strg *storage.Storage) {
 		return float64(minFreeDiskSpaceBytes.N)
 	})
 	metrics.NewGauge(fmt.Sprintf(`vm_storage_is_read_only{path=%q}`, *DataPath), func() float64 {
-		if strg.IsReadOnly() {
+		if strg.IsReadOnly() || getStorageMode() != modeReadWrite {
+			return 1
+		}
+		return 0
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_storage_is_draining{path=%q}`, *DataPath), func() float64 {
+		if getStorageMode() == modeDrain {
+			return 1
+		}
+		return 0
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_storage_write_pressure{path=%q}`, *DataPath), func() float64 {
+		return getWritePressureValue()
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_storage_backpressure_active{path=%q}`, *DataPath), func() float64 {
+		if getBackpressureReason() != "" {
 			return 1
 		}
 		return 0
@@ -458,6 +702,13 @@ func registerStorageMetrics(strg *storage.Storage) {
 		return float64(idbm().ActiveFileMerges)
 	})
 
+	metrics.NewGauge(`vm_merge_concurrency{type="storage/small"}`, func() float64 {
+		return float64(tm().SmallMergeConcurrency)
+	})
+	metrics.NewGauge(`vm_merge_concurrency{type="storage/big"}`, func() float64 {
+		return float64(tm().BigMergeConcurrency)
+	})
+
 	metrics.NewGauge(`vm_merges_total{type="storage/inmemory"}`, func() float64 {
 		return float64(tm().InmemoryMergesCount)
 	})
@@ -543,6 +794,9 @@ func registerStorageMetrics(strg *storage.Storage) {
 	metrics.NewGauge(`vm_composite_filter_missing_conversions_total`, func() float64 {
 		return float64(idbm().CompositeFilterMissingConversions)
 	})
+	metrics.NewGauge(`vm_composite_labels_filter_conversions_total`, func() float64 {
+		return float64(idbm().CompositeLabelsFilterConversions)
+	})
 
 	metrics.NewGauge(`vm_assisted_merges_total{type="storage/inmemory"}`, func() float64 {
 		return float64(tm().InmemoryAssistedMerges)
@@ -564,6 +818,12 @@ func registerStorageMetrics(strg *storage.Storage) {
 	metrics.NewGauge(`vm_indexdb_items_added_size_bytes_total`, func() float64 {
 		return float64(idbm().ItemsAddedSizeBytes)
 	})
+	metrics.NewGauge(`vm_indexdb_bloom_filter_checks_total`, func() float64 {
+		return float64(idbm().BloomFilterChecks)
+	})
+	metrics.NewGauge(`vm_indexdb_bloom_filter_skipped_parts_total`, func() float64 {
+		return float64(idbm().BloomFilterSkippedParts)
+	})
 
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/686
 	metrics.NewGauge(`vm_merge_need_free_disk_space`, func() float64 {
@@ -896,6 +1156,106 @@ func registerStorageMetrics(strg *storage.Storage) {
 	metrics.NewGauge(`vm_next_retention_seconds`, func() float64 {
 		return float64(m().NextRetentionSeconds)
 	})
+
+	metrics.NewGauge(`vm_wal_size_bytes`, func() float64 {
+		return float64(m().WALSizeBytes)
+	})
+	metrics.NewGauge(`vm_wal_syncs_total`, func() float64 {
+		return float64(m().WALSyncs)
+	})
+	metrics.NewGauge(`vm_wal_checkpoints_total`, func() float64 {
+		return float64(m().WALCheckpoints)
+	})
+
+	if *tieringDst != "" {
+		metrics.NewGauge(`vm_tiering_offloaded_partitions`, func() float64 {
+			return float64(tm().OffloadedPartitions)
+		})
+		metrics.NewGauge(`vm_tiering_cache_partitions`, func() float64 {
+			return float64(tm().TieringCachedPartitions)
+		})
+		metrics.NewGauge(`vm_tiering_cache_size_bytes`, func() float64 {
+			return float64(tm().TieringCacheSizeBytes)
+		})
+		metrics.NewGauge(`vm_tiering_cache_size_max_bytes`, func() float64 {
+			return float64(tm().TieringCacheMaxSizeBytes)
+		})
+		metrics.NewGauge(`vm_tiering_cache_evictions_total`, func() float64 {
+			return float64(tm().TieringCacheEvictions)
+		})
+		metrics.NewGauge(`vm_tiering_offloads_total`, func() float64 {
+			return float64(tm().TieringOffloads)
+		})
+		metrics.NewGauge(`vm_tiering_offload_errors_total`, func() float64 {
+			return float64(tm().TieringOffloadErrors)
+		})
+		metrics.NewGauge(`vm_tiering_fetches_total`, func() float64 {
+			return float64(tm().TieringFetches)
+		})
+		metrics.NewGauge(`vm_tiering_fetch_errors_total`, func() float64 {
+			return float64(tm().TieringFetchErrors)
+		})
+		metrics.NewGauge(`vm_rows_ignored_total{reason="offloaded_partition"}`, func() float64 {
+			return float64(tm().OffloadedPartitionRows)
+		})
+	}
+
+	if *backfillStagingInterval > 0 {
+		metrics.NewGauge(`vm_backfill_staging_pending_rows`, func() float64 {
+			return float64(tm().BackfillStagingPendingRows)
+		})
+		metrics.NewGauge(`vm_backfill_staging_rows_total`, func() float64 {
+			return float64(tm().BackfillStagingRows)
+		})
+		metrics.NewGauge(`vm_backfill_staging_flushes_total`, func() float64 {
+			return float64(tm().BackfillStagingFlushes)
+		})
+		metrics.NewGauge(`vm_backfill_staging_flush_errors_total`, func() float64 {
+			return float64(tm().BackfillStagingFlushErrors)
+		})
+	}
+
+	if len(*extraDataPaths) > 0 {
+		metrics.NewGauge(`vm_data_paths`, func() float64 {
+			return float64(tm().DataPaths)
+		})
+		metrics.NewGauge(`vm_data_paths_unhealthy`, func() float64 {
+			return float64(tm().UnhealthyDataPaths)
+		})
+		metrics.NewGauge(`vm_unavailable_partitions`, func() float64 {
+			return float64(tm().UnavailablePartitions)
+		})
+		metrics.NewGauge(`vm_rows_ignored_total{reason="unavailable_partition"}`, func() float64 {
+			return float64(tm().UnavailablePartitionRows)
+		})
+	}
+
+	metrics.NewGauge(`vm_read_your_writes_flushes_total`, func() float64 {
+		return float64(m().ReadYourWritesFlushes)
+	})
+
+	metrics.NewGauge(fmt.Sprintf(`vm_snapshots{path=%q}`, *DataPath), func() float64 {
+		return float64(m().SnapshotsCount)
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_snapshots_extra_size_bytes{path=%q}`, *DataPath), func() float64 {
+		return float64(m().SnapshotsExtraSizeBytes)
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_oldest_snapshot_age_seconds{path=%q}`, *DataPath), func() float64 {
+		return float64(m().OldestSnapshotAgeSeconds)
+	})
+	metrics.NewGauge(fmt.Sprintf(`vm_snapshot_merges_throttled{path=%q}`, *DataPath), func() float64 {
+		return float64(m().SnapshotMergesThrottled)
+	})
+
+	metrics.NewGauge(`vm_holds_active`, func() float64 {
+		return float64(m().ActiveHolds)
+	})
+	metrics.NewGauge(`vm_held_series`, func() float64 {
+		return float64(m().HeldSeries)
+	})
+	metrics.NewGauge(`vm_deletes_blocked_by_holds_total`, func() float64 {
+		return float64(m().DeletesBlockedByHolds)
+	})
 }
 
 func jsonResponseError(w http.ResponseWriter, err error) {
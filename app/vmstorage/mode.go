@@ -0,0 +1,133 @@
+package vmstorage
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+var maintenanceAuthKey = flag.String("maintenanceAuthKey", "", "authKey, which must be passed in query string to /internal/mode and /internal/upgrade/prepare pages. "+
+	"See https://docs.victoriametrics.com/#maintenance-modes")
+
+// storageMode is the mode set via /internal/mode page.
+type storageMode uint32
+
+const (
+	// modeReadWrite is the default mode, when the storage accepts both writes and reads.
+	modeReadWrite storageMode = iota
+
+	// modeReadOnly rejects new writes while serving reads.
+	modeReadOnly
+
+	// modeDrain rejects new writes, flushes pending data to disk and makes /health page return non-OK response,
+	// so load balancers and clients stop routing new data to the storage.
+	modeDrain
+)
+
+func (m storageMode) String() string {
+	switch m {
+	case modeReadWrite:
+		return "read-write"
+	case modeReadOnly:
+		return "read-only"
+	case modeDrain:
+		return "drain"
+	default:
+		logger.Panicf("BUG: unexpected storage mode: %d", uint32(m))
+		return ""
+	}
+}
+
+func parseStorageMode(s string) (storageMode, error) {
+	switch s {
+	case "read-write":
+		return modeReadWrite, nil
+	case "read-only":
+		return modeReadOnly, nil
+	case "drain":
+		return modeDrain, nil
+	default:
+		return 0, fmt.Errorf("unsupported mode %q; supported values: read-write, read-only, drain", s)
+	}
+}
+
+var currentMode uint32
+
+func getStorageMode() storageMode {
+	return storageMode(atomic.LoadUint32(&currentMode))
+}
+
+func setStorageMode(m storageMode) {
+	prevMode := storageMode(atomic.SwapUint32(&currentMode, uint32(m)))
+	if prevMode == m {
+		return
+	}
+	logger.Infof("switching storage mode from %s to %s", prevMode, m)
+	if m == modeDrain {
+		// Flush pending data in background, so it is persisted before the node is stopped.
+		go func() {
+			startTime := time.Now()
+			WG.Add(1)
+			Storage.DebugFlush()
+			WG.Done()
+			logger.Infof("pending data has been flushed to disk in %.3f seconds", time.Since(startTime).Seconds())
+		}()
+	}
+}
+
+// checkWritable returns an error if the storage cannot accept new data.
+func checkWritable() error {
+	m := getStorageMode()
+	if m != modeReadWrite {
+		return &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("the storage is in %s mode; writes are rejected until the mode is switched to read-write via /internal/mode", m),
+			StatusCode: http.StatusServiceUnavailable,
+		}
+	}
+	if Storage.IsReadOnly() {
+		return errReadOnly
+	}
+	if reason := getBackpressureReason(); reason != "" {
+		backpressureRejectedWrites.Inc()
+		return newBackpressureError(reason)
+	}
+	return nil
+}
+
+// healthCheck returns an error if the storage is in drain mode.
+func healthCheck() error {
+	if getStorageMode() == modeDrain {
+		return fmt.Errorf("the storage is in drain mode")
+	}
+	return nil
+}
+
+// handleMode processes /internal/mode requests.
+//
+// The mode is changed if `set` query arg is passed. The current mode is returned in JSON.
+func handleMode(w http.ResponseWriter, r *http.Request) {
+	if s := r.FormValue("set"); s != "" {
+		m, err := parseStorageMode(s)
+		ae := auditlog.NewEvent(r, "storage_mode_change")
+		ae.Target = s
+		auditlog.Log(ae, err)
+		if err != nil {
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+		setStorageMode(m)
+	}
+	var sm storage.Metrics
+	Storage.UpdateMetrics(&sm)
+	tm := &sm.TableMetrics
+	activeMerges := tm.ActiveInmemoryMerges + tm.ActiveSmallMerges + tm.ActiveBigMerges
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"ok","mode":%q,"activeMerges":%d,"pendingRows":%d}`, getStorageMode(), activeMerges, tm.PendingRows)
+}
@@ -0,0 +1,73 @@
+package vmstorage
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// getSnapshotTimeRange returns the time range for partial snapshot from `start`, `end` and `lookback` query args at r.
+//
+// nil is returned if r doesn't contain these args, i.e. a snapshot must contain all the partitions.
+func getSnapshotTimeRange(r *http.Request, currentTime time.Time) (*storage.TimeRange, error) {
+	startStr := r.FormValue("start")
+	endStr := r.FormValue("end")
+	lookbackStr := r.FormValue("lookback")
+	if startStr == "" && endStr == "" && lookbackStr == "" {
+		return nil, nil
+	}
+	if startStr != "" && lookbackStr != "" {
+		return nil, fmt.Errorf("`start` and `lookback` query args cannot be set simultaneously")
+	}
+
+	end := currentTime
+	if endStr != "" {
+		t, err := parseSnapshotTime(endStr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `end` query arg: %w", err)
+		}
+		end = t
+	}
+	var start time.Time
+	switch {
+	case startStr != "":
+		t, err := parseSnapshotTime(startStr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `start` query arg: %w", err)
+		}
+		start = t
+	case lookbackStr != "":
+		d, err := promutils.ParseDuration(lookbackStr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `lookback` query arg: %w", err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("`lookback` query arg must be positive; got %s", lookbackStr)
+		}
+		start = end.Add(-d)
+	}
+	tr := &storage.TimeRange{
+		MinTimestamp: start.UnixMilli(),
+		MaxTimestamp: end.UnixMilli(),
+	}
+	if tr.MinTimestamp > tr.MaxTimestamp {
+		return nil, fmt.Errorf("`start` cannot exceed `end`; got %s", tr)
+	}
+	return tr, nil
+}
+
+// parseSnapshotTime parses s in unix seconds or RFC3339 format.
+func parseSnapshotTime(s string) (time.Time, error) {
+	if secs, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.UnixMilli(int64(secs * 1e3)), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot parse %q as unix timestamp or RFC3339 time", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,67 @@
+package vmstorage
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestGetSnapshotTimeRangeSuccess(t *testing.T) {
+	currentTime := time.Unix(1700000000, 0)
+	f := func(query string, minTimestamp, maxTimestamp int64) {
+		t.Helper()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost:8428/snapshot/create?"+query, nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		tr, err := getSnapshotTimeRange(r, currentTime)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if minTimestamp == 0 && maxTimestamp == 0 {
+			if tr != nil {
+				t.Fatalf("expecting nil time range; got %s", tr)
+			}
+			return
+		}
+		if tr == nil {
+			t.Fatalf("expecting non-nil time range")
+		}
+		if tr.MinTimestamp != minTimestamp || tr.MaxTimestamp != maxTimestamp {
+			t.Fatalf("unexpected time range; got [%d..%d]; want [%d..%d]", tr.MinTimestamp, tr.MaxTimestamp, minTimestamp, maxTimestamp)
+		}
+	}
+
+	// full snapshot
+	f("", 0, 0)
+
+	// lookback
+	f("lookback=35d", 1700000000e3-35*24*3600e3, 1700000000e3)
+	f("lookback=1h&end=1600000000", 1600000000e3-3600e3, 1600000000e3)
+
+	// start and end
+	f("start=1600000000", 1600000000e3, 1700000000e3)
+	f("start=1600000000.5&end=1650000000", 1600000000500, 1650000000e3)
+	f("start=2023-01-01T00:00:00Z&end=2023-02-01T00:00:00Z", 1672531200e3, 1675209600e3)
+}
+
+func TestGetSnapshotTimeRangeFailure(t *testing.T) {
+	currentTime := time.Unix(1700000000, 0)
+	f := func(query string) {
+		t.Helper()
+		r, err := http.NewRequest(http.MethodGet, "http://localhost:8428/snapshot/create?"+query, nil)
+		if err != nil {
+			t.Fatalf("cannot create request: %s", err)
+		}
+		tr, err := getSnapshotTimeRange(r, currentTime)
+		if err == nil {
+			t.Fatalf("expecting non-nil error; got time range %s", tr)
+		}
+	}
+	f("start=foo")
+	f("end=foo")
+	f("lookback=foo")
+	f("lookback=-1h")
+	f("start=1600000000&lookback=1h")
+	f("start=1700000001&end=1700000000")
+}
@@ -0,0 +1,94 @@
+package vmstorage
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/storage"
+)
+
+// upgradeState contains the storage state reported by /internal/upgrade/prepare page.
+type upgradeState struct {
+	pendingRows      uint64
+	inmemoryParts    uint64
+	activeFastMerges uint64
+	activeBigMerges  uint64
+}
+
+func getUpgradeState() *upgradeState {
+	var sm storage.Metrics
+	Storage.UpdateMetrics(&sm)
+	tm := &sm.TableMetrics
+	idbm := &sm.IndexDBMetrics
+	return &upgradeState{
+		pendingRows:      tm.PendingRows + idbm.PendingItems,
+		inmemoryParts:    tm.InmemoryPartsCount + idbm.InmemoryPartsCount,
+		activeFastMerges: tm.ActiveInmemoryMerges + tm.ActiveSmallMerges + idbm.ActiveInmemoryMerges,
+		activeBigMerges:  tm.ActiveBigMerges,
+	}
+}
+
+// isReady returns true if the storage can be restarted without losing the ingested data and without waiting for fast merges.
+//
+// Big merges aren't taken into account, since they may take hours and they are safely resumed after the restart.
+func (us *upgradeState) isReady() bool {
+	return us.pendingRows == 0 && us.inmemoryParts == 0 && us.activeFastMerges == 0
+}
+
+// handleUpgradePrepare processes /internal/upgrade/prepare requests.
+//
+// It switches the storage to drain mode, flushes in-memory data to disk and waits until fast merges are finished.
+// The readiness for the restart is returned in JSON. The response status code is 503 if the storage isn't ready
+// for the restart after the `timeout` query arg.
+func handleUpgradePrepare(w http.ResponseWriter, r *http.Request) {
+	timeout := 30 * time.Second
+	if s := r.FormValue("timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			httpserver.Errorf(w, r, "cannot parse `timeout` query arg: %s", err)
+			return
+		}
+		timeout = d
+	}
+	ae := auditlog.NewEvent(r, "upgrade_prepare")
+	auditlog.Log(ae, nil)
+
+	startTime := time.Now()
+	deadline := startTime.Add(timeout)
+	setStorageMode(modeDrain)
+
+	WG.Add(1)
+	us := waitForUpgradeReadiness(deadline)
+	WG.Done()
+
+	duration := time.Since(startTime)
+	ready := us.isReady()
+	if ready {
+		logger.Infof("the storage is ready for the restart; preparation took %.3f seconds", duration.Seconds())
+	} else {
+		logger.Warnf("the storage isn't ready for the restart after %.3f seconds; pendingRows=%d, inmemoryParts=%d, activeFastMerges=%d",
+			duration.Seconds(), us.pendingRows, us.inmemoryParts, us.activeFastMerges)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	fmt.Fprintf(w, `{"status":"ok","mode":%q,"ready":%v,"pendingRows":%d,"inmemoryParts":%d,"activeFastMerges":%d,"activeBigMerges":%d,"durationSeconds":%.3f}`,
+		getStorageMode(), ready, us.pendingRows, us.inmemoryParts, us.activeFastMerges, us.activeBigMerges, duration.Seconds())
+}
+
+// waitForUpgradeReadiness flushes the storage to disk until it becomes ready for the restart or until the deadline is reached.
+func waitForUpgradeReadiness(deadline time.Time) *upgradeState {
+	for {
+		Storage.MustFlushToDisk()
+		us := getUpgradeState()
+		if us.isReady() || time.Now().After(deadline) {
+			return us
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
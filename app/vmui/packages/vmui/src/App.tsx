@@ -9,6 +9,7 @@ import CardinalityPanel from "./pages/CardinalityPanel";
 import TopQueries from "./pages/TopQueries";
 import ThemeProvider from "./components/Main/ThemeProvider/ThemeProvider";
 import TracePage from "./pages/TracePage";
+import SnapshotPage from "./pages/SnapshotPage";
 import ExploreMetrics from "./pages/ExploreMetrics";
 import PreviewIcons from "./components/Main/Icons/PreviewIcons";
 
@@ -47,6 +48,10 @@ const App: FC = () => {
                   path={router.trace}
                   element={<TracePage/>}
                 />
+                <Route
+                  path={router.snapshot}
+                  element={<SnapshotPage/>}
+                />
                 <Route
                   path={router.dashboards}
                   element={<DashboardsLayout/>}
@@ -7,3 +7,11 @@ export const getQueryUrl = (server: string, query: string, period: TimeParams, q
   `${server}/api/v1/query?query=${encodeURIComponent(query)}&time=${period.end}&step=${period.step}${queryTracing ? "&trace=1" : ""}`;
 
 export const getQueryOptions = (server: string) => `${server}/api/v1/label/__name__/values`;
+
+const getMatchArg = (metricName?: string) => metricName ? `match[]=${encodeURIComponent(`{__name__=${JSON.stringify(metricName)}}`)}` : "";
+
+export const getLabelsUrl = (server: string, metricName?: string) =>
+  `${server}/api/v1/labels?${getMatchArg(metricName)}`;
+
+export const getLabelValuesUrl = (server: string, labelName: string, metricName?: string) =>
+  `${server}/api/v1/label/${encodeURIComponent(labelName)}/values?${getMatchArg(metricName)}`;
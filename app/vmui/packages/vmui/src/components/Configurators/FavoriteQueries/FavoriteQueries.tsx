@@ -0,0 +1,89 @@
+import React, { FC, useRef, useState } from "preact/compat";
+import { MouseEvent as ReactMouseEvent } from "react";
+import Button from "../../Main/Button/Button";
+import Popper from "../../Main/Popper/Popper";
+import Tooltip from "../../Main/Tooltip/Tooltip";
+import { DeleteIcon, StarIcon } from "../../Main/Icons";
+import { useAppState } from "../../../state/common/StateContext";
+import "./style.scss";
+
+interface FavoriteQueriesProps {
+  favorites: string[]
+  onSelect: (query: string) => void
+  onRemove: (query: string) => void
+}
+
+const FavoriteQueries: FC<FavoriteQueriesProps> = ({ favorites, onSelect, onRemove }) => {
+  const { tenantId } = useAppState();
+
+  const [openList, setOpenList] = useState(false);
+  const buttonRef = useRef<HTMLDivElement>(null);
+
+  const toggleOpenList = () => {
+    setOpenList(prev => !prev);
+  };
+
+  const handleCloseList = () => {
+    setOpenList(false);
+  };
+
+  const createHandlerSelect = (query: string) => () => {
+    onSelect(query);
+    handleCloseList();
+  };
+
+  const createHandlerRemove = (query: string) => (e: ReactMouseEvent<HTMLButtonElement, MouseEvent>) => {
+    e.stopPropagation();
+    onRemove(query);
+  };
+
+  return (
+    <div className="vm-favorite-queries">
+      <Tooltip title={tenantId ? `Favorite queries for tenant ${tenantId}` : "Favorite queries"}>
+        <div ref={buttonRef}>
+          <Button
+            variant="outlined"
+            startIcon={<StarIcon/>}
+            onClick={toggleOpenList}
+          >
+            Favorites
+          </Button>
+        </div>
+      </Tooltip>
+      <Popper
+        open={openList}
+        placement="bottom-right"
+        onClose={handleCloseList}
+        buttonRef={buttonRef}
+      >
+        <div className="vm-list vm-favorite-queries-list">
+          {!favorites.length && (
+            <div className="vm-favorite-queries-list__empty">
+              No favorite queries yet. Click the star icon near the query in order to add it to favorites.
+            </div>
+          )}
+          {favorites.map(query => (
+            <div
+              className="vm-list-item vm-favorite-queries-list-item"
+              key={query}
+              onClick={createHandlerSelect(query)}
+            >
+              <code className="vm-favorite-queries-list-item__query">{query}</code>
+              <Tooltip title="Remove from favorites">
+                <Button
+                  size="small"
+                  variant="text"
+                  color="error"
+                  startIcon={<DeleteIcon/>}
+                  onClick={createHandlerRemove(query)}
+                />
+              </Tooltip>
+            </div>
+          ))}
+        </div>
+      </Popper>
+    </div>
+  );
+};
+
+export default FavoriteQueries;
@@ -1,10 +1,22 @@
-import React, { FC, useRef, useState } from "preact/compat";
+import React, { FC, useMemo, useRef, useState } from "preact/compat";
 import { KeyboardEvent } from "react";
 import { ErrorTypes } from "../../../types";
 import TextField from "../../Main/TextField/TextField";
 import Autocomplete from "../../Main/Autocomplete/Autocomplete";
+import { useFetchAutocompleteOptions } from "../../../hooks/useFetchAutocompleteOptions";
+import {
+  functions,
+  getFunction,
+  getFunctionDocsUrl,
+  getQueryContext,
+  getWordAtCaret,
+  insertAutocompleteValue,
+  QueryContextType
+} from "../../../utils/metricsql";
 import "./style.scss";
 
+const functionNames = functions.map(f => f.name);
+
 export interface QueryEditorProps {
   onChange: (query: string) => void;
   onEnter: () => void;
@@ -33,10 +45,32 @@ const QueryEditor: FC<QueryEditorProps> = ({
 }) => {
 
   const [openAutocomplete, setOpenAutocomplete] = useState(false);
+  const [caretPosition, setCaretPosition] = useState(value.length);
+  const [focusOption, setFocusOption] = useState("");
   const autocompleteAnchorEl = useRef<HTMLDivElement>(null);
 
+  const context = useMemo(() => {
+    return autocomplete ? getQueryContext(value, Math.min(caretPosition, value.length)) : null;
+  }, [autocomplete, value, caretPosition]);
+
+  const { labelOptions } = useFetchAutocompleteOptions(context);
+
+  const autocompleteOptions = useMemo(() => {
+    if (!context) return [];
+    if (context.type === QueryContextType.metricsql) return [...options, ...functionNames];
+    return labelOptions;
+  }, [context?.type, options, labelOptions]);
+
+  const docs = useMemo(() => {
+    if (openAutocomplete && focusOption) return getFunction(focusOption);
+    return getFunction(getWordAtCaret(value, caretPosition));
+  }, [openAutocomplete, focusOption, value, caretPosition]);
+
   const handleSelect = (val: string) => {
-    onChange(val);
+    if (!context) return;
+    const { query, caret } = insertAutocompleteValue(value, caretPosition, context, val);
+    onChange(query);
+    setCaretPosition(caret);
   };
 
   const handleKeyDown = (e: KeyboardEvent) => {
@@ -75,19 +109,42 @@ const QueryEditor: FC<QueryEditorProps> = ({
       type={"textarea"}
       autofocus={!!value}
       error={error}
+      caretPosition={caretPosition}
       onKeyDown={handleKeyDown}
       onChange={onChange}
+      onChangeCaret={setCaretPosition}
       disabled={disabled}
     />
-    {autocomplete && (
+    {autocomplete && context && (
       <Autocomplete
-        value={value}
-        options={options}
+        value={context.prefix}
+        options={autocompleteOptions}
         anchor={autocompleteAnchorEl}
+        minLength={context.type === QueryContextType.metricsql ? 1 : -1}
+        maxWords={context.type === QueryContextType.labelValue ? Infinity : 1}
         onSelect={handleSelect}
         onOpenAutocomplete={setOpenAutocomplete}
+        onChangeFocusOption={setFocusOption}
       />
     )}
+    {autocomplete && docs && (
+      <div className="vm-query-editor-docs">
+        <div className="vm-query-editor-docs__header">
+          <code className="vm-query-editor-docs__signature">{docs.signature}</code>
+          <span className="vm-query-editor-docs__type">{docs.type}</span>
+        </div>
+        <p className="vm-query-editor-docs__description">{docs.description}</p>
+        {docs.example && <code className="vm-query-editor-docs__example">{docs.example}</code>}
+        <a
+          className="vm-link vm-link_colored"
+          href={getFunctionDocsUrl(docs.name)}
+          target="_blank"
+          rel="noreferrer"
+        >
+          Read more in MetricsQL docs
+        </a>
+      </div>
+    )}
   </div>;
 };
 
@@ -48,6 +48,10 @@ const HeaderNav: FC<HeaderNavProps> = ({ color, background, direction }) => {
       label: routerOptions[router.trace].title,
       value: router.trace,
     },
+    {
+      label: routerOptions[router.snapshot].title,
+      value: router.snapshot,
+    },
     {
       label: routerOptions[router.dashboards].title,
       value: router.dashboards,
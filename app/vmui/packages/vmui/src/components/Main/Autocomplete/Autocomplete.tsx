@@ -17,6 +17,7 @@ interface AutocompleteProps {
   selected?: string[]
   onSelect: (val: string) => void,
   onOpenAutocomplete?: (val: boolean) => void
+  onChangeFocusOption?: (val: string) => void
 }
 
 const Autocomplete: FC<AutocompleteProps> = ({
@@ -30,7 +31,8 @@ const Autocomplete: FC<AutocompleteProps> = ({
   selected,
   noOptionsText,
   onSelect,
-  onOpenAutocomplete
+  onOpenAutocomplete,
+  onChangeFocusOption
 }) => {
   const wrapperEl = useRef<HTMLDivElement>(null);
 
@@ -98,7 +100,7 @@ const Autocomplete: FC<AutocompleteProps> = ({
   useEffect(() => {
     const words = (value.match(/[a-zA-Z_:.][a-zA-Z0-9_:.]*/gm) || []).length;
     setOpenAutocomplete(value.length > minLength && words <= maxWords);
-  }, [value]);
+  }, [value, minLength, maxWords]);
 
   useEffect(() => {
     scrollToValue();
@@ -118,6 +120,10 @@ const Autocomplete: FC<AutocompleteProps> = ({
     onOpenAutocomplete && onOpenAutocomplete(openAutocomplete);
   }, [openAutocomplete]);
 
+  useEffect(() => {
+    onChangeFocusOption && onChangeFocusOption(foundOptions[focusOption] || "");
+  }, [focusOption, foundOptions]);
+
   useClickOutside(wrapperEl, handleCloseAutocomplete, anchor);
 
   return (
@@ -15,7 +15,9 @@ interface TextFieldProps {
   disabled?: boolean
   autofocus?: boolean
   helperText?: string
+  caretPosition?: number
   onChange?: (value: string) => void
+  onChangeCaret?: (position: number) => void
   onEnter?: () => void
   onKeyDown?: (e: KeyboardEvent) => void
   onFocus?: () => void
@@ -33,7 +35,9 @@ const TextField: FC<TextFieldProps> = ({
   disabled = false,
   autofocus = false,
   helperText,
+  caretPosition,
   onChange,
+  onChangeCaret,
   onEnter,
   onKeyDown,
   onFocus,
@@ -64,8 +68,21 @@ const TextField: FC<TextFieldProps> = ({
   const handleChange = (e: React.FormEvent) => {
     if (disabled) return;
     onChange && onChange((e.target as HTMLInputElement).value);
+    updateCaretPosition(e);
   };
 
+  const updateCaretPosition = (e: React.SyntheticEvent) => {
+    const { selectionStart } = e.target as HTMLInputElement | HTMLTextAreaElement;
+    onChangeCaret && onChangeCaret(selectionStart || 0);
+  };
+
+  useEffect(() => {
+    const field = fieldRef?.current;
+    if (caretPosition === undefined || !field || field.selectionStart === caretPosition) return;
+    field.focus();
+    field.setSelectionRange(caretPosition, caretPosition);
+  }, [caretPosition]);
+
   useEffect(() => {
     if (!autofocus) return;
     fieldRef?.current?.focus && fieldRef.current.focus();
@@ -100,6 +117,8 @@ const TextField: FC<TextFieldProps> = ({
           placeholder={placeholder}
           onInput={handleChange}
           onKeyDown={handleKeyDown}
+          onKeyUp={updateCaretPosition}
+          onClick={updateCaretPosition}
           onFocus={handleFocus}
           onBlur={handleBlur}
         />
@@ -114,6 +133,8 @@ const TextField: FC<TextFieldProps> = ({
           placeholder={placeholder}
           onInput={handleChange}
           onKeyDown={handleKeyDown}
+          onKeyUp={updateCaretPosition}
+          onClick={updateCaretPosition}
           onFocus={handleFocus}
           onBlur={handleBlur}
         />
@@ -0,0 +1,69 @@
+import { useEffect, useRef, useState } from "preact/compat";
+import { getLabelsUrl, getLabelValuesUrl } from "../api/query-range";
+import { useAppState } from "../state/common/StateContext";
+import { QueryContext, QueryContextType } from "../utils/metricsql";
+
+/**
+ * Fetches label names and label values for the autocomplete context via labels API.
+ *
+ * The responses are cached per url, since the context changes on every keystroke.
+ */
+export const useFetchAutocompleteOptions = (context: QueryContext | null): {
+  labelOptions: string[],
+} => {
+  const { serverUrl } = useAppState();
+
+  const [labelOptions, setLabelOptions] = useState<string[]>([]);
+  const cache = useRef<Map<string, string[]>>(new Map());
+
+  const getUrl = () => {
+    if (!serverUrl || !context) return "";
+    switch (context.type) {
+      case QueryContextType.labelName:
+        return getLabelsUrl(serverUrl, context.metricName);
+      case QueryContextType.labelValue:
+        return context.labelName ? getLabelValuesUrl(serverUrl, context.labelName, context.metricName) : "";
+      default:
+        return "";
+    }
+  };
+
+  const url = getUrl();
+  const latestUrl = useRef(url);
+  latestUrl.current = url;
+
+  const fetchOptions = async () => {
+    if (!url) {
+      setLabelOptions([]);
+      return;
+    }
+    const cached = cache.current.get(url);
+    if (cached) {
+      setLabelOptions(cached);
+      return;
+    }
+
+    try {
+      const response = await fetch(url);
+      const resp = await response.json();
+      if (response.ok) {
+        const data = resp.data || [];
+        cache.current.set(url, data);
+        if (url !== latestUrl.current) return;
+        setLabelOptions(data);
+      }
+    } catch (e) {
+      console.error(e);
+    }
+  };
+
+  useEffect(() => {
+    cache.current.clear();
+  }, [serverUrl]);
+
+  useEffect(() => {
+    fetchOptions();
+  }, [url]);
+
+  return { labelOptions };
+};
@@ -6,13 +6,23 @@ import usePrevious from "../../../hooks/usePrevious";
 import { MAX_QUERY_FIELDS } from "../../../constants/graph";
 import { useQueryDispatch, useQueryState } from "../../../state/query/QueryStateContext";
 import { useTimeDispatch } from "../../../state/time/TimeStateContext";
-import { DeleteIcon, PlayIcon, PlusIcon, VisibilityIcon, VisibilityOffIcon } from "../../../components/Main/Icons";
+import {
+  DeleteIcon,
+  PlayIcon,
+  PlusIcon,
+  StarBorderIcon,
+  StarIcon,
+  VisibilityIcon,
+  VisibilityOffIcon
+} from "../../../components/Main/Icons";
 import Button from "../../../components/Main/Button/Button";
 import "./style.scss";
 import Tooltip from "../../../components/Main/Tooltip/Tooltip";
 import classNames from "classnames";
 import { MouseEvent as ReactMouseEvent } from "react";
 import { arrayEquals } from "../../../utils/array";
+import FavoriteQueries from "../../../components/Configurators/FavoriteQueries/FavoriteQueries";
+import { useFavoriteQueries } from "../hooks/useFavoriteQueries";
 
 export interface QueryConfiguratorProps {
   error?: ErrorTypes | string;
@@ -25,6 +35,7 @@ const QueryConfigurator: FC<QueryConfiguratorProps> = ({ error, queryOptions, on
   const { query, queryHistory, autocomplete } = useQueryState();
   const queryDispatch = useQueryDispatch();
   const timeDispatch = useTimeDispatch();
+  const { favorites, isFavorite, toggleFavorite } = useFavoriteQueries();
 
   const [stateQuery, setStateQuery] = useState(query || []);
   const [hideQuery, setHideQuery] = useState<number[]>([]);
@@ -73,6 +84,17 @@ const QueryConfigurator: FC<QueryConfiguratorProps> = ({ error, queryOptions, on
     setStateQuery(prev => prev.map((q, i) => i === index ? value : q));
   };
 
+  const handleSelectFavorite = (value: string) => {
+    const lastIndex = stateQuery.length - 1;
+    if (!stateQuery[lastIndex]) {
+      handleChangeQuery(value, lastIndex);
+    } else if (stateQuery.length < MAX_QUERY_FIELDS) {
+      setStateQuery(prev => [...prev, value]);
+    } else {
+      handleChangeQuery(value, lastIndex);
+    }
+  };
+
   const handleHistoryChange = (step: number, indexQuery: number) => {
     const { index, values } = queryHistory[indexQuery];
     const newIndexHistory = index + step;
@@ -97,6 +119,10 @@ const QueryConfigurator: FC<QueryConfiguratorProps> = ({ error, queryOptions, on
     setHideQuery(prev => prev.includes(i) ? prev.filter(n => n !== i) : prev.map(n => n > i ? n - 1: n));
   };
 
+  const createHandlerToggleFavorite = (i: number) => () => {
+    toggleFavorite(stateQuery[i]);
+  };
+
   const createHandlerHideQuery = (i: number) => (e: ReactMouseEvent<HTMLButtonElement, MouseEvent>) => {
     onToggleHideQuery(e, i);
   };
@@ -133,6 +159,17 @@ const QueryConfigurator: FC<QueryConfiguratorProps> = ({ error, queryOptions, on
             label={`Query ${i + 1}`}
             disabled={hideQuery.includes(i)}
           />
+          <Tooltip title={isFavorite(q) ? "Remove from favorites" : "Add to favorites"}>
+            <div className="vm-query-configurator-list-row__button">
+              <Button
+                variant={"text"}
+                color={isFavorite(q) ? "warning" : "gray"}
+                startIcon={isFavorite(q) ? <StarIcon/> : <StarBorderIcon/>}
+                onClick={createHandlerToggleFavorite(i)}
+                disabled={!q}
+              />
+            </div>
+          </Tooltip>
           <Tooltip title={hideQuery.includes(i) ? "Enable query" : "Disable query"}>
             <div className="vm-query-configurator-list-row__button">
               <Button
@@ -161,6 +198,11 @@ const QueryConfigurator: FC<QueryConfiguratorProps> = ({ error, queryOptions, on
     <div className="vm-query-configurator-settings">
       <AdditionalSettings/>
       <div className="vm-query-configurator-settings__buttons">
+        <FavoriteQueries
+          favorites={favorites}
+          onSelect={handleSelectFavorite}
+          onRemove={toggleFavorite}
+        />
         {stateQuery.length < MAX_QUERY_FIELDS && (
           <Button
             variant="outlined"
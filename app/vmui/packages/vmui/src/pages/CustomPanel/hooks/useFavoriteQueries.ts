@@ -0,0 +1,27 @@
+import { useCallback, useEffect, useState } from "preact/compat";
+import { useAppState } from "../../../state/common/StateContext";
+import { getFavoriteQueries, toggleFavoriteQuery } from "../../../utils/favorite-queries";
+
+export const useFavoriteQueries = () => {
+  const { tenantId } = useAppState();
+  const [favorites, setFavorites] = useState<string[]>(getFavoriteQueries(tenantId));
+
+  const handleStorageChange = useCallback(() => {
+    setFavorites(getFavoriteQueries(tenantId));
+  }, [tenantId]);
+
+  const isFavorite = (query: string) => !!query && favorites.includes(query);
+
+  const toggleFavorite = (query: string) => {
+    if (!query) return;
+    setFavorites(toggleFavoriteQuery(tenantId, query));
+  };
+
+  useEffect(() => {
+    handleStorageChange();
+    window.addEventListener("storage", handleStorageChange);
+    return () => window.removeEventListener("storage", handleStorageChange);
+  }, [handleStorageChange]);
+
+  return { favorites, isFavorite, toggleFavorite };
+};
@@ -20,6 +20,9 @@ import "./style.scss";
 import Alert from "../../components/Main/Alert/Alert";
 import TableView from "../../components/Views/TableView/TableView";
 import Button from "../../components/Main/Button/Button";
+import Tooltip from "../../components/Main/Tooltip/Tooltip";
+import { DownloadIcon } from "../../components/Main/Icons";
+import { createSnapshot, downloadSnapshot } from "../../utils/snapshot";
 
 const CustomPanel: FC = () => {
   const { displayType, isTracingEnabled } = useCustomPanelState();
@@ -69,6 +72,21 @@ const CustomPanel: FC = () => {
     setHideQuery(queries);
   };
 
+  const data = displayType === "chart" ? graphData : liveData;
+
+  const handleExportSnapshot = () => {
+    if (!data || !period) return;
+    downloadSnapshot(createSnapshot({
+      displayType,
+      query,
+      hideQuery,
+      period,
+      customStep,
+      data,
+      traces: tracesState,
+    }));
+  };
+
   useEffect(() => {
     if (traces) {
       setTracesState([...tracesState, ...traces]);
@@ -115,20 +133,31 @@ const CustomPanel: FC = () => {
       <div className="vm-custom-panel-body vm-block">
         <div className="vm-custom-panel-body-header">
           <DisplayTypeSwitch/>
-          {displayType === "chart" && (
-            <GraphSettings
-              yaxis={yaxis}
-              setYaxisLimits={setYaxisLimits}
-              toggleEnableLimits={toggleEnableLimits}
-            />
-          )}
-          {displayType === "table" && (
-            <TableSettings
-              data={liveData || []}
-              defaultColumns={displayColumns}
-              onChange={setDisplayColumns}
-            />
-          )}
+          <div className="vm-custom-panel-body-header__settings">
+            {data && (
+              <Tooltip title="Export snapshot">
+                <Button
+                  variant="text"
+                  startIcon={<DownloadIcon/>}
+                  onClick={handleExportSnapshot}
+                />
+              </Tooltip>
+            )}
+            {displayType === "chart" && (
+              <GraphSettings
+                yaxis={yaxis}
+                setYaxisLimits={setYaxisLimits}
+                toggleEnableLimits={toggleEnableLimits}
+              />
+            )}
+            {displayType === "table" && (
+              <TableSettings
+                data={liveData || []}
+                defaultColumns={displayColumns}
+                onChange={setDisplayColumns}
+              />
+            )}
+          </div>
         </div>
         {graphData && period && (displayType === "chart") && (
           <GraphView
@@ -0,0 +1,184 @@
+import React, { FC, useEffect, useMemo, useState } from "preact/compat";
+import { ChangeEvent } from "react";
+import Trace from "../../components/TraceQuery/Trace";
+import TracingsView from "../../components/TraceQuery/TracingsView";
+import GraphView from "../../components/Views/GraphView/GraphView";
+import JsonView from "../../components/Views/JsonView/JsonView";
+import TableView from "../../components/Views/TableView/TableView";
+import Tooltip from "../../components/Main/Tooltip/Tooltip";
+import Button from "../../components/Main/Button/Button";
+import Alert from "../../components/Main/Alert/Alert";
+import { InstantMetricResult, MetricResult } from "../../api/types";
+import { AxisRange, YaxisState } from "../../state/graph/reducer";
+import { TimeParams } from "../../types";
+import { parseSnapshot, Snapshot } from "../../utils/snapshot";
+import { setQueryStringWithoutPageReload } from "../../utils/query-string";
+import "./style.scss";
+
+const initialYaxis: YaxisState = {
+  limits: { enable: false, range: { "1": [0, 0] } }
+};
+
+const SnapshotPage: FC = () => {
+  const [snapshot, setSnapshot] = useState<Snapshot>();
+  const [filename, setFilename] = useState("");
+  const [error, setError] = useState("");
+  const [period, setPeriod] = useState<TimeParams>();
+  const [yaxis, setYaxis] = useState<YaxisState>(initialYaxis);
+
+  const traces = useMemo(() => {
+    if (!snapshot) return [];
+    return snapshot.traces.map(t => new Trace(t, filename));
+  }, [snapshot]);
+  const [tracesState, setTracesState] = useState<Trace[]>([]);
+
+  const handleOnload = (result: string, name: string) => {
+    try {
+      const s = parseSnapshot(result);
+      setSnapshot(s);
+      setFilename(name);
+      setPeriod(s.period);
+      setYaxis(initialYaxis);
+      setError("");
+    } catch (e) {
+      if (e instanceof Error) setError(`${name}: ${e.message}`);
+    }
+  };
+
+  const handleChange = (e: ChangeEvent<HTMLInputElement>) => {
+    const f = e.target.files?.[0];
+    if (!f) return;
+    const reader = new FileReader();
+    reader.onload = (e) => {
+      handleOnload(String(e.target?.result), f.name);
+    };
+    reader.readAsText(f);
+    e.target.value = "";
+  };
+
+  const setYaxisLimits = (range: AxisRange) => {
+    setYaxis(prev => ({ limits: { ...prev.limits, range } }));
+  };
+
+  const handleSetPeriod = ({ from, to }: {from: Date, to: Date}) => {
+    setPeriod(prev => prev && ({
+      ...prev,
+      start: from.getTime() / 1000,
+      end: to.getTime() / 1000,
+    }));
+  };
+
+  const handleTraceDelete = (trace: Trace) => {
+    setTracesState(prev => prev.filter((data) => data.idValue !== trace.idValue));
+  };
+
+  useEffect(() => {
+    setTracesState(traces);
+  }, [traces]);
+
+  useEffect(() => {
+    setQueryStringWithoutPageReload({});
+  }, []);
+
+  const UploadButton = () => (
+    <Tooltip title="The file must contain snapshot exported from the Query page">
+      <Button>
+        Upload snapshot
+        <input
+          id="snapshot"
+          type="file"
+          accept="application/json"
+          title=" "
+          onChange={handleChange}
+        />
+      </Button>
+    </Tooltip>
+  );
+
+  return (
+    <div className="vm-snapshot-page">
+      <div className="vm-snapshot-page-header">
+        <div>
+          {error && <Alert variant="error">{error}</Alert>}
+        </div>
+        {snapshot && <UploadButton/>}
+      </div>
+
+      {snapshot && period && (
+        <>
+          <div className="vm-snapshot-page-info vm-block">
+            <div className="vm-snapshot-page-info__row">
+              <b>File:</b>
+              <span>{filename}</span>
+            </div>
+            <div className="vm-snapshot-page-info__row">
+              <b>Exported at:</b>
+              <span>{snapshot.createdAt}</span>
+            </div>
+            <div className="vm-snapshot-page-info__row">
+              <b>Time range:</b>
+              <span>
+                {new Date(snapshot.period.start * 1000).toISOString()}
+                &nbsp;-&nbsp;
+                {new Date(snapshot.period.end * 1000).toISOString()}
+                {(snapshot.customStep || snapshot.period.step) && `, step: ${snapshot.customStep || snapshot.period.step}`}
+              </span>
+            </div>
+            {snapshot.query.map((q, i) => (
+              <div
+                className="vm-snapshot-page-info__row"
+                key={i}
+              >
+                <b>Query {i + 1}:</b>
+                <code className="vm-snapshot-page-info__query">
+                  {q}
+                  {snapshot.hideQuery.includes(i) && " (hidden)"}
+                </code>
+              </div>
+            ))}
+          </div>
+          {!!tracesState.length && (
+            <TracingsView
+              traces={tracesState}
+              onDeleteClick={handleTraceDelete}
+            />
+          )}
+          <div className="vm-snapshot-page-body vm-block">
+            {snapshot.displayType === "chart" && (
+              <GraphView
+                data={snapshot.data as MetricResult[]}
+                period={period}
+                customStep={snapshot.customStep}
+                query={snapshot.query}
+                yaxis={yaxis}
+                setYaxisLimits={setYaxisLimits}
+                setPeriod={handleSetPeriod}
+              />
+            )}
+            {snapshot.displayType === "code" && (
+              <JsonView data={snapshot.data as InstantMetricResult[]}/>
+            )}
+            {snapshot.displayType === "table" && (
+              <TableView data={snapshot.data as InstantMetricResult[]}/>
+            )}
+          </div>
+        </>
+      )}
+
+      {!snapshot && (
+        <div className="vm-snapshot-page-preview">
+          <p className="vm-snapshot-page-preview__text">
+            Please, upload snapshot file exported from the Query page.
+            {"\n"}
+            The snapshot contains queries, time range, query results and traces,
+            {"\n"}
+            so it can be reviewed without access to the original VictoriaMetrics instance.
+          </p>
+          <UploadButton/>
+        </div>
+      )}
+    </div>
+  );
+};
+
+export default SnapshotPage;
@@ -5,6 +5,7 @@ const router = {
   cardinality: "/cardinality",
   topQueries: "/top-queries",
   trace: "/trace",
+  snapshot: "/snapshot",
   icons: "/icons"
 };
 
@@ -61,6 +62,10 @@ export const routerOptions: {[key: string]: RouterOptions} = {
     title: "Trace analyzer",
     header: {}
   },
+  [router.snapshot]: {
+    title: "Snapshot viewer",
+    header: {}
+  },
   [router.dashboards]: {
     title: "Dashboards",
     ...routerOptionsDefault,
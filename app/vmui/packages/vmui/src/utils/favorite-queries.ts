@@ -0,0 +1,34 @@
+import { getFromStorage, saveToStorage } from "./storage";
+
+// FavoriteQueries holds the list of favorite queries per each tenant.
+type FavoriteQueries = Record<string, string[]>;
+
+// defaultTenantKey is used for storing favorite queries when tenant isn't set, e.g. in single-node VictoriaMetrics.
+const defaultTenantKey = "default";
+
+const getTenantKey = (tenantId: string) => tenantId || defaultTenantKey;
+
+const getAllFavoriteQueries = (): FavoriteQueries => {
+  const value = getFromStorage("FAVORITE_QUERIES");
+  if (!value || typeof value !== "object") return {};
+  return value as FavoriteQueries;
+};
+
+export const getFavoriteQueries = (tenantId: string): string[] => {
+  const queries = getAllFavoriteQueries()[getTenantKey(tenantId)];
+  return Array.isArray(queries) ? queries : [];
+};
+
+export const toggleFavoriteQuery = (tenantId: string, query: string): string[] => {
+  const all = getAllFavoriteQueries();
+  const key = getTenantKey(tenantId);
+  const queries = getFavoriteQueries(tenantId);
+  const updated = queries.includes(query) ? queries.filter(q => q !== query) : [...queries, query];
+  if (updated.length) {
+    all[key] = updated;
+  } else {
+    delete all[key];
+  }
+  saveToStorage("FAVORITE_QUERIES", Object.keys(all).length ? all : "");
+  return updated;
+};
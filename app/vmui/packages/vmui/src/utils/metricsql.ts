@@ -0,0 +1,134 @@
+import metricsqlFunctions from "../constants/metricsqlFunctions.json";
+
+export interface MetricsqlFunction {
+  name: string
+  type: "rollup" | "transform" | "label" | "aggregate"
+  signature: string
+  description: string
+  example?: string
+}
+
+export const functions: MetricsqlFunction[] = metricsqlFunctions as MetricsqlFunction[];
+
+const functionsByName = new Map(functions.map(f => [f.name, f]));
+
+export const getFunctionDocsUrl = (name: string): string =>
+  `https://docs.victoriametrics.com/MetricsQL.html#${name}`;
+
+export const getFunction = (name: string): MetricsqlFunction | undefined => functionsByName.get(name.toLowerCase());
+
+export enum QueryContextType {
+  // metric name or function name
+  metricsql = "metricsql",
+  labelName = "labelName",
+  labelValue = "labelValue",
+}
+
+export interface QueryContext {
+  type: QueryContextType
+  // the text before the caret, which must be completed
+  prefix: string
+  // the position of the prefix start in the query
+  start: number
+  // the metric name for label filters in curly braces
+  metricName?: string
+  // the label name for the label value
+  labelName?: string
+}
+
+const identRegexp = /[a-zA-Z_:.][a-zA-Z0-9_:.]*$/;
+const groupingRegexp = /\b(by|without|on|ignoring|group_left|group_right)\s*\(\s*([a-zA-Z0-9_.:,\s]*)$/i;
+const labelFilterRegexp = /([a-zA-Z_][a-zA-Z0-9_.]*)\s*(=~|!~|!=|=)\s*"((?:[^"\\]|\\.)*)$/;
+
+/**
+ * Returns the autocomplete context for the query at the given caret position.
+ */
+export const getQueryContext = (query: string, caret: number): QueryContext => {
+  const beforeCaret = query.slice(0, caret);
+
+  // Find the opening curly brace for label filters, which isn't closed before the caret.
+  let braceStart = -1;
+  let inQuotes = false;
+  for (let i = 0; i < beforeCaret.length; i++) {
+    const c = beforeCaret[i];
+    if (inQuotes) {
+      if (c === "\\") i++;
+      else if (c === "\"") inQuotes = false;
+      continue;
+    }
+    if (c === "\"") inQuotes = true;
+    else if (c === "{") braceStart = i;
+    else if (c === "}") braceStart = -1;
+  }
+
+  if (braceStart >= 0) {
+    const metricName = (query.slice(0, braceStart).match(identRegexp) || [])[0];
+    const filters = beforeCaret.slice(braceStart + 1);
+    if (inQuotes) {
+      const match = filters.match(labelFilterRegexp);
+      const prefix = match ? match[3] : "";
+      return {
+        type: QueryContextType.labelValue,
+        prefix,
+        start: caret - prefix.length,
+        metricName,
+        labelName: match ? match[1] : undefined,
+      };
+    }
+    const prefix = (filters.match(/[a-zA-Z_][a-zA-Z0-9_.]*$/) || [""])[0];
+    const isLabelName = /(^|,)\s*[a-zA-Z0-9_.]*$/.test(filters);
+    return {
+      type: isLabelName ? QueryContextType.labelName : QueryContextType.metricsql,
+      prefix: isLabelName ? prefix : "",
+      start: caret - prefix.length,
+      metricName,
+    };
+  }
+
+  if (!inQuotes) {
+    const grouping = beforeCaret.match(groupingRegexp);
+    if (grouping) {
+      const prefix = (grouping[2].match(/[a-zA-Z_][a-zA-Z0-9_.]*$/) || [""])[0];
+      return { type: QueryContextType.labelName, prefix, start: caret - prefix.length };
+    }
+  }
+
+  const prefix = inQuotes ? "" : (beforeCaret.match(identRegexp) || [""])[0];
+  return { type: QueryContextType.metricsql, prefix, start: caret - prefix.length };
+};
+
+/**
+ * Returns the identifier under the caret.
+ */
+export const getWordAtCaret = (query: string, caret: number): string => {
+  const before = (query.slice(0, caret).match(identRegexp) || [""])[0];
+  const after = (query.slice(caret).match(/^[a-zA-Z0-9_:.]*/) || [""])[0];
+  return before + after;
+};
+
+/**
+ * Replaces the prefix from the context with the selected autocomplete value.
+ *
+ * Returns the updated query and the caret position after the inserted value.
+ */
+export const insertAutocompleteValue = (query: string, caret: number, context: QueryContext, value: string): {
+  query: string,
+  caret: number
+} => {
+  let insert = value;
+  let end = caret;
+  if (context.type === QueryContextType.labelValue) {
+    insert = value.replace(/\\/g, "\\\\").replace(/"/g, "\\\"");
+  } else {
+    // Replace the rest of the identifier after the caret.
+    const rest = (query.slice(caret).match(/^[a-zA-Z0-9_:.]*/) || [""])[0];
+    end += rest.length;
+    if (context.type === QueryContextType.metricsql && functionsByName.has(value) && query[end] !== "(") {
+      insert += "(";
+    }
+  }
+  return {
+    query: query.slice(0, context.start) + insert + query.slice(end),
+    caret: context.start + insert.length,
+  };
+};
@@ -0,0 +1,77 @@
+import { InstantMetricResult, MetricResult, TracingData } from "../api/types";
+import { DisplayType } from "../pages/CustomPanel/DisplayTypeSwitch";
+import { TimeParams } from "../types";
+import Trace from "../components/TraceQuery/Trace";
+
+export const snapshotVersion = 1;
+
+export interface Snapshot {
+  version: number
+  createdAt: string
+  displayType: DisplayType
+  query: string[]
+  hideQuery: number[]
+  period: TimeParams
+  customStep: string
+  data: MetricResult[] | InstantMetricResult[]
+  traces: TracingData[]
+}
+
+interface CreateSnapshotParams {
+  displayType: DisplayType
+  query: string[]
+  hideQuery: number[]
+  period: TimeParams
+  customStep: string
+  data: MetricResult[] | InstantMetricResult[]
+  traces: Trace[]
+}
+
+export const createSnapshot = ({ traces, ...params }: CreateSnapshotParams): Snapshot => ({
+  version: snapshotVersion,
+  createdAt: new Date().toISOString(),
+  ...params,
+  traces: traces.map(t => JSON.parse(t.originalJSON)),
+});
+
+export const parseSnapshot = (json: string): Snapshot => {
+  const snapshot = JSON.parse(json);
+  if (!snapshot || typeof snapshot !== "object") {
+    throw new Error("snapshot must be a JSON object");
+  }
+  if (snapshot.version !== snapshotVersion) {
+    throw new Error(`unsupported snapshot version: ${snapshot.version}; supported version: ${snapshotVersion}`);
+  }
+  if (!["chart", "code", "table"].includes(snapshot.displayType)) {
+    throw new Error(`unsupported displayType: ${snapshot.displayType}`);
+  }
+  if (!Array.isArray(snapshot.query) || !Array.isArray(snapshot.data)) {
+    throw new Error("snapshot must contain query and data arrays");
+  }
+  const { start, end } = snapshot.period || {};
+  if (typeof start !== "number" || typeof end !== "number") {
+    throw new Error("snapshot must contain period with numeric start and end");
+  }
+  return {
+    ...snapshot,
+    hideQuery: snapshot.hideQuery || [],
+    customStep: snapshot.customStep || "",
+    traces: snapshot.traces || [],
+  };
+};
+
+export const getSnapshotFilename = (snapshot: Snapshot): string => {
+  return `vmui-snapshot-${snapshot.createdAt.replace(/[:.]/g, "-")}.json`;
+};
+
+export const downloadSnapshot = (snapshot: Snapshot) => {
+  const blob = new Blob([JSON.stringify(snapshot, null, 2)], { type: "application/json" });
+  const href = URL.createObjectURL(blob);
+  const link = document.createElement("a");
+  link.href = href;
+  link.download = getSnapshotFilename(snapshot);
+  document.body.appendChild(link);
+  link.click();
+  document.body.removeChild(link);
+  URL.revokeObjectURL(href);
+};
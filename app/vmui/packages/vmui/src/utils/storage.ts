@@ -8,6 +8,7 @@ export type StorageKeys = "BASIC_AUTH_DATA"
     | "TABLE_COMPACT"
     | "TIMEZONE"
     | "THEME"
+    | "FAVORITE_QUERIES"
 
 export const saveToStorage = (key: StorageKeys, value: string | boolean | Record<string, unknown>): void => {
   if (value) {
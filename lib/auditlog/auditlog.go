@@ -0,0 +1,341 @@
+// Package auditlog writes structured audit events for admin operations such as series deletion,
+// snapshot management and config reloads.
+//
+// Events are written in JSON lines format to the file specified via -auditLog.file
+// and/or are sent to the HTTP endpoint specified via -auditLog.url.
+//
+// See https://docs.victoriametrics.com/#audit-log
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	logFile = flag.String("auditLog.file", "", "Optional path to file for writing audit events for admin operations such as series deletion, "+
+		"snapshot management and config reloads. Events are written in JSON lines format. See https://docs.victoriametrics.com/#audit-log")
+	logURL = flag.String("auditLog.url", "", "Optional url for sending audit events for admin operations via HTTP POST requests in JSON lines format. "+
+		"See https://docs.victoriametrics.com/#audit-log")
+	userHeader = flag.String("auditLog.userHeader", "", "Optional HTTP request header with the name of the user, who initiated the admin operation. "+
+		"For example, X-Webauth-User. By default the username from Basic Auth is used. See https://docs.victoriametrics.com/#audit-log")
+	maxQueueSize = flag.Int("auditLog.maxQueueSize", 10000, "The maximum number of audit events, which may be queued for sending to -auditLog.url. "+
+		"Events are dropped when the queue is full")
+	sendTimeout = flag.Duration("auditLog.sendTimeout", 10*time.Second, "Timeout for sending audit events to -auditLog.url")
+)
+
+func init() {
+	// The -auditLog.url flag can contain basic auth creds, so it mustn't be visible when exposing the flags.
+	flagutil.RegisterSecretFlag("auditLog.url")
+}
+
+// Event is an audit event for admin operation.
+type Event struct {
+	// Timestamp is the time of the event in RFC3339 format.
+	Timestamp string `json:"ts"`
+
+	// Component is the name of the component, which generated the event.
+	Component string `json:"component"`
+
+	// Action is the name of admin operation. For example, delete_series or snapshot_create.
+	Action string `json:"action"`
+
+	// User is the name of the user, who initiated the operation.
+	User string `json:"user,omitempty"`
+
+	// RemoteAddr is the address of the client, which initiated the operation.
+	RemoteAddr string `json:"remote_addr,omitempty"`
+
+	// ForwardedFor is the value of X-Forwarded-For request header.
+	ForwardedFor string `json:"forwarded_for,omitempty"`
+
+	// Path is the request path for the operation.
+	Path string `json:"path,omitempty"`
+
+	// Target is the object of the operation. For example, snapshot name or config file path.
+	Target string `json:"target,omitempty"`
+
+	// Matches contains series selectors for the operation.
+	Matches []string `json:"match,omitempty"`
+
+	// Series is the number of series matched by the operation.
+	//
+	// It is a pointer, since zero matched series must be distinguished from operations without series.
+	Series *int `json:"series,omitempty"`
+
+	// Details contains additional operation-specific details.
+	Details map[string]string `json:"details,omitempty"`
+
+	// Status is either success or error.
+	Status string `json:"status"`
+
+	// Error contains the error for the failed operation.
+	Error string `json:"error,omitempty"`
+}
+
+// SetSeries sets the number of series matched by the operation.
+func (e *Event) SetSeries(n int) {
+	e.Series = &n
+}
+
+// SetDetail sets the detail with the given key to value.
+func (e *Event) SetDetail(key, value string) {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+	e.Details[key] = value
+}
+
+// NewEvent returns new audit event for the given action initiated by r.
+//
+// r may be nil for operations, which aren't initiated by HTTP requests. For example, for config reloads on SIGHUP.
+func NewEvent(r *http.Request, action string) *Event {
+	e := &Event{
+		Component: component,
+		Action:    action,
+	}
+	if r == nil {
+		return e
+	}
+	e.RemoteAddr = r.RemoteAddr
+	e.ForwardedFor = r.Header.Get("X-Forwarded-For")
+	e.Path = r.URL.Path
+	if *userHeader != "" {
+		e.User = r.Header.Get(*userHeader)
+	}
+	if e.User == "" {
+		e.User, _, _ = r.BasicAuth()
+	}
+	return e
+}
+
+var component = filepath.Base(os.Args[0])
+
+// IsEnabled returns true if audit log is enabled via -auditLog.file or -auditLog.url.
+func IsEnabled() bool {
+	return *logFile != "" || *logURL != ""
+}
+
+// Log writes e to the audit log with the status according to err.
+//
+// It is safe calling Log if audit log isn't enabled.
+func Log(e *Event, err error) {
+	if !IsEnabled() {
+		return
+	}
+	e.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	if err != nil {
+		e.Status = "error"
+		e.Error = err.Error()
+	} else {
+		e.Status = "success"
+	}
+	data, errMarshal := json.Marshal(e)
+	if errMarshal != nil {
+		logger.Panicf("BUG: cannot marshal audit event to JSON: %s", errMarshal)
+	}
+	data = append(data, '\n')
+	eventsTotal.Inc()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if f != nil {
+		if _, err := f.Write(data); err != nil {
+			fileErrors.Inc()
+			logger.Errorf("cannot write audit event to -auditLog.file=%q: %s; event: %s", *logFile, err, data)
+		}
+	}
+	if s != nil {
+		select {
+		case s.ch <- data:
+		default:
+			eventsDropped.Inc()
+			logger.Errorf("dropping audit event, since the queue for -auditLog.url is full; event: %s", data)
+		}
+	}
+}
+
+var (
+	// mu protects f and s
+	mu sync.Mutex
+	f  *os.File
+	s  *sender
+)
+
+var (
+	eventsTotal   = metrics.NewCounter(`vm_audit_log_events_total`)
+	eventsDropped = metrics.NewCounter(`vm_audit_log_events_dropped_total`)
+	fileErrors    = metrics.NewCounter(`vm_audit_log_errors_total{sink="file"}`)
+	urlErrors     = metrics.NewCounter(`vm_audit_log_errors_total{sink="url"}`)
+)
+
+// Init initializes audit log according to the provided command-line flags.
+//
+// MustStop must be called when the audit log is no longer needed.
+func Init() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if *logFile != "" {
+		file, err := os.OpenFile(*logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			logger.Fatalf("cannot open -auditLog.file=%q: %s", *logFile, err)
+		}
+		f = file
+	}
+	if *logURL != "" {
+		if _, err := url.Parse(*logURL); err != nil {
+			logger.Fatalf("cannot parse -auditLog.url: %s", err)
+		}
+		s = newSender(*logURL, *maxQueueSize)
+	}
+}
+
+var _ = metrics.NewGauge(`vm_audit_log_queue_size`, func() float64 {
+	mu.Lock()
+	defer mu.Unlock()
+	if s == nil {
+		return 0
+	}
+	return float64(len(s.ch))
+})
+
+// MustStop stops the audit log.
+//
+// Events queued for sending to -auditLog.url are sent before returning.
+func MustStop() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if f != nil {
+		if err := f.Close(); err != nil {
+			logger.Panicf("FATAL: cannot close -auditLog.file=%q: %s", *logFile, err)
+		}
+		f = nil
+	}
+	if s != nil {
+		s.mustStop()
+		s = nil
+	}
+}
+
+type sender struct {
+	url    string
+	c      *http.Client
+	ch     chan []byte
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newSender(u string, queueSize int) *sender {
+	s := &sender{
+		url: u,
+		c: &http.Client{
+			Timeout: *sendTimeout,
+		},
+		ch:     make(chan []byte, queueSize),
+		stopCh: make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run()
+	}()
+	return s
+}
+
+func (s *sender) mustStop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+const maxBatchSize = 1000
+
+func (s *sender) run() {
+	var batch []byte
+	for {
+		var data []byte
+		select {
+		case <-s.stopCh:
+			s.flushPending(batch)
+			return
+		case data = <-s.ch:
+		}
+		batch = append(batch[:0], data...)
+		batch = s.readQueued(batch)
+		// Retry sending the batch until success or stop, since audit events mustn't be lost on temporary errors.
+		retryDelay := time.Second
+		for {
+			err := s.send(batch)
+			if err == nil {
+				batch = batch[:0]
+				break
+			}
+			urlErrors.Inc()
+			logger.Errorf("cannot send audit events to -auditLog.url: %s; retrying in %s", err, retryDelay)
+			select {
+			case <-s.stopCh:
+				s.flushPending(batch)
+				return
+			case <-time.After(retryDelay):
+			}
+			retryDelay *= 2
+			if retryDelay > time.Minute {
+				retryDelay = time.Minute
+			}
+		}
+	}
+}
+
+// readQueued appends up to maxBatchSize-1 queued events to batch without blocking.
+func (s *sender) readQueued(batch []byte) []byte {
+	for i := 1; i < maxBatchSize; i++ {
+		select {
+		case data := <-s.ch:
+			batch = append(batch, data...)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// flushPending makes the last attempt to send batch and the queued events on shutdown.
+func (s *sender) flushPending(batch []byte) {
+	for {
+		batch = s.readQueued(batch)
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.send(batch); err != nil {
+			urlErrors.Inc()
+			logger.Errorf("cannot send audit events to -auditLog.url on shutdown: %s; the following events are lost: %s", err, batch)
+		}
+		batch = batch[:0]
+	}
+}
+
+func (s *sender) send(batch []byte) error {
+	resp, err := s.c.Post(s.url, "application/x-ndjson", bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response code %d; want 2xx", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewEvent(t *testing.T) {
+	r, err := http.NewRequest(http.MethodPost, "http://localhost/api/v1/admin/tsdb/delete_series", nil)
+	if err != nil {
+		t.Fatalf("cannot create request: %s", err)
+	}
+	r.RemoteAddr = "1.2.3.4:5678"
+	r.Header.Set("X-Forwarded-For", "5.6.7.8")
+	r.SetBasicAuth("foo", "bar")
+
+	e := NewEvent(r, "delete_series")
+	if e.User != "foo" || e.RemoteAddr != "1.2.3.4:5678" || e.ForwardedFor != "5.6.7.8" || e.Path != "/api/v1/admin/tsdb/delete_series" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+
+	*userHeader = "X-Webauth-User"
+	defer func() {
+		*userHeader = ""
+	}()
+	r.Header.Set("X-Webauth-User", "baz")
+	if e := NewEvent(r, "delete_series"); e.User != "baz" {
+		t.Fatalf("unexpected user; got %q; want %q", e.User, "baz")
+	}
+
+	if e := NewEvent(nil, "config_reload"); e.Action != "config_reload" || e.User != "" || e.Path != "" {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+}
+
+func TestLog(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("cannot read request body: %s", err)
+		}
+		mu.Lock()
+		received = append(received, strings.Split(strings.TrimSpace(string(data)), "\n")...)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	*logFile = path
+	*logURL = srv.URL
+	defer func() {
+		*logFile = ""
+		*logURL = ""
+	}()
+	Init()
+
+	e := NewEvent(nil, "delete_series")
+	e.Matches = []string{`{job="foo"}`}
+	e.SetSeries(0)
+	Log(e, nil)
+
+	e = NewEvent(nil, "snapshot_delete")
+	e.Target = "foobar"
+	Log(e, fmt.Errorf("cannot find snapshot"))
+
+	MustStop()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("cannot open audit log: %s", err)
+	}
+	defer func() { _ = file.Close() }()
+	var lines []string
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatalf("cannot read audit log: %s", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("unexpected number of events in the file; got %d; want 2", len(lines))
+	}
+	if fmt.Sprintf("%q", lines) != fmt.Sprintf("%q", received) {
+		t.Fatalf("events in the file and sent to the url mismatch;\nfile:\n%s\nurl:\n%s", lines, received)
+	}
+
+	var events []Event
+	for _, line := range lines {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("cannot unmarshal event %q: %s", line, err)
+		}
+		events = append(events, e)
+	}
+	if e := events[0]; e.Action != "delete_series" || e.Status != "success" || e.Series == nil || *e.Series != 0 || len(e.Matches) != 1 || e.Timestamp == "" {
+		t.Fatalf("unexpected event: %s", lines[0])
+	}
+	if e := events[1]; e.Action != "snapshot_delete" || e.Status != "error" || e.Error != "cannot find snapshot" || e.Series != nil || e.Target != "foobar" {
+		t.Fatalf("unexpected event: %s", lines[1])
+	}
+}
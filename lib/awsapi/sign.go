@@ -47,6 +47,13 @@ func signRequestWithTime(req *http.Request, service, region, payloadHash string,
 
 	canonicalHeaders := fmt.Sprintf("host:%s\nx-amz-date:%s\n", uri.Host, amzdate)
 	signedHeaders := "host;x-amz-date"
+	if service == "s3" {
+		// S3 requires signed x-amz-content-sha256 header in all the requests.
+		// See https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+		canonicalHeaders = fmt.Sprintf("host:%s\nx-amz-content-sha256:%s\nx-amz-date:%s\n", uri.Host, payloadHash, amzdate)
+		signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+		req.Header.Set("x-amz-content-sha256", payloadHash)
+	}
 	tmp := []string{
 		req.Method,
 		canonicalURL,
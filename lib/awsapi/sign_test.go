@@ -1,6 +1,8 @@
 package awsapi
 
 import (
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 )
@@ -27,3 +29,26 @@ func TestNewSignedRequest(t *testing.T) {
 	f("https://ec2.amazonaws.com/?Action=DescribeRegions&Version=2013-10-15",
 		"AWS4-HMAC-SHA256 Credential=fake-access-key/19700101/us-east-1/ec2/aws4_request, SignedHeaders=host;x-amz-date, Signature=79dc8f54719a4c11edcd5811824a071361b3514172a3f5c903b7e279dfa6a710")
 }
+
+func TestSignRequestS3(t *testing.T) {
+	ac := &credentials{
+		AccessKeyID:     "fake-access-key",
+		SecretAccessKey: "foobar",
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://bucket.s3.us-east-1.amazonaws.com/path/to/targets.json", nil)
+	if err != nil {
+		t.Fatalf("cannot create request: %s", err)
+	}
+	payloadHash := hashHex("")
+	if err := signRequestWithTime(req, "s3", "us-east-1", payloadHash, ac, time.Unix(0, 0).UTC()); err != nil {
+		t.Fatalf("cannot sign request: %s", err)
+	}
+	if h := req.Header.Get("x-amz-content-sha256"); h != payloadHash {
+		t.Fatalf("unexpected x-amz-content-sha256 header; got %q; want %q", h, payloadHash)
+	}
+	authHeader := req.Header.Get("Authorization")
+	authHeaderPrefix := "AWS4-HMAC-SHA256 Credential=fake-access-key/19700101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="
+	if !strings.HasPrefix(authHeader, authHeaderPrefix) {
+		t.Fatalf("unexpected auth header;\ngot\n%s\nwant prefix\n%s", authHeader, authHeaderPrefix)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fsremote"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/gcsremote"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/s3remote"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 )
 
 var (
@@ -23,6 +24,22 @@ var (
 		"or if both not set, DefaultSharedConfigProfile is used")
 	customS3Endpoint = flag.String("customS3Endpoint", "", "Custom S3 endpoint for use with S3-compatible storages (e.g. MinIO). S3 is used if not set")
 	s3ForcePathStyle = flag.Bool("s3ForcePathStyle", true, "Prefixing endpoint with bucket name when set false, true by default.")
+	s3ObjectLockMode = flag.String("s3ObjectLockMode", "", "Optional S3 Object Lock retention mode for uploaded objects: GOVERNANCE or COMPLIANCE. "+
+		"The bucket must have Object Lock enabled. See also -s3ObjectLockRetention and https://docs.victoriametrics.com/vmbackup.html#immutable-backups")
+	s3ObjectLockRetention = flag.Duration("s3ObjectLockRetention", 0, "Retention period for objects uploaded to S3 when -s3ObjectLockMode is set. "+
+		"Objects cannot be deleted or overwritten during this period")
+	s3ChecksumAlgorithm = flag.String("s3ChecksumAlgorithm", "", "Optional checksum algorithm for verifying objects uploaded to S3: SHA256 or CRC32C. "+
+		"Checksums returned by S3 are compared to locally calculated checksums after each upload. CRC32C is used by default if -s3ObjectLockMode is set. "+
+		"See https://docs.victoriametrics.com/vmbackup.html#immutable-backups")
+	s3UploadConcurrency = flag.Int("s3UploadConcurrency", 1, "The number of concurrent multipart upload requests per each object uploaded to S3. "+
+		"The total number of concurrent upload requests is -concurrency multiplied by -s3UploadConcurrency. "+
+		"See https://docs.victoriametrics.com/vmbackup.html#multipart-uploads")
+	s3UploadPartSize = flagutil.NewBytes("s3UploadPartSize", 0, "The size of multipart upload part for objects uploaded to S3. "+
+		"The part size is selected automatically depending on the object size if it is set to 0. "+
+		"See https://docs.victoriametrics.com/vmbackup.html#multipart-uploads")
+	s3UploadStateDir = flag.String("s3UploadStateDir", "", "Optional local directory for persisting the state of multipart uploads to S3. "+
+		"Interrupted uploads are resumed from the last uploaded part on the next run if it is set. "+
+		"See https://docs.victoriametrics.com/vmbackup.html#multipart-uploads")
 )
 
 func runParallel(concurrency int, parts []common.Part, f func(p common.Part) error, progress func(elapsed time.Duration)) error {
@@ -236,13 +253,19 @@ func NewRemoteFS(path string) (common.RemoteFS, error) {
 		bucket := dir[:n]
 		dir = dir[n:]
 		fs := &s3remote.FS{
-			CredsFilePath:    *credsFilePath,
-			ConfigFilePath:   *configFilePath,
-			CustomEndpoint:   *customS3Endpoint,
-			S3ForcePathStyle: *s3ForcePathStyle,
-			ProfileName:      *configProfile,
-			Bucket:           bucket,
-			Dir:              dir,
+			CredsFilePath:       *credsFilePath,
+			ConfigFilePath:      *configFilePath,
+			CustomEndpoint:      *customS3Endpoint,
+			S3ForcePathStyle:    *s3ForcePathStyle,
+			ProfileName:         *configProfile,
+			Bucket:              bucket,
+			Dir:                 dir,
+			ObjectLockMode:      *s3ObjectLockMode,
+			ObjectLockRetention: *s3ObjectLockRetention,
+			ChecksumAlgorithm:   *s3ChecksumAlgorithm,
+			UploadConcurrency:   *s3UploadConcurrency,
+			UploadPartSize:      s3UploadPartSize.N,
+			UploadStateDir:      *s3UploadStateDir,
 		}
 		if err := fs.Init(); err != nil {
 			return nil, fmt.Errorf("cannot initialize connection to s3: %w", err)
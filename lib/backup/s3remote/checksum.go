@@ -0,0 +1,116 @@
+package s3remote
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// newChecksumHash returns a function for creating hashes for the given checksum algorithm.
+func newChecksumHash(algorithm types.ChecksumAlgorithm) (func() hash.Hash, error) {
+	switch algorithm {
+	case types.ChecksumAlgorithmSha256:
+		return sha256.New, nil
+	case types.ChecksumAlgorithmCrc32c:
+		return func() hash.Hash {
+			return crc32.New(crc32cTable)
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported checksum algorithm %q; supported values: %s, %s", algorithm, types.ChecksumAlgorithmSha256, types.ChecksumAlgorithmCrc32c)
+	}
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// checksumReader calculates the checksum for the data read from r in the same way as S3 does for uploaded objects.
+//
+// Objects uploaded via multipart upload have composite checksums, which are calculated over checksums
+// for parts with partSize bytes. See https://docs.aws.amazon.com/AmazonS3/latest/userguide/checking-object-integrity.html
+type checksumReader struct {
+	r        io.Reader
+	newHash  func() hash.Hash
+	partSize int64
+
+	h           hash.Hash
+	partHash    hash.Hash
+	partLen     int64
+	partDigests []byte
+	parts       int
+}
+
+func newChecksumReader(r io.Reader, newHash func() hash.Hash, partSize int64) *checksumReader {
+	return &checksumReader{
+		r:        r,
+		newHash:  newHash,
+		partSize: partSize,
+		h:        newHash(),
+		partHash: newHash(),
+	}
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.update(p[:n])
+	return n, err
+}
+
+func (cr *checksumReader) update(b []byte) {
+	_, _ = cr.h.Write(b)
+	for len(b) > 0 {
+		n := cr.partSize - cr.partLen
+		if n > int64(len(b)) {
+			n = int64(len(b))
+		}
+		_, _ = cr.partHash.Write(b[:n])
+		cr.partLen += n
+		b = b[n:]
+		if cr.partLen == cr.partSize {
+			cr.finishPart()
+		}
+	}
+}
+
+func (cr *checksumReader) finishPart() {
+	cr.partDigests = cr.partHash.Sum(cr.partDigests)
+	cr.parts++
+	cr.partHash.Reset()
+	cr.partLen = 0
+}
+
+// verify verifies whether the checksum returned by the server for the uploaded object matches the data read from cr.
+func (cr *checksumReader) verify(serverChecksum *string) error {
+	if serverChecksum == nil || *serverChecksum == "" {
+		return fmt.Errorf("the server didn't return the checksum for the uploaded object; make sure the storage supports additional checksums")
+	}
+	checksum := *serverChecksum
+	n := strings.IndexByte(checksum, '-')
+	if n < 0 {
+		expected := base64.StdEncoding.EncodeToString(cr.h.Sum(nil))
+		if checksum != expected {
+			return fmt.Errorf("checksum mismatch for the uploaded object; got %q from the server; want %q", checksum, expected)
+		}
+		return nil
+	}
+	// The object has been uploaded via multipart upload.
+	if cr.partLen > 0 {
+		cr.finishPart()
+	}
+	expectedParts := strconv.Itoa(cr.parts)
+	if checksum[n+1:] != expectedParts {
+		return fmt.Errorf("unexpected number of parts in the checksum for the uploaded object; got %q from the server; want %s parts", checksum, expectedParts)
+	}
+	h := cr.newHash()
+	_, _ = h.Write(cr.partDigests)
+	expected := base64.StdEncoding.EncodeToString(h.Sum(nil)) + "-" + expectedParts
+	if checksum != expected {
+		return fmt.Errorf("checksum mismatch for the uploaded object; got %q from the server; want %q", checksum, expected)
+	}
+	return nil
+}
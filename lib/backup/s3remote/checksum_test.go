@@ -0,0 +1,93 @@
+package s3remote
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestChecksumReaderVerifySuccess(t *testing.T) {
+	f := func(algorithm types.ChecksumAlgorithm, data string, partSize int64, serverChecksum string) {
+		t.Helper()
+		newHash, err := newChecksumHash(algorithm)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		cr := newChecksumReader(iotest.HalfReader(strings.NewReader(data)), newHash, partSize)
+		if _, err := io.Copy(io.Discard, cr); err != nil {
+			t.Fatalf("cannot read data: %s", err)
+		}
+		if err := cr.verify(&serverChecksum); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	sha256Base64 := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return base64.StdEncoding.EncodeToString(h[:])
+	}
+	crc32cBase64 := func(s string) string {
+		h := crc32.New(crc32cTable)
+		_, _ = h.Write([]byte(s))
+		return base64.StdEncoding.EncodeToString(h.Sum(nil))
+	}
+
+	// Single part upload
+	f(types.ChecksumAlgorithmSha256, "foobar", 10, sha256Base64("foobar"))
+	f(types.ChecksumAlgorithmCrc32c, "foobar", 10, crc32cBase64("foobar"))
+
+	// Multipart upload
+	parts := sha256.Sum256([]byte("abcd"))
+	digests := append([]byte{}, parts[:]...)
+	parts = sha256.Sum256([]byte("efgh"))
+	digests = append(digests, parts[:]...)
+	parts = sha256.Sum256([]byte("ij"))
+	digests = append(digests, parts[:]...)
+	f(types.ChecksumAlgorithmSha256, "abcdefghij", 4, sha256Base64(string(digests))+"-3")
+
+	// Multipart upload with the size, which is multiple of part size
+	parts = sha256.Sum256([]byte("abcd"))
+	digests = append([]byte{}, parts[:]...)
+	parts = sha256.Sum256([]byte("efgh"))
+	digests = append(digests, parts[:]...)
+	f(types.ChecksumAlgorithmSha256, "abcdefgh", 4, sha256Base64(string(digests))+"-2")
+}
+
+func TestChecksumReaderVerifyFailure(t *testing.T) {
+	f := func(data string, partSize int64, serverChecksum *string) {
+		t.Helper()
+		cr := newChecksumReader(strings.NewReader(data), sha256.New, partSize)
+		if _, err := io.Copy(io.Discard, cr); err != nil {
+			t.Fatalf("cannot read data: %s", err)
+		}
+		if err := cr.verify(serverChecksum); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	s := func(s string) *string {
+		return &s
+	}
+
+	// Missing checksum
+	f("foobar", 10, nil)
+	f("foobar", 10, s(""))
+
+	// Checksum mismatch
+	f("foobar", 10, s("invalid"))
+	f("abcdefghij", 4, s("invalid-3"))
+
+	// Unexpected number of parts
+	f("abcdefghij", 4, s("invalid-2"))
+}
+
+func TestNewChecksumHashFailure(t *testing.T) {
+	if _, err := newChecksumHash("MD5"); err == nil {
+		t.Fatalf("expecting non-nil error for unsupported checksum algorithm")
+	}
+}
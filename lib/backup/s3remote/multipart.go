@@ -0,0 +1,353 @@
+package s3remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+const (
+	// minUploadPartSize is the minimum size of multipart upload part supported by S3.
+	minUploadPartSize = 5 * 1024 * 1024
+
+	// maxUploadPartSize is the maximum size of multipart upload part for adaptive part sizing.
+	maxUploadPartSize = 512 * 1024 * 1024
+
+	// maxUploadParts is the maximum number of parts in multipart upload supported by S3.
+	maxUploadParts = 10000
+
+	// targetUploadParts is the number of parts adaptive part sizing aims for.
+	//
+	// This allows uploading big objects with high concurrency, while keeping the number of requests low.
+	targetUploadParts = 64
+)
+
+// getUploadPartSize returns the size of multipart upload part for the object with the given size.
+//
+// If partSize is positive, then it is used as is after adjusting it to S3 limits.
+// Otherwise the part size is selected adaptively depending on objectSize.
+func getUploadPartSize(objectSize uint64, partSize int64) int64 {
+	if partSize <= 0 {
+		partSize = int64(objectSize / targetUploadParts)
+		// Round the part size up to MiB.
+		partSize = (partSize + (1<<20 - 1)) &^ (1<<20 - 1)
+		if partSize > maxUploadPartSize {
+			partSize = maxUploadPartSize
+		}
+	}
+	if partSize < minUploadPartSize {
+		partSize = minUploadPartSize
+	}
+	if n := int64((objectSize + maxUploadParts - 1) / maxUploadParts); partSize < n {
+		partSize = n
+	}
+	return partSize
+}
+
+// uploadState is the state of resumable multipart upload, which is persisted locally.
+type uploadState struct {
+	Bucket   string `json:"bucket"`
+	Key      string `json:"key"`
+	Size     uint64 `json:"size"`
+	PartSize int64  `json:"partSize"`
+	UploadID string `json:"uploadID"`
+}
+
+func (fs *FS) uploadStatePath(path string) string {
+	h := xxhash.Sum64([]byte(fs.Bucket + "/" + path))
+	return filepath.Join(fs.UploadStateDir, fmt.Sprintf("%016X.json", h))
+}
+
+// readUploadState reads upload state from statePath.
+//
+// nil is returned if the state is missing or if it doesn't match the given expected state.
+func readUploadState(statePath string, expected *uploadState) (*uploadState, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read upload state: %w", err)
+	}
+	var us uploadState
+	if err := json.Unmarshal(data, &us); err != nil {
+		logger.Warnf("ignoring broken upload state at %q: %s", statePath, err)
+		return nil, nil
+	}
+	if us.Bucket != expected.Bucket || us.Key != expected.Key || us.Size != expected.Size || us.PartSize != expected.PartSize || us.UploadID == "" {
+		return nil, nil
+	}
+	return &us, nil
+}
+
+func writeUploadState(statePath string, us *uploadState) error {
+	data, err := json.Marshal(us)
+	if err != nil {
+		return fmt.Errorf("cannot marshal upload state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0755); err != nil {
+		return fmt.Errorf("cannot create directory for upload state: %w", err)
+	}
+	if err := fs.WriteFileAtomically(statePath, data, true); err != nil {
+		return fmt.Errorf("cannot write upload state: %w", err)
+	}
+	return nil
+}
+
+// uploadResumable uploads size bytes from r to the given path at fs via multipart upload.
+//
+// The multipart upload id is persisted at fs.UploadStateDir, so the upload is resumed
+// from the last uploaded part if the previous attempt has failed.
+func (fs *FS) uploadResumable(path string, r io.Reader, size uint64, partSize int64) error {
+	statePath := fs.uploadStatePath(path)
+	expected := &uploadState{
+		Bucket:   fs.Bucket,
+		Key:      path,
+		Size:     size,
+		PartSize: partSize,
+	}
+	us, err := readUploadState(statePath, expected)
+	if err != nil {
+		return err
+	}
+	var uploaded map[int32]types.Part
+	if us != nil {
+		uploaded, err = fs.listUploadedParts(us)
+		if err != nil {
+			var nsu *types.NoSuchUpload
+			if !errors.As(err, &nsu) {
+				return err
+			}
+			logger.Infof("cannot resume upload of %q at %s, since the upload %q is missing; starting new upload", path, fs, us.UploadID)
+			us = nil
+		}
+	}
+	if us == nil {
+		us = expected
+		us.UploadID, err = fs.createMultipartUpload(path)
+		if err != nil {
+			return err
+		}
+		if err := writeUploadState(statePath, us); err != nil {
+			return err
+		}
+		uploaded = nil
+	}
+
+	var cr *checksumReader
+	if fs.newChecksumHash != nil {
+		cr = newChecksumReader(r, fs.newChecksumHash, partSize)
+		r = cr
+	}
+	completed, err := fs.uploadParts(us, r, uploaded)
+	if err != nil {
+		// Leave the upload and its state, so it could be resumed on the next run.
+		return err
+	}
+
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(us.Bucket),
+		Key:      aws.String(us.Key),
+		UploadId: aws.String(us.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completed,
+		},
+	}
+	out, err := fs.s3.CompleteMultipartUpload(context.Background(), input)
+	if err != nil {
+		return fmt.Errorf("cannot complete multipart upload: %w", err)
+	}
+	if err := os.Remove(statePath); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("cannot remove upload state at %q: %s", statePath, err)
+	}
+	if cr == nil {
+		return nil
+	}
+	serverChecksum := out.ChecksumCRC32C
+	if fs.ChecksumAlgorithm == string(types.ChecksumAlgorithmSha256) {
+		serverChecksum = out.ChecksumSHA256
+	}
+	if err := cr.verify(serverChecksum); err != nil {
+		checksumMismatches.Inc()
+		return err
+	}
+	return nil
+}
+
+func (fs *FS) createMultipartUpload(path string) (string, error) {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(fs.Bucket),
+		Key:    aws.String(path),
+	}
+	if fs.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(fs.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = fs.objectLockRetainUntilDate()
+	}
+	if fs.newChecksumHash != nil {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(fs.ChecksumAlgorithm)
+	}
+	out, err := fs.s3.CreateMultipartUpload(context.Background(), input)
+	if err != nil {
+		return "", fmt.Errorf("cannot create multipart upload: %w", err)
+	}
+	return *out.UploadId, nil
+}
+
+// listUploadedParts returns parts already uploaded for us.
+func (fs *FS) listUploadedParts(us *uploadState) (map[int32]types.Part, error) {
+	uploaded := make(map[int32]types.Part)
+	paginator := s3.NewListPartsPaginator(fs.s3, &s3.ListPartsInput{
+		Bucket:   aws.String(us.Bucket),
+		Key:      aws.String(us.Key),
+		UploadId: aws.String(us.UploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("cannot list uploaded parts for upload %q: %w", us.UploadID, err)
+		}
+		for _, p := range page.Parts {
+			uploaded[p.PartNumber] = p
+		}
+	}
+	return uploaded, nil
+}
+
+// uploadParts uploads parts read from r for us with fs.UploadConcurrency workers.
+//
+// Parts from uploaded with the expected size are skipped.
+func (fs *FS) uploadParts(us *uploadState, r io.Reader, uploaded map[int32]types.Part) ([]types.CompletedPart, error) {
+	concurrency := fs.UploadConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	type partWork struct {
+		number int32
+		data   []byte
+	}
+	workCh := make(chan partWork)
+	bufCh := make(chan []byte, concurrency)
+	for i := 0; i < concurrency; i++ {
+		bufCh <- make([]byte, us.PartSize)
+	}
+
+	var mu sync.Mutex
+	var completed []types.CompletedPart
+	var uploadErr error
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pw := range workCh {
+				cp, err := fs.uploadPart(us, pw.number, pw.data)
+				bufCh <- pw.data[:cap(pw.data)]
+				mu.Lock()
+				if err != nil {
+					if uploadErr == nil {
+						uploadErr = err
+					}
+				} else {
+					completed = append(completed, cp)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	var readErr error
+	remaining := us.Size
+	for number := int32(1); remaining > 0; number++ {
+		mu.Lock()
+		err := uploadErr
+		mu.Unlock()
+		if err != nil {
+			break
+		}
+		n := uint64(us.PartSize)
+		if n > remaining {
+			n = remaining
+		}
+		buf := <-bufCh
+		data := buf[:n]
+		if _, err := io.ReadFull(r, data); err != nil {
+			bufCh <- buf
+			readErr = fmt.Errorf("cannot read part #%d: %w", number, err)
+			break
+		}
+		remaining -= n
+		if p, ok := uploaded[number]; ok && uint64(p.Size) == n {
+			// The part has been already uploaded during the previous attempt.
+			bufCh <- buf
+			resumedBytes.Add(int(n))
+			mu.Lock()
+			completed = append(completed, types.CompletedPart{
+				PartNumber:     number,
+				ETag:           p.ETag,
+				ChecksumCRC32C: p.ChecksumCRC32C,
+				ChecksumSHA256: p.ChecksumSHA256,
+			})
+			mu.Unlock()
+			continue
+		}
+		workCh <- partWork{
+			number: number,
+			data:   data,
+		}
+	}
+	close(workCh)
+	wg.Wait()
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	if uploadErr != nil {
+		return nil, uploadErr
+	}
+	sort.Slice(completed, func(i, j int) bool {
+		return completed[i].PartNumber < completed[j].PartNumber
+	})
+	return completed, nil
+}
+
+func (fs *FS) uploadPart(us *uploadState, number int32, data []byte) (types.CompletedPart, error) {
+	input := &s3.UploadPartInput{
+		Bucket:        aws.String(us.Bucket),
+		Key:           aws.String(us.Key),
+		UploadId:      aws.String(us.UploadID),
+		PartNumber:    number,
+		Body:          bytes.NewReader(data),
+		ContentLength: int64(len(data)),
+	}
+	if fs.newChecksumHash != nil {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(fs.ChecksumAlgorithm)
+	}
+	out, err := fs.s3.UploadPart(context.Background(), input)
+	if err != nil {
+		return types.CompletedPart{}, fmt.Errorf("cannot upload part #%d for upload %q: %w", number, us.UploadID, err)
+	}
+	return types.CompletedPart{
+		PartNumber:     number,
+		ETag:           out.ETag,
+		ChecksumCRC32C: out.ChecksumCRC32C,
+		ChecksumSHA256: out.ChecksumSHA256,
+	}, nil
+}
+
+var resumedBytes = metrics.NewCounter(`vm_backup_resumed_bytes_total{type="s3"}`)
@@ -0,0 +1,97 @@
+package s3remote
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetUploadPartSize(t *testing.T) {
+	f := func(objectSize uint64, partSize, expected int64) {
+		t.Helper()
+		result := getUploadPartSize(objectSize, partSize)
+		if result != expected {
+			t.Fatalf("unexpected part size for objectSize=%d, partSize=%d; got %d; want %d", objectSize, partSize, result, expected)
+		}
+	}
+	const mib = 1024 * 1024
+
+	// adaptive part size
+	f(0, 0, minUploadPartSize)
+	f(1024, 0, minUploadPartSize)
+	f(100*mib, 0, minUploadPartSize)
+	f(1024*mib, 0, 16*mib)
+	f(1000*mib, 0, 16*mib)
+	f(64*1024*mib, 0, maxUploadPartSize)
+	f(10000*1024*mib, 0, 1024*mib)
+	f(10000*1024*mib+1, 0, 1024*mib+1)
+
+	// explicitly set part size
+	f(1024*mib, 100*mib, 100*mib)
+	f(1024*mib, 1024, minUploadPartSize)
+	f(100*1024*mib, minUploadPartSize, 100*1024*mib/maxUploadParts+1)
+}
+
+func TestUploadStateReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	fs := &FS{
+		Bucket:         "bucket",
+		UploadStateDir: filepath.Join(dir, "state"),
+	}
+	statePath := fs.uploadStatePath("foo/bar")
+	if statePath == fs.uploadStatePath("foo/baz") {
+		t.Fatalf("upload state paths must differ for distinct objects")
+	}
+	expected := &uploadState{
+		Bucket:   "bucket",
+		Key:      "foo/bar",
+		Size:     123456789,
+		PartSize: minUploadPartSize,
+	}
+
+	// missing state
+	us, err := readUploadState(statePath, expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if us != nil {
+		t.Fatalf("expecting nil state; got %+v", us)
+	}
+
+	// matching state
+	stored := *expected
+	stored.UploadID = "upload-id"
+	if err := writeUploadState(statePath, &stored); err != nil {
+		t.Fatalf("cannot write upload state: %s", err)
+	}
+	us, err = readUploadState(statePath, expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if us == nil || *us != stored {
+		t.Fatalf("unexpected state; got %+v; want %+v", us, stored)
+	}
+
+	// state for the object with distinct size must be ignored
+	changed := *expected
+	changed.Size++
+	us, err = readUploadState(statePath, &changed)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if us != nil {
+		t.Fatalf("expecting nil state for changed object; got %+v", us)
+	}
+
+	// broken state must be ignored
+	if err := os.WriteFile(statePath, []byte("foobar"), 0644); err != nil {
+		t.Fatalf("cannot write broken state: %s", err)
+	}
+	us, err = readUploadState(statePath, expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if us != nil {
+		t.Fatalf("expecting nil state for broken state file; got %+v", us)
+	}
+}
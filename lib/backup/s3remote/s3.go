@@ -4,17 +4,21 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fscommon"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
 )
 
 // FS represents filesystem for backups in S3.
@@ -42,8 +46,36 @@ type FS struct {
 	// The name of S3 config profile to use.
 	ProfileName string
 
+	// Object Lock retention mode for uploaded objects: GOVERNANCE or COMPLIANCE.
+	//
+	// Object Lock isn't applied if empty. The bucket must have Object Lock enabled.
+	ObjectLockMode string
+
+	// Retention period for uploaded objects if ObjectLockMode is set.
+	ObjectLockRetention time.Duration
+
+	// Checksum algorithm for verifying uploaded objects: SHA256 or CRC32C.
+	//
+	// Checksums aren't verified if empty.
+	ChecksumAlgorithm string
+
+	// The number of concurrent multipart upload requests per each uploaded object.
+	UploadConcurrency int
+
+	// The size of multipart upload part.
+	//
+	// The part size is selected adaptively depending on object size if zero.
+	UploadPartSize int64
+
+	// Local directory for persisting the state of multipart uploads.
+	//
+	// Interrupted multipart uploads are resumed from the last uploaded part if set.
+	UploadStateDir string
+
 	s3       *s3.Client
 	uploader *manager.Uploader
+
+	newChecksumHash func() hash.Hash
 }
 
 // Init initializes fs.
@@ -59,6 +91,9 @@ func (fs *FS) Init() error {
 	if !strings.HasSuffix(fs.Dir, "/") {
 		fs.Dir += "/"
 	}
+	if err := fs.initObjectLock(); err != nil {
+		return err
+	}
 	configOpts := []func(*config.LoadOptions) error{
 		config.WithSharedConfigProfile(fs.ProfileName),
 		config.WithDefaultRegion("us-east-1"),
@@ -100,12 +135,97 @@ func (fs *FS) Init() error {
 	}
 
 	fs.uploader = manager.NewUploader(fs.s3, func(u *manager.Uploader) {
-		// We manage upload concurrency by ourselves.
+		// We manage upload concurrency for distinct objects by ourselves.
 		u.Concurrency = 1
+		if fs.UploadConcurrency > 1 {
+			u.Concurrency = fs.UploadConcurrency
+		}
 	})
 	return nil
 }
 
+func (fs *FS) initObjectLock() error {
+	switch types.ObjectLockMode(fs.ObjectLockMode) {
+	case "":
+		if fs.ObjectLockRetention != 0 {
+			return fmt.Errorf("object lock retention %s cannot be set without object lock mode", fs.ObjectLockRetention)
+		}
+	case types.ObjectLockModeGovernance, types.ObjectLockModeCompliance:
+		if fs.ObjectLockRetention <= 0 {
+			return fmt.Errorf("object lock retention must be positive for object lock mode %q; got %s", fs.ObjectLockMode, fs.ObjectLockRetention)
+		}
+		if fs.ChecksumAlgorithm == "" {
+			// S3 requires integrity checks for objects uploaded with Object Lock.
+			fs.ChecksumAlgorithm = string(types.ChecksumAlgorithmCrc32c)
+		}
+	default:
+		return fmt.Errorf("unsupported object lock mode %q; supported values: %s, %s", fs.ObjectLockMode, types.ObjectLockModeGovernance, types.ObjectLockModeCompliance)
+	}
+	if fs.ChecksumAlgorithm != "" {
+		newHash, err := newChecksumHash(types.ChecksumAlgorithm(fs.ChecksumAlgorithm))
+		if err != nil {
+			return err
+		}
+		fs.newChecksumHash = newHash
+	}
+	return nil
+}
+
+// objectLockRetainUntilDate returns the retain until date for objects uploaded now.
+func (fs *FS) objectLockRetainUntilDate() *time.Time {
+	if fs.ObjectLockMode == "" {
+		return nil
+	}
+	t := time.Now().Add(fs.ObjectLockRetention)
+	return &t
+}
+
+// upload uploads size bytes from r to the given path at fs.
+//
+// Object Lock retention is applied to the uploaded object if fs.ObjectLockMode is set.
+// The checksum returned by the server is verified if fs.ChecksumAlgorithm is set.
+func (fs *FS) upload(path string, r io.Reader, size uint64) error {
+	partSize := getUploadPartSize(size, fs.UploadPartSize)
+	if fs.UploadStateDir != "" && size > uint64(partSize) {
+		return fs.uploadResumable(path, r, size, partSize)
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(fs.Bucket),
+		Key:    aws.String(path),
+		Body:   r,
+	}
+	if fs.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(fs.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = fs.objectLockRetainUntilDate()
+	}
+	var cr *checksumReader
+	if fs.newChecksumHash != nil {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(fs.ChecksumAlgorithm)
+		cr = newChecksumReader(r, fs.newChecksumHash, partSize)
+		input.Body = cr
+	}
+	out, err := fs.uploader.Upload(context.Background(), input, func(u *manager.Uploader) {
+		u.PartSize = partSize
+	})
+	if err != nil {
+		return err
+	}
+	if cr == nil {
+		return nil
+	}
+	serverChecksum := out.ChecksumCRC32C
+	if input.ChecksumAlgorithm == types.ChecksumAlgorithmSha256 {
+		serverChecksum = out.ChecksumSHA256
+	}
+	if err := cr.verify(serverChecksum); err != nil {
+		checksumMismatches.Inc()
+		return err
+	}
+	return nil
+}
+
+var checksumMismatches = metrics.NewCounter(`vm_backup_checksum_verification_errors_total{type="s3"}`)
+
 // MustStop stops fs.
 func (fs *FS) MustStop() {
 	fs.s3 = nil
@@ -192,6 +312,13 @@ func (fs *FS) CopyPart(srcFS common.OriginFS, p common.Part) error {
 		CopySource: aws.String(copySource),
 		Key:        aws.String(dstPath),
 	}
+	if fs.ObjectLockMode != "" {
+		input.ObjectLockMode = types.ObjectLockMode(fs.ObjectLockMode)
+		input.ObjectLockRetainUntilDate = fs.objectLockRetainUntilDate()
+	}
+	if fs.ChecksumAlgorithm != "" {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithm(fs.ChecksumAlgorithm)
+	}
 	_, err := fs.s3.CopyObject(context.Background(), input)
 	if err != nil {
 		return fmt.Errorf("cannot copy %q from %s to %s (copySource %q): %w", p.Path, src, fs, copySource, err)
@@ -230,13 +357,7 @@ func (fs *FS) UploadPart(p common.Part, r io.Reader) error {
 	sr := &statReader{
 		r: r,
 	}
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(fs.Bucket),
-		Key:    aws.String(path),
-		Body:   sr,
-	}
-	_, err := fs.uploader.Upload(context.Background(), input)
-	if err != nil {
+	if err := fs.upload(path, sr, p.Size); err != nil {
 		return fmt.Errorf("cannot upoad data to %q at %s (remote path %q): %w", p.Path, fs, path, err)
 	}
 	if uint64(sr.size) != p.Size {
@@ -281,13 +402,7 @@ func (fs *FS) CreateFile(filePath string, data []byte) error {
 	sr := &statReader{
 		r: bytes.NewReader(data),
 	}
-	input := &s3.PutObjectInput{
-		Bucket: aws.String(fs.Bucket),
-		Key:    aws.String(path),
-		Body:   sr,
-	}
-	_, err := fs.uploader.Upload(context.Background(), input)
-	if err != nil {
+	if err := fs.upload(path, sr, uint64(len(data))); err != nil {
 		return fmt.Errorf("cannot upoad data to %q at %s (remote path %q): %w", filePath, fs, path, err)
 	}
 	l := int64(len(data))
@@ -22,9 +22,11 @@ func init() {
 	if cpuQuota > 0 {
 		updateGOMAXPROCSToCPUQuota(cpuQuota)
 	}
-	cpuCoresAvailable := cpuQuota
-	if cpuCoresAvailable <= 0 {
-		cpuCoresAvailable = float64(runtime.NumCPU())
+	// runtime.NumCPU() returns the number of CPU cores the app is allowed to run on according to its CPU affinity,
+	// which may be limited via cpuset.cpus in cgroups or via taskset.
+	cpuCoresAvailable := float64(runtime.NumCPU())
+	if cpuQuota > 0 && cpuQuota < cpuCoresAvailable {
+		cpuCoresAvailable = cpuQuota
 	}
 	metrics.NewGauge(`process_cpu_cores_available`, func() float64 {
 		return cpuCoresAvailable
@@ -48,16 +48,27 @@ func GetMemoryLimit() int64 {
 	if err == nil {
 		return n
 	}
-	n, err = getMemStatV2("memory.max")
-	if err != nil {
-		return 0
-	}
-	return n
+	return getMemoryLimitV2("/sys/fs/cgroup", "/proc/self/cgroup")
 }
 
-func getMemStatV2(statName string) (int64, error) {
-	// See https: //www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
-	return getStatGeneric(statName, "/sys/fs/cgroup", "/proc/self/cgroup", "")
+// getMemoryLimitV2 returns the minimum of memory.max and memory.high limits for cgroup v2.
+//
+// memory.high is taken into account, since the kernel heavily throttles the app and reclaims its memory
+// when the memory usage exceeds this limit. It is set by systemd via MemoryHigh option.
+// See https://www.kernel.org/doc/html/latest/admin-guide/cgroup-v2.html#memory-interface-files
+func getMemoryLimitV2(sysPrefix, cgroupPath string) int64 {
+	var limit int64
+	for _, statName := range []string{"memory.max", "memory.high"} {
+		// getStatGeneric returns error for "max" value, which means there is no limit.
+		n, err := getStatGeneric(statName, sysPrefix, cgroupPath, "")
+		if err != nil || n <= 0 {
+			continue
+		}
+		if limit <= 0 || n < limit {
+			limit = n
+		}
+	}
+	return limit
 }
 
 func getMemStat(statName string) (int64, error) {
@@ -32,3 +32,16 @@ func TestGetHierarchicalMemoryLimitFailure(t *testing.T) {
 	}
 	f("testdata/", "testdata/none_existing_folder")
 }
+
+func TestGetMemoryLimitV2(t *testing.T) {
+	f := func(sysPath, cgroupPath string, want int64) {
+		t.Helper()
+		got := getMemoryLimitV2(sysPath, cgroupPath)
+		if got != want {
+			t.Fatalf("unexpected result, got: %d, want %d", got, want)
+		}
+	}
+	f("testdata/cgroup", "testdata/self/cgroupv2", 523372036854771712)
+	f("testdata/cgroupv2", "testdata/self/cgroupv2", 1073741824)
+	f("testdata/none_existing_folder", "testdata/self/none_existing_file", 0)
+}
@@ -0,0 +1,142 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// GetNUMAMemoryLimit returns the amount of memory on NUMA nodes the app is allowed to allocate memory from.
+//
+// The allowed NUMA nodes may be limited via cpuset.mems in cgroups or via numactl --membind.
+// 0 is returned if the app may allocate memory from all the NUMA nodes or if NUMA topology cannot be determined.
+func GetNUMAMemoryLimit() int64 {
+	return numaMemoryLimit
+}
+
+var numaMemoryLimit int64
+
+func init() {
+	nodes, err := getNUMANodes("/sys/devices/system/node")
+	if err != nil {
+		return
+	}
+	allowedNodes, err := getMemsAllowed("/proc/self/status")
+	if err != nil {
+		allowedNodes = nodes
+	}
+	allowedNodes = intersectNodes(nodes, allowedNodes)
+	metrics.NewGauge(`process_numa_nodes`, func() float64 {
+		return float64(len(nodes))
+	})
+	metrics.NewGauge(`process_numa_nodes_allowed`, func() float64 {
+		return float64(len(allowedNodes))
+	})
+	if len(allowedNodes) == 0 || len(allowedNodes) == len(nodes) {
+		return
+	}
+	n, err := getNUMANodesMemory("/sys/devices/system/node", allowedNodes)
+	if err != nil {
+		return
+	}
+	numaMemoryLimit = n
+}
+
+// getNUMANodes returns online NUMA nodes from sysPrefix.
+func getNUMANodes(sysPrefix string) ([]int, error) {
+	data, err := os.ReadFile(path.Join(sysPrefix, "online"))
+	if err != nil {
+		return nil, err
+	}
+	return parseIDList(string(data))
+}
+
+// getMemsAllowed returns NUMA nodes the process is allowed to allocate memory from according to Mems_allowed_list at statusPath.
+//
+// See https://man7.org/linux/man-pages/man5/proc.5.html
+func getMemsAllowed(statusPath string) ([]int, error) {
+	data, err := os.ReadFile(statusPath)
+	if err != nil {
+		return nil, err
+	}
+	s, err := grepFirstMatch(string(data), "Mems_allowed_list:", 1, ":")
+	if err != nil {
+		return nil, err
+	}
+	return parseIDList(s)
+}
+
+// getNUMANodesMemory returns the total memory in bytes for the given NUMA nodes at sysPrefix.
+func getNUMANodesMemory(sysPrefix string, nodes []int) (int64, error) {
+	var total int64
+	for _, node := range nodes {
+		nodeName := fmt.Sprintf("node%d", node)
+		data, err := os.ReadFile(path.Join(sysPrefix, nodeName, "meminfo"))
+		if err != nil {
+			return 0, err
+		}
+		// The line has the following format: `Node 0 MemTotal:       32834776 kB`
+		s, err := grepFirstMatch(string(data), "MemTotal:", 1, ":")
+		if err != nil {
+			return 0, err
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(s, "kB"))
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot parse MemTotal for %s: %w", nodeName, err)
+		}
+		total += n * 1024
+	}
+	return total, nil
+}
+
+func intersectNodes(nodes, allowedNodes []int) []int {
+	m := make(map[int]bool, len(nodes))
+	for _, node := range nodes {
+		m[node] = true
+	}
+	var result []int
+	for _, node := range allowedNodes {
+		if m[node] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// parseIDList parses list of ids in the format `0-3,5,7-8` used by Linux for cpu and node lists.
+func parseIDList(data string) ([]int, error) {
+	data = strings.TrimSpace(data)
+	if data == "" {
+		return nil, nil
+	}
+	var ids []int
+	for _, s := range strings.Split(data, ",") {
+		bounds := strings.Split(s, "-")
+		if len(bounds) > 2 {
+			return nil, fmt.Errorf("cannot parse %q: unexpected range %q", data, s)
+		}
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q: %w", data, err)
+		}
+		end := start
+		if len(bounds) == 2 {
+			end, err = strconv.Atoi(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %q: %w", data, err)
+			}
+			if end < start {
+				return nil, fmt.Errorf("cannot parse %q: range end cannot be smaller than range start in %q", data, s)
+			}
+		}
+		for id := start; id <= end; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,63 @@
+package cgroup
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIDListSuccess(t *testing.T) {
+	f := func(s string, idsExpected []int) {
+		t.Helper()
+		ids, err := parseIDList(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(ids, idsExpected) {
+			t.Fatalf("unexpected ids for %q; got %v; want %v", s, ids, idsExpected)
+		}
+	}
+	f("", nil)
+	f("0", []int{0})
+	f("0-3\n", []int{0, 1, 2, 3})
+	f("1,3-4,7", []int{1, 3, 4, 7})
+}
+
+func TestParseIDListFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := parseIDList(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	f("foo")
+	f("1-")
+	f("3-1")
+	f("1-2-3")
+}
+
+func TestGetNUMANodesMemory(t *testing.T) {
+	nodes, err := getNUMANodes("testdata/node")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(nodes, []int{0, 1}) {
+		t.Fatalf("unexpected NUMA nodes; got %v; want [0 1]", nodes)
+	}
+	allowedNodes, err := getMemsAllowed("testdata/self/status")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(allowedNodes, []int{1}) {
+		t.Fatalf("unexpected allowed NUMA nodes; got %v; want [1]", allowedNodes)
+	}
+	n, err := getNUMANodesMemory("testdata/node", allowedNodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 33018640*1024 {
+		t.Fatalf("unexpected memory for allowed NUMA nodes; got %d; want %d", n, 33018640*1024)
+	}
+	if _, err := getNUMANodesMemory("testdata/node", []int{2}); err == nil {
+		t.Fatalf("expecting non-nil error for missing NUMA node")
+	}
+}
@@ -0,0 +1,278 @@
+// Package configreload tracks versions of the applied configs and passes config reload and rollback requests
+// from HTTP API to the goroutine, which applies configs.
+//
+// This allows reloading configs without sending SIGHUP signal, verifying that the expected config is applied
+// and rolling back to the previously applied config.
+package configreload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// ErrNoPreviousVersion is returned for rollback requests if there is no previously applied config.
+var ErrNoPreviousVersion = &httpserver.ErrorWithStatusCode{
+	Err:        errors.New("cannot roll back the config, since there is no previously applied config"),
+	StatusCode: http.StatusConflict,
+}
+
+// NewHashMismatchError returns an error for the config with the hash, which doesn't match the expectedHash.
+func NewHashMismatchError(expectedHash, hash string) error {
+	return &httpserver.ErrorWithStatusCode{
+		Err:        fmt.Errorf("the config isn't applied, since its hash %q doesn't match the expected hash %q", hash, expectedHash),
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
+// Hash returns hex-encoded sha256 hash of the concatenated data.
+func Hash(data ...[]byte) string {
+	h := sha256.New()
+	for _, b := range data {
+		_, _ = h.Write(b)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Version describes the applied config.
+type Version struct {
+	// Version is incremented on every applied config, including rollbacks.
+	Version uint64 `json:"version"`
+
+	// Hash is the hash of the applied config. See Hash.
+	Hash string `json:"hash"`
+
+	// Timestamp is unix timestamp in seconds when the config has been applied.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// Request is a request for config reload or rollback.
+type Request struct {
+	// ExpectedHash is the expected hash of the config to apply.
+	//
+	// The config mustn't be applied if its hash doesn't match ExpectedHash. Any config may be applied if ExpectedHash is empty.
+	ExpectedHash string
+
+	// IsRollback is set if the previously applied config must be applied instead of reloading the config.
+	IsRollback bool
+
+	doneCh chan error
+}
+
+// Done must be called after the request is processed.
+//
+// err must be nil if the config has been successfully applied.
+func (req *Request) Done(err error) {
+	req.doneCh <- err
+}
+
+// Tracker tracks versions of the applied config.
+//
+// Tracker must be created via NewTracker.
+type Tracker struct {
+	requestsCh chan *Request
+
+	// mu protects the fields below.
+	mu       sync.Mutex
+	current  Version
+	previous *Version
+
+	hashInfoMetricName string
+	hashInfoMetricFmt  string
+
+	// configFlag is the command-line flag with the tracked config. It is used in audit log events.
+	configFlag string
+}
+
+// NewTracker returns new Tracker for the config pointed by configFlag, which exposes metrics with the given metricPrefix.
+func NewTracker(metricPrefix, configFlag string) *Tracker {
+	t := &Tracker{
+		requestsCh:        make(chan *Request),
+		hashInfoMetricFmt: metricPrefix + `_config_hash_info{hash=%q}`,
+		configFlag:        configFlag,
+	}
+	_ = metrics.NewGauge(metricPrefix+"_config_version", func() float64 {
+		v := t.Current()
+		return float64(v.Version)
+	})
+	return t
+}
+
+// Requests returns a channel with config reload and rollback requests.
+//
+// Every received request must be completed by calling Request.Done.
+func (t *Tracker) Requests() <-chan *Request {
+	return t.requestsCh
+}
+
+// Current returns the currently applied config version.
+func (t *Tracker) Current() Version {
+	t.mu.Lock()
+	v := t.current
+	t.mu.Unlock()
+	return v
+}
+
+// SetApplied must be called after the config with the given hash has been applied.
+func (t *Tracker) SetApplied(hash string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.current.Version > 0 {
+		prev := t.current
+		t.previous = &prev
+	}
+	t.setCurrentLocked(hash)
+}
+
+// SetRolledBack must be called after the previously applied config has been applied again.
+func (t *Tracker) SetRolledBack() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.previous == nil {
+		return
+	}
+	hash := t.previous.Hash
+	t.previous = nil
+	t.setCurrentLocked(hash)
+}
+
+func (t *Tracker) setCurrentLocked(hash string) {
+	t.current = Version{
+		Version:   t.current.Version + 1,
+		Hash:      hash,
+		Timestamp: time.Now().Unix(),
+	}
+	ae := auditlog.NewEvent(nil, "config_applied")
+	ae.Target = t.configFlag
+	ae.SetDetail("version", fmt.Sprintf("%d", t.current.Version))
+	ae.SetDetail("hash", hash)
+	auditlog.Log(ae, nil)
+
+	if t.hashInfoMetricName != "" {
+		metrics.UnregisterMetric(t.hashInfoMetricName)
+	}
+	t.hashInfoMetricName = fmt.Sprintf(t.hashInfoMetricFmt, hash)
+	metrics.GetOrCreateCounter(t.hashInfoMetricName).Set(1)
+}
+
+// HandleReload handles config reload request r.
+//
+// The config is applied only if its hash matches the `config_hash` query arg.
+// The applied config version is written to w on success.
+func (t *Tracker) HandleReload(w http.ResponseWriter, r *http.Request) {
+	req := &Request{
+		ExpectedHash: r.FormValue("config_hash"),
+	}
+	ae := auditlog.NewEvent(r, "config_reload_request")
+	if req.ExpectedHash != "" {
+		ae.SetDetail("expected_hash", req.ExpectedHash)
+	}
+	t.handleRequest(w, r, req, ae)
+}
+
+// HandleRollback handles config rollback request r.
+//
+// The applied config version is written to w on success.
+func (t *Tracker) HandleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		httpserver.Errorf(w, r, "path %q supports only POST method", r.URL.Path)
+		return
+	}
+	req := &Request{
+		IsRollback: true,
+	}
+	t.handleRequest(w, r, req, auditlog.NewEvent(r, "config_rollback_request"))
+}
+
+// Reload synchronously reloads the config.
+//
+// It returns non-nil error if the config cannot be applied.
+func (t *Tracker) Reload(ctx context.Context) error {
+	if t.Current().Version == 0 {
+		return fmt.Errorf("the config isn't loaded yet")
+	}
+	req := &Request{
+		doneCh: make(chan error, 1),
+	}
+	select {
+	case t.requestsCh <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-req.doneCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracker) handleRequest(w http.ResponseWriter, r *http.Request, req *Request, ae *auditlog.Event) {
+	ae.Target = t.configFlag
+	if t.Current().Version == 0 {
+		err := &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("the config isn't loaded yet"),
+			StatusCode: http.StatusServiceUnavailable,
+		}
+		auditlog.Log(ae, err)
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	req.doneCh = make(chan error, 1)
+	select {
+	case t.requestsCh <- req:
+	case <-r.Context().Done():
+		auditlog.Log(ae, r.Context().Err())
+		return
+	}
+	select {
+	case err := <-req.doneCh:
+		if err != nil {
+			auditlog.Log(ae, err)
+			httpserver.Errorf(w, r, "%s", err)
+			return
+		}
+	case <-r.Context().Done():
+		// The request may be still applied after the client disconnects. The result is recorded in config_applied event.
+		auditlog.Log(ae, r.Context().Err())
+		return
+	}
+	v := t.Current()
+	ae.SetDetail("version", fmt.Sprintf("%d", v.Version))
+	ae.SetDetail("hash", v.Hash)
+	auditlog.Log(ae, nil)
+	w.Header().Set("Content-Type", "application/json")
+	t.WriteVersion(w)
+}
+
+// WriteVersion writes the applied config version in JSON to w.
+func (t *Tracker) WriteVersion(w io.Writer) {
+	t.mu.Lock()
+	data := struct {
+		Version
+		Previous *Version `json:"previous"`
+	}{
+		Version:  t.current,
+		Previous: t.previous,
+	}
+	t.mu.Unlock()
+	b, err := json.Marshal(&data)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal config version to JSON: %s", err)
+	}
+	fmt.Fprintf(w, `{"status":"success","data":%s}`, b)
+}
@@ -0,0 +1,125 @@
+package configreload
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	// The hash must match `sha256sum` output for the concatenated data.
+	hash := Hash([]byte("foo"), []byte("bar"))
+	hashExpected := "c3ab8ff13720e8ad9047dd39466b3c8974e592c2fa383d4a3960714caef0c4f2"
+	if hash != hashExpected {
+		t.Fatalf("unexpected hash; got %q; want %q", hash, hashExpected)
+	}
+}
+
+func TestTrackerVersions(t *testing.T) {
+	tr := NewTracker("test_tracker_versions", "-test.config")
+	if v := tr.Current(); v.Version != 0 {
+		t.Fatalf("unexpected version for the tracker without applied configs: %+v", v)
+	}
+	tr.SetApplied("foo")
+	tr.SetApplied("bar")
+	if v := tr.Current(); v.Version != 2 || v.Hash != "bar" {
+		t.Fatalf("unexpected version after reload: %+v", v)
+	}
+	tr.SetRolledBack()
+	if v := tr.Current(); v.Version != 3 || v.Hash != "foo" {
+		t.Fatalf("unexpected version after rollback: %+v", v)
+	}
+
+	// Rollback without previous version must be ignored
+	tr.SetRolledBack()
+	if v := tr.Current(); v.Version != 3 || v.Hash != "foo" {
+		t.Fatalf("unexpected version after the second rollback: %+v", v)
+	}
+
+	var sb strings.Builder
+	tr.WriteVersion(&sb)
+	resultExpected := fmt.Sprintf(`{"status":"success","data":{"version":3,"hash":"foo","timestamp":%d,"previous":null}}`, tr.Current().Timestamp)
+	if sb.String() != resultExpected {
+		t.Fatalf("unexpected version response;\ngot\n%s\nwant\n%s", sb.String(), resultExpected)
+	}
+}
+
+func TestTrackerHandleRequests(t *testing.T) {
+	tr := NewTracker("test_tracker_handle_requests", "-test.config")
+	f := func(method, path string, statusCodeExpected int) {
+		t.Helper()
+		r := httptest.NewRequest(method, path, nil)
+		w := httptest.NewRecorder()
+		if strings.HasPrefix(path, "/-/rollback") {
+			tr.HandleRollback(w, r)
+		} else {
+			tr.HandleReload(w, r)
+		}
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code for %s %s; got %d; want %d; response: %s", method, path, w.Code, statusCodeExpected, w.Body.String())
+		}
+	}
+
+	// Requests must be rejected until the config is loaded
+	f("GET", "/-/reload", http.StatusServiceUnavailable)
+
+	// Emulate config reload loop
+	configs := []string{"foo", "bar"}
+	tr.SetApplied(Hash([]byte(configs[0])))
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		n := 0
+		hasPrevious := false
+		for {
+			select {
+			case <-stopCh:
+				return
+			case req := <-tr.Requests():
+				if req.IsRollback {
+					if !hasPrevious {
+						req.Done(ErrNoPreviousVersion)
+						continue
+					}
+					hasPrevious = false
+					tr.SetRolledBack()
+					req.Done(nil)
+					continue
+				}
+				hash := Hash([]byte(configs[(n+1)%len(configs)]))
+				if req.ExpectedHash != "" && req.ExpectedHash != hash {
+					req.Done(NewHashMismatchError(req.ExpectedHash, hash))
+					continue
+				}
+				n++
+				hasPrevious = true
+				tr.SetApplied(hash)
+				req.Done(nil)
+			}
+		}
+	}()
+
+	f("GET", "/-/reload?config_hash="+Hash([]byte("foo")), http.StatusPreconditionFailed)
+	f("POST", "/-/rollback", http.StatusConflict)
+	f("GET", "/-/reload?config_hash="+Hash([]byte("bar")), http.StatusOK)
+	if v := tr.Current(); v.Version != 2 || v.Hash != Hash([]byte("bar")) {
+		t.Fatalf("unexpected version after reload: %+v", v)
+	}
+	f("GET", "/-/rollback", http.StatusBadRequest)
+	f("POST", "/-/rollback", http.StatusOK)
+	if v := tr.Current(); v.Version != 3 || v.Hash != Hash([]byte("foo")) {
+		t.Fatalf("unexpected version after rollback: %+v", v)
+	}
+	if err := tr.Reload(context.Background()); err != nil {
+		t.Fatalf("unexpected error on synchronous reload: %s", err)
+	}
+	if v := tr.Current(); v.Version != 4 || v.Hash != Hash([]byte("foo")) {
+		t.Fatalf("unexpected version after synchronous reload: %+v", v)
+	}
+	close(stopCh)
+	<-doneCh
+}
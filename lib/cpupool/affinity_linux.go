@@ -0,0 +1,111 @@
+package cpupool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// pinCurrentGoroutine locks the current goroutine to OS thread and restricts the thread to the given cpus.
+//
+// The returned function must be called for restoring the original thread affinity and unlocking the goroutine from the thread.
+func pinCurrentGoroutine(cpus []int) (func(), error) {
+	runtime.LockOSThread()
+	var orig unix.CPUSet
+	// pid=0 means the current thread
+	if err := unix.SchedGetaffinity(0, &orig); err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("cannot obtain CPU affinity for the current thread: %w", err)
+	}
+	var set unix.CPUSet
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	if err := unix.SchedSetaffinity(0, &set); err != nil {
+		runtime.UnlockOSThread()
+		return nil, fmt.Errorf("cannot set CPU affinity to %s: %w", formatCPUSet(cpus), err)
+	}
+	unpin := func() {
+		// Restore the original affinity, since the thread may be used by other goroutines after the unlock.
+		_ = unix.SchedSetaffinity(0, &orig)
+		runtime.UnlockOSThread()
+	}
+	return unpin, nil
+}
+
+// getAllowedCPUs returns CPUs, which are allowed for the current process.
+//
+// The list may be restricted by cgroup cpuset or by taskset.
+func getAllowedCPUs() ([]int, error) {
+	var set unix.CPUSet
+	if err := unix.SchedGetaffinity(0, &set); err != nil {
+		return nil, fmt.Errorf("cannot obtain CPU affinity for the current process: %w", err)
+	}
+	var cpus []int
+	for cpu := 0; cpu <= maxCPU; cpu++ {
+		if set.IsSet(cpu) {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// getNUMANodes returns CPUs per every NUMA node.
+func getNUMANodes() ([][]int, error) {
+	return getNUMANodesFromSysfs("/sys/devices/system/node")
+}
+
+func getNUMANodesFromSysfs(path string) ([][]int, error) {
+	des, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read NUMA nodes: %w", err)
+	}
+	type node struct {
+		id   int
+		cpus []int
+	}
+	var nodes []node
+	for _, de := range des {
+		name := de.Name()
+		if !strings.HasPrefix(name, "node") {
+			continue
+		}
+		id, err := strconv.Atoi(name[len("node"):])
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, name, "cpulist"))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read CPUs for NUMA node %d: %w", id, err)
+		}
+		if strings.TrimSpace(string(data)) == "" {
+			// NUMA node without CPUs such as memory-only node
+			continue
+		}
+		cpus, err := parseCPUSet(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse CPUs for NUMA node %d: %w", id, err)
+		}
+		nodes = append(nodes, node{
+			id:   id,
+			cpus: cpus,
+		})
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("cannot find NUMA nodes at %q", path)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].id < nodes[j].id
+	})
+	result := make([][]int, len(nodes))
+	for i, n := range nodes {
+		result[i] = n.cpus
+	}
+	return result, nil
+}
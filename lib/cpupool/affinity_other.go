@@ -0,0 +1,21 @@
+//go:build !linux
+// +build !linux
+
+package cpupool
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func pinCurrentGoroutine(_ []int) (func(), error) {
+	return nil, fmt.Errorf("CPU pinning isn't supported on %s", runtime.GOOS)
+}
+
+func getAllowedCPUs() ([]int, error) {
+	return nil, fmt.Errorf("CPU pinning isn't supported on %s", runtime.GOOS)
+}
+
+func getNUMANodes() ([][]int, error) {
+	return nil, fmt.Errorf("NUMA nodes detection isn't supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,196 @@
+// Package cpupool provides worker pools pinned to CPU sets.
+//
+// Pinning CPU-bound workers to CPUs of a single NUMA node reduces cross-NUMA memory traffic on big multi-socket hosts.
+package cpupool
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Pools is a set of worker pools pinned to distinct CPU sets.
+//
+// Pools must be created via NewPools.
+type Pools struct {
+	pools []*Pool
+}
+
+// NewPools creates worker pools with the given name according to spec.
+//
+// The following spec values are supported:
+//
+//   - empty string - pools are disabled; nil is returned
+//   - `numa` - a pool per every NUMA node with CPUs of the node
+//   - semicolon-delimited list of CPU sets in Linux cpulist format such as `0-23,48-71;24-47,72-95` - a pool per every CPU set
+//
+// Every pool has a worker per every CPU in the pool. Workers are started on the first call to Pool.Do.
+// CPUs, which aren't allowed for the current process, are ignored.
+func NewPools(name, spec string) (*Pools, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	var cpuSets [][]int
+	if spec == "numa" {
+		nodes, err := getNUMANodes()
+		if err != nil {
+			return nil, err
+		}
+		cpuSets = nodes
+	} else {
+		for _, s := range strings.Split(spec, ";") {
+			cpus, err := parseCPUSet(s)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse CPU set %q: %w", s, err)
+			}
+			cpuSets = append(cpuSets, cpus)
+		}
+	}
+	allowed, err := getAllowedCPUs()
+	if err != nil {
+		return nil, err
+	}
+	ps := &Pools{}
+	for _, cpus := range cpuSets {
+		cpusAllowed := intersectCPUs(cpus, allowed)
+		if len(cpusAllowed) == 0 {
+			ps.MustStop()
+			return nil, fmt.Errorf("none of CPUs %s are allowed for the current process; allowed CPUs: %s", formatCPUSet(cpus), formatCPUSet(allowed))
+		}
+		ps.pools = append(ps.pools, newPool(name, cpusAllowed))
+	}
+	for _, p := range ps.pools {
+		logger.Infof("initialized %q worker pool pinned to CPUs %s", name, p.cpusStr)
+	}
+	return ps, nil
+}
+
+// Len returns the number of pools in ps.
+func (ps *Pools) Len() int {
+	if ps == nil {
+		return 0
+	}
+	return len(ps.pools)
+}
+
+// Get returns the pool for the given n.
+//
+// The same pool is returned for the same n, so the caller may use it for keeping the data locality.
+func (ps *Pools) Get(n uint64) *Pool {
+	return ps.pools[n%uint64(len(ps.pools))]
+}
+
+// MustStop stops all the pools in ps.
+func (ps *Pools) MustStop() {
+	if ps == nil {
+		return
+	}
+	for _, p := range ps.pools {
+		p.mustStop()
+	}
+	ps.pools = nil
+}
+
+// Pool is a pool of workers pinned to the given CPUs.
+type Pool struct {
+	cpus    []int
+	cpusStr string
+
+	startOnce sync.Once
+	workCh    chan func()
+	wg        sync.WaitGroup
+
+	tasks       *metrics.Counter
+	busySeconds *metrics.FloatCounter
+}
+
+func newPool(name string, cpus []int) *Pool {
+	cpusStr := formatCPUSet(cpus)
+	p := &Pool{
+		cpus:    cpus,
+		cpusStr: cpusStr,
+		workCh:  make(chan func()),
+
+		tasks:       metrics.GetOrCreateCounter(fmt.Sprintf(`vm_cpu_pool_tasks_total{pool=%q,cpus=%q}`, name, cpusStr)),
+		busySeconds: metrics.GetOrCreateFloatCounter(fmt.Sprintf(`vm_cpu_pool_busy_seconds_total{pool=%q,cpus=%q}`, name, cpusStr)),
+	}
+	metrics.GetOrCreateGauge(fmt.Sprintf(`vm_cpu_pool_cpus{pool=%q,cpus=%q}`, name, cpusStr), func() float64 {
+		return float64(len(cpus))
+	})
+	return p
+}
+
+func (p *Pool) startWorkers() {
+	for i := 0; i < len(p.cpus); i++ {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.runWorker()
+		}()
+	}
+}
+
+func (p *Pool) runWorker() {
+	unpin, err := pinCurrentGoroutine(p.cpus)
+	if err != nil {
+		logger.Errorf("cannot pin worker to CPUs %s; the worker runs on arbitrary CPUs: %s", p.cpusStr, err)
+	} else {
+		defer unpin()
+	}
+	for f := range p.workCh {
+		startTime := time.Now()
+		f()
+		p.busySeconds.Add(time.Since(startTime).Seconds())
+		p.tasks.Inc()
+	}
+}
+
+func (p *Pool) mustStop() {
+	// Prevent from starting workers after the stop.
+	p.startOnce.Do(func() {})
+	close(p.workCh)
+	p.wg.Wait()
+}
+
+// Do runs f on a worker from p and waits until f returns.
+//
+// The number of concurrently running functions is limited by the number of CPUs in p.
+func (p *Pool) Do(f func()) {
+	p.startOnce.Do(p.startWorkers)
+	doneCh := make(chan struct{})
+	p.workCh <- func() {
+		f()
+		close(doneCh)
+	}
+	<-doneCh
+}
+
+// Pin pins the current goroutine to CPUs from p until the returned function is called.
+//
+// This is useful for long-running tasks, which cannot be executed via Do.
+// The time between Pin and the call to the returned function is accounted as busy time for p.
+func (p *Pool) Pin() func() {
+	startTime := time.Now()
+	unpin, err := pinCurrentGoroutine(p.cpus)
+	if err != nil {
+		pinErrorsLogger.Errorf("cannot pin goroutine to CPUs %s: %s", p.cpusStr, err)
+		unpin = func() {}
+	}
+	return func() {
+		unpin()
+		p.busySeconds.Add(time.Since(startTime).Seconds())
+		p.tasks.Inc()
+	}
+}
+
+var pinErrorsLogger = logger.WithThrottler("cpupool_pin_errors", 5*time.Second)
+
+// String returns CPUs for p in Linux cpulist format.
+func (p *Pool) String() string {
+	return p.cpusStr
+}
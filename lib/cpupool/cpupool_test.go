@@ -0,0 +1,117 @@
+package cpupool
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseCPUSetSuccess(t *testing.T) {
+	f := func(s string, cpusExpected []int, sExpected string) {
+		t.Helper()
+		cpus, err := parseCPUSet(s)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(cpus, cpusExpected) {
+			t.Fatalf("unexpected cpus; got %v; want %v", cpus, cpusExpected)
+		}
+		if s := formatCPUSet(cpus); s != sExpected {
+			t.Fatalf("unexpected formatted CPU set; got %q; want %q", s, sExpected)
+		}
+	}
+	f("0", []int{0}, "0")
+	f("0-3", []int{0, 1, 2, 3}, "0-3")
+	f(" 0-2, 8,10-11\n", []int{0, 1, 2, 8, 10, 11}, "0-2,8,10-11")
+	f("3,1,2,1", []int{1, 2, 3}, "1-3")
+}
+
+func TestParseCPUSetFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := parseCPUSet(s); err == nil {
+			t.Fatalf("expecting non-nil error for %q", s)
+		}
+	}
+	f("")
+	f("foo")
+	f("1-")
+	f("3-1")
+	f("-1")
+	f("0,2048")
+}
+
+func TestGetNUMANodesFromSysfs(t *testing.T) {
+	nodes, err := getNUMANodesFromSysfs("testdata/node")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nodesExpected := [][]int{
+		{0, 1, 2, 3, 8, 9, 10, 11},
+		{4, 5, 6, 7, 12, 13, 14, 15},
+	}
+	if !reflect.DeepEqual(nodes, nodesExpected) {
+		t.Fatalf("unexpected NUMA nodes; got %v; want %v", nodes, nodesExpected)
+	}
+	if _, err := getNUMANodesFromSysfs("testdata/missing"); err == nil {
+		t.Fatalf("expecting non-nil error for missing sysfs dir")
+	}
+}
+
+func TestPools(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skipf("CPU pinning isn't supported on %s", runtime.GOOS)
+	}
+	ps, err := NewPools("test", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ps.Len() != 0 {
+		t.Fatalf("expecting zero pools for empty spec; got %d", ps.Len())
+	}
+
+	allowed, err := getAllowedCPUs()
+	if err != nil {
+		t.Fatalf("cannot obtain allowed CPUs: %s", err)
+	}
+	spec := formatCPUSet(allowed[:1]) + ";" + formatCPUSet(allowed)
+	ps, err = NewPools("test", spec)
+	if err != nil {
+		t.Fatalf("cannot create pools for %q: %s", spec, err)
+	}
+	if ps.Len() != 2 {
+		t.Fatalf("unexpected number of pools; got %d; want 2", ps.Len())
+	}
+	if p := ps.Get(0); p.String() != formatCPUSet(allowed[:1]) {
+		t.Fatalf("unexpected CPUs for the first pool; got %s; want %s", p, formatCPUSet(allowed[:1]))
+	}
+	var n uint64
+	for i := uint64(0); i < 10; i++ {
+		ps.Get(i).Do(func() {
+			atomic.AddUint64(&n, 1)
+		})
+	}
+	if n != 10 {
+		t.Fatalf("unexpected number of executed tasks; got %d; want 10", n)
+	}
+	unpin := ps.Get(1).Pin()
+	unpin()
+	ps.MustStop()
+
+	if _, err := NewPools("test", "1023"); err == nil && !containsCPU(allowed, 1023) {
+		t.Fatalf("expecting non-nil error for disallowed CPU")
+	}
+	if _, err := NewPools("test", "foo"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid spec")
+	}
+}
+
+func containsCPU(cpus []int, cpu int) bool {
+	for _, c := range cpus {
+		if c == cpu {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package cpupool
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseCPUSet parses CPU set in Linux cpulist format such as `0-3,8,10-11`.
+//
+// See https://man7.org/linux/man-pages/man7/cpuset.7.html
+func parseCPUSet(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("CPU set cannot be empty")
+	}
+	m := make(map[int]struct{})
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		n := strings.IndexByte(r, '-')
+		if n < 0 {
+			cpu, err := parseCPU(r)
+			if err != nil {
+				return nil, err
+			}
+			m[cpu] = struct{}{}
+			continue
+		}
+		start, err := parseCPU(r[:n])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseCPU(r[n+1:])
+		if err != nil {
+			return nil, err
+		}
+		if start > end {
+			return nil, fmt.Errorf("invalid CPU range %q; the start cannot exceed the end", r)
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			m[cpu] = struct{}{}
+		}
+	}
+	cpus := make([]int, 0, len(m))
+	for cpu := range m {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// maxCPU is the maximum CPU number supported by sched_setaffinity with the default CPU set size.
+const maxCPU = 1023
+
+func parseCPU(s string) (int, error) {
+	cpu, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse CPU number %q: %w", s, err)
+	}
+	if cpu < 0 || cpu > maxCPU {
+		return 0, fmt.Errorf("CPU number must be in the range [0...%d]; got %d", maxCPU, cpu)
+	}
+	return cpu, nil
+}
+
+// formatCPUSet formats sorted cpus in Linux cpulist format.
+func formatCPUSet(cpus []int) string {
+	var a []string
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if i == j {
+			a = append(a, strconv.Itoa(cpus[i]))
+		} else {
+			a = append(a, fmt.Sprintf("%d-%d", cpus[i], cpus[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(a, ",")
+}
+
+// intersectCPUs returns sorted cpus, which are contained in allowed.
+func intersectCPUs(cpus, allowed []int) []int {
+	m := make(map[int]struct{}, len(allowed))
+	for _, cpu := range allowed {
+		m[cpu] = struct{}{}
+	}
+	var result []int
+	for _, cpu := range cpus {
+		if _, ok := m[cpu]; ok {
+			result = append(result, cpu)
+		}
+	}
+	return result
+}
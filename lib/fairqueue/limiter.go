@@ -0,0 +1,264 @@
+package fairqueue
+
+import (
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+)
+
+// Limiter limits the number of concurrently executed requests.
+//
+// Requests, which cannot be executed immediately, are queued per tenant.
+// Free slots are distributed among queued tenants proportionally to their weights
+// according to weighted fair queueing, so tenants with bigger weights get proportionally
+// more slots under contention. Requests waiting for longer than maxWait are executed
+// before other requests regardless of weights in order to prevent starvation.
+type Limiter struct {
+	capacity      int
+	weights       map[string]int
+	defaultWeight int
+	maxWait       time.Duration
+
+	mu sync.Mutex
+
+	// inflight is the number of currently executed requests.
+	inflight int
+
+	// queued is the number of queued requests across all the tenants.
+	queued int
+
+	// vtime is the virtual time of the last started request.
+	vtime float64
+
+	// tenants contains queues for tenants with queued requests.
+	tenants map[string]*tenantQueue
+
+	// idlePasses contains pass values for tenants without queued requests.
+	// They are used when the tenant becomes active again, so the tenant cannot
+	// get more slots than it deserves by re-joining the queue.
+	idlePasses map[string]float64
+}
+
+type tenantQueue struct {
+	name string
+
+	// pass is the virtual time when the next request for the tenant must be started.
+	pass float64
+
+	// stride is the virtual time increment per each started request. It equals to 1/weight.
+	stride float64
+
+	waiters []*waiter
+}
+
+type waiter struct {
+	ch        chan struct{}
+	granted   bool
+	enqueueAt time.Time
+}
+
+// NewLimiter returns new Limiter, which allows executing up to capacity concurrent requests.
+//
+// weights contains per-tenant weights. Tenants missing in weights get defaultWeight.
+// Requests waiting in the queue for longer than maxWait are executed before other requests.
+// maxWait=0 disables starvation protection.
+func NewLimiter(capacity int, weights map[string]int, defaultWeight int, maxWait time.Duration) *Limiter {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	if defaultWeight <= 0 {
+		defaultWeight = 1
+	}
+	return &Limiter{
+		capacity:      capacity,
+		weights:       weights,
+		defaultWeight: defaultWeight,
+		maxWait:       maxWait,
+		tenants:       make(map[string]*tenantQueue),
+		idlePasses:    make(map[string]float64),
+	}
+}
+
+// Capacity returns the maximum number of concurrently executed requests.
+func (l *Limiter) Capacity() int {
+	return l.capacity
+}
+
+// Inflight returns the number of currently executed requests.
+func (l *Limiter) Inflight() int {
+	l.mu.Lock()
+	n := l.inflight
+	l.mu.Unlock()
+	return n
+}
+
+// Queued returns the number of requests waiting for execution.
+func (l *Limiter) Queued() int {
+	l.mu.Lock()
+	n := l.queued
+	l.mu.Unlock()
+	return n
+}
+
+// TryAcquire tries obtaining the execution slot without waiting.
+//
+// Release must be called after the request is executed if TryAcquire returns true.
+func (l *Limiter) TryAcquire() bool {
+	l.mu.Lock()
+	ok := l.tryAcquireLocked()
+	l.mu.Unlock()
+	return ok
+}
+
+func (l *Limiter) tryAcquireLocked() bool {
+	if l.inflight >= l.capacity || l.queued > 0 {
+		return false
+	}
+	l.inflight++
+	return true
+}
+
+// Acquire waits for up to timeout until the execution slot for the given tenant is obtained.
+//
+// It returns false if the slot couldn't be obtained during the timeout.
+// Release must be called after the request is executed if Acquire returns true.
+func (l *Limiter) Acquire(tenant string, timeout time.Duration) bool {
+	l.mu.Lock()
+	if l.tryAcquireLocked() {
+		l.mu.Unlock()
+		return true
+	}
+	w := l.enqueueLocked(tenant, time.Now())
+	l.mu.Unlock()
+
+	t := timerpool.Get(timeout)
+	select {
+	case <-w.ch:
+		timerpool.Put(t)
+		return true
+	case <-t.C:
+		timerpool.Put(t)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		// The slot has been granted concurrently with the timeout.
+		return true
+	}
+	l.removeLocked(tenant, w)
+	return false
+}
+
+// Release releases the execution slot obtained via TryAcquire or Acquire.
+//
+// The slot is passed to the next queued request if any.
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.queued == 0 {
+		l.inflight--
+		return
+	}
+	w := l.dequeueLocked(time.Now())
+	w.granted = true
+	close(w.ch)
+}
+
+func (l *Limiter) getWeight(tenant string) int {
+	if w, ok := l.weights[tenant]; ok && w > 0 {
+		return w
+	}
+	return l.defaultWeight
+}
+
+func (l *Limiter) enqueueLocked(tenant string, now time.Time) *waiter {
+	tq := l.tenants[tenant]
+	if tq == nil {
+		// The tenant becomes active. Start it at the current virtual time,
+		// so it cannot use the credit accumulated while it was idle.
+		pass := l.vtime
+		if p, ok := l.idlePasses[tenant]; ok {
+			if p > pass {
+				pass = p
+			}
+			delete(l.idlePasses, tenant)
+		}
+		tq = &tenantQueue{
+			name:   tenant,
+			pass:   pass,
+			stride: 1 / float64(l.getWeight(tenant)),
+		}
+		l.tenants[tenant] = tq
+	}
+	w := &waiter{
+		ch:        make(chan struct{}),
+		enqueueAt: now,
+	}
+	tq.waiters = append(tq.waiters, w)
+	l.queued++
+	return w
+}
+
+func (l *Limiter) removeLocked(tenant string, w *waiter) {
+	tq := l.tenants[tenant]
+	for i, x := range tq.waiters {
+		if x == w {
+			tq.waiters = append(tq.waiters[:i], tq.waiters[i+1:]...)
+			l.queued--
+			break
+		}
+	}
+	if len(tq.waiters) == 0 {
+		l.deactivateLocked(tq)
+	}
+}
+
+// deactivateLocked removes tq without queued requests from active tenants.
+func (l *Limiter) deactivateLocked(tq *tenantQueue) {
+	delete(l.tenants, tq.name)
+	if tq.pass > l.vtime {
+		l.idlePasses[tq.name] = tq.pass
+	}
+	if len(l.idlePasses) > 2*len(l.tenants)+100 {
+		// Drop outdated passes, since they are overridden by vtime when the tenant becomes active.
+		for tenant, pass := range l.idlePasses {
+			if pass <= l.vtime {
+				delete(l.idlePasses, tenant)
+			}
+		}
+	}
+}
+
+// dequeueLocked returns the next waiter to execute.
+//
+// l.queued must be bigger than 0.
+func (l *Limiter) dequeueLocked(now time.Time) *waiter {
+	var next, oldest *tenantQueue
+	for _, tq := range l.tenants {
+		if next == nil || tq.pass < next.pass || (tq.pass == next.pass && tq.name < next.name) {
+			next = tq
+		}
+		if oldest == nil || tq.waiters[0].enqueueAt.Before(oldest.waiters[0].enqueueAt) {
+			oldest = tq
+		}
+	}
+	if l.maxWait > 0 && now.Sub(oldest.waiters[0].enqueueAt) >= l.maxWait {
+		// Prevent from starvation of the request waiting in the queue for too long.
+		next = oldest
+	}
+	if next.pass > l.vtime {
+		l.vtime = next.pass
+	}
+	next.pass += next.stride
+
+	w := next.waiters[0]
+	next.waiters[0] = nil
+	next.waiters = next.waiters[1:]
+	l.queued--
+	if len(next.waiters) == 0 {
+		l.deactivateLocked(next)
+	}
+	return w
+}
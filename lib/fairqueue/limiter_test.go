@@ -0,0 +1,128 @@
+package fairqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterTryAcquire(t *testing.T) {
+	l := NewLimiter(2, nil, 1, 0)
+	if !l.TryAcquire() {
+		t.Fatalf("expecting successful TryAcquire")
+	}
+	if !l.TryAcquire() {
+		t.Fatalf("expecting successful TryAcquire")
+	}
+	if l.TryAcquire() {
+		t.Fatalf("expecting unsuccessful TryAcquire when all the slots are busy")
+	}
+	if n := l.Inflight(); n != 2 {
+		t.Fatalf("unexpected number of inflight requests; got %d; want %d", n, 2)
+	}
+	l.Release()
+	if !l.TryAcquire() {
+		t.Fatalf("expecting successful TryAcquire after Release")
+	}
+	l.Release()
+	l.Release()
+	if n := l.Inflight(); n != 0 {
+		t.Fatalf("unexpected number of inflight requests; got %d; want %d", n, 0)
+	}
+}
+
+func TestLimiterAcquireTimeout(t *testing.T) {
+	l := NewLimiter(1, nil, 1, 0)
+	if !l.Acquire("foo", time.Second) {
+		t.Fatalf("expecting successful Acquire")
+	}
+	if l.Acquire("foo", 10*time.Millisecond) {
+		t.Fatalf("expecting unsuccessful Acquire when all the slots are busy")
+	}
+	if n := l.Queued(); n != 0 {
+		t.Fatalf("unexpected number of queued requests after timeout; got %d; want %d", n, 0)
+	}
+	l.Release()
+	if !l.Acquire("bar", time.Second) {
+		t.Fatalf("expecting successful Acquire after Release")
+	}
+	l.Release()
+}
+
+func TestLimiterWeights(t *testing.T) {
+	weights := map[string]int{
+		"premium": 3,
+	}
+	l := NewLimiter(1, weights, 1, 0)
+	if !l.TryAcquire() {
+		t.Fatalf("expecting successful TryAcquire")
+	}
+
+	// Queue requests from two tenants.
+	const requestsPerTenant = 8
+	startedCh := make(chan string)
+	for _, tenant := range []string{"free", "premium"} {
+		for i := 0; i < requestsPerTenant; i++ {
+			go func(tenant string) {
+				if !l.Acquire(tenant, time.Minute) {
+					panic("BUG: unexpected timeout")
+				}
+				startedCh <- tenant
+			}(tenant)
+		}
+	}
+	for l.Queued() < 2*requestsPerTenant {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Release slots one by one and verify the order of started requests.
+	var started []string
+	for i := 0; i < 2*requestsPerTenant; i++ {
+		l.Release()
+		started = append(started, <-startedCh)
+	}
+	l.Release()
+
+	// The premium tenant must get 3x more slots than the free tenant while both tenants have queued requests.
+	premiumStarted := 0
+	for _, tenant := range started[:8] {
+		if tenant == "premium" {
+			premiumStarted++
+		}
+	}
+	if premiumStarted != 6 {
+		t.Fatalf("unexpected number of started premium requests among the first 8 requests; got %d; want %d; order: %q", premiumStarted, 6, started)
+	}
+	if n := l.Inflight(); n != 0 {
+		t.Fatalf("unexpected number of inflight requests; got %d; want %d", n, 0)
+	}
+}
+
+func TestLimiterStarvation(t *testing.T) {
+	weights := map[string]int{
+		"premium": 1000,
+	}
+	l := NewLimiter(1, weights, 1, time.Minute)
+	now := time.Now()
+
+	// The free tenant already got a slot, so its pass is far behind the premium tenant.
+	l.enqueueLocked("free", now)
+	if w := l.dequeueLocked(now); w == nil {
+		t.Fatalf("expecting non-nil waiter")
+	}
+	l.enqueueLocked("free", now)
+	for i := 0; i < 10; i++ {
+		l.enqueueLocked("premium", now.Add(time.Second))
+	}
+
+	// The premium tenant must be preferred while the free request waits for less than maxWait.
+	l.dequeueLocked(now.Add(2 * time.Second))
+	if tq := l.tenants["free"]; tq == nil || len(tq.waiters) != 1 {
+		t.Fatalf("the free request mustn't be started before maxWait")
+	}
+
+	// The free request must be started after waiting for maxWait.
+	l.dequeueLocked(now.Add(time.Minute))
+	if tq := l.tenants["free"]; tq != nil {
+		t.Fatalf("the free request must be started after waiting for maxWait")
+	}
+}
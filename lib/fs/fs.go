@@ -467,3 +467,16 @@ func isHTTPURL(targetURL string) bool {
 	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
 
 }
+
+// FileID identifies a file on the filesystem. Hard links to the same file have the same FileID.
+type FileID struct {
+	dev uint64
+	ino uint64
+}
+
+// GetFileID returns FileID for the file with the given fi.
+//
+// false is returned if FileID cannot be obtained on the current platform.
+func GetFileID(fi os.FileInfo) (FileID, bool) {
+	return getFileID(fi)
+}
@@ -3,6 +3,7 @@ package fs
 import (
 	"fmt"
 	"os"
+	"syscall"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"golang.org/x/sys/unix"
@@ -59,3 +60,14 @@ func mustGetFreeSpace(path string) uint64 {
 func freeSpace(stat unix.Statvfs_t) uint64 {
 	return uint64(stat.Bavail) * uint64(stat.Bsize)
 }
+
+func getFileID(fi os.FileInfo) (FileID, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+	return FileID{
+		dev: uint64(st.Dev),
+		ino: uint64(st.Ino),
+	}, true
+}
@@ -6,6 +6,7 @@ package fs
 import (
 	"fmt"
 	"os"
+	"syscall"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"golang.org/x/sys/unix"
@@ -51,3 +52,14 @@ func mustGetFreeSpace(path string) uint64 {
 	}
 	return freeSpace(stat)
 }
+
+func getFileID(fi os.FileInfo) (FileID, bool) {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileID{}, false
+	}
+	return FileID{
+		dev: uint64(st.Dev),
+		ino: uint64(st.Ino),
+	}, true
+}
@@ -144,3 +144,8 @@ func setPosixDelete(handle windows.Handle) error {
 	}
 	return fmt.Errorf("cannot set file disposition information: NT_STATUS: 0x%X, error: %w", r0, err)
 }
+
+// stub
+func getFileID(os.FileInfo) (FileID, bool) {
+	return FileID{}, false
+}
@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
+	"runtime/debug"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/metrics"
@@ -60,6 +62,57 @@ func (r *ReaderAt) MustReadAt(p []byte, off int64) {
 	readBytes.Add(len(p))
 }
 
+// TryReadAt reads len(p) bytes at off from r.
+//
+// Unlike MustReadAt, it returns an error instead of panicking if the data cannot be read because of IO error.
+// IO errors for mmapped files are detected via debug.SetPanicOnFault.
+func (r *ReaderAt) TryReadAt(p []byte, off int64) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if off < 0 {
+		logger.Panicf("off=%d cannot be negative", off)
+	}
+	if len(r.mmapData) == 0 {
+		n, err := r.f.ReadAt(p, off)
+		if err != nil {
+			return fmt.Errorf("cannot read %d bytes at offset %d of file %q: %w", len(p), off, r.f.Name(), err)
+		}
+		if n != len(p) {
+			return fmt.Errorf("unexpected number of bytes read from file %q at offset %d; got %d; want %d", r.f.Name(), off, n, len(p))
+		}
+	} else {
+		if off > int64(len(r.mmapData)-len(p)) {
+			logger.Panicf("off=%d is out of allowed range [0...%d] for len(p)=%d", off, len(r.mmapData)-len(p), len(p))
+		}
+		if err := copyMmapData(p, r.mmapData[off:]); err != nil {
+			return fmt.Errorf("cannot read %d bytes at offset %d of mmapped file %q: %w", len(p), off, r.f.Name(), err)
+		}
+	}
+	readCalls.Inc()
+	readBytes.Add(len(p))
+	return nil
+}
+
+// copyMmapData copies src from mmapped file to dst.
+//
+// IO error while reading mmapped file results in SIGBUS, which is converted to the returned error.
+func copyMmapData(dst, src []byte) (err error) {
+	prev := debug.SetPanicOnFault(true)
+	defer func() {
+		debug.SetPanicOnFault(prev)
+		if v := recover(); v != nil {
+			re, ok := v.(runtime.Error)
+			if !ok {
+				panic(v)
+			}
+			err = re
+		}
+	}()
+	copy(dst, src)
+	return nil
+}
+
 // MustClose closes r.
 func (r *ReaderAt) MustClose() {
 	fname := r.f.Name()
@@ -32,3 +32,39 @@ func testReaderAt(t *testing.T, bufSize int) {
 		r.MustReadAt(buf, offset)
 	}
 }
+
+func TestReaderAtTryReadAt(t *testing.T) {
+	f := func(mmapDisabled bool) {
+		t.Helper()
+		disableMmapOrig := *disableMmap
+		*disableMmap = mmapDisabled
+		defer func() {
+			*disableMmap = disableMmapOrig
+		}()
+
+		path := "TestReaderAtTryReadAt"
+		const fileSize = 1024 * 1024
+		if err := os.WriteFile(path, make([]byte, fileSize), 0600); err != nil {
+			t.Fatalf("cannot create %q: %s", path, err)
+		}
+		defer MustRemoveAll(path)
+		r := MustOpenReaderAt(path)
+		defer r.MustClose()
+
+		buf := make([]byte, 4096)
+		if err := r.TryReadAt(buf, fileSize-int64(len(buf))); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		// Truncate the file, so the data at the end cannot be read anymore.
+		// This results in SIGBUS for mmapped file, which must be converted to an error.
+		if err := os.Truncate(path, 0); err != nil {
+			t.Fatalf("cannot truncate %q: %s", path, err)
+		}
+		if err := r.TryReadAt(buf, fileSize-int64(len(buf))); err == nil {
+			t.Fatalf("expecting non-nil error when reading truncated file")
+		}
+	}
+	f(false)
+	f(true)
+}
@@ -0,0 +1,87 @@
+package hadedup
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Tracker elects a single replica per each cluster of HA replicas, which send the same data.
+//
+// Samples from the elected replica are accepted, while samples from the rest of replicas are dropped.
+// Another replica is elected if the elected replica doesn't send samples during the failover timeout.
+type Tracker struct {
+	failoverTimeout uint64
+
+	mu       sync.Mutex
+	clusters map[string]*electedReplica
+}
+
+type electedReplica struct {
+	replica string
+
+	// lastSeen is unix timestamp in seconds for the last sample received from replica.
+	lastSeen uint64
+}
+
+// NewTracker returns new Tracker with the given failoverTimeoutSecs.
+func NewTracker(failoverTimeoutSecs uint64) *Tracker {
+	return &Tracker{
+		failoverTimeout: failoverTimeoutSecs,
+		clusters:        make(map[string]*electedReplica),
+	}
+}
+
+// Accept returns true if samples from the given replica for the given cluster must be accepted at currentTime.
+//
+// currentTime must contain unix timestamp in seconds.
+func (t *Tracker) Accept(cluster, replica string, currentTime uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	er := t.clusters[cluster]
+	if er == nil {
+		// Copy cluster and replica, since they may refer to the buffer, which is re-used after the call.
+		er = &electedReplica{
+			replica:  strings.Clone(replica),
+			lastSeen: currentTime,
+		}
+		t.clusters[strings.Clone(cluster)] = er
+		return true
+	}
+	if er.replica == replica {
+		if currentTime > er.lastSeen {
+			er.lastSeen = currentTime
+		}
+		return true
+	}
+	if currentTime < er.lastSeen+t.failoverTimeout {
+		droppedSeries.Inc()
+		return false
+	}
+	// The elected replica didn't send samples during failoverTimeout. Elect the given replica.
+	er.replica = strings.Clone(replica)
+	er.lastSeen = currentTime
+	failovers.Inc()
+	return true
+}
+
+// ElectedReplica returns the elected replica for the given cluster.
+//
+// Empty string is returned if there is no elected replica for the cluster.
+func (t *Tracker) ElectedReplica(cluster string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	er := t.clusters[cluster]
+	if er == nil {
+		return ""
+	}
+	return er.replica
+}
+
+var (
+	droppedSeries = metrics.NewCounter(`vm_ha_dedup_dropped_series_total`)
+	failovers     = metrics.NewCounter(`vm_ha_dedup_failovers_total`)
+)
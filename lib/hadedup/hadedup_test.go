@@ -0,0 +1,39 @@
+package hadedup
+
+import (
+	"testing"
+)
+
+func TestTrackerAccept(t *testing.T) {
+	tr := NewTracker(30)
+	f := func(cluster, replica string, currentTime uint64, resultExpected bool, electedExpected string) {
+		t.Helper()
+		result := tr.Accept(cluster, replica, currentTime)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for cluster=%q, replica=%q, currentTime=%d; got %v; want %v", cluster, replica, currentTime, result, resultExpected)
+		}
+		elected := tr.ElectedReplica(cluster)
+		if elected != electedExpected {
+			t.Fatalf("unexpected elected replica for cluster=%q; got %q; want %q", cluster, elected, electedExpected)
+		}
+	}
+
+	// the first replica is elected
+	f("c1", "a", 100, true, "a")
+	f("c1", "b", 105, false, "a")
+	f("c1", "a", 110, true, "a")
+	f("c1", "b", 139, false, "a")
+
+	// clusters are tracked independently
+	f("c2", "b", 110, true, "b")
+	f("c2", "a", 111, false, "b")
+
+	// failover to another replica after the elected replica stops sending samples
+	f("c1", "b", 140, true, "b")
+	f("c1", "a", 150, false, "b")
+	f("c1", "b", 160, true, "b")
+
+	// failover happens only after failover timeout since the last sample from the elected replica
+	f("c2", "a", 139, false, "b")
+	f("c2", "a", 140, true, "a")
+}
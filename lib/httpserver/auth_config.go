@@ -0,0 +1,170 @@
+package httpserver
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"gopkg.in/yaml.v2"
+)
+
+var httpAuthConfigPath = flag.String("httpAuth.config", "", "Optional path to file with users, which are allowed to access the http server via HTTP Basic Auth, "+
+	"and with per-user path scopes. This allows locking down admin endpoints independently of read and write requests. "+
+	"It may be used together with -httpAuth.username, which has access to all the paths. See https://docs.victoriametrics.com/#http-auth-scopes")
+
+// authConfig contains users with path scopes loaded from -httpAuth.config.
+type authConfig struct {
+	Users []authUser `yaml:"users"`
+}
+
+// authUser is a user with the access to the given paths.
+type authUser struct {
+	// Username and Password are credentials for HTTP Basic Auth.
+	//
+	// Requests to Paths are allowed without authentication if Username is empty.
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// Paths contains regular expressions for paths the user has access to.
+	//
+	// Regular expressions are anchored to the beginning and to the end of the path.
+	// The user has access to all the paths if Paths is empty.
+	Paths []string `yaml:"paths,omitempty"`
+
+	pathsRe []*regexp.Regexp
+}
+
+func parseAuthConfig(data []byte) (*authConfig, error) {
+	data, err := envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars: %w", err)
+	}
+	var ac authConfig
+	if err := yaml.UnmarshalStrict(data, &ac); err != nil {
+		return nil, fmt.Errorf("cannot parse yaml: %w", err)
+	}
+	if len(ac.Users) == 0 {
+		return nil, fmt.Errorf("`users` list cannot be empty")
+	}
+	usernames := make(map[string]bool, len(ac.Users))
+	for i := range ac.Users {
+		u := &ac.Users[i]
+		if usernames[u.Username] {
+			if u.Username == "" {
+				return nil, fmt.Errorf("duplicate entry without username; merge the paths for unauthenticated access into a single entry")
+			}
+			return nil, fmt.Errorf("duplicate username %q", u.Username)
+		}
+		usernames[u.Username] = true
+		if u.Username == "" {
+			if u.Password != "" {
+				return nil, fmt.Errorf("password cannot be set without username")
+			}
+			if len(u.Paths) == 0 {
+				return nil, fmt.Errorf("`paths` must be set for the entry without username, since otherwise all the paths become accessible without authentication")
+			}
+		}
+		for _, path := range u.Paths {
+			re, err := regexp.Compile("^(?:" + path + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse path %q for username %q: %w", path, u.Username, err)
+			}
+			u.pathsRe = append(u.pathsRe, re)
+		}
+	}
+	return &ac, nil
+}
+
+// getUser returns the user with the given credentials.
+//
+// nil is returned if there is no such user.
+func (ac *authConfig) getUser(username, password string) *authUser {
+	for i := range ac.Users {
+		u := &ac.Users[i]
+		if u.Username != "" && u.Username == username && passwordEqual(password, u.Password) {
+			return u
+		}
+	}
+	return nil
+}
+
+// isAnonymousPath returns true if the given path is accessible without authentication.
+func (ac *authConfig) isAnonymousPath(path string) bool {
+	for i := range ac.Users {
+		u := &ac.Users[i]
+		if u.Username == "" && u.hasAccess(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *authUser) hasAccess(path string) bool {
+	if len(u.pathsRe) == 0 {
+		return true
+	}
+	for _, re := range u.pathsRe {
+		if re.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	authConfigV        *authConfig
+	authConfigInitOnce sync.Once
+)
+
+func initAuthConfig() {
+	authConfigInitOnce.Do(func() {
+		if *httpAuthConfigPath == "" {
+			return
+		}
+		data, err := os.ReadFile(*httpAuthConfigPath)
+		if err != nil {
+			logger.Fatalf("cannot read -httpAuth.config=%q: %s", *httpAuthConfigPath, err)
+		}
+		ac, err := parseAuthConfig(data)
+		if err != nil {
+			logger.Fatalf("cannot parse -httpAuth.config=%q: %s", *httpAuthConfigPath, err)
+		}
+		authConfigV = ac
+		logger.Infof("loaded %d users with path scopes from -httpAuth.config=%q", len(ac.Users), *httpAuthConfigPath)
+	})
+}
+
+// checkScopedAuth verifies whether the request r is allowed by -httpAuth.config.
+//
+// It returns true if the request may be processed further.
+func checkScopedAuth(w http.ResponseWriter, r *http.Request, ac *authConfig) bool {
+	path := r.URL.Path
+	username, password, ok := r.BasicAuth()
+	if ok {
+		if u := ac.getUser(username, password); u != nil {
+			if u.hasAccess(path) {
+				return true
+			}
+			scopedAuthForbiddenRequests.Inc()
+			http.Error(w, fmt.Sprintf("the user %q has no access to %q according to -httpAuth.config", username, path), http.StatusForbidden)
+			return false
+		}
+	} else if ac.isAnonymousPath(path) {
+		return true
+	}
+	scopedAuthUnauthorizedRequests.Inc()
+	w.Header().Set("WWW-Authenticate", `Basic realm="VictoriaMetrics"`)
+	http.Error(w, "", http.StatusUnauthorized)
+	return false
+}
+
+var (
+	scopedAuthUnauthorizedRequests = metrics.NewCounter(`vm_http_auth_errors_total{reason="unauthorized"}`)
+	scopedAuthForbiddenRequests    = metrics.NewCounter(`vm_http_auth_errors_total{reason="forbidden"}`)
+)
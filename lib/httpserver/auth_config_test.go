@@ -0,0 +1,99 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseAuthConfigFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := parseAuthConfig([]byte(s)); err == nil {
+			t.Fatalf("expecting non-nil error for\n%s", s)
+		}
+	}
+	// Invalid yaml
+	f(`foo`)
+	f(`users: [{username: foo, unknown_field: bar}]`)
+
+	// Empty users
+	f(`users: []`)
+
+	// Duplicate username
+	f(`
+users:
+- username: foo
+- username: foo
+`)
+
+	// Anonymous access to all the paths
+	f(`
+users:
+- password: bar
+  paths: ["/api/v1/query"]
+`)
+	f(`
+users:
+- paths: []
+`)
+
+	// Invalid path regexp
+	f(`
+users:
+- username: foo
+  paths: ["/api/v1/admin/(.+"]
+`)
+}
+
+func TestCheckScopedAuth(t *testing.T) {
+	ac, err := parseAuthConfig([]byte(`
+users:
+- username: admin
+  password: secret
+- username: reader
+  password: foo
+  paths: ["/api/v1/query.*", "/api/v1/labels"]
+- paths: ["/api/v1/write"]
+`))
+	if err != nil {
+		t.Fatalf("cannot parse auth config: %s", err)
+	}
+	f := func(path, username, password string, statusCodeExpected int) {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if username != "" {
+			r.SetBasicAuth(username, password)
+		}
+		w := httptest.NewRecorder()
+		ok := checkScopedAuth(w, r, ac)
+		statusCode := http.StatusOK
+		if !ok {
+			statusCode = w.Code
+		}
+		if statusCode != statusCodeExpected {
+			t.Fatalf("unexpected status code for path=%q, username=%q; got %d; want %d", path, username, statusCode, statusCodeExpected)
+		}
+	}
+
+	// Admin has access to all the paths
+	f("/api/v1/admin/tsdb/delete_series", "admin", "secret", http.StatusOK)
+	f("/api/v1/query", "admin", "secret", http.StatusOK)
+
+	// Reader has access only to the given paths
+	f("/api/v1/query_range", "reader", "foo", http.StatusOK)
+	f("/api/v1/labels", "reader", "foo", http.StatusOK)
+	f("/api/v1/labels/foo", "reader", "foo", http.StatusForbidden)
+	f("/api/v1/admin/tsdb/delete_series", "reader", "foo", http.StatusForbidden)
+
+	// Invalid credentials
+	f("/api/v1/query", "reader", "bar", http.StatusUnauthorized)
+	f("/api/v1/query", "reader", "fo", http.StatusUnauthorized)
+	f("/api/v1/query", "reader", "foo1", http.StatusUnauthorized)
+	f("/api/v1/query", "reader", "", http.StatusUnauthorized)
+	f("/api/v1/query", "unknown", "foo", http.StatusUnauthorized)
+
+	// Anonymous access
+	f("/api/v1/write", "", "", http.StatusOK)
+	f("/api/v1/query", "", "", http.StatusUnauthorized)
+}
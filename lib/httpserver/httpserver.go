@@ -3,6 +3,7 @@ package httpserver
 import (
 	"bufio"
 	"context"
+	"crypto/subtle"
 	"crypto/tls"
 	"errors"
 	"flag"
@@ -37,6 +38,12 @@ var (
 	tlsCipherSuites = flagutil.NewArrayString("tlsCipherSuites", "Optional list of TLS cipher suites for incoming requests over HTTPS if -tls is set. See the list of supported cipher suites at https://pkg.go.dev/crypto/tls#pkg-constants")
 	tlsMinVersion   = flag.String("tlsMinVersion", "", "Optional minimum TLS version to use for incoming requests over HTTPS if -tls is set. "+
 		"Supported values: TLS10, TLS11, TLS12, TLS13")
+	mtlsCAFile = flag.String("mtlsCAFile", "", "Optional path to file with CA certificates for verifying client certificates if -tls is set. "+
+		"Incoming HTTPS connections without client certificates signed by the given CA are rejected if this flag is set (aka mTLS). "+
+		"See https://docs.victoriametrics.com/#tls-mtls-and-ip-allowlist")
+	allowedNets = flagutil.NewArrayString("httpListenAddr.allowedNets", "Optional list of CIDRs or IP addresses, which are allowed to connect to HTTP listeners. "+
+		"Connections from other addresses are closed immediately after accept. By default connections from all the addresses are allowed. "+
+		"See https://docs.victoriametrics.com/#tls-mtls-and-ip-allowlist")
 
 	pathPrefix = flag.String("http.pathPrefix", "", "An optional prefix to add to all the paths handled by http server. For example, if '-http.pathPrefix=/foo/bar' is set, "+
 		"then all the http requests will be handled on '/foo/bar/*' paths. This may be useful for proxied requests. "+
@@ -59,6 +66,15 @@ var (
 	serversLock sync.Mutex
 )
 
+// SetHealthCheck sets f for additional health checking at /health page.
+//
+// /health page returns non-OK response with the error returned from f if f returns non-nil error.
+func SetHealthCheck(f func() error) {
+	healthCheck.Store(f)
+}
+
+var healthCheck atomic.Value
+
 type server struct {
 	shutdownDelayDeadline int64
 	s                     *http.Server
@@ -74,6 +90,9 @@ type RequestHandler func(w http.ResponseWriter, r *http.Request) bool
 
 // Serve starts an http server on the given addr with the given optional rh.
 //
+// addr may contain comma-separated list of addresses to listen to, e.g. `0.0.0.0:8428,[::]:8428`.
+// Stop must be called with the same addr for stopping the server.
+//
 // By default all the responses are transparently compressed, since Google
 // charges a lot for the egress traffic. The compression may be disabled
 // by calling DisableResponseCompression before writing the first byte to w.
@@ -92,28 +111,58 @@ func Serve(addr string, useProxyProtocol bool, rh RequestHandler) {
 	if *tlsEnable {
 		scheme = "https"
 	}
-	hostAddr := addr
-	if strings.HasPrefix(hostAddr, ":") {
-		hostAddr = "127.0.0.1" + hostAddr
+	initAuthConfig()
+	var lns []net.Listener
+	for _, listenAddr := range netutil.ParseListenAddrs(addr) {
+		hostAddr := listenAddr
+		if strings.HasPrefix(hostAddr, ":") {
+			hostAddr = "127.0.0.1" + hostAddr
+		}
+		logger.Infof("starting http server at %s://%s/", scheme, hostAddr)
+		logger.Infof("pprof handlers are exposed at %s://%s/debug/pprof/", scheme, hostAddr)
+		ln, err := NewTCPListener(scheme, listenAddr, useProxyProtocol)
+		if err != nil {
+			logger.Fatalf("cannot start http server at %s: %s", listenAddr, err)
+		}
+		lns = append(lns, ln)
+	}
+	if len(lns) == 0 {
+		logger.Fatalf("missing address to listen for http connections")
+	}
+	serveWithListeners(addr, lns, rh)
+}
+
+// NewTCPListener returns new TCP listener for serving http requests at the given addr.
+//
+// The listener accepts HTTPS connections if -tls is set. It requires client certificates if -mtlsCAFile is set.
+// It accepts connections only from -httpListenAddr.allowedNets if this flag is set.
+//
+// name is used for metrics. Each listener in the program must have a distinct name.
+func NewTCPListener(name, addr string, useProxyProtocol bool) (*netutil.TCPListener, error) {
+	nets, err := netutil.ParseAllowedNets(*allowedNets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse -httpListenAddr.allowedNets: %w", err)
 	}
-	logger.Infof("starting http server at %s://%s/", scheme, hostAddr)
-	logger.Infof("pprof handlers are exposed at %s://%s/debug/pprof/", scheme, hostAddr)
 	var tlsConfig *tls.Config
 	if *tlsEnable {
-		tc, err := netutil.GetServerTLSConfig(*tlsCertFile, *tlsKeyFile, *tlsMinVersion, *tlsCipherSuites)
+		tc, err := netutil.GetServerTLSConfig(*tlsCertFile, *tlsKeyFile, *tlsMinVersion, *tlsCipherSuites, *mtlsCAFile)
 		if err != nil {
-			logger.Fatalf("cannot load TLS cert from -tlsCertFile=%q, -tlsKeyFile=%q, -tlsMinVersion=%q: %s", *tlsCertFile, *tlsKeyFile, *tlsMinVersion, err)
+			return nil, fmt.Errorf("cannot load TLS cert from -tlsCertFile=%q, -tlsKeyFile=%q, -tlsMinVersion=%q, -mtlsCAFile=%q: %w",
+				*tlsCertFile, *tlsKeyFile, *tlsMinVersion, *mtlsCAFile, err)
 		}
 		tlsConfig = tc
+	} else if *mtlsCAFile != "" {
+		return nil, fmt.Errorf("-mtlsCAFile requires -tls to be set")
 	}
-	ln, err := netutil.NewTCPListener(scheme, addr, useProxyProtocol, tlsConfig)
+	ln, err := netutil.NewTCPListener(name, addr, useProxyProtocol, tlsConfig)
 	if err != nil {
-		logger.Fatalf("cannot start http server at %s: %s", addr, err)
+		return nil, err
 	}
-	serveWithListener(addr, ln, rh)
+	ln.SetAllowedNets(nets)
+	return ln, nil
 }
 
-func serveWithListener(addr string, ln net.Listener, rh RequestHandler) {
+func serveWithListeners(addr string, lns []net.Listener, rh RequestHandler) {
 	var s server
 	s.s = &http.Server{
 		Handler: gzipHandler(&s, rh),
@@ -142,13 +191,23 @@ func serveWithListener(addr string, ln net.Listener, rh RequestHandler) {
 	serversLock.Lock()
 	servers[addr] = &s
 	serversLock.Unlock()
-	if err := s.s.Serve(ln); err != nil {
-		if err == http.ErrServerClosed {
-			// The server gracefully closed.
-			return
-		}
-		logger.Panicf("FATAL: cannot serve http at %s: %s", addr, err)
+
+	// A single http.Server serves all the listeners, so they are stopped together by Stop.
+	var wg sync.WaitGroup
+	for _, ln := range lns {
+		wg.Add(1)
+		go func(ln net.Listener) {
+			defer wg.Done()
+			if err := s.s.Serve(ln); err != nil {
+				if err == http.ErrServerClosed {
+					// The server gracefully closed.
+					return
+				}
+				logger.Panicf("FATAL: cannot serve http at %s: %s", ln.Addr(), err)
+			}
+		}(ln)
 	}
+	wg.Wait()
 }
 
 func whetherToCloseConn(r *http.Request) bool {
@@ -267,6 +326,13 @@ func handlerWrapper(s *server, w http.ResponseWriter, r *http.Request, rh Reques
 		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 		deadline := atomic.LoadInt64(&s.shutdownDelayDeadline)
 		if deadline <= 0 {
+			if f, ok := healthCheck.Load().(func() error); ok {
+				if err := f(); err != nil {
+					// Return non-OK response, so load balancers could re-route new requests to other servers.
+					http.Error(w, err.Error(), http.StatusServiceUnavailable)
+					return
+				}
+			}
 			w.Write([]byte("OK"))
 			return
 		}
@@ -360,13 +426,25 @@ func CheckAuthFlag(w http.ResponseWriter, r *http.Request, flagValue string, fla
 
 // CheckBasicAuth validates credentials provided in request if httpAuth.* flags are set
 // returns true if credentials are valid or httpAuth.* flags are not set
+//
+// Path scopes from -httpAuth.config are verified if this flag is set.
 func CheckBasicAuth(w http.ResponseWriter, r *http.Request) bool {
+	if ac := authConfigV; ac != nil {
+		if len(*httpAuthUsername) > 0 {
+			// -httpAuth.username has access to all the paths.
+			username, password, ok := r.BasicAuth()
+			if ok && username == *httpAuthUsername && passwordEqual(password, *httpAuthPassword) {
+				return true
+			}
+		}
+		return checkScopedAuth(w, r, ac)
+	}
 	if len(*httpAuthUsername) == 0 {
 		// HTTP Basic Auth is disabled.
 		return true
 	}
 	username, password, ok := r.BasicAuth()
-	if ok && username == *httpAuthUsername && password == *httpAuthPassword {
+	if ok && username == *httpAuthUsername && passwordEqual(password, *httpAuthPassword) {
 		return true
 	}
 	w.Header().Set("WWW-Authenticate", `Basic realm="VictoriaMetrics"`)
@@ -374,6 +452,13 @@ func CheckBasicAuth(w http.ResponseWriter, r *http.Request) bool {
 	return false
 }
 
+// passwordEqual returns true if password equals to expectedPassword.
+//
+// The comparison is performed in constant time in order to prevent from timing attacks.
+func passwordEqual(password, expectedPassword string) bool {
+	return subtle.ConstantTimeCompare([]byte(password), []byte(expectedPassword)) == 1
+}
+
 func maybeGzipResponseWriter(w http.ResponseWriter, r *http.Request) http.ResponseWriter {
 	if *disableResponseCompression {
 		return w
@@ -617,18 +702,31 @@ func Errorf(w http.ResponseWriter, r *http.Request, format string, args ...inter
 	for _, arg := range args {
 		if err, ok := arg.(error); ok && errors.As(err, &esc) {
 			statusCode = esc.StatusCode
+			for k, vs := range esc.Header {
+				for _, v := range vs {
+					w.Header().Add(k, v)
+				}
+			}
 			break
 		}
 	}
 	http.Error(w, errStr, statusCode)
 }
 
+// BackpressureHeader is the name of response header, which is sent when the request is rejected because of backpressure.
+//
+// The header value contains the backpressure reason.
+const BackpressureHeader = "X-VictoriaMetrics-Backpressure"
+
 // ErrorWithStatusCode is error with HTTP status code.
 //
-// The given StatusCode is sent to client when the error is passed to Errorf.
+// The given StatusCode and Header are sent to client when the error is passed to Errorf.
 type ErrorWithStatusCode struct {
 	Err        error
 	StatusCode int
+
+	// Header contains optional response headers.
+	Header http.Header
 }
 
 // Unwrap returns e.Err.
@@ -26,15 +26,13 @@ var (
 
 // Server accepts InfluxDB line protocol over TCP and UDP.
 type Server struct {
-	addr  string
-	lnTCP net.Listener
-	lnUDP net.PacketConn
-	wg    sync.WaitGroup
-	cm    ingestserver.ConnsMap
+	servers []*server
 }
 
 // MustStart starts InfluxDB server on the given addr.
 //
+// addr may contain comma-separated list of addresses to listen to, e.g. `0.0.0.0:8089,[::]:8089`.
+//
 // The incoming connections are processed with insertHandler.
 //
 // If useProxyProtocol is set to true, then the incoming connections are accepted via proxy protocol.
@@ -42,6 +40,30 @@ type Server struct {
 //
 // MustStop must be called on the returned server when it is no longer needed.
 func MustStart(addr string, useProxyProtocol bool, insertHandler func(r io.Reader) error) *Server {
+	var s Server
+	for _, listenAddr := range netutil.ParseListenAddrs(addr) {
+		s.servers = append(s.servers, mustStart(listenAddr, useProxyProtocol, insertHandler))
+	}
+	return &s
+}
+
+// MustStop stops the server.
+func (s *Server) MustStop() {
+	for _, srv := range s.servers {
+		srv.mustStop()
+	}
+}
+
+// server accepts InfluxDB line protocol over TCP and UDP at a single addr.
+type server struct {
+	addr  string
+	lnTCP net.Listener
+	lnUDP net.PacketConn
+	wg    sync.WaitGroup
+	cm    ingestserver.ConnsMap
+}
+
+func mustStart(addr string, useProxyProtocol bool, insertHandler func(r io.Reader) error) *server {
 	logger.Infof("starting TCP InfluxDB server at %q", addr)
 	lnTCP, err := netutil.NewTCPListener("influx", addr, useProxyProtocol, nil)
 	if err != nil {
@@ -49,12 +71,12 @@ func MustStart(addr string, useProxyProtocol bool, insertHandler func(r io.Reade
 	}
 
 	logger.Infof("starting UDP InfluxDB server at %q", addr)
-	lnUDP, err := net.ListenPacket(netutil.GetUDPNetwork(), addr)
+	lnUDP, err := netutil.NewUDPListener("influx", addr)
 	if err != nil {
 		logger.Fatalf("cannot start UDP InfluxDB server at %q: %s", addr, err)
 	}
 
-	s := &Server{
+	s := &server{
 		addr:  addr,
 		lnTCP: lnTCP,
 		lnUDP: lnUDP,
@@ -75,8 +97,7 @@ func MustStart(addr string, useProxyProtocol bool, insertHandler func(r io.Reade
 	return s
 }
 
-// MustStop stops the server.
-func (s *Server) MustStop() {
+func (s *server) mustStop() {
 	logger.Infof("stopping TCP InfluxDB server at %q...", s.addr)
 	if err := s.lnTCP.Close(); err != nil {
 		logger.Errorf("cannot close TCP InfluxDB server: %s", err)
@@ -90,7 +111,7 @@ func (s *Server) MustStop() {
 	logger.Infof("TCP and UDP InfluxDB servers at %q have been stopped", s.addr)
 }
 
-func (s *Server) serveTCP(insertHandler func(r io.Reader) error) {
+func (s *server) serveTCP(insertHandler func(r io.Reader) error) {
 	var wg sync.WaitGroup
 	for {
 		c, err := s.lnTCP.Accept()
@@ -130,7 +151,7 @@ func (s *Server) serveTCP(insertHandler func(r io.Reader) error) {
 	wg.Wait()
 }
 
-func (s *Server) serveUDP(insertHandler func(r io.Reader) error) {
+func (s *server) serveUDP(insertHandler func(r io.Reader) error) {
 	gomaxprocs := cgroup.AvailableCPUs()
 	var wg sync.WaitGroup
 	for i := 0; i < gomaxprocs; i++ {
@@ -0,0 +1,91 @@
+package multiproto
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+)
+
+// protocol is the ingestion protocol detected for the incoming connection.
+type protocol int
+
+const (
+	protocolHTTP protocol = iota
+	protocolInflux
+	protocolGraphite
+	protocolOpenTSDB
+)
+
+func (p protocol) String() string {
+	switch p {
+	case protocolHTTP:
+		return "http"
+	case protocolInflux:
+		return "influx"
+	case protocolGraphite:
+		return "graphite"
+	case protocolOpenTSDB:
+		return "opentsdb"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(p))
+	}
+}
+
+// peekFirstLine returns the first line from br without consuming it.
+//
+// An error is returned if the first line doesn't fit br buffer.
+func peekFirstLine(br *bufio.Reader) ([]byte, error) {
+	checked := 0
+	for {
+		b, _ := br.Peek(br.Buffered())
+		if n := bytes.IndexByte(b[checked:], '\n'); n >= 0 {
+			return b[:checked+n], nil
+		}
+		checked = len(b)
+		// Wait until at least a single byte arrives after the buffered data.
+		if _, err := br.Peek(len(b) + 1); err != nil {
+			if err != bufio.ErrBufferFull && len(b) > 0 {
+				// The connection contains a single line without trailing newline.
+				return b, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// detectProtocol detects the ingestion protocol from the first line of the incoming connection.
+func detectProtocol(line []byte) protocol {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if bytes.HasPrefix(line[bytes.LastIndexByte(line, ' ')+1:], []byte("HTTP/1.")) {
+		// HTTP request line: `<method> <path> HTTP/1.x`
+		return protocolHTTP
+	}
+	line = bytes.TrimLeft(line, " ")
+	for _, prefix := range []string{"put ", "rollup ", "histogram "} {
+		if bytes.HasPrefix(line, []byte(prefix)) {
+			return protocolOpenTSDB
+		}
+	}
+
+	// Influx line protocol: `<measurement>[,<tags>] <fields> [<timestamp>]`, where fields contain `=`.
+	// Graphite plaintext protocol: `<metric_path> <value> [<timestamp>]`, where value doesn't contain `=`.
+	// Skip the first token with possibly escaped whitespace and inspect the second token.
+	i := 0
+	for i < len(line) && line[i] != ' ' {
+		if line[i] == '\\' {
+			i++
+		}
+		i++
+	}
+	if i >= len(line) {
+		return protocolGraphite
+	}
+	tail := bytes.TrimLeft(line[i:], " ")
+	if n := bytes.IndexByte(tail, ' '); n >= 0 {
+		tail = tail[:n]
+	}
+	if bytes.IndexByte(tail, '=') >= 0 {
+		return protocolInflux
+	}
+	return protocolGraphite
+}
@@ -0,0 +1,74 @@
+package multiproto
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestDetectProtocol(t *testing.T) {
+	f := func(line string, pExpected protocol) {
+		t.Helper()
+		p := detectProtocol([]byte(line))
+		if p != pExpected {
+			t.Fatalf("unexpected protocol detected for %q; got %s; want %s", line, p, pExpected)
+		}
+	}
+
+	// HTTP
+	f("POST /api/v1/write HTTP/1.1\r", protocolHTTP)
+	f("GET /health HTTP/1.0", protocolHTTP)
+
+	// OpenTSDB
+	f("put foo.bar 1234 5 a=b", protocolOpenTSDB)
+	f("  put foo 1234 5", protocolOpenTSDB)
+	f("rollup 1h-SUM foo 1234 5 a=b", protocolOpenTSDB)
+	f("histogram foo 1234 0 AAAA a=b", protocolOpenTSDB)
+
+	// Influx
+	f("cpu,host=a usage=1.2 1234", protocolInflux)
+	f("cpu usage=1.2", protocolInflux)
+	f(`cpu\ total,host=a usage=1.2`, protocolInflux)
+	f(`cpu msg="foo bar"`, protocolInflux)
+	f("CPU,host=a usage=1.2\r", protocolInflux)
+
+	// Graphite
+	f("foo.bar.baz 123 1234", protocolGraphite)
+	f("foo.bar;tag1=value1;tag2=value2 123 1234", protocolGraphite)
+	f("foo.bar 123", protocolGraphite)
+	f("foo", protocolGraphite)
+	f("", protocolGraphite)
+}
+
+func TestPeekFirstLine(t *testing.T) {
+	f := func(s string, lineExpected string) {
+		t.Helper()
+		br := bufio.NewReaderSize(strings.NewReader(s), 16)
+		line, err := peekFirstLine(br)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if string(line) != lineExpected {
+			t.Fatalf("unexpected line; got %q; want %q", line, lineExpected)
+		}
+		// The peeked data mustn't be consumed
+		if br.Buffered() < len(lineExpected) {
+			t.Fatalf("the first line has been consumed")
+		}
+	}
+	f("foo 1 2\nbar 3 4\n", "foo 1 2")
+	f("foo 1 2", "foo 1 2")
+	f("\n", "")
+
+	// Too long line
+	br := bufio.NewReaderSize(strings.NewReader(strings.Repeat("x", 100)+"\n"), 16)
+	if _, err := peekFirstLine(br); err == nil {
+		t.Fatalf("expecting non-nil error for too long line")
+	}
+
+	// Empty input
+	br = bufio.NewReaderSize(strings.NewReader(""), 16)
+	if _, err := peekFirstLine(br); err == nil {
+		t.Fatalf("expecting non-nil error for empty input")
+	}
+}
@@ -0,0 +1,286 @@
+package multiproto
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/ingestserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/netutil"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	writeRequestsTCP = metrics.NewCounter(`vm_ingestserver_requests_total{type="multiproto", name="write", net="tcp"}`)
+	writeErrorsTCP   = metrics.NewCounter(`vm_ingestserver_request_errors_total{type="multiproto", name="write", net="tcp"}`)
+
+	detectErrors = metrics.NewCounter(`vm_ingestserver_protocol_detection_errors_total{type="multiproto"}`)
+
+	detectedHTTP     = metrics.NewCounter(`vm_ingestserver_detected_protocols_total{type="multiproto", protocol="http"}`)
+	detectedInflux   = metrics.NewCounter(`vm_ingestserver_detected_protocols_total{type="multiproto", protocol="influx"}`)
+	detectedGraphite = metrics.NewCounter(`vm_ingestserver_detected_protocols_total{type="multiproto", protocol="graphite"}`)
+	detectedOpenTSDB = metrics.NewCounter(`vm_ingestserver_detected_protocols_total{type="multiproto", protocol="opentsdb"}`)
+)
+
+// Handlers contains insert handlers for the protocols served by Server.
+type Handlers struct {
+	// Influx processes InfluxDB line protocol data.
+	Influx func(r io.Reader) error
+	// Graphite processes Graphite plaintext protocol data.
+	Graphite func(r io.Reader) error
+	// OpenTSDB processes OpenTSDB telnet protocol data.
+	OpenTSDB func(r io.Reader) error
+	// HTTP processes HTTP requests such as Prometheus remote write requests.
+	HTTP func(req *http.Request) error
+}
+
+// Server accepts InfluxDB line protocol, Graphite plaintext protocol, OpenTSDB telnet protocol
+// and HTTP requests on a single TCP port.
+//
+// The protocol is detected from the first line of every accepted connection.
+type Server struct {
+	servers []*server
+}
+
+// MustStart starts multi-protocol server on the given addr.
+//
+// addr may contain comma-separated list of addresses to listen to, e.g. `0.0.0.0:2003,[::]:2003`.
+//
+// If useProxyProtocol is set to true, then the incoming connections are accepted via proxy protocol.
+// See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+//
+// MustStop must be called on the returned server when it is no longer needed.
+func MustStart(addr string, useProxyProtocol bool, handlers Handlers) *Server {
+	var s Server
+	for _, listenAddr := range netutil.ParseListenAddrs(addr) {
+		s.servers = append(s.servers, mustStart(listenAddr, useProxyProtocol, handlers))
+	}
+	return &s
+}
+
+// MustStop stops the server.
+func (s *Server) MustStop() {
+	for _, srv := range s.servers {
+		srv.mustStop()
+	}
+}
+
+// server is multi-protocol ingestion server at a single addr.
+type server struct {
+	addr     string
+	lnTCP    net.Listener
+	handlers Handlers
+
+	httpServer  *http.Server
+	httpConnsCh chan net.Conn
+	stopCh      chan struct{}
+	stopOnce    sync.Once
+
+	wg sync.WaitGroup
+	cm ingestserver.ConnsMap
+}
+
+func mustStart(addr string, useProxyProtocol bool, handlers Handlers) *server {
+	logger.Infof("starting TCP multi-protocol ingestion server at %q", addr)
+	lnTCP, err := netutil.NewTCPListener("multiproto", addr, useProxyProtocol, nil)
+	if err != nil {
+		logger.Fatalf("cannot start TCP multi-protocol ingestion server at %q: %s", addr, err)
+	}
+	s := &server{
+		addr:        addr,
+		lnTCP:       lnTCP,
+		handlers:    handlers,
+		httpConnsCh: make(chan net.Conn),
+		stopCh:      make(chan struct{}),
+	}
+	s.httpServer = &http.Server{
+		Handler:           http.HandlerFunc(s.serveHTTPRequest),
+		ReadHeaderTimeout: 5 * time.Second,
+		IdleTimeout:       time.Minute,
+		// Do not set ReadTimeout and WriteTimeout here,
+		// since these timeouts must be controlled by request handler.
+	}
+	s.cm.Init()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		err := s.httpServer.Serve(&chanListener{s: s})
+		if err != nil && err != http.ErrServerClosed {
+			logger.Fatalf("error serving HTTP requests at multi-protocol ingestion server %q: %s", addr, err)
+		}
+	}()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.serveTCP()
+		logger.Infof("stopped TCP multi-protocol ingestion server at %q", addr)
+	}()
+	return s
+}
+
+func (s *server) mustStop() {
+	logger.Infof("stopping TCP multi-protocol ingestion server at %q...", s.addr)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		logger.Errorf("cannot stop HTTP requests processing at multi-protocol ingestion server %q: %s", s.addr, err)
+	}
+	if err := s.lnTCP.Close(); err != nil {
+		logger.Errorf("cannot close TCP multi-protocol ingestion server: %s", err)
+	}
+	s.cm.CloseAll()
+	s.wg.Wait()
+	logger.Infof("TCP multi-protocol ingestion server at %q has been stopped", s.addr)
+}
+
+func (s *server) serveTCP() {
+	var wg sync.WaitGroup
+	for {
+		c, err := s.lnTCP.Accept()
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) {
+				if ne.Temporary() {
+					logger.Errorf("multiproto: temporary error when listening for TCP addr %q: %s", s.lnTCP.Addr(), err)
+					time.Sleep(time.Second)
+					continue
+				}
+				if strings.Contains(err.Error(), "use of closed network connection") {
+					break
+				}
+				logger.Fatalf("unrecoverable error when accepting TCP multi-protocol connections: %s", err)
+			}
+			logger.Fatalf("unexpected error when accepting TCP multi-protocol connections: %s", err)
+		}
+		if !s.cm.Add(c) {
+			_ = c.Close()
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.serveConn(c)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *server) serveConn(c net.Conn) {
+	br := bufio.NewReaderSize(c, 64*1024)
+	line, err := peekFirstLine(br)
+	if err != nil {
+		if err != io.EOF {
+			detectErrors.Inc()
+			logger.Errorf("cannot detect protocol for TCP conn %q<->%q: cannot read the first line: %s", c.LocalAddr(), c.RemoteAddr(), err)
+		}
+		s.cm.Delete(c)
+		_ = c.Close()
+		return
+	}
+	p := detectProtocol(line)
+	if p == protocolHTTP {
+		detectedHTTP.Inc()
+		// Pass the connection to HTTP server, which is responsible for closing it.
+		s.cm.Delete(c)
+		bc := &bufferedConn{
+			Conn: c,
+			br:   br,
+		}
+		select {
+		case s.httpConnsCh <- bc:
+		case <-s.stopCh:
+			_ = c.Close()
+		}
+		return
+	}
+
+	defer func() {
+		s.cm.Delete(c)
+		_ = c.Close()
+	}()
+	var insertHandler func(r io.Reader) error
+	switch p {
+	case protocolInflux:
+		detectedInflux.Inc()
+		insertHandler = s.handlers.Influx
+	case protocolGraphite:
+		detectedGraphite.Inc()
+		insertHandler = s.handlers.Graphite
+	case protocolOpenTSDB:
+		detectedOpenTSDB.Inc()
+		insertHandler = s.handlers.OpenTSDB
+	default:
+		logger.Panicf("BUG: unexpected protocol detected: %s", p)
+	}
+	writeRequestsTCP.Inc()
+	if insertHandler == nil {
+		writeErrorsTCP.Inc()
+		logger.Errorf("unsupported protocol %s in TCP conn %q<->%q", p, c.LocalAddr(), c.RemoteAddr())
+		return
+	}
+	if err := insertHandler(br); err != nil {
+		writeErrorsTCP.Inc()
+		logger.Errorf("error in TCP %s conn %q<->%q: %s", p, c.LocalAddr(), c.RemoteAddr(), err)
+	}
+}
+
+func (s *server) serveHTTPRequest(w http.ResponseWriter, r *http.Request) {
+	if !httpserver.CheckBasicAuth(w, r) {
+		return
+	}
+	writeRequestsTCP.Inc()
+	if s.handlers.HTTP == nil {
+		writeErrorsTCP.Inc()
+		httpserver.Errorf(w, r, "HTTP requests aren't supported")
+		return
+	}
+	if err := s.handlers.HTTP(r); err != nil {
+		writeErrorsTCP.Inc()
+		httpserver.Errorf(w, r, "%s", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bufferedConn reads data peeked during protocol detection before reading from the underlying conn.
+type bufferedConn struct {
+	net.Conn
+	br *bufio.Reader
+}
+
+func (bc *bufferedConn) Read(p []byte) (int, error) {
+	return bc.br.Read(p)
+}
+
+// chanListener passes HTTP connections detected by Server to http.Server.
+type chanListener struct {
+	s *server
+}
+
+func (cl *chanListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-cl.s.httpConnsCh:
+		return c, nil
+	case <-cl.s.stopCh:
+		return nil, net.ErrClosed
+	}
+}
+
+func (cl *chanListener) Close() error {
+	cl.s.stopOnce.Do(func() {
+		close(cl.s.stopCh)
+	})
+	return nil
+}
+
+func (cl *chanListener) Addr() net.Addr {
+	return cl.s.lnTCP.Addr()
+}
@@ -88,8 +88,8 @@ func (ls *listenerSwitch) worker() {
 			Conn:      c,
 			firstChar: buf[0],
 		}
-		if buf[0] == 'p' {
-			// Assume the request starts with `put`.
+		if buf[0] == 'p' || buf[0] == 'r' || buf[0] == 'h' {
+			// Assume the request starts with `put`, `rollup` or `histogram`.
 			ls.telnetConnsCh <- pc
 		} else {
 			// Assume the request starts with `POST`.
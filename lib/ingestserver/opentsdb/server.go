@@ -30,21 +30,43 @@ var (
 //
 // It accepts simultaneously Telnet put requests and HTTP put requests over TCP.
 type Server struct {
-	addr       string
-	ls         *listenerSwitch
-	httpServer *opentsdbhttp.Server
-	lnUDP      net.PacketConn
-	wg         sync.WaitGroup
-	cm         ingestserver.ConnsMap
+	servers []*server
 }
 
 // MustStart starts OpenTSDB collector on the given addr.
 //
+// addr may contain comma-separated list of addresses to listen to, e.g. `0.0.0.0:4242,[::]:4242`.
+//
 // If useProxyProtocol is set to true, then the incoming connections are accepted via proxy protocol.
 // See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
 //
 // MustStop must be called on the returned server when it is no longer needed.
 func MustStart(addr string, useProxyProtocol bool, telnetInsertHandler func(r io.Reader) error, httpInsertHandler func(req *http.Request) error) *Server {
+	var s Server
+	for _, listenAddr := range netutil.ParseListenAddrs(addr) {
+		s.servers = append(s.servers, mustStart(listenAddr, useProxyProtocol, telnetInsertHandler, httpInsertHandler))
+	}
+	return &s
+}
+
+// MustStop stops the server.
+func (s *Server) MustStop() {
+	for _, srv := range s.servers {
+		srv.mustStop()
+	}
+}
+
+// server collects OpenTSDB TCP and UDP metrics at a single addr.
+type server struct {
+	addr       string
+	ls         *listenerSwitch
+	httpServer *opentsdbhttp.Server
+	lnUDP      net.PacketConn
+	wg         sync.WaitGroup
+	cm         ingestserver.ConnsMap
+}
+
+func mustStart(addr string, useProxyProtocol bool, telnetInsertHandler func(r io.Reader) error, httpInsertHandler func(req *http.Request) error) *server {
 	logger.Infof("starting TCP OpenTSDB collector at %q", addr)
 	lnTCP, err := netutil.NewTCPListener("opentsdb", addr, useProxyProtocol, nil)
 	if err != nil {
@@ -56,12 +78,12 @@ func MustStart(addr string, useProxyProtocol bool, telnetInsertHandler func(r io
 	httpServer := opentsdbhttp.MustServe(lnHTTP, httpInsertHandler)
 
 	logger.Infof("starting UDP OpenTSDB collector at %q", addr)
-	lnUDP, err := net.ListenPacket(netutil.GetUDPNetwork(), addr)
+	lnUDP, err := netutil.NewUDPListener("opentsdb", addr)
 	if err != nil {
 		logger.Fatalf("cannot start UDP OpenTSDB collector at %q: %s", addr, err)
 	}
 
-	s := &Server{
+	s := &server{
 		addr:       addr,
 		ls:         ls,
 		httpServer: httpServer,
@@ -89,8 +111,7 @@ func MustStart(addr string, useProxyProtocol bool, telnetInsertHandler func(r io
 	return s
 }
 
-// MustStop stops the server.
-func (s *Server) MustStop() {
+func (s *server) mustStop() {
 	// Stop HTTP server. Do not emit log message, since it is emitted by the httpServer.
 	s.httpServer.MustStop()
 
@@ -108,7 +129,7 @@ func (s *Server) MustStop() {
 	logger.Infof("TCP and UDP OpenTSDB servers at %q have been stopped", s.addr)
 }
 
-func (s *Server) serveTelnet(ln net.Listener, insertHandler func(r io.Reader) error) {
+func (s *server) serveTelnet(ln net.Listener, insertHandler func(r io.Reader) error) {
 	var wg sync.WaitGroup
 	for {
 		c, err := ln.Accept()
@@ -148,7 +169,7 @@ func (s *Server) serveTelnet(ln net.Listener, insertHandler func(r io.Reader) er
 	wg.Wait()
 }
 
-func (s *Server) serveUDP(insertHandler func(r io.Reader) error) {
+func (s *server) serveUDP(insertHandler func(r io.Reader) error) {
 	gomaxprocs := cgroup.AvailableCPUs()
 	var wg sync.WaitGroup
 	for i := 0; i < gomaxprocs; i++ {
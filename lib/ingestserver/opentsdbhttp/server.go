@@ -20,30 +20,41 @@ var (
 
 // Server represents HTTP OpenTSDB server.
 type Server struct {
-	s  *http.Server
-	ln net.Listener
-	wg sync.WaitGroup
+	s    *http.Server
+	addr string
+	lns  []net.Listener
+	wg   sync.WaitGroup
 }
 
 // MustStart starts HTTP OpenTSDB server on the given addr.
 //
+// addr may contain comma-separated list of addresses to listen to, e.g. `0.0.0.0:4242,[::]:4242`.
+//
 // If useProxyProtocol is set to true, then the incoming connections are accepted via proxy protocol.
 // See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
 //
 // MustStop must be called on the returned server when it is no longer needed.
 func MustStart(addr string, useProxyProtocol bool, insertHandler func(r *http.Request) error) *Server {
-	logger.Infof("starting HTTP OpenTSDB server at %q", addr)
-	lnTCP, err := netutil.NewTCPListener("opentsdbhttp", addr, useProxyProtocol, nil)
-	if err != nil {
-		logger.Fatalf("cannot start HTTP OpenTSDB collector at %q: %s", addr, err)
+	var lns []net.Listener
+	for _, listenAddr := range netutil.ParseListenAddrs(addr) {
+		logger.Infof("starting HTTP OpenTSDB server at %q", listenAddr)
+		lnTCP, err := httpserver.NewTCPListener("opentsdbhttp", listenAddr, useProxyProtocol)
+		if err != nil {
+			logger.Fatalf("cannot start HTTP OpenTSDB collector at %q: %s", listenAddr, err)
+		}
+		lns = append(lns, lnTCP)
 	}
-	return MustServe(lnTCP, insertHandler)
+	return mustServe(addr, lns, insertHandler)
 }
 
 // MustServe serves OpenTSDB HTTP put requests from ln.
 //
 // MustStop must be called on the returned server when it is no longer needed.
 func MustServe(ln net.Listener, insertHandler func(r *http.Request) error) *Server {
+	return mustServe(ln.Addr().String(), []net.Listener{ln}, insertHandler)
+}
+
+func mustServe(addr string, lns []net.Listener, insertHandler func(r *http.Request) error) *Server {
 	h := newRequestHandler(insertHandler)
 	hs := &http.Server{
 		Handler:           h,
@@ -53,20 +64,23 @@ func MustServe(ln net.Listener, insertHandler func(r *http.Request) error) *Serv
 		// since these timeouts must be controlled by request handler.
 	}
 	s := &Server{
-		s:  hs,
-		ln: ln,
+		s:    hs,
+		addr: addr,
+		lns:  lns,
+	}
+	for _, ln := range lns {
+		s.wg.Add(1)
+		go func(ln net.Listener) {
+			defer s.wg.Done()
+			err := s.s.Serve(ln)
+			if err == http.ErrServerClosed {
+				return
+			}
+			if err != nil {
+				logger.Fatalf("error serving HTTP OpenTSDB at %q: %s", ln.Addr(), err)
+			}
+		}(ln)
 	}
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		err := s.s.Serve(s.ln)
-		if err == http.ErrServerClosed {
-			return
-		}
-		if err != nil {
-			logger.Fatalf("error serving HTTP OpenTSDB at %q: %s", s.ln.Addr(), err)
-		}
-	}()
 	return s
 }
 
@@ -77,14 +91,14 @@ func (s *Server) Wait() {
 
 // MustStop stops HTTP OpenTSDB server.
 func (s *Server) MustStop() {
-	logger.Infof("stopping HTTP OpenTSDB server at %q...", s.ln.Addr())
+	logger.Infof("stopping HTTP OpenTSDB server at %q...", s.addr)
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := s.s.Shutdown(ctx); err != nil {
-		logger.Fatalf("cannot close HTTP OpenTSDB server at %q: %s", s.ln.Addr(), err)
+		logger.Fatalf("cannot close HTTP OpenTSDB server at %q: %s", s.addr, err)
 	}
 	s.wg.Wait()
-	logger.Infof("OpenTSDB HTTP server at %q has been stopped", s.ln.Addr())
+	logger.Infof("OpenTSDB HTTP server at %q has been stopped", s.addr)
 }
 
 func newRequestHandler(insertHandler func(r *http.Request) error) http.Handler {
@@ -0,0 +1,340 @@
+package labelsintern
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// ErrUnknownRef is returned from Request.Unmarshal when the request refers to a label set, which is missing in the session.
+//
+// This may happen after the receiver restart or after the session is evicted because of inactivity or memory limits.
+// The sender must start a new session via Encoder.Reset and re-send the request.
+var ErrUnknownRef = errors.New("unknown interned label set reference")
+
+// Request is a write request with interned label sets.
+type Request struct {
+	// Timeseries contains the unmarshaled time series.
+	//
+	// Labels for the time series are shared with the session and mustn't be modified.
+	Timeseries []prompb.TimeSeries
+
+	samplesPool []prompb.Sample
+}
+
+// Reset resets r.
+func (r *Request) Reset() {
+	for i := range r.Timeseries {
+		ts := &r.Timeseries[i]
+		ts.Labels = nil
+		ts.Samples = nil
+	}
+	r.Timeseries = r.Timeseries[:0]
+	r.samplesPool = r.samplesPool[:0]
+}
+
+// Unmarshal unmarshals r from src for the session with the given sessionID.
+//
+// ErrUnknownRef is returned if src refers to a label set, which is missing in the session.
+func (r *Request) Unmarshal(sessionID string, src []byte) error {
+	r.Reset()
+	if len(src) == 0 {
+		return fmt.Errorf("missing format version")
+	}
+	if src[0] != formatVersion {
+		return fmt.Errorf("unsupported format version: %d; want %d", src[0], formatVersion)
+	}
+	src = src[1:]
+	tail, seriesCount, err := encoding.UnmarshalVarUint64(src)
+	if err != nil {
+		return fmt.Errorf("cannot unmarshal series count: %w", err)
+	}
+	src = tail
+
+	sess := getSession(sessionID)
+	sess.mu.Lock()
+	for sess.deleted {
+		// The session has been evicted after it was obtained.
+		sess.mu.Unlock()
+		sess = getSession(sessionID)
+		sess.mu.Lock()
+	}
+	sizeBefore := sess.sizeBytes
+	err = r.unmarshalTimeseries(sess, seriesCount, src)
+	atomic.AddUint64(&sessionsSizeBytes, sess.sizeBytes-sizeBefore)
+	sess.lastAccessTime = fasttime.UnixTimestamp()
+	sess.mu.Unlock()
+
+	if atomic.LoadUint64(&sessionsSizeBytes) > getMaxSessionsSize() {
+		evictSessions(sess)
+	}
+	return err
+}
+
+func (r *Request) unmarshalTimeseries(sess *session, seriesCount uint64, src []byte) error {
+	tss := r.Timeseries
+	samples := r.samplesPool
+	timestamp := int64(0)
+	ref := uint64(0)
+	for i := uint64(0); i < seriesCount; i++ {
+		tail, v, err := encoding.UnmarshalVarInt64(src)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal label set reference for series #%d: %w", i, err)
+		}
+		src = tail
+		ref += uint64(v >> 1)
+		var labels []prompb.Label
+		if v&1 != 0 {
+			tail, labels, err = unmarshalLabels(src)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal label set for series #%d: %w", i, err)
+			}
+			src = tail
+			sess.setLabels(ref, labels)
+		} else {
+			labels = sess.refs[ref]
+			if labels == nil {
+				unknownRefs.Inc()
+				return fmt.Errorf("%w %d for series #%d in session %q", ErrUnknownRef, ref, i, sess.id)
+			}
+		}
+
+		tail, samplesCount, err := encoding.UnmarshalVarUint64(src)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshal samples count for series #%d: %w", i, err)
+		}
+		src = tail
+		if samplesCount > uint64(len(src))/9 {
+			// Every sample occupies at least 9 bytes - 1 byte for timestamp delta and 8 bytes for value.
+			return fmt.Errorf("too short data for %d samples for series #%d; got %d bytes", samplesCount, i, len(src))
+		}
+		samplesLen := len(samples)
+		for j := uint64(0); j < samplesCount; j++ {
+			tail, delta, err := encoding.UnmarshalVarInt64(src)
+			if err != nil {
+				return fmt.Errorf("cannot unmarshal timestamp for sample #%d of series #%d: %w", j, i, err)
+			}
+			src = tail
+			if len(src) < 8 {
+				return fmt.Errorf("cannot unmarshal value for sample #%d of series #%d: too short data; got %d bytes; want at least 8 bytes", j, i, len(src))
+			}
+			timestamp += delta
+			samples = append(samples, prompb.Sample{
+				Timestamp: timestamp,
+				Value:     math.Float64frombits(encoding.UnmarshalUint64(src)),
+			})
+			src = src[8:]
+		}
+		tss = append(tss, prompb.TimeSeries{
+			Labels: labels,
+		})
+		// Samples are re-initialized below, since samples may be re-allocated by the next append() calls.
+		tss[len(tss)-1].Samples = samples[samplesLen:len(samples):len(samples)]
+	}
+	if len(src) > 0 {
+		return fmt.Errorf("unexpected trailing data left after unmarshaling %d series; len(tail)=%d bytes", seriesCount, len(src))
+	}
+	// Re-initialize Samples, since samples may be re-allocated after they were referred by tss.
+	n := 0
+	for i := range tss {
+		ts := &tss[i]
+		samplesLen := len(ts.Samples)
+		ts.Samples = samples[n : n+samplesLen]
+		n += samplesLen
+	}
+	r.Timeseries = tss
+	r.samplesPool = samples
+	return nil
+}
+
+func unmarshalLabels(src []byte) ([]byte, []prompb.Label, error) {
+	tail, labelsCount, err := encoding.UnmarshalVarUint64(src)
+	if err != nil {
+		return tail, nil, fmt.Errorf("cannot unmarshal labels count: %w", err)
+	}
+	src = tail
+	if labelsCount > uint64(len(src))/2 {
+		return src, nil, fmt.Errorf("too short data for %d labels; got %d bytes", labelsCount, len(src))
+	}
+	// Copy labels to a single buffer, since they are held by the session after the request is processed.
+	labels := make([]prompb.Label, labelsCount)
+	nameValues := make([][]byte, 0, 2*labelsCount)
+	bufLen := 0
+	for i := uint64(0); i < 2*labelsCount; i++ {
+		tail, b, err := encoding.UnmarshalBytes(src)
+		if err != nil {
+			return tail, nil, fmt.Errorf("cannot unmarshal label #%d: %w", i/2, err)
+		}
+		src = tail
+		nameValues = append(nameValues, b)
+		bufLen += len(b)
+	}
+	buf := make([]byte, 0, bufLen)
+	for i := range labels {
+		buf = append(buf, nameValues[2*i]...)
+		labels[i].Name = buf[len(buf)-len(nameValues[2*i]):]
+		buf = append(buf, nameValues[2*i+1]...)
+		labels[i].Value = buf[len(buf)-len(nameValues[2*i+1]):]
+	}
+	return src, labels, nil
+}
+
+// session holds label sets interned by a single sender.
+type session struct {
+	id string
+
+	// mu protects the fields below from concurrent access.
+	mu sync.Mutex
+
+	refs           map[uint64][]prompb.Label
+	sizeBytes      uint64
+	lastAccessTime uint64
+
+	// deleted is set when the session is evicted.
+	deleted bool
+}
+
+func (sess *session) setLabels(ref uint64, labels []prompb.Label) {
+	if _, ok := sess.refs[ref]; ok {
+		// The label set may be re-defined by the sender when the previous request with the definition failed.
+		// Leave the existing label set, since the definition cannot change within the session.
+		return
+	}
+	sess.refs[ref] = labels
+	n := uint64(48 + len(labels)*48)
+	for i := range labels {
+		n += uint64(len(labels[i].Name) + len(labels[i].Value))
+	}
+	sess.sizeBytes += n
+}
+
+var (
+	sessionsLock sync.Mutex
+	sessions     = make(map[string]*session)
+
+	sessionsLastCleanupTime uint64
+
+	sessionsSizeBytes uint64
+)
+
+// sessionMaxIdleSeconds is the duration after which inactive sessions are evicted.
+const sessionMaxIdleSeconds = 10 * 60
+
+func getSession(sessionID string) *session {
+	currentTime := fasttime.UnixTimestamp()
+
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	if currentTime-sessionsLastCleanupTime > 60 {
+		sessionsLastCleanupTime = currentTime
+		for id, sess := range sessions {
+			sess.mu.Lock()
+			if currentTime-sess.lastAccessTime > sessionMaxIdleSeconds {
+				deleteSessionLocked(id, sess)
+			}
+			sess.mu.Unlock()
+		}
+	}
+	sess := sessions[sessionID]
+	if sess == nil {
+		sess = &session{
+			id:             sessionID,
+			refs:           make(map[uint64][]prompb.Label),
+			lastAccessTime: currentTime,
+		}
+		sessions[sessionID] = sess
+		sessionsCreated.Inc()
+	}
+	return sess
+}
+
+// evictSessions evicts the least recently used sessions until the total size of sessions fits the limit.
+//
+// currSess is evicted last.
+func evictSessions(currSess *session) {
+	sessionsLock.Lock()
+	defer sessionsLock.Unlock()
+
+	ss := make([]*session, 0, len(sessions))
+	for _, sess := range sessions {
+		if sess != currSess {
+			ss = append(ss, sess)
+		}
+	}
+	lastAccessTimes := make(map[*session]uint64, len(ss))
+	for _, sess := range ss {
+		sess.mu.Lock()
+		lastAccessTimes[sess] = sess.lastAccessTime
+		sess.mu.Unlock()
+	}
+	sort.Slice(ss, func(i, j int) bool {
+		return lastAccessTimes[ss[i]] < lastAccessTimes[ss[j]]
+	})
+	maxSize := getMaxSessionsSize()
+	for _, sess := range ss {
+		if atomic.LoadUint64(&sessionsSizeBytes) <= maxSize {
+			return
+		}
+		sess.mu.Lock()
+		deleteSessionLocked(sess.id, sess)
+		sess.mu.Unlock()
+	}
+	if atomic.LoadUint64(&sessionsSizeBytes) > maxSize {
+		currSess.mu.Lock()
+		deleteSessionLocked(currSess.id, currSess)
+		currSess.mu.Unlock()
+	}
+}
+
+// deleteSessionLocked deletes sess from sessions.
+//
+// Both sessionsLock and sess.mu must be locked by the caller.
+func deleteSessionLocked(sessionID string, sess *session) {
+	if sess.deleted {
+		return
+	}
+	delete(sessions, sessionID)
+	atomic.AddUint64(&sessionsSizeBytes, ^(sess.sizeBytes - 1))
+	sess.refs = nil
+	sess.sizeBytes = 0
+	sess.deleted = true
+	sessionsEvicted.Inc()
+}
+
+func getMaxSessionsSize() uint64 {
+	maxSessionsSizeOnce.Do(func() {
+		maxSessionsSize = uint64(memory.Allowed() / 16)
+	})
+	return maxSessionsSize
+}
+
+var (
+	maxSessionsSize     uint64
+	maxSessionsSizeOnce sync.Once
+)
+
+var (
+	sessionsCreated = metrics.NewCounter(`vm_labels_intern_sessions_created_total`)
+	sessionsEvicted = metrics.NewCounter(`vm_labels_intern_sessions_evicted_total`)
+	unknownRefs     = metrics.NewCounter(`vm_labels_intern_unknown_refs_total`)
+
+	_ = metrics.NewGauge(`vm_labels_intern_sessions`, func() float64 {
+		sessionsLock.Lock()
+		n := len(sessions)
+		sessionsLock.Unlock()
+		return float64(n)
+	})
+	_ = metrics.NewGauge(`vm_labels_intern_sessions_size_bytes`, func() float64 {
+		return float64(atomic.LoadUint64(&sessionsSizeBytes))
+	})
+)
@@ -0,0 +1,143 @@
+package labelsintern
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+)
+
+// Encoder encodes write requests with label sets interned across requests within a single session.
+//
+// Encoder cannot be used from concurrently running goroutines.
+// Every request encoded by Encode must be either committed with Commit after it is accepted by the remote storage,
+// or dropped by calling Encode again.
+type Encoder struct {
+	maxSize int
+
+	sessionID string
+
+	// refs contains label sets known to the remote storage for the current session.
+	refs     map[string]uint64
+	refsSize int
+	nextRef  uint64
+
+	// pendingRefs contains label sets defined in the last encoded request.
+	pendingRefs     map[string]uint64
+	pendingRefsSize int
+
+	keyBuf []byte
+}
+
+// NewEncoder returns new Encoder, which may hold up to maxSize bytes of interned label sets.
+//
+// A new session is started when the size of interned label sets exceeds maxSize.
+func NewEncoder(maxSize int) *Encoder {
+	e := &Encoder{
+		maxSize: maxSize,
+	}
+	e.Reset()
+	return e
+}
+
+// SessionID returns the current session id for e.
+//
+// It must be sent in SessionHeader together with the request encoded by Encode.
+func (e *Encoder) SessionID() string {
+	return e.sessionID
+}
+
+// Reset starts a new session for e, so the next Encode call sends all the label sets in full.
+//
+// Reset must be called when the remote storage responds with ErrUnknownRef.
+func (e *Encoder) Reset() {
+	e.sessionID = newSessionID()
+	e.refs = make(map[string]uint64)
+	e.refsSize = 0
+	e.nextRef = 1
+	e.pendingRefs = make(map[string]uint64)
+	e.pendingRefsSize = 0
+}
+
+// Encode appends tss encoded with interned label sets to dst and returns the result.
+//
+// Label sets, which were defined in the previously committed requests, are sent as references.
+func (e *Encoder) Encode(dst []byte, tss []prompb.TimeSeries) []byte {
+	if e.refsSize > e.maxSize {
+		e.Reset()
+	}
+	for k := range e.pendingRefs {
+		delete(e.pendingRefs, k)
+	}
+	e.pendingRefsSize = 0
+
+	dst = append(dst, formatVersion)
+	dst = encoding.MarshalVarUint64(dst, uint64(len(tss)))
+	prevTimestamp := int64(0)
+	prevRef := uint64(0)
+	for i := range tss {
+		ts := &tss[i]
+		e.keyBuf = marshalLabelsKey(e.keyBuf[:0], ts.Labels)
+		ref, ok := e.refs[string(e.keyBuf)]
+		if !ok {
+			ref, ok = e.pendingRefs[string(e.keyBuf)]
+		}
+		if ok {
+			dst = encoding.MarshalVarInt64(dst, int64(ref-prevRef)<<1)
+		} else {
+			// Never re-use references, since the remote storage may already register them from failed requests.
+			ref = e.nextRef
+			e.nextRef++
+			e.pendingRefs[string(e.keyBuf)] = ref
+			e.pendingRefsSize += len(e.keyBuf)
+			dst = encoding.MarshalVarInt64(dst, int64(ref-prevRef)<<1|1)
+			dst = encoding.MarshalVarUint64(dst, uint64(len(ts.Labels)))
+			for j := range ts.Labels {
+				label := &ts.Labels[j]
+				dst = encoding.MarshalBytes(dst, label.Name)
+				dst = encoding.MarshalBytes(dst, label.Value)
+			}
+		}
+		prevRef = ref
+		dst = encoding.MarshalVarUint64(dst, uint64(len(ts.Samples)))
+		for j := range ts.Samples {
+			s := &ts.Samples[j]
+			dst = encoding.MarshalVarInt64(dst, s.Timestamp-prevTimestamp)
+			dst = encoding.MarshalUint64(dst, math.Float64bits(s.Value))
+			prevTimestamp = s.Timestamp
+		}
+	}
+	return dst
+}
+
+// Commit registers label sets defined in the last encoded request, so they are sent as references in the next requests.
+//
+// Commit must be called only after the remote storage successfully accepts the last encoded request.
+func (e *Encoder) Commit() {
+	for k, ref := range e.pendingRefs {
+		e.refs[k] = ref
+		delete(e.pendingRefs, k)
+	}
+	e.refsSize += e.pendingRefsSize
+	e.pendingRefsSize = 0
+}
+
+func marshalLabelsKey(dst []byte, labels []prompb.Label) []byte {
+	for i := range labels {
+		label := &labels[i]
+		dst = encoding.MarshalBytes(dst, label.Name)
+		dst = encoding.MarshalBytes(dst, label.Value)
+	}
+	return dst
+}
+
+func newSessionID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		logger.Panicf("FATAL: cannot generate labels interning session id: %s", err)
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,31 @@
+// Package labelsintern implements encoding for Prometheus remote_write requests with label sets interned across requests.
+//
+// The sender assigns a numeric reference to every unique label set and sends the label set in full only once per session.
+// The following requests from the same session refer to the label set by its reference.
+// This reduces network bandwidth usage for stable series sets, since label sets usually take the most of the request size.
+//
+// The request body has the following format:
+//
+//	formatVersion
+//	varuint seriesCount
+//	for every series:
+//	  varint (refDelta<<1 | hasLabels)
+//	  if hasLabels:
+//	    varuint labelsCount
+//	    for every label: bytes name, bytes value
+//	  varuint samplesCount
+//	  for every sample: varint timestampDelta, uint64 valueBits
+//
+// refDelta is the difference with the previous series reference in the request,
+// since series are usually sent in the same order. timestampDelta is the difference with the previous
+// sample timestamp in the request, since samples for distinct series in the request usually have close timestamps.
+package labelsintern
+
+// SupportHeader is the HTTP response header, which is set by remote storage supporting label sets interning.
+const SupportHeader = "X-VictoriaMetrics-Labels-Intern"
+
+// SessionHeader is the HTTP request header with the session id for request body with interned label sets.
+const SessionHeader = "X-VictoriaMetrics-Labels-Intern-Session"
+
+// formatVersion is the version of the request body format.
+const formatVersion = 1
@@ -0,0 +1,181 @@
+package labelsintern
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestEncodeUnmarshal(t *testing.T) {
+	e := NewEncoder(1024 * 1024)
+	var r Request
+
+	// The first request must contain label sets in full.
+	tss := newTestTimeseries(10, 1000)
+	data := e.Encode(nil, tss)
+	if err := r.Unmarshal(e.SessionID(), data); err != nil {
+		t.Fatalf("cannot unmarshal the first request: %s", err)
+	}
+	checkTimeseries(t, r.Timeseries, tss)
+	fullSize := len(data)
+
+	// The request must contain label sets in full until it is committed.
+	tss = newTestTimeseries(10, 2000)
+	data = e.Encode(data[:0], tss)
+	if len(data) != fullSize {
+		t.Fatalf("unexpected size for the request before commit; got %d bytes; want %d bytes", len(data), fullSize)
+	}
+	if err := r.Unmarshal(e.SessionID(), data); err != nil {
+		t.Fatalf("cannot unmarshal the second request: %s", err)
+	}
+	checkTimeseries(t, r.Timeseries, tss)
+	e.Commit()
+
+	// Committed label sets must be sent as references.
+	tss = newTestTimeseries(12, 3000)
+	data = e.Encode(data[:0], tss)
+	if err := r.Unmarshal(e.SessionID(), data); err != nil {
+		t.Fatalf("cannot unmarshal the request with references: %s", err)
+	}
+	checkTimeseries(t, r.Timeseries, tss)
+	if len(data) >= fullSize {
+		t.Fatalf("the request with references must be smaller than %d bytes; got %d bytes", fullSize, len(data))
+	}
+	e.Commit()
+
+	// Unknown session must result in ErrUnknownRef.
+	data = e.Encode(data[:0], tss)
+	err := r.Unmarshal("unknown-session", data)
+	if !errors.Is(err, ErrUnknownRef) {
+		t.Fatalf("expecting ErrUnknownRef for unknown session; got %v", err)
+	}
+
+	// Reset must start a new session with label sets sent in full.
+	e.Reset()
+	data = e.Encode(data[:0], tss)
+	if err := r.Unmarshal(e.SessionID(), data); err != nil {
+		t.Fatalf("cannot unmarshal the request after reset: %s", err)
+	}
+	checkTimeseries(t, r.Timeseries, tss)
+}
+
+func TestEncodeMaxSize(t *testing.T) {
+	e := NewEncoder(100)
+	tss := newTestTimeseries(10, 1000)
+	e.Encode(nil, tss)
+	e.Commit()
+	sessionID := e.SessionID()
+
+	// The encoder must start a new session after exceeding the max size.
+	var r Request
+	data := e.Encode(nil, tss)
+	if e.SessionID() == sessionID {
+		t.Fatalf("expecting new session after exceeding max size")
+	}
+	if err := r.Unmarshal(e.SessionID(), data); err != nil {
+		t.Fatalf("cannot unmarshal request: %s", err)
+	}
+	checkTimeseries(t, r.Timeseries, tss)
+}
+
+func TestRequestUnmarshalFailure(t *testing.T) {
+	f := func(data []byte) {
+		t.Helper()
+		var r Request
+		if err := r.Unmarshal("TestRequestUnmarshalFailure", data); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	f(nil)
+	f([]byte{2})
+
+	e := NewEncoder(1024)
+	data := e.Encode(nil, newTestTimeseries(3, 1000))
+	for i := 1; i < len(data); i++ {
+		f(data[:i])
+	}
+	f(append(data, 1))
+}
+
+func TestEncodeBandwidthReduction(t *testing.T) {
+	e := NewEncoder(64 * 1024 * 1024)
+	var plainSize, internedSize int
+	for i := 0; i < 10; i++ {
+		tss := newTestTimeseries(1000, int64(1670000000000+i*10000))
+
+		bb := prompbmarshal.MarshalWriteRequest(nil, &prompbmarshal.WriteRequest{
+			Timeseries: toPrompbmarshal(tss),
+		})
+		plainSize += len(zstd.CompressLevel(nil, bb, 0))
+
+		data := e.Encode(nil, tss)
+		internedSize += len(zstd.CompressLevel(nil, data, 0))
+		e.Commit()
+	}
+	if internedSize > plainSize*6/10 {
+		t.Fatalf("interned requests must be at least 40%% smaller than plain requests; got %d bytes vs %d bytes", internedSize, plainSize)
+	}
+}
+
+func newTestTimeseries(seriesCount int, timestamp int64) []prompb.TimeSeries {
+	var tss []prompb.TimeSeries
+	for i := 0; i < seriesCount; i++ {
+		// Generate Kubernetes-like labels with pod names containing random-looking suffixes.
+		podHash := xxhash.Sum64String(fmt.Sprintf("pod_%d", i/10))
+		tss = append(tss, prompb.TimeSeries{
+			Labels: []prompb.Label{
+				{Name: []byte("__name__"), Value: []byte(fmt.Sprintf("container_metric_%d", i%10))},
+				{Name: []byte("container"), Value: []byte(fmt.Sprintf("app-%d", i/100))},
+				{Name: []byte("instance"), Value: []byte(fmt.Sprintf("10.%d.%d.%d:8080", podHash%256, (podHash>>8)%256, (podHash>>16)%256))},
+				{Name: []byte("job"), Value: []byte("kubernetes-pods")},
+				{Name: []byte("namespace"), Value: []byte(fmt.Sprintf("namespace-%d", i/300))},
+				{Name: []byte("node"), Value: []byte(fmt.Sprintf("node-%x", (podHash>>24)%16))},
+				{Name: []byte("pod"), Value: []byte(fmt.Sprintf("app-%d-%x-%x", i/100, podHash>>40, podHash&0xfffff))},
+				{Name: []byte("uid"), Value: []byte(fmt.Sprintf("%016x-%04x", podHash, i%10))},
+			},
+			Samples: []prompb.Sample{
+				{Timestamp: timestamp + int64(i%3), Value: float64(i % 50)},
+			},
+		})
+	}
+	return tss
+}
+
+func checkTimeseries(t *testing.T, tss, tssExpected []prompb.TimeSeries) {
+	t.Helper()
+	if !reflect.DeepEqual(tss, tssExpected) {
+		t.Fatalf("unexpected time series\ngot\n%v\nwant\n%v", tss, tssExpected)
+	}
+}
+
+func toPrompbmarshal(tss []prompb.TimeSeries) []prompbmarshal.TimeSeries {
+	var dst []prompbmarshal.TimeSeries
+	for _, ts := range tss {
+		var labels []prompbmarshal.Label
+		for _, label := range ts.Labels {
+			labels = append(labels, prompbmarshal.Label{
+				Name:  string(label.Name),
+				Value: string(label.Value),
+			})
+		}
+		var samples []prompbmarshal.Sample
+		for _, s := range ts.Samples {
+			samples = append(samples, prompbmarshal.Sample{
+				Timestamp: s.Timestamp,
+				Value:     s.Value,
+			})
+		}
+		dst = append(dst, prompbmarshal.TimeSeries{
+			Labels:  labels,
+			Samples: samples,
+		})
+	}
+	return dst
+}
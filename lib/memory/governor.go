@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"flag"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	adaptiveCaches = flag.Bool("memory.adaptiveCaches", false, "Whether to shrink caches when the process memory usage approaches the memory limit "+
+		"and to grow them back when the memory pressure goes away. This may prevent OOM kills during query spikes. "+
+		"See also -memory.adaptiveCachesMinPercent and -memory.pressureThresholdPercent. See https://docs.victoriametrics.com/#adaptive-cache-sizes")
+	adaptiveCachesMinPercent = flag.Float64("memory.adaptiveCachesMinPercent", 25, "The minimum percent of the configured cache sizes caches may be shrunk to under memory pressure. "+
+		"See -memory.adaptiveCaches")
+	pressureThresholdPercent = flag.Float64("memory.pressureThresholdPercent", 80, "The percent of the memory limit for the process resident memory, "+
+		"which is considered as memory pressure. See -memory.adaptiveCaches")
+)
+
+// governorInterval is the interval between memory pressure checks.
+const governorInterval = time.Second
+
+const (
+	// shrinkFactor is the multiplier applied to cache sizes on every check under memory pressure.
+	shrinkFactor = 0.75
+
+	// growStep is added to the cache size scale on every check without memory pressure,
+	// so caches are grown back slower than they are shrunk.
+	growStep = 0.02
+
+	// growHysteresis is the gap below the memory pressure threshold, which must be reached before growing caches back.
+	growHysteresis = 0.1
+)
+
+var (
+	// cacheSizeScale holds math.Float64bits of the current scale for cache sizes in the range [minScale ... 1].
+	cacheSizeScale uint64 = math.Float64bits(1)
+
+	// underPressure is set to 1 while the process resident memory exceeds -memory.pressureThresholdPercent.
+	underPressure uint32
+
+	pressureEvents = metrics.NewCounter(`vm_memory_pressure_events_total`)
+)
+
+var _ = metrics.NewGauge(`vm_memory_cache_size_scale`, func() float64 {
+	return getCacheSizeScale()
+})
+
+var _ = metrics.NewGauge(`vm_memory_under_pressure`, func() float64 {
+	if UnderPressure() {
+		return 1
+	}
+	return 0
+})
+
+// ScaleCacheSize returns n scaled according to the current memory pressure.
+//
+// n is returned as is unless -memory.adaptiveCaches is set.
+// Caches must call ScaleCacheSize on every max size check, so they are shrunk under memory pressure.
+func ScaleCacheSize(n int) int {
+	return int(float64(n) * getCacheSizeScale())
+}
+
+// UnderPressure returns true if the process resident memory exceeds -memory.pressureThresholdPercent of the memory limit.
+//
+// Callers may skip storing new entries in caches, which cannot be shrunk, while UnderPressure returns true.
+// UnderPressure always returns false unless -memory.adaptiveCaches is set.
+func UnderPressure() bool {
+	return atomic.LoadUint32(&underPressure) != 0
+}
+
+func getCacheSizeScale() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&cacheSizeScale))
+}
+
+func startGovernor() {
+	if !*adaptiveCaches {
+		return
+	}
+	if *adaptiveCachesMinPercent <= 0 || *adaptiveCachesMinPercent > 100 {
+		logger.Fatalf("FATAL: -memory.adaptiveCachesMinPercent must be in the range (0...100]; got %g", *adaptiveCachesMinPercent)
+	}
+	if *pressureThresholdPercent <= 0 || *pressureThresholdPercent > 100 {
+		logger.Fatalf("FATAL: -memory.pressureThresholdPercent must be in the range (0...100]; got %g", *pressureThresholdPercent)
+	}
+	if getProcessRSS() <= 0 {
+		logger.Warnf("disabling -memory.adaptiveCaches, since the process resident memory cannot be obtained on this platform")
+		return
+	}
+	logger.Infof("adaptive cache sizes are enabled; caches are shrunk down to %g%% of their sizes when the process resident memory exceeds %g%% of %d bytes",
+		*adaptiveCachesMinPercent, *pressureThresholdPercent, memoryLimit)
+	go governor()
+}
+
+func governor() {
+	minScale := *adaptiveCachesMinPercent / 100
+	threshold := *pressureThresholdPercent / 100
+	t := time.NewTicker(governorInterval)
+	defer t.Stop()
+	for range t.C {
+		usage := float64(getProcessRSS()) / float64(memoryLimit)
+		scale := getCacheSizeScale()
+		scale, pressure := nextCacheSizeScale(scale, usage, minScale, threshold)
+		if pressure && !UnderPressure() {
+			pressureEvents.Inc()
+			logger.Warnf("the process resident memory exceeds %g%% of %d bytes; shrinking caches", *pressureThresholdPercent, memoryLimit)
+		}
+		setUnderPressure(pressure)
+		atomic.StoreUint64(&cacheSizeScale, math.Float64bits(scale))
+	}
+}
+
+// nextCacheSizeScale returns the next cache size scale and memory pressure state for the given memory usage.
+//
+// usage and threshold are fractions of the memory limit.
+func nextCacheSizeScale(scale, usage, minScale, threshold float64) (float64, bool) {
+	if usage > threshold {
+		scale *= shrinkFactor
+		if scale < minScale {
+			scale = minScale
+		}
+		return scale, true
+	}
+	if usage < threshold-growHysteresis {
+		scale += growStep
+		if scale > 1 {
+			scale = 1
+		}
+	}
+	return scale, false
+}
+
+func setUnderPressure(pressure bool) {
+	n := uint32(0)
+	if pressure {
+		n = 1
+	}
+	atomic.StoreUint32(&underPressure, n)
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+var pageSize = os.Getpagesize()
+
+// getProcessRSS returns the resident memory size of the current process in bytes.
+//
+// It returns 0 if the resident memory size cannot be obtained.
+func getProcessRSS() int {
+	data, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		return 0
+	}
+	// See https://man7.org/linux/man-pages/man5/proc.5.html
+	fields := bytes.Fields(data)
+	if len(fields) < 2 {
+		return 0
+	}
+	pages, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return 0
+	}
+	return pages * pageSize
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package memory
+
+// getProcessRSS returns 0, since obtaining the resident memory size isn't supported on this platform.
+func getProcessRSS() int {
+	return 0
+}
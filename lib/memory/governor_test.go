@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"testing"
+)
+
+func TestNextCacheSizeScale(t *testing.T) {
+	f := func(scale, usage float64, scaleExpected float64, pressureExpected bool) {
+		t.Helper()
+		scaleNew, pressure := nextCacheSizeScale(scale, usage, 0.25, 0.8)
+		if scaleNew != scaleExpected {
+			t.Fatalf("unexpected scale; got %g; want %g", scaleNew, scaleExpected)
+		}
+		if pressure != pressureExpected {
+			t.Fatalf("unexpected pressure; got %v; want %v", pressure, pressureExpected)
+		}
+	}
+	// shrink under pressure
+	f(1, 0.9, 0.75, true)
+	f(0.3, 0.9, 0.25, true)
+	f(0.25, 1.5, 0.25, true)
+
+	// keep the scale in hysteresis zone
+	f(0.5, 0.8, 0.5, false)
+	f(0.5, 0.75, 0.5, false)
+
+	// grow without pressure
+	f(0.5, 0.5, 0.52, false)
+	f(0.99, 0.1, 1, false)
+	f(1, 0.1, 1, false)
+}
+
+func TestGetProcessRSS(t *testing.T) {
+	if n := getProcessRSS(); n < 0 {
+		t.Fatalf("unexpected negative resident memory size: %d", n)
+	}
+}
@@ -18,6 +18,10 @@ func sysTotalMemory() int {
 	if uint64(maxInt)/uint64(si.Totalram) > uint64(si.Unit) {
 		totalMem = int(uint64(si.Totalram) * uint64(si.Unit))
 	}
+	if mem := cgroup.GetNUMAMemoryLimit(); mem > 0 && int64(int(mem)) == mem && int(mem) < totalMem {
+		// The app is allowed to allocate memory only from a subset of NUMA nodes.
+		totalMem = int(mem)
+	}
 	mem := cgroup.GetMemoryLimit()
 	if mem <= 0 || int64(int(mem)) != mem || int(mem) > totalMem {
 		// Try reading hierarchical memory limit.
@@ -35,6 +35,12 @@ type blockStreamWriter struct {
 
 	// whether the first item for mr has been caught.
 	mrFirstItemCaught bool
+
+	// mp is the destination inmemoryPart if bsw is initialized via InitFromInmemoryPart.
+	mp *inmemoryPart
+
+	// bkc collects keys for the bloom filter of the destination part if bloom key callback is set via setBloomKeyCallback.
+	bkc bloomKeysCollector
 }
 
 func (bsw *blockStreamWriter) reset() {
@@ -61,12 +67,16 @@ func (bsw *blockStreamWriter) reset() {
 	bsw.indexBlockOffset = 0
 
 	bsw.mrFirstItemCaught = false
+
+	bsw.mp = nil
+	bsw.bkc.reset()
 }
 
 func (bsw *blockStreamWriter) InitFromInmemoryPart(mp *inmemoryPart, compressLevel int) {
 	bsw.reset()
 
 	bsw.compressLevel = compressLevel
+	bsw.mp = mp
 	bsw.metaindexWriter = &mp.metaindexData
 	bsw.indexWriter = &mp.indexData
 	bsw.itemsWriter = &mp.itemsData
@@ -134,6 +144,13 @@ func (bsw *blockStreamWriter) InitFromFilePart(path string, nocache bool, compre
 	return nil
 }
 
+// setBloomKeyCallback enables building bloom filter for keys returned by bloomKey for the destination part.
+//
+// It must be called after Init* and before the first WriteBlock call.
+func (bsw *blockStreamWriter) setBloomKeyCallback(bloomKey BloomKeyCallback) {
+	bsw.bkc.bloomKey = bloomKey
+}
+
 // MustClose closes the bsw.
 //
 // It closes *Writer files passed to Init*.
@@ -151,6 +168,15 @@ func (bsw *blockStreamWriter) MustClose() {
 	bsw.itemsWriter.MustClose()
 	bsw.lensWriter.MustClose()
 
+	// Store bloom filter for the destination part.
+	if bf := bsw.bkc.newBloomFilter(); bf != nil {
+		if bsw.mp != nil {
+			bsw.mp.bf = bf
+		} else {
+			mustWriteBloomFilter(bsw.path, bf)
+		}
+	}
+
 	// Sync bsw.path contents to make sure it doesn't disappear
 	// after system crash or power loss.
 	if bsw.path != "" {
@@ -164,6 +190,7 @@ func (bsw *blockStreamWriter) MustClose() {
 //
 // ib must be sorted.
 func (bsw *blockStreamWriter) WriteBlock(ib *inmemoryBlock) {
+	bsw.bkc.addItems(ib)
 	bsw.bh.firstItem, bsw.bh.commonPrefix, bsw.bh.itemsCount, bsw.bh.marshalType = ib.MarshalSortedData(&bsw.sb, bsw.bh.firstItem[:0], bsw.bh.commonPrefix[:0], bsw.compressLevel)
 
 	if !bsw.mrFirstItemCaught {
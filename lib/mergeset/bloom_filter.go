@@ -0,0 +1,196 @@
+package mergeset
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/cespare/xxhash/v2"
+)
+
+// BloomKeyCallback must return the key for the given item, which must be registered in per-part bloom filter.
+//
+// It must return nil if the item mustn't be registered in bloom filter.
+// The returned key must be a prefix of the item, so items with the same key are adjacent in the sorted part.
+// When called for the prefix passed to TableSearch.SeekPrefix, it must return either nil
+// or the key shared by all the items with the given prefix.
+//
+// Parts are skipped during TableSearch.SeekPrefix if their bloom filter doesn't contain the key for the prefix.
+type BloomKeyCallback func(item []byte) []byte
+
+// bloomFilterBitsPerKey is the number of bits in bloom filter per each key.
+//
+// This gives false positive rate of around 0.2% with bloomFilterHashesCount hashes.
+const bloomFilterBitsPerKey = 16
+
+// bloomFilterHashesCount is the number of hashes per each key in bloom filter.
+const bloomFilterHashesCount = 4
+
+// bloomFilter is per-part bloom filter for keys returned by BloomKeyCallback.
+type bloomFilter struct {
+	bits []uint64
+}
+
+// newBloomFilter returns bloom filter for the given hashes of keys.
+func newBloomFilter(hashes []uint64) *bloomFilter {
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+	keysCount := 0
+	for i, h := range hashes {
+		if i == 0 || h != hashes[i-1] {
+			keysCount++
+		}
+	}
+	bf := &bloomFilter{
+		bits: make([]uint64, (keysCount*bloomFilterBitsPerKey+63)/64),
+	}
+	if len(bf.bits) == 0 {
+		return bf
+	}
+	maxBits := uint64(len(bf.bits)) * 64
+	for _, h := range hashes {
+		h1, h2 := splitBloomHash(h)
+		for i := uint64(0); i < bloomFilterHashesCount; i++ {
+			idx := (h1 + i*h2) % maxBits
+			bf.bits[idx/64] |= 1 << (idx % 64)
+		}
+	}
+	return bf
+}
+
+// mayContainHash returns false if bf definitely doesn't contain a key with the hash h.
+func (bf *bloomFilter) mayContainHash(h uint64) bool {
+	if len(bf.bits) == 0 {
+		return false
+	}
+	maxBits := uint64(len(bf.bits)) * 64
+	h1, h2 := splitBloomHash(h)
+	for i := uint64(0); i < bloomFilterHashesCount; i++ {
+		idx := (h1 + i*h2) % maxBits
+		if bf.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func splitBloomHash(h uint64) (uint64, uint64) {
+	// Use double hashing for obtaining bloomFilterHashesCount hashes from h.
+	// See https://www.eecs.harvard.edu/~michaelm/postscripts/rsa2008.pdf
+	return h, (h >> 32) | (h << 32) | 1
+}
+
+func getBloomKeyHash(key []byte) uint64 {
+	return xxhash.Sum64(key)
+}
+
+func (bf *bloomFilter) sizeBytes() uint64 {
+	return uint64(len(bf.bits)) * 8
+}
+
+func (bf *bloomFilter) marshal(dst []byte) []byte {
+	for _, w := range bf.bits {
+		dst = encoding.MarshalUint64(dst, w)
+	}
+	return dst
+}
+
+func unmarshalBloomFilter(src []byte) (*bloomFilter, error) {
+	if len(src)%8 != 0 {
+		return nil, fmt.Errorf("unexpected bloom filter size; got %d bytes; it must be multiple of 8", len(src))
+	}
+	bf := &bloomFilter{
+		bits: make([]uint64, len(src)/8),
+	}
+	for i := range bf.bits {
+		bf.bits[i] = encoding.UnmarshalUint64(src[i*8:])
+	}
+	return bf, nil
+}
+
+const bloomFilterFilename = "bloom.bin"
+
+// mustWriteBloomFilter writes bf to the part at the given path.
+func mustWriteBloomFilter(path string, bf *bloomFilter) {
+	bloomPath := path + "/" + bloomFilterFilename
+	if err := fs.WriteFileAndSync(bloomPath, bf.marshal(nil)); err != nil {
+		logger.Panicf("FATAL: cannot store bloom filter: %s", err)
+	}
+}
+
+// readBloomFilter reads bloom filter from the part at the given path.
+//
+// nil is returned if the part has no bloom filter. This is the case for parts created by older releases
+// or created without BloomKeyCallback.
+func readBloomFilter(path string) (*bloomFilter, uint64, error) {
+	bloomPath := path + "/" + bloomFilterFilename
+	data, err := os.ReadFile(bloomPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("cannot read %q: %w", bloomPath, err)
+	}
+	bf, err := unmarshalBloomFilter(data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot unmarshal %q: %w", bloomPath, err)
+	}
+	return bf, uint64(len(data)), nil
+}
+
+// maxBloomFilterKeys is the maximum number of keys per part bloom filter.
+//
+// Bloom filter isn't built for parts with bigger number of keys in order to limit memory usage during merges.
+// Such parts are never skipped during TableSearch.SeekPrefix.
+const maxBloomFilterKeys = 8 * 1024 * 1024
+
+// bloomKeysCollector collects hashes of keys returned by BloomKeyCallback for the sorted items.
+type bloomKeysCollector struct {
+	bloomKey BloomKeyCallback
+
+	prevKey []byte
+	hashes  []uint64
+
+	// tooManyKeys is set if the number of keys exceeds maxBloomFilterKeys.
+	tooManyKeys bool
+}
+
+func (bkc *bloomKeysCollector) reset() {
+	bkc.bloomKey = nil
+	bkc.prevKey = bkc.prevKey[:0]
+	bkc.hashes = bkc.hashes[:0]
+	bkc.tooManyKeys = false
+}
+
+// addItems registers keys for the sorted items from ib.
+func (bkc *bloomKeysCollector) addItems(ib *inmemoryBlock) {
+	if bkc.bloomKey == nil || bkc.tooManyKeys {
+		return
+	}
+	data := ib.data
+	for _, it := range ib.items {
+		key := bkc.bloomKey(it.Bytes(data))
+		if key == nil || string(key) == string(bkc.prevKey) {
+			continue
+		}
+		if len(bkc.hashes) >= maxBloomFilterKeys {
+			bkc.tooManyKeys = true
+			bkc.hashes = bkc.hashes[:0]
+			return
+		}
+		bkc.prevKey = append(bkc.prevKey[:0], key...)
+		bkc.hashes = append(bkc.hashes, getBloomKeyHash(key))
+	}
+}
+
+// newBloomFilter returns bloom filter for the collected keys.
+//
+// nil is returned if bkc has no BloomKeyCallback or if the number of keys exceeds maxBloomFilterKeys.
+func (bkc *bloomKeysCollector) newBloomFilter() *bloomFilter {
+	if bkc.bloomKey == nil || bkc.tooManyKeys {
+		return nil
+	}
+	return newBloomFilter(bkc.hashes)
+}
@@ -0,0 +1,170 @@
+package mergeset
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestBloomFilter(t *testing.T) {
+	f := func(keysCount int) {
+		t.Helper()
+		hashes := make([]uint64, 0, keysCount)
+		for i := 0; i < keysCount; i++ {
+			hashes = append(hashes, getBloomKeyHash([]byte(fmt.Sprintf("key_%d", i))))
+		}
+		bf := newBloomFilter(hashes)
+
+		// Verify marshal/unmarshal
+		data := bf.marshal(nil)
+		if uint64(len(data)) != bf.sizeBytes() {
+			t.Fatalf("unexpected marshaled size; got %d; want %d", len(data), bf.sizeBytes())
+		}
+		bf, err := unmarshalBloomFilter(data)
+		if err != nil {
+			t.Fatalf("cannot unmarshal bloom filter: %s", err)
+		}
+
+		// The added keys must be always found
+		for i := 0; i < keysCount; i++ {
+			h := getBloomKeyHash([]byte(fmt.Sprintf("key_%d", i)))
+			if !bf.mayContainHash(h) {
+				t.Fatalf("cannot find key_%d in bloom filter", i)
+			}
+		}
+
+		// Missing keys must be rarely found
+		falsePositives := 0
+		for i := 0; i < keysCount; i++ {
+			h := getBloomKeyHash([]byte(fmt.Sprintf("missing_key_%d", i)))
+			if bf.mayContainHash(h) {
+				falsePositives++
+			}
+		}
+		if p := float64(falsePositives) / float64(keysCount+1); p > 0.01 {
+			t.Fatalf("too high false positive rate for %d keys: %.4f", keysCount, p)
+		}
+	}
+	f(0)
+	f(1)
+	f(10)
+	f(1000)
+	f(100000)
+}
+
+func TestUnmarshalBloomFilterFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := unmarshalBloomFilter([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	f("a")
+	f("foobarbazs")
+}
+
+func TestTableSearchSeekPrefixBloomFilter(t *testing.T) {
+	const path = "TestTableSearchSeekPrefixBloomFilter"
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+	}()
+
+	// The key is the item prefix ending with ':'
+	bloomKey := func(item []byte) []byte {
+		n := bytes.IndexByte(item, ':')
+		if n < 0 {
+			return nil
+		}
+		return item[:n+1]
+	}
+	const partsCount = 5
+	const itemsPerPart = 1000
+
+	f := func(tb *Table) {
+		t.Helper()
+
+		var ts TableSearch
+		ts.Init(tb)
+		defer ts.MustClose()
+
+		// Search for existing keys
+		for i := 0; i < partsCount; i++ {
+			prefix := []byte(fmt.Sprintf("part_%d:", i))
+			ts.SeekPrefix(prefix)
+			n := 0
+			for ts.NextItem() && bytes.HasPrefix(ts.Item, prefix) {
+				n++
+			}
+			if err := ts.Error(); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if n != itemsPerPart {
+				t.Fatalf("unexpected number of items found for prefix %q; got %d; want %d", prefix, n, itemsPerPart)
+			}
+		}
+
+		// Search for missing key must skip parts
+		var m TableMetrics
+		tb.UpdateMetrics(&m)
+		checksPrev := m.BloomFilterChecks
+		skippedPrev := m.BloomFilterSkippedParts
+		prefix := []byte("part_missing:")
+		ts.SeekPrefix(prefix)
+		for ts.NextItem() {
+			if bytes.HasPrefix(ts.Item, prefix) {
+				t.Fatalf("unexpected item found: %q", ts.Item)
+			}
+		}
+		if err := ts.Error(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		m = TableMetrics{}
+		tb.UpdateMetrics(&m)
+		checks := m.BloomFilterChecks - checksPrev
+		skipped := m.BloomFilterSkippedParts - skippedPrev
+		if checks == 0 {
+			t.Fatalf("expecting non-zero bloom filter checks")
+		}
+		if skipped != checks {
+			t.Fatalf("unexpected number of skipped parts; got %d; want %d", skipped, checks)
+		}
+
+		// Prefix without bloom key mustn't skip parts
+		ts.SeekPrefix([]byte("part_"))
+		n := 0
+		for ts.NextItem() {
+			n++
+		}
+		if n != partsCount*itemsPerPart {
+			t.Fatalf("unexpected number of items found; got %d; want %d", n, partsCount*itemsPerPart)
+		}
+	}
+
+	var isReadOnly uint32
+	tb, err := OpenTable(path, nil, nil, bloomKey, &isReadOnly)
+	if err != nil {
+		t.Fatalf("cannot open table: %s", err)
+	}
+	for i := 0; i < partsCount; i++ {
+		var items [][]byte
+		for j := 0; j < itemsPerPart; j++ {
+			items = append(items, []byte(fmt.Sprintf("part_%d:%d", i, j)))
+		}
+		tb.AddItems(items)
+		tb.DebugFlush()
+	}
+	f(tb)
+	tb.MustClose()
+
+	// Re-open the table and verify bloom filters are loaded from disk.
+	tb, err = OpenTable(path, nil, nil, bloomKey, &isReadOnly)
+	if err != nil {
+		t.Fatalf("cannot open table: %s", err)
+	}
+	f(tb)
+	tb.MustClose()
+}
@@ -19,6 +19,9 @@ type inmemoryPart struct {
 	indexData     bytesutil.ByteBuffer
 	itemsData     bytesutil.ByteBuffer
 	lensData      bytesutil.ByteBuffer
+
+	// bf is an optional bloom filter for keys returned by BloomKeyCallback.
+	bf *bloomFilter
 }
 
 func (mp *inmemoryPart) Reset() {
@@ -30,6 +33,7 @@ func (mp *inmemoryPart) Reset() {
 	mp.indexData.Reset()
 	mp.itemsData.Reset()
 	mp.lensData.Reset()
+	mp.bf = nil
 }
 
 // StoreToDisk stores mp to the given path on disk.
@@ -53,6 +57,9 @@ func (mp *inmemoryPart) StoreToDisk(path string) error {
 	if err := fs.WriteFileAndSync(lensPath, mp.lensData.B); err != nil {
 		return fmt.Errorf("cannot store lens: %w", err)
 	}
+	if mp.bf != nil {
+		mustWriteBloomFilter(path, mp.bf)
+	}
 	if err := mp.ph.WriteMetadata(path); err != nil {
 		return fmt.Errorf("cannot store metadata: %w", err)
 	}
@@ -105,13 +112,21 @@ func (mp *inmemoryPart) Init(ib *inmemoryBlock) {
 	inmemoryPartBytePool.Put(bb)
 }
 
+// initBloomFilter initializes bloom filter for mp from the sorted items in ib, which has been passed to Init.
+func (mp *inmemoryPart) initBloomFilter(ib *inmemoryBlock, bloomKey BloomKeyCallback) {
+	var bkc bloomKeysCollector
+	bkc.bloomKey = bloomKey
+	bkc.addItems(ib)
+	mp.bf = bkc.newBloomFilter()
+}
+
 var inmemoryPartBytePool bytesutil.ByteBufferPool
 
 // It is safe calling NewPart multiple times.
 // It is unsafe re-using mp while the returned part is in use.
 func (mp *inmemoryPart) NewPart() *part {
 	size := mp.size()
-	p, err := newPart(&mp.ph, "", size, mp.metaindexData.NewReader(), &mp.indexData, &mp.itemsData, &mp.lensData)
+	p, err := newPart(&mp.ph, "", size, mp.metaindexData.NewReader(), &mp.indexData, &mp.itemsData, &mp.lensData, mp.bf)
 	if err != nil {
 		logger.Panicf("BUG: cannot create a part from inmemoryPart: %s", err)
 	}
@@ -119,5 +134,9 @@ func (mp *inmemoryPart) NewPart() *part {
 }
 
 func (mp *inmemoryPart) size() uint64 {
-	return uint64(cap(mp.metaindexData.B) + cap(mp.indexData.B) + cap(mp.itemsData.B) + cap(mp.lensData.B))
+	n := uint64(cap(mp.metaindexData.B) + cap(mp.indexData.B) + cap(mp.itemsData.B) + cap(mp.lensData.B))
+	if mp.bf != nil {
+		n += mp.bf.sizeBytes()
+	}
+	return n
 }
@@ -26,7 +26,7 @@ func getMaxIndexBlocksCacheSize() int {
 			maxIndexBlockCacheSize = int(0.10 * float64(memory.Allowed()))
 		}
 	})
-	return maxIndexBlockCacheSize
+	return memory.ScaleCacheSize(maxIndexBlockCacheSize)
 }
 
 var (
@@ -45,7 +45,7 @@ func getMaxInmemoryBlocksCacheSize() int {
 			maxInmemoryBlockCacheSize = int(0.25 * float64(memory.Allowed()))
 		}
 	})
-	return maxInmemoryBlockCacheSize
+	return memory.ScaleCacheSize(maxInmemoryBlockCacheSize)
 }
 
 var (
@@ -65,6 +65,11 @@ type part struct {
 	indexFile fs.MustReadAtCloser
 	itemsFile fs.MustReadAtCloser
 	lensFile  fs.MustReadAtCloser
+
+	// bf is an optional bloom filter for keys returned by BloomKeyCallback.
+	//
+	// It is nil if the part has been created without BloomKeyCallback.
+	bf *bloomFilter
 }
 
 func openFilePart(path string) (*part, error) {
@@ -94,11 +99,20 @@ func openFilePart(path string) (*part, error) {
 	lensFile := fs.MustOpenReaderAt(lensPath)
 	lensSize := fs.MustFileSize(lensPath)
 
-	size := metaindexSize + indexSize + itemsSize + lensSize
-	return newPart(&ph, path, size, metaindexFile, indexFile, itemsFile, lensFile)
+	bf, bloomSize, err := readBloomFilter(path)
+	if err != nil {
+		metaindexFile.MustClose()
+		indexFile.MustClose()
+		itemsFile.MustClose()
+		lensFile.MustClose()
+		return nil, err
+	}
+
+	size := metaindexSize + indexSize + itemsSize + lensSize + bloomSize
+	return newPart(&ph, path, size, metaindexFile, indexFile, itemsFile, lensFile, bf)
 }
 
-func newPart(ph *partHeader, path string, size uint64, metaindexReader filestream.ReadCloser, indexFile, itemsFile, lensFile fs.MustReadAtCloser) (*part, error) {
+func newPart(ph *partHeader, path string, size uint64, metaindexReader filestream.ReadCloser, indexFile, itemsFile, lensFile fs.MustReadAtCloser, bf *bloomFilter) (*part, error) {
 	var errors []error
 	mrs, err := unmarshalMetaindexRows(nil, metaindexReader)
 	if err != nil {
@@ -114,6 +128,7 @@ func newPart(ph *partHeader, path string, size uint64, metaindexReader filestrea
 	p.indexFile = indexFile
 	p.itemsFile = itemsFile
 	p.lensFile = lensFile
+	p.bf = bf
 
 	p.ph.CopyFrom(ph)
 	if len(errors) > 0 {
@@ -112,6 +112,9 @@ type Table struct {
 	itemsAdded          uint64
 	itemsAddedSizeBytes uint64
 
+	bloomFilterChecks       uint64
+	bloomFilterSkippedParts uint64
+
 	mergeIdx uint64
 
 	path string
@@ -121,6 +124,7 @@ type Table struct {
 	needFlushCallbackCall uint32
 
 	prepareBlock PrepareBlockCallback
+	bloomKey     BloomKeyCallback
 	isReadOnly   *uint32
 
 	// rawItems contains recently added items that haven't been converted to parts yet.
@@ -313,8 +317,10 @@ func (pw *partWrapper) decRef() {
 // Optional prepareBlock is called during merge before flushing the prepared block
 // to persistent storage.
 //
+// Optional bloomKey is used for building per-part bloom filters, which allow skipping parts during TableSearch.SeekPrefix.
+//
 // The table is created if it doesn't exist yet.
-func OpenTable(path string, flushCallback func(), prepareBlock PrepareBlockCallback, isReadOnly *uint32) (*Table, error) {
+func OpenTable(path string, flushCallback func(), prepareBlock PrepareBlockCallback, bloomKey BloomKeyCallback, isReadOnly *uint32) (*Table, error) {
 	path = filepath.Clean(path)
 	logger.Infof("opening table %q...", path)
 	startTime := time.Now()
@@ -341,6 +347,7 @@ func OpenTable(path string, flushCallback func(), prepareBlock PrepareBlockCallb
 		path:          path,
 		flushCallback: flushCallback,
 		prepareBlock:  prepareBlock,
+		bloomKey:      bloomKey,
 		isReadOnly:    isReadOnly,
 		fileParts:     pws,
 		mergeIdx:      uint64(time.Now().UnixNano()),
@@ -451,6 +458,9 @@ type TableMetrics struct {
 	ItemsAdded          uint64
 	ItemsAddedSizeBytes uint64
 
+	BloomFilterChecks       uint64
+	BloomFilterSkippedParts uint64
+
 	PendingItems uint64
 
 	InmemoryPartsCount uint64
@@ -502,6 +512,9 @@ func (tb *Table) UpdateMetrics(m *TableMetrics) {
 	m.ItemsAdded += atomic.LoadUint64(&tb.itemsAdded)
 	m.ItemsAddedSizeBytes += atomic.LoadUint64(&tb.itemsAddedSizeBytes)
 
+	m.BloomFilterChecks += atomic.LoadUint64(&tb.bloomFilterChecks)
+	m.BloomFilterSkippedParts += atomic.LoadUint64(&tb.bloomFilterSkippedParts)
+
 	m.PendingItems += uint64(tb.rawItems.Len())
 
 	tb.partsLock.Lock()
@@ -600,6 +613,19 @@ func (tb *Table) mergePartsOptimal(pws []*partWrapper) error {
 //
 // This function is only for debugging and testing.
 func (tb *Table) DebugFlush() {
+	tb.MakePendingItemsVisible()
+}
+
+// MustFlushToDisk flushes all the items added to tb before the call to disk, so they survive process crash.
+func (tb *Table) MustFlushToDisk() {
+	tb.MakePendingItemsVisible()
+	tb.flushInmemoryParts(time.Now().Add(dataFlushInterval), true)
+}
+
+// MakePendingItemsVisible makes all the items added to tb before the call visible to search.
+//
+// Unlike MustFlushToDisk, it doesn't write in-memory parts to disk.
+func (tb *Table) MakePendingItemsVisible() {
 	tb.flushPendingItems(nil, true)
 
 	// Wait for background flushers to finish.
@@ -630,7 +656,7 @@ func (tb *Table) inmemoryPartsFlusher() {
 		case <-tb.stopCh:
 			return
 		case <-ticker.C:
-			tb.flushInmemoryParts(false)
+			tb.flushInmemoryParts(time.Now(), false)
 		}
 	}
 }
@@ -658,34 +684,35 @@ func (tb *Table) flushPendingItems(dst []*inmemoryBlock, isFinal bool) []*inmemo
 
 func (tb *Table) flushInmemoryItems() {
 	tb.rawItems.flush(tb, nil, true)
-	tb.flushInmemoryParts(true)
+	tb.flushInmemoryParts(time.Now().Add(dataFlushInterval), true)
 }
 
-func (tb *Table) flushInmemoryParts(isFinal bool) {
+// flushInmemoryParts flushes in-memory parts with flushToDiskDeadline not exceeding the given deadline to disk.
+//
+// If isFinal is set, then it waits until parts, which are being merged at the moment, are flushed to disk too.
+func (tb *Table) flushInmemoryParts(deadline time.Time, isFinal bool) {
 	for {
-		currentTime := time.Now()
 		var pws []*partWrapper
+		hasPendingMerges := false
 
 		tb.partsLock.Lock()
 		for _, pw := range tb.inmemoryParts {
-			if !pw.isInMerge && (isFinal || pw.flushToDiskDeadline.Before(currentTime)) {
-				pw.isInMerge = true
-				pws = append(pws, pw)
+			if pw.flushToDiskDeadline.After(deadline) {
+				continue
+			}
+			if pw.isInMerge {
+				hasPendingMerges = true
+				continue
 			}
+			pw.isInMerge = true
+			pws = append(pws, pw)
 		}
 		tb.partsLock.Unlock()
 
 		if err := tb.mergePartsOptimal(pws); err != nil {
 			logger.Panicf("FATAL: cannot merge in-memory parts: %s", err)
 		}
-		if !isFinal {
-			return
-		}
-		tb.partsLock.Lock()
-		n := len(tb.inmemoryParts)
-		tb.partsLock.Unlock()
-		if n == 0 {
-			// All the in-memory parts were flushed to disk.
+		if !isFinal || !hasPendingMerges {
 			return
 		}
 		// Some parts weren't flushed to disk because they were being merged.
@@ -900,6 +927,9 @@ func (tb *Table) createInmemoryPart(ibs []*inmemoryBlock) *partWrapper {
 		bsr := bsrs[0]
 		mp := &inmemoryPart{}
 		mp.Init(&bsr.Block)
+		if tb.bloomKey != nil {
+			mp.initBloomFilter(&bsr.Block, tb.bloomKey)
+		}
 		putBlockStreamReader(bsr)
 		return newPartWrapperFromInmemoryPart(mp, flushToDiskDeadline)
 	}
@@ -909,6 +939,7 @@ func (tb *Table) createInmemoryPart(ibs []*inmemoryBlock) *partWrapper {
 	bsw := getBlockStreamWriter()
 	mpDst := &inmemoryPart{}
 	bsw.InitFromInmemoryPart(mpDst, compressLevel)
+	bsw.setBloomKeyCallback(tb.bloomKey)
 
 	// Merge parts.
 	// The merge shouldn't be interrupted by stopCh,
@@ -1145,6 +1176,7 @@ func (tb *Table) mergeParts(pws []*partWrapper, stopCh <-chan struct{}, isFinal
 			return fmt.Errorf("cannot create destination part at %q: %w", tmpPartPath, err)
 		}
 	}
+	bsw.setBloomKeyCallback(tb.bloomKey)
 
 	// Merge source parts to destination part.
 	ph, err := tb.mergePartsInternal(tmpPartPath, bsw, bsrs, dstPartType, stopCh)
@@ -5,6 +5,7 @@ import (
 	"container/heap"
 	"fmt"
 	"io"
+	"sync/atomic"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 )
@@ -82,16 +83,46 @@ func (ts *TableSearch) Init(tb *Table) {
 
 // Seek seeks for the first item greater or equal to k in the ts.
 func (ts *TableSearch) Seek(k []byte) {
+	ts.seek(k, false)
+}
+
+// SeekPrefix seeks for the first item with the given prefix in the ts.
+//
+// Unlike Seek, it skips parts, which do not contain items with the given prefix according to their bloom filters.
+// The subsequent NextItem calls return items in sorted order as usual, but items without the given prefix
+// may be incomplete because of the skipped parts. So the caller must stop the search at the first item
+// without the given prefix.
+func (ts *TableSearch) SeekPrefix(prefix []byte) {
+	ts.seek(prefix, true)
+}
+
+func (ts *TableSearch) seek(k []byte, isPrefix bool) {
 	if err := ts.Error(); err != nil {
 		// Do nothing on unrecoverable error.
 		return
 	}
 	ts.err = nil
 
+	var bloomKeyHash uint64
+	useBloomFilter := false
+	if isPrefix && ts.tb.bloomKey != nil {
+		if key := ts.tb.bloomKey(k); key != nil {
+			bloomKeyHash = getBloomKeyHash(key)
+			useBloomFilter = true
+		}
+	}
+
 	// Initialize the psHeap.
 	ts.psHeap = ts.psHeap[:0]
 	for i := range ts.psPool {
 		ps := &ts.psPool[i]
+		if useBloomFilter && ps.p.bf != nil {
+			atomic.AddUint64(&ts.tb.bloomFilterChecks, 1)
+			if !ps.p.bf.mayContainHash(bloomKeyHash) {
+				atomic.AddUint64(&ts.tb.bloomFilterSkippedParts, 1)
+				continue
+			}
+		}
 		ps.Seek(k)
 		if !ps.NextItem() {
 			if err := ps.Error(); err != nil {
@@ -42,7 +42,7 @@ func TestTableSearchSerial(t *testing.T) {
 	func() {
 		// Re-open the table and verify the search works.
 		var isReadOnly uint32
-		tb, err := OpenTable(path, nil, nil, &isReadOnly)
+		tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 		if err != nil {
 			t.Fatalf("cannot open table: %s", err)
 		}
@@ -79,7 +79,7 @@ func TestTableSearchConcurrent(t *testing.T) {
 	// Re-open the table and verify the search works.
 	func() {
 		var isReadOnly uint32
-		tb, err := OpenTable(path, nil, nil, &isReadOnly)
+		tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 		if err != nil {
 			t.Fatalf("cannot open table: %s", err)
 		}
@@ -156,7 +156,7 @@ func newTestTable(r *rand.Rand, path string, itemsCount int) (*Table, []string,
 		atomic.AddUint64(&flushes, 1)
 	}
 	var isReadOnly uint32
-	tb, err := OpenTable(path, flushCallback, nil, &isReadOnly)
+	tb, err := OpenTable(path, flushCallback, nil, nil, &isReadOnly)
 	if err != nil {
 		return nil, nil, fmt.Errorf("cannot open table: %w", err)
 	}
@@ -21,7 +21,7 @@ func TestTableOpenClose(t *testing.T) {
 
 	// Create a new table
 	var isReadOnly uint32
-	tb, err := OpenTable(path, nil, nil, &isReadOnly)
+	tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot create new table: %s", err)
 	}
@@ -31,7 +31,7 @@ func TestTableOpenClose(t *testing.T) {
 
 	// Re-open created table multiple times.
 	for i := 0; i < 4; i++ {
-		tb, err := OpenTable(path, nil, nil, &isReadOnly)
+		tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 		if err != nil {
 			t.Fatalf("cannot open created table: %s", err)
 		}
@@ -46,14 +46,14 @@ func TestTableOpenMultipleTimes(t *testing.T) {
 	}()
 
 	var isReadOnly uint32
-	tb1, err := OpenTable(path, nil, nil, &isReadOnly)
+	tb1, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open table: %s", err)
 	}
 	defer tb1.MustClose()
 
 	for i := 0; i < 4; i++ {
-		tb2, err := OpenTable(path, nil, nil, &isReadOnly)
+		tb2, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 		if err == nil {
 			tb2.MustClose()
 			t.Fatalf("expecting non-nil error when opening already opened table")
@@ -76,7 +76,7 @@ func TestTableAddItemsSerial(t *testing.T) {
 		atomic.AddUint64(&flushes, 1)
 	}
 	var isReadOnly uint32
-	tb, err := OpenTable(path, flushCallback, nil, &isReadOnly)
+	tb, err := OpenTable(path, flushCallback, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -102,7 +102,7 @@ func TestTableAddItemsSerial(t *testing.T) {
 	testReopenTable(t, path, itemsCount)
 
 	// Add more items in order to verify merge between inmemory parts and file-based parts.
-	tb, err = OpenTable(path, nil, nil, &isReadOnly)
+	tb, err = OpenTable(path, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -134,7 +134,7 @@ func TestTableCreateSnapshotAt(t *testing.T) {
 	}()
 
 	var isReadOnly uint32
-	tb, err := OpenTable(path, nil, nil, &isReadOnly)
+	tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -163,13 +163,13 @@ func TestTableCreateSnapshotAt(t *testing.T) {
 	}()
 
 	// Verify snapshots contain all the data.
-	tb1, err := OpenTable(snapshot1, nil, nil, &isReadOnly)
+	tb1, err := OpenTable(snapshot1, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
 	defer tb1.MustClose()
 
-	tb2, err := OpenTable(snapshot2, nil, nil, &isReadOnly)
+	tb2, err := OpenTable(snapshot2, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -221,7 +221,7 @@ func TestTableAddItemsConcurrent(t *testing.T) {
 		return data, items
 	}
 	var isReadOnly uint32
-	tb, err := OpenTable(path, flushCallback, prepareBlock, &isReadOnly)
+	tb, err := OpenTable(path, flushCallback, prepareBlock, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -247,7 +247,7 @@ func TestTableAddItemsConcurrent(t *testing.T) {
 	testReopenTable(t, path, itemsCount)
 
 	// Add more items in order to verify merge between inmemory parts and file-based parts.
-	tb, err = OpenTable(path, nil, nil, &isReadOnly)
+	tb, err = OpenTable(path, nil, nil, nil, &isReadOnly)
 	if err != nil {
 		t.Fatalf("cannot open %q: %s", path, err)
 	}
@@ -289,7 +289,7 @@ func testReopenTable(t *testing.T, path string, itemsCount int) {
 
 	for i := 0; i < 10; i++ {
 		var isReadOnly uint32
-		tb, err := OpenTable(path, nil, nil, &isReadOnly)
+		tb, err := OpenTable(path, nil, nil, nil, &isReadOnly)
 		if err != nil {
 			t.Fatalf("cannot re-open %q: %s", path, err)
 		}
@@ -6,6 +6,7 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -21,7 +22,7 @@ var enableTCP6 = flag.Bool("enableTCP6", false, "Whether to enable IPv6 for list
 // If useProxyProtocol is set to true, then the returned listener accepts TCP connections via proxy protocol.
 // See https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
 func NewTCPListener(name, addr string, useProxyProtocol bool, tlsConfig *tls.Config) (*TCPListener, error) {
-	network := GetTCPNetwork()
+	network := GetTCPNetworkForAddr(addr)
 	ln, err := net.Listen(network, addr)
 	if err != nil {
 		return nil, err
@@ -36,6 +37,8 @@ func NewTCPListener(name, addr string, useProxyProtocol bool, tlsConfig *tls.Con
 
 		accepts:      ms.NewCounter(fmt.Sprintf(`vm_tcplistener_accepts_total{name=%q, addr=%q}`, name, addr)),
 		acceptErrors: ms.NewCounter(fmt.Sprintf(`vm_tcplistener_errors_total{name=%q, addr=%q, type="accept"}`, name, addr)),
+
+		rejectedConns: ms.NewCounter(fmt.Sprintf(`vm_tcplistener_rejected_conns_total{name=%q, addr=%q}`, name, addr)),
 	}
 	tln.connMetrics.init(ms, "vm_tcplistener", name, addr)
 	return tln, err
@@ -64,6 +67,51 @@ func GetTCPNetwork() string {
 	return "tcp4"
 }
 
+// GetTCPNetworkForAddr returns tcp network for listening at the given addr.
+//
+// IPv6-only network is returned for addr with IPv6 host such as `[::]:8428` even if -enableTCP6 isn't set,
+// while IPv4-only network is returned for addr with IPv4 host such as `0.0.0.0:8428`.
+// GetTCPNetwork is returned for all the other addrs.
+func GetTCPNetworkForAddr(addr string) string {
+	return getNetworkForAddr(GetTCPNetwork(), "tcp", addr)
+}
+
+// GetUDPNetworkForAddr returns udp network for listening at the given addr.
+//
+// See GetTCPNetworkForAddr for details.
+func GetUDPNetworkForAddr(addr string) string {
+	return getNetworkForAddr(GetUDPNetwork(), "udp", addr)
+}
+
+func getNetworkForAddr(defaultNetwork, prefix, addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return defaultNetwork
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return defaultNetwork
+	}
+	if ip.To4() == nil {
+		return prefix + "6"
+	}
+	return prefix + "4"
+}
+
+// ParseListenAddrs returns addrs from the comma-separated list of addrs passed to `-*ListenAddr` command-line flag.
+//
+// IPv6 addrs must be enclosed in square brackets, e.g. `[::1]:8428`.
+func ParseListenAddrs(addrs string) []string {
+	var result []string
+	for _, addr := range strings.Split(addrs, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr != "" {
+			result = append(result, addr)
+		}
+	}
+	return result
+}
+
 // TCPListener listens for the addr passed to NewTCPListener.
 //
 // It also gathers various stats for the accepted connections.
@@ -75,6 +123,9 @@ type TCPListener struct {
 
 	useProxyProtocol bool
 
+	allowedNets   []*net.IPNet
+	rejectedConns *metrics.Counter
+
 	connMetrics
 }
 
@@ -99,6 +150,11 @@ func (ln *TCPListener) Accept() (net.Conn, error) {
 				return nil, err
 			}
 		}
+		if !ln.isAllowedConn(conn) {
+			ln.rejectedConns.Inc()
+			_ = conn.Close()
+			continue
+		}
 		ln.conns.Inc()
 		sc := &statConn{
 			Conn: conn,
@@ -107,3 +163,59 @@ func (ln *TCPListener) Accept() (net.Conn, error) {
 		return sc, nil
 	}
 }
+
+// SetAllowedNets limits the accepted connections to connections from the given nets.
+//
+// All the connections are accepted if nets is empty. SetAllowedNets must be called before Accept.
+func (ln *TCPListener) SetAllowedNets(nets []*net.IPNet) {
+	ln.allowedNets = nets
+}
+
+func (ln *TCPListener) isAllowedConn(conn net.Conn) bool {
+	if len(ln.allowedNets) == 0 {
+		return true
+	}
+	host, _, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range ln.allowedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAllowedNets parses the given CIDRs into a list of nets.
+//
+// Plain IP addresses are treated as nets containing only the given address.
+func ParseAllowedNets(cidrs []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, s := range cidrs {
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("cannot parse IP address %q", s)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			s = fmt.Sprintf("%s/%d", s, bits)
+		}
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse CIDR %q: %w", s, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
@@ -0,0 +1,165 @@
+package netutil
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAllowedNetsSuccess(t *testing.T) {
+	f := func(cidrs []string, resultExpected []string) {
+		t.Helper()
+		nets, err := ParseAllowedNets(cidrs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(nets) != len(resultExpected) {
+			t.Fatalf("unexpected number of nets; got %d; want %d", len(nets), len(resultExpected))
+		}
+		for i, n := range nets {
+			if s := n.String(); s != resultExpected[i] {
+				t.Fatalf("unexpected net #%d; got %q; want %q", i, s, resultExpected[i])
+			}
+		}
+	}
+	f(nil, nil)
+	f([]string{""}, nil)
+	f([]string{"10.0.0.0/8", "192.168.1.5", "2001:db8::/32", "::1"}, []string{"10.0.0.0/8", "192.168.1.5/32", "2001:db8::/32", "::1/128"})
+}
+
+func TestParseAllowedNetsFailure(t *testing.T) {
+	f := func(cidr string) {
+		t.Helper()
+		if _, err := ParseAllowedNets([]string{cidr}); err == nil {
+			t.Fatalf("expecting non-nil error for %q", cidr)
+		}
+	}
+	f("foobar")
+	f("10.0.0.0/33")
+	f("300.1.2.3")
+}
+
+func TestTCPListenerAllowedNets(t *testing.T) {
+	f := func(name string, cidrs []string, allowed bool) {
+		t.Helper()
+		nets, err := ParseAllowedNets(cidrs)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		ln, err := NewTCPListener(name, "127.0.0.1:0", false, nil)
+		if err != nil {
+			t.Fatalf("cannot create listener: %s", err)
+		}
+		defer func() {
+			_ = ln.Close()
+		}()
+		ln.SetAllowedNets(nets)
+		acceptedCh := make(chan bool, 1)
+		go func() {
+			c, err := ln.Accept()
+			if err != nil {
+				acceptedCh <- false
+				return
+			}
+			_ = c.Close()
+			acceptedCh <- true
+		}()
+		c, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("cannot connect to listener: %s", err)
+		}
+		defer func() {
+			_ = c.Close()
+		}()
+		if allowed {
+			select {
+			case ok := <-acceptedCh:
+				if !ok {
+					t.Fatalf("expecting accepted connection")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timeout when waiting for accepted connection")
+			}
+			return
+		}
+		// The rejected connection must be closed by the listener.
+		_ = c.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var buf [1]byte
+		if _, err := c.Read(buf[:]); err == nil {
+			t.Fatalf("expecting closed connection")
+		}
+		select {
+		case <-acceptedCh:
+			t.Fatalf("unexpected accepted connection")
+		default:
+		}
+	}
+	f("test_allowed_nets_empty", nil, true)
+	f("test_allowed_nets_cidr", []string{"127.0.0.0/8"}, true)
+	f("test_allowed_nets_ip", []string{"10.0.0.0/8", "127.0.0.1"}, true)
+	f("test_allowed_nets_rejected", []string{"10.0.0.0/8"}, false)
+}
+
+func TestParseListenAddrs(t *testing.T) {
+	f := func(addrs string, resultExpected []string) {
+		t.Helper()
+		result := ParseListenAddrs(addrs)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result for %q; got %q; want %q", addrs, result, resultExpected)
+		}
+	}
+	f("", nil)
+	f(":8428", []string{":8428"})
+	f("0.0.0.0:8428,[::]:8428", []string{"0.0.0.0:8428", "[::]:8428"})
+	f(" 127.0.0.1:8428 , [::1]:8428,", []string{"127.0.0.1:8428", "[::1]:8428"})
+}
+
+func TestGetNetworkForAddr(t *testing.T) {
+	f := func(addr, tcpNetworkExpected, udpNetworkExpected string) {
+		t.Helper()
+		if network := GetTCPNetworkForAddr(addr); network != tcpNetworkExpected {
+			t.Fatalf("unexpected tcp network for %q; got %q; want %q", addr, network, tcpNetworkExpected)
+		}
+		if network := GetUDPNetworkForAddr(addr); network != udpNetworkExpected {
+			t.Fatalf("unexpected udp network for %q; got %q; want %q", addr, network, udpNetworkExpected)
+		}
+	}
+	f(":8428", GetTCPNetwork(), GetUDPNetwork())
+	f("localhost:8428", GetTCPNetwork(), GetUDPNetwork())
+	f("0.0.0.0:8428", "tcp4", "udp4")
+	f("127.0.0.1:8428", "tcp4", "udp4")
+	f("[::]:8428", "tcp6", "udp6")
+	f("[::1]:8428", "tcp6", "udp6")
+	f("[2001:db8::1]:8428", "tcp6", "udp6")
+}
+
+func TestNewUDPListener(t *testing.T) {
+	ln, err := NewUDPListener("test", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start UDP listener: %s", err)
+	}
+	defer func() { _ = ln.Close() }()
+	conn, err := net.Dial("udp4", ln.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("cannot dial UDP listener: %s", err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.Write([]byte("foobar")); err != nil {
+		t.Fatalf("cannot send UDP packet: %s", err)
+	}
+	buf := make([]byte, 64)
+	if err := ln.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatalf("cannot set read deadline: %s", err)
+	}
+	n, _, err := ln.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("cannot read UDP packet: %s", err)
+	}
+	if string(buf[:n]) != "foobar" {
+		t.Fatalf("unexpected packet; got %q; want %q", buf[:n], "foobar")
+	}
+	if v := ln.readBytes.Get(); v != 6 {
+		t.Fatalf("unexpected read bytes; got %d; want 6", v)
+	}
+}
@@ -2,7 +2,9 @@ package netutil
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 
@@ -10,7 +12,9 @@ import (
 )
 
 // GetServerTLSConfig returns TLS config for the server.
-func GetServerTLSConfig(tlsCertFile, tlsKeyFile, tlsMinVersion string, tlsCipherSuites []string) (*tls.Config, error) {
+//
+// If mtlsCAFile isn't empty, then the server requires client certificates signed by CA from mtlsCAFile.
+func GetServerTLSConfig(tlsCertFile, tlsKeyFile, tlsMinVersion string, tlsCipherSuites []string, mtlsCAFile string) (*tls.Config, error) {
 	var certLock sync.Mutex
 	var certDeadline uint64
 	var cert *tls.Certificate
@@ -46,6 +50,18 @@ func GetServerTLSConfig(tlsCertFile, tlsKeyFile, tlsMinVersion string, tlsCipher
 		},
 		CipherSuites: cipherSuites,
 	}
+	if mtlsCAFile != "" {
+		data, err := os.ReadFile(mtlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read mtlsCAFile=%q: %w", mtlsCAFile, err)
+		}
+		cp := x509.NewCertPool()
+		if !cp.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("cannot parse CA certificates from mtlsCAFile=%q", mtlsCAFile)
+		}
+		cfg.ClientCAs = cp
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
 	return cfg, nil
 }
 
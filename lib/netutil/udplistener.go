@@ -0,0 +1,50 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// NewUDPListener returns new UDP listener for the given addr.
+//
+// name is used for metrics. Each listener in the program must have a distinct name.
+func NewUDPListener(name, addr string) (*UDPListener, error) {
+	network := GetUDPNetworkForAddr(addr)
+	pc, err := net.ListenPacket(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	uln := &UDPListener{
+		PacketConn: pc,
+
+		readCalls:  metrics.GetOrCreateCounter(fmt.Sprintf(`vm_udplistener_read_calls_total{name=%q, addr=%q}`, name, addr)),
+		readBytes:  metrics.GetOrCreateCounter(fmt.Sprintf(`vm_udplistener_read_bytes_total{name=%q, addr=%q}`, name, addr)),
+		readErrors: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_udplistener_errors_total{name=%q, addr=%q, type="read"}`, name, addr)),
+	}
+	return uln, nil
+}
+
+// UDPListener listens for the addr passed to NewUDPListener.
+//
+// It also gathers stats for the received packets.
+type UDPListener struct {
+	net.PacketConn
+
+	readCalls  *metrics.Counter
+	readBytes  *metrics.Counter
+	readErrors *metrics.Counter
+}
+
+// ReadFrom reads a packet from the addr passed to NewUDPListener.
+func (ln *UDPListener) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := ln.PacketConn.ReadFrom(p)
+	ln.readCalls.Inc()
+	ln.readBytes.Add(n)
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		ln.readErrors.Inc()
+	}
+	return n, addr, err
+}
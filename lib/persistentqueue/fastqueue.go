@@ -3,7 +3,9 @@ package persistentqueue
 import (
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -21,8 +23,11 @@ type FastQueue struct {
 	// or when MustClose is called.
 	cond sync.Cond
 
-	// pq is file-based queue
-	pq *queue
+	// pq is file-based or remote queue
+	pq blockQueue
+
+	// path is the path to pq
+	path string
 
 	// ch is in-memory queue
 	ch chan *bytesutil.ByteBuffer
@@ -43,9 +48,39 @@ type FastQueue struct {
 // reaches maxPendingSize.
 func MustOpenFastQueue(path, name string, maxInmemoryBlocks int, maxPendingBytes int64) *FastQueue {
 	pq := mustOpen(path, name, maxPendingBytes)
+	return newFastQueue(pq, path, maxInmemoryBlocks)
+}
+
+// MustOpenFastQueueAtRemoteFS opens persistent queue at the given remote fs such as S3 or GCS.
+//
+// It holds up to maxInmemoryBlocks in memory before falling back to remote persistence.
+// Blocks are uploaded to fs in chunks with chunkSize bytes.
+// path is used for identifying the queue in logs and metrics.
+//
+// if maxPendingBytes is 0, then the queue size is unlimited.
+// Otherwise its size is limited by maxPendingBytes. The oldest data is dropped when the queue
+// reaches maxPendingSize.
+func MustOpenFastQueueAtRemoteFS(fs common.RemoteFS, path string, maxInmemoryBlocks int, chunkSize, maxPendingBytes int64) *FastQueue {
+	pq := mustOpenRemote(fs, path, chunkSize, maxPendingBytes)
+	return newFastQueue(pq, path, maxInmemoryBlocks)
+}
+
+// blockQueue is a queue used by FastQueue when readers don't catch up with writers.
+//
+// It is unsafe to call blockQueue methods from concurrent goroutines.
+type blockQueue interface {
+	MustWriteBlock(block []byte)
+	MustReadBlockNonblocking(dst []byte) ([]byte, bool)
+	GetPendingBytes() uint64
+	ResetIfEmpty()
+	MustClose()
+}
+
+func newFastQueue(pq blockQueue, path string, maxInmemoryBlocks int) *FastQueue {
 	fq := &FastQueue{
-		pq: pq,
-		ch: make(chan *bytesutil.ByteBuffer, maxInmemoryBlocks),
+		pq:   pq,
+		path: path,
+		ch:   make(chan *bytesutil.ByteBuffer, maxInmemoryBlocks),
 	}
 	fq.cond.L = &fq.mu
 	fq.lastInmemoryBlockReadTime = fasttime.UnixTimestamp()
@@ -87,7 +122,7 @@ func (fq *FastQueue) MustClose() {
 	// Close fq.pq
 	fq.pq.MustClose()
 
-	logger.Infof("closed fast persistent queue at %q", fq.pq.dir)
+	logger.Infof("closed fast persistent queue at %q", fq.path)
 }
 
 func (fq *FastQueue) flushInmemoryBlocksToFileIfNeededLocked() {
@@ -189,6 +224,13 @@ func (fq *FastQueue) MustReadBlock(dst []byte) ([]byte, bool) {
 				return data, true
 			}
 			dst = data
+			if fq.pq.GetPendingBytes() > 0 {
+				// The pending data cannot be read at the moment, e.g. because of temporary errors at remote queue.
+				// Wait for a while before the next attempt without blocking writers.
+				fq.mu.Unlock()
+				time.Sleep(time.Second)
+				fq.mu.Lock()
+			}
 			continue
 		}
 		if fq.stopDeadline > 0 {
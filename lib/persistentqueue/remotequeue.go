@@ -0,0 +1,311 @@
+package persistentqueue
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// remoteQueue is a queue, which stores blocks at object storage such as S3 or GCS.
+//
+// Blocks are buffered in memory until the buffer reaches chunkSize. Then the buffer is uploaded
+// to object storage as a single chunk object. Chunks are downloaded and deleted from object storage in the order they were uploaded.
+//
+// Blocks from the partially read chunk may be read again after unclean shutdown.
+// Blocks from the in-memory buffer are lost on unclean shutdown.
+//
+// It is unsafe to call remoteQueue methods from concurrent goroutines.
+type remoteQueue struct {
+	fs              common.RemoteFS
+	path            string
+	chunkSize       uint64
+	maxPendingBytes uint64
+
+	// chunks contains chunks at fs in the order they must be read.
+	chunks []common.Part
+
+	// chunksBytes is the total size of chunks.
+	chunksBytes uint64
+
+	// nextChunkID is the id for the next chunk to upload.
+	nextChunkID uint64
+
+	// readPart is the chunk, which is being read from readBuf.
+	readPart common.Part
+
+	// readBuf contains the downloaded readPart.
+	readBuf []byte
+
+	// readOffset is the offset of the next block in readBuf.
+	readOffset int
+
+	// writeBuf contains blocks, which aren't uploaded to fs yet.
+	writeBuf []byte
+
+	// lastErrorTime is the time of the last error when communicating with fs.
+	// It is used for limiting the rate of requests to fs on errors.
+	lastErrorTime uint64
+
+	bytesDropped *metrics.Counter
+
+	blocksWritten *metrics.Counter
+	bytesWritten  *metrics.Counter
+
+	blocksRead *metrics.Counter
+	bytesRead  *metrics.Counter
+
+	chunksUploaded   *metrics.Counter
+	chunksDownloaded *metrics.Counter
+	remoteErrors     *metrics.Counter
+}
+
+// remoteQueueRetryInterval is the interval between attempts to communicate with fs after errors.
+const remoteQueueRetryInterval = 5
+
+func mustOpenRemote(fs common.RemoteFS, path string, chunkSize, maxPendingBytes int64) *remoteQueue {
+	if chunkSize <= 0 {
+		chunkSize = MaxBlockSize
+	}
+	if maxPendingBytes < 0 {
+		maxPendingBytes = 0
+	}
+	q := &remoteQueue{
+		fs:              fs,
+		path:            path,
+		chunkSize:       uint64(chunkSize),
+		maxPendingBytes: uint64(maxPendingBytes),
+
+		bytesDropped:  metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_bytes_dropped_total{path=%q}`, path)),
+		blocksWritten: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_blocks_written_total{path=%q}`, path)),
+		bytesWritten:  metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_bytes_written_total{path=%q}`, path)),
+		blocksRead:    metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_blocks_read_total{path=%q}`, path)),
+		bytesRead:     metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_bytes_read_total{path=%q}`, path)),
+
+		chunksUploaded:   metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_remote_chunks_uploaded_total{path=%q}`, path)),
+		chunksDownloaded: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_remote_chunks_downloaded_total{path=%q}`, path)),
+		remoteErrors:     metrics.GetOrCreateCounter(fmt.Sprintf(`vm_persistentqueue_remote_errors_total{path=%q}`, path)),
+	}
+	parts, err := fs.ListParts()
+	if err != nil {
+		logger.Panicf("FATAL: cannot list chunks at %s: %s", fs, err)
+	}
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].Path < parts[j].Path
+	})
+	for _, p := range parts {
+		var id uint64
+		if _, err := fmt.Sscanf(p.Path, "%016X", &id); err != nil || p.Offset != 0 || p.Size != p.FileSize {
+			logger.Errorf("skipping unexpected object %s at %s", &p, fs)
+			continue
+		}
+		if p.ActualSize != p.Size {
+			logger.Errorf("deleting broken chunk %s at %s, since its actual size=%d doesn't match the expected size", &p, fs, p.ActualSize)
+			q.mustDeletePart(p)
+			continue
+		}
+		q.chunks = append(q.chunks, p)
+		q.chunksBytes += p.Size
+		if id >= q.nextChunkID {
+			q.nextChunkID = id + 1
+		}
+	}
+	return q
+}
+
+// GetPendingBytes returns the number of pending bytes in the queue.
+func (q *remoteQueue) GetPendingBytes() uint64 {
+	return uint64(len(q.readBuf)-q.readOffset) + q.chunksBytes + uint64(len(q.writeBuf))
+}
+
+// ResetIfEmpty does nothing, since chunks are deleted from fs after they are read.
+func (q *remoteQueue) ResetIfEmpty() {}
+
+// MustWriteBlock writes block to q.
+//
+// The block size cannot exceed MaxBlockSize.
+func (q *remoteQueue) MustWriteBlock(block []byte) {
+	if len(block) > MaxBlockSize {
+		logger.Panicf("BUG: too big block to send: %d bytes; it mustn't exceed %d bytes", len(block), MaxBlockSize)
+	}
+	blockSize := uint64(len(block) + 8)
+	if q.maxPendingBytes > 0 {
+		if blockSize > q.maxPendingBytes {
+			// The block is too big to put it into the queue. Drop it.
+			q.bytesDropped.Add(len(block))
+			return
+		}
+		// Drop the oldest data until the number of pending bytes becomes enough for the block.
+		bb := blockBufPool.Get()
+		for q.GetPendingBytes()+blockSize > q.maxPendingBytes {
+			if len(q.chunks) > 0 && q.readOffset >= len(q.readBuf) {
+				// Drop the whole chunk without downloading it.
+				p := q.chunks[0]
+				q.chunks = q.chunks[1:]
+				q.chunksBytes -= p.Size
+				q.bytesDropped.Add(int(p.Size))
+				q.mustDeletePart(p)
+				continue
+			}
+			var ok bool
+			bb.B, ok = q.MustReadBlockNonblocking(bb.B[:0])
+			if !ok {
+				break
+			}
+			q.bytesDropped.Add(len(bb.B))
+		}
+		blockBufPool.Put(bb)
+	}
+	q.writeBuf = encoding.MarshalUint64(q.writeBuf, uint64(len(block)))
+	q.writeBuf = append(q.writeBuf, block...)
+	q.blocksWritten.Inc()
+	q.bytesWritten.Add(len(block))
+	if uint64(len(q.writeBuf)) >= q.chunkSize {
+		q.uploadWriteBuf()
+	}
+}
+
+func (q *remoteQueue) uploadWriteBuf() {
+	if len(q.writeBuf) == 0 || q.isRetryDelayed() {
+		return
+	}
+	p := common.Part{
+		Path:     fmt.Sprintf("%016X", q.nextChunkID),
+		FileSize: uint64(len(q.writeBuf)),
+		Size:     uint64(len(q.writeBuf)),
+	}
+	if err := q.fs.UploadPart(p, bytes.NewReader(q.writeBuf)); err != nil {
+		// Keep the data in writeBuf, so the upload is retried later.
+		q.registerError("cannot upload chunk %s to %s: %s", &p, q.fs, err)
+		return
+	}
+	q.nextChunkID++
+	q.chunks = append(q.chunks, p)
+	q.chunksBytes += p.Size
+	q.writeBuf = q.writeBuf[:0]
+	q.chunksUploaded.Inc()
+}
+
+// MustReadBlockNonblocking reads the next block from q to dst and returns it.
+//
+// false is returned if q is empty or if the next chunk cannot be downloaded from fs at the moment.
+func (q *remoteQueue) MustReadBlockNonblocking(dst []byte) ([]byte, bool) {
+	if q.readOffset >= len(q.readBuf) {
+		if !q.nextReadBuf() {
+			return dst, false
+		}
+	}
+	buf := q.readBuf[q.readOffset:]
+	if len(buf) < 8 {
+		logger.Errorf("skipping corrupted chunk %s at %s, since block header cannot be read from it", &q.readPart, q.fs)
+		q.readOffset = len(q.readBuf)
+		return q.MustReadBlockNonblocking(dst)
+	}
+	blockLen := encoding.UnmarshalUint64(buf)
+	buf = buf[8:]
+	if blockLen > uint64(len(buf)) {
+		logger.Errorf("skipping corrupted chunk %s at %s, since too big block size is read from it: %d bytes", &q.readPart, q.fs, blockLen)
+		q.readOffset = len(q.readBuf)
+		return q.MustReadBlockNonblocking(dst)
+	}
+	dst = append(dst, buf[:blockLen]...)
+	q.readOffset += 8 + int(blockLen)
+	q.blocksRead.Inc()
+	q.bytesRead.Add(int(blockLen))
+	return dst, true
+}
+
+// nextReadBuf fills readBuf with the oldest data in q.
+func (q *remoteQueue) nextReadBuf() bool {
+	if q.readPart.Size > 0 {
+		// The previous chunk has been read. Delete it from fs.
+		q.mustDeletePart(q.readPart)
+		q.readPart = common.Part{}
+	}
+	q.readBuf = q.readBuf[:0]
+	q.readOffset = 0
+	if len(q.chunks) == 0 {
+		if len(q.writeBuf) == 0 {
+			return false
+		}
+		// Read blocks from writeBuf without uploading them to fs.
+		q.readBuf, q.writeBuf = q.writeBuf, q.readBuf
+		return true
+	}
+	if q.isRetryDelayed() {
+		return false
+	}
+	p := q.chunks[0]
+	bb := &bytesutil.ByteBuffer{
+		B: q.readBuf,
+	}
+	if err := q.fs.DownloadPart(p, bb); err != nil {
+		q.registerError("cannot download chunk %s from %s: %s", &p, q.fs, err)
+		return false
+	}
+	q.chunks = q.chunks[1:]
+	q.chunksBytes -= p.Size
+	q.readPart = p
+	q.readBuf = bb.B
+	q.chunksDownloaded.Inc()
+	return true
+}
+
+// MustClose uploads the unread data to fs.
+func (q *remoteQueue) MustClose() {
+	if q.readOffset < len(q.readBuf) {
+		// Put the unread part of readBuf in front of the queue, so the already read blocks aren't read again after the restart.
+		unread := append([]byte{}, q.readBuf[q.readOffset:]...)
+		q.readBuf = q.readBuf[:0]
+		q.readOffset = 0
+		if q.readPart.Size == 0 {
+			// readBuf contains blocks from writeBuf.
+			q.writeBuf = append(unread, q.writeBuf...)
+		} else {
+			p := q.readPart
+			p.FileSize = uint64(len(unread))
+			p.Size = p.FileSize
+			if err := q.fs.UploadPart(p, bytes.NewReader(unread)); err != nil {
+				logger.Errorf("cannot upload the unread part of chunk %s to %s; the already read blocks will be read again after the restart: %s", &q.readPart, q.fs, err)
+			} else {
+				q.mustDeletePart(q.readPart)
+			}
+		}
+		q.readPart = common.Part{}
+	}
+	q.lastErrorTime = 0
+	n := len(q.writeBuf)
+	q.uploadWriteBuf()
+	if len(q.writeBuf) > 0 {
+		logger.Errorf("dropping %d bytes of pending data, since they cannot be uploaded to %s", n, q.fs)
+		q.bytesDropped.Add(n)
+	}
+	q.fs.MustStop()
+}
+
+func (q *remoteQueue) mustDeletePart(p common.Part) {
+	if err := q.fs.DeletePart(p); err != nil {
+		// The chunk will be read again after the restart.
+		q.registerError("cannot delete chunk %s from %s: %s", &p, q.fs, err)
+		return
+	}
+	if err := q.fs.RemoveEmptyDirs(); err != nil {
+		logger.Errorf("cannot remove empty dirs at %s: %s", q.fs, err)
+	}
+}
+
+func (q *remoteQueue) isRetryDelayed() bool {
+	return q.lastErrorTime > 0 && fasttime.UnixTimestamp() < q.lastErrorTime+remoteQueueRetryInterval
+}
+
+func (q *remoteQueue) registerError(format string, args ...interface{}) {
+	q.lastErrorTime = fasttime.UnixTimestamp()
+	q.remoteErrors.Inc()
+	logger.Errorf(format, args...)
+}
@@ -0,0 +1,107 @@
+package persistentqueue
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fsremote"
+)
+
+func newTestRemoteFS(t *testing.T, path string) *fsremote.FS {
+	t.Helper()
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		t.Fatalf("cannot obtain absolute path for %q: %s", path, err)
+	}
+	return &fsremote.FS{Dir: dir}
+}
+
+func TestRemoteQueueWriteReadRestart(t *testing.T) {
+	path := "remote-queue-write-read-restart"
+	mustDeleteDir(path)
+
+	fq := MustOpenFastQueueAtRemoteFS(newTestRemoteFS(t, path), path, 10, 100, 0)
+	var blocks []string
+	for i := 0; i < 1000; i++ {
+		block := fmt.Sprintf("block %d", i)
+		fq.MustWriteBlock([]byte(block))
+		blocks = append(blocks, block)
+	}
+	// Read a part of blocks before the restart.
+	for _, block := range blocks[:333] {
+		buf, ok := fq.MustReadBlock(nil)
+		if !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+		if string(buf) != block {
+			t.Fatalf("unexpected block read; got %q; want %q", buf, block)
+		}
+	}
+	fq.MustClose()
+
+	// Read the remaining blocks after the restart.
+	fq = MustOpenFastQueueAtRemoteFS(newTestRemoteFS(t, path), path, 10, 100, 0)
+	if n := fq.GetPendingBytes(); n == 0 {
+		t.Fatalf("the number of pending bytes must be greater than 0")
+	}
+	for _, block := range blocks[333:] {
+		buf, ok := fq.MustReadBlock(nil)
+		if !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+		if string(buf) != block {
+			t.Fatalf("unexpected block read; got %q; want %q", buf, block)
+		}
+	}
+	if n := fq.GetPendingBytes(); n != 0 {
+		t.Fatalf("the number of pending bytes must be 0; got %d", n)
+	}
+	fq.MustClose()
+	mustDeleteDir(path)
+}
+
+func TestRemoteQueueMaxPendingBytes(t *testing.T) {
+	path := "remote-queue-max-pending-bytes"
+	mustDeleteDir(path)
+
+	maxPendingBytes := int64(1000)
+	q := mustOpenRemote(newTestRemoteFS(t, path), path, 100, maxPendingBytes)
+	var blocks []string
+	for i := 0; i < 1000; i++ {
+		block := fmt.Sprintf("block %d", i)
+		q.MustWriteBlock([]byte(block))
+		blocks = append(blocks, block)
+		if n := q.GetPendingBytes(); n > uint64(maxPendingBytes) {
+			t.Fatalf("too many pending bytes; got %d; mustn't exceed %d", n, maxPendingBytes)
+		}
+	}
+	// The newest blocks must be readable in order.
+	buf, ok := q.MustReadBlockNonblocking(nil)
+	if !ok {
+		t.Fatalf("unexpected ok=false")
+	}
+	n := -1
+	for i, block := range blocks {
+		if string(buf) == block {
+			n = i
+		}
+	}
+	if n < 0 {
+		t.Fatalf("unexpected block read: %q", buf)
+	}
+	for _, block := range blocks[n+1:] {
+		buf, ok := q.MustReadBlockNonblocking(nil)
+		if !ok {
+			t.Fatalf("unexpected ok=false")
+		}
+		if string(buf) != block {
+			t.Fatalf("unexpected block read; got %q; want %q", buf, block)
+		}
+	}
+	if _, ok := q.MustReadBlockNonblocking(nil); ok {
+		t.Fatalf("unexpected ok=true for empty queue")
+	}
+	q.MustClose()
+	mustDeleteDir(path)
+}
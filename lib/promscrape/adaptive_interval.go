@@ -0,0 +1,83 @@
+package promscrape
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// adaptiveIntervalThreshold is the number of consecutive scrapes with the same outcome
+// needed for changing the effective scrape interval.
+const adaptiveIntervalThreshold = 3
+
+// scrapeIntervalAdapter adapts the effective scrape interval for overloaded targets.
+//
+// The effective interval is doubled after adaptiveIntervalThreshold consecutive scrapes of overloaded target
+// until it reaches maxInterval. It is halved after adaptiveIntervalThreshold consecutive scrapes without overload
+// until it returns to baseInterval.
+//
+// See https://docs.victoriametrics.com/vmagent.html#adaptive-scrape-interval
+type scrapeIntervalAdapter struct {
+	baseInterval time.Duration
+	maxInterval  time.Duration
+
+	interval   time.Duration
+	overloads  int
+	successful int
+}
+
+func newScrapeIntervalAdapter(baseInterval, maxInterval time.Duration) *scrapeIntervalAdapter {
+	return &scrapeIntervalAdapter{
+		baseInterval: baseInterval,
+		maxInterval:  maxInterval,
+		interval:     baseInterval,
+	}
+}
+
+// update registers the scrape outcome and returns true if the effective scrape interval has been changed.
+func (sia *scrapeIntervalAdapter) update(overloaded bool) bool {
+	if overloaded {
+		sia.successful = 0
+		sia.overloads++
+		if sia.overloads < adaptiveIntervalThreshold || sia.interval >= sia.maxInterval {
+			return false
+		}
+		sia.overloads = 0
+		sia.interval *= 2
+		if sia.interval > sia.maxInterval {
+			sia.interval = sia.maxInterval
+		}
+		return true
+	}
+	sia.overloads = 0
+	sia.successful++
+	if sia.successful < adaptiveIntervalThreshold || sia.interval <= sia.baseInterval {
+		return false
+	}
+	sia.successful = 0
+	sia.interval /= 2
+	if sia.interval < sia.baseInterval {
+		sia.interval = sia.baseInterval
+	}
+	return true
+}
+
+// isOverloadError returns true if err indicates that the scrape target is overloaded,
+// i.e. the scrape has been timed out or the target responded with 429 or 503 status code.
+func isOverloadError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isTimeoutError(err) {
+		return true
+	}
+	var sce *statusCodeError
+	if errors.As(err, &sce) {
+		return sce.statusCode == http.StatusTooManyRequests || sce.statusCode == http.StatusServiceUnavailable
+	}
+	return false
+}
+
+var scrapeIntervalAdaptations = metrics.NewCounter(`vm_promscrape_scrape_interval_adaptations_total`)
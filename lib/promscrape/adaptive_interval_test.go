@@ -0,0 +1,85 @@
+package promscrape
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/fasthttp"
+)
+
+func TestScrapeIntervalAdapter(t *testing.T) {
+	sia := newScrapeIntervalAdapter(10*time.Second, 50*time.Second)
+	f := func(overloaded, changedExpected bool, intervalExpected time.Duration) {
+		t.Helper()
+		changed := sia.update(overloaded)
+		if changed != changedExpected {
+			t.Fatalf("unexpected changed result; got %v; want %v", changed, changedExpected)
+		}
+		if sia.interval != intervalExpected {
+			t.Fatalf("unexpected interval; got %s; want %s", sia.interval, intervalExpected)
+		}
+	}
+
+	// successful scrapes do not change the interval
+	f(false, false, 10*time.Second)
+	f(false, false, 10*time.Second)
+	f(false, false, 10*time.Second)
+	f(false, false, 10*time.Second)
+
+	// the interval is doubled after adaptiveIntervalThreshold consecutive overloads
+	f(true, false, 10*time.Second)
+	f(true, false, 10*time.Second)
+	f(true, true, 20*time.Second)
+
+	// non-consecutive overloads do not change the interval
+	f(true, false, 20*time.Second)
+	f(false, false, 20*time.Second)
+	f(true, false, 20*time.Second)
+	f(true, false, 20*time.Second)
+	f(true, true, 40*time.Second)
+
+	// the interval is limited by maxInterval
+	f(true, false, 40*time.Second)
+	f(true, false, 40*time.Second)
+	f(true, true, 50*time.Second)
+	f(true, false, 50*time.Second)
+	f(true, false, 50*time.Second)
+	f(true, false, 50*time.Second)
+
+	// the interval is halved after adaptiveIntervalThreshold consecutive scrapes without overload
+	f(false, false, 50*time.Second)
+	f(false, false, 50*time.Second)
+	f(false, true, 25*time.Second)
+	f(false, false, 25*time.Second)
+	f(false, false, 25*time.Second)
+	f(false, true, 12500*time.Millisecond)
+
+	// the interval cannot become smaller than baseInterval
+	f(false, false, 12500*time.Millisecond)
+	f(false, false, 12500*time.Millisecond)
+	f(false, true, 10*time.Second)
+	f(false, false, 10*time.Second)
+	f(false, false, 10*time.Second)
+	f(false, false, 10*time.Second)
+}
+
+func TestIsOverloadError(t *testing.T) {
+	f := func(err error, resultExpected bool) {
+		t.Helper()
+		result := isOverloadError(err)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for error %v; got %v; want %v", err, result, resultExpected)
+		}
+	}
+	f(nil, false)
+	f(fmt.Errorf("connection refused"), false)
+	f(fmt.Errorf("error when scraping: %w", fasthttp.ErrTimeout), true)
+	f(fmt.Errorf("cannot scrape: %w", context.DeadlineExceeded), true)
+	f(&statusCodeError{statusCode: http.StatusTooManyRequests, err: fmt.Errorf("foo")}, true)
+	f(&statusCodeError{statusCode: http.StatusServiceUnavailable, err: fmt.Errorf("foo")}, true)
+	f(&statusCodeError{statusCode: http.StatusNotFound, err: fmt.Errorf("foo")}, false)
+	f(&statusCodeError{statusCode: http.StatusInternalServerError, err: fmt.Errorf("foo")}, false)
+}
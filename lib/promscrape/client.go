@@ -6,8 +6,8 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
@@ -21,7 +21,8 @@ import (
 
 var (
 	maxScrapeSize = flagutil.NewBytes("promscrape.maxScrapeSize", 16*1024*1024, "The maximum size of scrape response in bytes to process from Prometheus targets. "+
-		"Bigger responses are rejected")
+		"Bigger responses are rejected. The limit can be overridden on a per-job basis via max_scrape_size option at scrape_config. "+
+		"See https://docs.victoriametrics.com/vmagent.html#scrape_config-enhancements")
 	maxResponseHeadersSize = flagutil.NewBytes("promscrape.maxResponseHeadersSize", 4096, "The maximum size of http response headers from Prometheus scrape targets")
 	disableCompression     = flag.Bool("promscrape.disableCompression", false, "Whether to disable sending 'Accept-Encoding: gzip' request headers to all the scrape targets. "+
 		"This may reduce CPU usage on scrape targets at the cost of higher network bandwidth utilization. "+
@@ -55,6 +56,9 @@ type client struct {
 	denyRedirects           bool
 	disableCompression      bool
 	disableKeepAlive        bool
+
+	// maxScrapeSizeName is the name of the option, which limits the response size.
+	maxScrapeSizeName string
 }
 
 func addMissingPort(addr string, isTLS bool) string {
@@ -114,6 +118,15 @@ func newClient(sw *ScrapeWork, ctx context.Context) *client {
 	}
 	hostPort = addMissingPort(hostPort, isTLS)
 	dialAddr = addMissingPort(dialAddr, isTLS)
+	maxBodySize := maxScrapeSize.N
+	maxScrapeSizeName := "-promscrape.maxScrapeSize"
+	if sw.MaxScrapeSize > 0 {
+		maxBodySize = sw.MaxScrapeSize
+		maxScrapeSizeName = "max_scrape_size"
+	}
+	if maxBodySize > math.MaxInt {
+		maxBodySize = math.MaxInt
+	}
 	dialFunc, err := newStatDialFunc(proxyURL, sw.ProxyAuthConfig)
 	if err != nil {
 		logger.Fatalf("cannot create dial func: %s", err)
@@ -127,32 +140,30 @@ func newClient(sw *ScrapeWork, ctx context.Context) *client {
 		MaxIdleConnDuration:          2 * sw.ScrapeInterval,
 		ReadTimeout:                  sw.ScrapeTimeout,
 		WriteTimeout:                 10 * time.Second,
-		MaxResponseBodySize:          maxScrapeSize.IntN(),
+		MaxResponseBodySize:          int(maxBodySize),
 		MaxIdempotentRequestAttempts: 1,
 		ReadBufferSize:               maxResponseHeadersSize.IntN(),
 	}
-	var sc *http.Client
-	var proxyURLFunc func(*http.Request) (*url.URL, error)
-	if pu := sw.ProxyURL.GetURL(); pu != nil {
-		proxyURLFunc = http.ProxyURL(pu)
-	}
-	sc = &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:        tlsCfg,
-			Proxy:                  proxyURLFunc,
-			TLSHandshakeTimeout:    10 * time.Second,
-			IdleConnTimeout:        2 * sw.ScrapeInterval,
-			DisableCompression:     *disableCompression || sw.DisableCompression,
-			DisableKeepAlives:      *disableKeepAlive || sw.DisableKeepAlive,
-			DialContext:            statStdDial,
-			MaxIdleConnsPerHost:    100,
-			MaxResponseHeaderBytes: int64(maxResponseHeadersSize.N),
+	tr := &http.Transport{
+		TLSClientConfig:        tlsCfg,
+		TLSHandshakeTimeout:    10 * time.Second,
+		IdleConnTimeout:        2 * sw.ScrapeInterval,
+		DisableCompression:     *disableCompression || sw.DisableCompression,
+		DisableKeepAlives:      *disableKeepAlive || sw.DisableKeepAlive,
+		DialContext:            statStdDial,
+		MaxIdleConnsPerHost:    100,
+		MaxResponseHeaderBytes: int64(maxResponseHeadersSize.N),
 
-			// Set timeout for receiving the first response byte,
-			// since the duration for reading the full response can be much bigger because of stream parsing.
-			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1017#issuecomment-767235047
-			ResponseHeaderTimeout: sw.ScrapeTimeout,
-		},
+		// Set timeout for receiving the first response byte,
+		// since the duration for reading the full response can be much bigger because of stream parsing.
+		// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/1017#issuecomment-767235047
+		ResponseHeaderTimeout: sw.ScrapeTimeout,
+	}
+	if err := sw.ProxyURL.ConfigureTransport(tr, sw.ProxyAuthConfig); err != nil {
+		logger.Fatalf("cannot configure proxy: %s", err)
+	}
+	sc := &http.Client{
+		Transport: tr,
 
 		// Set 30x bigger timeout than the sw.ScrapeTimeout, since the duration for reading the full response
 		// can be much bigger because of stream parsing.
@@ -179,6 +190,7 @@ func newClient(sw *ScrapeWork, ctx context.Context) *client {
 		denyRedirects:           sw.DenyRedirects,
 		disableCompression:      sw.DisableCompression,
 		disableKeepAlive:        sw.DisableKeepAlive,
+		maxScrapeSizeName:       maxScrapeSizeName,
 	}
 }
 
@@ -212,8 +224,11 @@ func (c *client) GetStreamReader() (*streamReader, error) {
 		respBody, _ := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
 		cancel()
-		return nil, fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
-			c.scrapeURL, resp.StatusCode, http.StatusOK, respBody)
+		return nil, &statusCodeError{
+			statusCode: resp.StatusCode,
+			err: fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
+				c.scrapeURL, resp.StatusCode, http.StatusOK, respBody),
+		}
 	}
 	scrapesOK.Inc()
 	return &streamReader{
@@ -221,6 +236,7 @@ func (c *client) GetStreamReader() (*streamReader, error) {
 		cancel:      cancel,
 		scrapeURL:   c.scrapeURL,
 		maxBodySize: int64(c.hc.MaxResponseBodySize),
+		limitName:   c.maxScrapeSizeName,
 	}, nil
 }
 
@@ -298,8 +314,8 @@ func (c *client) ReadData(dst []byte) ([]byte, error) {
 		}
 		if err == fasthttp.ErrBodyTooLarge {
 			maxScrapeSizeExceeded.Inc()
-			return dst, fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize=%d; "+
-				"either reduce the response size for the target or increase -promscrape.maxScrapeSize", c.scrapeURL, maxScrapeSize.N)
+			return dst, fmt.Errorf("the response from %q exceeds %s=%d; "+
+				"either reduce the response size for the target or increase %s", c.scrapeURL, c.maxScrapeSizeName, c.hc.MaxResponseBodySize, c.maxScrapeSizeName)
 		}
 		return dst, fmt.Errorf("error when scraping %q: %w", c.scrapeURL, err)
 	}
@@ -325,13 +341,16 @@ func (c *client) ReadData(dst []byte) ([]byte, error) {
 	fasthttp.ReleaseResponse(resp)
 	if len(dst) > c.hc.MaxResponseBodySize {
 		maxScrapeSizeExceeded.Inc()
-		return dst, fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize=%d (the actual response size is %d bytes); "+
-			"either reduce the response size for the target or increase -promscrape.maxScrapeSize", c.scrapeURL, maxScrapeSize.N, len(dst))
+		return dst, fmt.Errorf("the response from %q exceeds %s=%d (the actual response size is %d bytes); "+
+			"either reduce the response size for the target or increase %s", c.scrapeURL, c.maxScrapeSizeName, c.hc.MaxResponseBodySize, len(dst), c.maxScrapeSizeName)
 	}
 	if statusCode != fasthttp.StatusOK {
 		metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_scrapes_total{status_code="%d"}`, statusCode)).Inc()
-		return dst, fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
-			c.scrapeURL, statusCode, fasthttp.StatusOK, dst)
+		return dst, &statusCodeError{
+			statusCode: statusCode,
+			err: fmt.Errorf("unexpected status code returned when scraping %q: %d; expecting %d; response body: %q",
+				c.scrapeURL, statusCode, fasthttp.StatusOK, dst),
+		}
 	}
 	scrapesOK.Inc()
 	return dst, nil
@@ -339,6 +358,17 @@ func (c *client) ReadData(dst []byte) ([]byte, error) {
 
 var gunzipBufPool bytesutil.ByteBufferPool
 
+// statusCodeError is returned when scrape target responds with unexpected status code.
+type statusCodeError struct {
+	statusCode int
+	err        error
+}
+
+// Error implements error interface.
+func (e *statusCodeError) Error() string {
+	return e.err.Error()
+}
+
 var (
 	maxScrapeSizeExceeded = metrics.NewCounter(`vm_promscrape_max_scrape_size_exceeded_errors_total`)
 	scrapesTimedout       = metrics.NewCounter(`vm_promscrape_scrapes_timed_out_total`)
@@ -384,6 +414,7 @@ type streamReader struct {
 	bytesRead   int64
 	scrapeURL   string
 	maxBodySize int64
+	limitName   string
 }
 
 func (sr *streamReader) Read(p []byte) (int, error) {
@@ -391,8 +422,8 @@ func (sr *streamReader) Read(p []byte) (int, error) {
 	sr.bytesRead += int64(n)
 	if err == nil && sr.bytesRead > sr.maxBodySize {
 		maxScrapeSizeExceeded.Inc()
-		err = fmt.Errorf("the response from %q exceeds -promscrape.maxScrapeSize=%d; "+
-			"either reduce the response size for the target or increase -promscrape.maxScrapeSize", sr.scrapeURL, sr.maxBodySize)
+		err = fmt.Errorf("the response from %q exceeds %s=%d; "+
+			"either reduce the response size for the target or increase %s", sr.scrapeURL, sr.limitName, sr.maxBodySize, sr.limitName)
 	}
 	return n, err
 }
@@ -15,6 +15,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
@@ -41,7 +42,10 @@ import (
 )
 
 var (
-	noStaleMarkers       = flag.Bool("promscrape.noStaleMarkers", false, "Whether to disable sending Prometheus stale markers for metrics when scrape target disappears. This option may reduce memory usage if stale markers aren't needed for your setup. This option also disables populating the scrape_series_added metric. See https://prometheus.io/docs/concepts/jobs_instances/#automatically-generated-labels-and-time-series")
+	noStaleMarkers        = flag.Bool("promscrape.noStaleMarkers", false, "Whether to disable sending Prometheus stale markers for metrics when scrape target disappears. This option may reduce memory usage if stale markers aren't needed for your setup. This option also disables populating the scrape_series_added metric. See https://prometheus.io/docs/concepts/jobs_instances/#automatically-generated-labels-and-time-series")
+	extendedScrapeMetrics = flag.Bool("promscrape.extendedScrapeMetrics", false, "Whether to generate extended automatically generated metrics such as scrape_response_size_bytes "+
+		"and scrape_interval_seconds for every scrape target. This option can be overridden on a per-job basis via extended_scrape_metrics option at scrape_config. "+
+		"See https://docs.victoriametrics.com/vmagent.html#automatically-generated-metrics")
 	seriesLimitPerTarget = flag.Int("promscrape.seriesLimitPerTarget", 0, "Optional limit on the number of unique time series a single scrape target can expose. See https://docs.victoriametrics.com/vmagent.html#cardinality-limiter for more info")
 	strictParse          = flag.Bool("promscrape.config.strictParse", true, "Whether to deny unsupported fields in -promscrape.config . Set to false in order to silently skip unsupported fields")
 	dryRun               = flag.Bool("promscrape.config.dryRun", false, "Checks -promscrape.config file for errors and unsupported fields and then exits. "+
@@ -174,6 +178,26 @@ func (cfg *Config) mustRestart(prevCfg *Config) {
 	logger.Infof("restarted service discovery routines in %.3f seconds, stopped=%d, started=%d, restarted=%d", time.Since(startTime).Seconds(), stopped, started, restarted)
 }
 
+// cloneForRestart returns a copy of cfg, which can be passed to mustRestart after cfg has been stopped.
+//
+// This is used for rolling back to the previous config.
+func (cfg *Config) cloneForRestart() (*Config, error) {
+	cfgCopy := &Config{
+		Global:  cfg.Global,
+		baseDir: cfg.baseDir,
+	}
+	for _, sc := range cfg.ScrapeConfigs {
+		sc = sc.clone()
+		swc, err := getScrapeWorkConfig(sc, cfgCopy.baseDir, &cfgCopy.Global)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse `scrape_config`: %w", err)
+		}
+		sc.swc = swc
+		cfgCopy.ScrapeConfigs = append(cfgCopy.ScrapeConfigs, sc)
+	}
+	return cfgCopy, nil
+}
+
 func areEqualGlobalConfigs(a, b *GlobalConfig) bool {
 	sa := a.marshalJSON()
 	sb := b.marshalJSON()
@@ -248,6 +272,7 @@ type ScrapeConfig struct {
 	Params               map[string][]string         `yaml:"params,omitempty"`
 	HTTPClientConfig     promauth.HTTPClientConfig   `yaml:",inline"`
 	ProxyURL             *proxy.URL                  `yaml:"proxy_url,omitempty"`
+	NoProxy              *proxy.NoProxy              `yaml:"no_proxy,omitempty"`
 	RelabelConfigs       []promrelabel.RelabelConfig `yaml:"relabel_configs,omitempty"`
 	MetricRelabelConfigs []promrelabel.RelabelConfig `yaml:"metric_relabel_configs,omitempty"`
 	SampleLimit          int                         `yaml:"sample_limit,omitempty"`
@@ -277,6 +302,13 @@ type ScrapeConfig struct {
 	ScrapeOffset        *promutils.Duration        `yaml:"scrape_offset,omitempty"`
 	SeriesLimit         int                        `yaml:"series_limit,omitempty"`
 	NoStaleMarkers      *bool                      `yaml:"no_stale_markers,omitempty"`
+	ExtendedMetrics     *bool                      `yaml:"extended_scrape_metrics,omitempty"`
+	ScrapeCacheMaxAge   *promutils.Duration        `yaml:"scrape_cache_max_age,omitempty"`
+	MaxScrapeInterval   *promutils.Duration        `yaml:"max_scrape_interval,omitempty"`
+	StalenessInterval   *promutils.Duration        `yaml:"staleness_interval,omitempty"`
+	MaxScrapeSize       string                     `yaml:"max_scrape_size,omitempty"`
+	ProbeModule         string                     `yaml:"probe_module,omitempty"`
+	MetadataOnly        bool                       `yaml:"metadata_only,omitempty"`
 	ProxyClientConfig   promauth.ProxyClientConfig `yaml:",inline"`
 
 	// This is set in loadConfig
@@ -357,17 +389,26 @@ type StaticConfig struct {
 }
 
 func loadStaticConfigs(path string) ([]StaticConfig, error) {
-	data, err := fs.ReadFileOrHTTP(path)
+	data, err := readFileSDSource(path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read `static_configs` from %q: %w", path, err)
 	}
-	data, err = envtemplate.ReplaceBytes(data)
+	return parseStaticConfigs(data, path)
+}
+
+// parseStaticConfigs parses and validates target groups in JSON or YAML format from data loaded from the given path.
+func parseStaticConfigs(data []byte, path string) ([]StaticConfig, error) {
+	data, err := envtemplate.ReplaceBytes(data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot expand environment vars in %q: %w", path, err)
 	}
 	var stcs []StaticConfig
 	if err := yaml.UnmarshalStrict(data, &stcs); err != nil {
-		return nil, fmt.Errorf("cannot unmarshal `static_configs` from %q: %w", path, err)
+		return nil, fmt.Errorf("cannot unmarshal `static_configs` from %q: %w; the file must contain a list of target groups "+
+			"with `targets` and optional `labels` fields; see https://docs.victoriametrics.com/sd_configs.html#file_sd_configs", path, err)
+	}
+	if err := validateStaticConfigs(stcs); err != nil {
+		return nil, fmt.Errorf("invalid `static_configs` in %q: %w", path, err)
 	}
 	return stcs, nil
 }
@@ -445,6 +486,14 @@ func (cfg *Config) parseData(data []byte, path string) ([]byte, error) {
 	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, scs...)
 	dataNew := append(data, scsData...)
 
+	// Load scrape configs added via /api/v1/scrape_configs API
+	dscs, dscsData, err := loadDynamicScrapeConfigs()
+	if err != nil {
+		return nil, fmt.Errorf("cannot load scrape configs from -promscrape.dynamicScrapeConfigsFile: %w", err)
+	}
+	cfg.ScrapeConfigs = append(cfg.ScrapeConfigs, dscs...)
+	dataNew = append(dataNew, dscsData...)
+
 	// Check that all the scrape configs have unique JobName
 	m := make(map[string]struct{}, len(cfg.ScrapeConfigs))
 	for _, sc := range cfg.ScrapeConfigs {
@@ -967,15 +1016,50 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 	if err != nil {
 		return nil, fmt.Errorf("cannot parse `metric_relabel_configs` for `job_name` %q: %w", jobName, err)
 	}
+	if d := sc.MaxScrapeInterval.Duration(); d > 0 && d < scrapeInterval {
+		return nil, fmt.Errorf("`max_scrape_interval: %s` cannot be smaller than `scrape_interval: %s` for `job_name` %q", d, scrapeInterval, jobName)
+	}
+	if sc.StreamParse && sc.ScrapeCacheMaxAge.Duration() > 0 {
+		return nil, fmt.Errorf("`scrape_cache_max_age` cannot be used together with `stream_parse: true` for `job_name` %q, "+
+			"since responses aren't cached in stream parsing mode", jobName)
+	}
+	if err := checkProbeModule(sc.ProbeModule); err != nil {
+		return nil, fmt.Errorf("cannot parse `probe_module` for `job_name` %q: %w", jobName, err)
+	}
+	if sc.StreamParse && sc.ProbeModule != "" {
+		return nil, fmt.Errorf("`probe_module` cannot be used together with `stream_parse: true` for `job_name` %q", jobName)
+	}
+	if sc.MetadataOnly && sc.StreamParse {
+		return nil, fmt.Errorf("`metadata_only: true` cannot be used together with `stream_parse: true` for `job_name` %q", jobName)
+	}
+	if sc.MetadataOnly && sc.ProbeModule != "" {
+		return nil, fmt.Errorf("`metadata_only: true` cannot be used together with `probe_module` for `job_name` %q, "+
+			"since probe results do not contain metric metadata", jobName)
+	}
 	externalLabels := globalCfg.ExternalLabels
 	noStaleTracking := *noStaleMarkers
 	if sc.NoStaleMarkers != nil {
 		noStaleTracking = *sc.NoStaleMarkers
 	}
+	extendedMetrics := *extendedScrapeMetrics
+	if sc.ExtendedMetrics != nil {
+		extendedMetrics = *sc.ExtendedMetrics
+	}
 	seriesLimit := *seriesLimitPerTarget
 	if sc.SeriesLimit > 0 {
 		seriesLimit = sc.SeriesLimit
 	}
+	var maxScrapeSize int64
+	if sc.MaxScrapeSize != "" {
+		var b flagutil.Bytes
+		if err := b.Set(sc.MaxScrapeSize); err != nil {
+			return nil, fmt.Errorf("cannot parse `max_scrape_size` for `job_name` %q: %w", jobName, err)
+		}
+		if b.N <= 0 {
+			return nil, fmt.Errorf("`max_scrape_size` for `job_name` %q must be positive; got %q", jobName, sc.MaxScrapeSize)
+		}
+		maxScrapeSize = b.N
+	}
 	swc := &scrapeWorkConfig{
 		scrapeInterval:       scrapeInterval,
 		scrapeIntervalString: scrapeInterval.String(),
@@ -986,6 +1070,7 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 		scheme:               scheme,
 		params:               params,
 		proxyURL:             sc.ProxyURL,
+		noProxy:              sc.NoProxy,
 		proxyAuthConfig:      proxyAC,
 		authConfig:           ac,
 		honorLabels:          honorLabels,
@@ -1002,6 +1087,13 @@ func getScrapeWorkConfig(sc *ScrapeConfig, baseDir string, globalCfg *GlobalConf
 		scrapeOffset:         sc.ScrapeOffset.Duration(),
 		seriesLimit:          seriesLimit,
 		noStaleMarkers:       noStaleTracking,
+		extendedMetrics:      extendedMetrics,
+		scrapeCacheMaxAge:    sc.ScrapeCacheMaxAge.Duration(),
+		maxScrapeInterval:    sc.MaxScrapeInterval.Duration(),
+		stalenessInterval:    sc.StalenessInterval.Duration(),
+		maxScrapeSize:        maxScrapeSize,
+		probeModule:          sc.ProbeModule,
+		metadataOnly:         sc.MetadataOnly,
 	}
 	return swc, nil
 }
@@ -1016,6 +1108,7 @@ type scrapeWorkConfig struct {
 	scheme               string
 	params               map[string][]string
 	proxyURL             *proxy.URL
+	noProxy              *proxy.NoProxy
 	proxyAuthConfig      *promauth.Config
 	authConfig           *promauth.Config
 	honorLabels          bool
@@ -1032,6 +1125,13 @@ type scrapeWorkConfig struct {
 	scrapeOffset         time.Duration
 	seriesLimit          int
 	noStaleMarkers       bool
+	extendedMetrics      bool
+	scrapeCacheMaxAge    time.Duration
+	maxScrapeInterval    time.Duration
+	stalenessInterval    time.Duration
+	maxScrapeSize        int64
+	probeModule          string
+	metadataOnly         bool
 }
 
 type targetLabelsGetter interface {
@@ -1094,6 +1194,10 @@ func (sdc *FileSDConfig) appendScrapeWork(dst []*ScrapeWork, swsMapPrev map[stri
 	metaLabels := promutils.GetLabels()
 	defer promutils.PutLabels(metaLabels)
 	for _, file := range sdc.Files {
+		if isFileSDURL(file) {
+			dst = appendFileSDScrapeWork(dst, swsMapPrev, baseDir, file, swc, metaLabels)
+			continue
+		}
 		pathPattern := fs.GetFilepath(baseDir, file)
 		paths := []string{pathPattern}
 		if strings.Contains(pathPattern, "*") {
@@ -1106,33 +1210,38 @@ func (sdc *FileSDConfig) appendScrapeWork(dst []*ScrapeWork, swsMapPrev map[stri
 			}
 		}
 		for _, path := range paths {
-			stcs, err := loadStaticConfigs(path)
-			if err != nil {
-				// Do not return this error, since other paths may contain valid scrape configs.
-				if sws := swsMapPrev[path]; sws != nil {
-					// Re-use the previous valid scrape work for this path.
-					logger.Errorf("keeping the previously loaded `static_configs` from %q because of error when re-loading the file: %s", path, err)
-					dst = append(dst, sws...)
-				} else {
-					logger.Errorf("skipping loading `static_configs` from %q because of error: %s", path, err)
-				}
-				continue
-			}
-			pathShort := path
-			if strings.HasPrefix(pathShort, baseDir) {
-				pathShort = path[len(baseDir):]
-				if len(pathShort) > 0 && pathShort[0] == filepath.Separator {
-					pathShort = pathShort[1:]
-				}
-			}
-			metaLabels.Reset()
-			metaLabels.Add("__meta_filepath", pathShort)
-			metaLabels.Add("__vm_filepath", path) // This label is needed for internal promscrape logic
-			for i := range stcs {
-				dst = stcs[i].appendScrapeWork(dst, swc, metaLabels)
-			}
+			dst = appendFileSDScrapeWork(dst, swsMapPrev, baseDir, path, swc, metaLabels)
+		}
+	}
+	return dst
+}
+
+func appendFileSDScrapeWork(dst []*ScrapeWork, swsMapPrev map[string][]*ScrapeWork, baseDir, path string, swc *scrapeWorkConfig, metaLabels *promutils.Labels) []*ScrapeWork {
+	stcs, err := loadFileSDStaticConfigs(path)
+	if err != nil {
+		// Do not return this error, since other paths may contain valid scrape configs.
+		if sws := swsMapPrev[path]; sws != nil {
+			// Re-use the previous valid scrape work for this path.
+			logger.Errorf("keeping the previously loaded `static_configs` from %q because of error when re-loading the file: %s", path, err)
+			dst = append(dst, sws...)
+		} else {
+			logger.Errorf("skipping loading `static_configs` from %q because of error: %s", path, err)
+		}
+		return dst
+	}
+	pathShort := path
+	if !isFileSDURL(path) && strings.HasPrefix(pathShort, baseDir) {
+		pathShort = path[len(baseDir):]
+		if len(pathShort) > 0 && pathShort[0] == filepath.Separator {
+			pathShort = pathShort[1:]
 		}
 	}
+	metaLabels.Reset()
+	metaLabels.Add("__meta_filepath", pathShort)
+	metaLabels.Add("__vm_filepath", path) // This label is needed for internal promscrape logic
+	for i := range stcs {
+		dst = stcs[i].appendScrapeWork(dst, swc, metaLabels)
+	}
 	return dst
 }
 
@@ -1167,25 +1276,46 @@ func appendScrapeWorkKey(dst []byte, labels *promutils.Labels) []byte {
 	return dst
 }
 
+// needSkipScrapeWork returns true if the scrape work with the given key mustn't be scraped by the cluster member with the given memberNum.
+//
+// The scrape work is assigned to replicasCount members with the highest rendezvous hashing scores for the given key.
+// This minimizes the number of targets, which move between members when membersCount changes.
+// See https://en.wikipedia.org/wiki/Rendezvous_hashing
 func needSkipScrapeWork(key string, membersCount, replicasCount, memberNum int) bool {
 	if membersCount <= 1 {
 		return false
 	}
-	h := xxhash.Sum64(bytesutil.ToUnsafeBytes(key))
-	idx := int(h % uint64(membersCount))
 	if replicasCount < 1 {
 		replicasCount = 1
 	}
-	for i := 0; i < replicasCount; i++ {
-		if idx == memberNum {
-			return false
+	if replicasCount >= membersCount {
+		return false
+	}
+	h := xxhash.Sum64(bytesutil.ToUnsafeBytes(key))
+	score := getRendezvousScore(h, memberNum)
+	higherScores := 0
+	for i := 0; i < membersCount; i++ {
+		if i == memberNum {
+			continue
 		}
-		idx++
-		if idx >= membersCount {
-			idx = 0
+		n := getRendezvousScore(h, i)
+		if n > score || (n == score && i < memberNum) {
+			higherScores++
+			if higherScores >= replicasCount {
+				return true
+			}
 		}
 	}
-	return true
+	return false
+}
+
+// getRendezvousScore returns rendezvous hashing score for the cluster member with the given memberNum and the given key hash.
+func getRendezvousScore(h uint64, memberNum int) uint64 {
+	// Mix h with memberNum via splitmix64 finalizer. See https://xorshift.di.unimi.it/splitmix64.c
+	x := h + uint64(memberNum+1)*0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
 }
 
 var scrapeWorkKeyBufPool bytesutil.ByteBufferPool
@@ -1321,6 +1451,29 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 		}
 		streamParse = b
 	}
+	// Read staleness_interval option from __staleness_interval__ label.
+	// See https://docs.victoriametrics.com/vmagent.html#prometheus-staleness-markers
+	stalenessInterval := swc.stalenessInterval
+	if s := labels.Get("__staleness_interval__"); len(s) > 0 {
+		d, err := promutils.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse __staleness_interval__=%q: %w", s, err)
+		}
+		stalenessInterval = d
+	}
+	// Read probe_module option from __probe_module__ label.
+	// See https://docs.victoriametrics.com/vmagent.html#probing
+	probeModule := swc.probeModule
+	if s := labels.Get("__probe_module__"); len(s) > 0 {
+		if err := checkProbeModule(s); err != nil {
+			return nil, fmt.Errorf("cannot parse __probe_module__=%q: %w", s, err)
+		}
+		probeModule = s
+	}
+	if probeModule != "" {
+		// Probe results are too small for stream parsing.
+		streamParse = false
+	}
 	// Remove labels with "__" prefix according to https://www.robustperception.io/life-of-a-label/
 	labels.RemoveLabelsWithDoubleUnderscorePrefix()
 	// Add missing "instance" label according to https://www.robustperception.io/life-of-a-label
@@ -1346,7 +1499,7 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 		OriginalLabels:       originalLabels,
 		Labels:               labelsCopy,
 		ExternalLabels:       swc.externalLabels,
-		ProxyURL:             swc.proxyURL,
+		ProxyURL:             swc.noProxy.GetProxyURL(swc.proxyURL, scrapeURL),
 		ProxyAuthConfig:      swc.proxyAuthConfig,
 		AuthConfig:           swc.authConfig,
 		RelabelConfigs:       swc.relabelConfigs,
@@ -1359,6 +1512,13 @@ func (swc *scrapeWorkConfig) getScrapeWork(target string, extraLabels, metaLabel
 		ScrapeOffset:         swc.scrapeOffset,
 		SeriesLimit:          seriesLimit,
 		NoStaleMarkers:       swc.noStaleMarkers,
+		ExtendedMetrics:      swc.extendedMetrics,
+		ScrapeCacheMaxAge:    swc.scrapeCacheMaxAge,
+		MaxScrapeInterval:    swc.maxScrapeInterval,
+		StalenessInterval:    stalenessInterval,
+		MaxScrapeSize:        swc.maxScrapeSize,
+		ProbeModule:          probeModule,
+		MetadataOnly:         swc.metadataOnly,
 		AuthToken:            at,
 
 		jobNameOriginal: swc.jobName,
@@ -134,8 +134,74 @@ func TestNeedSkipScrapeWork(t *testing.T) {
 
 	// A cluster with 3 nodes with replicationFactor=2
 	f("foo", 3, 2, 0, false)
-	f("foo", 3, 2, 1, true)
-	f("foo", 3, 2, 2, false)
+	f("foo", 3, 2, 1, false)
+	f("foo", 3, 2, 2, true)
+
+	// A cluster with 3 nodes with replicationFactor exceeding the number of nodes
+	f("foo", 3, 5, 0, false)
+	f("foo", 3, 5, 1, false)
+	f("foo", 3, 5, 2, false)
+}
+
+func TestNeedSkipScrapeWorkDistribution(t *testing.T) {
+	const keysCount = 10000
+	getMembers := func(key string, membersCount, replicationFactor int) []int {
+		var members []int
+		for i := 0; i < membersCount; i++ {
+			if !needSkipScrapeWork(key, membersCount, replicationFactor, i) {
+				members = append(members, i)
+			}
+		}
+		return members
+	}
+	f := func(membersCount, replicationFactor int) {
+		t.Helper()
+		perMember := make([]int, membersCount)
+		movedKeys := 0
+		for i := 0; i < keysCount; i++ {
+			key := fmt.Sprintf("instance=host-%d:9100,job=node_exporter,", i)
+			members := getMembers(key, membersCount, replicationFactor)
+			if len(members) != replicationFactor {
+				t.Fatalf("unexpected number of members for key %q; got %d; want %d", key, len(members), replicationFactor)
+			}
+			for _, m := range members {
+				perMember[m]++
+			}
+			// Verify that adding a member moves only the keys assigned to the new member.
+			membersNew := getMembers(key, membersCount+1, replicationFactor)
+			for _, m := range members {
+				found := false
+				for _, mNew := range membersNew {
+					if m == mNew {
+						found = true
+						break
+					}
+				}
+				if !found {
+					movedKeys++
+					if membersNew[len(membersNew)-1] != membersCount {
+						t.Fatalf("key %q must be moved only to the new member %d; got members %d; previous members %d", key, membersCount, membersNew, members)
+					}
+				}
+			}
+		}
+		// Every member must scrape roughly replicationFactor/membersCount share of keys.
+		expectedPerMember := keysCount * replicationFactor / membersCount
+		for i, n := range perMember {
+			if n < expectedPerMember*8/10 || n > expectedPerMember*12/10 {
+				t.Fatalf("unbalanced distribution for member %d; got %d keys; want %d+-20%%", i, n, expectedPerMember)
+			}
+		}
+		// Roughly replicationFactor/(membersCount+1) share of keys must move to the new member.
+		expectedMovedKeys := keysCount * replicationFactor / (membersCount + 1)
+		if movedKeys < expectedMovedKeys*8/10 || movedKeys > expectedMovedKeys*12/10 {
+			t.Fatalf("unexpected number of moved keys; got %d; want %d+-20%%", movedKeys, expectedMovedKeys)
+		}
+	}
+	f(2, 1)
+	f(4, 1)
+	f(4, 2)
+	f(7, 3)
 }
 
 func TestLoadStaticConfigs(t *testing.T) {
@@ -214,6 +280,31 @@ func TestLoadConfig(t *testing.T) {
 	}
 }
 
+func TestConfigCloneForRestart(t *testing.T) {
+	cfg, _, err := loadConfig("testdata/prometheus-with-scrape-config-files.yml")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cfgCopy, err := cfg.cloneForRestart()
+	if err != nil {
+		t.Fatalf("cannot clone config: %s", err)
+	}
+	if len(cfgCopy.ScrapeConfigs) != len(cfg.ScrapeConfigs) {
+		t.Fatalf("unexpected number of scrape configs; got %d; want %d", len(cfgCopy.ScrapeConfigs), len(cfg.ScrapeConfigs))
+	}
+	for i, sc := range cfgCopy.ScrapeConfigs {
+		if sc == cfg.ScrapeConfigs[i] {
+			t.Fatalf("scrape config #%d must be copied", i)
+		}
+		if sc.swc == nil {
+			t.Fatalf("missing scrape work config for scrape config #%d", i)
+		}
+	}
+	if data, dataExpected := cfgCopy.marshal(), cfg.marshal(); string(data) != string(dataExpected) {
+		t.Fatalf("unexpected config copy;\ngot\n%s\nwant\n%s", data, dataExpected)
+	}
+}
+
 func TestAddressWithFullURL(t *testing.T) {
 	data := `
 scrape_configs:
@@ -492,6 +583,81 @@ scrape_configs:
   - targets: ["foo"]
 `)
 
+	// Invalid max_scrape_size
+	f(`
+scrape_configs:
+- job_name: x
+  max_scrape_size: foo
+  static_configs:
+  - targets: ["foo"]
+`)
+	f(`
+scrape_configs:
+- job_name: x
+  max_scrape_size: 0
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// max_scrape_interval smaller than scrape_interval
+	f(`
+scrape_configs:
+- job_name: x
+  scrape_interval: 1m
+  max_scrape_interval: 30s
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// scrape_cache_max_age in stream parsing mode
+	f(`
+scrape_configs:
+- job_name: x
+  stream_parse: true
+  scrape_cache_max_age: 5m
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// Unsupported probe_module
+	f(`
+scrape_configs:
+- job_name: x
+  probe_module: dns
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// probe_module in stream parsing mode
+	f(`
+scrape_configs:
+- job_name: x
+  stream_parse: true
+  probe_module: tcp
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// metadata_only in stream parsing mode
+	f(`
+scrape_configs:
+- job_name: x
+  stream_parse: true
+  metadata_only: true
+  static_configs:
+  - targets: ["foo"]
+`)
+
+	// metadata_only with probe_module
+	f(`
+scrape_configs:
+- job_name: x
+  metadata_only: true
+  probe_module: tcp
+  static_configs:
+  - targets: ["foo"]
+`)
+
 	// Missing username in `basic_auth`
 	f(`
 scrape_configs:
@@ -892,6 +1058,7 @@ scrape_configs:
     p: ["x&y", "="]
     xaa:
   proxy_url: http://foo.bar
+  no_proxy: aaa
   static_configs:
   - targets: ["foo.bar", "aaa"]
     labels:
@@ -938,7 +1105,6 @@ scrape_configs:
 			}),
 			AuthConfig:      &promauth.Config{},
 			ProxyAuthConfig: &promauth.Config{},
-			ProxyURL:        proxy.MustNewURL("http://foo.bar"),
 			jobNameOriginal: "foo",
 		},
 		{
@@ -1296,6 +1462,109 @@ scrape_configs:
 		},
 	})
 	f(`
+scrape_configs:
+- job_name: big
+  max_scrape_size: 64MiB
+  static_configs:
+  - targets: ["foo.bar:1234"]
+`, []*ScrapeWork{
+		{
+			ScrapeURL:       "http://foo.bar:1234/metrics",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			HonorTimestamps: true,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:1234",
+				"job":      "big",
+			}),
+			MaxScrapeSize:   64 * 1024 * 1024,
+			jobNameOriginal: "big",
+			AuthConfig:      &promauth.Config{},
+			ProxyAuthConfig: &promauth.Config{},
+		},
+	})
+	f(`
+scrape_configs:
+- job_name: uptime
+  probe_module: http
+  metrics_path: /
+  static_configs:
+  - targets: ["foo.bar"]
+  - targets: ["foo.bar:5432"]
+    labels:
+      __probe_module__: tcp
+`, []*ScrapeWork{
+		{
+			ScrapeURL:       "http://foo.bar:80/",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			HonorTimestamps: true,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:80",
+				"job":      "uptime",
+			}),
+			ProbeModule:     "http",
+			jobNameOriginal: "uptime",
+			AuthConfig:      &promauth.Config{},
+			ProxyAuthConfig: &promauth.Config{},
+		},
+		{
+			ScrapeURL:       "http://foo.bar:5432/",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			HonorTimestamps: true,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:5432",
+				"job":      "uptime",
+			}),
+			ProbeModule:     "tcp",
+			jobNameOriginal: "uptime",
+			AuthConfig:      &promauth.Config{},
+			ProxyAuthConfig: &promauth.Config{},
+		},
+	})
+	f(`
+scrape_configs:
+- job_name: batch
+  staleness_interval: 1h
+  static_configs:
+  - targets: ["foo.bar:1234", "foo.bar:5678"]
+  relabel_configs:
+  - source_labels: [__address__]
+    regex: ".+:5678"
+    target_label: __staleness_interval__
+    replacement: 5m
+`, []*ScrapeWork{
+		{
+			ScrapeURL:       "http://foo.bar:1234/metrics",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			HonorTimestamps: true,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:1234",
+				"job":      "batch",
+			}),
+			StalenessInterval: time.Hour,
+			jobNameOriginal:   "batch",
+			AuthConfig:        &promauth.Config{},
+			ProxyAuthConfig:   &promauth.Config{},
+		},
+		{
+			ScrapeURL:       "http://foo.bar:5678/metrics",
+			ScrapeInterval:  defaultScrapeInterval,
+			ScrapeTimeout:   defaultScrapeTimeout,
+			HonorTimestamps: true,
+			Labels: promutils.NewLabelsFromMap(map[string]string{
+				"instance": "foo.bar:5678",
+				"job":      "batch",
+			}),
+			StalenessInterval: 5 * time.Minute,
+			jobNameOriginal:   "batch",
+			AuthConfig:        &promauth.Config{},
+			ProxyAuthConfig:   &promauth.Config{},
+		},
+	})
+	f(`
 global:
   scrape_timeout: 1d
 scrape_configs:
@@ -110,7 +110,7 @@ func newAPIConfig(sdc *SDConfig, baseDir string) (*apiConfig, error) {
 	if err != nil {
 		return nil, err
 	}
-	c, err := discoveryutils.NewClient(env.ResourceManagerEndpoint, ac, sdc.ProxyURL, proxyAC)
+	c, err := discoveryutils.NewClient(env.ResourceManagerEndpoint, ac, sdc.NoProxy.GetProxyURL(sdc.ProxyURL, env.ResourceManagerEndpoint), proxyAC)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create client for %q: %w", env.ResourceManagerEndpoint, err)
 	}
@@ -222,7 +222,7 @@ func getRefreshTokenFunc(sdc *SDConfig, ac, proxyAC *promauth.Config, env *cloud
 		return nil, fmt.Errorf("unsupported `authentication_method: %q` only `OAuth` and `ManagedIdentity` are supported", authenticationMethod)
 	}
 
-	authClient, err := discoveryutils.NewClient(tokenEndpoint, ac, sdc.ProxyURL, proxyAC)
+	authClient, err := discoveryutils.NewClient(tokenEndpoint, ac, sdc.NoProxy.GetProxyURL(sdc.ProxyURL, tokenEndpoint), proxyAC)
 	if err != nil {
 		return nil, fmt.Errorf("cannot build auth client: %w", err)
 	}
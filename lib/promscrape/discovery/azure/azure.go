@@ -40,6 +40,7 @@ type SDConfig struct {
 
 	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
 	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	NoProxy           *proxy.NoProxy             `yaml:"no_proxy,omitempty"`
 	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
 }
 
@@ -28,6 +28,7 @@ type SDConfig struct {
 	Password          *promauth.Secret           `yaml:"password"`
 	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
 	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	NoProxy           *proxy.NoProxy             `yaml:"no_proxy,omitempty"`
 	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
 	Services          []string                   `yaml:"services,omitempty"`
 	Tags              []string                   `yaml:"tags,omitempty"`
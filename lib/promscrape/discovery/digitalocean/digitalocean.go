@@ -26,6 +26,7 @@ type SDConfig struct {
 	Server            string                     `yaml:"server,omitempty"`
 	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
 	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	NoProxy           *proxy.NoProxy             `yaml:"no_proxy,omitempty"`
 	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
 	Port              int                        `yaml:"port,omitempty"`
 }
@@ -26,6 +26,7 @@ type SDConfig struct {
 
 	HTTPClientConfig  promauth.HTTPClientConfig  `yaml:",inline"`
 	ProxyURL          *proxy.URL                 `yaml:"proxy_url,omitempty"`
+	NoProxy           *proxy.NoProxy             `yaml:"no_proxy,omitempty"`
 	ProxyClientConfig promauth.ProxyClientConfig `yaml:",inline"`
 	// refresh_interval is obtained from `-promscrape.dockerSDCheckInterval` command-line option
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promauth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 	"github.com/VictoriaMetrics/metrics"
 )
 
@@ -76,7 +77,7 @@ func newAPIWatcher(apiServer string, ac *promauth.Config, sdc *SDConfig, swcFunc
 	}
 	selectors := sdc.Selectors
 	attachNodeMetadata := sdc.AttachMetadata.Node
-	proxyURL := sdc.ProxyURL.GetURL()
+	proxyURL := sdc.NoProxy.GetProxyURL(sdc.ProxyURL, apiServer)
 	gw := getGroupWatcher(apiServer, ac, namespaces, selectors, attachNodeMetadata, proxyURL)
 	role := sdc.role()
 	return &apiWatcher{
@@ -145,23 +146,56 @@ func (aw *apiWatcher) setScrapeWorks(uw *urlWatcher, key string, labelss []*prom
 		swosByKey = make(map[string][]interface{})
 		aw.swosByURLWatcher[uw] = swosByKey
 	}
-	aw.swosCount.Add(len(swos) - len(swosByKey[key]))
+	swosPrevLen := len(swosByKey[key])
+	aw.swosCount.Add(len(swos) - swosPrevLen)
 	if len(swos) == 0 {
 		delete(swosByKey, key)
 	} else {
 		swosByKey[key] = swos
 	}
 	aw.swosByURLWatcherLock.Unlock()
+	if len(swos) < swosPrevLen {
+		// Some targets have been removed, for example, the pod has been switched to Succeeded phase.
+		notifyTargetsRemoved()
+	}
 }
 
 func (aw *apiWatcher) removeScrapeWorks(uw *urlWatcher, key string) {
 	aw.swosByURLWatcherLock.Lock()
 	swosByKey := aw.swosByURLWatcher[uw]
+	swosPrevLen := 0
 	if len(swosByKey) > 0 {
-		aw.swosCount.Add(-len(swosByKey[key]))
+		swosPrevLen = len(swosByKey[key])
+		aw.swosCount.Add(-swosPrevLen)
 		delete(swosByKey, key)
 	}
 	aw.swosByURLWatcherLock.Unlock()
+	if swosPrevLen > 0 {
+		notifyTargetsRemoved()
+	}
+}
+
+// targetsRemovedCh is notified when scrape targets are removed because of deleted Kubernetes objects.
+var targetsRemovedCh = make(chan struct{}, 1)
+
+func notifyTargetsRemoved() {
+	if *SDRemovalCheckInterval <= 0 {
+		return
+	}
+	select {
+	case targetsRemovedCh <- struct{}{}:
+	default:
+		// The notification is already pending.
+	}
+}
+
+// TargetsRemovedCh returns a channel, which is notified when scrape targets are removed by kubernetes_sd_configs,
+// for example, when the pod is deleted.
+//
+// This allows stopping scrapers for the removed targets and sending staleness markers for their series
+// without waiting for SDCheckInterval.
+func TargetsRemovedCh() <-chan struct{} {
+	return targetsRemovedCh
 }
 
 func getScrapeWorkObjectsForLabels(swcFunc ScrapeWorkConstructorFunc, labelss []*promutils.Labels) []interface{} {
@@ -215,20 +249,19 @@ type groupWatcher struct {
 	m  map[string]*urlWatcher
 }
 
-func newGroupWatcher(apiServer string, ac *promauth.Config, namespaces []string, selectors []Selector, attachNodeMetadata bool, proxyURL *url.URL) *groupWatcher {
-	var proxy func(*http.Request) (*url.URL, error)
-	if proxyURL != nil {
-		proxy = http.ProxyURL(proxyURL)
+func newGroupWatcher(apiServer string, ac *promauth.Config, namespaces []string, selectors []Selector, attachNodeMetadata bool, proxyURL *proxy.URL) *groupWatcher {
+	tr := &http.Transport{
+		TLSClientConfig:     ac.NewTLSConfig(),
+		TLSHandshakeTimeout: 10 * time.Second,
+		IdleConnTimeout:     *apiServerTimeout,
+		MaxIdleConnsPerHost: 100,
+	}
+	if err := proxyURL.ConfigureTransport(tr, nil); err != nil {
+		logger.Fatalf("cannot configure proxy for kubernetes_sd_config: %s", err)
 	}
 	client := &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig:     ac.NewTLSConfig(),
-			Proxy:               proxy,
-			TLSHandshakeTimeout: 10 * time.Second,
-			IdleConnTimeout:     *apiServerTimeout,
-			MaxIdleConnsPerHost: 100,
-		},
-		Timeout: *apiServerTimeout,
+		Transport: tr,
+		Timeout:   *apiServerTimeout,
 	}
 	return &groupWatcher{
 		apiServer:          apiServer,
@@ -242,9 +275,9 @@ func newGroupWatcher(apiServer string, ac *promauth.Config, namespaces []string,
 	}
 }
 
-func getGroupWatcher(apiServer string, ac *promauth.Config, namespaces []string, selectors []Selector, attachNodeMetadata bool, proxyURL *url.URL) *groupWatcher {
+func getGroupWatcher(apiServer string, ac *promauth.Config, namespaces []string, selectors []Selector, attachNodeMetadata bool, proxyURL *proxy.URL) *groupWatcher {
 	proxyURLStr := "<nil>"
-	if proxyURL != nil {
+	if proxyURL.GetURL() != nil {
 		proxyURLStr = proxyURL.String()
 	}
 	key := fmt.Sprintf("apiServer=%s, namespaces=%s, selectors=%s, attachNodeMetadata=%v, proxyURL=%s, authConfig=%s",
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/metrics"
 )
 
 func TestGetAPIPathsWithNamespaces(t *testing.T) {
@@ -1025,3 +1026,44 @@ func addAPIURLHandler(t *testing.T, mux *http.ServeMux, apiURL string, initObjec
 		_, _ = w.Write(initObjects)
 	})
 }
+
+func TestRemoveScrapeWorksNotifiesTargetsRemoved(t *testing.T) {
+	// Drain pending notifications from other tests
+	select {
+	case <-TargetsRemovedCh():
+	default:
+	}
+	isNotified := func() bool {
+		select {
+		case <-TargetsRemovedCh():
+			return true
+		default:
+			return false
+		}
+	}
+
+	uw := &urlWatcher{}
+	aw := &apiWatcher{
+		swosByURLWatcher: map[*urlWatcher]map[string][]interface{}{
+			uw: {
+				"default/pod-1": {"target-1", "target-2"},
+			},
+		},
+		swosCount: metrics.NewSet().NewCounter("swos_count"),
+	}
+	aw.swosCount.Add(2)
+
+	// Removal of unknown object mustn't trigger notification
+	aw.removeScrapeWorks(uw, "default/pod-2")
+	if isNotified() {
+		t.Fatalf("unexpected notification for unknown object")
+	}
+
+	aw.removeScrapeWorks(uw, "default/pod-1")
+	if !isNotified() {
+		t.Fatalf("expecting notification for removed targets")
+	}
+	if n := aw.swosCount.Get(); n != 0 {
+		t.Fatalf("unexpected swosCount; got %d; want 0", n)
+	}
+}
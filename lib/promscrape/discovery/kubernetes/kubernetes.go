@@ -15,6 +15,12 @@ var SDCheckInterval = flag.Duration("promscrape.kubernetesSDCheckInterval", 30*t
 	"This works only if kubernetes_sd_configs is configured in '-promscrape.config' file. "+
 	"See https://docs.victoriametrics.com/sd_configs.html#kubernetes_sd_configs for details")
 
+// SDRemovalCheckInterval defines the minimum interval between refreshes triggered by removed targets.
+var SDRemovalCheckInterval = flag.Duration("promscrape.kubernetesSDRemovalCheckInterval", time.Second, "The minimum interval between refreshes of targets "+
+	"removed by kubernetes_sd_configs, for example, because of deleted pods. Scrapers for the removed targets are stopped and staleness markers "+
+	"are sent for their series just after the removal instead of waiting for -promscrape.kubernetesSDCheckInterval. "+
+	"Set it to zero for disabling immediate removal of targets. See https://docs.victoriametrics.com/vmagent.html#prometheus-staleness-markers")
+
 // SDConfig represents kubernetes-based service discovery config.
 //
 // See https://prometheus.io/docs/prometheus/latest/configuration/configuration/#kubernetes_sd_config
@@ -29,6 +35,7 @@ type SDConfig struct {
 
 	HTTPClientConfig promauth.HTTPClientConfig `yaml:",inline"`
 	ProxyURL         *proxy.URL                `yaml:"proxy_url,omitempty"`
+	NoProxy          *proxy.NoProxy            `yaml:"no_proxy,omitempty"`
 	Namespaces       Namespaces                `yaml:"namespaces,omitempty"`
 	Selectors        []Selector                `yaml:"selectors,omitempty"`
 	AttachMetadata   AttachMetadata            `yaml:"attach_metadata,omitempty"`
@@ -36,6 +36,25 @@ type server struct {
 	Flavor   struct {
 		ID string `json:"id"`
 	} `json:"flavor"`
+	Image serverImage `json:"image"`
+}
+
+// serverImage is the image the server was booted from.
+//
+// Nova returns an empty string instead of an object for servers booted from volume.
+type serverImage struct {
+	ID string `json:"id"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (si *serverImage) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		// The server has been booted from volume, so it has no image.
+		*si = serverImage{}
+		return nil
+	}
+	type image serverImage
+	return json.Unmarshal(data, (*image)(si))
 }
 
 func parseServersDetail(data []byte) (*serversDetail, error) {
@@ -56,6 +75,9 @@ func addInstanceLabels(servers []server, port int) []*promutils.Labels {
 		commonLabels.Add("__meta_openstack_project_id", server.TenantID)
 		commonLabels.Add("__meta_openstack_user_id", server.UserID)
 		commonLabels.Add("__meta_openstack_instance_flavor", server.Flavor.ID)
+		if len(server.Image.ID) > 0 {
+			commonLabels.Add("__meta_openstack_instance_image", server.Image.ID)
+		}
 		for k, v := range server.Metadata {
 			commonLabels.Add(discoveryutils.SanitizeLabelName("__meta_openstack_tag_"+k), v)
 		}
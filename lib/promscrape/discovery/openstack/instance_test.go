@@ -39,6 +39,7 @@ func Test_addInstanceLabels(t *testing.T) {
 						Flavor: struct {
 							ID string `json:"id"`
 						}{ID: "5"},
+						Image: serverImage{ID: "some-image-id"},
 						Addresses: map[string][]struct {
 							Address string `json:"addr"`
 							Version int    `json:"version"`
@@ -61,6 +62,7 @@ func Test_addInstanceLabels(t *testing.T) {
 					"__meta_openstack_address_pool":    "test",
 					"__meta_openstack_instance_flavor": "5",
 					"__meta_openstack_instance_id":     "10",
+					"__meta_openstack_instance_image":  "some-image-id",
 					"__meta_openstack_instance_name":   "server-1",
 					"__meta_openstack_instance_status": "enabled",
 					"__meta_openstack_private_ip":      "192.168.0.1",
@@ -218,6 +220,7 @@ func Test_parseServersDetail(t *testing.T) {
 						Flavor: struct {
 							ID string `json:"id"`
 						}{ID: "1"},
+						Image:    serverImage{ID: "253f7a69-dc79-4fb2-86f8-9ec92c94107a"},
 						ID:       "c9f68076-01a3-489a-aebe-8b773c71e7f3",
 						TenantID: "d34be4e44f9c444eab9a5ec7b953951f",
 						UserID:   "e55737f142ac42f18093037760656bd7",
@@ -261,3 +264,17 @@ func Test_parseServersDetail(t *testing.T) {
 		})
 	}
 }
+
+func TestParseServersDetailBootFromVolume(t *testing.T) {
+	data := []byte(`{"servers":[{"id":"1","name":"vm","image":"","flavor":{"id":"2"}}]}`)
+	srvd, err := parseServersDetail(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(srvd.Servers) != 1 {
+		t.Fatalf("unexpected number of servers; got %d; want 1", len(srvd.Servers))
+	}
+	if id := srvd.Servers[0].Image.ID; id != "" {
+		t.Fatalf("unexpected image id; got %q; want empty string", id)
+	}
+}
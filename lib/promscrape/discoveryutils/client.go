@@ -99,32 +99,33 @@ func NewClient(apiServer string, ac *promauth.Config, proxyURL *proxy.URL, proxy
 		tlsCfg = ac.NewTLSConfig()
 	}
 
-	var proxyURLFunc func(*http.Request) (*url.URL, error)
-	if pu := proxyURL.GetURL(); pu != nil {
-		proxyURLFunc = http.ProxyURL(pu)
+	tr := &http.Transport{
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   10 * time.Second,
+		MaxIdleConnsPerHost:   *maxConcurrency,
+		ResponseHeaderTimeout: DefaultClientReadTimeout,
+		DialContext:           dialFunc,
+	}
+	if err := proxyURL.ConfigureTransport(tr, proxyAC); err != nil {
+		return nil, err
 	}
-
 	client := &http.Client{
-		Timeout: DefaultClientReadTimeout,
-		Transport: &http.Transport{
-			TLSClientConfig:       tlsCfg,
-			Proxy:                 proxyURLFunc,
-			TLSHandshakeTimeout:   10 * time.Second,
-			MaxIdleConnsPerHost:   *maxConcurrency,
-			ResponseHeaderTimeout: DefaultClientReadTimeout,
-			DialContext:           dialFunc,
-		},
+		Timeout:   DefaultClientReadTimeout,
+		Transport: tr,
+	}
+	blockingTr := &http.Transport{
+		TLSClientConfig:       tlsCfg,
+		TLSHandshakeTimeout:   10 * time.Second,
+		MaxIdleConnsPerHost:   1000,
+		ResponseHeaderTimeout: BlockingClientReadTimeout,
+		DialContext:           dialFunc,
+	}
+	if err := proxyURL.ConfigureTransport(blockingTr, proxyAC); err != nil {
+		return nil, err
 	}
 	blockingClient := &http.Client{
-		Timeout: BlockingClientReadTimeout,
-		Transport: &http.Transport{
-			TLSClientConfig:       tlsCfg,
-			Proxy:                 proxyURLFunc,
-			TLSHandshakeTimeout:   10 * time.Second,
-			MaxIdleConnsPerHost:   1000,
-			ResponseHeaderTimeout: BlockingClientReadTimeout,
-			DialContext:           dialFunc,
-		},
+		Timeout:   BlockingClientReadTimeout,
+		Transport: blockingTr,
 	}
 
 	setHTTPHeaders := func(req *http.Request) {}
@@ -134,7 +135,8 @@ func NewClient(apiServer string, ac *promauth.Config, proxyURL *proxy.URL, proxy
 		}
 	}
 	setHTTPProxyHeaders := func(req *http.Request) {}
-	if proxyAC != nil {
+	if proxyAC != nil && !isTLS && proxyURL.IsHTTPOrHTTPS() {
+		// Proxy headers for TLS requests and for socks5 proxies are set by the transport configured above.
 		setHTTPProxyHeaders = func(req *http.Request) {
 			proxyURL.SetHeaders(proxyAC, req)
 		}
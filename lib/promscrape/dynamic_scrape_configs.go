@@ -0,0 +1,215 @@
+package promscrape
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auditlog"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"gopkg.in/yaml.v2"
+)
+
+var dynamicScrapeConfigsFile = flag.String("promscrape.dynamicScrapeConfigsFile", "", "Optional path to file for persisting scrape configs "+
+	"added via /api/v1/scrape_configs/<job_name> API. Scrape configs from this file are loaded in addition to scrape configs from -promscrape.config. "+
+	"The API is disabled if the flag isn't set. See https://docs.victoriametrics.com/vmagent.html#dynamic-scrape-configs")
+
+// maxScrapeConfigSize is the maximum size of scrape config accepted by ScrapeConfigsHandler.
+const maxScrapeConfigSize = 1024 * 1024
+
+// dynamicScrapeConfigsLock serializes updates of -promscrape.dynamicScrapeConfigsFile.
+var dynamicScrapeConfigsLock sync.Mutex
+
+// loadDynamicScrapeConfigs loads scrape configs from -promscrape.dynamicScrapeConfigsFile.
+func loadDynamicScrapeConfigs() ([]*ScrapeConfig, []byte, error) {
+	path := *dynamicScrapeConfigsFile
+	if path == "" || !fs.IsPathExist(path) {
+		return nil, nil, nil
+	}
+	return loadScrapeConfigFiles("", []string{path})
+}
+
+// ScrapeConfigsHandler processes requests to /api/v1/scrape_configs/<jobName>.
+//
+// POST request adds or replaces the scrape config with the given jobName, DELETE request removes it,
+// while GET request with empty jobName returns all the scrape configs added via the API.
+// Changes are persisted to -promscrape.dynamicScrapeConfigsFile and are applied before returning the response.
+//
+// See https://docs.victoriametrics.com/vmagent.html#dynamic-scrape-configs
+func ScrapeConfigsHandler(w http.ResponseWriter, r *http.Request, jobName string) error {
+	if *dynamicScrapeConfigsFile == "" {
+		return fmt.Errorf("the API is disabled; set -promscrape.dynamicScrapeConfigsFile command-line flag for enabling it")
+	}
+	if jobName == "" {
+		if r.Method != http.MethodGet {
+			return fmt.Errorf("missing job_name in the path %q", r.URL.Path)
+		}
+		dynamicScrapeConfigsLock.Lock()
+		scs, err := readDynamicScrapeConfigs()
+		dynamicScrapeConfigsLock.Unlock()
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(scs)
+		if err != nil {
+			return fmt.Errorf("BUG: cannot marshal scrape configs: %w", err)
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(data)
+		return nil
+	}
+
+	var ae *auditlog.Event
+	var updateFunc func(scs []yaml.MapSlice) ([]yaml.MapSlice, error)
+	switch r.Method {
+	case http.MethodPost:
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			return fmt.Errorf("unsupported Content-Type for scrape config: %q; use `Content-Type: application/yaml`", r.Header.Get("Content-Type"))
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxScrapeConfigSize+1))
+		if err != nil {
+			return fmt.Errorf("cannot read scrape config: %w", err)
+		}
+		if len(data) > maxScrapeConfigSize {
+			return fmt.Errorf("too big scrape config; it mustn't exceed %d bytes", maxScrapeConfigSize)
+		}
+		sc, err := parseDynamicScrapeConfig(data, jobName)
+		if err != nil {
+			return err
+		}
+		ae = auditlog.NewEvent(r, "scrape_config_add")
+		updateFunc = func(scs []yaml.MapSlice) ([]yaml.MapSlice, error) {
+			if idx := getDynamicScrapeConfigIdx(scs, jobName); idx >= 0 {
+				scs[idx] = sc
+				return scs, nil
+			}
+			return append(scs, sc), nil
+		}
+	case http.MethodDelete:
+		ae = auditlog.NewEvent(r, "scrape_config_delete")
+		updateFunc = func(scs []yaml.MapSlice) ([]yaml.MapSlice, error) {
+			idx := getDynamicScrapeConfigIdx(scs, jobName)
+			if idx < 0 {
+				return nil, &httpserver.ErrorWithStatusCode{
+					Err:        fmt.Errorf("cannot find scrape config with job_name=%q added via API", jobName),
+					StatusCode: http.StatusNotFound,
+				}
+			}
+			return append(scs[:idx], scs[idx+1:]...), nil
+		}
+	default:
+		return fmt.Errorf("unsupported method %q; use POST for adding scrape config and DELETE for removing it", r.Method)
+	}
+	ae.Target = jobName
+	err := updateDynamicScrapeConfigs(r, updateFunc)
+	auditlog.Log(ae, err)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	configTracker.WriteVersion(w)
+	return nil
+}
+
+// parseDynamicScrapeConfig parses and validates scrape config from data for the given jobName.
+//
+// The scrape config is returned as yaml.MapSlice in order to persist it as is.
+// Otherwise secrets would be lost, since they are hidden when marshaling ScrapeConfig.
+func parseDynamicScrapeConfig(data []byte, jobName string) (yaml.MapSlice, error) {
+	var sc ScrapeConfig
+	if err := yaml.UnmarshalStrict(data, &sc); err != nil {
+		return nil, fmt.Errorf("cannot parse scrape config: %w", err)
+	}
+	if sc.JobName != "" && sc.JobName != jobName {
+		return nil, fmt.Errorf("job_name=%q in scrape config doesn't match job_name=%q from the path", sc.JobName, jobName)
+	}
+	var ms yaml.MapSlice
+	if err := yaml.Unmarshal(data, &ms); err != nil {
+		return nil, fmt.Errorf("cannot parse scrape config: %w", err)
+	}
+	if sc.JobName == "" {
+		ms = append(yaml.MapSlice{{Key: "job_name", Value: jobName}}, ms...)
+	}
+	return ms, nil
+}
+
+func getDynamicScrapeConfigIdx(scs []yaml.MapSlice, jobName string) int {
+	for i, sc := range scs {
+		for _, item := range sc {
+			if item.Key == "job_name" && item.Value == jobName {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// readDynamicScrapeConfigs reads scrape configs from -promscrape.dynamicScrapeConfigsFile.
+//
+// dynamicScrapeConfigsLock must be locked by the caller.
+func readDynamicScrapeConfigs() ([]yaml.MapSlice, error) {
+	path := *dynamicScrapeConfigsFile
+	if !fs.IsPathExist(path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var scs []yaml.MapSlice
+	if err := yaml.Unmarshal(data, &scs); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return scs, nil
+}
+
+// updateDynamicScrapeConfigs updates scrape configs at -promscrape.dynamicScrapeConfigsFile with updateFunc and applies them.
+//
+// The previous file contents is restored if the updated scrape configs cannot be applied.
+func updateDynamicScrapeConfigs(r *http.Request, updateFunc func(scs []yaml.MapSlice) ([]yaml.MapSlice, error)) error {
+	dynamicScrapeConfigsLock.Lock()
+	defer dynamicScrapeConfigsLock.Unlock()
+
+	path := *dynamicScrapeConfigsFile
+	var prevData []byte
+	if fs.IsPathExist(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		prevData = data
+	}
+	var scs []yaml.MapSlice
+	if err := yaml.Unmarshal(prevData, &scs); err != nil {
+		return fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	scs, err := updateFunc(scs)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(scs)
+	if err != nil {
+		return fmt.Errorf("BUG: cannot marshal scrape configs: %w", err)
+	}
+	if err := fs.WriteFileAtomically(path, data, true); err != nil {
+		return fmt.Errorf("cannot save scrape configs: %w", err)
+	}
+	reloadErr := configTracker.Reload(r.Context())
+	if reloadErr == nil {
+		return nil
+	}
+	if prevData != nil {
+		err = fs.WriteFileAtomically(path, prevData, true)
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot apply scrape configs: %s; cannot restore the previous contents of %q: %w", reloadErr, path, err)
+	}
+	return fmt.Errorf("cannot apply scrape configs: %w", reloadErr)
+}
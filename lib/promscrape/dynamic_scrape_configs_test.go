@@ -0,0 +1,120 @@
+package promscrape
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseDynamicScrapeConfigSuccess(t *testing.T) {
+	f := func(data, jobName, resultExpected string) {
+		t.Helper()
+		ms, err := parseDynamicScrapeConfig([]byte(data), jobName)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		result, err := yaml.Marshal(ms)
+		if err != nil {
+			t.Fatalf("cannot marshal scrape config: %s", err)
+		}
+		if string(result) != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+	}
+	// job_name is added from the path
+	f(`
+static_configs:
+- targets: [foo:1234]
+`, "foo", `job_name: foo
+static_configs:
+- targets:
+  - foo:1234
+`)
+	// secrets are preserved
+	f(`
+job_name: bar
+basic_auth:
+  username: user
+  password: secret
+static_configs:
+- targets: [bar]
+`, "bar", `job_name: bar
+basic_auth:
+  username: user
+  password: secret
+static_configs:
+- targets:
+  - bar
+`)
+}
+
+func TestParseDynamicScrapeConfigFailure(t *testing.T) {
+	f := func(data, jobName string) {
+		t.Helper()
+		if _, err := parseDynamicScrapeConfig([]byte(data), jobName); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+	// invalid yaml
+	f(`foo: [bar`, "foo")
+	// unknown field
+	f(`foo: bar`, "foo")
+	// job_name mismatch
+	f(`job_name: bar`, "foo")
+}
+
+func TestGetDynamicScrapeConfigIdx(t *testing.T) {
+	var scs []yaml.MapSlice
+	if err := yaml.Unmarshal([]byte(`
+- job_name: foo
+- job_name: bar
+`), &scs); err != nil {
+		t.Fatalf("cannot parse scrape configs: %s", err)
+	}
+	f := func(jobName string, idxExpected int) {
+		t.Helper()
+		if idx := getDynamicScrapeConfigIdx(scs, jobName); idx != idxExpected {
+			t.Fatalf("unexpected index for job_name=%q; got %d; want %d", jobName, idx, idxExpected)
+		}
+	}
+	f("foo", 0)
+	f("bar", 1)
+	f("baz", -1)
+}
+
+func TestLoadConfigWithDynamicScrapeConfigs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dynamic.yml")
+	if err := os.WriteFile(path, []byte(`
+- job_name: dynamic
+  static_configs:
+  - targets: [foo:1234]
+`), 0644); err != nil {
+		t.Fatalf("cannot write %q: %s", path, err)
+	}
+	defer func(v string) {
+		*dynamicScrapeConfigsFile = v
+	}(*dynamicScrapeConfigsFile)
+	*dynamicScrapeConfigsFile = path
+
+	cfg, _, err := loadConfig("testdata/prometheus.yml")
+	if err != nil {
+		t.Fatalf("cannot load config: %s", err)
+	}
+	jobNames := cfg.getJobNames()
+	if len(jobNames) == 0 || jobNames[len(jobNames)-1] != "dynamic" {
+		t.Fatalf("missing job from -promscrape.dynamicScrapeConfigsFile in %q", jobNames)
+	}
+
+	// Duplicate job names must be rejected
+	if err := os.WriteFile(path, []byte(`
+- job_name: dynamic
+- job_name: dynamic
+`), 0644); err != nil {
+		t.Fatalf("cannot write %q: %s", path, err)
+	}
+	if _, _, err := loadConfig("testdata/prometheus.yml"); err == nil {
+		t.Fatalf("expecting non-nil error for duplicate job names")
+	}
+}
@@ -0,0 +1,255 @@
+package promscrape
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/awsapi"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
+)
+
+var (
+	fileSDS3Region = flag.String("promscrape.fileSD.s3Region", "", "Optional AWS region for reading s3:// files from 'file_sd_configs'. "+
+		"By default the region is obtained from AWS_REGION env var or from instance metadata. "+
+		"See https://docs.victoriametrics.com/sd_configs.html#file_sd_configs")
+	fileSDS3Endpoint = flag.String("promscrape.fileSD.s3Endpoint", "", "Optional S3 endpoint for reading s3:// files from 'file_sd_configs', e.g. http://minio:9000 . "+
+		"By default https://<bucket>.s3.<region>.amazonaws.com is used. See https://docs.victoriametrics.com/sd_configs.html#file_sd_configs")
+)
+
+// isFileSDURL returns true if path is http://, https:// or s3:// url.
+func isFileSDURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "s3://")
+}
+
+// readFileSDSource reads the contents of `file_sd_configs` source at the given path.
+//
+// The path may be local file path, http://, https:// or s3:// url.
+func readFileSDSource(path string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		return readFileSDHTTP(path)
+	case strings.HasPrefix(path, "s3://"):
+		return readFileSDS3(path)
+	default:
+		return os.ReadFile(path)
+	}
+}
+
+func readFileSDHTTP(path string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	return doFileSDRequest(req)
+}
+
+func readFileSDS3(path string) ([]byte, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse s3 url: %w", err)
+	}
+	bucket := u.Host
+	key := strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("s3 url must have s3://<bucket>/<key> format")
+	}
+	cfg, err := getFileSDS3Config()
+	if err != nil {
+		return nil, err
+	}
+	objectURL := getS3ObjectURL(*fileSDS3Endpoint, cfg.GetRegion(), bucket, key)
+	req, err := http.NewRequest(http.MethodGet, objectURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	if err := cfg.SignRequest(req, awsapi.HashHex(nil)); err != nil {
+		return nil, fmt.Errorf("cannot sign request to %q: %w", objectURL, err)
+	}
+	return doFileSDRequest(req)
+}
+
+// getS3ObjectURL returns url for reading the given key from the given bucket.
+//
+// Path-style url is returned if endpoint is set, since S3-compatible storage systems usually do not support virtual-hosted-style urls.
+func getS3ObjectURL(endpoint, region, bucket, key string) string {
+	if endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+}
+
+var (
+	fileSDS3Cfg     *awsapi.Config
+	fileSDS3CfgErr  error
+	fileSDS3CfgOnce sync.Once
+)
+
+func getFileSDS3Config() (*awsapi.Config, error) {
+	fileSDS3CfgOnce.Do(func() {
+		fileSDS3Cfg, fileSDS3CfgErr = awsapi.NewConfig("", "", *fileSDS3Region, "", "", "", "s3")
+		if fileSDS3CfgErr != nil {
+			fileSDS3CfgErr = fmt.Errorf("cannot initialize AWS config for reading s3:// files: %w", fileSDS3CfgErr)
+		}
+	})
+	return fileSDS3Cfg, fileSDS3CfgErr
+}
+
+var fileSDClient = &http.Client{
+	Timeout: time.Minute,
+}
+
+func doFileSDRequest(req *http.Request) ([]byte, error) {
+	resp, err := fileSDClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %q: %w", req.URL.Redacted(), err)
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response from %q: %w", req.URL.Redacted(), err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code for %q; got %d; want %d; response body: %q", req.URL.Redacted(), resp.StatusCode, http.StatusOK, data)
+	}
+	return data, nil
+}
+
+// validateStaticConfigs validates target groups loaded from `file_sd_configs` source.
+func validateStaticConfigs(stcs []StaticConfig) error {
+	for i := range stcs {
+		stc := &stcs[i]
+		if len(stc.Targets) == 0 {
+			return fmt.Errorf("target group #%d has no targets; each target group must contain non-empty `targets` list", i+1)
+		}
+		for j, target := range stc.Targets {
+			if target == "" {
+				return fmt.Errorf("target group #%d contains empty target at position %d", i+1, j+1)
+			}
+			if strings.ContainsAny(target, " \t\n") {
+				return fmt.Errorf("target group #%d contains target %q with whitespace at position %d", i+1, target, j+1)
+			}
+		}
+		if stc.Labels == nil {
+			continue
+		}
+		for _, label := range stc.Labels.GetLabels() {
+			if !isValidLabelName(label.Name) {
+				return fmt.Errorf("target group #%d contains invalid label name %q; label names must match [a-zA-Z_][a-zA-Z0-9_]*", i+1, label.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func isValidLabelName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// fileSDSource holds the last successfully loaded contents for `file_sd_configs` source.
+type fileSDSource struct {
+	// lastRefreshTimestamp is unix timestamp in seconds for the last successful refresh.
+	//
+	// It must be accessed atomically.
+	lastRefreshTimestamp uint64
+
+	refreshes     *metrics.Counter
+	refreshErrors *metrics.Counter
+	updates       *metrics.Counter
+
+	// mu protects checksum and stcs.
+	mu       sync.Mutex
+	checksum uint64
+	stcs     []StaticConfig
+}
+
+var (
+	fileSDSourcesLock sync.Mutex
+	fileSDSources     = make(map[string]*fileSDSource)
+)
+
+func getFileSDSource(path string) *fileSDSource {
+	fileSDSourcesLock.Lock()
+	defer fileSDSourcesLock.Unlock()
+
+	src := fileSDSources[path]
+	if src != nil {
+		return src
+	}
+	source := path
+	if isFileSDURL(path) {
+		if u, err := url.Parse(path); err == nil {
+			source = u.Redacted()
+		}
+	}
+	src = &fileSDSource{
+		refreshes:     metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_file_sd_refreshes_total{source=%q}`, source)),
+		refreshErrors: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_file_sd_refresh_errors_total{source=%q}`, source)),
+		updates:       metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_file_sd_updates_total{source=%q}`, source)),
+	}
+	metrics.GetOrCreateGauge(fmt.Sprintf(`vm_promscrape_file_sd_last_successful_refresh_timestamp_seconds{source=%q}`, source), func() float64 {
+		return float64(atomic.LoadUint64(&src.lastRefreshTimestamp))
+	})
+	fileSDSources[path] = src
+	return src
+}
+
+// loadFileSDStaticConfigs loads target groups from `file_sd_configs` source at the given path.
+//
+// The previously loaded target groups are returned without parsing if the source contents didn't change since the last load.
+func loadFileSDStaticConfigs(path string) ([]StaticConfig, error) {
+	src := getFileSDSource(path)
+	src.refreshes.Inc()
+	stcs, err := src.load(path)
+	if err != nil {
+		src.refreshErrors.Inc()
+		return nil, err
+	}
+	atomic.StoreUint64(&src.lastRefreshTimestamp, uint64(time.Now().Unix()))
+	return stcs, nil
+}
+
+func (src *fileSDSource) load(path string) ([]StaticConfig, error) {
+	data, err := readFileSDSource(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read `static_configs` from %q: %w", path, err)
+	}
+	checksum := xxhash.Sum64(data)
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+
+	if src.stcs != nil && src.checksum == checksum {
+		return src.stcs, nil
+	}
+	stcs, err := parseStaticConfigs(data, path)
+	if err != nil {
+		return nil, err
+	}
+	if src.stcs != nil {
+		logger.Infof("found changes in `file_sd_configs` source %q", path)
+	}
+	src.updates.Inc()
+	src.checksum = checksum
+	src.stcs = stcs
+	return stcs, nil
+}
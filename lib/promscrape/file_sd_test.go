@@ -0,0 +1,141 @@
+package promscrape
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseStaticConfigsSuccess(t *testing.T) {
+	f := func(data string, targetsExpected int) {
+		t.Helper()
+		stcs, err := parseStaticConfigs([]byte(data), "test")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		targets := 0
+		for _, stc := range stcs {
+			targets += len(stc.Targets)
+		}
+		if targets != targetsExpected {
+			t.Fatalf("unexpected number of targets; got %d; want %d", targets, targetsExpected)
+		}
+	}
+	f(``, 0)
+	f(`[]`, 0)
+	f(`[{"targets":["foo:1234","bar:5678"],"labels":{"job":"foo","__scheme__":"https"}}]`, 2)
+	f(`
+- targets: [foo:1234]
+- targets: [bar:5678, baz:9012]
+  labels:
+    env: prod
+`, 3)
+}
+
+func TestParseStaticConfigsFailure(t *testing.T) {
+	f := func(data, errExpected string) {
+		t.Helper()
+		stcs, err := parseStaticConfigs([]byte(data), "test")
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if stcs != nil {
+			t.Fatalf("unexpected non-nil static configs: %#v", stcs)
+		}
+		if !strings.Contains(err.Error(), errExpected) {
+			t.Fatalf("unexpected error; got %q; want it to contain %q", err, errExpected)
+		}
+	}
+
+	// invalid format
+	f(`{"targets":["foo:1234"]}`, "the file must contain a list of target groups")
+	f(`[{"target":["foo:1234"]}]`, "the file must contain a list of target groups")
+
+	// missing targets
+	f(`[{"labels":{"job":"foo"}}]`, "target group #1 has no targets")
+	f(`
+- targets: [foo:1234]
+- targets: []
+`, "target group #2 has no targets")
+
+	// invalid targets
+	f(`[{"targets":["foo:1234",""]}]`, "target group #1 contains empty target at position 2")
+	f(`[{"targets":["foo 1234"]}]`, `target group #1 contains target "foo 1234" with whitespace`)
+
+	// invalid label names
+	f(`[{"targets":["foo:1234"],"labels":{"foo-bar":"baz"}}]`, `target group #1 contains invalid label name "foo-bar"`)
+	f(`[{"targets":["foo:1234"],"labels":{"1foo":"baz"}}]`, `target group #1 contains invalid label name "1foo"`)
+}
+
+func TestLoadFileSDStaticConfigsHTTP(t *testing.T) {
+	var data atomic.Value
+	data.Store(`[{"targets":["foo:1234"]}]`)
+	var statusCode int32 = http.StatusOK
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&statusCode)))
+		fmt.Fprintf(w, "%s", data.Load().(string))
+	}))
+	defer s.Close()
+
+	path := s.URL + "/targets.json"
+	src := getFileSDSource(path)
+	f := func(targetsExpected int, refreshes, refreshErrors, updates uint64) {
+		t.Helper()
+		stcs, err := loadFileSDStaticConfigs(path)
+		if targetsExpected < 0 {
+			if err == nil {
+				t.Fatalf("expecting non-nil error")
+			}
+		} else {
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(stcs) != 1 || len(stcs[0].Targets) != targetsExpected {
+				t.Fatalf("unexpected static configs: %#v", stcs)
+			}
+		}
+		if n := src.refreshes.Get(); n != refreshes {
+			t.Fatalf("unexpected number of refreshes; got %d; want %d", n, refreshes)
+		}
+		if n := src.refreshErrors.Get(); n != refreshErrors {
+			t.Fatalf("unexpected number of refresh errors; got %d; want %d", n, refreshErrors)
+		}
+		if n := src.updates.Get(); n != updates {
+			t.Fatalf("unexpected number of updates; got %d; want %d", n, updates)
+		}
+	}
+
+	// initial load
+	f(1, 1, 0, 1)
+
+	// unchanged contents
+	f(1, 2, 0, 1)
+
+	// changed contents
+	data.Store(`[{"targets":["foo:1234","bar:5678"]}]`)
+	f(2, 3, 0, 2)
+
+	// invalid contents
+	data.Store(`[{"targets":[]}]`)
+	f(-1, 4, 1, 2)
+
+	// unexpected status code
+	atomic.StoreInt32(&statusCode, http.StatusNotFound)
+	f(-1, 5, 2, 2)
+}
+
+func TestGetS3ObjectURL(t *testing.T) {
+	f := func(endpoint, region, bucket, key, resultExpected string) {
+		t.Helper()
+		result := getS3ObjectURL(endpoint, region, bucket, key)
+		if result != resultExpected {
+			t.Fatalf("unexpected url; got %q; want %q", result, resultExpected)
+		}
+	}
+	f("", "us-east-1", "bucket", "path/to/targets.json", "https://bucket.s3.us-east-1.amazonaws.com/path/to/targets.json")
+	f("http://minio:9000", "us-east-1", "bucket", "targets.yml", "http://minio:9000/bucket/targets.yml")
+	f("http://minio:9000/", "us-east-1", "bucket", "targets.yml", "http://minio:9000/bucket/targets.yml")
+}
@@ -0,0 +1,240 @@
+package promscrape
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// metricMetadata contains HELP, TYPE and UNIT for a metric exposed by scrape targets.
+//
+// See https://github.com/OpenObservability/OpenMetrics/blob/main/specification/OpenMetrics.md#metricfamily
+type metricMetadata struct {
+	Type string
+	Help string
+	Unit string
+}
+
+// parseMetricMetadata appends metadata from `# HELP`, `# TYPE` and `# UNIT` comments in s to dst and returns the result.
+//
+// The result is keyed by metric name.
+func parseMetricMetadata(dst map[string]*metricMetadata, s string) map[string]*metricMetadata {
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		var line string
+		if n < 0 {
+			line = s
+			s = ""
+		} else {
+			line = s[:n]
+			s = s[n+1:]
+		}
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimLeft(line[1:], " \t")
+		n = strings.IndexAny(line, " \t")
+		if n < 0 {
+			continue
+		}
+		kind := line[:n]
+		if kind != "HELP" && kind != "TYPE" && kind != "UNIT" {
+			continue
+		}
+		line = strings.TrimLeft(line[n+1:], " \t")
+		metricName := line
+		value := ""
+		if n := strings.IndexAny(line, " \t"); n >= 0 {
+			metricName = line[:n]
+			value = strings.TrimLeft(line[n+1:], " \t")
+		}
+		if metricName == "" {
+			continue
+		}
+		mm := dst[metricName]
+		if mm == nil {
+			mm = &metricMetadata{}
+			dst[metricName] = mm
+		}
+		switch kind {
+		case "HELP":
+			mm.Help = unescapeHelp(value)
+		case "TYPE":
+			mm.Type = value
+		case "UNIT":
+			mm.Unit = value
+		}
+	}
+	return dst
+}
+
+// unescapeHelp unescapes `\\` and `\n` sequences in HELP text according to Prometheus text exposition format.
+func unescapeHelp(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			sb.WriteByte('\n')
+		case '\\':
+			sb.WriteByte('\\')
+		default:
+			sb.WriteByte('\\')
+			sb.WriteByte(s[i])
+		}
+	}
+	return sb.String()
+}
+
+// metadataStorage holds metric metadata collected from targets with `metadata_only: true` option.
+type metadataStorage struct {
+	mu sync.Mutex
+
+	// m contains deadlines in unix milliseconds for metadata entries per each metric name.
+	m map[string]map[metricMetadata]int64
+}
+
+var metadataStorageGlobal = &metadataStorage{
+	m: make(map[string]map[metricMetadata]int64),
+}
+
+var _ = metrics.NewGauge(`vm_promscrape_metadata_entries`, func() float64 {
+	return float64(metadataStorageGlobal.Len())
+})
+
+// Update registers metadata in mms, which remains available until the given deadline in unix milliseconds.
+func (ms *metadataStorage) Update(mms map[string]*metricMetadata, deadline int64) {
+	ms.mu.Lock()
+	for metricName, mm := range mms {
+		entries := ms.m[metricName]
+		if entries == nil {
+			entries = make(map[metricMetadata]int64)
+			ms.m[metricName] = entries
+		}
+		if entries[*mm] < deadline {
+			entries[*mm] = deadline
+		}
+	}
+	ms.mu.Unlock()
+}
+
+// Len returns the number of metadata entries in ms.
+func (ms *metadataStorage) Len() int {
+	ms.mu.Lock()
+	n := 0
+	for _, entries := range ms.m {
+		n += len(entries)
+	}
+	ms.mu.Unlock()
+	return n
+}
+
+// Get returns metadata entries for the given metricName, which are active at currentTime in unix milliseconds.
+//
+// Metadata for all the metrics is returned if metricName is empty.
+// The number of returned metrics is limited by limit if it is positive.
+func (ms *metadataStorage) Get(metricName string, limit int, currentTime int64) map[string][]metricMetadata {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	// Remove expired entries.
+	for name, entries := range ms.m {
+		for mm, deadline := range entries {
+			if deadline < currentTime {
+				delete(entries, mm)
+			}
+		}
+		if len(entries) == 0 {
+			delete(ms.m, name)
+		}
+	}
+
+	var names []string
+	if metricName != "" {
+		if _, ok := ms.m[metricName]; ok {
+			names = append(names, metricName)
+		}
+	} else {
+		for name := range ms.m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	result := make(map[string][]metricMetadata, len(names))
+	for _, name := range names {
+		var mms []metricMetadata
+		for mm := range ms.m[name] {
+			mms = append(mms, mm)
+		}
+		sort.Slice(mms, func(i, j int) bool {
+			a, b := &mms[i], &mms[j]
+			if a.Type != b.Type {
+				return a.Type < b.Type
+			}
+			if a.Help != b.Help {
+				return a.Help < b.Help
+			}
+			return a.Unit < b.Unit
+		})
+		result[name] = mms
+	}
+	return result
+}
+
+// storeMetadata stores metric metadata from the scraped body for sw with `metadata_only: true` option.
+func (sw *scrapeWork) storeMetadata(body string, realTimestamp int64) {
+	mms := parseMetricMetadata(make(map[string]*metricMetadata), body)
+	// Keep the metadata until the target misses a few consecutive scrapes.
+	deadline := realTimestamp + 3*sw.Config.ScrapeInterval.Milliseconds()
+	metadataStorageGlobal.Update(mms, deadline)
+	metadataScraped.Add(len(mms))
+}
+
+var metadataScraped = metrics.NewCounter(`vm_promscrape_metadata_scraped_total`)
+
+// WriteMetadata writes metric metadata collected from targets with `metadata_only: true` option to w
+// in the format compatible with https://prometheus.io/docs/prometheus/latest/querying/api/#querying-metric-metadata
+//
+// Metadata is returned only for the given metricName if it isn't empty.
+// The number of returned metrics is limited by limit if it is positive.
+func WriteMetadata(w io.Writer, metricName string, limit int) {
+	currentTime := time.Now().UnixNano() / 1e6
+	result := metadataStorageGlobal.Get(metricName, limit, currentTime)
+	names := make([]string, 0, len(result))
+	for name := range result {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintf(w, `{"status":"success","data":{`)
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintf(w, `,`)
+		}
+		fmt.Fprintf(w, `%q:[`, name)
+		for j, mm := range result[name] {
+			if j > 0 {
+				fmt.Fprintf(w, `,`)
+			}
+			fmt.Fprintf(w, `{"type":%q,"help":%q,"unit":%q}`, mm.Type, mm.Help, mm.Unit)
+		}
+		fmt.Fprintf(w, `]`)
+	}
+	fmt.Fprintf(w, `}}`)
+}
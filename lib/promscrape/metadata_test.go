@@ -0,0 +1,97 @@
+package promscrape
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestParseMetricMetadata(t *testing.T) {
+	f := func(s string, resultExpected map[string]*metricMetadata) {
+		t.Helper()
+		result := parseMetricMetadata(make(map[string]*metricMetadata), s)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+	}
+	f("", map[string]*metricMetadata{})
+	f(`foo 123`, map[string]*metricMetadata{})
+	f(`# some comment`, map[string]*metricMetadata{})
+	f(`# HELP`, map[string]*metricMetadata{})
+	f(`
+# HELP foo_total The number of foos. Escaped: \\ and \n
+# TYPE foo_total counter
+foo_total{bar="baz"} 123
+#TYPE bar gauge
+# UNIT bar_seconds seconds
+# HELP baz
+`, map[string]*metricMetadata{
+		"foo_total": {
+			Type: "counter",
+			Help: "The number of foos. Escaped: \\ and \n",
+		},
+		"bar": {
+			Type: "gauge",
+		},
+		"bar_seconds": {
+			Unit: "seconds",
+		},
+		"baz": {},
+	})
+}
+
+func TestMetadataStorage(t *testing.T) {
+	ms := &metadataStorage{
+		m: make(map[string]map[metricMetadata]int64),
+	}
+	ms.Update(map[string]*metricMetadata{
+		"foo": {Type: "counter", Help: "foo help"},
+		"bar": {Type: "gauge", Help: "bar help"},
+	}, 100)
+	ms.Update(map[string]*metricMetadata{
+		"foo": {Type: "counter", Help: "another foo help"},
+	}, 200)
+	if n := ms.Len(); n != 3 {
+		t.Fatalf("unexpected number of entries; got %d; want 3", n)
+	}
+
+	f := func(metricName string, limit int, currentTime int64, resultExpected map[string][]metricMetadata) {
+		t.Helper()
+		result := ms.Get(metricName, limit, currentTime)
+		if !reflect.DeepEqual(result, resultExpected) {
+			t.Fatalf("unexpected result;\ngot\n%v\nwant\n%v", result, resultExpected)
+		}
+	}
+	f("", 0, 50, map[string][]metricMetadata{
+		"bar": {{Type: "gauge", Help: "bar help"}},
+		"foo": {{Type: "counter", Help: "another foo help"}, {Type: "counter", Help: "foo help"}},
+	})
+	f("", 1, 50, map[string][]metricMetadata{
+		"bar": {{Type: "gauge", Help: "bar help"}},
+	})
+	f("foo", 0, 50, map[string][]metricMetadata{
+		"foo": {{Type: "counter", Help: "another foo help"}, {Type: "counter", Help: "foo help"}},
+	})
+	f("missing", 0, 50, map[string][]metricMetadata{})
+
+	// Expired entries must be removed
+	f("", 0, 150, map[string][]metricMetadata{
+		"foo": {{Type: "counter", Help: "another foo help"}},
+	})
+	if n := ms.Len(); n != 1 {
+		t.Fatalf("unexpected number of entries after expiration; got %d; want 1", n)
+	}
+}
+
+func TestWriteMetadata(t *testing.T) {
+	metadataStorageGlobal.Update(map[string]*metricMetadata{
+		"TestWriteMetadata_foo": {Type: "counter", Help: `foo "help"`},
+	}, 1<<62)
+	var bb bytes.Buffer
+	WriteMetadata(&bb, "TestWriteMetadata_foo", 0)
+	result := bb.String()
+	resultExpected := `{"status":"success","data":{"TestWriteMetadata_foo":[{"type":"counter","help":"foo \"help\"","unit":""}]}}`
+	if result != resultExpected {
+		t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", result, resultExpected)
+	}
+}
@@ -0,0 +1,175 @@
+package promscrape
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Supported probe modules.
+//
+// See https://docs.victoriametrics.com/vmagent.html#probing
+const (
+	probeModuleHTTP = "http"
+	probeModuleTCP  = "tcp"
+	probeModuleICMP = "icmp"
+)
+
+func checkProbeModule(module string) error {
+	switch module {
+	case "", probeModuleHTTP, probeModuleTCP, probeModuleICMP:
+		return nil
+	default:
+		return fmt.Errorf("unsupported probe module %q; supported values: %s, %s, %s", module, probeModuleHTTP, probeModuleTCP, probeModuleICMP)
+	}
+}
+
+// prober checks the availability of the scrape target instead of scraping metrics from it.
+//
+// It returns probe_success and probe_duration_seconds metrics in Prometheus text exposition format,
+// so they are processed in the same way as the metrics scraped from regular targets.
+type prober struct {
+	module  string
+	timeout time.Duration
+
+	// tcpAddr is the host:port to connect to in tcp module.
+	tcpAddr string
+
+	// host is the host to ping in icmp module.
+	host string
+
+	// readData is used for sending http requests in http module.
+	readData func(dst []byte) ([]byte, error)
+
+	probesFailed *metrics.Counter
+}
+
+func newProber(sw *ScrapeWork, c *client) *prober {
+	p := &prober{
+		module:       sw.ProbeModule,
+		timeout:      sw.ScrapeTimeout,
+		readData:     c.ReadData,
+		probesFailed: metrics.GetOrCreateCounter(fmt.Sprintf(`vm_promscrape_probes_failed_total{module=%q}`, sw.ProbeModule)),
+	}
+	if u, err := url.Parse(sw.ScrapeURL); err == nil {
+		p.tcpAddr = addMissingPort(u.Host, u.Scheme == "https")
+		p.host = u.Hostname()
+	}
+	return p
+}
+
+// ReadData probes the target and appends the probe results to dst.
+func (p *prober) ReadData(dst []byte) ([]byte, error) {
+	startTime := time.Now()
+	err := p.probe(startTime.Add(p.timeout))
+	duration := time.Since(startTime).Seconds()
+	success := 1
+	if err != nil {
+		p.probesFailed.Inc()
+		success = 0
+	}
+	dst = append(dst, "probe_success "...)
+	dst = strconv.AppendInt(dst, int64(success), 10)
+	dst = append(dst, "\nprobe_duration_seconds "...)
+	dst = strconv.AppendFloat(dst, duration, 'f', -1, 64)
+	dst = append(dst, '\n')
+	return dst, nil
+}
+
+func (p *prober) probe(deadline time.Time) error {
+	switch p.module {
+	case probeModuleHTTP:
+		_, err := p.readData(nil)
+		return err
+	case probeModuleTCP:
+		conn, err := net.DialTimeout("tcp", p.tcpAddr, time.Until(deadline))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	case probeModuleICMP:
+		return probeICMP(p.host, deadline)
+	default:
+		return fmt.Errorf("BUG: unexpected probe module %q", p.module)
+	}
+}
+
+// probeICMP sends ICMP echo request to host and waits for the reply until the deadline.
+//
+// It requires permissions for opening raw sockets such as CAP_NET_RAW capability on Linux.
+func probeICMP(host string, deadline time.Time) error {
+	ipAddr, err := net.ResolveIPAddr("ip", host)
+	if err != nil {
+		return err
+	}
+	network := "ip4:icmp"
+	requestType, replyType := byte(8), byte(0)
+	isIPv6 := ipAddr.IP.To4() == nil
+	if isIPv6 {
+		network = "ip6:ipv6-icmp"
+		requestType, replyType = 128, 129
+	}
+	conn, err := net.ListenPacket(network, "")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	id := uint16(os.Getpid())
+	seq := uint16(atomic.AddUint32(&icmpSeq, 1))
+	msg := []byte{requestType, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq), 'v', 'm'}
+	if !isIPv6 {
+		// The checksum for ICMPv6 messages is calculated by the kernel.
+		cs := icmpChecksum(msg)
+		msg[2] = byte(cs >> 8)
+		msg[3] = byte(cs)
+	}
+	if _, err := conn.WriteTo(msg, ipAddr); err != nil {
+		return err
+	}
+	buf := make([]byte, 1500)
+	for {
+		// Raw sockets receive all the ICMP messages, so skip messages not related to the sent request.
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		reply := buf[:n]
+		if len(reply) < 8 || reply[0] != replyType {
+			continue
+		}
+		if peerAddr, ok := peer.(*net.IPAddr); !ok || !peerAddr.IP.Equal(ipAddr.IP) {
+			continue
+		}
+		if uint16(reply[4])<<8|uint16(reply[5]) == id && uint16(reply[6])<<8|uint16(reply[7]) == seq {
+			return nil
+		}
+	}
+}
+
+var icmpSeq uint32
+
+func icmpChecksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
@@ -0,0 +1,45 @@
+package promscrape
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProberTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cannot start listener: %s", err)
+	}
+	addr := ln.Addr().String()
+
+	f := func(expectedSuccess string) {
+		t.Helper()
+		sw := &ScrapeWork{
+			ScrapeURL:     "http://" + addr + "/",
+			ScrapeTimeout: time.Second,
+			ProbeModule:   probeModuleTCP,
+		}
+		p := newProber(sw, &client{})
+		data, err := p.ReadData(nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !strings.HasPrefix(string(data), expectedSuccess+"\nprobe_duration_seconds ") {
+			t.Fatalf("unexpected probe result; got\n%s\nwant prefix\n%s", data, expectedSuccess)
+		}
+	}
+
+	f("probe_success 1")
+	_ = ln.Close()
+	f("probe_success 0")
+}
+
+func TestICMPChecksum(t *testing.T) {
+	// Echo request with id=1, seq=1 and zero checksum.
+	msg := []byte{8, 0, 0, 0, 0, 1, 0, 1}
+	if cs := icmpChecksum(msg); cs != 0xf7fd {
+		t.Fatalf("unexpected checksum; got 0x%x; want 0xf7fd", cs)
+	}
+}
@@ -0,0 +1,23 @@
+package promscrape
+
+import (
+	"io"
+	"net/http"
+)
+
+// ReloadConfig reloads -promscrape.config on request r and writes the applied config version to w.
+//
+// The config is applied only if its hash matches the optional `config_hash` query arg.
+func ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	configTracker.HandleReload(w, r)
+}
+
+// RollbackConfig rolls back to the previously applied -promscrape.config on request r and writes the applied config version to w.
+func RollbackConfig(w http.ResponseWriter, r *http.Request) {
+	configTracker.HandleRollback(w, r)
+}
+
+// WriteConfigVersion writes the applied -promscrape.config version to w.
+func WriteConfigVersion(w io.Writer) {
+	configTracker.WriteVersion(w)
+}
@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/configreload"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cpupool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
@@ -30,7 +32,9 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/openstack"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promscrape/discovery/yandexcloud"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
 	"github.com/VictoriaMetrics/metrics"
+	"github.com/cespare/xxhash/v2"
 )
 
 var (
@@ -44,6 +48,9 @@ var (
 
 	fileSDCheckInterval = flag.Duration("promscrape.fileSDCheckInterval", time.Minute, "Interval for checking for changes in 'file_sd_config'. "+
 		"See https://docs.victoriametrics.com/sd_configs.html#file_sd_configs for details")
+	cpuPools = flag.String("promscrape.cpuPools", "", "Optional worker pools pinned to CPU sets for processing scraped data. "+
+		"Set it to 'numa' for a pool per NUMA node or to semicolon-delimited list of CPU sets such as '0-23,48-71;24-47,72-95' for a pool per CPU set. "+
+		"Every scrape target is processed in the same pool. This flag is supported only on Linux. See https://docs.victoriametrics.com/vmagent.html#cpu-pinning")
 )
 
 // CheckConfig checks -promscrape.config for errors and unsupported options.
@@ -60,6 +67,11 @@ func CheckConfig() error {
 // Scraped data is passed to pushData.
 func Init(pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)) {
 	mustInitClusterMemberID()
+	ps, err := cpupool.NewPools("scrape", *cpuPools)
+	if err != nil {
+		logger.Fatalf("cannot initialize -promscrape.cpuPools=%q: %s", *cpuPools, err)
+	}
+	scrapeCPUPools = ps
 	globalStopChan = make(chan struct{})
 	scraperWG.Add(1)
 	go func() {
@@ -72,11 +84,15 @@ func Init(pushData func(at *auth.Token, wr *prompbmarshal.WriteRequest)) {
 func Stop() {
 	close(globalStopChan)
 	scraperWG.Wait()
+	scrapeCPUPools.MustStop()
 }
 
 var (
 	globalStopChan chan struct{}
 	scraperWG      sync.WaitGroup
+
+	// scrapeCPUPools contains optional worker pools for processing scraped data. See -promscrape.cpuPools.
+	scrapeCPUPools *cpupool.Pools
 	// PendingScrapeConfigs - zero value means, that
 	// all scrapeConfigs are inited and ready for work.
 	PendingScrapeConfigs int32
@@ -118,6 +134,15 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 
 	configSuccess.Set(1)
 	configTimestamp.Set(fasttime.UnixTimestamp())
+	configTracker.SetApplied(configreload.Hash(data))
+
+	// fileData contains the config data, which has been loaded from configFile the last time.
+	// It may differ from data after the rollback to the previous config.
+	fileData := data
+
+	// prevCfg and prevData contain the previously applied config for rollback.
+	var prevCfg *Config
+	var prevData []byte
 
 	scs := newScrapeConfigs(pushData, globalStopCh)
 	scs.add("azure_sd_configs", *azure.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getAzureSDScrapeWork(swsPrev) })
@@ -131,7 +156,7 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 	scs.add("file_sd_configs", *fileSDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getFileSDScrapeWork(swsPrev) })
 	scs.add("gce_sd_configs", *gce.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getGCESDScrapeWork(swsPrev) })
 	scs.add("http_sd_configs", *http.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getHTTPDScrapeWork(swsPrev) })
-	scs.add("kubernetes_sd_configs", *kubernetes.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKubernetesSDScrapeWork(swsPrev) })
+	scs.addWithUpdateCh("kubernetes_sd_configs", *kubernetes.SDCheckInterval, kubernetes.TargetsRemovedCh(), *kubernetes.SDRemovalCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getKubernetesSDScrapeWork(swsPrev) })
 	scs.add("nomad_sd_configs", *nomad.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getNomadSDScrapeWork(swsPrev) })
 	scs.add("openstack_sd_configs", *openstack.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getOpenStackSDScrapeWork(swsPrev) })
 	scs.add("yandexcloud_sd_configs", *yandexcloud.SDCheckInterval, func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork { return cfg.getYandexCloudSDScrapeWork(swsPrev) })
@@ -156,15 +181,18 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 				logger.Errorf("cannot read %q on SIGHUP: %s; continuing with the previous config", configFile, err)
 				goto waitForChans
 			}
+			fileData = dataNew
 			if bytes.Equal(data, dataNew) {
 				logger.Infof("nothing changed in %q", configFile)
 				goto waitForChans
 			}
+			prevCfg, prevData = cfg, data
 			cfgNew.mustRestart(cfg)
 			cfg = cfgNew
 			data = dataNew
 			marshaledData = cfgNew.marshal()
 			configData.Store(&marshaledData)
+			configTracker.SetApplied(configreload.Hash(data))
 		case <-tickerCh:
 			cfgNew, dataNew, err := loadConfig(configFile)
 			if err != nil {
@@ -173,15 +201,72 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 				logger.Errorf("cannot read %q: %s; continuing with the previous config", configFile, err)
 				goto waitForChans
 			}
+			if bytes.Equal(fileData, dataNew) {
+				// Nothing changed since the previous loadConfig.
+				// Compare to fileData instead of data, so the config isn't re-applied after the rollback until it is changed.
+				goto waitForChans
+			}
+			fileData = dataNew
 			if bytes.Equal(data, dataNew) {
-				// Nothing changed since the previous loadConfig
 				goto waitForChans
 			}
+			prevCfg, prevData = cfg, data
 			cfgNew.mustRestart(cfg)
 			cfg = cfgNew
 			data = dataNew
 			marshaledData = cfgNew.marshal()
 			configData.Store(&marshaledData)
+			configTracker.SetApplied(configreload.Hash(data))
+		case req := <-configTracker.Requests():
+			if req.IsRollback {
+				if prevCfg == nil {
+					req.Done(configreload.ErrNoPreviousVersion)
+					goto waitForChans
+				}
+				logger.Infof("rolling back to the previous Prometheus config")
+				cfgNew, err := prevCfg.cloneForRestart()
+				if err != nil {
+					configReloadErrors.Inc()
+					req.Done(fmt.Errorf("cannot roll back to the previous config: %w", err))
+					goto waitForChans
+				}
+				cfgNew.mustRestart(cfg)
+				cfg = cfgNew
+				data = prevData
+				prevCfg, prevData = nil, nil
+				marshaledData = cfgNew.marshal()
+				configData.Store(&marshaledData)
+				configTracker.SetRolledBack()
+				req.Done(nil)
+				break
+			}
+			logger.Infof("reload requested via API; reloading Prometheus configs from %q", configFile)
+			cfgNew, dataNew, err := loadConfig(configFile)
+			if err != nil {
+				configReloadErrors.Inc()
+				configSuccess.Set(0)
+				logger.Errorf("cannot read %q on reload request: %s; continuing with the previous config", configFile, err)
+				req.Done(fmt.Errorf("cannot read %q: %w; continuing with the previous config", configFile, err))
+				goto waitForChans
+			}
+			if hash := configreload.Hash(dataNew); req.ExpectedHash != "" && hash != req.ExpectedHash {
+				req.Done(configreload.NewHashMismatchError(req.ExpectedHash, hash))
+				goto waitForChans
+			}
+			fileData = dataNew
+			if bytes.Equal(data, dataNew) {
+				logger.Infof("nothing changed in %q", configFile)
+				req.Done(nil)
+				goto waitForChans
+			}
+			prevCfg, prevData = cfg, data
+			cfgNew.mustRestart(cfg)
+			cfg = cfgNew
+			data = dataNew
+			marshaledData = cfgNew.marshal()
+			configData.Store(&marshaledData)
+			configTracker.SetApplied(configreload.Hash(data))
+			req.Done(nil)
 		case <-globalStopCh:
 			cfg.mustStop()
 			logger.Infof("stopping Prometheus scrapers")
@@ -198,6 +283,8 @@ func runScraper(configFile string, pushData func(at *auth.Token, wr *prompbmarsh
 }
 
 var (
+	configTracker = configreload.NewTracker("vm_promscrape", "-promscrape.config")
+
 	configReloads      = metrics.NewCounter(`vm_promscrape_config_reloads_total`)
 	configReloadErrors = metrics.NewCounter(`vm_promscrape_config_reloads_errors_total`)
 	configSuccess      = metrics.NewCounter(`vm_promscrape_config_last_reload_successful`)
@@ -221,16 +308,29 @@ func newScrapeConfigs(pushData func(at *auth.Token, wr *prompbmarshal.WriteReque
 }
 
 func (scs *scrapeConfigs) add(name string, checkInterval time.Duration, getScrapeWork func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork) {
+	scs.addWithUpdateCh(name, checkInterval, nil, 0, getScrapeWork)
+}
+
+// addWithUpdateCh adds scrape config with the given name, which is refreshed every checkInterval
+// and additionally on every notification from updateCh, but not more frequently than minUpdateInterval.
+func (scs *scrapeConfigs) addWithUpdateCh(name string, checkInterval time.Duration, updateCh <-chan struct{}, minUpdateInterval time.Duration,
+	getScrapeWork func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork) {
 	atomic.AddInt32(&PendingScrapeConfigs, 1)
+	if minUpdateInterval <= 0 {
+		updateCh = nil
+	}
 	scfg := &scrapeConfig{
-		name:          name,
-		pushData:      scs.pushData,
-		getScrapeWork: getScrapeWork,
-		checkInterval: checkInterval,
-		cfgCh:         make(chan *Config, 1),
-		stopCh:        scs.stopCh,
+		name:              name,
+		pushData:          scs.pushData,
+		getScrapeWork:     getScrapeWork,
+		checkInterval:     checkInterval,
+		updateCh:          updateCh,
+		minUpdateInterval: minUpdateInterval,
+		cfgCh:             make(chan *Config, 1),
+		stopCh:            scs.stopCh,
 
 		discoveryDuration: metrics.GetOrCreateHistogram(fmt.Sprintf("vm_promscrape_service_discovery_duration_seconds{type=%q}", name)),
+		updatesTriggered:  metrics.GetOrCreateCounter(fmt.Sprintf("vm_promscrape_triggered_discovery_updates_total{type=%q}", name)),
 	}
 	scs.wg.Add(1)
 	go func() {
@@ -260,7 +360,14 @@ type scrapeConfig struct {
 	cfgCh         chan *Config
 	stopCh        <-chan struct{}
 
+	// updateCh is an optional channel for triggering immediate refresh of scrape targets, for example, when they are removed.
+	updateCh <-chan struct{}
+
+	// minUpdateInterval is the minimum interval between refreshes triggered by updateCh.
+	minUpdateInterval time.Duration
+
 	discoveryDuration *metrics.Histogram
+	updatesTriggered  *metrics.Counter
 }
 
 func (scfg *scrapeConfig) run(globalStopCh <-chan struct{}) {
@@ -297,6 +404,23 @@ func (scfg *scrapeConfig) run(globalStopCh <-chan struct{}) {
 			return
 		case cfg = <-scfg.cfgCh:
 		case <-tickerCh:
+		case <-scfg.updateCh:
+			// Stop scrapers for the removed targets, so they send staleness markers for the scraped series
+			// without waiting for checkInterval.
+			updateScrapeWork(cfg)
+			scfg.updatesTriggered.Inc()
+
+			// Limit the rate of refreshes triggered by updateCh, since every refresh may be expensive
+			// when many targets are removed one by one.
+			t := timerpool.Get(scfg.minUpdateInterval)
+			select {
+			case <-scfg.stopCh:
+				timerpool.Put(t)
+				return
+			case <-t.C:
+				timerpool.Put(t)
+			}
+			continue
 		}
 		updateScrapeWork(cfg)
 	}
@@ -445,6 +569,13 @@ func newScraper(sw *ScrapeWork, group string, pushData func(at *auth.Token, wr *
 	sc.sw.ScrapeGroup = group
 	sc.sw.ReadData = c.ReadData
 	sc.sw.GetStreamReader = c.GetStreamReader
+	if sw.ProbeModule != "" {
+		sc.sw.ReadData = newProber(sw, c).ReadData
+	}
 	sc.sw.PushData = pushData
+	if scrapeCPUPools.Len() > 0 {
+		// Process scraped data for the target in the same pool, so the target state stays local to the pool CPUs.
+		sc.sw.cpuPool = scrapeCPUPools.Get(xxhash.Sum64String(sw.key()))
+	}
 	return sc
 }
@@ -0,0 +1,68 @@
+package promscrape
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+func TestScrapeConfigRunWithUpdateCh(t *testing.T) {
+	var getScrapeWorkCalls atomic.Int32
+	updateCh := make(chan struct{}, 1)
+	stopCh := make(chan struct{})
+	scfg := &scrapeConfig{
+		name:     "test_update_ch",
+		pushData: func(at *auth.Token, wr *prompbmarshal.WriteRequest) {},
+		getScrapeWork: func(cfg *Config, swsPrev []*ScrapeWork) []*ScrapeWork {
+			getScrapeWorkCalls.Add(1)
+			return nil
+		},
+		checkInterval:     time.Hour,
+		updateCh:          updateCh,
+		minUpdateInterval: time.Millisecond,
+		cfgCh:             make(chan *Config, 1),
+		stopCh:            stopCh,
+
+		discoveryDuration: metrics.NewSet().NewHistogram("discovery_duration"),
+		updatesTriggered:  metrics.NewSet().NewCounter("updates_triggered"),
+	}
+	atomic.AddInt32(&PendingScrapeConfigs, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scfg.run(nil)
+	}()
+	scfg.cfgCh <- &Config{}
+
+	waitForCalls := func(n int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for getScrapeWorkCalls.Load() < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout when waiting for %d getScrapeWork calls; got %d calls", n, getScrapeWorkCalls.Load())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The initial update
+	waitForCalls(1)
+
+	// Notifications from updateCh must trigger updates without waiting for checkInterval
+	updateCh <- struct{}{}
+	waitForCalls(2)
+	updateCh <- struct{}{}
+	waitForCalls(3)
+	if n := scfg.updatesTriggered.Get(); n == 0 {
+		t.Fatalf("expecting non-zero number of triggered updates")
+	}
+
+	close(stopCh)
+	wg.Wait()
+}
@@ -2,22 +2,26 @@ package promscrape
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"math"
 	"math/bits"
+	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bloomfilter"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cpupool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
-	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/leveledbytebufferpool"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
@@ -29,6 +33,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus/stream"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/proxy"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/timerpool"
+	"github.com/VictoriaMetrics/fasthttp"
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/cespare/xxhash/v2"
 )
@@ -134,6 +139,37 @@ type ScrapeWork struct {
 	// See https://docs.victoriametrics.com/vmagent.html#prometheus-staleness-markers
 	NoStaleMarkers bool
 
+	// Whether to generate extended automatically generated metrics such as scrape_response_size_bytes.
+	// See https://docs.victoriametrics.com/vmagent.html#automatically-generated-metrics
+	ExtendedMetrics bool
+
+	// The maximum age of the cached response, which can be used instead of the response from the target on scrape timeout.
+	// Responses aren't cached if ScrapeCacheMaxAge is zero.
+	// See https://docs.victoriametrics.com/vmagent.html#scrape-cache
+	ScrapeCacheMaxAge time.Duration
+
+	// The maximum effective scrape interval for overloaded target.
+	// The scrape interval isn't adapted if MaxScrapeInterval doesn't exceed ScrapeInterval.
+	// See https://docs.victoriametrics.com/vmagent.html#adaptive-scrape-interval
+	MaxScrapeInterval time.Duration
+
+	// The duration series may be missing in scrape responses before they are marked as stale.
+	// Series are marked as stale immediately after they disappear if StalenessInterval is zero.
+	// See https://docs.victoriametrics.com/vmagent.html#prometheus-staleness-markers
+	StalenessInterval time.Duration
+
+	// The maximum size of scrape response in bytes.
+	// -promscrape.maxScrapeSize is used if MaxScrapeSize is zero.
+	MaxScrapeSize int64
+
+	// The module for probing the target instead of scraping metrics from it.
+	// See https://docs.victoriametrics.com/vmagent.html#probing
+	ProbeModule string
+
+	// Whether to collect only metric metadata (HELP, TYPE and UNIT) from the target without samples.
+	// See https://docs.victoriametrics.com/vmagent.html#metadata-only-scraping
+	MetadataOnly bool
+
 	// The Tenant Info
 	AuthToken *auth.Token
 
@@ -144,7 +180,9 @@ type ScrapeWork struct {
 func (sw *ScrapeWork) canSwitchToStreamParseMode() bool {
 	// Deny switching to stream parse mode if `sample_limit` or `series_limit` options are set,
 	// since these limits cannot be applied in stream parsing mode.
-	return sw.SampleLimit <= 0 && sw.SeriesLimit <= 0
+	// Deny switching to stream parse mode if `scrape_cache_max_age` option is set,
+	// since responses aren't cached in stream parsing mode.
+	return sw.SampleLimit <= 0 && sw.SeriesLimit <= 0 && sw.ScrapeCacheMaxAge <= 0
 }
 
 // key returns unique identifier for the given sw.
@@ -158,12 +196,12 @@ func (sw *ScrapeWork) key() string {
 		"ExternalLabels=%s, "+
 		"ProxyURL=%s, ProxyAuthConfig=%s, AuthConfig=%s, MetricRelabelConfigs=%q, "+
 		"SampleLimit=%d, DisableCompression=%v, DisableKeepAlive=%v, StreamParse=%v, "+
-		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, NoStaleMarkers=%v",
+		"ScrapeAlignInterval=%s, ScrapeOffset=%s, SeriesLimit=%d, NoStaleMarkers=%v, ExtendedMetrics=%v, ScrapeCacheMaxAge=%s, MaxScrapeInterval=%s, StalenessInterval=%s, MaxScrapeSize=%d, ProbeModule=%s, MetadataOnly=%v",
 		sw.jobNameOriginal, sw.ScrapeURL, sw.ScrapeInterval, sw.ScrapeTimeout, sw.HonorLabels, sw.HonorTimestamps, sw.DenyRedirects, sw.Labels.String(),
 		sw.ExternalLabels.String(),
 		sw.ProxyURL.String(), sw.ProxyAuthConfig.String(), sw.AuthConfig.String(), sw.MetricRelabelConfigs.String(),
 		sw.SampleLimit, sw.DisableCompression, sw.DisableKeepAlive, sw.StreamParse,
-		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.NoStaleMarkers)
+		sw.ScrapeAlignInterval, sw.ScrapeOffset, sw.SeriesLimit, sw.NoStaleMarkers, sw.ExtendedMetrics, sw.ScrapeCacheMaxAge, sw.MaxScrapeInterval, sw.StalenessInterval, sw.MaxScrapeSize, sw.ProbeModule, sw.MetadataOnly)
 	return key
 }
 
@@ -200,6 +238,9 @@ type scrapeWork struct {
 	// Optional limiter on the number of unique series per scrape target.
 	seriesLimiter *bloomfilter.Limiter
 
+	// cpuPool is an optional worker pool for processing scraped data. See -promscrape.cpuPools.
+	cpuPool *cpupool.Pool
+
 	// prevBodyLen contains the previous response body length for the given scrape work.
 	// It is used as a hint in order to reduce memory usage for body buffers.
 	prevBodyLen int
@@ -218,6 +259,11 @@ type scrapeWork struct {
 	// equals to or exceeds -promscrape.minResponseSizeForStreamParse
 	lastScrapeCompressed []byte
 
+	// lastScrapeSeriesHash is the hash for the set of series in lastScrape.
+	// It is used in stream parsing mode for detecting changes in the set of scraped series
+	// without loading lastScrape. Zero value means the hash is unknown.
+	lastScrapeSeriesHash uint64
+
 	// nextErrorLogTime is the timestamp in millisecond when the next scrape error should be logged.
 	nextErrorLogTime int64
 
@@ -226,6 +272,54 @@ type scrapeWork struct {
 
 	// successRequestsCount is the number of success requests during the last suppressScrapeErrorsDelay
 	successRequestsCount int
+
+	// cachedResponse holds the last successful response from scrape target if Config.ScrapeCacheMaxAge is set.
+	// It is used instead of the response from scrape target on scrape timeout.
+	cachedResponse []byte
+
+	// cachedResponseTimestamp is the timestamp in milliseconds for the cachedResponse.
+	cachedResponseTimestamp int64
+
+	// pendingStaleSeries contains series, which disappeared from scrape responses, but aren't marked as stale yet
+	// because of Config.StalenessInterval. It maps the series key to the timestamp in milliseconds when the series disappeared.
+	pendingStaleSeries map[string]int64
+
+	// intervalAdapter adapts the effective scrape interval for overloaded target if Config.MaxScrapeInterval is set.
+	intervalAdapter *scrapeIntervalAdapter
+
+	// effectiveScrapeInterval is the current scrape interval in nanoseconds.
+	//
+	// It must be accessed atomically, since it is read when generating /api/v1/targets response.
+	effectiveScrapeInterval int64
+}
+
+// getEffectiveScrapeInterval returns the current scrape interval for sw, which may be bigger than Config.ScrapeInterval for overloaded target.
+func (sw *scrapeWork) getEffectiveScrapeInterval() time.Duration {
+	if d := atomic.LoadInt64(&sw.effectiveScrapeInterval); d > 0 {
+		return time.Duration(d)
+	}
+	return sw.Config.ScrapeInterval
+}
+
+// adaptScrapeInterval adapts the effective scrape interval for sw according to the scrape error.
+func (sw *scrapeWork) adaptScrapeInterval(err error) {
+	sia := sw.intervalAdapter
+	if sia == nil {
+		return
+	}
+	prevInterval := sia.interval
+	if !sia.update(isOverloadError(err)) {
+		return
+	}
+	scrapeIntervalAdaptations.Inc()
+	atomic.StoreInt64(&sw.effectiveScrapeInterval, int64(sia.interval))
+	if sia.interval > prevInterval {
+		logger.Warnf("increasing scrape interval for overloaded target %q (%s) from %s to %s; the last error: %s",
+			sw.Config.ScrapeURL, sw.Config.Labels.String(), prevInterval, sia.interval, err)
+	} else {
+		logger.Infof("decreasing scrape interval for target %q (%s) from %s to %s, since it is no longer overloaded",
+			sw.Config.ScrapeURL, sw.Config.Labels.String(), prevInterval, sia.interval)
+	}
 }
 
 func (sw *scrapeWork) loadLastScrape() string {
@@ -248,6 +342,7 @@ func (sw *scrapeWork) storeLastScrape(lastScrape []byte) {
 		sw.lastScrape = append(sw.lastScrape[:0], lastScrape...)
 		sw.lastScrapeCompressed = nil
 	}
+	sw.lastScrapeSeriesHash = 0
 }
 
 func (sw *scrapeWork) finalizeLastScrape() {
@@ -265,6 +360,9 @@ func (sw *scrapeWork) finalizeLastScrape() {
 func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{}) {
 	var randSleep uint64
 	scrapeInterval := sw.Config.ScrapeInterval
+	if sw.Config.MaxScrapeInterval > scrapeInterval {
+		sw.intervalAdapter = newScrapeIntervalAdapter(scrapeInterval, sw.Config.MaxScrapeInterval)
+	}
 	scrapeAlignInterval := sw.Config.ScrapeAlignInterval
 	scrapeOffset := sw.Config.ScrapeOffset
 	if scrapeOffset > 0 {
@@ -314,6 +412,7 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 		ticker = time.NewTicker(scrapeInterval)
 		timestamp = time.Now().UnixNano() / 1e6
 		sw.scrapeAndLogError(timestamp, timestamp)
+		scrapeInterval = sw.resetTickerIfNeeded(ticker, scrapeInterval)
 	}
 	defer ticker.Stop()
 	for {
@@ -332,6 +431,9 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 				// Use the current real timestamp for staleness markers, so queries
 				// stop returning data just after the time the target disappears.
 				sw.sendStaleSeries(lastScrape, "", t, true)
+				if pendingStaleSeries := sw.popPendingStaleSeries(math.MaxInt64); pendingStaleSeries != "" {
+					sw.sendStaleSeries(pendingStaleSeries, "", t, false)
+				}
 			}
 			if sw.seriesLimiter != nil {
 				sw.seriesLimiter.MustStop()
@@ -345,10 +447,22 @@ func (sw *scrapeWork) run(stopCh <-chan struct{}, globalStopCh <-chan struct{})
 				timestamp = t
 			}
 			sw.scrapeAndLogError(timestamp, t)
+			scrapeInterval = sw.resetTickerIfNeeded(ticker, scrapeInterval)
 		}
 	}
 }
 
+// resetTickerIfNeeded resets ticker if the effective scrape interval for sw differs from scrapeInterval.
+//
+// It returns the effective scrape interval.
+func (sw *scrapeWork) resetTickerIfNeeded(ticker *time.Ticker, scrapeInterval time.Duration) time.Duration {
+	d := sw.getEffectiveScrapeInterval()
+	if d != scrapeInterval {
+		ticker.Reset(d)
+	}
+	return d
+}
+
 func (sw *scrapeWork) logError(s string) {
 	if !*suppressScrapeErrors {
 		logger.ErrorfSkipframes(1, "error when scraping %q from job %q with labels %s: %s; "+
@@ -359,6 +473,7 @@ func (sw *scrapeWork) logError(s string) {
 
 func (sw *scrapeWork) scrapeAndLogError(scrapeTimestamp, realTimestamp int64) {
 	err := sw.scrapeInternal(scrapeTimestamp, realTimestamp)
+	sw.adaptScrapeInterval(err)
 	if *suppressScrapeErrors {
 		return
 	}
@@ -387,6 +502,7 @@ var (
 	scrapedSamples              = metrics.NewHistogram("vm_promscrape_scraped_samples")
 	scrapesSkippedBySampleLimit = metrics.NewCounter("vm_promscrape_scrapes_skipped_by_sample_limit_total")
 	scrapesFailed               = metrics.NewCounter("vm_promscrape_scrapes_failed_total")
+	scrapesServedFromCache      = metrics.NewCounter("vm_promscrape_scrapes_served_from_cache_total")
 	pushDataDuration            = metrics.NewHistogram("vm_promscrape_push_data_duration_seconds")
 )
 
@@ -397,9 +513,18 @@ func (sw *scrapeWork) mustSwitchToStreamParseMode(responseSize int) bool {
 	return sw.Config.canSwitchToStreamParseMode() && responseSize >= minResponseSizeForStreamParse.IntN()
 }
 
+func (sw *scrapeWork) isStreamParseMode() bool {
+	// Probed targets are always read via prober.ReadData.
+	// Metadata-only targets are always read in usual mode, since metadata isn't parsed in stream parsing mode.
+	if sw.Config.ProbeModule != "" || sw.Config.MetadataOnly {
+		return false
+	}
+	return *streamParse || sw.Config.StreamParse || sw.mustSwitchToStreamParseMode(sw.prevBodyLen)
+}
+
 // getTargetResponse() fetches response from sw target in the same way as when scraping the target.
 func (sw *scrapeWork) getTargetResponse() ([]byte, error) {
-	if *streamParse || sw.Config.StreamParse || sw.mustSwitchToStreamParseMode(sw.prevBodyLen) {
+	if sw.isStreamParseMode() {
 		// Read the response in stream mode.
 		sr, err := sw.GetStreamReader()
 		if err != nil {
@@ -414,7 +539,7 @@ func (sw *scrapeWork) getTargetResponse() ([]byte, error) {
 }
 
 func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error {
-	if *streamParse || sw.Config.StreamParse || sw.mustSwitchToStreamParseMode(sw.prevBodyLen) {
+	if sw.isStreamParseMode() {
 		// Read data from scrape targets in streaming manner.
 		// This case is optimized for targets exposing more than ten thousand of metrics per target.
 		return sw.scrapeStream(scrapeTimestamp, realTimestamp)
@@ -426,7 +551,14 @@ func (sw *scrapeWork) scrapeInternal(scrapeTimestamp, realTimestamp int64) error
 	body := leveledbytebufferpool.Get(sw.prevBodyLen)
 	var err error
 	body.B, err = sw.ReadData(body.B[:0])
-	releaseBody, err := sw.processScrapedData(scrapeTimestamp, realTimestamp, body, err)
+	var releaseBody bool
+	if sw.cpuPool != nil {
+		sw.cpuPool.Do(func() {
+			releaseBody, err = sw.processScrapedData(scrapeTimestamp, realTimestamp, body, err)
+		})
+	} else {
+		releaseBody, err = sw.processScrapedData(scrapeTimestamp, realTimestamp, body, err)
+	}
 	if releaseBody {
 		leveledbytebufferpool.Put(body)
 	}
@@ -447,8 +579,26 @@ func (sw *scrapeWork) processScrapedData(scrapeTimestamp, realTimestamp int64, b
 	endTimestamp := time.Now().UnixNano() / 1e6
 	duration := float64(endTimestamp-realTimestamp) / 1e3
 	scrapeDuration.Update(duration)
-	scrapeResponseSize.Update(float64(len(body.B)))
+	responseSize := len(body.B)
+	scrapeResponseSize.Update(float64(responseSize))
 	up := 1
+	cachedResponseAge := float64(0)
+	isCachedResponse := false
+	if err != nil && sw.canUseCachedResponse(err, realTimestamp) {
+		// Use the last successful response instead of leaving a gap on scrape timeout.
+		// See https://docs.victoriametrics.com/vmagent.html#scrape-cache
+		body.B = append(body.B[:0], sw.cachedResponse...)
+		cachedResponseAge = float64(realTimestamp-sw.cachedResponseTimestamp) / 1e3
+		isCachedResponse = true
+		scrapesServedFromCache.Inc()
+	}
+	if sw.Config.MetadataOnly {
+		if err == nil {
+			sw.storeMetadata(bytesutil.ToUnsafeString(body.B), realTimestamp)
+		}
+		// Samples from metadata-only targets are dropped, so only auto metrics such as `up` are sent to remote storage.
+		body.B = body.B[:0]
+	}
 	wc := writeRequestCtxPool.Get(sw.prevLabelsLen)
 	lastScrape := sw.loadLastScrape()
 	bodyString := bytesutil.ToUnsafeString(body.B)
@@ -456,7 +606,8 @@ func (sw *scrapeWork) processScrapedData(scrapeTimestamp, realTimestamp int64, b
 	if err != nil {
 		up = 0
 		scrapesFailed.Inc()
-	} else {
+	}
+	if err == nil || isCachedResponse {
 		wc.rows.UnmarshalWithErrLogger(bodyString, sw.logError)
 	}
 	srcRows := wc.rows.Rows
@@ -473,9 +624,13 @@ func (sw *scrapeWork) processScrapedData(scrapeTimestamp, realTimestamp int64, b
 		err = fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
 			"either reduce the sample count for the target or increase sample_limit", sw.Config.ScrapeURL, sw.Config.SampleLimit)
 	}
-	if up == 0 {
+	if up == 0 && !isCachedResponse {
 		bodyString = ""
 	}
+	if up == 1 && sw.Config.ScrapeCacheMaxAge > 0 {
+		sw.cachedResponse = append(sw.cachedResponse[:0], body.B...)
+		sw.cachedResponseTimestamp = realTimestamp
+	}
 	seriesAdded := 0
 	if !areIdenticalSeries {
 		// The returned value for seriesAdded may be bigger than the real number of added series
@@ -494,6 +649,8 @@ func (sw *scrapeWork) processScrapedData(scrapeTimestamp, realTimestamp int64, b
 		samplesPostRelabeling:     samplesPostRelabeling,
 		seriesAdded:               seriesAdded,
 		seriesLimitSamplesDropped: samplesDropped,
+		cachedResponseAge:         cachedResponseAge,
+		responseSize:              responseSize,
 	}
 	sw.addAutoMetrics(am, wc, scrapeTimestamp)
 	sw.pushData(sw.Config.AuthToken, &wc.writeRequest)
@@ -507,100 +664,86 @@ func (sw *scrapeWork) processScrapedData(scrapeTimestamp, realTimestamp int64, b
 		writeRequestCtxPool.Put(wc)
 	}
 	// body must be released only after wc is released, since wc refers to body.
+	// Send stale markers for disappeared metrics with the real scrape timestamp
+	// in order to guarantee that query doesn't return data after this time for the disappeared metrics.
+	sw.processDisappearedSeries(lastScrape, bodyString, areIdenticalSeries, realTimestamp)
 	if !areIdenticalSeries {
-		// Send stale markers for disappeared metrics with the real scrape timestamp
-		// in order to guarantee that query doesn't return data after this time for the disappeared metrics.
-		sw.sendStaleSeries(lastScrape, bodyString, realTimestamp, false)
 		sw.storeLastScrape(body.B)
 	}
 	sw.finalizeLastScrape()
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, responseSize, err)
 	return !mustSwitchToStreamParse, err
 }
 
-func (sw *scrapeWork) pushData(at *auth.Token, wr *prompbmarshal.WriteRequest) {
-	startTime := time.Now()
-	sw.PushData(at, wr)
-	pushDataDuration.UpdateDuration(startTime)
-}
-
-type streamBodyReader struct {
-	body       []byte
-	bodyLen    int
-	readOffset int
+// canUseCachedResponse returns true if the cached response can be used instead of the response from scrape target,
+// which failed with the given err.
+func (sw *scrapeWork) canUseCachedResponse(err error, realTimestamp int64) bool {
+	maxAge := sw.Config.ScrapeCacheMaxAge
+	if maxAge <= 0 || len(sw.cachedResponse) == 0 || !isTimeoutError(err) {
+		return false
+	}
+	return realTimestamp-sw.cachedResponseTimestamp <= maxAge.Milliseconds()
 }
 
-func (sbr *streamBodyReader) Init(sr *streamReader) error {
-	sbr.body = nil
-	sbr.bodyLen = 0
-	sbr.readOffset = 0
-	// Read the whole response body in memory before parsing it in stream mode.
-	// This minimizes the time needed for reading response body from scrape target.
-	startTime := fasttime.UnixTimestamp()
-	body, err := io.ReadAll(sr)
-	if err != nil {
-		d := fasttime.UnixTimestamp() - startTime
-		return fmt.Errorf("cannot read stream body in %d seconds: %w", d, err)
+func isTimeoutError(err error) bool {
+	if errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
 	}
-	sbr.body = body
-	sbr.bodyLen = len(body)
-	return nil
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
 }
 
-func (sbr *streamBodyReader) Read(b []byte) (int, error) {
-	if sbr.readOffset >= len(sbr.body) {
-		return 0, io.EOF
-	}
-	n := copy(b, sbr.body[sbr.readOffset:])
-	sbr.readOffset += n
-	return n, nil
+func (sw *scrapeWork) pushData(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+	startTime := time.Now()
+	sw.PushData(at, wr)
+	pushDataDuration.UpdateDuration(startTime)
 }
 
 func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 	samplesScraped := 0
 	samplesPostRelabeling := 0
 	wc := writeRequestCtxPool.Get(sw.prevLabelsLen)
-	// Do not pool sbr and do not pre-allocate sbr.body in order to reduce memory usage when scraping big responses.
-	var sbr streamBodyReader
-
-	lastScrape := sw.loadLastScrape()
-	bodyString := ""
-	areIdenticalSeries := true
+	// The response body is parsed while being read from scrape target, so it isn't held in memory.
+	// Only series keys are collected in compressed blocks for staleness tracking and for scrape_series_added metric.
+	// Do not pool sk in order to reduce memory usage when scraping big responses.
+	var sk seriesKeys
+	trackSeries := !sw.Config.NoStaleMarkers || sw.Config.SeriesLimit > 0
+	bodyLen := 0
 	samplesDropped := 0
 	sr, err := sw.GetStreamReader()
 	if err != nil {
 		err = fmt.Errorf("cannot read data: %s", err)
 	} else {
 		var mu sync.Mutex
-		err = sbr.Init(sr)
-		if err == nil {
-			bodyString = bytesutil.ToUnsafeString(sbr.body)
-			areIdenticalSeries = sw.areIdenticalSeries(lastScrape, bodyString)
-			err = stream.Parse(&sbr, scrapeTimestamp, false, func(rows []parser.Row) error {
-				mu.Lock()
-				defer mu.Unlock()
-				samplesScraped += len(rows)
-				for i := range rows {
-					sw.addRowToTimeseries(wc, &rows[i], scrapeTimestamp, true)
-				}
-				samplesPostRelabeling += len(wc.writeRequest.Timeseries)
-				if sw.Config.SampleLimit > 0 && samplesPostRelabeling > sw.Config.SampleLimit {
-					wc.resetNoRows()
-					scrapesSkippedBySampleLimit.Inc()
-					return fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
-						"either reduce the sample count for the target or increase sample_limit", sw.Config.ScrapeURL, sw.Config.SampleLimit)
-				}
-				if sw.seriesLimitExceeded || !areIdenticalSeries {
-					samplesDropped += sw.applySeriesLimit(wc)
+		err = stream.Parse(sr, scrapeTimestamp, "", func(rows []parser.Row) error {
+			mu.Lock()
+			defer mu.Unlock()
+			samplesScraped += len(rows)
+			for i := range rows {
+				r := &rows[i]
+				if trackSeries {
+					sk.add(r)
 				}
-				// Push the collected rows to sw before returning from the callback, since they cannot be held
-				// after returning from the callback - this will result in data race.
-				// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/825#issuecomment-723198247
-				sw.pushData(sw.Config.AuthToken, &wc.writeRequest)
+				sw.addRowToTimeseries(wc, r, scrapeTimestamp, true)
+			}
+			samplesPostRelabeling += len(wc.writeRequest.Timeseries)
+			if sw.Config.SampleLimit > 0 && samplesPostRelabeling > sw.Config.SampleLimit {
 				wc.resetNoRows()
-				return nil
-			}, sw.logError)
-		}
+				scrapesSkippedBySampleLimit.Inc()
+				return fmt.Errorf("the response from %q exceeds sample_limit=%d; "+
+					"either reduce the sample count for the target or increase sample_limit", sw.Config.ScrapeURL, sw.Config.SampleLimit)
+			}
+			// The set of series in the response is unknown until the whole response is read,
+			// so series_limit must be applied to every block of rows.
+			samplesDropped += sw.applySeriesLimit(wc)
+			// Push the collected rows to sw before returning from the callback, since they cannot be held
+			// after returning from the callback - this will result in data race.
+			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/825#issuecomment-723198247
+			sw.pushData(sw.Config.AuthToken, &wc.writeRequest)
+			wc.resetNoRows()
+			return nil
+		}, sw.logError)
+		bodyLen = int(sr.bytesRead)
 		sr.MustClose()
 	}
 
@@ -608,7 +751,7 @@ func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 	endTimestamp := time.Now().UnixNano() / 1e6
 	duration := float64(endTimestamp-realTimestamp) / 1e3
 	scrapeDuration.Update(duration)
-	scrapeResponseSize.Update(float64(sbr.bodyLen))
+	scrapeResponseSize.Update(float64(bodyLen))
 	up := 1
 	if err != nil {
 		// Mark the scrape as failed even if it already read and pushed some samples
@@ -616,6 +759,19 @@ func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 		up = 0
 		scrapesFailed.Inc()
 	}
+
+	// Do not update the last scrape on errors, since the collected series keys may be incomplete.
+	// Load the last scrape only if the set of scraped series has been changed, since this may require
+	// big amounts of memory for targets exposing big number of series.
+	areIdenticalSeries := !trackSeries || err != nil || sk.seriesHash() == sw.lastScrapeSeriesHash
+	lastScrape := ""
+	bodyString := ""
+	if !areIdenticalSeries {
+		lastScrape = sw.loadLastScrape()
+		bodyString = bytesutil.ToUnsafeString(sk.marshal(nil))
+		// Release memory occupied by compressed blocks, since they are no longer needed.
+		sk.blocks = nil
+	}
 	seriesAdded := 0
 	if !areIdenticalSeries {
 		// The returned value for seriesAdded may be bigger than the real number of added series
@@ -630,21 +786,23 @@ func (sw *scrapeWork) scrapeStream(scrapeTimestamp, realTimestamp int64) error {
 		samplesPostRelabeling:     samplesPostRelabeling,
 		seriesAdded:               seriesAdded,
 		seriesLimitSamplesDropped: samplesDropped,
+		responseSize:              bodyLen,
 	}
 	sw.addAutoMetrics(am, wc, scrapeTimestamp)
 	sw.pushData(sw.Config.AuthToken, &wc.writeRequest)
 	sw.prevLabelsLen = len(wc.labels)
-	sw.prevBodyLen = sbr.bodyLen
+	sw.prevBodyLen = bodyLen
 	wc.reset()
 	writeRequestCtxPool.Put(wc)
+	// Send stale markers for disappeared metrics with the real scrape timestamp
+	// in order to guarantee that query doesn't return data after this time for the disappeared metrics.
+	sw.processDisappearedSeries(lastScrape, bodyString, areIdenticalSeries, realTimestamp)
 	if !areIdenticalSeries {
-		// Send stale markers for disappeared metrics with the real scrape timestamp
-		// in order to guarantee that query doesn't return data after this time for the disappeared metrics.
-		sw.sendStaleSeries(lastScrape, bodyString, realTimestamp, false)
-		sw.storeLastScrape(sbr.body)
+		sw.storeLastScrape(bytesutil.ToUnsafeBytes(bodyString))
+		sw.lastScrapeSeriesHash = sk.seriesHash()
 	}
 	sw.finalizeLastScrape()
-	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, err)
+	tsmGlobal.Update(sw, up == 1, realTimestamp, int64(duration*1000), samplesScraped, bodyLen, err)
 	// Do not track active series in streaming mode, since this may need too big amounts of memory
 	// when the target exports too big number of metrics.
 	return err
@@ -767,6 +925,77 @@ func (sw *scrapeWork) applySeriesLimit(wc *writeRequestCtx) int {
 	return samplesDropped
 }
 
+// processDisappearedSeries sends stale markers for series from lastScrape, which are missing in currScrape.
+//
+// If Config.StalenessInterval is set, then stale markers are sent only for series, which are missing
+// in scrape responses for longer than Config.StalenessInterval.
+func (sw *scrapeWork) processDisappearedSeries(lastScrape, currScrape string, areIdenticalSeries bool, timestamp int64) {
+	if sw.Config.StalenessInterval <= 0 || sw.Config.NoStaleMarkers {
+		if !areIdenticalSeries {
+			sw.sendStaleSeries(lastScrape, currScrape, timestamp, false)
+		}
+		return
+	}
+	if !areIdenticalSeries {
+		// Postpone stale markers for the disappeared series.
+		diff := parser.GetRowsDiff(lastScrape, currScrape)
+		for _, line := range strings.Split(diff, "\n") {
+			key := strings.TrimSuffix(line, " 0")
+			if key == "" {
+				continue
+			}
+			if sw.pendingStaleSeries == nil {
+				sw.pendingStaleSeries = make(map[string]int64)
+			}
+			if _, ok := sw.pendingStaleSeries[key]; !ok {
+				sw.pendingStaleSeries[key] = timestamp
+			}
+		}
+	}
+	if len(sw.pendingStaleSeries) == 0 {
+		return
+	}
+
+	// Forget series, which re-appeared in currScrape.
+	var bb []byte
+	for key := range sw.pendingStaleSeries {
+		bb = append(bb, key...)
+		bb = append(bb, " 0\n"...)
+	}
+	missing := parser.GetRowsDiff(bytesutil.ToUnsafeString(bb), currScrape)
+	missingKeys := make(map[string]struct{}, len(sw.pendingStaleSeries))
+	for _, line := range strings.Split(missing, "\n") {
+		missingKeys[strings.TrimSuffix(line, " 0")] = struct{}{}
+	}
+	for key := range sw.pendingStaleSeries {
+		if _, ok := missingKeys[key]; !ok {
+			delete(sw.pendingStaleSeries, key)
+		}
+	}
+
+	// Send stale markers for series, which are missing for longer than StalenessInterval.
+	deadline := timestamp - sw.Config.StalenessInterval.Milliseconds()
+	if staleSeries := sw.popPendingStaleSeries(deadline); staleSeries != "" {
+		sw.sendStaleSeries(staleSeries, "", timestamp, false)
+	}
+}
+
+// popPendingStaleSeries removes series, which disappeared before the given deadline in milliseconds, from sw.pendingStaleSeries.
+//
+// It returns the removed series in Prometheus text exposition format.
+func (sw *scrapeWork) popPendingStaleSeries(deadline int64) string {
+	var bb []byte
+	for key, disappearedAt := range sw.pendingStaleSeries {
+		if disappearedAt > deadline {
+			continue
+		}
+		bb = append(bb, key...)
+		bb = append(bb, " 0\n"...)
+		delete(sw.pendingStaleSeries, key)
+	}
+	return string(bb)
+}
+
 var sendStaleSeriesConcurrencyLimitCh = make(chan struct{}, cgroup.AvailableCPUs())
 
 func (sw *scrapeWork) sendStaleSeries(lastScrape, currScrape string, timestamp int64, addAutoSeries bool) {
@@ -797,11 +1026,19 @@ func (sw *scrapeWork) sendStaleSeries(lastScrape, currScrape string, timestamp i
 		// and https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3675
 		var mu sync.Mutex
 		br := bytes.NewBufferString(bodyString)
-		err := stream.Parse(br, timestamp, false, func(rows []parser.Row) error {
+		err := stream.Parse(br, timestamp, "", func(rows []parser.Row) error {
 			mu.Lock()
 			defer mu.Unlock()
 			for i := range rows {
-				sw.addRowToTimeseries(wc, &rows[i], timestamp, true)
+				r := &rows[i]
+				// Put stale markers after the last sample with the timestamp exposed by the target.
+				// Otherwise stale markers may overwrite this sample if honor_timestamps is set, which results in gaps.
+				if r.Timestamp <= timestamp {
+					r.Timestamp = 0
+				} else {
+					r.Timestamp++
+				}
+				sw.addRowToTimeseries(wc, r, timestamp, true)
 			}
 			// Apply series limit to stale markers in order to prevent sending stale markers for newly created series.
 			// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/3660
@@ -859,6 +1096,8 @@ type autoMetrics struct {
 	samplesPostRelabeling     int
 	seriesAdded               int
 	seriesLimitSamplesDropped int
+	cachedResponseAge         float64
+	responseSize              int
 }
 
 func isAutoMetric(s string) bool {
@@ -867,7 +1106,8 @@ func isAutoMetric(s string) bool {
 		"scrape_samples_post_metric_relabeling", "scrape_series_added",
 		"scrape_timeout_seconds", "scrape_samples_limit",
 		"scrape_series_limit_samples_dropped", "scrape_series_limit",
-		"scrape_series_current":
+		"scrape_series_current", "scrape_cached_response_age_seconds",
+		"scrape_response_size_bytes", "scrape_interval_seconds":
 		return true
 	}
 	return false
@@ -880,6 +1120,12 @@ func (sw *scrapeWork) addAutoMetrics(am *autoMetrics, wc *writeRequestCtx, times
 	sw.addAutoTimeseries(wc, "scrape_samples_post_metric_relabeling", float64(am.samplesPostRelabeling), timestamp)
 	sw.addAutoTimeseries(wc, "scrape_series_added", float64(am.seriesAdded), timestamp)
 	sw.addAutoTimeseries(wc, "scrape_timeout_seconds", sw.Config.ScrapeTimeout.Seconds(), timestamp)
+	if sw.Config.ExtendedMetrics {
+		// Expose extended metrics only if extended_scrape_metrics config is set for the target,
+		// since they increase the number of series per target.
+		sw.addAutoTimeseries(wc, "scrape_response_size_bytes", float64(am.responseSize), timestamp)
+		sw.addAutoTimeseries(wc, "scrape_interval_seconds", sw.Config.ScrapeInterval.Seconds(), timestamp)
+	}
 	if sampleLimit := sw.Config.SampleLimit; sampleLimit > 0 {
 		// Expose scrape_samples_limit metric if sample_limt config is set for the target.
 		// See https://github.com/VictoriaMetrics/operator/issues/497
@@ -890,6 +1136,11 @@ func (sw *scrapeWork) addAutoMetrics(am *autoMetrics, wc *writeRequestCtx, times
 		sw.addAutoTimeseries(wc, "scrape_series_limit", float64(sl.MaxItems()), timestamp)
 		sw.addAutoTimeseries(wc, "scrape_series_current", float64(sl.CurrentItems()), timestamp)
 	}
+	if sw.Config.ScrapeCacheMaxAge > 0 {
+		// Expose scrape_cached_response_age_seconds metric if scrape_cache_max_age config is set for the target.
+		// It is set to zero if the response is received from the target.
+		sw.addAutoTimeseries(wc, "scrape_cached_response_age_seconds", am.cachedResponseAge, timestamp)
+	}
 }
 
 // addAutoTimeseries adds automatically generated time series with the given name, value and timestamp.
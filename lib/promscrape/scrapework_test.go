@@ -2,16 +2,22 @@ package promscrape
 
 import (
 	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/auth"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/decimal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promrelabel"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
 	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+	"github.com/VictoriaMetrics/fasthttp"
 )
 
 func TestIsAutoMetric(t *testing.T) {
@@ -32,6 +38,9 @@ func TestIsAutoMetric(t *testing.T) {
 	f("scrape_series_limit_samples_dropped", true)
 	f("scrape_series_limit", true)
 	f("scrape_series_current", true)
+	f("scrape_cached_response_age_seconds", true)
+	f("scrape_response_size_bytes", true)
+	f("scrape_interval_seconds", true)
 
 	f("foobar", false)
 	f("exported_up", false)
@@ -118,6 +127,155 @@ func TestScrapeWorkScrapeInternalFailure(t *testing.T) {
 	}
 }
 
+func TestScrapeWorkScrapeStreamFailure(t *testing.T) {
+	dataExpected := `
+		up{instance="foo.com:1234",job="xx"} 0 123
+		scrape_samples_scraped{instance="foo.com:1234",job="xx"} 0 123
+		scrape_duration_seconds{instance="foo.com:1234",job="xx"} 0 123
+		scrape_samples_post_metric_relabeling{instance="foo.com:1234",job="xx"} 0 123
+		scrape_series_added{instance="foo.com:1234",job="xx"} 0 123
+		scrape_timeout_seconds{instance="foo.com:1234",job="xx"} 42 123
+		scrape_response_size_bytes{instance="foo.com:1234",job="xx"} 0 123
+		scrape_interval_seconds{instance="foo.com:1234",job="xx"} 60 123
+`
+	timeseriesExpected := parseData(dataExpected)
+
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		ScrapeInterval:  time.Minute,
+		ScrapeTimeout:   time.Second * 42,
+		StreamParse:     true,
+		ExtendedMetrics: true,
+		Labels: promutils.NewLabelsFromMap(map[string]string{
+			"instance": "foo.com:1234",
+			"job":      "xx",
+		}),
+	}
+	sw.GetStreamReader = func() (*streamReader, error) {
+		return nil, fmt.Errorf("dial tcp foo.com:1234: connect: connection refused")
+	}
+
+	pushDataCalls := 0
+	var pushDataErr error
+	sw.PushData = func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+		if err := expectEqualTimeseries(wr.Timeseries, timeseriesExpected); err != nil {
+			pushDataErr = fmt.Errorf("unexpected data pushed: %w\ngot\n%#v\nwant\n%#v", err, wr.Timeseries, timeseriesExpected)
+		}
+		pushDataCalls++
+	}
+
+	timestamp := int64(123000)
+	if err := sw.scrapeInternal(timestamp, timestamp); err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if pushDataErr != nil {
+		t.Fatalf("unexpected error: %s", pushDataErr)
+	}
+	if pushDataCalls != 1 {
+		t.Fatalf("unexpected number of pushData calls; got %d; want %d", pushDataCalls, 1)
+	}
+}
+
+func TestScrapeWorkScrapeInternalCachedResponse(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		ScrapeTimeout:     time.Second * 42,
+		ScrapeCacheMaxAge: time.Minute,
+	}
+
+	var readDataErr error
+	sw.ReadData = func(dst []byte) ([]byte, error) {
+		if readDataErr != nil {
+			return dst, readDataErr
+		}
+		return append(dst, "foo 1\nbar 2\n"...), nil
+	}
+
+	var dataExpected string
+	pushDataCalls := 0
+	var pushDataErr error
+	sw.PushData = func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+		pushDataCalls++
+		if pushDataCalls > 1 {
+			// Skip stale markers, which are pushed after the scraped data.
+			return
+		}
+		timeseriesExpected := parseData(dataExpected)
+		if err := expectEqualTimeseries(wr.Timeseries, timeseriesExpected); err != nil {
+			pushDataErr = fmt.Errorf("unexpected data pushed: %w\ngot\n%#v\nwant\n%#v", err, wr.Timeseries, timeseriesExpected)
+		}
+	}
+
+	f := func(timestamp int64, err error, data string, errExpected bool) {
+		t.Helper()
+		readDataErr = err
+		dataExpected = data
+		pushDataCalls = 0
+		pushDataErr = nil
+		err = sw.scrapeInternal(timestamp, timestamp)
+		if (err != nil) != errExpected {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pushDataErr != nil {
+			t.Fatalf("unexpected error: %s", pushDataErr)
+		}
+		if pushDataCalls == 0 {
+			t.Fatalf("missing pushData calls")
+		}
+	}
+
+	// Successful scrape must be cached.
+	f(123000, nil, `
+		foo 1 123
+		bar 2 123
+		up 1 123
+		scrape_samples_scraped 2 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 2 123
+		scrape_series_added 2 123
+		scrape_timeout_seconds 42 123
+		scrape_cached_response_age_seconds 0 123
+`, false)
+
+	// The cached response must be used on timeout.
+	f(153000, fmt.Errorf("error when scraping: %w", fasthttp.ErrTimeout), `
+		foo 1 153
+		bar 2 153
+		up 0 153
+		scrape_samples_scraped 2 153
+		scrape_duration_seconds 0 153
+		scrape_samples_post_metric_relabeling 2 153
+		scrape_series_added 0 153
+		scrape_timeout_seconds 42 153
+		scrape_cached_response_age_seconds 30 153
+`, true)
+
+	// The cached response mustn't be used on errors other than timeout.
+	f(163000, fmt.Errorf("connection refused"), `
+		up 0 163
+		scrape_samples_scraped 0 163
+		scrape_duration_seconds 0 163
+		scrape_samples_post_metric_relabeling 0 163
+		scrape_series_added 0 163
+		scrape_timeout_seconds 42 163
+		scrape_cached_response_age_seconds 0 163
+`, true)
+
+	// The cached response mustn't be used if it is older than ScrapeCacheMaxAge.
+	f(193000, fmt.Errorf("error when scraping: %w", fasthttp.ErrTimeout), `
+		up 0 193
+		scrape_samples_scraped 0 193
+		scrape_duration_seconds 0 193
+		scrape_samples_post_metric_relabeling 0 193
+		scrape_series_added 0 193
+		scrape_timeout_seconds 42 193
+		scrape_cached_response_age_seconds 0 193
+`, true)
+}
+
 func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 	f := func(data string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
@@ -181,6 +339,22 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		scrape_series_added 0 123
 		scrape_timeout_seconds 42 123
 	`)
+	// Samples must be dropped for metadata-only target
+	f(`
+		# HELP foo Foo help
+		# TYPE foo counter
+		foo{bar="baz"} 34.45 3
+	`, &ScrapeWork{
+		ScrapeTimeout: time.Second * 42,
+		MetadataOnly:  true,
+	}, `
+		up 1 123
+		scrape_samples_scraped 0 123
+		scrape_duration_seconds 0 123
+		scrape_samples_post_metric_relabeling 0 123
+		scrape_series_added 0 123
+		scrape_timeout_seconds 42 123
+	`)
 	f(`
 		foo{bar="baz",empty_label=""} 34.45 3
 		abc -2
@@ -239,8 +413,10 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		no_instance{instance="",job="some_job",label="val1",test=""} 5555
 		test_with_instance{instance="some_instance",job="some_job",label="val2",test=""} 1555
 	`, &ScrapeWork{
-		ScrapeTimeout: time.Second * 42,
-		HonorLabels:   true,
+		ScrapeInterval:  time.Second * 30,
+		ScrapeTimeout:   time.Second * 42,
+		HonorLabels:     true,
+		ExtendedMetrics: true,
 		Labels: promutils.NewLabelsFromMap(map[string]string{
 			"instance": "foobar",
 			"job":      "xxx",
@@ -254,6 +430,8 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 		scrape_samples_post_metric_relabeling{instance="foobar",job="xxx"} 2 123
 		scrape_series_added{instance="foobar",job="xxx"} 2 123
 		scrape_timeout_seconds{instance="foobar",job="xxx"} 42 123
+		scrape_response_size_bytes{instance="foobar",job="xxx"} 158 123
+		scrape_interval_seconds{instance="foobar",job="xxx"} 30 123
 	`)
 	f(`
 		no_instance{instance="",job="some_job",label="val1",test=""} 5555
@@ -469,6 +647,77 @@ func TestScrapeWorkScrapeInternalSuccess(t *testing.T) {
 	`)
 }
 
+func TestScrapeWorkScrapeStream(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		ScrapeTimeout: time.Second * 42,
+		StreamParse:   true,
+	}
+	var body string
+	sw.GetStreamReader = func() (*streamReader, error) {
+		return &streamReader{
+			r:           io.NopCloser(strings.NewReader(body)),
+			cancel:      func() {},
+			maxBodySize: math.MaxInt64,
+		}, nil
+	}
+	var seriesAdded float64
+	var staleSeries []string
+	sw.PushData = func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+		for _, ts := range wr.Timeseries {
+			name := ts.Labels[0].Value
+			if name == "scrape_series_added" {
+				seriesAdded = ts.Samples[0].Value
+			}
+			if decimal.IsStaleNaN(ts.Samples[0].Value) {
+				staleSeries = append(staleSeries, name)
+			}
+		}
+	}
+	f := func(data string, seriesAddedExpected float64, staleSeriesExpected []string) {
+		t.Helper()
+		body = data
+		seriesAdded = -1
+		staleSeries = nil
+		timestamp := int64(123000)
+		if err := sw.scrapeInternal(timestamp, timestamp); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if seriesAdded != seriesAddedExpected {
+			t.Fatalf("unexpected scrape_series_added; got %v; want %v", seriesAdded, seriesAddedExpected)
+		}
+		sort.Strings(staleSeries)
+		if !reflect.DeepEqual(staleSeries, staleSeriesExpected) {
+			t.Fatalf("unexpected stale series; got %q; want %q", staleSeries, staleSeriesExpected)
+		}
+	}
+
+	f("# HELP foo help\nfoo 1\nbar{x=\"y\"} 2\n", 2, nil)
+	// Changed values and order of series mustn't result in new series.
+	f("bar{x=\"y\"} 3 123\nfoo 4\n", 0, nil)
+	f("foo 5\nbaz 6\n", 1, []string{"bar"})
+	f("", 0, []string{"baz", "foo"})
+
+	// Big responses are split into multiple blocks with series keys.
+	var bb strings.Builder
+	for i := 0; i < 10000; i++ {
+		fmt.Fprintf(&bb, "metric_with_long_name_%d{instance=\"host-%d\"} %d\n", i, i, i)
+	}
+	f(bb.String(), 10000, nil)
+	f(bb.String(), 0, nil)
+	body = "metric_with_long_name_0{instance=\"host-0\"} 1\n"
+	staleSeries = nil
+	if err := sw.scrapeInternal(124000, 124000); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(staleSeries) != 9999 {
+		t.Fatalf("unexpected number of stale series; got %d; want %d", len(staleSeries), 9999)
+	}
+}
+
 func TestAddRowToTimeseriesNoRelabeling(t *testing.T) {
 	f := func(row string, cfg *ScrapeWork, dataExpected string) {
 		t.Helper()
@@ -741,6 +990,83 @@ func TestSendStaleSeries(t *testing.T) {
 	f(generateScrape(20000), generateScrape(10), 19990)
 }
 
+func TestSendStaleSeriesHonorTimestamps(t *testing.T) {
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		HonorTimestamps: true,
+	}
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	timestamps := make(map[string]int64)
+	sw.PushData = func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+		for _, ts := range wr.Timeseries {
+			if !decimal.IsStaleNaN(ts.Samples[0].Value) {
+				t.Errorf("expecting stale marker for %s; got %v", ts.Labels[0].Value, ts.Samples[0].Value)
+			}
+			timestamps[ts.Labels[0].Value] = ts.Samples[0].Timestamp
+		}
+	}
+	// Stale markers must be put after samples with timestamps exposed by the target.
+	sw.sendStaleSeries("foo 1 1670000001000\nbar 2 1670000005000\nbaz 3\n", "", 1670000003000, false)
+	timestampsExpected := map[string]int64{
+		"foo": 1670000003000,
+		"bar": 1670000005001,
+		"baz": 1670000003000,
+	}
+	if !reflect.DeepEqual(timestamps, timestampsExpected) {
+		t.Fatalf("unexpected stale marker timestamps; got %v; want %v", timestamps, timestampsExpected)
+	}
+}
+
+func TestProcessDisappearedSeriesStalenessInterval(t *testing.T) {
+	var sw scrapeWork
+	sw.Config = &ScrapeWork{
+		StalenessInterval: time.Minute,
+	}
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	var staleSeries []string
+	sw.PushData = func(at *auth.Token, wr *prompbmarshal.WriteRequest) {
+		for _, ts := range wr.Timeseries {
+			staleSeries = append(staleSeries, ts.Labels[0].Value)
+		}
+	}
+	f := func(lastScrape, currScrape string, timestamp int64, staleSeriesExpected []string) {
+		t.Helper()
+		staleSeries = nil
+		areIdenticalSeries := parser.AreIdenticalSeriesFast(lastScrape, currScrape)
+		sw.processDisappearedSeries(lastScrape, currScrape, areIdenticalSeries, timestamp)
+		sort.Strings(staleSeries)
+		if !reflect.DeepEqual(staleSeries, staleSeriesExpected) {
+			t.Fatalf("unexpected stale series at %d; got %q; want %q", timestamp, staleSeries, staleSeriesExpected)
+		}
+	}
+
+	// foo and bar disappear, but they aren't marked as stale until the staleness interval passes.
+	f("foo 1\nbar 1\nbaz 1\n", "baz 1\n", 10e3, nil)
+	f("baz 1\n", "baz 1\n", 40e3, nil)
+
+	// foo re-appears, so it mustn't be marked as stale.
+	f("baz 1\n", "baz 1\nfoo 1\n", 60e3, nil)
+	f("baz 1\nfoo 1\n", "baz 1\nfoo 1\n", 70e3, []string{"bar"})
+
+	// Failed scrape. baz and foo are marked as stale after the staleness interval.
+	f("baz 1\nfoo 1\n", "", 80e3, nil)
+	f("", "", 139e3, nil)
+	f("", "", 141e3, []string{"baz", "foo"})
+	if n := len(sw.pendingStaleSeries); n != 0 {
+		t.Fatalf("unexpected number of pending stale series; got %d; want 0", n)
+	}
+
+	// All the pending series must be returned on target removal.
+	f("foo 1\n", "", 150e3, nil)
+	if s := sw.popPendingStaleSeries(math.MaxInt64); s != "foo 0\n" {
+		t.Fatalf("unexpected pending stale series; got %q; want %q", s, "foo 0\n")
+	}
+}
+
 func parsePromRow(data string) *parser.Row {
 	var rows parser.Rows
 	errLogger := func(s string) {
@@ -0,0 +1,67 @@
+package promscrape
+
+import (
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+	"github.com/cespare/xxhash/v2"
+)
+
+// seriesKeysBlockSize is the maximum size of uncompressed block with series keys.
+const seriesKeysBlockSize = 64 * 1024
+
+// seriesKeys collects series keys from the scrape response parsed in stream mode.
+//
+// Keys are stored in independently compressed blocks, so big scrape responses
+// do not require big contiguous buffers while being parsed.
+type seriesKeys struct {
+	// hash is an order-independent hash for the collected keys.
+	hash uint64
+
+	// n is the number of the collected keys.
+	n int
+
+	// buf holds the current uncompressed block.
+	buf []byte
+
+	// blocks holds compressed blocks.
+	blocks [][]byte
+}
+
+// add adds series key for r to sk.
+func (sk *seriesKeys) add(r *parser.Row) {
+	n := len(sk.buf)
+	sk.buf = parser.AppendSeriesKey(sk.buf, r)
+	sk.hash += xxhash.Sum64(sk.buf[n:])
+	sk.n++
+	// Store keys in the format compatible with parser.GetRowsDiff output.
+	sk.buf = append(sk.buf, " 0\n"...)
+	if len(sk.buf) >= seriesKeysBlockSize {
+		sk.blocks = append(sk.blocks, encoding.CompressZSTDLevel(nil, sk.buf, 1))
+		sk.buf = sk.buf[:0]
+	}
+}
+
+// seriesHash returns a hash for the set of the collected keys.
+//
+// The hash doesn't depend on the order of keys in the scrape response.
+// Zero hash is never returned, so it can be used as a marker for unknown set of series.
+func (sk *seriesKeys) seriesHash() uint64 {
+	h := sk.hash ^ (uint64(sk.n) * 0x9e3779b97f4a7c15)
+	if h == 0 {
+		h = 1
+	}
+	return h
+}
+
+// marshal appends the collected keys in Prometheus text exposition format to dst and returns the result.
+func (sk *seriesKeys) marshal(dst []byte) []byte {
+	for _, block := range sk.blocks {
+		var err error
+		dst, err = encoding.DecompressZSTD(dst, block)
+		if err != nil {
+			logger.Panicf("BUG: cannot decompress series keys block: %s", err)
+		}
+	}
+	return append(dst, sk.buf...)
+}
@@ -0,0 +1,69 @@
+package promscrape
+
+import (
+	"fmt"
+	"testing"
+
+	parser "github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/prometheus"
+)
+
+func TestSeriesKeys(t *testing.T) {
+	f := func(data, keysExpected string) {
+		t.Helper()
+		var rows parser.Rows
+		rows.Unmarshal(data)
+		var sk seriesKeys
+		for i := range rows.Rows {
+			sk.add(&rows.Rows[i])
+		}
+		keys := string(sk.marshal(nil))
+		if keys != keysExpected {
+			t.Fatalf("unexpected keys;\ngot\n%s\nwant\n%s", keys, keysExpected)
+		}
+	}
+	f("", "")
+	f("# HELP foo bar\nfoo 1 123\n", "foo 0\n")
+	f(`foo{bar="baz",x="a\"b"} 1`+"\nbar 2\n", `foo{bar="baz",x="a\"b"} 0`+"\nbar 0\n")
+
+	// Big number of keys must be stored in multiple blocks.
+	var sk seriesKeys
+	var data, keysExpected []byte
+	for i := 0; i < 10000; i++ {
+		data = fmt.Appendf(data[:0], `metric_%d{job="foobar"} %d`, i, i)
+		var rows parser.Rows
+		rows.Unmarshal(string(data))
+		sk.add(&rows.Rows[0])
+		keysExpected = fmt.Appendf(keysExpected, "metric_%d{job=\"foobar\"} 0\n", i)
+	}
+	if len(sk.blocks) == 0 {
+		t.Fatalf("expecting non-empty compressed blocks")
+	}
+	if keys := sk.marshal(nil); string(keys) != string(keysExpected) {
+		t.Fatalf("unexpected keys after decompression")
+	}
+}
+
+func TestSeriesKeysHash(t *testing.T) {
+	hash := func(data string) uint64 {
+		var rows parser.Rows
+		rows.Unmarshal(data)
+		var sk seriesKeys
+		for i := range rows.Rows {
+			sk.add(&rows.Rows[i])
+		}
+		return sk.seriesHash()
+	}
+	f := func(data1, data2 string, equalExpected bool) {
+		t.Helper()
+		if equal := hash(data1) == hash(data2); equal != equalExpected {
+			t.Fatalf("unexpected hash equality for %q and %q; got %v; want %v", data1, data2, equal, equalExpected)
+		}
+	}
+	f("", "", true)
+	f("foo 1\nbar 2\n", "bar 3\n# comment\nfoo 4 123\n", true)
+	f("foo 1\n", "foo 1\nbar 2\n", false)
+	f(`foo{a="b"} 1`, `foo{a="c"} 1`, false)
+	if h := hash(""); h == 0 {
+		t.Fatalf("hash mustn't be zero")
+	}
+}
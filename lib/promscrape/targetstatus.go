@@ -122,7 +122,7 @@ func (tsm *targetStatusMap) Unregister(sw *scrapeWork) {
 	tsm.mu.Unlock()
 }
 
-func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, samplesScraped int, err error) {
+func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDuration int64, samplesScraped, responseSize int, err error) {
 	tsm.mu.Lock()
 	ts := tsm.m[sw]
 	if ts == nil {
@@ -135,6 +135,7 @@ func (tsm *targetStatusMap) Update(sw *scrapeWork, up bool, scrapeTime, scrapeDu
 	ts.scrapeTime = scrapeTime
 	ts.scrapeDuration = scrapeDuration
 	ts.samplesScraped = samplesScraped
+	ts.responseSize = responseSize
 	ts.scrapesTotal++
 	if !up {
 		ts.scrapesFailed++
@@ -208,6 +209,9 @@ func (tsm *targetStatusMap) WriteActiveTargetsJSON(w io.Writer) {
 		fmt.Fprintf(w, `,"lastScrape":%q`, time.Unix(ts.scrapeTime/1000, (ts.scrapeTime%1000)*1e6).Format(time.RFC3339Nano))
 		fmt.Fprintf(w, `,"lastScrapeDuration":%g`, (time.Millisecond * time.Duration(ts.scrapeDuration)).Seconds())
 		fmt.Fprintf(w, `,"lastSamplesScraped":%d`, ts.samplesScraped)
+		scrapeInterval := ts.sw.getEffectiveScrapeInterval()
+		fmt.Fprintf(w, `,"scrapeInterval":%q`, scrapeInterval)
+		fmt.Fprintf(w, `,"scrapeIntervalAdapted":%v`, scrapeInterval != ts.sw.Config.ScrapeInterval)
 		state := "up"
 		if !ts.up {
 			state = "down"
@@ -238,6 +242,7 @@ type targetStatus struct {
 	scrapeTime     int64
 	scrapeDuration int64
 	samplesScraped int
+	responseSize   int
 	scrapesTotal   int
 	scrapesFailed  int
 	err            error
@@ -1,10 +1,10 @@
 // Code generated by qtc from "targetstatus.qtpl". DO NOT EDIT.
 // See https://github.com/valyala/quicktemplate for details.
 
-//line lib/promscrape/targetstatus.qtpl:1
+//line targetstatus.qtpl:1
 package promscrape
 
-//line lib/promscrape/targetstatus.qtpl:1
+//line targetstatus.qtpl:1
 import (
 	"net/url"
 	"time"
@@ -13,913 +13,925 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/promutils"
 )
 
-//line lib/promscrape/targetstatus.qtpl:11
+//line targetstatus.qtpl:11
 import (
 	qtio422016 "io"
 
 	qt422016 "github.com/valyala/quicktemplate"
 )
 
-//line lib/promscrape/targetstatus.qtpl:11
+//line targetstatus.qtpl:11
 var (
 	_ = qtio422016.Copy
 	_ = qt422016.AcquireByteBuffer
 )
 
-//line lib/promscrape/targetstatus.qtpl:11
+//line targetstatus.qtpl:11
 func StreamTargetsResponsePlain(qw422016 *qt422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:13
+//line targetstatus.qtpl:13
 	if tsr.err != nil {
-//line lib/promscrape/targetstatus.qtpl:14
+//line targetstatus.qtpl:14
 		qw422016.N().S(tsr.err.Error())
-//line lib/promscrape/targetstatus.qtpl:15
+//line targetstatus.qtpl:15
 		return
-//line lib/promscrape/targetstatus.qtpl:16
+//line targetstatus.qtpl:16
 	}
-//line lib/promscrape/targetstatus.qtpl:18
+//line targetstatus.qtpl:18
 	for _, jts := range tsr.jobTargetsStatuses {
-//line lib/promscrape/targetstatus.qtpl:18
+//line targetstatus.qtpl:18
 		qw422016.N().S(`job=`)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(jts.jobName)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(`(`)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().D(jts.upCount)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(`/`)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().D(jts.targetsTotal)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:19
+//line targetstatus.qtpl:19
 		qw422016.N().S(`up)`)
-//line lib/promscrape/targetstatus.qtpl:20
+//line targetstatus.qtpl:20
 		qw422016.N().S(`
 `)
-//line lib/promscrape/targetstatus.qtpl:21
+//line targetstatus.qtpl:21
 		for _, ts := range jts.targetsStatus {
-//line lib/promscrape/targetstatus.qtpl:22
+//line targetstatus.qtpl:22
 			qw422016.N().S("\t")
-//line lib/promscrape/targetstatus.qtpl:22
+//line targetstatus.qtpl:22
 			qw422016.N().S(`state=`)
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			if ts.up {
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 				qw422016.N().S(`up`)
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			} else {
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 				qw422016.N().S(`down`)
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			}
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:23
+//line targetstatus.qtpl:23
 			qw422016.N().S(`endpoint=`)
-//line lib/promscrape/targetstatus.qtpl:24
+//line targetstatus.qtpl:24
 			qw422016.N().S(ts.sw.Config.ScrapeURL)
-//line lib/promscrape/targetstatus.qtpl:24
+//line targetstatus.qtpl:24
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:24
+//line targetstatus.qtpl:24
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:24
+//line targetstatus.qtpl:24
 			qw422016.N().S(`labels=`)
-//line lib/promscrape/targetstatus.qtpl:25
+//line targetstatus.qtpl:25
 			qw422016.N().S(ts.sw.Config.Labels.String())
-//line lib/promscrape/targetstatus.qtpl:25
+//line targetstatus.qtpl:25
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:25
+//line targetstatus.qtpl:25
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 			if filter.showOriginalLabels {
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 				qw422016.N().S(`originalLabels=`)
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 				qw422016.N().S(ts.sw.Config.OriginalLabels.String())
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 				qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 				qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 			}
-//line lib/promscrape/targetstatus.qtpl:26
+//line targetstatus.qtpl:26
 			qw422016.N().S(`scrapes_total=`)
-//line lib/promscrape/targetstatus.qtpl:27
+//line targetstatus.qtpl:27
 			qw422016.N().D(ts.scrapesTotal)
-//line lib/promscrape/targetstatus.qtpl:27
+//line targetstatus.qtpl:27
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:27
+//line targetstatus.qtpl:27
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:27
+//line targetstatus.qtpl:27
 			qw422016.N().S(`scrapes_failed=`)
-//line lib/promscrape/targetstatus.qtpl:28
+//line targetstatus.qtpl:28
 			qw422016.N().D(ts.scrapesFailed)
-//line lib/promscrape/targetstatus.qtpl:28
+//line targetstatus.qtpl:28
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:28
+//line targetstatus.qtpl:28
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:28
+//line targetstatus.qtpl:28
 			qw422016.N().S(`last_scrape=`)
-//line lib/promscrape/targetstatus.qtpl:29
+//line targetstatus.qtpl:29
 			qw422016.N().D(int(ts.getDurationFromLastScrape().Milliseconds()))
-//line lib/promscrape/targetstatus.qtpl:29
+//line targetstatus.qtpl:29
 			qw422016.N().S(`ms ago,`)
-//line lib/promscrape/targetstatus.qtpl:29
+//line targetstatus.qtpl:29
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:29
+//line targetstatus.qtpl:29
 			qw422016.N().S(`scrape_duration=`)
-//line lib/promscrape/targetstatus.qtpl:30
+//line targetstatus.qtpl:30
 			qw422016.N().D(int(ts.scrapeDuration))
-//line lib/promscrape/targetstatus.qtpl:30
+//line targetstatus.qtpl:30
 			qw422016.N().S(`ms,`)
-//line lib/promscrape/targetstatus.qtpl:30
+//line targetstatus.qtpl:30
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:30
+//line targetstatus.qtpl:30
 			qw422016.N().S(`samples_scraped=`)
-//line lib/promscrape/targetstatus.qtpl:31
+//line targetstatus.qtpl:31
 			qw422016.N().D(ts.samplesScraped)
-//line lib/promscrape/targetstatus.qtpl:31
+//line targetstatus.qtpl:31
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:31
+//line targetstatus.qtpl:31
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:31
+//line targetstatus.qtpl:31
+			qw422016.N().S(`response_size=`)
+//line targetstatus.qtpl:32
+			qw422016.N().D(ts.responseSize)
+//line targetstatus.qtpl:32
+			qw422016.N().S(`,`)
+//line targetstatus.qtpl:32
+			qw422016.N().S(` `)
+//line targetstatus.qtpl:32
 			qw422016.N().S(`error=`)
-//line lib/promscrape/targetstatus.qtpl:32
+//line targetstatus.qtpl:33
 			if ts.err != nil {
-//line lib/promscrape/targetstatus.qtpl:32
+//line targetstatus.qtpl:33
 				qw422016.N().S(ts.err.Error())
-//line lib/promscrape/targetstatus.qtpl:32
+//line targetstatus.qtpl:33
 			}
-//line lib/promscrape/targetstatus.qtpl:33
+//line targetstatus.qtpl:34
 			qw422016.N().S(`
 `)
-//line lib/promscrape/targetstatus.qtpl:34
+//line targetstatus.qtpl:35
 		}
-//line lib/promscrape/targetstatus.qtpl:35
+//line targetstatus.qtpl:36
 	}
-//line lib/promscrape/targetstatus.qtpl:37
+//line targetstatus.qtpl:38
 	for _, jobName := range tsr.emptyJobs {
-//line lib/promscrape/targetstatus.qtpl:37
+//line targetstatus.qtpl:38
 		qw422016.N().S(`job=`)
-//line lib/promscrape/targetstatus.qtpl:38
+//line targetstatus.qtpl:39
 		qw422016.N().S(jobName)
-//line lib/promscrape/targetstatus.qtpl:38
+//line targetstatus.qtpl:39
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:38
+//line targetstatus.qtpl:39
 		qw422016.N().S(`(0/0 up)`)
-//line lib/promscrape/targetstatus.qtpl:39
+//line targetstatus.qtpl:40
 		qw422016.N().S(`
 `)
-//line lib/promscrape/targetstatus.qtpl:40
+//line targetstatus.qtpl:41
 	}
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 }
 
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 func WriteTargetsResponsePlain(qq422016 qtio422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	StreamTargetsResponsePlain(qw422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 }
 
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 func TargetsResponsePlain(tsr *targetsStatusResult, filter *requestFilter) string {
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	WriteTargetsResponsePlain(qb422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:42
+//line targetstatus.qtpl:43
 }
 
-//line lib/promscrape/targetstatus.qtpl:44
+//line targetstatus.qtpl:45
 func StreamTargetsResponseHTML(qw422016 *qt422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:44
+//line targetstatus.qtpl:45
 	qw422016.N().S(`<!DOCTYPE html><html lang="en"><head>`)
-//line lib/promscrape/targetstatus.qtpl:48
+//line targetstatus.qtpl:49
 	htmlcomponents.StreamCommonHeader(qw422016)
-//line lib/promscrape/targetstatus.qtpl:48
+//line targetstatus.qtpl:49
 	qw422016.N().S(`<title>Active Targets</title></head><body>`)
-//line lib/promscrape/targetstatus.qtpl:52
+//line targetstatus.qtpl:53
 	htmlcomponents.StreamNavbar(qw422016)
-//line lib/promscrape/targetstatus.qtpl:52
+//line targetstatus.qtpl:53
 	qw422016.N().S(`<div class="container-fluid">`)
-//line lib/promscrape/targetstatus.qtpl:54
+//line targetstatus.qtpl:55
 	if tsr.err != nil {
-//line lib/promscrape/targetstatus.qtpl:55
+//line targetstatus.qtpl:56
 		htmlcomponents.StreamErrorNotification(qw422016, tsr.err)
-//line lib/promscrape/targetstatus.qtpl:56
+//line targetstatus.qtpl:57
 	}
-//line lib/promscrape/targetstatus.qtpl:56
+//line targetstatus.qtpl:57
 	qw422016.N().S(`<div class="row"><main class="col-12"><h1>Active Targets</h1><hr />`)
-//line lib/promscrape/targetstatus.qtpl:61
+//line targetstatus.qtpl:62
 	streamfiltersForm(qw422016, filter)
-//line lib/promscrape/targetstatus.qtpl:61
+//line targetstatus.qtpl:62
 	qw422016.N().S(`<hr />`)
-//line lib/promscrape/targetstatus.qtpl:63
+//line targetstatus.qtpl:64
 	streamtargetsTabs(qw422016, tsr, filter, "scrapeTargets")
-//line lib/promscrape/targetstatus.qtpl:63
+//line targetstatus.qtpl:64
 	qw422016.N().S(`</main></div></div></body></html>`)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 }
 
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 func WriteTargetsResponseHTML(qq422016 qtio422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	StreamTargetsResponseHTML(qw422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 }
 
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 func TargetsResponseHTML(tsr *targetsStatusResult, filter *requestFilter) string {
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	WriteTargetsResponseHTML(qb422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:69
+//line targetstatus.qtpl:70
 }
 
-//line lib/promscrape/targetstatus.qtpl:71
+//line targetstatus.qtpl:72
 func StreamServiceDiscoveryResponse(qw422016 *qt422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:71
+//line targetstatus.qtpl:72
 	qw422016.N().S(`<!DOCTYPE html><html lang="en"><head>`)
-//line lib/promscrape/targetstatus.qtpl:75
+//line targetstatus.qtpl:76
 	htmlcomponents.StreamCommonHeader(qw422016)
-//line lib/promscrape/targetstatus.qtpl:75
+//line targetstatus.qtpl:76
 	qw422016.N().S(`<title>Discovered Targets</title></head><body>`)
-//line lib/promscrape/targetstatus.qtpl:79
+//line targetstatus.qtpl:80
 	htmlcomponents.StreamNavbar(qw422016)
-//line lib/promscrape/targetstatus.qtpl:79
+//line targetstatus.qtpl:80
 	qw422016.N().S(`<div class="container-fluid">`)
-//line lib/promscrape/targetstatus.qtpl:81
+//line targetstatus.qtpl:82
 	if tsr.err != nil {
-//line lib/promscrape/targetstatus.qtpl:82
+//line targetstatus.qtpl:83
 		htmlcomponents.StreamErrorNotification(qw422016, tsr.err)
-//line lib/promscrape/targetstatus.qtpl:83
+//line targetstatus.qtpl:84
 	}
-//line lib/promscrape/targetstatus.qtpl:83
+//line targetstatus.qtpl:84
 	qw422016.N().S(`<div class="row"><main class="col-12"><h1>Discovered Targets</h1><hr />`)
-//line lib/promscrape/targetstatus.qtpl:88
+//line targetstatus.qtpl:89
 	streamfiltersForm(qw422016, filter)
-//line lib/promscrape/targetstatus.qtpl:88
+//line targetstatus.qtpl:89
 	qw422016.N().S(`<hr />`)
-//line lib/promscrape/targetstatus.qtpl:90
+//line targetstatus.qtpl:91
 	streamtargetsTabs(qw422016, tsr, filter, "discoveredTargets")
-//line lib/promscrape/targetstatus.qtpl:90
+//line targetstatus.qtpl:91
 	qw422016.N().S(`</main></div></div></body></html>`)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 }
 
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 func WriteServiceDiscoveryResponse(qq422016 qtio422016.Writer, tsr *targetsStatusResult, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	StreamServiceDiscoveryResponse(qw422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 }
 
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 func ServiceDiscoveryResponse(tsr *targetsStatusResult, filter *requestFilter) string {
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	WriteServiceDiscoveryResponse(qb422016, tsr, filter)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:96
+//line targetstatus.qtpl:97
 }
 
-//line lib/promscrape/targetstatus.qtpl:98
+//line targetstatus.qtpl:99
 func streamfiltersForm(qw422016 *qt422016.Writer, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:98
+//line targetstatus.qtpl:99
 	qw422016.N().S(`<div class="row g-3 align-items-center mb-3"><div class="col-auto"><button id="all-btn" type="button" class="btn`)
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 	if !filter.showOnlyUnhealthy {
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 		qw422016.N().S(`btn-secondary`)
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 	} else {
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 		qw422016.N().S(`btn-success`)
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 	}
-//line lib/promscrape/targetstatus.qtpl:101
+//line targetstatus.qtpl:102
 	qw422016.N().S(`"onclick="location.href='?`)
-//line lib/promscrape/targetstatus.qtpl:102
+//line targetstatus.qtpl:103
 	streamqueryArgs(qw422016, filter, map[string]string{"show_only_unhealthy": "false"})
-//line lib/promscrape/targetstatus.qtpl:102
+//line targetstatus.qtpl:103
 	qw422016.N().S(`'">All</button></div><div class="col-auto"><button id="unhealthy-btn" type="button" class="btn`)
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 	if filter.showOnlyUnhealthy {
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 		qw422016.N().S(`btn-secondary`)
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 	} else {
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 		qw422016.N().S(`btn-danger`)
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 	}
-//line lib/promscrape/targetstatus.qtpl:107
+//line targetstatus.qtpl:108
 	qw422016.N().S(`"onclick="location.href='?`)
-//line lib/promscrape/targetstatus.qtpl:108
+//line targetstatus.qtpl:109
 	streamqueryArgs(qw422016, filter, map[string]string{"show_only_unhealthy": "true"})
-//line lib/promscrape/targetstatus.qtpl:108
+//line targetstatus.qtpl:109
 	qw422016.N().S(`'">Unhealthy</button></div><div class="col-auto"><button type="button" class="btn btn-primary" onclick="document.querySelectorAll('.scrape-job').forEach((el) => { el.style.display = 'none'; })">Collapse all</button></div><div class="col-auto"><button type="button" class="btn btn-secondary" onclick="document.querySelectorAll('.scrape-job').forEach((el) => { el.style.display = 'block'; })">Expand all</button></div><div class="col-auto"><button type="button" class="btn btn-success" onclick="document.getElementById('filters').style.display='block'">Filter targets</button></div></div><div id="filters"`)
-//line lib/promscrape/targetstatus.qtpl:128
+//line targetstatus.qtpl:129
 	if filter.endpointSearch == "" && filter.labelSearch == "" {
-//line lib/promscrape/targetstatus.qtpl:128
+//line targetstatus.qtpl:129
 		qw422016.N().S(`style="display:none"`)
-//line lib/promscrape/targetstatus.qtpl:128
+//line targetstatus.qtpl:129
 	}
-//line lib/promscrape/targetstatus.qtpl:128
+//line targetstatus.qtpl:129
 	qw422016.N().S(`><form class="form-horizontal"><div class="form-group mb-3"><label for="endpoint_search" class="col-sm-10 control-label">Endpoint filter (<a target="_blank" href="https://github.com/google/re2/wiki/Syntax">Regexp</a> is accepted)</label><div class="col-sm-10"><input type="text" id="endpoint_search" name="endpoint_search"placeholder="For example, 127.0.0.1" class="form-control" value="`)
-//line lib/promscrape/targetstatus.qtpl:134
+//line targetstatus.qtpl:135
 	qw422016.E().S(filter.endpointSearch)
-//line lib/promscrape/targetstatus.qtpl:134
+//line targetstatus.qtpl:135
 	qw422016.N().S(`"/></div></div><div class="form-group mb-3"><label for="label_search" class="col-sm-10 control-label">Labels filter (<a target="_blank" href="https://prometheus.io/docs/prometheus/latest/querying/basics/#time-series-selectors">Arbitrary time series selectors</a> are accepted)</label><div class="col-sm-10"><input type="text" id="label_search" name="label_search"placeholder="For example, {instance=~'.+:9100'}" class="form-control" value="`)
-//line lib/promscrape/targetstatus.qtpl:141
+//line targetstatus.qtpl:142
 	qw422016.E().S(filter.labelSearch)
-//line lib/promscrape/targetstatus.qtpl:141
+//line targetstatus.qtpl:142
 	qw422016.N().S(`"/></div></div><input type="hidden" name="show_only_unhealthy" value="`)
-//line lib/promscrape/targetstatus.qtpl:144
+//line targetstatus.qtpl:145
 	qw422016.E().V(filter.showOnlyUnhealthy)
-//line lib/promscrape/targetstatus.qtpl:144
+//line targetstatus.qtpl:145
 	qw422016.N().S(`"/><input type="hidden" name="show_original_labels" value="`)
-//line lib/promscrape/targetstatus.qtpl:145
+//line targetstatus.qtpl:146
 	qw422016.E().V(filter.showOriginalLabels)
-//line lib/promscrape/targetstatus.qtpl:145
+//line targetstatus.qtpl:146
 	qw422016.N().S(`"/><button type="submit" class="btn btn-success mb-3">Submit</button><button type="button" class="btn btn-danger mb-3" onclick="location.href='?'">Clear target filters</button></form></div>`)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 }
 
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 func writefiltersForm(qq422016 qtio422016.Writer, filter *requestFilter) {
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	streamfiltersForm(qw422016, filter)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 }
 
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 func filtersForm(filter *requestFilter) string {
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	writefiltersForm(qb422016, filter)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:150
+//line targetstatus.qtpl:151
 }
 
-//line lib/promscrape/targetstatus.qtpl:152
+//line targetstatus.qtpl:153
 func streamtargetsTabs(qw422016 *qt422016.Writer, tsr *targetsStatusResult, filter *requestFilter, activeTab string) {
-//line lib/promscrape/targetstatus.qtpl:152
+//line targetstatus.qtpl:153
 	qw422016.N().S(`<ul class="nav nav-tabs" id="myTab" role="tablist"><li class="nav-item" role="presentation"><button class="nav-link`)
-//line lib/promscrape/targetstatus.qtpl:155
+//line targetstatus.qtpl:156
 	if activeTab == "scrapeTargets" {
-//line lib/promscrape/targetstatus.qtpl:155
+//line targetstatus.qtpl:156
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:155
+//line targetstatus.qtpl:156
 		qw422016.N().S(`active`)
-//line lib/promscrape/targetstatus.qtpl:155
+//line targetstatus.qtpl:156
 	}
-//line lib/promscrape/targetstatus.qtpl:155
+//line targetstatus.qtpl:156
 	qw422016.N().S(`" type="button" role="tab"onclick="location.href='targets?`)
-//line lib/promscrape/targetstatus.qtpl:156
+//line targetstatus.qtpl:157
 	streamqueryArgs(qw422016, filter, nil)
-//line lib/promscrape/targetstatus.qtpl:156
+//line targetstatus.qtpl:157
 	qw422016.N().S(`'">Active targets</button></li><li class="nav-item" role="presentation"><button class="nav-link`)
-//line lib/promscrape/targetstatus.qtpl:161
+//line targetstatus.qtpl:162
 	if activeTab == "discoveredTargets" {
-//line lib/promscrape/targetstatus.qtpl:161
+//line targetstatus.qtpl:162
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:161
+//line targetstatus.qtpl:162
 		qw422016.N().S(`active`)
-//line lib/promscrape/targetstatus.qtpl:161
+//line targetstatus.qtpl:162
 	}
-//line lib/promscrape/targetstatus.qtpl:161
+//line targetstatus.qtpl:162
 	qw422016.N().S(`" type="button" role="tab"onclick="location.href='service-discovery?`)
-//line lib/promscrape/targetstatus.qtpl:162
+//line targetstatus.qtpl:163
 	streamqueryArgs(qw422016, filter, nil)
-//line lib/promscrape/targetstatus.qtpl:162
+//line targetstatus.qtpl:163
 	qw422016.N().S(`'">Discovered targets</button></li></ul><div class="tab-content"><div class="tab-pane active" role="tabpanel">`)
-//line lib/promscrape/targetstatus.qtpl:169
+//line targetstatus.qtpl:170
 	switch activeTab {
-//line lib/promscrape/targetstatus.qtpl:170
+//line targetstatus.qtpl:171
 	case "scrapeTargets":
-//line lib/promscrape/targetstatus.qtpl:171
+//line targetstatus.qtpl:172
 		streamscrapeTargets(qw422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:172
+//line targetstatus.qtpl:173
 	case "discoveredTargets":
-//line lib/promscrape/targetstatus.qtpl:173
+//line targetstatus.qtpl:174
 		streamdiscoveredTargets(qw422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:174
+//line targetstatus.qtpl:175
 	}
-//line lib/promscrape/targetstatus.qtpl:174
+//line targetstatus.qtpl:175
 	qw422016.N().S(`</div></div>`)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 }
 
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 func writetargetsTabs(qq422016 qtio422016.Writer, tsr *targetsStatusResult, filter *requestFilter, activeTab string) {
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	streamtargetsTabs(qw422016, tsr, filter, activeTab)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 }
 
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 func targetsTabs(tsr *targetsStatusResult, filter *requestFilter, activeTab string) string {
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	writetargetsTabs(qb422016, tsr, filter, activeTab)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:177
+//line targetstatus.qtpl:178
 }
 
-//line lib/promscrape/targetstatus.qtpl:179
+//line targetstatus.qtpl:180
 func streamscrapeTargets(qw422016 *qt422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:179
+//line targetstatus.qtpl:180
 	qw422016.N().S(`<div class="row mt-4"><div class="col-12">`)
-//line lib/promscrape/targetstatus.qtpl:182
+//line targetstatus.qtpl:183
 	for i, jts := range tsr.jobTargetsStatuses {
-//line lib/promscrape/targetstatus.qtpl:183
+//line targetstatus.qtpl:184
 		streamscrapeJobTargets(qw422016, i, jts)
-//line lib/promscrape/targetstatus.qtpl:184
+//line targetstatus.qtpl:185
 	}
-//line lib/promscrape/targetstatus.qtpl:185
+//line targetstatus.qtpl:186
 	for i, jobName := range tsr.emptyJobs {
-//line lib/promscrape/targetstatus.qtpl:187
+//line targetstatus.qtpl:188
 		num := i + len(tsr.jobTargetsStatuses)
 		jts := &jobTargetsStatuses{
 			jobName: jobName,
 		}
 
-//line lib/promscrape/targetstatus.qtpl:192
+//line targetstatus.qtpl:193
 		streamscrapeJobTargets(qw422016, num, jts)
-//line lib/promscrape/targetstatus.qtpl:193
+//line targetstatus.qtpl:194
 	}
-//line lib/promscrape/targetstatus.qtpl:193
+//line targetstatus.qtpl:194
 	qw422016.N().S(`</div></div>`)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 }
 
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 func writescrapeTargets(qq422016 qtio422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	streamscrapeTargets(qw422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 }
 
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 func scrapeTargets(tsr *targetsStatusResult) string {
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	writescrapeTargets(qb422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:196
+//line targetstatus.qtpl:197
 }
 
-//line lib/promscrape/targetstatus.qtpl:198
+//line targetstatus.qtpl:199
 func streamscrapeJobTargets(qw422016 *qt422016.Writer, num int, jts *jobTargetsStatuses) {
-//line lib/promscrape/targetstatus.qtpl:198
+//line targetstatus.qtpl:199
 	qw422016.N().S(`<div class="row mb-4"><div class="col-12"><h4><span class="me-2">`)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.E().S(jts.jobName)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().S(`(`)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().D(jts.upCount)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().S(`/`)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().D(jts.targetsTotal)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:202
+//line targetstatus.qtpl:203
 	qw422016.N().S(`up)</span>`)
-//line lib/promscrape/targetstatus.qtpl:203
+//line targetstatus.qtpl:204
 	streamshowHideScrapeJobButtons(qw422016, num)
-//line lib/promscrape/targetstatus.qtpl:203
+//line targetstatus.qtpl:204
 	qw422016.N().S(`</h4><div id="scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:205
+//line targetstatus.qtpl:206
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:205
-	qw422016.N().S(`" class="scrape-job table-responsive"><table class="table table-striped table-hover table-bordered table-sm"><thead><tr><th scope="col">Endpoint</th><th scope="col">State</th><th scope="col" title="target labels">Labels</th><th scope="col" title="debug relabeling">Debug relabeling</th><th scope="col" title="total scrapes">Scrapes</th><th scope="col" title="total scrape errors">Errors</th><th scope="col" title="the time of the last scrape">Last Scrape</th><th scope="col" title="the duration of the last scrape">Duration</th><th scope="col" title="the number of metrics scraped during the last scrape">Samples</th><th scope="col" title="error from the last scrape (if any)">Last error</th></tr></thead><tbody>`)
-//line lib/promscrape/targetstatus.qtpl:222
+//line targetstatus.qtpl:206
+	qw422016.N().S(`" class="scrape-job table-responsive"><table class="table table-striped table-hover table-bordered table-sm"><thead><tr><th scope="col">Endpoint</th><th scope="col">State</th><th scope="col" title="target labels">Labels</th><th scope="col" title="debug relabeling">Debug relabeling</th><th scope="col" title="total scrapes">Scrapes</th><th scope="col" title="total scrape errors">Errors</th><th scope="col" title="the time of the last scrape">Last Scrape</th><th scope="col" title="the duration of the last scrape">Duration</th><th scope="col" title="the number of metrics scraped during the last scrape">Samples</th><th scope="col" title="the size of the response in bytes during the last scrape">Response size</th><th scope="col" title="error from the last scrape (if any)">Last error</th></tr></thead><tbody>`)
+//line targetstatus.qtpl:224
 	for _, ts := range jts.targetsStatus {
-//line lib/promscrape/targetstatus.qtpl:224
+//line targetstatus.qtpl:226
 		endpoint := ts.sw.Config.ScrapeURL
 		// The target is uniquely identified by a pointer to its original labels.
 		targetID := getLabelsID(ts.sw.Config.OriginalLabels)
 		lastScrapeDuration := ts.getDurationFromLastScrape()
 
-//line lib/promscrape/targetstatus.qtpl:228
+//line targetstatus.qtpl:230
 		qw422016.N().S(`<tr`)
-//line lib/promscrape/targetstatus.qtpl:229
+//line targetstatus.qtpl:231
 		if !ts.up {
-//line lib/promscrape/targetstatus.qtpl:229
+//line targetstatus.qtpl:231
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:229
+//line targetstatus.qtpl:231
 			qw422016.N().S(`class="alert alert-danger" role="alert"`)
-//line lib/promscrape/targetstatus.qtpl:229
+//line targetstatus.qtpl:231
 		}
-//line lib/promscrape/targetstatus.qtpl:229
+//line targetstatus.qtpl:231
 		qw422016.N().S(`><td class="endpoint"><a href="`)
-//line lib/promscrape/targetstatus.qtpl:231
+//line targetstatus.qtpl:233
 		qw422016.E().S(endpoint)
-//line lib/promscrape/targetstatus.qtpl:231
+//line targetstatus.qtpl:233
 		qw422016.N().S(`" target="_blank">`)
-//line lib/promscrape/targetstatus.qtpl:231
+//line targetstatus.qtpl:233
 		qw422016.E().S(endpoint)
-//line lib/promscrape/targetstatus.qtpl:231
+//line targetstatus.qtpl:233
 		qw422016.N().S(`</a> (<a href="target_response?id=`)
-//line lib/promscrape/targetstatus.qtpl:232
+//line targetstatus.qtpl:234
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:232
+//line targetstatus.qtpl:234
 		qw422016.N().S(`" target="_blank"title="click to fetch target response on behalf of the scraper">response</a>)</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:237
+//line targetstatus.qtpl:239
 		if ts.up {
-//line lib/promscrape/targetstatus.qtpl:237
+//line targetstatus.qtpl:239
 			qw422016.N().S(`<span class="badge bg-success">UP</span>`)
-//line lib/promscrape/targetstatus.qtpl:239
+//line targetstatus.qtpl:241
 		} else {
-//line lib/promscrape/targetstatus.qtpl:239
+//line targetstatus.qtpl:241
 			qw422016.N().S(`<span class="badge bg-danger">DOWN</span>`)
-//line lib/promscrape/targetstatus.qtpl:241
+//line targetstatus.qtpl:243
 		}
-//line lib/promscrape/targetstatus.qtpl:241
+//line targetstatus.qtpl:243
 		qw422016.N().S(`</td><td class="labels"><div title="click to show original labels"onclick="document.getElementById('original-labels-`)
-//line lib/promscrape/targetstatus.qtpl:245
+//line targetstatus.qtpl:247
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:245
+//line targetstatus.qtpl:247
 		qw422016.N().S(`').style.display='block'">`)
-//line lib/promscrape/targetstatus.qtpl:246
+//line targetstatus.qtpl:248
 		streamformatLabels(qw422016, ts.sw.Config.Labels)
-//line lib/promscrape/targetstatus.qtpl:246
+//line targetstatus.qtpl:248
 		qw422016.N().S(`</div><div style="display:none" id="original-labels-`)
-//line lib/promscrape/targetstatus.qtpl:248
+//line targetstatus.qtpl:250
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:248
+//line targetstatus.qtpl:250
 		qw422016.N().S(`">`)
-//line lib/promscrape/targetstatus.qtpl:249
+//line targetstatus.qtpl:251
 		streamformatLabels(qw422016, ts.sw.Config.OriginalLabels)
-//line lib/promscrape/targetstatus.qtpl:249
+//line targetstatus.qtpl:251
 		qw422016.N().S(`</div></td><td><a href="target-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:253
+//line targetstatus.qtpl:255
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:253
+//line targetstatus.qtpl:255
 		qw422016.N().S(`" target="_blank">target</a>`)
-//line lib/promscrape/targetstatus.qtpl:253
+//line targetstatus.qtpl:255
 		qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:253
+//line targetstatus.qtpl:255
 		qw422016.N().S(`<a href="metric-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:254
+//line targetstatus.qtpl:256
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:254
+//line targetstatus.qtpl:256
 		qw422016.N().S(`" target="_blank">metrics</a></td><td>`)
-//line lib/promscrape/targetstatus.qtpl:256
+//line targetstatus.qtpl:258
 		qw422016.N().D(ts.scrapesTotal)
-//line lib/promscrape/targetstatus.qtpl:256
+//line targetstatus.qtpl:258
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:257
+//line targetstatus.qtpl:259
 		qw422016.N().D(ts.scrapesFailed)
-//line lib/promscrape/targetstatus.qtpl:257
+//line targetstatus.qtpl:259
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:259
+//line targetstatus.qtpl:261
 		if lastScrapeDuration < 365*24*time.Hour {
-//line lib/promscrape/targetstatus.qtpl:260
+//line targetstatus.qtpl:262
 			qw422016.N().D(int(lastScrapeDuration.Milliseconds()))
-//line lib/promscrape/targetstatus.qtpl:260
+//line targetstatus.qtpl:262
 			qw422016.N().S(`ms ago`)
-//line lib/promscrape/targetstatus.qtpl:261
+//line targetstatus.qtpl:263
 		} else {
-//line lib/promscrape/targetstatus.qtpl:261
+//line targetstatus.qtpl:263
 			qw422016.N().S(`none`)
-//line lib/promscrape/targetstatus.qtpl:263
+//line targetstatus.qtpl:265
 		}
-//line lib/promscrape/targetstatus.qtpl:263
+//line targetstatus.qtpl:265
 		qw422016.N().S(`<td>`)
-//line lib/promscrape/targetstatus.qtpl:264
+//line targetstatus.qtpl:266
 		qw422016.N().D(int(ts.scrapeDuration))
-//line lib/promscrape/targetstatus.qtpl:264
+//line targetstatus.qtpl:266
 		qw422016.N().S(`ms</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:265
+//line targetstatus.qtpl:267
 		qw422016.N().D(ts.samplesScraped)
-//line lib/promscrape/targetstatus.qtpl:265
+//line targetstatus.qtpl:267
+		qw422016.N().S(`</td><td>`)
+//line targetstatus.qtpl:268
+		qw422016.N().D(ts.responseSize)
+//line targetstatus.qtpl:268
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:266
+//line targetstatus.qtpl:269
 		if ts.err != nil {
-//line lib/promscrape/targetstatus.qtpl:266
+//line targetstatus.qtpl:269
 			qw422016.E().S(ts.err.Error())
-//line lib/promscrape/targetstatus.qtpl:266
+//line targetstatus.qtpl:269
 		}
-//line lib/promscrape/targetstatus.qtpl:266
+//line targetstatus.qtpl:269
 		qw422016.N().S(`</td></tr>`)
-//line lib/promscrape/targetstatus.qtpl:268
+//line targetstatus.qtpl:271
 	}
-//line lib/promscrape/targetstatus.qtpl:268
+//line targetstatus.qtpl:271
 	qw422016.N().S(`</tbody></table></div></div></div>`)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 }
 
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 func writescrapeJobTargets(qq422016 qtio422016.Writer, num int, jts *jobTargetsStatuses) {
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	streamscrapeJobTargets(qw422016, num, jts)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 }
 
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 func scrapeJobTargets(num int, jts *jobTargetsStatuses) string {
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	writescrapeJobTargets(qb422016, num, jts)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:274
+//line targetstatus.qtpl:277
 }
 
-//line lib/promscrape/targetstatus.qtpl:276
+//line targetstatus.qtpl:279
 func streamdiscoveredTargets(qw422016 *qt422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:277
+//line targetstatus.qtpl:280
 	tljs := tsr.getTargetLabelsByJob()
 
-//line lib/promscrape/targetstatus.qtpl:277
+//line targetstatus.qtpl:280
 	qw422016.N().S(`<div class="row mt-4"><div class="col-12">`)
-//line lib/promscrape/targetstatus.qtpl:280
+//line targetstatus.qtpl:283
 	for i, tlj := range tljs {
-//line lib/promscrape/targetstatus.qtpl:281
+//line targetstatus.qtpl:284
 		streamdiscoveredJobTargets(qw422016, i, tlj)
-//line lib/promscrape/targetstatus.qtpl:282
+//line targetstatus.qtpl:285
 	}
-//line lib/promscrape/targetstatus.qtpl:282
+//line targetstatus.qtpl:285
 	qw422016.N().S(`</div></div>`)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 }
 
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 func writediscoveredTargets(qq422016 qtio422016.Writer, tsr *targetsStatusResult) {
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	streamdiscoveredTargets(qw422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 }
 
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 func discoveredTargets(tsr *targetsStatusResult) string {
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	writediscoveredTargets(qb422016, tsr)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:285
+//line targetstatus.qtpl:288
 }
 
-//line lib/promscrape/targetstatus.qtpl:287
+//line targetstatus.qtpl:290
 func streamdiscoveredJobTargets(qw422016 *qt422016.Writer, num int, tlj *targetLabelsByJob) {
-//line lib/promscrape/targetstatus.qtpl:287
+//line targetstatus.qtpl:290
 	qw422016.N().S(`<h4><span class="me-2">`)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.E().S(tlj.jobName)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().S(`(`)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().D(tlj.activeTargets)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().S(`/`)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().D(tlj.activeTargets + tlj.droppedTargets)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:289
+//line targetstatus.qtpl:292
 	qw422016.N().S(`active)</span>`)
-//line lib/promscrape/targetstatus.qtpl:290
+//line targetstatus.qtpl:293
 	streamshowHideScrapeJobButtons(qw422016, num)
-//line lib/promscrape/targetstatus.qtpl:290
+//line targetstatus.qtpl:293
 	qw422016.N().S(`</h4><div id="scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:292
+//line targetstatus.qtpl:295
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:292
+//line targetstatus.qtpl:295
 	qw422016.N().S(`" class="scrape-job table-responsive"><table class="table table-striped table-hover table-bordered table-sm"><thead><tr><th scope="col" style="width: 5%">Status</th><th scope="col" style="width: 60%">Discovered Labels</th><th scope="col" style="width: 30%">Target Labels</th><th scope="col" stile="width: 5%">Debug relabeling</a></tr></thead><tbody>`)
-//line lib/promscrape/targetstatus.qtpl:303
+//line targetstatus.qtpl:306
 	for _, t := range tlj.targets {
-//line lib/promscrape/targetstatus.qtpl:303
+//line targetstatus.qtpl:306
 		qw422016.N().S(`<tr`)
-//line lib/promscrape/targetstatus.qtpl:305
+//line targetstatus.qtpl:308
 		if !t.up {
-//line lib/promscrape/targetstatus.qtpl:306
+//line targetstatus.qtpl:309
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:306
+//line targetstatus.qtpl:309
 			qw422016.N().S(`role="alert"`)
-//line lib/promscrape/targetstatus.qtpl:306
+//line targetstatus.qtpl:309
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:307
+//line targetstatus.qtpl:310
 			if t.labels.Len() > 0 {
-//line lib/promscrape/targetstatus.qtpl:307
+//line targetstatus.qtpl:310
 				qw422016.N().S(`class="alert alert-danger"`)
-//line lib/promscrape/targetstatus.qtpl:309
+//line targetstatus.qtpl:312
 			} else {
-//line lib/promscrape/targetstatus.qtpl:309
+//line targetstatus.qtpl:312
 				qw422016.N().S(`class="alert alert-warning"`)
-//line lib/promscrape/targetstatus.qtpl:311
+//line targetstatus.qtpl:314
 			}
-//line lib/promscrape/targetstatus.qtpl:312
+//line targetstatus.qtpl:315
 		}
-//line lib/promscrape/targetstatus.qtpl:312
+//line targetstatus.qtpl:315
 		qw422016.N().S(`><td>`)
-//line lib/promscrape/targetstatus.qtpl:315
+//line targetstatus.qtpl:318
 		if t.up {
-//line lib/promscrape/targetstatus.qtpl:315
+//line targetstatus.qtpl:318
 			qw422016.N().S(`<span class="badge bg-success">UP</span>`)
-//line lib/promscrape/targetstatus.qtpl:317
+//line targetstatus.qtpl:320
 		} else if t.labels.Len() > 0 {
-//line lib/promscrape/targetstatus.qtpl:317
+//line targetstatus.qtpl:320
 			qw422016.N().S(`<span class="badge bg-danger">DOWN</span>`)
-//line lib/promscrape/targetstatus.qtpl:319
+//line targetstatus.qtpl:322
 		} else {
-//line lib/promscrape/targetstatus.qtpl:319
+//line targetstatus.qtpl:322
 			qw422016.N().S(`<span class="badge bg-warning">DROPPED</span>`)
-//line lib/promscrape/targetstatus.qtpl:321
+//line targetstatus.qtpl:324
 		}
-//line lib/promscrape/targetstatus.qtpl:321
+//line targetstatus.qtpl:324
 		qw422016.N().S(`</td><td class="labels">`)
-//line lib/promscrape/targetstatus.qtpl:324
+//line targetstatus.qtpl:327
 		streamformatLabels(qw422016, t.originalLabels)
-//line lib/promscrape/targetstatus.qtpl:324
+//line targetstatus.qtpl:327
 		qw422016.N().S(`</td><td class="labels">`)
-//line lib/promscrape/targetstatus.qtpl:327
+//line targetstatus.qtpl:330
 		streamformatLabels(qw422016, t.labels)
-//line lib/promscrape/targetstatus.qtpl:327
+//line targetstatus.qtpl:330
 		qw422016.N().S(`</td><td>`)
-//line lib/promscrape/targetstatus.qtpl:330
+//line targetstatus.qtpl:333
 		targetID := getLabelsID(t.originalLabels)
 
-//line lib/promscrape/targetstatus.qtpl:330
+//line targetstatus.qtpl:333
 		qw422016.N().S(`<a href="target-relabel-debug?id=`)
-//line lib/promscrape/targetstatus.qtpl:331
+//line targetstatus.qtpl:334
 		qw422016.E().S(targetID)
-//line lib/promscrape/targetstatus.qtpl:331
+//line targetstatus.qtpl:334
 		qw422016.N().S(`" target="_blank">debug</a></td></tr>`)
-//line lib/promscrape/targetstatus.qtpl:334
+//line targetstatus.qtpl:337
 	}
-//line lib/promscrape/targetstatus.qtpl:334
+//line targetstatus.qtpl:337
 	qw422016.N().S(`</tbody></table></div>`)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 }
 
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 func writediscoveredJobTargets(qq422016 qtio422016.Writer, num int, tlj *targetLabelsByJob) {
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	streamdiscoveredJobTargets(qw422016, num, tlj)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 }
 
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 func discoveredJobTargets(num int, tlj *targetLabelsByJob) string {
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	writediscoveredJobTargets(qb422016, num, tlj)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:338
+//line targetstatus.qtpl:341
 }
 
-//line lib/promscrape/targetstatus.qtpl:340
+//line targetstatus.qtpl:343
 func streamshowHideScrapeJobButtons(qw422016 *qt422016.Writer, num int) {
-//line lib/promscrape/targetstatus.qtpl:340
+//line targetstatus.qtpl:343
 	qw422016.N().S(`<button type="button" class="btn btn-primary btn-sm me-1"onclick="document.getElementById('scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:342
+//line targetstatus.qtpl:345
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:342
+//line targetstatus.qtpl:345
 	qw422016.N().S(`').style.display='none'">collapse</button><button type="button" class="btn btn-secondary btn-sm me-1"onclick="document.getElementById('scrape-job-`)
-//line lib/promscrape/targetstatus.qtpl:346
+//line targetstatus.qtpl:349
 	qw422016.N().D(num)
-//line lib/promscrape/targetstatus.qtpl:346
+//line targetstatus.qtpl:349
 	qw422016.N().S(`').style.display='block'">expand</button>`)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 }
 
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 func writeshowHideScrapeJobButtons(qq422016 qtio422016.Writer, num int) {
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	streamshowHideScrapeJobButtons(qw422016, num)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 }
 
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 func showHideScrapeJobButtons(num int) string {
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	writeshowHideScrapeJobButtons(qb422016, num)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:349
+//line targetstatus.qtpl:352
 }
 
-//line lib/promscrape/targetstatus.qtpl:351
+//line targetstatus.qtpl:354
 func streamqueryArgs(qw422016 *qt422016.Writer, filter *requestFilter, override map[string]string) {
-//line lib/promscrape/targetstatus.qtpl:353
+//line targetstatus.qtpl:356
 	showOnlyUnhealthy := "false"
 	if filter.showOnlyUnhealthy {
 		showOnlyUnhealthy = "true"
@@ -937,89 +949,89 @@ func streamqueryArgs(qw422016 *qt422016.Writer, filter *requestFilter, override
 		qa[k] = []string{v}
 	}
 
-//line lib/promscrape/targetstatus.qtpl:370
+//line targetstatus.qtpl:373
 	qw422016.E().S(qa.Encode())
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 }
 
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 func writequeryArgs(qq422016 qtio422016.Writer, filter *requestFilter, override map[string]string) {
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	streamqueryArgs(qw422016, filter, override)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 }
 
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 func queryArgs(filter *requestFilter, override map[string]string) string {
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	writequeryArgs(qb422016, filter, override)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:371
+//line targetstatus.qtpl:374
 }
 
-//line lib/promscrape/targetstatus.qtpl:373
+//line targetstatus.qtpl:376
 func streamformatLabels(qw422016 *qt422016.Writer, labels *promutils.Labels) {
-//line lib/promscrape/targetstatus.qtpl:374
+//line targetstatus.qtpl:377
 	labelsList := labels.GetLabels()
 
-//line lib/promscrape/targetstatus.qtpl:374
+//line targetstatus.qtpl:377
 	qw422016.N().S(`{`)
-//line lib/promscrape/targetstatus.qtpl:376
+//line targetstatus.qtpl:379
 	for i, label := range labelsList {
-//line lib/promscrape/targetstatus.qtpl:377
+//line targetstatus.qtpl:380
 		qw422016.E().S(label.Name)
-//line lib/promscrape/targetstatus.qtpl:377
+//line targetstatus.qtpl:380
 		qw422016.N().S(`=`)
-//line lib/promscrape/targetstatus.qtpl:377
+//line targetstatus.qtpl:380
 		qw422016.E().Q(label.Value)
-//line lib/promscrape/targetstatus.qtpl:378
+//line targetstatus.qtpl:381
 		if i+1 < len(labelsList) {
-//line lib/promscrape/targetstatus.qtpl:378
+//line targetstatus.qtpl:381
 			qw422016.N().S(`,`)
-//line lib/promscrape/targetstatus.qtpl:378
+//line targetstatus.qtpl:381
 			qw422016.N().S(` `)
-//line lib/promscrape/targetstatus.qtpl:378
+//line targetstatus.qtpl:381
 		}
-//line lib/promscrape/targetstatus.qtpl:379
+//line targetstatus.qtpl:382
 	}
-//line lib/promscrape/targetstatus.qtpl:379
+//line targetstatus.qtpl:382
 	qw422016.N().S(`}`)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 }
 
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 func writeformatLabels(qq422016 qtio422016.Writer, labels *promutils.Labels) {
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	qw422016 := qt422016.AcquireWriter(qq422016)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	streamformatLabels(qw422016, labels)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	qt422016.ReleaseWriter(qw422016)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 }
 
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 func formatLabels(labels *promutils.Labels) string {
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	qb422016 := qt422016.AcquireByteBuffer()
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	writeformatLabels(qb422016, labels)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	qs422016 := string(qb422016.B)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	qt422016.ReleaseByteBuffer(qb422016)
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 	return qs422016
-//line lib/promscrape/targetstatus.qtpl:381
+//line targetstatus.qtpl:384
 }
@@ -1,13 +1,64 @@
 package common
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
 	"io"
+	"net/http"
 	"sync"
 
+	"github.com/golang/snappy"
 	"github.com/klauspost/compress/gzip"
 	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
 )
 
+// SupportedContentEncodings contains a comma-separated list of Content-Encoding values supported by GetUncompressedReader.
+const SupportedContentEncodings = "gzip, deflate, zstd, snappy"
+
+// GetUncompressedReader returns reader for uncompressed data read from r, which is compressed with the given contentEncoding.
+//
+// Empty contentEncoding and `identity` mean that the data isn't compressed.
+// Return back the reader when it is no longer needed with PutUncompressedReader.
+func GetUncompressedReader(r io.Reader, contentEncoding string) (io.Reader, error) {
+	switch contentEncoding {
+	case "", "identity":
+		return r, nil
+	case "gzip":
+		return GetGzipReader(r)
+	case "deflate":
+		return GetZlibReader(r)
+	case "zstd":
+		return getZstdReader(r)
+	case "snappy":
+		return getSnappyReader(r)
+	default:
+		return nil, &httpserver.ErrorWithStatusCode{
+			Err:        fmt.Errorf("unsupported Content-Encoding: %q; supported values: %s", contentEncoding, SupportedContentEncodings),
+			StatusCode: http.StatusUnsupportedMediaType,
+		}
+	}
+}
+
+// PutUncompressedReader returns back the reader obtained via GetUncompressedReader.
+func PutUncompressedReader(r io.Reader) {
+	switch t := r.(type) {
+	case *gzip.Reader:
+		PutGzipReader(t)
+	case *zstd.Decoder:
+		putZstdReader(t)
+	case *snappy.Reader:
+		putSnappyReader(t)
+	case io.ReadCloser:
+		if _, ok := t.(zlib.Resetter); ok {
+			PutZlibReader(t)
+		}
+	}
+}
+
 // GetGzipReader returns new gzip reader from the pool.
 //
 // Return back the gzip reader when it no longer needed with PutGzipReader.
@@ -51,3 +102,79 @@ func PutZlibReader(zr io.ReadCloser) {
 }
 
 var zlibReaderPool sync.Pool
+
+func getZstdReader(r io.Reader) (*zstd.Decoder, error) {
+	v := zstdReaderPool.Get()
+	if v == nil {
+		// Disable concurrent decoding, since every request is processed by a separate goroutine.
+		return zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	}
+	zr := v.(*zstd.Decoder)
+	if err := zr.Reset(r); err != nil {
+		return nil, err
+	}
+	return zr, nil
+}
+
+func putZstdReader(zr *zstd.Decoder) {
+	// Do not call zr.Close(), since it makes zr unusable.
+	_ = zr.Reset(nil)
+	zstdReaderPool.Put(zr)
+}
+
+var zstdReaderPool sync.Pool
+
+// snappyStreamHeader is the header of snappy framing format.
+//
+// See https://github.com/google/snappy/blob/main/framing_format.txt
+const snappyStreamHeader = "\xff\x06\x00\x00sNaPpY"
+
+// maxSnappyBlockSize is the maximum size of uncompressed data in snappy block format.
+//
+// Snappy block format cannot be decoded in streaming manner, so it is decoded in memory.
+// Bigger data must be sent in snappy framing format.
+const maxSnappyBlockSize = 64 * 1024 * 1024
+
+// getSnappyReader returns reader for snappy-compressed data from r.
+//
+// Both snappy framing format and snappy block format are supported.
+// The block format is used by Prometheus remote_write protocol.
+func getSnappyReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, len(snappyStreamHeader))
+	header, err := br.Peek(len(snappyStreamHeader))
+	if err == nil && string(header) == snappyStreamHeader {
+		v := snappyReaderPool.Get()
+		if v == nil {
+			return snappy.NewReader(br), nil
+		}
+		sr := v.(*snappy.Reader)
+		sr.Reset(br)
+		return sr, nil
+	}
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(br, maxSnappyBlockSize+1))
+	if err != nil {
+		return nil, err
+	}
+	n, err := snappy.DecodedLen(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode snappy block: %w", err)
+	}
+	if n > maxSnappyBlockSize || len(data) > maxSnappyBlockSize {
+		return nil, fmt.Errorf("too big snappy block; it mustn't exceed %d bytes; use snappy framing format for bigger data", maxSnappyBlockSize)
+	}
+	buf, err := snappy.Decode(nil, data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode snappy block: %w", err)
+	}
+	return bytes.NewReader(buf), nil
+}
+
+func putSnappyReader(sr *snappy.Reader) {
+	sr.Reset(nil)
+	snappyReaderPool.Put(sr)
+}
+
+var snappyReaderPool sync.Pool
@@ -0,0 +1,90 @@
+package common
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zlib"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+)
+
+func TestGetUncompressedReader(t *testing.T) {
+	data := strings.Repeat("foo{bar=\"baz\"} 123 456\n", 100)
+
+	f := func(contentEncoding string, compressedData []byte) {
+		t.Helper()
+		// Run multiple times in order to verify readers obtained from the pool.
+		for i := 0; i < 3; i++ {
+			r, err := GetUncompressedReader(bytes.NewReader(compressedData), contentEncoding)
+			if err != nil {
+				t.Fatalf("unexpected error for Content-Encoding=%q: %s", contentEncoding, err)
+			}
+			result, err := io.ReadAll(r)
+			PutUncompressedReader(r)
+			if err != nil {
+				t.Fatalf("cannot read data for Content-Encoding=%q: %s", contentEncoding, err)
+			}
+			if string(result) != data {
+				t.Fatalf("unexpected data for Content-Encoding=%q; got\n%q\nwant\n%q", contentEncoding, result, data)
+			}
+		}
+	}
+
+	f("", []byte(data))
+	f("identity", []byte(data))
+
+	var bb bytes.Buffer
+	zw := gzip.NewWriter(&bb)
+	_, _ = zw.Write([]byte(data))
+	_ = zw.Close()
+	f("gzip", bb.Bytes())
+
+	bb.Reset()
+	zlw := zlib.NewWriter(&bb)
+	_, _ = zlw.Write([]byte(data))
+	_ = zlw.Close()
+	f("deflate", bb.Bytes())
+
+	bb.Reset()
+	zsw, err := zstd.NewWriter(&bb)
+	if err != nil {
+		t.Fatalf("cannot create zstd writer: %s", err)
+	}
+	_, _ = zsw.Write([]byte(data))
+	_ = zsw.Close()
+	f("zstd", bb.Bytes())
+
+	// snappy framing format
+	bb.Reset()
+	sw := snappy.NewBufferedWriter(&bb)
+	_, _ = sw.Write([]byte(data))
+	_ = sw.Close()
+	f("snappy", bb.Bytes())
+
+	// snappy block format
+	f("snappy", snappy.Encode(nil, []byte(data)))
+}
+
+func TestGetUncompressedReaderFailure(t *testing.T) {
+	_, err := GetUncompressedReader(strings.NewReader("foo"), "br")
+	if err == nil {
+		t.Fatalf("expecting non-nil error for unsupported Content-Encoding")
+	}
+	var esc *httpserver.ErrorWithStatusCode
+	if !errors.As(err, &esc) || esc.StatusCode != http.StatusUnsupportedMediaType {
+		t.Fatalf("expecting error with status code %d; got %v", http.StatusUnsupportedMediaType, err)
+	}
+
+	// Invalid snappy block
+	if _, err := GetUncompressedReader(strings.NewReader("\xff\xff\xff\xff\xff"), "snappy"); err == nil {
+		t.Fatalf("expecting non-nil error for invalid snappy block")
+	}
+}
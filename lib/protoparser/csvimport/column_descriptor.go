@@ -80,7 +80,7 @@ func ParseColumnDescriptors(s string) ([]ColumnDescriptor, error) {
 			if hasTimeCol {
 				return nil, fmt.Errorf("duplicate time column has been found at entry #%d %q for %q", i+1, col, s)
 			}
-			parseTimestamp, err := parseTimeFormat(a[2])
+			parseTimestamp, err := ParseTimeFormat(a[2])
 			if err != nil {
 				return nil, fmt.Errorf("cannot parse time format from the entry #%d %q: %w", i+1, col, err)
 			}
@@ -116,7 +116,11 @@ func ParseColumnDescriptors(s string) ([]ColumnDescriptor, error) {
 	return cds, nil
 }
 
-func parseTimeFormat(format string) (func(s string) (int64, error), error) {
+// ParseTimeFormat returns a function for parsing timestamps in the given format into unix timestamps in milliseconds.
+//
+// The following formats are supported: unix_s, unix_ms, unix_ns, rfc3339 and custom:<layout>.
+// See ParseColumnDescriptors for details.
+func ParseTimeFormat(format string) (func(s string) (int64, error), error) {
 	if strings.HasPrefix(format, "custom:") {
 		format = format[len("custom:"):]
 		return newParseCustomTimeFunc(format), nil
@@ -38,14 +38,12 @@ func Parse(req *http.Request, callback func(rows []csvimport.Row) error) error {
 	if err != nil {
 		return fmt.Errorf("cannot parse the provided csv format: %w", err)
 	}
-	if req.Header.Get("Content-Encoding") == "gzip" {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped csv data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, req.Header.Get("Content-Encoding"))
+	if err != nil {
+		return fmt.Errorf("cannot decompress csv data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 	ctx := getStreamContext(r)
 	defer putStreamContext(ctx)
 	for ctx.Read() {
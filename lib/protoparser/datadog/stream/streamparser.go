@@ -39,22 +39,12 @@ func Parse(r io.Reader, contentEncoding string, callback func(series []datadog.S
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
 
-	switch contentEncoding {
-	case "gzip":
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped DataDog data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
-	case "deflate":
-		zlr, err := common.GetZlibReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read deflated DataDog data: %w", err)
-		}
-		defer common.PutZlibReader(zlr)
-		r = zlr
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return fmt.Errorf("cannot decompress DataDog data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 	ctx := getPushCtx(r)
 	defer putPushCtx(ctx)
 	if err := ctx.Read(); err != nil {
@@ -28,19 +28,17 @@ var (
 // The callback can be called concurrently multiple times for streamed data from r.
 //
 // callback shouldn't hold rows after returning.
-func Parse(r io.Reader, isGzipped bool, precision, db string, callback func(db string, rows []influx.Row) error) error {
+func Parse(r io.Reader, contentEncoding string, precision, db string, callback func(db string, rows []influx.Row) error) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
 
-	if isGzipped {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped influx line protocol data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return fmt.Errorf("cannot decompress influx line protocol data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 
 	tsMultiplier := int64(0)
 	switch precision {
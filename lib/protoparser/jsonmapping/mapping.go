@@ -0,0 +1,312 @@
+package jsonmapping
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/valyala/fastjson"
+	"gopkg.in/yaml.v2"
+)
+
+// MappingConfig is a config for extracting metrics from arbitrary JSON documents.
+//
+// See https://docs.victoriametrics.com/#how-to-import-arbitrary-json-documents
+type MappingConfig struct {
+	Metrics []MetricMapping `yaml:"metrics"`
+}
+
+// MetricMapping describes how to extract a single metric from JSON document.
+//
+// Paths must start either with `$` for referring the document root or with `@` for referring the current item from Items.
+// For example, `$.device.id`, `@.readings[0].value` or `$['dotted.key']`.
+type MetricMapping struct {
+	// Items is an optional path to an array in the form `$.path.to.array[*]`.
+	//
+	// If set, then a sample is extracted from every array item.
+	Items string `yaml:"items,omitempty"`
+
+	// Name is the metric name.
+	Name string `yaml:"name,omitempty"`
+
+	// NamePath is the path to the metric name. It is used if Name is empty.
+	NamePath string `yaml:"name_path,omitempty"`
+
+	// ValuePath is the path to the metric value.
+	//
+	// The value may be a number, a boolean or a string containing a number.
+	ValuePath string `yaml:"value_path"`
+
+	// TimestampPath is an optional path to the sample timestamp.
+	//
+	// The timestamp may be a number in TimestampUnit units or an RFC3339 string.
+	// The current time is used if the timestamp is missing.
+	TimestampPath string `yaml:"timestamp_path,omitempty"`
+
+	// TimestampUnit is the unit for numeric timestamps: s, ms, us or ns. By default ms is used.
+	TimestampUnit string `yaml:"timestamp_unit,omitempty"`
+
+	// Labels contains label names with paths to label values.
+	//
+	// Values not starting with `$` or `@` are used as constant label values.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	items         *path
+	namePath      *path
+	valuePath     *path
+	timestampPath *path
+	timestampMult int64
+	labels        []labelMapping
+}
+
+type labelMapping struct {
+	name  string
+	value string
+	path  *path
+}
+
+// Mapper extracts metrics from JSON documents according to MappingConfig.
+type Mapper struct {
+	metrics []MetricMapping
+}
+
+// LoadMapper loads mapping config from the given path, which can be either local file or http url.
+func LoadMapper(path string) (*Mapper, error) {
+	data, err := fs.ReadFileOrHTTP(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read JSON mapping config: %w", err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars in %q: %w", path, err)
+	}
+	m, err := ParseMapper(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseMapper parses mapping config from data.
+func ParseMapper(data []byte) (*Mapper, error) {
+	var cfg MappingConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.Metrics) == 0 {
+		return nil, fmt.Errorf("`metrics` list cannot be empty")
+	}
+	for i := range cfg.Metrics {
+		mm := &cfg.Metrics[i]
+		if err := mm.init(); err != nil {
+			return nil, fmt.Errorf("invalid metric mapping #%d: %w", i+1, err)
+		}
+	}
+	return &Mapper{
+		metrics: cfg.Metrics,
+	}, nil
+}
+
+func (mm *MetricMapping) init() error {
+	if mm.Items != "" {
+		p, err := parsePath(mm.Items)
+		if err != nil {
+			return fmt.Errorf("cannot parse `items`: %w", err)
+		}
+		if len(p.steps) == 0 || !p.steps[len(p.steps)-1].wildcard {
+			return fmt.Errorf("`items` must end with `[*]`; got %q", mm.Items)
+		}
+		p.steps = p.steps[:len(p.steps)-1]
+		if p.hasWildcard() {
+			return fmt.Errorf("`items` may contain only a single `[*]` at the end; got %q", mm.Items)
+		}
+		if p.relative {
+			return fmt.Errorf("`items` must start with `$`; got %q", mm.Items)
+		}
+		mm.items = p
+	}
+	var err error
+	switch {
+	case mm.Name != "" && mm.NamePath != "":
+		return fmt.Errorf("`name` and `name_path` cannot be set simultaneously")
+	case mm.Name != "":
+	case mm.NamePath != "":
+		if mm.namePath, err = mm.parseValuePath(mm.NamePath); err != nil {
+			return fmt.Errorf("cannot parse `name_path`: %w", err)
+		}
+	default:
+		return fmt.Errorf("missing `name` or `name_path`")
+	}
+	if mm.ValuePath == "" {
+		return fmt.Errorf("missing `value_path`")
+	}
+	if mm.valuePath, err = mm.parseValuePath(mm.ValuePath); err != nil {
+		return fmt.Errorf("cannot parse `value_path`: %w", err)
+	}
+	if mm.TimestampPath != "" {
+		if mm.timestampPath, err = mm.parseValuePath(mm.TimestampPath); err != nil {
+			return fmt.Errorf("cannot parse `timestamp_path`: %w", err)
+		}
+	}
+	switch mm.TimestampUnit {
+	case "s":
+		mm.timestampMult = 1e3
+	case "", "ms":
+		mm.timestampMult = 1
+	case "us":
+		mm.timestampMult = -1e3
+	case "ns":
+		mm.timestampMult = -1e6
+	default:
+		return fmt.Errorf("unsupported `timestamp_unit: %s`; supported values: s, ms, us, ns", mm.TimestampUnit)
+	}
+	names := make([]string, 0, len(mm.Labels))
+	for name := range mm.Labels {
+		if name == "" || name == "__name__" {
+			return fmt.Errorf("invalid label name %q; use `name` or `name_path` for setting metric name", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := mm.Labels[name]
+		lm := labelMapping{
+			name: name,
+		}
+		if strings.HasPrefix(value, "$") || strings.HasPrefix(value, "@") {
+			if lm.path, err = mm.parseValuePath(value); err != nil {
+				return fmt.Errorf("cannot parse path for label %q: %w", name, err)
+			}
+		} else {
+			lm.value = value
+		}
+		mm.labels = append(mm.labels, lm)
+	}
+	return nil
+}
+
+func (mm *MetricMapping) parseValuePath(s string) (*path, error) {
+	p, err := parsePath(s)
+	if err != nil {
+		return nil, err
+	}
+	if p.hasWildcard() {
+		return nil, fmt.Errorf("`[*]` is allowed only in `items`; got %q", s)
+	}
+	if p.relative && mm.items == nil {
+		return nil, fmt.Errorf("paths starting with `@` may be used only together with `items`; got %q", s)
+	}
+	return p, nil
+}
+
+// path is a parsed JSONPath-like path such as `$.foo.bar[0]` or `@['baz']`.
+type path struct {
+	// relative is set to true if the path starts with `@`.
+	relative bool
+	steps    []pathStep
+}
+
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+func (p *path) hasWildcard() bool {
+	for _, step := range p.steps {
+		if step.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the value at p for the given document root and the current item.
+//
+// nil is returned if the value is missing.
+func (p *path) get(root, item *fastjson.Value) *fastjson.Value {
+	v := root
+	if p.relative {
+		v = item
+	}
+	for _, step := range p.steps {
+		if v == nil {
+			return nil
+		}
+		if step.isIndex {
+			a, err := v.Array()
+			if err != nil || step.index >= len(a) {
+				return nil
+			}
+			v = a[step.index]
+			continue
+		}
+		v = v.Get(step.key)
+	}
+	return v
+}
+
+func parsePath(s string) (*path, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf("path cannot be empty")
+	}
+	var p path
+	switch s[0] {
+	case '$':
+	case '@':
+		p.relative = true
+	default:
+		return nil, fmt.Errorf("path must start with `$` or `@`; got %q", s)
+	}
+	tail := s[1:]
+	for len(tail) > 0 {
+		switch tail[0] {
+		case '.':
+			tail = tail[1:]
+			n := strings.IndexAny(tail, ".[")
+			if n < 0 {
+				n = len(tail)
+			}
+			if n == 0 {
+				return nil, fmt.Errorf("missing key name after `.` in %q", s)
+			}
+			p.steps = append(p.steps, pathStep{
+				key: tail[:n],
+			})
+			tail = tail[n:]
+		case '[':
+			n := strings.IndexByte(tail, ']')
+			if n < 0 {
+				return nil, fmt.Errorf("missing `]` in %q", s)
+			}
+			expr := tail[1:n]
+			tail = tail[n+1:]
+			switch {
+			case expr == "*":
+				p.steps = append(p.steps, pathStep{
+					wildcard: true,
+				})
+			case len(expr) >= 2 && (expr[0] == '\'' || expr[0] == '"') && expr[len(expr)-1] == expr[0]:
+				p.steps = append(p.steps, pathStep{
+					key: expr[1 : len(expr)-1],
+				})
+			default:
+				idx, err := strconv.Atoi(expr)
+				if err != nil || idx < 0 {
+					return nil, fmt.Errorf("unsupported `[%s]` in %q; supported forms: [N], [*], ['key']", expr, s)
+				}
+				p.steps = append(p.steps, pathStep{
+					index:   idx,
+					isIndex: true,
+				})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected char %q at %q; expecting `.` or `[`", tail[0], s)
+		}
+	}
+	return &p, nil
+}
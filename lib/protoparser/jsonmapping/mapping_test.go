@@ -0,0 +1,134 @@
+package jsonmapping
+
+import (
+	"testing"
+)
+
+func TestParseMapperFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		m, err := ParseMapper([]byte(s))
+		if err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+		if m != nil {
+			t.Fatalf("expecting nil mapper")
+		}
+	}
+
+	// Empty config
+	f(``)
+	f(`metrics: []`)
+
+	// Unknown field
+	f(`
+metrics:
+- name: foo
+  value_path: $.value
+  foo: bar
+`)
+
+	// Missing name
+	f(`
+metrics:
+- value_path: $.value
+`)
+
+	// Both name and name_path
+	f(`
+metrics:
+- name: foo
+  name_path: $.name
+  value_path: $.value
+`)
+
+	// Missing value_path
+	f(`
+metrics:
+- name: foo
+`)
+
+	// Invalid paths
+	f(`
+metrics:
+- name: foo
+  value_path: value
+`)
+	f(`
+metrics:
+- name: foo
+  value_path: $..value
+`)
+	f(`
+metrics:
+- name: foo
+  value_path: $.values[foo]
+`)
+	f(`
+metrics:
+- name: foo
+  value_path: $.values[0
+`)
+
+	// Wildcard outside items
+	f(`
+metrics:
+- name: foo
+  value_path: $.values[*]
+`)
+
+	// Relative path without items
+	f(`
+metrics:
+- name: foo
+  value_path: '@.value'
+`)
+
+	// Items without trailing wildcard
+	f(`
+metrics:
+- items: $.readings
+  name: foo
+  value_path: '@.value'
+`)
+
+	// Unsupported timestamp unit
+	f(`
+metrics:
+- name: foo
+  value_path: $.value
+  timestamp_path: $.ts
+  timestamp_unit: m
+`)
+
+	// Invalid label name
+	f(`
+metrics:
+- name: foo
+  value_path: $.value
+  labels:
+    __name__: $.name
+`)
+}
+
+func TestParsePathSuccess(t *testing.T) {
+	f := func(s string, relativeExpected bool, stepsExpected int) {
+		t.Helper()
+		p, err := parsePath(s)
+		if err != nil {
+			t.Fatalf("unexpected error when parsing %q: %s", s, err)
+		}
+		if p.relative != relativeExpected {
+			t.Fatalf("unexpected relative for %q; got %v; want %v", s, p.relative, relativeExpected)
+		}
+		if len(p.steps) != stepsExpected {
+			t.Fatalf("unexpected number of steps for %q; got %d; want %d", s, len(p.steps), stepsExpected)
+		}
+	}
+	f(`$`, false, 0)
+	f(`@`, true, 0)
+	f(`$.foo`, false, 1)
+	f(`$.foo.bar[0]`, false, 3)
+	f(`$['foo.bar']["baz"]`, false, 2)
+	f(`@.readings[*]`, true, 2)
+}
@@ -0,0 +1,256 @@
+package jsonmapping
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// Rows contains rows extracted from JSON documents.
+type Rows struct {
+	Rows []Row
+
+	p         fastjson.Parser
+	tagsPool  []Tag
+	bytesPool []byte
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	// Reset items, so they can be GC'ed
+
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+
+	rs.bytesPool = rs.bytesPool[:0]
+}
+
+// Unmarshal extracts rows from JSON documents in s according to m.
+//
+// Every line in s must contain a single JSON document. currentTimestamp in milliseconds is used for rows without timestamps.
+func (rs *Rows) Unmarshal(s string, m *Mapper, currentTimestamp int64) {
+	rs.Reset()
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		line := s
+		if n >= 0 {
+			line = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := rs.unmarshalDocument(line, m, currentTimestamp); err != nil {
+			logger.Errorf("cannot extract metrics from JSON document %q: %s; skipping it", line, err)
+			invalidLines.Inc()
+		}
+	}
+}
+
+var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="jsonmapping"}`)
+
+func (rs *Rows) unmarshalDocument(s string, m *Mapper, currentTimestamp int64) error {
+	root, err := rs.p.Parse(s)
+	if err != nil {
+		return fmt.Errorf("cannot parse JSON: %w", err)
+	}
+	rowsLen := len(rs.Rows)
+	tagsLen := len(rs.tagsPool)
+	for i := range m.metrics {
+		mm := &m.metrics[i]
+		if mm.items == nil {
+			err = rs.addRow(mm, root, root, currentTimestamp)
+		} else {
+			items, _ := mm.items.get(root, root).Array()
+			for _, item := range items {
+				if err = rs.addRow(mm, root, item, currentTimestamp); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			// Drop all the rows for the invalid document.
+			rs.Rows = rs.Rows[:rowsLen]
+			rs.tagsPool = rs.tagsPool[:tagsLen]
+			return err
+		}
+	}
+	return nil
+}
+
+// addRow adds a row for mm to rs.
+//
+// The row is silently skipped if the value is missing in the document, so multiple document kinds may be sent to the same endpoint.
+func (rs *Rows) addRow(mm *MetricMapping, root, item *fastjson.Value, currentTimestamp int64) error {
+	v := mm.valuePath.get(root, item)
+	if v == nil || v.Type() == fastjson.TypeNull {
+		return nil
+	}
+	value, err := getValue(v)
+	if err != nil {
+		return fmt.Errorf("cannot parse value at %q: %w", mm.ValuePath, err)
+	}
+	metric := mm.Name
+	if mm.namePath != nil {
+		metric, err = rs.getString(mm.namePath.get(root, item))
+		if err != nil {
+			return fmt.Errorf("cannot get metric name at %q: %w", mm.NamePath, err)
+		}
+		if metric == "" {
+			return nil
+		}
+	}
+	timestamp := currentTimestamp
+	if mm.timestampPath != nil {
+		if tv := mm.timestampPath.get(root, item); tv != nil && tv.Type() != fastjson.TypeNull {
+			timestamp, err = getTimestamp(tv, mm.timestampMult)
+			if err != nil {
+				return fmt.Errorf("cannot parse timestamp at %q: %w", mm.TimestampPath, err)
+			}
+		}
+	}
+	tagsStart := len(rs.tagsPool)
+	for i := range mm.labels {
+		lm := &mm.labels[i]
+		labelValue := lm.value
+		if lm.path != nil {
+			labelValue, err = rs.getString(lm.path.get(root, item))
+			if err != nil {
+				return fmt.Errorf("cannot get value for label %q: %w", lm.name, err)
+			}
+		}
+		if labelValue == "" {
+			// Skip missing labels
+			continue
+		}
+		rs.tagsPool = append(rs.tagsPool, Tag{
+			Key:   lm.name,
+			Value: labelValue,
+		})
+	}
+	r := Row{
+		Metric:    metric,
+		Value:     value,
+		Timestamp: timestamp,
+	}
+	if tags := rs.tagsPool[tagsStart:]; len(tags) > 0 {
+		r.Tags = tags[:len(tags):len(tags)]
+	}
+	rs.Rows = append(rs.Rows, r)
+	return nil
+}
+
+// getString returns string representation for scalar v.
+//
+// The returned string remains valid until rs.Reset call.
+func (rs *Rows) getString(v *fastjson.Value) (string, error) {
+	if v == nil {
+		return "", nil
+	}
+	var b []byte
+	switch v.Type() {
+	case fastjson.TypeNull:
+		return "", nil
+	case fastjson.TypeString:
+		b = v.GetStringBytes()
+	case fastjson.TypeNumber, fastjson.TypeTrue, fastjson.TypeFalse:
+		b = v.MarshalTo(nil)
+	default:
+		return "", fmt.Errorf("unexpected JSON type %s; want string, number or boolean", v.Type())
+	}
+	bytesPoolLen := len(rs.bytesPool)
+	rs.bytesPool = append(rs.bytesPool, b...)
+	return bytesutil.ToUnsafeString(rs.bytesPool[bytesPoolLen:]), nil
+}
+
+func getValue(v *fastjson.Value) (float64, error) {
+	switch v.Type() {
+	case fastjson.TypeNumber:
+		return v.Float64()
+	case fastjson.TypeTrue:
+		return 1, nil
+	case fastjson.TypeFalse:
+		return 0, nil
+	case fastjson.TypeString:
+		return fastfloat.Parse(bytesutil.ToUnsafeString(v.GetStringBytes()))
+	default:
+		return 0, fmt.Errorf("unexpected JSON type %s; want number, boolean or string", v.Type())
+	}
+}
+
+func getTimestamp(v *fastjson.Value, mult int64) (int64, error) {
+	var f float64
+	switch v.Type() {
+	case fastjson.TypeNumber:
+		n, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		f = n
+	case fastjson.TypeString:
+		s := bytesutil.ToUnsafeString(v.GetStringBytes())
+		n, err := fastfloat.Parse(s)
+		if err != nil {
+			t, errTime := time.Parse(time.RFC3339Nano, s)
+			if errTime != nil {
+				return 0, fmt.Errorf("cannot parse %q neither as number nor as RFC3339 time", s)
+			}
+			return t.UnixMilli(), nil
+		}
+		f = n
+	default:
+		return 0, fmt.Errorf("unexpected JSON type %s; want number or string", v.Type())
+	}
+	if mult > 0 {
+		f *= float64(mult)
+	} else {
+		f /= float64(-mult)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid timestamp: %v", f)
+	}
+	return int64(f), nil
+}
+
+// Row is a single sample extracted from JSON document.
+type Row struct {
+	Metric    string
+	Tags      []Tag
+	Value     float64
+	Timestamp int64
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Value = 0
+	r.Timestamp = 0
+}
+
+// Tag is a label extracted from JSON document.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
@@ -0,0 +1,132 @@
+package jsonmapping
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshal(t *testing.T) {
+	const currentTimestamp = 1670000000000
+
+	f := func(config, s string, rowsExpected []Row) {
+		t.Helper()
+		m, err := ParseMapper([]byte(config))
+		if err != nil {
+			t.Fatalf("cannot parse mapping config: %s", err)
+		}
+		var rows Rows
+		rows.Unmarshal(s, m, currentTimestamp)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s, m, currentTimestamp)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows at second unmarshal;\ngot\n%+v\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		rows.Reset()
+		if len(rows.Rows) != 0 {
+			t.Fatalf("non-empty rows after reset: %+v", rows.Rows)
+		}
+	}
+
+	// Multiple metrics from a single document
+	f(`
+metrics:
+- name: temperature
+  value_path: $.sensors.temp
+  timestamp_path: $.ts
+  timestamp_unit: s
+  labels:
+    device: $.device.id
+    site: home
+- name: humidity
+  value_path: $.sensors.humidity
+  labels:
+    device: $.device.id
+`, `{"device":{"id":"d1"},"ts":1670000100,"sensors":{"temp":21.5,"humidity":"40"}}`, []Row{
+		{
+			Metric: "temperature",
+			Tags: []Tag{
+				{
+					Key:   "device",
+					Value: "d1",
+				},
+				{
+					Key:   "site",
+					Value: "home",
+				},
+			},
+			Value:     21.5,
+			Timestamp: 1670000100000,
+		},
+		{
+			Metric: "humidity",
+			Tags: []Tag{{
+				Key:   "device",
+				Value: "d1",
+			}},
+			Value:     40,
+			Timestamp: currentTimestamp,
+		},
+	})
+
+	// Items with metric names and RFC3339 timestamps; missing values and labels are skipped
+	f(`
+metrics:
+- items: $.readings[*]
+  name_path: '@.kind'
+  value_path: '@.v'
+  timestamp_path: '@.t'
+  labels:
+    device: $.id
+    unit: '@.unit'
+`, `{"id":42,"readings":[{"kind":"power","v":true,"t":"2022-12-02T17:06:40Z","unit":"W"},{"kind":"voltage"},{"kind":"current","v":1.5}]}`, []Row{
+		{
+			Metric: "power",
+			Tags: []Tag{
+				{
+					Key:   "device",
+					Value: "42",
+				},
+				{
+					Key:   "unit",
+					Value: "W",
+				},
+			},
+			Value:     1,
+			Timestamp: 1670000800000,
+		},
+		{
+			Metric: "current",
+			Tags: []Tag{{
+				Key:   "device",
+				Value: "42",
+			}},
+			Value:     1.5,
+			Timestamp: currentTimestamp,
+		},
+	})
+
+	// Multiple documents with invalid documents in the middle
+	f(`
+metrics:
+- name: foo
+  value_path: $.value
+  timestamp_path: $.ts
+  timestamp_unit: ns
+`, "{\"value\":1,\"ts\":1670000000123456789}\r\n\n{invalid json}\n{\"value\":\"bar\"}\n{\"value\":{}}\n{\"value\":2,\"ts\":\"baz\"}\n  {\"value\":3}  ", []Row{
+		{
+			Metric:    "foo",
+			Value:     1,
+			Timestamp: 1670000000123,
+		},
+		{
+			Metric:    "foo",
+			Value:     3,
+			Timestamp: currentTimestamp,
+		},
+	})
+}
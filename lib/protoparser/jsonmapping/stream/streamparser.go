@@ -0,0 +1,252 @@
+package stream
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/jsonmapping"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	mappingConfig = flag.String("jsonImport.mappingConfig", "", "Optional path to a file with rules for extracting metrics from arbitrary JSON documents "+
+		"sent to /api/v1/import/json. The path can point either to local file or to http url. The config is reloaded on SIGHUP signal. "+
+		"See https://docs.victoriametrics.com/#how-to-import-arbitrary-json-documents")
+	maxLineLen = flagutil.NewBytes("jsonImport.maxLineLen", 10*1024*1024, "The maximum length in bytes of a single JSON document accepted by /api/v1/import/json")
+)
+
+// Init must be called after flag.Parse and before using Parse.
+func Init() {
+	if len(*mappingConfig) == 0 {
+		return
+	}
+	// Register SIGHUP handler for config re-read just before LoadMapper call.
+	// This guarantees that the config will be re-read if the signal arrives during LoadMapper call.
+	sighupCh := procutil.NewSighupChan()
+
+	m, err := jsonmapping.LoadMapper(*mappingConfig)
+	if err != nil {
+		logger.Fatalf("cannot load -jsonImport.mappingConfig: %s", err)
+	}
+	mapperGlobal.Store(m)
+	configSuccess.Set(1)
+	configTimestamp.Set(fasttime.UnixTimestamp())
+
+	go func() {
+		for range sighupCh {
+			configReloads.Inc()
+			logger.Infof("received SIGHUP; reloading -jsonImport.mappingConfig=%q...", *mappingConfig)
+			m, err := jsonmapping.LoadMapper(*mappingConfig)
+			if err != nil {
+				configReloadErrors.Inc()
+				configSuccess.Set(0)
+				logger.Errorf("cannot load the updated -jsonImport.mappingConfig: %s; preserving the previous config", err)
+				continue
+			}
+			mapperGlobal.Store(m)
+			configSuccess.Set(1)
+			configTimestamp.Set(fasttime.UnixTimestamp())
+			logger.Infof("successfully reloaded -jsonImport.mappingConfig=%q", *mappingConfig)
+		}
+	}()
+}
+
+var (
+	configReloads      = metrics.NewCounter(`vm_jsonimport_config_reloads_total`)
+	configReloadErrors = metrics.NewCounter(`vm_jsonimport_config_reloads_errors_total`)
+	configSuccess      = metrics.NewCounter(`vm_jsonimport_config_last_reload_successful`)
+	configTimestamp    = metrics.NewCounter(`vm_jsonimport_config_last_reload_success_timestamp_seconds`)
+)
+
+var mapperGlobal atomic.Value
+
+// Parse parses JSON documents from r, extracts metrics from them according to -jsonImport.mappingConfig
+// and calls callback for the extracted rows.
+//
+// The callback can be called concurrently multiple times for streamed data from reader.
+//
+// callback shouldn't hold rows after returning.
+func Parse(r io.Reader, contentEncoding string, callback func(rows []jsonmapping.Row) error) error {
+	m, _ := mapperGlobal.Load().(*jsonmapping.Mapper)
+	if m == nil {
+		return fmt.Errorf("missing -jsonImport.mappingConfig command-line flag; see https://docs.victoriametrics.com/#how-to-import-arbitrary-json-documents")
+	}
+
+	wcr := writeconcurrencylimiter.GetReader(r)
+	defer writeconcurrencylimiter.PutReader(wcr)
+	r = wcr
+
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return fmt.Errorf("cannot decompress JSON data: %w", err)
+	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
+	ctx := getStreamContext(r)
+	defer putStreamContext(ctx)
+	for ctx.Read() {
+		uw := getUnmarshalWork()
+		uw.ctx = ctx
+		uw.callback = callback
+		uw.mapper = m
+		uw.reqBuf, ctx.reqBuf = ctx.reqBuf, uw.reqBuf
+		ctx.wg.Add(1)
+		common.ScheduleUnmarshalWork(uw)
+		wcr.DecConcurrency()
+	}
+	ctx.wg.Wait()
+	if err := ctx.Error(); err != nil {
+		return err
+	}
+	return ctx.callbackErr
+}
+
+func (ctx *streamContext) Read() bool {
+	readCalls.Inc()
+	if ctx.err != nil || ctx.hasCallbackError() {
+		return false
+	}
+	ctx.reqBuf, ctx.tailBuf, ctx.err = common.ReadLinesBlockExt(ctx.br, ctx.reqBuf, ctx.tailBuf, maxLineLen.IntN())
+	if ctx.err != nil {
+		if ctx.err != io.EOF {
+			readErrors.Inc()
+			ctx.err = fmt.Errorf("cannot read JSON data: %w", ctx.err)
+		}
+		return false
+	}
+	return true
+}
+
+var (
+	readCalls  = metrics.NewCounter(`vm_protoparser_read_calls_total{type="jsonmapping"}`)
+	readErrors = metrics.NewCounter(`vm_protoparser_read_errors_total{type="jsonmapping"}`)
+	rowsRead   = metrics.NewCounter(`vm_protoparser_rows_read_total{type="jsonmapping"}`)
+)
+
+type streamContext struct {
+	br      *bufio.Reader
+	reqBuf  []byte
+	tailBuf []byte
+	err     error
+
+	wg              sync.WaitGroup
+	callbackErrLock sync.Mutex
+	callbackErr     error
+}
+
+func (ctx *streamContext) Error() error {
+	if ctx.err == io.EOF {
+		return nil
+	}
+	return ctx.err
+}
+
+func (ctx *streamContext) hasCallbackError() bool {
+	ctx.callbackErrLock.Lock()
+	ok := ctx.callbackErr != nil
+	ctx.callbackErrLock.Unlock()
+	return ok
+}
+
+func (ctx *streamContext) reset() {
+	ctx.br.Reset(nil)
+	ctx.reqBuf = ctx.reqBuf[:0]
+	ctx.tailBuf = ctx.tailBuf[:0]
+	ctx.err = nil
+	ctx.callbackErr = nil
+}
+
+func getStreamContext(r io.Reader) *streamContext {
+	select {
+	case ctx := <-streamContextPoolCh:
+		ctx.br.Reset(r)
+		return ctx
+	default:
+		if v := streamContextPool.Get(); v != nil {
+			ctx := v.(*streamContext)
+			ctx.br.Reset(r)
+			return ctx
+		}
+		return &streamContext{
+			br: bufio.NewReaderSize(r, 64*1024),
+		}
+	}
+}
+
+func putStreamContext(ctx *streamContext) {
+	ctx.reset()
+	select {
+	case streamContextPoolCh <- ctx:
+	default:
+		streamContextPool.Put(ctx)
+	}
+}
+
+var streamContextPool sync.Pool
+var streamContextPoolCh = make(chan *streamContext, cgroup.AvailableCPUs())
+
+type unmarshalWork struct {
+	rows     jsonmapping.Rows
+	ctx      *streamContext
+	callback func(rows []jsonmapping.Row) error
+	mapper   *jsonmapping.Mapper
+	reqBuf   []byte
+}
+
+func (uw *unmarshalWork) reset() {
+	uw.rows.Reset()
+	uw.ctx = nil
+	uw.callback = nil
+	uw.mapper = nil
+	uw.reqBuf = uw.reqBuf[:0]
+}
+
+func (uw *unmarshalWork) runCallback(rows []jsonmapping.Row) {
+	ctx := uw.ctx
+	if err := uw.callback(rows); err != nil {
+		ctx.callbackErrLock.Lock()
+		if ctx.callbackErr == nil {
+			ctx.callbackErr = fmt.Errorf("error when processing imported data: %w", err)
+		}
+		ctx.callbackErrLock.Unlock()
+	}
+	ctx.wg.Done()
+}
+
+// Unmarshal implements common.UnmarshalWork
+func (uw *unmarshalWork) Unmarshal() {
+	currentTimestamp := time.Now().UnixNano() / 1e6
+	uw.rows.Unmarshal(bytesutil.ToUnsafeString(uw.reqBuf), uw.mapper, currentTimestamp)
+	rows := uw.rows.Rows
+	rowsRead.Add(len(rows))
+	uw.runCallback(rows)
+	putUnmarshalWork(uw)
+}
+
+func getUnmarshalWork() *unmarshalWork {
+	v := unmarshalWorkPool.Get()
+	if v == nil {
+		return &unmarshalWork{}
+	}
+	return v.(*unmarshalWork)
+}
+
+func putUnmarshalWork(uw *unmarshalWork) {
+	uw.reset()
+	unmarshalWorkPool.Put(uw)
+}
+
+var unmarshalWorkPool sync.Pool
@@ -19,19 +19,17 @@ import (
 // The callback can be called concurrently multiple times for streamed data from r.
 //
 // callback shouldn't hold block after returning.
-func Parse(r io.Reader, isGzip bool, callback func(block *Block) error) error {
+func Parse(r io.Reader, contentEncoding string, callback func(block *Block) error) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
 
-	if isGzip {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped vmimport data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return fmt.Errorf("cannot decompress native data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 	br := getBufferedReader(r)
 	defer putBufferedReader(br)
 
@@ -31,9 +31,10 @@ func (rs *Rows) Reset() {
 	rs.tagsPool = rs.tagsPool[:0]
 }
 
-// Unmarshal unmarshals OpenTSDB put rows from s.
+// Unmarshal unmarshals OpenTSDB put and rollup rows from s.
 //
 // See http://opentsdb.net/docs/build/html/api_telnet/put.html
+// and http://opentsdb.net/docs/build/html/api_telnet/rollup.html
 //
 // s shouldn't be modified when rs is in use.
 func (rs *Rows) Unmarshal(s string) {
@@ -58,10 +59,24 @@ func (r *Row) reset() {
 func (r *Row) unmarshal(s string, tagsPool []Tag) ([]Tag, error) {
 	r.reset()
 	s = trimLeadingSpaces(s)
-	if !strings.HasPrefix(s, "put ") {
-		return tagsPool, fmt.Errorf("missing `put ` prefix in %q", s)
+	rollupSpec := ""
+	switch {
+	case strings.HasPrefix(s, "put "):
+		s = s[len("put "):]
+	case strings.HasPrefix(s, "rollup "):
+		// See http://opentsdb.net/docs/build/html/api_telnet/rollup.html
+		s = trimLeadingSpaces(s[len("rollup "):])
+		n := strings.IndexByte(s, ' ')
+		if n < 0 {
+			return tagsPool, fmt.Errorf("cannot find whitespace between rollup spec and metric in %q", s)
+		}
+		rollupSpec = s[:n]
+		s = s[n+1:]
+	case strings.HasPrefix(s, "histogram "):
+		return tagsPool, fmt.Errorf("binary-encoded histograms aren't supported; send histograms with `buckets` to /api/histogram instead")
+	default:
+		return tagsPool, fmt.Errorf("missing `put ` or `rollup ` prefix in %q", s)
 	}
-	s = s[len("put "):]
 	s = trimLeadingSpaces(s)
 	n := strings.IndexByte(s, ' ')
 	if n < 0 {
@@ -71,6 +86,17 @@ func (r *Row) unmarshal(s string, tagsPool []Tag) ([]Tag, error) {
 	if len(r.Metric) == 0 {
 		return tagsPool, fmt.Errorf("metric cannot be empty")
 	}
+	if rollupSpec != "" {
+		interval, aggregator, groupByAggregator, err := parseRollupSpec(rollupSpec)
+		if err != nil {
+			return tagsPool, err
+		}
+		metric, err := RollupMetricName(r.Metric, interval, aggregator, groupByAggregator)
+		if err != nil {
+			return tagsPool, err
+		}
+		r.Metric = metric
+	}
 	tail := trimLeadingSpaces(s[n+1:])
 	n = strings.IndexByte(tail, ' ')
 	if n < 0 {
@@ -208,6 +234,60 @@ func (t *Tag) unmarshal(s string) error {
 	return nil
 }
 
+// parseRollupSpec parses `<interval>-<aggregator>[:<groupByAggregator>]` or `:<groupByAggregator>` from s.
+func parseRollupSpec(s string) (string, string, string, error) {
+	rollup := s
+	groupByAggregator := ""
+	if n := strings.IndexByte(s, ':'); n >= 0 {
+		rollup = s[:n]
+		groupByAggregator = s[n+1:]
+	}
+	if rollup == "" {
+		return "", "", groupByAggregator, nil
+	}
+	n := strings.IndexByte(rollup, '-')
+	if n < 0 {
+		return "", "", "", fmt.Errorf("missing `-` between interval and aggregator in rollup spec %q", s)
+	}
+	return rollup[:n], rollup[n+1:], groupByAggregator, nil
+}
+
+// RollupMetricName returns metric name for OpenTSDB rollup or pre-aggregate with the given params.
+//
+// Rollups are stored under `<metric>:<interval>_<aggregator>` name, while pre-aggregates
+// are stored under `<metric>:preagg_<groupByAggregator>` name, so they do not mix with raw data.
+// Rollups of pre-aggregates are stored under `<metric>:<interval>_<aggregator>:preagg_<groupByAggregator>` name.
+func RollupMetricName(metric, interval, aggregator, groupByAggregator string) (string, error) {
+	if interval == "" && aggregator == "" && groupByAggregator == "" {
+		return "", fmt.Errorf("missing interval, aggregator and groupByAggregator for rollup")
+	}
+	if (interval == "") != (aggregator == "") {
+		return "", fmt.Errorf("interval and aggregator must be set together for rollup; got interval=%q, aggregator=%q", interval, aggregator)
+	}
+	for _, s := range []string{interval, aggregator, groupByAggregator} {
+		if !isAlphanumeric(s) {
+			return "", fmt.Errorf("rollup params may contain only alphanumeric chars; got %q", s)
+		}
+	}
+	if interval != "" {
+		metric += ":" + interval + "_" + strings.ToLower(aggregator)
+	}
+	if groupByAggregator != "" {
+		metric += ":preagg_" + strings.ToLower(groupByAggregator)
+	}
+	return metric, nil
+}
+
+func isAlphanumeric(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
 func trimLeadingSpaces(s string) string {
 	for len(s) > 0 && s[0] == ' ' {
 		s = s[1:]
@@ -46,6 +46,17 @@ func TestRowsUnmarshalFailure(t *testing.T) {
 
 	// Invalid tag
 	f("put aaa 123 4.5 foo")
+
+	// Invalid rollup
+	f("rollup 1h-sum")
+	f("rollup 1h foo 123 4.5")
+	f("rollup 1h- foo 123 4.5")
+	f("rollup -sum foo 123 4.5")
+	f("rollup : foo 123 4.5")
+	f("rollup 1h-s.m foo 123 4.5")
+
+	// Binary-encoded histogram
+	f("histogram foo 123 0 AAAAAQ== a=b")
 }
 
 func TestRowsUnmarshalSuccess(t *testing.T) {
@@ -189,6 +200,33 @@ func TestRowsUnmarshalSuccess(t *testing.T) {
 		}},
 	})
 
+	// Rollups
+	f("rollup 1h-SUM foo 2 1 bar=baz", &Rows{
+		Rows: []Row{{
+			Metric: "foo:1h_sum",
+			Tags: []Tag{{
+				Key:   "bar",
+				Value: "baz",
+			}},
+			Value:     1,
+			Timestamp: 2,
+		}},
+	})
+	f("rollup 1h-SUM:MAX foo 2 1", &Rows{
+		Rows: []Row{{
+			Metric:    "foo:1h_sum:preagg_max",
+			Value:     1,
+			Timestamp: 2,
+		}},
+	})
+	f("rollup :count foo 2 1", &Rows{
+		Rows: []Row{{
+			Metric:    "foo:preagg_count",
+			Value:     1,
+			Timestamp: 2,
+		}},
+	})
+
 	// Multi lines
 	f("put foo 2 0.3 a=b\nput bar.baz 43 0.34 a=b\n", &Rows{
 		Rows: []Row{
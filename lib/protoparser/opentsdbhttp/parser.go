@@ -2,9 +2,12 @@ package opentsdbhttp
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/opentsdb"
 	"github.com/VictoriaMetrics/metrics"
 	"github.com/valyala/fastjson"
 	"github.com/valyala/fastjson/fastfloat"
@@ -37,7 +40,31 @@ func (rs *Rows) Reset() {
 //
 // s shouldn't be modified when rs is in use.
 func (rs *Rows) Unmarshal(av *fastjson.Value) {
-	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], av, rs.tagsPool[:0])
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], av, rs.tagsPool[:0], unmarshalPutRow)
+}
+
+// UnmarshalRollups unmarshals OpenTSDB rollup and pre-aggregate rows from av.
+//
+// Rows are stored under the metric names returned from opentsdb.RollupMetricName.
+//
+// See http://opentsdb.net/docs/build/html/api_http/rollup.html
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) UnmarshalRollups(av *fastjson.Value) {
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], av, rs.tagsPool[:0], unmarshalRollupRow)
+}
+
+// UnmarshalHistograms unmarshals OpenTSDB histograms with `buckets` from av.
+//
+// Every histogram is converted to VictoriaMetrics histogram with `<metric>_bucket{vmrange="<start>...<end>"}`
+// rows for buckets and `<metric>_count` row for the total number of observations.
+// `underflow` and `overflow` counts are stored in `-Inf...<start>` and `<end>...+Inf` buckets.
+//
+// See http://opentsdb.net/docs/build/html/api_http/histogram.html
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) UnmarshalHistograms(av *fastjson.Value) {
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], av, rs.tagsPool[:0], unmarshalHistogramRows)
 }
 
 // Row is a single OpenTSDB row.
@@ -56,6 +83,24 @@ func (r *Row) reset() {
 }
 
 func (r *Row) unmarshal(o *fastjson.Value, tagsPool []Tag) ([]Tag, error) {
+	tagsPool, err := r.unmarshalCommon(o, tagsPool)
+	if err != nil {
+		return tagsPool, err
+	}
+	rawV := o.Get("value")
+	if rawV == nil {
+		return tagsPool, fmt.Errorf("missing `value` in %s", o)
+	}
+	v, err := getFloat64(rawV)
+	if err != nil {
+		return tagsPool, fmt.Errorf("invalid `value` in %s: %w", o, err)
+	}
+	r.Value = v
+	return tagsPool, nil
+}
+
+// unmarshalCommon unmarshals metric, timestamp and tags from o into r.
+func (r *Row) unmarshalCommon(o *fastjson.Value, tagsPool []Tag) ([]Tag, error) {
 	r.reset()
 	m := o.GetStringBytes("metric")
 	if len(m) == 0 {
@@ -76,16 +121,6 @@ func (r *Row) unmarshal(o *fastjson.Value, tagsPool []Tag) ([]Tag, error) {
 		r.Timestamp = 0
 	}
 
-	rawV := o.Get("value")
-	if rawV == nil {
-		return tagsPool, fmt.Errorf("missing `value` in %s", o)
-	}
-	v, err := getFloat64(rawV)
-	if err != nil {
-		return tagsPool, fmt.Errorf("invalid `value` in %s: %w", o, err)
-	}
-	r.Value = v
-
 	vt := o.Get("tags")
 	if vt == nil {
 		// Allow empty tags.
@@ -122,14 +157,14 @@ func getFloat64(v *fastjson.Value) (float64, error) {
 	}
 }
 
-func unmarshalRows(dst []Row, av *fastjson.Value, tagsPool []Tag) ([]Row, []Tag) {
+func unmarshalRows(dst []Row, av *fastjson.Value, tagsPool []Tag, f rowsUnmarshaler) ([]Row, []Tag) {
 	switch av.Type() {
 	case fastjson.TypeObject:
-		return unmarshalRow(dst, av, tagsPool)
+		return unmarshalObject(dst, av, tagsPool, f)
 	case fastjson.TypeArray:
 		a, _ := av.Array()
 		for _, o := range a {
-			dst, tagsPool = unmarshalRow(dst, o, tagsPool)
+			dst, tagsPool = unmarshalObject(dst, o, tagsPool, f)
 		}
 		return dst, tagsPool
 	default:
@@ -139,23 +174,159 @@ func unmarshalRows(dst []Row, av *fastjson.Value, tagsPool []Tag) ([]Row, []Tag)
 	}
 }
 
-func unmarshalRow(dst []Row, o *fastjson.Value, tagsPool []Tag) ([]Row, []Tag) {
-	if cap(dst) > len(dst) {
-		dst = dst[:len(dst)+1]
-	} else {
-		dst = append(dst, Row{})
-	}
-	r := &dst[len(dst)-1]
-	var err error
-	tagsPool, err = r.unmarshal(o, tagsPool)
+// rowsUnmarshaler appends rows unmarshaled from o to dst.
+type rowsUnmarshaler func(dst []Row, o *fastjson.Value, tagsPool []Tag) ([]Row, []Tag, error)
+
+func unmarshalObject(dst []Row, o *fastjson.Value, tagsPool []Tag, f rowsUnmarshaler) ([]Row, []Tag) {
+	dstLen := len(dst)
+	dst, tagsPool, err := f(dst, o, tagsPool)
 	if err != nil {
-		dst = dst[:len(dst)-1]
+		dst = dst[:dstLen]
 		logger.Errorf("cannot unmarshal OpenTSDB object %s: %s", o, err)
 		invalidLines.Inc()
 	}
 	return dst, tagsPool
 }
 
+func unmarshalPutRow(dst []Row, o *fastjson.Value, tagsPool []Tag) ([]Row, []Tag, error) {
+	dst = appendRow(dst)
+	r := &dst[len(dst)-1]
+	tagsPool, err := r.unmarshal(o, tagsPool)
+	return dst, tagsPool, err
+}
+
+func unmarshalRollupRow(dst []Row, o *fastjson.Value, tagsPool []Tag) ([]Row, []Tag, error) {
+	dst, tagsPool, err := unmarshalPutRow(dst, o, tagsPool)
+	if err != nil {
+		return dst, tagsPool, err
+	}
+	r := &dst[len(dst)-1]
+	interval := bytesutil.ToUnsafeString(o.GetStringBytes("interval"))
+	aggregator := bytesutil.ToUnsafeString(o.GetStringBytes("aggregator"))
+	groupByAggregator := bytesutil.ToUnsafeString(o.GetStringBytes("groupByAggregator"))
+	metric, err := opentsdb.RollupMetricName(r.Metric, interval, aggregator, groupByAggregator)
+	if err != nil {
+		return dst, tagsPool, err
+	}
+	r.Metric = metric
+	return dst, tagsPool, nil
+}
+
+func unmarshalHistogramRows(dst []Row, o *fastjson.Value, tagsPool []Tag) ([]Row, []Tag, error) {
+	var hr Row
+	tagsPool, err := hr.unmarshalCommon(o, tagsPool)
+	if err != nil {
+		return dst, tagsPool, err
+	}
+	vb := o.Get("buckets")
+	if vb == nil {
+		if o.Exists("value") {
+			return dst, tagsPool, fmt.Errorf("binary-encoded histograms aren't supported; send histograms with `buckets` instead")
+		}
+		return dst, tagsPool, fmt.Errorf("missing `buckets` in %s", o)
+	}
+	rawBuckets, err := vb.Object()
+	if err != nil {
+		return dst, tagsPool, fmt.Errorf("invalid `buckets` in %s: %w", o, err)
+	}
+	if rawBuckets.Len() == 0 {
+		return dst, tagsPool, fmt.Errorf("`buckets` cannot be empty in %s", o)
+	}
+
+	bucketMetric := hr.Metric + "_bucket"
+	total := float64(0)
+	minStart := ""
+	minStartValue := math.Inf(1)
+	maxEnd := ""
+	maxEndValue := math.Inf(-1)
+	addBucket := func(vmrange string, count float64) {
+		dst = appendRow(dst)
+		r := &dst[len(dst)-1]
+		r.Metric = bucketMetric
+		r.Timestamp = hr.Timestamp
+		r.Value = count
+		tagsStart := len(tagsPool)
+		tagsPool = append(tagsPool, hr.Tags...)
+		tagsPool = append(tagsPool, Tag{
+			Key:   "vmrange",
+			Value: vmrange,
+		})
+		tags := tagsPool[tagsStart:]
+		r.Tags = tags[:len(tags):len(tags)]
+		total += count
+	}
+	rawBuckets.Visit(func(k []byte, v *fastjson.Value) {
+		if err != nil {
+			return
+		}
+		bucket := bytesutil.ToUnsafeString(k)
+		n := strings.IndexByte(bucket, ',')
+		if n < 0 {
+			err = fmt.Errorf("missing `,` between bucket bounds in %q", bucket)
+			return
+		}
+		start, end := bucket[:n], bucket[n+1:]
+		startValue, errLocal := fastfloat.Parse(start)
+		if errLocal != nil {
+			err = fmt.Errorf("cannot parse bucket start in %q: %w", bucket, errLocal)
+			return
+		}
+		endValue, errLocal := fastfloat.Parse(end)
+		if errLocal != nil {
+			err = fmt.Errorf("cannot parse bucket end in %q: %w", bucket, errLocal)
+			return
+		}
+		if startValue >= endValue {
+			err = fmt.Errorf("bucket start must be smaller than bucket end in %q", bucket)
+			return
+		}
+		count, errLocal := getFloat64(v)
+		if errLocal != nil {
+			err = fmt.Errorf("invalid count for bucket %q: %w", bucket, errLocal)
+			return
+		}
+		if startValue < minStartValue {
+			minStart, minStartValue = start, startValue
+		}
+		if endValue > maxEndValue {
+			maxEnd, maxEndValue = end, endValue
+		}
+		addBucket(start+"..."+end, count)
+	})
+	if err != nil {
+		return dst, tagsPool, err
+	}
+	if rawV := o.Get("underflow"); rawV != nil {
+		count, err := getFloat64(rawV)
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("invalid `underflow` in %s: %w", o, err)
+		}
+		addBucket("-Inf..."+minStart, count)
+	}
+	if rawV := o.Get("overflow"); rawV != nil {
+		count, err := getFloat64(rawV)
+		if err != nil {
+			return dst, tagsPool, fmt.Errorf("invalid `overflow` in %s: %w", o, err)
+		}
+		addBucket(maxEnd+"...+Inf", count)
+	}
+
+	dst = appendRow(dst)
+	r := &dst[len(dst)-1]
+	r.Metric = hr.Metric + "_count"
+	r.Tags = hr.Tags
+	r.Timestamp = hr.Timestamp
+	r.Value = total
+	return dst, tagsPool, nil
+}
+
+func appendRow(dst []Row) []Row {
+	if cap(dst) > len(dst) {
+		return dst[:len(dst)+1]
+	}
+	return append(dst, Row{})
+}
+
 var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="opentsdbhttp"}`)
 
 func unmarshalTags(dst []Tag, o *fastjson.Object) ([]Tag, error) {
@@ -244,3 +244,138 @@ func TestRowsUnmarshalSuccess(t *testing.T) {
 		},
 	})
 }
+
+func TestRowsUnmarshalRollups(t *testing.T) {
+	f := func(s string, rowsExpected []Row) {
+		t.Helper()
+		var rows Rows
+		p := GetJSONParser()
+		defer PutJSONParser(p)
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse json %s: %s", s, err)
+		}
+		rows.UnmarshalRollups(v)
+		if len(rows.Rows) == 0 && len(rowsExpected) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+	}
+
+	// Invalid rollups
+	f(`{"metric": "foo", "value": 1, "timestamp": 2}`, nil)
+	f(`{"metric": "foo", "value": 1, "timestamp": 2, "interval": "1h"}`, nil)
+	f(`{"metric": "foo", "value": 1, "timestamp": 2, "aggregator": "SUM"}`, nil)
+	f(`{"metric": "foo", "value": 1, "timestamp": 2, "interval": "1h", "aggregator": "S M"}`, nil)
+
+	// Valid rollups
+	f(`[{"metric": "foo", "value": 1, "timestamp": 2, "interval": "1h", "aggregator": "SUM", "tags": {"a":"b"}},
+{"metric": "foo", "value": 3, "timestamp": 2, "groupByAggregator": "max"},
+{"metric": "foo", "value": 4, "timestamp": 2, "interval": "1d", "aggregator": "count", "groupByAggregator": "sum"}]`, []Row{
+		{
+			Metric:    "foo:1h_sum",
+			Value:     1,
+			Timestamp: 2,
+			Tags: []Tag{{
+				Key:   "a",
+				Value: "b",
+			}},
+		},
+		{
+			Metric:    "foo:preagg_max",
+			Value:     3,
+			Timestamp: 2,
+		},
+		{
+			Metric:    "foo:1d_count:preagg_sum",
+			Value:     4,
+			Timestamp: 2,
+		},
+	})
+}
+
+func TestRowsUnmarshalHistograms(t *testing.T) {
+	f := func(s string, rowsExpected []Row) {
+		t.Helper()
+		var rows Rows
+		p := GetJSONParser()
+		defer PutJSONParser(p)
+		v, err := p.Parse(s)
+		if err != nil {
+			t.Fatalf("cannot parse json %s: %s", s, err)
+		}
+		rows.UnmarshalHistograms(v)
+		if len(rows.Rows) == 0 && len(rowsExpected) == 0 {
+			return
+		}
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+
+		// Try again
+		rows.Reset()
+		rows.UnmarshalHistograms(v)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected) {
+			t.Fatalf("unexpected rows on the second call;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected)
+		}
+	}
+
+	// Invalid histograms
+	f(`{"metric": "foo", "timestamp": 2}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "id": 0, "value": "AAAAAQ=="}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"1": 2}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"x,1": 2}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"1,x": 2}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"1,1": 2}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"0,1": "x"}}`, nil)
+	f(`{"metric": "foo", "timestamp": 2, "buckets": {"0,1": 2}, "overflow": "x"}`, nil)
+
+	// Valid histograms
+	f(`[{"metric": "foo", "timestamp": 2, "buckets": {"0,1.75": 12, "1.75,3.5": 16}, "underflow": 1, "overflow": 2, "tags": {"a":"b"}},
+{"metric": "bar", "timestamp": 3, "buckets": {"1,2": 5}}]`, []Row{
+		{
+			Metric:    "foo_bucket",
+			Value:     12,
+			Timestamp: 2,
+			Tags:      []Tag{{Key: "a", Value: "b"}, {Key: "vmrange", Value: "0...1.75"}},
+		},
+		{
+			Metric:    "foo_bucket",
+			Value:     16,
+			Timestamp: 2,
+			Tags:      []Tag{{Key: "a", Value: "b"}, {Key: "vmrange", Value: "1.75...3.5"}},
+		},
+		{
+			Metric:    "foo_bucket",
+			Value:     1,
+			Timestamp: 2,
+			Tags:      []Tag{{Key: "a", Value: "b"}, {Key: "vmrange", Value: "-Inf...0"}},
+		},
+		{
+			Metric:    "foo_bucket",
+			Value:     2,
+			Timestamp: 2,
+			Tags:      []Tag{{Key: "a", Value: "b"}, {Key: "vmrange", Value: "3.5...+Inf"}},
+		},
+		{
+			Metric:    "foo_count",
+			Value:     31,
+			Timestamp: 2,
+			Tags:      []Tag{{Key: "a", Value: "b"}},
+		},
+		{
+			Metric:    "bar_bucket",
+			Value:     5,
+			Timestamp: 3,
+			Tags:      []Tag{{Key: "vmrange", Value: "1...2"}},
+		},
+		{
+			Metric:    "bar_count",
+			Value:     5,
+			Timestamp: 3,
+		},
+	})
+}
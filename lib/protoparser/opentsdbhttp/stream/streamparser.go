@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -27,6 +28,8 @@ var (
 
 // Parse parses OpenTSDB http lines from req and calls callback for the parsed rows.
 //
+// Requests to `/api/rollup` and `/api/histogram` are parsed as OpenTSDB rollups and histograms.
+//
 // The callback can be called concurrently multiple times for streamed data from req.
 //
 // callback shouldn't hold rows after returning.
@@ -36,15 +39,13 @@ func Parse(req *http.Request, callback func(rows []opentsdbhttp.Row) error) erro
 	r := io.Reader(req.Body)
 
 	readCalls.Inc()
-	if req.Header.Get("Content-Encoding") == "gzip" {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			readErrors.Inc()
-			return fmt.Errorf("cannot read gzipped http protocol data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, req.Header.Get("Content-Encoding"))
+	if err != nil {
+		readErrors.Inc()
+		return fmt.Errorf("cannot decompress http protocol data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 
 	ctx := getStreamContext(r)
 	defer putStreamContext(ctx)
@@ -72,7 +73,14 @@ func Parse(req *http.Request, callback func(rows []opentsdbhttp.Row) error) erro
 	}
 	rs := getRows()
 	defer putRows(rs)
-	rs.Unmarshal(v)
+	switch {
+	case strings.HasSuffix(req.URL.Path, "/api/rollup"):
+		rs.UnmarshalRollups(v)
+	case strings.HasSuffix(req.URL.Path, "/api/histogram"):
+		rs.UnmarshalHistograms(v)
+	default:
+		rs.Unmarshal(v)
+	}
 	rows := rs.Rows
 	rowsRead.Add(len(rows))
 
@@ -500,6 +500,13 @@ func appendKeys(dst []byte, li *linesIterator) []byte {
 	}
 }
 
+// AppendSeriesKey appends series key for r to dst and returns the result.
+//
+// The series key is in Prometheus text exposition format without the value and the timestamp.
+func AppendSeriesKey(dst []byte, r *Row) []byte {
+	return marshalMetricNameWithTags(dst, r)
+}
+
 func marshalMetricNameWithTags(dst []byte, r *Row) []byte {
 	dst = append(dst, r.Metric...)
 	if len(r.Tags) == 0 {
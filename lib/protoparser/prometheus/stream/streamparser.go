@@ -20,19 +20,17 @@ import (
 // The callback can be called concurrently multiple times for streamed data from r.
 //
 // callback shouldn't hold rows after returning.
-func Parse(r io.Reader, defaultTimestamp int64, isGzipped bool, callback func(rows []prometheus.Row) error, errLogger func(string)) error {
+func Parse(r io.Reader, defaultTimestamp int64, contentEncoding string, callback func(rows []prometheus.Row) error, errLogger func(string)) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
 
-	if isGzipped {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped lines with Prometheus exposition format: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return fmt.Errorf("cannot decompress lines with Prometheus exposition format: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 	ctx := getStreamContext(r)
 	defer putStreamContext(ctx)
 	for ctx.Read() {
@@ -2,8 +2,10 @@ package stream
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
@@ -11,6 +13,8 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding/zstd"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/httpserver"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/labelsintern"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
 	"github.com/VictoriaMetrics/metrics"
@@ -23,6 +27,19 @@ var maxInsertRequestSize = flagutil.NewBytes("maxInsertRequestSize", 32*1024*102
 //
 // callback shouldn't hold tss after returning.
 func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSeries) error) error {
+	return parse(r, isVMRemoteWrite, "", callback)
+}
+
+// ParseInterned parses zstd-compressed remote_write message with label sets interned for the given sessionID.
+//
+// See lib/labelsintern for details. labelsintern.ErrUnknownRef is returned if the message refers to unknown label sets.
+//
+// callback shouldn't hold tss after returning.
+func ParseInterned(r io.Reader, sessionID string, callback func(tss []prompb.TimeSeries) error) error {
+	return parse(r, true, sessionID, callback)
+}
+
+func parse(r io.Reader, isVMRemoteWrite bool, sessionID string, callback func(tss []prompb.TimeSeries) error) error {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
@@ -53,6 +70,9 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 	if int64(len(bb.B)) > maxInsertRequestSize.N {
 		return fmt.Errorf("too big unpacked request; mustn't exceed `-maxInsertRequestSize=%d` bytes; got %d bytes", maxInsertRequestSize.N, len(bb.B))
 	}
+	if sessionID != "" {
+		return parseInterned(bb.B, sessionID, callback)
+	}
 	wr := getWriteRequest()
 	defer putWriteRequest(wr)
 	if err := wr.Unmarshal(bb.B); err != nil {
@@ -73,6 +93,35 @@ func Parse(r io.Reader, isVMRemoteWrite bool, callback func(tss []prompb.TimeSer
 	return nil
 }
 
+func parseInterned(data []byte, sessionID string, callback func(tss []prompb.TimeSeries) error) error {
+	ir := getInternedRequest()
+	defer putInternedRequest(ir)
+	if err := ir.Unmarshal(sessionID, data); err != nil {
+		err = fmt.Errorf("cannot unmarshal request with interned labels with size %d bytes: %w", len(data), err)
+		if errors.Is(err, labelsintern.ErrUnknownRef) {
+			// Notify the sender that it must start a new session.
+			return &httpserver.ErrorWithStatusCode{
+				Err:        err,
+				StatusCode: http.StatusPreconditionFailed,
+			}
+		}
+		unmarshalErrors.Inc()
+		return err
+	}
+
+	rows := 0
+	tss := ir.Timeseries
+	for i := range tss {
+		rows += len(tss[i].Samples)
+	}
+	rowsRead.Add(rows)
+
+	if err := callback(tss); err != nil {
+		return fmt.Errorf("error when processing imported data: %w", err)
+	}
+	return nil
+}
+
 var bodyBufferPool bytesutil.ByteBufferPool
 
 type pushCtx struct {
@@ -151,3 +200,18 @@ func putWriteRequest(wr *prompb.WriteRequest) {
 }
 
 var writeRequestPool sync.Pool
+
+func getInternedRequest() *labelsintern.Request {
+	v := internedRequestPool.Get()
+	if v == nil {
+		return &labelsintern.Request{}
+	}
+	return v.(*labelsintern.Request)
+}
+
+func putInternedRequest(ir *labelsintern.Request) {
+	ir.Reset()
+	internedRequestPool.Put(ir)
+}
+
+var internedRequestPool sync.Pool
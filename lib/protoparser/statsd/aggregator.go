@@ -0,0 +1,325 @@
+package statsd
+
+import (
+	"flag"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/procutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	flushInterval = flag.Duration("statsd.flushInterval", 10*time.Second, "The interval for flushing aggregated StatsD metrics received at -statsdListenAddr")
+	mappingConfig = flag.String("statsd.mappingConfig", "", "Optional path to config with rules for converting StatsD metric names received at -statsdListenAddr "+
+		"into Prometheus-style series. The path can point either to local file or to http url. The config is re-read on SIGHUP signal. "+
+		"See https://docs.victoriametrics.com/#how-to-send-data-from-statsd-compatible-clients")
+	seriesTTL = flag.Duration("statsd.seriesTTL", 5*time.Minute, "StatsD series received at -statsdListenAddr are no longer flushed "+
+		"if they weren't updated during the given duration")
+)
+
+// summaryQuantiles are the quantiles calculated over timer, histogram and distribution values received during the flush interval.
+var summaryQuantiles = []float64{0.5, 0.9, 0.99}
+
+// Aggregator aggregates StatsD rows over -statsd.flushInterval and converts them into Prometheus-style series.
+//
+// Counters are converted into cumulative counters, gauges keep the last value, timers, histograms and distributions
+// are converted into summaries with cumulative _sum and _count plus quantiles over the flush interval,
+// while sets are converted into the number of unique values seen during the flush interval.
+type Aggregator struct {
+	pushFunc func(tss []prompbmarshal.TimeSeries)
+
+	mu     sync.Mutex
+	mapper *Mapper
+	series map[string]*seriesState
+	keyBuf []byte
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type seriesState struct {
+	labels []prompbmarshal.Label
+
+	// kind is one of TypeCounter, TypeGauge, TypeTimer or TypeSet.
+	// TypeHistogram and TypeDistribution are stored as TypeTimer.
+	kind string
+
+	// value contains the counter or gauge value.
+	value float64
+
+	// sum and count contain cumulative sum and count for timers.
+	sum   float64
+	count float64
+
+	// samples contain timer values received during the current flush interval.
+	samples []float64
+
+	// set contains unique values received during the current flush interval.
+	set map[string]struct{}
+
+	lastUpdate uint64
+}
+
+// MustStartAggregator starts Aggregator, which passes aggregated series to pushFunc every -statsd.flushInterval.
+//
+// pushFunc mustn't hold the passed series after returning.
+//
+// MustStop must be called on the returned Aggregator when it is no longer needed.
+func MustStartAggregator(pushFunc func(tss []prompbmarshal.TimeSeries)) *Aggregator {
+	var mapper *Mapper
+	if *mappingConfig != "" {
+		m, err := LoadMapper(*mappingConfig)
+		if err != nil {
+			logger.Fatalf("cannot load -statsd.mappingConfig: %s", err)
+		}
+		mapper = m
+		mappingConfigSuccess.Set(1)
+	}
+	a := newAggregator(mapper, pushFunc)
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		a.runFlusher()
+	}()
+	return a
+}
+
+func newAggregator(mapper *Mapper, pushFunc func(tss []prompbmarshal.TimeSeries)) *Aggregator {
+	return &Aggregator{
+		pushFunc: pushFunc,
+		mapper:   mapper,
+		series:   make(map[string]*seriesState),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// MustStop stops a and flushes the remaining aggregated series.
+func (a *Aggregator) MustStop() {
+	close(a.stopCh)
+	a.wg.Wait()
+	a.flush(fasttime.UnixTimestamp())
+}
+
+func (a *Aggregator) runFlusher() {
+	sighupCh := procutil.NewSighupChan()
+	t := time.NewTicker(*flushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-a.stopCh:
+			return
+		case <-t.C:
+			a.flush(fasttime.UnixTimestamp())
+		case <-sighupCh:
+			if *mappingConfig == "" {
+				continue
+			}
+			mappingConfigReloads.Inc()
+			mapper, err := LoadMapper(*mappingConfig)
+			if err != nil {
+				mappingConfigReloadErrors.Inc()
+				mappingConfigSuccess.Set(0)
+				logger.Errorf("cannot reload -statsd.mappingConfig; continuing using the previously loaded config; error: %s", err)
+				continue
+			}
+			mappingConfigSuccess.Set(1)
+			a.mu.Lock()
+			a.mapper = mapper
+			a.mu.Unlock()
+			logger.Infof("successfully reloaded -statsd.mappingConfig=%q", *mappingConfig)
+		}
+	}
+}
+
+var (
+	mappingConfigReloads      = metrics.NewCounter(`vm_statsd_mapping_config_reloads_total`)
+	mappingConfigReloadErrors = metrics.NewCounter(`vm_statsd_mapping_config_reloads_errors_total`)
+	mappingConfigSuccess      = metrics.NewCounter(`vm_statsd_mapping_config_last_reload_successful`)
+	rowsDropped               = metrics.NewCounter(`vm_statsd_rows_dropped_total`)
+)
+
+// Add adds rows to a.
+func (a *Aggregator) Add(rows []Row) {
+	a.add(rows, fasttime.UnixTimestamp())
+}
+
+func (a *Aggregator) add(rows []Row, currentTime uint64) {
+	var labels []prompbmarshal.Label
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := range rows {
+		r := &rows[i]
+		var ok bool
+		labels, ok = a.mapper.Map(labels[:0], r.Metric, r.Type)
+		if !ok {
+			rowsDropped.Inc()
+			continue
+		}
+		for j := range r.Tags {
+			tag := &r.Tags[j]
+			labels = append(labels, prompbmarshal.Label{
+				Name:  sanitizeName(tag.Key),
+				Value: tag.Value,
+			})
+		}
+		kind := r.Type
+		if kind == TypeHistogram || kind == TypeDistribution {
+			kind = TypeTimer
+		}
+		a.keyBuf = marshalSeriesKey(a.keyBuf[:0], kind, labels)
+		s := a.series[string(a.keyBuf)]
+		if s == nil {
+			s = newSeriesState(kind, labels)
+			a.series[string(a.keyBuf)] = s
+		}
+		s.lastUpdate = currentTime
+		s.add(r)
+	}
+}
+
+func newSeriesState(kind string, labels []prompbmarshal.Label) *seriesState {
+	labelsCopy := make([]prompbmarshal.Label, len(labels))
+	for i, label := range labels {
+		labelsCopy[i] = prompbmarshal.Label{
+			Name:  strings.Clone(label.Name),
+			Value: strings.Clone(label.Value),
+		}
+	}
+	return &seriesState{
+		labels: labelsCopy,
+		kind:   kind,
+	}
+}
+
+func (s *seriesState) add(r *Row) {
+	switch s.kind {
+	case TypeCounter:
+		s.value += r.Value / r.SampleRate
+	case TypeGauge:
+		if r.IsGaugeDelta {
+			s.value += r.Value
+		} else {
+			s.value = r.Value
+		}
+	case TypeTimer:
+		v := r.Value
+		if r.Type == TypeTimer {
+			// Convert milliseconds to seconds according to Prometheus naming conventions.
+			v /= 1e3
+		}
+		s.sum += v / r.SampleRate
+		s.count += 1 / r.SampleRate
+		s.samples = append(s.samples, v)
+	case TypeSet:
+		if s.set == nil {
+			s.set = make(map[string]struct{})
+		}
+		if _, ok := s.set[r.SetValue]; !ok {
+			s.set[strings.Clone(r.SetValue)] = struct{}{}
+		}
+	}
+}
+
+func marshalSeriesKey(dst []byte, kind string, labels []prompbmarshal.Label) []byte {
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Name < labels[j].Name
+	})
+	dst = append(dst, kind...)
+	for _, label := range labels {
+		dst = append(dst, '|')
+		dst = append(dst, label.Name...)
+		dst = append(dst, '=')
+		dst = append(dst, label.Value...)
+	}
+	return dst
+}
+
+// flush passes the aggregated series to pushFunc with the given timestamp in seconds.
+func (a *Aggregator) flush(currentTime uint64) {
+	timestamp := int64(currentTime) * 1e3
+	deadline := uint64(0)
+	if ttl := uint64(seriesTTL.Seconds()); currentTime > ttl {
+		deadline = currentTime - ttl
+	}
+
+	var tss []prompbmarshal.TimeSeries
+	a.mu.Lock()
+	for key, s := range a.series {
+		if s.lastUpdate < deadline {
+			delete(a.series, key)
+			continue
+		}
+		tss = s.appendTimeSeries(tss, timestamp)
+	}
+	a.mu.Unlock()
+
+	if len(tss) > 0 {
+		a.pushFunc(tss)
+	}
+}
+
+func (s *seriesState) appendTimeSeries(dst []prompbmarshal.TimeSeries, timestamp int64) []prompbmarshal.TimeSeries {
+	switch s.kind {
+	case TypeCounter, TypeGauge:
+		dst = appendTimeSeries(dst, s.labels, "", "", "", s.value, timestamp)
+	case TypeTimer:
+		if len(s.samples) > 0 {
+			sort.Float64s(s.samples)
+			for _, q := range summaryQuantiles {
+				dst = appendTimeSeries(dst, s.labels, "", "quantile", strconv.FormatFloat(q, 'g', -1, 64), quantile(s.samples, q), timestamp)
+			}
+			s.samples = s.samples[:0]
+		}
+		dst = appendTimeSeries(dst, s.labels, "_sum", "", "", s.sum, timestamp)
+		dst = appendTimeSeries(dst, s.labels, "_count", "", "", s.count, timestamp)
+	case TypeSet:
+		if len(s.set) > 0 {
+			dst = appendTimeSeries(dst, s.labels, "", "", "", float64(len(s.set)), timestamp)
+			s.set = nil
+		}
+	}
+	return dst
+}
+
+func appendTimeSeries(dst []prompbmarshal.TimeSeries, labels []prompbmarshal.Label, suffix, extraName, extraValue string,
+	value float64, timestamp int64) []prompbmarshal.TimeSeries {
+	labelsCopy := make([]prompbmarshal.Label, 0, len(labels)+1)
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			label.Value += suffix
+		}
+		labelsCopy = append(labelsCopy, label)
+	}
+	if extraName != "" {
+		labelsCopy = append(labelsCopy, prompbmarshal.Label{
+			Name:  extraName,
+			Value: extraValue,
+		})
+	}
+	return append(dst, prompbmarshal.TimeSeries{
+		Labels: labelsCopy,
+		Samples: []prompbmarshal.Sample{{
+			Value:     value,
+			Timestamp: timestamp,
+		}},
+	})
+}
+
+// quantile returns phi-quantile over sorted samples using nearest-rank method.
+func quantile(samples []float64, phi float64) float64 {
+	n := int(math.Ceil(phi * float64(len(samples))))
+	if n < 1 {
+		n = 1
+	}
+	return samples[n-1]
+}
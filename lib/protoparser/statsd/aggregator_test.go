@@ -0,0 +1,93 @@
+package statsd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestAggregator(t *testing.T) {
+	var result []string
+	a := newAggregator(nil, func(tss []prompbmarshal.TimeSeries) {
+		for _, ts := range tss {
+			result = append(result, fmt.Sprintf("%s %g %d", formatLabels(ts.Labels), ts.Samples[0].Value, ts.Samples[0].Timestamp))
+		}
+	})
+	f := func(data string, currentTime uint64, resultExpected string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(data)
+		a.add(rows.Rows, currentTime)
+		result = result[:0]
+		a.flush(currentTime)
+		sort.Strings(result)
+		if s := strings.Join(result, "\n"); s != resultExpected {
+			t.Fatalf("unexpected result;\ngot\n%s\nwant\n%s", s, resultExpected)
+		}
+	}
+
+	f(`
+foo:1|c
+foo:2|c|@0.5
+foo:1|c|#env:prod
+gauge:5|g
+gauge:+2|g
+latency:250|ms
+latency:750|ms
+latency:500|ms
+size:10|h
+users:a|s
+users:b|s
+users:a|s
+`, 100, `foo 5 100000
+foo{env="prod"} 1 100000
+gauge 7 100000
+latency_count 3 100000
+latency_sum 1.5 100000
+latency{quantile="0.5"} 0.5 100000
+latency{quantile="0.9"} 0.75 100000
+latency{quantile="0.99"} 0.75 100000
+size_count 1 100000
+size_sum 10 100000
+size{quantile="0.5"} 10 100000
+size{quantile="0.9"} 10 100000
+size{quantile="0.99"} 10 100000
+users 2 100000`)
+
+	// Counters, gauges and timer sums remain, while quantiles and sets are reset after the flush
+	f(`
+foo:1|c
+latency:500|ms
+`, 110, `foo 6 110000
+foo{env="prod"} 1 110000
+gauge 7 110000
+latency_count 4 110000
+latency_sum 2 110000
+latency{quantile="0.5"} 0.5 110000
+latency{quantile="0.9"} 0.5 110000
+latency{quantile="0.99"} 0.5 110000
+size_count 1 110000
+size_sum 10 110000`)
+
+	// Series without updates during -statsd.seriesTTL are removed
+	f(`foo:1|c`, 1000, `foo 7 1000000`)
+}
+
+func formatLabels(labels []prompbmarshal.Label) string {
+	var name string
+	var a []string
+	for _, label := range labels {
+		if label.Name == "__name__" {
+			name = label.Value
+			continue
+		}
+		a = append(a, fmt.Sprintf("%s=%q", label.Name, label.Value))
+	}
+	if len(a) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(a, ",") + "}"
+}
@@ -0,0 +1,265 @@
+package statsd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/envtemplate"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+	"gopkg.in/yaml.v2"
+)
+
+// MappingConfig is a config for converting StatsD metric names into Prometheus-style series.
+//
+// It is compatible with the glob subset of statsd_exporter mapping config.
+// See https://github.com/prometheus/statsd_exporter#metric-mapping-and-configuration
+type MappingConfig struct {
+	Mappings []Mapping `yaml:"mappings"`
+}
+
+// Mapping is a single mapping rule.
+type Mapping struct {
+	// Match is a glob for matching dot-delimited StatsD metric names.
+	//
+	// `*` matches any part of a single dot-delimited component.
+	Match string `yaml:"match"`
+
+	// MatchMetricType limits the rule to the given metric type: counter, gauge, timer, observer, set.
+	MatchMetricType string `yaml:"match_metric_type,omitempty"`
+
+	// Name is the resulting metric name. It may refer to `*` matches via $1, $2, etc.
+	Name string `yaml:"name,omitempty"`
+
+	// Labels are the resulting labels. Their values may refer to `*` matches via $1, $2, etc.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Action is either `map` (default) or `drop`.
+	Action string `yaml:"action,omitempty"`
+
+	matchParts []string
+	labelNames []string
+}
+
+// Mapper converts StatsD metric names into Prometheus-style series according to MappingConfig.
+//
+// Metric names without matching rules are converted by replacing chars unsupported by Prometheus with `_`.
+type Mapper struct {
+	mappings []Mapping
+}
+
+// LoadMapper loads mapping config from the given path, which can be either local file or http url.
+func LoadMapper(path string) (*Mapper, error) {
+	data, err := fs.ReadFileOrHTTP(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read StatsD mapping config: %w", err)
+	}
+	data, err = envtemplate.ReplaceBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot expand environment vars in %q: %w", path, err)
+	}
+	m, err := ParseMapper(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	return m, nil
+}
+
+// ParseMapper parses mapping config from data.
+func ParseMapper(data []byte) (*Mapper, error) {
+	var cfg MappingConfig
+	if err := yaml.UnmarshalStrict(data, &cfg); err != nil {
+		return nil, err
+	}
+	for i := range cfg.Mappings {
+		m := &cfg.Mappings[i]
+		if err := m.init(); err != nil {
+			return nil, fmt.Errorf("invalid mapping #%d with match=%q: %w", i+1, m.Match, err)
+		}
+	}
+	return &Mapper{
+		mappings: cfg.Mappings,
+	}, nil
+}
+
+func (m *Mapping) init() error {
+	if m.Match == "" {
+		return fmt.Errorf("missing `match`")
+	}
+	switch m.Action {
+	case "", "map":
+		if m.Name == "" {
+			return fmt.Errorf("missing `name`")
+		}
+	case "drop":
+	default:
+		return fmt.Errorf("unsupported `action: %s`; supported values: map, drop", m.Action)
+	}
+	switch m.MatchMetricType {
+	case "", "counter", "gauge", "timer", "observer", "set":
+	default:
+		return fmt.Errorf("unsupported `match_metric_type: %s`; supported values: counter, gauge, timer, observer, set", m.MatchMetricType)
+	}
+	m.matchParts = strings.Split(m.Match, ".")
+	for _, part := range m.matchParts {
+		if strings.Count(part, "*") > 1 {
+			return fmt.Errorf("only a single `*` is allowed per dot-delimited component; got %q", part)
+		}
+	}
+	for name := range m.Labels {
+		m.labelNames = append(m.labelNames, name)
+	}
+	sort.Strings(m.labelNames)
+	return nil
+}
+
+// Map returns labels for the given StatsD metric with the given metricType.
+//
+// The metric name is stored in the `__name__` label. false is returned if the metric must be dropped.
+func (mp *Mapper) Map(dst []prompbmarshal.Label, metric, metricType string) ([]prompbmarshal.Label, bool) {
+	var matches []string
+	if mp != nil {
+		for i := range mp.mappings {
+			m := &mp.mappings[i]
+			if m.MatchMetricType != "" && m.MatchMetricType != metricTypeName(metricType) {
+				continue
+			}
+			var ok bool
+			matches, ok = matchGlob(matches[:0], m.matchParts, metric)
+			if !ok {
+				continue
+			}
+			if m.Action == "drop" {
+				return dst, false
+			}
+			dst = append(dst, prompbmarshal.Label{
+				Name:  "__name__",
+				Value: expandMatches(m.Name, matches),
+			})
+			for _, name := range m.labelNames {
+				dst = append(dst, prompbmarshal.Label{
+					Name:  name,
+					Value: expandMatches(m.Labels[name], matches),
+				})
+			}
+			return dst, true
+		}
+	}
+	dst = append(dst, prompbmarshal.Label{
+		Name:  "__name__",
+		Value: sanitizeName(metric),
+	})
+	return dst, true
+}
+
+func metricTypeName(metricType string) string {
+	switch metricType {
+	case TypeCounter:
+		return "counter"
+	case TypeGauge:
+		return "gauge"
+	case TypeTimer:
+		return "timer"
+	case TypeHistogram, TypeDistribution:
+		return "observer"
+	case TypeSet:
+		return "set"
+	default:
+		return ""
+	}
+}
+
+// matchGlob matches metric against matchParts and appends the values matching `*` to dst.
+func matchGlob(dst, matchParts []string, metric string) ([]string, bool) {
+	for i, part := range matchParts {
+		var component string
+		if i == len(matchParts)-1 {
+			component = metric
+			if strings.IndexByte(component, '.') >= 0 {
+				return dst, false
+			}
+		} else {
+			n := strings.IndexByte(metric, '.')
+			if n < 0 {
+				return dst, false
+			}
+			component = metric[:n]
+			metric = metric[n+1:]
+		}
+		n := strings.IndexByte(part, '*')
+		if n < 0 {
+			if part != component {
+				return dst, false
+			}
+			continue
+		}
+		prefix := part[:n]
+		suffix := part[n+1:]
+		if len(component) < len(prefix)+len(suffix) || !strings.HasPrefix(component, prefix) || !strings.HasSuffix(component, suffix) {
+			return dst, false
+		}
+		dst = append(dst, component[len(prefix):len(component)-len(suffix)])
+	}
+	return dst, true
+}
+
+// expandMatches replaces $N and ${N} references in s with the corresponding matches.
+func expandMatches(s string, matches []string) string {
+	if strings.IndexByte(s, '$') < 0 {
+		return s
+	}
+	var b strings.Builder
+	for {
+		n := strings.IndexByte(s, '$')
+		if n < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:n])
+		s = s[n+1:]
+		braced := strings.HasPrefix(s, "{")
+		if braced {
+			s = s[1:]
+		}
+		digits := 0
+		for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 || braced && (digits == len(s) || s[digits] != '}') {
+			b.WriteByte('$')
+			if braced {
+				b.WriteByte('{')
+			}
+			continue
+		}
+		idx, _ := strconv.Atoi(s[:digits])
+		if idx >= 1 && idx <= len(matches) {
+			b.WriteString(matches[idx-1])
+		}
+		s = s[digits:]
+		if braced {
+			s = s[1:]
+		}
+	}
+}
+
+// sanitizeName replaces chars unsupported in Prometheus metric names with `_`.
+func sanitizeName(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c == ':' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		if b == nil {
+			b = []byte(s)
+		}
+		b[i] = '_'
+	}
+	if b == nil {
+		return s
+	}
+	return string(b)
+}
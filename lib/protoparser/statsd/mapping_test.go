@@ -0,0 +1,139 @@
+package statsd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompbmarshal"
+)
+
+func TestParseMapperFailure(t *testing.T) {
+	f := func(data string) {
+		t.Helper()
+		if _, err := ParseMapper([]byte(data)); err == nil {
+			t.Fatalf("expecting non-nil error")
+		}
+	}
+
+	// Invalid yaml
+	f(`foo`)
+	f(`mappings: [{match: a.b, unknown: c}]`)
+
+	// Missing match
+	f(`mappings: [{name: foo}]`)
+
+	// Missing name
+	f(`mappings: [{match: a.b}]`)
+
+	// Invalid action
+	f(`mappings: [{match: a.b, name: foo, action: bar}]`)
+
+	// Invalid match_metric_type
+	f(`mappings: [{match: a.b, name: foo, match_metric_type: bar}]`)
+
+	// Multiple globs in a single component
+	f(`mappings: [{match: a.*_*, name: foo}]`)
+}
+
+func TestMapperMap(t *testing.T) {
+	f := func(config, metric, metricType string, labelsExpected []prompbmarshal.Label, okExpected bool) {
+		t.Helper()
+		mp, err := ParseMapper([]byte(config))
+		if err != nil {
+			t.Fatalf("cannot parse mapping config: %s", err)
+		}
+		labels, ok := mp.Map(nil, metric, metricType)
+		if ok != okExpected {
+			t.Fatalf("unexpected ok; got %v; want %v", ok, okExpected)
+		}
+		if !reflect.DeepEqual(labels, labelsExpected) {
+			t.Fatalf("unexpected labels;\ngot\n%+v\nwant\n%+v", labels, labelsExpected)
+		}
+	}
+	config := `
+mappings:
+- match: "test.dispatcher.*.*.*"
+  name: "dispatcher_events_total"
+  labels:
+    processor: "$1"
+    action: "$2"
+    outcome: "${3}_x"
+- match: "request_*.time"
+  match_metric_type: timer
+  name: "request_duration_seconds"
+  labels:
+    method: "$1"
+- match: "debug.*"
+  action: drop
+`
+
+	// Without mapping config
+	f(``, "foo.bar-baz", TypeCounter, []prompbmarshal.Label{{
+		Name:  "__name__",
+		Value: "foo_bar_baz",
+	}}, true)
+
+	// Matching rule with multiple globs
+	f(config, "test.dispatcher.FooProcessor.send.success", TypeCounter, []prompbmarshal.Label{
+		{
+			Name:  "__name__",
+			Value: "dispatcher_events_total",
+		},
+		{
+			Name:  "action",
+			Value: "send",
+		},
+		{
+			Name:  "outcome",
+			Value: "success_x",
+		},
+		{
+			Name:  "processor",
+			Value: "FooProcessor",
+		},
+	}, true)
+
+	// The number of components mismatch
+	f(config, "test.dispatcher.FooProcessor.send", TypeCounter, []prompbmarshal.Label{{
+		Name:  "__name__",
+		Value: "test_dispatcher_FooProcessor_send",
+	}}, true)
+
+	// Glob with prefix and matching metric type
+	f(config, "request_get.time", TypeTimer, []prompbmarshal.Label{
+		{
+			Name:  "__name__",
+			Value: "request_duration_seconds",
+		},
+		{
+			Name:  "method",
+			Value: "get",
+		},
+	}, true)
+
+	// Metric type mismatch
+	f(config, "request_get.time", TypeGauge, []prompbmarshal.Label{{
+		Name:  "__name__",
+		Value: "request_get_time",
+	}}, true)
+
+	// Dropped metric
+	f(config, "debug.foo", TypeGauge, nil, false)
+}
+
+func TestExpandMatches(t *testing.T) {
+	f := func(s string, matches []string, resultExpected string) {
+		t.Helper()
+		result := expandMatches(s, matches)
+		if result != resultExpected {
+			t.Fatalf("unexpected result; got %q; want %q", result, resultExpected)
+		}
+	}
+	f("", nil, "")
+	f("foo", []string{"a"}, "foo")
+	f("$1_$2", []string{"a", "b"}, "a_b")
+	f("${1}x$3", []string{"a"}, "ax")
+	f("$$1", []string{"a"}, "$a")
+	f("${1", []string{"a"}, "${1")
+	f("$x", []string{"a"}, "$x")
+}
@@ -0,0 +1,255 @@
+package statsd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/valyala/fastjson/fastfloat"
+)
+
+// Rows contains parsed StatsD rows.
+type Rows struct {
+	Rows []Row
+
+	tagsPool []Tag
+}
+
+// Reset resets rs.
+func (rs *Rows) Reset() {
+	// Reset items, so they can be GC'ed
+
+	for i := range rs.Rows {
+		rs.Rows[i].reset()
+	}
+	rs.Rows = rs.Rows[:0]
+
+	for i := range rs.tagsPool {
+		rs.tagsPool[i].reset()
+	}
+	rs.tagsPool = rs.tagsPool[:0]
+}
+
+// Unmarshal unmarshals StatsD rows from s.
+//
+// Every line must have the `metric:value|type[|@sample_rate][|#tag1:value1,...,tagN:valueN]` format.
+// Tags may be also passed in InfluxDB style: `metric,tag1=value1,...,tagN=valueN:value|type`.
+//
+// See https://github.com/statsd/statsd/blob/master/docs/metric_types.md
+// and https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+//
+// s shouldn't be modified when rs is in use.
+func (rs *Rows) Unmarshal(s string) {
+	rs.Rows, rs.tagsPool = unmarshalRows(rs.Rows[:0], s, rs.tagsPool[:0])
+}
+
+// Metric types supported by StatsD protocol.
+const (
+	TypeCounter      = "c"
+	TypeGauge        = "g"
+	TypeTimer        = "ms"
+	TypeHistogram    = "h"
+	TypeDistribution = "d"
+	TypeSet          = "s"
+)
+
+// Row is a single StatsD row.
+type Row struct {
+	Metric string
+	Tags   []Tag
+
+	// Type is the metric type. See Type* constants.
+	Type string
+
+	// Value is the parsed value for all the types except of TypeSet.
+	Value float64
+
+	// SetValue is the raw value for TypeSet.
+	SetValue string
+
+	// IsGaugeDelta is set to true if the TypeGauge value starts with `+` or `-`.
+	// In this case Value must be added to the current gauge value.
+	IsGaugeDelta bool
+
+	// SampleRate is the sample rate for TypeCounter, TypeTimer, TypeHistogram and TypeDistribution values.
+	//
+	// It equals to 1 if sample rate isn't set.
+	SampleRate float64
+}
+
+func (r *Row) reset() {
+	r.Metric = ""
+	r.Tags = nil
+	r.Type = ""
+	r.Value = 0
+	r.SetValue = ""
+	r.IsGaugeDelta = false
+	r.SampleRate = 0
+}
+
+func (r *Row) unmarshal(s string, tagsPool []Tag) ([]Tag, error) {
+	r.reset()
+	n := strings.IndexByte(s, ':')
+	if n < 0 {
+		return tagsPool, fmt.Errorf("cannot find separator between metric name and value")
+	}
+	metricAndTags := s[:n]
+	s = s[n+1:]
+	n = strings.IndexByte(metricAndTags, ',')
+	if n < 0 {
+		r.Metric = metricAndTags
+	} else {
+		// InfluxDB-style tags
+		r.Metric = metricAndTags[:n]
+		tagsPool = unmarshalTags(tagsPool, metricAndTags[n+1:], '=')
+	}
+	if len(r.Metric) == 0 {
+		return tagsPool, fmt.Errorf("metric cannot be empty")
+	}
+
+	n = strings.IndexByte(s, '|')
+	if n < 0 {
+		return tagsPool, fmt.Errorf("cannot find metric type")
+	}
+	valueStr := s[:n]
+	s = s[n+1:]
+	n = strings.IndexByte(s, '|')
+	tail := ""
+	if n >= 0 {
+		tail = s[n+1:]
+		s = s[:n]
+	}
+	switch s {
+	case TypeCounter, TypeGauge, TypeTimer, TypeHistogram, TypeDistribution, TypeSet:
+		r.Type = s
+	default:
+		return tagsPool, fmt.Errorf("unsupported metric type %q; supported types: c, g, ms, h, d, s", s)
+	}
+
+	r.SampleRate = 1
+	for len(tail) > 0 {
+		n = strings.IndexByte(tail, '|')
+		field := tail
+		if n >= 0 {
+			field = tail[:n]
+			tail = tail[n+1:]
+		} else {
+			tail = ""
+		}
+		switch {
+		case strings.HasPrefix(field, "@"):
+			sampleRate, err := fastfloat.Parse(field[1:])
+			if err != nil {
+				return tagsPool, fmt.Errorf("cannot parse sample rate from %q: %w", field, err)
+			}
+			if sampleRate <= 0 || sampleRate > 1 {
+				return tagsPool, fmt.Errorf("sample rate must be in the range (0..1]; got %v", sampleRate)
+			}
+			r.SampleRate = sampleRate
+		case strings.HasPrefix(field, "#"):
+			// DogStatsD tags
+			tagsPool = unmarshalTags(tagsPool, field[1:], ':')
+		default:
+			// Skip unknown extensions such as DogStatsD timestamps and container ids.
+		}
+	}
+
+	if r.Type == TypeSet {
+		if len(valueStr) == 0 {
+			return tagsPool, fmt.Errorf("set value cannot be empty")
+		}
+		r.SetValue = valueStr
+		return tagsPool, nil
+	}
+	if r.Type == TypeGauge && len(valueStr) > 0 && (valueStr[0] == '+' || valueStr[0] == '-') {
+		r.IsGaugeDelta = true
+		if valueStr[0] == '+' {
+			valueStr = valueStr[1:]
+		}
+	}
+	v, err := fastfloat.Parse(valueStr)
+	if err != nil {
+		return tagsPool, fmt.Errorf("cannot unmarshal value from %q: %w", valueStr, err)
+	}
+	r.Value = v
+	return tagsPool, nil
+}
+
+func unmarshalRows(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	for len(s) > 0 {
+		n := strings.IndexByte(s, '\n')
+		if n < 0 {
+			// The last line.
+			return unmarshalRow(dst, s, tagsPool)
+		}
+		dst, tagsPool = unmarshalRow(dst, s[:n], tagsPool)
+		s = s[n+1:]
+	}
+	return dst, tagsPool
+}
+
+func unmarshalRow(dst []Row, s string, tagsPool []Tag) ([]Row, []Tag) {
+	s = strings.TrimSpace(s)
+	if len(s) == 0 {
+		// Skip empty line
+		return dst, tagsPool
+	}
+	if cap(dst) > len(dst) {
+		dst = dst[:len(dst)+1]
+	} else {
+		dst = append(dst, Row{})
+	}
+	r := &dst[len(dst)-1]
+	tagsStart := len(tagsPool)
+	var err error
+	tagsPool, err = r.unmarshal(s, tagsPool)
+	if err != nil {
+		dst = dst[:len(dst)-1]
+		tagsPool = tagsPool[:tagsStart]
+		logger.Errorf("cannot unmarshal StatsD line %q: %s", s, err)
+		invalidLines.Inc()
+		return dst, tagsPool
+	}
+	tags := tagsPool[tagsStart:]
+	if len(tags) > 0 {
+		r.Tags = tags[:len(tags):len(tags)]
+	}
+	return dst, tagsPool
+}
+
+var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="statsd"}`)
+
+func unmarshalTags(dst []Tag, s string, kvSeparator byte) []Tag {
+	for len(s) > 0 {
+		n := strings.IndexByte(s, ',')
+		tagStr := s
+		if n >= 0 {
+			tagStr = s[:n]
+			s = s[n+1:]
+		} else {
+			s = ""
+		}
+		n = strings.IndexByte(tagStr, kvSeparator)
+		if n <= 0 || n == len(tagStr)-1 {
+			// Skip tags without name or value
+			continue
+		}
+		dst = append(dst, Tag{
+			Key:   tagStr[:n],
+			Value: tagStr[n+1:],
+		})
+	}
+	return dst
+}
+
+// Tag is a StatsD tag.
+type Tag struct {
+	Key   string
+	Value string
+}
+
+func (t *Tag) reset() {
+	t.Key = ""
+	t.Value = ""
+}
@@ -0,0 +1,178 @@
+package statsd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRowsUnmarshalFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+
+		// Try again
+		rows.Unmarshal(s)
+		if len(rows.Rows) != 0 {
+			t.Fatalf("expecting zero rows; got %d rows", len(rows.Rows))
+		}
+	}
+
+	// Missing value
+	f("foo")
+	f("foo|c")
+
+	// Missing type
+	f("foo:1")
+
+	// Empty metric
+	f(":1|c")
+	f(",tag=value:1|c")
+
+	// Unsupported type
+	f("foo:1|x")
+
+	// Invalid value
+	f("foo:bar|c")
+	f("foo:|g")
+	f("foo:|s")
+
+	// Invalid sample rate
+	f("foo:1|c|@bar")
+	f("foo:1|c|@0")
+	f("foo:1|c|@1.5")
+}
+
+func TestRowsUnmarshalSuccess(t *testing.T) {
+	f := func(s string, rowsExpected *Rows) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		// Try unmarshaling again
+		rows.Unmarshal(s)
+		if !reflect.DeepEqual(rows.Rows, rowsExpected.Rows) {
+			t.Fatalf("unexpected rows at second unmarshal;\ngot\n%+v;\nwant\n%+v", rows.Rows, rowsExpected.Rows)
+		}
+
+		rows.Reset()
+		if len(rows.Rows) != 0 {
+			t.Fatalf("non-empty rows after reset: %+v", rows.Rows)
+		}
+	}
+
+	// Empty line
+	f("", &Rows{})
+	f("\r", &Rows{})
+	f("\n\n", &Rows{})
+
+	// Single line for every type
+	f("foo.bar:123|c", &Rows{
+		Rows: []Row{{
+			Metric:     "foo.bar",
+			Type:       TypeCounter,
+			Value:      123,
+			SampleRate: 1,
+		}},
+	})
+	f("foo:-1.5|g", &Rows{
+		Rows: []Row{{
+			Metric:       "foo",
+			Type:         TypeGauge,
+			Value:        -1.5,
+			IsGaugeDelta: true,
+			SampleRate:   1,
+		}},
+	})
+	f("foo:+2|g", &Rows{
+		Rows: []Row{{
+			Metric:       "foo",
+			Type:         TypeGauge,
+			Value:        2,
+			IsGaugeDelta: true,
+			SampleRate:   1,
+		}},
+	})
+	f("foo:1.5|g\r\n", &Rows{
+		Rows: []Row{{
+			Metric:     "foo",
+			Type:       TypeGauge,
+			Value:      1.5,
+			SampleRate: 1,
+		}},
+	})
+	f("foo:320|ms|@0.1", &Rows{
+		Rows: []Row{{
+			Metric:     "foo",
+			Type:       TypeTimer,
+			Value:      320,
+			SampleRate: 0.1,
+		}},
+	})
+	f("foo:user-1|s", &Rows{
+		Rows: []Row{{
+			Metric:     "foo",
+			Type:       TypeSet,
+			SetValue:   "user-1",
+			SampleRate: 1,
+		}},
+	})
+
+	// DogStatsD tags
+	f("foo:2|h|@0.5|#env:prod,novalue,host:a:b", &Rows{
+		Rows: []Row{{
+			Metric: "foo",
+			Tags: []Tag{
+				{
+					Key:   "env",
+					Value: "prod",
+				},
+				{
+					Key:   "host",
+					Value: "a:b",
+				},
+			},
+			Type:       TypeHistogram,
+			Value:      2,
+			SampleRate: 0.5,
+		}},
+	})
+
+	// InfluxDB-style tags and unknown extensions
+	f("foo,env=prod:3|d|T1656581400|c:container", &Rows{
+		Rows: []Row{{
+			Metric: "foo",
+			Tags: []Tag{{
+				Key:   "env",
+				Value: "prod",
+			}},
+			Type:       TypeDistribution,
+			Value:      3,
+			SampleRate: 1,
+		}},
+	})
+
+	// Multiple lines with invalid line in the middle
+	f("foo:1|c\nbar\n  baz:2|g  ", &Rows{
+		Rows: []Row{
+			{
+				Metric:     "foo",
+				Type:       TypeCounter,
+				Value:      1,
+				SampleRate: 1,
+			},
+			{
+				Metric:     "baz",
+				Type:       TypeGauge,
+				Value:      2,
+				SampleRate: 1,
+			},
+		},
+	})
+}
@@ -0,0 +1,103 @@
+package stream
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/statsd"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+// Parse parses StatsD lines from r and calls callback for the parsed rows.
+//
+// The callback is called sequentially for the streamed data from r, since StatsD rows are aggregated by the caller.
+//
+// callback shouldn't hold rows after returning.
+func Parse(r io.Reader, callback func(rows []statsd.Row) error) error {
+	wcr := writeconcurrencylimiter.GetReader(r)
+	defer writeconcurrencylimiter.PutReader(wcr)
+	r = wcr
+
+	ctx := getStreamContext(r)
+	defer putStreamContext(ctx)
+
+	for ctx.Read() {
+		ctx.rows.Unmarshal(bytesutil.ToUnsafeString(ctx.reqBuf))
+		rows := ctx.rows.Rows
+		rowsRead.Add(len(rows))
+		if err := callback(rows); err != nil {
+			return fmt.Errorf("error when processing imported data: %w", err)
+		}
+		wcr.DecConcurrency()
+	}
+	return ctx.Error()
+}
+
+func (ctx *streamContext) Read() bool {
+	readCalls.Inc()
+	if ctx.err != nil {
+		return false
+	}
+	ctx.reqBuf, ctx.tailBuf, ctx.err = common.ReadLinesBlock(ctx.br, ctx.reqBuf, ctx.tailBuf)
+	if ctx.err != nil {
+		if ctx.err != io.EOF {
+			readErrors.Inc()
+			ctx.err = fmt.Errorf("cannot read StatsD data: %w", ctx.err)
+		}
+		return false
+	}
+	return true
+}
+
+type streamContext struct {
+	br      *bufio.Reader
+	reqBuf  []byte
+	tailBuf []byte
+	err     error
+
+	rows statsd.Rows
+}
+
+func (ctx *streamContext) Error() error {
+	if ctx.err == io.EOF {
+		return nil
+	}
+	return ctx.err
+}
+
+func (ctx *streamContext) reset() {
+	ctx.br.Reset(nil)
+	ctx.reqBuf = ctx.reqBuf[:0]
+	ctx.tailBuf = ctx.tailBuf[:0]
+	ctx.err = nil
+	ctx.rows.Reset()
+}
+
+var (
+	readCalls  = metrics.NewCounter(`vm_protoparser_read_calls_total{type="statsd"}`)
+	readErrors = metrics.NewCounter(`vm_protoparser_read_errors_total{type="statsd"}`)
+	rowsRead   = metrics.NewCounter(`vm_protoparser_rows_read_total{type="statsd"}`)
+)
+
+func getStreamContext(r io.Reader) *streamContext {
+	if v := streamContextPool.Get(); v != nil {
+		ctx := v.(*streamContext)
+		ctx.br.Reset(r)
+		return ctx
+	}
+	return &streamContext{
+		br: bufio.NewReaderSize(r, 64*1024),
+	}
+}
+
+func putStreamContext(ctx *streamContext) {
+	ctx.reset()
+	streamContextPool.Put(ctx)
+}
+
+var streamContextPool sync.Pool
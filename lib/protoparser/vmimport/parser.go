@@ -15,15 +15,33 @@ import (
 type Rows struct {
 	Rows []Row
 
+	// Errors contains errors for invalid lines skipped during Unmarshal.
+	Errors []LineError
+
 	tu tagsUnmarshaler
 }
 
+// LineError is an error for invalid line in `/api/v1/import` request.
+type LineError struct {
+	// Line is 1-based line number.
+	Line int `json:"line"`
+
+	// Reason is the reason why the line is invalid.
+	Reason string `json:"reason"`
+}
+
+// Error implements error interface.
+func (le *LineError) Error() string {
+	return fmt.Sprintf("cannot unmarshal json line #%d: %s", le.Line, le.Reason)
+}
+
 // Reset resets rs.
 func (rs *Rows) Reset() {
 	for i := range rs.Rows {
 		rs.Rows[i].reset()
 	}
 	rs.Rows = rs.Rows[:0]
+	rs.Errors = rs.Errors[:0]
 
 	rs.tu.reset()
 }
@@ -33,9 +51,10 @@ func (rs *Rows) Reset() {
 // See https://docs.influxdata.com/influxdb/v1.7/write_protocols/line_protocol_tutorial/
 //
 // s shouldn't be modified when rs is in use.
+// Invalid lines are skipped. Errors for them are put into rs.Errors.
 func (rs *Rows) Unmarshal(s string) {
 	rs.tu.reset()
-	rs.Rows = unmarshalRows(rs.Rows[:0], s, &rs.tu)
+	rs.Rows, rs.Errors = unmarshalRows(rs.Rows[:0], rs.Errors[:0], s, &rs.tu)
 }
 
 // Row is a single row from `/api/v1/import` request.
@@ -208,25 +227,27 @@ func (tu *tagsUnmarshaler) unmarshalTags(o *fastjson.Object) error {
 	return tu.err
 }
 
-func unmarshalRows(dst []Row, s string, tu *tagsUnmarshaler) []Row {
+func unmarshalRows(dst []Row, errs []LineError, s string, tu *tagsUnmarshaler) ([]Row, []LineError) {
+	line := 0
 	for len(s) > 0 {
+		line++
 		n := strings.IndexByte(s, '\n')
 		if n < 0 {
 			// The last line.
-			return unmarshalRow(dst, s, tu)
+			return unmarshalRow(dst, errs, s, line, tu)
 		}
-		dst = unmarshalRow(dst, s[:n], tu)
+		dst, errs = unmarshalRow(dst, errs, s[:n], line, tu)
 		s = s[n+1:]
 	}
-	return dst
+	return dst, errs
 }
 
-func unmarshalRow(dst []Row, s string, tu *tagsUnmarshaler) []Row {
+func unmarshalRow(dst []Row, errs []LineError, s string, line int, tu *tagsUnmarshaler) ([]Row, []LineError) {
 	if len(s) > 0 && s[len(s)-1] == '\r' {
 		s = s[:len(s)-1]
 	}
 	if len(s) == 0 {
-		return dst
+		return dst, errs
 	}
 	if cap(dst) > len(dst) {
 		dst = dst[:len(dst)+1]
@@ -238,8 +259,12 @@ func unmarshalRow(dst []Row, s string, tu *tagsUnmarshaler) []Row {
 		dst = dst[:len(dst)-1]
 		logger.Errorf("cannot unmarshal json line %q: %s; skipping it", s, err)
 		invalidLines.Inc()
+		errs = append(errs, LineError{
+			Line:   line,
+			Reason: err.Error(),
+		})
 	}
-	return dst
+	return dst, errs
 }
 
 var invalidLines = metrics.NewCounter(`vm_rows_invalid_total{type="vmimport"}`)
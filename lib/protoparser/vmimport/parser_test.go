@@ -7,6 +7,30 @@ import (
 	"testing"
 )
 
+func TestRowsUnmarshalLineErrors(t *testing.T) {
+	f := func(s string, rowsExpected int, linesExpected []int) {
+		t.Helper()
+		var rows Rows
+		rows.Unmarshal(s)
+		if len(rows.Rows) != rowsExpected {
+			t.Fatalf("unexpected number of rows; got %d; want %d", len(rows.Rows), rowsExpected)
+		}
+		var lines []int
+		for _, le := range rows.Errors {
+			if le.Reason == "" {
+				t.Fatalf("missing reason for line #%d", le.Line)
+			}
+			lines = append(lines, le.Line)
+		}
+		if !reflect.DeepEqual(lines, linesExpected) {
+			t.Fatalf("unexpected lines with errors; got %v; want %v", lines, linesExpected)
+		}
+	}
+	f(`{"metric":{"foo":"bar"},"values":[1],"timestamps":[2]}`, 1, nil)
+	f("foo\n\n"+`{"metric":{"foo":"bar"},"values":[1],"timestamps":[2]}`+"\r\n[]\r\n", 1, []int{1, 4})
+	f(`{"metric":{"foo":"bar"},"values":[1],"timestamps":[2]}`+"\n"+`{"metric":{"foo":"bar"},"values":[1,2],"timestamps":[2]}`, 1, []int{2})
+}
+
 func TestRowsUnmarshalFailure(t *testing.T) {
 	f := func(s string) {
 		t.Helper()
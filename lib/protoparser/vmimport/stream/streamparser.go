@@ -2,13 +2,19 @@ package stream
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/cgroup"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/flagutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/vmimport"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/writeconcurrencylimiter"
@@ -18,40 +24,143 @@ import (
 var maxLineLen = flagutil.NewBytes("import.maxLineLen", 100*1024*1024, "The maximum length in bytes of a single line accepted by /api/v1/import; "+
 	"the line length can be limited with 'max_rows_per_line' query arg passed to /api/v1/export")
 
+// maxReportedLineErrors is the maximum number of line errors returned in Result.
+const maxReportedLineErrors = 100
+
+// Result contains the result of parsing `/api/v1/import` request.
+type Result struct {
+	// LinesTotal is the number of read lines.
+	LinesTotal int `json:"linesTotal"`
+
+	// LinesInvalid is the number of invalid lines, which were skipped.
+	LinesInvalid int `json:"linesInvalid"`
+
+	// Errors contains up to maxReportedLineErrors errors for invalid lines sorted by line number.
+	Errors []vmimport.LineError `json:"errors"`
+}
+
+// MarshalJSON returns JSON representation for res.
+func (res *Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	return json.Marshal(&struct {
+		Status          string `json:"status"`
+		LinesAccepted   int    `json:"linesAccepted"`
+		ErrorsTruncated bool   `json:"errorsTruncated"`
+		*result
+	}{
+		Status:          "partial",
+		LinesAccepted:   res.LinesTotal - res.LinesInvalid,
+		ErrorsTruncated: res.LinesInvalid > len(res.Errors),
+		result:          (*result)(res),
+	})
+}
+
+func (res *Result) addLineErrors(firstLine int, errs []vmimport.LineError) {
+	res.LinesInvalid += len(errs)
+	for _, le := range errs {
+		le.Line += firstLine - 1
+		res.Errors = append(res.Errors, le)
+	}
+	if len(res.Errors) > 2*maxReportedLineErrors {
+		// Blocks may be processed out of order, so keep errors for the first lines.
+		res.sortErrors()
+	}
+}
+
+func (res *Result) sortErrors() {
+	sort.Slice(res.Errors, func(i, j int) bool {
+		return res.Errors[i].Line < res.Errors[j].Line
+	})
+	if len(res.Errors) > maxReportedLineErrors {
+		res.Errors = res.Errors[:maxReportedLineErrors]
+	}
+}
+
+// WriteResponse writes response for successfully processed `/api/v1/import` request with the given res to w.
+//
+// 204 No Content is returned if all the lines have been accepted.
+// Otherwise 200 OK is returned with JSON summary for invalid lines.
+func WriteResponse(w http.ResponseWriter, res *Result) {
+	if res == nil || res.LinesInvalid == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal import result: %s", err)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
+// IsStrictMode returns true if the strict mode is requested via `strict` query arg at req.
+//
+// In strict mode the whole request is rejected on the first invalid line.
+func IsStrictMode(req *http.Request) bool {
+	switch strings.ToLower(req.FormValue("strict")) {
+	case "", "0", "f", "false", "no":
+		return false
+	default:
+		return true
+	}
+}
+
 // Parse parses /api/v1/import lines from req and calls callback for the parsed rows.
 //
+// Invalid lines are skipped and are reported in the returned result.
+// If strict is set, then parsing stops on the first invalid line and the error for it is returned.
+// Note that rows from the preceding lines may be already passed to callback in this case.
+//
 // The callback can be called concurrently multiple times for streamed data from reader.
 //
 // callback shouldn't hold rows after returning.
-func Parse(r io.Reader, isGzipped bool, callback func(rows []vmimport.Row) error) error {
+func Parse(r io.Reader, contentEncoding string, strict bool, callback func(rows []vmimport.Row) error) (*Result, error) {
 	wcr := writeconcurrencylimiter.GetReader(r)
 	defer writeconcurrencylimiter.PutReader(wcr)
 	r = wcr
 
-	if isGzipped {
-		zr, err := common.GetGzipReader(r)
-		if err != nil {
-			return fmt.Errorf("cannot read gzipped vmimport data: %w", err)
-		}
-		defer common.PutGzipReader(zr)
-		r = zr
+	zr, err := common.GetUncompressedReader(r, contentEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress vmimport data: %w", err)
 	}
+	defer common.PutUncompressedReader(zr)
+	r = zr
 	ctx := getStreamContext(r)
 	defer putStreamContext(ctx)
+	ctx.strict = strict
+	res := &Result{}
+	ctx.result = res
 	for ctx.Read() {
 		uw := getUnmarshalWork()
 		uw.ctx = ctx
 		uw.callback = callback
 		uw.reqBuf, ctx.reqBuf = ctx.reqBuf, uw.reqBuf
+		uw.firstLine = res.LinesTotal + 1
+		res.LinesTotal += countLines(uw.reqBuf)
 		ctx.wg.Add(1)
 		common.ScheduleUnmarshalWork(uw)
 		wcr.DecConcurrency()
 	}
 	ctx.wg.Wait()
+	res.sortErrors()
 	if err := ctx.Error(); err != nil {
-		return err
+		return res, err
+	}
+	if strict && len(res.Errors) > 0 {
+		return res, &res.Errors[0]
+	}
+	return res, ctx.callbackErr
+}
+
+// countLines returns the number of lines in b.
+//
+// b mustn't end with newline, since common.ReadLinesBlockExt strips it.
+func countLines(b []byte) int {
+	if len(b) == 0 {
+		return 0
 	}
-	return ctx.callbackErr
+	return bytes.Count(b, []byte("\n")) + 1
 }
 
 func (ctx *streamContext) Read() bool {
@@ -82,9 +191,15 @@ type streamContext struct {
 	tailBuf []byte
 	err     error
 
+	// strict is set if parsing must stop on the first invalid line.
+	strict bool
+
 	wg              sync.WaitGroup
 	callbackErrLock sync.Mutex
 	callbackErr     error
+
+	// result is protected by callbackErrLock.
+	result *Result
 }
 
 func (ctx *streamContext) Error() error {
@@ -106,7 +221,9 @@ func (ctx *streamContext) reset() {
 	ctx.reqBuf = ctx.reqBuf[:0]
 	ctx.tailBuf = ctx.tailBuf[:0]
 	ctx.err = nil
+	ctx.strict = false
 	ctx.callbackErr = nil
+	ctx.result = nil
 }
 
 func getStreamContext(r io.Reader) *streamContext {
@@ -139,10 +256,11 @@ var streamContextPool sync.Pool
 var streamContextPoolCh = make(chan *streamContext, cgroup.AvailableCPUs())
 
 type unmarshalWork struct {
-	rows     vmimport.Rows
-	ctx      *streamContext
-	callback func(rows []vmimport.Row) error
-	reqBuf   []byte
+	rows      vmimport.Rows
+	ctx       *streamContext
+	callback  func(rows []vmimport.Row) error
+	reqBuf    []byte
+	firstLine int
 }
 
 func (uw *unmarshalWork) reset() {
@@ -150,6 +268,7 @@ func (uw *unmarshalWork) reset() {
 	uw.ctx = nil
 	uw.callback = nil
 	uw.reqBuf = uw.reqBuf[:0]
+	uw.firstLine = 0
 }
 
 func (uw *unmarshalWork) runCallback(rows []vmimport.Row) {
@@ -161,18 +280,35 @@ func (uw *unmarshalWork) runCallback(rows []vmimport.Row) {
 		}
 		ctx.callbackErrLock.Unlock()
 	}
-	ctx.wg.Done()
 }
 
 // Unmarshal implements common.UnmarshalWork
 func (uw *unmarshalWork) Unmarshal() {
+	ctx := uw.ctx
 	uw.rows.Unmarshal(bytesutil.ToUnsafeString(uw.reqBuf))
+	if errs := uw.rows.Errors; len(errs) > 0 {
+		ctx.callbackErrLock.Lock()
+		ctx.result.addLineErrors(uw.firstLine, errs)
+		stop := ctx.strict
+		if stop && ctx.callbackErr == nil {
+			// Stop reading the request body.
+			ctx.callbackErr = fmt.Errorf("found invalid line in strict mode")
+		}
+		ctx.callbackErrLock.Unlock()
+		if stop {
+			// Do not ingest rows from the block with invalid lines in strict mode.
+			ctx.wg.Done()
+			putUnmarshalWork(uw)
+			return
+		}
+	}
 	rows := uw.rows.Rows
 	for i := range rows {
 		row := &rows[i]
 		rowsRead.Add(len(row.Timestamps))
 	}
 	uw.runCallback(rows)
+	ctx.wg.Done()
 	putUnmarshalWork(uw)
 }
 
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/protoparser/vmimport"
+)
+
+func TestParse(t *testing.T) {
+	common.StartUnmarshalWorkers()
+	defer common.StopUnmarshalWorkers()
+
+	const validLine = `{"metric":{"__name__":"foo"},"values":[1,2],"timestamps":[3,4]}`
+	f := func(data string, strict bool, samplesExpected, linesTotal, linesInvalid int, errLines []int, errExpected bool) {
+		t.Helper()
+		var samples int64
+		res, err := Parse(strings.NewReader(data), "", strict, func(rows []vmimport.Row) error {
+			for i := range rows {
+				atomic.AddInt64(&samples, int64(len(rows[i].Values)))
+			}
+			return nil
+		})
+		if errExpected {
+			if err == nil {
+				t.Fatalf("expecting non-nil error")
+			}
+		} else if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !strict && int(samples) != samplesExpected {
+			t.Fatalf("unexpected number of samples; got %d; want %d", samples, samplesExpected)
+		}
+		if res.LinesTotal != linesTotal {
+			t.Fatalf("unexpected number of lines; got %d; want %d", res.LinesTotal, linesTotal)
+		}
+		if res.LinesInvalid != linesInvalid {
+			t.Fatalf("unexpected number of invalid lines; got %d; want %d", res.LinesInvalid, linesInvalid)
+		}
+		if len(res.Errors) != len(errLines) {
+			t.Fatalf("unexpected number of line errors; got %d; want %d", len(res.Errors), len(errLines))
+		}
+		for i, le := range res.Errors {
+			if le.Line != errLines[i] {
+				t.Fatalf("unexpected line for error #%d; got %d; want %d", i, le.Line, errLines[i])
+			}
+		}
+	}
+
+	// valid lines
+	f(validLine+"\n"+validLine+"\n", false, 4, 2, 0, nil, false)
+
+	// partial accept
+	f(validLine+"\nfoo\n"+validLine+"\n{}", false, 4, 4, 2, []int{2, 4}, false)
+
+	// strict mode
+	f(validLine+"\nfoo\n"+validLine, true, 0, 3, 1, []int{2}, true)
+
+	// too many invalid lines
+	var bb bytes.Buffer
+	var errLines []int
+	for i := 0; i < 2*maxReportedLineErrors; i++ {
+		fmt.Fprintf(&bb, "invalid line %d\n", i)
+		if i < maxReportedLineErrors {
+			errLines = append(errLines, i+1)
+		}
+	}
+	f(bb.String(), false, 0, 2*maxReportedLineErrors, 2*maxReportedLineErrors, errLines, false)
+}
+
+func TestWriteResponse(t *testing.T) {
+	f := func(res *Result, statusCodeExpected int, bodyExpected string) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		WriteResponse(w, res)
+		if w.Code != statusCodeExpected {
+			t.Fatalf("unexpected status code; got %d; want %d", w.Code, statusCodeExpected)
+		}
+		body := w.Body.String()
+		if body != bodyExpected {
+			t.Fatalf("unexpected body\ngot\n%s\nwant\n%s", body, bodyExpected)
+		}
+		if body != "" && !json.Valid([]byte(body)) {
+			t.Fatalf("invalid json body: %s", body)
+		}
+	}
+	f(nil, 204, "")
+	f(&Result{LinesTotal: 10}, 204, "")
+	f(&Result{
+		LinesTotal:   10,
+		LinesInvalid: 2,
+		Errors: []vmimport.LineError{
+			{Line: 3, Reason: "missing tags"},
+		},
+	}, 200, `{"status":"partial","linesAccepted":8,"errorsTruncated":true,"linesTotal":10,"linesInvalid":2,"errors":[{"line":3,"reason":"missing tags"}]}`)
+}
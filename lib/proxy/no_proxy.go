@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NoProxy contains hosts, which must be accessed directly instead of proxy_url.
+//
+// It is configured via no_proxy option in the same format as NO_PROXY environment variable:
+// comma-separated list of IP addresses, CIDRs and domain names with optional ports.
+// A domain name matches itself and all its subdomains, while a domain name starting with '.' matches only subdomains.
+// '*' matches all the hosts.
+type NoProxy struct {
+	s string
+
+	matchAll bool
+	nets     []noProxyNet
+	domains  []noProxyDomain
+}
+
+type noProxyNet struct {
+	n    *net.IPNet
+	port string
+}
+
+type noProxyDomain struct {
+	name string
+	port string
+
+	// onlySubdomains is set for names starting with '.'
+	onlySubdomains bool
+}
+
+// ParseNoProxy parses no_proxy value from s.
+func ParseNoProxy(s string) (*NoProxy, error) {
+	np := &NoProxy{
+		s: s,
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			np.matchAll = true
+			continue
+		}
+		if _, n, err := net.ParseCIDR(entry); err == nil {
+			np.nets = append(np.nets, noProxyNet{
+				n: n,
+			})
+			continue
+		}
+		host, port := splitHostPort(entry)
+		if host == "" {
+			return nil, fmt.Errorf("missing host in no_proxy entry %q", entry)
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+				bits = 8 * net.IPv4len
+			}
+			np.nets = append(np.nets, noProxyNet{
+				n: &net.IPNet{
+					IP:   ip,
+					Mask: net.CIDRMask(bits, bits),
+				},
+				port: port,
+			})
+			continue
+		}
+		d := noProxyDomain{
+			name: strings.TrimPrefix(host, "*"),
+			port: port,
+		}
+		if strings.HasPrefix(d.name, ".") {
+			d.name = d.name[1:]
+			d.onlySubdomains = true
+		}
+		if d.name == "" {
+			return nil, fmt.Errorf("missing domain name in no_proxy entry %q", entry)
+		}
+		np.domains = append(np.domains, d)
+	}
+	return np, nil
+}
+
+// String returns string representation of np.
+func (np *NoProxy) String() string {
+	if np == nil {
+		return ""
+	}
+	return np.s
+}
+
+// Match returns true if the given addr must be accessed without proxy.
+//
+// addr may contain optional port.
+func (np *NoProxy) Match(addr string) bool {
+	if np == nil {
+		return false
+	}
+	if np.matchAll {
+		return true
+	}
+	host, port := splitHostPort(strings.ToLower(addr))
+	if ip := net.ParseIP(host); ip != nil {
+		for _, n := range np.nets {
+			if (n.port == "" || n.port == port) && n.n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	host = strings.TrimSuffix(host, ".")
+	for _, d := range np.domains {
+		if d.port != "" && d.port != port {
+			continue
+		}
+		if strings.HasSuffix(host, "."+d.name) || (!d.onlySubdomains && host == d.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProxyURL returns proxyURL for accessing the given targetURL.
+//
+// nil is returned if targetURL must be accessed without proxy.
+func (np *NoProxy) GetProxyURL(proxyURL *URL, targetURL string) *URL {
+	if np == nil || proxyURL.GetURL() == nil {
+		return proxyURL
+	}
+	if !strings.Contains(targetURL, "://") {
+		// targetURL may contain only host and port such as `localhost:8500`.
+		targetURL = "http://" + targetURL
+	}
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return proxyURL
+	}
+	if np.Match(u.Host) {
+		return nil
+	}
+	return proxyURL
+}
+
+// MarshalYAML implements yaml.Marshaler interface.
+func (np *NoProxy) MarshalYAML() (interface{}, error) {
+	return np.s, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler interface.
+func (np *NoProxy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	npLocal, err := ParseNoProxy(s)
+	if err != nil {
+		return fmt.Errorf("cannot parse no_proxy=%q: %w", s, err)
+	}
+	*np = *npLocal
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler interface.
+func (np *NoProxy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(np.s)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface.
+func (np *NoProxy) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	npLocal, err := ParseNoProxy(s)
+	if err != nil {
+		return fmt.Errorf("cannot parse no_proxy=%q: %w", s, err)
+	}
+	*np = *npLocal
+	return nil
+}
+
+// splitHostPort splits addr into host and optional port.
+func splitHostPort(addr string) (string, string) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// addr has no port.
+		return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"), ""
+	}
+	return host, port
+}
@@ -0,0 +1,86 @@
+package proxy
+
+import (
+	"testing"
+)
+
+func TestParseNoProxyFailure(t *testing.T) {
+	f := func(s string) {
+		t.Helper()
+		if _, err := ParseNoProxy(s); err == nil {
+			t.Fatalf("expecting non-nil error for no_proxy=%q", s)
+		}
+	}
+	f(":80")
+	f(".")
+	f("foo.bar, :443")
+}
+
+func TestNoProxyMatch(t *testing.T) {
+	f := func(noProxy, addr string, resultExpected bool) {
+		t.Helper()
+		np, err := ParseNoProxy(noProxy)
+		if err != nil {
+			t.Fatalf("cannot parse no_proxy=%q: %s", noProxy, err)
+		}
+		if result := np.Match(addr); result != resultExpected {
+			t.Fatalf("unexpected result for no_proxy=%q, addr=%q; got %v; want %v", noProxy, addr, result, resultExpected)
+		}
+	}
+
+	// Empty no_proxy
+	f("", "foo.bar", false)
+
+	// Match all
+	f("*", "foo.bar:1234", true)
+
+	// Domain names
+	f("foo.bar", "foo.bar", true)
+	f("foo.bar", "FOO.bar:443", true)
+	f("foo.bar", "baz.foo.bar:8080", true)
+	f("foo.bar", "bazfoo.bar", false)
+	f(".foo.bar", "foo.bar", false)
+	f(".foo.bar", "baz.foo.bar", true)
+	f("*.foo.bar", "baz.foo.bar", true)
+	f("baz, foo.bar", "foo.bar", true)
+
+	// Domain names with ports
+	f("foo.bar:8080", "foo.bar:8080", true)
+	f("foo.bar:8080", "foo.bar:443", false)
+	f("foo.bar:8080", "foo.bar", false)
+
+	// IP addresses and CIDRs
+	f("10.0.0.0/8", "10.1.2.3:9100", true)
+	f("10.0.0.0/8", "192.168.1.1", false)
+	f("192.168.1.1", "192.168.1.1:80", true)
+	f("192.168.1.1:80", "192.168.1.1:8080", false)
+	f("::1", "[::1]:9100", true)
+	f("2001:db8::/32", "[2001:db8::1]:80", true)
+	f("2001:db8::/32", "[2001:db9::1]", false)
+}
+
+func TestNoProxyGetProxyURL(t *testing.T) {
+	proxyURL := MustNewURL("socks5://proxy:1080")
+	np, err := ParseNoProxy("foo.bar,10.0.0.0/8")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	f := func(targetURL string, proxyExpected bool) {
+		t.Helper()
+		pu := np.GetProxyURL(proxyURL, targetURL)
+		if (pu != nil) != proxyExpected {
+			t.Fatalf("unexpected proxy url for %q; got %v; want proxy: %v", targetURL, pu, proxyExpected)
+		}
+	}
+	f("http://foo.bar:9100/metrics", false)
+	f("foo.bar:8500", false)
+	f("https://10.2.3.4/api", false)
+	f("http://baz:9100/metrics", true)
+	f("baz:8500", true)
+
+	// nil no_proxy mustn't change proxy url
+	var npNil *NoProxy
+	if pu := npNil.GetProxyURL(proxyURL, "http://foo.bar"); pu != proxyURL {
+		t.Fatalf("unexpected proxy url for nil no_proxy; got %v; want %v", pu, proxyURL)
+	}
+}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"encoding/base64"
 	"fmt"
@@ -139,7 +140,11 @@ func (u *URL) NewDialFunc(ac *promauth.Config) (fasthttp.DialFunc, error) {
 		}
 	}
 	if pu.Scheme == "socks5" || pu.Scheme == "tls+socks5" {
-		return socks5DialFunc(proxyAddr, pu, tlsCfg)
+		d := newSOCKS5Dialer(proxyAddr, pu, ac, tlsCfg, nil)
+		dialFunc := func(addr string) (net.Conn, error) {
+			return d.DialContext(context.Background(), netutil.GetTCPNetwork(), addr)
+		}
+		return dialFunc, nil
 	}
 	dialFunc := func(addr string) (net.Conn, error) {
 		proxyConn, err := defaultDialFunc(proxyAddr)
@@ -164,31 +169,137 @@ func (u *URL) NewDialFunc(ac *promauth.Config) (fasthttp.DialFunc, error) {
 	return dialFunc, nil
 }
 
-func socks5DialFunc(proxyAddr string, pu *url.URL, tlsCfg *tls.Config) (fasthttp.DialFunc, error) {
-	var sac *proxy.Auth
+// ConfigureTransport configures tr for sending requests via u with the given proxy auth config ac.
+//
+// Requests are sent via http and https proxies with Proxy-Authorization header obtained from u and ac,
+// while connections via socks5 and tls+socks5 proxies are established with tr.DialContext if it is set.
+func (u *URL) ConfigureTransport(tr *http.Transport, ac *promauth.Config) error {
+	pu := u.GetURL()
+	if pu == nil {
+		return nil
+	}
+	switch pu.Scheme {
+	case "http", "https":
+		tr.Proxy = http.ProxyURL(pu)
+		req := &http.Request{
+			Header: make(http.Header),
+		}
+		if ah := u.getAuthHeader(ac); ah != "" {
+			req.Header.Set("Proxy-Authorization", ah)
+		}
+		if ac != nil {
+			ac.SetHeaders(req, false)
+		}
+		tr.ProxyConnectHeader = req.Header
+		return nil
+	case "socks5", "tls+socks5":
+		var tlsCfg *tls.Config
+		proxyAddr := addMissingPort(pu.Host, false)
+		if pu.Scheme == "tls+socks5" {
+			proxyAddr = addMissingPort(pu.Host, true)
+			tlsCfg = ac.NewTLSConfig()
+			if !tlsCfg.InsecureSkipVerify && tlsCfg.ServerName == "" {
+				tlsCfg.ServerName = tlsServerName(proxyAddr)
+			}
+		}
+		d := newSOCKS5Dialer(proxyAddr, pu, ac, tlsCfg, tr.DialContext)
+		tr.Proxy = nil
+		tr.DialContext = d.DialContext
+		return nil
+	default:
+		return fmt.Errorf("unknown scheme=%q for proxy_url=%q, must be http, https, socks5 or tls+socks5", pu.Scheme, pu.Redacted())
+	}
+}
+
+// socks5Dialer establishes connections via socks5 proxy.
+type socks5Dialer struct {
+	proxyAddr string
+	pu        *url.URL
+	ac        *promauth.Config
+
+	// forward is used for establishing connections to the proxy.
+	forward *forwardDialer
+}
+
+func newSOCKS5Dialer(proxyAddr string, pu *url.URL, ac *promauth.Config, tlsCfg *tls.Config, dial func(ctx context.Context, network, addr string) (net.Conn, error)) *socks5Dialer {
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	return &socks5Dialer{
+		proxyAddr: proxyAddr,
+		pu:        pu,
+		ac:        ac,
+		forward: &forwardDialer{
+			dial:   dial,
+			tlsCfg: tlsCfg,
+		},
+	}
+}
+
+// DialContext establishes connection to addr via socks5 proxy.
+func (sd *socks5Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	// Create socks5 dialer on every dial, since proxy auth may change over time.
+	d, err := proxy.SOCKS5(network, sd.proxyAddr, getSOCKS5Auth(sd.pu, sd.ac), sd.forward)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create socks5 proxy for url: %s, err: %w", sd.pu.Redacted(), err)
+	}
+	return d.(proxy.ContextDialer).DialContext(ctx, network, addr)
+}
+
+// getSOCKS5Auth returns auth for socks5 proxy from the proxy url or from proxy_basic_auth in ac.
+func getSOCKS5Auth(pu *url.URL, ac *promauth.Config) *proxy.Auth {
 	if pu.User != nil {
-		username := pu.User.Username()
 		password, _ := pu.User.Password()
-		sac = &proxy.Auth{
-			User:     username,
+		return &proxy.Auth{
+			User:     pu.User.Username(),
 			Password: password,
 		}
 	}
-	network := netutil.GetTCPNetwork()
-	var dialer proxy.Dialer = proxy.Direct
-	if tlsCfg != nil {
-		dialer = &tls.Dialer{
-			Config: tlsCfg,
-		}
+	if ac == nil {
+		return nil
+	}
+	ah := ac.GetAuthHeader()
+	if !strings.HasPrefix(ah, "Basic ") {
+		return nil
 	}
-	d, err := proxy.SOCKS5(network, proxyAddr, sac, dialer)
+	data, err := base64.StdEncoding.DecodeString(ah[len("Basic "):])
 	if err != nil {
-		return nil, fmt.Errorf("cannot create socks5 proxy for url: %s, err: %w", pu.Redacted(), err)
+		return nil
 	}
-	dialFunc := func(addr string) (net.Conn, error) {
-		return d.Dial(network, addr)
+	username, password, _ := strings.Cut(string(data), ":")
+	return &proxy.Auth{
+		User:     username,
+		Password: password,
 	}
-	return dialFunc, nil
+}
+
+// forwardDialer establishes optionally encrypted connections to socks5 proxy.
+type forwardDialer struct {
+	dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	tlsCfg *tls.Config
+}
+
+// Dial implements proxy.Dialer interface.
+func (fd *forwardDialer) Dial(network, addr string) (net.Conn, error) {
+	return fd.DialContext(context.Background(), network, addr)
+}
+
+// DialContext implements proxy.ContextDialer interface.
+func (fd *forwardDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := fd.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if fd.tlsCfg == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, fd.tlsCfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("cannot establish TLS connection to socks5 proxy %q: %w", addr, err)
+	}
+	return tlsConn, nil
 }
 
 func addMissingPort(addr string, isTLS bool) string {
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/memory"
+)
+
+var (
+	backfillStagingInterval time.Duration
+	backfillStagingMaxRows  int
+	backfillStagingMaxBytes int
+)
+
+// SetBackfillStaging enables staging area for samples older than the current per-month partition.
+//
+// Such samples are accumulated in the staging area for up to the given interval or until maxRows samples are accumulated
+// or until the staged samples occupy maxBytes of memory. Then they are added to the corresponding historical partitions in big batches.
+// This reduces the number of small parts and merges in historical partitions during continuous backfilling.
+//
+// The staged samples are invisible to queries until they are added to partitions.
+//
+// Zero interval disables the staging area. Zero maxBytes limits the staging area memory usage to 5% of the allowed memory.
+//
+// This function must be called before opening the storage.
+func SetBackfillStaging(interval time.Duration, maxRows, maxBytes int) {
+	backfillStagingInterval = interval
+	backfillStagingMaxRows = maxRows
+	backfillStagingMaxBytes = maxBytes
+}
+
+func getBackfillStagingMaxBytes() int {
+	if backfillStagingMaxBytes > 0 {
+		return backfillStagingMaxBytes
+	}
+	return memory.Allowed() / 20
+}
+
+// backfillStaging accumulates rows for historical partitions before adding them to the table.
+type backfillStaging struct {
+	// Atomic counters must be at the top of struct for proper 8-byte alignment on 32-bit archs.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/212
+	stagedRows  uint64
+	flushes     uint64
+	flushErrors uint64
+
+	tb *table
+
+	// mu protects rows.
+	mu   sync.Mutex
+	rows []rawRow
+
+	// flushLock serializes flushes, so flush returns only after all the rows staged before the call are added to tb.
+	flushLock sync.Mutex
+
+	wg sync.WaitGroup
+}
+
+func newBackfillStaging(tb *table) *backfillStaging {
+	return &backfillStaging{
+		tb: tb,
+	}
+}
+
+// addRows stages rows older than the current partition and returns the remaining rows.
+func (bs *backfillStaging) addRows(rows []rawRow) []rawRow {
+	var tr TimeRange
+	tr.fromPartitionTimestamp(int64(fasttime.UnixTimestamp() * 1000))
+	minTimestamp := tr.MinTimestamp
+
+	stagedRowsCount := 0
+	for i := range rows {
+		if rows[i].Timestamp < minTimestamp {
+			stagedRowsCount++
+		}
+	}
+	if stagedRowsCount == 0 {
+		// Fast path - all the rows belong to the current partition or to future partitions.
+		return rows
+	}
+
+	// Do not modify rows, since they may be used by the caller. Copy the remaining rows instead.
+	var currRows []rawRow
+	if stagedRowsCount < len(rows) {
+		currRows = make([]rawRow, 0, len(rows)-stagedRowsCount)
+	}
+	bs.mu.Lock()
+	for i := range rows {
+		r := &rows[i]
+		if r.Timestamp < minTimestamp {
+			bs.rows = append(bs.rows, *r)
+		} else {
+			currRows = append(currRows, *r)
+		}
+	}
+	needFlush := len(bs.rows) >= backfillStagingMaxRows || cap(bs.rows)*int(unsafe.Sizeof(rawRow{})) >= getBackfillStagingMaxBytes()
+	bs.mu.Unlock()
+	atomic.AddUint64(&bs.stagedRows, uint64(stagedRowsCount))
+
+	if needFlush {
+		bs.flush()
+	}
+	return currRows
+}
+
+// flush adds all the staged rows to the corresponding partitions.
+func (bs *backfillStaging) flush() {
+	bs.flushLock.Lock()
+	defer bs.flushLock.Unlock()
+
+	bs.mu.Lock()
+	rows := bs.rows
+	bs.rows = nil
+	bs.mu.Unlock()
+
+	if len(rows) == 0 {
+		return
+	}
+	atomic.AddUint64(&bs.flushes, 1)
+	if err := bs.tb.addRowsToPartitions(rows); err != nil {
+		atomic.AddUint64(&bs.flushErrors, 1)
+		logger.Errorf("cannot add %d rows from backfill staging area: %s", len(rows), err)
+	}
+}
+
+func (bs *backfillStaging) startFlusher(stopCh <-chan struct{}) {
+	bs.wg.Add(1)
+	go func() {
+		defer bs.wg.Done()
+		bs.flusher(stopCh)
+	}()
+}
+
+func (bs *backfillStaging) flusher(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(backfillStagingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			bs.flush()
+		}
+	}
+}
+
+// mustStop stops the flusher and adds the staged rows to the corresponding partitions.
+//
+// stopCh passed to startFlusher must be closed before calling this function.
+func (bs *backfillStaging) mustStop() {
+	bs.wg.Wait()
+	bs.flush()
+}
+
+type backfillStagingMetrics struct {
+	BackfillStagingPendingRows uint64
+	BackfillStagingRows        uint64
+	BackfillStagingFlushes     uint64
+	BackfillStagingFlushErrors uint64
+}
+
+func (bs *backfillStaging) updateMetrics(m *backfillStagingMetrics) {
+	bs.mu.Lock()
+	m.BackfillStagingPendingRows += uint64(len(bs.rows))
+	bs.mu.Unlock()
+
+	m.BackfillStagingRows += atomic.LoadUint64(&bs.stagedRows)
+	m.BackfillStagingFlushes += atomic.LoadUint64(&bs.flushes)
+	m.BackfillStagingFlushErrors += atomic.LoadUint64(&bs.flushErrors)
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"os"
+	"sort"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestTableBackfillStaging(t *testing.T) {
+	const path = "TestTableBackfillStaging"
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+	}()
+
+	SetBackfillStaging(time.Hour, 1000, 0)
+	defer SetBackfillStaging(0, 0, 0)
+
+	now := timestampFromTime(time.Now())
+	newRows := func(ts int64) []rawRow {
+		var rows []rawRow
+		var r rawRow
+		r.PrecisionBits = 24
+		var ptr TimeRange
+		ptr.fromPartitionTimestamp(ts)
+		for i := 0; i < 100; i++ {
+			r.TSID.MetricID = uint64(i % 10)
+			r.Timestamp = ptr.MinTimestamp + int64(i)*1000
+			r.Value = float64(i)
+			rows = append(rows, r)
+		}
+		return rows
+	}
+	oldRows := newRows(now - 31*24*3600*1000)
+	currRows := newRows(now)
+	rows := append(append([]rawRow{}, oldRows...), currRows...)
+
+	var trData TimeRange
+	trData.fromPartitionTimestamp(now - 31*24*3600*1000)
+	trData.MaxTimestamp = now + 3600*1000
+	var tsids []TSID
+	for i := 0; i < 10; i++ {
+		tsids = append(tsids, TSID{MetricID: uint64(i)})
+	}
+	sort.Slice(tsids, func(i, j int) bool { return tsids[i].Less(&tsids[j]) })
+	rbsExpected := getTestExpectedRawBlocks(rows, tsids, trData)
+
+	strg := newTestStorage()
+	tb, err := openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+
+	// Rows for the current partition must be added immediately, while old rows must be staged.
+	if err := tb.AddRows(rows); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	if n := len(tb.ptws); n != 1 {
+		t.Fatalf("unexpected number of partitions; got %d; want 1", n)
+	}
+	var m TableMetrics
+	tb.UpdateMetrics(&m)
+	if m.BackfillStagingPendingRows != uint64(len(oldRows)) {
+		t.Fatalf("unexpected number of pending rows in staging area; got %d; want %d", m.BackfillStagingPendingRows, len(oldRows))
+	}
+	if m.BackfillStagingRows != uint64(len(oldRows)) {
+		t.Fatalf("unexpected number of staged rows; got %d; want %d", m.BackfillStagingRows, len(oldRows))
+	}
+
+	// The staged rows must be added to the historical partition on flush.
+	tb.flushPendingRows()
+	if n := len(tb.ptws); n != 2 {
+		t.Fatalf("unexpected number of partitions; got %d; want 2", n)
+	}
+	m = TableMetrics{}
+	tb.UpdateMetrics(&m)
+	if m.BackfillStagingPendingRows != 0 {
+		t.Fatalf("unexpected number of pending rows in staging area after flush; got %d; want 0", m.BackfillStagingPendingRows)
+	}
+	if m.BackfillStagingFlushes != 1 {
+		t.Fatalf("unexpected number of staging area flushes; got %d; want 1", m.BackfillStagingFlushes)
+	}
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+
+	// The staged rows must be flushed when the table is closed.
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+	if err := tb.AddRows(rows); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	tb.MustClose()
+	SetBackfillStaging(0, 0, 0)
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot open table: %s", err)
+	}
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+
+	// The staged rows must be flushed when their number exceeds the limit.
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	SetBackfillStaging(time.Hour, len(oldRows)/2, 0)
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+	if err := tb.AddRows(rows); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	if n := len(tb.ptws); n != 2 {
+		t.Fatalf("unexpected number of partitions; got %d; want 2", n)
+	}
+	tb.flushPendingRows()
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+
+	// The staged rows must be flushed when their memory usage exceeds the limit.
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+	SetBackfillStaging(time.Hour, 2*len(oldRows), len(oldRows)/2*int(unsafe.Sizeof(rawRow{})))
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+	if err := tb.AddRows(rows); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	if n := len(tb.ptws); n != 2 {
+		t.Fatalf("unexpected number of partitions; got %d; want 2", n)
+	}
+	tb.flushPendingRows()
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+}
@@ -16,6 +16,9 @@ type blockStreamMerger struct {
 	// Blocks with smaller timestamps are removed because of retention.
 	retentionDeadline int64
 
+	// holdDeadlines contains retention deadlines for held metricIDs. See Storage.AddHold.
+	holdDeadlines map[uint64]int64
+
 	// Whether the call to NextBlock must be no-op.
 	nextBlockNoop bool
 
@@ -32,14 +35,16 @@ func (bsm *blockStreamMerger) reset() {
 	bsm.bsrHeap = bsm.bsrHeap[:0]
 
 	bsm.retentionDeadline = 0
+	bsm.holdDeadlines = nil
 	bsm.nextBlockNoop = false
 	bsm.err = nil
 }
 
 // Init initializes bsm with the given bsrs.
-func (bsm *blockStreamMerger) Init(bsrs []*blockStreamReader, retentionDeadline int64) {
+func (bsm *blockStreamMerger) Init(bsrs []*blockStreamReader, retentionDeadline int64, holdDeadlines map[uint64]int64) {
 	bsm.reset()
 	bsm.retentionDeadline = retentionDeadline
+	bsm.holdDeadlines = holdDeadlines
 	for _, bsr := range bsrs {
 		if bsr.NextBlock() {
 			bsm.bsrHeap = append(bsm.bsrHeap, bsr)
@@ -62,6 +67,9 @@ func (bsm *blockStreamMerger) Init(bsrs []*blockStreamReader, retentionDeadline
 }
 
 func (bsm *blockStreamMerger) getRetentionDeadline(bh *blockHeader) int64 {
+	if d, ok := bsm.holdDeadlines[bh.TSID.MetricID]; ok && d < bsm.retentionDeadline {
+		return d
+	}
 	return bsm.retentionDeadline
 }
 
@@ -0,0 +1,367 @@
+package storage
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"sync"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+	"github.com/cespare/xxhash/v2"
+)
+
+// SetCardinalitySketches enables maintaining cardinality sketches for the ingested series.
+//
+// Sketches are updated when series are registered in per-day index, so TSDB status for recent days
+// can be obtained via Storage.GetTSDBStatusFromSketches without scanning indexdb.
+// If tenantLabel isn't empty, then separate sketches are maintained per each value of the given label.
+//
+// This function must be called before opening the storage.
+func SetCardinalitySketches(enabled bool, tenantLabel string) {
+	cardinalitySketchesEnabled = enabled
+	cardinalitySketchesTenantLabel = tenantLabel
+}
+
+var (
+	cardinalitySketchesEnabled     bool
+	cardinalitySketchesTenantLabel string
+)
+
+const (
+	// cardinalitySketchTopN is the maximum number of top entries tracked per each stats kind.
+	//
+	// It equals to the maximum topN value accepted by /api/v1/status/tsdb.
+	cardinalitySketchTopN = 1000
+
+	// cardinalitySketchMaxLabelNames is the maximum number of label names to track distinct values for per each sketch.
+	cardinalitySketchMaxLabelNames = 10000
+
+	// cardinalitySketchMaxDates is the number of recent days with sketches.
+	cardinalitySketchMaxDates = 3
+)
+
+// cardinalitySketches contains cardinality sketches for recent days.
+type cardinalitySketches struct {
+	tenantLabel string
+
+	mu sync.Mutex
+	m  map[uint64]*dateCardinalitySketches
+}
+
+type dateCardinalitySketches struct {
+	// seen contains metricIDs registered in the sketches for the given date.
+	seen uint64set.Set
+
+	tenants map[string]*cardinalitySketch
+}
+
+func newCardinalitySketches(tenantLabel string) *cardinalitySketches {
+	return &cardinalitySketches{
+		tenantLabel: tenantLabel,
+		m:           make(map[uint64]*dateCardinalitySketches),
+	}
+}
+
+// register registers mn with the given metricID in sketches for the given date.
+//
+// Every metricID is registered only once per date.
+func (css *cardinalitySketches) register(date, metricID uint64, mn *MetricName) {
+	currentDate := fasttime.UnixDate()
+	if date+1 < currentDate {
+		// Do not maintain sketches for historical data.
+		return
+	}
+	tenant := ""
+	if css.tenantLabel != "" {
+		tenant = string(mn.GetTagValue(css.tenantLabel))
+	}
+
+	css.mu.Lock()
+	defer css.mu.Unlock()
+
+	dcs := css.m[date]
+	if dcs == nil {
+		dcs = &dateCardinalitySketches{
+			tenants: make(map[string]*cardinalitySketch),
+		}
+		css.m[date] = dcs
+		// Drop sketches for old dates.
+		for d := range css.m {
+			if d+cardinalitySketchMaxDates <= date {
+				delete(css.m, d)
+			}
+		}
+	}
+	if dcs.seen.Has(metricID) {
+		return
+	}
+	dcs.seen.Add(metricID)
+	cs := dcs.tenants[tenant]
+	if cs == nil {
+		cs = newCardinalitySketch()
+		dcs.tenants[tenant] = cs
+	}
+	cs.register(mn)
+}
+
+// getTSDBStatus returns TSDB status for the given date and tenant from sketches.
+func (css *cardinalitySketches) getTSDBStatus(date uint64, tenant string, topN int) (*TSDBStatus, error) {
+	css.mu.Lock()
+	defer css.mu.Unlock()
+
+	dcs := css.m[date]
+	if dcs == nil {
+		return nil, fmt.Errorf("missing cardinality sketches for date=%s; sketches are available only for the last %d days since the storage start",
+			dateToString(date), cardinalitySketchMaxDates)
+	}
+	cs := dcs.tenants[tenant]
+	if cs == nil {
+		return &TSDBStatus{}, nil
+	}
+	return cs.getTSDBStatus(topN), nil
+}
+
+// cardinalitySketch contains approximate cardinality stats for series.
+type cardinalitySketch struct {
+	totalSeries uint64
+
+	labelValuePairs *hyperLogLog
+
+	seriesCountByMetricName     *topKSketch
+	seriesCountByLabelName      *topKSketch
+	seriesCountByLabelValuePair *topKSketch
+
+	labelValuesByLabelName map[string]*hyperLogLog
+
+	buf []byte
+}
+
+func newCardinalitySketch() *cardinalitySketch {
+	return &cardinalitySketch{
+		labelValuePairs:             newHyperLogLog(),
+		seriesCountByMetricName:     newTopKSketch(cardinalitySketchTopN),
+		seriesCountByLabelName:      newTopKSketch(cardinalitySketchTopN),
+		seriesCountByLabelValuePair: newTopKSketch(cardinalitySketchTopN),
+		labelValuesByLabelName:      make(map[string]*hyperLogLog),
+	}
+}
+
+func (cs *cardinalitySketch) register(mn *MetricName) {
+	cs.totalSeries++
+	cs.seriesCountByMetricName.add(mn.MetricGroup)
+	cs.registerLabel([]byte("__name__"), mn.MetricGroup)
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		cs.registerLabel(tag.Key, tag.Value)
+	}
+}
+
+func (cs *cardinalitySketch) registerLabel(name, value []byte) {
+	cs.buf = append(cs.buf[:0], name...)
+	cs.buf = append(cs.buf, '=')
+	cs.buf = append(cs.buf, value...)
+	cs.labelValuePairs.add(cs.buf)
+	cs.seriesCountByLabelValuePair.add(cs.buf)
+	cs.seriesCountByLabelName.add(name)
+
+	hll := cs.labelValuesByLabelName[string(name)]
+	if hll == nil {
+		if len(cs.labelValuesByLabelName) >= cardinalitySketchMaxLabelNames {
+			return
+		}
+		hll = newHyperLogLog()
+		cs.labelValuesByLabelName[string(name)] = hll
+	}
+	hll.add(value)
+}
+
+func (cs *cardinalitySketch) getTSDBStatus(topN int) *TSDBStatus {
+	labelValueCounts := make([]TopHeapEntry, 0, len(cs.labelValuesByLabelName))
+	for name, hll := range cs.labelValuesByLabelName {
+		labelValueCounts = append(labelValueCounts, TopHeapEntry{
+			Name:  name,
+			Count: hll.estimate(),
+		})
+	}
+	return &TSDBStatus{
+		TotalSeries:                 cs.totalSeries,
+		TotalLabelValuePairs:        cs.labelValuePairs.estimate(),
+		SeriesCountByMetricName:     cs.seriesCountByMetricName.getTop(topN),
+		SeriesCountByLabelName:      cs.seriesCountByLabelName.getTop(topN),
+		SeriesCountByLabelValuePair: cs.seriesCountByLabelValuePair.getTop(topN),
+		LabelValueCountByLabelName:  getTopEntries(labelValueCounts, topN),
+	}
+}
+
+func getTopEntries(a []TopHeapEntry, topN int) []TopHeapEntry {
+	sort.Slice(a, func(i, j int) bool {
+		if a[i].Count != a[j].Count {
+			return a[i].Count > a[j].Count
+		}
+		return a[i].Name < a[j].Name
+	})
+	if len(a) > topN {
+		a = a[:topN]
+	}
+	return a
+}
+
+const (
+	topKSketchDepth = 4
+	topKSketchWidth = 1 << 14
+)
+
+// topKSketch tracks approximate counts for the most frequent keys.
+//
+// Counts are estimated with Count-Min sketch, so they may be overestimated.
+type topKSketch struct {
+	counts [topKSketchDepth][topKSketchWidth]uint32
+
+	top   topKHeap
+	topM  map[string]*topKEntry
+	limit int
+}
+
+type topKEntry struct {
+	name  string
+	count uint64
+	idx   int
+}
+
+func newTopKSketch(limit int) *topKSketch {
+	return &topKSketch{
+		topM:  make(map[string]*topKEntry),
+		limit: limit,
+	}
+}
+
+func (tks *topKSketch) add(key []byte) {
+	h := xxhash.Sum64(key)
+	h1 := uint32(h)
+	h2 := uint32(h >> 32)
+	n := uint32(math.MaxUint32)
+	for i := range tks.counts {
+		idx := (h1 + uint32(i)*h2) % topKSketchWidth
+		c := tks.counts[i][idx]
+		if c < math.MaxUint32 {
+			c++
+			tks.counts[i][idx] = c
+		}
+		if c < n {
+			n = c
+		}
+	}
+	count := uint64(n)
+
+	if e := tks.topM[string(key)]; e != nil {
+		e.count = count
+		heap.Fix(&tks.top, e.idx)
+		return
+	}
+	if len(tks.top) < tks.limit {
+		e := &topKEntry{
+			name:  string(key),
+			count: count,
+		}
+		tks.topM[e.name] = e
+		heap.Push(&tks.top, e)
+		return
+	}
+	if e := tks.top[0]; count > e.count {
+		// Replace the entry with the minimum count.
+		delete(tks.topM, e.name)
+		e.name = string(key)
+		e.count = count
+		tks.topM[e.name] = e
+		heap.Fix(&tks.top, 0)
+	}
+}
+
+func (tks *topKSketch) getTop(topN int) []TopHeapEntry {
+	a := make([]TopHeapEntry, 0, len(tks.top))
+	for _, e := range tks.top {
+		a = append(a, TopHeapEntry{
+			Name:  e.name,
+			Count: e.count,
+		})
+	}
+	return getTopEntries(a, topN)
+}
+
+// topKHeap is a min-heap of topKEntry items ordered by count.
+type topKHeap []*topKEntry
+
+func (h topKHeap) Len() int {
+	return len(h)
+}
+
+func (h topKHeap) Less(i, j int) bool {
+	return h[i].count < h[j].count
+}
+
+func (h topKHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].idx = i
+	h[j].idx = j
+}
+
+func (h *topKHeap) Push(x interface{}) {
+	e := x.(*topKEntry)
+	e.idx = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *topKHeap) Pop() interface{} {
+	a := *h
+	e := a[len(a)-1]
+	*h = a[:len(a)-1]
+	return e
+}
+
+const (
+	hyperLogLogPrecision = 10
+	hyperLogLogRegisters = 1 << hyperLogLogPrecision
+)
+
+// hyperLogLog estimates the number of distinct items.
+//
+// The standard error is around 3%.
+type hyperLogLog struct {
+	registers [hyperLogLogRegisters]uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{}
+}
+
+func (hll *hyperLogLog) add(item []byte) {
+	h := xxhash.Sum64(item)
+	idx := h >> (64 - hyperLogLogPrecision)
+	w := h<<hyperLogLogPrecision | (1 << (hyperLogLogPrecision - 1))
+	rho := uint8(bits.LeadingZeros64(w)) + 1
+	if rho > hll.registers[idx] {
+		hll.registers[idx] = rho
+	}
+}
+
+func (hll *hyperLogLog) estimate() uint64 {
+	const m = float64(hyperLogLogRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range hll.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	alpha := 0.7213 / (1 + 1.079/m)
+	e := alpha * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		// Small range correction.
+		e = m * math.Log(m/float64(zeros))
+	}
+	return uint64(e + 0.5)
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+)
+
+func TestHyperLogLog(t *testing.T) {
+	f := func(n int) {
+		t.Helper()
+		hll := newHyperLogLog()
+		for i := 0; i < n; i++ {
+			// Add every item twice in order to verify that duplicates aren't counted.
+			hll.add([]byte(fmt.Sprintf("item_%d", i)))
+			hll.add([]byte(fmt.Sprintf("item_%d", i)))
+		}
+		estimate := hll.estimate()
+		if math.Abs(float64(estimate)-float64(n)) > 0.1*float64(n)+1 {
+			t.Fatalf("too big estimation error for n=%d; got %d", n, estimate)
+		}
+	}
+	f(0)
+	f(1)
+	f(10)
+	f(1000)
+	f(100000)
+}
+
+func TestTopKSketch(t *testing.T) {
+	tks := newTopKSketch(3)
+	for i := 0; i < 100; i++ {
+		for j := 0; j <= i%10; j++ {
+			tks.add([]byte(fmt.Sprintf("key_%d", j)))
+		}
+	}
+	top := tks.getTop(2)
+	topExpected := []TopHeapEntry{
+		{Name: "key_0", Count: 100},
+		{Name: "key_1", Count: 90},
+	}
+	if !reflect.DeepEqual(top, topExpected) {
+		t.Fatalf("unexpected top entries;\ngot\n%v\nwant\n%v", top, topExpected)
+	}
+	if n := len(tks.getTop(10)); n != 3 {
+		t.Fatalf("unexpected number of top entries; got %d; want 3", n)
+	}
+}
+
+func TestCardinalitySketches(t *testing.T) {
+	css := newCardinalitySketches("tenant")
+	date := fasttime.UnixDate()
+	var mn MetricName
+	for i := 0; i < 100; i++ {
+		mn.Reset()
+		mn.MetricGroup = []byte(fmt.Sprintf("metric_%d", i%2))
+		mn.AddTag("instance", fmt.Sprintf("host_%d", i))
+		if i%4 == 0 {
+			mn.AddTag("tenant", "foo")
+		}
+		mn.sortTags()
+		// Series must be registered only once per day.
+		css.register(date, uint64(i), &mn)
+		css.register(date, uint64(i), &mn)
+	}
+
+	// Historical data mustn't be registered.
+	css.register(date-10, 1, &mn)
+	if _, err := css.getTSDBStatus(date-10, "", 10); err == nil {
+		t.Fatalf("expecting non-nil error for missing date")
+	}
+
+	status, err := css.getTSDBStatus(date, "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if status.TotalSeries != 75 {
+		t.Fatalf("unexpected total series; got %d; want 75", status.TotalSeries)
+	}
+	seriesCountByMetricNameExpected := []TopHeapEntry{
+		{Name: "metric_1", Count: 50},
+		{Name: "metric_0", Count: 25},
+	}
+	if !reflect.DeepEqual(status.SeriesCountByMetricName, seriesCountByMetricNameExpected) {
+		t.Fatalf("unexpected series count by metric name;\ngot\n%v\nwant\n%v", status.SeriesCountByMetricName, seriesCountByMetricNameExpected)
+	}
+	seriesCountByLabelNameExpected := []TopHeapEntry{
+		{Name: "__name__", Count: 75},
+		{Name: "instance", Count: 75},
+	}
+	if !reflect.DeepEqual(status.SeriesCountByLabelName, seriesCountByLabelNameExpected) {
+		t.Fatalf("unexpected series count by label name;\ngot\n%v\nwant\n%v", status.SeriesCountByLabelName, seriesCountByLabelNameExpected)
+	}
+	if len(status.LabelValueCountByLabelName) != 2 || status.LabelValueCountByLabelName[0].Name != "instance" {
+		t.Fatalf("unexpected label value count by label name: %v", status.LabelValueCountByLabelName)
+	}
+
+	status, err = css.getTSDBStatus(date, "foo", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if status.TotalSeries != 25 {
+		t.Fatalf("unexpected total series for tenant foo; got %d; want 25", status.TotalSeries)
+	}
+	status, err = css.getTSDBStatus(date, "bar", 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if status.TotalSeries != 0 {
+		t.Fatalf("unexpected total series for missing tenant; got %d; want 0", status.TotalSeries)
+	}
+}
+
+func TestStorageGetTSDBStatusFromSketches(t *testing.T) {
+	SetCardinalitySketches(true, "")
+	defer SetCardinalitySketches(false, "")
+
+	path := "TestStorageGetTSDBStatusFromSketches"
+	s, err := OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	timestamp := timestampFromTime(time.Now())
+	var mrs []MetricRow
+	var mn MetricName
+	for i := 0; i < 30; i++ {
+		mn.Reset()
+		mn.MetricGroup = []byte(fmt.Sprintf("metric_%d", i%3))
+		mn.AddTag("job", "foo")
+		mn.AddTag("instance", fmt.Sprintf("host_%d", i))
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         float64(i),
+		})
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+			t.Fatalf("unexpected error when adding rows: %s", err)
+		}
+	}
+	date := uint64(timestamp) / msecPerDay
+	status, err := s.GetTSDBStatusFromSketches(date, "", 10)
+	if err != nil {
+		t.Fatalf("cannot obtain tsdb status from sketches: %s", err)
+	}
+	if status.TotalSeries != 30 {
+		t.Fatalf("unexpected total series; got %d; want 30", status.TotalSeries)
+	}
+	seriesCountByMetricNameExpected := []TopHeapEntry{
+		{Name: "metric_0", Count: 10},
+		{Name: "metric_1", Count: 10},
+		{Name: "metric_2", Count: 10},
+	}
+	if !reflect.DeepEqual(status.SeriesCountByMetricName, seriesCountByMetricNameExpected) {
+		t.Fatalf("unexpected series count by metric name;\ngot\n%v\nwant\n%v", status.SeriesCountByMetricName, seriesCountByMetricNameExpected)
+	}
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// SetCompositeLabelsIndexes sets label sets, which must have composite per-day index entries.
+//
+// Every item in labelSets must contain label names delimited by '+' such as `job+namespace`.
+// Composite index entries are used for speeding up searching for series with `{job="foo",namespace="bar"}` filters
+// on large tenants, where intersecting big per-label postings is slow.
+//
+// This function must be called before opening the storage.
+func SetCompositeLabelsIndexes(labelSets []string) error {
+	lss, err := parseCompositeLabelsIndexes(labelSets)
+	if err != nil {
+		return err
+	}
+	compositeLabelsIndexLabelSets = lss
+	return nil
+}
+
+var compositeLabelsIndexLabelSets [][]string
+
+func parseCompositeLabelsIndexes(labelSets []string) ([][]string, error) {
+	var lss [][]string
+	seen := make(map[string]bool)
+	for _, labelSet := range labelSets {
+		if strings.TrimSpace(labelSet) == "" {
+			continue
+		}
+		var labels []string
+		labelsSeen := make(map[string]bool)
+		for _, label := range strings.Split(labelSet, "+") {
+			label = strings.TrimSpace(label)
+			if label == "" {
+				return nil, fmt.Errorf("empty label name in composite index %q", labelSet)
+			}
+			if label == "__name__" {
+				return nil, fmt.Errorf("composite index %q cannot contain __name__, since metric names are already indexed together with every label", labelSet)
+			}
+			if labelsSeen[label] {
+				return nil, fmt.Errorf("duplicate label %q in composite index %q", label, labelSet)
+			}
+			labelsSeen[label] = true
+			labels = append(labels, label)
+		}
+		if len(labels) < 2 {
+			return nil, fmt.Errorf("composite index %q must contain at least two label names", labelSet)
+		}
+		sort.Strings(labels)
+		name := strings.Join(labels, "+")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		lss = append(lss, labels)
+	}
+	return lss, nil
+}
+
+// compositeLabelsIndex contains per-day index entries for series with all the labels from the given label set.
+type compositeLabelsIndex struct {
+	// labels contains sorted label names for the index.
+	labels []string
+
+	// key is the artificial tag key used for index entries.
+	key []byte
+
+	// minTimestamp is the minimum timestamp for the time range, which can be searched via the index.
+	//
+	// Series registered in per-day index before the index has been enabled have no composite entries.
+	minTimestamp int64
+}
+
+func newCompositeLabelsIndex(labels []string, minTimestamp int64) *compositeLabelsIndex {
+	return &compositeLabelsIndex{
+		labels:       labels,
+		key:          marshalCompositeLabelsKey(nil, labels),
+		minTimestamp: minTimestamp,
+	}
+}
+
+// The prefix for composite labels tag, which is used for speeding up searching
+// for filters with exact matches on all the labels from -storage.compositeIndexLabels.
+//
+// It is expected that the given prefix isn't used by users.
+const compositeLabelsKeyPrefix = '\xfd'
+
+func marshalCompositeLabelsKey(dst []byte, labels []string) []byte {
+	dst = append(dst, compositeLabelsKeyPrefix)
+	for _, label := range labels {
+		dst = encoding.MarshalVarUint64(dst, uint64(len(label)))
+		dst = append(dst, label...)
+	}
+	return dst
+}
+
+func unmarshalCompositeLabelsKey(src []byte) ([]string, error) {
+	if len(src) == 0 || src[0] != compositeLabelsKeyPrefix {
+		return nil, fmt.Errorf("missing composite labels key prefix in %q", src)
+	}
+	src = src[1:]
+	var labels []string
+	for len(src) > 0 {
+		tail, n, err := encoding.UnmarshalVarUint64(src)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal label name length from composite labels key: %w", err)
+		}
+		if uint64(len(tail)) < n {
+			return nil, fmt.Errorf("missing label name with length %d in composite labels key %q", n, tail)
+		}
+		labels = append(labels, string(tail[:n]))
+		src = tail[n:]
+	}
+	return labels, nil
+}
+
+func isCompositeLabelsKey(key []byte) bool {
+	return len(key) > 0 && key[0] == compositeLabelsKeyPrefix
+}
+
+// marshalValue appends composite value for mn to dst.
+//
+// false is returned if mn misses some of the labels from cli.
+func (cli *compositeLabelsIndex) marshalValue(dst []byte, mn *MetricName) ([]byte, bool) {
+	for _, label := range cli.labels {
+		value := mn.GetTagValue(label)
+		if len(value) == 0 {
+			return dst, false
+		}
+		dst = encoding.MarshalVarUint64(dst, uint64(len(value)))
+		dst = append(dst, value...)
+	}
+	return dst, true
+}
+
+// marshalFilterValue appends composite value for exact filters from tfs to dst.
+//
+// false is returned if tfs has no exact filters for all the labels from cli.
+func (cli *compositeLabelsIndex) marshalFilterValue(dst []byte, tfs []tagFilter) ([]byte, bool) {
+	for _, label := range cli.labels {
+		var value []byte
+		for i := range tfs {
+			tf := &tfs[i]
+			if tf.isNegative || tf.isRegexp || len(tf.value) == 0 || string(tf.key) != label {
+				continue
+			}
+			if value != nil && string(value) != string(tf.value) {
+				// Conflicting filters such as {job="a",job="b"} match nothing. Leave them as is.
+				return dst, false
+			}
+			value = tf.value
+		}
+		if value == nil {
+			return dst, false
+		}
+		dst = encoding.MarshalVarUint64(dst, uint64(len(value)))
+		dst = append(dst, value...)
+	}
+	return dst, true
+}
+
+func (ii *indexItems) registerCompositeLabelsIndexes(prefix []byte, mn *MetricName, metricID uint64, clis []*compositeLabelsIndex) {
+	if len(clis) == 0 {
+		return
+	}
+	value := kbPool.Get()
+	for _, cli := range clis {
+		var ok bool
+		value.B, ok = cli.marshalValue(value.B[:0], mn)
+		if !ok {
+			continue
+		}
+		ii.B = append(ii.B, prefix...)
+		ii.B = marshalTagValue(ii.B, cli.key)
+		ii.B = marshalTagValue(ii.B, value.B)
+		ii.B = encoding.MarshalUint64(ii.B, metricID)
+		ii.Next()
+	}
+	kbPool.Put(value)
+}
+
+// addCompositeLabelsFilters adds composite labels filters to tfss if they contain exact filters
+// on all the labels from composite labels indexes, which cover the given tr.
+//
+// The original filters are preserved, so the composite labels filters may be dropped at any time without affecting the search results.
+func addCompositeLabelsFilters(tfss []*TagFilters, tr TimeRange, clis []*compositeLabelsIndex) []*TagFilters {
+	if len(clis) == 0 {
+		return tfss
+	}
+	var tfssNew []*TagFilters
+	var value []byte
+	for i, tfs := range tfss {
+		var tfsNew *TagFilters
+		for _, cli := range clis {
+			if tr.MinTimestamp < cli.minTimestamp {
+				continue
+			}
+			var ok bool
+			value, ok = cli.marshalFilterValue(value[:0], tfs.tfs)
+			if !ok {
+				continue
+			}
+			if tfsNew == nil {
+				tfsNew = &TagFilters{
+					tfs:          append([]tagFilter{}, tfs.tfs...),
+					commonPrefix: tfs.commonPrefix,
+				}
+			}
+			tf := tfsNew.addTagFilter()
+			if err := tf.Init(tfs.commonPrefix, cli.key, value, false, false); err != nil {
+				logger.Panicf("BUG: unexpected error when creating composite labels filter for %q: %s", cli.labels, err)
+			}
+		}
+		if tfsNew == nil {
+			if tfssNew != nil {
+				tfssNew = append(tfssNew, tfs)
+			}
+			continue
+		}
+		if tfssNew == nil {
+			tfssNew = append(make([]*TagFilters, 0, len(tfss)), tfss[:i]...)
+		}
+		tfssNew = append(tfssNew, tfsNew)
+		atomic.AddUint64(&compositeLabelsFilterConversions, 1)
+	}
+	if tfssNew == nil {
+		return tfss
+	}
+	return tfssNew
+}
+
+// removeCompositeLabelsFilters removes composite labels filters from tfs.
+//
+// This is needed for searching in the global index, since it has no composite labels entries.
+func removeCompositeLabelsFilters(tfs *TagFilters) *TagFilters {
+	n := 0
+	for i := range tfs.tfs {
+		if isCompositeLabelsKey(tfs.tfs[i].key) {
+			n++
+		}
+	}
+	if n == 0 {
+		return tfs
+	}
+	tfsNew := &TagFilters{
+		tfs:          make([]tagFilter, 0, len(tfs.tfs)-n),
+		commonPrefix: tfs.commonPrefix,
+	}
+	for _, tf := range tfs.tfs {
+		if !isCompositeLabelsKey(tf.key) {
+			tfsNew.tfs = append(tfsNew.tfs, tf)
+		}
+	}
+	return tfsNew
+}
+
+var compositeLabelsFilterConversions uint64
+
+// mustLoadCompositeLabelsIndexes returns composite labels indexes for the given labelSets.
+//
+// The minimum timestamp for every index is persisted at metadataDir, so it survives restarts.
+// Indexes missing in labelSets are forgotten, so they start from scratch when enabled again.
+func mustLoadCompositeLabelsIndexes(metadataDir string, isEmptyDB bool, labelSets [][]string) []*compositeLabelsIndex {
+	path := metadataDir + "/compositeLabelsIndexes"
+	minTimestamps := make(map[string]int64)
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &minTimestamps); err != nil {
+			logger.Errorf("cannot parse %q, so re-creating it; error: %s", path, err)
+			minTimestamps = make(map[string]int64)
+		}
+	} else if !os.IsNotExist(err) {
+		logger.Errorf("cannot read %q, so re-creating it; error: %s", path, err)
+	}
+	if len(labelSets) == 0 && len(minTimestamps) == 0 {
+		return nil
+	}
+
+	var newMinTimestamp int64
+	if !isEmptyDB {
+		// The current and the next day can already contain per-day indexes without composite labels entries,
+		// so they cannot be queried via the newly enabled composite labels index.
+		date := time.Now().UnixNano() / 1e6 / msecPerDay
+		newMinTimestamp = (date + 2) * msecPerDay
+	}
+	var clis []*compositeLabelsIndex
+	minTimestampsNew := make(map[string]int64, len(labelSets))
+	for _, labels := range labelSets {
+		name := strings.Join(labels, "+")
+		minTimestamp, ok := minTimestamps[name]
+		if !ok {
+			minTimestamp = newMinTimestamp
+			logger.Infof("enabling composite index for labels %q; it will be used for queries starting from %s",
+				name, time.Unix(minTimestamp/1e3, 0).UTC().Format(time.RFC3339))
+		}
+		minTimestampsNew[name] = minTimestamp
+		clis = append(clis, newCompositeLabelsIndex(labels, minTimestamp))
+	}
+	data, err = json.Marshal(minTimestampsNew)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal composite labels indexes: %s", err)
+	}
+	if err := fs.WriteFileAtomically(path, data, true); err != nil {
+		logger.Fatalf("cannot store composite labels indexes: %s", err)
+	}
+	return clis
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseCompositeLabelsIndexesSuccess(t *testing.T) {
+	f := func(labelSets []string, resultExpected [][]string) {
+		t.Helper()
+		lss, err := parseCompositeLabelsIndexes(labelSets)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(lss, resultExpected) {
+			t.Fatalf("unexpected result; got %q; want %q", lss, resultExpected)
+		}
+	}
+	f(nil, nil)
+	f([]string{""}, nil)
+	f([]string{"job+namespace"}, [][]string{{"job", "namespace"}})
+	f([]string{"namespace+ job", "job+namespace", "a+b+c"}, [][]string{{"job", "namespace"}, {"a", "b", "c"}})
+}
+
+func TestParseCompositeLabelsIndexesFailure(t *testing.T) {
+	f := func(labelSet string) {
+		t.Helper()
+		if _, err := parseCompositeLabelsIndexes([]string{labelSet}); err == nil {
+			t.Fatalf("expecting non-nil error for %q", labelSet)
+		}
+	}
+	f("job")
+	f("job+")
+	f("job+job")
+	f("__name__+job")
+}
+
+func TestCompositeLabelsKeyMarshalUnmarshal(t *testing.T) {
+	labels := []string{"job", "namespace"}
+	key := marshalCompositeLabelsKey(nil, labels)
+	if !isCompositeLabelsKey(key) {
+		t.Fatalf("expecting composite labels key for %q", key)
+	}
+	if !isArtificialTagKey(key) {
+		t.Fatalf("composite labels key %q must be artificial", key)
+	}
+	result, err := unmarshalCompositeLabelsKey(key)
+	if err != nil {
+		t.Fatalf("cannot unmarshal composite labels key: %s", err)
+	}
+	if !reflect.DeepEqual(result, labels) {
+		t.Fatalf("unexpected labels; got %q; want %q", result, labels)
+	}
+}
+
+func TestAddCompositeLabelsFilters(t *testing.T) {
+	clis := []*compositeLabelsIndex{
+		newCompositeLabelsIndex([]string{"job", "namespace"}, 1000),
+	}
+	tr := TimeRange{
+		MinTimestamp: 2000,
+		MaxTimestamp: 3000,
+	}
+	f := func(filters []string, tr TimeRange, resultExpected string) {
+		t.Helper()
+		tfs := NewTagFilters()
+		for i := 0; i < len(filters); i += 3 {
+			isRegexp := filters[i+1] == "=~"
+			if err := tfs.Add([]byte(filters[i]), []byte(filters[i+2]), false, isRegexp); err != nil {
+				t.Fatalf("cannot add filter: %s", err)
+			}
+		}
+		tfssOrig := tfs.String()
+		tfss := addCompositeLabelsFilters([]*TagFilters{tfs}, tr, clis)
+		if len(tfss) != 1 {
+			t.Fatalf("unexpected number of tfss; got %d; want 1", len(tfss))
+		}
+		if result := tfss[0].String(); result != resultExpected {
+			t.Fatalf("unexpected filters;\ngot\n%s\nwant\n%s", result, resultExpected)
+		}
+		if s := tfs.String(); s != tfssOrig {
+			t.Fatalf("the original filters must be left unchanged; got %s; want %s", s, tfssOrig)
+		}
+		tfsWithout := removeCompositeLabelsFilters(tfss[0])
+		if s := tfsWithout.String(); s != tfssOrig {
+			t.Fatalf("unexpected filters after removing composite labels filters; got %s; want %s", s, tfssOrig)
+		}
+	}
+
+	// Exact filters on all the labels.
+	f([]string{"namespace", "=", "bar", "job", "=", "foo"}, tr,
+		`{namespace="bar",job="foo",composite_labels(job+namespace)="\x03foo\x03bar"}`)
+
+	// Missing label
+	f([]string{"job", "=", "foo", "instance", "=", "x"}, tr, `{job="foo",instance="x"}`)
+
+	// Regexp filter
+	f([]string{"job", "=~", "foo.+", "namespace", "=", "bar"}, tr, `{job=~"foo.+",namespace="bar"}`)
+
+	// Time range before the index has been enabled
+	f([]string{"job", "=", "foo", "namespace", "=", "bar"}, TimeRange{MinTimestamp: 500, MaxTimestamp: 3000}, `{job="foo",namespace="bar"}`)
+}
+
+func TestStorageCompositeLabelsIndex(t *testing.T) {
+	if err := SetCompositeLabelsIndexes([]string{"job+namespace"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer func() {
+		_ = SetCompositeLabelsIndexes(nil)
+	}()
+
+	path := "TestStorageCompositeLabelsIndex"
+	s, err := OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	if len(s.compositeLabelsIndexes) != 1 || s.compositeLabelsIndexes[0].minTimestamp != 0 {
+		t.Fatalf("composite labels index must be enabled from the beginning for empty storage")
+	}
+
+	const seriesCount = 300
+	timestamp := timestampFromTime(time.Now())
+	var mrs []MetricRow
+	var mn MetricName
+	for i := 0; i < seriesCount; i++ {
+		mn.Reset()
+		mn.MetricGroup = []byte("up")
+		mn.AddTag("job", fmt.Sprintf("job_%d", i%3))
+		mn.AddTag("instance", fmt.Sprintf("instance_%d", i))
+		if i%10 != 0 {
+			mn.AddTag("namespace", fmt.Sprintf("ns_%d", i%5))
+		}
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     timestamp,
+			Value:         float64(i),
+		})
+	}
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error when adding rows: %s", err)
+	}
+	s.DebugFlush()
+
+	tr := TimeRange{
+		MinTimestamp: timestamp - 3600*1000,
+		MaxTimestamp: timestamp + 1000,
+	}
+	searchSeries := func(filters ...string) int {
+		t.Helper()
+		tfs := NewTagFilters()
+		for i := 0; i < len(filters); i += 2 {
+			key := []byte(filters[i])
+			if filters[i] == "__name__" {
+				key = nil
+			}
+			if err := tfs.Add(key, []byte(filters[i+1]), false, false); err != nil {
+				t.Fatalf("cannot add filter: %s", err)
+			}
+		}
+		metricNames, err := s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+		if err != nil {
+			t.Fatalf("cannot search metric names: %s", err)
+		}
+		return len(metricNames)
+	}
+
+	// Series with i%3 == 1 && i%5 == 2 && i%10 != 0
+	conversions := atomic.LoadUint64(&compositeLabelsFilterConversions)
+	if n := searchSeries("job", "job_1", "namespace", "ns_2"); n != 20 {
+		t.Fatalf("unexpected number of series found; got %d; want 20", n)
+	}
+	if n := searchSeries("__name__", "up", "job", "job_1", "namespace", "ns_2", "instance", "instance_7"); n != 1 {
+		t.Fatalf("unexpected number of series found; got %d; want 1", n)
+	}
+	if n := atomic.LoadUint64(&compositeLabelsFilterConversions) - conversions; n != 2 {
+		t.Fatalf("unexpected number of composite labels filter conversions; got %d; want 2", n)
+	}
+
+	// Per-day index must contain composite labels entries.
+	cli := s.compositeLabelsIndexes[0]
+	tfs := NewTagFilters()
+	value, _ := cli.marshalFilterValue(nil, []tagFilter{
+		{key: []byte("job"), value: []byte("job_1")},
+		{key: []byte("namespace"), value: []byte("ns_2")},
+	})
+	if err := tfs.addTagFilter().Init(tfs.commonPrefix, cli.key, value, false, false); err != nil {
+		t.Fatalf("cannot init composite labels filter: %s", err)
+	}
+	metricNames, err := s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+	if err != nil {
+		t.Fatalf("cannot search metric names: %s", err)
+	}
+	if len(metricNames) != 20 {
+		t.Fatalf("unexpected number of series found via composite labels filter; got %d; want 20", len(metricNames))
+	}
+
+	// Series without namespace label must be found via the ordinary index.
+	if n := searchSeries("job", "job_0"); n != 100 {
+		t.Fatalf("unexpected number of series found; got %d; want 100", n)
+	}
+
+	// Composite labels entries mustn't be visible via label names API.
+	lns, err := s.SearchLabelNamesWithFiltersOnTimeRange(nil, nil, tr, 1e5, 1e9, noDeadline)
+	if err != nil {
+		t.Fatalf("cannot search label names: %s", err)
+	}
+	for _, ln := range lns {
+		if isArtificialTagKey([]byte(ln)) {
+			t.Fatalf("unexpected artificial label name %q", ln)
+		}
+	}
+	if len(lns) != 4 {
+		t.Fatalf("unexpected label names; got %q; want 4 label names", lns)
+	}
+	s.MustClose()
+
+	// Re-enabling the index for non-empty storage must postpone its usage.
+	if err := SetCompositeLabelsIndexes(nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s, err = OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	s.MustClose()
+	if err := SetCompositeLabelsIndexes([]string{"namespace+job"}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s, err = OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	if len(s.compositeLabelsIndexes) != 1 || s.compositeLabelsIndexes[0].minTimestamp <= timestamp {
+		t.Fatalf("re-enabled composite labels index must be used only for the future days")
+	}
+	conversions = atomic.LoadUint64(&compositeLabelsFilterConversions)
+	if n := searchSeries("job", "job_1", "namespace", "ns_2"); n != 20 {
+		t.Fatalf("unexpected number of series found; got %d; want 20", n)
+	}
+	if n := atomic.LoadUint64(&compositeLabelsFilterConversions) - conversions; n != 0 {
+		t.Fatalf("unexpected number of composite labels filter conversions; got %d; want 0", n)
+	}
+	s.MustClose()
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var extraDataPaths []string
+
+// SetExtraDataPaths sets additional paths for storing per-month partitions.
+//
+// New partitions are placed either at the storage path or at one of the extra paths
+// depending on the available free disk space. Every extra path is usually located at a distinct disk (aka JBOD).
+// The extra paths, which become unavailable, are excluded from placement of new partitions,
+// while partitions located at the unavailable paths are skipped when opening the storage.
+//
+// This function must be called before opening the storage.
+func SetExtraDataPaths(paths []string) {
+	extraDataPaths = append(extraDataPaths[:0], paths...)
+}
+
+// dataPathsCheckInterval is the interval for checking the health and free disk space for data paths.
+var dataPathsCheckInterval = 10 * time.Second
+
+// dataPaths distributes partitions of the table among multiple data paths.
+//
+// Partitions located at extra data paths are symlinked from the table directory,
+// so they are opened, searched and merged in the same way as the partitions located at the table directory.
+type dataPaths struct {
+	// Atomic counters must go at the top of the structure in order to properly align by 8 bytes on 32-bit archs.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/212 .
+	unavailablePartitionRows uint64
+
+	// paths contains all the data paths for the table. paths[0] is the table path.
+	paths []*dataPath
+
+	// unavailablePartitions contains names of partitions, which couldn't be opened because their data path was unavailable.
+	unavailablePartitions map[string]bool
+
+	stopCh    chan struct{}
+	watcherWG sync.WaitGroup
+}
+
+// dataPath is a single data path for the table.
+type dataPath struct {
+	// freeSpace is the free disk space at path according to the last check.
+	//
+	// It must go at the top of the structure in order to properly align by 8 bytes on 32-bit archs.
+	freeSpace uint64
+
+	// path is the path to the table at the given disk.
+	path string
+
+	flockF *os.File
+
+	// isOpened is set to true if path has been successfully opened on startup.
+	isOpened bool
+
+	// healthy is set to 1 if path can be used for new partitions.
+	healthy uint32
+}
+
+// openDataPaths opens extra data paths for the table located at path.
+//
+// Every extra data path gets a directory with the same name as the table directory.
+func openDataPaths(path string, extraPaths []string) (*dataPaths, error) {
+	dps := &dataPaths{
+		paths: []*dataPath{{
+			path:     path,
+			isOpened: true,
+		}},
+		unavailablePartitions: make(map[string]bool),
+		stopCh:                make(chan struct{}),
+	}
+	tableName := filepath.Base(path)
+	for _, extraPath := range extraPaths {
+		tablePath, err := filepath.Abs(filepath.Join(extraPath, tableName))
+		if err != nil {
+			dps.mustClose()
+			return nil, fmt.Errorf("cannot determine absolute path for %q: %w", extraPath, err)
+		}
+		dp, err := openDataPath(tablePath)
+		if err != nil {
+			// Do not fail on unavailable extra path, since it may contain only a part of data.
+			// Partitions located at this path will be skipped when opening the table.
+			logger.Errorf("skipping unavailable data path %q: %s", tablePath, err)
+			dp = &dataPath{
+				path: tablePath,
+			}
+		}
+		dps.paths = append(dps.paths, dp)
+	}
+	dps.checkHealth()
+	return dps, nil
+}
+
+func openDataPath(path string) (*dataPath, error) {
+	for _, dir := range []string{path + "/small/snapshots", path + "/big/snapshots"} {
+		if err := fs.MkdirAllIfNotExist(dir); err != nil {
+			return nil, fmt.Errorf("cannot create directory %q: %w", dir, err)
+		}
+	}
+	flockF, err := fs.CreateFlockFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create lock file in %q; make sure the dir isn't used by other processes: %w", path, err)
+	}
+	fs.MustRemoveTemporaryDirs(path + "/small")
+	fs.MustRemoveTemporaryDirs(path + "/big")
+	fs.MustRemoveTemporaryDirs(path + "/small/snapshots")
+	fs.MustRemoveTemporaryDirs(path + "/big/snapshots")
+	dp := &dataPath{
+		path:     path,
+		flockF:   flockF,
+		isOpened: true,
+	}
+	return dp, nil
+}
+
+func (dps *dataPaths) startWatcher() {
+	dps.watcherWG.Add(1)
+	go func() {
+		defer dps.watcherWG.Done()
+		ticker := time.NewTicker(dataPathsCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-dps.stopCh:
+				return
+			case <-ticker.C:
+				dps.checkHealth()
+			}
+		}
+	}()
+}
+
+func (dps *dataPaths) mustClose() {
+	close(dps.stopCh)
+	dps.watcherWG.Wait()
+	for _, dp := range dps.paths {
+		if dp.flockF != nil {
+			fs.MustClose(dp.flockF)
+			dp.flockF = nil
+		}
+	}
+}
+
+// checkHealth updates health status and free disk space for all the data paths.
+func (dps *dataPaths) checkHealth() {
+	for _, dp := range dps.paths {
+		freeSpace, err := dp.check()
+		if err != nil {
+			if atomic.SwapUint32(&dp.healthy, 0) == 1 {
+				logger.Errorf("excluding data path %q from placement of new partitions: %s", dp.path, err)
+			}
+			continue
+		}
+		atomic.StoreUint64(&dp.freeSpace, freeSpace)
+		if atomic.SwapUint32(&dp.healthy, 1) == 0 {
+			logger.Infof("data path %q is available for placement of new partitions; free disk space: %d bytes", dp.path, freeSpace)
+		}
+	}
+}
+
+// check verifies whether new data can be written to dp and returns the free disk space at dp.
+func (dp *dataPath) check() (uint64, error) {
+	if !dp.isOpened {
+		return 0, fmt.Errorf("the path couldn't be opened on startup")
+	}
+	probePath := dp.path + "/small/health_check.tmp"
+	if err := os.WriteFile(probePath, []byte("ok"), 0600); err != nil {
+		return 0, fmt.Errorf("cannot write probe file: %w", err)
+	}
+	if err := os.Remove(probePath); err != nil {
+		return 0, fmt.Errorf("cannot remove probe file: %w", err)
+	}
+	freeSpace := fs.MustGetFreeSpace(dp.path)
+	if freeSpace < freeDiskSpaceLimitBytes {
+		return freeSpace, fmt.Errorf("free disk space is lower than -storage.minFreeDiskSpaceBytes=%d; %d bytes left", freeDiskSpaceLimitBytes, freeSpace)
+	}
+	return freeSpace, nil
+}
+
+// placePartition prepares directories for the partition with the given name at the data path with the most free disk space.
+//
+// The partition directories at smallPartitionsPath and bigPartitionsPath are symlinked to the selected data path
+// if it differs from the table path. Nothing is done if dps is nil.
+func (dps *dataPaths) placePartition(smallPartitionsPath, bigPartitionsPath, ptName string) error {
+	if dps == nil {
+		return nil
+	}
+	var dpBest *dataPath
+	for _, dp := range dps.paths {
+		if atomic.LoadUint32(&dp.healthy) == 0 {
+			continue
+		}
+		if dpBest == nil || atomic.LoadUint64(&dp.freeSpace) > atomic.LoadUint64(&dpBest.freeSpace) {
+			dpBest = dp
+		}
+	}
+	if dpBest == nil || dpBest == dps.paths[0] {
+		// Place the partition at the table path.
+		return nil
+	}
+	logger.Infof("placing partition %q at data path %q with %d bytes of free disk space", ptName, dpBest.path, atomic.LoadUint64(&dpBest.freeSpace))
+	for _, ptsPath := range []string{"small", "big"} {
+		dstPath := dpBest.path + "/" + ptsPath + "/" + ptName
+		if err := fs.MkdirAllFailIfExist(dstPath); err != nil {
+			return fmt.Errorf("cannot create partition directory at data path %q: %w", dpBest.path, err)
+		}
+		linkPath := smallPartitionsPath + "/" + ptName
+		if ptsPath == "big" {
+			linkPath = bigPartitionsPath + "/" + ptName
+		}
+		if err := os.Symlink(dstPath, linkPath); err != nil {
+			return fmt.Errorf("cannot create symlink for partition directory %q: %w", dstPath, err)
+		}
+		fs.MustSyncPath(filepath.Dir(linkPath))
+	}
+	return nil
+}
+
+// isPartitionAvailable returns false if the partition at smallPartsPath and bigPartsPath is located at unavailable data path.
+//
+// Such partitions are skipped when opening the table.
+func (dps *dataPaths) isPartitionAvailable(ptName, smallPartsPath, bigPartsPath string) bool {
+	if dps == nil {
+		return true
+	}
+	for _, path := range []string{smallPartsPath, bigPartsPath} {
+		_, err := os.Stat(path)
+		if err == nil {
+			continue
+		}
+		if _, errLink := os.Readlink(path); os.IsNotExist(err) && errLink != nil {
+			// Partition directory may be missing after restoring from backup.
+			continue
+		}
+		logger.Errorf("skipping partition %q, since it is located at unavailable data path: %s", ptName, err)
+		dps.unavailablePartitions[ptName] = true
+		return false
+	}
+	return true
+}
+
+// hasUnavailablePartition returns true if timestamp belongs to the partition located at unavailable data path.
+//
+// Such partitions cannot be created again until their data path becomes available after the restart.
+func (dps *dataPaths) hasUnavailablePartition(timestamp int64) bool {
+	if dps == nil || len(dps.unavailablePartitions) == 0 {
+		return false
+	}
+	return dps.unavailablePartitions[timestampToPartitionName(timestamp)]
+}
+
+// createSnapshotPaths returns paths for the snapshot of the partition located at smallPartsPath and bigPartsPath.
+//
+// Snapshots are created via hard links, so snapshots for partitions located at extra data paths must be created at the same data path.
+// Such snapshots are symlinked from dstSmallPath and dstBigPath.
+func (dps *dataPaths) createSnapshotPaths(snapshotName, ptName, smallPartsPath, dstSmallPath, dstBigPath string) (string, string, error) {
+	if dps == nil {
+		return dstSmallPath, dstBigPath, nil
+	}
+	target, err := os.Readlink(smallPartsPath)
+	if err != nil {
+		// The partition is located at the table path.
+		return dstSmallPath, dstBigPath, nil
+	}
+	// target has the form <dataPath>/small/<ptName>
+	dataPath := filepath.Dir(filepath.Dir(target))
+	srcSmallPath := fmt.Sprintf("%s/small/snapshots/%s/%s", dataPath, snapshotName, ptName)
+	srcBigPath := fmt.Sprintf("%s/big/snapshots/%s/%s", dataPath, snapshotName, ptName)
+	for _, dir := range []string{filepath.Dir(srcSmallPath), filepath.Dir(srcBigPath)} {
+		if err := fs.MkdirAllIfNotExist(dir); err != nil {
+			return "", "", fmt.Errorf("cannot create snapshot dir at data path %q: %w", dataPath, err)
+		}
+	}
+	if err := os.Symlink(srcSmallPath, dstSmallPath); err != nil {
+		return "", "", fmt.Errorf("cannot create symlink for snapshot %q: %w", srcSmallPath, err)
+	}
+	if err := os.Symlink(srcBigPath, dstBigPath); err != nil {
+		return "", "", fmt.Errorf("cannot create symlink for snapshot %q: %w", srcBigPath, err)
+	}
+	return srcSmallPath, srcBigPath, nil
+}
+
+// mustDeleteSnapshot deletes snapshot with the given snapshotName from the available extra data paths.
+func (dps *dataPaths) mustDeleteSnapshot(snapshotName string) {
+	if dps == nil {
+		return
+	}
+	for _, dp := range dps.paths[1:] {
+		if !dp.isOpened {
+			continue
+		}
+		fs.MustRemoveDirAtomic(dp.path + "/small/snapshots/" + snapshotName)
+		fs.MustRemoveDirAtomic(dp.path + "/big/snapshots/" + snapshotName)
+	}
+}
+
+type dataPathsMetrics struct {
+	DataPaths                uint64
+	UnhealthyDataPaths       uint64
+	UnavailablePartitions    uint64
+	UnavailablePartitionRows uint64
+}
+
+func (dps *dataPaths) updateMetrics(m *dataPathsMetrics) {
+	m.DataPaths += uint64(len(dps.paths))
+	for _, dp := range dps.paths {
+		if atomic.LoadUint32(&dp.healthy) == 0 {
+			m.UnhealthyDataPaths++
+		}
+	}
+	m.UnavailablePartitions += uint64(len(dps.unavailablePartitions))
+	m.UnavailablePartitionRows += atomic.LoadUint64(&dps.unavailablePartitionRows)
+}
+
+// mustRemovePartitionDir removes partition directory at path.
+//
+// If path is a symlink to partition directory at extra data path, then the directory is removed too.
+func mustRemovePartitionDir(path string) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		fs.MustRemoveDirAtomic(path)
+		return
+	}
+	fs.MustRemoveDirAtomic(target)
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		logger.Panicf("FATAL: cannot remove symlink %q: %s", path, err)
+	}
+	fs.MustSyncPath(filepath.Dir(path))
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestTableExtraDataPaths(t *testing.T) {
+	const path = "TestTableExtraDataPaths"
+	extraPath, err := filepath.Abs("TestTableExtraDataPaths-extra")
+	if err != nil {
+		t.Fatalf("cannot obtain absolute path: %s", err)
+	}
+	for _, p := range []string{path, extraPath, extraPath + ".bak"} {
+		if err := os.RemoveAll(p); err != nil {
+			t.Fatalf("cannot remove %q: %s", p, err)
+		}
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+		_ = os.RemoveAll(extraPath)
+		_ = os.RemoveAll(extraPath + ".bak")
+	}()
+
+	SetExtraDataPaths([]string{extraPath})
+	defer SetExtraDataPaths(nil)
+
+	now := timestampFromTime(time.Now())
+	var rows []rawRow
+	var r rawRow
+	r.PrecisionBits = 24
+	var trData TimeRange
+	trData.fromPartitionTimestamp(now - 31*24*3600*1000)
+	trData.MaxTimestamp = now + 3600*1000
+	var ptNames []string
+	for _, ts := range []int64{now - 31*24*3600*1000, now} {
+		var ptr TimeRange
+		ptr.fromPartitionTimestamp(ts)
+		ptNames = append(ptNames, timestampToPartitionName(ts))
+		for i := 0; i < 100; i++ {
+			r.TSID.MetricID = uint64(i % 10)
+			r.Timestamp = ptr.MinTimestamp + int64(i)*1000
+			r.Value = float64(i)
+			rows = append(rows, r)
+		}
+	}
+	var tsids []TSID
+	for i := 0; i < 10; i++ {
+		tsids = append(tsids, TSID{MetricID: uint64(i)})
+	}
+	sort.Slice(tsids, func(i, j int) bool { return tsids[i].Less(&tsids[j]) })
+	rbsExpected := getTestExpectedRawBlocks(rows, tsids, trData)
+
+	strg := newTestStorage()
+	tb, err := openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+
+	// The first partition must be placed at the extra data path, since it has more free space.
+	atomic.StoreUint64(&tb.dataPaths.paths[0].freeSpace, 0)
+	if err := tb.AddRows(rows[:100]); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	// The second partition must be placed at the table path.
+	atomic.StoreUint32(&tb.dataPaths.paths[1].healthy, 0)
+	if err := tb.AddRows(rows[100:]); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	tb.flushPendingRows()
+
+	extraTablePath := extraPath + "/" + path
+	if _, err := os.Readlink(path + "/small/" + ptNames[0]); err != nil {
+		t.Fatalf("partition %q must be symlinked to the extra data path: %s", ptNames[0], err)
+	}
+	if !fs.IsPathExist(extraTablePath + "/big/" + ptNames[0]) {
+		t.Fatalf("partition %q must be located at the extra data path", ptNames[0])
+	}
+	if fs.IsPathExist(extraTablePath + "/small/" + ptNames[1]) {
+		t.Fatalf("partition %q mustn't be located at the extra data path", ptNames[1])
+	}
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+
+	// Snapshots for partitions at the extra data path must be created at the extra data path.
+	const snapshotName = "snapshot"
+	if _, _, err := tb.CreateSnapshot(snapshotName, nil); err != nil {
+		t.Fatalf("cannot create snapshot: %s", err)
+	}
+	if !fs.IsPathExist(extraTablePath + "/small/snapshots/" + snapshotName + "/" + ptNames[0]) {
+		t.Fatalf("snapshot for partition %q must be located at the extra data path", ptNames[0])
+	}
+	if !fs.IsPathExist(path + "/small/snapshots/" + snapshotName + "/" + ptNames[0]) {
+		t.Fatalf("snapshot for partition %q must be available at the table path", ptNames[0])
+	}
+	tb.MustDeleteSnapshot(snapshotName)
+	if fs.IsPathExist(extraTablePath + "/small/snapshots/" + snapshotName) {
+		t.Fatalf("snapshot must be deleted from the extra data path")
+	}
+	tb.MustClose()
+
+	// Partitions at the extra data path must be available after re-opening the table.
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot open table: %s", err)
+	}
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+
+	// Partitions at unavailable extra data path must be skipped, while the remaining partitions must be opened.
+	if err := os.Rename(extraPath, extraPath+".bak"); err != nil {
+		t.Fatalf("cannot rename %q: %s", extraPath, err)
+	}
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot open table with unavailable extra data path: %s", err)
+	}
+	if n := len(tb.ptws); n != 1 {
+		t.Fatalf("unexpected number of opened partitions; got %d; want 1", n)
+	}
+	if err := tb.AddRows(rows[:1]); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	var m TableMetrics
+	tb.UpdateMetrics(&m)
+	if m.UnavailablePartitions != 1 {
+		t.Fatalf("unexpected number of unavailable partitions; got %d; want 1", m.UnavailablePartitions)
+	}
+	if m.UnavailablePartitionRows != 1 {
+		t.Fatalf("unexpected number of rows for unavailable partitions; got %d; want 1", m.UnavailablePartitionRows)
+	}
+	tb.MustClose()
+}
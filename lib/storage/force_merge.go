@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// ForceMergeScope limits the partitions for forced merge started via Storage.StartForceMerge.
+type ForceMergeScope struct {
+	// PartitionPrefix limits forced merge to partitions with names starting with the given prefix.
+	PartitionPrefix string
+
+	// MinPartition and MaxPartition limit forced merge to partitions in the range [MinPartition ... MaxPartition].
+	//
+	// Partition names have YYYY_MM format. Empty value means no limit.
+	MinPartition string
+	MaxPartition string
+}
+
+func (scope *ForceMergeScope) validate() error {
+	for _, name := range []string{scope.MinPartition, scope.MaxPartition} {
+		if name == "" {
+			continue
+		}
+		var tr TimeRange
+		if err := tr.fromPartitionName(name); err != nil {
+			return err
+		}
+	}
+	if scope.MinPartition != "" && scope.MaxPartition != "" && scope.MinPartition > scope.MaxPartition {
+		return fmt.Errorf("min partition %q cannot exceed max partition %q", scope.MinPartition, scope.MaxPartition)
+	}
+	return nil
+}
+
+func (scope *ForceMergeScope) matchPartition(name string) bool {
+	if !strings.HasPrefix(name, scope.PartitionPrefix) {
+		return false
+	}
+	// Partition names in YYYY_MM format are ordered by time when compared as strings.
+	if scope.MinPartition != "" && name < scope.MinPartition {
+		return false
+	}
+	if scope.MaxPartition != "" && name > scope.MaxPartition {
+		return false
+	}
+	return true
+}
+
+// ForceMergeJob is forced merge started via Storage.StartForceMerge.
+type ForceMergeJob struct {
+	// ID is the unique id of the job.
+	ID uint64
+
+	// Scope is the scope for the job.
+	Scope ForceMergeScope
+
+	// StartTime is the job start time.
+	StartTime time.Time
+
+	mu sync.Mutex
+
+	finishTime time.Time
+	err        error
+
+	currentPartition string
+	partitionsTotal  int
+	partitionsMerged int
+
+	partsTotal  uint64
+	bytesTotal  uint64
+	partsMerged uint64
+	bytesMerged uint64
+
+	// currentParts and currentBytes are the number of parts and their size in the current partition at the start of its merge.
+	currentParts uint64
+	currentBytes uint64
+
+	// currentPartsMerged and currentBytesMerged are the number of merged parts and their size in the current partition.
+	currentPartsMerged uint64
+	currentBytesMerged uint64
+}
+
+// ForceMergeJobStatus contains the status of ForceMergeJob.
+type ForceMergeJobStatus struct {
+	ID    uint64
+	Scope ForceMergeScope
+
+	// State can be `running`, `done` or `failed`.
+	State string
+
+	// Error contains the error for the `failed` state.
+	Error string
+
+	StartTime  time.Time
+	FinishTime time.Time
+
+	// CurrentPartition is the name of the partition being merged.
+	CurrentPartition string
+
+	PartitionsTotal  int
+	PartitionsMerged int
+
+	PartsTotal  uint64
+	PartsMerged uint64
+
+	BytesTotal     uint64
+	BytesMerged    uint64
+	BytesRemaining uint64
+
+	// ETA is the estimated duration until the job is finished.
+	//
+	// It is zero if it cannot be estimated yet.
+	ETA time.Duration
+}
+
+// Status returns the current status for fmj.
+func (fmj *ForceMergeJob) Status() *ForceMergeJobStatus {
+	fmj.mu.Lock()
+	defer fmj.mu.Unlock()
+
+	st := &ForceMergeJobStatus{
+		ID:               fmj.ID,
+		Scope:            fmj.Scope,
+		State:            "running",
+		StartTime:        fmj.StartTime,
+		FinishTime:       fmj.finishTime,
+		CurrentPartition: fmj.currentPartition,
+		PartitionsTotal:  fmj.partitionsTotal,
+		PartitionsMerged: fmj.partitionsMerged,
+		PartsTotal:       fmj.partsTotal,
+		PartsMerged:      fmj.partsMerged + fmj.currentPartsMerged,
+		BytesTotal:       fmj.bytesTotal,
+		BytesMerged:      fmj.bytesMerged + fmj.currentBytesMerged,
+	}
+	if !fmj.finishTime.IsZero() {
+		st.State = "done"
+		if fmj.err != nil {
+			st.State = "failed"
+			st.Error = fmj.err.Error()
+		}
+		return st
+	}
+	if st.BytesMerged < st.BytesTotal {
+		st.BytesRemaining = st.BytesTotal - st.BytesMerged
+	}
+	if st.BytesMerged > 0 {
+		elapsed := time.Since(fmj.StartTime)
+		st.ETA = time.Duration(float64(elapsed) * float64(st.BytesRemaining) / float64(st.BytesMerged))
+	}
+	return st
+}
+
+func (fmj *ForceMergeJob) startPartition(pt *partition) {
+	if fmj == nil {
+		return
+	}
+	pws := pt.GetParts(nil, true)
+	parts := uint64(len(pws))
+	bytes := getPartsSize(pws)
+	pt.PutParts(pws)
+
+	fmj.mu.Lock()
+	fmj.currentPartition = pt.name
+	fmj.currentParts = parts
+	fmj.currentBytes = bytes
+	fmj.currentPartsMerged = 0
+	fmj.currentBytesMerged = 0
+	fmj.mu.Unlock()
+}
+
+func (fmj *ForceMergeJob) addMergedParts(parts int, bytes uint64) {
+	if fmj == nil {
+		return
+	}
+	fmj.mu.Lock()
+	// Merged parts may be merged again if the partition contains many parts,
+	// so cap the progress by the size of the partition at the start of its merge.
+	fmj.currentPartsMerged += uint64(parts)
+	if fmj.currentPartsMerged > fmj.currentParts {
+		fmj.currentPartsMerged = fmj.currentParts
+	}
+	fmj.currentBytesMerged += bytes
+	if fmj.currentBytesMerged > fmj.currentBytes {
+		fmj.currentBytesMerged = fmj.currentBytes
+	}
+	fmj.mu.Unlock()
+}
+
+func (fmj *ForceMergeJob) finishPartition() {
+	if fmj == nil {
+		return
+	}
+	fmj.mu.Lock()
+	fmj.partitionsMerged++
+	fmj.partsMerged += fmj.currentParts
+	fmj.bytesMerged += fmj.currentBytes
+	fmj.currentPartition = ""
+	fmj.currentParts = 0
+	fmj.currentBytes = 0
+	fmj.currentPartsMerged = 0
+	fmj.currentBytesMerged = 0
+	fmj.mu.Unlock()
+}
+
+func (fmj *ForceMergeJob) finish(err error) {
+	fmj.mu.Lock()
+	fmj.finishTime = time.Now()
+	fmj.err = err
+	fmj.currentPartition = ""
+	fmj.mu.Unlock()
+}
+
+// maxForceMergeJobs is the maximum number of the most recent forced merge jobs to keep in memory.
+const maxForceMergeJobs = 100
+
+// forceMergeJobs contains recent forced merge jobs.
+type forceMergeJobs struct {
+	mu     sync.Mutex
+	nextID uint64
+	jobs   []*ForceMergeJob
+}
+
+// add adds fmj to fmjs and assigns an unique id to it.
+func (fmjs *forceMergeJobs) add(fmj *ForceMergeJob) {
+	fmjs.mu.Lock()
+	fmjs.nextID++
+	fmj.ID = fmjs.nextID
+	fmjs.jobs = append(fmjs.jobs, fmj)
+	if len(fmjs.jobs) > maxForceMergeJobs {
+		fmjs.jobs = append(fmjs.jobs[:0], fmjs.jobs[len(fmjs.jobs)-maxForceMergeJobs:]...)
+	}
+	fmjs.mu.Unlock()
+}
+
+func (fmjs *forceMergeJobs) get(id uint64) *ForceMergeJob {
+	fmjs.mu.Lock()
+	defer fmjs.mu.Unlock()
+	for _, fmj := range fmjs.jobs {
+		if fmj.ID == id {
+			return fmj
+		}
+	}
+	return nil
+}
+
+func (fmjs *forceMergeJobs) getAll() []*ForceMergeJob {
+	fmjs.mu.Lock()
+	jobs := append([]*ForceMergeJob{}, fmjs.jobs...)
+	fmjs.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// StartForceMerge starts forced merge in background for partitions matching the given scope.
+//
+// The returned job can be used for tracking the progress of the forced merge.
+// The job can be obtained later via GetForceMergeJob.
+func (s *Storage) StartForceMerge(scope *ForceMergeScope) (*ForceMergeJob, error) {
+	if err := scope.validate(); err != nil {
+		return nil, err
+	}
+	ptws := s.tb.getPartitionsForForceMerge(scope)
+	fmj := &ForceMergeJob{
+		Scope:           *scope,
+		StartTime:       time.Now(),
+		partitionsTotal: len(ptws),
+	}
+	for _, ptw := range ptws {
+		pws := ptw.pt.GetParts(nil, true)
+		fmj.partsTotal += uint64(len(pws))
+		fmj.bytesTotal += getPartsSize(pws)
+		ptw.pt.PutParts(pws)
+	}
+	s.forceMergeJobs.add(fmj)
+	go func() {
+		defer s.tb.PutPartitions(ptws)
+		logger.Infof("forced merge job %d for %d partitions has been started", fmj.ID, len(ptws))
+		err := forceMergePartitions(ptws, fmj)
+		fmj.finish(err)
+		if err != nil {
+			logger.Errorf("error in forced merge job %d: %s", fmj.ID, err)
+			return
+		}
+		logger.Infof("forced merge job %d has been finished in %.3f seconds", fmj.ID, time.Since(fmj.StartTime).Seconds())
+	}()
+	return fmj, nil
+}
+
+// GetForceMergeJob returns forced merge job with the given id.
+//
+// nil is returned if there is no such job among the recent jobs.
+func (s *Storage) GetForceMergeJob(id uint64) *ForceMergeJob {
+	return s.forceMergeJobs.get(id)
+}
+
+// GetForceMergeJobs returns recent forced merge jobs ordered by id.
+func (s *Storage) GetForceMergeJobs() []*ForceMergeJob {
+	return s.forceMergeJobs.getAll()
+}
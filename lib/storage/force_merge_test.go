@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestForceMergeScopeMatchPartition(t *testing.T) {
+	f := func(scope *ForceMergeScope, name string, resultExpected bool) {
+		t.Helper()
+		if err := scope.validate(); err != nil {
+			t.Fatalf("unexpected error in validate(): %s", err)
+		}
+		result := scope.matchPartition(name)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for matchPartition(%q); got %v; want %v", name, result, resultExpected)
+		}
+	}
+	f(&ForceMergeScope{}, "2023_05", true)
+	f(&ForceMergeScope{PartitionPrefix: "2023"}, "2023_05", true)
+	f(&ForceMergeScope{PartitionPrefix: "2022"}, "2023_05", false)
+	f(&ForceMergeScope{MinPartition: "2023_05"}, "2023_05", true)
+	f(&ForceMergeScope{MinPartition: "2023_06"}, "2023_05", false)
+	f(&ForceMergeScope{MaxPartition: "2023_05"}, "2023_05", true)
+	f(&ForceMergeScope{MaxPartition: "2023_04"}, "2023_05", false)
+	f(&ForceMergeScope{MinPartition: "2022_11", MaxPartition: "2023_02"}, "2022_12", true)
+	f(&ForceMergeScope{MinPartition: "2022_11", MaxPartition: "2023_02"}, "2023_03", false)
+	f(&ForceMergeScope{PartitionPrefix: "2023", MinPartition: "2022_11", MaxPartition: "2023_02"}, "2022_12", false)
+}
+
+func TestForceMergeScopeValidateFailure(t *testing.T) {
+	f := func(scope *ForceMergeScope) {
+		t.Helper()
+		if err := scope.validate(); err == nil {
+			t.Fatalf("expecting non-nil error for scope %+v", scope)
+		}
+	}
+	f(&ForceMergeScope{MinPartition: "foo"})
+	f(&ForceMergeScope{MaxPartition: "2023-05"})
+	f(&ForceMergeScope{MinPartition: "2023_06", MaxPartition: "2023_05"})
+}
+
+func TestStorageStartForceMerge(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	path := "TestStorageStartForceMerge"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	const rowsPerAdd = 1e3
+	const addsCount = 10
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 3*msecsPerMonth
+	for i := 0; i < addsCount; i++ {
+		mrs := testGenerateMetricRows(rng, rowsPerAdd, minTimestamp, maxTimestamp)
+		if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+			t.Fatalf("unexpected error when adding mrs: %s", err)
+		}
+	}
+	s.DebugFlush()
+
+	if _, err := s.StartForceMerge(&ForceMergeScope{MinPartition: "foobar"}); err == nil {
+		t.Fatalf("expecting non-nil error for invalid scope")
+	}
+
+	// Force merge only the most recent partition.
+	lastPartition := timestampToPartitionName(maxTimestamp)
+	fmj, err := s.StartForceMerge(&ForceMergeScope{MinPartition: lastPartition})
+	if err != nil {
+		t.Fatalf("cannot start forced merge: %s", err)
+	}
+	if fmj.ID != 1 {
+		t.Fatalf("unexpected job id; got %d; want 1", fmj.ID)
+	}
+	deadline := time.Now().Add(time.Minute)
+	for fmj.Status().State == "running" {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout when waiting for forced merge job to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	st := fmj.Status()
+	if st.State != "done" {
+		t.Fatalf("unexpected job state; got %q; want %q; error: %s", st.State, "done", st.Error)
+	}
+	if st.PartitionsTotal != 1 || st.PartitionsMerged != 1 {
+		t.Fatalf("unexpected number of partitions; got total=%d, merged=%d; want 1", st.PartitionsTotal, st.PartitionsMerged)
+	}
+	if st.PartsMerged != st.PartsTotal || st.BytesMerged != st.BytesTotal {
+		t.Fatalf("unexpected progress for finished job; parts %d of %d; bytes %d of %d", st.PartsMerged, st.PartsTotal, st.BytesMerged, st.BytesTotal)
+	}
+	if st.BytesRemaining != 0 {
+		t.Fatalf("unexpected bytes remaining for finished job; got %d; want 0", st.BytesRemaining)
+	}
+	if s.GetForceMergeJob(fmj.ID) != fmj {
+		t.Fatalf("cannot find forced merge job by id %d", fmj.ID)
+	}
+	if jobs := s.GetForceMergeJobs(); len(jobs) != 1 {
+		t.Fatalf("unexpected number of jobs; got %d; want 1", len(jobs))
+	}
+
+	// Verify that only the most recent partition has been merged into a single part.
+	ptws := s.tb.GetPartitions(nil)
+	for _, ptw := range ptws {
+		if ptw.pt.name != lastPartition {
+			continue
+		}
+		pws := ptw.pt.GetParts(nil, true)
+		numParts := len(pws)
+		ptw.pt.PutParts(pws)
+		if numParts != 1 {
+			t.Fatalf("unexpected number of parts for partition %q after forced merge; got %d; want 1", ptw.pt.name, numParts)
+		}
+	}
+	s.tb.PutPartitions(ptws)
+
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
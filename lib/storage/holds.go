@@ -0,0 +1,556 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// Hold protects series matching Filters from deletion by retention and by DeleteSeries.
+//
+// Samples for the matching series starting from TimeRange.MinTimestamp aren't deleted
+// by retention until the hold is released.
+type Hold struct {
+	// ID is a unique identifier of the hold. It is generated by Storage.AddHold.
+	ID string
+
+	// Filters contains series filters for the hold. Series matching any of the filters are held.
+	Filters [][]TagFilter
+
+	// TimeRange is the time range for held samples.
+	TimeRange TimeRange
+
+	// Reason is the reason for the hold such as a case number.
+	Reason string
+
+	// Author is the name of the hold author.
+	Author string
+
+	// CreatedAt is unix timestamp in seconds when the hold was created.
+	CreatedAt int64
+}
+
+// FiltersString returns string representation of h.Filters.
+func (h *Hold) FiltersString() []string {
+	a := make([]string, len(h.Filters))
+	for i, tfs := range h.Filters {
+		a[i] = holdFiltersToString(tfs)
+	}
+	return a
+}
+
+func holdFiltersToString(tfs []TagFilter) string {
+	a := make([]string, len(tfs))
+	for i, tf := range tfs {
+		key := string(tf.Key)
+		if key == "" {
+			key = "__name__"
+		}
+		a[i] = fmt.Sprintf("%s%s%q", key, tf.getOp(), tf.Value)
+	}
+	return "{" + strings.Join(a, ",") + "}"
+}
+
+// HoldAuditRecord is an audit record for actions with holds.
+type HoldAuditRecord struct {
+	// Timestamp is unix timestamp in seconds for the action.
+	Timestamp int64 `json:"timestamp"`
+
+	// Action is the action name - `create`, `release` or `delete_blocked`.
+	Action string `json:"action"`
+
+	// HoldID is the hold identifier. It is empty for `delete_blocked` action.
+	HoldID string `json:"hold_id,omitempty"`
+
+	Author string `json:"author,omitempty"`
+	Reason string `json:"reason,omitempty"`
+
+	// Filters contains series filters for the hold or for the blocked delete request.
+	Filters []string `json:"filters,omitempty"`
+
+	// Start and End contain the hold time range in milliseconds.
+	Start int64 `json:"start,omitempty"`
+	End   int64 `json:"end,omitempty"`
+
+	// Series is the number of series, which weren't deleted because of holds.
+	Series int `json:"series,omitempty"`
+}
+
+// holdsUpdateInterval is the interval for updating the set of held series.
+//
+// New series matching the existing holds may appear in the database, so the set must be updated periodically.
+var holdsUpdateInterval = time.Minute
+
+type holds struct {
+	path string
+
+	// mu serializes changes to holds.
+	mu sync.Mutex
+
+	// hs contains []*Hold with active holds.
+	hs atomic.Value
+
+	// deadlines contains map[uint64]int64 with the minimum timestamp for samples,
+	// which mustn't be deleted by retention, per each held metricID.
+	deadlines atomic.Value
+
+	// deadlinesUpdateLock serializes deadlines updates, so the last update reflects the current holds.
+	deadlinesUpdateLock sync.Mutex
+
+	// auditLock serializes writes to the audit log.
+	auditLock sync.Mutex
+
+	deletesBlocked uint64
+}
+
+type holdTagFilterJSON struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	IsNegative bool   `json:"is_negative,omitempty"`
+	IsRegexp   bool   `json:"is_regexp,omitempty"`
+}
+
+type holdJSON struct {
+	ID        string                `json:"id"`
+	Filters   [][]holdTagFilterJSON `json:"filters"`
+	Start     int64                 `json:"start"`
+	End       int64                 `json:"end"`
+	Reason    string                `json:"reason"`
+	Author    string                `json:"author,omitempty"`
+	CreatedAt int64                 `json:"created_at"`
+}
+
+func mustOpenHolds(path string) *holds {
+	if err := fs.MkdirAllIfNotExist(path); err != nil {
+		logger.Panicf("FATAL: cannot create directory for holds: %s", err)
+	}
+	h := &holds{
+		path: path,
+	}
+	hs, err := h.load()
+	if err != nil {
+		logger.Panicf("FATAL: cannot load holds: %s", err)
+	}
+	h.hs.Store(hs)
+	h.deadlines.Store(map[uint64]int64{})
+	if len(hs) > 0 {
+		logger.Infof("loaded %d active holds from %q", len(hs), h.holdsFilePath())
+	}
+	return h
+}
+
+func (h *holds) holdsFilePath() string {
+	return h.path + "/holds.json"
+}
+
+func (h *holds) auditLogPath() string {
+	return h.path + "/audit.log"
+}
+
+func (h *holds) load() ([]*Hold, error) {
+	path := h.holdsFilePath()
+	if !fs.IsPathExist(path) {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var hjs []holdJSON
+	if err := json.Unmarshal(data, &hjs); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", path, err)
+	}
+	hs := make([]*Hold, len(hjs))
+	for i, hj := range hjs {
+		hold := &Hold{
+			ID: hj.ID,
+			TimeRange: TimeRange{
+				MinTimestamp: hj.Start,
+				MaxTimestamp: hj.End,
+			},
+			Reason:    hj.Reason,
+			Author:    hj.Author,
+			CreatedAt: hj.CreatedAt,
+		}
+		for _, tfsj := range hj.Filters {
+			tfs := make([]TagFilter, len(tfsj))
+			for j, tfj := range tfsj {
+				tfs[j] = TagFilter{
+					Key:        []byte(tfj.Key),
+					Value:      []byte(tfj.Value),
+					IsNegative: tfj.IsNegative,
+					IsRegexp:   tfj.IsRegexp,
+				}
+			}
+			hold.Filters = append(hold.Filters, tfs)
+		}
+		hs[i] = hold
+	}
+	return hs, nil
+}
+
+func (h *holds) mustSave(hs []*Hold) {
+	hjs := make([]holdJSON, len(hs))
+	for i, hold := range hs {
+		hj := holdJSON{
+			ID:        hold.ID,
+			Start:     hold.TimeRange.MinTimestamp,
+			End:       hold.TimeRange.MaxTimestamp,
+			Reason:    hold.Reason,
+			Author:    hold.Author,
+			CreatedAt: hold.CreatedAt,
+		}
+		for _, tfs := range hold.Filters {
+			tfsj := make([]holdTagFilterJSON, len(tfs))
+			for j, tf := range tfs {
+				tfsj[j] = holdTagFilterJSON{
+					Key:        string(tf.Key),
+					Value:      string(tf.Value),
+					IsNegative: tf.IsNegative,
+					IsRegexp:   tf.IsRegexp,
+				}
+			}
+			hj.Filters = append(hj.Filters, tfsj)
+		}
+		hjs[i] = hj
+	}
+	data, err := json.MarshalIndent(hjs, "", "  ")
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal holds: %s", err)
+	}
+	if err := fs.WriteFileAtomically(h.holdsFilePath(), data, true); err != nil {
+		logger.Panicf("FATAL: cannot save holds: %s", err)
+	}
+}
+
+func (h *holds) getHolds() []*Hold {
+	return h.hs.Load().([]*Hold)
+}
+
+func (h *holds) getDeadlines() map[uint64]int64 {
+	return h.deadlines.Load().(map[uint64]int64)
+}
+
+// mustWriteAudit appends ar to the audit log.
+func (h *holds) mustWriteAudit(ar *HoldAuditRecord) {
+	ar.Timestamp = int64(fasttime.UnixTimestamp())
+	data, err := json.Marshal(ar)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal audit record: %s", err)
+	}
+	data = append(data, '\n')
+
+	h.auditLock.Lock()
+	defer h.auditLock.Unlock()
+	path := h.auditLogPath()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Panicf("FATAL: cannot open audit log: %s", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		logger.Panicf("FATAL: cannot write audit record to %q: %s", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		logger.Panicf("FATAL: cannot sync %q: %s", path, err)
+	}
+	if err := f.Close(); err != nil {
+		logger.Panicf("FATAL: cannot close %q: %s", path, err)
+	}
+	logger.Infof("hold audit: %s", data[:len(data)-1])
+}
+
+func (h *holds) readAudit() ([]HoldAuditRecord, error) {
+	h.auditLock.Lock()
+	defer h.auditLock.Unlock()
+
+	path := h.auditLogPath()
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	var ars []HoldAuditRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 16*1024*1024)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var ar HoldAuditRecord
+		if err := json.Unmarshal(line, &ar); err != nil {
+			return nil, fmt.Errorf("cannot parse audit record %q at %q: %w", line, path, err)
+		}
+		ars = append(ars, ar)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", path, err)
+	}
+	return ars, nil
+}
+
+func (s *Storage) startHoldsWatcher() {
+	s.holdsWatcherWG.Add(1)
+	go func() {
+		s.holdsWatcher()
+		s.holdsWatcherWG.Done()
+	}()
+}
+
+func (s *Storage) holdsWatcher() {
+	ticker := time.NewTicker(holdsUpdateInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.updateHeldMetricIDs()
+	}
+}
+
+// updateHeldMetricIDs updates the set of metricIDs for series matching the active holds.
+func (s *Storage) updateHeldMetricIDs() {
+	if s.holds == nil {
+		return
+	}
+	s.holds.deadlinesUpdateLock.Lock()
+	defer s.holds.deadlinesUpdateLock.Unlock()
+
+	hs := s.holds.getHolds()
+	deadlines := make(map[uint64]int64)
+	for _, hold := range hs {
+		tfss, err := hold.getTagFilterss()
+		if err != nil {
+			logger.Panicf("BUG: cannot create tag filters for hold %q: %s", hold.ID, err)
+		}
+		metricIDs, err := s.idb().searchMetricIDs(nil, tfss, hold.TimeRange, 2e9, noDeadline)
+		if err != nil {
+			// Preserve the previous set of held metricIDs, since it is better to hold more data than to lose it.
+			logger.Errorf("cannot search for series matching hold %q: %s; preserving the previously held series", hold.ID, err)
+			return
+		}
+		for _, metricID := range metricIDs {
+			if d, ok := deadlines[metricID]; !ok || hold.TimeRange.MinTimestamp < d {
+				deadlines[metricID] = hold.TimeRange.MinTimestamp
+			}
+		}
+	}
+	s.holds.deadlines.Store(deadlines)
+}
+
+func (hold *Hold) getTagFilterss() ([]*TagFilters, error) {
+	tfss := make([]*TagFilters, 0, len(hold.Filters))
+	for _, tagFilters := range hold.Filters {
+		tfs := NewTagFilters()
+		for i := range tagFilters {
+			tf := &tagFilters[i]
+			if err := tfs.Add(tf.Key, tf.Value, tf.IsNegative, tf.IsRegexp); err != nil {
+				return nil, fmt.Errorf("cannot parse tag filter %s: %w", tf, err)
+			}
+		}
+		tfss = append(tfss, tfs)
+	}
+	return tfss, nil
+}
+
+// AddHold adds the given hold to s.
+//
+// Series matching hold.Filters aren't deleted by DeleteSeries,
+// while their samples starting from hold.TimeRange.MinTimestamp aren't deleted by retention until the hold is released via ReleaseHold.
+//
+// hold.ID and hold.CreatedAt are set by AddHold.
+func (s *Storage) AddHold(hold *Hold) error {
+	if len(hold.Filters) == 0 {
+		return fmt.Errorf("missing series filters for the hold")
+	}
+	if hold.TimeRange.MinTimestamp > hold.TimeRange.MaxTimestamp {
+		return fmt.Errorf("the hold start cannot exceed the hold end; got start=%d, end=%d", hold.TimeRange.MinTimestamp, hold.TimeRange.MaxTimestamp)
+	}
+	if hold.Reason == "" {
+		return fmt.Errorf("missing reason for the hold")
+	}
+	if _, err := hold.getTagFilterss(); err != nil {
+		return err
+	}
+
+	h := s.holds
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs := h.getHolds()
+	hold.CreatedAt = int64(fasttime.UnixTimestamp())
+	hold.ID = fmt.Sprintf("%016X", uint64(time.Now().UnixNano()))
+	for _, x := range hs {
+		if x.ID == hold.ID {
+			return fmt.Errorf("hold with id %q already exists; try again", hold.ID)
+		}
+	}
+
+	// Calculate held series before storing the hold, so they aren't deleted by concurrently running merges.
+	hsNew := append(append([]*Hold{}, hs...), hold)
+	h.hs.Store(hsNew)
+	s.updateHeldMetricIDs()
+	h.mustSave(hsNew)
+	h.mustWriteAudit(&HoldAuditRecord{
+		Action:  "create",
+		HoldID:  hold.ID,
+		Author:  hold.Author,
+		Reason:  hold.Reason,
+		Filters: hold.FiltersString(),
+		Start:   hold.TimeRange.MinTimestamp,
+		End:     hold.TimeRange.MaxTimestamp,
+	})
+	return nil
+}
+
+// ReleaseHold releases the hold with the given id.
+//
+// The data, which was held only by the released hold, is deleted by retention eventually.
+func (s *Storage) ReleaseHold(id, author, reason string) error {
+	if reason == "" {
+		return fmt.Errorf("missing reason for releasing the hold")
+	}
+
+	h := s.holds
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hs := h.getHolds()
+	var hold *Hold
+	hsNew := make([]*Hold, 0, len(hs))
+	for _, x := range hs {
+		if x.ID == id {
+			hold = x
+			continue
+		}
+		hsNew = append(hsNew, x)
+	}
+	if hold == nil {
+		return fmt.Errorf("cannot find hold with id %q", id)
+	}
+	h.mustSave(hsNew)
+	h.hs.Store(hsNew)
+	s.updateHeldMetricIDs()
+	h.mustWriteAudit(&HoldAuditRecord{
+		Action:  "release",
+		HoldID:  hold.ID,
+		Author:  author,
+		Reason:  reason,
+		Filters: hold.FiltersString(),
+		Start:   hold.TimeRange.MinTimestamp,
+		End:     hold.TimeRange.MaxTimestamp,
+	})
+	return nil
+}
+
+// ListHolds returns active holds.
+func (s *Storage) ListHolds() []Hold {
+	hs := s.holds.getHolds()
+	result := make([]Hold, len(hs))
+	for i, hold := range hs {
+		result[i] = *hold
+	}
+	return result
+}
+
+// GetHoldsAuditLog returns audit records for actions with holds.
+func (s *Storage) GetHoldsAuditLog() ([]HoldAuditRecord, error) {
+	return s.holds.readAudit()
+}
+
+// hasHolds returns true if s contains active holds.
+func (s *Storage) hasHolds() bool {
+	return s.holds != nil && len(s.holds.getHolds()) > 0
+}
+
+// getHoldDeadlines returns the minimum timestamps for samples, which mustn't be deleted by retention, per each held metricID.
+func (s *Storage) getHoldDeadlines() map[uint64]int64 {
+	if s == nil || s.holds == nil {
+		return nil
+	}
+	return s.holds.getDeadlines()
+}
+
+// isHeldTimeRange returns true if tr intersects with time range of any active hold.
+//
+// Data on such time range mustn't be dropped at once by retention.
+func (s *Storage) isHeldTimeRange(tr TimeRange) bool {
+	if s == nil || s.holds == nil {
+		return false
+	}
+	for _, hold := range s.holds.getHolds() {
+		if tr.MinTimestamp <= hold.TimeRange.MaxTimestamp && tr.MaxTimestamp >= hold.TimeRange.MinTimestamp {
+			return true
+		}
+	}
+	return false
+}
+
+// removeHeldMetricIDs removes held metricIDs from metricIDs.
+func (s *Storage) removeHeldMetricIDs(metricIDs []uint64) []uint64 {
+	deadlines := s.getHoldDeadlines()
+	if len(deadlines) == 0 {
+		return metricIDs
+	}
+	dst := metricIDs[:0]
+	for _, metricID := range metricIDs {
+		if _, ok := deadlines[metricID]; !ok {
+			dst = append(dst, metricID)
+		}
+	}
+	return dst
+}
+
+// registerBlockedDeletes writes an audit record if tfss match held series.
+func (s *Storage) registerBlockedDeletes(qt *querytracer.Tracer, tfss []*TagFilters) error {
+	deadlines := s.getHoldDeadlines()
+	if len(deadlines) == 0 {
+		return nil
+	}
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: (1 << 63) - 1,
+	}
+	metricIDs, err := s.idb().searchMetricIDs(qt, tfss, tr, 2e9, noDeadline)
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, metricID := range metricIDs {
+		if _, ok := deadlines[metricID]; ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	atomic.AddUint64(&s.holds.deletesBlocked, uint64(n))
+	filters := make([]string, len(tfss))
+	for i, tfs := range tfss {
+		filters[i] = tfs.String()
+	}
+	s.holds.mustWriteAudit(&HoldAuditRecord{
+		Action:  "delete_blocked",
+		Filters: filters,
+		Series:  n,
+	})
+	qt.Printf("skip deleting %d series, since they are held", n)
+	return nil
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestStorageHolds(t *testing.T) {
+	path := "TestStorageHolds"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove %q: %s", path, err)
+		}
+	}()
+
+	const metricsCount = 10
+	var mrs []MetricRow
+	for i := 0; i < metricsCount; i++ {
+		var mn MetricName
+		mn.MetricGroup = []byte("metric")
+		mn.AddTag("job", fmt.Sprintf("job_%d", i))
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     1e10 + int64(i)*1000,
+			Value:         float64(i),
+		})
+	}
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("cannot add rows: %s", err)
+	}
+	s.DebugFlush()
+
+	newTagFilters := func(job string) *TagFilters {
+		tfs := NewTagFilters()
+		if err := tfs.Add(nil, []byte("metric"), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		if err := tfs.Add([]byte("job"), []byte(job), false, true); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		return tfs
+	}
+
+	// Hold series with job_1 and job_2
+	hold := &Hold{
+		Filters: [][]TagFilter{{
+			{Key: nil, Value: []byte("metric")},
+			{Key: []byte("job"), Value: []byte("job_(1|2)"), IsRegexp: true},
+		}},
+		TimeRange: TimeRange{
+			MinTimestamp: 1e9,
+			MaxTimestamp: 2e10,
+		},
+		Reason: "case 123",
+		Author: "legal",
+	}
+	if err := s.AddHold(hold); err != nil {
+		t.Fatalf("cannot add hold: %s", err)
+	}
+	if hold.ID == "" {
+		t.Fatalf("missing hold id")
+	}
+	if err := s.AddHold(&Hold{Filters: hold.Filters, TimeRange: hold.TimeRange}); err == nil {
+		t.Fatalf("expecting non-nil error for hold without reason")
+	}
+	if n := len(s.getHoldDeadlines()); n != 2 {
+		t.Fatalf("unexpected number of held series; got %d; want 2", n)
+	}
+	for _, d := range s.getHoldDeadlines() {
+		if d != hold.TimeRange.MinTimestamp {
+			t.Fatalf("unexpected deadline for held series; got %d; want %d", d, hold.TimeRange.MinTimestamp)
+		}
+	}
+	if !s.isHeldTimeRange(TimeRange{MinTimestamp: 0, MaxTimestamp: 1e9}) {
+		t.Fatalf("the time range must be held")
+	}
+	if s.isHeldTimeRange(TimeRange{MinTimestamp: 3e10, MaxTimestamp: 4e10}) {
+		t.Fatalf("the time range mustn't be held")
+	}
+
+	// Held series mustn't be deleted
+	n, err := s.DeleteSeries(nil, []*TagFilters{newTagFilters("job_[0-2]")})
+	if err != nil {
+		t.Fatalf("cannot delete series: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected number of deleted series; got %d; want 1", n)
+	}
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.ActiveHolds != 1 || m.HeldSeries != 2 || m.DeletesBlockedByHolds != 2 {
+		t.Fatalf("unexpected metrics; ActiveHolds=%d, HeldSeries=%d, DeletesBlockedByHolds=%d", m.ActiveHolds, m.HeldSeries, m.DeletesBlockedByHolds)
+	}
+	s.MustClose()
+
+	// Holds must be preserved after the restart
+	s, err = OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	hs := s.ListHolds()
+	if len(hs) != 1 {
+		t.Fatalf("unexpected number of holds; got %d; want 1", len(hs))
+	}
+	if hs[0].ID != hold.ID || hs[0].Reason != hold.Reason || hs[0].Author != hold.Author || hs[0].TimeRange != hold.TimeRange {
+		t.Fatalf("unexpected hold after restart;\ngot\n%+v\nwant\n%+v", hs[0], *hold)
+	}
+	if got, want := fmt.Sprintf("%s", hs[0].FiltersString()), `[{__name__="metric",job=~"job_(1|2)"}]`; got != want {
+		t.Fatalf("unexpected hold filters; got %s; want %s", got, want)
+	}
+	if n := len(s.getHoldDeadlines()); n != 2 {
+		t.Fatalf("unexpected number of held series after restart; got %d; want 2", n)
+	}
+
+	// Released series can be deleted
+	if err := s.ReleaseHold(hold.ID, "legal", ""); err == nil {
+		t.Fatalf("expecting non-nil error when releasing the hold without reason")
+	}
+	if err := s.ReleaseHold("foobar", "legal", "case closed"); err == nil {
+		t.Fatalf("expecting non-nil error when releasing unknown hold")
+	}
+	if err := s.ReleaseHold(hold.ID, "legal", "case closed"); err != nil {
+		t.Fatalf("cannot release hold: %s", err)
+	}
+	if n := len(s.ListHolds()); n != 0 {
+		t.Fatalf("unexpected number of holds after release; got %d; want 0", n)
+	}
+	n, err = s.DeleteSeries(nil, []*TagFilters{newTagFilters("job_[0-2]")})
+	if err != nil {
+		t.Fatalf("cannot delete series: %s", err)
+	}
+	if n != 2 {
+		t.Fatalf("unexpected number of deleted series after releasing the hold; got %d; want 2", n)
+	}
+
+	// Verify audit log
+	ars, err := s.GetHoldsAuditLog()
+	if err != nil {
+		t.Fatalf("cannot read audit log: %s", err)
+	}
+	var actions []string
+	for _, ar := range ars {
+		actions = append(actions, ar.Action)
+	}
+	if got, want := fmt.Sprintf("%s", actions), "[create delete_blocked release]"; got != want {
+		t.Fatalf("unexpected audit actions; got %s; want %s", got, want)
+	}
+	if ars[2].HoldID != hold.ID || ars[2].Reason != "case closed" || ars[2].Author != "legal" {
+		t.Fatalf("unexpected audit record for release: %+v", ars[2])
+	}
+	if ars[1].Series != 2 {
+		t.Fatalf("unexpected number of blocked series in the audit record; got %d; want 2", ars[1].Series)
+	}
+	s.MustClose()
+}
+
+func TestBlockStreamMergerGetRetentionDeadline(t *testing.T) {
+	var bsm blockStreamMerger
+	bsm.Init(nil, 1000, map[uint64]int64{
+		1: 500,
+		2: 2000,
+	})
+	f := func(metricID uint64, deadlineExpected int64) {
+		t.Helper()
+		var bh blockHeader
+		bh.TSID.MetricID = metricID
+		if d := bsm.getRetentionDeadline(&bh); d != deadlineExpected {
+			t.Fatalf("unexpected retention deadline for metricID=%d; got %d; want %d", metricID, d, deadlineExpected)
+		}
+	}
+	f(0, 1000)
+	f(1, 500)
+	// The hold cannot decrease the retention.
+	f(2, 1000)
+}
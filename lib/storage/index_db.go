@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"reflect"
 	"sort"
@@ -50,6 +51,9 @@ const (
 
 	// Prefix for (Date,Tag)->MetricID entries.
 	nsPrefixDateTagToMetricIDs = 6
+
+	// Prefix for ShortLabelValue->LabelValue entries.
+	nsPrefixLabelValueDict = 7
 )
 
 // indexDB represents an index db.
@@ -85,6 +89,10 @@ type indexDB struct {
 
 	mustDrop uint64
 
+	// hasLabelValueDict is set to 1 if the label value dictionary contains entries.
+	// It is used for skipping the resolution of label values, which just look like short values.
+	hasLabelValueDict uint32
+
 	// generation identifies the index generation ID
 	// and is used for syncing items from different indexDBs
 	generation uint64
@@ -143,7 +151,7 @@ func openIndexDB(path string, s *Storage, rotationTimestamp uint64, isReadOnly *
 		return nil, fmt.Errorf("failed to parse indexdb path %q: %w", path, err)
 	}
 
-	tb, err := mergeset.OpenTable(path, invalidateTagFiltersCache, mergeTagToMetricIDsRows, isReadOnly)
+	tb, err := mergeset.OpenTable(path, invalidateTagFiltersCache, mergeTagToMetricIDsRows, getTagToMetricIDsBloomKey, isReadOnly)
 	if err != nil {
 		return nil, fmt.Errorf("cannot open indexDB %q: %w", path, err)
 	}
@@ -163,6 +171,7 @@ func openIndexDB(path string, s *Storage, rotationTimestamp uint64, isReadOnly *
 		s:                          s,
 		loopsPerDateTagFilterCache: workingsetcache.New(mem / 128),
 	}
+	db.initHasLabelValueDict()
 	return db, nil
 }
 
@@ -200,6 +209,8 @@ type IndexDBMetrics struct {
 	CompositeFilterSuccessConversions uint64
 	CompositeFilterMissingConversions uint64
 
+	CompositeLabelsFilterConversions uint64
+
 	mergeset.TableMetrics
 }
 
@@ -238,6 +249,7 @@ func (db *indexDB) UpdateMetrics(m *IndexDBMetrics) {
 	m.MinTimestampForCompositeIndex = uint64(db.s.minTimestampForCompositeIndex)
 	m.CompositeFilterSuccessConversions = atomic.LoadUint64(&compositeFilterSuccessConversions)
 	m.CompositeFilterMissingConversions = atomic.LoadUint64(&compositeFilterMissingConversions)
+	m.CompositeLabelsFilterConversions = atomic.LoadUint64(&compositeLabelsFilterConversions)
 
 	db.tb.UpdateMetrics(&m.TableMetrics)
 	db.doExtDB(func(extDB *indexDB) {
@@ -510,6 +522,9 @@ type indexSearch struct {
 	// hack in GetOrCreateTSIDByName. See the comment there.
 	tsidByNameMisses int
 	tsidByNameSkips  int
+
+	// shortMetricNameBuf is used for holding metricName with shortened label values in GetOrCreateTSIDByName.
+	shortMetricNameBuf []byte
 }
 
 // GetOrCreateTSIDByName fills the dst with TSID for the given metricName.
@@ -517,11 +532,20 @@ type indexSearch struct {
 // It also registers the metricName in global and per-day indexes
 // for the given date if the metricName->TSID entry is missing in the index.
 func (is *indexSearch) GetOrCreateTSIDByName(dst *TSID, metricName, metricNameRaw []byte, date uint64) error {
+	// Replace too long label values with short values if -maxIndexedLabelValueLen is set.
+	// See SetMaxIndexedLabelValueLen for details.
+	var err error
+	is.shortMetricNameBuf, metricName, err = is.shortenMetricName(is.shortMetricNameBuf[:0], metricName)
+	if err != nil {
+		userReadableMetricName := getUserReadableMetricName(metricNameRaw)
+		return fmt.Errorf("cannot shorten label values for %s: %w", userReadableMetricName, err)
+	}
+
 	// A hack: skip searching for the TSID after many serial misses.
 	// This should improve insertion performance for big batches
 	// of new time series.
 	if is.tsidByNameMisses < 100 {
-		err := is.getTSIDByMetricName(dst, metricName)
+		err = is.getTSIDByMetricName(dst, metricName)
 		if err == nil {
 			// Fast path - the TSID for the given metricName has been found in the index.
 			is.tsidByNameMisses = 0
@@ -661,6 +685,9 @@ func generateTSID(dst *TSID, mn *MetricName) {
 }
 
 func (is *indexSearch) createGlobalIndexes(tsid *TSID, mn *MetricName) {
+	// mn may contain full label values if it is obtained from metricNameRaw.
+	is.shortenLabelValues(mn)
+
 	// The order of index items is important.
 	// It guarantees index consistency.
 
@@ -863,9 +890,9 @@ func (is *indexSearch) searchLabelNamesWithFiltersOnDate(qt *querytracer.Tracer,
 			// The last char in kb.B must be tagSeparatorChar.
 			// Just increment it in order to jump to the next tag key.
 			kb.B = is.marshalCommonPrefixForDate(kb.B[:0], date)
-			if len(labelName) > 0 && labelName[0] == compositeTagKeyPrefix {
+			if len(labelName) > 0 && (labelName[0] == compositeTagKeyPrefix || labelName[0] == compositeLabelsKeyPrefix) {
 				// skip composite tag entries
-				kb.B = append(kb.B, compositeTagKeyPrefix)
+				kb.B = append(kb.B, labelName[0])
 			} else {
 				kb.B = marshalTagValue(kb.B, labelName)
 			}
@@ -1377,6 +1404,13 @@ func (is *indexSearch) getTSDBStatus(qt *querytracer.Tracer, tfss []*TagFilters,
 		qt.Printf("no matching series for filter=%s", tfss)
 		return &TSDBStatus{}, nil
 	}
+	return is.getTSDBStatusForMetricIDs(filter, date, focusLabel, topN)
+}
+
+// getTSDBStatusForMetricIDs returns topN entries for tsdb status for the given filter, date and focusLabel.
+//
+// Stats are collected for all the series if filter is nil.
+func (is *indexSearch) getTSDBStatusForMetricIDs(filter *uint64set.Set, date uint64, focusLabel string, topN int) (*TSDBStatus, error) {
 	ts := &is.ts
 	kb := &is.kb
 	mp := &is.mp
@@ -1424,8 +1458,8 @@ func (is *indexSearch) getTSDBStatus(qt *querytracer.Tracer, tfss []*TagFilters,
 		if isArtificialTagKey(labelName) {
 			// Skip artificially created tag keys.
 			kb.B = append(kb.B[:0], prefix...)
-			if len(labelName) > 0 && labelName[0] == compositeTagKeyPrefix {
-				kb.B = append(kb.B, compositeTagKeyPrefix)
+			if len(labelName) > 0 && (labelName[0] == compositeTagKeyPrefix || labelName[0] == compositeLabelsKeyPrefix) {
+				kb.B = append(kb.B, labelName[0])
 			} else {
 				kb.B = marshalTagValue(kb.B, labelName)
 			}
@@ -1663,6 +1697,7 @@ func (db *indexDB) DeleteTSIDs(qt *querytracer.Tracer, tfss []*TagFilters) (int,
 	if err != nil {
 		return 0, err
 	}
+	metricIDs = db.s.removeHeldMetricIDs(metricIDs)
 	db.deleteMetricIDs(metricIDs)
 
 	// Delete TSIDs in the extDB.
@@ -1753,6 +1788,7 @@ func (db *indexDB) searchMetricIDs(qt *querytracer.Tracer, tfss []*TagFilters, t
 	if len(tfss) == 0 {
 		return nil, nil
 	}
+	tfss = addCompositeLabelsFilters(tfss, tr, db.s.compositeLabelsIndexes)
 	if tr.MinTimestamp >= db.s.minTimestampForCompositeIndex {
 		tfss = convertToCompositeTagFilterss(tfss)
 	}
@@ -2004,7 +2040,102 @@ func (is *indexSearch) searchMetricName(dst []byte, metricID uint64) ([]byte, er
 		return dst, fmt.Errorf("error when searching metricName by metricID; searchPrefix %q: %w", kb.B, err)
 	}
 	v := ts.Item[len(kb.B):]
+	dstLen := len(dst)
 	dst = append(dst, v...)
+	return is.db.resolveMetricName(dst, dstLen)
+}
+
+// metricIDName contains metricID and the corresponding marshaled metric name.
+type metricIDName struct {
+	metricID   uint64
+	metricName string
+}
+
+// searchMetricNamesAfterMetricID appends up to limit metricIDName entries with metricID bigger than afterMetricID to dst
+// in the order of metricIDs and returns the result.
+//
+// Deleted metricIDs and metric names not matching tfss are skipped. All the metric names are matched if tfss is empty.
+func (is *indexSearch) searchMetricNamesAfterMetricID(dst []metricIDName, tfss []*TagFilters, afterMetricID uint64, limit int) ([]metricIDName, error) {
+	if afterMetricID == math.MaxUint64 {
+		return dst, nil
+	}
+	tfsList := make([][]*tagFilter, 0, len(tfss))
+	for _, tfs := range tfss {
+		a := make([]*tagFilter, 0, len(tfs.tfs))
+		for i := range tfs.tfs {
+			a = append(a, &tfs.tfs[i])
+		}
+		tfsList = append(tfsList, a)
+	}
+	dmis := is.db.s.getDeletedMetricIDs()
+	mn := GetMetricName()
+	defer PutMetricName(mn)
+	var kb bytesutil.ByteBuffer
+	var metricName []byte
+	ts := &is.ts
+	prefix := marshalCommonPrefix(nil, nsPrefixMetricIDToMetricName)
+	kb.B = append(kb.B[:0], prefix...)
+	kb.B = encoding.MarshalUint64(kb.B, afterMetricID+1)
+	ts.Seek(kb.B)
+	dstLen := len(dst)
+	loopsPaceLimiter := 0
+	for len(dst)-dstLen < limit && ts.NextItem() {
+		if loopsPaceLimiter&paceLimiterSlowIterationsMask == 0 {
+			if err := checkSearchDeadlineAndPace(is.deadline); err != nil {
+				return dst, err
+			}
+		}
+		loopsPaceLimiter++
+		item := ts.Item
+		if !bytes.HasPrefix(item, prefix) {
+			break
+		}
+		tail := item[len(prefix):]
+		if len(tail) < 8 {
+			return dst, fmt.Errorf("cannot unmarshal metricID from metricID->metricName row %q; it must contain at least 8 bytes", item)
+		}
+		metricID := encoding.UnmarshalUint64(tail)
+		if dmis.Has(metricID) {
+			continue
+		}
+		if n := len(dst); n > dstLen && dst[n-1].metricID == metricID {
+			// Skip duplicate entry for the same metricID.
+			continue
+		}
+		var err error
+		metricName = append(metricName[:0], tail[8:]...)
+		if len(tfsList) > 0 {
+			if err := mn.Unmarshal(metricName); err != nil {
+				return dst, fmt.Errorf("cannot unmarshal metricName %q: %w", metricName, err)
+			}
+			// tfs contain shortened label values, so shorten them in mn too.
+			shortenLabelValuesForMatch(mn)
+			ok := false
+			for _, tfs := range tfsList {
+				ok, err = matchTagFilters(mn, tfs, &kb)
+				if err != nil {
+					return dst, fmt.Errorf("cannot match MetricName %s against tagFilters: %w", mn, err)
+				}
+				if ok {
+					break
+				}
+			}
+			if !ok {
+				continue
+			}
+		}
+		metricName, err = is.db.resolveMetricName(metricName, 0)
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, metricIDName{
+			metricID:   metricID,
+			metricName: string(metricName),
+		})
+	}
+	if err := ts.Error(); err != nil {
+		return dst, fmt.Errorf("error when searching metric names after metricID=%d: %w", afterMetricID, err)
+	}
 	return dst, nil
 }
 
@@ -2097,6 +2228,8 @@ func (is *indexSearch) updateMetricIDsByMetricNameMatch(qt *querytracer.Tracer,
 		if err := mn.Unmarshal(metricName.B); err != nil {
 			return fmt.Errorf("cannot unmarshal metricName %q: %w", metricName.B, err)
 		}
+		// tfs contain shortened label values, so shorten them in mn too.
+		shortenLabelValuesForMatch(mn)
 
 		// Match the mn against tfs.
 		ok, err := matchTagFilters(mn, tfs, &is.kb)
@@ -2191,6 +2324,10 @@ func matchTagFilters(mn *MetricName, tfs []*tagFilter, kb *bytesutil.ByteBuffer)
 			// since mn doesn't contain the corresponding tag.
 			continue
 		}
+		if isCompositeLabelsKey(tf.key) {
+			// Skip composite labels filter, since tfs contain the original filters for its labels.
+			continue
+		}
 		if len(tf.key) == 0 || string(tf.key) == "__graphite__" {
 			// Match against mn.MetricGroup.
 			b := marshalTagValue(kb.B, nil)
@@ -2364,6 +2501,7 @@ func (is *indexSearch) updateMetricIDsForTagFilters(qt *querytracer.Tracer, metr
 	// Slow path - fall back to search in the global inverted index.
 	qt.Printf("cannot find metric ids in per-day index; fall back to global index")
 	atomic.AddUint64(&is.db.globalSearchCalls, 1)
+	tfs = removeCompositeLabelsFilters(tfs)
 	m, err := is.getMetricIDsForDateAndFilters(qt, 0, tfs, maxMetrics)
 	if err != nil {
 		if errors.Is(err, errFallbackToGlobalSearch) {
@@ -2520,7 +2658,8 @@ func (is *indexSearch) updateMetricIDsForOrSuffix(prefix []byte, metricIDs *uint
 	mp := &is.mp
 	var loopsCount int64
 	loopsPaceLimiter := 0
-	ts.Seek(prefix)
+	// The prefix contains the full `tag=value` pair, so parts without it can be skipped with bloom filters.
+	ts.SeekPrefix(prefix)
 	for metricIDs.Len() < maxMetrics && ts.NextItem() {
 		if loopsPaceLimiter&paceLimiterFastIterationsMask == 0 {
 			if err := checkSearchDeadlineAndPace(is.deadline); err != nil {
@@ -2825,6 +2964,9 @@ const (
 )
 
 func (is *indexSearch) createPerDayIndexes(date, metricID uint64, mn *MetricName) {
+	// mn may contain full label values if it is obtained from metricNameRaw or from searchMetricName.
+	is.shortenLabelValues(mn)
+
 	ii := getIndexItems()
 	defer putIndexItems(ii)
 
@@ -2839,8 +2981,12 @@ func (is *indexSearch) createPerDayIndexes(date, metricID uint64, mn *MetricName
 	kb.B = marshalCommonPrefix(kb.B[:0], nsPrefixDateTagToMetricIDs)
 	kb.B = encoding.MarshalUint64(kb.B, date)
 	ii.registerTagIndexes(kb.B, mn, metricID)
+	ii.registerCompositeLabelsIndexes(kb.B, mn, metricID, is.db.s.compositeLabelsIndexes)
 	is.db.tb.AddItems(ii.Items)
 	is.db.s.dateMetricIDCache.Set(date, metricID)
+	if css := is.db.s.cardinalitySketches; css != nil {
+		css.register(date, metricID, mn)
+	}
 }
 
 func (ii *indexItems) registerTagIndexes(prefix []byte, mn *MetricName, metricID uint64) {
@@ -2898,6 +3044,9 @@ func isArtificialTagKey(key []byte) bool {
 	if len(key) > 0 && key[0] == compositeTagKeyPrefix {
 		return true
 	}
+	if isCompositeLabelsKey(key) {
+		return true
+	}
 	return false
 }
 
@@ -3118,6 +3267,37 @@ func marshalCommonPrefix(dst []byte, nsPrefix byte) []byte {
 	return dst
 }
 
+// getTagToMetricIDsBloomKey returns `tag=value` key for the given (date, tag=value)->metricIDs or tag=value->metricIDs item.
+//
+// The returned key is registered in per-part bloom filters at indexdb, so parts without the given `tag=value` pair
+// are skipped during the search for metricIDs. nil is returned for other items.
+func getTagToMetricIDsBloomKey(item []byte) []byte {
+	if len(item) < commonPrefixLen {
+		return nil
+	}
+	n := commonPrefixLen
+	switch item[0] {
+	case nsPrefixTagToMetricIDs:
+	case nsPrefixDateTagToMetricIDs:
+		n += 8
+	default:
+		return nil
+	}
+	if len(item) < n {
+		return nil
+	}
+	tail := item[n:]
+	k := bytes.IndexByte(tail, tagSeparatorChar)
+	if k < 0 {
+		return nil
+	}
+	v := bytes.IndexByte(tail[k+1:], tagSeparatorChar)
+	if v < 0 {
+		return nil
+	}
+	return item[:n+k+1+v+1]
+}
+
 // This function is needed only for minimizing the difference between code for single-node and cluster version.
 func (is *indexSearch) marshalCommonPrefix(dst []byte, nsPrefix byte) []byte {
 	return marshalCommonPrefix(dst, nsPrefix)
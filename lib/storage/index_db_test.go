@@ -111,6 +111,51 @@ func TestReverseBytes(t *testing.T) {
 	f("foo.bar", "rab.oof")
 }
 
+func TestGetTagToMetricIDsBloomKey(t *testing.T) {
+	f := func(item []byte, keyExpected []byte) {
+		t.Helper()
+		key := getTagToMetricIDsBloomKey(item)
+		if string(key) != string(keyExpected) {
+			t.Fatalf("unexpected key for item %q; got %q; want %q", item, key, keyExpected)
+		}
+		if key == nil {
+			return
+		}
+		// The key must be returned for the key itself, since it is used as a prefix for TableSearch.SeekPrefix.
+		if k := getTagToMetricIDsBloomKey(key); string(k) != string(key) {
+			t.Fatalf("unexpected key for key %q; got %q; want %q", key, k, key)
+		}
+	}
+	tagValue := func(prefix []byte, key, value string) []byte {
+		dst := append([]byte{}, prefix...)
+		dst = marshalTagValue(dst, []byte(key))
+		return marshalTagValue(dst, []byte(value))
+	}
+	metricIDs := encoding.MarshalUint64(nil, 123)
+
+	// global index
+	prefix := marshalCommonPrefix(nil, nsPrefixTagToMetricIDs)
+	key := tagValue(prefix, "job", "foo")
+	f(append(append([]byte{}, key...), metricIDs...), key)
+	key = tagValue(prefix, "", "http_requests_total")
+	f(append(append([]byte{}, key...), metricIDs...), key)
+
+	// per-day index
+	prefix = marshalCommonPrefix(nil, nsPrefixDateTagToMetricIDs)
+	prefix = encoding.MarshalUint64(prefix, 19000)
+	key = tagValue(prefix, "job", "foo")
+	f(append(append([]byte{}, key...), metricIDs...), key)
+
+	// incomplete items
+	f(nil, nil)
+	f(marshalCommonPrefix(nil, nsPrefixDateTagToMetricIDs), nil)
+	f(marshalTagValue(marshalCommonPrefix(nil, nsPrefixTagToMetricIDs), []byte("job")), nil)
+	f(append(marshalTagValue(marshalCommonPrefix(nil, nsPrefixTagToMetricIDs), []byte("job")), "fo"...), nil)
+
+	// other namespaces
+	f(tagValue(marshalCommonPrefix(nil, nsPrefixMetricIDToTSID), "job", "foo"), nil)
+}
+
 func TestMergeTagToMetricIDsRows(t *testing.T) {
 	f := func(items []string, expectedItems []string) {
 		t.Helper()
@@ -0,0 +1,284 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// minIndexedLabelValueLen is the minimum value for SetMaxIndexedLabelValueLen.
+//
+// Shortened label values must keep a meaningful prefix of the original value
+// in addition to labelValueHashSuffixLen bytes of hash.
+const minIndexedLabelValueLen = 32
+
+// labelValueHashSuffixLen is the length of `#<hex hash>` suffix appended to shortened label values.
+const labelValueHashSuffixLen = 1 + 16
+
+var maxIndexedLabelValueLen = 0
+
+// SetMaxIndexedLabelValueLen sets the maximum length for label values stored in indexdb.
+//
+// Longer label values are replaced in indexdb with a shortened value consisting of the value prefix
+// and the hash of the full value, while the full value is stored only once in the label value dictionary.
+// Values smaller than 32 are rounded up to 32. Zero disables label value shortening.
+func SetMaxIndexedLabelValueLen(n int) {
+	if n > 0 && n < minIndexedLabelValueLen {
+		n = minIndexedLabelValueLen
+	}
+	maxIndexedLabelValueLen = n
+}
+
+// appendShortLabelValue appends shortened value for the given label value to dst.
+//
+// The shortened value has maxIndexedLabelValueLen length and consists of value prefix
+// followed by `#` and hex-encoded xxhash of the full value.
+func appendShortLabelValue(dst, value []byte) []byte {
+	n := maxIndexedLabelValueLen - labelValueHashSuffixLen
+	dst = append(dst, value[:n]...)
+	dst = append(dst, '#')
+	h := xxhash.Sum64(value)
+	for i := 60; i >= 0; i -= 4 {
+		dst = append(dst, hexDigits[(h>>uint(i))&0xf])
+	}
+	return dst
+}
+
+const hexDigits = "0123456789abcdef"
+
+// needShortLabelValue returns true if the given label value must be shortened before storing it in indexdb.
+func needShortLabelValue(value []byte) bool {
+	return maxIndexedLabelValueLen > 0 && len(value) > maxIndexedLabelValueLen
+}
+
+// isShortLabelValue returns true if the given value looks like a value returned by appendShortLabelValue.
+//
+// The check doesn't depend on the current maxIndexedLabelValueLen, so values shortened
+// with the previous maxIndexedLabelValueLen are still resolved after the limit change.
+func isShortLabelValue(value []byte) bool {
+	if len(value) < minIndexedLabelValueLen {
+		return false
+	}
+	suffix := value[len(value)-labelValueHashSuffixLen:]
+	if suffix[0] != '#' {
+		return false
+	}
+	for _, c := range suffix[1:] {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// shortenMetricName replaces too long label values in the given marshaled metricName with short values.
+//
+// It returns metricName as is if it doesn't contain too long label values.
+// Otherwise the shortened metricName is appended to dst and the full label values
+// are registered in the label value dictionary.
+func (is *indexSearch) shortenMetricName(dst, metricName []byte) ([]byte, []byte, error) {
+	if maxIndexedLabelValueLen <= 0 || len(metricName) <= maxIndexedLabelValueLen {
+		// Fast path - metricName cannot contain too long label values.
+		return dst, metricName, nil
+	}
+	mn := GetMetricName()
+	defer PutMetricName(mn)
+	if err := mn.Unmarshal(metricName); err != nil {
+		return dst, nil, fmt.Errorf("cannot unmarshal metricName %q: %w", metricName, err)
+	}
+	if !is.shortenLabelValues(mn) {
+		return dst, metricName, nil
+	}
+	dstLen := len(dst)
+	dst = mn.Marshal(dst)
+	return dst, dst[dstLen:], nil
+}
+
+// shortenLabelValues replaces too long label values in mn with short values.
+//
+// It registers the full label values in the label value dictionary.
+// It returns false if mn doesn't contain too long label values.
+func (is *indexSearch) shortenLabelValues(mn *MetricName) bool {
+	shortened := false
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		if !needShortLabelValue(tag.Value) {
+			continue
+		}
+		shortValue := appendShortLabelValue(nil, tag.Value)
+		is.registerLabelValue(shortValue, tag.Value)
+		tag.Value = shortValue
+		shortened = true
+	}
+	return shortened
+}
+
+// shortenLabelValuesForMatch replaces too long label values in mn with short values
+// without registering them in the label value dictionary.
+//
+// This is needed for matching mn against tag filters, which contain short label values.
+func shortenLabelValuesForMatch(mn *MetricName) {
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		if needShortLabelValue(tag.Value) {
+			tag.Value = appendShortLabelValue(nil, tag.Value)
+		}
+	}
+}
+
+// registerLabelValue stores shortValue->fullValue entry in the label value dictionary if it is missing there.
+func (is *indexSearch) registerLabelValue(shortValue, fullValue []byte) {
+	kb := &is.kb
+	kb.B = is.marshalCommonPrefix(kb.B[:0], nsPrefixLabelValueDict)
+	kb.B = marshalTagValue(kb.B, shortValue)
+	if err := is.ts.FirstItemWithPrefix(kb.B); err == nil {
+		// The entry already exists.
+		return
+	}
+	ii := getIndexItems()
+	ii.B = append(ii.B, kb.B...)
+	ii.B = append(ii.B, fullValue...)
+	ii.Next()
+	is.db.tb.AddItems(ii.Items)
+	putIndexItems(ii)
+	atomic.StoreUint32(&is.db.hasLabelValueDict, 1)
+}
+
+// initHasLabelValueDict sets db.hasLabelValueDict if the label value dictionary in db contains entries.
+func (db *indexDB) initHasLabelValueDict() {
+	is := db.getIndexSearch(noDeadline)
+	kb := &is.kb
+	kb.B = is.marshalCommonPrefix(kb.B[:0], nsPrefixLabelValueDict)
+	if err := is.ts.FirstItemWithPrefix(kb.B); err == nil {
+		atomic.StoreUint32(&db.hasLabelValueDict, 1)
+	}
+	db.putIndexSearch(is)
+}
+
+// mayHaveShortLabelValues returns true if the label value dictionary has been used in db or in db.extDB.
+//
+// Label values cannot be resolved via the empty dictionary, so there is no need in searching for them.
+func (db *indexDB) mayHaveShortLabelValues() bool {
+	if atomic.LoadUint32(&db.hasLabelValueDict) != 0 {
+		return true
+	}
+	ok := false
+	db.doExtDB(func(extDB *indexDB) {
+		ok = atomic.LoadUint32(&extDB.hasLabelValueDict) != 0
+	})
+	return ok
+}
+
+// searchLabelValue appends the full label value for the given shortValue to dst.
+//
+// It returns io.EOF if shortValue is missing in the label value dictionary.
+func (is *indexSearch) searchLabelValue(dst, shortValue []byte) ([]byte, error) {
+	ts := &is.ts
+	kb := &is.kb
+	kb.B = is.marshalCommonPrefix(kb.B[:0], nsPrefixLabelValueDict)
+	kb.B = marshalTagValue(kb.B, shortValue)
+	if err := ts.FirstItemWithPrefix(kb.B); err != nil {
+		if err == io.EOF {
+			return dst, err
+		}
+		return dst, fmt.Errorf("error when searching label value by short value %q: %w", shortValue, err)
+	}
+	dst = append(dst, ts.Item[len(kb.B):]...)
+	return dst, nil
+}
+
+// resolveLabelValue appends the full label value for the given shortValue to dst.
+//
+// It searches the value in db and in db.extDB. It returns io.EOF if shortValue cannot be resolved.
+func (db *indexDB) resolveLabelValue(dst, shortValue []byte) ([]byte, error) {
+	is := db.getIndexSearch(noDeadline)
+	dst, err := is.searchLabelValue(dst, shortValue)
+	db.putIndexSearch(is)
+	if err != io.EOF {
+		return dst, err
+	}
+	db.doExtDB(func(extDB *indexDB) {
+		is := extDB.getIndexSearch(noDeadline)
+		dst, err = is.searchLabelValue(dst, shortValue)
+		extDB.putIndexSearch(is)
+	})
+	return dst, err
+}
+
+// resolveMetricName replaces short label values in the marshaled metricName at dst[dstLen:] with full values.
+func (db *indexDB) resolveMetricName(dst []byte, dstLen int) ([]byte, error) {
+	metricName := dst[dstLen:]
+	if !hasShortLabelValues(metricName) || !db.mayHaveShortLabelValues() {
+		return dst, nil
+	}
+	mn := GetMetricName()
+	defer PutMetricName(mn)
+	if err := mn.Unmarshal(metricName); err != nil {
+		return dst, fmt.Errorf("cannot unmarshal metricName %q: %w", metricName, err)
+	}
+	var buf []byte
+	for i := range mn.Tags {
+		tag := &mn.Tags[i]
+		if !isShortLabelValue(tag.Value) {
+			continue
+		}
+		var err error
+		bufLen := len(buf)
+		buf, err = db.resolveLabelValue(buf, tag.Value)
+		if err != nil {
+			if err == io.EOF {
+				// Leave the short value as is, since the dictionary entry may be missing
+				// for label values, which just look like short values.
+				continue
+			}
+			return dst, err
+		}
+		tag.Value = buf[bufLen:]
+	}
+	dst = mn.Marshal(dst[:dstLen])
+	return dst, nil
+}
+
+// hasShortLabelValues returns true if the marshaled metricName may contain short label values.
+func hasShortLabelValues(metricName []byte) bool {
+	if len(metricName) < minIndexedLabelValueLen {
+		return false
+	}
+	// Every short label value ends with `#<hex hash>` followed by tagSeparatorChar.
+	for {
+		n := bytes.IndexByte(metricName, tagSeparatorChar)
+		if n < 0 {
+			return false
+		}
+		if n >= labelValueHashSuffixLen && metricName[n-labelValueHashSuffixLen] == '#' {
+			return true
+		}
+		metricName = metricName[n+1:]
+	}
+}
+
+// resolveLabelValues replaces short label values in values with full values.
+func (db *indexDB) resolveLabelValues(values []string) ([]string, error) {
+	if !db.mayHaveShortLabelValues() {
+		return values, nil
+	}
+	var buf []byte
+	for i, v := range values {
+		if !isShortLabelValue([]byte(v)) {
+			continue
+		}
+		var err error
+		buf, err = db.resolveLabelValue(buf[:0], []byte(v))
+		if err != nil {
+			if err == io.EOF {
+				continue
+			}
+			return nil, err
+		}
+		values[i] = string(buf)
+	}
+	return values, nil
+}
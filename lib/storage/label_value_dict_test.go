@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendShortLabelValue(t *testing.T) {
+	defer SetMaxIndexedLabelValueLen(0)
+	SetMaxIndexedLabelValueLen(40)
+
+	value := []byte("https://example.com/" + strings.Repeat("very/long/path/", 10))
+	if !needShortLabelValue(value) {
+		t.Fatalf("expecting %q to be shortened", value)
+	}
+	shortValue := appendShortLabelValue(nil, value)
+	if len(shortValue) != 40 {
+		t.Fatalf("unexpected short value length; got %d; want %d", len(shortValue), 40)
+	}
+	if !strings.HasPrefix(string(shortValue), string(value[:40-labelValueHashSuffixLen])) {
+		t.Fatalf("short value %q must start with the prefix of %q", shortValue, value)
+	}
+	if !isShortLabelValue(shortValue) {
+		t.Fatalf("expecting %q to be detected as short value", shortValue)
+	}
+	shortValue2 := appendShortLabelValue(nil, value)
+	if string(shortValue) != string(shortValue2) {
+		t.Fatalf("short values must be deterministic; got %q and %q", shortValue, shortValue2)
+	}
+	shortValueOther := appendShortLabelValue(nil, append(value, 'x'))
+	if string(shortValue) == string(shortValueOther) {
+		t.Fatalf("short values for distinct label values must differ; got %q", shortValue)
+	}
+
+	f := func(value string, resultExpected bool) {
+		t.Helper()
+		result := isShortLabelValue([]byte(value))
+		if result != resultExpected {
+			t.Fatalf("unexpected isShortLabelValue(%q); got %v; want %v", value, result, resultExpected)
+		}
+	}
+	f("", false)
+	f("foobar", false)
+	f("#0123456789abcdef", false)
+	f(strings.Repeat("x", 20)+"#0123456789abcdef", true)
+	f(strings.Repeat("x", 20)+"#0123456789abcdeF", false)
+	f(strings.Repeat("x", 20)+"-0123456789abcdef", false)
+}
+
+func TestSetMaxIndexedLabelValueLen(t *testing.T) {
+	defer SetMaxIndexedLabelValueLen(0)
+
+	SetMaxIndexedLabelValueLen(5)
+	if maxIndexedLabelValueLen != minIndexedLabelValueLen {
+		t.Fatalf("unexpected maxIndexedLabelValueLen; got %d; want %d", maxIndexedLabelValueLen, minIndexedLabelValueLen)
+	}
+	SetMaxIndexedLabelValueLen(0)
+	if needShortLabelValue([]byte(strings.Repeat("x", 1000))) {
+		t.Fatalf("label values mustn't be shortened when maxIndexedLabelValueLen=0")
+	}
+}
+
+func TestStorageLongLabelValues(t *testing.T) {
+	defer SetMaxIndexedLabelValueLen(0)
+	SetMaxIndexedLabelValueLen(64)
+
+	path := "TestStorageLongLabelValues"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	defer func() {
+		s.MustClose()
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove %q: %s", path, err)
+		}
+	}()
+
+	const urlsCount = 10
+	var urlsExpected []string
+	var mrs []MetricRow
+	now := timestampFromTime(time.Now())
+	for i := 0; i < urlsCount; i++ {
+		url := fmt.Sprintf("https://example.com/%s%d", strings.Repeat("some/long/path/", 10), i)
+		urlsExpected = append(urlsExpected, url)
+		mn := MetricName{
+			MetricGroup: []byte("http_requests_total"),
+			Tags: []Tag{
+				{[]byte("job"), []byte("webservice")},
+				{[]byte("url"), []byte(url)},
+			},
+		}
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     now,
+		})
+	}
+	if s.idb().mayHaveShortLabelValues() {
+		t.Fatalf("the label value dictionary must be empty before registering long label values")
+	}
+	if err := s.RegisterMetricNames(nil, mrs); err != nil {
+		t.Fatalf("unexpected error in RegisterMetricNames: %s", err)
+	}
+	if !s.idb().mayHaveShortLabelValues() {
+		t.Fatalf("the label value dictionary must be non-empty after registering long label values")
+	}
+	// Register the same metric names again in order to verify they are found in the index.
+	if err := s.RegisterMetricNames(nil, mrs); err != nil {
+		t.Fatalf("unexpected error in RegisterMetricNames: %s", err)
+	}
+	s.DebugFlush()
+
+	// Verify that label values are returned in the full form.
+	urls, err := s.SearchLabelValuesWithFiltersOnTimeRange(nil, "url", nil, TimeRange{}, 1e3, 1e9, noDeadline)
+	if err != nil {
+		t.Fatalf("error in SearchLabelValuesWithFiltersOnTimeRange: %s", err)
+	}
+	sort.Strings(urls)
+	sort.Strings(urlsExpected)
+	if !reflect.DeepEqual(urls, urlsExpected) {
+		t.Fatalf("unexpected label values;\ngot\n%q\nwant\n%q", urls, urlsExpected)
+	}
+
+	// Verify that the full label value can be used in exact match filter.
+	tr := TimeRange{
+		MinTimestamp: now - msecPerDay,
+		MaxTimestamp: now + 60*1000,
+	}
+	for _, url := range urlsExpected {
+		tfs := NewTagFilters()
+		if err := tfs.Add([]byte("url"), []byte(url), false, false); err != nil {
+			t.Fatalf("cannot add tag filter: %s", err)
+		}
+		metricNames, err := s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e3, noDeadline)
+		if err != nil {
+			t.Fatalf("error in SearchMetricNames: %s", err)
+		}
+		if len(metricNames) != 1 {
+			t.Fatalf("unexpected number of metric names found for url=%q; got %d; want 1", url, len(metricNames))
+		}
+		var mn MetricName
+		if err := mn.UnmarshalString(metricNames[0]); err != nil {
+			t.Fatalf("cannot unmarshal metric name: %s", err)
+		}
+		if v := mn.GetTagValue("url"); string(v) != url {
+			t.Fatalf("unexpected url label value; got %q; want %q", v, url)
+		}
+	}
+
+	// Verify that the full label values can be used in regexp filter with alternations.
+	tfs := NewTagFilters()
+	re := regexp.QuoteMeta(urlsExpected[0]) + "|" + regexp.QuoteMeta(urlsExpected[1])
+	if err := tfs.Add([]byte("url"), []byte(re), false, true); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	metricNames, err := s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e3, noDeadline)
+	if err != nil {
+		t.Fatalf("error in SearchMetricNames: %s", err)
+	}
+	if len(metricNames) != 2 {
+		t.Fatalf("unexpected number of metric names found for url=~%q; got %d; want 2", re, len(metricNames))
+	}
+
+	// Verify that the full label value can be used in negative filter.
+	tfs = NewTagFilters()
+	if err := tfs.Add([]byte("url"), []byte(urlsExpected[0]), true, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	metricNames, err = s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e3, noDeadline)
+	if err != nil {
+		t.Fatalf("error in SearchMetricNames: %s", err)
+	}
+	if len(metricNames) != urlsCount-1 {
+		t.Fatalf("unexpected number of metric names found; got %d; want %d", len(metricNames), urlsCount-1)
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
 	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
+	"github.com/cespare/xxhash/v2"
 )
 
 const (
@@ -428,19 +429,30 @@ func (mn *MetricName) Unmarshal(src []byte) error {
 
 // The maximum length of label name.
 //
-// Longer names are truncated.
-const maxLabelNameLen = 256
+// Longer names are handled according to labelLimitsPolicy.
+var maxLabelNameLen = 256
+
+// SetMaxLabelNameLen sets the limit on the label name length.
+//
+// This function can be called before using the storage package.
+//
+// Label names with longer length are handled according to the policy set via SetLabelLimitsPolicy.
+func SetMaxLabelNameLen(n int) {
+	if n > 0 {
+		maxLabelNameLen = n
+	}
+}
 
 // The maximum length of label value.
 //
-// Longer values are truncated.
+// Longer values are handled according to labelLimitsPolicy.
 var maxLabelValueLen = 16 * 1024
 
 // SetMaxLabelValueLen sets the limit on the label value length.
 //
 // This function can be called before using the storage package.
 //
-// Label values with longer length are truncated.
+// Label values with longer length are handled according to the policy set via SetLabelLimitsPolicy.
 func SetMaxLabelValueLen(n int) {
 	if n > 0 {
 		maxLabelValueLen = n
@@ -455,17 +467,60 @@ var maxLabelsPerTimeseries = 30
 //
 // This function can be called before using the storage package.
 //
-// Superfluous labels are dropped.
+// Superfluous labels are dropped unless the reject policy is set via SetLabelLimitsPolicy.
 func SetMaxLabelsPerTimeseries(maxLabels int) {
 	if maxLabels > 0 {
 		maxLabelsPerTimeseries = maxLabels
 	}
 }
 
+// Policies for labels exceeding -maxLabelNameLen, -maxLabelValueLen and -maxLabelsPerTimeseries limits.
+const (
+	// LabelLimitsPolicyTruncate truncates too long label names and values.
+	LabelLimitsPolicyTruncate = "truncate"
+
+	// LabelLimitsPolicyTruncateWithHash truncates too long label names and values
+	// and replaces their tails with the hash of the original name or value.
+	// This keeps distinct long values distinct after the truncation.
+	LabelLimitsPolicyTruncateWithHash = "truncateWithHash"
+
+	// LabelLimitsPolicyDropLabel drops labels with too long names or values.
+	LabelLimitsPolicyDropLabel = "dropLabel"
+
+	// LabelLimitsPolicyReject rejects time series with too long label names or values
+	// or with too many labels.
+	LabelLimitsPolicyReject = "reject"
+)
+
+var labelLimitsPolicy = LabelLimitsPolicyTruncate
+
+// SetLabelLimitsPolicy sets the policy for labels exceeding the limits on label name length,
+// label value length and the number of labels per time series.
+//
+// This function can be called before using the storage package.
+func SetLabelLimitsPolicy(policy string) error {
+	switch policy {
+	case LabelLimitsPolicyTruncate, LabelLimitsPolicyTruncateWithHash, LabelLimitsPolicyDropLabel, LabelLimitsPolicyReject:
+		labelLimitsPolicy = policy
+		return nil
+	default:
+		return fmt.Errorf("unsupported policy %q; supported values: %s, %s, %s, %s", policy,
+			LabelLimitsPolicyTruncate, LabelLimitsPolicyTruncateWithHash, LabelLimitsPolicyDropLabel, LabelLimitsPolicyReject)
+	}
+}
+
 // MarshalMetricNameRaw marshals labels to dst and returns the result.
 //
+// Labels exceeding the limits are handled according to the policy set via SetLabelLimitsPolicy.
+// dst is returned unchanged if labels are rejected.
+//
 // The result must be unmarshaled with MetricName.UnmarshalRaw
 func MarshalMetricNameRaw(dst []byte, labels []prompb.Label) []byte {
+	if labelLimitsPolicy == LabelLimitsPolicyReject && exceedsLabelLimits(labels) {
+		trackRejectedLabels(labels)
+		return dst
+	}
+
 	// Calculate the required space for dst.
 	dstLen := len(dst)
 	dstSize := dstLen
@@ -477,11 +532,15 @@ func MarshalMetricNameRaw(dst []byte, labels []prompb.Label) []byte {
 		label := &labels[i]
 		if len(label.Name) > maxLabelNameLen {
 			atomic.AddUint64(&TooLongLabelNames, 1)
-			label.Name = label.Name[:maxLabelNameLen]
+			label.Name = limitLabelLen(label.Name, maxLabelNameLen)
+			if label.Name == nil {
+				// The label is dropped, so do not count it.
+				label.Value = nil
+			}
 		}
 		if len(label.Value) > maxLabelValueLen {
 			atomic.AddUint64(&TooLongLabelValues, 1)
-			label.Value = label.Value[:maxLabelValueLen]
+			label.Value = limitLabelLen(label.Value, maxLabelValueLen)
 		}
 		if len(label.Value) == 0 {
 			// Skip labels without values, since they have no sense in prometheus.
@@ -512,6 +571,44 @@ func MarshalMetricNameRaw(dst []byte, labels []prompb.Label) []byte {
 	return dst
 }
 
+// limitLabelLen returns b limited to maxLen bytes according to labelLimitsPolicy.
+//
+// nil is returned if the label must be dropped.
+func limitLabelLen(b []byte, maxLen int) []byte {
+	switch labelLimitsPolicy {
+	case LabelLimitsPolicyDropLabel:
+		atomic.AddUint64(&LabelLimitsDroppedLabels, 1)
+		return nil
+	case LabelLimitsPolicyTruncateWithHash:
+		if maxLen > labelHashSuffixLen {
+			atomic.AddUint64(&LabelLimitsTruncatedWithHash, 1)
+			// Allocate new slice instead of modifying b in place, since b may refer to the request buffer.
+			dst := make([]byte, 0, maxLen)
+			dst = append(dst, b[:maxLen-labelHashSuffixLen]...)
+			return fmt.Appendf(dst, "_%015x", xxhash.Sum64(b)&(1<<60-1))
+		}
+		// There is no space for the hash suffix, so fall back to truncation.
+	}
+	atomic.AddUint64(&LabelLimitsTruncated, 1)
+	return b[:maxLen]
+}
+
+// labelHashSuffixLen is the length of the `_<hash>` suffix for LabelLimitsPolicyTruncateWithHash.
+const labelHashSuffixLen = 1 + 15
+
+func exceedsLabelLimits(labels []prompb.Label) bool {
+	if len(labels) > maxLabelsPerTimeseries {
+		return true
+	}
+	for i := range labels {
+		label := &labels[i]
+		if len(label.Name) > maxLabelNameLen || len(label.Value) > maxLabelValueLen {
+			return true
+		}
+	}
+	return false
+}
+
 var (
 	// MetricsWithDroppedLabels is the number of metrics with at least a single dropped label
 	MetricsWithDroppedLabels uint64
@@ -521,8 +618,30 @@ var (
 
 	// TooLongLabelValues is the number of too long label values
 	TooLongLabelValues uint64
+
+	// LabelLimitsTruncated is the number of label names and values truncated because of limits
+	LabelLimitsTruncated uint64
+
+	// LabelLimitsTruncatedWithHash is the number of label names and values truncated with hash suffix because of limits
+	LabelLimitsTruncatedWithHash uint64
+
+	// LabelLimitsDroppedLabels is the number of labels dropped because of too long names or values
+	LabelLimitsDroppedLabels uint64
+
+	// LabelLimitsRejectedSeries is the number of time series rejected because of label limits
+	LabelLimitsRejectedSeries uint64
 )
 
+func trackRejectedLabels(labels []prompb.Label) {
+	atomic.AddUint64(&LabelLimitsRejectedSeries, 1)
+	select {
+	case <-droppedLabelsLogTicker.C:
+		logger.Warnf("rejecting time series %s, since it exceeds -maxLabelsPerTimeseries=%d, -maxLabelNameLen=%d or -maxLabelValueLen=%d limits; "+
+			"see -labelLimitsPolicy", labelsToString(labels), maxLabelsPerTimeseries, maxLabelNameLen, maxLabelValueLen)
+	default:
+	}
+}
+
 func trackDroppedLabels(labels, droppedLabels []prompb.Label) {
 	atomic.AddUint64(&MetricsWithDroppedLabels, 1)
 	select {
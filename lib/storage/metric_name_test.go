@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/prompb"
 )
 
 func TestMetricNameString(t *testing.T) {
@@ -170,6 +172,102 @@ func TestMetricNameMarshalUnmarshalRaw(t *testing.T) {
 	}
 }
 
+func TestMarshalMetricNameRawLabelLimits(t *testing.T) {
+	defer func() {
+		maxLabelNameLen = 256
+		maxLabelValueLen = 16 * 1024
+		maxLabelsPerTimeseries = 30
+		labelLimitsPolicy = LabelLimitsPolicyTruncate
+	}()
+	SetMaxLabelNameLen(8)
+	SetMaxLabelValueLen(20)
+	SetMaxLabelsPerTimeseries(3)
+
+	f := func(policy string, labels []prompb.Label, resultExpected string) {
+		t.Helper()
+		if err := SetLabelLimitsPolicy(policy); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		data := MarshalMetricNameRaw([]byte("prefix"), labels)
+		if string(data[:len("prefix")]) != "prefix" {
+			t.Fatalf("unexpected prefix in %q", data)
+		}
+		data = data[len("prefix"):]
+		if len(data) == 0 {
+			if resultExpected != "" {
+				t.Fatalf("unexpected rejection for policy %q; want %s", policy, resultExpected)
+			}
+			return
+		}
+		var mn MetricName
+		if err := mn.UnmarshalRaw(data); err != nil {
+			t.Fatalf("cannot unmarshal metric name: %s", err)
+		}
+		mn.sortTags()
+		if result := mn.String(); result != resultExpected {
+			t.Fatalf("unexpected result for policy %q;\ngot\n%s\nwant\n%s", policy, result, resultExpected)
+		}
+	}
+	newLabels := func(kvs ...string) []prompb.Label {
+		var labels []prompb.Label
+		for i := 0; i < len(kvs); i += 2 {
+			labels = append(labels, prompb.Label{
+				Name:  []byte(kvs[i]),
+				Value: []byte(kvs[i+1]),
+			})
+		}
+		return labels
+	}
+
+	longValue := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	otherLongValue := "aaaaaaaaaaaaaaaaaaaaaaaaaaaaab"
+
+	// labels within limits
+	for _, policy := range []string{LabelLimitsPolicyTruncate, LabelLimitsPolicyTruncateWithHash, LabelLimitsPolicyDropLabel, LabelLimitsPolicyReject} {
+		f(policy, newLabels("__name__", "foo", "job", "bar"), `foo{job="bar"}`)
+	}
+
+	// truncate
+	f(LabelLimitsPolicyTruncate, newLabels("__name__", "foo", "job", longValue), `foo{job="aaaaaaaaaaaaaaaaaaaa"}`)
+	f(LabelLimitsPolicyTruncate, newLabels("__name__", "foo", "too_long_name", "x"), `foo{too_long="x"}`)
+
+	// truncateWithHash keeps distinct values distinct
+	truncateWithHash := func(value string) string {
+		t.Helper()
+		labels := newLabels("__name__", "foo", "job", value)
+		if err := SetLabelLimitsPolicy(LabelLimitsPolicyTruncateWithHash); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		_ = MarshalMetricNameRaw(nil, labels)
+		v := string(labels[1].Value)
+		if len(v) != 20 || v[:4] != "aaaa" || v[4] != '_' {
+			t.Fatalf("unexpected truncated value %q", v)
+		}
+		return v
+	}
+	if v1, v2 := truncateWithHash(longValue), truncateWithHash(otherLongValue); v1 == v2 {
+		t.Fatalf("distinct values must remain distinct after truncation; got %q", v1)
+	}
+	// there is no space for the hash in label names, so they are truncated
+	f(LabelLimitsPolicyTruncateWithHash, newLabels("__name__", "foo", "too_long_name", "x"), `foo{too_long="x"}`)
+
+	// dropLabel
+	f(LabelLimitsPolicyDropLabel, newLabels("__name__", "foo", "job", longValue, "instance", "x"), `foo{instance="x"}`)
+	f(LabelLimitsPolicyDropLabel, newLabels("__name__", "foo", "too_long_name", "x", "instance", "x"), `foo{instance="x"}`)
+
+	// reject
+	f(LabelLimitsPolicyReject, newLabels("__name__", "foo", "job", longValue), "")
+	f(LabelLimitsPolicyReject, newLabels("__name__", "foo", "too_long_name", "x"), "")
+	f(LabelLimitsPolicyReject, newLabels("__name__", "foo", "a", "1", "b", "2", "c", "3"), "")
+
+	// superfluous labels are dropped for policies other than reject
+	f(LabelLimitsPolicyDropLabel, newLabels("__name__", "foo", "a", "1", "b", "2", "c", "3"), `foo{a="1",b="2"}`)
+
+	if err := SetLabelLimitsPolicy("foobar"); err == nil {
+		t.Fatalf("expecting non-nil error for unsupported policy")
+	}
+}
+
 func TestMetricNameCopyFrom(t *testing.T) {
 	var from MetricName
 	from.MetricGroup = []byte("group")
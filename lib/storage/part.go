@@ -18,7 +18,7 @@ func getMaxIndexBlocksCacheSize() int {
 	maxIndexBlockCacheSizeOnce.Do(func() {
 		maxIndexBlockCacheSize = int(0.1 * float64(memory.Allowed()))
 	})
-	return maxIndexBlockCacheSize
+	return memory.ScaleCacheSize(maxIndexBlockCacheSize)
 }
 
 var (
@@ -43,6 +43,18 @@ type part struct {
 	indexFile      fs.MustReadAtCloser
 
 	metaindex []metaindexRow
+
+	// quarantined is set to 1 while the part is quarantined because of read error. See SetReadErrorQuarantine.
+	quarantined uint32
+
+	// quarantineLock protects quarantineStopCh.
+	quarantineLock sync.Mutex
+
+	// quarantineStopCh is closed in order to stop background read retries for the quarantined part.
+	quarantineStopCh chan struct{}
+
+	// quarantineWG waits for background read retries for the quarantined part.
+	quarantineWG sync.WaitGroup
 }
 
 // openFilePart opens file-based part from the given path.
@@ -121,6 +133,8 @@ func (p *part) String() string {
 
 // MustClose closes all the part files.
 func (p *part) MustClose() {
+	p.stopQuarantine()
+
 	p.timestampsFile.MustClose()
 	p.valuesFile.MustClose()
 	p.indexFile.MustClose()
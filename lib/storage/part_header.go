@@ -128,6 +128,13 @@ func (ph *partHeader) Reset() {
 	ph.MinDedupInterval = 0
 }
 
+func (ph *partHeader) getTimeRange() TimeRange {
+	return TimeRange{
+		MinTimestamp: ph.MinTimestamp,
+		MaxTimestamp: ph.MaxTimestamp,
+	}
+}
+
 func (ph *partHeader) readMinDedupInterval(partPath string) error {
 	filePath := partPath + "/min_dedup_interval"
 	data, err := os.ReadFile(filePath)
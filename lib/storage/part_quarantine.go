@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/metrics"
+)
+
+var (
+	readErrorQuarantine    bool
+	readErrorRetryInterval = time.Minute
+)
+
+// SetReadErrorQuarantine enables or disables quarantine for parts with read errors.
+//
+// By default the process panics on read errors. If quarantine is enabled, then parts with read errors
+// are excluded from searches and merges, while the failed read is retried every retryInterval in background.
+// The part is returned back to searches and merges after the read succeeds.
+//
+// This function must be called before opening the storage.
+func SetReadErrorQuarantine(enable bool, retryInterval time.Duration) {
+	readErrorQuarantine = enable
+	readErrorRetryInterval = retryInterval
+}
+
+// ErrPartQuarantined is returned when the data cannot be read from a part, since the part is quarantined.
+//
+// See SetReadErrorQuarantine.
+var ErrPartQuarantined = errors.New("the part is quarantined because of read error")
+
+// GetQuarantinedPartsCount returns the number of parts, which are currently quarantined because of read errors.
+//
+// Search results may miss data from quarantined parts.
+func GetQuarantinedPartsCount() int {
+	return int(atomic.LoadInt64(&quarantinedParts))
+}
+
+// GetPartQuarantineEvents returns the number of times parts have been quarantined because of read errors since the process start.
+//
+// It may be used for detecting whether some parts have been quarantined during the search.
+func GetPartQuarantineEvents() uint64 {
+	return partQuarantineEvents.Get()
+}
+
+var (
+	quarantinedParts      int64
+	partQuarantineEvents  = metrics.NewCounter(`vm_parts_quarantine_events_total`)
+	partQuarantineRetries = metrics.NewCounter(`vm_parts_quarantine_read_retries_total`)
+)
+
+var _ = metrics.NewGauge(`vm_parts_quarantined`, func() float64 {
+	return float64(GetQuarantinedPartsCount())
+})
+
+type tryReaderAt interface {
+	TryReadAt(p []byte, off int64) error
+}
+
+// readAt reads len(buf) bytes at off from r, which belongs to p.
+//
+// It returns an error wrapping ErrPartQuarantined if the data cannot be read and read error quarantine is enabled.
+// Otherwise it panics on read errors.
+func (p *part) readAt(r fs.MustReadAtCloser, buf []byte, off int64) error {
+	if !readErrorQuarantine {
+		r.MustReadAt(buf, off)
+		return nil
+	}
+	if p.isQuarantined() {
+		return ErrPartQuarantined
+	}
+	tr, ok := r.(tryReaderAt)
+	if !ok {
+		// In-memory parts cannot have read errors.
+		r.MustReadAt(buf, off)
+		return nil
+	}
+	if err := tr.TryReadAt(buf, off); err != nil {
+		p.startQuarantine(tr, len(buf), off, err)
+		return fmt.Errorf("%w: %s", ErrPartQuarantined, err)
+	}
+	return nil
+}
+
+func (p *part) isQuarantined() bool {
+	return atomic.LoadUint32(&p.quarantined) != 0
+}
+
+// startQuarantine excludes p from searches and merges until the failed read of size bytes at off from r succeeds.
+func (p *part) startQuarantine(r tryReaderAt, size int, off int64, err error) {
+	p.quarantineLock.Lock()
+	defer p.quarantineLock.Unlock()
+
+	if p.quarantineStopCh != nil {
+		// The part has been already quarantined by concurrent goroutine.
+		return
+	}
+	logger.Errorf("quarantining part %q because of read error: %s; the part is excluded from searches and merges, "+
+		"so query results may be incomplete; retrying the read every %s", p, err, readErrorRetryInterval)
+	stopCh := make(chan struct{})
+	p.quarantineStopCh = stopCh
+	atomic.StoreUint32(&p.quarantined, 1)
+	atomic.AddInt64(&quarantinedParts, 1)
+	partQuarantineEvents.Inc()
+
+	p.quarantineWG.Add(1)
+	go func() {
+		defer p.quarantineWG.Done()
+		p.retryQuarantinedRead(r, size, off, stopCh)
+	}()
+}
+
+func (p *part) retryQuarantinedRead(r tryReaderAt, size int, off int64, stopCh <-chan struct{}) {
+	buf := make([]byte, size)
+	t := time.NewTicker(readErrorRetryInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-t.C:
+		}
+		partQuarantineRetries.Inc()
+		if err := r.TryReadAt(buf, off); err != nil {
+			logger.Warnf("part %q remains quarantined: %s", p, err)
+			continue
+		}
+
+		p.quarantineLock.Lock()
+		if p.quarantineStopCh != stopCh {
+			// The quarantine has been stopped by stopQuarantine.
+			p.quarantineLock.Unlock()
+			return
+		}
+		p.quarantineStopCh = nil
+		atomic.StoreUint32(&p.quarantined, 0)
+		atomic.AddInt64(&quarantinedParts, -1)
+		p.quarantineLock.Unlock()
+
+		logger.Infof("part %q is returned from quarantine, since its data can be read again", p)
+		return
+	}
+}
+
+// stopQuarantine stops background read retries for p.
+//
+// It must be called before closing p files.
+func (p *part) stopQuarantine() {
+	p.quarantineLock.Lock()
+	stopCh := p.quarantineStopCh
+	p.quarantineStopCh = nil
+	if stopCh != nil {
+		atomic.AddInt64(&quarantinedParts, -1)
+	}
+	p.quarantineLock.Unlock()
+
+	if stopCh != nil {
+		close(stopCh)
+	}
+	p.quarantineWG.Wait()
+}
@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type failingReaderAt struct {
+	fail  uint32
+	reads uint64
+}
+
+func (r *failingReaderAt) MustReadAt(p []byte, off int64) {
+	if err := r.TryReadAt(p, off); err != nil {
+		panic(err)
+	}
+}
+
+func (r *failingReaderAt) TryReadAt(p []byte, off int64) error {
+	atomic.AddUint64(&r.reads, 1)
+	if atomic.LoadUint32(&r.fail) != 0 {
+		return fmt.Errorf("input/output error")
+	}
+	return nil
+}
+
+func (r *failingReaderAt) MustClose() {}
+
+func TestPartReadErrorQuarantine(t *testing.T) {
+	readErrorQuarantineOrig, readErrorRetryIntervalOrig := readErrorQuarantine, readErrorRetryInterval
+	SetReadErrorQuarantine(true, 10*time.Millisecond)
+	defer SetReadErrorQuarantine(readErrorQuarantineOrig, readErrorRetryIntervalOrig)
+
+	waitFor := func(f func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !f() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timeout")
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	r := &failingReaderAt{}
+	p := &part{
+		path: "TestPartReadErrorQuarantine",
+	}
+	buf := make([]byte, 16)
+	if err := p.readAt(r, buf, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Read error must quarantine the part
+	atomic.StoreUint32(&r.fail, 1)
+	if err := p.readAt(r, buf, 0); !errors.Is(err, ErrPartQuarantined) {
+		t.Fatalf("unexpected error; got %v; want %v", err, ErrPartQuarantined)
+	}
+	if !p.isQuarantined() {
+		t.Fatalf("the part must be quarantined")
+	}
+	if n := GetQuarantinedPartsCount(); n != 1 {
+		t.Fatalf("unexpected number of quarantined parts; got %d; want 1", n)
+	}
+
+	// Reads from quarantined part must fail without touching the file
+	reads := atomic.LoadUint64(&r.reads)
+	if err := p.readAt(r, buf, 0); err != ErrPartQuarantined {
+		t.Fatalf("unexpected error; got %v; want %v", err, ErrPartQuarantined)
+	}
+
+	// The part must remain quarantined while background retries fail
+	waitFor(func() bool {
+		return atomic.LoadUint64(&r.reads) > reads+1
+	})
+	if !p.isQuarantined() {
+		t.Fatalf("the part must remain quarantined")
+	}
+
+	// The part must be returned from quarantine after successful read
+	atomic.StoreUint32(&r.fail, 0)
+	waitFor(func() bool {
+		return !p.isQuarantined()
+	})
+	if n := GetQuarantinedPartsCount(); n != 0 {
+		t.Fatalf("unexpected number of quarantined parts; got %d; want 0", n)
+	}
+	if err := p.readAt(r, buf, 0); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Closing the quarantined part must stop background retries
+	atomic.StoreUint32(&r.fail, 1)
+	if err := p.readAt(r, buf, 0); !errors.Is(err, ErrPartQuarantined) {
+		t.Fatalf("unexpected error; got %v; want %v", err, ErrPartQuarantined)
+	}
+	p.stopQuarantine()
+	if n := GetQuarantinedPartsCount(); n != 0 {
+		t.Fatalf("unexpected number of quarantined parts after stopping the quarantine; got %d; want 0", n)
+	}
+}
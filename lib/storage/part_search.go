@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -65,7 +66,7 @@ func (ps *partSearch) Init(p *part, tsids []TSID, tr TimeRange) {
 	ps.reset()
 	ps.p = p
 
-	if p.ph.MinTimestamp <= tr.MaxTimestamp && p.ph.MaxTimestamp >= tr.MinTimestamp {
+	if p.ph.MinTimestamp <= tr.MaxTimestamp && p.ph.MaxTimestamp >= tr.MinTimestamp && !p.isQuarantined() {
 		if isInTest && !sort.SliceIsSorted(tsids, func(i, j int) bool { return tsids[i].Less(&tsids[j]) }) {
 			logger.Panicf("BUG: tsids must be sorted; got %+v", tsids)
 		}
@@ -183,6 +184,11 @@ func (ps *partSearch) nextBHS() bool {
 		if b == nil {
 			// Slow path - actually read and unpack the index block.
 			ib, err := ps.readIndexBlock(mr)
+			if errors.Is(err, ErrPartQuarantined) {
+				// Skip the quarantined part, so the search returns partial results.
+				ps.err = io.EOF
+				return false
+			}
 			if err != nil {
 				ps.err = fmt.Errorf("cannot read index block for part %q at offset %d with size %d: %w",
 					&ps.p.ph, mr.IndexBlockOffset, mr.IndexBlockSize, err)
@@ -236,7 +242,9 @@ func skipSmallMetaindexRows(metaindex []metaindexRow, tsid *TSID) []metaindexRow
 
 func (ps *partSearch) readIndexBlock(mr *metaindexRow) (*indexBlock, error) {
 	ps.compressedIndexBuf = bytesutil.ResizeNoCopyMayOverallocate(ps.compressedIndexBuf, int(mr.IndexBlockSize))
-	ps.p.indexFile.MustReadAt(ps.compressedIndexBuf, int64(mr.IndexBlockOffset))
+	if err := ps.p.readAt(ps.p.indexFile, ps.compressedIndexBuf, int64(mr.IndexBlockOffset)); err != nil {
+		return nil, err
+	}
 
 	var err error
 	ps.indexBuf, err = encoding.DecompressZSTD(ps.indexBuf[:0], ps.compressedIndexBuf)
@@ -163,6 +163,9 @@ type partition struct {
 	stopCh chan struct{}
 
 	wg sync.WaitGroup
+
+	// Use syncwg instead of sync, since Add/Wait may be called from concurrent goroutines.
+	rawRowsPendingFlushesWG syncwg.WaitGroup
 }
 
 // partWrapper is a wrapper for the part.
@@ -246,8 +249,8 @@ func (pt *partition) Drop() {
 	// Wait until all the pending transaction deletions are finished before removing partition directories.
 	pendingTxnDeletionsWG.Wait()
 
-	fs.MustRemoveDirAtomic(pt.smallPartsPath)
-	fs.MustRemoveDirAtomic(pt.bigPartsPath)
+	mustRemovePartitionDir(pt.smallPartsPath)
+	mustRemovePartitionDir(pt.bigPartsPath)
 	logger.Infof("partition %q has been dropped", pt.name)
 }
 
@@ -842,7 +845,7 @@ func (pt *partition) inmemoryPartsFlusher() {
 		case <-pt.stopCh:
 			return
 		case <-ticker.C:
-			pt.flushInmemoryParts(false)
+			pt.flushInmemoryParts(time.Now(), false)
 		}
 	}
 }
@@ -867,34 +870,49 @@ func (pt *partition) flushPendingRows(dst []rawRow, isFinal bool) []rawRow {
 
 func (pt *partition) flushInmemoryRows() {
 	pt.rawRows.flush(pt, nil, true)
-	pt.flushInmemoryParts(true)
+	pt.flushInmemoryParts(time.Now().Add(dataFlushInterval), true)
+}
+
+// mustFlushToDisk flushes all the rows added to pt before the call to disk, so they survive process crash.
+func (pt *partition) mustFlushToDisk() {
+	pt.makePendingRowsVisible()
+	pt.flushInmemoryParts(time.Now().Add(dataFlushInterval), true)
+}
+
+// makePendingRowsVisible makes all the rows added to pt before the call visible to search.
+func (pt *partition) makePendingRowsVisible() {
+	pt.flushPendingRows(nil, true)
+
+	// Wait for background flushers to finish, since they may hold rows, which aren't visible to search yet.
+	pt.rawRowsPendingFlushesWG.Wait()
 }
 
-func (pt *partition) flushInmemoryParts(isFinal bool) {
+// flushInmemoryParts flushes in-memory parts with flushToDiskDeadline not exceeding the given deadline to disk.
+//
+// If isFinal is set, then it waits until parts, which are being merged at the moment, are flushed to disk too.
+func (pt *partition) flushInmemoryParts(deadline time.Time, isFinal bool) {
 	for {
-		currentTime := time.Now()
 		var pws []*partWrapper
+		hasPendingMerges := false
 
 		pt.partsLock.Lock()
 		for _, pw := range pt.inmemoryParts {
-			if !pw.isInMerge && (isFinal || pw.flushToDiskDeadline.Before(currentTime)) {
-				pw.isInMerge = true
-				pws = append(pws, pw)
+			if pw.flushToDiskDeadline.After(deadline) {
+				continue
+			}
+			if pw.isInMerge {
+				hasPendingMerges = true
+				continue
 			}
+			pw.isInMerge = true
+			pws = append(pws, pw)
 		}
 		pt.partsLock.Unlock()
 
-		if err := pt.mergePartsOptimal(pws, nil); err != nil {
+		if err := pt.mergePartsOptimal(pws, nil, nil); err != nil {
 			logger.Panicf("FATAL: cannot merge in-memory parts: %s", err)
 		}
-		if !isFinal {
-			return
-		}
-		pt.partsLock.Lock()
-		n := len(pt.inmemoryParts)
-		pt.partsLock.Unlock()
-		if n == 0 {
-			// All the in-memory parts were flushed to disk.
+		if !isFinal || !hasPendingMerges {
 			return
 		}
 		// Some parts weren't flushed to disk because they were being merged.
@@ -904,6 +922,9 @@ func (pt *partition) flushInmemoryParts(isFinal bool) {
 }
 
 func (rrss *rawRowsShards) flush(pt *partition, dst []rawRow, isFinal bool) []rawRow {
+	pt.rawRowsPendingFlushesWG.Add(1)
+	defer pt.rawRowsPendingFlushesWG.Done()
+
 	for i := range rrss.shards {
 		dst = rrss.shards[i].appendRawRowsToFlush(dst, pt, isFinal)
 	}
@@ -931,7 +952,7 @@ func (rrs *rawRowsShard) appendRawRowsToFlush(dst []rawRow, pt *partition, isFin
 	return dst
 }
 
-func (pt *partition) mergePartsOptimal(pws []*partWrapper, stopCh <-chan struct{}) error {
+func (pt *partition) mergePartsOptimal(pws []*partWrapper, stopCh <-chan struct{}, fmj *ForceMergeJob) error {
 	sortPartsForOptimalMerge(pws)
 	for len(pws) > 0 {
 		n := defaultPartsToMerge
@@ -940,8 +961,10 @@ func (pt *partition) mergePartsOptimal(pws []*partWrapper, stopCh <-chan struct{
 		}
 		pwsChunk := pws[:n]
 		pws = pws[n:]
+		chunkSize := getPartsSize(pwsChunk)
 		err := pt.mergeParts(pwsChunk, stopCh, true)
 		if err == nil {
+			fmj.addMergedParts(n, chunkSize)
 			continue
 		}
 		pt.releasePartsToMerge(pws)
@@ -955,6 +978,11 @@ func (pt *partition) mergePartsOptimal(pws []*partWrapper, stopCh <-chan struct{
 
 // ForceMergeAllParts runs merge for all the parts in pt.
 func (pt *partition) ForceMergeAllParts() error {
+	return pt.forceMergeAllParts(nil)
+}
+
+// forceMergeAllParts runs merge for all the parts in pt and reports the progress to fmj if it isn't nil.
+func (pt *partition) forceMergeAllParts(fmj *ForceMergeJob) error {
 	pws := pt.getAllPartsForMerge()
 	if len(pws) == 0 {
 		// Nothing to merge.
@@ -974,7 +1002,7 @@ func (pt *partition) ForceMergeAllParts() error {
 		// If len(pws) == 1, then the merge must run anyway.
 		// This allows applying the configured retention, removing the deleted series
 		// and performing de-duplication if needed.
-		if err := pt.mergePartsOptimal(pws, pt.stopCh); err != nil {
+		if err := pt.mergePartsOptimal(pws, pt.stopCh, fmj); err != nil {
 			return fmt.Errorf("cannot force merge %d parts from partition %q: %w", len(pws), pt.name, err)
 		}
 		pws = pt.getAllPartsForMerge()
@@ -1202,16 +1230,28 @@ func (pt *partition) mergeExistingParts(isFinal bool) error {
 		return errReadOnlyMode
 	}
 	maxOutBytes := pt.getMaxBigPartSize()
+	isThrottled := pt.s.isSnapshotMergeThrottled()
+	if isThrottled {
+		// Merge only small parts while too old snapshot exists, since the snapshot holds hard links to the source parts.
+		// Merging big parts would double disk space usage for them until the snapshot is deleted.
+		if n := pt.getMaxSmallPartSize(); n < maxOutBytes {
+			maxOutBytes = n
+		}
+		isFinal = false
+	}
 
 	pt.partsLock.Lock()
 	dst := make([]*partWrapper, 0, len(pt.inmemoryParts)+len(pt.smallParts)+len(pt.bigParts))
 	dst = append(dst, pt.inmemoryParts...)
 	dst = append(dst, pt.smallParts...)
-	dst = append(dst, pt.bigParts...)
+	if !isThrottled {
+		dst = append(dst, pt.bigParts...)
+	}
 	pws, needFreeSpace := getPartsToMerge(dst, maxOutBytes, isFinal)
 	pt.partsLock.Unlock()
 
-	atomicSetBool(&pt.mergeNeedFreeDiskSpace, needFreeSpace)
+	// Throttled merges may skip parts because of the reduced maxOutBytes. This doesn't mean there is no enough free disk space.
+	atomicSetBool(&pt.mergeNeedFreeDiskSpace, needFreeSpace && !isThrottled)
 	return pt.mergeParts(pws, pt.stopCh, isFinal)
 }
 
@@ -1502,7 +1542,7 @@ func (pt *partition) mergePartsInternal(tmpPartPath string, bsw *blockStreamWrit
 	default:
 		logger.Panicf("BUG: unknown partType=%d", dstPartType)
 	}
-	retentionDeadline := timestampFromTime(time.Now()) - pt.s.retentionMsecs
+	retentionDeadline := timestampFromTime(time.Now()) - pt.s.getRetentionMsecs()
 	atomic.AddUint64(activeMerges, 1)
 	err := mergeBlockStreams(&ph, bsw, bsrs, stopCh, pt.s, retentionDeadline, rowsMerged, rowsDeleted)
 	atomic.AddUint64(activeMerges, ^uint64(0))
@@ -1687,23 +1727,23 @@ func (pt *partition) stalePartsRemover() {
 func (pt *partition) removeStaleParts() {
 	m := make(map[*partWrapper]bool)
 	startTime := time.Now()
-	retentionDeadline := timestampFromTime(startTime) - pt.s.retentionMsecs
+	retentionDeadline := timestampFromTime(startTime) - pt.s.getRetentionMsecs()
 
 	pt.partsLock.Lock()
 	for _, pw := range pt.inmemoryParts {
-		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline {
+		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline && !pt.s.isHeldTimeRange(pw.p.ph.getTimeRange()) {
 			atomic.AddUint64(&pt.inmemoryRowsDeleted, pw.p.ph.RowsCount)
 			m[pw] = true
 		}
 	}
 	for _, pw := range pt.smallParts {
-		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline {
+		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline && !pt.s.isHeldTimeRange(pw.p.ph.getTimeRange()) {
 			atomic.AddUint64(&pt.smallRowsDeleted, pw.p.ph.RowsCount)
 			m[pw] = true
 		}
 	}
 	for _, pw := range pt.bigParts {
-		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline {
+		if !pw.isInMerge && pw.p.ph.MaxTimestamp < retentionDeadline && !pt.s.isHeldTimeRange(pw.p.ph.getTimeRange()) {
 			atomic.AddUint64(&pt.bigRowsDeleted, pw.p.ph.RowsCount)
 			m[pw] = true
 		}
@@ -1728,7 +1768,7 @@ func (pt *partition) removeStaleParts() {
 	pt.snapshotLock.RLock()
 	for pw := range m {
 		if pw.mp == nil {
-			logger.Infof("removing part %q, since its data is out of the configured retention (%d secs)", pw.p.path, pt.s.retentionMsecs/1000)
+			logger.Infof("removing part %q, since its data is out of the configured retention (%d secs)", pw.p.path, pt.s.getRetentionMsecs()/1000)
 			fs.MustRemoveDirAtomic(pw.p.path)
 		}
 	}
@@ -1750,7 +1790,8 @@ func (pt *partition) removeStaleParts() {
 func getPartsToMerge(pws []*partWrapper, maxOutBytes uint64, isFinal bool) ([]*partWrapper, bool) {
 	pwsRemaining := make([]*partWrapper, 0, len(pws))
 	for _, pw := range pws {
-		if !pw.isInMerge {
+		// Quarantined parts cannot be merged until their data can be read again.
+		if !pw.isInMerge && !pw.p.isQuarantined() {
 			pwsRemaining = append(pwsRemaining, pw)
 		}
 	}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// RetentionReport contains information about the retention and about partitions, which are going to be deleted by retention.
+type RetentionReport struct {
+	// RetentionMsecs is the current retention in milliseconds.
+	RetentionMsecs int64
+
+	// BaseRetentionMsecs is the retention passed to OpenStorage.
+	//
+	// It is smaller than RetentionMsecs if the retention has been extended via Storage.ExtendRetention.
+	BaseRetentionMsecs int64
+
+	// NextRetentionCycle is unix timestamp in milliseconds for the next indexdb rotation.
+	NextRetentionCycle int64
+
+	// Partitions contains information about monthly partitions ordered by time.
+	Partitions []PartitionRetention
+}
+
+// PartitionRetention contains retention information for a monthly partition.
+type PartitionRetention struct {
+	// Name is the partition name in the form YYYY_MM.
+	Name string
+
+	// MinTimestamp and MaxTimestamp is the time range for the partition in milliseconds.
+	MinTimestamp int64
+	MaxTimestamp int64
+
+	// DeleteAt is unix timestamp in milliseconds when the partition is deleted by retention.
+	//
+	// The partition is deleted within a minute after DeleteAt.
+	DeleteAt int64
+
+	// Held is set if the partition cannot be deleted because of active holds.
+	Held bool
+
+	// DeletedAtNextCycle is set if the partition is deleted until the next retention cycle.
+	DeletedAtNextCycle bool
+}
+
+// retentionState is persisted at metadata/retention when the retention is extended via ExtendRetention.
+type retentionState struct {
+	// RetentionMsecs is the extended retention.
+	RetentionMsecs int64 `json:"retentionMsecs"`
+
+	// BaseRetentionMsecs is the retention passed to OpenStorage when the retention has been extended.
+	//
+	// The extended retention is ignored after the restart with another retention.
+	BaseRetentionMsecs int64 `json:"baseRetentionMsecs"`
+
+	// MinIndexDBRotationTimestamp is the minimum unix timestamp in milliseconds for the next indexdb rotation.
+	MinIndexDBRotationTimestamp int64 `json:"minIndexDBRotationTimestamp"`
+}
+
+func (s *Storage) getRetentionMsecs() int64 {
+	return atomic.LoadInt64(&s.retentionMsecs)
+}
+
+// RetentionMsecs returns the current retention for s in milliseconds.
+func (s *Storage) RetentionMsecs() int64 {
+	return s.getRetentionMsecs()
+}
+
+// ExtendRetention extends the retention for s to retentionMsecs without restart.
+//
+// Partitions and indexdb, which aren't deleted yet, are preserved according to the extended retention.
+// The extended retention is persisted, so it remains active after the restart with the same retention passed to OpenStorage.
+// The retention cannot be reduced via ExtendRetention, since this may result in unexpected data loss.
+func (s *Storage) ExtendRetention(retentionMsecs int64) error {
+	s.retentionLock.Lock()
+	defer s.retentionLock.Unlock()
+
+	retentionMsecsCurr := s.getRetentionMsecs()
+	if retentionMsecs <= retentionMsecsCurr {
+		return fmt.Errorf("the new retention %s must exceed the current retention %s; the retention can be reduced only by restarting with smaller -retentionPeriod",
+			retentionDurationString(retentionMsecs), retentionDurationString(retentionMsecsCurr))
+	}
+	if retentionMsecs > maxRetentionMsecs {
+		return fmt.Errorf("the new retention %s cannot exceed %s", retentionDurationString(retentionMsecs), retentionDurationString(maxRetentionMsecs))
+	}
+
+	// The current indexdb was created at the previous retention cycle for the current retention.
+	// It becomes the previous indexdb at the next rotation, so the rotation after that must be performed
+	// only after its contents go out of the extended retention. Postpone the next rotation accordingly.
+	now := time.Now().UnixNano() / 1e6
+	currIndexDBCreated := nextRetentionDeadline(now, retentionMsecsCurr) - roundRetentionToDays(retentionMsecsCurr)
+	minRotationTimestamp := currIndexDBCreated + roundRetentionToDays(retentionMsecs)
+	if ts := atomic.LoadInt64(&s.minIndexDBRotationTimestamp); ts > minRotationTimestamp {
+		minRotationTimestamp = ts
+	}
+
+	rs := &retentionState{
+		RetentionMsecs:              retentionMsecs,
+		BaseRetentionMsecs:          s.baseRetentionMsecs,
+		MinIndexDBRotationTimestamp: minRotationTimestamp,
+	}
+	if err := rs.store(s.retentionStatePath()); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&s.minIndexDBRotationTimestamp, minRotationTimestamp)
+	atomic.StoreInt64(&s.retentionMsecs, retentionMsecs)
+	select {
+	case s.retentionUpdatedCh <- struct{}{}:
+	default:
+	}
+	logger.Infof("extended retention at %q from %s to %s", s.path, retentionDurationString(retentionMsecsCurr), retentionDurationString(retentionMsecs))
+	return nil
+}
+
+// GetRetentionReport returns retention report for s.
+func (s *Storage) GetRetentionReport() *RetentionReport {
+	retentionMsecs := s.getRetentionMsecs()
+	nextCycle := s.nextIndexDBRotationTimestamp()
+	rr := &RetentionReport{
+		RetentionMsecs:     retentionMsecs,
+		BaseRetentionMsecs: s.baseRetentionMsecs,
+		NextRetentionCycle: nextCycle,
+	}
+	ptws := s.tb.GetPartitions(nil)
+	for _, ptw := range ptws {
+		pt := ptw.pt
+		deleteAt := pt.tr.MaxTimestamp + retentionMsecs + 1
+		held := s.isHeldTimeRange(pt.tr)
+		rr.Partitions = append(rr.Partitions, PartitionRetention{
+			Name:               pt.name,
+			MinTimestamp:       pt.tr.MinTimestamp,
+			MaxTimestamp:       pt.tr.MaxTimestamp,
+			DeleteAt:           deleteAt,
+			Held:               held,
+			DeletedAtNextCycle: !held && deleteAt <= nextCycle,
+		})
+	}
+	s.tb.PutPartitions(ptws)
+	sort.Slice(rr.Partitions, func(i, j int) bool {
+		return rr.Partitions[i].MinTimestamp < rr.Partitions[j].MinTimestamp
+	})
+	return rr
+}
+
+// nextIndexDBRotationTimestamp returns unix timestamp in milliseconds for the next indexdb rotation.
+func (s *Storage) nextIndexDBRotationTimestamp() int64 {
+	t := time.Now().UnixNano() / 1e6
+	if minTimestamp := atomic.LoadInt64(&s.minIndexDBRotationTimestamp); minTimestamp > t {
+		t = minTimestamp
+	}
+	return nextRetentionDeadline(t, s.getRetentionMsecs())
+}
+
+func (s *Storage) nextIndexDBRotationDuration() time.Duration {
+	d := s.nextIndexDBRotationTimestamp() - time.Now().UnixNano()/1e6
+	return time.Duration(d) * time.Millisecond
+}
+
+func (s *Storage) retentionStatePath() string {
+	return s.path + "/metadata/retention"
+}
+
+// mustLoadRetention loads the retention extended via ExtendRetention from metadataDir.
+func (s *Storage) mustLoadRetention(metadataDir string) {
+	path := metadataDir + "/retention"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Panicf("FATAL: cannot read %q: %s", path, err)
+		}
+		return
+	}
+	var rs retentionState
+	if err := json.Unmarshal(data, &rs); err != nil {
+		logger.Panicf("FATAL: cannot parse %q: %s", path, err)
+	}
+	if rs.BaseRetentionMsecs != s.baseRetentionMsecs || rs.RetentionMsecs <= s.baseRetentionMsecs {
+		logger.Infof("ignoring the retention %s extended at %q, since the storage is opened with another retention %s",
+			retentionDurationString(rs.RetentionMsecs), s.path, retentionDurationString(s.baseRetentionMsecs))
+		fs.MustRemoveAll(path)
+		return
+	}
+	logger.Infof("using the retention %s extended at %q instead of %s", retentionDurationString(rs.RetentionMsecs), s.path, retentionDurationString(s.baseRetentionMsecs))
+	s.retentionMsecs = rs.RetentionMsecs
+	s.minIndexDBRotationTimestamp = rs.MinIndexDBRotationTimestamp
+}
+
+func (rs *retentionState) store(path string) error {
+	data, err := json.Marshal(rs)
+	if err != nil {
+		logger.Panicf("BUG: cannot marshal retention state: %s", err)
+	}
+	if err := fs.WriteFileAtomically(path, data, true); err != nil {
+		return fmt.Errorf("cannot store retention state: %w", err)
+	}
+	return nil
+}
+
+func roundRetentionToDays(retentionMsecs int64) int64 {
+	return ((retentionMsecs + msecPerDay - 1) / msecPerDay) * msecPerDay
+}
+
+func retentionDurationString(retentionMsecs int64) string {
+	d := time.Duration(retentionMsecs) * time.Millisecond
+	if retentionMsecs%msecPerDay == 0 {
+		return fmt.Sprintf("%dd", retentionMsecs/msecPerDay)
+	}
+	return d.String()
+}
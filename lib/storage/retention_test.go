@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestStorageExtendRetention(t *testing.T) {
+	path := "TestStorageExtendRetention"
+	retentionMsecs := int64(30 * msecPerDay)
+	s, err := OpenStorage(path, retentionMsecs, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+
+	// Add rows to the current partition and to the partition for the previous month.
+	now := timestampFromTime(time.Now())
+	var mn MetricName
+	mn.MetricGroup = []byte("foo")
+	mrs := []MetricRow{
+		{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     now,
+			Value:         1,
+		},
+		{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     now - 25*msecPerDay,
+			Value:         2,
+		},
+	}
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("cannot add rows: %s", err)
+	}
+	s.DebugFlush()
+
+	checkReport := func(retentionMsecsExpected int64) {
+		t.Helper()
+		rr := s.GetRetentionReport()
+		if rr.RetentionMsecs != retentionMsecsExpected {
+			t.Fatalf("unexpected retention; got %d; want %d", rr.RetentionMsecs, retentionMsecsExpected)
+		}
+		if rr.BaseRetentionMsecs != retentionMsecs {
+			t.Fatalf("unexpected base retention; got %d; want %d", rr.BaseRetentionMsecs, retentionMsecs)
+		}
+		if rr.NextRetentionCycle <= now {
+			t.Fatalf("next retention cycle must be in the future; got %d; now %d", rr.NextRetentionCycle, now)
+		}
+		if len(rr.Partitions) == 0 {
+			t.Fatalf("expecting non-empty partitions")
+		}
+		for i, pr := range rr.Partitions {
+			if i > 0 && pr.MinTimestamp <= rr.Partitions[i-1].MinTimestamp {
+				t.Fatalf("partitions must be sorted by time; got %q after %q", pr.Name, rr.Partitions[i-1].Name)
+			}
+			if pr.DeleteAt != pr.MaxTimestamp+retentionMsecsExpected+1 {
+				t.Fatalf("unexpected deletion time for partition %q; got %d; want %d", pr.Name, pr.DeleteAt, pr.MaxTimestamp+retentionMsecsExpected+1)
+			}
+			if pr.DeletedAtNextCycle != (pr.DeleteAt <= rr.NextRetentionCycle) {
+				t.Fatalf("unexpected DeletedAtNextCycle for partition %q", pr.Name)
+			}
+		}
+	}
+	checkReport(retentionMsecs)
+
+	// The retention cannot be reduced.
+	if err := s.ExtendRetention(retentionMsecs); err == nil {
+		t.Fatalf("expecting non-nil error when extending retention to the same value")
+	}
+	if err := s.ExtendRetention(20 * msecPerDay); err == nil {
+		t.Fatalf("expecting non-nil error when reducing retention")
+	}
+	if err := s.ExtendRetention(2 * maxRetentionMsecs); err == nil {
+		t.Fatalf("expecting non-nil error when extending retention beyond the maximum")
+	}
+
+	// Extend the retention.
+	nextCycle := s.nextIndexDBRotationTimestamp()
+	retentionMsecsExtended := int64(90 * msecPerDay)
+	if err := s.ExtendRetention(retentionMsecsExtended); err != nil {
+		t.Fatalf("cannot extend retention: %s", err)
+	}
+	if n := s.RetentionMsecs(); n != retentionMsecsExtended {
+		t.Fatalf("unexpected retention after the extension; got %d; want %d", n, retentionMsecsExtended)
+	}
+	checkReport(retentionMsecsExtended)
+
+	// The previous indexdb mustn't be dropped until its contents go out of the extended retention.
+	minNextCycle := nextCycle - retentionMsecs + retentionMsecsExtended
+	if ts := s.nextIndexDBRotationTimestamp(); ts < minNextCycle {
+		t.Fatalf("too early indexdb rotation after retention extension; got %d; want at least %d", ts, minNextCycle)
+	}
+	s.MustClose()
+
+	// The extended retention must survive the restart with the same retention.
+	s, err = OpenStorage(path, retentionMsecs, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	if n := s.RetentionMsecs(); n != retentionMsecsExtended {
+		t.Fatalf("unexpected retention after the restart; got %d; want %d", n, retentionMsecsExtended)
+	}
+	s.MustClose()
+
+	// The extended retention must be ignored after the restart with another retention.
+	retentionMsecsNew := int64(60 * msecPerDay)
+	s, err = OpenStorage(path, retentionMsecsNew, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	if n := s.RetentionMsecs(); n != retentionMsecsNew {
+		t.Fatalf("unexpected retention after the restart with another retention; got %d; want %d", n, retentionMsecsNew)
+	}
+	if fs.IsPathExist(s.retentionStatePath()) {
+		t.Fatalf("the extended retention state must be removed after the restart with another retention")
+	}
+	s.MustClose()
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
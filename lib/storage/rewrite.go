@@ -0,0 +1,212 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+)
+
+// SeriesRewriter must return the rewritten metric name for mn.
+//
+// It must return nil if the series must be dropped. It may modify and return mn.
+type SeriesRewriter func(mn *MetricName) *MetricName
+
+// RewriteStats contains stats for RewriteSeries call.
+type RewriteStats struct {
+	// SeriesMatched is the number of series matching the filters.
+	SeriesMatched int
+
+	// SeriesHeld is the number of matching series, which are left untouched because of active holds. See AddHold.
+	SeriesHeld int
+
+	// SeriesRewritten is the number of series with changed metric names.
+	SeriesRewritten int
+
+	// SeriesDropped is the number of dropped series.
+	SeriesDropped int
+
+	// SamplesRewritten is the number of samples copied to the rewritten series.
+	SamplesRewritten uint64
+}
+
+// rewriteBatchSize is the number of rows to add to the storage in a single batch during RewriteSeries.
+const rewriteBatchSize = 10000
+
+// rewriteProgressInterval is the interval for logging RewriteSeries progress.
+const rewriteProgressInterval = 10 * time.Second
+
+// RewriteSeries rewrites metric names for all the series matching tfss with rewriter.
+//
+// Samples for series with changed metric names are copied to new series, while the original series are deleted
+// after all the samples are copied, so the original series remain untouched if RewriteSeries fails in the middle.
+// Series, which are dropped by rewriter, are deleted. Series matching active holds are left untouched.
+//
+// If dryRun is set, then only the stats are returned without modifying the storage.
+//
+// RewriteSeries is intended for offline maintenance, when no data is ingested into the storage.
+func (s *Storage) RewriteSeries(qt *querytracer.Tracer, tfss []*TagFilters, rewriter SeriesRewriter, dryRun bool) (*RewriteStats, error) {
+	qt = qt.NewChild("rewrite series for %s, dryRun=%v", tfss, dryRun)
+	defer qt.Done()
+
+	s.makePendingRowsVisibleIfNeeded()
+	s.updateHeldMetricIDs()
+	tr := TimeRange{
+		MinTimestamp: 0,
+		MaxTimestamp: (1 << 63) - 1,
+	}
+	metricIDs, err := s.idb().searchMetricIDs(qt, tfss, tr, 2e9, noDeadline)
+	if err != nil {
+		return nil, fmt.Errorf("cannot search series: %w", err)
+	}
+	var rs RewriteStats
+	rs.SeriesMatched = len(metricIDs)
+	metricIDs = s.removeHeldMetricIDs(metricIDs)
+	rs.SeriesHeld = rs.SeriesMatched - len(metricIDs)
+
+	// Build the rewrite plan before modifying the storage, so invalid rewrites are detected early.
+	// The plan maps metricID of the changed series to the raw metric name of the rewritten series.
+	// Nil raw metric name means the series must be dropped.
+	plan := make(map[uint64][]byte)
+	targets := make(map[string]string)
+	sources := make(map[string]struct{})
+	idb := s.idb()
+	var metricName []byte
+	var mn MetricName
+	for _, metricID := range metricIDs {
+		metricName, err = idb.searchMetricNameWithCache(metricName[:0], metricID)
+		if err != nil {
+			if err == io.EOF {
+				// Skip missing metricName for metricID. See indexDB.searchMetricName for details.
+				continue
+			}
+			return nil, fmt.Errorf("cannot find metric name for metricID=%d: %w", metricID, err)
+		}
+		if err := mn.Unmarshal(metricName); err != nil {
+			return nil, fmt.Errorf("cannot unmarshal metric name for metricID=%d: %w", metricID, err)
+		}
+		mnNew := rewriter(&mn)
+		if mnNew == nil {
+			plan[metricID] = nil
+			sources[string(metricName)] = struct{}{}
+			rs.SeriesDropped++
+			continue
+		}
+		if len(mnNew.MetricGroup) == 0 && len(mnNew.Tags) == 0 {
+			return nil, fmt.Errorf("the series %s is rewritten to a series without labels", metricName)
+		}
+		mnNew.sortTags()
+		target := string(mnNew.Marshal(nil))
+		if target == string(metricName) {
+			continue
+		}
+		plan[metricID] = mnNew.marshalRaw(nil)
+		sources[string(metricName)] = struct{}{}
+		targets[target] = string(metricName)
+		rs.SeriesRewritten++
+	}
+	for target, source := range targets {
+		if _, ok := sources[target]; ok {
+			// The samples copied to the target series would be deleted together with the source series.
+			return nil, fmt.Errorf("the series %s is rewritten to the series %s, which is rewritten or dropped itself; "+
+				"such series must be rewritten in separate runs", source, target)
+		}
+	}
+	qt.Printf("series matched: %d, held: %d, rewritten: %d, dropped: %d", rs.SeriesMatched, rs.SeriesHeld, rs.SeriesRewritten, rs.SeriesDropped)
+	if dryRun || len(plan) == 0 {
+		return &rs, nil
+	}
+
+	if rs.SeriesRewritten > 0 {
+		n, err := s.copyRewrittenSeries(qt, tfss, tr, plan)
+		rs.SamplesRewritten = n
+		if err != nil {
+			return &rs, err
+		}
+	}
+
+	// Delete the original series at once after all the samples are copied to the rewritten series.
+	deletedMetricIDs := make([]uint64, 0, len(plan))
+	for metricID := range plan {
+		deletedMetricIDs = append(deletedMetricIDs, metricID)
+	}
+	idb.deleteMetricIDs(deletedMetricIDs)
+	qt.Printf("deleted %d original series", len(deletedMetricIDs))
+	return &rs, nil
+}
+
+// copyRewrittenSeries copies samples for series from plan to the rewritten series.
+//
+// It returns the number of copied samples.
+func (s *Storage) copyRewrittenSeries(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, plan map[uint64][]byte) (uint64, error) {
+	var sr Search
+	sr.Init(qt, s, tfss, tr, 2e9, noDeadline)
+	defer sr.MustClose()
+
+	seriesTotal := 0
+	for _, metricNameRaw := range plan {
+		if metricNameRaw != nil {
+			seriesTotal++
+		}
+	}
+	var samples uint64
+	seriesProcessed := 0
+	prevMetricID := uint64(0)
+	lastLogTime := time.Now()
+	var b Block
+	var timestamps []int64
+	var values []float64
+	mrs := make([]MetricRow, 0, rewriteBatchSize)
+	for sr.NextMetricBlock() {
+		br := sr.MetricBlockRef.BlockRef
+		metricID := br.bh.TSID.MetricID
+		metricNameRaw := plan[metricID]
+		if metricNameRaw == nil {
+			continue
+		}
+		if metricID != prevMetricID {
+			prevMetricID = metricID
+			seriesProcessed++
+			if time.Since(lastLogTime) > rewriteProgressInterval {
+				logger.Infof("rewritten %d out of %d series; copied %d samples", seriesProcessed, seriesTotal, samples)
+				lastLogTime = time.Now()
+			}
+		}
+		if err := br.ReadBlock(&b); err != nil {
+			return samples, fmt.Errorf("cannot read block for metricID=%d: %w", metricID, err)
+		}
+		if err := b.UnmarshalData(); err != nil {
+			return samples, fmt.Errorf("cannot unmarshal block for metricID=%d: %w", metricID, err)
+		}
+		timestamps, values = b.AppendRowsWithTimeRangeFilter(timestamps[:0], values[:0], tr)
+		for i, ts := range timestamps {
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     ts,
+				Value:         values[i],
+			})
+			if len(mrs) >= rewriteBatchSize {
+				if err := s.AddRows(mrs, 64); err != nil {
+					return samples, fmt.Errorf("cannot add rewritten samples: %w", err)
+				}
+				samples += uint64(len(mrs))
+				mrs = mrs[:0]
+			}
+		}
+	}
+	if err := sr.Error(); err != nil {
+		return samples, fmt.Errorf("cannot read series: %w", err)
+	}
+	if len(mrs) > 0 {
+		if err := s.AddRows(mrs, 64); err != nil {
+			return samples, fmt.Errorf("cannot add rewritten samples: %w", err)
+		}
+		samples += uint64(len(mrs))
+	}
+	// Make sure the copied samples are persisted before deleting the original series.
+	s.DebugFlush()
+	qt.Printf("copied %d samples to %d rewritten series", samples, seriesProcessed)
+	return samples, nil
+}
@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestStorageRewriteSeries(t *testing.T) {
+	path := "TestStorageRewriteSeries"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+
+	const rowsPerSeries = 100
+	timestamp := time.Now().UnixMilli() - rowsPerSeries*1000
+	addSeries := func(metricGroup string, tags ...string) {
+		t.Helper()
+		var mn MetricName
+		mn.MetricGroup = []byte(metricGroup)
+		for i := 0; i < len(tags); i += 2 {
+			mn.AddTag(tags[i], tags[i+1])
+		}
+		metricNameRaw := mn.marshalRaw(nil)
+		var mrs []MetricRow
+		for i := 0; i < rowsPerSeries; i++ {
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: metricNameRaw,
+				Timestamp:     timestamp + int64(i)*1000,
+				Value:         float64(i),
+			})
+		}
+		if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+			t.Fatalf("cannot add rows: %s", err)
+		}
+	}
+	addSeries("metric", "job", "a", "typo_label", "x")
+	addSeries("metric", "job", "b")
+	addSeries("dropme", "job", "c")
+	s.DebugFlush()
+
+	tfs := NewTagFilters()
+	if err := tfs.Add(nil, []byte(".+"), false, true); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	tfss := []*TagFilters{tfs}
+	getMetricNames := func() []string {
+		t.Helper()
+		metricNames, err := s.SearchMetricNames(nil, tfss, TimeRange{MinTimestamp: 0, MaxTimestamp: (1 << 63) - 1}, 1e5, noDeadline)
+		if err != nil {
+			t.Fatalf("cannot search metric names: %s", err)
+		}
+		var result []string
+		for _, metricName := range metricNames {
+			var mn MetricName
+			if err := mn.UnmarshalString(metricName); err != nil {
+				t.Fatalf("cannot unmarshal metric name: %s", err)
+			}
+			result = append(result, mn.String())
+		}
+		sort.Strings(result)
+		return result
+	}
+	checkMetricNames := func(metricNamesExpected []string) {
+		t.Helper()
+		metricNames := getMetricNames()
+		if !reflect.DeepEqual(metricNames, metricNamesExpected) {
+			t.Fatalf("unexpected metric names;\ngot\n%q\nwant\n%q", metricNames, metricNamesExpected)
+		}
+	}
+	rewriter := func(mn *MetricName) *MetricName {
+		if string(mn.MetricGroup) == "dropme" {
+			return nil
+		}
+		if v := mn.GetTagValue("typo_label"); v != nil {
+			v = append([]byte{}, v...)
+			mn.RemoveTag("typo_label")
+			mn.AddTagBytes([]byte("fixed_label"), v)
+		}
+		return mn
+	}
+	metricNamesOrig := []string{
+		`dropme{job="c"}`,
+		`metric{job="a",typo_label="x"}`,
+		`metric{job="b"}`,
+	}
+	checkMetricNames(metricNamesOrig)
+
+	// dry run mustn't change series
+	rs, err := s.RewriteSeries(nil, tfss, rewriter, true)
+	if err != nil {
+		t.Fatalf("unexpected error in dry run: %s", err)
+	}
+	if rs.SeriesMatched != 3 || rs.SeriesRewritten != 1 || rs.SeriesDropped != 1 || rs.SamplesRewritten != 0 {
+		t.Fatalf("unexpected stats for dry run: %+v", rs)
+	}
+	checkMetricNames(metricNamesOrig)
+
+	// chained rewrites must be rejected
+	_, err = s.RewriteSeries(nil, tfss, func(mn *MetricName) *MetricName {
+		switch string(mn.GetTagValue("job")) {
+		case "a":
+			mn.RemoveTag("typo_label")
+			mn.RemoveTag("job")
+			mn.AddTag("job", "b")
+		case "b":
+			mn.RemoveTag("job")
+			mn.AddTag("job", "c")
+		}
+		return mn
+	}, false)
+	if err == nil {
+		t.Fatalf("expecting non-nil error for chained rewrites")
+	}
+	checkMetricNames(metricNamesOrig)
+
+	rs, err = s.RewriteSeries(nil, tfss, rewriter, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rs.SeriesMatched != 3 || rs.SeriesRewritten != 1 || rs.SeriesDropped != 1 || rs.SamplesRewritten != rowsPerSeries {
+		t.Fatalf("unexpected stats: %+v", rs)
+	}
+	s.DebugFlush()
+	checkMetricNames([]string{
+		`metric{job="a",fixed_label="x"}`,
+		`metric{job="b"}`,
+	})
+
+	// Verify the samples are copied to the rewritten series
+	tfsFixed := NewTagFilters()
+	if err := tfsFixed.Add([]byte("fixed_label"), []byte("x"), false, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	var sr Search
+	sr.Init(nil, s, []*TagFilters{tfsFixed}, TimeRange{MinTimestamp: 0, MaxTimestamp: (1 << 63) - 1}, 1e5, noDeadline)
+	rowsCount := 0
+	for sr.NextMetricBlock() {
+		rowsCount += sr.MetricBlockRef.BlockRef.RowsCount()
+	}
+	if err := sr.Error(); err != nil {
+		t.Fatalf("unexpected error in search: %s", err)
+	}
+	sr.MustClose()
+	if rowsCount != rowsPerSeries {
+		t.Fatalf("unexpected number of rows in the rewritten series; got %d; want %d", rowsCount, rowsPerSeries)
+	}
+
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
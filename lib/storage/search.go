@@ -54,6 +54,11 @@ func (br *BlockRef) RowsCount() int {
 	return int(br.bh.RowsCount)
 }
 
+// SizeBytes returns the approximate size in bytes occupied by br on disk.
+func (br *BlockRef) SizeBytes() int {
+	return int(br.bh.TimestampsBlockSize) + int(br.bh.ValuesBlockSize) + marshaledBlockHeaderSize
+}
+
 // PartRef returns PartRef from br.
 func (br *BlockRef) PartRef() PartRef {
 	return PartRef{
@@ -68,14 +73,29 @@ type PartRef struct {
 
 // MustReadBlock reads block from br to dst.
 func (br *BlockRef) MustReadBlock(dst *Block) {
+	if err := br.ReadBlock(dst); err != nil {
+		logger.Panicf("FATAL: cannot read block from part %q: %s", br.p, err)
+	}
+}
+
+// ReadBlock reads block from br to dst.
+//
+// It returns an error wrapping ErrPartQuarantined if the block cannot be read, since the part
+// containing the block is quarantined because of read error. See SetReadErrorQuarantine.
+func (br *BlockRef) ReadBlock(dst *Block) error {
 	dst.Reset()
 	dst.bh = br.bh
 
 	dst.timestampsData = bytesutil.ResizeNoCopyMayOverallocate(dst.timestampsData, int(br.bh.TimestampsBlockSize))
-	br.p.timestampsFile.MustReadAt(dst.timestampsData, int64(br.bh.TimestampsBlockOffset))
+	if err := br.p.readAt(br.p.timestampsFile, dst.timestampsData, int64(br.bh.TimestampsBlockOffset)); err != nil {
+		return err
+	}
 
 	dst.valuesData = bytesutil.ResizeNoCopyMayOverallocate(dst.valuesData, int(br.bh.ValuesBlockSize))
-	br.p.valuesFile.MustReadAt(dst.valuesData, int64(br.bh.ValuesBlockOffset))
+	if err := br.p.readAt(br.p.valuesFile, dst.valuesData, int64(br.bh.ValuesBlockOffset)); err != nil {
+		return err
+	}
+	return nil
 }
 
 // MetricBlockRef contains reference to time series block for a single metric.
@@ -98,6 +118,9 @@ type Search struct {
 	// retentionDeadline is used for filtering out blocks outside the configured retention.
 	retentionDeadline int64
 
+	// holdDeadlines contains retention deadlines for held metricIDs.
+	holdDeadlines map[uint64]int64
+
 	ts tableSearch
 
 	// tr contains time range used in the search.
@@ -124,6 +147,7 @@ func (s *Search) reset() {
 
 	s.idb = nil
 	s.retentionDeadline = 0
+	s.holdDeadlines = nil
 	s.ts.reset()
 	s.tr = TimeRange{}
 	s.tfss = nil
@@ -145,11 +169,13 @@ func (s *Search) Init(qt *querytracer.Tracer, storage *Storage, tfss []*TagFilte
 	if s.needClosing {
 		logger.Panicf("BUG: missing MustClose call before the next call to Init")
 	}
-	retentionDeadline := int64(fasttime.UnixTimestamp()*1e3) - storage.retentionMsecs
+	storage.makePendingRowsVisibleIfNeeded()
+	retentionDeadline := int64(fasttime.UnixTimestamp()*1e3) - storage.getRetentionMsecs()
 
 	s.reset()
 	s.idb = storage.idb()
 	s.retentionDeadline = retentionDeadline
+	s.holdDeadlines = storage.getHoldDeadlines()
 	s.tr = tr
 	s.tfss = tfss
 	s.deadline = deadline
@@ -207,7 +233,11 @@ func (s *Search) NextMetricBlock() bool {
 		s.loops++
 		tsid := &s.ts.BlockRef.bh.TSID
 		if tsid.MetricID != s.prevMetricID {
-			if s.ts.BlockRef.bh.MaxTimestamp < s.retentionDeadline {
+			retentionDeadline := s.retentionDeadline
+			if d, ok := s.holdDeadlines[tsid.MetricID]; ok && d < retentionDeadline {
+				retentionDeadline = d
+			}
+			if s.ts.BlockRef.bh.MaxTimestamp < retentionDeadline {
 				// Skip the block, since it contains only data outside the configured retention.
 				continue
 			}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/querytracer"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/uint64set"
+)
+
+// SelectorStats contains stats for series matching the given selector.
+//
+// The stats are obtained from the index only, so they are cheap to obtain comparing to the query over the matching series.
+type SelectorStats struct {
+	// TotalSeries is the number of unique series matching the selector on the requested time range.
+	TotalSeries uint64
+
+	// SeriesCountByDate contains the number of series matching the selector per each day on the requested time range.
+	SeriesCountByDate []DateSeriesCount
+
+	// Status contains per-label breakdown for the series matching the selector.
+	Status *TSDBStatus
+}
+
+// DateSeriesCount contains the number of series for the given Date.
+type DateSeriesCount struct {
+	// Date is the number of days since unix epoch.
+	Date uint64
+
+	// Count is the number of series seen during the Date.
+	Count uint64
+}
+
+// GetSelectorStats returns stats for series matching tfss on the given tr.
+//
+// Per-label breakdown is limited by topN entries. See TSDBStatus for details.
+func (s *Storage) GetSelectorStats(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, focusLabel string, topN, maxMetrics int, deadline uint64) (*SelectorStats, error) {
+	qt = qt.NewChild("get selector stats: filters=%s, timeRange=%s", tfss, &tr)
+	defer qt.Done()
+	return s.idb().GetSelectorStats(qt, tfss, tr, focusLabel, topN, maxMetrics, deadline)
+}
+
+// GetSelectorStats returns stats for series matching tfss on the given tr.
+func (db *indexDB) GetSelectorStats(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, focusLabel string, topN, maxMetrics int, deadline uint64) (*SelectorStats, error) {
+	if len(tfss) == 0 {
+		return nil, fmt.Errorf("missing series selector")
+	}
+	minDate := uint64(tr.MinTimestamp) / msecPerDay
+	maxDate := uint64(tr.MaxTimestamp) / msecPerDay
+	dmis := db.s.getDeletedMetricIDs()
+	ss := &SelectorStats{}
+	metricIDs := &uint64set.Set{}
+	for date := minDate; date <= maxDate; date++ {
+		dateMetricIDs, err := db.searchMetricIDsOnDate(qt, tfss, date, maxMetrics, deadline)
+		if err != nil {
+			return nil, err
+		}
+		dateMetricIDs.Subtract(dmis)
+		ss.SeriesCountByDate = append(ss.SeriesCountByDate, DateSeriesCount{
+			Date:  date,
+			Count: uint64(dateMetricIDs.Len()),
+		})
+		metricIDs.UnionMayOwn(dateMetricIDs)
+		if metricIDs.Len() > maxMetrics {
+			return nil, fmt.Errorf("the number of matching timeseries exceeds %d; either narrow down the search "+
+				"or increase -search.max* command-line flag values at vmselect", maxMetrics)
+		}
+	}
+	ss.TotalSeries = uint64(metricIDs.Len())
+	qt.Printf("found %d matching series on %d days", ss.TotalSeries, len(ss.SeriesCountByDate))
+	if ss.TotalSeries == 0 {
+		ss.Status = &TSDBStatus{}
+		return ss, nil
+	}
+
+	// Use per-day index for the breakdown if the time range covers a single day,
+	// since it is much smaller than the global index.
+	date := minDate
+	if minDate != maxDate {
+		date = 0
+	}
+	qtChild := qt.NewChild("collect per-label stats in the current indexdb")
+	is := db.getIndexSearch(deadline)
+	status, err := is.getTSDBStatusForMetricIDs(metricIDs, date, focusLabel, topN)
+	db.putIndexSearch(is)
+	qtChild.Done()
+	if err != nil {
+		return nil, err
+	}
+	if !status.hasEntries() {
+		db.doExtDB(func(extDB *indexDB) {
+			qtChild := qt.NewChild("collect per-label stats in the previous indexdb")
+			is := extDB.getIndexSearch(deadline)
+			status, err = is.getTSDBStatusForMetricIDs(metricIDs, date, focusLabel, topN)
+			extDB.putIndexSearch(is)
+			qtChild.Done()
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error when obtaining per-label stats from extDB: %w", err)
+		}
+	}
+	ss.Status = status
+	return ss, nil
+}
+
+// searchMetricIDsOnDate returns metricIDs for series matching tfss on the given date in db and extDB.
+func (db *indexDB) searchMetricIDsOnDate(qt *querytracer.Tracer, tfss []*TagFilters, date uint64, maxMetrics int, deadline uint64) (*uint64set.Set, error) {
+	is := db.getIndexSearch(deadline)
+	metricIDs, err := is.searchMetricIDsWithFiltersOnDate(qt, tfss, date, maxMetrics)
+	db.putIndexSearch(is)
+	if err != nil {
+		return nil, err
+	}
+	db.doExtDB(func(extDB *indexDB) {
+		is := extDB.getIndexSearch(deadline)
+		var extMetricIDs *uint64set.Set
+		extMetricIDs, err = is.searchMetricIDsWithFiltersOnDate(qt, tfss, date, maxMetrics)
+		extDB.putIndexSearch(is)
+		if err == nil {
+			metricIDs.UnionMayOwn(extMetricIDs)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error when searching for metricIDs in the previous indexdb: %w", err)
+	}
+	return metricIDs, nil
+}
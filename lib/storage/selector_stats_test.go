@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestStorageGetSelectorStats(t *testing.T) {
+	path := "TestStorageGetSelectorStats"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	defer func() {
+		s.MustClose()
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove %q: %s", path, err)
+		}
+	}()
+
+	// Register 10 series for the first day and 5 series for the second day.
+	const day0 = 19000
+	var mrs []MetricRow
+	addRows := func(date uint64, n int) {
+		for i := 0; i < n; i++ {
+			var mn MetricName
+			mn.MetricGroup = []byte("metric")
+			mn.AddTag("job", "ingress")
+			mn.AddTag("instance", fmt.Sprintf("host_%d", i))
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: mn.marshalRaw(nil),
+				Timestamp:     int64(date)*msecPerDay + 1000,
+				Value:         float64(i),
+			})
+		}
+		var mn MetricName
+		mn.MetricGroup = []byte("other")
+		mn.AddTag("job", "other")
+		mrs = append(mrs, MetricRow{
+			MetricNameRaw: mn.marshalRaw(nil),
+			Timestamp:     int64(date)*msecPerDay + 1000,
+		})
+	}
+	addRows(day0, 10)
+	addRows(day0+1, 5)
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("cannot add rows: %s", err)
+	}
+	s.DebugFlush()
+
+	tfs := NewTagFilters()
+	if err := tfs.Add([]byte("job"), []byte("ingress"), false, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	tr := TimeRange{
+		MinTimestamp: day0 * msecPerDay,
+		MaxTimestamp: (day0+2)*msecPerDay - 1,
+	}
+	ss, err := s.GetSelectorStats(nil, []*TagFilters{tfs}, tr, "instance", 3, 1e6, noDeadline)
+	if err != nil {
+		t.Fatalf("cannot obtain selector stats: %s", err)
+	}
+	if ss.TotalSeries != 10 {
+		t.Fatalf("unexpected total series; got %d; want 10", ss.TotalSeries)
+	}
+	if got, want := fmt.Sprintf("%v", ss.SeriesCountByDate), fmt.Sprintf("[{%d 10} {%d 5}]", day0, day0+1); got != want {
+		t.Fatalf("unexpected series count by date; got %s; want %s", got, want)
+	}
+	if got, want := fmt.Sprintf("%v", ss.Status.SeriesCountByMetricName), "[{metric 10}]"; got != want {
+		t.Fatalf("unexpected series count by metric name; got %s; want %s", got, want)
+	}
+	if got, want := fmt.Sprintf("%v", ss.Status.LabelValueCountByLabelName), "[{instance 10} {__name__ 1} {job 1}]"; got != want {
+		t.Fatalf("unexpected label value count by label name; got %s; want %s", got, want)
+	}
+	if n := len(ss.Status.SeriesCountByFocusLabelValue); n != 3 {
+		t.Fatalf("unexpected number of entries for focus label; got %d; want 3", n)
+	}
+
+	// Single day uses per-day index.
+	tr.MinTimestamp = (day0 + 1) * msecPerDay
+	ss, err = s.GetSelectorStats(nil, []*TagFilters{tfs}, tr, "", 3, 1e6, noDeadline)
+	if err != nil {
+		t.Fatalf("cannot obtain selector stats: %s", err)
+	}
+	if ss.TotalSeries != 5 || ss.Status.TotalSeries != 5 {
+		t.Fatalf("unexpected total series; got %d and %d; want 5", ss.TotalSeries, ss.Status.TotalSeries)
+	}
+
+	// Too many series.
+	tr.MinTimestamp = day0 * msecPerDay
+	if _, err := s.GetSelectorStats(nil, []*TagFilters{tfs}, tr, "", 3, 5, noDeadline); err == nil {
+		t.Fatalf("expecting non-nil error when the number of matching series exceeds maxMetrics")
+	}
+}
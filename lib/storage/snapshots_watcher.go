@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/snapshot"
+)
+
+// snapshotMergeThrottleDelaySeconds is the age of the oldest snapshot after which background merges are throttled.
+//
+// Throttling is disabled if it is set to 0.
+var snapshotMergeThrottleDelaySeconds uint64
+
+// SetSnapshotMergeThrottleDelay sets the age of the oldest snapshot after which background merges are throttled.
+//
+// Snapshots hold hard links to parts, so every merge of these parts increases disk space usage
+// until the snapshot is deleted. Throttling is disabled if delay <= 0.
+//
+// This function may be called only before Storage initialization.
+func SetSnapshotMergeThrottleDelay(delay time.Duration) {
+	if delay <= 0 {
+		snapshotMergeThrottleDelaySeconds = 0
+		return
+	}
+	snapshotMergeThrottleDelaySeconds = uint64(delay.Seconds() + 1)
+}
+
+// isSnapshotMergeThrottled returns true if background merges must be throttled because of too old snapshot.
+func (s *Storage) isSnapshotMergeThrottled() bool {
+	if snapshotMergeThrottleDelaySeconds == 0 {
+		return false
+	}
+	oldestTimestamp := atomic.LoadUint64(&s.oldestSnapshotTimestamp)
+	return oldestTimestamp > 0 && fasttime.UnixTimestamp() > oldestTimestamp+snapshotMergeThrottleDelaySeconds
+}
+
+func (s *Storage) startSnapshotsWatcher() {
+	s.updateSnapshotsState()
+	s.snapshotsWatcherWG.Add(1)
+	go func() {
+		s.snapshotsWatcher()
+		s.snapshotsWatcherWG.Done()
+	}()
+}
+
+func (s *Storage) snapshotsWatcher() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	isThrottled := false
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.updateSnapshotsState()
+		s.updateSnapshotsExtraSize()
+		if s.isSnapshotMergeThrottled() != isThrottled {
+			isThrottled = !isThrottled
+			if isThrottled {
+				logger.Warnf("throttling background merges at %q, since it contains snapshots older than -snapshotMergeThrottleDelay=%ds; "+
+					"delete the snapshots after the backup is finished in order to resume merges", s.path, snapshotMergeThrottleDelaySeconds-1)
+			} else {
+				logger.Infof("resuming background merges at %q, since it has no snapshots older than -snapshotMergeThrottleDelay=%ds",
+					s.path, snapshotMergeThrottleDelaySeconds-1)
+			}
+		}
+	}
+}
+
+// updateSnapshotsState updates the number of snapshots and the creation time for the oldest snapshot.
+func (s *Storage) updateSnapshotsState() {
+	snapshotNames, err := s.ListSnapshots()
+	if err != nil {
+		logger.Errorf("cannot update snapshots state: %s", err)
+		return
+	}
+	var oldestTimestamp uint64
+	for _, snapshotName := range snapshotNames {
+		t, err := snapshot.Time(snapshotName)
+		if err != nil {
+			continue
+		}
+		timestamp := uint64(t.Unix())
+		if oldestTimestamp == 0 || timestamp < oldestTimestamp {
+			oldestTimestamp = timestamp
+		}
+	}
+	atomic.StoreUint64(&s.snapshotsCount, uint64(len(snapshotNames)))
+	atomic.StoreUint64(&s.oldestSnapshotTimestamp, oldestTimestamp)
+	if len(snapshotNames) == 0 {
+		atomic.StoreUint64(&s.snapshotsExtraSizeBytes, 0)
+	}
+}
+
+// updateSnapshotsExtraSize updates the size of files held only by snapshots.
+//
+// These files occupy disk space, which would be freed if snapshots are deleted.
+// The size is approximate, since parts may be merged while the files are scanned.
+func (s *Storage) updateSnapshotsExtraSize() {
+	if atomic.LoadUint64(&s.snapshotsCount) == 0 {
+		atomic.StoreUint64(&s.snapshotsExtraSizeBytes, 0)
+		return
+	}
+	liveFiles := make(map[fs.FileID]struct{})
+	snapshotFiles := make(map[fs.FileID]uint64)
+	_ = filepath.WalkDir(s.path, func(path string, de os.DirEntry, err error) error {
+		if err != nil {
+			// The file may be already deleted by background merge.
+			return nil
+		}
+		if !de.Type().IsRegular() {
+			// Skip directories and symlinks.
+			return nil
+		}
+		fi, err := de.Info()
+		if err != nil {
+			return nil
+		}
+		fileID, ok := fs.GetFileID(fi)
+		if !ok {
+			return nil
+		}
+		if isSnapshotFilePath(s.path, path) {
+			snapshotFiles[fileID] = uint64(fi.Size())
+		} else {
+			liveFiles[fileID] = struct{}{}
+		}
+		return nil
+	})
+	n := uint64(0)
+	for fileID, size := range snapshotFiles {
+		if _, ok := liveFiles[fileID]; !ok {
+			n += size
+		}
+	}
+	atomic.StoreUint64(&s.snapshotsExtraSizeBytes, n)
+}
+
+// isSnapshotFilePath returns true if the given path under storagePath belongs to a snapshot.
+func isSnapshotFilePath(storagePath, path string) bool {
+	relPath, err := filepath.Rel(storagePath, path)
+	if err != nil {
+		return false
+	}
+	for _, name := range strings.Split(filepath.ToSlash(relPath), "/") {
+		if name == "snapshots" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"math/rand"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+)
+
+func TestIsSnapshotFilePath(t *testing.T) {
+	f := func(path string, resultExpected bool) {
+		t.Helper()
+		result := isSnapshotFilePath("/storage", path)
+		if result != resultExpected {
+			t.Fatalf("unexpected result for isSnapshotFilePath(%q); got %v; want %v", path, result, resultExpected)
+		}
+	}
+	f("/storage/data/small/2022_10/123_456/values.bin", false)
+	f("/storage/indexdb/1234/123_456/items.bin", false)
+	f("/storage/data/small/snapshots/20221016020304-1234/2022_10/123_456/values.bin", true)
+	f("/storage/indexdb/snapshots/20221016020304-1234/1234/123_456/items.bin", true)
+	f("/storage/snapshots/20221016020304-1234/metadata/minTimestampForCompositeIndex", true)
+	f("/storage/snapshots_foo/bar", false)
+}
+
+func TestStorageSnapshotsState(t *testing.T) {
+	path := "TestStorageSnapshotsState"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 24*3600*1000
+	for i := 0; i < 10; i++ {
+		mrs := testGenerateMetricRows(rng, 1e3, minTimestamp, maxTimestamp)
+		if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+			t.Fatalf("unexpected error when adding mrs: %s", err)
+		}
+	}
+
+	snapshotName, err := s.CreateSnapshot()
+	if err != nil {
+		t.Fatalf("cannot create snapshot: %s", err)
+	}
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.SnapshotsCount != 1 {
+		t.Fatalf("unexpected number of snapshots; got %d; want 1", m.SnapshotsCount)
+	}
+	if n := atomic.LoadUint64(&s.oldestSnapshotTimestamp); n == 0 {
+		t.Fatalf("oldestSnapshotTimestamp must be set after snapshot creation")
+	}
+
+	// The snapshot shares all the parts with the live data right after its creation.
+	s.updateSnapshotsExtraSize()
+	extraSizeBytesBefore := atomic.LoadUint64(&s.snapshotsExtraSizeBytes)
+
+	// Merged parts are deleted from the live data, so they are held only by the snapshot.
+	if err := s.ForceMergePartitions(""); err != nil {
+		t.Fatalf("error when force merging partitions: %s", err)
+	}
+	s.updateSnapshotsExtraSize()
+	extraSizeBytesAfter := atomic.LoadUint64(&s.snapshotsExtraSizeBytes)
+	if extraSizeBytesAfter <= extraSizeBytesBefore {
+		t.Fatalf("snapshot extra size must increase after merge; got %d bytes before merge and %d bytes after merge", extraSizeBytesBefore, extraSizeBytesAfter)
+	}
+
+	// Verify merge throttling.
+	if s.isSnapshotMergeThrottled() {
+		t.Fatalf("merges mustn't be throttled when -snapshotMergeThrottleDelay isn't set")
+	}
+	SetSnapshotMergeThrottleDelay(time.Hour)
+	if s.isSnapshotMergeThrottled() {
+		t.Fatalf("merges mustn't be throttled for fresh snapshot")
+	}
+	atomic.StoreUint64(&s.oldestSnapshotTimestamp, fasttime.UnixTimestamp()-2*3600)
+	if !s.isSnapshotMergeThrottled() {
+		t.Fatalf("merges must be throttled for too old snapshot")
+	}
+	s.UpdateMetrics(&m)
+	if m.SnapshotMergesThrottled != 1 {
+		t.Fatalf("unexpected SnapshotMergesThrottled; got %d; want 1", m.SnapshotMergesThrottled)
+	}
+
+	if err := s.DeleteSnapshot(snapshotName); err != nil {
+		t.Fatalf("cannot delete snapshot: %s", err)
+	}
+	if s.isSnapshotMergeThrottled() {
+		t.Fatalf("merges mustn't be throttled after the snapshot is deleted")
+	}
+	SetSnapshotMergeThrottleDelay(0)
+	var m1 Metrics
+	s.UpdateMetrics(&m1)
+	if m1.SnapshotsCount != 0 || m1.SnapshotsExtraSizeBytes != 0 || m1.SnapshotMergesThrottled != 0 {
+		t.Fatalf("unexpected snapshot metrics after the snapshot deletion: SnapshotsCount=%d, SnapshotsExtraSizeBytes=%d, SnapshotMergesThrottled=%d",
+			m1.SnapshotsCount, m1.SnapshotsExtraSizeBytes, m1.SnapshotMergesThrottled)
+	}
+
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
@@ -51,10 +51,32 @@ type Storage struct {
 	hourlySeriesLimitRowsDropped uint64
 	dailySeriesLimitRowsDropped  uint64
 
-	path           string
-	cachePath      string
+	readYourWritesFlushes uint64
+
+	// pendingRowsVisibleTime is the unix timestamp in nanoseconds for the last start of making pending rows visible to search.
+	// All the rows added before this time are visible to search.
+	pendingRowsVisibleTime int64
+
+	// retentionMsecs is the current retention. It may be extended via ExtendRetention.
 	retentionMsecs int64
 
+	// minIndexDBRotationTimestamp is the minimum unix timestamp in milliseconds for the next indexdb rotation.
+	//
+	// It is set when the retention is extended, so the previous indexdb isn't dropped while it contains data within the extended retention.
+	minIndexDBRotationTimestamp int64
+
+	path      string
+	cachePath string
+
+	// baseRetentionMsecs is the retention passed to OpenStorage.
+	baseRetentionMsecs int64
+
+	// retentionLock serializes retention changes.
+	retentionLock sync.Mutex
+
+	// retentionUpdatedCh is notified when the retention is extended via ExtendRetention.
+	retentionUpdatedCh chan struct{}
+
 	// lock file for exclusive access to the storage on the given path.
 	flockF *os.File
 
@@ -62,6 +84,9 @@ type Storage struct {
 
 	tb *table
 
+	// forceMergeJobs contains recent forced merge jobs started via StartForceMerge.
+	forceMergeJobs forceMergeJobs
+
 	// Series cardinality limiters.
 	hourlySeriesLimiter *bloomfilter.Limiter
 	dailySeriesLimiter  *bloomfilter.Limiter
@@ -113,6 +138,15 @@ type Storage struct {
 	nextDayMetricIDsUpdaterWG  sync.WaitGroup
 	retentionWatcherWG         sync.WaitGroup
 	freeDiskSpaceWatcherWG     sync.WaitGroup
+	snapshotsWatcherWG         sync.WaitGroup
+	holdsWatcherWG             sync.WaitGroup
+	walWorkersWG               sync.WaitGroup
+
+	// wal is an optional write-ahead log for the added rows. It is enabled via SetWAL.
+	wal *wal
+
+	// pendingRowsVisibilityLock serializes calls to makePendingRowsVisible.
+	pendingRowsVisibilityLock sync.Mutex
 
 	// The snapshotLock prevents from concurrent creation of snapshots,
 	// since this may result in snapshots without recently added data,
@@ -123,6 +157,12 @@ type Storage struct {
 	// The minimum timestamp when composite index search can be used.
 	minTimestampForCompositeIndex int64
 
+	// Composite labels indexes enabled via SetCompositeLabelsIndexes.
+	compositeLabelsIndexes []*compositeLabelsIndex
+
+	// cardinalitySketches is non-nil if cardinality sketches are enabled via SetCardinalitySketches.
+	cardinalitySketches *cardinalitySketches
+
 	// An inmemory set of deleted metricIDs.
 	//
 	// It is safe to keep the set in memory even for big number of deleted
@@ -131,6 +171,19 @@ type Storage struct {
 	deletedMetricIDsUpdateLock sync.Mutex
 
 	isReadOnly uint32
+
+	// snapshotsCount is the number of snapshots at path/snapshots.
+	snapshotsCount uint64
+
+	// oldestSnapshotTimestamp is the creation unix timestamp in seconds for the oldest snapshot.
+	// It is set to 0 if there are no snapshots.
+	oldestSnapshotTimestamp uint64
+
+	// snapshotsExtraSizeBytes is the size of files held only by snapshots.
+	snapshotsExtraSizeBytes uint64
+
+	// holds contains active holds, which protect the matching series from deletion.
+	holds *holds
 }
 
 // OpenStorage opens storage on the given path with the given retentionMsecs.
@@ -146,10 +199,12 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 		retentionMsecs = maxRetentionMsecs
 	}
 	s := &Storage{
-		path:           path,
-		cachePath:      path + "/cache",
-		retentionMsecs: retentionMsecs,
-		stop:           make(chan struct{}),
+		path:               path,
+		cachePath:          path + "/cache",
+		retentionMsecs:     retentionMsecs,
+		baseRetentionMsecs: retentionMsecs,
+		retentionUpdatedCh: make(chan struct{}, 1),
+		stop:               make(chan struct{}),
 	}
 	if err := fs.MkdirAllIfNotExist(path); err != nil {
 		return nil, fmt.Errorf("cannot create a directory for the storage at %q: %w", path, err)
@@ -224,6 +279,11 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 		return nil, fmt.Errorf("cannot create %q: %w", metadataDir, err)
 	}
 	s.minTimestampForCompositeIndex = mustGetMinTimestampForCompositeIndex(metadataDir, isEmptyDB)
+	s.compositeLabelsIndexes = mustLoadCompositeLabelsIndexes(metadataDir, isEmptyDB, compositeLabelsIndexLabelSets)
+	s.mustLoadRetention(metadataDir)
+	if cardinalitySketchesEnabled {
+		s.cardinalitySketches = newCardinalitySketches(cardinalitySketchesTenantLabel)
+	}
 
 	// Load indexdb
 	idbPath := path + "/indexdb"
@@ -251,6 +311,10 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 	s.setDeletedMetricIDs(dmisCurr)
 	s.updateDeletedMetricIDs(dmisPrev)
 
+	// Load holds before opening the table, so the held data isn't deleted by retention.
+	s.holds = mustOpenHolds(path + "/holds")
+	s.updateHeldMetricIDs()
+
 	// Load data
 	tablePath := path + "/data"
 	tb, err := openTable(tablePath, s)
@@ -260,10 +324,27 @@ func OpenStorage(path string, retentionMsecs int64, maxHourlySeries, maxDailySer
 	}
 	s.tb = tb
 
+	// Recover rows from the write-ahead log left after unclean shutdown.
+	// This is performed even if the write-ahead log is disabled now.
+	walPath := path + "/wal"
+	s.mustReplayWAL(walPath)
+	if walSyncPolicy != "" {
+		w, err := openWAL(walPath)
+		if err != nil {
+			s.tb.MustClose()
+			s.idb().MustClose()
+			return nil, fmt.Errorf("cannot open write-ahead log at %q: %w", walPath, err)
+		}
+		s.wal = w
+		s.startWALWorkers()
+	}
+
 	s.startCurrHourMetricIDsUpdater()
 	s.startNextDayMetricIDsUpdater()
 	s.startRetentionWatcher()
 	s.startFreeDiskSpaceWatcher()
+	s.startSnapshotsWatcher()
+	s.startHoldsWatcher()
 
 	return s, nil
 }
@@ -307,7 +388,25 @@ func (s *Storage) DebugFlush() {
 
 // CreateSnapshot creates snapshot for s and returns the snapshot name.
 func (s *Storage) CreateSnapshot() (string, error) {
-	logger.Infof("creating Storage snapshot for %q...", s.path)
+	return s.createSnapshot(nil)
+}
+
+// CreatePartialSnapshot creates snapshot for s, which contains only partitions overlapping the given tr, and returns the snapshot name.
+//
+// IndexDB is included in the snapshot in full, since it is shared among all the partitions.
+func (s *Storage) CreatePartialSnapshot(tr TimeRange) (string, error) {
+	if tr.MinTimestamp > tr.MaxTimestamp {
+		return "", fmt.Errorf("invalid time range %s: start must be smaller than end", &tr)
+	}
+	return s.createSnapshot(&tr)
+}
+
+func (s *Storage) createSnapshot(tr *TimeRange) (string, error) {
+	if tr != nil {
+		logger.Infof("creating Storage snapshot for %q on time range %s...", s.path, tr)
+	} else {
+		logger.Infof("creating Storage snapshot for %q...", s.path)
+	}
 	startTime := time.Now()
 
 	s.snapshotLock.Lock()
@@ -324,7 +423,7 @@ func (s *Storage) CreateSnapshot() (string, error) {
 		return "", fmt.Errorf("cannot create dir %q: %w", dstDataDir, err)
 	}
 
-	smallDir, bigDir, err := s.tb.CreateSnapshot(snapshotName)
+	smallDir, bigDir, err := s.tb.CreateSnapshot(snapshotName, tr)
 	if err != nil {
 		return "", fmt.Errorf("cannot create table snapshot: %w", err)
 	}
@@ -363,6 +462,7 @@ func (s *Storage) CreateSnapshot() (string, error) {
 	}
 
 	fs.MustSyncPath(dstDir)
+	s.updateSnapshotsState()
 
 	logger.Infof("created Storage snapshot for %q at %q in %.3f seconds", srcDir, dstDir, time.Since(startTime).Seconds())
 	return snapshotName, nil
@@ -406,6 +506,7 @@ func (s *Storage) DeleteSnapshot(snapshotName string) error {
 	idbPath := fmt.Sprintf("%s/indexdb/snapshots/%s", s.path, snapshotName)
 	fs.MustRemoveDirAtomic(idbPath)
 	fs.MustRemoveDirAtomic(snapshotPath)
+	s.updateSnapshotsState()
 
 	logger.Infof("deleted snapshot %q in %.3f seconds", snapshotPath, time.Since(startTime).Seconds())
 
@@ -497,6 +598,21 @@ type Metrics struct {
 
 	NextRetentionSeconds uint64
 
+	WALSizeBytes   uint64
+	WALSyncs       uint64
+	WALCheckpoints uint64
+
+	ReadYourWritesFlushes uint64
+
+	SnapshotsCount           uint64
+	SnapshotsExtraSizeBytes  uint64
+	OldestSnapshotAgeSeconds uint64
+	SnapshotMergesThrottled  uint64
+
+	ActiveHolds           uint64
+	HeldSeries            uint64
+	DeletesBlockedByHolds uint64
+
 	IndexDBMetrics IndexDBMetrics
 	TableMetrics   TableMetrics
 }
@@ -583,7 +699,32 @@ func (s *Storage) UpdateMetrics(m *Metrics) {
 	m.PrefetchedMetricIDsSize += uint64(prefetchedMetricIDs.Len())
 	m.PrefetchedMetricIDsSizeBytes += uint64(prefetchedMetricIDs.SizeBytes())
 
-	m.NextRetentionSeconds = uint64(nextRetentionDuration(s.retentionMsecs).Seconds())
+	m.NextRetentionSeconds = uint64(s.nextIndexDBRotationDuration().Seconds())
+
+	if w := s.wal; w != nil {
+		m.WALSizeBytes += atomic.LoadUint64(&w.sizeBytes)
+		m.WALSyncs += atomic.LoadUint64(&w.syncs)
+		m.WALCheckpoints += atomic.LoadUint64(&w.checkpoints)
+	}
+
+	m.ReadYourWritesFlushes += atomic.LoadUint64(&s.readYourWritesFlushes)
+
+	m.SnapshotsCount += atomic.LoadUint64(&s.snapshotsCount)
+
+	if s.holds != nil {
+		m.ActiveHolds += uint64(len(s.holds.getHolds()))
+		m.HeldSeries += uint64(len(s.holds.getDeadlines()))
+		m.DeletesBlockedByHolds += atomic.LoadUint64(&s.holds.deletesBlocked)
+	}
+	m.SnapshotsExtraSizeBytes += atomic.LoadUint64(&s.snapshotsExtraSizeBytes)
+	if oldestTimestamp := atomic.LoadUint64(&s.oldestSnapshotTimestamp); oldestTimestamp > 0 {
+		if ct := fasttime.UnixTimestamp(); ct > oldestTimestamp {
+			m.OldestSnapshotAgeSeconds = ct - oldestTimestamp
+		}
+	}
+	if s.isSnapshotMergeThrottled() {
+		m.SnapshotMergesThrottled = 1
+	}
 
 	s.idb().UpdateMetrics(&m.IndexDBMetrics)
 	s.tb.UpdateMetrics(&m.TableMetrics)
@@ -645,11 +786,19 @@ func (s *Storage) startRetentionWatcher() {
 
 func (s *Storage) retentionWatcher() {
 	for {
-		d := nextRetentionDuration(s.retentionMsecs)
+		d := s.nextIndexDBRotationDuration()
 		select {
 		case <-s.stop:
 			return
+		case <-s.retentionUpdatedCh:
+			// The retention has been extended. Re-calculate the next indexdb rotation time.
+			continue
 		case <-time.After(d):
+			if s.hasHolds() {
+				// The previous indexdb may contain entries for the held series, so it mustn't be dropped.
+				logger.Infof("postponing indexdb rotation at %q, since the storage contains active holds", s.path)
+				continue
+			}
 			s.mustRotateIndexDB()
 		}
 	}
@@ -780,13 +929,21 @@ func (s *Storage) MustClose() {
 	close(s.stop)
 
 	s.freeDiskSpaceWatcherWG.Wait()
+	s.snapshotsWatcherWG.Wait()
+	s.holdsWatcherWG.Wait()
 	s.retentionWatcherWG.Wait()
 	s.currHourMetricIDsUpdaterWG.Wait()
 	s.nextDayMetricIDsUpdaterWG.Wait()
+	s.walWorkersWG.Wait()
 
 	s.tb.MustClose()
 	s.idb().MustClose()
 
+	// All the data is flushed to disk, so the write-ahead log is no longer needed.
+	if s.wal != nil {
+		s.wal.mustClose()
+	}
+
 	// Save caches.
 	s.mustSaveCache(s.tsidCache, "MetricName->TSID", "metricName_tsid")
 	s.tsidCache.Stop()
@@ -1036,6 +1193,48 @@ func (s *Storage) mustSaveCache(c *workingsetcache.Cache, info, name string) {
 // saveCacheLock prevents from data races when multiple concurrent goroutines save the same cache.
 var saveCacheLock sync.Mutex
 
+// SetReadYourWrites enables read-your-writes mode for search.
+//
+// By default recently added rows become visible to search with up to a second delay,
+// since they are buffered in memory before being converted to searchable parts.
+// In read-your-writes mode all the rows added more than maxStaleness ago are guaranteed
+// to be visible to search. Zero maxStaleness means that all the rows added before the search are visible to it.
+//
+// This function must be called before initializing the storage.
+func SetReadYourWrites(enabled bool, maxStaleness time.Duration) {
+	readYourWrites = enabled
+	readYourWritesMaxStaleness = maxStaleness
+}
+
+var (
+	readYourWrites             bool
+	readYourWritesMaxStaleness time.Duration
+)
+
+// makePendingRowsVisibleIfNeeded makes the rows added to s visible to search if read-your-writes mode is enabled.
+func (s *Storage) makePendingRowsVisibleIfNeeded() {
+	if !readYourWrites {
+		return
+	}
+	minVisibleTime := time.Now().Add(-readYourWritesMaxStaleness).UnixNano()
+	if atomic.LoadInt64(&s.pendingRowsVisibleTime) >= minVisibleTime {
+		// Fast path - the rows have been already made visible by the recent call.
+		return
+	}
+	s.pendingRowsVisibilityLock.Lock()
+	defer s.pendingRowsVisibilityLock.Unlock()
+	if atomic.LoadInt64(&s.pendingRowsVisibleTime) >= minVisibleTime {
+		// The rows have been made visible by concurrent goroutine while waiting for the lock.
+		return
+	}
+	startTime := time.Now().UnixNano()
+	s.tb.makePendingRowsVisible()
+	// Newly registered series may be buffered in indexdb, so make them visible too.
+	s.idb().tb.MakePendingItemsVisible()
+	atomic.StoreInt64(&s.pendingRowsVisibleTime, startTime)
+	atomic.AddUint64(&s.readYourWritesFlushes, 1)
+}
+
 // SetRetentionTimezoneOffset sets the offset, which is used for calculating the time for indexdb rotation.
 // See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/2574
 func SetRetentionTimezoneOffset(offset time.Duration) {
@@ -1045,9 +1244,15 @@ func SetRetentionTimezoneOffset(offset time.Duration) {
 var retentionTimezoneOffsetMsecs int64
 
 func nextRetentionDuration(retentionMsecs int64) time.Duration {
-	// Round retentionMsecs to days. This guarantees that per-day inverted index works as expected.
-	retentionMsecs = ((retentionMsecs + msecPerDay - 1) / msecPerDay) * msecPerDay
 	t := time.Now().UnixNano() / 1e6
+	deadline := nextRetentionDeadline(t, retentionMsecs)
+	return time.Duration(deadline-t) * time.Millisecond
+}
+
+// nextRetentionDeadline returns unix timestamp in milliseconds for the next indexdb rotation after t for the given retentionMsecs.
+func nextRetentionDeadline(t, retentionMsecs int64) int64 {
+	// Round retentionMsecs to days. This guarantees that per-day inverted index works as expected.
+	retentionMsecs = roundRetentionToDays(retentionMsecs)
 	deadline := ((t + retentionMsecs - 1) / retentionMsecs) * retentionMsecs
 	// Schedule the deadline to +4 hours from the next retention period start.
 	// This should prevent from possible double deletion of indexdb
@@ -1056,7 +1261,7 @@ func nextRetentionDuration(retentionMsecs int64) time.Duration {
 	// The effect of time zone on retention period is moved out.
 	// See https://github.com/VictoriaMetrics/VictoriaMetrics/pull/2574
 	deadline -= retentionTimezoneOffsetMsecs
-	return time.Duration(deadline-t) * time.Millisecond
+	return deadline
 }
 
 // SearchMetricNames returns marshaled metric names matching the given tfss on the given tr.
@@ -1065,6 +1270,7 @@ func nextRetentionDuration(retentionMsecs int64) time.Duration {
 func (s *Storage) SearchMetricNames(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, maxMetrics int, deadline uint64) ([]string, error) {
 	qt = qt.NewChild("search for matching metric names: filters=%s, timeRange=%s", tfss, &tr)
 	defer qt.Done()
+	s.makePendingRowsVisibleIfNeeded()
 	metricIDs, err := s.idb().searchMetricIDs(qt, tfss, tr, maxMetrics, deadline)
 	if err != nil {
 		return nil, err
@@ -1106,6 +1312,66 @@ func (s *Storage) SearchMetricNames(qt *querytracer.Tracer, tfss []*TagFilters,
 	return metricNames, nil
 }
 
+// IterateMetricNames returns up to limit marshaled metric names matching tfss for series with metricID bigger than afterMetricID.
+//
+// Metric names are returned in the order of their metricIDs, i.e. in the order they are stored in the index,
+// so the iteration remains stable while new series are registered. All the metric names are returned if tfss is empty.
+//
+// The returned nextMetricID must be passed as afterMetricID to the next call in order to continue the iteration.
+// nextMetricID is 0 when the iteration is finished.
+//
+// The marshaled metric names must be unmarshaled via MetricName.UnmarshalString().
+func (s *Storage) IterateMetricNames(qt *querytracer.Tracer, tfss []*TagFilters, afterMetricID uint64, limit int, deadline uint64) ([]string, uint64, error) {
+	qt = qt.NewChild("iterate over metric names: filters=%s, afterMetricID=%d, limit=%d", tfss, afterMetricID, limit)
+	defer qt.Done()
+	if limit <= 0 {
+		return nil, 0, fmt.Errorf("limit must be positive; got %d", limit)
+	}
+	s.makePendingRowsVisibleIfNeeded()
+	idb := s.idb()
+	is := idb.getIndexSearch(deadline)
+	mns, err := is.searchMetricNamesAfterMetricID(nil, tfss, afterMetricID, limit)
+	idb.putIndexSearch(is)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The previous indexDB may contain series, which weren't registered in the current indexDB yet.
+	idb.doExtDB(func(extDB *indexDB) {
+		is := extDB.getIndexSearch(deadline)
+		n := len(mns)
+		mns, err = is.searchMetricNamesAfterMetricID(mns, tfss, afterMetricID, limit)
+		extDB.putIndexSearch(is)
+		if err == nil && n > 0 && len(mns) > n {
+			sort.Slice(mns, func(i, j int) bool {
+				return mns[i].metricID < mns[j].metricID
+			})
+		}
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	metricNames := make([]string, 0, limit)
+	var nextMetricID uint64
+	for i := range mns {
+		if len(metricNames) >= limit {
+			break
+		}
+		if i > 0 && mns[i].metricID == mns[i-1].metricID {
+			// The series is registered in both indexDBs.
+			continue
+		}
+		metricNames = append(metricNames, mns[i].metricName)
+		nextMetricID = mns[i].metricID
+	}
+	if len(metricNames) < limit {
+		// There are no more matching metric names.
+		nextMetricID = 0
+	}
+	qt.Printf("found %d metric names; nextMetricID=%d", len(metricNames), nextMetricID)
+	return metricNames, nextMetricID, nil
+}
+
 // prefetchMetricNames pre-fetches metric names for the given metricIDs into metricID->metricName cache.
 //
 // This should speed-up further searchMetricNameWithCache calls for srcMetricIDs from tsids.
@@ -1203,7 +1469,14 @@ var ErrDeadlineExceeded = fmt.Errorf("deadline exceeded")
 // DeleteSeries deletes all the series matching the given tfss.
 //
 // Returns the number of metrics deleted.
+//
+// Series matching active holds aren't deleted. See AddHold.
 func (s *Storage) DeleteSeries(qt *querytracer.Tracer, tfss []*TagFilters) (int, error) {
+	// Update held series, since new series matching the holds could be registered after the last update.
+	s.updateHeldMetricIDs()
+	if err := s.registerBlockedDeletes(qt, tfss); err != nil {
+		return 0, fmt.Errorf("cannot search for held series: %w", err)
+	}
 	deletedCount, err := s.idb().DeleteTSIDs(qt, tfss)
 	if err != nil {
 		return deletedCount, fmt.Errorf("cannot delete tsids: %w", err)
@@ -1219,13 +1492,20 @@ func (s *Storage) DeleteSeries(qt *querytracer.Tracer, tfss []*TagFilters) (int,
 
 // SearchLabelNamesWithFiltersOnTimeRange searches for label names matching the given tfss on tr.
 func (s *Storage) SearchLabelNamesWithFiltersOnTimeRange(qt *querytracer.Tracer, tfss []*TagFilters, tr TimeRange, maxLabelNames, maxMetrics int, deadline uint64) ([]string, error) {
+	s.makePendingRowsVisibleIfNeeded()
 	return s.idb().SearchLabelNamesWithFiltersOnTimeRange(qt, tfss, tr, maxLabelNames, maxMetrics, deadline)
 }
 
 // SearchLabelValuesWithFiltersOnTimeRange searches for label values for the given labelName, filters and tr.
 func (s *Storage) SearchLabelValuesWithFiltersOnTimeRange(qt *querytracer.Tracer, labelName string, tfss []*TagFilters,
 	tr TimeRange, maxLabelValues, maxMetrics int, deadline uint64) ([]string, error) {
-	return s.idb().SearchLabelValuesWithFiltersOnTimeRange(qt, labelName, tfss, tr, maxLabelValues, maxMetrics, deadline)
+	s.makePendingRowsVisibleIfNeeded()
+	idb := s.idb()
+	values, err := idb.SearchLabelValuesWithFiltersOnTimeRange(qt, labelName, tfss, tr, maxLabelValues, maxMetrics, deadline)
+	if err != nil {
+		return nil, err
+	}
+	return idb.resolveLabelValues(values)
 }
 
 // SearchTagValueSuffixes returns all the tag value suffixes for the given tagKey and tagValuePrefix on the given tr.
@@ -1235,11 +1515,13 @@ func (s *Storage) SearchLabelValuesWithFiltersOnTimeRange(qt *querytracer.Tracer
 // If more than maxTagValueSuffixes suffixes is found, then only the first maxTagValueSuffixes suffixes is returned.
 func (s *Storage) SearchTagValueSuffixes(qt *querytracer.Tracer, tr TimeRange, tagKey, tagValuePrefix string,
 	delimiter byte, maxTagValueSuffixes int, deadline uint64) ([]string, error) {
+	s.makePendingRowsVisibleIfNeeded()
 	return s.idb().SearchTagValueSuffixes(qt, tr, tagKey, tagValuePrefix, delimiter, maxTagValueSuffixes, deadline)
 }
 
 // SearchGraphitePaths returns all the matching paths for the given graphite query on the given tr.
 func (s *Storage) SearchGraphitePaths(qt *querytracer.Tracer, tr TimeRange, query []byte, maxPaths int, deadline uint64) ([]string, error) {
+	s.makePendingRowsVisibleIfNeeded()
 	query = replaceAlternateRegexpsWithGraphiteWildcards(query)
 	return s.searchGraphitePaths(qt, tr, nil, query, maxPaths, deadline)
 }
@@ -1427,6 +1709,16 @@ func (s *Storage) GetTSDBStatus(qt *querytracer.Tracer, tfss []*TagFilters, date
 	return s.idb().GetTSDBStatus(qt, tfss, date, focusLabel, topN, maxMetrics, deadline)
 }
 
+// GetTSDBStatusFromSketches returns approximate TSDB status for the given date and tenant from cardinality sketches.
+//
+// It doesn't scan indexdb, so it works fast for any number of series. See SetCardinalitySketches.
+func (s *Storage) GetTSDBStatusFromSketches(date uint64, tenant string, topN int) (*TSDBStatus, error) {
+	if s.cardinalitySketches == nil {
+		return nil, fmt.Errorf("cardinality sketches are disabled; enable them via -storage.cardinalitySketches command-line flag")
+	}
+	return s.cardinalitySketches.getTSDBStatus(date, tenant, topN)
+}
+
 // MetricRow is a metric to insert into storage.
 type MetricRow struct {
 	// MetricNameRaw contains raw metric name, which must be decoded
@@ -1507,6 +1799,12 @@ func (s *Storage) AddRows(mrs []MetricRow, precisionBits uint8) error {
 		return nil
 	}
 
+	if w := s.wal; w != nil {
+		w.addRowsLock.RLock()
+		defer w.addRowsLock.RUnlock()
+		w.mustWriteRows(mrs, precisionBits)
+	}
+
 	// Add rows to the storage in blocks with limited size in order to reduce memory usage.
 	var firstErr error
 	ic := getMetricRowsInsertCtx()
@@ -2004,10 +2302,7 @@ func (s *Storage) updatePerDateData(rows []rawRow, mrs []*MetricRow) error {
 			}
 			continue
 		}
-		if !ok {
-			// The (date, metricID) entry is missing in the indexDB. Add it there together with per-day indexes.
-			// It is OK if the (date, metricID) entry is added multiple times to db
-			// by concurrent goroutines.
+		if !ok || s.cardinalitySketches != nil {
 			if err := mn.UnmarshalRaw(dmid.mr.MetricNameRaw); err != nil {
 				if firstError == nil {
 					firstError = fmt.Errorf("cannot unmarshal MetricNameRaw %q: %w", dmid.mr.MetricNameRaw, err)
@@ -2015,8 +2310,18 @@ func (s *Storage) updatePerDateData(rows []rawRow, mrs []*MetricRow) error {
 				continue
 			}
 			mn.sortTags()
+		}
+		if !ok {
+			// The (date, metricID) entry is missing in the indexDB. Add it there together with per-day indexes.
+			// It is OK if the (date, metricID) entry is added multiple times to db
+			// by concurrent goroutines.
 			is.createPerDayIndexes(date, metricID, mn)
 		}
+		if ok && s.cardinalitySketches != nil {
+			// The (date, metricID) entry already exists in the indexDB, e.g. after the restart.
+			// Register it in sketches, since they aren't persisted.
+			s.cardinalitySketches.register(date, metricID, mn)
+		}
 		dateMetricIDsForCache = append(dateMetricIDsForCache, dateMetricID{
 			date:     date,
 			metricID: metricID,
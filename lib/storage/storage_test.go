@@ -807,6 +807,115 @@ func TestStorageRegisterMetricNamesConcurrent(t *testing.T) {
 	}
 }
 
+func TestStorageIterateMetricNames(t *testing.T) {
+	path := "TestStorageIterateMetricNames"
+	s, err := OpenStorage(path, 0, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+
+	const metricsCount = 100
+	registerMetricNames := func(start, end int) {
+		t.Helper()
+		var mrs []MetricRow
+		var mn MetricName
+		now := timestampFromTime(time.Now())
+		for i := start; i < end; i++ {
+			mn.MetricGroup = []byte(fmt.Sprintf("metric_%d", i))
+			mn.Tags = []Tag{
+				{[]byte("job"), []byte(fmt.Sprintf("job_%d", i%2))},
+			}
+			mrs = append(mrs, MetricRow{
+				MetricNameRaw: mn.marshalRaw(nil),
+				Timestamp:     now,
+			})
+		}
+		if err := s.RegisterMetricNames(nil, mrs); err != nil {
+			t.Fatalf("unexpected error in RegisterMetricNames: %s", err)
+		}
+	}
+	// Register a part of metric names in the previous indexDB in order to verify they are returned too.
+	registerMetricNames(0, metricsCount/2)
+	s.DebugFlush()
+	s.mustRotateIndexDB()
+	registerMetricNames(metricsCount/2, metricsCount)
+	s.DebugFlush()
+
+	iterate := func(tfss []*TagFilters, limit int) []string {
+		t.Helper()
+		var result []string
+		var afterMetricID uint64
+		for {
+			metricNames, nextMetricID, err := s.IterateMetricNames(nil, tfss, afterMetricID, limit, noDeadline)
+			if err != nil {
+				t.Fatalf("unexpected error in IterateMetricNames: %s", err)
+			}
+			if len(metricNames) > limit {
+				t.Fatalf("too many metric names returned; got %d; want up to %d", len(metricNames), limit)
+			}
+			var mn MetricName
+			for _, metricName := range metricNames {
+				if err := mn.UnmarshalString(metricName); err != nil {
+					t.Fatalf("cannot unmarshal metric name: %s", err)
+				}
+				result = append(result, mn.String())
+			}
+			if nextMetricID == 0 {
+				return result
+			}
+			if nextMetricID <= afterMetricID {
+				t.Fatalf("nextMetricID=%d must be bigger than afterMetricID=%d", nextMetricID, afterMetricID)
+			}
+			afterMetricID = nextMetricID
+		}
+	}
+	f := func(tfss []*TagFilters, limit int, metricNamesExpected []string) {
+		t.Helper()
+		metricNames := iterate(tfss, limit)
+		// Metric names must be returned in the same order on repeated iterations.
+		if metricNamesRepeated := iterate(tfss, limit); !reflect.DeepEqual(metricNames, metricNamesRepeated) {
+			t.Fatalf("unstable iteration order;\ngot\n%q\nwant\n%q", metricNamesRepeated, metricNames)
+		}
+		sort.Strings(metricNames)
+		if !reflect.DeepEqual(metricNames, metricNamesExpected) {
+			t.Fatalf("unexpected metric names;\ngot\n%q\nwant\n%q", metricNames, metricNamesExpected)
+		}
+	}
+
+	var all, job1 []string
+	for i := 0; i < metricsCount; i++ {
+		metricName := fmt.Sprintf(`metric_%d{job="job_%d"}`, i, i%2)
+		all = append(all, metricName)
+		if i%2 == 1 {
+			job1 = append(job1, metricName)
+		}
+	}
+	sort.Strings(all)
+	sort.Strings(job1)
+	f(nil, 1, all)
+	f(nil, 7, all)
+	f(nil, metricsCount, all)
+	f(nil, 10*metricsCount, all)
+
+	tfs := NewTagFilters()
+	if err := tfs.Add([]byte("job"), []byte("job_1"), false, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	f([]*TagFilters{tfs}, 3, job1)
+
+	// Multiple tfss are joined with `or`.
+	tfs0 := NewTagFilters()
+	if err := tfs0.Add([]byte("job"), []byte("job_0"), false, false); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	f([]*TagFilters{tfs0, tfs}, 11, all)
+
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
+
 func testStorageRegisterMetricNames(s *Storage) error {
 	const metricsPerAdd = 1e3
 	const addsCount = 10
@@ -1223,6 +1332,77 @@ func TestStorageDeleteStaleSnapshots(t *testing.T) {
 	}
 }
 
+func TestStoragePartialSnapshot(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	path := "TestStoragePartialSnapshot"
+	retentionMsecs := int64(msecsPerMonth * 10)
+	s, err := OpenStorage(path, retentionMsecs, 1e5, 1e5)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - s.retentionMsecs
+	mrs := testGenerateMetricRows(rng, 1e4, minTimestamp, maxTimestamp)
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error when adding mrs: %s", err)
+	}
+
+	// Invalid time range must be rejected.
+	if _, err := s.CreatePartialSnapshot(TimeRange{MinTimestamp: maxTimestamp, MaxTimestamp: minTimestamp}); err == nil {
+		t.Fatalf("expecting non-nil error for invalid time range")
+	}
+
+	// Create a snapshot for the last 35 days.
+	tr := TimeRange{
+		MinTimestamp: maxTimestamp - 35*msecPerDay,
+		MaxTimestamp: maxTimestamp,
+	}
+	snapshotName, err := s.CreatePartialSnapshot(tr)
+	if err != nil {
+		t.Fatalf("cannot create partial snapshot: %s", err)
+	}
+
+	getPartitionNames := func(s *Storage) []string {
+		ptws := s.tb.GetPartitions(nil)
+		defer s.tb.PutPartitions(ptws)
+		var names []string
+		for _, ptw := range ptws {
+			names = append(names, ptw.pt.name)
+		}
+		return names
+	}
+	allNames := getPartitionNames(s)
+
+	// Verify the snapshot contains only partitions overlapping tr.
+	snapshotPath := s.path + "/snapshots/" + snapshotName
+	s1, err := OpenStorage(snapshotPath, retentionMsecs, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage from snapshot: %s", err)
+	}
+	names := getPartitionNames(s1)
+	s1.MustClose()
+	if len(names) == 0 || len(names) > 2 || len(names) >= len(allNames) {
+		t.Fatalf("unexpected partitions in partial snapshot; got %q; all partitions: %q", names, allNames)
+	}
+	for _, name := range names {
+		var ptr TimeRange
+		if err := ptr.fromPartitionName(name); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if !ptr.overlapsWith(&tr) {
+			t.Fatalf("partition %q doesn't overlap with time range %s", name, &tr)
+		}
+	}
+
+	if err := s.DeleteSnapshot(snapshotName); err != nil {
+		t.Fatalf("cannot delete snapshot %q: %s", snapshotName, err)
+	}
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
+
 func containsString(a []string, s string) bool {
 	for i := range a {
 		if a[i] == s {
@@ -1231,3 +1411,82 @@ func containsString(a []string, s string) bool {
 	}
 	return false
 }
+
+func TestStorageReadYourWrites(t *testing.T) {
+	SetReadYourWrites(true, 0)
+	defer SetReadYourWrites(false, 0)
+
+	path := "TestStorageReadYourWrites"
+	s, err := OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 24*3600*1000
+	tr := TimeRange{
+		MinTimestamp: minTimestamp,
+		MaxTimestamp: maxTimestamp,
+	}
+	tfs := NewTagFilters()
+	if err := tfs.Add(nil, []byte("metric_.+"), false, true); err != nil {
+		t.Fatalf("cannot add tag filter: %s", err)
+	}
+	searchRows := func() int {
+		t.Helper()
+		var sr Search
+		sr.Init(nil, s, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+		rowsCount := 0
+		for sr.NextMetricBlock() {
+			var b Block
+			sr.MetricBlockRef.BlockRef.MustReadBlock(&b)
+			rowsCount += b.RowsCount()
+		}
+		if err := sr.Error(); err != nil {
+			t.Fatalf("search error: %s", err)
+		}
+		sr.MustClose()
+		return rowsCount
+	}
+	getFlushes := func() uint64 {
+		var m Metrics
+		s.UpdateMetrics(&m)
+		return m.ReadYourWritesFlushes
+	}
+
+	// Rows must be visible to search immediately after they are added.
+	const rowsCount = 1000
+	for i := 0; i < 3; i++ {
+		mrs := testGenerateMetricRows(rng, rowsCount, minTimestamp, maxTimestamp)
+		if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+			t.Fatalf("unexpected error when adding rows: %s", err)
+		}
+		if n := searchRows(); n != rowsCount*(i+1) {
+			t.Fatalf("unexpected number of rows found; got %d; want %d", n, rowsCount*(i+1))
+		}
+		metricNames, err := s.SearchMetricNames(nil, []*TagFilters{tfs}, tr, 1e5, noDeadline)
+		if err != nil {
+			t.Fatalf("cannot search metric names: %s", err)
+		}
+		if len(metricNames) != rowsCount {
+			t.Fatalf("unexpected number of metric names found; got %d; want %d", len(metricNames), rowsCount)
+		}
+	}
+	if n := getFlushes(); n == 0 {
+		t.Fatalf("expecting non-zero number of read-your-writes flushes")
+	}
+
+	// Searches must not flush pending rows if they were made visible during the last maxStaleness.
+	SetReadYourWrites(true, time.Hour)
+	flushes := getFlushes()
+	searchRows()
+	searchRows()
+	if n := getFlushes(); n != flushes {
+		t.Fatalf("unexpected number of read-your-writes flushes; got %d; want %d", n, flushes)
+	}
+
+	s.MustClose()
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func BenchmarkStorageAddRows(b *testing.B) {
@@ -15,6 +16,22 @@ func BenchmarkStorageAddRows(b *testing.B) {
 	}
 }
 
+func BenchmarkStorageAddRowsWAL(b *testing.B) {
+	defer func() {
+		walSyncPolicy = ""
+	}()
+	for _, syncPolicy := range []string{WALSyncNone, WALSyncInterval, WALSyncAlways} {
+		if err := SetWAL(syncPolicy, time.Second, time.Hour); err != nil {
+			b.Fatalf("cannot enable write-ahead log: %s", err)
+		}
+		for _, rowsPerBatch := range []int{1, 100, 1000} {
+			b.Run(fmt.Sprintf("syncPolicy_%s/rowsPerBatch_%d", syncPolicy, rowsPerBatch), func(b *testing.B) {
+				benchmarkStorageAddRows(b, rowsPerBatch)
+			})
+		}
+	}
+}
+
 func benchmarkStorageAddRows(b *testing.B, rowsPerBatch int) {
 	path := fmt.Sprintf("BenchmarkStorageAddRows_%d", rowsPerBatch)
 	s, err := OpenStorage(path, 0, 0, 0)
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,8 +24,21 @@ type table struct {
 	ptws     []*partitionWrapper
 	ptwsLock sync.Mutex
 
+	// addRowsLock is held in read mode while rows are added to partitions.
+	// It is locked in write mode by tiering in order to wait until in-flight writers release detached partitions.
+	addRowsLock sync.RWMutex
+
 	flockF *os.File
 
+	// tiering is an optional offloading of cold partitions to remote storage. It is enabled via SetTiering.
+	tiering *tiering
+
+	// dataPaths is an optional distribution of partitions among multiple data paths. It is enabled via SetExtraDataPaths.
+	dataPaths *dataPaths
+
+	// staging is an optional staging area for rows older than the current partition. It is enabled via SetBackfillStaging.
+	staging *backfillStaging
+
 	stop chan struct{}
 
 	retentionWatcherWG  sync.WaitGroup
@@ -117,9 +129,28 @@ func openTable(path string, s *Storage) (*table, error) {
 	}
 	fs.MustRemoveTemporaryDirs(bigSnapshotsPath)
 
+	var t *tiering
+	if tieringRemoteFS != nil {
+		t, err = openTiering(path, smallPartitionsPath, bigPartitionsPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open tiering for the table %q: %w", path, err)
+		}
+	}
+
+	var dps *dataPaths
+	if len(extraDataPaths) > 0 {
+		dps, err = openDataPaths(path, extraDataPaths)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open extra data paths for the table %q: %w", path, err)
+		}
+	}
+
 	// Open partitions.
-	pts, err := openPartitions(smallPartitionsPath, bigPartitionsPath, s)
+	pts, err := openPartitions(smallPartitionsPath, bigPartitionsPath, s, dps)
 	if err != nil {
+		if dps != nil {
+			dps.mustClose()
+		}
 		return nil, fmt.Errorf("cannot open partitions in the table %q: %w", path, err)
 	}
 
@@ -131,21 +162,41 @@ func openTable(path string, s *Storage) (*table, error) {
 
 		flockF: flockF,
 
+		tiering:   t,
+		dataPaths: dps,
+
 		stop: make(chan struct{}),
 	}
 	for _, pt := range pts {
 		tb.addPartitionNolock(pt)
 	}
+	if backfillStagingInterval > 0 {
+		tb.staging = newBackfillStaging(tb)
+		tb.staging.startFlusher(tb.stop)
+	}
 	tb.startRetentionWatcher()
 	tb.startFinalDedupWatcher()
+	if t != nil {
+		t.tb = tb
+		t.startWatcher()
+	}
+	if dps != nil {
+		dps.startWatcher()
+	}
 	return tb, nil
 }
 
 // CreateSnapshot creates tb snapshot and returns paths to small and big parts of it.
-func (tb *table) CreateSnapshot(snapshotName string) (string, string, error) {
+//
+// Only partitions overlapping tr are included in the snapshot if tr isn't nil.
+func (tb *table) CreateSnapshot(snapshotName string, tr *TimeRange) (string, string, error) {
 	logger.Infof("creating table snapshot of %q...", tb.path)
 	startTime := time.Now()
 
+	if tb.staging != nil {
+		// Add the staged rows to partitions, so they are included in the snapshot.
+		tb.staging.flush()
+	}
 	ptws := tb.GetPartitions(nil)
 	defer tb.PutPartitions(ptws)
 
@@ -159,8 +210,15 @@ func (tb *table) CreateSnapshot(snapshotName string) (string, string, error) {
 	}
 
 	for _, ptw := range ptws {
+		if tr != nil && !ptw.pt.tr.overlapsWith(tr) {
+			continue
+		}
 		smallPath := dstSmallDir + "/" + ptw.pt.name
 		bigPath := dstBigDir + "/" + ptw.pt.name
+		smallPath, bigPath, err := tb.dataPaths.createSnapshotPaths(snapshotName, ptw.pt.name, ptw.pt.smallPartsPath, smallPath, bigPath)
+		if err != nil {
+			return "", "", fmt.Errorf("cannot create snapshot for partition %q in %q: %w", ptw.pt.name, tb.path, err)
+		}
 		if err := ptw.pt.CreateSnapshotAt(smallPath, bigPath); err != nil {
 			return "", "", fmt.Errorf("cannot create snapshot for partition %q in %q: %w", ptw.pt.name, tb.path, err)
 		}
@@ -181,6 +239,7 @@ func (tb *table) MustDeleteSnapshot(snapshotName string) {
 	fs.MustRemoveDirAtomic(smallDir)
 	bigDir := fmt.Sprintf("%s/big/snapshots/%s", tb.path, snapshotName)
 	fs.MustRemoveDirAtomic(bigDir)
+	tb.dataPaths.mustDeleteSnapshot(snapshotName)
 }
 
 func (tb *table) addPartitionNolock(pt *partition) {
@@ -197,6 +256,16 @@ func (tb *table) MustClose() {
 	close(tb.stop)
 	tb.retentionWatcherWG.Wait()
 	tb.finalDedupWatcherWG.Wait()
+	if tb.staging != nil {
+		// Add the staged rows to partitions before closing them.
+		tb.staging.mustStop()
+	}
+	if tb.tiering != nil {
+		tb.tiering.mustClose()
+	}
+	if tb.dataPaths != nil {
+		tb.dataPaths.mustClose()
+	}
 
 	tb.ptwsLock.Lock()
 	ptws := tb.ptws
@@ -220,6 +289,9 @@ func (tb *table) MustClose() {
 //
 // This function is for debug purposes only.
 func (tb *table) flushPendingRows() {
+	if tb.staging != nil {
+		tb.staging.flush()
+	}
 	ptws := tb.GetPartitions(nil)
 	defer tb.PutPartitions(ptws)
 
@@ -229,11 +301,41 @@ func (tb *table) flushPendingRows() {
 	}
 }
 
+// makePendingRowsVisible makes all the rows added to tb before the call visible to search.
+func (tb *table) makePendingRowsVisible() {
+	ptws := tb.GetPartitions(nil)
+	defer tb.PutPartitions(ptws)
+
+	for _, ptw := range ptws {
+		ptw.pt.makePendingRowsVisible()
+	}
+}
+
+// mustFlushToDisk flushes all the rows added to tb before the call to disk, so they survive process crash.
+func (tb *table) mustFlushToDisk() {
+	if tb.staging != nil {
+		tb.staging.flush()
+	}
+	ptws := tb.GetPartitions(nil)
+	defer tb.PutPartitions(ptws)
+
+	for _, ptw := range ptws {
+		ptw.pt.mustFlushToDisk()
+	}
+}
+
 // TableMetrics contains essential metrics for the table.
 type TableMetrics struct {
 	partitionMetrics
+	tieringMetrics
+	dataPathsMetrics
+	backfillStagingMetrics
 
 	PartitionsRefCount uint64
+
+	// SmallMergeConcurrency and BigMergeConcurrency are the maximum number of concurrent merges for small and big parts.
+	SmallMergeConcurrency uint64
+	BigMergeConcurrency   uint64
 }
 
 // UpdateMetrics updates m with metrics from tb.
@@ -244,34 +346,84 @@ func (tb *table) UpdateMetrics(m *TableMetrics) {
 		m.PartitionsRefCount += atomic.LoadUint64(&ptw.refCount)
 	}
 	tb.ptwsLock.Unlock()
+
+	m.SmallMergeConcurrency = uint64(cap(mergeWorkersLimitCh))
+	m.BigMergeConcurrency = uint64(cap(bigMergeWorkersLimitCh))
+
+	if tb.tiering != nil {
+		tb.tiering.updateMetrics(&m.tieringMetrics)
+	}
+	if tb.dataPaths != nil {
+		tb.dataPaths.updateMetrics(&m.dataPathsMetrics)
+	}
+	if tb.staging != nil {
+		tb.staging.updateMetrics(&m.backfillStagingMetrics)
+	}
 }
 
 // ForceMergePartitions force-merges partitions in tb with names starting from the given partitionNamePrefix.
 //
 // Partitions are merged sequentially in order to reduce load on the system.
 func (tb *table) ForceMergePartitions(partitionNamePrefix string) error {
-	ptws := tb.GetPartitions(nil)
+	ptws := tb.getPartitionsForForceMerge(&ForceMergeScope{
+		PartitionPrefix: partitionNamePrefix,
+	})
 	defer tb.PutPartitions(ptws)
+	return forceMergePartitions(ptws, nil)
+}
+
+// getPartitionsForForceMerge returns partitions matching the given scope.
+//
+// The returned partitions must be released with PutPartitions.
+func (tb *table) getPartitionsForForceMerge(scope *ForceMergeScope) []*partitionWrapper {
+	ptws := tb.GetPartitions(nil)
+	dst := ptws[:0]
+	var ptwsSkipped []*partitionWrapper
 	for _, ptw := range ptws {
-		if !strings.HasPrefix(ptw.pt.name, partitionNamePrefix) {
-			continue
+		if scope.matchPartition(ptw.pt.name) {
+			dst = append(dst, ptw)
+		} else {
+			ptwsSkipped = append(ptwsSkipped, ptw)
 		}
-		logger.Infof("starting forced merge for partition %q", ptw.pt.name)
+	}
+	tb.PutPartitions(ptwsSkipped)
+	return dst
+}
+
+// forceMergePartitions sequentially runs forced merge for ptws and reports the progress to fmj if it isn't nil.
+//
+// Partitions are merged sequentially in order to reduce load on the system.
+func forceMergePartitions(ptws []*partitionWrapper, fmj *ForceMergeJob) error {
+	for _, ptw := range ptws {
+		pt := ptw.pt
+		fmj.startPartition(pt)
+		logger.Infof("starting forced merge for partition %q", pt.name)
 		startTime := time.Now()
-		if err := ptw.pt.ForceMergeAllParts(); err != nil {
-			return fmt.Errorf("cannot complete forced merge for partition %q: %w", ptw.pt.name, err)
+		if err := pt.forceMergeAllParts(fmj); err != nil {
+			return fmt.Errorf("cannot complete forced merge for partition %q: %w", pt.name, err)
 		}
-		logger.Infof("forced merge for partition %q has been finished in %.3f seconds", ptw.pt.name, time.Since(startTime).Seconds())
+		fmj.finishPartition()
+		logger.Infof("forced merge for partition %q has been finished in %.3f seconds", pt.name, time.Since(startTime).Seconds())
 	}
 	return nil
 }
 
 // AddRows adds the given rows to the table tb.
 func (tb *table) AddRows(rows []rawRow) error {
+	if tb.staging != nil {
+		rows = tb.staging.addRows(rows)
+	}
+	return tb.addRowsToPartitions(rows)
+}
+
+func (tb *table) addRowsToPartitions(rows []rawRow) error {
 	if len(rows) == 0 {
 		return nil
 	}
 
+	tb.addRowsLock.RLock()
+	defer tb.addRowsLock.RUnlock()
+
 	// Verify whether all the rows may be added to a single partition.
 	ptwsX := getPartitionWrappers()
 	defer putPartitionWrappers(ptwsX)
@@ -339,6 +491,8 @@ func (tb *table) AddRows(rows []rawRow) error {
 	// Create new partitions for these rows.
 	// Do this under tb.ptwsLock.
 	minTimestamp, maxTimestamp := tb.getMinMaxTimestamps()
+	offloadedRows := 0
+	var firstOffloadedTimestamp int64
 	tb.ptwsLock.Lock()
 	for i := range missingRows {
 		r := &missingRows[i]
@@ -348,6 +502,21 @@ func (tb *table) AddRows(rows []rawRow) error {
 			continue
 		}
 
+		if tb.tiering != nil && tb.tiering.hasTimestamp(r.Timestamp) {
+			// Skip row for the offloaded partition, since it cannot be modified.
+			if offloadedRows == 0 {
+				firstOffloadedTimestamp = r.Timestamp
+			}
+			offloadedRows++
+			continue
+		}
+
+		if tb.dataPaths.hasUnavailablePartition(r.Timestamp) {
+			// Skip row for the partition located at unavailable data path, since it cannot be created again.
+			atomic.AddUint64(&tb.dataPaths.unavailablePartitionRows, 1)
+			continue
+		}
+
 		// Make sure the partition for the r hasn't been added by another goroutines.
 		ptFound := false
 		for _, ptw := range tb.ptws {
@@ -361,6 +530,10 @@ func (tb *table) AddRows(rows []rawRow) error {
 			continue
 		}
 
+		if err := tb.dataPaths.placePartition(tb.smallPartitionsPath, tb.bigPartitionsPath, timestampToPartitionName(r.Timestamp)); err != nil {
+			tb.ptwsLock.Unlock()
+			return fmt.Errorf("errors while adding rows to table %q: %w", tb.path, err)
+		}
 		pt, err := createPartition(r.Timestamp, tb.smallPartitionsPath, tb.bigPartitionsPath, tb.s)
 		if err != nil {
 			// Return only the first error, since it has no sense in returning all errors.
@@ -372,12 +545,15 @@ func (tb *table) AddRows(rows []rawRow) error {
 	}
 	tb.ptwsLock.Unlock()
 
+	if offloadedRows > 0 {
+		tb.tiering.logIgnoredRows(offloadedRows, firstOffloadedTimestamp)
+	}
 	return nil
 }
 
 func (tb *table) getMinMaxTimestamps() (int64, int64) {
 	now := int64(fasttime.UnixTimestamp() * 1000)
-	minTimestamp := now - tb.s.retentionMsecs
+	minTimestamp := now - tb.s.getRetentionMsecs()
 	maxTimestamp := now + 2*24*3600*1000 // allow max +2 days from now due to timezones shit :)
 	if minTimestamp < 0 {
 		// Negative timestamps aren't supported by the storage.
@@ -407,12 +583,12 @@ func (tb *table) retentionWatcher() {
 		case <-ticker.C:
 		}
 
-		minTimestamp := int64(fasttime.UnixTimestamp()*1000) - tb.s.retentionMsecs
+		minTimestamp := int64(fasttime.UnixTimestamp()*1000) - tb.s.getRetentionMsecs()
 		var ptwsDrop []*partitionWrapper
 		tb.ptwsLock.Lock()
 		dst := tb.ptws[:0]
 		for _, ptw := range tb.ptws {
-			if ptw.pt.tr.MaxTimestamp < minTimestamp {
+			if ptw.pt.tr.MaxTimestamp < minTimestamp && !tb.s.isHeldTimeRange(ptw.pt.tr) {
 				ptwsDrop = append(ptwsDrop, ptw)
 			} else {
 				dst = append(dst, ptw)
@@ -499,7 +675,7 @@ func (tb *table) PutPartitions(ptws []*partitionWrapper) {
 	}
 }
 
-func openPartitions(smallPartitionsPath, bigPartitionsPath string, s *Storage) ([]*partition, error) {
+func openPartitions(smallPartitionsPath, bigPartitionsPath string, s *Storage, dps *dataPaths) ([]*partition, error) {
 	// Certain partition directories in either `big` or `small` dir may be missing
 	// after restoring from backup. So populate partition names from both dirs.
 	ptNames := make(map[string]bool)
@@ -513,6 +689,9 @@ func openPartitions(smallPartitionsPath, bigPartitionsPath string, s *Storage) (
 	for ptName := range ptNames {
 		smallPartsPath := smallPartitionsPath + "/" + ptName
 		bigPartsPath := bigPartitionsPath + "/" + ptName
+		if !dps.isPartitionAvailable(ptName, smallPartsPath, bigPartsPath) {
+			continue
+		}
 		pt, err := openPartition(smallPartsPath, bigPartsPath, s)
 		if err != nil {
 			mustClosePartitions(pts)
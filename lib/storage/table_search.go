@@ -66,7 +66,7 @@ func (ts *tableSearch) Init(tb *table, tsids []TSID, tr TimeRange) {
 	// Adjust tr.MinTimestamp, so it doesn't obtain data older
 	// than the tb retention.
 	now := int64(fasttime.UnixTimestamp() * 1000)
-	minTimestamp := now - tb.s.retentionMsecs
+	minTimestamp := now - tb.s.getRetentionMsecs()
 	if tr.MinTimestamp < minTimestamp {
 		tr.MinTimestamp = minTimestamp
 	}
@@ -82,6 +82,14 @@ func (ts *tableSearch) Init(tb *table, tsids []TSID, tr TimeRange) {
 	}
 
 	ts.ptws = tb.GetPartitions(ts.ptws[:0])
+	if tb.tiering != nil {
+		ptws, err := tb.tiering.getPartitions(ts.ptws, tr)
+		ts.ptws = ptws
+		if err != nil {
+			ts.err = fmt.Errorf("cannot initialize table search: %w", err)
+			return
+		}
+	}
 
 	// Initialize the ptsPool.
 	if n := len(ts.ptws) - cap(ts.ptsPool); n > 0 {
@@ -97,8 +97,8 @@ func convertToCompositeTagFilters(tfs *TagFilters) []*TagFilters {
 				// Skip the tf, since it is used as a prefix in composite filter.
 				continue
 			}
-			if string(tf.key) == "__graphite__" || bytes.Equal(tf.key, graphiteReverseTagKey) {
-				// Leave as is __graphite__ filters, since they cannot be used for building composite filter.
+			if string(tf.key) == "__graphite__" || bytes.Equal(tf.key, graphiteReverseTagKey) || isCompositeLabelsKey(tf.key) {
+				// Leave as is __graphite__ and composite labels filters, since they cannot be used for building composite filter.
 				tfsNew = append(tfsNew, tf)
 				continue
 			}
@@ -178,6 +178,12 @@ func (tfs *TagFilters) Add(key, value []byte, isNegative, isRegexp bool) error {
 		value = []byte(".+")
 	}
 
+	if !isRegexp && len(key) > 0 && needShortLabelValue(value) {
+		// Too long label values are stored in indexdb in the shortened form.
+		// See SetMaxIndexedLabelValueLen for details.
+		value = appendShortLabelValue(nil, value)
+	}
+
 	tf := tfs.addTagFilter()
 	if err := tf.Init(tfs.commonPrefix, key, value, isNegative, isRegexp); err != nil {
 		return fmt.Errorf("cannot initialize tagFilter: %w", err)
@@ -304,6 +310,13 @@ func (tf *tagFilter) String() string {
 		}
 		return fmt.Sprintf("composite(%s,%s)%s%q", metricName, key, op, value)
 	}
+	if isCompositeLabelsKey(tf.key) {
+		labels, err := unmarshalCompositeLabelsKey(tf.key)
+		if err != nil {
+			logger.Panicf("BUG: cannot unmarshal composite labels key: %s", err)
+		}
+		return fmt.Sprintf("composite_labels(%s)%s%q", strings.Join(labels, "+"), op, value)
+	}
 	if len(tf.key) == 0 {
 		return fmt.Sprintf("__name__%s%q", op, value)
 	}
@@ -448,6 +461,9 @@ func (tf *tagFilter) Init(commonPrefix, key, value []byte, isNegative, isRegexp
 	tf.reSuffixMatch = rcv.reMatch
 	tf.matchCost = rcv.reCost
 	tf.isEmptyMatch = len(prefix) == 0 && tf.reSuffixMatch(nil)
+	if len(key) > 0 {
+		tf.shortenOrSuffixes(commonPrefix, prefix)
+	}
 	if !tf.isNegative && len(key) == 0 && strings.IndexByte(rcv.literalSuffix, '.') >= 0 {
 		// Reverse suffix is needed only for non-negative regexp filters on __name__ that contains dots.
 		tf.graphiteReverseSuffix = reverseBytes(tf.graphiteReverseSuffix[:0], []byte(rcv.literalSuffix))
@@ -455,6 +471,43 @@ func (tf *tagFilter) Init(commonPrefix, key, value []byte, isNegative, isRegexp
 	return nil
 }
 
+// shortenOrSuffixes replaces too long values in tf.orSuffixes with the shortened values.
+//
+// Too long label values are stored in indexdb in the shortened form, so filters like {url=~"long1|long2"}
+// must search for shortened values. See SetMaxIndexedLabelValueLen for details.
+func (tf *tagFilter) shortenOrSuffixes(commonPrefix []byte, prefix string) {
+	if maxIndexedLabelValueLen <= 0 {
+		return
+	}
+	needShorten := false
+	for _, orSuffix := range tf.orSuffixes {
+		if len(prefix)+len(orSuffix) > maxIndexedLabelValueLen {
+			needShorten = true
+			break
+		}
+	}
+	if !needShorten {
+		return
+	}
+	// The shortened value may not start with the common prefix, so move the prefix into orSuffixes.
+	orSuffixes := make([]string, 0, len(tf.orSuffixes))
+	for _, orSuffix := range tf.orSuffixes {
+		value := []byte(prefix + orSuffix)
+		if needShortLabelValue(value) {
+			value = appendShortLabelValue(nil, value)
+		}
+		orSuffixes = append(orSuffixes, string(value))
+	}
+	// Sort orSuffixes for faster seek later.
+	sort.Strings(orSuffixes)
+	tf.regexpPrefix = ""
+	tf.prefix = append(tf.prefix[:0], commonPrefix...)
+	tf.prefix = marshalTagValue(tf.prefix, tf.key)
+	tf.prefix = marshalTagValueNoTrailingTagSeparator(tf.prefix, "")
+	tf.orSuffixes = append(tf.orSuffixes[:0], orSuffixes...)
+	tf.reSuffixMatch, tf.matchCost = newMatchFuncForOrSuffixes(orSuffixes)
+}
+
 func (tf *tagFilter) match(b []byte) (bool, error) {
 	prefix := tf.prefix
 	if !bytes.HasPrefix(b, prefix) {
@@ -818,7 +871,7 @@ func getMaxRegexpCacheSize() int {
 	maxRegexpCacheSizeOnce.Do(func() {
 		maxRegexpCacheSize = int(0.05 * float64(memory.Allowed()))
 	})
-	return maxRegexpCacheSize
+	return memory.ScaleCacheSize(maxRegexpCacheSize)
 }
 
 var (
@@ -873,7 +926,7 @@ func getMaxPrefixesCacheSize() int {
 	maxPrefixesCacheSizeOnce.Do(func() {
 		maxPrefixesCacheSize = int(0.05 * float64(memory.Allowed()))
 	})
-	return maxPrefixesCacheSize
+	return memory.ScaleCacheSize(maxPrefixesCacheSize)
 }
 
 var (
@@ -0,0 +1,678 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/common"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fslocal"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fasttime"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+var (
+	tieringRemoteFS     common.RemoteFS
+	tieringOffloadAfter time.Duration
+	tieringMaxCacheSize uint64
+)
+
+// SetTiering enables offloading of per-month partitions to remoteFS.
+//
+// Partitions with data older than offloadAfter are uploaded to remoteFS and then are deleted from the local storage.
+// Offloaded partitions are fetched from remoteFS into local cache when queries touch their time ranges.
+// The cache size is limited by maxCacheSizeBytes. Least recently used partitions are evicted from the cache
+// when its size exceeds the limit.
+//
+// Samples with timestamps belonging to offloaded partitions are ignored during data ingestion.
+//
+// This function must be called before opening the storage.
+func SetTiering(remoteFS common.RemoteFS, offloadAfter time.Duration, maxCacheSizeBytes int64) error {
+	if remoteFS == nil {
+		return fmt.Errorf("remoteFS cannot be nil")
+	}
+	if offloadAfter <= 0 {
+		return fmt.Errorf("offloadAfter must be positive; got %s", offloadAfter)
+	}
+	if maxCacheSizeBytes <= 0 {
+		return fmt.Errorf("maxCacheSizeBytes must be positive; got %d", maxCacheSizeBytes)
+	}
+	tieringRemoteFS = remoteFS
+	tieringOffloadAfter = offloadAfter
+	tieringMaxCacheSize = uint64(maxCacheSizeBytes)
+	return nil
+}
+
+// tieringCheckInterval is the interval for checking for partitions to offload and for offloaded partitions outside the retention.
+var tieringCheckInterval = time.Hour
+
+// tiering offloads cold partitions of the table to remote storage and fetches them back on queries.
+//
+// The remote storage has the same layout as the local table: parts of the offloaded partition are stored
+// under small/<partition_name> and big/<partition_name> paths. The partition is considered offloaded
+// only after <partition_name>_offloaded.ignore file is created in the remote storage.
+//
+// The names of offloaded partitions are persisted as files in the `offloaded` directory of the table.
+type tiering struct {
+	// Atomic counters must go at the top of the structure in order to properly align by 8 bytes on 32-bit archs.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/212 .
+	offloads       uint64
+	offloadErrors  uint64
+	fetches        uint64
+	fetchErrors    uint64
+	cacheEvictions uint64
+	ignoredRows    uint64
+
+	tb       *table
+	remoteFS common.RemoteFS
+
+	offloadedPath string
+	uploadPath    string
+	cachePath     string
+
+	offloadAfter time.Duration
+	maxCacheSize uint64
+
+	// mu protects the fields below.
+	mu        sync.Mutex
+	pts       map[string]*offloadedPartition
+	cacheSize uint64
+
+	watcherWG sync.WaitGroup
+}
+
+// offloadedPartition is a partition, which has been offloaded to remote storage.
+type offloadedPartition struct {
+	name string
+	tr   TimeRange
+
+	// The fields below are protected by tiering.mu.
+
+	// ptw is the locally cached partition. It is nil if the partition isn't cached.
+	//
+	// ptw holds a reference, which is released when the partition is evicted from the cache.
+	ptw *partitionWrapper
+
+	// sizeBytes is the size of the cached partition.
+	sizeBytes uint64
+
+	// lastAccessTime is unix timestamp in seconds for the last access to the cached partition.
+	lastAccessTime uint64
+
+	// fetchCh is non-nil while the partition is fetched from remote storage. It is closed when the fetch is finished.
+	fetchCh chan struct{}
+
+	// fetchErr is the error for the last fetch.
+	fetchErr error
+
+	// isOffloading is set while the partition is uploaded to remote storage.
+	// Such a partition cannot be evicted from the cache, since it isn't available at remote storage yet.
+	isOffloading bool
+}
+
+func offloadedMarkerFilename(ptName string) string {
+	return ptName + "_offloaded.ignore"
+}
+
+// openTiering opens tiering for the table at tablePath.
+//
+// Local copies of the offloaded partitions are removed from smallPartitionsPath and bigPartitionsPath,
+// so they aren't opened as regular partitions.
+func openTiering(tablePath, smallPartitionsPath, bigPartitionsPath string) (*tiering, error) {
+	t := &tiering{
+		remoteFS:      tieringRemoteFS,
+		offloadedPath: tablePath + "/offloaded",
+		uploadPath:    tablePath + "/tiering/upload",
+		cachePath:     tablePath + "/tiering/cache",
+		offloadAfter:  tieringOffloadAfter,
+		maxCacheSize:  tieringMaxCacheSize,
+		pts:           make(map[string]*offloadedPartition),
+	}
+	if err := fs.MkdirAllIfNotExist(t.offloadedPath); err != nil {
+		return nil, fmt.Errorf("cannot create directory for offloaded partitions: %w", err)
+	}
+	// The cache and pending uploads aren't preserved across restarts.
+	tieringPath := tablePath + "/tiering"
+	if err := fs.MkdirAllIfNotExist(tieringPath); err != nil {
+		return nil, fmt.Errorf("cannot create directory for tiering: %w", err)
+	}
+	fs.MustRemoveTemporaryDirs(tieringPath)
+	fs.MustRemoveDirAtomic(t.uploadPath)
+	fs.MustRemoveDirAtomic(t.cachePath)
+
+	d, err := os.Open(t.offloadedPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open directory with offloaded partitions: %w", err)
+	}
+	defer fs.MustClose(d)
+	fis, err := d.Readdir(-1)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read directory with offloaded partitions %q: %w", t.offloadedPath, err)
+	}
+	for _, fi := range fis {
+		ptName := fi.Name()
+		if fs.IsTemporaryFileName(ptName) {
+			continue
+		}
+		op := &offloadedPartition{
+			name: ptName,
+		}
+		if err := op.tr.fromPartitionName(ptName); err != nil {
+			return nil, fmt.Errorf("cannot obtain time range for offloaded partition: %w", err)
+		}
+		// The local copy of the partition can be left after the offloading if the process has been stopped
+		// before it has been evicted from the cache.
+		fs.MustRemoveDirAtomic(smallPartitionsPath + "/" + ptName)
+		fs.MustRemoveDirAtomic(bigPartitionsPath + "/" + ptName)
+		t.pts[ptName] = op
+	}
+	return t, nil
+}
+
+func (t *tiering) startWatcher() {
+	t.watcherWG.Add(1)
+	go func() {
+		t.watcher()
+		t.watcherWG.Done()
+	}()
+}
+
+func (t *tiering) watcher() {
+	ticker := time.NewTicker(tieringCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-t.tb.stop:
+			return
+		case <-ticker.C:
+		}
+		t.dropPartitionsOutsideRetention()
+		t.offloadPartitions()
+	}
+}
+
+// mustClose closes cached partitions.
+//
+// It is expected that all the pending searches are finished and the watcher is stopped before calling mustClose.
+func (t *tiering) mustClose() {
+	t.watcherWG.Wait()
+
+	t.mu.Lock()
+	var ptws []*partitionWrapper
+	for _, op := range t.pts {
+		if op.ptw != nil {
+			ptws = append(ptws, op.ptw)
+			op.ptw = nil
+		}
+	}
+	t.cacheSize = 0
+	t.mu.Unlock()
+
+	for _, ptw := range ptws {
+		if n := atomic.LoadUint64(&ptw.refCount); n != 1 {
+			logger.Panicf("BUG: unexpected refCount=%d when closing the cached partition; probably there are pending searches", n)
+		}
+		ptw.decRef()
+	}
+}
+
+// hasTimestamp returns true if the given timestamp belongs to offloaded partition.
+func (t *tiering) hasTimestamp(timestamp int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, op := range t.pts {
+		if timestamp >= op.tr.MinTimestamp && timestamp <= op.tr.MaxTimestamp {
+			return true
+		}
+	}
+	return false
+}
+
+// logIgnoredRows registers n rows, which have been skipped because they belong to offloaded partitions.
+//
+// firstTimestamp is the timestamp of the first skipped row.
+func (t *tiering) logIgnoredRows(n int, firstTimestamp int64) {
+	atomic.AddUint64(&t.ignoredRows, uint64(n))
+	tieringIgnoredRowsLogger.Warnf("ignoring %d rows, since they belong to partitions offloaded to %s; the first ignored row has timestamp %d in the partition %q; "+
+		"offloaded partitions cannot be modified, so -storage.tieringOffloadAfter must exceed the maximum delay for ingested samples; "+
+		"see https://docs.victoriametrics.com/#storage-tiering", n, t.remoteFS, firstTimestamp, timestampToPartitionName(firstTimestamp))
+}
+
+var tieringIgnoredRowsLogger = logger.WithThrottler("tieringIgnoredRows", 5*time.Second)
+
+// offloadPartitions offloads partitions with data older than t.offloadAfter to remote storage.
+func (t *tiering) offloadPartitions() {
+	now := int64(fasttime.UnixTimestamp() * 1000)
+	maxTimestamp := now - t.offloadAfter.Milliseconds()
+	currentPartitionName := timestampToPartitionName(now)
+
+	// Detach partitions from the table, so new rows aren't added to them.
+	// Detached partitions remain available for search via t.pts until they are evicted from the cache.
+	var ptws []*partitionWrapper
+	tb := t.tb
+	tb.ptwsLock.Lock()
+	t.mu.Lock()
+	dst := tb.ptws[:0]
+	for _, ptw := range tb.ptws {
+		pt := ptw.pt
+		if pt.tr.MaxTimestamp >= maxTimestamp || pt.name == currentPartitionName || t.pts[pt.name] != nil {
+			dst = append(dst, ptw)
+			continue
+		}
+		t.pts[pt.name] = &offloadedPartition{
+			name:         pt.name,
+			tr:           pt.tr,
+			ptw:          ptw,
+			isOffloading: true,
+		}
+		ptws = append(ptws, ptw)
+	}
+	tb.ptws = dst
+	t.mu.Unlock()
+	tb.ptwsLock.Unlock()
+
+	if len(ptws) > 0 {
+		// Concurrent AddRows calls may still hold the detached partitions obtained before the detach.
+		// Wait until they finish, so all the rows written to the detached partitions get into the snapshot
+		// created by offloadPartition. Subsequent AddRows calls cannot see the detached partitions.
+		tb.addRowsLock.Lock()
+		tb.addRowsLock.Unlock()
+	}
+
+	for _, ptw := range ptws {
+		pt := ptw.pt
+		logger.Infof("offloading partition %q to %s", pt.name, t.remoteFS)
+		startTime := time.Now()
+		if err := t.offloadPartition(pt); err != nil {
+			atomic.AddUint64(&t.offloadErrors, 1)
+			logger.Errorf("cannot offload partition %q to %s: %s; the partition remains in local storage", pt.name, t.remoteFS, err)
+
+			// Return the partition to the table.
+			tb.ptwsLock.Lock()
+			t.mu.Lock()
+			delete(t.pts, pt.name)
+			tb.ptws = append(tb.ptws, ptw)
+			t.mu.Unlock()
+			tb.ptwsLock.Unlock()
+			continue
+		}
+		atomic.AddUint64(&t.offloads, 1)
+
+		var m partitionMetrics
+		pt.UpdateMetrics(&m)
+		t.mu.Lock()
+		op := t.pts[pt.name]
+		op.isOffloading = false
+		op.sizeBytes = m.SmallSizeBytes + m.BigSizeBytes
+		op.lastAccessTime = fasttime.UnixTimestamp()
+		t.cacheSize += op.sizeBytes
+		t.mu.Unlock()
+		logger.Infof("partition %q has been offloaded to %s in %.3f seconds", pt.name, t.remoteFS, time.Since(startTime).Seconds())
+	}
+	if len(ptws) > 0 {
+		t.evictPartitions(nil)
+	}
+}
+
+func (t *tiering) offloadPartition(pt *partition) error {
+	smallPath := t.uploadPath + "/small/" + pt.name
+	bigPath := t.uploadPath + "/big/" + pt.name
+	fs.MustRemoveDirAtomic(smallPath)
+	fs.MustRemoveDirAtomic(bigPath)
+	defer func() {
+		fs.MustRemoveDirAtomic(smallPath)
+		fs.MustRemoveDirAtomic(bigPath)
+	}()
+	if err := pt.CreateSnapshotAt(smallPath, bigPath); err != nil {
+		return fmt.Errorf("cannot create partition snapshot: %w", err)
+	}
+
+	// Delete parts left after the previous unsuccessful attempt to offload the partition.
+	if err := t.deleteRemotePartition(pt.name); err != nil {
+		return err
+	}
+
+	src := &fslocal.FS{
+		Dir: t.uploadPath,
+	}
+	if err := src.Init(); err != nil {
+		return fmt.Errorf("cannot initialize local fs: %w", err)
+	}
+	defer src.MustStop()
+	parts, err := src.ListParts()
+	if err != nil {
+		return fmt.Errorf("cannot list partition files: %w", err)
+	}
+	for _, p := range parts {
+		if err := t.uploadPart(src, p); err != nil {
+			return err
+		}
+	}
+	if err := t.remoteFS.CreateFile(offloadedMarkerFilename(pt.name), []byte("ok")); err != nil {
+		return fmt.Errorf("cannot create marker file for offloaded partition: %w", err)
+	}
+	if err := fs.WriteFileAtomically(t.offloadedPath+"/"+pt.name, nil, true); err != nil {
+		return fmt.Errorf("cannot persist the name of offloaded partition: %w", err)
+	}
+	return nil
+}
+
+func (t *tiering) uploadPart(src *fslocal.FS, p common.Part) error {
+	r, err := src.NewReadCloser(p)
+	if err != nil {
+		return fmt.Errorf("cannot open %s: %w", &p, err)
+	}
+	err = t.remoteFS.UploadPart(p, r)
+	_ = r.Close()
+	if err != nil {
+		return fmt.Errorf("cannot upload %s: %w", &p, err)
+	}
+	return nil
+}
+
+// getPartitions appends offloaded partitions overlapping with tr to dst and returns the result.
+//
+// The partitions are fetched from remote storage if they aren't cached yet.
+// The returned partitions must be released via table.PutPartitions.
+func (t *tiering) getPartitions(dst []*partitionWrapper, tr TimeRange) ([]*partitionWrapper, error) {
+	t.mu.Lock()
+	var ops []*offloadedPartition
+	for _, op := range t.pts {
+		if op.tr.MinTimestamp <= tr.MaxTimestamp && tr.MinTimestamp <= op.tr.MaxTimestamp {
+			ops = append(ops, op)
+		}
+	}
+	t.mu.Unlock()
+	if len(ops) == 0 {
+		return dst, nil
+	}
+
+	for _, op := range ops {
+		ptw, err := t.getPartition(op)
+		if err != nil {
+			return dst, fmt.Errorf("cannot fetch offloaded partition %q from %s: %w", op.name, t.remoteFS, err)
+		}
+		dst = append(dst, ptw)
+	}
+	t.evictPartitions(ops)
+	return dst, nil
+}
+
+func (t *tiering) getPartition(op *offloadedPartition) (*partitionWrapper, error) {
+	t.mu.Lock()
+	for op.fetchCh != nil {
+		// Wait until the concurrent fetch is finished.
+		fetchCh := op.fetchCh
+		t.mu.Unlock()
+		<-fetchCh
+		t.mu.Lock()
+		if op.ptw == nil && op.fetchErr != nil {
+			err := op.fetchErr
+			t.mu.Unlock()
+			return nil, err
+		}
+	}
+	if ptw := op.ptw; ptw != nil {
+		// Fast path - the partition is cached.
+		ptw.incRef()
+		op.lastAccessTime = fasttime.UnixTimestamp()
+		t.mu.Unlock()
+		return ptw, nil
+	}
+	op.fetchCh = make(chan struct{})
+	t.mu.Unlock()
+
+	// Slow path - fetch the partition from remote storage.
+	atomic.AddUint64(&t.fetches, 1)
+	logger.Infof("fetching offloaded partition %q from %s", op.name, t.remoteFS)
+	startTime := time.Now()
+	pt, err := t.fetchPartition(op.name)
+
+	t.mu.Lock()
+	close(op.fetchCh)
+	op.fetchCh = nil
+	op.fetchErr = err
+	if err != nil {
+		t.mu.Unlock()
+		atomic.AddUint64(&t.fetchErrors, 1)
+		return nil, err
+	}
+	ptw := &partitionWrapper{
+		pt:       pt,
+		refCount: 2,
+	}
+	if t.pts[op.name] != op {
+		// The partition has been dropped while it was fetched.
+		// Drop it after the caller releases it.
+		t.mu.Unlock()
+		ptw.scheduleToDrop()
+		ptw.decRef()
+		return ptw, nil
+	}
+	var m partitionMetrics
+	pt.UpdateMetrics(&m)
+	op.ptw = ptw
+	op.sizeBytes = m.SmallSizeBytes + m.BigSizeBytes
+	op.lastAccessTime = fasttime.UnixTimestamp()
+	t.cacheSize += op.sizeBytes
+	t.mu.Unlock()
+	logger.Infof("offloaded partition %q has been fetched from %s in %.3f seconds; size: %d bytes",
+		op.name, t.remoteFS, time.Since(startTime).Seconds(), op.sizeBytes)
+	return ptw, nil
+}
+
+func (t *tiering) fetchPartition(ptName string) (*partition, error) {
+	ok, err := t.remoteFS.HasFile(offloadedMarkerFilename(ptName))
+	if err != nil {
+		return nil, fmt.Errorf("cannot check for marker file: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("missing marker file %q for the offloaded partition", offloadedMarkerFilename(ptName))
+	}
+	parts, err := t.getRemoteParts(ptName)
+	if err != nil {
+		return nil, err
+	}
+
+	smallPath := t.cachePath + "/small/" + ptName
+	bigPath := t.cachePath + "/big/" + ptName
+	fs.MustRemoveDirAtomic(smallPath)
+	fs.MustRemoveDirAtomic(bigPath)
+	dst := &fslocal.FS{
+		Dir: t.cachePath,
+	}
+	if err := dst.Init(); err != nil {
+		return nil, fmt.Errorf("cannot initialize local fs: %w", err)
+	}
+	defer dst.MustStop()
+	for _, p := range parts {
+		if err := t.downloadPart(dst, p); err != nil {
+			fs.MustRemoveDirAtomic(smallPath)
+			fs.MustRemoveDirAtomic(bigPath)
+			return nil, err
+		}
+	}
+	pt, err := openPartition(smallPath, bigPath, t.tb.s)
+	if err != nil {
+		fs.MustRemoveDirAtomic(smallPath)
+		fs.MustRemoveDirAtomic(bigPath)
+		return nil, fmt.Errorf("cannot open fetched partition: %w", err)
+	}
+	return pt, nil
+}
+
+func (t *tiering) downloadPart(dst *fslocal.FS, p common.Part) error {
+	if p.ActualSize != p.Size {
+		return fmt.Errorf("broken %s; actual size: %d", &p, p.ActualSize)
+	}
+	w, err := dst.NewWriteCloser(p)
+	if err != nil {
+		return fmt.Errorf("cannot create file for %s: %w", &p, err)
+	}
+	if err := t.remoteFS.DownloadPart(p, w); err != nil {
+		_ = w.Close()
+		return fmt.Errorf("cannot download %s: %w", &p, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("cannot close file for %s: %w", &p, err)
+	}
+	return nil
+}
+
+// getRemoteParts returns parts for the partition with the given ptName at remote storage.
+func (t *tiering) getRemoteParts(ptName string) ([]common.Part, error) {
+	parts, err := t.remoteFS.ListParts()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list parts at %s: %w", t.remoteFS, err)
+	}
+	smallPrefix := "small/" + ptName + "/"
+	bigPrefix := "big/" + ptName + "/"
+	dst := parts[:0]
+	for _, p := range parts {
+		if strings.HasPrefix(p.Path, smallPrefix) || strings.HasPrefix(p.Path, bigPrefix) {
+			dst = append(dst, p)
+		}
+	}
+	return dst, nil
+}
+
+func (t *tiering) deleteRemotePartition(ptName string) error {
+	if err := t.remoteFS.DeleteFile(offloadedMarkerFilename(ptName)); err != nil {
+		return fmt.Errorf("cannot delete marker file for offloaded partition: %w", err)
+	}
+	parts, err := t.getRemoteParts(ptName)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if err := t.remoteFS.DeletePart(p); err != nil {
+			return fmt.Errorf("cannot delete %s from %s: %w", &p, t.remoteFS, err)
+		}
+	}
+	if len(parts) > 0 {
+		if err := t.remoteFS.RemoveEmptyDirs(); err != nil {
+			return fmt.Errorf("cannot remove empty dirs at %s: %w", t.remoteFS, err)
+		}
+	}
+	return nil
+}
+
+// evictPartitions evicts the least recently used partitions from the cache until its size exceeds t.maxCacheSize.
+//
+// Partitions from opsKeep aren't evicted.
+func (t *tiering) evictPartitions(opsKeep []*offloadedPartition) {
+	var ptws []*partitionWrapper
+	t.mu.Lock()
+	for t.cacheSize > t.maxCacheSize {
+		var lru *offloadedPartition
+		for _, op := range t.pts {
+			if op.ptw == nil || op.isOffloading || containsOffloadedPartition(opsKeep, op) {
+				continue
+			}
+			if lru == nil || op.lastAccessTime < lru.lastAccessTime {
+				lru = op
+			}
+		}
+		if lru == nil {
+			break
+		}
+		ptws = append(ptws, lru.ptw)
+		lru.ptw = nil
+		t.cacheSize -= lru.sizeBytes
+		lru.sizeBytes = 0
+		atomic.AddUint64(&t.cacheEvictions, 1)
+	}
+	t.mu.Unlock()
+
+	// Drop the evicted partitions after all the pending searches are done.
+	for _, ptw := range ptws {
+		ptw.scheduleToDrop()
+		ptw.decRef()
+	}
+}
+
+func containsOffloadedPartition(ops []*offloadedPartition, op *offloadedPartition) bool {
+	for _, x := range ops {
+		if x == op {
+			return true
+		}
+	}
+	return false
+}
+
+// dropPartitionsOutsideRetention drops offloaded partitions outside the retention from local cache and from remote storage.
+func (t *tiering) dropPartitionsOutsideRetention() {
+	minTimestamp := int64(fasttime.UnixTimestamp()*1000) - t.tb.s.getRetentionMsecs()
+	var ops []*offloadedPartition
+	var ptws []*partitionWrapper
+	t.mu.Lock()
+	for ptName, op := range t.pts {
+		if op.tr.MaxTimestamp >= minTimestamp || op.isOffloading || t.tb.s.isHeldTimeRange(op.tr) {
+			continue
+		}
+		if op.ptw != nil {
+			ptws = append(ptws, op.ptw)
+			op.ptw = nil
+			t.cacheSize -= op.sizeBytes
+			op.sizeBytes = 0
+		}
+		ops = append(ops, op)
+		delete(t.pts, ptName)
+	}
+	t.mu.Unlock()
+
+	for _, ptw := range ptws {
+		ptw.scheduleToDrop()
+		ptw.decRef()
+	}
+	for _, op := range ops {
+		if err := t.deleteRemotePartition(op.name); err != nil {
+			// The partition will be deleted on the next retention check.
+			logger.Errorf("cannot delete offloaded partition %q outside the retention from %s: %s", op.name, t.remoteFS, err)
+			t.mu.Lock()
+			t.pts[op.name] = op
+			t.mu.Unlock()
+			continue
+		}
+		fs.MustRemoveAll(t.offloadedPath + "/" + op.name)
+		logger.Infof("offloaded partition %q outside the retention has been deleted from %s", op.name, t.remoteFS)
+	}
+}
+
+// tieringMetrics contains metrics for the tiering.
+type tieringMetrics struct {
+	OffloadedPartitions      uint64
+	TieringCachedPartitions  uint64
+	TieringCacheSizeBytes    uint64
+	TieringCacheMaxSizeBytes uint64
+	TieringOffloads          uint64
+	TieringOffloadErrors     uint64
+	TieringFetches           uint64
+	TieringFetchErrors       uint64
+	TieringCacheEvictions    uint64
+	OffloadedPartitionRows   uint64
+}
+
+func (t *tiering) updateMetrics(m *tieringMetrics) {
+	t.mu.Lock()
+	m.OffloadedPartitions += uint64(len(t.pts))
+	for _, op := range t.pts {
+		if op.ptw != nil {
+			m.TieringCachedPartitions++
+		}
+	}
+	m.TieringCacheSizeBytes += t.cacheSize
+	t.mu.Unlock()
+
+	m.TieringCacheMaxSizeBytes += t.maxCacheSize
+	m.TieringOffloads += atomic.LoadUint64(&t.offloads)
+	m.TieringOffloadErrors += atomic.LoadUint64(&t.offloadErrors)
+	m.TieringFetches += atomic.LoadUint64(&t.fetches)
+	m.TieringFetchErrors += atomic.LoadUint64(&t.fetchErrors)
+	m.TieringCacheEvictions += atomic.LoadUint64(&t.cacheEvictions)
+	m.OffloadedPartitionRows += atomic.LoadUint64(&t.ignoredRows)
+}
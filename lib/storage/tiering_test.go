@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/backup/fsremote"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestTableTiering(t *testing.T) {
+	const path = "TestTableTiering"
+	remotePath, err := filepath.Abs("TestTableTiering-remote")
+	if err != nil {
+		t.Fatalf("cannot obtain absolute path: %s", err)
+	}
+	for _, p := range []string{path, remotePath} {
+		if err := os.RemoveAll(p); err != nil {
+			t.Fatalf("cannot remove %q: %s", p, err)
+		}
+	}
+	defer func() {
+		_ = os.RemoveAll(path)
+		_ = os.RemoveAll(remotePath)
+	}()
+
+	remoteFS := &fsremote.FS{
+		Dir: remotePath,
+	}
+	if err := SetTiering(remoteFS, 30*24*time.Hour, 1); err != nil {
+		t.Fatalf("cannot set tiering: %s", err)
+	}
+	defer func() {
+		tieringRemoteFS = nil
+		tieringOffloadAfter = 0
+		tieringMaxCacheSize = 0
+	}()
+
+	// Generate rows for the current partition and for two partitions, which must be offloaded.
+	rng := rand.New(rand.NewSource(1))
+	now := timestampFromTime(time.Now())
+	var rows []rawRow
+	var r rawRow
+	r.PrecisionBits = 24
+	var trData TimeRange
+	trData.fromPartitionTimestamp(now - 4*31*24*3600*1000)
+	trData.MaxTimestamp = now + 3600*1000
+	for _, ts := range []int64{now - 4*31*24*3600*1000, now - 3*31*24*3600*1000, now} {
+		var ptr TimeRange
+		ptr.fromPartitionTimestamp(ts)
+		for i := 0; i < 1000; i++ {
+			r.TSID.MetricID = uint64(rng.Intn(10))
+			r.Timestamp = ptr.MinTimestamp + int64(i)*1000
+			r.Value = float64(int(rng.NormFloat64() * 1e5))
+			rows = append(rows, r)
+		}
+	}
+	var tsids []TSID
+	for i := 0; i < 10; i++ {
+		tsids = append(tsids, TSID{MetricID: uint64(i)})
+	}
+	sort.Slice(tsids, func(i, j int) bool { return tsids[i].Less(&tsids[j]) })
+	rbsExpected := getTestExpectedRawBlocks(rows, tsids, trData)
+
+	strg := newTestStorage()
+	tb, err := openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot create table: %s", err)
+	}
+	if err := tb.AddRows(rows); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	tb.flushPendingRows()
+
+	tb.tiering.offloadPartitions()
+	var m TableMetrics
+	tb.UpdateMetrics(&m)
+	if m.TieringOffloads != 2 || m.TieringOffloadErrors != 0 {
+		t.Fatalf("unexpected offloads; got %d; want 2; errors: %d", m.TieringOffloads, m.TieringOffloadErrors)
+	}
+	if m.OffloadedPartitions != 2 {
+		t.Fatalf("unexpected number of offloaded partitions; got %d; want 2", m.OffloadedPartitions)
+	}
+	if n := len(tb.ptws); n != 1 {
+		t.Fatalf("unexpected number of local partitions; got %d; want 1", n)
+	}
+
+	// Offloaded partitions must be evicted from local storage, since the cache size is smaller than partition sizes.
+	if m.TieringCachedPartitions != 0 {
+		t.Fatalf("unexpected number of cached partitions; got %d; want 0", m.TieringCachedPartitions)
+	}
+	for ptName := range tb.tiering.pts {
+		if fs.IsPathExist(path + "/small/" + ptName) {
+			t.Fatalf("the local copy of the offloaded partition %q must be deleted", ptName)
+		}
+	}
+
+	// Offloaded partitions must be fetched on search.
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.UpdateMetrics(&m)
+	if m.TieringFetches != 2 || m.TieringFetchErrors != 0 {
+		t.Fatalf("unexpected fetches; got %d; want 2; errors: %d", m.TieringFetches, m.TieringFetchErrors)
+	}
+
+	// Rows for offloaded partitions must be ignored.
+	if err := tb.AddRows(rows[:1]); err != nil {
+		t.Fatalf("cannot add rows to table: %s", err)
+	}
+	tb.UpdateMetrics(&m)
+	if m.OffloadedPartitionRows != 1 {
+		t.Fatalf("unexpected number of ignored rows; got %d; want 1", m.OffloadedPartitionRows)
+	}
+	tb.MustClose()
+
+	// Offloaded partitions must be available after re-opening the table.
+	tb, err = openTable(path, strg)
+	if err != nil {
+		t.Fatalf("cannot open table: %s", err)
+	}
+	testTableSearch(t, tb, tsids, trData, rbsExpected, -1)
+	tb.MustClose()
+}
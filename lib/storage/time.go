@@ -39,6 +39,11 @@ func (tr *TimeRange) String() string {
 	return fmt.Sprintf("[%s..%s]", start, end)
 }
 
+// overlapsWith returns true if tr overlaps with x.
+func (tr *TimeRange) overlapsWith(x *TimeRange) bool {
+	return tr.MinTimestamp <= x.MaxTimestamp && x.MinTimestamp <= tr.MaxTimestamp
+}
+
 // TimestampToHumanReadableFormat converts the given timestamp to human-readable format.
 func TimestampToHumanReadableFormat(timestamp int64) string {
 	t := timestampToTime(timestamp).UTC()
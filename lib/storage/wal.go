@@ -0,0 +1,497 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/bytesutil"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/logger"
+)
+
+// Supported sync policies for the write-ahead log.
+const (
+	// WALSyncAlways syncs the write-ahead log to disk after every write.
+	WALSyncAlways = "always"
+
+	// WALSyncInterval syncs the write-ahead log to disk with the interval passed to SetWAL.
+	WALSyncInterval = "interval"
+
+	// WALSyncNone relies on the OS for syncing the write-ahead log to disk.
+	WALSyncNone = "none"
+)
+
+var (
+	walSyncPolicy         string
+	walSyncInterval       time.Duration
+	walCheckpointInterval time.Duration
+)
+
+// SetWAL enables write-ahead log for the storage.
+//
+// Rows added to the storage are written to the write-ahead log before being added to in-memory parts,
+// so they can be recovered on the next start after unclean shutdown.
+// syncPolicy must be one of WALSyncAlways, WALSyncInterval or WALSyncNone.
+// syncInterval is used for WALSyncInterval policy.
+// checkpointInterval is the interval for flushing the ingested data to disk and removing the obsolete log segments.
+//
+// This function must be called before opening the storage.
+func SetWAL(syncPolicy string, syncInterval, checkpointInterval time.Duration) error {
+	switch syncPolicy {
+	case WALSyncAlways, WALSyncInterval, WALSyncNone:
+	default:
+		return fmt.Errorf("unsupported sync policy %q; supported values: %q, %q, %q", syncPolicy, WALSyncAlways, WALSyncInterval, WALSyncNone)
+	}
+	if syncPolicy == WALSyncInterval && syncInterval <= 0 {
+		return fmt.Errorf("sync interval must be positive; got %s", syncInterval)
+	}
+	if checkpointInterval <= 0 {
+		return fmt.Errorf("checkpoint interval must be positive; got %s", checkpointInterval)
+	}
+	walSyncPolicy = syncPolicy
+	walSyncInterval = syncInterval
+	walCheckpointInterval = checkpointInterval
+	return nil
+}
+
+// maxWALRecordSize is the maximum size of a single write-ahead log record payload.
+//
+// Bigger records are treated as corrupted during replay.
+const maxWALRecordSize = 32 * 1024 * 1024
+
+// wal is a write-ahead log for rows added to Storage.
+//
+// The log consists of segments. Every segment contains records with marshaled rows passed to Storage.AddRows calls.
+// Segments are rotated on every checkpoint. Rotated segments are removed after the data
+// from them is flushed to disk.
+//
+// Concurrent writers are group-committed: records from all the writers, which arrived while the previous
+// write is in progress, are written (and synced if needed) to the segment with a single write call.
+type wal struct {
+	// Atomic counters must go at the top of the structure in order to properly align by 8 bytes on 32-bit archs.
+	// See https://github.com/VictoriaMetrics/VictoriaMetrics/issues/212 .
+	sizeBytes   uint64
+	syncs       uint64
+	checkpoints uint64
+
+	path string
+
+	// addRowsLock prevents from rotating the log while rows are added to the storage.
+	// This guarantees that all the rows from rotated segments are registered in the storage.
+	addRowsLock sync.RWMutex
+
+	// mu protects the fields below.
+	mu sync.Mutex
+
+	// writeCond is used for waiting for the write of pending records by the current leader.
+	writeCond *sync.Cond
+
+	f           *os.File
+	segmentIdx  uint64
+	segmentSize uint64
+	needSync    bool
+
+	// pending contains records, which aren't written to f yet.
+	pending []byte
+
+	// spare is the buffer for the next pending records.
+	spare []byte
+
+	// pendingSeq is the sequence number of the last record added to pending.
+	pendingSeq uint64
+
+	// writtenSeq is the sequence number of the last record written to f.
+	writtenSeq uint64
+
+	// writing is set while the leader writes pending records to f.
+	writing bool
+}
+
+var walSegmentNameRegexp = regexp.MustCompile("^[0-9A-F]{16}$")
+
+func openWAL(path string) (*wal, error) {
+	if err := fs.MkdirAllIfNotExist(path); err != nil {
+		return nil, fmt.Errorf("cannot create directory for write-ahead log: %w", err)
+	}
+	segments, err := getWALSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &wal{
+		path: path,
+	}
+	w.writeCond = sync.NewCond(&w.mu)
+	if len(segments) > 0 {
+		// Continue numbering after the existing segments, so new segments are replayed after them.
+		w.segmentIdx = segments[len(segments)-1] + 1
+	}
+	if err := w.createSegment(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *wal) segmentPath(idx uint64) string {
+	return fmt.Sprintf("%s/%016X", w.path, idx)
+}
+
+func (w *wal) createSegment() error {
+	path := w.segmentPath(w.segmentIdx)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("cannot create write-ahead log segment: %w", err)
+	}
+	fs.MustSyncPath(w.path)
+	w.f = f
+	w.segmentSize = 0
+	w.needSync = false
+	return nil
+}
+
+// mustWriteRows writes mrs to w.
+//
+// mrs are split into records with payload size not exceeding maxWALRecordSize.
+func (w *wal) mustWriteRows(mrs []MetricRow, precisionBits uint8) {
+	bb := walBufPool.Get()
+	buf := bb.B[:0]
+	for len(mrs) > 0 {
+		recordStart := len(buf)
+		buf = append(buf, make([]byte, 8)...)
+		payloadStart := len(buf)
+		buf = append(buf, precisionBits)
+		n := 0
+		for n < len(mrs) && (n == 0 || len(buf)-payloadStart < maxWALRecordSize/2) {
+			buf = mrs[n].Marshal(buf)
+			n++
+		}
+		mrs = mrs[n:]
+		payload := buf[payloadStart:]
+		if len(payload) > maxWALRecordSize {
+			logger.Panicf("BUG: too big write-ahead log record: %d bytes; it mustn't exceed %d bytes", len(payload), maxWALRecordSize)
+		}
+		// Every record starts with the header containing payload length and payload checksum.
+		header := encoding.MarshalUint32(buf[recordStart:recordStart], uint32(len(payload)))
+		encoding.MarshalUint32(header, crc32.ChecksumIEEE(payload))
+	}
+	w.mustWrite(buf)
+	bb.B = buf
+	walBufPool.Put(bb)
+}
+
+var walBufPool bytesutil.ByteBufferPool
+
+// mustWrite writes buf to w and waits until it is written (and synced for WALSyncAlways policy).
+func (w *wal) mustWrite(buf []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = append(w.pending, buf...)
+	w.pendingSeq++
+	seq := w.pendingSeq
+	for w.writtenSeq < seq {
+		if w.writing {
+			w.writeCond.Wait()
+			continue
+		}
+
+		// Become the leader and write all the pending records from concurrent writers at once.
+		w.writing = true
+		data := w.pending
+		lastSeq := w.pendingSeq
+		w.pending = w.spare[:0]
+		f := w.f
+		w.mu.Unlock()
+
+		if _, err := f.Write(data); err != nil {
+			logger.Panicf("FATAL: cannot write %d bytes to write-ahead log segment %q: %s", len(data), f.Name(), err)
+		}
+		if walSyncPolicy == WALSyncAlways {
+			if err := f.Sync(); err != nil {
+				logger.Panicf("FATAL: cannot sync write-ahead log segment %q: %s", f.Name(), err)
+			}
+			atomic.AddUint64(&w.syncs, 1)
+		}
+
+		w.mu.Lock()
+		w.segmentSize += uint64(len(data))
+		atomic.AddUint64(&w.sizeBytes, uint64(len(data)))
+		w.needSync = walSyncPolicy != WALSyncAlways
+		w.spare = data[:0]
+		w.writtenSeq = lastSeq
+		w.writing = false
+		w.writeCond.Broadcast()
+	}
+}
+
+// mustSyncIfNeeded syncs the current segment if it has unsynced writes.
+//
+// The caller must hold w.addRowsLock, so the segment isn't rotated during the sync.
+func (w *wal) mustSyncIfNeeded() {
+	w.mu.Lock()
+	needSync := w.needSync
+	w.needSync = false
+	f := w.f
+	w.mu.Unlock()
+
+	if !needSync {
+		return
+	}
+	if err := f.Sync(); err != nil {
+		logger.Panicf("FATAL: cannot sync write-ahead log segment %q: %s", f.Name(), err)
+	}
+	atomic.AddUint64(&w.syncs, 1)
+}
+
+func (w *wal) mustSyncLocked() {
+	if err := w.f.Sync(); err != nil {
+		logger.Panicf("FATAL: cannot sync write-ahead log segment %q: %s", w.f.Name(), err)
+	}
+	w.needSync = false
+	atomic.AddUint64(&w.syncs, 1)
+}
+
+// mustRotate starts a new segment and returns its index.
+//
+// false is returned if the current segment is empty, i.e. there is no need in rotation.
+func (w *wal) mustRotate() (uint64, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.segmentSize == 0 {
+		return 0, false
+	}
+	w.mustSyncLocked()
+	fs.MustClose(w.f)
+	w.segmentIdx++
+	if err := w.createSegment(); err != nil {
+		logger.Panicf("FATAL: %s", err)
+	}
+	return w.segmentIdx, true
+}
+
+// mustRemoveSegmentsBefore removes segments with indexes smaller than segmentIdx.
+func (w *wal) mustRemoveSegmentsBefore(segmentIdx uint64) {
+	segments, err := getWALSegments(w.path)
+	if err != nil {
+		logger.Panicf("FATAL: %s", err)
+	}
+	for _, idx := range segments {
+		if idx >= segmentIdx {
+			continue
+		}
+		path := w.segmentPath(idx)
+		size := fs.MustFileSize(path)
+		if err := os.Remove(path); err != nil {
+			logger.Panicf("FATAL: cannot remove write-ahead log segment: %s", err)
+		}
+		atomic.AddUint64(&w.sizeBytes, ^uint64(size-1))
+	}
+	fs.MustSyncPath(w.path)
+}
+
+// mustClose closes w and removes all its segments.
+//
+// It must be called only after all the data from w is flushed to disk.
+func (w *wal) mustClose() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	fs.MustClose(w.f)
+	w.f = nil
+	w.mustRemoveSegmentsBefore(w.segmentIdx + 1)
+}
+
+// getWALSegments returns sorted indexes of write-ahead log segments at the given path.
+func getWALSegments(path string) ([]uint64, error) {
+	des, err := os.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot read write-ahead log directory: %w", err)
+	}
+	var segments []uint64
+	for _, de := range des {
+		name := de.Name()
+		if !de.Type().IsRegular() || !walSegmentNameRegexp.MatchString(name) {
+			continue
+		}
+		idx, err := strconv.ParseUint(name, 16, 64)
+		if err != nil {
+			logger.Panicf("BUG: cannot parse write-ahead log segment name %q: %s", name, err)
+		}
+		segments = append(segments, idx)
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i] < segments[j]
+	})
+	return segments, nil
+}
+
+// mustReplayWAL adds rows from the write-ahead log at the given path to s, flushes them to disk
+// and then removes the write-ahead log segments.
+func (s *Storage) mustReplayWAL(path string) {
+	segments, err := getWALSegments(path)
+	if err != nil {
+		logger.Panicf("FATAL: %s", err)
+	}
+	if len(segments) == 0 {
+		return
+	}
+	logger.Infof("replaying %d write-ahead log segments at %q...", len(segments), path)
+	startTime := time.Now()
+	rowsCount := 0
+	for _, idx := range segments {
+		segmentPath := fmt.Sprintf("%s/%016X", path, idx)
+		n, err := s.replayWALSegment(segmentPath)
+		if err != nil {
+			logger.Warnf("the write-ahead log segment %q is truncated or corrupted; %d rows were recovered from it; "+
+				"this is expected after unclean shutdown; error: %s", segmentPath, n, err)
+		}
+		rowsCount += n
+	}
+	s.MustFlushToDisk()
+	for _, idx := range segments {
+		segmentPath := fmt.Sprintf("%s/%016X", path, idx)
+		if err := os.Remove(segmentPath); err != nil {
+			logger.Panicf("FATAL: cannot remove write-ahead log segment: %s", err)
+		}
+	}
+	fs.MustSyncPath(path)
+	logger.Infof("replayed %d rows from write-ahead log at %q in %.3f seconds", rowsCount, path, time.Since(startTime).Seconds())
+}
+
+// replayWALSegment adds rows from the write-ahead log segment at the given path to s.
+//
+// It returns the number of replayed rows.
+func (s *Storage) replayWALSegment(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer fs.MustClose(f)
+
+	br := bufio.NewReader(f)
+	rowsCount := 0
+	var header [8]byte
+	var payload []byte
+	var mrs []MetricRow
+	for {
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return rowsCount, nil
+			}
+			return rowsCount, fmt.Errorf("cannot read record header: %w", err)
+		}
+		payloadLen := encoding.UnmarshalUint32(header[:4])
+		checksum := encoding.UnmarshalUint32(header[4:])
+		if payloadLen == 0 {
+			return rowsCount, fmt.Errorf("unexpected zero record length")
+		}
+		if payloadLen > maxWALRecordSize {
+			// Do not allocate memory for the record, since its length is likely corrupted.
+			return rowsCount, fmt.Errorf("too big record length: %d bytes; it mustn't exceed %d bytes", payloadLen, maxWALRecordSize)
+		}
+		payload = bytesutil.ResizeNoCopyNoOverallocate(payload, int(payloadLen))
+		if _, err := io.ReadFull(br, payload); err != nil {
+			return rowsCount, fmt.Errorf("cannot read record with length %d: %w", payloadLen, err)
+		}
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return rowsCount, fmt.Errorf("checksum mismatch for record with length %d", payloadLen)
+		}
+		precisionBits := payload[0]
+		tail := payload[1:]
+		mrs = mrs[:0]
+		for len(tail) > 0 {
+			if cap(mrs) > len(mrs) {
+				mrs = mrs[:len(mrs)+1]
+			} else {
+				mrs = append(mrs, MetricRow{})
+			}
+			tail, err = mrs[len(mrs)-1].UnmarshalX(tail)
+			if err != nil {
+				return rowsCount, fmt.Errorf("cannot unmarshal row: %w", err)
+			}
+		}
+		if err := s.AddRows(mrs, precisionBits); err != nil {
+			logger.Warnf("cannot add rows from write-ahead log segment %q: %s", path, err)
+		}
+		rowsCount += len(mrs)
+	}
+}
+
+// MustFlushToDisk flushes all the data added to s before the call to disk, so it survives process crash.
+func (s *Storage) MustFlushToDisk() {
+	s.tb.mustFlushToDisk()
+	s.idb().tb.MustFlushToDisk()
+}
+
+func (s *Storage) startWALWorkers() {
+	s.walWorkersWG.Add(1)
+	go func() {
+		s.walCheckpointer()
+		s.walWorkersWG.Done()
+	}()
+	if walSyncPolicy != WALSyncInterval {
+		return
+	}
+	s.walWorkersWG.Add(1)
+	go func() {
+		s.walSyncer()
+		s.walWorkersWG.Done()
+	}()
+}
+
+func (s *Storage) walSyncer() {
+	ticker := time.NewTicker(walSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			w := s.wal
+			w.addRowsLock.RLock()
+			w.mustSyncIfNeeded()
+			w.addRowsLock.RUnlock()
+		}
+	}
+}
+
+func (s *Storage) walCheckpointer() {
+	ticker := time.NewTicker(walCheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.mustCheckpointWAL()
+		}
+	}
+}
+
+// mustCheckpointWAL flushes the data from the write-ahead log to disk and removes the obsolete log segments.
+func (s *Storage) mustCheckpointWAL() {
+	w := s.wal
+	w.addRowsLock.Lock()
+	segmentIdx, ok := w.mustRotate()
+	w.addRowsLock.Unlock()
+	if !ok {
+		return
+	}
+	s.MustFlushToDisk()
+	w.mustRemoveSegmentsBefore(segmentIdx)
+	atomic.AddUint64(&w.checkpoints, 1)
+}
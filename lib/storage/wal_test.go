@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/encoding"
+	"github.com/VictoriaMetrics/VictoriaMetrics/lib/fs"
+)
+
+func TestSetWAL(t *testing.T) {
+	defer func() {
+		walSyncPolicy = ""
+	}()
+	f := func(syncPolicy string, syncInterval, checkpointInterval time.Duration, resultExpected bool) {
+		t.Helper()
+		err := SetWAL(syncPolicy, syncInterval, checkpointInterval)
+		if (err == nil) != resultExpected {
+			t.Fatalf("unexpected result for SetWAL(%q, %s, %s); got error %v", syncPolicy, syncInterval, checkpointInterval, err)
+		}
+	}
+	f(WALSyncAlways, 0, time.Minute, true)
+	f(WALSyncInterval, time.Second, time.Minute, true)
+	f(WALSyncNone, 0, time.Minute, true)
+	f("foobar", time.Second, time.Minute, false)
+	f(WALSyncInterval, 0, time.Minute, false)
+	f(WALSyncAlways, 0, 0, false)
+}
+
+func TestStorageWALReplay(t *testing.T) {
+	path := "TestStorageWALReplay"
+	walPath := path + "/wal"
+	rng := rand.New(rand.NewSource(1))
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 24*3600*1000
+
+	// Write rows to the write-ahead log and leave it as is like after unclean shutdown.
+	w, err := openWAL(walPath)
+	if err != nil {
+		t.Fatalf("cannot open write-ahead log: %s", err)
+	}
+	const rowsPerWrite = 1000
+	const writesCount = 5
+	// Write rows concurrently in order to verify group commit doesn't corrupt records.
+	var wg sync.WaitGroup
+	for i := 0; i < writesCount; i++ {
+		mrs := testGenerateMetricRows(rng, rowsPerWrite, minTimestamp, maxTimestamp)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.mustWriteRows(mrs, defaultPrecisionBits)
+		}()
+	}
+	wg.Wait()
+	fs.MustClose(w.f)
+
+	// Append incomplete record to the segment.
+	f, err := os.OpenFile(w.segmentPath(w.segmentIdx), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("cannot open write-ahead log segment: %s", err)
+	}
+	if _, err := f.Write([]byte{0, 0, 1, 0, 1, 2, 3}); err != nil {
+		t.Fatalf("cannot write to write-ahead log segment: %s", err)
+	}
+	fs.MustClose(f)
+
+	// Verify the rows are recovered on storage open.
+	s, err := OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if rowsCount := m.TableMetrics.TotalRowsCount(); rowsCount != rowsPerWrite*writesCount {
+		t.Fatalf("unexpected number of rows recovered from write-ahead log; got %d; want %d", rowsCount, rowsPerWrite*writesCount)
+	}
+	if m.TableMetrics.InmemoryPartsCount != 0 {
+		t.Fatalf("recovered rows must be flushed to disk; got %d in-memory parts", m.TableMetrics.InmemoryPartsCount)
+	}
+	segments, err := getWALSegments(walPath)
+	if err != nil {
+		t.Fatalf("cannot get write-ahead log segments: %s", err)
+	}
+	if len(segments) != 0 {
+		t.Fatalf("write-ahead log segments must be removed after replay; got %d segments", len(segments))
+	}
+	s.MustClose()
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
+
+func TestStorageWALCheckpoint(t *testing.T) {
+	if err := SetWAL(WALSyncAlways, 0, time.Hour); err != nil {
+		t.Fatalf("cannot enable write-ahead log: %s", err)
+	}
+	defer func() {
+		walSyncPolicy = ""
+	}()
+
+	path := "TestStorageWALCheckpoint"
+	walPath := path + "/wal"
+	s, err := OpenStorage(path, -1, 0, 0)
+	if err != nil {
+		t.Fatalf("cannot open storage: %s", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	maxTimestamp := timestampFromTime(time.Now())
+	minTimestamp := maxTimestamp - 24*3600*1000
+	const rowsCount = 1000
+	mrs := testGenerateMetricRows(rng, rowsCount, minTimestamp, maxTimestamp)
+	if err := s.AddRows(mrs, defaultPrecisionBits); err != nil {
+		t.Fatalf("unexpected error when adding rows: %s", err)
+	}
+
+	var m Metrics
+	s.UpdateMetrics(&m)
+	if m.WALSizeBytes == 0 {
+		t.Fatalf("expecting non-zero write-ahead log size")
+	}
+	if m.WALSyncs == 0 {
+		t.Fatalf("expecting non-zero write-ahead log syncs for %q sync policy", WALSyncAlways)
+	}
+
+	s.mustCheckpointWAL()
+
+	m.Reset()
+	s.UpdateMetrics(&m)
+	if m.WALSizeBytes != 0 {
+		t.Fatalf("unexpected write-ahead log size after checkpoint; got %d; want 0", m.WALSizeBytes)
+	}
+	if m.WALCheckpoints != 1 {
+		t.Fatalf("unexpected number of write-ahead log checkpoints; got %d; want 1", m.WALCheckpoints)
+	}
+	if n := m.TableMetrics.TotalRowsCount(); n != rowsCount {
+		t.Fatalf("unexpected number of rows after checkpoint; got %d; want %d", n, rowsCount)
+	}
+	if m.TableMetrics.InmemoryPartsCount != 0 {
+		t.Fatalf("rows must be flushed to disk after checkpoint; got %d in-memory parts", m.TableMetrics.InmemoryPartsCount)
+	}
+	segments, err := getWALSegments(walPath)
+	if err != nil {
+		t.Fatalf("cannot get write-ahead log segments: %s", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("unexpected number of write-ahead log segments after checkpoint; got %d; want 1", len(segments))
+	}
+
+	// The checkpoint must be skipped if no rows were added since the previous checkpoint.
+	s.mustCheckpointWAL()
+	m.Reset()
+	s.UpdateMetrics(&m)
+	if m.WALCheckpoints != 1 {
+		t.Fatalf("unexpected number of write-ahead log checkpoints; got %d; want 1", m.WALCheckpoints)
+	}
+
+	s.MustClose()
+	segments, err = getWALSegments(walPath)
+	if err != nil {
+		t.Fatalf("cannot get write-ahead log segments: %s", err)
+	}
+	if len(segments) != 0 {
+		t.Fatalf("write-ahead log segments must be removed on close; got %d segments", len(segments))
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		t.Fatalf("cannot remove %q: %s", path, err)
+	}
+}
+
+func TestOpenWALSegmentIndex(t *testing.T) {
+	path := "TestOpenWALSegmentIndex"
+	defer func() {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove %q: %s", path, err)
+		}
+	}()
+
+	w, err := openWAL(path)
+	if err != nil {
+		t.Fatalf("cannot open write-ahead log: %s", err)
+	}
+	if w.segmentIdx != 0 {
+		t.Fatalf("unexpected segment index for empty write-ahead log; got %d; want 0", w.segmentIdx)
+	}
+	fs.MustClose(w.f)
+
+	// New segments must be created after the existing segments.
+	if err := os.WriteFile(path+"/00000000000000FF", nil, 0644); err != nil {
+		t.Fatalf("cannot create write-ahead log segment: %s", err)
+	}
+	w, err = openWAL(path)
+	if err != nil {
+		t.Fatalf("cannot open write-ahead log: %s", err)
+	}
+	if w.segmentIdx != 0x100 {
+		t.Fatalf("unexpected segment index; got %d; want %d", w.segmentIdx, 0x100)
+	}
+	fs.MustClose(w.f)
+}
+
+func TestReplayWALSegmentTooBigRecord(t *testing.T) {
+	path := "TestReplayWALSegmentTooBigRecord"
+	defer func() {
+		if err := os.RemoveAll(path); err != nil {
+			t.Fatalf("cannot remove %q: %s", path, err)
+		}
+	}()
+
+	// The record header with corrupted length mustn't result in big memory allocation.
+	header := encoding.MarshalUint32(nil, 0xfffffff0)
+	header = encoding.MarshalUint32(header, 0)
+	if err := os.WriteFile(path, header, 0644); err != nil {
+		t.Fatalf("cannot create write-ahead log segment: %s", err)
+	}
+	var s Storage
+	n, err := s.replayWALSegment(path)
+	if err == nil {
+		t.Fatalf("expecting non-nil error")
+	}
+	if !strings.Contains(err.Error(), "too big record length") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 0 {
+		t.Fatalf("unexpected number of replayed rows; got %d; want 0", n)
+	}
+}